@@ -18,9 +18,17 @@ const CURATED_WASMS = [
   'tree-sitter-go.wasm',
   'tree-sitter-rust.wasm',
   'tree-sitter-java.wasm',
+  'tree-sitter-kotlin.wasm',
   'tree-sitter-c.wasm',
   'tree-sitter-cpp.wasm',
-  'tree-sitter-c_sharp.wasm'
+  'tree-sitter-c_sharp.wasm',
+  'tree-sitter-ruby.wasm',
+  'tree-sitter-php.wasm',
+  'tree-sitter-swift.wasm',
+  'tree-sitter-objc.wasm',
+  'tree-sitter-scala.wasm',
+  'tree-sitter-elixir.wasm',
+  'tree-sitter-zig.wasm'
 ];
 
 const sourceDir = path.join(path.dirname(require.resolve('tree-sitter-wasms/package.json')), 'out');
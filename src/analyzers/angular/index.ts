@@ -1177,4 +1177,10 @@ export class AngularAnalyzer implements FrameworkAnalyzer {
   getSnippetPattern(category: string, name: string): RegExp | null {
     return AngularAnalyzer.SNIPPET_PATTERNS[category]?.[name] || null;
   }
+
+  detectRoles(chunk: CodeChunk): string[] {
+    return chunk.framework === 'angular' && chunk.componentType === 'component'
+      ? ['ui_component']
+      : [];
+  }
 }
@@ -9,6 +9,7 @@ import type { TSESTree } from '@typescript-eslint/typescript-estree';
 import {
   FrameworkAnalyzer,
   AnalysisResult,
+  AnalyzeOptions,
   CodebaseMetadata,
   CodeChunk,
   CodeComponent,
@@ -19,11 +20,19 @@ import {
 import { createChunksFromCode } from '../../utils/chunking.js';
 import {
   createASTAlignedChunks,
+  DEFAULT_AST_CHUNK_OPTIONS,
   MAX_AST_CHUNK_FILE_SIZE,
   MAX_AST_CHUNK_FILE_LINES
 } from '../../utils/ast-chunker.js';
+import { createInfraChunks } from '../../utils/infra-chunker.js';
+import { createSfcChunks, isSfcLanguage, type SfcFile } from '../../utils/sfc-chunker.js';
+import { createNotebookChunks } from '../../utils/notebook-chunker.js';
+import { createSchemaChunks } from '../../utils/schema-chunker.js';
+import { createSqlChunks } from '../../utils/sql-chunker.js';
+import { createMarkdownChunks } from '../../utils/markdown-chunker.js';
 import { detectLanguage } from '../../utils/language-detection.js';
 import { extractTreeSitterSymbols, type TreeSitterSymbol } from '../../utils/tree-sitter.js';
+import { grammarPluginForExtension } from '../../grammars/plugins.js';
 import {
   detectWorkspaceType,
   scanWorkspacePackageJsons,
@@ -31,6 +40,19 @@ import {
   categorizeDependency
 } from '../../utils/dependency-detection.js';
 import type { WorkspacePackageJson } from '../../utils/workspace-detection.js';
+import { detectGenericRoles } from './roles.js';
+
+export interface GenericAnalyzerOptions {
+  /** Estimated-token ceiling per AST chunk. Default: CODEBASE_CONTEXT_MAX_CHUNK_TOKENS or none */
+  maxChunkTokens?: number;
+  /** Overlap lines for split oversized symbols. Default: CODEBASE_CONTEXT_CHUNK_OVERLAP_LINES or 0 */
+  chunkOverlapLines?: number;
+}
+
+function readPositiveIntEnv(name: string): number | undefined {
+  const parsed = Number.parseInt(process.env[name] ?? '', 10);
+  return Number.isFinite(parsed) && parsed > 0 ? parsed : undefined;
+}
 
 export class GenericAnalyzer implements FrameworkAnalyzer {
   readonly name = 'generic';
@@ -43,6 +65,10 @@ export class GenericAnalyzer implements FrameworkAnalyzer {
     '.tsx',
     '.mjs',
     '.cjs',
+    // Single-file components
+    '.vue',
+    '.svelte',
+    '.astro',
     // Python
     '.py',
     '.pyi',
@@ -67,10 +93,17 @@ export class GenericAnalyzer implements FrameworkAnalyzer {
     '.php',
     // Ruby
     '.rb',
-    // Swift
+    // Swift/Objective-C
     '.swift',
+    '.m',
+    '.mm',
     // Scala
     '.scala',
+    // Elixir
+    '.ex',
+    '.exs',
+    // Zig
+    '.zig',
     // Shell
     '.sh',
     '.bash',
@@ -81,11 +114,20 @@ export class GenericAnalyzer implements FrameworkAnalyzer {
     '.yml',
     '.toml',
     '.xml',
+    // Schemas
+    '.proto',
+    '.sql',
+    // Infrastructure
+    '.tf',
+    '.tfvars',
+    '.hcl',
     // Markup
     '.html',
     '.htm',
     '.md',
     '.mdx',
+    // Notebooks
+    '.ipynb',
     // Styles
     '.css',
     '.scss',
@@ -94,14 +136,32 @@ export class GenericAnalyzer implements FrameworkAnalyzer {
   ];
   readonly priority = 10; // Low priority - fallback analyzer
 
+  private readonly maxChunkTokens?: number;
+  private readonly chunkOverlapLines: number;
+
+  constructor(options: GenericAnalyzerOptions = {}) {
+    this.maxChunkTokens =
+      options.maxChunkTokens ?? readPositiveIntEnv('CODEBASE_CONTEXT_MAX_CHUNK_TOKENS');
+    this.chunkOverlapLines =
+      options.chunkOverlapLines ?? readPositiveIntEnv('CODEBASE_CONTEXT_CHUNK_OVERLAP_LINES') ?? 0;
+  }
+
   canAnalyze(filePath: string, _content?: string): boolean {
     const ext = path.extname(filePath).toLowerCase();
-    return this.supportedExtensions.includes(ext);
+    return this.supportedExtensions.includes(ext) || grammarPluginForExtension(ext) !== undefined;
   }
 
-  async analyze(filePath: string, content: string): Promise<AnalysisResult> {
-    const language = detectLanguage(filePath);
+  async analyze(
+    filePath: string,
+    content: string,
+    options: AnalyzeOptions = {}
+  ): Promise<AnalysisResult> {
+    const language = detectLanguage(filePath, content);
     const relativePath = path.relative(process.cwd(), filePath);
+    // Project chunking config for this language wins over the analyzer-wide settings
+    const chunking = options.chunking ?? {};
+    const maxChunkTokens = chunking.maxTokens ?? this.maxChunkTokens;
+    const overlapLines = chunking.overlapLines ?? this.chunkOverlapLines;
 
     // Parse based on language
     let components: CodeComponent[] = [];
@@ -114,7 +174,10 @@ export class GenericAnalyzer implements FrameworkAnalyzer {
     try {
       const treeSitterResult = await extractTreeSitterSymbols(content, language);
       if (treeSitterResult && treeSitterResult.symbols.length > 0) {
-        treeSitterSymbols = treeSitterResult.symbols;
+        // A grammar plugin's `chunks` query sets the chunk boundaries instead of its symbols
+        treeSitterSymbols = treeSitterResult.chunkBoundaries?.length
+          ? treeSitterResult.chunkBoundaries
+          : treeSitterResult.symbols;
         // Legacy: replaced by createASTAlignedChunks for AST-aligned chunking
         components = this.convertTreeSitterSymbolsToComponents(treeSitterResult.symbols);
         treeSitterGrammar = treeSitterResult.grammarFile;
@@ -164,12 +227,147 @@ export class GenericAnalyzer implements FrameworkAnalyzer {
       byteSize <= MAX_AST_CHUNK_FILE_SIZE &&
       lineCount <= MAX_AST_CHUNK_FILE_LINES;
 
+    // Protobuf messages/rpcs, GraphQL types/root fields and OpenAPI operations/schemas get one
+    // chunk each
+    const schemaChunks =
+      language === 'proto' || language === 'graphql' || language === 'yaml' || language === 'json'
+        ? createSchemaChunks(content, { filePath, relativePath, language })
+        : null;
+
+    // SQL scripts get one chunk per statement
+    const sqlChunks =
+      language === 'sql' ? createSqlChunks(content, { filePath, relativePath, language }) : null;
+
+    // Terraform blocks and Kubernetes manifests get one chunk per resource
+    const infraChunks =
+      !schemaChunks && (language === 'hcl' || language === 'yaml')
+        ? createInfraChunks(content, { filePath, relativePath, language })
+        : null;
+
+    // Vue/Svelte/Astro components are chunked per script, template and style block
+    const sfc = isSfcLanguage(language)
+      ? await createSfcChunks(content, {
+          filePath,
+          relativePath,
+          language,
+          maxChunkTokens,
+          overlapLines
+        })
+      : null;
+
+    // Jupyter notebooks are chunked per cell; outputs are not indexed
+    const notebook =
+      language === 'jupyter'
+        ? await createNotebookChunks(content, {
+            filePath,
+            relativePath,
+            maxChunkTokens,
+            overlapLines
+          })
+        : null;
+
+    // Markdown is chunked per heading section, with the heading breadcrumb in metadata
+    const markdownChunks =
+      language === 'markdown' || language === 'mdx'
+        ? createMarkdownChunks(content, {
+            filePath,
+            relativePath,
+            language,
+            maxChunkTokens,
+            overlapLines
+          })
+        : null;
+
     let chunks: CodeChunk[];
-    if (useASTChunking) {
+    if (notebook) {
+      chunks = notebook.chunks;
+      components = [];
+      metadata.chunkStrategy = 'notebook-cell';
+      metadata.notebookLanguage = notebook.notebook.language;
+      metadata.cellCount = notebook.notebook.cells.length;
+    } else if (sfc) {
+      chunks = sfc.chunks;
+      metadata.chunkStrategy = 'sfc-block';
+      metadata.componentName = sfc.file.componentName;
+      components = [
+        {
+          name: sfc.file.componentName,
+          type: 'component',
+          componentType: 'component',
+          startLine: 1,
+          endLine: lineCount,
+          properties: sfc.file.props.map((prop) => ({ name: prop.name })),
+          metadata: { extraction: 'sfc', framework: language }
+        }
+      ];
+      const scripts = await this.parseSfcScripts(filePath, sfc.file);
+      imports = scripts.imports;
+      exports = scripts.exports;
+    } else if (markdownChunks && markdownChunks.length > 0) {
+      chunks = markdownChunks;
+      components = [];
+      metadata.chunkStrategy = 'markdown-section';
+    } else if (schemaChunks) {
+      chunks = schemaChunks;
+      metadata.chunkStrategy = 'schema-block';
+      components = schemaChunks.flatMap((chunk) => {
+        const schema = chunk.metadata.schema;
+        if (!schema) return [];
+        return [
+          {
+            name: schema.address,
+            type: schema.type,
+            componentType: 'schema',
+            startLine: chunk.startLine,
+            endLine: chunk.endLine,
+            metadata: { extraction: 'schema', schema }
+          }
+        ];
+      });
+    } else if (sqlChunks) {
+      chunks = sqlChunks;
+      metadata.chunkStrategy = 'sql-statement';
+      components = sqlChunks.flatMap((chunk) => {
+        const [statement] = chunk.metadata.sql ?? [];
+        if (!statement?.object) return [];
+        return [
+          {
+            name: statement.object,
+            type: statement.statement,
+            componentType: 'sql',
+            startLine: chunk.startLine,
+            endLine: chunk.endLine,
+            metadata: { extraction: 'sql', tables: statement.tables }
+          }
+        ];
+      });
+    } else if (infraChunks) {
+      chunks = infraChunks;
+      metadata.chunkStrategy = 'infra-block';
+      components = infraChunks.flatMap((chunk) => {
+        const infra = chunk.metadata.infra;
+        if (!infra) return [];
+        return [
+          {
+            name: infra.address,
+            type: infra.blockType ?? 'manifest',
+            componentType: 'infrastructure',
+            startLine: chunk.startLine,
+            endLine: chunk.endLine,
+            metadata: { extraction: 'infra', infra }
+          }
+        ];
+      });
+    } else if (useASTChunking) {
       try {
         chunks = createASTAlignedChunks(content, treeSitterSymbols, {
-          minChunkLines: 10,
-          maxChunkLines: 150,
+          minChunkLines:
+            chunking.mergeSmall === false
+              ? 0
+              : (chunking.minLines ?? DEFAULT_AST_CHUNK_OPTIONS.minChunkLines),
+          maxChunkLines: chunking.maxLines ?? DEFAULT_AST_CHUNK_OPTIONS.maxChunkLines,
+          maxChunkTokens,
+          overlapLines,
           filePath,
           language,
           framework: 'generic',
@@ -219,6 +417,32 @@ export class GenericAnalyzer implements FrameworkAnalyzer {
     };
   }
 
+  /** Imports and exports of the script blocks, with file line numbers */
+  private async parseSfcScripts(
+    filePath: string,
+    file: SfcFile
+  ): Promise<{ imports: ImportStatement[]; exports: ExportStatement[] }> {
+    const imports: ImportStatement[] = [];
+    const exports: ExportStatement[] = [];
+    for (const block of file.blocks) {
+      if (block.kind !== 'script') continue;
+      const typescript = block.lang?.startsWith('typescript') ?? false;
+      const jsx = block.lang?.endsWith('react') ?? false;
+      // The parser enables JSX by file name
+      const scriptPath = `${filePath}.${typescript ? 'ts' : 'js'}${jsx ? 'x' : ''}`;
+      const parsed = await this.parseJSTSFile(
+        scriptPath,
+        block.content,
+        typescript ? 'typescript' : 'javascript'
+      );
+      for (const imp of parsed.imports) {
+        imports.push({ ...imp, line: imp.line ? imp.line + block.startLine - 1 : undefined });
+      }
+      exports.push(...parsed.exports);
+    }
+    return { imports, exports };
+  }
+
   private convertTreeSitterSymbolsToComponents(symbols: TreeSitterSymbol[]): CodeComponent[] {
     return symbols.map((symbol) => ({
       name: symbol.name,
@@ -500,6 +724,11 @@ export class GenericAnalyzer implements FrameworkAnalyzer {
     return components;
   }
 
+  /** React/Vue components, HTTP handlers, migrations, cron jobs and tests, by heuristics */
+  detectRoles(chunk: CodeChunk): string[] {
+    return detectGenericRoles(chunk);
+  }
+
   /**
    * Generate generic summary for any code chunk
    */
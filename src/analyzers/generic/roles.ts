@@ -0,0 +1,122 @@
+/**
+ * Heuristic chunk roles for the common frameworks, from decorators, signatures and path
+ * conventions. A chunk can have several (a NestJS controller method under `test/` is both
+ * `http_handler` and `test`); none is assigned when nothing matches.
+ */
+
+import type { CodeChunk } from '../../types/index.js';
+import { isTestFile } from '../../utils/language-detection.js';
+
+/** The roles assigned here; other analyzers may add their own */
+export const CHUNK_ROLES: readonly string[] = [
+  'ui_component',
+  'http_handler',
+  'migration',
+  'cron_job',
+  'test'
+];
+
+const HTTP_HANDLER_PATTERNS: RegExp[] = [
+  // Express, Koa, Fastify, Hono routes and (req, res) handlers; not HTTP clients (`api.get`)
+  /\b(?:app|router|server)\.(?:get|post|put|patch|delete|all)\s*\(\s*['"`][^'"`]*['"`]\s*,/,
+  /\(\s*req\b[^,()]*,\s*res\b[^,()]*(?:,\s*next\b[^)]*)?\)\s*(?:=>|\{)/,
+  /\bfastify\.route\s*\(/,
+  // Next.js route handlers and API routes
+  /export\s+(?:async\s+)?function\s+(?:GET|POST|PUT|PATCH|DELETE|HEAD|OPTIONS)\s*\(/,
+  /\b(?:NextApiRequest|NextRequest)\b/,
+  // NestJS, Spring, JAX-RS, ASP.NET
+  /@(?:Get|Post|Put|Patch|Delete|All)\s*\(/,
+  /@(?:Get|Post|Put|Patch|Delete|Request)Mapping\b/,
+  /@(?:RestController|Controller)\b/,
+  /@(?:GET|POST|PUT|DELETE|PATCH)\s*$/m,
+  /\[(?:Http(?:Get|Post|Put|Patch|Delete)|Route)\b/,
+  /class\s+\w+\s*:\s*(?:Controller|ControllerBase)\b/,
+  // Flask, FastAPI, Django REST framework
+  /@(?:\w+\.)?(?:route|get|post|put|patch|delete|api_view)\s*\(/,
+  // Gin, Echo, Fiber, net/http
+  /\*gin\.Context\b/,
+  /\becho\.Context\b/,
+  /\*fiber\.Ctx\b/,
+  /\bhttp\.ResponseWriter\s*,\s*\w+\s+\*http\.Request\b/,
+  /\.(?:GET|POST|PUT|PATCH|DELETE|HandleFunc|Handle)\s*\(\s*"/,
+  // Actix, Axum, Rocket
+  /#\[(?:get|post|put|patch|delete)\s*\(/,
+  /\bRouter::new\(\)\s*\.route\s*\(/,
+  // Rails and Laravel controllers
+  /class\s+\w+Controller\s*<\s*(?:Application|Action)Controller/,
+  /class\s+\w+Controller\s+extends\s+Controller\b/,
+  /\bRoute::(?:get|post|put|patch|delete)\s*\(/
+];
+
+const MIGRATION_PATH =
+  /(?:^|\/)(?:migrations?|migrate|db\/migrate|alembic\/versions|flyway|liquibase)\//i;
+const MIGRATION_FILE = /(?:^|\/)(?:V\d+(?:[._]\d+)*__\w+\.sql|\d{4,}[_-][\w-]+\.\w+)$/;
+const MIGRATION_PATTERNS: RegExp[] = [
+  /\bextends\s+(?:Migration|AbstractMigration)\b/,
+  /\bimplements\s+MigrationInterface\b/,
+  /<\s*ActiveRecord::Migration\b/,
+  /\bmigrations\.Migration\b/,
+  /\bop\.(?:create_table|add_column|drop_table|alter_column)\s*\(/,
+  /\bexports\.up\s*=|export\s+(?:async\s+)?function\s+up\s*\(/,
+  /\bSchema::(?:create|table)\s*\(/,
+  /\bgoose\.AddMigration\b/
+];
+
+const CRON_PATTERNS: RegExp[] = [
+  /\bcron\.schedule\s*\(/,
+  /\bnew\s+CronJob\s*\(/,
+  /@Cron\s*\(/,
+  /@Scheduled\s*\(/,
+  /@(?:\w+\.)?periodic_task\b/,
+  /\bcrontab\s*\(/,
+  /\bschedule\.every\s*\(/,
+  /\bcron\.New\s*\(/,
+  /\.AddFunc\s*\(\s*"[^"]*[*@]/,
+  /^\s*kind:\s*CronJob\b/m,
+  /^\s*-?\s*cron:\s*['"][^'"]+['"]/m,
+  /\bnode-cron\b|\bnode-schedule\b|\bagenda\.every\s*\(/
+];
+
+const TEST_PATTERNS: RegExp[] = [/#\[(?:cfg\()?test\)?\]/, /@(?:Test|ParameterizedTest)\b/];
+
+const UI_COMPONENT_PATTERNS: RegExp[] = [
+  // React function and class components
+  /\bextends\s+(?:React\.)?(?:Pure)?Component\s*[<{]/,
+  /:\s*(?:React\.)?(?:FC|FunctionComponent)\s*</,
+  /\bReact\.(?:forwardRef|memo)\s*\(/,
+  /\bdefineComponent\s*\(/
+];
+/** A PascalCase function or arrow returning JSX */
+const JSX_COMPONENT =
+  /(?:function\s+[A-Z]\w*|const\s+[A-Z]\w*\s*(?::[^=]+)?=)[\s\S]*?(?:return|=>)\s*\(?\s*<[A-Za-z>]/;
+
+function isUiComponent(chunk: CodeChunk): boolean {
+  if (chunk.metadata?.sfcBlock === 'script' || chunk.metadata?.sfcBlock === 'template') {
+    return true;
+  }
+  const content = chunk.content ?? '';
+  if (UI_COMPONENT_PATTERNS.some((pattern) => pattern.test(content))) return true;
+  return /\.(?:jsx|tsx)$/.test(chunk.filePath ?? '') && JSX_COMPONENT.test(content);
+}
+
+/** The roles the generic heuristics find for one chunk */
+export function detectGenericRoles(chunk: CodeChunk): string[] {
+  const file = (chunk.relativePath || chunk.filePath || '').replace(/\\/g, '/');
+  const content = chunk.content ?? '';
+  const roles: string[] = [];
+
+  if (isUiComponent(chunk)) roles.push('ui_component');
+  if (HTTP_HANDLER_PATTERNS.some((pattern) => pattern.test(content))) roles.push('http_handler');
+  if (
+    (MIGRATION_PATH.test(file) && !/\.(?:md|txt)$/i.test(file)) ||
+    (MIGRATION_FILE.test(file) && /\.sql$/i.test(file)) ||
+    MIGRATION_PATTERNS.some((pattern) => pattern.test(content))
+  ) {
+    roles.push('migration');
+  }
+  if (CRON_PATTERNS.some((pattern) => pattern.test(content))) roles.push('cron_job');
+  if (isTestFile(file) || TEST_PATTERNS.some((pattern) => pattern.test(content))) {
+    roles.push('test');
+  }
+  return roles;
+}
@@ -288,12 +288,23 @@ export function formatSearch(
   intent?: string
 ): void {
   const g = getGlyphs();
-  const { searchQuality: quality, preflight, results, relatedMemories: memories } = data;
+  const { searchQuality: quality, preflight, results, relatedMemories: memories, debug } = data;
 
   const boxLines: string[] = [];
 
   const showPreflight = intent === 'edit' || intent === 'refactor' || intent === 'migrate';
 
+  if (debug) {
+    const rerankState = debug.rerank.applied ? 'applied' : 'not applied';
+    const { weights } = debug;
+    boxLines.push(
+      `Ranking: ${debug.intent} ${g.dot} ${debug.mode} ${g.dot} ` +
+        `semantic ${weights.semantic} / keyword ${weights.keyword} ${g.dot} ` +
+        `rerank ${debug.rerank.mode} (${rerankState})`
+    );
+    if (debug.rescued) boxLines.push(`Rescue: ${debug.queryVariants.join(' | ')}`);
+  }
+
   if (quality) {
     const status = quality.status === 'ok' ? 'ok' : 'low confidence';
     const conf = quality.confidence ?? '';
@@ -404,6 +415,21 @@ export function formatSearch(
         console.log(`    ${g.warn} ${r.patternWarning}`);
       }
 
+      if (r.debug) {
+        const why: string[] = [];
+        if (r.debug.vector) why.push(`vector #${r.debug.vector.rank + 1} ${r.debug.vector.score}`);
+        if (r.debug.keyword) {
+          why.push(`keyword #${r.debug.keyword.rank + 1} ${r.debug.keyword.score}`);
+        }
+        why.push(`fused ${r.debug.fused}`, ...(r.debug.adjustments ?? []));
+        if (r.debug.rerank !== undefined) why.push(`rerank ${r.debug.rerank}`);
+        console.log(`    why: ${why.join(` ${g.dot} `)}`);
+        const chunk = r.debug.chunk;
+        const chunkParts = [chunk.symbol, chunk.strategy].filter(Boolean);
+        const overlap = chunk.overlapLines ? `, ${chunk.overlapLines} overlap` : '';
+        console.log(`    chunk: lines ${chunk.lines}${overlap} ${chunkParts.join(' ')}`.trimEnd());
+      }
+
       const hints = r.hints;
       if (hints?.callers && hints.callers.length > 0) {
         const shortCallers = hints.callers.slice(0, 3).map((c) => shortPath(c, rootPath));
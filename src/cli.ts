@@ -1,7 +1,13 @@
 /**
  * CLI subcommands for codebase-context.
  * Memory list/add/remove — vendor-neutral access without any AI agent.
- * search/metadata/status/reindex/style-guide/patterns/refs/cycles — all MCP tools.
+ * export/import — share a prebuilt index (e.g. from CI) as a single archive file.
+ * index/stats/purge/gc — build, inspect, clean and delete the index without an MCP client (CI,
+ * scripts).
+ * eval — score a golden query set (recall@K, MRR) and compare two index configurations.
+ * fetch-model — download local ONNX model files for an offline machine.
+ * config — which config files and profile are in effect, and what they set.
+ * search/metadata/status/reindex/rollback/style-guide/patterns/refs/cycles — all MCP tools.
  */
 
 import path from 'path';
@@ -11,9 +17,27 @@ import {
   MEMORY_FILENAME,
   INTELLIGENCE_FILENAME,
   KEYWORD_INDEX_FILENAME,
-  VECTOR_DB_DIRNAME
+  VECTOR_DB_DIRNAME,
+  EVAL_INDEXES_DIRNAME
 } from './constants/codebase-context.js';
 import { CodebaseIndexer } from './core/indexer.js';
+import { CodebaseSearcher } from './core/search.js';
+import {
+  evaluateGoldenSet,
+  compareGoldenRuns,
+  formatGoldenComparison,
+  formatGoldenReport,
+  parseGoldenSet
+} from './eval/harness.js';
+import type { GoldenRun, GoldenSet } from './eval/types.js';
+import { exportIndex, importIndex } from './core/index-archive.js';
+import { collectIndexStats, purgeIndex, reconcileIndex } from './core/index-maintenance.js';
+import { buildEmbeddingCollection } from './core/embedding-collections.js';
+import { fetchLocalModel } from './embeddings/local-models.js';
+import { DEFAULT_MODEL } from './embeddings/types.js';
+import { resolveRerankerConfig } from './core/reranker.js';
+import { appliedConfig, loadConfigFiles, resolveConfigSettings } from './core/config-file.js';
+import type { CodebaseConfig, IndexingProgress } from './types/index.js';
 import { dispatchTool } from './tools/index.js';
 import type { ToolContext } from './tools/index.js';
 import type { IndexState } from './tools/types.js';
@@ -21,7 +45,10 @@ import { analyzerRegistry } from './core/analyzer-registry.js';
 import { AngularAnalyzer } from './analyzers/angular/index.js';
 import { GenericAnalyzer } from './analyzers/generic/index.js';
 import { formatJson } from './cli-formatters.js';
+import { OUTPUT_FORMATS, formatToolResponse, isOutputFormat } from './tools/output-format.js';
 import { handleMemoryCli } from './cli-memory.js';
+import { normalizeRootPath } from './utils/path-normalization.js';
+import { loadGrammarPlugins } from './grammars/plugins.js';
 export { handleMemoryCli } from './cli-memory.js';
 
 analyzerRegistry.register(new AngularAnalyzer());
@@ -33,10 +60,26 @@ const _CLI_COMMANDS = [
   'metadata',
   'status',
   'reindex',
+  'rollback',
   'style-guide',
   'patterns',
   'refs',
-  'cycles'
+  'symbols',
+  'callers',
+  'callees',
+  'diff',
+  'cycles',
+  'export',
+  'import',
+  'index',
+  'stats',
+  'purge',
+  'gc',
+  'eval',
+  'fetch-model',
+  'index-remote',
+  'index-dependency',
+  'config'
 ] as const;
 
 type CliCommand = (typeof _CLI_COMMANDS)[number];
@@ -46,6 +89,8 @@ function isCliCommand(value: string): value is CliCommand {
   return CLI_COMMAND_SET.has(value);
 }
 
+const DEFAULT_ARCHIVE_FILENAME = 'codebase-context-index.json.gz';
+
 const SEARCH_INTENTS = ['explore', 'edit', 'refactor', 'migrate'] as const;
 type SearchIntent = (typeof SEARCH_INTENTS)[number];
 const SEARCH_INTENT_SET: ReadonlySet<string> = new Set(SEARCH_INTENTS);
@@ -53,6 +98,20 @@ function isSearchIntent(value: string): value is SearchIntent {
   return SEARCH_INTENT_SET.has(value);
 }
 
+const SEARCH_MODES = ['hybrid', 'keyword', 'semantic'] as const;
+type SearchMode = (typeof SEARCH_MODES)[number];
+const SEARCH_MODE_SET: ReadonlySet<string> = new Set(SEARCH_MODES);
+function isSearchMode(value: string): value is SearchMode {
+  return SEARCH_MODE_SET.has(value);
+}
+
+const RERANK_MODES = ['auto', 'always', 'off'] as const;
+type RerankMode = (typeof RERANK_MODES)[number];
+const RERANK_MODE_SET: ReadonlySet<string> = new Set(RERANK_MODES);
+function isRerankMode(value: string): value is RerankMode {
+  return RERANK_MODE_SET.has(value);
+}
+
 const TEAM_PATTERN_CATEGORIES = ['all', 'di', 'state', 'testing', 'libraries'] as const;
 type TeamPatternCategory = (typeof TEAM_PATTERN_CATEGORIES)[number];
 const TEAM_PATTERN_CATEGORY_SET: ReadonlySet<string> = new Set(TEAM_PATTERN_CATEGORIES);
@@ -68,26 +127,64 @@ function printUsage(): void {
   console.log('  search --query <q>                 Search the indexed codebase');
   console.log('         [--intent explore|edit|refactor|migrate]');
   console.log('         [--limit <n>] [--lang <l>] [--framework <f>] [--layer <l>]');
+  console.log('         [--mode hybrid|keyword|semantic] [--ref <git-ref>]');
+  console.log('         [--collection <name>]       Vectors from an embedding collection');
+  console.log('         [--rerank auto|always|off] [--dotnet-project <name>]');
+  console.log('         [--package <name>] [--role <role>] [--meta <field=value,...>]');
+  console.log('         [--path <glob>] [--exclude-tests] [--modified-after <date>]');
+  console.log('         [--modified-before <date>] [--docs-only | --code-only] [--debug]');
+  console.log('         [--rewrite]                 Also search code-vocabulary rewrites');
+  console.log('         [--max-per-file <n>] [--mmr <lambda>]');
+  console.log('         [--recency <weight>] [--churn <weight>]  Boost recent, often edited files');
+  console.log('         [--deps [<name,...>]]       Include indexed dependencies');
   console.log('  metadata                           Project structure, frameworks, deps');
   console.log('  status                             Index state and progress');
   console.log('  reindex [--incremental] [--reason <r>]  Re-index the codebase');
+  console.log('          [--ref <git-ref>]          Index a branch/tag/commit from git');
+  console.log('  rollback [--dry-run]               Restore the index the last re-index replaced');
   console.log('  style-guide [--query <q>] [--category <c>]  Style guide rules');
   console.log('  patterns [--category all|di|state|testing|libraries]  Team patterns');
   console.log('  refs --symbol <name> [--limit <n>]  Symbol references');
+  console.log('  symbols --query <name> [--kind <k,...>] [--lang <l>] [--path <glob>]');
+  console.log('          [--exact] [--limit <n>]    Find definitions by name');
+  console.log('  callers --symbol <name> [--limit <n>]  Functions that call a symbol');
+  console.log('  callees --symbol <name> [--file <path>] [--external] [--limit <n>]');
+  console.log('                                     Functions a symbol calls');
+  console.log('  diff --base <ref> [--head <ref>] [--limit <n>]  Review context for a diff');
   console.log('  cycles [--scope <path>]            Circular dependency detection');
+  console.log('  export [--out <file>]              Write the index to a portable archive');
+  console.log('  import --file <file>               Replace the index with an archived one');
+  console.log('  index [--incremental] [--ref <git-ref>] [--keyword-only]');
+  console.log('                                     Build the index and wait (exit 1 on failure)');
+  console.log('  index --collection <name>          Embed the index for one embedding collection');
+  console.log('  stats [--ref <git-ref>]            Index size, languages, build info, disk usage');
+  console.log('  purge [--ref <git-ref>] [--dry-run]  Delete the index, keep memory and config');
+  console.log('  gc [--dry-run]                     Remove stale chunks, compact the store');
+  console.log('  eval --golden <set.yaml> [--k <n>] [--config <a.json>] [--compare <b.json>]');
+  console.log('                                     Recall@K and MRR for a golden query set');
+  console.log('  fetch-model --to <dir> [--model <name>] [--reranker]');
+  console.log('                                     Download ONNX model files for offline use');
+  console.log('  index-remote --url <repo-url> [--ref <ref>] [--method auto|clone|tarball]');
+  console.log('               [--refresh]           Fetch a GitHub/GitLab repo and index it');
+  console.log('  index-dependency --name <module[@version]> [--refresh]');
+  console.log('                                     Index a dependency from its local source');
+  console.log('  config                             Config files, profile and settings in effect');
   console.log('');
   console.log('Global flags:');
   console.log('  --json    Output raw JSON (default: human-readable)');
+  console.log('  --format json|markdown|plain');
+  console.log('            Tool output in the stable result schema, or as markdown/plain text');
   console.log('  --help    Show this help');
   console.log('');
   console.log('Environment:');
   console.log('  CODEBASE_ROOT    Project root path (default: cwd)');
+  console.log('  CODEBASE_CONTEXT_PROFILE  Profile from codebase-context.yaml to use');
   console.log('  CODEBASE_CONTEXT_ASCII=1  Force ASCII-only box output');
   console.log('  CODEBASE_CONTEXT_DEBUG=1  Enable verbose logs');
 }
 
 async function initToolContext(): Promise<ToolContext> {
-  const rootPath = path.resolve(process.env.CODEBASE_ROOT || process.cwd());
+  const rootPath = normalizeRootPath(process.env.CODEBASE_ROOT || process.cwd());
 
   const paths = {
     baseDir: path.join(rootPath, CODEBASE_CONTEXT_DIRNAME),
@@ -115,20 +212,10 @@ async function initToolContext(): Promise<ToolContext> {
     console.error(`Indexing (${mode})${reason ? ` — ${reason}` : ''}: ${rootPath}`);
 
     try {
-      let lastLoggedProgress = { phase: '', percentage: -1 };
       const indexer = new CodebaseIndexer({
         rootPath,
         incrementalOnly,
-        onProgress: (progress) => {
-          const shouldLog =
-            progress.phase !== lastLoggedProgress.phase ||
-            (progress.percentage % 10 === 0 &&
-              progress.percentage !== lastLoggedProgress.percentage);
-          if (shouldLog) {
-            console.error(`[${progress.phase}] ${progress.percentage}%`);
-            lastLoggedProgress = { phase: progress.phase, percentage: progress.percentage };
-          }
-        }
+        onProgress: createProgressLogger()
       });
 
       indexState.indexer = indexer;
@@ -152,6 +239,20 @@ async function initToolContext(): Promise<ToolContext> {
   return { indexState, paths, rootPath, performIndexing };
 }
 
+/** Logs each phase change and every 10% to stderr */
+function createProgressLogger(): (progress: IndexingProgress) => void {
+  let lastLoggedProgress = { phase: '', percentage: -1 };
+  return (progress) => {
+    const shouldLog =
+      progress.phase !== lastLoggedProgress.phase ||
+      (progress.percentage % 10 === 0 && progress.percentage !== lastLoggedProgress.percentage);
+    if (shouldLog) {
+      console.error(`[${progress.phase}] ${progress.percentage}%`);
+      lastLoggedProgress = { phase: progress.phase, percentage: progress.percentage };
+    }
+  };
+}
+
 function extractText(result: { content?: Array<{ type: string; text: string }> }): string {
   return result.content?.[0]?.text ?? '';
 }
@@ -227,6 +328,87 @@ function booleanFlag(flags: Flags, key: string, usage: string): boolean {
   exitWithError(`Error: --${key} must be a boolean (true/false)\nUsage: ${usage}`);
 }
 
+/**
+ * Build an index and wait for it, exiting non-zero on failure (CI-friendly, unlike `reindex`,
+ * which reports failures through the index status).
+ */
+async function runForegroundIndex(
+  rootPath: string,
+  options: { ref?: string; incremental: boolean; keywordOnly?: boolean },
+  useJson: boolean,
+  command: string
+): Promise<void> {
+  const indexer = new CodebaseIndexer({
+    rootPath,
+    ref: options.ref,
+    incrementalOnly: options.incremental,
+    onProgress: createProgressLogger(),
+    ...(options.keywordOnly ? { config: { skipEmbedding: true } } : {})
+  });
+  try {
+    const stats = await indexer.index();
+    formatJson(
+      JSON.stringify({
+        status: 'ready',
+        ...(options.ref ? { ref: options.ref } : {}),
+        mode: options.incremental ? 'incremental' : 'full',
+        ...(options.keywordOnly ? { keywordOnly: true } : {}),
+        indexedFiles: stats.indexedFiles,
+        totalChunks: stats.totalChunks,
+        durationMs: stats.duration
+      }),
+      useJson,
+      command,
+      rootPath
+    );
+  } catch (error) {
+    exitWithError(`Error: ${error instanceof Error ? error.message : String(error)}`);
+  }
+}
+
+/**
+ * Build a scratch index with the config in `configPath` (`{ "label": ..., "config": {...} }`)
+ * under `.codebase-context/eval/<label>/` and score the golden set against it, leaving the
+ * project's own index untouched.
+ */
+async function evaluateGoldenConfig(
+  rootPath: string,
+  set: GoldenSet,
+  configPath: string,
+  k: number | undefined
+): Promise<GoldenRun> {
+  const parsed: unknown = JSON.parse(await fs.readFile(path.resolve(configPath), 'utf-8'));
+  if (typeof parsed !== 'object' || parsed === null || Array.isArray(parsed)) {
+    throw new Error(`${configPath}: expected { "label": "...", "config": { ... } }`);
+  }
+  const { label: rawLabel, config: rawConfig } = parsed as { label?: unknown; config?: unknown };
+  const label =
+    typeof rawLabel === 'string' && rawLabel.trim()
+      ? rawLabel.trim()
+      : path.basename(configPath, path.extname(configPath));
+  const config: Partial<CodebaseConfig> =
+    typeof rawConfig === 'object' && rawConfig !== null
+      ? (rawConfig as Partial<CodebaseConfig>)
+      : {};
+
+  const contextDir = path.join(
+    rootPath,
+    CODEBASE_CONTEXT_DIRNAME,
+    EVAL_INDEXES_DIRNAME,
+    label.replace(/[^A-Za-z0-9._-]+/g, '-')
+  );
+  console.error(`Indexing for eval run "${label}": ${contextDir}`);
+  await new CodebaseIndexer({
+    rootPath,
+    contextDir,
+    config,
+    onProgress: createProgressLogger()
+  }).index();
+
+  const searcher = new CodebaseSearcher(rootPath, { contextDir, embedding: config.embedding });
+  return evaluateGoldenSet({ set, searcher, label, k });
+}
+
 export async function handleCliCommand(argv: string[]): Promise<void> {
   const rawCommand = argv[0];
 
@@ -251,7 +433,12 @@ export async function handleCliCommand(argv: string[]): Promise<void> {
   const useJson = argv.includes('--json');
 
   const flags = parseFlags(argv);
+  const outputFormat = flags.format;
+  if (outputFormat !== undefined && !isOutputFormat(outputFormat)) {
+    exitWithError(`--format must be one of: ${OUTPUT_FORMATS.join(', ')}`);
+  }
 
+  await loadGrammarPlugins();
   const ctx = await initToolContext();
 
   type DispatchSpec =
@@ -261,20 +448,66 @@ export async function handleCliCommand(argv: string[]): Promise<void> {
     | { toolName: 'get_style_guide'; toolArgs: StyleGuideToolArgs }
     | { toolName: 'get_team_patterns'; toolArgs: TeamPatternsToolArgs }
     | { toolName: 'get_symbol_references'; toolArgs: SymbolReferencesToolArgs }
-    | { toolName: 'detect_circular_dependencies'; toolArgs: DetectCircularDependenciesToolArgs };
+    | { toolName: 'search_symbols'; toolArgs: SearchSymbolsToolArgs }
+    | { toolName: 'find_callers'; toolArgs: FindCallersToolArgs }
+    | { toolName: 'find_callees'; toolArgs: FindCalleesToolArgs }
+    | { toolName: 'get_diff_context'; toolArgs: DiffContextToolArgs }
+    | { toolName: 'detect_circular_dependencies'; toolArgs: DetectCircularDependenciesToolArgs }
+    | { toolName: 'index_remote'; toolArgs: IndexRemoteToolArgs }
+    | { toolName: 'index_dependency'; toolArgs: { name: string; refresh: boolean } };
 
   type SearchToolArgs = {
     query: string;
     includeSnippets: boolean;
     intent?: SearchIntent;
     limit?: number;
-    filters?: { language?: string; framework?: string; layer?: string };
+    mode?: SearchMode;
+    ref?: string;
+    collection?: string;
+    rerank?: RerankMode;
+    debug?: boolean;
+    rewrite?: 'synonyms';
+    diversity?: { lambda?: number; maxPerFile?: number };
+    recency?: { recencyWeight?: number; churnWeight?: number };
+    includeDependencies?: true | string[];
+    filters?: {
+      language?: string;
+      framework?: string;
+      layer?: string;
+      dotnetProject?: string;
+      package?: string;
+      role?: string;
+      metadata?: Record<string, string | boolean>;
+      path?: string;
+      excludeTests?: boolean;
+      docsOnly?: boolean;
+      codeOnly?: boolean;
+      modifiedAfter?: string;
+      modifiedBefore?: string;
+    };
   };
 
   type StyleGuideToolArgs = { query?: string; category?: string };
   type TeamPatternsToolArgs = { category?: TeamPatternCategory };
   type SymbolReferencesToolArgs = { symbol: string; limit?: number };
+  type SearchSymbolsToolArgs = {
+    query: string;
+    kind?: string[];
+    language?: string;
+    path?: string;
+    exact?: boolean;
+    limit?: number;
+  };
+  type FindCallersToolArgs = { symbol: string; limit?: number };
+  type FindCalleesToolArgs = {
+    symbol: string;
+    file?: string;
+    includeExternal?: boolean;
+    limit?: number;
+  };
+  type DiffContextToolArgs = { base: string; head?: string; limit?: number };
   type DetectCircularDependenciesToolArgs = { scope?: string };
+  type IndexRemoteToolArgs = { url: string; ref?: string; method?: string; refresh: boolean };
 
   let dispatch: DispatchSpec;
   let formatQuery: string | undefined;
@@ -295,20 +528,127 @@ export async function handleCliCommand(argv: string[]): Promise<void> {
         intent = intentValue;
       }
       const limit = optionalPositiveIntFlag(flags, 'limit', usage);
+      const modeValue = optionalStringFlag(flags, 'mode', usage);
+      let mode: SearchMode | undefined;
+      if (modeValue) {
+        if (!isSearchMode(modeValue)) {
+          exitWithError(
+            `Error: invalid --mode "${modeValue}". Allowed: ${SEARCH_MODES.join(', ')}\nUsage: ${usage}`
+          );
+        }
+        mode = modeValue;
+      }
+      const ref = optionalStringFlag(flags, 'ref', usage);
+      const collection = optionalStringFlag(flags, 'collection', usage);
+      const rerankValue = optionalStringFlag(flags, 'rerank', usage);
+      let rerank: RerankMode | undefined;
+      if (rerankValue) {
+        if (!isRerankMode(rerankValue)) {
+          exitWithError(
+            `Error: invalid --rerank "${rerankValue}". Allowed: ${RERANK_MODES.join(', ')}\nUsage: ${usage}`
+          );
+        }
+        rerank = rerankValue;
+      }
       const lang = optionalStringFlag(flags, 'lang', usage);
       const framework = optionalStringFlag(flags, 'framework', usage);
       const layer = optionalStringFlag(flags, 'layer', usage);
+      const dotnetProject = optionalStringFlag(flags, 'dotnet-project', usage);
+      const pkg = optionalStringFlag(flags, 'package', usage);
+      const role = optionalStringFlag(flags, 'role', usage);
+      const meta = optionalStringFlag(flags, 'meta', usage);
+      const pathGlob = optionalStringFlag(flags, 'path', usage);
+      const excludeTests = booleanFlag(flags, 'exclude-tests', usage);
+      const docsOnly = booleanFlag(flags, 'docs-only', usage);
+      const codeOnly = booleanFlag(flags, 'code-only', usage);
+      if (docsOnly && codeOnly) {
+        exitWithError(`Error: --docs-only and --code-only exclude each other\nUsage: ${usage}`);
+      }
+      const modifiedAfter = optionalStringFlag(flags, 'modified-after', usage);
+      const modifiedBefore = optionalStringFlag(flags, 'modified-before', usage);
+      const debug = booleanFlag(flags, 'debug', usage);
+      const rewrite = booleanFlag(flags, 'rewrite', usage);
+      const maxPerFile = optionalPositiveIntFlag(flags, 'max-per-file', usage);
+      const mmrValue = optionalStringFlag(flags, 'mmr', usage);
+      const lambda = mmrValue !== undefined ? Number(mmrValue) : undefined;
+      if (lambda !== undefined && !(lambda >= 0 && lambda <= 1)) {
+        exitWithError(`Error: --mmr expects a lambda between 0 and 1\nUsage: ${usage}`);
+      }
+      const boostWeight = (flag: 'recency' | 'churn'): number | undefined => {
+        const value = optionalStringFlag(flags, flag, usage);
+        if (value === undefined) return undefined;
+        const weight = Number(value);
+        if (!(weight >= 0 && weight <= 1)) {
+          exitWithError(`Error: --${flag} expects a weight between 0 and 1\nUsage: ${usage}`);
+        }
+        return weight;
+      };
+      const recencyWeight = boostWeight('recency');
+      const churnWeight = boostWeight('churn');
+      // `--deps` searches every indexed dependency, `--deps gin,serde` only those
+      const deps = flags.deps;
+      const includeDependencies =
+        deps === true
+          ? true
+          : typeof deps === 'string' && deps.trim()
+            ? deps
+                .split(',')
+                .map((name) => name.trim())
+                .filter(Boolean)
+            : undefined;
 
-      const filters: { language?: string; framework?: string; layer?: string } = {};
+      const filters: NonNullable<SearchToolArgs['filters']> = {};
       if (lang) filters.language = lang;
       if (framework) filters.framework = framework;
       if (layer) filters.layer = layer;
+      if (dotnetProject) filters.dotnetProject = dotnetProject;
+      if (pkg) filters.package = pkg;
+      if (role) filters.role = role;
+      if (pathGlob) filters.path = pathGlob;
+      if (excludeTests) filters.excludeTests = true;
+      if (docsOnly) filters.docsOnly = true;
+      if (codeOnly) filters.codeOnly = true;
+      if (modifiedAfter) filters.modifiedAfter = modifiedAfter;
+      if (modifiedBefore) filters.modifiedBefore = modifiedBefore;
+      if (meta) {
+        // field=value pairs; a bare field means "is set"
+        const metadata: Record<string, string | boolean> = {};
+        for (const pair of meta.split(',')) {
+          const [field, ...rest] = pair.split('=');
+          if (!field.trim()) continue;
+          metadata[field.trim()] = rest.length > 0 ? rest.join('=').trim() : true;
+        }
+        filters.metadata = metadata;
+      }
 
       const args: SearchToolArgs = {
         query,
         includeSnippets: true,
         ...(intent ? { intent } : {}),
         ...(limit != null ? { limit } : {}),
+        ...(mode ? { mode } : {}),
+        ...(ref ? { ref } : {}),
+        ...(collection ? { collection } : {}),
+        ...(rerank ? { rerank } : {}),
+        ...(debug ? { debug: true } : {}),
+        ...(rewrite ? { rewrite: 'synonyms' } : {}),
+        ...(maxPerFile != null || lambda !== undefined
+          ? {
+              diversity: {
+                ...(lambda !== undefined ? { lambda } : {}),
+                ...(maxPerFile != null ? { maxPerFile } : {})
+              }
+            }
+          : {}),
+        ...(recencyWeight !== undefined || churnWeight !== undefined
+          ? {
+              recency: {
+                ...(recencyWeight !== undefined ? { recencyWeight } : {}),
+                ...(churnWeight !== undefined ? { churnWeight } : {})
+              }
+            }
+          : {}),
+        ...(includeDependencies ? { includeDependencies } : {}),
         ...(Object.keys(filters).length > 0 ? { filters } : {})
       };
       dispatch = { toolName: 'search_codebase', toolArgs: args };
@@ -325,14 +665,26 @@ export async function handleCliCommand(argv: string[]): Promise<void> {
       break;
     }
     case 'reindex': {
-      const usage = 'codebase-context reindex [--incremental] [--reason <r>]';
+      const usage = 'codebase-context reindex [--incremental] [--reason <r>] [--ref <git-ref>]';
       const reason = optionalStringFlag(flags, 'reason', usage);
       const incremental = booleanFlag(flags, 'incremental', usage);
+      const ref = optionalStringFlag(flags, 'ref', usage);
+      if (ref) {
+        // Ref builds are separate from the working-tree index state, so run them in the foreground
+        await runForegroundIndex(ctx.rootPath, { ref, incremental }, useJson, command);
+        return;
+      }
       await ctx.performIndexing(incremental, reason);
       const statusResult = await dispatchTool('get_indexing_status', {}, ctx);
       formatJson(extractText(statusResult), useJson, 'status', ctx.rootPath);
       return;
     }
+    case 'rollback': {
+      const usage = 'codebase-context rollback [--dry-run]';
+      const dryRun = booleanFlag(flags, 'dry-run', usage);
+      dispatch = { toolName: 'rollback_index', toolArgs: dryRun ? { dryRun } : {} };
+      break;
+    }
     case 'style-guide': {
       const usage = 'codebase-context style-guide [--query <q>] [--category <c>]';
       const query = optionalStringFlag(flags, 'query', usage);
@@ -379,6 +731,74 @@ export async function handleCliCommand(argv: string[]): Promise<void> {
       };
       break;
     }
+    case 'symbols': {
+      const usage =
+        'codebase-context symbols --query <name> [--kind <k,...>] [--lang <l>] [--path <glob>] [--exact] [--limit <n>]';
+      const query = requireStringFlag(flags, 'query', usage);
+      const kind = optionalStringFlag(flags, 'kind', usage);
+      const language = optionalStringFlag(flags, 'lang', usage);
+      const pathGlob = optionalStringFlag(flags, 'path', usage);
+      const exact = booleanFlag(flags, 'exact', usage);
+      const limit = optionalPositiveIntFlag(flags, 'limit', usage);
+      dispatch = {
+        toolName: 'search_symbols',
+        toolArgs: {
+          query,
+          ...(kind ? { kind: kind.split(',').map((k) => k.trim()) } : {}),
+          ...(language ? { language } : {}),
+          ...(pathGlob ? { path: pathGlob } : {}),
+          ...(exact ? { exact } : {}),
+          ...(limit != null ? { limit } : {})
+        }
+      };
+      break;
+    }
+    case 'callers': {
+      const usage = 'codebase-context callers --symbol <name> [--limit <n>]';
+      const symbol = requireStringFlag(flags, 'symbol', usage);
+      const limit = optionalPositiveIntFlag(flags, 'limit', usage);
+      dispatch = {
+        toolName: 'find_callers',
+        toolArgs: {
+          symbol,
+          ...(limit != null ? { limit } : {})
+        }
+      };
+      break;
+    }
+    case 'callees': {
+      const usage =
+        'codebase-context callees --symbol <name> [--file <path>] [--external] [--limit <n>]';
+      const symbol = requireStringFlag(flags, 'symbol', usage);
+      const file = optionalStringFlag(flags, 'file', usage);
+      const includeExternal = booleanFlag(flags, 'external', usage);
+      const limit = optionalPositiveIntFlag(flags, 'limit', usage);
+      dispatch = {
+        toolName: 'find_callees',
+        toolArgs: {
+          symbol,
+          ...(file ? { file } : {}),
+          ...(includeExternal ? { includeExternal } : {}),
+          ...(limit != null ? { limit } : {})
+        }
+      };
+      break;
+    }
+    case 'diff': {
+      const usage = 'codebase-context diff --base <ref> [--head <ref>] [--limit <n>]';
+      const base = requireStringFlag(flags, 'base', usage);
+      const head = optionalStringFlag(flags, 'head', usage);
+      const limit = optionalPositiveIntFlag(flags, 'limit', usage);
+      dispatch = {
+        toolName: 'get_diff_context',
+        toolArgs: {
+          base,
+          ...(head ? { head } : {}),
+          ...(limit != null ? { limit } : {})
+        }
+      };
+      break;
+    }
     case 'cycles': {
       const usage = 'codebase-context cycles [--scope <path>]';
       const scope = optionalStringFlag(flags, 'scope', usage);
@@ -390,6 +810,206 @@ export async function handleCliCommand(argv: string[]): Promise<void> {
       };
       break;
     }
+    case 'export': {
+      const usage = 'codebase-context export [--out <file>]';
+      const out = optionalStringFlag(flags, 'out', usage) ?? DEFAULT_ARCHIVE_FILENAME;
+      try {
+        const result = await exportIndex(ctx.rootPath, path.resolve(out));
+        formatJson(JSON.stringify({ status: 'success', ...result }), useJson, command);
+      } catch (error) {
+        exitWithError(`Error: ${error instanceof Error ? error.message : String(error)}`);
+      }
+      return;
+    }
+    case 'import': {
+      const usage = 'codebase-context import --file <file>';
+      const file = requireStringFlag(flags, 'file', usage);
+      try {
+        const result = await importIndex(ctx.rootPath, path.resolve(file));
+        for (const warning of result.warnings) console.error(`Warning: ${warning}`);
+        formatJson(JSON.stringify({ status: 'success', ...result }), useJson, command);
+      } catch (error) {
+        exitWithError(`Error: ${error instanceof Error ? error.message : String(error)}`);
+      }
+      return;
+    }
+    case 'index': {
+      const usage =
+        'codebase-context index [--incremental] [--ref <git-ref>] [--keyword-only] ' +
+        '[--collection <name>]';
+      const collection = optionalStringFlag(flags, 'collection', usage);
+      if (collection) {
+        try {
+          const meta = await buildEmbeddingCollection({ rootPath: ctx.rootPath, name: collection });
+          formatJson(JSON.stringify({ status: 'ready', ...meta }), useJson, command);
+        } catch (error) {
+          exitWithError(`Error: ${error instanceof Error ? error.message : String(error)}`);
+        }
+        return;
+      }
+      const incremental = booleanFlag(flags, 'incremental', usage);
+      const ref = optionalStringFlag(flags, 'ref', usage);
+      const keywordOnly = booleanFlag(flags, 'keyword-only', usage);
+      await runForegroundIndex(ctx.rootPath, { ref, incremental, keywordOnly }, useJson, command);
+      return;
+    }
+    case 'stats': {
+      const usage = 'codebase-context stats [--ref <git-ref>]';
+      const ref = optionalStringFlag(flags, 'ref', usage);
+      const report = await collectIndexStats(ctx.rootPath, { ref });
+      formatJson(JSON.stringify({ status: 'success', ...report }), useJson, command);
+      return;
+    }
+    case 'purge': {
+      const usage = 'codebase-context purge [--ref <git-ref>] [--dry-run]';
+      const ref = optionalStringFlag(flags, 'ref', usage);
+      const dryRun = booleanFlag(flags, 'dry-run', usage);
+      try {
+        const result = await purgeIndex(ctx.rootPath, { ref, dryRun });
+        for (const warning of result.warnings) console.error(`Warning: ${warning}`);
+        formatJson(
+          JSON.stringify({ status: 'success', ...(dryRun ? { dryRun } : {}), ...result }),
+          useJson,
+          command
+        );
+      } catch (error) {
+        exitWithError(`Error: ${error instanceof Error ? error.message : String(error)}`);
+      }
+      return;
+    }
+    case 'gc': {
+      const usage = 'codebase-context gc [--dry-run]';
+      const dryRun = booleanFlag(flags, 'dry-run', usage);
+      try {
+        const report = await reconcileIndex(ctx.rootPath, { dryRun });
+        for (const warning of report.warnings) console.error(`Warning: ${warning}`);
+        if (!dryRun && report.changedFiles.length > 0) {
+          console.error(
+            `${report.changedFiles.length} changed file(s) dropped; ` +
+              'run `codebase-context index --incremental` to re-add them'
+          );
+        }
+        formatJson(JSON.stringify({ status: 'success', ...report }), useJson, command);
+      } catch (error) {
+        exitWithError(`Error: ${error instanceof Error ? error.message : String(error)}`);
+      }
+      return;
+    }
+    case 'eval': {
+      const usage =
+        'codebase-context eval --golden <file> [--k <n>] [--config <a.json>] [--compare <b.json>]';
+      const golden = requireStringFlag(flags, 'golden', usage);
+      const k = optionalPositiveIntFlag(flags, 'k', usage);
+      const configPath = optionalStringFlag(flags, 'config', usage);
+      const comparePath = optionalStringFlag(flags, 'compare', usage);
+      try {
+        const set = parseGoldenSet(await fs.readFile(path.resolve(golden), 'utf-8'));
+        const runs: GoldenRun[] = [
+          configPath
+            ? await evaluateGoldenConfig(ctx.rootPath, set, configPath, k)
+            : await evaluateGoldenSet({
+                set,
+                searcher: new CodebaseSearcher(ctx.rootPath),
+                label: 'current',
+                k
+              })
+        ];
+        if (comparePath) {
+          runs.push(await evaluateGoldenConfig(ctx.rootPath, set, comparePath, k));
+        }
+        const [a, b] = runs;
+        if (useJson) {
+          console.log(
+            JSON.stringify(
+              { status: 'success', runs, ...(b ? { comparison: compareGoldenRuns(a, b) } : {}) },
+              null,
+              2
+            )
+          );
+        } else {
+          console.log(runs.map((run) => formatGoldenReport(run)).join('\n\n'));
+          if (b) console.log(`\n${formatGoldenComparison(a, b)}`);
+        }
+      } catch (error) {
+        exitWithError(`Error: ${error instanceof Error ? error.message : String(error)}`);
+      }
+      return;
+    }
+    case 'fetch-model': {
+      const usage = 'codebase-context fetch-model --to <dir> [--model <name>] [--reranker]';
+      const to = requireStringFlag(flags, 'to', usage);
+      const reranker = booleanFlag(flags, 'reranker', usage);
+      const model =
+        optionalStringFlag(flags, 'model', usage) ??
+        (reranker ? resolveRerankerConfig().model : DEFAULT_MODEL);
+      try {
+        console.error(`Downloading ${model} to ${path.resolve(to)}...`);
+        const result = await fetchLocalModel(model, to, reranker ? 'reranker' : 'embedding');
+        console.error(`Set EMBEDDING_MODEL_PATH=${path.resolve(to)} on the offline machine`);
+        formatJson(JSON.stringify({ status: 'success', ...result }), useJson, command);
+      } catch (error) {
+        exitWithError(`Error: ${error instanceof Error ? error.message : String(error)}`);
+      }
+      return;
+    }
+    case 'index-remote': {
+      const usage =
+        'codebase-context index-remote --url <repo-url> [--ref <ref>] [--method <m>] [--refresh]';
+      const url = requireStringFlag(flags, 'url', usage);
+      const ref = optionalStringFlag(flags, 'ref', usage);
+      const method = optionalStringFlag(flags, 'method', usage);
+      if (method && !['auto', 'clone', 'tarball'].includes(method)) {
+        exitWithError(
+          `Error: invalid --method "${method}". Allowed: auto, clone, tarball\nUsage: ${usage}`
+        );
+      }
+      const refresh = booleanFlag(flags, 'refresh', usage);
+      dispatch = {
+        toolName: 'index_remote',
+        toolArgs: { url, ...(ref ? { ref } : {}), ...(method ? { method } : {}), refresh }
+      };
+      break;
+    }
+    case 'config': {
+      try {
+        const files = loadConfigFiles(ctx.rootPath);
+        const { profile, settings } = resolveConfigSettings(
+          files,
+          process.env.CODEBASE_CONTEXT_PROFILE
+        );
+        const applied = appliedConfig();
+        // Variable names only: values may be credentials
+        formatJson(
+          JSON.stringify({
+            status: 'success',
+            sources: files.map((file) => file.file),
+            ...(profile ? { profile } : {}),
+            profiles: [...new Set(files.flatMap((file) => Object.keys(file.profiles)))],
+            ...(applied
+              ? {
+                  set: applied.set,
+                  overridden: applied.overridden,
+                  ...(applied.unresolved.length > 0 ? { unresolved: applied.unresolved } : {})
+                }
+              : {}),
+            ignore: settings.ignore ?? [],
+            ...(settings.chunking ? { chunking: settings.chunking } : {})
+          }),
+          useJson,
+          command
+        );
+      } catch (error) {
+        exitWithError(`Error: ${error instanceof Error ? error.message : String(error)}`);
+      }
+      return;
+    }
+    case 'index-dependency': {
+      const usage = 'codebase-context index-dependency --name <module[@version]> [--refresh]';
+      const name = requireStringFlag(flags, 'name', usage);
+      const refresh = booleanFlag(flags, 'refresh', usage);
+      dispatch = { toolName: 'index_dependency', toolArgs: { name, refresh } };
+      break;
+    }
     default: {
       console.error(`Unknown command: ${command}`);
       console.error('');
@@ -400,6 +1020,12 @@ export async function handleCliCommand(argv: string[]): Promise<void> {
 
   try {
     const result = await dispatchTool(dispatch.toolName, dispatch.toolArgs, ctx);
+    if (outputFormat !== undefined) {
+      const formatted = formatToolResponse(dispatch.toolName, result, outputFormat);
+      (result.isError ? console.error : console.log)(extractText(formatted));
+      if (result.isError) process.exit(1);
+      return;
+    }
     if (result.isError) {
       console.error(extractText(result));
       process.exit(1);
@@ -0,0 +1,39 @@
+/**
+ * Applies `codebase-context.yaml` and the user-level config file to the environment before
+ * anything reads it. Must stay the first import of the entry point: embedding and storage
+ * defaults are read from the environment when their modules load.
+ *
+ * Server-wide settings come from the primary project's file (same root precedence as the
+ * server: CLI argument, CODEBASE_ROOT, first CODEBASE_ROOTS entry, cwd).
+ */
+
+import path from 'path';
+import { CLI_SUBCOMMANDS } from './constants/cli.js';
+import { applyConfigFiles } from './core/config-file.js';
+import { parseRootList } from './core/workspace.js';
+
+function primaryRoot(): string {
+  const arg = process.argv[2];
+  // CLI commands take their root from CODEBASE_ROOT or the working directory
+  if (arg && !arg.startsWith('-') && !CLI_SUBCOMMANDS.includes(arg)) return path.resolve(arg);
+  if (process.env.CODEBASE_ROOT) return path.resolve(process.env.CODEBASE_ROOT);
+  const [first] = parseRootList(process.env.CODEBASE_ROOTS);
+  return path.resolve(first?.rootPath ?? process.cwd());
+}
+
+try {
+  const applied = applyConfigFiles(primaryRoot());
+  if (process.env.CODEBASE_CONTEXT_DEBUG && applied.sources.length > 0) {
+    console.error(
+      `[config] ${applied.sources.join(', ')}` +
+        (applied.profile ? ` (profile ${applied.profile})` : '') +
+        `: set ${applied.set.join(', ') || 'nothing'}`
+    );
+  }
+  for (const entry of applied.unresolved) {
+    console.error(`[config] ${entry} references an unset variable; left out`);
+  }
+} catch (error) {
+  console.error(`[config] ${error instanceof Error ? error.message : String(error)}`);
+  process.exit(1);
+}
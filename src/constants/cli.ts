@@ -0,0 +1,27 @@
+/** First arguments that run a CLI command instead of starting the MCP server */
+export const CLI_SUBCOMMANDS: readonly string[] = [
+  'memory',
+  'search',
+  'metadata',
+  'status',
+  'reindex',
+  'style-guide',
+  'patterns',
+  'refs',
+  'symbols',
+  'callers',
+  'callees',
+  'diff',
+  'cycles',
+  'export',
+  'import',
+  'index',
+  'stats',
+  'purge',
+  'gc',
+  'eval',
+  'fetch-model',
+  'index-remote',
+  'index-dependency',
+  'config'
+];
@@ -13,15 +13,58 @@ export const CODEBASE_CONTEXT_DIRNAME = '.codebase-context' as const;
  */
 export const INDEX_FORMAT_VERSION = 1 as const;
 
-/** Schema version for `.codebase-context/index-meta.json` itself. */
-export const INDEX_META_VERSION = 1 as const;
+/**
+ * Schema version for `.codebase-context/index-meta.json` itself. Older versions are migrated
+ * on read (see `index-meta.ts`); bump together with a migration.
+ */
+export const INDEX_META_VERSION = 2 as const;
 
 export const INDEX_META_FILENAME = 'index-meta.json' as const;
 
 export const MEMORY_FILENAME = 'memory.json' as const;
+/** Project settings meant to be committed with memory.json (metadata enrichment rules). */
+export const PROJECT_CONFIG_FILENAME = 'config.json' as const;
+/** Hand-written settings at the repo root: providers, storage, ignore rules, profiles. */
+export const PROJECT_CONFIG_YAML_FILENAME = 'codebase-context.yaml' as const;
 export const INTELLIGENCE_FILENAME = 'intelligence.json' as const;
 export const KEYWORD_INDEX_FILENAME = 'index.json' as const;
 export const INDEXING_STATS_FILENAME = 'indexing-stats.json' as const;
 export const VECTOR_DB_DIRNAME = 'index' as const;
 export const MANIFEST_FILENAME = 'manifest.json' as const;
 export const RELATIONSHIPS_FILENAME = 'relationships.json' as const;
+/** Per-ref indexes built from the git object store live under `.codebase-context/refs/<slug>/`. */
+export const REF_INDEXES_DIRNAME = 'refs' as const;
+/** Scratch indexes built by `codebase-context eval --config` live under `.codebase-context/eval/<label>/`. */
+export const EVAL_INDEXES_DIRNAME = 'eval' as const;
+/** Standalone indexes of third-party dependencies (`index_dependency`) live under `.codebase-context/deps/<slug>/`. */
+export const DEPENDENCY_INDEXES_DIRNAME = 'deps' as const;
+/** Vectors from additional embedding models (`embeddingCollections`) live under `.codebase-context/collections/<name>/`. */
+export const EMBEDDING_COLLECTIONS_DIRNAME = 'collections' as const;
+/** Content-hash -> embedding cache; survives full rebuilds so unchanged chunks aren't re-embedded. */
+export const EMBEDDING_CACHE_FILENAME = 'embedding-cache.json' as const;
+/** Commit messages, hunks and their embeddings for `search_history`; updated incrementally. */
+export const HISTORY_FILENAME = 'history.json' as const;
+/** Sampled `summarize_file` text per file, reused until the file's content hash changes. */
+export const FILE_SUMMARIES_FILENAME = 'file-summaries.json' as const;
+/** Module cards per directory, rebuilt when the directory's content hash changes. */
+export const MODULE_CARDS_FILENAME = 'module-cards.json' as const;
+/** Definitions, references and hover text imported from a SCIP/LSIF index; re-imported when it changes. */
+export const PRECISE_INDEX_FILENAME = 'precise-index.json' as const;
+/** Held by the build (or gc, purge, import) writing the index; other processes wait for it. */
+export const BUILD_LOCK_FILENAME = 'index.lock' as const;
+/** Present while a new generation is swapped in; readers in other processes wait for it. */
+export const SWAP_LOCK_FILENAME = 'swap.lock' as const;
+/** Left by a cancelled or interrupted build so the next start resumes it; removed on success. */
+export const INDEX_CHECKPOINT_FILENAME = 'index-checkpoint.json' as const;
+/** Present while a build re-embeds with a newly configured model; removed once it swaps in. */
+export const MODEL_MIGRATION_FILENAME = 'model-migration.json' as const;
+/** Files and symbols each build added, modified and removed, for changes_since cursors. */
+export const CHANGE_JOURNAL_FILENAME = 'changes.json' as const;
+/** Paths deleted or moved away by recent builds; search never returns a hit on one of them. */
+export const TOMBSTONES_FILENAME = 'tombstones.json' as const;
+/** Queries and the chunks `search_codebase` returned for them, keyed by queryId; newest last. */
+export const ACCESS_LOG_FILENAME = 'access-log.jsonl' as const;
+/** `rate_result` thumbs up/down per returned chunk; also feeds the per-chunk ranking boost. */
+export const FEEDBACK_FILENAME = 'feedback.json' as const;
+/** What compliance filters masked or left out, per build: file, rule, action, match count. */
+export const COMPLIANCE_AUDIT_FILENAME = 'compliance-audit.jsonl' as const;
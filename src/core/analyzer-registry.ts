@@ -3,7 +3,7 @@
  * Automatically selects the best analyzer based on file type and priority
  */
 
-import { FrameworkAnalyzer, AnalysisResult } from '../types/index.js';
+import { FrameworkAnalyzer, AnalysisResult, AnalyzeOptions, CodeChunk } from '../types/index.js';
 
 export class AnalyzerRegistry {
   private analyzers: Map<string, FrameworkAnalyzer> = new Map();
@@ -56,6 +56,17 @@ export class AnalyzerRegistry {
     return null;
   }
 
+  /**
+   * Roles every registered analyzer finds for a chunk, in priority order without duplicates
+   */
+  detectRoles(chunk: CodeChunk): string[] {
+    const roles = new Set<string>();
+    for (const analyzer of this.sortedAnalyzers) {
+      for (const role of analyzer.detectRoles?.(chunk) ?? []) roles.add(role);
+    }
+    return [...roles];
+  }
+
   /**
    * Find all analyzers that can handle a file
    */
@@ -66,7 +77,11 @@ export class AnalyzerRegistry {
   /**
    * Analyze a file using the best available analyzer
    */
-  async analyzeFile(filePath: string, content: string): Promise<AnalysisResult | null> {
+  async analyzeFile(
+    filePath: string,
+    content: string,
+    options?: AnalyzeOptions
+  ): Promise<AnalysisResult | null> {
     const analyzer = this.findAnalyzer(filePath, content);
 
     if (!analyzer) {
@@ -79,7 +94,7 @@ export class AnalyzerRegistry {
     // console.error(`Analyzing ${filePath} with ${analyzer.name} analyzer`);
 
     try {
-      return await analyzer.analyze(filePath, content);
+      return await analyzer.analyze(filePath, content, options);
     } catch (error) {
       console.error(`Error analyzing ${filePath} with ${analyzer.name}:`, error);
       return null;
@@ -2,24 +2,36 @@ export interface AutoRefreshController {
   /**
    * Called when a file watcher detects a change.
    * Returns true when an incremental refresh should run immediately.
+   * Changed paths reported while indexing are kept for the queued refresh.
    */
-  onFileChange: (isIndexing: boolean) => boolean;
+  onFileChange: (isIndexing: boolean, changedPaths?: string[]) => boolean;
   /**
    * Called after an indexing run completes.
    * Returns true when a queued incremental refresh should run next.
    */
   consumeQueuedRefresh: (indexStatus: 'ready' | 'error' | 'idle' | 'indexing') => boolean;
+  /**
+   * Returns the paths collected for the queued refresh and clears them.
+   * Returns undefined when any queued change arrived without paths (full incremental scan).
+   */
+  takeQueuedPaths: () => string[] | undefined;
   /** Clears any queued refresh. */
   reset: () => void;
 }
 
 export function createAutoRefreshController(): AutoRefreshController {
   let queued = false;
+  let queuedPaths: Set<string> | null = new Set();
 
   return {
-    onFileChange: (isIndexing: boolean) => {
+    onFileChange: (isIndexing: boolean, changedPaths?: string[]) => {
       if (isIndexing) {
         queued = true;
+        if (!changedPaths) {
+          queuedPaths = null;
+        } else if (queuedPaths) {
+          for (const p of changedPaths) queuedPaths.add(p);
+        }
         return false;
       }
       return true;
@@ -33,8 +45,14 @@ export function createAutoRefreshController(): AutoRefreshController {
       queued = false;
       return shouldRun;
     },
+    takeQueuedPaths: () => {
+      const paths = queuedPaths ? [...queuedPaths] : undefined;
+      queuedPaths = new Set();
+      return paths;
+    },
     reset: () => {
       queued = false;
+      queuedPaths = new Set();
     }
   };
 }
@@ -0,0 +1,146 @@
+/**
+ * Who last changed a chunk, from `git blame` over its line range, so an answer can say
+ * "changed last week by the payments team". Blame runs at query time, on the working tree
+ * (or the indexed commit of a ref index), and only when a search asks for ownership.
+ *
+ * Author names and commit subjects are reported; email addresses never are.
+ */
+
+import { execFile } from 'child_process';
+import { promisify } from 'util';
+import { GIT_PLATFORM_ARGS } from '../utils/git-tree.js';
+
+const execFileAsync = promisify(execFile);
+
+const BLAME_TIMEOUT_MS = 5000;
+const MAX_AUTHORS = 3;
+/** What blame reports for lines changed in the working tree but not committed */
+const UNCOMMITTED = /^0{40}$/;
+
+export interface BlameSummary {
+  /** Author of the newest commit touching the range */
+  lastAuthor: string;
+  /** Its author date, YYYY-MM-DD */
+  lastChanged: string;
+  /** The same date relative to now ("6 days ago") */
+  ago: string;
+  /** Short SHA and subject line of that commit */
+  commit: string;
+  subject: string;
+  /** Authors of the range's lines, most lines first */
+  authors: string[];
+  /** Lines edited in the working tree since the last commit */
+  uncommittedLines?: number;
+}
+
+interface BlameCommit {
+  author: string;
+  time: number;
+  subject: string;
+  lines: number;
+}
+
+const DAY_MS = 24 * 60 * 60 * 1000;
+
+/** Coarse relative age: days up to two weeks, then weeks, months and years */
+export function describeAge(date: Date, now: Date = new Date()): string {
+  const days = Math.max(0, Math.floor((now.getTime() - date.getTime()) / DAY_MS));
+  const unit = (count: number, name: string) => `${count} ${name}${count === 1 ? '' : 's'} ago`;
+  if (days === 0) return 'today';
+  if (days < 14) return unit(days, 'day');
+  if (days < 60) return unit(Math.floor(days / 7), 'week');
+  if (days < 730) return unit(Math.floor(days / 30), 'month');
+  return unit(Math.floor(days / 365), 'year');
+}
+
+/** Summarize `git blame --porcelain` output; null when no line is committed */
+export function parseBlamePorcelain(output: string, now: Date = new Date()): BlameSummary | null {
+  const commits = new Map<string, BlameCommit>();
+  let uncommittedLines = 0;
+  let current: BlameCommit | undefined;
+  for (const line of output.split('\n')) {
+    // Header of each blamed line: `<sha> <original line> <final line> [<group size>]`
+    const header = /^([0-9a-f]{40}) \d+ \d+/.exec(line);
+    if (header) {
+      const sha = header[1];
+      if (UNCOMMITTED.test(sha)) {
+        uncommittedLines++;
+        current = undefined;
+        continue;
+      }
+      current = commits.get(sha);
+      if (!current) {
+        current = { author: '', time: 0, subject: '', lines: 0 };
+        commits.set(sha, current);
+      }
+      current.lines++;
+      continue;
+    }
+    // Commit details follow the first line blamed on each commit
+    if (!current) continue;
+    const space = line.indexOf(' ');
+    const key = line.slice(0, space);
+    const value = line.slice(space + 1);
+    if (key === 'author') current.author = value;
+    else if (key === 'author-time') current.time = Number(value) || 0;
+    else if (key === 'summary') current.subject = value;
+  }
+
+  let lastSha = '';
+  let last: BlameCommit | undefined;
+  for (const [sha, commit] of commits) {
+    if (!last || commit.time > last.time) [lastSha, last] = [sha, commit];
+  }
+  if (!last) return null;
+
+  const linesByAuthor = new Map<string, number>();
+  for (const commit of commits.values()) {
+    linesByAuthor.set(commit.author, (linesByAuthor.get(commit.author) ?? 0) + commit.lines);
+  }
+  const changed = new Date(last.time * 1000);
+  return {
+    lastAuthor: last.author,
+    lastChanged: changed.toISOString().slice(0, 10),
+    ago: describeAge(changed, now),
+    commit: lastSha.slice(0, 8),
+    subject: last.subject,
+    authors: [...linesByAuthor]
+      .sort((a, b) => b[1] - a[1] || a[0].localeCompare(b[0]))
+      .slice(0, MAX_AUTHORS)
+      .map(([author]) => author),
+    ...(uncommittedLines > 0 && { uncommittedLines })
+  };
+}
+
+/**
+ * Blame lines `startLine`-`endLine` of a file, in the working tree or at `commit`. Null when
+ * git is unavailable, the file is untracked, or the range no longer exists.
+ */
+export async function blameRange(
+  rootPath: string,
+  relativeFile: string,
+  startLine: number,
+  endLine: number,
+  commit?: string
+): Promise<BlameSummary | null> {
+  if (commit !== undefined && !/^[0-9a-f]{7,40}$/i.test(commit)) return null;
+  try {
+    const { stdout } = await execFileAsync(
+      'git',
+      [
+        ...GIT_PLATFORM_ARGS,
+        'blame',
+        '--porcelain',
+        '-L',
+        `${startLine},${Math.max(startLine, endLine)}`,
+        ...(commit ? [commit] : []),
+        '--',
+        relativeFile
+      ],
+      { cwd: rootPath, timeout: BLAME_TIMEOUT_MS, maxBuffer: 10 * 1024 * 1024 }
+    );
+    return parseBlamePorcelain(stdout);
+  } catch {
+    return null;
+  }
+}
@@ -0,0 +1,119 @@
+/**
+ * In-memory BM25 inverted index over keyword-index chunks.
+ *
+ * Complements the fuzzy Fuse.js channel: BM25 rewards exact identifier and error-string
+ * hits (e.g. `NewCalculator`, "connection refused") that fuzzy matching dilutes.
+ */
+
+/** Term frequency saturation */
+const BM25_K1 = 1.2;
+/** Document length normalization */
+const BM25_B = 0.75;
+
+const MIN_TOKEN_LENGTH = 2;
+
+export interface BM25Document {
+  id: string;
+  text: string;
+}
+
+export interface BM25Hit {
+  id: string;
+  score: number;
+}
+
+/**
+ * Tokenize text for BM25. Identifiers are kept whole (lowercased) and also split on
+ * camelCase / snake_case / kebab-case boundaries so `NewCalculator` matches both
+ * `newcalculator` and `calculator`.
+ */
+export function tokenizeForBM25(text: string): string[] {
+  const tokens: string[] = [];
+  const words = text.match(/[A-Za-z0-9_$]+/g) ?? [];
+
+  for (const word of words) {
+    const whole = word.toLowerCase();
+    if (whole.length >= MIN_TOKEN_LENGTH) tokens.push(whole);
+
+    const parts = word
+      .replace(/([a-z0-9])([A-Z])/g, '$1 $2')
+      .replace(/([A-Z]+)([A-Z][a-z])/g, '$1 $2')
+      .split(/[\s_$]+/)
+      .map((p) => p.toLowerCase())
+      .filter((p) => p.length >= MIN_TOKEN_LENGTH);
+
+    if (parts.length > 1) {
+      for (const part of parts) {
+        if (part !== whole) tokens.push(part);
+      }
+    }
+  }
+
+  return tokens;
+}
+
+export class BM25Index {
+  private postings = new Map<string, Map<number, number>>();
+  private docIds: string[] = [];
+  private docLengths: number[] = [];
+  private avgDocLength = 0;
+
+  constructor(documents: BM25Document[]) {
+    let totalLength = 0;
+
+    documents.forEach((doc, docIndex) => {
+      const tokens = tokenizeForBM25(doc.text);
+      this.docIds.push(doc.id);
+      this.docLengths.push(tokens.length);
+      totalLength += tokens.length;
+
+      for (const token of tokens) {
+        let posting = this.postings.get(token);
+        if (!posting) {
+          posting = new Map();
+          this.postings.set(token, posting);
+        }
+        posting.set(docIndex, (posting.get(docIndex) ?? 0) + 1);
+      }
+    });
+
+    this.avgDocLength = documents.length > 0 ? totalLength / documents.length : 0;
+  }
+
+  get size(): number {
+    return this.docIds.length;
+  }
+
+  /**
+   * Score documents against the query. Only documents sharing at least one term are returned,
+   * sorted by descending BM25 score.
+   */
+  search(query: string, limit: number): BM25Hit[] {
+    const queryTerms = [...new Set(tokenizeForBM25(query))];
+    if (queryTerms.length === 0 || this.docIds.length === 0) return [];
+
+    const totalDocs = this.docIds.length;
+    const scores = new Map<number, number>();
+
+    for (const term of queryTerms) {
+      const posting = this.postings.get(term);
+      if (!posting) continue;
+
+      // Lucene-style IDF: stays positive even for terms present in most documents
+      const docFreq = posting.size;
+      const idf = Math.log(1 + (totalDocs - docFreq + 0.5) / (docFreq + 0.5));
+
+      for (const [docIndex, termFreq] of posting) {
+        const lengthNorm =
+          1 - BM25_B + BM25_B * (this.docLengths[docIndex] / (this.avgDocLength || 1));
+        const termScore = (idf * (termFreq * (BM25_K1 + 1))) / (termFreq + BM25_K1 * lengthNorm);
+        scores.set(docIndex, (scores.get(docIndex) ?? 0) + termScore);
+      }
+    }
+
+    return Array.from(scores.entries())
+      .sort((a, b) => b[1] - a[1])
+      .slice(0, limit)
+      .map(([docIndex, score]) => ({ id: this.docIds[docIndex], score }));
+  }
+}
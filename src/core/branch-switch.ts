@@ -0,0 +1,50 @@
+/**
+ * Branch switches against one shared working-tree index. The index records the HEAD it was
+ * built at; when HEAD moves to another branch, only the files that differ between the two
+ * commits are re-indexed, and the embedding cache turns a switch back to a branch seen
+ * before into re-chunking without re-embedding.
+ */
+
+import path from 'path';
+import { listChangedFiles, readGitHead, type GitHead } from '../utils/git-tree.js';
+
+export interface BranchSwitch {
+  from: GitHead;
+  to: GitHead;
+  /** Absolute paths that differ between the two commits; undefined when that can't be told */
+  changedPaths?: string[];
+}
+
+/**
+ * A different branch, or a different commit between two detached HEADs. New commits on the
+ * same branch are ordinary edits, which the file watcher and incremental refresh handle.
+ */
+export function isBranchSwitch(indexed: GitHead, current: GitHead): boolean {
+  if (indexed.branch || current.branch) return indexed.branch !== current.branch;
+  return indexed.commit !== current.commit;
+}
+
+/** The switch from the indexed HEAD to the current one, or null when there wasn't one */
+export async function detectBranchSwitch(
+  rootPath: string,
+  indexed: GitHead | undefined
+): Promise<BranchSwitch | null> {
+  if (!indexed) return null;
+  const current = await readGitHead(rootPath);
+  if (!current || !isBranchSwitch(indexed, current)) return null;
+
+  let changedPaths: string[] | undefined;
+  try {
+    const changed = await listChangedFiles(rootPath, indexed.commit, current.commit);
+    changedPaths = changed.map((relative) => path.join(rootPath, relative));
+  } catch {
+    // The indexed commit is gone (rebased, gc'd): fall back to a full incremental diff
+    changedPaths = undefined;
+  }
+  return { from: indexed, to: current, changedPaths };
+}
+
+export function describeBranchSwitch({ from, to }: BranchSwitch): string {
+  const label = (head: GitHead) => head.branch ?? head.commit.slice(0, 12);
+  return `Branch changed (${label(from)} -> ${label(to)})`;
+}
@@ -0,0 +1,175 @@
+/**
+ * Bazel/Buck target graph, as stored in the relationships sidecar: which targets own a file,
+ * and which targets depend on a target, transitively. In a monorepo built by Bazel or Buck the
+ * target is the unit a change affects, so "what depends on this" follows `deps`, not imports.
+ */
+
+import path from 'path';
+import { CODEBASE_CONTEXT_DIRNAME, RELATIONSHIPS_FILENAME } from '../constants/codebase-context.js';
+import type { BuildGraphData } from '../utils/build-files.js';
+import { labelFile, normalizeLabel } from '../utils/build-files.js';
+import { readIndexArtifact } from './index-lock.js';
+
+export type { BuildGraphData, BuildTargetInfo } from '../utils/build-files.js';
+
+/** Rule kinds that are tests (`go_test`, `cc_test`, `java_test`, `test_suite`) */
+const TEST_KIND = /(?:^|_)test(?:_suite)?$/;
+
+export function isTestTarget(kind: string): boolean {
+  return TEST_KIND.test(kind);
+}
+
+/**
+ * Load the build graph from the relationships sidecar.
+ * Returns null when the project isn't built with Bazel or Buck or the index predates it.
+ */
+export async function loadBuildGraph(rootPath: string): Promise<BuildGraphData | null> {
+  try {
+    const raw = await readIndexArtifact(
+      path.join(rootPath, CODEBASE_CONTEXT_DIRNAME),
+      RELATIONSHIPS_FILENAME
+    );
+    const build = (JSON.parse(raw) as { build?: BuildGraphData }).build;
+    return build && typeof build.targets === 'object' ? build : null;
+  } catch {
+    return null;
+  }
+}
+
+/** Repo-relative file -> labels of the targets listing it in srcs/hdrs */
+export function targetsByFile(graph: BuildGraphData): Map<string, string[]> {
+  const owners = new Map<string, string[]>();
+  for (const [label, target] of Object.entries(graph.targets)) {
+    for (const file of target.files ?? []) {
+      const list = owners.get(file) ?? [];
+      list.push(label);
+      owners.set(file, list);
+    }
+  }
+  return owners;
+}
+
+export interface ResolvedTargets {
+  /** Labels the query names; for a file, its owning targets and the file label itself */
+  labels: string[];
+  /** Several targets share a bare name: the labels to pick from, nothing resolved */
+  candidates?: string[];
+}
+
+/**
+ * Resolve what a caller typed to labels: a label (`//pkg:name`, `//pkg`, `@repo//x:y`), a
+ * package pattern (`//pkg/...`, `//pkg:all`, `//pkg:*`), a repo-relative file, or the bare name
+ * of a target when only one has it.
+ */
+export function resolveBuildTarget(graph: BuildGraphData, query: string): ResolvedTargets {
+  const input = query.trim().replace(/\\/g, '/').replace(/^\.\//, '');
+  const labels = Object.keys(graph.targets);
+
+  const recursive = /^\/\/(.*?)\/?\.\.\.$/.exec(input);
+  if (recursive) {
+    const pkg = recursive[1];
+    return {
+      labels: labels.filter((label) => {
+        const own = label.slice(2, label.indexOf(':'));
+        return pkg === '' || own === pkg || own.startsWith(`${pkg}/`);
+      })
+    };
+  }
+  const wildcard = /^\/\/([^:]*):(?:all|\*|all-targets)$/.exec(input);
+  if (wildcard) {
+    return { labels: labels.filter((label) => label.startsWith(`//${wildcard[1]}:`)) };
+  }
+
+  if (input.startsWith('//') || input.startsWith('@')) {
+    const label = normalizeLabel(input, '', graph.cells);
+    return { labels: graph.targets[label] || isDependedOn(graph, label) ? [label] : [] };
+  }
+
+  const owners = targetsByFile(graph).get(input);
+  if (owners) {
+    const fileLabels = labels.flatMap((label) =>
+      (graph.targets[label].deps ?? []).filter((dep) => labelFile(dep) === input)
+    );
+    return { labels: [...new Set([...owners, ...fileLabels])] };
+  }
+
+  const name = input.replace(/^:/, '');
+  const named = labels.filter((label) => label.slice(label.indexOf(':') + 1) === name);
+  if (named.length > 1) return { labels: [], candidates: named.sort() };
+  return { labels: named };
+}
+
+function isDependedOn(graph: BuildGraphData, label: string): boolean {
+  return Object.values(graph.targets).some((target) => target.deps?.includes(label));
+}
+
+export interface BuildDependent {
+  label: string;
+  kind: string;
+  /** 1 for direct dependents */
+  depth: number;
+  /** The target this one depends on, one step closer to the query */
+  via: string;
+  test: boolean;
+  /** Source files the target lists */
+  files: number;
+}
+
+export interface BuildDependentsOptions {
+  /** Levels of reverse dependencies to follow (default: 1, direct dependents only) */
+  depth?: number;
+  /** Only return test targets (traversal still passes through the others) */
+  testsOnly?: boolean;
+}
+
+/** label -> targets whose deps list it */
+function reverseDeps(graph: BuildGraphData): Map<string, string[]> {
+  const reverse = new Map<string, string[]>();
+  for (const [label, target] of Object.entries(graph.targets)) {
+    for (const dep of target.deps ?? []) {
+      const list = reverse.get(dep) ?? [];
+      list.push(label);
+      reverse.set(dep, list);
+    }
+  }
+  return reverse;
+}
+
+/**
+ * Targets that depend on any of `labels`, breadth-first up to `depth` levels (`bazel query
+ * rdeps(//..., label, depth)` without the labels themselves), nearest first.
+ */
+export function getBuildDependents(
+  graph: BuildGraphData,
+  labels: readonly string[],
+  options: BuildDependentsOptions = {}
+): BuildDependent[] {
+  const maxDepth = Math.max(1, options.depth ?? 1);
+  const reverse = reverseDeps(graph);
+  const seen = new Set(labels);
+  const found: BuildDependent[] = [];
+  let frontier = [...labels];
+  for (let depth = 1; depth <= maxDepth && frontier.length > 0; depth++) {
+    const next: string[] = [];
+    for (const label of frontier) {
+      for (const dependent of (reverse.get(label) ?? []).sort()) {
+        if (seen.has(dependent)) continue;
+        seen.add(dependent);
+        next.push(dependent);
+        const target = graph.targets[dependent];
+        const test = isTestTarget(target.kind);
+        if (options.testsOnly && !test) continue;
+        found.push({
+          label: dependent,
+          kind: target.kind,
+          depth,
+          via: label,
+          test,
+          files: target.files?.length ?? 0
+        });
+      }
+    }
+    frontier = next;
+  }
+  return found;
+}
@@ -0,0 +1,229 @@
+/**
+ * Static call graph built from Tree-sitter call sites during indexing.
+ *
+ * Edges are name-based: a call to `save(...)` links to every definition named `save`.
+ * Dynamic dispatch, aliasing and same-name functions in different files are not
+ * disambiguated, so results are a navigation aid, not a proof of reachability.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import { CODEBASE_CONTEXT_DIRNAME, RELATIONSHIPS_FILENAME } from '../constants/codebase-context.js';
+import type { TreeSitterCallExtraction } from '../utils/tree-sitter.js';
+
+export interface CallGraphDefinition {
+  file: string;
+  kind: string;
+  startLine: number;
+  endLine: number;
+}
+
+export interface CallGraphEdge {
+  /** Enclosing function/method name, null for top-level code */
+  caller: string | null;
+  callee: string;
+  file: string;
+  line: number;
+  callerStartLine?: number;
+  callerEndLine?: number;
+}
+
+export interface CallGraphData {
+  definitions: Record<string, CallGraphDefinition[]>;
+  calls: CallGraphEdge[];
+}
+
+export interface CallerEntry {
+  /** Calling function/method, null when the call is top-level code */
+  symbol: string | null;
+  /** "path:startLine-endLine" of the caller body, or just the path for top-level calls */
+  file: string;
+  callLines: number[];
+}
+
+export interface CalleeEntry {
+  symbol: string;
+  callLines: number[];
+  /** "path:startLine-endLine" of matching definitions; absent for external calls */
+  definedAt?: string[];
+}
+
+export interface CallLookupResult<T> {
+  symbol: string;
+  /** Where the symbol itself is defined ("path:startLine-endLine") */
+  definedAt: string[];
+  total: number;
+  results: T[];
+}
+
+const MAX_DEFINITION_LOCATIONS = 3;
+
+/** Use the last segment of qualified input (`UserService.save`, `pkg::save`, `A#save`). */
+export function normalizeCallSymbol(symbol: string): string {
+  const parts = symbol.trim().split(/::|\.|#|->/);
+  return parts[parts.length - 1].trim();
+}
+
+function formatRange(file: string, startLine: number, endLine: number): string {
+  return `${file}:${startLine}-${endLine}`;
+}
+
+function definitionLocations(data: CallGraphData, name: string): string[] {
+  return (data.definitions[name] ?? [])
+    .slice(0, MAX_DEFINITION_LOCATIONS)
+    .map((d) => formatRange(d.file, d.startLine, d.endLine));
+}
+
+export class CallGraphBuilder {
+  private definitions = new Map<string, CallGraphDefinition[]>();
+  private calls: CallGraphEdge[] = [];
+
+  constructor(private rootPath: string) {}
+
+  trackFile(filePath: string, extraction: TreeSitterCallExtraction): void {
+    const file = path.relative(this.rootPath, filePath).replace(/\\/g, '/');
+
+    for (const symbol of extraction.symbols) {
+      const entry: CallGraphDefinition = {
+        file,
+        kind: symbol.kind,
+        startLine: symbol.startLine,
+        endLine: symbol.endLine
+      };
+      const existing = this.definitions.get(symbol.name);
+      if (existing) {
+        existing.push(entry);
+      } else {
+        this.definitions.set(symbol.name, [entry]);
+      }
+    }
+
+    for (const call of extraction.calls) {
+      this.calls.push({
+        caller: call.caller?.name ?? null,
+        callee: call.callee,
+        file,
+        line: call.line,
+        ...(call.caller
+          ? { callerStartLine: call.caller.startLine, callerEndLine: call.caller.endLine }
+          : {})
+      });
+    }
+  }
+
+  toJSON(): CallGraphData {
+    return {
+      definitions: Object.fromEntries(this.definitions),
+      calls: this.calls
+    };
+  }
+}
+
+function isCallGraphData(value: unknown): value is CallGraphData {
+  if (!value || typeof value !== 'object') return false;
+  const candidate = value as { definitions?: unknown; calls?: unknown };
+  return (
+    typeof candidate.definitions === 'object' &&
+    candidate.definitions !== null &&
+    Array.isArray(candidate.calls)
+  );
+}
+
+/**
+ * Load the call graph from the relationships sidecar.
+ * Returns null when the index predates call graph extraction or hasn't been built.
+ */
+export async function loadCallGraph(rootPath: string): Promise<CallGraphData | null> {
+  const relationshipsPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, RELATIONSHIPS_FILENAME);
+  try {
+    const parsed = JSON.parse(await fs.readFile(relationshipsPath, 'utf-8')) as {
+      callGraph?: unknown;
+    };
+    return isCallGraphData(parsed.callGraph) ? parsed.callGraph : null;
+  } catch {
+    return null;
+  }
+}
+
+/** Functions/methods that call `symbol`, grouped per caller with the call-site lines. */
+export function findCallers(
+  data: CallGraphData,
+  symbol: string,
+  limit: number
+): CallLookupResult<CallerEntry> {
+  const name = normalizeCallSymbol(symbol);
+  const grouped = new Map<string, CallerEntry>();
+
+  for (const edge of data.calls) {
+    if (edge.callee !== name) continue;
+    const file =
+      edge.caller && edge.callerStartLine !== undefined && edge.callerEndLine !== undefined
+        ? formatRange(edge.file, edge.callerStartLine, edge.callerEndLine)
+        : edge.file;
+    const key = `${edge.caller ?? ''}@${file}`;
+    const entry = grouped.get(key);
+    if (entry) {
+      entry.callLines.push(edge.line);
+    } else {
+      grouped.set(key, { symbol: edge.caller, file, callLines: [edge.line] });
+    }
+  }
+
+  const results = Array.from(grouped.values()).sort(
+    (a, b) => b.callLines.length - a.callLines.length || a.file.localeCompare(b.file)
+  );
+
+  return {
+    symbol: name,
+    definedAt: definitionLocations(data, name),
+    total: results.length,
+    results: results.slice(0, limit)
+  };
+}
+
+/**
+ * Symbols called from within `symbol`. Callees defined in the project come first;
+ * external ones (stdlib, dependencies) are only included when `includeExternal` is set.
+ */
+export function findCallees(
+  data: CallGraphData,
+  symbol: string,
+  limit: number,
+  options: { file?: string; includeExternal?: boolean } = {}
+): CallLookupResult<CalleeEntry> {
+  const name = normalizeCallSymbol(symbol);
+  const fileFilter = options.file?.replace(/\\/g, '/');
+  const grouped = new Map<string, CalleeEntry>();
+
+  for (const edge of data.calls) {
+    if (edge.caller !== name) continue;
+    if (fileFilter && !edge.file.endsWith(fileFilter)) continue;
+    const entry = grouped.get(edge.callee);
+    if (entry) {
+      if (!entry.callLines.includes(edge.line)) entry.callLines.push(edge.line);
+    } else {
+      grouped.set(edge.callee, { symbol: edge.callee, callLines: [edge.line] });
+    }
+  }
+
+  const resolved: CalleeEntry[] = [];
+  const external: CalleeEntry[] = [];
+  for (const entry of grouped.values()) {
+    const locations = definitionLocations(data, entry.symbol);
+    if (locations.length > 0) {
+      resolved.push({ ...entry, definedAt: locations });
+    } else if (options.includeExternal) {
+      external.push(entry);
+    }
+  }
+
+  const byFirstCall = (a: CalleeEntry, b: CalleeEntry) => a.callLines[0] - b.callLines[0];
+  const results = [...resolved.sort(byFirstCall), ...external.sort(byFirstCall)];
+
+  return {
+    symbol: name,
+    definedAt: definitionLocations(data, name),
+    total: results.length,
+    results: results.slice(0, limit)
+  };
+}
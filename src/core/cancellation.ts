@@ -0,0 +1,116 @@
+/**
+ * Cancellation and time budgets for tool calls.
+ *
+ * Every call gets an AbortSignal that fires when the client cancels the request
+ * (`notifications/cancelled`) or the call runs past its budget. Long tools check it between
+ * units of work and return what they have so far, marked `partial`; a tool that doesn't is
+ * answered for it once a short grace period has passed.
+ */
+
+import { ToolTimeoutError } from '../errors/index.js';
+
+export const DEFAULT_TOOL_TIMEOUT_MS = 60_000;
+
+/** Time a tool gets after the abort to return its partial result */
+export const TOOL_TIMEOUT_GRACE_MS = 2_000;
+
+// Foreground indexing reports progress and is cancelled by the client, so no default budget
+const UNBOUNDED_TOOLS = new Set(['refresh_index', 'index_remote', 'index_dependency']);
+
+/**
+ * Budget for one call of `tool` in ms (0 = none). CODEBASE_CONTEXT_TOOL_TIMEOUT_MS is either
+ * one value for every tool or a list such as `search_codebase=10000,*=30000`.
+ */
+export function resolveToolTimeoutMs(tool: string, env: NodeJS.ProcessEnv = process.env): number {
+  const budgets = new Map<string, number>();
+  for (const entry of (env.CODEBASE_CONTEXT_TOOL_TIMEOUT_MS ?? '').split(',')) {
+    const [name, value] = entry.includes('=') ? entry.split('=', 2) : ['*', entry];
+    const ms = Number.parseInt(value.trim(), 10);
+    if (Number.isFinite(ms) && ms >= 0) budgets.set(name.trim(), ms);
+  }
+  const explicit = budgets.get(tool);
+  if (explicit !== undefined) return explicit;
+  if (UNBOUNDED_TOOLS.has(tool)) return 0;
+  return budgets.get('*') ?? DEFAULT_TOOL_TIMEOUT_MS;
+}
+
+/**
+ * An AbortController that also aborts when any of `parents` does. Call `release` when done
+ * so long-lived parents don't collect listeners.
+ */
+export function linkedAbortController(parents: Array<AbortSignal | undefined>): {
+  controller: AbortController;
+  release: () => void;
+} {
+  const controller = new AbortController();
+  const unlinks: Array<() => void> = [];
+  for (const parent of parents) {
+    if (!parent) continue;
+    if (parent.aborted) {
+      controller.abort(parent.reason);
+      break;
+    }
+    const onAbort = () => controller.abort(parent.reason);
+    parent.addEventListener('abort', onAbort, { once: true });
+    unlinks.push(() => parent.removeEventListener('abort', onAbort));
+  }
+  return { controller, release: () => unlinks.forEach((unlink) => unlink()) };
+}
+
+export type StopReason = 'timeout' | 'cancelled';
+
+export function stopReason(signal?: AbortSignal): StopReason | undefined {
+  if (!signal?.aborted) return undefined;
+  return signal.reason instanceof ToolTimeoutError ? 'timeout' : 'cancelled';
+}
+
+/** `{ partial: true, stoppedBy }` once `signal` has fired, else `{}`; spread into payloads */
+export function partialMarker(signal?: AbortSignal): { partial?: true; stoppedBy?: StopReason } {
+  const reason = stopReason(signal);
+  return reason ? { partial: true, stoppedBy: reason } : {};
+}
+
+/**
+ * Run `task` under a time budget. Its signal aborts on timeout or when `signal` does; if the
+ * task hasn't settled `graceMs` after that, `onStopped` answers instead and the task's
+ * eventual result is dropped.
+ */
+export async function withTimeBudget<T>(
+  task: (signal: AbortSignal) => Promise<T>,
+  options: {
+    timeoutMs: number;
+    signal?: AbortSignal;
+    graceMs?: number;
+    onStopped: (reason: StopReason) => T;
+  }
+): Promise<T> {
+  const { controller, release } = linkedAbortController([options.signal]);
+  let budgetTimer: NodeJS.Timeout | undefined;
+  let graceTimer: NodeJS.Timeout | undefined;
+  if (options.timeoutMs > 0) {
+    budgetTimer = setTimeout(
+      () => controller.abort(new ToolTimeoutError(options.timeoutMs)),
+      options.timeoutMs
+    );
+    budgetTimer.unref();
+  }
+  const stopped = new Promise<T>((resolve) => {
+    const onAbort = () => {
+      graceTimer = setTimeout(
+        () => resolve(options.onStopped(stopReason(controller.signal) ?? 'cancelled')),
+        options.graceMs ?? TOOL_TIMEOUT_GRACE_MS
+      );
+      graceTimer.unref();
+    };
+    if (controller.signal.aborted) onAbort();
+    else controller.signal.addEventListener('abort', onAbort, { once: true });
+  });
+
+  try {
+    return await Promise.race([task(controller.signal), stopped]);
+  } finally {
+    clearTimeout(budgetTimer);
+    clearTimeout(graceTimer);
+    release();
+  }
+}
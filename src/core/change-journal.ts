@@ -0,0 +1,334 @@
+/**
+ * Change journal: the files and symbols each index build added, modified and removed, so an
+ * agent can ask what changed since it last looked instead of re-reading the index.
+ *
+ * `.codebase-context/changes.json` holds the entries of the last builds and a snapshot of
+ * file and symbol content hashes from the latest one, which the next build is compared
+ * against. Cursors are build sequence numbers. A cursor from before the retained entries, or
+ * from before a rollback (which resets the journal), is expired: the caller has to re-read
+ * what it needs and continue from the current cursor.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import { CHANGE_JOURNAL_FILENAME } from '../constants/codebase-context.js';
+import type { SymbolDefinition } from './symbol-index.js';
+
+/** Builds kept in the journal */
+export const MAX_JOURNAL_ENTRIES = 200;
+/** Paths or symbols kept per list of one entry; longer lists are cut and marked truncated */
+const MAX_ENTRY_ITEMS = 2000;
+
+export interface SymbolChange {
+  name: string;
+  kind: string;
+  file: string;
+  /** Current lines; absent for removed symbols */
+  startLine?: number;
+  endLine?: number;
+}
+
+export interface ChangeLists<T> {
+  added: T[];
+  modified: T[];
+  removed: T[];
+}
+
+export interface ChangeEntry {
+  cursor: number;
+  buildId: string;
+  generatedAt: string;
+  files: ChangeLists<string>;
+  symbols: ChangeLists<SymbolChange>;
+  /** Lists were cut at MAX_ENTRY_ITEMS */
+  truncated?: boolean;
+}
+
+interface JournalSnapshot {
+  /** relativePath -> content hash (as in the manifest) */
+  files: Record<string, string>;
+  /** relativePath -> symbol key (`kind:qualified name`) -> content hash */
+  symbols: Record<string, Record<string, string>>;
+}
+
+export interface ChangeJournal {
+  version: 1;
+  /** Cursor of the latest build */
+  cursor: number;
+  /** Oldest cursor changes can still be listed from, and when that build finished */
+  baseline: number;
+  baselineAt: string;
+  entries: ChangeEntry[];
+  /** Absent after a reset: the next build starts a new baseline */
+  snapshot?: JournalSnapshot;
+}
+
+export interface BuildSnapshot {
+  buildId: string;
+  generatedAt: string;
+  files: Record<string, string>;
+  definitions: SymbolDefinition[];
+  /** Content hash per definition, aligned with `definitions` */
+  hashes: string[];
+}
+
+function journalPath(contextDir: string): string {
+  return path.join(contextDir, CHANGE_JOURNAL_FILENAME);
+}
+
+export async function readChangeJournal(contextDir: string): Promise<ChangeJournal | null> {
+  try {
+    const parsed = JSON.parse(await fs.readFile(journalPath(contextDir), 'utf-8')) as ChangeJournal;
+    return parsed.version === 1 && Array.isArray(parsed.entries) ? parsed : null;
+  } catch {
+    return null;
+  }
+}
+
+async function writeChangeJournal(contextDir: string, journal: ChangeJournal): Promise<void> {
+  const file = journalPath(contextDir);
+  const tmp = `${file}.${process.pid}.tmp`;
+  await fs.writeFile(tmp, JSON.stringify(journal));
+  await fs.rename(tmp, file);
+}
+
+function symbolKey(definition: SymbolDefinition): string {
+  return `${definition.kind}:${definition.qualifiedName ?? definition.name}`;
+}
+
+/** One key per definition of each file; overloads and repeats get `#2`, `#3`, ... */
+function keyedDefinitions(
+  definitions: SymbolDefinition[],
+  hashes: string[]
+): Map<string, Map<string, { definition: SymbolDefinition; hash: string }>> {
+  const byFile = new Map<string, Map<string, { definition: SymbolDefinition; hash: string }>>();
+  definitions.forEach((definition, i) => {
+    let keyed = byFile.get(definition.file);
+    if (!keyed) byFile.set(definition.file, (keyed = new Map()));
+    const base = symbolKey(definition);
+    let key = base;
+    for (let n = 2; keyed.has(key); n++) key = `${base}#${n}`;
+    keyed.set(key, { definition, hash: hashes[i] ?? '' });
+  });
+  return byFile;
+}
+
+/** Name and kind back from a key: the kind never contains `:` */
+function fromKey(key: string, file: string): SymbolChange {
+  const colon = key.indexOf(':');
+  return {
+    kind: key.slice(0, colon),
+    name: key.slice(colon + 1).replace(/#\d+$/, ''),
+    file
+  };
+}
+
+function located(definition: SymbolDefinition): SymbolChange {
+  return {
+    name: definition.qualifiedName ?? definition.name,
+    kind: definition.kind,
+    file: definition.file,
+    startLine: definition.startLine,
+    endLine: definition.endLine
+  };
+}
+
+type KeyedDefinitions = ReturnType<typeof keyedDefinitions>;
+
+function snapshotOf(build: BuildSnapshot, current: KeyedDefinitions): JournalSnapshot {
+  const symbols: JournalSnapshot['symbols'] = {};
+  for (const [file, keyed] of current) {
+    symbols[file] = Object.fromEntries([...keyed].map(([key, { hash }]) => [key, hash]));
+  }
+  return { files: build.files, symbols };
+}
+
+function diffBuild(previous: JournalSnapshot, build: BuildSnapshot, current: KeyedDefinitions) {
+  const files: ChangeLists<string> = { added: [], modified: [], removed: [] };
+  const symbols: ChangeLists<SymbolChange> = { added: [], modified: [], removed: [] };
+  for (const [file, hash] of Object.entries(build.files)) {
+    const before = previous.files[file];
+    if (before === hash) continue;
+    (before === undefined ? files.added : files.modified).push(file);
+    const oldSymbols = previous.symbols[file] ?? {};
+    const newSymbols = current.get(file) ?? new Map();
+    for (const [key, { definition, hash: symbolHash }] of newSymbols) {
+      const old = oldSymbols[key];
+      if (old === undefined) symbols.added.push(located(definition));
+      else if (old !== symbolHash) symbols.modified.push(located(definition));
+    }
+    for (const key of Object.keys(oldSymbols)) {
+      if (!newSymbols.has(key)) symbols.removed.push(fromKey(key, file));
+    }
+  }
+  for (const file of Object.keys(previous.files)) {
+    if (file in build.files) continue;
+    files.removed.push(file);
+    for (const key of Object.keys(previous.symbols[file] ?? {})) {
+      symbols.removed.push(fromKey(key, file));
+    }
+  }
+  return { files, symbols };
+}
+
+function capLists<T>(lists: ChangeLists<T>): { lists: ChangeLists<T>; cut: boolean } {
+  const cut = Object.values(lists).some((list) => list.length > MAX_ENTRY_ITEMS);
+  return {
+    lists: {
+      added: lists.added.slice(0, MAX_ENTRY_ITEMS),
+      modified: lists.modified.slice(0, MAX_ENTRY_ITEMS),
+      removed: lists.removed.slice(0, MAX_ENTRY_ITEMS)
+    },
+    cut
+  };
+}
+
+/**
+ * Record a finished build: compare it with the journal's snapshot, append an entry when
+ * anything changed, and keep its hashes as the new snapshot. The first build (and the first
+ * after a reset) only sets the baseline. Returns the cursor after the build.
+ */
+export async function recordIndexChanges(
+  contextDir: string,
+  build: BuildSnapshot
+): Promise<number> {
+  const journal = await readChangeJournal(contextDir);
+  const current = keyedDefinitions(build.definitions, build.hashes);
+  const snapshot = snapshotOf(build, current);
+
+  if (!journal || !journal.snapshot) {
+    const cursor = (journal?.cursor ?? 0) + 1;
+    await writeChangeJournal(contextDir, {
+      version: 1,
+      cursor,
+      baseline: cursor,
+      baselineAt: build.generatedAt,
+      entries: [],
+      snapshot
+    });
+    return cursor;
+  }
+
+  const { files, symbols } = diffBuild(journal.snapshot, build, current);
+  const changed = files.added.length + files.modified.length + files.removed.length > 0;
+  if (changed) {
+    const cappedFiles = capLists(files);
+    const cappedSymbols = capLists(symbols);
+    journal.cursor += 1;
+    journal.entries.push({
+      cursor: journal.cursor,
+      buildId: build.buildId,
+      generatedAt: build.generatedAt,
+      files: cappedFiles.lists,
+      symbols: cappedSymbols.lists,
+      ...(cappedFiles.cut || cappedSymbols.cut ? { truncated: true } : {})
+    });
+    const dropped = journal.entries.splice(0, journal.entries.length - MAX_JOURNAL_ENTRIES);
+    if (dropped.length > 0) {
+      journal.baseline = dropped[dropped.length - 1].cursor;
+      journal.baselineAt = dropped[dropped.length - 1].generatedAt;
+    }
+  }
+  journal.snapshot = snapshot;
+  await writeChangeJournal(contextDir, journal);
+  return journal.cursor;
+}
+
+/**
+ * Forget the recorded changes, e.g. after a rollback swapped in an older generation the
+ * snapshot doesn't describe. Earlier cursors expire; the next build sets a new baseline.
+ */
+export async function resetChangeJournal(contextDir: string): Promise<void> {
+  const journal = await readChangeJournal(contextDir);
+  if (!journal) return;
+  const cursor = journal.cursor + 1;
+  await writeChangeJournal(contextDir, {
+    version: 1,
+    cursor,
+    baseline: cursor,
+    baselineAt: new Date().toISOString(),
+    entries: []
+  });
+}
+
+export interface ChangeSet {
+  status: 'success';
+  cursor: number;
+  from: number;
+  /** Builds the changes span */
+  builds: number;
+  /** Some build listed only part of its changes */
+  truncated: boolean;
+  files: ChangeLists<string>;
+  symbols: ChangeLists<SymbolChange>;
+}
+
+export type ChangesSince =
+  | ChangeSet
+  | { status: 'expired'; cursor: number; baseline: number }
+  | { status: 'invalid'; cursor: number };
+
+/**
+ * Net changes after `from`: a file added and then removed again is left out, one added and
+ * then modified counts as added, one removed and then added back as modified.
+ */
+export function changesSince(journal: ChangeJournal, from: number): ChangesSince {
+  if (from > journal.cursor) return { status: 'invalid', cursor: journal.cursor };
+  if (from < journal.baseline) {
+    return { status: 'expired', cursor: journal.cursor, baseline: journal.baseline };
+  }
+  const entries = journal.entries.filter((entry) => entry.cursor > from);
+  const fileState = new Map<string, keyof ChangeLists<string>>();
+  const symbolState = new Map<string, { change: SymbolChange; state: keyof ChangeLists<string> }>();
+
+  const merge = (first: keyof ChangeLists<string> | undefined, next: keyof ChangeLists<string>) => {
+    if (!first) return next;
+    if (first === 'added') return next === 'removed' ? undefined : 'added';
+    if (first === 'removed') return next === 'added' ? 'modified' : next;
+    return next;
+  };
+  for (const entry of entries) {
+    for (const state of ['added', 'modified', 'removed'] as const) {
+      for (const file of entry.files[state]) {
+        const merged = merge(fileState.get(file), state);
+        if (merged) fileState.set(file, merged);
+        else fileState.delete(file);
+      }
+      for (const change of entry.symbols[state]) {
+        const key = `${change.file}\0${change.kind}:${change.name}`;
+        const merged = merge(symbolState.get(key)?.state, state);
+        if (merged) symbolState.set(key, { change, state: merged });
+        else symbolState.delete(key);
+      }
+    }
+  }
+
+  const files: ChangeLists<string> = { added: [], modified: [], removed: [] };
+  for (const [file, state] of fileState) files[state].push(file);
+  const symbols: ChangeLists<SymbolChange> = { added: [], modified: [], removed: [] };
+  for (const { change, state } of symbolState.values()) {
+    symbols[state].push(
+      state === 'removed' ? { name: change.name, kind: change.kind, file: change.file } : change
+    );
+  }
+  for (const list of Object.values(files)) list.sort();
+  return {
+    status: 'success',
+    cursor: journal.cursor,
+    from,
+    builds: entries.length,
+    truncated: entries.some((entry) => entry.truncated),
+    files,
+    symbols
+  };
+}
+
+/** Cursor of the last build finished at or before `timestamp` (ISO 8601) */
+export function cursorAt(journal: ChangeJournal, timestamp: string): number | null {
+  const time = Date.parse(timestamp);
+  if (!Number.isFinite(time)) return null;
+  // Before the baseline: nothing recorded reaches back that far
+  if (time < Date.parse(journal.baselineAt)) return journal.baseline - 1;
+  const later = journal.entries.find((entry) => Date.parse(entry.generatedAt) > time);
+  return later ? later.cursor - 1 : journal.cursor;
+}
@@ -0,0 +1,281 @@
+/**
+ * Chunk post-processors: a chain of text transforms applied to each chunk's embedding input,
+ * so boilerplate such as license headers, comment banners and minified lines doesn't dominate
+ * similarity scores. Stored chunks, snippets and the keyword index keep the original text.
+ *
+ * Set under the `postProcessing` key of `.codebase-context/config.json` (or the indexer
+ * config):
+ *
+ * - `processors` lists the chain for every language, applied in order.
+ * - `languages.<id>` replaces that list for one language (`[]` turns it off there).
+ * - `rules` defines named regex replacements that the lists can use like built-ins.
+ *
+ * Built-ins: `strip-license-header`, `fold-comment-banners`, `strip-minified` and
+ * `normalize-whitespace`. Library users can add more with `registerChunkPostProcessor`. The
+ * chain's signature is recorded with the index's embedding model, so changing it re-embeds
+ * on the next build; chunks whose input didn't change keep their cached vectors.
+ */
+
+import { createHash } from 'crypto';
+import { promises as fs } from 'fs';
+import path from 'path';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  PROJECT_CONFIG_FILENAME
+} from '../constants/codebase-context.js';
+import type { CodebaseConfig } from '../types/index.js';
+import { commentSyntax, type CommentSyntax } from '../utils/syntax-highlight.js';
+import type { EmbeddingFingerprint } from './index-meta.js';
+
+export type PostProcessingConfig = NonNullable<CodebaseConfig['postProcessing']>;
+
+export interface ChunkPostProcessorContext {
+  /** Language id of the chunk (`typescript`, `go`, ...) */
+  language: string;
+  relativePath?: string;
+}
+
+export type ChunkPostProcessor = (content: string, context: ChunkPostProcessorContext) => string;
+
+export interface ChunkPostProcessorChain {
+  /** Identifies the configured chain in index meta; undefined when nothing is applied */
+  readonly signature: string | undefined;
+  apply(content: string, context: ChunkPostProcessorContext): string;
+}
+
+const LICENSE_WORDS = new RegExp(
+  '\\b(?:copyright|licen[cs]ed?|spdx-license-identifier|all rights reserved|' +
+    'permission is hereby granted)\\b',
+  'i'
+);
+/** Comment lines kept from one run of comments; the rest of a banner is dropped */
+const MAX_COMMENT_RUN = 8;
+/** A line made only of comment markers and rule characters, like `// -------------` */
+const DECORATION = /^[\s/*#=\-_~+.<>!;]{8,}$/;
+const MINIFIED_LINE_LENGTH = 500;
+/** Below this share of whitespace a long line is taken for minified code */
+const MINIFIED_WHITESPACE_RATIO = 0.1;
+
+function isLineComment(line: string, syntax: CommentSyntax): boolean {
+  const trimmed = line.trim();
+  return trimmed !== '' && syntax.lineComments.some((marker) => trimmed.startsWith(marker));
+}
+
+/** End (exclusive) of the comment starting at `start`: a block comment or a run of line comments */
+function commentEnd(lines: readonly string[], start: number, syntax: CommentSyntax): number {
+  const first = lines[start]?.trim();
+  if (first === undefined) return start;
+  const block = syntax.blockComments.find(([open]) => first.startsWith(open));
+  if (block) {
+    for (let i = start; i < lines.length; i++) {
+      const text = i === start ? first.slice(block[0].length) : lines[i];
+      if (text.includes(block[1])) return i + 1;
+    }
+    return lines.length;
+  }
+  let end = start;
+  while (end < lines.length && isLineComment(lines[end], syntax)) end++;
+  return end;
+}
+
+/** Drop a leading comment that mentions a copyright or license, keeping any shebang */
+function stripLicenseHeader(content: string, { language }: ChunkPostProcessorContext): string {
+  const syntax = commentSyntax(language);
+  if (!syntax) return content;
+  const lines = content.split('\n');
+  let start = lines[0]?.startsWith('#!') ? 1 : 0;
+  while (start < lines.length && !lines[start].trim()) start++;
+  const end = commentEnd(lines, start, syntax);
+  if (end === start || !LICENSE_WORDS.test(lines.slice(start, end).join('\n'))) return content;
+  let next = end;
+  while (next < lines.length && !lines[next].trim()) next++;
+  return [...lines.slice(0, start), ...lines.slice(next)].join('\n');
+}
+
+/** Drop rule lines from comments and cut long runs of comment lines short */
+function foldCommentBanners(content: string, { language }: ChunkPostProcessorContext): string {
+  const syntax = commentSyntax(language);
+  if (!syntax) return content;
+  const kept: string[] = [];
+  let run = 0;
+  // Closing marker of the block comment being read
+  let open: string | null = null;
+  for (const line of content.split('\n')) {
+    const trimmed = line.trim();
+    let comment = open !== null || isLineComment(line, syntax);
+    if (open !== null) {
+      if (trimmed.includes(open)) open = null;
+    } else if (!comment) {
+      const block = syntax.blockComments.find(([start]) => trimmed.startsWith(start));
+      if (block) {
+        comment = true;
+        if (!trimmed.slice(block[0].length).includes(block[1])) open = block[1];
+      }
+    }
+    if (!comment) {
+      run = 0;
+      kept.push(line);
+    } else if (!DECORATION.test(trimmed) && ++run <= MAX_COMMENT_RUN) {
+      kept.push(line);
+    }
+  }
+  return kept.join('\n');
+}
+
+/** Drop very long lines with almost no whitespace, as bundlers emit */
+function stripMinified(content: string): string {
+  return content
+    .split('\n')
+    .filter((line) => {
+      if (line.length < MINIFIED_LINE_LENGTH) return true;
+      const whitespace = line.match(/\s/g)?.length ?? 0;
+      return whitespace / line.length >= MINIFIED_WHITESPACE_RATIO;
+    })
+    .join('\n');
+}
+
+/** Trim trailing whitespace, expand tabs, remove shared indentation, squeeze blank lines */
+function normalizeWhitespace(content: string): string {
+  const lines = content.split('\n').map((line) => line.replace(/\s+$/, '').replace(/\t/g, '  '));
+  const indents = lines.filter(Boolean).map((line) => line.length - line.trimStart().length);
+  const shared = indents.length > 0 ? Math.min(...indents) : 0;
+  const normalized: string[] = [];
+  for (const line of lines) {
+    if (!line && (normalized.length === 0 || normalized[normalized.length - 1] === '')) continue;
+    normalized.push(line.slice(shared));
+  }
+  while (normalized[normalized.length - 1] === '') normalized.pop();
+  return normalized.join('\n');
+}
+
+const PROCESSORS = new Map<string, ChunkPostProcessor>([
+  ['strip-license-header', stripLicenseHeader],
+  ['fold-comment-banners', foldCommentBanners],
+  ['strip-minified', stripMinified],
+  ['normalize-whitespace', normalizeWhitespace]
+]);
+
+export const BUILTIN_CHUNK_POST_PROCESSORS: readonly string[] = [...PROCESSORS.keys()];
+
+/** Add a processor that `postProcessing` lists can name; built-ins can't be replaced */
+export function registerChunkPostProcessor(name: string, processor: ChunkPostProcessor): void {
+  if (BUILTIN_CHUNK_POST_PROCESSORS.includes(name)) {
+    throw new Error(`'${name}' is a built-in chunk post-processor`);
+  }
+  PROCESSORS.set(name, processor);
+}
+
+function signatureOf(config: PostProcessingConfig): string {
+  const languages = Object.entries(config.languages ?? {})
+    .map(([language, names]) => [language.toLowerCase(), names] as const)
+    .sort(([a], [b]) => a.localeCompare(b));
+  const canonical = JSON.stringify({
+    processors: config.processors ?? [],
+    languages,
+    rules: (config.rules ?? []).map(({ name, pattern, flags, replace }) => [
+      name,
+      pattern,
+      flags ?? '',
+      replace ?? ''
+    ])
+  });
+  return createHash('sha256').update(canonical).digest('hex').slice(0, 16);
+}
+
+export function createChunkPostProcessorChain(
+  config: PostProcessingConfig = {}
+): ChunkPostProcessorChain {
+  const rules = new Map<string, ChunkPostProcessor>();
+  for (const rule of config.rules ?? []) {
+    if (!rule?.name || !rule.pattern) continue;
+    try {
+      const regex = new RegExp(rule.pattern, `${(rule.flags ?? '').replace(/[gy]/g, '')}g`);
+      const replacement = rule.replace ?? '';
+      rules.set(rule.name, (content) => content.replace(regex, replacement));
+    } catch {
+      console.error(`[post-processing] Ignoring invalid rule pattern: ${rule.pattern}`);
+    }
+  }
+
+  const resolve = (names: unknown): Array<[string, ChunkPostProcessor]> => {
+    if (!Array.isArray(names)) return [];
+    const chain: Array<[string, ChunkPostProcessor]> = [];
+    for (const name of names) {
+      const processor =
+        typeof name === 'string' ? (rules.get(name) ?? PROCESSORS.get(name)) : undefined;
+      if (processor) chain.push([String(name), processor]);
+      else console.error(`[post-processing] Ignoring unknown processor: ${String(name)}`);
+    }
+    return chain;
+  };
+  const defaults = resolve(config.processors);
+  const perLanguage = new Map(
+    Object.entries(config.languages ?? {}).map(([language, names]) => [
+      language.toLowerCase(),
+      resolve(names)
+    ])
+  );
+  const active = defaults.length > 0 || [...perLanguage.values()].some((chain) => chain.length);
+  const failed = new Set<string>();
+
+  return {
+    signature: active ? signatureOf(config) : undefined,
+    apply(content, context) {
+      const chain = perLanguage.get(context.language.toLowerCase()) ?? defaults;
+      let text = content;
+      for (const [name, processor] of chain) {
+        try {
+          text = processor(text, context);
+        } catch (error) {
+          // A broken plugin leaves the text as it was rather than failing the build
+          if (!failed.has(name)) {
+            failed.add(name);
+            console.error(
+              `[post-processing] '${name}' failed and was skipped: ` +
+                (error instanceof Error ? error.message : String(error))
+            );
+          }
+        }
+      }
+      return text;
+    }
+  };
+}
+
+/**
+ * Post-processing settings saved with the project, from the `postProcessing` key of
+ * `.codebase-context/config.json`. Returns undefined when the file or key is missing.
+ */
+export async function loadProjectPostProcessingConfig(
+  rootPath: string
+): Promise<PostProcessingConfig | undefined> {
+  const configPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME);
+  try {
+    const parsed = JSON.parse(await fs.readFile(configPath, 'utf-8')) as {
+      postProcessing?: unknown;
+    };
+    const postProcessing = parsed.postProcessing;
+    return postProcessing && typeof postProcessing === 'object'
+      ? (postProcessing as PostProcessingConfig)
+      : undefined;
+  } catch {
+    return undefined;
+  }
+}
+
+/** The project's chain, for building embedding inputs the way the indexer does */
+export async function loadChunkPostProcessors(
+  rootPath: string,
+  config?: PostProcessingConfig
+): Promise<ChunkPostProcessorChain> {
+  return createChunkPostProcessorChain(config ?? (await loadProjectPostProcessingConfig(rootPath)));
+}
+
+/** Why vectors in the index came from other embedding inputs; null when they didn't */
+export function describePostProcessingDrift(
+  built: EmbeddingFingerprint | undefined,
+  signature: string | undefined
+): string | null {
+  if (!built || built.postProcessing === signature) return null;
+  return 'Chunk post-processing changed, so embedding inputs differ (rebuild required)';
+}
@@ -0,0 +1,89 @@
+/**
+ * Per-language chunk sizing from the `chunking` key of `.codebase-context/config.json`, laid
+ * over the `chunking` section of `codebase-context.yaml` (see config-file.ts), if any.
+ *
+ * Top-level fields apply to every language and `languages.<id>` overrides them, e.g.
+ * `{ "maxLines": 120, "languages": { "go": { "maxLines": 80 } } }`. Language ids are the ones chunks carry (`typescript`, `go`, `python`, ...).
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  PROJECT_CONFIG_FILENAME
+} from '../constants/codebase-context.js';
+import { loadProjectConfigSettings } from './config-file.js';
+import type { ChunkingConfig, LanguageChunkingOptions } from '../types/index.js';
+
+const COUNT_FIELDS = ['maxTokens', 'maxLines', 'minLines', 'overlapLines'] as const;
+
+/** The valid fields of one options object: counts are whole numbers, overlap may be 0 */
+function sanitizeOptions(value: unknown): LanguageChunkingOptions {
+  if (!value || typeof value !== 'object') return {};
+  const raw = value as Record<string, unknown>;
+  const options: LanguageChunkingOptions = {};
+  for (const field of COUNT_FIELDS) {
+    const count = raw[field];
+    const min = field === 'overlapLines' || field === 'minLines' ? 0 : 1;
+    if (typeof count === 'number' && Number.isInteger(count) && count >= min) {
+      options[field] = count;
+    }
+  }
+  if (typeof raw.mergeSmall === 'boolean') options.mergeSmall = raw.mergeSmall;
+  return options;
+}
+
+export function sanitizeChunkingConfig(value: unknown): ChunkingConfig {
+  const config: ChunkingConfig = sanitizeOptions(value);
+  const languages = (value as { languages?: unknown } | undefined)?.languages;
+  if (languages && typeof languages === 'object') {
+    config.languages = Object.fromEntries(
+      Object.entries(languages).map(([language, options]) => [
+        language.toLowerCase(),
+        sanitizeOptions(options)
+      ])
+    );
+  }
+  return config;
+}
+
+/** Chunking for one language: its overrides on top of the project-wide fields */
+export function resolveLanguageChunking(
+  config: ChunkingConfig | undefined,
+  language: string
+): LanguageChunkingOptions {
+  if (!config) return {};
+  const { languages, ...defaults } = config;
+  return { ...defaults, ...languages?.[language.toLowerCase()] };
+}
+
+/** config.json fields over the YAML file's, language by language */
+function layerChunkingConfigs(base: ChunkingConfig, over: ChunkingConfig): ChunkingConfig {
+  const languages = { ...base.languages };
+  for (const [language, options] of Object.entries(over.languages ?? {})) {
+    languages[language] = { ...languages[language], ...options };
+  }
+  return { ...base, ...over, ...(Object.keys(languages).length > 0 ? { languages } : {}) };
+}
+
+/**
+ * The `chunking` key of `.codebase-context/config.json` over the `chunking` section of
+ * `codebase-context.yaml`; undefined when neither has one
+ */
+export async function loadProjectChunkingConfig(
+  rootPath: string
+): Promise<ChunkingConfig | undefined> {
+  const fromYaml = loadProjectConfigSettings(rootPath)?.chunking;
+  let fromJson: ChunkingConfig | undefined;
+  const configPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME);
+  try {
+    const parsed = JSON.parse(await fs.readFile(configPath, 'utf-8')) as { chunking?: unknown };
+    if (parsed.chunking && typeof parsed.chunking === 'object') {
+      fromJson = sanitizeChunkingConfig(parsed.chunking);
+    }
+  } catch {
+    // no config.json
+  }
+  if (!fromYaml) return fromJson;
+  return layerChunkingConfigs(sanitizeChunkingConfig(fromYaml), fromJson ?? {});
+}
@@ -0,0 +1,134 @@
+/**
+ * Citations for retrieved code: a stable id per cited line range, and a source map entry
+ * locating it (path, lines, commit, link to the code host), so an answer built from the
+ * chunks can cite them and a reader or client can check each citation.
+ *
+ * Ids hash the path, the lines and their text: the same range cites the same way across
+ * searches and re-indexes until its code changes. Commits and links are read from the local
+ * git repository; nothing is sent anywhere.
+ */
+
+import { createHash } from 'crypto';
+import { readGitFileAtRef, readGitPathPrefix, readGitRemoteUrl } from '../utils/git-tree.js';
+
+/** Link template with `{commit}`, `{path}`, `{start}` and `{end}`, for self-hosted code hosts */
+export const CITATION_URL_ENV = 'CODEBASE_CONTEXT_CITATION_URL';
+
+export interface CitedRange {
+  /** Repo-relative posix path */
+  file: string;
+  startLine: number;
+  endLine: number;
+  /** The cited lines, as returned to the caller */
+  content: string;
+}
+
+export interface CitationSource {
+  id: string;
+  path: string;
+  startLine: number;
+  endLine: number;
+  /** Commit the index was built from; null outside a git repository */
+  commit: string | null;
+  /** The cited lines read the same at `commit`; false for uncommitted or redacted lines */
+  matchesCommit: boolean;
+  /** The lines at `commit` on the code host, when the remote is a known host */
+  url?: string;
+}
+
+function normalizeLines(text: string): string[] {
+  return text.replace(/\r\n?/g, '\n').split('\n');
+}
+
+/** `c` + 10 hex of SHA-256 over path, lines and text */
+export function citationId(range: CitedRange): string {
+  const hash = createHash('sha256')
+    .update(`${range.file}\0${range.startLine}\0${range.endLine}\0`)
+    .update(normalizeLines(range.content).join('\n'))
+    .digest('hex');
+  return `c${hash.slice(0, 10)}`;
+}
+
+/**
+ * Link template for a git remote on GitHub, GitLab or Bitbucket (by host name, so GitHub
+ * Enterprise and self-managed GitLab count); null for other hosts. Credentials in the remote
+ * URL are dropped.
+ */
+export function linkTemplateForRemote(remote: string): string | null {
+  const match =
+    /^(?:[a-z+]+:\/\/)?(?:[^@/]+@)?([^/:]+)(?::\d+)?[:/](.+?)(?:\.git)?\/?$/i.exec(remote.trim());
+  if (!match) return null;
+  const [, host, repo] = match;
+  const base = `https://${host}/${repo.replace(/^\/+/, '')}`;
+  if (/github/i.test(host)) return `${base}/blob/{commit}/{path}#L{start}-L{end}`;
+  if (/gitlab/i.test(host)) return `${base}/-/blob/{commit}/{path}#L{start}-{end}`;
+  if (/bitbucket/i.test(host)) return `${base}/src/{commit}/{path}#lines-{start}:{end}`;
+  return null;
+}
+
+function fillTemplate(template: string, commit: string, file: string, range: CitedRange): string {
+  const encodedPath = file.split('/').map(encodeURIComponent).join('/');
+  return template
+    .replace(/\{commit\}/g, commit)
+    .replace(/\{path\}/g, encodedPath)
+    .replace(/\{start\}/g, String(range.startLine))
+    .replace(/\{end\}/g, String(range.endLine));
+}
+
+/** Whether `content` is lines startLine..endLine of `file` */
+function sameLines(file: string, range: CitedRange): boolean {
+  const expected = normalizeLines(file).slice(range.startLine - 1, range.endLine);
+  const actual = normalizeLines(range.content);
+  return (
+    expected.length === actual.length &&
+    expected.every((line, i) => line.trimEnd() === actual[i].trimEnd())
+  );
+}
+
+/**
+ * Source map entries for `ranges`, in order. Each range is checked against `commit` so a
+ * citation only carries a link when the lines it links to are the lines that were cited.
+ */
+export async function citeRanges(
+  rootPath: string,
+  ranges: readonly CitedRange[],
+  commit: string | null
+): Promise<CitationSource[]> {
+  const committed = new Map<string, Promise<string | null>>();
+  let template: string | null = process.env[CITATION_URL_ENV]?.trim() || null;
+  let prefix = '';
+  if (commit) {
+    if (!template) {
+      const remote = await readGitRemoteUrl(rootPath);
+      template = remote ? linkTemplateForRemote(remote) : null;
+    }
+    // Code hosts link from the repository root, which needn't be the indexed root
+    if (template) prefix = await readGitPathPrefix(rootPath);
+  }
+
+  return Promise.all(
+    ranges.map(async (range): Promise<CitationSource> => {
+      let matchesCommit = false;
+      if (commit) {
+        let content = committed.get(range.file);
+        if (!content) {
+          content = readGitFileAtRef(rootPath, commit, `./${range.file}`);
+          committed.set(range.file, content);
+        }
+        const atCommit = await content;
+        matchesCommit = atCommit !== null && sameLines(atCommit, range);
+      }
+      return {
+        id: citationId(range),
+        path: range.file,
+        startLine: range.startLine,
+        endLine: range.endLine,
+        commit,
+        matchesCommit,
+        ...(commit && matchesCommit && template
+          ? { url: fillTemplate(template, commit, `${prefix}${range.file}`, range) }
+          : {})
+      };
+    })
+  );
+}
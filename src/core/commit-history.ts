@@ -0,0 +1,468 @@
+/**
+ * Commit history index for `search_history`.
+ *
+ * Each non-merge commit contributes one document for its message and one per diff hunk.
+ * Documents are ranked with BM25 and, when an embedding provider is available, cosine
+ * similarity, fused with RRF and grouped back into commits. The index is stored under
+ * `.codebase-context/` and updated incrementally: only commits not seen before are read
+ * and embedded. Messages and hunks pass through secret redaction before they are stored, and
+ * the file is sealed with the index key when encryption at rest is on.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import { BM25Index, type BM25Document } from './bm25.js';
+import { CODEBASE_CONTEXT_DIRNAME, HISTORY_FILENAME } from '../constants/codebase-context.js';
+import {
+  DEFAULT_EMBEDDING_CONFIG,
+  embedInBatches,
+  getEmbeddingProvider,
+  type EmbeddingProvider
+} from '../embeddings/index.js';
+import { listRecentCommits, readCommitRecords } from '../utils/git-tree.js';
+import { rrfContribution } from '../utils/rrf.js';
+import { redactSecrets, resolveRedactionOptions } from '../utils/secret-redaction.js';
+import { loadComplianceFilter } from './compliance-filters.js';
+import {
+  decodeArtifact,
+  encodeArtifact,
+  isSealedText,
+  resolveIndexKey
+} from './index-encryption.js';
+
+const HISTORY_VERSION = 1;
+
+export const DEFAULT_HISTORY_MAX_COMMITS = 300;
+
+/** Hunks kept per commit; the rest of a large change is summarised by its file list */
+const MAX_HUNKS_PER_COMMIT = 10;
+/** Diff lines kept per stored hunk */
+const MAX_HUNK_LINES = 40;
+/** Diff lines returned per hunk in search results */
+const MAX_RESULT_HUNK_LINES = 12;
+const MAX_RESULT_MESSAGE_LENGTH = 500;
+const MAX_RESULT_FILES = 10;
+const CANDIDATES_PER_CHANNEL = 200;
+
+/** Generated files whose hunks only add noise */
+const SKIPPED_FILENAMES = new Set([
+  'package-lock.json',
+  'pnpm-lock.yaml',
+  'yarn.lock',
+  'Cargo.lock',
+  'go.sum',
+  'poetry.lock',
+  'composer.lock'
+]);
+const MINIFIED_FILE_PATTERN = /\.min\.(js|css)$/;
+
+export interface HistoryHunk {
+  file: string;
+  /** `@@ -a,b +c,d @@ context` line */
+  header: string;
+  diff: string;
+  embedding?: number[];
+}
+
+export interface HistoryCommit {
+  sha: string;
+  author: string;
+  email: string;
+  date: string;
+  message: string;
+  files: string[];
+  hunks: HistoryHunk[];
+  embedding?: number[];
+}
+
+export interface HistoryIndex {
+  version: number;
+  /** `<provider>:<model>` the stored vectors were computed with */
+  embeddingModel?: string;
+  commits: HistoryCommit[];
+}
+
+export interface HistoryIndexOptions {
+  maxCommits?: number;
+  /** Keyword-only: skip embedding new commits and the query */
+  skipEmbedding?: boolean;
+  /** Save the updated history index (default: true; off on read-only servers) */
+  persist?: boolean;
+}
+
+export interface HistorySearchOptions extends HistoryIndexOptions {
+  limit?: number;
+  /** Case-insensitive substring of the author name or email */
+  author?: string;
+  /** ISO date; only commits authored at or after it */
+  since?: string;
+  /** ISO date; only commits authored at or before it */
+  until?: string;
+  /** Repo-relative file or directory the commit must touch */
+  path?: string;
+  /** Hunks returned per commit */
+  maxHunks?: number;
+}
+
+export interface HistorySearchResult {
+  sha: string;
+  author: string;
+  date: string;
+  message: string;
+  filesChanged: number;
+  files: string[];
+  hunks: Array<{ file: string; header: string; diff: string }>;
+}
+
+export interface HistorySearchResponse {
+  mode: 'hybrid' | 'keyword';
+  indexedCommits: number;
+  results: HistorySearchResult[];
+}
+
+/** Search unit: id is "<commit>" for a message, "<commit>:<hunk>" for a hunk */
+interface HistoryDocument extends BM25Document {
+  commit: number;
+  hunk?: number;
+  vector?: number[];
+}
+
+export function resolveHistoryMaxCommits(env: NodeJS.ProcessEnv = process.env): number {
+  const value = Number.parseInt(env.CODEBASE_CONTEXT_HISTORY_MAX_COMMITS ?? '', 10);
+  return Number.isFinite(value) && value > 0 ? value : DEFAULT_HISTORY_MAX_COMMITS;
+}
+
+/** Split a commit's unified diff into touched files and per-hunk text. */
+export function parseCommitPatch(patch: string): { files: string[]; hunks: HistoryHunk[] } {
+  const files: string[] = [];
+  const hunks: HistoryHunk[] = [];
+  let file: string | null = null;
+  let hunk: { file: string; header: string; lines: string[]; truncated: boolean } | null = null;
+
+  const flush = () => {
+    if (hunk && hunks.length < MAX_HUNKS_PER_COMMIT) {
+      const diff = hunk.truncated ? [...hunk.lines, '…'] : hunk.lines;
+      hunks.push({ file: hunk.file, header: hunk.header, diff: diff.join('\n') });
+    }
+    hunk = null;
+  };
+
+  for (const line of patch.split('\n')) {
+    const fileHeader = line.match(/^diff --git a\/(.+) b\/(.+)$/);
+    if (fileHeader) {
+      flush();
+      file = fileHeader[2];
+      files.push(file);
+      continue;
+    }
+    if (line.startsWith('@@')) {
+      flush();
+      if (file && !isSkippedFile(file)) {
+        hunk = { file, header: line, lines: [], truncated: false };
+      }
+      continue;
+    }
+    if (!hunk || !/^[ +\-\\]/.test(line)) continue;
+    if (hunk.lines.length < MAX_HUNK_LINES) hunk.lines.push(line);
+    else hunk.truncated = true;
+  }
+  flush();
+
+  return { files, hunks };
+}
+
+function isSkippedFile(file: string): boolean {
+  return SKIPPED_FILENAMES.has(path.posix.basename(file)) || MINIFIED_FILE_PATTERN.test(file);
+}
+
+function commitText(commit: HistoryCommit): string {
+  return `${commit.message}\n${commit.files.join('\n')}`;
+}
+
+function hunkText(commit: HistoryCommit, hunk: HistoryHunk): string {
+  return `${commit.message.split('\n')[0]}\n${hunk.file}\n${hunk.diff}`;
+}
+
+function cosineSimilarity(a: number[], b: number[]): number {
+  if (a.length !== b.length) return 0;
+  let dot = 0;
+  let normA = 0;
+  let normB = 0;
+  for (let i = 0; i < a.length; i++) {
+    dot += a[i] * b[i];
+    normA += a[i] * a[i];
+    normB += b[i] * b[i];
+  }
+  const denom = Math.sqrt(normA) * Math.sqrt(normB);
+  return denom === 0 ? 0 : dot / denom;
+}
+
+/** The stored index, and whether the file was sealed; null when missing or unreadable */
+async function loadHistoryIndex(
+  file: string
+): Promise<{ index: HistoryIndex; sealed: boolean } | null> {
+  try {
+    const raw = await fs.readFile(file, 'utf-8');
+    const parsed = (await decodeArtifact(raw, 'The history index')) as Partial<HistoryIndex>;
+    if (parsed.version !== HISTORY_VERSION || !Array.isArray(parsed.commits)) return null;
+    return { index: parsed as HistoryIndex, sealed: isSealedText(raw) };
+  } catch {
+    return null;
+  }
+}
+
+function modelKey(provider: EmbeddingProvider): string {
+  return `${provider.name}:${provider.modelName}`;
+}
+
+async function loadEmbeddingProvider(skip?: boolean): Promise<EmbeddingProvider | null> {
+  if (skip) return null;
+  try {
+    return await getEmbeddingProvider();
+  } catch (error) {
+    console.error(
+      '[history] Embeddings unavailable, using keyword search:',
+      error instanceof Error ? error.message : error
+    );
+    return null;
+  }
+}
+
+/** Embed every commit message and hunk that has no vector yet; failures leave them unset. */
+async function embedMissing(
+  commits: HistoryCommit[],
+  provider: EmbeddingProvider
+): Promise<number> {
+  const targets: Array<{ text: string; assign: (vector: number[]) => void }> = [];
+  for (const commit of commits) {
+    if (!commit.embedding) {
+      targets.push({ text: commitText(commit), assign: (v) => (commit.embedding = v) });
+    }
+    for (const hunk of commit.hunks) {
+      if (!hunk.embedding) {
+        targets.push({ text: hunkText(commit, hunk), assign: (v) => (hunk.embedding = v) });
+      }
+    }
+  }
+  if (targets.length === 0) return 0;
+
+  let embedded = 0;
+  try {
+    await embedInBatches(
+      provider,
+      targets.map((target) => target.text),
+      {
+        batchSize: DEFAULT_EMBEDDING_CONFIG.batchSize ?? 32,
+        concurrency: DEFAULT_EMBEDDING_CONFIG.concurrency ?? 1,
+        maxRetries: DEFAULT_EMBEDDING_CONFIG.maxRetries ?? 3,
+        onBatch: (offset, vectors) => {
+          vectors.forEach((vector, i) => targets[offset + i].assign(vector));
+          embedded += vectors.length;
+        }
+      }
+    );
+  } catch (error) {
+    console.error(
+      `[history] Embedded ${embedded}/${targets.length} history documents before failing:`,
+      error instanceof Error ? error.message : error
+    );
+  }
+  return embedded;
+}
+
+async function refreshHistoryIndex(
+  rootPath: string,
+  options: HistoryIndexOptions,
+  provider: EmbeddingProvider | null
+): Promise<HistoryIndex> {
+  const file = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, HISTORY_FILENAME);
+  const shas = await listRecentCommits(rootPath, options.maxCommits ?? resolveHistoryMaxCommits());
+  const loaded = await loadHistoryIndex(file);
+  const previous = loaded?.index;
+  const model = provider ? modelKey(provider) : undefined;
+  // A plaintext file from before encryption was turned on is rewritten sealed
+  let changed = !loaded || (!loaded.sealed && (await resolveIndexKey()) !== null);
+
+  const known = new Map((previous?.commits ?? []).map((commit) => [commit.sha, commit]));
+  if (model && previous?.embeddingModel && previous.embeddingModel !== model) {
+    // Vectors from another model are not comparable with this one's query vectors
+    for (const commit of known.values()) {
+      delete commit.embedding;
+      for (const hunk of commit.hunks) delete hunk.embedding;
+    }
+    changed = true;
+  }
+
+  const redaction = resolveRedactionOptions();
+  const compliance = await loadComplianceFilter(rootPath);
+  const scrub = (text: string) => compliance.mask(redactSecrets(text, redaction).text).text;
+  const missing = shas.filter((sha) => !known.has(sha));
+  for (const record of await readCommitRecords(rootPath, missing)) {
+    const { files, hunks } = parseCommitPatch(record.patch);
+    known.set(record.sha, {
+      sha: record.sha,
+      author: record.author,
+      email: record.email,
+      date: record.date,
+      message: scrub(record.message),
+      files,
+      hunks: hunks.map((hunk) => ({ ...hunk, diff: scrub(hunk.diff) }))
+    });
+    changed = true;
+  }
+
+  const commits = shas.flatMap((sha) => known.get(sha) ?? []);
+  if (commits.length !== (previous?.commits.length ?? 0)) changed = true;
+  if (provider && (await embedMissing(commits, provider)) > 0) changed = true;
+
+  const embeddingModel = model ?? previous?.embeddingModel;
+  const index: HistoryIndex = {
+    version: HISTORY_VERSION,
+    ...(embeddingModel ? { embeddingModel } : {}),
+    commits
+  };
+  if (changed && options.persist !== false) {
+    await fs.mkdir(path.dirname(file), { recursive: true });
+    const tmp = `${file}.${process.pid}.tmp`;
+    await fs.writeFile(tmp, await encodeArtifact(index));
+    await fs.rename(tmp, file);
+  }
+  return index;
+}
+
+/** Bring the history index up to date with HEAD. Throws outside a git repository. */
+export async function updateHistoryIndex(
+  rootPath: string,
+  options: HistoryIndexOptions = {}
+): Promise<HistoryIndex> {
+  return refreshHistoryIndex(rootPath, options, await loadEmbeddingProvider(options.skipEmbedding));
+}
+
+function touchesPath(file: string, filter: string): boolean {
+  const prefix = filter.replace(/\\/g, '/').replace(/^\.\//, '').replace(/\/+$/, '');
+  return file === prefix || file.startsWith(`${prefix}/`);
+}
+
+function trimHunk(diff: string): string {
+  const lines = diff.split('\n');
+  return lines.length > MAX_RESULT_HUNK_LINES
+    ? [...lines.slice(0, MAX_RESULT_HUNK_LINES), '…'].join('\n')
+    : diff;
+}
+
+/** Rank commits for `query`, returning their metadata and best matching hunks. */
+export async function searchHistory(
+  rootPath: string,
+  query: string,
+  options: HistorySearchOptions = {}
+): Promise<HistorySearchResponse> {
+  const provider = await loadEmbeddingProvider(options.skipEmbedding);
+  const index = await refreshHistoryIndex(rootPath, options, provider);
+  const since = options.since ? Date.parse(options.since) : undefined;
+  const until = options.until ? Date.parse(options.until) : undefined;
+  const author = options.author?.toLowerCase();
+
+  const candidates = index.commits.filter((commit) => {
+    const date = Date.parse(commit.date);
+    if (since !== undefined && date < since) return false;
+    if (until !== undefined && date > until) return false;
+    if (
+      author &&
+      !commit.author.toLowerCase().includes(author) &&
+      !commit.email.toLowerCase().includes(author)
+    ) {
+      return false;
+    }
+    return !options.path || commit.files.some((file) => touchesPath(file, options.path!));
+  });
+
+  const documents: HistoryDocument[] = [];
+  candidates.forEach((commit, c) => {
+    documents.push({ id: `${c}`, text: commitText(commit), commit: c, vector: commit.embedding });
+    commit.hunks.forEach((hunk, h) => {
+      if (options.path && !touchesPath(hunk.file, options.path)) return;
+      documents.push({
+        id: `${c}:${h}`,
+        text: hunkText(commit, hunk),
+        commit: c,
+        hunk: h,
+        vector: hunk.embedding
+      });
+    });
+  });
+
+  const rankings: string[][] = [
+    new BM25Index(documents).search(query, CANDIDATES_PER_CHANNEL).map((hit) => hit.id)
+  ];
+  let mode: HistorySearchResponse['mode'] = 'keyword';
+  if (provider && index.embeddingModel === modelKey(provider) && documents.some((d) => d.vector)) {
+    try {
+      const queryVector = await provider.embed(query);
+      rankings.push(
+        documents
+          .filter((doc) => doc.vector)
+          .map((doc) => ({ id: doc.id, score: cosineSimilarity(queryVector, doc.vector!) }))
+          .sort((a, b) => b.score - a.score)
+          .slice(0, CANDIDATES_PER_CHANNEL)
+          .map((hit) => hit.id)
+      );
+      mode = 'hybrid';
+    } catch (error) {
+      console.error(
+        '[history] Query embedding failed:',
+        error instanceof Error ? error.message : error
+      );
+    }
+  }
+
+  const docScores = new Map<string, number>();
+  for (const ranking of rankings) {
+    ranking.forEach((id, rank) => {
+      docScores.set(id, (docScores.get(id) ?? 0) + rrfContribution(rank));
+    });
+  }
+
+  const byId = new Map(documents.map((doc) => [doc.id, doc]));
+  const commitScores = new Map<number, number>();
+  const commitHunks = new Map<number, Array<{ hunk: number; score: number }>>();
+  for (const [id, score] of docScores) {
+    const doc = byId.get(id)!;
+    commitScores.set(doc.commit, Math.max(commitScores.get(doc.commit) ?? 0, score));
+    if (doc.hunk !== undefined) {
+      const list = commitHunks.get(doc.commit) ?? [];
+      list.push({ hunk: doc.hunk, score });
+      commitHunks.set(doc.commit, list);
+    }
+  }
+
+  const limit = options.limit ?? 5;
+  const maxHunks = options.maxHunks ?? 2;
+  const results = [...commitScores.entries()]
+    .sort((a, b) => b[1] - a[1])
+    .slice(0, limit)
+    .map(([c]) => {
+      const commit = candidates[c];
+      const hunks = (commitHunks.get(c) ?? [])
+        .sort((a, b) => b.score - a.score)
+        .slice(0, maxHunks)
+        .map(({ hunk }) => commit.hunks[hunk]);
+      return {
+        sha: commit.sha,
+        author: commit.author,
+        date: commit.date,
+        message:
+          commit.message.length > MAX_RESULT_MESSAGE_LENGTH
+            ? `${commit.message.slice(0, MAX_RESULT_MESSAGE_LENGTH)}…`
+            : commit.message,
+        filesChanged: commit.files.length,
+        files: commit.files.slice(0, MAX_RESULT_FILES),
+        hunks: hunks.map((hunk) => ({
+          file: hunk.file,
+          header: hunk.header,
+          diff: trimHunk(hunk.diff)
+        }))
+      };
+    });
+
+  return { mode, indexedCommits: index.commits.length, results };
+}
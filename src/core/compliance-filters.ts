@@ -0,0 +1,284 @@
+/**
+ * Compliance filters: rules for personal data and other content that must not be indexed,
+ * applied on top of secret redaction. Set under the `compliance` key of
+ * `.codebase-context/config.json` (or the indexer config):
+ *
+ * - `excludePaths` (.gitignore syntax) leaves files out of the index, and responses drop them
+ *   like `security.denyPaths`.
+ * - `detectors` turns on built-in matchers: `email`, `us-ssn`, `uk-nino`, `credit-card` (Luhn
+ *   checked) and `iban` (checksum verified).
+ * - `rules` adds named regexes; `action: "mask"` (default) masks matches, `"exclude"` leaves out
+ *   every file with a match, such as customer data fixtures.
+ * - `allowlist` regexes keep matching values (`@example\\.com$`).
+ *
+ * Masks read `[FILTERED:<rule>]` and never add or remove lines. Each build appends what it
+ * filtered (file, rule, action and count, never the matched text) to
+ * `.codebase-context/compliance-audit.jsonl` unless `audit` is false.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import ignore from 'ignore';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  COMPLIANCE_AUDIT_FILENAME,
+  PROJECT_CONFIG_FILENAME
+} from '../constants/codebase-context.js';
+import type { CodebaseConfig } from '../types/index.js';
+
+export type ComplianceConfig = NonNullable<CodebaseConfig['compliance']>;
+
+export type ComplianceAction = 'masked' | 'excluded';
+
+export interface ComplianceAuditEntry {
+  at: string;
+  file: string;
+  /** Detector or rule name; `excludePaths` for files left out by path */
+  rule: string;
+  action: ComplianceAction;
+  /** Matches masked, or the matches that got the file excluded */
+  matches: number;
+}
+
+export interface ComplianceScan {
+  text: string;
+  /** Matches masked per rule */
+  matches: Record<string, number>;
+}
+
+export interface ComplianceFilter {
+  /** Any rule is configured */
+  readonly active: boolean;
+  readonly audit: boolean;
+  /** The path is under `excludePaths` (repo-relative, posix) */
+  excludesPath(relativeFile: string): boolean;
+  /** An `exclude` rule matching the content, with its match count; null when none does */
+  excludingRule(content: string): { rule: string; matches: number } | null;
+  /** Mask every `mask` rule and detector match */
+  mask(text: string): ComplianceScan;
+}
+
+interface CompiledRule {
+  name: string;
+  regex: RegExp;
+  action: 'mask' | 'exclude';
+  /** Extra check on the match, for checksummed numbers */
+  accept?: (value: string) => boolean;
+}
+
+/** Entries kept in the audit log; it is cut back to this once it grows a fifth past it */
+const MAX_AUDIT_ENTRIES = 10000;
+
+function digits(value: string): string {
+  return value.replace(/\D/g, '');
+}
+
+/** Luhn checksum, as on payment cards */
+export function passesLuhn(value: string): boolean {
+  const number = digits(value);
+  if (number.length < 13 || number.length > 19) return false;
+  let sum = 0;
+  for (let i = 0; i < number.length; i++) {
+    let digit = Number(number[number.length - 1 - i]);
+    if (i % 2 === 1) {
+      digit *= 2;
+      if (digit > 9) digit -= 9;
+    }
+    sum += digit;
+  }
+  return sum % 10 === 0;
+}
+
+/** ISO 13616 mod-97 check */
+export function isValidIban(value: string): boolean {
+  const iban = value.replace(/\s/g, '').toUpperCase();
+  if (!/^[A-Z]{2}\d{2}[A-Z0-9]{11,30}$/.test(iban)) return false;
+  const rearranged = iban.slice(4) + iban.slice(0, 4);
+  let remainder = 0;
+  for (const ch of rearranged) {
+    const code = /\d/.test(ch) ? ch : String(ch.charCodeAt(0) - 55);
+    for (const digit of code) remainder = (remainder * 10 + Number(digit)) % 97;
+  }
+  return remainder === 1;
+}
+
+const DETECTORS: Record<string, Omit<CompiledRule, 'name' | 'action'>> = {
+  email: { regex: /\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b/g },
+  // Area 000, 666 and 9xx, group 00 and serial 0000 are never issued
+  'us-ssn': { regex: /\b(?!000|666|9\d\d)\d{3}-(?!00)\d{2}-(?!0000)\d{4}\b/g },
+  'uk-nino': {
+    regex: new RegExp(
+      '\\b(?!BG|GB|KN|NK|NT|TN|ZZ)[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z]' +
+        ' ?\\d{2} ?\\d{2} ?\\d{2} ?[A-D]\\b',
+      'g'
+    )
+  },
+  'credit-card': { regex: /\b\d(?:[ -]?\d){12,18}\b/g, accept: passesLuhn },
+  iban: {
+    regex: /\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,4})?\b/g,
+    accept: isValidIban
+  }
+};
+
+export const COMPLIANCE_DETECTORS = Object.keys(DETECTORS);
+
+function compileRegexes(entries: readonly string[] = []): RegExp[] {
+  const compiled: RegExp[] = [];
+  for (const entry of entries) {
+    try {
+      compiled.push(new RegExp(entry));
+    } catch {
+      console.error(`[compliance] Ignoring invalid allowlist pattern: ${entry}`);
+    }
+  }
+  return compiled;
+}
+
+function compileRules(config: ComplianceConfig): CompiledRule[] {
+  const rules: CompiledRule[] = [];
+  for (const name of config.detectors ?? []) {
+    const detector = DETECTORS[name];
+    if (detector) rules.push({ name, action: 'mask', ...detector });
+    else console.error(`[compliance] Ignoring unknown detector: ${name}`);
+  }
+  for (const entry of config.rules ?? []) {
+    if (!entry?.name || !entry.pattern) continue;
+    try {
+      const flags = `${(entry.flags ?? '').replace(/[gy]/g, '')}g`;
+      rules.push({
+        name: entry.name,
+        regex: new RegExp(entry.pattern, flags),
+        action: entry.action === 'exclude' ? 'exclude' : 'mask'
+      });
+    } catch {
+      console.error(`[compliance] Ignoring invalid rule pattern: ${entry.pattern}`);
+    }
+  }
+  return rules;
+}
+
+/** The mask for a match, keeping its line breaks so line numbers stay valid */
+function marker(rule: string, match: string): string {
+  return `[FILTERED:${rule}]${match.replace(/[^\n]/g, '')}`;
+}
+
+export function createComplianceFilter(config: ComplianceConfig = {}): ComplianceFilter {
+  const excludePaths = (config.excludePaths ?? []).filter(
+    (entry) => typeof entry === 'string' && entry.trim()
+  );
+  const paths = excludePaths.length > 0 ? ignore.default().add(excludePaths) : null;
+  const rules = compileRules(config);
+  const allowlist = compileRegexes(config.allowlist);
+  const allowed = (value: string) => allowlist.some((re) => re.test(value));
+
+  const matchesOf = (rule: CompiledRule, text: string): string[] => {
+    rule.regex.lastIndex = 0;
+    return [...text.matchAll(rule.regex)]
+      .map((match) => match[0])
+      .filter((value) => value && (!rule.accept || rule.accept(value)) && !allowed(value));
+  };
+
+  return {
+    active: paths !== null || rules.length > 0,
+    audit: config.audit !== false,
+    excludesPath(relativeFile) {
+      return paths !== null && paths.ignores(relativeFile);
+    },
+    excludingRule(content) {
+      for (const rule of rules) {
+        if (rule.action !== 'exclude') continue;
+        const found = matchesOf(rule, content);
+        if (found.length > 0) return { rule: rule.name, matches: found.length };
+      }
+      return null;
+    },
+    mask(text) {
+      let result = text;
+      const matches: Record<string, number> = {};
+      for (const rule of rules) {
+        if (rule.action !== 'mask' || !text) continue;
+        rule.regex.lastIndex = 0;
+        result = result.replace(rule.regex, (match: string) => {
+          if (match.startsWith('[FILTERED:') || (rule.accept && !rule.accept(match))) return match;
+          if (allowed(match)) return match;
+          matches[rule.name] = (matches[rule.name] ?? 0) + 1;
+          return marker(rule.name, match);
+        });
+      }
+      return { text: result, matches };
+    }
+  };
+}
+
+/**
+ * Compliance settings saved with the project, from the `compliance` key of
+ * `.codebase-context/config.json`. Returns undefined when the file or key is missing.
+ */
+export async function loadProjectComplianceConfig(
+  rootPath: string
+): Promise<ComplianceConfig | undefined> {
+  const configPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME);
+  try {
+    const parsed = JSON.parse(await fs.readFile(configPath, 'utf-8')) as { compliance?: unknown };
+    const compliance = parsed.compliance;
+    return compliance && typeof compliance === 'object'
+      ? (compliance as ComplianceConfig)
+      : undefined;
+  } catch {
+    return undefined;
+  }
+}
+
+/** The project's filter, for masking content read from disk before it is returned */
+export async function loadComplianceFilter(
+  rootPath: string,
+  config?: ComplianceConfig
+): Promise<ComplianceFilter> {
+  return createComplianceFilter(config ?? (await loadProjectComplianceConfig(rootPath)));
+}
+
+async function writeAtomically(file: string, content: string): Promise<void> {
+  const tmp = `${file}.${process.pid}.tmp`;
+  await fs.writeFile(tmp, content);
+  await fs.rename(tmp, file);
+}
+
+export async function readComplianceAudit(contextDir: string): Promise<ComplianceAuditEntry[]> {
+  let content: string;
+  try {
+    content = await fs.readFile(path.join(contextDir, COMPLIANCE_AUDIT_FILENAME), 'utf-8');
+  } catch {
+    return [];
+  }
+  const entries: ComplianceAuditEntry[] = [];
+  for (const line of content.split('\n')) {
+    if (!line.trim()) continue;
+    try {
+      entries.push(JSON.parse(line) as ComplianceAuditEntry);
+    } catch {
+      // A line cut short by a crash only loses that entry
+    }
+  }
+  return entries;
+}
+
+/** Append one build's findings to the audit log, oldest entries dropped past the cap */
+export async function appendComplianceAudit(
+  contextDir: string,
+  entries: readonly ComplianceAuditEntry[]
+): Promise<void> {
+  if (entries.length === 0) return;
+  const file = path.join(contextDir, COMPLIANCE_AUDIT_FILENAME);
+  await fs.mkdir(contextDir, { recursive: true });
+  await fs.appendFile(file, entries.map((entry) => `${JSON.stringify(entry)}\n`).join(''));
+
+  const stat = await fs.stat(file);
+  // Cheap check first: entries are about a hundred bytes
+  if (stat.size > MAX_AUDIT_ENTRIES * 100) {
+    const all = await readComplianceAudit(contextDir);
+    if (all.length > MAX_AUDIT_ENTRIES * 1.2) {
+      const kept = all.slice(-MAX_AUDIT_ENTRIES);
+      await writeAtomically(file, kept.map((entry) => JSON.stringify(entry)).join('\n') + '\n');
+    }
+  }
+}
@@ -0,0 +1,455 @@
+/**
+ * Configuration files: `codebase-context.yaml` at the repo root and a user-level
+ * `~/.config/codebase-context/config.yaml` (`$XDG_CONFIG_HOME` is honoured, and
+ * CODEBASE_CONTEXT_CONFIG names another file).
+ *
+ * Both hold the same sections: `embedding`, `storage` and `reranker` (server-wide, applied as
+ * the environment variables they stand for), `ignore` (.gitignore-syntax rules) and `chunking`
+ * (per project, same shape as the `chunking` key of `.codebase-context/config.json`), plus an
+ * `env` mapping for any other variable. Named `profiles` hold the same sections and are laid
+ * over the top-level ones; the profile comes from CODEBASE_CONTEXT_PROFILE, else the files'
+ * own `profile` key.
+ *
+ * Precedence, lowest first: user file, project file, the profile as the user file defines it,
+ * the profile as the project file defines it, then the real environment, which always wins.
+ * Credentials are references only (`apiKey: ${OPENAI_API_KEY}`): a literal key in a file
+ * that usually gets committed is refused.
+ *
+ * A project file comes with whatever repo was cloned, so it only tunes how that repo is
+ * indexed: `ignore`, `chunking` and the keys in PROJECT_SECTION_KEYS (model name, dimensions,
+ * batching, quantization). Providers, endpoints, URLs, collections, credentials and `env`
+ * decide where code is sent and what runs, and are refused outside the user file.
+ */
+
+import { readFileSync } from 'fs';
+import os from 'os';
+import path from 'path';
+import { PROJECT_CONFIG_YAML_FILENAME } from '../constants/codebase-context.js';
+import { parseSimpleYaml, type YamlMapping, type YamlValue } from '../utils/simple-yaml.js';
+
+type Scalar = string | number | boolean;
+
+export interface ConfigSettings {
+  embedding?: Record<string, Scalar>;
+  storage?: Record<string, Scalar>;
+  reranker?: Record<string, Scalar>;
+  /** .gitignore-syntax rules applied at the repo root, after `.mcpignore` */
+  ignore?: string[];
+  /** Raw `chunking` section; validated by the chunking config loader */
+  chunking?: YamlMapping;
+  /** Any other environment variable */
+  env?: Record<string, Scalar>;
+}
+
+export interface ConfigFile extends ConfigSettings {
+  file: string;
+  /** Default profile of this file */
+  profile?: string;
+  profiles: Record<string, ConfigSettings>;
+}
+
+export interface AppliedConfig {
+  /** Files read, user file first */
+  sources: string[];
+  profile?: string;
+  /** Variables set from the files */
+  set: string[];
+  /** Variables the files set that the environment already had; the environment won */
+  overridden: string[];
+  /** `section.key` entries that reference an unset variable, so were left out */
+  unresolved: string[];
+}
+
+type Section = 'embedding' | 'storage' | 'reranker';
+
+const SECTION_KEYS: Record<Section, Record<string, 'string' | 'number' | 'boolean'>> = {
+  embedding: {
+    provider: 'string',
+    model: 'string',
+    dimensions: 'number',
+    truncateDimensions: 'number',
+    batchSize: 'number',
+    concurrency: 'number',
+    maxRetries: 'number',
+    modelPath: 'string',
+    allowDownload: 'boolean',
+    endpoint: 'string',
+    apiKey: 'string'
+  },
+  storage: {
+    provider: 'string',
+    url: 'string',
+    collection: 'string',
+    apiKey: 'string',
+    quantization: 'string'
+  },
+  reranker: { provider: 'string', model: 'string', url: 'string', apiKey: 'string' }
+};
+
+/** Keys that map to one variable whatever the provider */
+const FIXED_ENV: Record<Section, Record<string, string>> = {
+  embedding: {
+    provider: 'EMBEDDING_PROVIDER',
+    model: 'EMBEDDING_MODEL',
+    dimensions: 'EMBEDDING_DIMENSIONS',
+    truncateDimensions: 'EMBEDDING_TRUNCATE_DIMENSIONS',
+    batchSize: 'EMBEDDING_BATCH_SIZE',
+    concurrency: 'EMBEDDING_CONCURRENCY',
+    maxRetries: 'EMBEDDING_MAX_RETRIES',
+    modelPath: 'EMBEDDING_MODEL_PATH',
+    allowDownload: 'EMBEDDING_ALLOW_DOWNLOAD'
+  },
+  storage: { provider: 'STORAGE_PROVIDER', quantization: 'CODEBASE_CONTEXT_QUANTIZATION' },
+  reranker: {
+    provider: 'RERANKER_PROVIDER',
+    model: 'RERANKER_MODEL',
+    url: 'RERANKER_API_URL',
+    apiKey: 'RERANKER_API_KEY'
+  }
+};
+
+/** Keys whose variable depends on the provider they configure */
+const PROVIDER_ENV: Record<string, Record<string, Record<string, string>>> = {
+  embedding: {
+    apiKey: {
+      openai: 'OPENAI_API_KEY',
+      'azure-openai': 'AZURE_OPENAI_API_KEY',
+      voyage: 'VOYAGE_API_KEY',
+      cohere: 'COHERE_API_KEY'
+    },
+    endpoint: { ollama: 'OLLAMA_HOST', 'azure-openai': 'AZURE_OPENAI_ENDPOINT' }
+  },
+  storage: {
+    url: { qdrant: 'QDRANT_URL', pgvector: 'PGVECTOR_URL', milvus: 'MILVUS_URL' },
+    collection: {
+      qdrant: 'QDRANT_COLLECTION',
+      pgvector: 'PGVECTOR_TABLE',
+      milvus: 'MILVUS_COLLECTION'
+    },
+    apiKey: { qdrant: 'QDRANT_API_KEY', milvus: 'MILVUS_TOKEN' }
+  }
+};
+
+const REFERENCE = /\$\{([A-Za-z_][A-Za-z0-9_]*)\}/g;
+const WHOLE_REFERENCE = /^\$\{[A-Za-z_][A-Za-z0-9_]*\}$/;
+const SECRET_VARIABLE = /(KEY|TOKEN|SECRET|PASSWORD)$/;
+/** The section keys a project file may set; everything else there is user-file only */
+const PROJECT_SECTION_KEYS: Record<Section, ReadonlySet<string>> = {
+  embedding: new Set([
+    'model',
+    'dimensions',
+    'truncateDimensions',
+    'batchSize',
+    'concurrency',
+    'maxRetries'
+  ]),
+  storage: new Set(['quantization']),
+  reranker: new Set(['model'])
+};
+
+let lastApplied: AppliedConfig | undefined;
+/** Broken-file messages already logged by the per-project loader */
+const reported = new Set<string>();
+
+function isMapping(value: YamlValue | undefined): value is YamlMapping {
+  return typeof value === 'object' && value !== null && !Array.isArray(value);
+}
+
+/** The user-level file: CODEBASE_CONTEXT_CONFIG, else the XDG config directory */
+export function userConfigPath(env: NodeJS.ProcessEnv = process.env): string {
+  const explicit = env.CODEBASE_CONTEXT_CONFIG?.trim();
+  if (explicit) return path.resolve(explicit);
+  const base = env.XDG_CONFIG_HOME?.trim() || path.join(os.homedir(), '.config');
+  return path.join(base, 'codebase-context', 'config.yaml');
+}
+
+function parseSection(value: YamlValue, section: Section, where: string): Record<string, Scalar> {
+  if (!isMapping(value)) throw new Error(`${where}: "${section}" must be a mapping`);
+  const parsed: Record<string, Scalar> = {};
+  for (const [key, raw] of Object.entries(value)) {
+    const type = SECTION_KEYS[section][key];
+    if (!type) throw new Error(`${where}: unknown key "${section}.${key}"`);
+    if (raw === null) continue;
+    if (typeof raw !== type) throw new Error(`${where}: "${section}.${key}" must be a ${type}`);
+    if (key === 'apiKey' && !WHOLE_REFERENCE.test(String(raw))) {
+      throw new Error(
+        `${where}: "${section}.apiKey" must reference a variable, e.g. \${MY_API_KEY}; ` +
+          'keys are not stored in config files'
+      );
+    }
+    parsed[key] = raw as Scalar;
+  }
+  return parsed;
+}
+
+function parseSettings(value: YamlMapping, where: string, allowTopLevel: boolean): ConfigSettings {
+  const settings: ConfigSettings = {};
+  for (const [key, raw] of Object.entries(value)) {
+    if (raw === null) continue;
+    if (key === 'embedding' || key === 'storage' || key === 'reranker') {
+      settings[key] = parseSection(raw, key, where);
+    } else if (key === 'ignore') {
+      const rules = Array.isArray(raw) ? raw : [raw];
+      if (!rules.every((rule) => typeof rule === 'string')) {
+        throw new Error(`${where}: "ignore" must be a list of patterns`);
+      }
+      settings.ignore = rules as string[];
+    } else if (key === 'chunking') {
+      if (!isMapping(raw)) throw new Error(`${where}: "chunking" must be a mapping`);
+      settings.chunking = raw;
+    } else if (key === 'env') {
+      if (!isMapping(raw)) throw new Error(`${where}: "env" must be a mapping`);
+      const env: Record<string, Scalar> = {};
+      for (const [name, entry] of Object.entries(raw)) {
+        if (entry === null || typeof entry === 'object') {
+          throw new Error(`${where}: "env.${name}" must be a string, number or boolean`);
+        }
+        if (SECRET_VARIABLE.test(name) && !WHOLE_REFERENCE.test(String(entry))) {
+          throw new Error(`${where}: "env.${name}" looks like a credential; reference a variable`);
+        }
+        env[name] = entry;
+      }
+      settings.env = env;
+    } else if (!allowTopLevel || (key !== 'profile' && key !== 'profiles')) {
+      throw new Error(`${where}: unknown key "${key}"`);
+    }
+  }
+  return settings;
+}
+
+/** Parse one config file's YAML; errors name the file and the offending key */
+export function parseConfigFile(source: string, file: string): ConfigFile {
+  let raw: YamlValue;
+  try {
+    raw = parseSimpleYaml(source);
+  } catch (error) {
+    throw new Error(`${file}: ${error instanceof Error ? error.message : String(error)}`);
+  }
+  if (raw === null) return { file, profiles: {} };
+  if (!isMapping(raw)) throw new Error(`${file}: expected a mapping at the top level`);
+
+  const profiles: Record<string, ConfigSettings> = {};
+  if (raw.profiles !== undefined && raw.profiles !== null) {
+    if (!isMapping(raw.profiles)) throw new Error(`${file}: "profiles" must be a mapping`);
+    for (const [name, value] of Object.entries(raw.profiles)) {
+      if (!isMapping(value)) throw new Error(`${file}: profile "${name}" must be a mapping`);
+      profiles[name] = parseSettings(value, `${file} (profile ${name})`, false);
+    }
+  }
+  if (raw.profile !== undefined && raw.profile !== null && typeof raw.profile !== 'string') {
+    throw new Error(`${file}: "profile" must be a profile name`);
+  }
+  return {
+    ...parseSettings(raw, file, true),
+    file,
+    ...(typeof raw.profile === 'string' ? { profile: raw.profile } : {}),
+    profiles
+  };
+}
+
+function readIfExists(file: string): string | null {
+  try {
+    return readFileSync(file, 'utf-8');
+  } catch {
+    return null;
+  }
+}
+
+function refuseUserOnlySettings(config: ConfigFile): void {
+  const scopes: Array<[string, ConfigSettings]> = [
+    [config.file, config],
+    ...Object.entries(config.profiles).map(
+      ([name, settings]): [string, ConfigSettings] => [`${config.file} (profile ${name})`, settings]
+    )
+  ];
+  for (const [where, settings] of scopes) {
+    const refused = [
+      ...Object.keys(settings.env ?? {}).map((name) => `env.${name}`),
+      ...(['embedding', 'storage', 'reranker'] as const).flatMap((section) =>
+        Object.keys(settings[section] ?? {})
+          .filter((key) => !PROJECT_SECTION_KEYS[section].has(key))
+          .map((key) => `${section}.${key}`)
+      )
+    ];
+    if (refused.length > 0) {
+      throw new Error(`${where}: "${refused[0]}" can only be set in the user config`);
+    }
+  }
+}
+
+/** The user file and the project's file (`.yaml`, else `.yml`) that exist, user first */
+export function loadConfigFiles(
+  rootPath: string,
+  env: NodeJS.ProcessEnv = process.env
+): ConfigFile[] {
+  const projectFile = path.join(rootPath, PROJECT_CONFIG_YAML_FILENAME);
+  const files: ConfigFile[] = [];
+  for (const [scope, candidates] of [
+    ['user', [userConfigPath(env)]],
+    ['project', [projectFile, projectFile.replace(/\.yaml$/, '.yml')]]
+  ] as const) {
+    for (const file of candidates) {
+      const source = readIfExists(file);
+      if (source === null) continue;
+      const config = parseConfigFile(source, file);
+      if (scope === 'project') refuseUserOnlySettings(config);
+      files.push(config);
+      break;
+    }
+  }
+  return files;
+}
+
+function mergeSettings(base: ConfigSettings, over: ConfigSettings): ConfigSettings {
+  const merged: ConfigSettings = { ...base };
+  for (const key of ['embedding', 'storage', 'reranker', 'env'] as const) {
+    if (over[key]) merged[key] = { ...base[key], ...over[key] };
+  }
+  if (over.ignore) merged.ignore = [...(base.ignore ?? []), ...over.ignore];
+  if (over.chunking) {
+    const baseLanguages = base.chunking?.languages;
+    const overLanguages = over.chunking.languages;
+    const languages = {
+      ...(isMapping(baseLanguages) ? baseLanguages : {}),
+      ...(isMapping(overLanguages) ? overLanguages : {})
+    };
+    merged.chunking = {
+      ...base.chunking,
+      ...over.chunking,
+      ...(Object.keys(languages).length > 0 ? { languages } : {})
+    };
+  }
+  return merged;
+}
+
+function settingsOf(file: ConfigFile): ConfigSettings {
+  const { embedding, storage, reranker, ignore, chunking, env } = file;
+  return { embedding, storage, reranker, ignore, chunking, env };
+}
+
+/**
+ * Settings of `files` (user file first) under the profile: `requested` (from
+ * CODEBASE_CONTEXT_PROFILE), else the project file's `profile`, else the user file's.
+ */
+export function resolveConfigSettings(
+  files: ConfigFile[],
+  requested?: string
+): { profile?: string; settings: ConfigSettings } {
+  const profile = requested?.trim() || [...files].reverse().find((file) => file.profile)?.profile;
+  let settings = files.reduce<ConfigSettings>(
+    (merged, file) => mergeSettings(merged, settingsOf(file)),
+    {}
+  );
+  if (!profile) return { settings };
+
+  const defined = files.filter((file) => file.profiles[profile]);
+  if (defined.length === 0) {
+    const known = [...new Set(files.flatMap((file) => Object.keys(file.profiles)))];
+    throw new Error(
+      `Unknown profile "${profile}"` + (known.length > 0 ? ` (defined: ${known.join(', ')})` : '')
+    );
+  }
+  for (const file of defined) settings = mergeSettings(settings, file.profiles[profile]);
+  return { profile, settings };
+}
+
+/**
+ * The variables `settings` stand for, with `${VAR}` references resolved against `env`.
+ * `value` is undefined when a reference is unset.
+ */
+export function configEnvAssignments(
+  settings: ConfigSettings,
+  env: NodeJS.ProcessEnv = process.env
+): Array<{ name: string; value?: string; from: string }> {
+  const assignments: Array<{ name: string; value?: string; from: string }> = [];
+  const resolve = (raw: Scalar): string | undefined => {
+    let unset = false;
+    const value = String(raw).replace(REFERENCE, (_, name: string) => {
+      const resolved = env[name];
+      if (resolved === undefined || resolved === '') unset = true;
+      return resolved ?? '';
+    });
+    return unset ? undefined : value;
+  };
+
+  for (const section of ['embedding', 'storage', 'reranker'] as const) {
+    const values = settings[section];
+    if (!values) continue;
+    const provider = String(values.provider ?? env[FIXED_ENV[section].provider] ?? '');
+    for (const [key, raw] of Object.entries(values)) {
+      const byProvider = PROVIDER_ENV[section]?.[key];
+      const name = FIXED_ENV[section][key] ?? byProvider?.[provider.toLowerCase()];
+      if (!name) {
+        const providers = Object.keys(byProvider ?? {}).join(', ');
+        throw new Error(
+          `"${section}.${key}" applies to ${providers} only` +
+            (provider ? `, not provider "${provider}"` : `; set "${section}.provider"`)
+        );
+      }
+      assignments.push({ name, value: resolve(raw), from: `${section}.${key}` });
+    }
+  }
+  for (const [name, raw] of Object.entries(settings.env ?? {})) {
+    assignments.push({ name, value: resolve(raw), from: `env.${name}` });
+  }
+  return assignments;
+}
+
+/**
+ * Read the config files for `rootPath` and set the variables they stand for in `env`, except
+ * ones `env` already has. Runs once at startup, before the modules that read the environment
+ * at load time; the outcome is kept for `appliedConfig()`.
+ */
+export function applyConfigFiles(
+  rootPath: string,
+  env: NodeJS.ProcessEnv = process.env
+): AppliedConfig {
+  const files = loadConfigFiles(rootPath, env);
+  const { profile, settings } = resolveConfigSettings(files, env.CODEBASE_CONTEXT_PROFILE);
+  const applied: AppliedConfig = {
+    sources: files.map((file) => file.file),
+    ...(profile ? { profile } : {}),
+    set: [],
+    overridden: [],
+    unresolved: []
+  };
+  for (const { name, value, from } of configEnvAssignments(settings, env)) {
+    if (value === undefined) applied.unresolved.push(from);
+    else if (env[name] !== undefined) applied.overridden.push(name);
+    else {
+      env[name] = value;
+      applied.set.push(name);
+    }
+  }
+  lastApplied = applied;
+  return applied;
+}
+
+/** What `applyConfigFiles` did at startup, if it ran */
+export function appliedConfig(): AppliedConfig | undefined {
+  return lastApplied;
+}
+
+/**
+ * The per-project sections (`ignore`, `chunking`) of the config files for `rootPath`, under
+ * the active profile. Undefined when there are no files; a broken file is reported once and
+ * treated as absent.
+ */
+export function loadProjectConfigSettings(
+  rootPath: string,
+  env: NodeJS.ProcessEnv = process.env
+): Pick<ConfigSettings, 'ignore' | 'chunking'> | undefined {
+  try {
+    const files = loadConfigFiles(rootPath, env);
+    if (files.length === 0) return undefined;
+    const { settings } = resolveConfigSettings(files, env.CODEBASE_CONTEXT_PROFILE);
+    return { ignore: settings.ignore, chunking: settings.chunking };
+  } catch (error) {
+    const message = error instanceof Error ? error.message : String(error);
+    if (!reported.has(message)) {
+      reported.add(message);
+      console.warn(`[config] ${message}`);
+    }
+    return undefined;
+  }
+}
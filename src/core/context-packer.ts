@@ -0,0 +1,189 @@
+/**
+ * Token-budget context packing: turn ranked search hits into one payload of line-numbered
+ * file sections that stays within a token budget.
+ *
+ * Overlapping or adjacent hits in the same file are merged into one section, sections are
+ * ordered by their best hit, and the first hit that doesn't fit whole is truncated to fill
+ * what's left. Tokens are counted by the caller's tokenizer, the chars/4 estimate by default.
+ */
+
+import { estimateTokens } from '../utils/ast-chunker.js';
+
+export interface PackCandidate {
+  /** Repo-relative posix path */
+  file: string;
+  startLine: number;
+  endLine: number;
+  score: number;
+}
+
+export interface PackedSection {
+  file: string;
+  startLine: number;
+  endLine: number;
+  score: number;
+  /** Set when the section was cut short to fit the budget */
+  truncated?: boolean;
+}
+
+export interface PackedContext {
+  text: string;
+  tokens: number;
+  sections: PackedSection[];
+  /** Candidates left out because the budget ran out */
+  omitted: number;
+}
+
+/** Returns the file's lines (without trailing newline), or null when it can't be read */
+export type LineReader = (file: string) => Promise<string[] | null>;
+
+/** Fewest lines worth including when a hit has to be truncated */
+const MIN_TRUNCATED_LINES = 3;
+/** Hits this close to an existing section are merged into it */
+const MERGE_GAP_LINES = 1;
+
+export function renderSections(sections: PackedSection[], lines: Map<string, string[]>): string {
+  return sections
+    .map((section) => {
+      const fileLines = lines.get(section.file) ?? [];
+      const width = String(section.endLine).length;
+      const body = fileLines
+        .slice(section.startLine - 1, section.endLine)
+        .map((line, i) => `${String(section.startLine + i).padStart(width)} | ${line}`)
+        .join('\n');
+      const suffix = section.truncated ? ' (truncated)' : '';
+      return `// ${section.file}:${section.startLine}-${section.endLine}${suffix}\n${body}`;
+    })
+    .join('\n\n');
+}
+
+function insertCandidate(sections: PackedSection[], candidate: PackCandidate): PackedSection[] {
+  const next = sections.map((s) => ({ ...s }));
+  let merged: PackedSection | undefined;
+  for (const section of next) {
+    if (section.file !== candidate.file) continue;
+    const overlaps =
+      candidate.startLine <= section.endLine + MERGE_GAP_LINES &&
+      candidate.endLine >= section.startLine - MERGE_GAP_LINES;
+    if (!overlaps) continue;
+    section.startLine = Math.min(section.startLine, candidate.startLine);
+    section.endLine = Math.max(section.endLine, candidate.endLine);
+    section.score = Math.max(section.score, candidate.score);
+    merged = section;
+    break;
+  }
+  if (!merged) {
+    next.push({ ...candidate });
+    return next;
+  }
+  // The grown section may now touch its neighbours
+  return coalesce(next);
+}
+
+function coalesce(sections: PackedSection[]): PackedSection[] {
+  const result: PackedSection[] = [];
+  for (const section of sections) {
+    const neighbour = result.find(
+      (s) =>
+        s.file === section.file &&
+        section.startLine <= s.endLine + MERGE_GAP_LINES &&
+        section.endLine >= s.startLine - MERGE_GAP_LINES
+    );
+    if (neighbour) {
+      neighbour.startLine = Math.min(neighbour.startLine, section.startLine);
+      neighbour.endLine = Math.max(neighbour.endLine, section.endLine);
+      neighbour.score = Math.max(neighbour.score, section.score);
+      neighbour.truncated = neighbour.truncated || section.truncated;
+    } else {
+      result.push(section);
+    }
+  }
+  return result;
+}
+
+/** Best-scoring file first; within a file, sections in line order */
+function orderSections(sections: PackedSection[]): PackedSection[] {
+  const fileScore = new Map<string, number>();
+  for (const s of sections) fileScore.set(s.file, Math.max(fileScore.get(s.file) ?? 0, s.score));
+  return [...sections].sort(
+    (a, b) =>
+      (fileScore.get(b.file) ?? 0) - (fileScore.get(a.file) ?? 0) ||
+      a.file.localeCompare(b.file) ||
+      a.startLine - b.startLine
+  );
+}
+
+/**
+ * Greedily pack candidates (highest score first) while the rendered payload fits `tokenBudget`.
+ */
+export async function packContext(
+  candidates: PackCandidate[],
+  tokenBudget: number,
+  readLines: LineReader,
+  countTokens: (text: string) => number = estimateTokens
+): Promise<PackedContext> {
+  const lines = new Map<string, string[]>();
+  let sections: PackedSection[] = [];
+  let omitted = 0;
+  let truncatedOne = false;
+
+  const fits = (candidate: PackedSection[]) =>
+    countTokens(renderSections(orderSections(candidate), lines)) <= tokenBudget;
+
+  for (const candidate of [...candidates].sort((a, b) => b.score - a.score)) {
+    if (!lines.has(candidate.file)) {
+      const fileLines = await readLines(candidate.file);
+      if (!fileLines) {
+        omitted++;
+        continue;
+      }
+      lines.set(candidate.file, fileLines);
+    }
+    const fileLength = lines.get(candidate.file)!.length;
+    const hit = {
+      ...candidate,
+      startLine: Math.max(1, candidate.startLine),
+      endLine: Math.min(fileLength, candidate.endLine)
+    };
+    if (hit.endLine < hit.startLine) {
+      omitted++;
+      continue;
+    }
+
+    const withHit = insertCandidate(sections, hit);
+    if (fits(withHit)) {
+      sections = withHit;
+      continue;
+    }
+
+    // Truncate the first hit that doesn't fit to whatever still fits (binary search on lines)
+    if (!truncatedOne) {
+      let low = MIN_TRUNCATED_LINES;
+      let high = hit.endLine - hit.startLine;
+      let best: PackedSection[] | null = null;
+      while (low <= high) {
+        const count = Math.floor((low + high) / 2);
+        const endLine = hit.startLine + count - 1;
+        const attempt = insertCandidate(sections, { ...hit, endLine });
+        const cut = attempt.find((s) => s.file === hit.file && s.endLine === endLine);
+        if (cut) cut.truncated = true;
+        if (fits(attempt)) {
+          best = attempt;
+          low = count + 1;
+        } else {
+          high = count - 1;
+        }
+      }
+      if (best) {
+        sections = best;
+        truncatedOne = true;
+        continue;
+      }
+    }
+    omitted++;
+  }
+
+  const ordered = orderSections(sections);
+  const text = renderSections(ordered, lines);
+  return { text, tokens: countTokens(text), sections: ordered, omitted };
+}
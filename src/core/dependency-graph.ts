@@ -0,0 +1,311 @@
+/**
+ * File and package dependency graph: import resolution at index time and
+ * dependency/dependent traversal for the get_dependencies / get_dependents tools.
+ *
+ * Nodes are repo-relative posix file paths. Imports of workspace packages resolve to the
+ * package's files when possible; other bare specifiers are kept as external package names.
+ */
+
+import { promises as fs } from 'fs';
+import { builtinModules } from 'module';
+import path from 'path';
+import type { FileExport, ImportEdgeDetail } from '../utils/usage-tracker.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  RELATIONSHIPS_FILENAME
+} from '../constants/codebase-context.js';
+
+export interface DependencyGraphData {
+  /** file -> files it imports */
+  imports: Record<string, string[]>;
+  /** file -> files that import it */
+  importedBy: Record<string, string[]>;
+  importDetails?: Record<string, Record<string, ImportEdgeDetail>>;
+  /** file -> external packages it imports */
+  externalImports?: Record<string, string[]>;
+  /** file -> names it exports */
+  exports?: Record<string, FileExport[]>;
+  /** workspace package name -> repo-relative directory */
+  packages?: Record<string, string>;
+}
+
+export interface ResolvedImport {
+  /** Repo-relative posix path of the imported file, when it is part of the project */
+  file?: string;
+  /** Package name for bare specifiers (`@scope/name`, `lodash`) */
+  package?: string;
+}
+
+const SOURCE_EXTENSIONS = ['.ts', '.tsx', '.mts', '.cts', '.js', '.jsx', '.mjs', '.cjs'];
+// ESM sources import `./foo.js` for `foo.ts`
+const EMITTED_TO_SOURCE: Record<string, string[]> = {
+  '.js': ['.ts', '.tsx'],
+  '.jsx': ['.tsx'],
+  '.mjs': ['.mts'],
+  '.cjs': ['.cts']
+};
+const BUILTINS = new Set(builtinModules);
+
+/** `@scope/name/sub/path` -> `@scope/name`, `lodash/fp` -> `lodash` */
+export function packageNameOf(specifier: string): string {
+  const parts = specifier.split('/');
+  return specifier.startsWith('@') ? parts.slice(0, 2).join('/') : parts[0];
+}
+
+function isBuiltin(specifier: string): boolean {
+  return specifier.startsWith('node:') || BUILTINS.has(packageNameOf(specifier));
+}
+
+/**
+ * Resolve import specifiers against the set of indexed files.
+ * Relative imports try the literal path, TS sources for emitted `.js` paths, known
+ * extensions and `index` files; workspace package imports try `src/` then the package root.
+ */
+export function createImportResolver(
+  knownFiles: Iterable<string>,
+  packages: Record<string, string> = {}
+): (fromFile: string, specifier: string) => ResolvedImport | null {
+  const files = new Set(knownFiles);
+
+  const resolveFile = (base: string): string | undefined => {
+    const normalized = path.posix.normalize(base).replace(/^\.\//, '').replace(/\/$/, '');
+    if (files.has(normalized)) return normalized;
+
+    const ext = path.posix.extname(normalized);
+    for (const sourceExt of EMITTED_TO_SOURCE[ext] ?? []) {
+      const candidate = normalized.slice(0, -ext.length) + sourceExt;
+      if (files.has(candidate)) return candidate;
+    }
+    for (const candidate of [
+      ...SOURCE_EXTENSIONS.map((e) => normalized + e),
+      ...SOURCE_EXTENSIONS.map((e) => `${normalized}/index${e}`)
+    ]) {
+      if (files.has(candidate)) return candidate;
+    }
+    return undefined;
+  };
+
+  return (fromFile, specifier) => {
+    if (specifier.startsWith('.')) {
+      const file = resolveFile(path.posix.join(path.posix.dirname(fromFile), specifier));
+      return file ? { file } : null;
+    }
+    if (specifier.startsWith('/') || isBuiltin(specifier)) return null;
+
+    const name = packageNameOf(specifier);
+    const directory = packages[name];
+    if (directory !== undefined) {
+      const subpath = specifier.slice(name.length).replace(/^\//, '');
+      const prefix = directory ? `${directory}/` : '';
+      const file =
+        resolveFile(`${prefix}src/${subpath || 'index'}`) ??
+        resolveFile(`${prefix}${subpath || 'index'}`);
+      if (file) return { file, package: name };
+    }
+    return { package: name };
+  };
+}
+
+/**
+ * Load the import graph from the relationships sidecar.
+ * Returns null when the index hasn't been built.
+ */
+export async function loadDependencyGraph(rootPath: string): Promise<DependencyGraphData | null> {
+  const relationshipsPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, RELATIONSHIPS_FILENAME);
+  try {
+    const parsed = JSON.parse(await fs.readFile(relationshipsPath, 'utf-8')) as {
+      graph?: Partial<DependencyGraphData>;
+    };
+    const graph = parsed.graph;
+    if (!graph?.imports) return null;
+    return {
+      ...graph,
+      imports: graph.imports,
+      importedBy: graph.importedBy ?? invert(graph.imports)
+    };
+  } catch {
+    return null;
+  }
+}
+
+function invert(edges: Record<string, string[]>): Record<string, string[]> {
+  const inverted: Record<string, string[]> = {};
+  for (const [from, targets] of Object.entries(edges)) {
+    for (const to of targets) (inverted[to] ??= []).push(from);
+  }
+  return inverted;
+}
+
+export type DependencyTarget =
+  | { kind: 'file'; file: string }
+  | { kind: 'package'; name: string; directory?: string; files: string[] };
+
+function normalizeTarget(rootPath: string, target: string): string {
+  const posix = target.trim().replace(/\\/g, '/');
+  const root = rootPath.replace(/\\/g, '/').replace(/\/$/, '');
+  const relative = posix.startsWith(`${root}/`) ? posix.slice(root.length + 1) : posix;
+  return relative.replace(/^\.\//, '').replace(/\/$/, '');
+}
+
+/**
+ * Interpret `target` as an indexed file, a workspace package (by name or directory)
+ * or an external package imported somewhere in the project.
+ */
+export function resolveDependencyTarget(
+  data: DependencyGraphData,
+  rootPath: string,
+  target: string
+): DependencyTarget | null {
+  const normalized = normalizeTarget(rootPath, target);
+  if (!normalized) return null;
+  if (data.imports[normalized] || data.importedBy[normalized]) {
+    return { kind: 'file', file: normalized };
+  }
+
+  const packages = data.packages ?? {};
+  const byDirectory = Object.entries(packages).find(([, dir]) => dir && dir === normalized);
+  const name = packages[target.trim()] !== undefined ? target.trim() : byDirectory?.[0];
+  if (name !== undefined) {
+    const directory = packages[name];
+    const prefix = directory ? `${directory}/` : '';
+    const files = allFiles(data).filter((file) => file.startsWith(prefix));
+    return { kind: 'package', name, directory, files };
+  }
+
+  const isExternal = Object.values(data.externalImports ?? {}).some((pkgs) =>
+    pkgs.includes(target.trim())
+  );
+  return isExternal ? { kind: 'package', name: target.trim(), files: [] } : null;
+}
+
+function allFiles(data: DependencyGraphData): string[] {
+  return [...new Set([...Object.keys(data.imports), ...Object.keys(data.importedBy)])].sort();
+}
+
+/** Workspace package owning `file` (longest directory prefix) */
+export function owningPackage(data: DependencyGraphData, file: string): string | undefined {
+  let best: { name: string; length: number } | undefined;
+  for (const [name, directory] of Object.entries(data.packages ?? {})) {
+    const owns = directory === '' || file.startsWith(`${directory}/`);
+    if (owns && (!best || directory.length > best.length)) {
+      best = { name, length: directory.length };
+    }
+  }
+  return best?.name;
+}
+
+export interface DependencyEntry {
+  file: string;
+  /** 1 = direct, 2 = imported through one intermediate file, ... */
+  depth: number;
+  package?: string;
+  /** Import line (in the importing file) for direct edges */
+  line?: number;
+  symbols?: string[];
+}
+
+export interface DependencyLookupResult {
+  target: string;
+  kind: DependencyTarget['kind'];
+  total: number;
+  results: DependencyEntry[];
+  /** Result counts per workspace package ('(root)' for files outside any package) */
+  byPackage?: Record<string, number>;
+  /** External packages imported directly by the target (dependencies only) */
+  externalPackages?: string[];
+}
+
+function traverse(
+  data: DependencyGraphData,
+  target: DependencyTarget,
+  direction: 'dependencies' | 'dependents',
+  maxDepth: number,
+  limit: number
+): DependencyLookupResult {
+  const seeds = target.kind === 'file' ? [target.file] : target.files;
+  const edges = direction === 'dependencies' ? data.imports : data.importedBy;
+  const seen = new Set(seeds);
+  const results: DependencyEntry[] = [];
+
+  const detail = (from: string, to: string): Pick<DependencyEntry, 'line' | 'symbols'> => {
+    const [importer, imported] = direction === 'dependencies' ? [from, to] : [to, from];
+    const edge = data.importDetails?.[importer]?.[imported];
+    return {
+      ...(edge?.line ? { line: edge.line } : {}),
+      ...(edge?.importedSymbols?.length ? { symbols: edge.importedSymbols } : {})
+    };
+  };
+
+  const visit = (from: string[], depth: number): string[] => {
+    const reached: string[] = [];
+    for (const source of from) {
+      for (const file of edges[source] ?? []) {
+        if (seen.has(file)) continue;
+        seen.add(file);
+        reached.push(file);
+        results.push({ file, depth, ...(depth === 1 ? detail(source, file) : {}) });
+      }
+    }
+    return reached;
+  };
+
+  let frontier = visit(seeds, 1);
+  if (direction === 'dependents' && target.kind === 'package') {
+    // Importers of a package name that didn't resolve to a file (external or entry-less)
+    for (const [file, pkgs] of Object.entries(data.externalImports ?? {})) {
+      if (!pkgs.includes(target.name) || seen.has(file)) continue;
+      seen.add(file);
+      frontier.push(file);
+      results.push({ file, depth: 1 });
+    }
+  }
+  for (let depth = 2; depth <= maxDepth && frontier.length > 0; depth++) {
+    frontier = visit(frontier, depth);
+  }
+
+  const hasPackages = Object.keys(data.packages ?? {}).length > 0;
+  const byPackage: Record<string, number> = {};
+  for (const entry of results) {
+    const pkg = hasPackages ? owningPackage(data, entry.file) : undefined;
+    if (pkg) entry.package = pkg;
+    if (hasPackages) byPackage[pkg ?? '(root)'] = (byPackage[pkg ?? '(root)'] ?? 0) + 1;
+  }
+
+  results.sort((a, b) => a.depth - b.depth || a.file.localeCompare(b.file));
+
+  const externalPackages =
+    direction === 'dependencies'
+      ? [...new Set(seeds.flatMap((file) => data.externalImports?.[file] ?? []))]
+          .filter((pkg) => target.kind !== 'package' || pkg !== target.name)
+          .sort()
+      : undefined;
+
+  return {
+    target: target.kind === 'file' ? target.file : target.name,
+    kind: target.kind,
+    total: results.length,
+    results: results.slice(0, limit),
+    ...(hasPackages ? { byPackage } : {}),
+    ...(externalPackages?.length ? { externalPackages } : {})
+  };
+}
+
+/** Files the target imports, transitively up to `maxDepth` hops. */
+export function getDependencies(
+  data: DependencyGraphData,
+  target: DependencyTarget,
+  maxDepth: number,
+  limit: number
+): DependencyLookupResult {
+  return traverse(data, target, 'dependencies', maxDepth, limit);
+}
+
+/** Files that import the target, transitively up to `maxDepth` hops (the blast radius). */
+export function getDependents(
+  data: DependencyGraphData,
+  target: DependencyTarget,
+  maxDepth: number,
+  limit: number
+): DependencyLookupResult {
+  return traverse(data, target, 'dependents', maxDepth, limit);
+}
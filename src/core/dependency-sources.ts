@@ -0,0 +1,357 @@
+/**
+ * Third-party dependencies opted into the index one by one (`index_dependency`), e.g.
+ * `github.com/gin-gonic/gin@v1.9`, `express` or `serde@1.0`. Sources are resolved locally,
+ * never downloaded:
+ *
+ * - Go: `vendor/<module>`, else the module cache (GOMODCACHE, `$GOPATH/pkg/mod`), at the
+ *   version asked for or the one `go.mod` requires
+ * - npm: `node_modules/<name>` (its package.json version must match, when one is given)
+ * - Cargo: `vendor/<crate>` (`cargo vendor`), else `$CARGO_HOME/registry/src/*`, at the
+ *   version asked for or the one `Cargo.lock` pins
+ *
+ * Each dependency gets a standalone index under `.codebase-context/deps/<slug>/`, so the
+ * project's own index is never mixed with it. Searches leave dependencies out unless asked.
+ */
+
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  DEPENDENCY_INDEXES_DIRNAME,
+  KEYWORD_INDEX_FILENAME
+} from '../constants/codebase-context.js';
+import { CodebaseIndexer } from './indexer.js';
+import { CodebaseSearcher, type SearchOptions } from './search.js';
+import { getRefIndexSlug } from '../utils/git-tree.js';
+import type { CodebaseConfig, SearchFilters, SearchResult } from '../types/index.js';
+
+/** Written next to each dependency index: what was resolved, from where and when */
+const DEPENDENCY_SOURCE_FILENAME = 'dependency.json';
+
+export type DependencyEcosystem = 'go' | 'npm' | 'cargo';
+
+export interface DependencySpec {
+  name: string;
+  version?: string;
+}
+
+export interface ResolvedDependency {
+  ecosystem: DependencyEcosystem;
+  name: string;
+  version?: string;
+  /** `name@version` (or `name`): how results and filters refer to it */
+  id: string;
+  sourceDir: string;
+}
+
+export interface IndexedDependency extends ResolvedDependency {
+  contextDir: string;
+  indexedAt: string;
+  indexedFiles: number;
+  totalChunks: number;
+}
+
+export interface DependencyOptions {
+  /** Re-index even when the same version is already indexed */
+  refresh?: boolean;
+  config?: Partial<CodebaseConfig>;
+  env?: NodeJS.ProcessEnv;
+}
+
+/** `github.com/gin-gonic/gin@v1.9`, `@types/node@20`, `serde` */
+export function parseDependencySpec(input: string): DependencySpec {
+  const trimmed = input.trim();
+  const at = trimmed.lastIndexOf('@');
+  const [name, version] =
+    at > 0 ? [trimmed.slice(0, at), trimmed.slice(at + 1)] : [trimmed, undefined];
+  const segments = name.split('/');
+  if (!name || segments.some((s) => !s || s === '.' || s === '..' || !/^[@\w.~-]+$/.test(s))) {
+    throw new Error(`Not a dependency name: '${input}'`);
+  }
+  if (version !== undefined && !/^[\w.+-]+$/.test(version)) {
+    throw new Error(`Not a version: '${version}'`);
+  }
+  return { name, ...(version ? { version } : {}) };
+}
+
+/** Whether `candidate` is `wanted` or a more specific version of it (`v1.9` -> `v1.9.1`) */
+export function versionMatches(candidate: string, wanted: string): boolean {
+  const strip = (v: string) => v.replace(/^v/, '');
+  const have = strip(candidate);
+  const want = strip(wanted);
+  return have === want || have.startsWith(`${want}.`) || have.startsWith(`${want}-`);
+}
+
+function compareVersions(a: string, b: string): number {
+  return a.localeCompare(b, 'en', { numeric: true });
+}
+
+async function isDirectory(dir: string): Promise<boolean> {
+  return fs
+    .stat(dir)
+    .then((stat) => stat.isDirectory())
+    .catch(() => false);
+}
+
+async function readText(file: string): Promise<string | null> {
+  return fs.readFile(file, 'utf-8').catch(() => null);
+}
+
+function withId(resolved: Omit<ResolvedDependency, 'id'>): ResolvedDependency {
+  return {
+    ...resolved,
+    id: resolved.version ? `${resolved.name}@${resolved.version}` : resolved.name
+  };
+}
+
+// --- Go ---
+
+/** Module cache paths spell capitals as `!` + lowercase */
+function escapeModulePath(value: string): string {
+  return value.replace(/[A-Z]/g, (c) => `!${c.toLowerCase()}`);
+}
+
+function goModCache(env: NodeJS.ProcessEnv): string {
+  if (env.GOMODCACHE?.trim()) return env.GOMODCACHE.trim();
+  const gopath = env.GOPATH?.split(path.delimiter).find((p) => p.trim());
+  return path.join(gopath ?? path.join(os.homedir(), 'go'), 'pkg', 'mod');
+}
+
+/** Version of `module` in go.mod's require directives */
+export function goModRequirement(goMod: string, module: string): string | undefined {
+  const escaped = module.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+  const match = goMod.match(new RegExp(`^\\s*(?:require\\s+)?${escaped}\\s+(v\\S+)`, 'm'));
+  return match?.[1];
+}
+
+async function resolveGo(
+  rootPath: string,
+  spec: DependencySpec,
+  env: NodeJS.ProcessEnv
+): Promise<ResolvedDependency | null> {
+  const goMod = await readText(path.join(rootPath, 'go.mod'));
+  const required = goMod ? goModRequirement(goMod, spec.name) : undefined;
+  const wanted = spec.version ?? required;
+
+  // `go mod vendor` keeps exactly the required version
+  const vendored = path.join(rootPath, 'vendor', ...spec.name.split('/'));
+  const vendorMatches = !spec.version || (!!required && versionMatches(required, spec.version));
+  if (vendorMatches && (await isDirectory(vendored))) {
+    return withId({ ecosystem: 'go', name: spec.name, version: required, sourceDir: vendored });
+  }
+
+  const escaped = escapeModulePath(spec.name);
+  const parent = path.join(goModCache(env), path.dirname(escaped));
+  const prefix = `${path.basename(escaped)}@`;
+  const entries = await fs.readdir(parent).catch(() => [] as string[]);
+  const versions = entries
+    .filter((entry) => entry.startsWith(prefix))
+    .map((entry) => entry.slice(prefix.length))
+    .filter((version) => !wanted || versionMatches(version, escapeModulePath(wanted)))
+    .sort(compareVersions);
+  const version = versions[versions.length - 1];
+  if (!version) return null;
+  return withId({
+    ecosystem: 'go',
+    name: spec.name,
+    version: version.replace(/!([a-z])/g, (_m, c: string) => c.toUpperCase()),
+    sourceDir: path.join(parent, `${prefix}${version}`)
+  });
+}
+
+// --- npm ---
+
+async function resolveNpm(
+  rootPath: string,
+  spec: DependencySpec
+): Promise<ResolvedDependency | null> {
+  const sourceDir = path.join(rootPath, 'node_modules', ...spec.name.split('/'));
+  const manifest = await readText(path.join(sourceDir, 'package.json'));
+  if (manifest === null) return null;
+  let version: string | undefined;
+  try {
+    const parsed = JSON.parse(manifest) as { version?: unknown };
+    version = typeof parsed.version === 'string' ? parsed.version : undefined;
+  } catch {
+    // Unreadable manifest: index it without a version
+  }
+  if (spec.version && !(version && versionMatches(version, spec.version))) {
+    throw new Error(
+      `node_modules has ${spec.name}@${version ?? 'unknown'}, not ${spec.version}. ` +
+        'Install that version first.'
+    );
+  }
+  return withId({ ecosystem: 'npm', name: spec.name, version, sourceDir });
+}
+
+// --- Cargo ---
+
+/** Versions of `crate` pinned in Cargo.lock */
+export function cargoLockVersions(lock: string, crate: string): string[] {
+  const versions: string[] = [];
+  for (const block of lock.split(/^\[\[package\]\]\s*$/m)) {
+    const name = block.match(/^name\s*=\s*"([^"]+)"/m)?.[1];
+    const version = block.match(/^version\s*=\s*"([^"]+)"/m)?.[1];
+    if (name === crate && version) versions.push(version);
+  }
+  return versions.sort(compareVersions);
+}
+
+async function resolveCargo(
+  rootPath: string,
+  spec: DependencySpec,
+  env: NodeJS.ProcessEnv
+): Promise<ResolvedDependency | null> {
+  const lock = await readText(path.join(rootPath, 'Cargo.lock'));
+  const pinned = lock ? cargoLockVersions(lock, spec.name) : [];
+  const candidates = spec.version
+    ? pinned.filter((v) => versionMatches(v, spec.version as string))
+    : pinned;
+  const version = candidates[candidates.length - 1] ?? spec.version;
+
+  // `cargo vendor` uses `<crate>`, or `<crate>-<version>` when several versions are vendored
+  const vendorDirs = version ? [`${spec.name}-${version}`, spec.name] : [spec.name];
+  for (const dir of vendorDirs) {
+    const sourceDir = path.join(rootPath, 'vendor', dir);
+    if (await isDirectory(sourceDir)) {
+      return withId({ ecosystem: 'cargo', name: spec.name, version, sourceDir });
+    }
+  }
+
+  if (!version) return null;
+  const cargoHome = env.CARGO_HOME?.trim() || path.join(os.homedir(), '.cargo');
+  const registries = path.join(cargoHome, 'registry', 'src');
+  for (const registry of await fs.readdir(registries).catch(() => [] as string[])) {
+    const sourceDir = path.join(registries, registry, `${spec.name}-${version}`);
+    if (await isDirectory(sourceDir)) {
+      return withId({ ecosystem: 'cargo', name: spec.name, version, sourceDir });
+    }
+  }
+  return null;
+}
+
+/**
+ * Find a dependency's source on disk. Names with a dot in the first segment are Go modules;
+ * others are looked up in node_modules, then as crates.
+ */
+export async function resolveDependencySource(
+  rootPath: string,
+  spec: DependencySpec,
+  env: NodeJS.ProcessEnv = process.env
+): Promise<ResolvedDependency> {
+  const goModule = spec.name.split('/')[0].includes('.');
+  const resolved = goModule
+    ? await resolveGo(rootPath, spec, env)
+    : ((await resolveNpm(rootPath, spec)) ?? (await resolveCargo(rootPath, spec, env)));
+  if (!resolved) {
+    const wanted = spec.version ? `${spec.name}@${spec.version}` : spec.name;
+    const where = goModule
+      ? 'vendor/ or the Go module cache (run `go mod download`)'
+      : 'node_modules, vendor/ or the Cargo registry (run `npm install` / `cargo fetch`)';
+    throw new Error(`No source for ${wanted} in ${where}.`);
+  }
+  return resolved;
+}
+
+export function dependencyContextDir(rootPath: string, id: string): string {
+  const slug = getRefIndexSlug(id);
+  return path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, DEPENDENCY_INDEXES_DIRNAME, slug);
+}
+
+/** Dependencies indexed for the project, by id */
+export async function listIndexedDependencies(rootPath: string): Promise<IndexedDependency[]> {
+  const base = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, DEPENDENCY_INDEXES_DIRNAME);
+  const indexed: IndexedDependency[] = [];
+  for (const entry of await fs.readdir(base).catch(() => [] as string[])) {
+    const raw = await readText(path.join(base, entry, DEPENDENCY_SOURCE_FILENAME));
+    if (raw === null) continue;
+    try {
+      const parsed = JSON.parse(raw) as IndexedDependency;
+      if (typeof parsed.id === 'string' && typeof parsed.sourceDir === 'string') {
+        indexed.push({ ...parsed, contextDir: path.join(base, entry) });
+      }
+    } catch {
+      // Half-written record: the dependency is indexed again on request
+    }
+  }
+  return indexed.sort((a, b) => a.id.localeCompare(b.id));
+}
+
+/**
+ * Select indexed dependencies by name or id (`gin`, `github.com/gin-gonic/gin`,
+ * `github.com/gin-gonic/gin@v1.9.1`); `true` selects all of them.
+ */
+export function selectDependencies(
+  indexed: IndexedDependency[],
+  selector: true | string[]
+): IndexedDependency[] {
+  if (selector === true) return indexed;
+  return indexed.filter((dep) =>
+    selector.some((s) => s === dep.id || s === dep.name || dep.name.endsWith(`/${s}`))
+  );
+}
+
+/** Resolve a dependency's source and build (or reuse) its standalone index */
+export async function indexDependency(
+  rootPath: string,
+  spec: DependencySpec,
+  options: DependencyOptions = {}
+): Promise<IndexedDependency & { reused: boolean }> {
+  const resolved = await resolveDependencySource(rootPath, spec, options.env);
+  const contextDir = dependencyContextDir(rootPath, resolved.id);
+  const recordPath = path.join(contextDir, DEPENDENCY_SOURCE_FILENAME);
+
+  if (!options.refresh) {
+    const existing = (await listIndexedDependencies(rootPath)).find((d) => d.id === resolved.id);
+    const hasIndex = await fs
+      .access(path.join(contextDir, KEYWORD_INDEX_FILENAME))
+      .then(() => true)
+      .catch(() => false);
+    if (existing && hasIndex && existing.sourceDir === resolved.sourceDir) {
+      return { ...existing, reused: true };
+    }
+  }
+
+  const stats = await new CodebaseIndexer({
+    rootPath: resolved.sourceDir,
+    contextDir,
+    ...(options.config ? { config: options.config } : {})
+  }).index();
+  const record: IndexedDependency = {
+    ...resolved,
+    contextDir,
+    indexedAt: new Date().toISOString(),
+    indexedFiles: stats.indexedFiles,
+    totalChunks: stats.totalChunks
+  };
+  await fs.writeFile(recordPath, JSON.stringify(record, null, 2));
+  return { ...record, reused: false };
+}
+
+/**
+ * Search the selected dependency indexes; results carry `dependency`. An index that can't be
+ * read is reported in `skipped` instead of failing the project search.
+ */
+export async function searchDependencies(
+  rootPath: string,
+  selector: true | string[],
+  query: string,
+  limit: number,
+  filters: SearchFilters | undefined,
+  options: SearchOptions
+): Promise<{ results: SearchResult[]; searched: string[]; skipped: string[] }> {
+  const selected = selectDependencies(await listIndexedDependencies(rootPath), selector);
+  const results: SearchResult[] = [];
+  const skipped: string[] = [];
+  for (const dep of selected) {
+    try {
+      const searcher = new CodebaseSearcher(dep.sourceDir, { contextDir: dep.contextDir });
+      const found = await searcher.search(query, limit, filters, options);
+      results.push(...found.map((result) => ({ ...result, dependency: dep.id })));
+    } catch (error) {
+      skipped.push(`${dep.id}: ${error instanceof Error ? error.message : String(error)}`);
+    }
+  }
+  return { results, searched: selected.map((dep) => dep.id), skipped };
+}
@@ -0,0 +1,257 @@
+/**
+ * Diff context: map unified-diff hunks onto the functions/classes they touch.
+ *
+ * Hunk ranges come from `git diff --unified=0` (or a pasted diff); enclosing symbols come
+ * from Tree-sitter on the post-change file content. Files without a grammar still report
+ * their hunks, just without symbols.
+ */
+
+import path from 'path';
+import { detectLanguage } from '../utils/language-detection.js';
+import { extractTreeSitterSymbols, type TreeSitterSymbol } from '../utils/tree-sitter.js';
+import { readGitFileAtRef } from '../utils/git-tree.js';
+import { readTextFile } from '../utils/text-encoding.js';
+
+export type DiffFileStatus = 'added' | 'deleted' | 'modified' | 'renamed';
+
+export interface DiffHunk {
+  oldStart: number;
+  oldLines: number;
+  newStart: number;
+  newLines: number;
+  /** Body lines with their ` `, `-` or `+` prefix, when parsed with `hunkLines` */
+  lines?: string[];
+}
+
+export interface DiffFile {
+  /** Repo-relative path after the change (the old path for deletions) */
+  path: string;
+  oldPath?: string;
+  status: DiffFileStatus;
+  additions: number;
+  deletions: number;
+  hunks: DiffHunk[];
+}
+
+export interface ChangedSymbol {
+  name: string;
+  kind: string;
+  /** "startLine-endLine" in the post-change file */
+  lines: string;
+  signature: string;
+}
+
+export interface DiffFileContext extends DiffFile {
+  changedSymbols: ChangedSymbol[];
+}
+
+const HUNK_HEADER = /^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@/;
+const MAX_SIGNATURE_LENGTH = 120;
+
+function stripDiffPathPrefix(raw: string): string | null {
+  const trimmed = raw.trim().replace(/^"(.*)"$/, '$1');
+  if (trimmed === '/dev/null') return null;
+  return trimmed.replace(/^[ab]\//, '');
+}
+
+/**
+ * Parse a unified diff (git or plain `diff -u`) into per-file hunk ranges. With `hunkLines`,
+ * each hunk also keeps its body lines so the change can be applied.
+ */
+export function parseUnifiedDiff(
+  diff: string,
+  options: { hunkLines?: boolean } = {}
+): DiffFile[] {
+  const files: DiffFile[] = [];
+  let current: DiffFile | null = null;
+  let currentHunk: DiffHunk | null = null;
+  let oldPath: string | null = null;
+  // Lines still expected in the current hunk; header detection is suspended meanwhile so
+  // a removed line such as "-- comment" is never mistaken for a "--- " file header
+  let oldRemaining = 0;
+  let newRemaining = 0;
+
+  const emptyFile = (): DiffFile => ({
+    path: '',
+    status: 'modified',
+    additions: 0,
+    deletions: 0,
+    hunks: []
+  });
+
+  for (const line of diff.split(/\r?\n/)) {
+    if (current && (oldRemaining > 0 || newRemaining > 0)) {
+      if (line.startsWith('+')) {
+        current.additions++;
+        newRemaining--;
+      } else if (line.startsWith('-')) {
+        current.deletions++;
+        oldRemaining--;
+      } else if (line.startsWith(' ') || line === '') {
+        oldRemaining--;
+        newRemaining--;
+      } else {
+        continue;
+      }
+      currentHunk?.lines?.push(line === '' ? ' ' : line);
+      continue;
+    }
+
+    if (line.startsWith('diff --git ')) {
+      if (current?.path) files.push(current);
+      const file = emptyFile();
+      current = file;
+      oldPath = null;
+      const match = line.match(/^diff --git a\/(.+) b\/(.+)$/);
+      if (match) {
+        file.path = match[2];
+        if (match[1] !== match[2]) {
+          file.oldPath = match[1];
+          file.status = 'renamed';
+        }
+      }
+      continue;
+    }
+
+    // Plain `diff -u` output has no "diff --git" line between files
+    if (line.startsWith('--- ') && (!current || current.hunks.length > 0)) {
+      if (current?.path) files.push(current);
+      current = emptyFile();
+      oldPath = null;
+    }
+    if (!current) continue;
+    const file: DiffFile = current;
+
+    if (line.startsWith('new file mode')) {
+      file.status = 'added';
+    } else if (line.startsWith('deleted file mode')) {
+      file.status = 'deleted';
+    } else if (line.startsWith('rename from ')) {
+      file.oldPath = line.slice('rename from '.length);
+      file.status = 'renamed';
+    } else if (line.startsWith('rename to ')) {
+      file.path = line.slice('rename to '.length);
+    } else if (line.startsWith('--- ')) {
+      oldPath = stripDiffPathPrefix(line.slice(4).split('\t')[0]);
+      if (oldPath === null) file.status = 'added';
+      else if (!file.path) file.path = oldPath;
+    } else if (line.startsWith('+++ ')) {
+      const newPath = stripDiffPathPrefix(line.slice(4).split('\t')[0]);
+      if (newPath === null) {
+        file.status = 'deleted';
+        if (oldPath) file.path = oldPath;
+      } else {
+        file.path = newPath;
+      }
+    } else {
+      const match = line.match(HUNK_HEADER);
+      if (match) {
+        const hunk: DiffHunk = {
+          oldStart: Number(match[1]),
+          oldLines: match[2] === undefined ? 1 : Number(match[2]),
+          newStart: Number(match[3]),
+          newLines: match[4] === undefined ? 1 : Number(match[4]),
+          ...(options.hunkLines && { lines: [] })
+        };
+        file.hunks.push(hunk);
+        currentHunk = hunk;
+        oldRemaining = hunk.oldLines;
+        newRemaining = hunk.newLines;
+      }
+    }
+  }
+
+  if (current?.path) files.push(current);
+  return files;
+}
+
+/** Post-change line range of a hunk; pure deletions collapse to the line they sit after. */
+export function hunkLineRange(hunk: DiffHunk): { start: number; end: number } {
+  if (hunk.newLines === 0) {
+    const anchor = Math.max(hunk.newStart, 1);
+    return { start: anchor, end: anchor };
+  }
+  return { start: hunk.newStart, end: hunk.newStart + hunk.newLines - 1 };
+}
+
+/**
+ * Innermost symbols overlapping any hunk. A class is only reported when the change
+ * falls outside all of its methods.
+ */
+export function findChangedSymbols(
+  symbols: TreeSitterSymbol[],
+  hunks: DiffHunk[]
+): TreeSitterSymbol[] {
+  const touched = new Set<TreeSitterSymbol>();
+
+  for (const hunk of hunks) {
+    const { start, end } = hunkLineRange(hunk);
+    const overlapping = symbols.filter((s) => s.startLine <= end && s.endLine >= start);
+    for (const symbol of overlapping) {
+      const hasInnerMatch = overlapping.some(
+        (other) =>
+          other !== symbol &&
+          other.startIndex >= symbol.startIndex &&
+          other.endIndex <= symbol.endIndex &&
+          // The inner symbol must contain the whole hunk for the outer one to be redundant
+          other.startLine <= start &&
+          other.endLine >= end
+      );
+      if (!hasInnerMatch) touched.add(symbol);
+    }
+  }
+
+  return symbols.filter((s) => touched.has(s));
+}
+
+function toChangedSymbol(symbol: TreeSitterSymbol): ChangedSymbol {
+  const firstLine = symbol.content.split('\n')[0].trim();
+  return {
+    name: symbol.name,
+    kind: symbol.kind,
+    lines: `${symbol.startLine}-${symbol.endLine}`,
+    signature:
+      firstLine.length > MAX_SIGNATURE_LENGTH
+        ? `${firstLine.slice(0, MAX_SIGNATURE_LENGTH)}...`
+        : firstLine
+  };
+}
+
+/**
+ * Resolve enclosing symbols for each changed file. Content is read at `head` when given,
+ * otherwise from the working tree; deleted files are reported without symbols.
+ */
+export async function buildDiffFileContexts(
+  rootPath: string,
+  files: DiffFile[],
+  options: { head?: string; maxSymbolsPerFile?: number } = {}
+): Promise<DiffFileContext[]> {
+  const maxSymbols = options.maxSymbolsPerFile ?? 10;
+  const contexts: DiffFileContext[] = [];
+
+  for (const file of files) {
+    let changedSymbols: ChangedSymbol[] = [];
+
+    if (file.status !== 'deleted' && file.hunks.length > 0) {
+      const content = options.head
+        ? await readGitFileAtRef(rootPath, options.head, file.path)
+        : await readTextFile(path.join(rootPath, file.path)).catch(() => null);
+
+      if (content !== null) {
+        const extraction = await extractTreeSitterSymbols(
+          content,
+          detectLanguage(file.path, content)
+        );
+        if (extraction) {
+          changedSymbols = findChangedSymbols(extraction.symbols, file.hunks)
+            .slice(0, maxSymbols)
+            .map(toChangedSymbol);
+        }
+      }
+    }
+
+    contexts.push({ ...file, changedSymbols });
+  }
+
+  return contexts;
+}
@@ -0,0 +1,157 @@
+/**
+ * Result diversification: a per-file cap and maximal marginal relevance (MMR), so a result
+ * set is not five near-identical chunks of one file or module.
+ *
+ * MMR picks results one at a time by `lambda * relevance - (1 - lambda) * similarity` to the
+ * ones already picked. Similarity mixes token overlap of the chunk text with path closeness
+ * (same file, same directory). `lambda: 1` is plain relevance order.
+ *
+ * Defaults keep the ranking as it was: one chunk per file, no MMR. Projects change them
+ * under `search.diversity` in `.codebase-context/config.json`; a query can override both.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  PROJECT_CONFIG_FILENAME
+} from '../constants/codebase-context.js';
+
+export interface DiversityOptions {
+  /** Relevance vs. novelty trade-off in [0, 1]; 1 turns MMR off */
+  lambda?: number;
+  /** Most chunks returned from one file */
+  maxPerFile?: number;
+}
+
+export const DEFAULT_DIVERSITY: Required<DiversityOptions> = { lambda: 1, maxPerFile: 1 };
+
+const MAX_PER_FILE_CAP = 10;
+
+export interface DiversityCandidate {
+  filePath: string;
+  score: number;
+  snippet?: string;
+  summary?: string;
+}
+
+/** Later sources override earlier ones; out-of-range values are ignored */
+export function resolveDiversity(
+  ...sources: Array<DiversityOptions | undefined>
+): Required<DiversityOptions> {
+  const resolved = { ...DEFAULT_DIVERSITY };
+  for (const source of sources) {
+    if (!source) continue;
+    const { lambda, maxPerFile } = source;
+    if (typeof lambda === 'number' && lambda >= 0 && lambda <= 1) resolved.lambda = lambda;
+    if (typeof maxPerFile === 'number' && Number.isFinite(maxPerFile) && maxPerFile >= 1) {
+      resolved.maxPerFile = Math.min(Math.floor(maxPerFile), MAX_PER_FILE_CAP);
+    }
+  }
+  return resolved;
+}
+
+/** The `search.diversity` key of `.codebase-context/config.json`, if any */
+export async function loadProjectDiversityConfig(
+  rootPath: string
+): Promise<DiversityOptions | undefined> {
+  const configPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME);
+  try {
+    const parsed = JSON.parse(await fs.readFile(configPath, 'utf-8')) as { search?: unknown };
+    const diversity = (parsed.search as { diversity?: unknown } | undefined)?.diversity;
+    if (!diversity || typeof diversity !== 'object') return undefined;
+    const { lambda, maxPerFile } = diversity as Record<string, unknown>;
+    return {
+      ...(typeof lambda === 'number' ? { lambda } : {}),
+      ...(typeof maxPerFile === 'number' ? { maxPerFile } : {})
+    };
+  } catch {
+    return undefined;
+  }
+}
+
+function normalizePath(filePath: string): string {
+  return filePath.toLowerCase().replace(/\\/g, '/');
+}
+
+function tokenSet(candidate: DiversityCandidate): Set<string> {
+  const text = `${candidate.snippet ?? ''} ${candidate.summary ?? ''}`.toLowerCase();
+  return new Set(text.match(/[a-z_][a-z0-9_]{2,}/g) ?? []);
+}
+
+function jaccard(a: Set<string>, b: Set<string>): number {
+  if (a.size === 0 || b.size === 0) return 0;
+  let shared = 0;
+  for (const token of a) if (b.has(token)) shared++;
+  return shared / (a.size + b.size - shared);
+}
+
+function pathCloseness(a: string, b: string): number {
+  if (a === b) return 1;
+  const dirA = path.posix.dirname(a);
+  const dirB = path.posix.dirname(b);
+  if (dirA === dirB) return 0.6;
+  return path.posix.dirname(dirA) === path.posix.dirname(dirB) ? 0.3 : 0;
+}
+
+/**
+ * Up to `limit` candidates (sorted best first) with at most `maxPerFile` per file, picked by
+ * MMR when `lambda` < 1. With `lambda: 1` this is the relevance order, capped per file.
+ */
+export function diversifyResults<T extends DiversityCandidate>(
+  candidates: T[],
+  limit: number,
+  options: Required<DiversityOptions>
+): T[] {
+  const perFile = new Map<string, number>();
+  const underCap = (candidate: T) =>
+    (perFile.get(normalizePath(candidate.filePath)) ?? 0) < options.maxPerFile;
+  const take = (candidate: T) => {
+    const key = normalizePath(candidate.filePath);
+    perFile.set(key, (perFile.get(key) ?? 0) + 1);
+  };
+
+  if (options.lambda >= 1) {
+    const picked: T[] = [];
+    for (const candidate of candidates) {
+      if (picked.length >= limit) break;
+      if (!underCap(candidate)) continue;
+      take(candidate);
+      picked.push(candidate);
+    }
+    return picked;
+  }
+
+  const maxScore = candidates.reduce((max, c) => Math.max(max, c.score), 0) || 1;
+  const features = new Map(
+    candidates.map((c) => [c, { tokens: tokenSet(c), file: normalizePath(c.filePath) }])
+  );
+  const similarity = (a: T, b: T) => {
+    const fa = features.get(a)!;
+    const fb = features.get(b)!;
+    return 0.5 * jaccard(fa.tokens, fb.tokens) + 0.5 * pathCloseness(fa.file, fb.file);
+  };
+
+  const picked: T[] = [];
+  const remaining = [...candidates];
+  while (picked.length < limit) {
+    let bestIndex = -1;
+    let bestValue = -Infinity;
+    for (let i = 0; i < remaining.length; i++) {
+      const candidate = remaining[i];
+      if (!underCap(candidate)) continue;
+      const redundancy = picked.reduce((max, p) => Math.max(max, similarity(candidate, p)), 0);
+      const value =
+        options.lambda * (candidate.score / maxScore) - (1 - options.lambda) * redundancy;
+      if (value > bestValue) {
+        bestIndex = i;
+        bestValue = value;
+      }
+    }
+    if (bestIndex < 0) break;
+    const [candidate] = remaining.splice(bestIndex, 1);
+    take(candidate);
+    picked.push(candidate);
+  }
+  return picked;
+}
@@ -0,0 +1,134 @@
+/**
+ * Embedding cache keyed by SHA-256 of the exact text sent to the embedding provider.
+ *
+ * Lives next to the active index (not in the staging dir), so full rebuilds reuse vectors for
+ * every chunk whose embedding input didn't change. Scoped to one provider/model: a different
+ * model or dimension count discards the whole cache.
+ */
+
+import { createHash } from 'crypto';
+import { promises as fs } from 'fs';
+import path from 'path';
+import { EMBEDDING_CACHE_FILENAME } from '../constants/codebase-context.js';
+
+const CACHE_VERSION = 1;
+
+interface PersistedEmbeddingCache {
+  version: number;
+  model: string;
+  dimensions: number;
+  /** hash -> base64 little-endian Float32 vector */
+  entries: Record<string, string>;
+}
+
+export function hashEmbeddingInput(text: string): string {
+  return createHash('sha256').update(text).digest('hex');
+}
+
+function encodeVector(vector: number[]): string {
+  return Buffer.from(new Float32Array(vector).buffer).toString('base64');
+}
+
+function decodeVector(encoded: string, dimensions: number): number[] | null {
+  const buffer = Buffer.from(encoded, 'base64');
+  if (buffer.length !== dimensions * 4) return null;
+  const floats = new Float32Array(buffer.buffer, buffer.byteOffset, dimensions);
+  return Array.from(floats);
+}
+
+export class EmbeddingCache {
+  private entries: Map<string, string>;
+  private used = new Set<string>();
+  private dirty = false;
+
+  hits = 0;
+  misses = 0;
+
+  private constructor(
+    private filePath: string,
+    private model: string,
+    private dimensions: number,
+    entries: Map<string, string>
+  ) {
+    this.entries = entries;
+  }
+
+  /**
+   * Load the cache for `model`. A missing, unreadable or foreign-model cache yields an empty one.
+   * `model` should identify provider and model, e.g. `transformers:Xenova/bge-small-en-v1.5`.
+   */
+  static async load(
+    contextDir: string,
+    model: string,
+    dimensions: number
+  ): Promise<EmbeddingCache> {
+    const filePath = path.join(contextDir, EMBEDDING_CACHE_FILENAME);
+    let entries = new Map<string, string>();
+    try {
+      const parsed = JSON.parse(await fs.readFile(filePath, 'utf-8')) as PersistedEmbeddingCache;
+      if (
+        parsed.version === CACHE_VERSION &&
+        parsed.model === model &&
+        parsed.dimensions === dimensions &&
+        parsed.entries &&
+        typeof parsed.entries === 'object'
+      ) {
+        entries = new Map(Object.entries(parsed.entries));
+      }
+    } catch {
+      // No cache yet (or corrupt) - start empty
+    }
+    return new EmbeddingCache(filePath, model, dimensions, entries);
+  }
+
+  get size(): number {
+    return this.entries.size;
+  }
+
+  get(hash: string): number[] | null {
+    const encoded = this.entries.get(hash);
+    const vector = encoded ? decodeVector(encoded, this.dimensions) : null;
+    if (vector) {
+      this.hits++;
+      this.used.add(hash);
+    } else {
+      this.misses++;
+    }
+    return vector;
+  }
+
+  set(hash: string, vector: number[]): void {
+    if (vector.length !== this.dimensions) return;
+    this.entries.set(hash, encodeVector(vector));
+    this.used.add(hash);
+    this.dirty = true;
+  }
+
+  /**
+   * Persist the cache. With `prune`, entries not read or written during this run are dropped,
+   * which keeps the file proportional to the current codebase after a full rebuild.
+   */
+  async save(options: { prune: boolean }): Promise<void> {
+    if (options.prune) {
+      for (const hash of this.entries.keys()) {
+        if (!this.used.has(hash)) {
+          this.entries.delete(hash);
+          this.dirty = true;
+        }
+      }
+    }
+    if (!this.dirty) return;
+
+    const payload: PersistedEmbeddingCache = {
+      version: CACHE_VERSION,
+      model: this.model,
+      dimensions: this.dimensions,
+      entries: Object.fromEntries(this.entries)
+    };
+    await fs.mkdir(path.dirname(this.filePath), { recursive: true });
+    const tmpPath = `${this.filePath}.tmp`;
+    await fs.writeFile(tmpPath, JSON.stringify(payload));
+    await fs.rename(tmpPath, this.filePath);
+    this.dirty = false;
+  }
+}
@@ -0,0 +1,330 @@
+/**
+ * Embedding collections: vectors from another embedding model, kept next to the main index so
+ * a model can be tried on part of the codebase without rebuilding everything.
+ *
+ * Collections are declared under `embeddingCollections` in `.codebase-context/config.json`,
+ * e.g. `{ "voyage-src": { "provider": "voyage", "model": "voyage-code-3", "include": ["src"] } }`.
+ * Each is built from the chunks already in the keyword index (all of them, or the files under
+ * `include`) into `.codebase-context/collections/<name>/` with its own embedding cache, and is
+ * queried with `search_codebase({ collection })`. Once built, a collection is refreshed after
+ * every index build; only chunks whose text changed are embedded again.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  EMBEDDING_COLLECTIONS_DIRNAME,
+  KEYWORD_INDEX_FILENAME,
+  PROJECT_CONFIG_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../constants/codebase-context.js';
+import {
+  DEFAULT_EMBEDDING_CONFIG,
+  embedInBatches,
+  providerFingerprint,
+  getEmbeddingProvider,
+  type EmbeddingConfig
+} from '../embeddings/index.js';
+import {
+  DEFAULT_STORAGE_CONFIG,
+  getStorageProvider,
+  isRemoteStorageProvider,
+  type CodeChunkWithEmbedding,
+  type StorageConfig
+} from '../storage/index.js';
+import { IndexingCancelledError } from '../errors/index.js';
+import { openIndexedChunks } from './index-encryption.js';
+import type { CodeChunk } from '../types/index.js';
+import { EmbeddingCache, hashEmbeddingInput } from './embedding-cache.js';
+import { matchesPathFilter } from './file-filters.js';
+import { type EmbeddingFingerprint, readIndexMeta } from './index-meta.js';
+import { buildEmbeddingInput } from './indexer.js';
+import { loadChunkPostProcessors } from './chunk-post-processors.js';
+
+export const COLLECTION_META_FILENAME = 'collection.json';
+
+const COLLECTION_NAME = /^[A-Za-z0-9][A-Za-z0-9._-]*$/;
+
+const EMBEDDING_PROVIDERS: ReadonlyArray<EmbeddingConfig['provider']> = [
+  'transformers',
+  'ollama',
+  'openai',
+  'azure-openai',
+  'voyage',
+  'cohere'
+];
+
+export interface EmbeddingCollectionSpec {
+  /** Provider, model and batching; API keys come from the environment as for the main index */
+  embedding: Partial<EmbeddingConfig>;
+  /** Repo-relative paths or globs the collection covers; empty for every indexed file */
+  include: string[];
+}
+
+export interface EmbeddingCollectionMeta {
+  name: string;
+  embedding: EmbeddingFingerprint;
+  include: string[];
+  files: number;
+  chunks: number;
+  /** buildId of the main index the chunks were taken from */
+  sourceBuildId: string;
+  builtAt: string;
+}
+
+export function isValidCollectionName(name: string): boolean {
+  return COLLECTION_NAME.test(name);
+}
+
+export function getCollectionDir(contextDir: string, name: string): string {
+  return path.join(contextDir, EMBEDDING_COLLECTIONS_DIRNAME, name);
+}
+
+/**
+ * Collections always live on disk next to the index: with a remote backend they use LanceDB,
+ * so trying a model never creates server-side collections.
+ */
+export function collectionStorageConfig(
+  collectionDir: string,
+  storagePath = path.join(collectionDir, VECTOR_DB_DIRNAME)
+): Partial<StorageConfig> & { path: string } {
+  const provider = DEFAULT_STORAGE_CONFIG.provider;
+  return {
+    provider: isRemoteStorageProvider(provider) ? 'lancedb' : provider,
+    path: storagePath,
+    rootPath: collectionDir
+  };
+}
+
+function sanitizeSpec(value: unknown): EmbeddingCollectionSpec | null {
+  if (!value || typeof value !== 'object' || Array.isArray(value)) return null;
+  const raw = value as Record<string, unknown>;
+  const provider = EMBEDDING_PROVIDERS.find((candidate) => candidate === raw.provider);
+  if (!provider) return null;
+  const embedding: Partial<EmbeddingConfig> = { provider };
+  for (const field of ['model', 'apiEndpoint', 'apiVersion'] as const) {
+    const text = raw[field];
+    if (typeof text === 'string' && text.trim()) embedding[field] = text.trim();
+  }
+  for (const field of ['dimensions', 'truncateDimensions', 'batchSize', 'concurrency'] as const) {
+    const count = raw[field];
+    if (typeof count === 'number' && Number.isInteger(count) && count >= 1) {
+      embedding[field] = count;
+    }
+  }
+  const include = Array.isArray(raw.include)
+    ? raw.include.filter((entry): entry is string => typeof entry === 'string' && !!entry.trim())
+    : [];
+  return { embedding, include };
+}
+
+/** The valid entries of `embeddingCollections` in `.codebase-context/config.json` */
+export async function loadEmbeddingCollectionSpecs(
+  rootPath: string
+): Promise<Record<string, EmbeddingCollectionSpec>> {
+  const configPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME);
+  const specs: Record<string, EmbeddingCollectionSpec> = {};
+  try {
+    const parsed = JSON.parse(await fs.readFile(configPath, 'utf-8')) as {
+      embeddingCollections?: unknown;
+    };
+    const declared = parsed.embeddingCollections;
+    if (!declared || typeof declared !== 'object') return specs;
+    for (const [name, value] of Object.entries(declared)) {
+      const spec = sanitizeSpec(value);
+      if (spec && isValidCollectionName(name)) specs[name] = spec;
+    }
+  } catch {
+    // No project config
+  }
+  return specs;
+}
+
+/** The built collection's metadata, or null when it hasn't been built */
+export async function readEmbeddingCollection(
+  contextDir: string,
+  name: string
+): Promise<EmbeddingCollectionMeta | null> {
+  if (!isValidCollectionName(name)) return null;
+  try {
+    const metaPath = path.join(getCollectionDir(contextDir, name), COLLECTION_META_FILENAME);
+    const parsed = JSON.parse(await fs.readFile(metaPath, 'utf-8')) as EmbeddingCollectionMeta;
+    return parsed.name === name && parsed.embedding ? parsed : null;
+  } catch {
+    return null;
+  }
+}
+
+export async function listEmbeddingCollections(
+  contextDir: string
+): Promise<EmbeddingCollectionMeta[]> {
+  let names: string[];
+  try {
+    names = await fs.readdir(path.join(contextDir, EMBEDDING_COLLECTIONS_DIRNAME));
+  } catch {
+    return [];
+  }
+  const collections = await Promise.all(
+    names.sort().map((name) => readEmbeddingCollection(contextDir, name))
+  );
+  return collections.filter((meta): meta is EmbeddingCollectionMeta => meta !== null);
+}
+
+async function readIndexedChunks(contextDir: string): Promise<CodeChunk[]> {
+  const raw = await fs.readFile(path.join(contextDir, KEYWORD_INDEX_FILENAME), 'utf-8');
+  const parsed = JSON.parse(raw) as { chunks?: CodeChunk[] };
+  return parsed.chunks ?? [];
+}
+
+function covers(include: string[], relativePath: string): boolean {
+  const normalized = relativePath.replace(/\\/g, '/');
+  return include.length === 0 || include.some((pattern) => matchesPathFilter(normalized, pattern));
+}
+
+export interface BuildCollectionOptions {
+  rootPath: string;
+  name: string;
+  /** Defaults to the project's `.codebase-context` directory */
+  contextDir?: string;
+  /** Defaults to the declaration in the project config */
+  spec?: EmbeddingCollectionSpec;
+  signal?: AbortSignal;
+}
+
+/**
+ * Embed the covered chunks of the current index with the collection's model and replace its
+ * vectors. Needs a built index; the index itself is not touched.
+ */
+export async function buildEmbeddingCollection(
+  options: BuildCollectionOptions
+): Promise<EmbeddingCollectionMeta> {
+  const { rootPath, name, signal } = options;
+  const contextDir = options.contextDir ?? path.join(rootPath, CODEBASE_CONTEXT_DIRNAME);
+  const spec = options.spec ?? (await loadEmbeddingCollectionSpecs(rootPath))[name];
+  if (!spec) {
+    throw new Error(
+      `No embedding collection '${name}' under embeddingCollections in ` +
+        `${CODEBASE_CONTEXT_DIRNAME}/${PROJECT_CONFIG_FILENAME}`
+    );
+  }
+
+  const indexMeta = await readIndexMeta(rootPath, contextDir);
+  const stored = (await readIndexedChunks(contextDir)).filter((chunk) =>
+    covers(spec.include, chunk.relativePath)
+  );
+  // Sealed chunks are embedded from their plaintext and stored sealed as they are
+  const chunks = await openIndexedChunks(stored);
+  const provider = await getEmbeddingProvider(spec.embedding);
+  const collectionDir = getCollectionDir(contextDir, name);
+  await fs.mkdir(collectionDir, { recursive: true });
+
+  const cache = await EmbeddingCache.load(
+    collectionDir,
+    `${provider.name}:${provider.modelName}`,
+    provider.dimensions
+  );
+  const postProcessors = await loadChunkPostProcessors(rootPath);
+  const withEmbeddings: CodeChunkWithEmbedding[] = [];
+  const pending: Array<{ chunk: CodeChunk; text: string; hash: string }> = [];
+  for (const [i, chunk] of chunks.entries()) {
+    const text = buildEmbeddingInput(chunk, postProcessors);
+    const hash = hashEmbeddingInput(text);
+    const cached = cache.get(hash);
+    if (cached) withEmbeddings.push({ ...stored[i], embedding: cached });
+    else pending.push({ chunk: stored[i], text, hash });
+  }
+  console.error(
+    `[collections] ${name}: embedding ${pending.length} of ${chunks.length} chunks with ` +
+      `${provider.name}:${provider.modelName}`
+  );
+
+  const {
+    batchSize = DEFAULT_EMBEDDING_CONFIG.batchSize ?? 32,
+    concurrency = DEFAULT_EMBEDDING_CONFIG.concurrency ?? 1,
+    maxRetries = DEFAULT_EMBEDDING_CONFIG.maxRetries ?? 3
+  } = spec.embedding;
+  try {
+    await embedInBatches(
+      provider,
+      pending.map((entry) => entry.text),
+      {
+        batchSize,
+        concurrency,
+        maxRetries,
+        beforeBatch: () => {
+          if (signal?.aborted) throw new IndexingCancelledError();
+        },
+        onBatch: (offset, vectors) => {
+          vectors.forEach((vector, i) => {
+            const entry = pending[offset + i];
+            cache.set(entry.hash, vector);
+            withEmbeddings.push({ ...entry.chunk, embedding: vector });
+          });
+        }
+      }
+    );
+  } catch (error) {
+    // Keep finished batches so a retry only embeds the rest
+    await cache.save({ prune: false }).catch(() => undefined);
+    throw error;
+  }
+  await cache.save({ prune: true });
+
+  // Write the new vectors next to the old ones and swap, so searches never see a partial store
+  const activeDir = path.join(collectionDir, VECTOR_DB_DIRNAME);
+  const nextDir = `${activeDir}.next`;
+  await fs.rm(nextDir, { recursive: true, force: true });
+  const storage = await getStorageProvider(collectionStorageConfig(collectionDir, nextDir));
+  try {
+    await storage.store(withEmbeddings);
+  } finally {
+    await storage.close?.();
+  }
+  await fs.rm(activeDir, { recursive: true, force: true });
+  await fs.rename(nextDir, activeDir);
+
+  const meta: EmbeddingCollectionMeta = {
+    name,
+    embedding: providerFingerprint(provider),
+    include: spec.include,
+    files: new Set(chunks.map((chunk) => chunk.relativePath)).size,
+    chunks: withEmbeddings.length,
+    sourceBuildId: indexMeta.buildId,
+    builtAt: new Date().toISOString()
+  };
+  await fs.writeFile(
+    path.join(collectionDir, COLLECTION_META_FILENAME),
+    JSON.stringify(meta, null, 2)
+  );
+  return meta;
+}
+
+/**
+ * Rebuild every collection that was built before and is still declared, after the index
+ * changed. Failures are logged and leave that collection as it was.
+ */
+export async function refreshEmbeddingCollections(
+  rootPath: string,
+  contextDir: string,
+  signal?: AbortSignal
+): Promise<string[]> {
+  const built = await listEmbeddingCollections(contextDir);
+  if (built.length === 0) return [];
+  const specs = await loadEmbeddingCollectionSpecs(rootPath);
+  const refreshed: string[] = [];
+  for (const { name } of built) {
+    const spec = specs[name];
+    if (!spec) continue;
+    try {
+      await buildEmbeddingCollection({ rootPath, contextDir, name, spec, signal });
+      refreshed.push(name);
+    } catch (error) {
+      console.error(
+        `[collections] ${name}: refresh failed, keeping the previous vectors:`,
+        error instanceof Error ? error.message : String(error)
+      );
+    }
+  }
+  return refreshed;
+}
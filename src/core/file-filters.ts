@@ -0,0 +1,131 @@
+/**
+ * File-level search filters: path glob, test exclusion, docs-only/code-only, file size and
+ * recency.
+ *
+ * They are resolved against the keyword index into the set of matching files, which storage
+ * backends receive as `filePaths` (or `excludePaths`, whichever list is shorter). Vector
+ * queries are then filtered inside the store instead of over-fetched and post-filtered.
+ */
+
+import path from 'path';
+import { matchesGlob } from '../utils/git-tree.js';
+import { isTestSourceFile } from './test-mapping.js';
+import type { CodeChunk, SearchFilters } from '../types/index.js';
+
+/** Default cap: beyond this many paths either way, results are post-filtered instead */
+export const MAX_PUSHDOWN_PATHS = 1000;
+
+export interface FileScope {
+  /** `relativePath` values (as stored) of the files that pass */
+  files: Set<string>;
+  /** The indexed files that don't */
+  excluded: string[];
+}
+
+const toPosix = (value: string) => value.replace(/\\/g, '/');
+
+const DOCUMENTATION_EXTENSIONS = new Set(['.md', '.mdx', '.markdown', '.rst', '.adoc', '.txt']);
+
+/** Prose files (design docs, ADRs, READMEs) as opposed to code and config */
+export function isDocumentationPath(relativePath: string): boolean {
+  return DOCUMENTATION_EXTENSIONS.has(path.posix.extname(relativePath).toLowerCase());
+}
+
+export function hasFileFilters(filters?: SearchFilters): boolean {
+  return Boolean(
+    filters &&
+      (filters.path ||
+        filters.excludeTests ||
+        filters.docsOnly ||
+        filters.codeOnly ||
+        filters.modifiedAfter ||
+        filters.modifiedBefore ||
+        filters.minFileSize !== undefined ||
+        filters.maxFileSize !== undefined ||
+        filters.filePaths?.length ||
+        filters.excludePaths?.length)
+  );
+}
+
+/** A pattern without glob characters scopes to that file or directory */
+export function matchesPathFilter(relativePath: string, pattern: string): boolean {
+  const normalized = toPosix(pattern).replace(/^\.?\//, '');
+  if (/[*?{]/.test(normalized)) return matchesGlob(relativePath, normalized);
+  const dir = normalized.replace(/\/+$/, '');
+  return relativePath === dir || relativePath.startsWith(`${dir}/`);
+}
+
+function listed(chunk: CodeChunk, paths: string[]): boolean {
+  const relativePath = toPosix(chunk.relativePath);
+  return paths.some((p) => p === chunk.filePath || toPosix(p) === relativePath);
+}
+
+/** True when the chunk's file passes every file-level filter */
+export function matchesFileFilters(chunk: CodeChunk, filters: SearchFilters): boolean {
+  const relativePath = toPosix(chunk.relativePath);
+  if (filters.path && !matchesPathFilter(relativePath, filters.path)) return false;
+  if (filters.excludeTests && isTestSourceFile(relativePath)) return false;
+  if (filters.docsOnly && !isDocumentationPath(relativePath)) return false;
+  if (filters.codeOnly && isDocumentationPath(relativePath)) return false;
+  if (filters.filePaths?.length && !listed(chunk, filters.filePaths)) return false;
+  if (filters.excludePaths?.length && listed(chunk, filters.excludePaths)) return false;
+
+  const size = chunk.metadata?.fileSize;
+  if (filters.minFileSize !== undefined && (size === undefined || size < filters.minFileSize)) {
+    return false;
+  }
+  if (filters.maxFileSize !== undefined && (size === undefined || size > filters.maxFileSize)) {
+    return false;
+  }
+
+  if (filters.modifiedAfter || filters.modifiedBefore) {
+    // Files without a known date (indexes built before dates were recorded) never match
+    const modified = Date.parse(chunk.metadata?.lastModified ?? '');
+    if (Number.isNaN(modified)) return false;
+    if (filters.modifiedAfter && modified < Date.parse(filters.modifiedAfter)) return false;
+    if (filters.modifiedBefore && modified >= Date.parse(filters.modifiedBefore)) return false;
+  }
+  return true;
+}
+
+/** The indexed files passing the file-level filters; null when there are none to apply */
+export function resolveFileScope(chunks: CodeChunk[], filters?: SearchFilters): FileScope | null {
+  if (!filters || !hasFileFilters(filters)) return null;
+
+  const decided = new Map<string, boolean>();
+  for (const chunk of chunks) {
+    // Size, date and path are per file, so the first chunk decides for all of them
+    if (!decided.has(chunk.relativePath)) {
+      decided.set(chunk.relativePath, matchesFileFilters(chunk, filters));
+    }
+  }
+  const files = new Set<string>();
+  const excluded: string[] = [];
+  for (const [relativePath, passes] of decided) {
+    if (passes) files.add(relativePath);
+    else excluded.push(relativePath);
+  }
+  return { files, excluded };
+}
+
+/**
+ * Storage filters for a resolved scope: the matching files as `filePaths`, or the rest as
+ * `excludePaths` when that list is shorter. `pushed` is false when neither list is small
+ * enough, in which case the caller must over-fetch and post-filter.
+ */
+export function pushdownFileScope(
+  filters: SearchFilters,
+  scope: FileScope,
+  maxPaths = MAX_PUSHDOWN_PATHS
+): { filters: SearchFilters; pushed: boolean } {
+  const base: SearchFilters = { ...filters, filePaths: undefined, excludePaths: undefined };
+  if (scope.excluded.length === 0) return { filters: base, pushed: true };
+
+  if (scope.files.size <= scope.excluded.length && scope.files.size <= maxPaths) {
+    return { filters: { ...base, filePaths: [...scope.files] }, pushed: true };
+  }
+  if (scope.excluded.length <= maxPaths) {
+    return { filters: { ...base, excludePaths: scope.excluded }, pushed: true };
+  }
+  return { filters: base, pushed: false };
+}
@@ -0,0 +1,140 @@
+/**
+ * Indexed files and their chunks, read back for the `repo://` resources: the file list and a
+ * file's chunk list are paged with opaque cursors, and only files present in the keyword
+ * index can be read, so a resource URI can't reach anything indexing skipped.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME
+} from '../constants/codebase-context.js';
+import { InvalidCursorError } from '../errors/index.js';
+import { openIndexedChunks } from './index-encryption.js';
+import { withIndexReadLock } from './index-lock.js';
+import { redactSecrets, resolveRedactionOptions } from '../utils/secret-redaction.js';
+import { loadComplianceFilter } from './compliance-filters.js';
+import type { CodeChunk } from '../types/index.js';
+import { readTextFile } from '../utils/text-encoding.js';
+
+export const FILE_PAGE_SIZE = 200;
+export const CHUNK_PAGE_SIZE = 50;
+
+export interface Page<T> {
+  items: T[];
+  /** Pass back to get the next page; absent on the last one */
+  nextCursor?: string;
+  total: number;
+}
+
+export interface IndexedChunkEntry {
+  /** 0-based position among the file's chunks, in line order */
+  index: number;
+  startLine: number;
+  endLine: number;
+  symbol?: string;
+  kind?: string;
+}
+
+const encodeCursor = (offset: number) => Buffer.from(`o:${offset}`).toString('base64url');
+
+function decodeCursor(cursor: string | undefined): number {
+  if (!cursor) return 0;
+  const match = /^o:(\d+)$/.exec(Buffer.from(cursor, 'base64url').toString('utf-8'));
+  if (!match) throw new InvalidCursorError(cursor);
+  return Number(match[1]);
+}
+
+/** One page of `items` starting at `cursor` */
+export function paginate<T>(items: T[], cursor: string | undefined, pageSize: number): Page<T> {
+  const offset = decodeCursor(cursor);
+  const end = offset + pageSize;
+  return {
+    items: items.slice(offset, end),
+    ...(end < items.length ? { nextCursor: encodeCursor(end) } : {}),
+    total: items.length
+  };
+}
+
+interface LoadedIndex {
+  mtimeMs: number;
+  files: Map<string, CodeChunk[]>;
+}
+
+// Reloaded when the keyword index file changes
+const loaded = new Map<string, LoadedIndex>();
+
+/** Indexed files (repo-relative posix paths, sorted) to their chunks; null without an index */
+export async function loadIndexedFiles(
+  rootPath: string
+): Promise<Map<string, CodeChunk[]> | null> {
+  const indexPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME);
+  const read = await withIndexReadLock(path.dirname(indexPath), async () => {
+    try {
+      const mtimeMs = (await fs.stat(indexPath)).mtimeMs;
+      const cached = loaded.get(rootPath);
+      if (cached && cached.mtimeMs === mtimeMs) return { mtimeMs, files: cached.files };
+      return { mtimeMs, raw: await fs.readFile(indexPath, 'utf-8') };
+    } catch {
+      return null;
+    }
+  });
+  if (!read) return null;
+  if ('files' in read) return read.files;
+  const { mtimeMs } = read;
+
+  let chunks: CodeChunk[];
+  try {
+    chunks = (JSON.parse(read.raw) as { chunks?: CodeChunk[] }).chunks ?? [];
+  } catch {
+    return null;
+  }
+  chunks = await openIndexedChunks(chunks);
+
+  const byFile = new Map<string, CodeChunk[]>();
+  for (const chunk of chunks) {
+    // filePath is absolute; relativePath is relative to the indexer's working directory
+    const file = path.relative(rootPath, chunk.filePath).replace(/\\/g, '/');
+    const list = byFile.get(file) ?? [];
+    list.push(chunk);
+    byFile.set(file, list);
+  }
+  const files = new Map(
+    [...byFile.entries()]
+      .sort(([a], [b]) => a.localeCompare(b))
+      .map(([file, list]) => [file, list.sort((a, b) => a.startLine - b.startLine)] as const)
+  );
+  loaded.set(rootPath, { mtimeMs, files });
+  return files;
+}
+
+export function describeChunk(chunk: CodeChunk, index: number): IndexedChunkEntry {
+  const kind = chunk.metadata?.symbolKind ?? chunk.componentType;
+  return {
+    index,
+    startLine: chunk.startLine,
+    endLine: chunk.endLine,
+    ...(chunk.metadata?.symbolName ? { symbol: chunk.metadata.symbolName } : {}),
+    ...(kind ? { kind } : {})
+  };
+}
+
+/**
+ * Current content of an indexed file with secrets and compliance matches masked; null when it
+ * isn't indexed
+ */
+export async function readIndexedFile(
+  rootPath: string,
+  files: Map<string, CodeChunk[]>,
+  relativePath: string
+): Promise<string | null> {
+  if (!files.has(relativePath)) return null;
+  try {
+    const content = await readTextFile(path.join(rootPath, relativePath));
+    const compliance = await loadComplianceFilter(rootPath);
+    return compliance.mask(redactSecrets(content, resolveRedactionOptions()).text).text;
+  } catch {
+    return null;
+  }
+}
@@ -0,0 +1,335 @@
+/**
+ * Structured per-file summaries for `summarize_file`.
+ *
+ * Exports, symbols and import edges come from the index on every call, so they stay in step
+ * with refreshes. Purpose and responsibilities are either derived from doc comments and
+ * symbol names, or written by the client's model through MCP sampling. Sampled text is
+ * cached under `.codebase-context/` keyed by the file's content hash, so it is regenerated
+ * only after the file changes.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  FILE_SUMMARIES_FILENAME,
+  KEYWORD_INDEX_FILENAME
+} from '../constants/codebase-context.js';
+import { loadDependencyGraph } from './dependency-graph.js';
+import { decodeArtifact, encodeArtifact, openIndexedChunks } from './index-encryption.js';
+import { readIndexArtifact } from './index-lock.js';
+import { hashFileContent } from './manifest.js';
+import {
+  loadSymbolIndex,
+  topLevelDefinitions,
+  type SymbolDefinition
+} from './symbol-index.js';
+import { detectLanguage } from '../utils/language-detection.js';
+import { redactSecrets, resolveRedactionOptions } from '../utils/secret-redaction.js';
+import { loadComplianceFilter } from './compliance-filters.js';
+import type { CodeChunk, Sampler } from '../types/index.js';
+import { readTextFile } from '../utils/text-encoding.js';
+
+const CACHE_VERSION = 1;
+const MAX_EXPORTS = 20;
+const MAX_SYMBOLS = 20;
+const MAX_DEPENDENCIES = 15;
+const MAX_USED_BY = 10;
+const MAX_RESPONSIBILITIES = 5;
+const MAX_SENTENCE_LENGTH = 200;
+/** Source characters sent to the client's model */
+const MAX_SAMPLED_CHARS = 24_000;
+const SAMPLING_MAX_TOKENS = 400;
+
+export interface SummaryText {
+  purpose: string;
+  responsibilities: string[];
+}
+
+export interface FileSummary extends SummaryText {
+  file: string;
+  language: string;
+  lines: number;
+  /** First 16 hex chars of the content's SHA-256 */
+  hash: string;
+  /** `sampling` when written by the client's model, `static` when derived locally */
+  source: 'sampling' | 'static';
+  /** The sampled text was reused from the cache */
+  cached: boolean;
+  role?: { framework?: string; componentType?: string; layer?: string };
+  exports: string[];
+  symbols: Array<{ name: string; kind: string; line: number }>;
+  dependencies: { files: string[]; packages: string[] };
+  usedBy: { total: number; files: string[] };
+  /** Why sampling was not used although a sampler was available */
+  samplingError?: string;
+}
+
+export interface SummarizeFileOptions {
+  /** MCP sampling hook; without it the summary is derived locally */
+  sample?: Sampler;
+  /** Ignore the cache and regenerate the sampled text */
+  refresh?: boolean;
+  /** Save sampled summaries to the cache (default: true; off on read-only servers) */
+  persist?: boolean;
+}
+
+interface CachedSummary extends SummaryText {
+  hash: string;
+  generatedAt: string;
+}
+
+interface SummaryCacheFile {
+  version: number;
+  files: Record<string, CachedSummary>;
+}
+
+/** Repo-relative posix path for `file`, or null when it points outside the project. */
+export function toProjectRelativePath(rootPath: string, file: string): string | null {
+  const absolute = path.resolve(rootPath, file.trim());
+  const relative = path.relative(path.resolve(rootPath), absolute);
+  if (!relative || relative.startsWith('..') || path.isAbsolute(relative)) return null;
+  return relative.split(path.sep).join('/');
+}
+
+/** First sentence of `text`, whitespace collapsed and capped at 200 characters */
+export function firstSentence(text: string): string {
+  const collapsed = text.replace(/\s+/g, ' ').trim();
+  const match = collapsed.match(/^(.+?[.!?])(\s|$)/);
+  const sentence = match ? match[1] : collapsed;
+  return sentence.length > MAX_SENTENCE_LENGTH
+    ? `${sentence.slice(0, MAX_SENTENCE_LENGTH - 1)}…`
+    : sentence;
+}
+
+function stripCommentMarkers(lines: string[]): string {
+  return lines
+    .map((line) =>
+      line
+        .trim()
+        .replace(/^(\/\*\*?|\*\/|\*|\/\/\/?|#+|"""|''')\s?/, '')
+        .replace(/\*\/$|"""$|'''$/, '')
+        .trim()
+    )
+    .filter((line) => line && !line.startsWith('@'))
+    .join(' ');
+}
+
+/** First sentence of the comment block at the top of the file (after shebang/blank lines). */
+export function leadingDocComment(lines: string[]): string | undefined {
+  let i = 0;
+  while (i < lines.length && (!lines[i].trim() || lines[i].startsWith('#!'))) i++;
+  const first = lines[i]?.trim() ?? '';
+  const block: string[] = [];
+
+  if (first.startsWith('/*') || first.startsWith('"""') || first.startsWith("'''")) {
+    const close = first.startsWith('/*') ? '*/' : first.slice(0, 3);
+    for (let j = i; j < lines.length; j++) {
+      block.push(lines[j]);
+      if ((j > i || first.length > 3) && lines[j].trim().endsWith(close)) break;
+    }
+  } else if (/^(\/\/|#(?!include|define|import|pragma|\[))/.test(first)) {
+    for (let j = i; j < lines.length && /^\s*(\/\/|#)/.test(lines[j]); j++) block.push(lines[j]);
+  }
+
+  const text = stripCommentMarkers(block);
+  return text ? firstSentence(text) : undefined;
+}
+
+/** First sentence of the comment directly above 1-based `line`, skipping decorators. */
+function commentAbove(lines: string[], line: number): string | undefined {
+  const block: string[] = [];
+  let i = line - 2;
+  while (i >= 0 && /^\s*@/.test(lines[i])) i--;
+  for (; i >= 0; i--) {
+    const trimmed = lines[i].trim();
+    if (!/^(\/\/|\/\*|\*|#)/.test(trimmed)) break;
+    block.unshift(lines[i]);
+    if (trimmed.startsWith('/*')) break;
+  }
+  const text = stripCommentMarkers(block);
+  return text ? firstSentence(text) : undefined;
+}
+
+async function loadFileChunks(rootPath: string, relativeFile: string): Promise<CodeChunk[]> {
+  let chunks: CodeChunk[];
+  try {
+    const raw = await readIndexArtifact(
+      path.join(rootPath, CODEBASE_CONTEXT_DIRNAME),
+      KEYWORD_INDEX_FILENAME
+    );
+    chunks = (JSON.parse(raw) as { chunks?: CodeChunk[] }).chunks ?? [];
+  } catch {
+    return [];
+  }
+  return openIndexedChunks(
+    chunks.filter((chunk) => chunk.relativePath?.replace(/\\/g, '/') === relativeFile)
+  );
+}
+
+function staticText(
+  lines: string[],
+  symbols: SymbolDefinition[],
+  role: FileSummary['role'],
+  language: string
+): SummaryText {
+  const responsibilities = symbols.slice(0, MAX_RESPONSIBILITIES).map((def) => {
+    const doc = commentAbove(lines, def.startLine);
+    return doc ? `${def.name} (${def.kind}): ${doc}` : `${def.name} (${def.kind})`;
+  });
+
+  let purpose = leadingDocComment(lines);
+  if (!purpose && role?.componentType) {
+    const kind = [role.framework, role.componentType].filter(Boolean).join(' ');
+    const layer = role.layer && role.layer !== 'unknown' ? ` in the ${role.layer} layer` : '';
+    purpose = `${kind}${layer}.`;
+  }
+  if (!purpose && symbols.length > 0) {
+    const names = symbols.slice(0, 3).map((def) => def.name);
+    const more = symbols.length > 3 ? ` and ${symbols.length - 3} more` : '';
+    purpose = `Defines ${names.join(', ')}${more}.`;
+  }
+  return { purpose: purpose ?? `${language} file.`, responsibilities };
+}
+
+function parseSampledText(reply: string): SummaryText | null {
+  const json = reply.match(/\{[\s\S]*\}/);
+  if (!json) return null;
+  try {
+    const parsed = JSON.parse(json[0]) as { purpose?: unknown; responsibilities?: unknown };
+    if (typeof parsed.purpose !== 'string' || !parsed.purpose.trim()) return null;
+    const responsibilities = Array.isArray(parsed.responsibilities)
+      ? parsed.responsibilities.filter((r): r is string => typeof r === 'string' && !!r.trim())
+      : [];
+    return {
+      purpose: parsed.purpose.trim(),
+      responsibilities: responsibilities.slice(0, MAX_RESPONSIBILITIES).map((r) => r.trim())
+    };
+  } catch {
+    return null;
+  }
+}
+
+function buildSamplingPrompt(summary: FileSummary, content: string): string {
+  const facts = {
+    exports: summary.exports,
+    symbols: summary.symbols.map((s) => `${s.kind} ${s.name}`),
+    imports: [...summary.dependencies.files, ...summary.dependencies.packages]
+  };
+  const source =
+    content.length > MAX_SAMPLED_CHARS ? `${content.slice(0, MAX_SAMPLED_CHARS)}\n…` : content;
+  return [
+    `Summarize the file ${summary.file} for a developer who has not read it.`,
+    `Known facts: ${JSON.stringify(facts)}`,
+    'Reply with JSON only: {"purpose": "<one sentence>", "responsibilities": ' +
+      `["<short phrase>", ... at most ${MAX_RESPONSIBILITIES}]}`,
+    '',
+    '<file>',
+    source,
+    '</file>'
+  ].join('\n');
+}
+
+async function readCache(file: string): Promise<SummaryCacheFile> {
+  try {
+    const raw = await fs.readFile(file, 'utf-8');
+    const parsed = (await decodeArtifact(raw, 'File summaries')) as Partial<SummaryCacheFile>;
+    if (parsed.version === CACHE_VERSION && parsed.files && typeof parsed.files === 'object') {
+      return { version: CACHE_VERSION, files: parsed.files };
+    }
+  } catch {
+    // Missing or unreadable: start empty
+  }
+  return { version: CACHE_VERSION, files: {} };
+}
+
+async function writeCache(file: string, cache: SummaryCacheFile): Promise<void> {
+  await fs.mkdir(path.dirname(file), { recursive: true });
+  const tmp = `${file}.${process.pid}.tmp`;
+  await fs.writeFile(tmp, await encodeArtifact(cache));
+  await fs.rename(tmp, file);
+}
+
+/**
+ * Summarize `relativeFile` (repo-relative posix path). Throws when the file can't be read.
+ */
+export async function summarizeFile(
+  rootPath: string,
+  relativeFile: string,
+  options: SummarizeFileOptions = {}
+): Promise<FileSummary> {
+  const content = await readTextFile(path.join(rootPath, relativeFile));
+  const lines = content.split(/\r?\n/);
+  const hash = hashFileContent(content);
+
+  const [definitions, graph, chunks] = await Promise.all([
+    loadSymbolIndex(rootPath),
+    loadDependencyGraph(rootPath),
+    loadFileChunks(rootPath, relativeFile)
+  ]);
+  const symbols = topLevelDefinitions((definitions ?? []).filter((d) => d.file === relativeFile));
+  const roleChunk = chunks.find((chunk) => chunk.componentType || chunk.framework);
+  const role: FileSummary['role'] = roleChunk
+    ? {
+        ...(roleChunk.framework ? { framework: roleChunk.framework } : {}),
+        ...(roleChunk.componentType ? { componentType: roleChunk.componentType } : {}),
+        ...(roleChunk.layer ? { layer: roleChunk.layer } : {})
+      }
+    : undefined;
+  const language = chunks[0]?.language ?? symbols[0]?.language ?? detectLanguage(relativeFile);
+  const usedBy = graph?.importedBy[relativeFile] ?? [];
+  const exportNames = (graph?.exports?.[relativeFile] ?? []).map((exp) => exp.name);
+
+  const summary: FileSummary = {
+    file: relativeFile,
+    language,
+    lines: lines.length,
+    hash,
+    source: 'static',
+    cached: false,
+    ...(role ? { role } : {}),
+    ...staticText(lines, symbols, role, language),
+    exports: [...new Set(exportNames)].slice(0, MAX_EXPORTS),
+    symbols: symbols
+      .slice(0, MAX_SYMBOLS)
+      .map((def) => ({ name: def.name, kind: def.kind, line: def.startLine })),
+    dependencies: {
+      files: (graph?.imports[relativeFile] ?? []).slice(0, MAX_DEPENDENCIES),
+      packages: (graph?.externalImports?.[relativeFile] ?? []).slice(0, MAX_DEPENDENCIES)
+    },
+    usedBy: { total: usedBy.length, files: usedBy.slice(0, MAX_USED_BY) }
+  };
+
+  if (!options.sample) return summary;
+
+  const cacheFile = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, FILE_SUMMARIES_FILENAME);
+  const cache = await readCache(cacheFile);
+  const cached = cache.files[relativeFile];
+  if (!options.refresh && cached?.hash === hash) {
+    return {
+      ...summary,
+      purpose: cached.purpose,
+      responsibilities: cached.responsibilities,
+      source: 'sampling',
+      cached: true
+    };
+  }
+
+  try {
+    const compliance = await loadComplianceFilter(rootPath);
+    const redacted = compliance.mask(redactSecrets(content, resolveRedactionOptions()).text).text;
+    const reply = await options.sample({
+      systemPrompt: 'You summarize source files precisely and briefly. Answer with JSON only.',
+      prompt: buildSamplingPrompt(summary, redacted),
+      maxTokens: SAMPLING_MAX_TOKENS
+    });
+    const text = parseSampledText(reply);
+    if (!text) return { ...summary, samplingError: 'Model reply was not the requested JSON' };
+
+    cache.files[relativeFile] = { ...text, hash, generatedAt: new Date().toISOString() };
+    if (options.persist !== false) await writeCache(cacheFile, cache);
+    return { ...summary, ...text, source: 'sampling' };
+  } catch (error) {
+    return { ...summary, samplingError: error instanceof Error ? error.message : String(error) };
+  }
+}
@@ -1,6 +1,7 @@
 import chokidar from 'chokidar';
 import path from 'path';
 import { getSupportedExtensions } from '../utils/language-detection.js';
+import { grammarPluginForExtension } from '../grammars/plugins.js';
 
 export interface FileWatcherOptions {
   rootPath: string;
@@ -8,37 +9,48 @@ export interface FileWatcherOptions {
   debounceMs?: number;
   /** Called once chokidar finishes initial scan and starts emitting change events */
   onReady?: () => void;
-  /** Called once the debounce window expires after the last detected change */
-  onChanged: () => void;
+  /**
+   * Called once the debounce window expires after the last detected change.
+   * Receives the absolute paths added, changed or removed during the window.
+   */
+  onChanged: (changedPaths: string[]) => void;
 }
 
 const TRACKED_EXTENSIONS = new Set(
   getSupportedExtensions().map((extension) => extension.toLowerCase())
 );
 
-const TRACKED_METADATA_FILES = new Set(['.gitignore']);
+const TRACKED_METADATA_FILES = new Set(['.gitignore', '.mcpignore']);
 
 function isTrackedSourcePath(filePath: string): boolean {
   const basename = path.basename(filePath).toLowerCase();
   if (TRACKED_METADATA_FILES.has(basename)) return true;
   const extension = path.extname(filePath).toLowerCase();
-  return extension.length > 0 && TRACKED_EXTENSIONS.has(extension);
+  // Grammar plugins load after this module, at startup
+  return (
+    extension.length > 0 &&
+    (TRACKED_EXTENSIONS.has(extension) || grammarPluginForExtension(extension) !== undefined)
+  );
 }
 
 /**
- * Watch rootPath for source file changes and call onChanged (debounced).
+ * Watch rootPath for source file changes and call onChanged (debounced) with the paths touched.
  * Returns a stop() function that cancels the debounce timer and closes the watcher.
  */
 export function startFileWatcher(opts: FileWatcherOptions): () => void {
   const { rootPath, debounceMs = 2000, onReady, onChanged } = opts;
   let debounceTimer: ReturnType<typeof setTimeout> | undefined;
+  const pendingPaths = new Set<string>();
 
   const trigger = (filePath: string) => {
     if (!isTrackedSourcePath(filePath)) return;
+    pendingPaths.add(path.resolve(rootPath, filePath));
     if (debounceTimer !== undefined) clearTimeout(debounceTimer);
     debounceTimer = setTimeout(() => {
       debounceTimer = undefined;
-      onChanged();
+      const changedPaths = [...pendingPaths];
+      pendingPaths.clear();
+      onChanged(changedPaths);
     }, debounceMs);
   };
 
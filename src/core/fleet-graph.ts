@@ -0,0 +1,217 @@
+/**
+ * Cross-repository links between workspace projects (`CODEBASE_ROOTS`): the packages each
+ * project publishes (npm names, Go module paths, crate names) and which other projects depend
+ * on them according to their manifests. A fleet of services sharing client libraries becomes
+ * one graph, so a symbol defined in a library can be followed into every service using it.
+ *
+ * Each project keeps its own index; references are found per project and joined here.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import {
+  detectWorkspacePackages,
+  parseCargoDependencies,
+  parseCargoPackageName,
+  parseGoModulePath,
+  parseGoRequires,
+  scanWorkspacePackageJsons,
+  type WorkspacePackageJson
+} from '../utils/workspace-detection.js';
+import type { WorkspaceProject } from './workspace.js';
+
+export interface ProjectPackages {
+  /** Names other projects can depend on */
+  publishes: string[];
+  /** Package names the project's manifests declare as dependencies */
+  dependsOn: string[];
+}
+
+export interface FleetLink {
+  project: string;
+  /** Packages of `project` this one depends on */
+  packages: string[];
+}
+
+export interface FleetGraph {
+  /** Project name -> packages it publishes and the projects it depends on */
+  projects: Record<string, { publishes: string[]; dependsOn: FleetLink[] }>;
+}
+
+async function readText(file: string): Promise<string | null> {
+  try {
+    return await fs.readFile(file, 'utf-8');
+  } catch {
+    return null;
+  }
+}
+
+type NpmManifest = Omit<WorkspacePackageJson, 'filePath'>;
+
+function addNpmManifest(
+  pkg: NpmManifest,
+  publishes: Set<string>,
+  dependsOn: Set<string>
+): void {
+  if (pkg.name) publishes.add(pkg.name);
+  for (const deps of [pkg.dependencies, pkg.devDependencies, pkg.peerDependencies]) {
+    for (const name of Object.keys(deps ?? {})) dependsOn.add(name);
+  }
+}
+
+/** Crate names are matched with `-` and `_` interchangeable, as Cargo does */
+function normalizeCrate(name: string): string {
+  return name.replace(/_/g, '-');
+}
+
+/** Published and depended-on package names from the root and workspace manifests */
+export async function readProjectPackages(rootPath: string): Promise<ProjectPackages> {
+  const publishes = new Set<string>();
+  const dependsOn = new Set<string>();
+
+  for (const pkg of await scanWorkspacePackageJsons(rootPath)) {
+    addNpmManifest(pkg, publishes, dependsOn);
+  }
+
+  const packages = await detectWorkspacePackages(rootPath);
+  const directories = [
+    '',
+    ...packages.filter((pkg) => pkg.ecosystem !== 'bazel').map((pkg) => pkg.directory)
+  ];
+  for (const pkg of packages) {
+    if (pkg.ecosystem !== 'bazel') publishes.add(pkg.name);
+  }
+  for (const directory of new Set(directories)) {
+    // Workspace members outside the conventional apps/, packages/ and libs/ folders
+    const packageJson = await readText(path.join(rootPath, directory, 'package.json'));
+    if (packageJson) {
+      try {
+        addNpmManifest(JSON.parse(packageJson) as NpmManifest, publishes, dependsOn);
+      } catch {
+        // skip
+      }
+    }
+    const goMod = await readText(path.join(rootPath, directory, 'go.mod'));
+    if (goMod) {
+      const module = parseGoModulePath(goMod);
+      if (module) publishes.add(module);
+      for (const required of parseGoRequires(goMod)) dependsOn.add(required);
+    }
+    const cargo = await readText(path.join(rootPath, directory, 'Cargo.toml'));
+    if (cargo) {
+      const crate = parseCargoPackageName(cargo);
+      if (crate) publishes.add(normalizeCrate(crate));
+      for (const name of parseCargoDependencies(cargo)) dependsOn.add(normalizeCrate(name));
+    }
+  }
+
+  // A project's own packages are not dependencies on another project
+  for (const name of publishes) dependsOn.delete(name);
+  return { publishes: [...publishes].sort(), dependsOn: [...dependsOn].sort() };
+}
+
+/** `dependency` names `published`, or a package inside it (Go modules nest by path) */
+function provides(published: string, dependency: string): boolean {
+  return dependency === published || dependency.startsWith(`${published}/`);
+}
+
+/** Link projects whose manifests depend on packages another project publishes */
+export function linkProjects(packagesByProject: Record<string, ProjectPackages>): FleetGraph {
+  const names = Object.keys(packagesByProject);
+  const projects: FleetGraph['projects'] = {};
+  for (const name of names) {
+    const { publishes, dependsOn } = packagesByProject[name];
+    const links: FleetLink[] = [];
+    for (const other of names) {
+      if (other === name) continue;
+      const shared = packagesByProject[other].publishes.filter((published) =>
+        dependsOn.some((dependency) => provides(published, dependency))
+      );
+      if (shared.length > 0) links.push({ project: other, packages: shared });
+    }
+    projects[name] = { publishes, dependsOn: links };
+  }
+  return { projects };
+}
+
+export async function buildFleetGraph(projects: readonly WorkspaceProject[]): Promise<FleetGraph> {
+  const packagesByProject: Record<string, ProjectPackages> = {};
+  for (const project of projects) {
+    packagesByProject[project.name] = await readProjectPackages(project.rootPath);
+  }
+  return linkProjects(packagesByProject);
+}
+
+/** Projects that depend on `project`, directly or through other projects */
+export function projectDependents(graph: FleetGraph, project: string): string[] {
+  const found = new Set<string>();
+  let frontier = [project];
+  while (frontier.length > 0) {
+    const next: string[] = [];
+    for (const [name, entry] of Object.entries(graph.projects)) {
+      if (name === project || found.has(name)) continue;
+      if (entry.dependsOn.some((link) => frontier.includes(link.project))) {
+        found.add(name);
+        next.push(name);
+      }
+    }
+    frontier = next;
+  }
+  return [...found].sort();
+}
+
+export interface ProjectReferences<T> {
+  project: string;
+  /** Declaration sites the project's own index found */
+  definitions: string[];
+  references: T[];
+}
+
+export interface FleetReferences<T> {
+  /** Projects defining the symbol */
+  definedIn: string[];
+  /** References in the defining projects and the projects that depend on them */
+  references: Array<T & { project: string }>;
+  /** Same-named references in projects with no dependency on a defining project */
+  unlinked: Array<{ project: string; references: number }>;
+}
+
+/**
+ * Join per-project references: a project's hits count when it defines the symbol or depends,
+ * possibly through other projects, on one that does. When no project defines it (a
+ * third-party type, say) every project's hits count.
+ */
+export function joinFleetReferences<T>(
+  graph: FleetGraph,
+  perProject: ReadonlyArray<ProjectReferences<T>>,
+  limit: number
+): FleetReferences<T> {
+  const definedIn = perProject
+    .filter((entry) => entry.definitions.length > 0)
+    .map((entry) => entry.project);
+  const linked = new Set(definedIn);
+  for (const project of definedIn) {
+    for (const dependent of projectDependents(graph, project)) linked.add(dependent);
+  }
+
+  const references: Array<T & { project: string }> = [];
+  const unlinked: FleetReferences<T>['unlinked'] = [];
+  // Defining projects first, so the declarations lead
+  const ordered = [
+    ...perProject.filter((entry) => definedIn.includes(entry.project)),
+    ...perProject.filter((entry) => !definedIn.includes(entry.project))
+  ];
+  for (const entry of ordered) {
+    if (definedIn.length > 0 && !linked.has(entry.project)) {
+      if (entry.references.length > 0) {
+        unlinked.push({ project: entry.project, references: entry.references.length });
+      }
+      continue;
+    }
+    for (const reference of entry.references) {
+      if (references.length >= limit) break;
+      references.push({ ...reference, project: entry.project });
+    }
+  }
+  return { definedIn, references, unlinked };
+}
@@ -0,0 +1,337 @@
+/**
+ * Portable index archives: build the index once (e.g. in CI), share one file, import it on
+ * another machine without re-embedding.
+ *
+ * An archive is gzipped JSON holding the index artifacts and the embedding cache. Absolute
+ * paths under the project root are stored root-relative and re-rooted on import. The vector
+ * store itself isn't copied (its files are backend-specific and path-bound); import rebuilds
+ * it from the chunks and the cached vectors, so any storage provider can be used.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import { gunzipSync, gzipSync } from 'zlib';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  EMBEDDING_CACHE_FILENAME,
+  INDEX_FORMAT_VERSION,
+  INDEX_META_FILENAME,
+  INDEXING_STATS_FILENAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MANIFEST_FILENAME,
+  RELATIONSHIPS_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../constants/codebase-context.js';
+import { readIndexMeta, validateIndexArtifacts } from './index-meta.js';
+import { EmbeddingCache, hashEmbeddingInput } from './embedding-cache.js';
+import { openIndexedChunks } from './index-encryption.js';
+import { readConsistently, withBuildLock } from './index-lock.js';
+import { atomicSwapStagingToActive, buildEmbeddingInput } from './indexer.js';
+import { loadChunkPostProcessors } from './chunk-post-processors.js';
+import {
+  getStorageProvider,
+  isRemoteStorageProvider,
+  DEFAULT_STORAGE_CONFIG
+} from '../storage/index.js';
+import { DEFAULT_EMBEDDING_CONFIG } from '../embeddings/index.js';
+import { isPathInside } from '../utils/path-normalization.js';
+import type { CodeChunk } from '../types/index.js';
+
+const ARCHIVE_FORMAT = 'codebase-context-index';
+const ARCHIVE_VERSION = 1;
+/** Stands in for the project root inside archived paths */
+const ROOT_TOKEN = '<codebase-root>';
+const BUILD_ID_PATTERN = /^[A-Za-z0-9_-]+$/;
+
+/** Artifacts that may contain absolute paths and are re-rooted on import */
+const PATH_BEARING_ARTIFACTS = [
+  KEYWORD_INDEX_FILENAME,
+  INTELLIGENCE_FILENAME,
+  RELATIONSHIPS_FILENAME
+] as const;
+const ARCHIVED_ARTIFACTS = [
+  INDEX_META_FILENAME,
+  ...PATH_BEARING_ARTIFACTS,
+  MANIFEST_FILENAME,
+  INDEXING_STATS_FILENAME
+] as const;
+
+interface IndexArchive {
+  format: typeof ARCHIVE_FORMAT;
+  archiveVersion: number;
+  formatVersion: number;
+  buildId: string;
+  toolVersion: string;
+  createdAt: string;
+  /** `provider:model` the vectors were produced with, when the archive has any */
+  embeddingModel?: string;
+  /** Artifact file name -> file content */
+  files: Record<string, string>;
+}
+
+export interface ExportResult {
+  file: string;
+  bytes: number;
+  buildId: string;
+  chunks: number;
+  embeddingModel?: string;
+}
+
+export interface ImportResult {
+  buildId: string;
+  chunks: number;
+  /** Chunks stored in the vector index with their archived vector */
+  embeddedChunks: number;
+  /** Chunks without a vector; a refresh embeds just these */
+  missingEmbeddings: number;
+  embeddingModel?: string;
+  warnings: string[];
+}
+
+function mapStrings(value: unknown, map: (text: string) => string): unknown {
+  if (typeof value === 'string') return map(value);
+  if (Array.isArray(value)) return value.map((item) => mapStrings(item, map));
+  if (value && typeof value === 'object') {
+    return Object.fromEntries(
+      Object.entries(value).map(([key, item]) => [map(key), mapStrings(item, map)])
+    );
+  }
+  return value;
+}
+
+function toArchivedPath(rootPath: string): (text: string) => string {
+  const roots = [...new Set([rootPath, rootPath.replace(/\\/g, '/')])];
+  return (text) => {
+    for (const root of roots) {
+      if (text === root) return ROOT_TOKEN;
+      if (text.startsWith(`${root}/`) || text.startsWith(`${root}\\`)) {
+        return `${ROOT_TOKEN}/${text.slice(root.length + 1).replace(/\\/g, '/')}`;
+      }
+    }
+    return text;
+  };
+}
+
+function fromArchivedPath(rootPath: string): (text: string) => string {
+  return (text) => {
+    if (text === ROOT_TOKEN) return rootPath;
+    if (!text.startsWith(`${ROOT_TOKEN}/`)) return text;
+    const resolved = path.join(rootPath, ...text.slice(ROOT_TOKEN.length + 1).split('/'));
+    // `<root>/../x` names a file outside the project the archive is imported into
+    if (!isPathInside(rootPath, resolved)) {
+      throw new Error(`Archive path ${text} points outside the project`);
+    }
+    return resolved;
+  };
+}
+
+async function readOptional(filePath: string): Promise<string | null> {
+  try {
+    return await fs.readFile(filePath, 'utf-8');
+  } catch {
+    return null;
+  }
+}
+
+function parseArchive(buffer: Buffer): IndexArchive {
+  let parsed: Partial<IndexArchive>;
+  try {
+    parsed = JSON.parse(gunzipSync(buffer).toString('utf-8')) as Partial<IndexArchive>;
+  } catch (error) {
+    const reason = error instanceof Error ? error.message : String(error);
+    throw new Error(`Not a codebase-context index archive: ${reason}`);
+  }
+  if (parsed.format !== ARCHIVE_FORMAT || !parsed.files || typeof parsed.files !== 'object') {
+    throw new Error('Not a codebase-context index archive');
+  }
+  if (parsed.archiveVersion !== ARCHIVE_VERSION) {
+    throw new Error(
+      `Unsupported archive version ${parsed.archiveVersion} (expected ${ARCHIVE_VERSION})`
+    );
+  }
+  if (parsed.formatVersion !== INDEX_FORMAT_VERSION) {
+    throw new Error(
+      `Archive index format ${parsed.formatVersion} does not match this version's ` +
+        `${INDEX_FORMAT_VERSION}; rebuild the archive with a matching codebase-context release`
+    );
+  }
+  for (const name of [INDEX_META_FILENAME, KEYWORD_INDEX_FILENAME]) {
+    if (typeof parsed.files[name] !== 'string') throw new Error(`Archive is missing ${name}`);
+  }
+  // The build id names the staging and previous-generation directories
+  if (typeof parsed.buildId !== 'string' || !BUILD_ID_PATTERN.test(parsed.buildId)) {
+    throw new Error(`Archive has an invalid build id ${JSON.stringify(parsed.buildId)}`);
+  }
+  return parsed as IndexArchive;
+}
+
+/** Write the project's current index to `outFile`, read as one generation */
+export async function exportIndex(rootPath: string, outFile: string): Promise<ExportResult> {
+  const contextDir = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME);
+  return readConsistently(contextDir, () => writeArchive(rootPath, outFile));
+}
+
+async function writeArchive(rootPath: string, outFile: string): Promise<ExportResult> {
+  const contextDir = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME);
+  const meta = await readIndexMeta(rootPath);
+  await validateIndexArtifacts(rootPath, meta);
+
+  const toArchived = toArchivedPath(path.resolve(rootPath));
+  const files: Record<string, string> = {};
+  let chunks = 0;
+  for (const name of ARCHIVED_ARTIFACTS) {
+    const content = await readOptional(path.join(contextDir, name));
+    if (content === null) continue;
+    if ((PATH_BEARING_ARTIFACTS as readonly string[]).includes(name)) {
+      const parsed = mapStrings(JSON.parse(content), toArchived);
+      if (name === KEYWORD_INDEX_FILENAME) {
+        chunks = (parsed as { chunks: unknown[] }).chunks.length;
+      }
+      files[name] = JSON.stringify(parsed);
+    } else {
+      files[name] = content;
+    }
+  }
+
+  // Vectors travel as the embedding cache, which is keyed by embedding input, not by path
+  const cache = await readOptional(path.join(contextDir, EMBEDDING_CACHE_FILENAME));
+  let embeddingModel: string | undefined;
+  if (cache !== null) {
+    files[EMBEDDING_CACHE_FILENAME] = cache;
+    embeddingModel = (JSON.parse(cache) as { model?: string }).model;
+  }
+
+  const archive: IndexArchive = {
+    format: ARCHIVE_FORMAT,
+    archiveVersion: ARCHIVE_VERSION,
+    formatVersion: meta.formatVersion,
+    buildId: meta.buildId,
+    toolVersion: meta.toolVersion,
+    createdAt: new Date().toISOString(),
+    ...(embeddingModel ? { embeddingModel } : {}),
+    files
+  };
+  const compressed = gzipSync(JSON.stringify(archive));
+  await fs.mkdir(path.dirname(path.resolve(outFile)), { recursive: true });
+  await fs.writeFile(outFile, compressed);
+
+  return {
+    file: outFile,
+    bytes: compressed.length,
+    buildId: meta.buildId,
+    chunks,
+    ...(embeddingModel ? { embeddingModel } : {})
+  };
+}
+
+/**
+ * Replace the project's index with the one in `archiveFile`. The swap is atomic: a failed
+ * import leaves the existing index in place. Waits for a build in progress to finish.
+ */
+export async function importIndex(rootPath: string, archiveFile: string): Promise<ImportResult> {
+  const contextDir = path.join(path.resolve(rootPath), CODEBASE_CONTEXT_DIRNAME);
+  return withBuildLock(contextDir, () => importArchive(rootPath, archiveFile));
+}
+
+async function importArchive(rootPath: string, archiveFile: string): Promise<ImportResult> {
+  const archive = parseArchive(await fs.readFile(archiveFile));
+  const resolvedRoot = path.resolve(rootPath);
+  const contextDir = path.join(resolvedRoot, CODEBASE_CONTEXT_DIRNAME);
+  const stagingDir = path.join(contextDir, '.staging', `import-${archive.buildId}`);
+  const fromArchived = fromArchivedPath(resolvedRoot);
+  const warnings: string[] = [];
+
+  const localModel = `${DEFAULT_EMBEDDING_CONFIG.provider}:${DEFAULT_EMBEDDING_CONFIG.model}`;
+  if (archive.embeddingModel && archive.embeddingModel !== localModel) {
+    warnings.push(
+      `Archive vectors come from ${archive.embeddingModel} but this machine is configured for ` +
+        `${localModel}; set EMBEDDING_PROVIDER/EMBEDDING_MODEL to match or semantic search ` +
+        'will be unreliable.'
+    );
+  }
+
+  await fs.rm(stagingDir, { recursive: true, force: true });
+  await fs.mkdir(stagingDir, { recursive: true });
+  try {
+    const keywordIndex = mapStrings(
+      JSON.parse(archive.files[KEYWORD_INDEX_FILENAME]),
+      fromArchived
+    ) as { header: unknown; chunks: CodeChunk[] };
+
+    for (const name of ARCHIVED_ARTIFACTS) {
+      const content = archive.files[name];
+      if (typeof content !== 'string') continue;
+      let output = content;
+      if (name === KEYWORD_INDEX_FILENAME) {
+        output = JSON.stringify(keywordIndex);
+      } else if (name === INDEX_META_FILENAME) {
+        // The vector store is rebuilt with whatever backend this machine uses
+        const meta = JSON.parse(content) as { artifacts?: { vectorDb?: { provider?: string } } };
+        if (meta.artifacts?.vectorDb) {
+          meta.artifacts.vectorDb.provider = DEFAULT_STORAGE_CONFIG.provider;
+        }
+        output = JSON.stringify(meta, null, 2);
+      } else if ((PATH_BEARING_ARTIFACTS as readonly string[]).includes(name)) {
+        output = JSON.stringify(mapStrings(JSON.parse(content), fromArchived), null, 2);
+      }
+      await fs.writeFile(path.join(stagingDir, name), output);
+    }
+
+    const cacheContent = archive.files[EMBEDDING_CACHE_FILENAME];
+    const chunksWithEmbeddings: Array<CodeChunk & { embedding: number[] }> = [];
+    if (typeof cacheContent === 'string') {
+      const { model, dimensions } = JSON.parse(cacheContent) as {
+        model: string;
+        dimensions: number;
+      };
+      await fs.writeFile(path.join(stagingDir, EMBEDDING_CACHE_FILENAME), cacheContent);
+      const cache = await EmbeddingCache.load(stagingDir, model, dimensions);
+      // Vectors are keyed by plaintext; sealed chunks are stored sealed
+      const opened = await openIndexedChunks(keywordIndex.chunks);
+      const postProcessors = await loadChunkPostProcessors(resolvedRoot);
+      for (const [i, chunk] of opened.entries()) {
+        const vector = cache.get(hashEmbeddingInput(buildEmbeddingInput(chunk, postProcessors)));
+        if (vector) chunksWithEmbeddings.push({ ...keywordIndex.chunks[i], embedding: vector });
+      }
+    }
+
+    const vectorDir = path.join(stagingDir, VECTOR_DB_DIRNAME);
+    const storage = await getStorageProvider({ path: vectorDir, rootPath: resolvedRoot });
+    if (isRemoteStorageProvider(storage.name)) await storage.clear();
+    if (chunksWithEmbeddings.length > 0) await storage.store(chunksWithEmbeddings);
+    await storage.close?.();
+    await fs.mkdir(vectorDir, { recursive: true });
+    await fs.writeFile(
+      path.join(vectorDir, 'index-build.json'),
+      JSON.stringify({ buildId: archive.buildId, formatVersion: archive.formatVersion })
+    );
+
+    await atomicSwapStagingToActive(contextDir, stagingDir, archive.buildId);
+    // The cache lives next to the active index (it isn't swapped) and speeds up later refreshes;
+    // it replaces the local one only once the imported index is live
+    if (typeof cacheContent === 'string') {
+      const cachePath = path.join(contextDir, EMBEDDING_CACHE_FILENAME);
+      await fs.writeFile(`${cachePath}.import`, cacheContent);
+      await fs.rename(`${cachePath}.import`, cachePath);
+    }
+
+    const missingEmbeddings = keywordIndex.chunks.length - chunksWithEmbeddings.length;
+    if (missingEmbeddings > 0) {
+      warnings.push(
+        `${missingEmbeddings} chunks have no archived vector; run refresh_index to embed them.`
+      );
+    }
+    return {
+      buildId: archive.buildId,
+      chunks: keywordIndex.chunks.length,
+      embeddedChunks: chunksWithEmbeddings.length,
+      missingEmbeddings,
+      ...(archive.embeddingModel ? { embeddingModel: archive.embeddingModel } : {}),
+      warnings
+    };
+  } catch (error) {
+    await fs.rm(stagingDir, { recursive: true, force: true });
+    throw error;
+  }
+}
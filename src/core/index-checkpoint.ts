@@ -0,0 +1,51 @@
+/**
+ * Marker for an index build that was cancelled before it finished (client cancel, shutdown,
+ * client gone). The server resumes such builds on its next start.
+ *
+ * Resuming is cheap where it matters: vectors embedded before the stop are already in the
+ * embedding cache, so the resumed run only embeds what was still missing. Scanning and
+ * parsing are redone. The previous index stays active the whole time.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import { INDEX_CHECKPOINT_FILENAME } from '../constants/codebase-context.js';
+import type { IndexingPhase } from '../types/index.js';
+
+export interface IndexCheckpoint {
+  mode: 'full' | 'incremental';
+  /** Phase the build stopped in */
+  phase: IndexingPhase;
+  filesProcessed: number;
+  totalFiles: number;
+  chunksEmbedded?: number;
+  chunksToEmbed?: number;
+  stoppedAt: string;
+}
+
+export async function readIndexCheckpoint(contextDir: string): Promise<IndexCheckpoint | null> {
+  try {
+    const parsed = JSON.parse(
+      await fs.readFile(path.join(contextDir, INDEX_CHECKPOINT_FILENAME), 'utf-8')
+    ) as Partial<IndexCheckpoint>;
+    if (parsed.mode !== 'full' && parsed.mode !== 'incremental') return null;
+    return parsed as IndexCheckpoint;
+  } catch {
+    return null;
+  }
+}
+
+export async function writeIndexCheckpoint(
+  contextDir: string,
+  checkpoint: IndexCheckpoint
+): Promise<void> {
+  await fs.mkdir(contextDir, { recursive: true });
+  await fs.writeFile(
+    path.join(contextDir, INDEX_CHECKPOINT_FILENAME),
+    JSON.stringify(checkpoint, null, 2)
+  );
+}
+
+export async function clearIndexCheckpoint(contextDir: string): Promise<void> {
+  await fs.rm(path.join(contextDir, INDEX_CHECKPOINT_FILENAME), { force: true });
+}
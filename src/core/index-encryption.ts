@@ -0,0 +1,213 @@
+/**
+ * Encryption at rest: chunk content and metadata are sealed with AES-256-GCM before they are
+ * written to the keyword index or a vector store, and opened again when they are read.
+ *
+ * The key is 32 bytes, hex or base64, from CODEBASE_CONTEXT_ENCRYPTION_KEY or from the OS
+ * keychain entry named by CODEBASE_CONTEXT_ENCRYPTION_KEYCHAIN (`security` on macOS,
+ * `secret-tool` on Linux). A sealed chunk keeps its path, line range, classification and tags
+ * readable, so filters, stats and incremental builds work as before; its content carries the
+ * ciphertext of `{ content, metadata }` and its metadata is empty.
+ *
+ * Other artifacts that hold source fragments (commit history with its diff hunks, file
+ * summaries, module cards) are sealed whole with the same key: the file is one sealed string.
+ */
+
+import { createCipheriv, createDecipheriv, createHash, randomBytes } from 'crypto';
+import { execFile } from 'child_process';
+import { promisify } from 'util';
+import { IndexKeyError } from '../errors/index.js';
+import type { ChunkMetadata, CodeChunk } from '../types/index.js';
+
+const execFileAsync = promisify(execFile);
+
+export const ENCRYPTION_KEY_ENV = 'CODEBASE_CONTEXT_ENCRYPTION_KEY';
+export const ENCRYPTION_KEYCHAIN_ENV = 'CODEBASE_CONTEXT_ENCRYPTION_KEYCHAIN';
+export const ENCRYPTION_ALGORITHM = 'aes-256-gcm';
+
+/** Marks sealed content: `ccsealed:v1:<iv>.<tag>.<ciphertext>`, each part base64 */
+const SEALED_PREFIX = 'ccsealed:v1:';
+const KEY_BYTES = 32;
+const IV_BYTES = 12;
+const KEYCHAIN_TIMEOUT_MS = 5000;
+
+export interface IndexKey {
+  /** First 16 hex chars of the key's SHA-256, recorded in index-meta.json */
+  id: string;
+  key: Buffer;
+}
+
+/** A 32-byte key written as 64 hex chars or as base64 */
+export function parseIndexKey(text: string): IndexKey {
+  const trimmed = text.trim();
+  const key = /^[0-9a-f]{64}$/i.test(trimmed)
+    ? Buffer.from(trimmed, 'hex')
+    : /^[A-Za-z0-9+/_-]+={0,2}$/.test(trimmed)
+      ? Buffer.from(trimmed, 'base64')
+      : Buffer.alloc(0);
+  if (key.length !== KEY_BYTES) {
+    throw new IndexKeyError(
+      `The index encryption key must be ${KEY_BYTES} bytes, as 64 hex characters or base64`
+    );
+  }
+  return { id: createHash('sha256').update(key).digest('hex').slice(0, 16), key };
+}
+
+async function readKeychain(service: string): Promise<string> {
+  const lookup: [string, string[]] | null =
+    process.platform === 'darwin'
+      ? ['security', ['find-generic-password', '-s', service, '-w']]
+      : process.platform === 'linux'
+        ? ['secret-tool', ['lookup', 'service', service]]
+        : null;
+  if (!lookup) {
+    throw new IndexKeyError(
+      `${ENCRYPTION_KEYCHAIN_ENV} is not supported on ${process.platform}; ` +
+        `set ${ENCRYPTION_KEY_ENV} instead`
+    );
+  }
+  try {
+    const { stdout } = await execFileAsync(lookup[0], lookup[1], {
+      timeout: KEYCHAIN_TIMEOUT_MS,
+      windowsHide: true
+    });
+    if (!stdout.trim()) throw new Error('empty secret');
+    return stdout;
+  } catch (error) {
+    throw new IndexKeyError(
+      `Could not read the index encryption key '${service}' with ${lookup[0]}: ${
+        error instanceof Error ? error.message : String(error)
+      }`
+    );
+  }
+}
+
+let resolved: { source: string; key: Promise<IndexKey | null> } | null = null;
+
+/**
+ * The configured index key, or null when encryption is off. Keychain lookups run once per
+ * process for a given configuration.
+ */
+export function resolveIndexKey(env: NodeJS.ProcessEnv = process.env): Promise<IndexKey | null> {
+  const value = env[ENCRYPTION_KEY_ENV]?.trim();
+  const service = env[ENCRYPTION_KEYCHAIN_ENV]?.trim();
+  const source = value ? `env:${value}` : service ? `keychain:${service}` : '';
+  if (resolved?.source !== source) {
+    const key = value
+      ? Promise.resolve(value).then(parseIndexKey)
+      : service
+        ? readKeychain(service).then(parseIndexKey)
+        : Promise.resolve(null);
+    resolved = { source, key };
+    // A failed lookup is retried on the next call
+    key.catch(() => {
+      if (resolved?.key === key) resolved = null;
+    });
+  }
+  return resolved.key;
+}
+
+export function isSealedText(text: string): boolean {
+  return text.startsWith(SEALED_PREFIX);
+}
+
+export function isSealedChunk(chunk: Pick<CodeChunk, 'content'>): boolean {
+  return typeof chunk.content === 'string' && isSealedText(chunk.content);
+}
+
+function sealText(plaintext: string, key: IndexKey): string {
+  const iv = randomBytes(IV_BYTES);
+  const cipher = createCipheriv(ENCRYPTION_ALGORITHM, key.key, iv);
+  const data = Buffer.concat([cipher.update(plaintext, 'utf-8'), cipher.final()]);
+  const sealed = [iv, cipher.getAuthTag(), data].map((part) => part.toString('base64')).join('.');
+  return `${SEALED_PREFIX}${sealed}`;
+}
+
+/** The plaintext of sealed `text`; throws IndexKeyError naming `what` when it won't open */
+function openText(text: string, key: IndexKey, what: string): string {
+  const [iv, tag, data] = text
+    .slice(SEALED_PREFIX.length)
+    .split('.')
+    .map((part) => Buffer.from(part, 'base64'));
+  try {
+    const decipher = createDecipheriv(ENCRYPTION_ALGORITHM, key.key, iv);
+    decipher.setAuthTag(tag);
+    return Buffer.concat([decipher.update(data), decipher.final()]).toString('utf-8');
+  } catch {
+    throw new IndexKeyError(
+      `${what} could not be decrypted: the index was sealed with a different key ` +
+        'or the stored data is damaged'
+    );
+  }
+}
+
+/** The chunk as it is stored when encryption is on */
+export function sealChunk<T extends CodeChunk>(chunk: T, key: IndexKey): T {
+  if (isSealedChunk(chunk)) return chunk;
+  const plaintext = JSON.stringify({ content: chunk.content, metadata: chunk.metadata });
+  return { ...chunk, content: sealText(plaintext, key), metadata: {} };
+}
+
+/** A sealed chunk's content and metadata restored; other chunks are returned as they are */
+export function openChunk<T extends CodeChunk>(chunk: T, key: IndexKey): T {
+  if (!isSealedChunk(chunk)) return chunk;
+  const plaintext = openText(chunk.content, key, `Chunk ${chunk.id}`);
+  let opened: { content: string; metadata: ChunkMetadata };
+  try {
+    opened = JSON.parse(plaintext) as { content: string; metadata: ChunkMetadata };
+  } catch {
+    throw new IndexKeyError(`Chunk ${chunk.id} could not be decrypted: the stored data is damaged`);
+  }
+  return { ...chunk, content: opened.content, metadata: opened.metadata ?? {} };
+}
+
+/** An artifact's JSON as it is written: sealed whole when encryption is on */
+export async function encodeArtifact(value: unknown): Promise<string> {
+  const json = JSON.stringify(value);
+  const key = await resolveIndexKey();
+  return key ? sealText(json, key) : json;
+}
+
+/**
+ * An artifact written by `encodeArtifact`, parsed. Throws IndexKeyError when it is sealed and
+ * the configured key (or none) doesn't open it.
+ */
+export async function decodeArtifact(raw: string, what: string): Promise<unknown> {
+  if (!isSealedText(raw)) return JSON.parse(raw);
+  const key = await resolveIndexKey();
+  if (!key) {
+    throw new IndexKeyError(
+      `${what} is encrypted; set ${ENCRYPTION_KEY_ENV} or ${ENCRYPTION_KEYCHAIN_ENV} ` +
+        'to the key it was built with'
+    );
+  }
+  return JSON.parse(openText(raw, key, what));
+}
+
+/** Seal chunks for storage, or return them unchanged when encryption is off */
+export function sealChunks<T extends CodeChunk>(chunks: T[], key: IndexKey | null): T[] {
+  return key ? chunks.map((chunk) => sealChunk(chunk, key)) : chunks;
+}
+
+/**
+ * Chunks read from the keyword index or a vector store, opened with the configured key.
+ * Throws IndexKeyError when some are sealed and no key is configured.
+ */
+export async function openIndexedChunks<T extends CodeChunk>(chunks: T[]): Promise<T[]> {
+  if (!chunks.some(isSealedChunk)) return chunks;
+  const key = await resolveIndexKey();
+  if (!key) {
+    throw new IndexKeyError(
+      `The index is encrypted; set ${ENCRYPTION_KEY_ENV} or ${ENCRYPTION_KEYCHAIN_ENV} ` +
+        'to the key it was built with'
+    );
+  }
+  return chunks.map((chunk) => openChunk(chunk, key));
+}
+
+/** Vector store results with their chunks opened */
+export async function openSearchResults<T extends { chunk: CodeChunk }>(
+  results: T[]
+): Promise<T[]> {
+  const chunks = await openIndexedChunks(results.map((result) => result.chunk));
+  return results.map((result, i) => ({ ...result, chunk: chunks[i] }));
+}
@@ -0,0 +1,259 @@
+/**
+ * Concurrency control for one index directory.
+ *
+ * Builds, imports, rollbacks, gc and purge each change the index as a whole, so they take
+ * `index.lock`: one at a time per directory, across processes, with a lock left by a dead
+ * process taken over. Queries never take it.
+ *
+ * Queries instead share a readers-writer lock with the swap that puts a new generation in
+ * place. Within a process a query reads either the old generation or the new one, never the
+ * half-moved state in between; a swap waits for the queries already reading and queries that
+ * arrive during it wait for the swap. Other processes see `swap.lock` while a swap is under way
+ * and wait for it to go before they read. That part is best effort: a read that started just
+ * before the marker appeared can still fail, and is retried once by `readConsistently`.
+ */
+
+import { AsyncLocalStorage } from 'async_hooks';
+import { promises as fs } from 'fs';
+import path from 'path';
+import { BUILD_LOCK_FILENAME, SWAP_LOCK_FILENAME } from '../constants/codebase-context.js';
+import { IndexCorruptedError, IndexingCancelledError } from '../errors/index.js';
+
+/** How long a build waits for another one before giving up */
+export const BUILD_LOCK_TIMEOUT_MS = 30 * 60_000;
+const BUILD_LOCK_POLL_MS = 200;
+/** A lock file that can't be parsed after this long was abandoned while being written */
+const UNREADABLE_LOCK_GRACE_MS = 2_000;
+/** Longest a reader waits for another process's swap; swaps are a handful of renames */
+const SWAP_WAIT_MS = 10_000;
+const SWAP_POLL_MS = 50;
+
+interface LockOwner {
+  pid: number;
+  startedAt: string;
+}
+
+interface RwState {
+  readers: number;
+  writing: boolean;
+  queue: Array<{ write: boolean; grant: () => void }>;
+}
+
+const rwLocks = new Map<string, RwState>();
+/** Index directories the current async context holds a read lock on */
+const heldReads = new AsyncLocalStorage<ReadonlySet<string>>();
+/** Index directories whose build lock this process holds */
+const heldBuilds = new Set<string>();
+
+const sleep = (ms: number) => new Promise<void>((resolve) => setTimeout(resolve, ms));
+
+function isProcessAlive(pid: number): boolean {
+  try {
+    process.kill(pid, 0);
+    return true;
+  } catch (error) {
+    // EPERM: the process exists but belongs to someone else
+    return (error as NodeJS.ErrnoException).code === 'EPERM';
+  }
+}
+
+async function readOwner(lockPath: string): Promise<LockOwner | null | 'missing'> {
+  let text: string;
+  try {
+    text = await fs.readFile(lockPath, 'utf-8');
+  } catch (error) {
+    if ((error as NodeJS.ErrnoException).code === 'ENOENT') return 'missing';
+    return null;
+  }
+  try {
+    const owner = JSON.parse(text) as Partial<LockOwner>;
+    return typeof owner.pid === 'number' && typeof owner.startedAt === 'string'
+      ? { pid: owner.pid, startedAt: owner.startedAt }
+      : null;
+  } catch {
+    return null;
+  }
+}
+
+function ownerRecord(): string {
+  return JSON.stringify({ pid: process.pid, startedAt: new Date().toISOString() });
+}
+
+function pump(key: string, state: RwState): void {
+  while (state.queue.length > 0 && !state.writing) {
+    const next = state.queue[0];
+    if (next.write) {
+      if (state.readers > 0) break;
+      state.queue.shift();
+      state.writing = true;
+      next.grant();
+      break;
+    }
+    state.queue.shift();
+    state.readers++;
+    next.grant();
+  }
+  if (state.readers === 0 && !state.writing && state.queue.length === 0) rwLocks.delete(key);
+}
+
+function acquire(key: string, write: boolean): Promise<void> {
+  let state = rwLocks.get(key);
+  if (!state) {
+    state = { readers: 0, writing: false, queue: [] };
+    rwLocks.set(key, state);
+  }
+  // Writers go first: a reader that arrives while one waits queues behind it
+  if (!state.writing && state.queue.length === 0 && (write ? state.readers === 0 : true)) {
+    if (write) state.writing = true;
+    else state.readers++;
+    return Promise.resolve();
+  }
+  const queued = state;
+  return new Promise((grant) => queued.queue.push({ write, grant }));
+}
+
+function release(key: string, write: boolean): void {
+  const state = rwLocks.get(key);
+  if (!state) return;
+  if (write) state.writing = false;
+  else state.readers--;
+  pump(key, state);
+}
+
+/** Wait while another live process is swapping a generation into `dir` */
+async function waitForForeignSwap(dir: string): Promise<void> {
+  const markerPath = path.join(dir, SWAP_LOCK_FILENAME);
+  const deadline = Date.now() + SWAP_WAIT_MS;
+  while (Date.now() < deadline) {
+    const owner = await readOwner(markerPath);
+    if (owner === 'missing' || owner === null) return;
+    if (owner.pid === process.pid || !isProcessAlive(owner.pid)) return;
+    if (Date.now() - Date.parse(owner.startedAt) > SWAP_WAIT_MS) return;
+    await sleep(SWAP_POLL_MS);
+  }
+}
+
+/**
+ * Run `fn` while the active generation of `contextDir` can't be swapped. Nested calls in the
+ * same async context run straight away.
+ */
+export async function withIndexReadLock<T>(contextDir: string, fn: () => Promise<T>): Promise<T> {
+  const key = path.resolve(contextDir);
+  const held = heldReads.getStore();
+  if (held?.has(key)) return fn();
+  await waitForForeignSwap(key);
+  await acquire(key, false);
+  try {
+    return await heldReads.run(new Set([...(held ?? []), key]), fn);
+  } finally {
+    release(key, false);
+  }
+}
+
+/**
+ * Run `fn`, which replaces the active generation of `contextDir`, once no query is reading
+ * it. `swap.lock` marks the swap for readers in other processes.
+ */
+export async function withIndexWriteLock<T>(contextDir: string, fn: () => Promise<T>): Promise<T> {
+  const key = path.resolve(contextDir);
+  if (heldReads.getStore()?.has(key)) {
+    throw new Error(`Cannot swap the index in ${key} while reading it`);
+  }
+  await acquire(key, true);
+  const markerPath = path.join(key, SWAP_LOCK_FILENAME);
+  try {
+    await fs.writeFile(markerPath, ownerRecord()).catch(() => undefined);
+    return await fn();
+  } finally {
+    await fs.rm(markerPath, { force: true }).catch(() => undefined);
+    release(key, true);
+  }
+}
+
+/** One artifact of the active generation of `contextDir`, never read mid-swap */
+export function readIndexArtifact(contextDir: string, name: string): Promise<string> {
+  return withIndexReadLock(contextDir, () => fs.readFile(path.join(contextDir, name), 'utf-8'));
+}
+
+/**
+ * Run a read of the active generation, retrying it once when it fails as if the index were
+ * damaged: a reader in another process can catch a swap half-way, and the retry sees the new
+ * generation. A second failure is real and is rethrown.
+ */
+export async function readConsistently<T>(contextDir: string, fn: () => Promise<T>): Promise<T> {
+  try {
+    return await withIndexReadLock(contextDir, fn);
+  } catch (error) {
+    if (!(error instanceof IndexCorruptedError)) throw error;
+    await sleep(SWAP_POLL_MS);
+    return withIndexReadLock(contextDir, fn);
+  }
+}
+
+async function isStaleBuildLock(lockPath: string): Promise<boolean> {
+  const owner = await readOwner(lockPath);
+  if (owner === 'missing') return true;
+  if (owner === null) {
+    const stat = await fs.stat(lockPath).catch(() => null);
+    return !stat || Date.now() - stat.mtimeMs > UNREADABLE_LOCK_GRACE_MS;
+  }
+  // Our pid without our record is a lock from an earlier process that had the same pid
+  return owner.pid === process.pid || !isProcessAlive(owner.pid);
+}
+
+/**
+ * Take the build lock of `contextDir`, waiting for the build that holds it. Resolves to the
+ * function that releases it. Throws IndexingCancelledError when `signal` aborts first.
+ */
+export async function acquireBuildLock(
+  contextDir: string,
+  options: { signal?: AbortSignal; timeoutMs?: number } = {}
+): Promise<() => Promise<void>> {
+  const key = path.resolve(contextDir);
+  const lockPath = path.join(key, BUILD_LOCK_FILENAME);
+  const timeoutMs = options.timeoutMs ?? BUILD_LOCK_TIMEOUT_MS;
+  const deadline = Date.now() + timeoutMs;
+  await fs.mkdir(key, { recursive: true });
+
+  for (;;) {
+    if (options.signal?.aborted) throw new IndexingCancelledError();
+    if (!heldBuilds.has(key)) {
+      try {
+        await fs.writeFile(lockPath, ownerRecord(), { flag: 'wx' });
+        heldBuilds.add(key);
+        return async () => {
+          heldBuilds.delete(key);
+          await fs.rm(lockPath, { force: true }).catch(() => undefined);
+        };
+      } catch (error) {
+        if ((error as NodeJS.ErrnoException).code !== 'EEXIST') throw error;
+      }
+      if (await isStaleBuildLock(lockPath)) {
+        await fs.rm(lockPath, { force: true });
+        continue;
+      }
+    }
+    if (Date.now() >= deadline) {
+      const waited = `${Math.round(timeoutMs / 1000)}s`;
+      throw new Error(
+        `Another build of this index has held ${lockPath} for over ${waited}; ` +
+          'remove the file if no build is running'
+      );
+    }
+    await sleep(BUILD_LOCK_POLL_MS);
+  }
+}
+
+/** Run `fn` holding the build lock of `contextDir` */
+export async function withBuildLock<T>(
+  contextDir: string,
+  fn: () => Promise<T>,
+  options: { signal?: AbortSignal; timeoutMs?: number } = {}
+): Promise<T> {
+  const releaseLock = await acquireBuildLock(contextDir, options);
+  try {
+    return await fn();
+  } finally {
+    await releaseLock();
+  }
+}
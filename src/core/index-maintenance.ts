@@ -0,0 +1,515 @@
+/**
+ * Index inspection and cleanup for the `stats`, `purge` and `gc` CLI commands (and the
+ * get_index_stats tool).
+ *
+ * All work on the on-disk artifacts under `.codebase-context/` (or a per-ref directory) and
+ * never touch team-owned files: `memory.json` and `config.json` survive a purge.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import {
+  BUILD_LOCK_FILENAME,
+  CODEBASE_CONTEXT_DIRNAME,
+  INDEXING_STATS_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MANIFEST_FILENAME,
+  MEMORY_FILENAME,
+  PROJECT_CONFIG_FILENAME,
+  REF_INDEXES_DIRNAME,
+  RELATIONSHIPS_FILENAME,
+  SWAP_LOCK_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../constants/codebase-context.js';
+import { readIndexMeta, type EmbeddingFingerprint } from './index-meta.js';
+import { withBuildLock, withIndexWriteLock } from './index-lock.js';
+import { hashFileContent, readManifest, writeManifest } from './manifest.js';
+import { getRefContextDir } from '../utils/git-tree.js';
+import {
+  DEFAULT_STORAGE_CONFIG,
+  STORAGE_CAPABILITIES,
+  getStorageProvider,
+  isRemoteStorageProvider,
+  isStorageProviderName,
+  type StorageCapabilities
+} from '../storage/index.js';
+import type { CodeChunk } from '../types/index.js';
+import { readTextFile } from '../utils/text-encoding.js';
+
+/** Team-owned files kept by `purge` */
+export const PRESERVED_CONTEXT_FILES: ReadonlySet<string> = new Set([
+  MEMORY_FILENAME,
+  PROJECT_CONFIG_FILENAME
+]);
+
+const MAX_LANGUAGES = 10;
+/** Files hashed by the staleness estimate; larger indexes are sampled */
+const MAX_STALENESS_SAMPLE = 2000;
+
+export interface LanguageStats {
+  language: string;
+  files: number;
+  chunks: number;
+}
+
+export interface IndexStatsReport {
+  rootPath: string;
+  contextDir: string;
+  indexed: boolean;
+  /** Why the index can't be read, when `indexed` is false but artifacts exist */
+  problem?: string;
+  buildId?: string;
+  generatedAt?: string;
+  toolVersion?: string;
+  formatVersion?: number;
+  gitRef?: { ref: string; commit: string };
+  storageProvider?: string;
+  /** What the backend filters itself; search applies the rest (see vector-query-plan.ts) */
+  storageCapabilities?: StorageCapabilities;
+  /** Model the vectors were embedded with; absent for keyword-only or pre-v2 indexes */
+  embedding?: EmbeddingFingerprint;
+  files: number;
+  chunks: number;
+  /** Largest languages by chunk count */
+  languages: LanguageStats[];
+  relationships?: Record<string, unknown>;
+  /** Working-tree index only: indexed files deleted or edited since, see `estimateStaleFiles` */
+  staleFiles?: StaleFilesEstimate;
+  disk: { totalBytes: number; artifacts: Record<string, number> };
+  /** Slugs of the per-ref indexes next to the working-tree index */
+  refIndexes: string[];
+}
+
+async function diskUsage(target: string): Promise<number> {
+  try {
+    const stat = await fs.stat(target);
+    if (!stat.isDirectory()) return stat.size;
+    let total = 0;
+    for (const entry of await fs.readdir(target)) {
+      total += await diskUsage(path.join(target, entry));
+    }
+    return total;
+  } catch {
+    return 0;
+  }
+}
+
+async function readJson<T>(filePath: string): Promise<T | null> {
+  try {
+    return JSON.parse(await fs.readFile(filePath, 'utf-8')) as T;
+  } catch {
+    return null;
+  }
+}
+
+export interface StaleFilesEstimate {
+  /** Indexed files no longer on disk */
+  missing: number;
+  /** Indexed files whose content changed since indexing */
+  changed: number;
+  /** Files checked; fewer than `indexed` when the counts are extrapolated from a sample */
+  checked: number;
+  indexed: number;
+  sampled: boolean;
+}
+
+/**
+ * How many indexed files are missing or changed, from the manifest hashes. Files not modified
+ * since the manifest was written are trusted without hashing; above MAX_STALENESS_SAMPLE files
+ * an even sample is checked and the counts scaled up. Files added since are not counted.
+ */
+export async function estimateStaleFiles(
+  rootPath: string,
+  contextDir: string = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME)
+): Promise<StaleFilesEstimate | undefined> {
+  const manifest = await readManifest(path.join(contextDir, MANIFEST_FILENAME));
+  if (!manifest) return undefined;
+  const indexedAt = Date.parse(manifest.generatedAt);
+  const entries = Object.entries(manifest.files);
+  const step = Math.max(1, Math.ceil(entries.length / MAX_STALENESS_SAMPLE));
+  const sample = entries.filter((_, i) => i % step === 0);
+
+  let missing = 0;
+  let changed = 0;
+  for (const [relativePath, hash] of sample) {
+    const filePath = path.join(rootPath, relativePath);
+    try {
+      const stat = await fs.stat(filePath);
+      if (Number.isFinite(indexedAt) && stat.mtimeMs <= indexedAt) continue;
+      if (hashFileContent(await readTextFile(filePath)) !== hash) changed++;
+    } catch {
+      missing++;
+    }
+  }
+
+  const scale = sample.length > 0 ? entries.length / sample.length : 1;
+  return {
+    missing: Math.round(missing * scale),
+    changed: Math.round(changed * scale),
+    checked: sample.length,
+    indexed: entries.length,
+    sampled: step > 1
+  };
+}
+
+/** Counts, languages, build info and disk usage of the working-tree index or a ref index. */
+export async function collectIndexStats(
+  rootPath: string,
+  options: { ref?: string } = {}
+): Promise<IndexStatsReport> {
+  const contextDir = options.ref
+    ? getRefContextDir(rootPath, options.ref)
+    : path.join(rootPath, CODEBASE_CONTEXT_DIRNAME);
+
+  const artifacts: Record<string, number> = {};
+  let entries: string[] = [];
+  try {
+    entries = await fs.readdir(contextDir);
+  } catch {
+    // No context directory yet
+  }
+  for (const entry of entries.sort()) {
+    // Ref indexes are reported separately (see refIndexes)
+    if (!options.ref && entry === REF_INDEXES_DIRNAME) continue;
+    artifacts[entry] = await diskUsage(path.join(contextDir, entry));
+  }
+
+  let refIndexes: string[] = [];
+  if (!options.ref) {
+    try {
+      refIndexes = (
+        await fs.readdir(path.join(contextDir, REF_INDEXES_DIRNAME), { withFileTypes: true })
+      )
+        .filter((entry) => entry.isDirectory())
+        .map((entry) => entry.name)
+        .sort();
+    } catch {
+      // No ref indexes
+    }
+  }
+
+  const report: IndexStatsReport = {
+    rootPath,
+    contextDir,
+    indexed: false,
+    files: 0,
+    chunks: 0,
+    languages: [],
+    disk: {
+      totalBytes: Object.values(artifacts).reduce((sum, bytes) => sum + bytes, 0),
+      artifacts
+    },
+    refIndexes
+  };
+
+  try {
+    const meta = await readIndexMeta(rootPath, contextDir);
+    report.buildId = meta.buildId;
+    report.generatedAt = meta.generatedAt;
+    report.toolVersion = meta.toolVersion;
+    report.formatVersion = meta.formatVersion;
+    report.storageProvider = meta.artifacts.vectorDb.provider;
+    if (isStorageProviderName(report.storageProvider)) {
+      report.storageCapabilities = STORAGE_CAPABILITIES[report.storageProvider];
+    }
+    if (meta.embedding) report.embedding = meta.embedding;
+    if (meta.gitRef) report.gitRef = meta.gitRef;
+  } catch (error) {
+    // Only memory/config on disk means "not indexed yet", not a broken index
+    if (Object.keys(artifacts).some((name) => !PRESERVED_CONTEXT_FILES.has(name))) {
+      report.problem = error instanceof Error ? error.message : String(error);
+    }
+    return report;
+  }
+
+  const keywordIndex = await readJson<{ chunks?: CodeChunk[] }>(
+    path.join(contextDir, KEYWORD_INDEX_FILENAME)
+  );
+  const chunks = Array.isArray(keywordIndex?.chunks) ? keywordIndex.chunks : [];
+  const files = new Set<string>();
+  const byLanguage = new Map<string, { files: Set<string>; chunks: number }>();
+  for (const chunk of chunks) {
+    files.add(chunk.relativePath);
+    const language = chunk.language || 'unknown';
+    const entry = byLanguage.get(language) ?? { files: new Set<string>(), chunks: 0 };
+    entry.files.add(chunk.relativePath);
+    entry.chunks++;
+    byLanguage.set(language, entry);
+  }
+
+  const persisted = await readJson<{ indexedFiles?: number }>(
+    path.join(contextDir, INDEXING_STATS_FILENAME)
+  );
+  const relationships = await readJson<{ stats?: Record<string, unknown> }>(
+    path.join(contextDir, RELATIONSHIPS_FILENAME)
+  );
+
+  report.indexed = true;
+  report.files = files.size || (persisted?.indexedFiles ?? 0);
+  report.chunks = chunks.length;
+  report.languages = [...byLanguage]
+    .map(([language, entry]) => ({ language, files: entry.files.size, chunks: entry.chunks }))
+    .sort((a, b) => b.chunks - a.chunks || a.language.localeCompare(b.language))
+    .slice(0, MAX_LANGUAGES);
+  if (relationships?.stats) report.relationships = relationships.stats;
+  // A ref index describes a commit, so it can't go stale
+  if (!options.ref) report.staleFiles = await estimateStaleFiles(rootPath, contextDir);
+  return report;
+}
+
+export interface PurgeResult {
+  contextDir: string;
+  removed: string[];
+  kept: string[];
+  freedBytes: number;
+  /** Set when a remote vector collection was cleared too */
+  remoteCleared?: string;
+  warnings: string[];
+}
+
+const LOCK_FILES: ReadonlySet<string> = new Set([BUILD_LOCK_FILENAME, SWAP_LOCK_FILENAME]);
+
+async function dirExists(dir: string): Promise<boolean> {
+  try {
+    return (await fs.stat(dir)).isDirectory();
+  } catch {
+    return false;
+  }
+}
+
+/**
+ * Delete the generated index artifacts (or one ref index), keeping memory and project config.
+ * Remote vector collections (Qdrant, pgvector, Milvus) for the index are cleared as well.
+ * Waits for a build in progress to finish.
+ */
+export async function purgeIndex(
+  rootPath: string,
+  options: { ref?: string; dryRun?: boolean } = {}
+): Promise<PurgeResult> {
+  const contextDir = options.ref
+    ? getRefContextDir(rootPath, options.ref)
+    : path.join(rootPath, CODEBASE_CONTEXT_DIRNAME);
+  if (options.dryRun || !(await dirExists(contextDir))) {
+    return purgeContextDir(rootPath, contextDir, options);
+  }
+  return withBuildLock(contextDir, () =>
+    withIndexWriteLock(contextDir, () => purgeContextDir(rootPath, contextDir, options))
+  );
+}
+
+async function purgeContextDir(
+  rootPath: string,
+  contextDir: string,
+  options: { ref?: string; dryRun?: boolean }
+): Promise<PurgeResult> {
+  const result: PurgeResult = { contextDir, removed: [], kept: [], freedBytes: 0, warnings: [] };
+
+  let entries: string[];
+  try {
+    entries = (await fs.readdir(contextDir)).sort();
+  } catch {
+    return result;
+  }
+
+  const provider = DEFAULT_STORAGE_CONFIG.provider;
+  if (isRemoteStorageProvider(provider) && !options.dryRun) {
+    try {
+      const storage = await getStorageProvider({
+        path: path.join(contextDir, VECTOR_DB_DIRNAME),
+        // Same derivation as the indexer: ref indexes own a collection named after their dir
+        rootPath: options.ref ? contextDir : rootPath
+      });
+      await storage.clear();
+      await storage.close?.();
+      result.remoteCleared = provider;
+    } catch (error) {
+      result.warnings.push(
+        `Could not clear the ${provider} collection: ${error instanceof Error ? error.message : String(error)}`
+      );
+    }
+  }
+
+  for (const entry of entries) {
+    // Held by this purge; they go when it finishes
+    if (LOCK_FILES.has(entry)) continue;
+    if (!options.ref && PRESERVED_CONTEXT_FILES.has(entry)) {
+      result.kept.push(entry);
+      continue;
+    }
+    const target = path.join(contextDir, entry);
+    result.freedBytes += await diskUsage(target);
+    if (!options.dryRun) await fs.rm(target, { recursive: true, force: true });
+    result.removed.push(entry);
+  }
+
+  if (options.ref && !options.dryRun) {
+    await fs.rm(contextDir, { recursive: true, force: true });
+  }
+  return result;
+}
+
+export interface ReconcileReport {
+  contextDir: string;
+  dryRun: boolean;
+  /** Indexed files that no longer exist on disk */
+  missingFiles: string[];
+  /** Indexed files whose content no longer matches the manifest hash */
+  changedFiles: string[];
+  /** Keyword-index chunks dropped */
+  removedChunks: number;
+  /** Vector-store files with no live source or no keyword-index entry */
+  orphanedVectorFiles: string[];
+  /** Vector rows deleted (0 on a dry run) */
+  removedVectors: number;
+  compacted: boolean;
+  warnings: string[];
+}
+
+const toPosix = (value: string) => value.replace(/\\/g, '/');
+
+/**
+ * Garbage-collect the working-tree index: drop chunks of deleted files and of files edited
+ * since they were indexed, delete vector rows whose file is gone or unknown to the keyword
+ * index, then compact the vector store.
+ *
+ * Changed files also lose their manifest entry, so the next incremental index re-adds them.
+ * Relationship and intelligence data are left as they are until that run. Waits for a build
+ * in progress to finish.
+ */
+export async function reconcileIndex(
+  rootPath: string,
+  options: { dryRun?: boolean } = {}
+): Promise<ReconcileReport> {
+  const contextDir = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME);
+  if (options.dryRun || !(await dirExists(contextDir))) {
+    return reconcileContextDir(rootPath, contextDir, options);
+  }
+  return withBuildLock(contextDir, () => reconcileContextDir(rootPath, contextDir, options));
+}
+
+async function reconcileContextDir(
+  rootPath: string,
+  contextDir: string,
+  options: { dryRun?: boolean }
+): Promise<ReconcileReport> {
+  const dryRun = options.dryRun === true;
+  const report: ReconcileReport = {
+    contextDir,
+    dryRun,
+    missingFiles: [],
+    changedFiles: [],
+    removedChunks: 0,
+    orphanedVectorFiles: [],
+    removedVectors: 0,
+    compacted: false,
+    warnings: []
+  };
+
+  const keywordIndexPath = path.join(contextDir, KEYWORD_INDEX_FILENAME);
+  const keywordIndex = await readJson<{ header?: unknown; chunks?: CodeChunk[] }>(
+    keywordIndexPath
+  );
+  if (!keywordIndex || !Array.isArray(keywordIndex.chunks)) {
+    report.warnings.push('No index found; run `codebase-context index` first');
+    return report;
+  }
+  const manifestPath = path.join(contextDir, MANIFEST_FILENAME);
+  const manifest = await readManifest(manifestPath);
+
+  const indexedFiles = new Set<string>(Object.keys(manifest?.files ?? {}));
+  for (const chunk of keywordIndex.chunks) indexedFiles.add(toPosix(chunk.relativePath));
+
+  const stale = new Set<string>();
+  for (const relativePath of [...indexedFiles].sort()) {
+    let content: string;
+    try {
+      content = await readTextFile(path.join(rootPath, relativePath));
+    } catch {
+      report.missingFiles.push(relativePath);
+      stale.add(relativePath);
+      continue;
+    }
+    const indexedHash = manifest?.files[relativePath];
+    if (indexedHash && hashFileContent(content) !== indexedHash) {
+      report.changedFiles.push(relativePath);
+      stale.add(relativePath);
+    }
+  }
+
+  const keptChunks = keywordIndex.chunks.filter(
+    (chunk) => !stale.has(toPosix(chunk.relativePath))
+  );
+  report.removedChunks = keywordIndex.chunks.length - keptChunks.length;
+  const liveFiles = new Set(keptChunks.map((chunk) => toPosix(chunk.relativePath)));
+
+  // Rewritten in place, so queries wait rather than read a half-written file
+  await withIndexWriteLock(contextDir, async () => {
+    if (!dryRun && report.removedChunks > 0) {
+      await fs.writeFile(keywordIndexPath, JSON.stringify({ ...keywordIndex, chunks: keptChunks }));
+    }
+    if (!dryRun && manifest && stale.size > 0) {
+      for (const relativePath of stale) delete manifest.files[relativePath];
+      await writeManifest(manifestPath, manifest);
+    }
+  });
+
+  const vectorDir = path.join(contextDir, VECTOR_DB_DIRNAME);
+  const provider = DEFAULT_STORAGE_CONFIG.provider;
+  if (!isRemoteStorageProvider(provider)) {
+    try {
+      await fs.access(vectorDir);
+    } catch {
+      return report; // Keyword-only index
+    }
+  }
+
+  try {
+    const storage = await getStorageProvider({ path: vectorDir, rootPath });
+    try {
+      let orphanPaths: string[];
+      if (storage.listFilePaths) {
+        orphanPaths = (await storage.listFilePaths()).filter((filePath) => {
+          const relativePath = toPosix(path.relative(rootPath, filePath));
+          return stale.has(relativePath) || !liveFiles.has(relativePath);
+        });
+        report.orphanedVectorFiles = orphanPaths
+          .map((filePath) => toPosix(path.relative(rootPath, filePath)))
+          .sort();
+      } else {
+        // No listing support: delete by the paths the indexer would have stored
+        orphanPaths = [
+          ...new Set(
+            [...stale].flatMap((relativePath) => [
+              toPosix(path.join(rootPath, relativePath)),
+              path.resolve(rootPath, relativePath)
+            ])
+          )
+        ];
+        report.orphanedVectorFiles = [...stale].sort();
+      }
+
+      if (!dryRun) {
+        if (orphanPaths.length > 0) {
+          report.removedVectors = await storage.deleteByFilePaths(orphanPaths);
+        }
+        if (storage.compact) {
+          await storage.compact();
+          report.compacted = true;
+        }
+      }
+    } finally {
+      await storage.close?.();
+    }
+  } catch (error) {
+    report.warnings.push(
+      `Vector store cleanup failed: ${error instanceof Error ? error.message : String(error)}`
+    );
+  }
+  return report;
+}
+
+/** Minutes between scheduled reconciliations in the server; 0 (the default) disables them */
+export function resolveGcIntervalMinutes(env: NodeJS.ProcessEnv = process.env): number {
+  const minutes = Number.parseFloat(env.CODEBASE_CONTEXT_GC_INTERVAL_MINUTES ?? '');
+  return Number.isFinite(minutes) && minutes > 0 ? minutes : 0;
+}
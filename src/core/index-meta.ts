@@ -47,6 +47,39 @@ export const IndexMetaSchema = z.object({
   buildId: z.string().min(1),
   generatedAt: z.string().datetime(),
   toolVersion: z.string().min(1),
+  /** Model the vectors were embedded with; absent when embedding was skipped or unknown */
+  embedding: z
+    .object({
+      provider: z.string().min(1),
+      model: z.string().min(1),
+      dimensions: z.number().int().nonnegative(),
+      /** Vectors were cut to their first `dimensions` components (Matryoshka truncation) */
+      truncateDimensions: z.number().int().positive().optional(),
+      /** Chunk post-processors applied to the inputs (see chunk-post-processors.ts) */
+      postProcessing: z.string().min(1).optional()
+    })
+    .optional(),
+  /** HEAD of the working tree when it was indexed (branch is null when detached) */
+  head: z
+    .object({
+      commit: z.string().min(1),
+      branch: z.string().min(1).nullable()
+    })
+    .optional(),
+  /** Chunk content and metadata are sealed with this key (see index-encryption.ts) */
+  encryption: z
+    .object({
+      algorithm: z.literal('aes-256-gcm'),
+      keyId: z.string().min(1)
+    })
+    .optional(),
+  /** Present when the index was built from a git ref instead of the working tree */
+  gitRef: z
+    .object({
+      ref: z.string().min(1),
+      commit: z.string().min(1)
+    })
+    .optional(),
   artifacts: z
     .object({
       keywordIndex: z.object({
@@ -66,6 +99,58 @@ export const IndexMetaSchema = z.object({
 });
 
 export type IndexMeta = z.infer<typeof IndexMetaSchema>;
+export type EmbeddingFingerprint = NonNullable<IndexMeta['embedding']>;
+
+type RawMeta = Record<string, unknown>;
+
+/**
+ * In-place upgrades for older index-meta.json layouts, keyed by the metaVersion they upgrade
+ * from. Only the meta file can be migrated; a formatVersion change still means a rebuild.
+ */
+const META_MIGRATIONS: Record<number, (meta: RawMeta) => RawMeta> = {
+  // v2 records the embedding model; a v1 index doesn't know its own, so it goes unchecked
+  1: (meta) => ({ ...meta, metaVersion: 2 })
+};
+
+/** Apply migrations up to INDEX_META_VERSION; returns null when none applied */
+export function migrateIndexMeta(raw: unknown): RawMeta | null {
+  if (!raw || typeof raw !== 'object' || Array.isArray(raw)) return null;
+  let meta = raw as RawMeta;
+  let migrated = false;
+  while (typeof meta.metaVersion === 'number' && meta.metaVersion < INDEX_META_VERSION) {
+    const migrate = META_MIGRATIONS[meta.metaVersion];
+    if (!migrate) break;
+    meta = migrate(meta);
+    migrated = true;
+  }
+  return migrated ? meta : null;
+}
+
+/**
+ * Why vectors built with `built` can't be queried with the configured model, or null when
+ * they can (or when the index predates fingerprints). Dimensions are compared when known.
+ */
+export function describeEmbeddingDrift(
+  built: EmbeddingFingerprint | undefined,
+  configured: { provider: string; model: string; dimensions?: number; truncateDimensions?: number }
+): string | null {
+  if (!built) return null;
+  const sameModel = built.provider === configured.provider && built.model === configured.model;
+  const sameDimensions =
+    !configured.dimensions || !built.dimensions || configured.dimensions === built.dimensions;
+  // Sizes aren't always known before the first embedding (Ollama, unknown models); truncation is
+  const sameTruncation = (built.truncateDimensions ?? 0) === (configured.truncateDimensions ?? 0);
+  if (sameModel && sameDimensions && sameTruncation) return null;
+  const describe = (m: { provider: string; model: string; dimensions?: number }) =>
+    `${m.provider}:${m.model}` + (m.dimensions ? ` (${m.dimensions}d)` : '');
+  const truncation = (m: { truncateDimensions?: number }) =>
+    m.truncateDimensions ? `, truncated to ${m.truncateDimensions}` : '';
+  return (
+    `Embedding model changed (rebuild required): index built with ` +
+    `${describe(built)}${truncation(built)}, configured ${describe(configured)}` +
+    truncation(configured)
+  );
+}
 
 async function pathExists(targetPath: string): Promise<boolean> {
   try {
@@ -99,8 +184,11 @@ function asIndexCorrupted(message: string, error: unknown): IndexCorruptedError
   return new IndexCorruptedError(`${message}: ${suffix}`);
 }
 
-export async function readIndexMeta(rootDir: string): Promise<IndexMeta> {
-  const metaPath = path.join(rootDir, CODEBASE_CONTEXT_DIRNAME, INDEX_META_FILENAME);
+export async function readIndexMeta(
+  rootDir: string,
+  contextDir = path.join(rootDir, CODEBASE_CONTEXT_DIRNAME)
+): Promise<IndexMeta> {
+  const metaPath = path.join(contextDir, INDEX_META_FILENAME);
 
   let parsed: unknown;
   try {
@@ -110,6 +198,17 @@ export async function readIndexMeta(rootDir: string): Promise<IndexMeta> {
     throw asIndexCorrupted('Index meta missing or unreadable (rebuild required)', error);
   }
 
+  const migrated = migrateIndexMeta(parsed);
+  if (migrated) {
+    parsed = migrated;
+    // Best-effort: persist so the upgrade runs once; readers of the old file still parse it
+    const tmpPath = `${metaPath}.${process.pid}.tmp`;
+    await fs
+      .writeFile(tmpPath, JSON.stringify(migrated, null, 2))
+      .then(() => fs.rename(tmpPath, metaPath))
+      .catch(() => fs.rm(tmpPath, { force: true }).catch(() => undefined));
+  }
+
   const result = IndexMetaSchema.safeParse(parsed);
   if (!result.success) {
     throw new IndexCorruptedError(
@@ -120,8 +219,9 @@ export async function readIndexMeta(rootDir: string): Promise<IndexMeta> {
   const meta = result.data;
 
   if (meta.metaVersion !== INDEX_META_VERSION) {
+    const origin = meta.metaVersion > INDEX_META_VERSION ? ' (written by a newer version)' : '';
     throw new IndexCorruptedError(
-      `Index meta version mismatch (rebuild required): expected metaVersion=${INDEX_META_VERSION}, found metaVersion=${meta.metaVersion}`
+      `Index meta version mismatch${origin} (rebuild required): expected metaVersion=${INDEX_META_VERSION}, found metaVersion=${meta.metaVersion}`
     );
   }
 
@@ -134,9 +234,11 @@ export async function readIndexMeta(rootDir: string): Promise<IndexMeta> {
   return meta;
 }
 
-export async function validateIndexArtifacts(rootDir: string, meta: IndexMeta): Promise<void> {
-  const contextDir = path.join(rootDir, CODEBASE_CONTEXT_DIRNAME);
-
+export async function validateIndexArtifacts(
+  rootDir: string,
+  meta: IndexMeta,
+  contextDir = path.join(rootDir, CODEBASE_CONTEXT_DIRNAME)
+): Promise<void> {
   const keywordPath = path.join(contextDir, KEYWORD_INDEX_FILENAME);
   const vectorDir = path.join(contextDir, VECTOR_DB_DIRNAME);
   const vectorBuildPath = path.join(vectorDir, 'index-build.json');
@@ -259,3 +361,21 @@ export async function validateIndexArtifacts(rootDir: string, meta: IndexMeta):
     }
   }
 }
+
+/**
+ * Why the index can't serve queries with the configured embedding model (an unreadable or
+ * unmigratable meta file, or vectors from another model), or null when it can.
+ */
+export async function checkIndexCompatibility(
+  rootDir: string,
+  configured: { provider: string; model: string; truncateDimensions?: number },
+  contextDir = path.join(rootDir, CODEBASE_CONTEXT_DIRNAME)
+): Promise<string | null> {
+  try {
+    const meta = await readIndexMeta(rootDir, contextDir);
+    return describeEmbeddingDrift(meta.embedding, configured);
+  } catch (error) {
+    if (error instanceof IndexCorruptedError) return error.message;
+    throw error;
+  }
+}
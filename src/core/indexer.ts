@@ -7,7 +7,6 @@ import { randomUUID } from 'crypto';
 import { promises as fs } from 'fs';
 import path from 'path';
 import { glob } from 'glob';
-import ignore from 'ignore';
 import {
   CodebaseMetadata,
   CodeChunk,
@@ -15,14 +14,114 @@ import {
   IndexingStats,
   IndexingPhase,
   CodebaseConfig,
+  ChunkingConfig,
   Dependency,
   ArchitecturalLayer,
-  IntelligenceData
+  IntelligenceData,
+  AnalysisResult
 } from '../types/index.js';
 import { analyzerRegistry } from './analyzer-registry.js';
-import { isCodeFile, isBinaryFile } from '../utils/language-detection.js';
-import { getEmbeddingProvider, DEFAULT_MODEL } from '../embeddings/index.js';
-import { getStorageProvider, CodeChunkWithEmbedding } from '../storage/index.js';
+import { isCodeFile, isBinaryFile, detectLanguage } from '../utils/language-detection.js';
+import {
+  ALWAYS_SKIPPED_DIRS,
+  CONTENT_SNIFF_BYTES,
+  GITIGNORE_FILENAME,
+  IgnoreRules,
+  MCPIGNORE_FILENAME,
+  createFsIgnoreLoader,
+  createMapIgnoreLoader,
+  detectGeneratedFile,
+  looksBinary,
+  looksMinified,
+  withRootRules
+} from '../utils/ignore-rules.js';
+import { extractTreeSitterCalls, type TreeSitterCallExtraction } from '../utils/tree-sitter.js';
+import { extractSfcCalls, isSfcLanguage } from '../utils/sfc-chunker.js';
+import { detectDotnetProjects, findOwningProject } from '../utils/dotnet-projects.js';
+import {
+  detectWorkspacePackages,
+  findOwningPackage,
+  npmPackageDirs
+} from '../utils/workspace-detection.js';
+import {
+  redactSecrets,
+  resolveRedactionOptions,
+  type RedactionOptions
+} from '../utils/secret-redaction.js';
+import {
+  getRefContextDir,
+  listGitTreeFiles,
+  matchesGlob,
+  readGitBlob,
+  readGitBlobBytes,
+  readGitHead,
+  resolveGitCommit
+} from '../utils/git-tree.js';
+import { decodeText, readText, type DecodedText } from '../utils/text-encoding.js';
+import { CallGraphBuilder } from './call-graph.js';
+import { buildTestLinks } from './test-mapping.js';
+import { createMetadataEnricher, loadProjectEnrichmentConfig } from './metadata-enrichment.js';
+import { loadProjectChunkingConfig, resolveLanguageChunking } from './chunking-config.js';
+import {
+  describePostProcessingDrift,
+  loadChunkPostProcessors,
+  type ChunkPostProcessorChain
+} from './chunk-post-processors.js';
+import { loadProjectConfigSettings } from './config-file.js';
+import { loadPathPolicy } from './path-policy.js';
+import {
+  appendComplianceAudit,
+  loadComplianceFilter,
+  type ComplianceAuditEntry
+} from './compliance-filters.js';
+import { SymbolIndexBuilder } from './symbol-index.js';
+import { TypeHierarchyBuilder } from './type-hierarchy.js';
+import { createImportResolver } from './dependency-graph.js';
+import { SwiftObjcBridge } from './swift-objc-bridge.js';
+import { SchemaLinker } from './schema-links.js';
+import { targetsByFile } from './build-graph.js';
+import { detectBuildGraph } from '../utils/build-files.js';
+import { findSchemaBlocks, schemaSymbols } from '../utils/schema-chunker.js';
+import { attachEmbeddedSql, sqlSymbols } from '../utils/sql-chunker.js';
+import { EmbeddingCache, hashEmbeddingInput } from './embedding-cache.js';
+import { clearIndexCheckpoint, writeIndexCheckpoint } from './index-checkpoint.js';
+import { clearModelMigration, startModelMigration } from './model-migration.js';
+import { acquireBuildLock, withBuildLock, withIndexWriteLock } from './index-lock.js';
+import { refreshEmbeddingCollections } from './embedding-collections.js';
+import { IndexingCancelledError, IndexKeyError } from '../errors/index.js';
+import {
+  ENCRYPTION_ALGORITHM,
+  ENCRYPTION_KEY_ENV,
+  resolveIndexKey,
+  sealChunks
+} from './index-encryption.js';
+import { mapOrdered } from './pipeline.js';
+import { metrics, startSpan, startTimer, withSpan, type TraceSpan } from './telemetry.js';
+import {
+  describeEmbeddingDrift,
+  readIndexMeta,
+  validateIndexArtifacts,
+  type EmbeddingFingerprint
+} from './index-meta.js';
+import {
+  getEmbeddingProvider,
+  resolveEmbeddingModel,
+  providerFingerprint,
+  embedInBatches,
+  DEFAULT_EMBEDDING_CONFIG,
+  DEFAULT_MODEL
+} from '../embeddings/index.js';
+import { loadChunkTokenBudget, type ChunkTokenBudget } from '../embeddings/tokenizers.js';
+import {
+  getStorageProvider,
+  CodeChunkWithEmbedding,
+  DEFAULT_STORAGE_CONFIG,
+  StorageConfig,
+  isStorageProviderName,
+  isRemoteStorageProvider,
+  resolveQuantizationMode,
+  VectorStorageProvider
+} from '../storage/index.js';
 import {
   LibraryUsageTracker,
   PatternDetector,
@@ -31,7 +130,9 @@ import {
   FileExport
 } from '../utils/usage-tracker.js';
 import { mergeSmallChunks } from '../utils/chunking.js';
-import { getFileCommitDates } from '../utils/git-dates.js';
+import { listGrammarPlugins } from '../grammars/plugins.js';
+import { splitOversizedChunks } from '../utils/ast-chunker.js';
+import { getFileCommitDates, getRecentCommitCounts } from '../utils/git-dates.js';
 import {
   CODEBASE_CONTEXT_DIRNAME,
   INDEX_FORMAT_VERSION,
@@ -47,15 +148,44 @@ import {
 
 const STAGING_DIRNAME = '.staging';
 const PREVIOUS_DIRNAME = '.previous';
+/** Embedding batches between embedding-cache checkpoints */
+const EMBEDDING_CHECKPOINT_BATCHES = 20;
+/** Files read and parsed at once unless `parsing.concurrency` says otherwise */
+const DEFAULT_PARSE_CONCURRENCY = envPositiveInteger('CODEBASE_CONTEXT_INDEX_CONCURRENCY', 4);
+const DEFAULT_MAX_FILE_SIZE = 1048576;
+const DEFAULT_MAX_CHUNKS_PER_FILE = 400;
+const DEFAULT_GENERATED_SAMPLE_CHUNKS = 2;
+
+function envPositiveInteger(name: string, fallback: number): number {
+  const value = Number.parseInt(process.env[name] ?? '', 10);
+  return Number.isFinite(value) && value >= 1 ? value : fallback;
+}
+
+/** What the concurrent stage of analysis hands to the in-order tracking loop */
+interface AnalyzedSource {
+  rawContent: string;
+  content: string;
+  result: AnalysisResult | null;
+  fileLanguage: string;
+  callExtraction: TreeSitterCallExtraction | null;
+  /** Lockfile or generator output, see `parsing.generatedFiles` */
+  generated: boolean;
+  /** What the file was saved as (its content is decoded to text) */
+  encoding: DecodedText['encoding'];
+}
 
 import {
   computeFileHashes,
+  computeFileHashesForChanges,
   readManifest,
   writeManifest,
   diffManifest,
   type FileManifest,
+  type FileRename,
   type ManifestDiff
 } from './manifest.js';
+import { recordIndexChanges, resetChangeJournal } from './change-journal.js';
+import { recordTombstones } from './tombstones.js';
 
 let cachedToolVersion: string | null = null;
 
@@ -80,9 +210,21 @@ async function getToolVersion(): Promise<string> {
 /**
  * Perform a Windows-safe atomic swap of staging artifacts into active location.
  * Strategy: move current active to .previous, then rename staging to active.
- * If staging rename fails, restore from .previous.
+ * If staging rename fails, restore from .previous. The replaced generation stays in
+ * .previous afterwards, for rollbackToPreviousIndex.
+ *
+ * Runs under the index write lock, so queries in this process never see the artifacts
+ * half-moved.
  */
-async function atomicSwapStagingToActive(
+export async function atomicSwapStagingToActive(
+  contextDir: string,
+  stagingDir: string,
+  buildId: string
+): Promise<void> {
+  await withIndexWriteLock(contextDir, () => swapGeneration(contextDir, stagingDir, buildId));
+}
+
+async function swapGeneration(
   contextDir: string,
   stagingDir: string,
   buildId: string
@@ -104,7 +246,8 @@ async function atomicSwapStagingToActive(
   const stagingStatsPath = path.join(stagingDir, INDEXING_STATS_FILENAME);
   const stagingRelationshipsPath = path.join(stagingDir, RELATIONSHIPS_FILENAME);
 
-  // Step 1: Create .previous directory and move current active there
+  // Step 1: Replace .previous with the current active generation
+  await cleanupDirectory(previousDir);
   await fs.mkdir(previousDir, { recursive: true });
 
   const moveIfExists = async (src: string, dest: string): Promise<void> => {
@@ -152,8 +295,7 @@ async function atomicSwapStagingToActive(
     await moveIfExists(stagingRelationshipsPath, activeRelationshipsPath);
     await moveDirIfExists(stagingVectorDir, activeVectorDir);
 
-    // Step 3: Clean up .previous and staging directories
-    await cleanupDirectory(previousDir);
+    // Step 3: Clean up the staging directory (.previous is kept for rollback)
     await cleanupDirectory(stagingDir);
 
     // Also clean up the parent .staging/ directory if empty
@@ -189,9 +331,96 @@ async function atomicSwapStagingToActive(
   }
 }
 
+export interface IndexGeneration {
+  buildId: string;
+  generatedAt: string;
+}
+
+/** The generation a rollback would restore, or null when there is none or it is invalid */
+export async function readPreviousIndexGeneration(
+  contextDir: string
+): Promise<IndexGeneration | null> {
+  const previousDir = path.join(contextDir, PREVIOUS_DIRNAME);
+  try {
+    const meta = await readIndexMeta(contextDir, previousDir);
+    await validateIndexArtifacts(contextDir, meta, previousDir);
+    return { buildId: meta.buildId, generatedAt: meta.generatedAt };
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Make the previous generation active again. The generation it replaces becomes the new
+ * .previous, so a second rollback undoes the first. Returns the restored generation, or
+ * null when there is nothing valid to roll back to. Waits for a build in progress to finish.
+ */
+export async function rollbackToPreviousIndex(
+  contextDir: string,
+  options: { signal?: AbortSignal } = {}
+): Promise<IndexGeneration | null> {
+  return withBuildLock(
+    contextDir,
+    async () => {
+      const previous = await readPreviousIndexGeneration(contextDir);
+      if (!previous) return null;
+
+      // Swap it in like any staged build
+      const stagingDir = path.join(contextDir, STAGING_DIRNAME, `rollback-${previous.buildId}`);
+      await fs.mkdir(path.dirname(stagingDir), { recursive: true });
+      await cleanupDirectory(stagingDir);
+      await fs.rename(path.join(contextDir, PREVIOUS_DIRNAME), stagingDir);
+      await atomicSwapStagingToActive(contextDir, stagingDir, previous.buildId);
+      // The journal's snapshot describes the generation that was just replaced
+      await resetChangeJournal(contextDir);
+      return previous;
+    },
+    options
+  );
+}
+
+/**
+ * Text sent to the embedding provider: light metadata prefix + chunk content, after the
+ * project's post-processors when given
+ */
+export function buildEmbeddingInput(
+  chunk: CodeChunk,
+  postProcessors?: ChunkPostProcessorChain
+): string {
+  const meta: string[] = [];
+  if (chunk.relativePath) {
+    meta.push(`path:${chunk.relativePath}`);
+  }
+  if (chunk.componentType && chunk.componentType !== 'unknown') {
+    meta.push(`type:${chunk.componentType}`);
+  }
+  if (chunk.metadata?.componentName) {
+    meta.push(`component:${chunk.metadata.componentName}`);
+  }
+  if (chunk.layer && chunk.layer !== 'unknown') {
+    meta.push(`layer:${chunk.layer}`);
+  }
+  const prefix = meta.length > 0 ? meta.join(' ') + '\n' : '';
+  const content = postProcessors
+    ? postProcessors.apply(chunk.content, {
+        language: chunk.language,
+        relativePath: chunk.relativePath
+      })
+    : chunk.content;
+  return prefix + content;
+}
+
 /**
  * Best-effort cleanup of a directory and its contents.
  */
+async function copyDirIfExists(src: string, dest: string): Promise<void> {
+  try {
+    await fs.cp(src, dest, { recursive: true });
+  } catch (error) {
+    if ((error as NodeJS.ErrnoException).code !== 'ENOENT') throw error;
+  }
+}
+
 async function cleanupDirectory(dirPath: string): Promise<void> {
   try {
     await fs.rm(dirPath, { recursive: true, force: true });
@@ -205,6 +434,27 @@ export interface IndexerOptions {
   config?: Partial<CodebaseConfig>;
   onProgress?: (progress: IndexingProgress) => void;
   incrementalOnly?: boolean;
+  /**
+   * Incremental only: files known to have changed since the last run (e.g. from the file
+   * watcher). Other files reuse their manifest hash instead of being re-read and re-hashed.
+   */
+  changedPaths?: string[];
+  /**
+   * Index a branch, tag or commit straight from the git object store instead of the working
+   * tree. Artifacts go to a per-ref directory so several refs can coexist with the main index.
+   */
+  ref?: string;
+  /**
+   * Write a standalone index to this directory instead of `.codebase-context/` (evaluation
+   * runs that must not replace the project's index). Only read back with the same directory.
+   */
+  contextDir?: string;
+  /**
+   * Abort the run. Checked between files and embedding batches, never once storing starts,
+   * so a cancelled run leaves the previous index untouched. It leaves a checkpoint instead
+   * (see index-checkpoint.ts) that the server resumes from.
+   */
+  signal?: AbortSignal;
 }
 
 interface PersistedIndexingStats {
@@ -220,12 +470,39 @@ export class CodebaseIndexer {
   private progress: IndexingProgress;
   private onProgressCallback?: (progress: IndexingProgress) => void;
   private incrementalOnly: boolean;
+  private changedPaths?: string[];
+  private ref?: string;
+  private contextDir: string;
+  private standaloneIndex: boolean;
+  /** Ref builds only: resolved commit and blob id per absolute file path */
+  private refCommit: string | null = null;
+  private refBlobs = new Map<string, string>();
+  private signal?: AbortSignal;
+  private redaction: RedactionOptions;
+  /** Per-language chunk sizing for this run (explicit config, else the project config) */
+  private chunking?: ChunkingConfig;
+  /** Tokenizer and input limit of the embedding model, when this run embeds and both are known */
+  private chunkTokens: ChunkTokenBudget | null = null;
+  /** The indexing stage in progress, timed for metrics and traced when tracing is on */
+  private stage?: { phase: IndexingPhase; span: TraceSpan; elapsed: () => number };
+  /** Chunks parsed so far in this run, searchable before the build finishes */
+  private partialChunks: CodeChunk[] = [];
 
   constructor(options: IndexerOptions) {
     this.rootPath = path.resolve(options.rootPath);
     this.config = this.mergeConfig(options.config);
+    this.redaction = resolveRedactionOptions(this.config.redaction);
     this.onProgressCallback = options.onProgress;
     this.incrementalOnly = options.incrementalOnly ?? false;
+    this.changedPaths = options.changedPaths;
+    this.ref = options.ref?.trim() || undefined;
+    this.signal = options.signal;
+    this.standaloneIndex = Boolean(options.contextDir);
+    this.contextDir = options.contextDir
+      ? path.resolve(options.contextDir)
+      : this.ref
+        ? getRefContextDir(this.rootPath, this.ref)
+        : path.join(this.rootPath, CODEBASE_CONTEXT_DIRNAME);
 
     this.progress = {
       phase: 'initializing',
@@ -246,15 +523,45 @@ export class CodebaseIndexer {
         vue: { enabled: false, priority: 90 },
         generic: { enabled: true, priority: 10 }
       },
-      include: ['**/*.{ts,tsx,js,jsx,html,css,scss,sass,less}'],
-      exclude: ['node_modules/**', 'dist/**', 'build/**', '.git/**', 'coverage/**'],
+      include: [
+        '**/*.{ts,tsx,js,jsx,vue,svelte,astro,html,css,scss,sass,less}',
+        // Languages with Tree-sitter grammars
+        '**/*.{py,go,rs,java,kt,kts,cs,c,cpp,cc,h,hpp,rb,php,swift,m,mm,scala,ex,exs,zig}',
+        // SQL scripts (statement-level chunks)
+        '**/*.sql',
+        // Terraform and Kubernetes manifests (resource-level chunks)
+        '**/*.{tf,tfvars,hcl,yaml,yml}',
+        // Jupyter notebooks (cell-level chunks)
+        '**/*.ipynb',
+        // Protobuf, GraphQL and OpenAPI/Swagger schemas (YAML specs are covered above)
+        '**/*.proto',
+        '**/*.{graphql,gql}',
+        '**/{openapi,swagger}*.json',
+        '**/*.{openapi,swagger}.json',
+        // Design docs, ADRs and READMEs (heading-section chunks)
+        '**/*.{md,mdx}',
+        // Languages added by grammar plugins
+        ...listGrammarPlugins().flatMap((plugin) => plugin.extensions.map((ext) => `**/*${ext}`))
+      ],
+      exclude: [
+        'node_modules/**',
+        'dist/**',
+        'build/**',
+        '.git/**',
+        'coverage/**',
+        '**/pnpm-lock.yaml'
+      ],
       respectGitignore: true,
       parsing: {
-        maxFileSize: 1048576,
+        maxFileSize: DEFAULT_MAX_FILE_SIZE,
+        maxChunksPerFile: DEFAULT_MAX_CHUNKS_PER_FILE,
+        generatedFiles: 'sample',
+        generatedSampleChunks: DEFAULT_GENERATED_SAMPLE_CHUNKS,
         chunkSize: 50,
         chunkOverlap: 0,
         parseTests: true,
-        parseNodeModules: false
+        parseNodeModules: false,
+        concurrency: DEFAULT_PARSE_CONCURRENCY
       },
       styleGuides: {
         autoDetect: true,
@@ -267,13 +574,15 @@ export class CodebaseIndexer {
         includeChangelogs: false
       },
       embedding: {
-        provider: 'transformers',
+        provider: DEFAULT_EMBEDDING_CONFIG.provider,
         model: DEFAULT_MODEL,
-        batchSize: 32
+        batchSize: DEFAULT_EMBEDDING_CONFIG.batchSize,
+        concurrency: DEFAULT_EMBEDDING_CONFIG.concurrency,
+        maxRetries: DEFAULT_EMBEDDING_CONFIG.maxRetries
       },
       skipEmbedding: false,
       storage: {
-        provider: 'lancedb',
+        provider: DEFAULT_STORAGE_CONFIG.provider,
         path: './codebase-index'
       }
     };
@@ -293,7 +602,61 @@ export class CodebaseIndexer {
     };
   }
 
+  /**
+   * Build the index. Builds of one index directory run one at a time, across processes; this
+   * waits for a build already running and is cancelled by the signal while it waits.
+   */
   async index(): Promise<IndexingStats> {
+    const releaseLock = await acquireBuildLock(this.contextDir, { signal: this.signal });
+    try {
+      return await this.indexUnderLock();
+    } finally {
+      this.partialChunks = [];
+      await releaseLock();
+    }
+  }
+
+  private async indexUnderLock(): Promise<IndexingStats> {
+    const mode = this.incrementalOnly ? 'incremental' : 'full';
+    const attributes = {
+      'codebase_context.index.mode': mode,
+      'codebase_context.index.ref': !!this.ref
+    };
+    return withSpan('codebase_context.index', attributes, async (span) => {
+      try {
+        const stats = await this.runIndex();
+        await clearIndexCheckpoint(this.contextDir).catch(() => undefined);
+        span.setAttribute('codebase_context.index.files', stats.indexedFiles);
+        span.setAttribute('codebase_context.index.chunks', stats.totalChunks);
+        metrics.indexBuilds.inc({ mode, status: 'ok' });
+        return stats;
+      } catch (error) {
+        // runIndex has marked progress as failed; the open stage is where it stopped
+        const stoppedIn = this.stage?.phase ?? this.progress.phase;
+        this.endStage(error);
+        const status = error instanceof IndexingCancelledError ? 'cancelled' : 'error';
+        if (status === 'cancelled') {
+          await writeIndexCheckpoint(this.contextDir, {
+            mode,
+            phase: stoppedIn,
+            filesProcessed: this.progress.filesProcessed,
+            totalFiles: this.progress.totalFiles,
+            ...(this.progress.chunksToEmbed !== undefined && {
+              chunksEmbedded: this.progress.chunksEmbedded ?? 0,
+              chunksToEmbed: this.progress.chunksToEmbed
+            }),
+            stoppedAt: new Date().toISOString()
+          }).catch(() => undefined);
+        }
+        metrics.indexBuilds.inc({ mode, status });
+        throw error;
+      } finally {
+        this.endStage();
+      }
+    });
+  }
+
+  private async runIndex(): Promise<IndexingStats> {
     const startTime = Date.now();
     const stats: IndexingStats = {
       totalFiles: 0,
@@ -320,6 +683,8 @@ export class CodebaseIndexer {
     };
 
     let stagingDir: string | null = null;
+    // Remote rebuild that stays out of searches until the local generation swaps in
+    let pendingGeneration: VectorStorageProvider | null = null;
 
     try {
       // Ensure there is at least a generic fallback analyzer registered when the indexer
@@ -332,10 +697,43 @@ export class CodebaseIndexer {
       const buildId = randomUUID();
       const generatedAt = new Date().toISOString();
       const toolVersion = await getToolVersion();
+      // The checkout this working-tree index reflects, for detecting branch switches
+      const head = this.ref ? null : await readGitHead(this.rootPath);
 
       // Phase 1: Scanning
       this.updateProgress('scanning', 0);
-      let files = await this.scanFiles();
+      let files = this.ref ? await this.scanGitRef(this.ref) : await this.scanFiles();
+
+      // Compliance rules (explicit config wins over .codebase-context/config.json): files under
+      // excludePaths are left out before the path policy, which denies them too, so they're logged
+      const compliance = await loadComplianceFilter(this.rootPath, this.config.compliance);
+      const complianceAudit: ComplianceAuditEntry[] = [];
+      if (compliance.active) {
+        files = files.filter((file) => {
+          const relativeFile = path.relative(this.rootPath, file).replace(/\\/g, '/');
+          if (!compliance.excludesPath(relativeFile)) return true;
+          complianceAudit.push({
+            at: generatedAt,
+            file: relativeFile,
+            rule: 'excludePaths',
+            action: 'excluded',
+            matches: 0
+          });
+          return false;
+        });
+        if (complianceAudit.length > 0) stats.complianceExcludedFiles = complianceAudit.length;
+      }
+
+      // Path policy (explicit config wins over .codebase-context/config.json; env adds to it):
+      // denied files never reach the index, and ones indexed before are dropped as deleted
+      const pathPolicy = await loadPathPolicy(this.rootPath, this.config.security);
+      if (pathPolicy.active) {
+        const scanned = files.length;
+        files = files.filter((file) => pathPolicy.allows(file));
+        if (files.length < scanned) {
+          console.error(`Path policy: left out ${scanned - files.length} file(s)`);
+        }
+      }
 
       // Memory safety: limit total files to prevent heap exhaustion
       const MAX_FILES = 10000;
@@ -351,39 +749,122 @@ export class CodebaseIndexer {
 
       stats.totalFiles = files.length;
       this.progress.totalFiles = files.length;
+      this.throwIfCancelled();
 
       console.error(`Found ${files.length} files to index`);
 
       // Phase 1b: Incremental diff (if incremental mode)
-      const contextDir = path.join(this.rootPath, CODEBASE_CONTEXT_DIRNAME);
+      const contextDir = this.contextDir;
       const manifestPath = path.join(contextDir, MANIFEST_FILENAME);
       const indexingStatsPath = path.join(contextDir, INDEXING_STATS_FILENAME);
       let diff: ManifestDiff | null = null;
       let currentHashes: Record<string, string> | null = null;
       let previousManifest: FileManifest | null = null;
 
-      if (this.incrementalOnly) {
-        this.updateProgress('scanning', 10);
-        console.error('Computing file hashes for incremental diff...');
-        currentHashes = await computeFileHashes(files, this.rootPath);
+      // Vectors from another embedding model, or from differently post-processed inputs, can't
+      // be mixed in, so that needs a full rebuild
+      const previousMeta = this.incrementalOnly
+        ? await readIndexMeta(this.rootPath, contextDir).catch(() => null)
+        : null;
+      const postProcessors = await loadChunkPostProcessors(
+        this.rootPath,
+        this.config.postProcessing
+      );
+      const modelDrift = this.config.skipEmbedding
+        ? null
+        : (describeEmbeddingDrift(
+            previousMeta?.embedding,
+            resolveEmbeddingModel(this.config.embedding)
+          ) ?? describePostProcessingDrift(previousMeta?.embedding, postProcessors.signature));
+      if (modelDrift) {
+        console.error(`${modelDrift}; running a full rebuild instead of an incremental one`);
+      }
+      // Searches keep using the active vectors, and the model they came from, until this build
+      // swaps in with the configured one
+      const migration = this.config.skipEmbedding
+        ? null
+        : await startModelMigration(
+            this.rootPath,
+            resolveEmbeddingModel(this.config.embedding),
+            contextDir
+          );
+      if (migration) {
+        console.error(
+          `Re-embedding with ${migration.to.provider}:${migration.to.model}; serving ` +
+            `${migration.from.provider}:${migration.from.model} vectors until it completes`
+        );
+      }
+
+      // An encrypted index is never rewritten in plaintext, and one index never mixes keys
+      const indexKey = await resolveIndexKey();
+      const sealedWith = (
+        this.incrementalOnly
+          ? previousMeta
+          : await readIndexMeta(this.rootPath, contextDir).catch(() => null)
+      )?.encryption?.keyId;
+      if (sealedWith && !indexKey) {
+        throw new IndexKeyError(
+          `The index is encrypted (key ${sealedWith}); set ${ENCRYPTION_KEY_ENV} to rebuild ` +
+            'it, or run `codebase-context purge` to start over unencrypted'
+        );
+      }
+      const keyDrift = this.incrementalOnly && !modelDrift && sealedWith !== indexKey?.id;
+      if (keyDrift) {
+        console.error(
+          'Index encryption key changed; running a full rebuild instead of an incremental one'
+        );
+      }
 
+      if (this.incrementalOnly && !modelDrift && !keyDrift) {
+        this.updateProgress('scanning', 10);
         previousManifest = await readManifest(manifestPath);
+
+        // An ignore-file edit can change the file set in ways the hint doesn't describe
+        const touchesIgnoreFile = this.changedPaths?.some((p) => {
+          const name = path.basename(p);
+          return name === GITIGNORE_FILENAME || name === MCPIGNORE_FILENAME;
+        });
+        const changedPaths = touchesIgnoreFile ? undefined : this.changedPaths;
+
+        if (this.ref) {
+          // Blob ids already identify content; no need to read anything
+          currentHashes = this.getRefBlobHashes();
+        } else if (previousManifest && changedPaths) {
+          console.error(`Computing file hashes for ${changedPaths.length} changed path(s)...`);
+          currentHashes = await computeFileHashesForChanges(
+            files,
+            this.rootPath,
+            previousManifest,
+            changedPaths
+          );
+        } else {
+          console.error('Computing file hashes for incremental diff...');
+          currentHashes = await computeFileHashes(files, this.rootPath);
+        }
+
         diff = diffManifest(previousManifest, currentHashes);
 
         console.error(
           `Incremental diff: ${diff.added.length} added, ${diff.changed.length} changed, ` +
-            `${diff.deleted.length} deleted, ${diff.unchanged.length} unchanged`
+            `${diff.deleted.length} deleted, ${diff.renamed.length} renamed, ` +
+            `${diff.unchanged.length} unchanged`
         );
 
         stats.incremental = {
           added: diff.added.length,
           changed: diff.changed.length,
           deleted: diff.deleted.length,
-          unchanged: diff.unchanged.length
+          unchanged: diff.unchanged.length,
+          renamed: diff.renamed.length
         };
 
         // Short-circuit: nothing changed
-        if (diff.added.length === 0 && diff.changed.length === 0 && diff.deleted.length === 0) {
+        if (
+          diff.added.length === 0 &&
+          diff.changed.length === 0 &&
+          diff.deleted.length === 0 &&
+          diff.renamed.length === 0
+        ) {
           console.error('No files changed - skipping re-index.');
           this.updateProgress('complete', 100);
           stats.duration = Date.now() - startTime;
@@ -457,34 +938,213 @@ export class CodebaseIndexer {
       // but embedding only runs on filesToProcess
       this.updateProgress('analyzing', 0);
       const allChunks: CodeChunk[] = [];
+      this.partialChunks = allChunks;
       const changedChunks: CodeChunk[] = []; // Only chunks from added/changed files
       const libraryTracker = new LibraryUsageTracker();
       const patternDetector = new PatternDetector();
       const importGraph = new ImportGraph();
       const internalFileGraph = new InternalFileGraph(this.rootPath);
+      const callGraph = new CallGraphBuilder(this.rootPath);
+      const symbolIndex = new SymbolIndexBuilder(this.rootPath);
+      const typeHierarchy = new TypeHierarchyBuilder(this.rootPath);
 
       // Fetch git commit dates for pattern momentum analysis
       const fileDates = await getFileCommitDates(this.rootPath);
+      const recentCommits = this.ref
+        ? new Map<string, number>()
+        : await getRecentCommitCounts(this.rootPath);
+
+      // .NET projects (working tree only): chunks are tagged with their owning .csproj
+      const dotnetProjects = this.ref ? [] : await detectDotnetProjects(this.rootPath);
+
+      // Monorepo packages (npm/pnpm, go.work, Cargo, Bazel): chunks are tagged with their owner
+      const packages = this.ref ? [] : await detectWorkspacePackages(this.rootPath);
+
+      // Owners, module tags and annotations from the enrichment config (explicit config wins
+      // over .codebase-context/config.json); CODEOWNERS is read from the working tree only
+      const enricher = await createMetadataEnricher(
+        this.rootPath,
+        this.config.enrichment ?? (await loadProjectEnrichmentConfig(this.rootPath)),
+        { readCodeowners: !this.ref }
+      );
+      this.chunking = this.config.chunking ?? (await loadProjectChunkingConfig(this.rootPath));
+      // Chunks are measured with the embedding model's tokenizer, so none is cut short when
+      // embedded; the provider is only loaded when this run has something to embed
+      this.chunkTokens =
+        !this.config.skipEmbedding && filesToProcess.length > 0
+          ? await this.loadChunkTokenBudget()
+          : null;
+
+      // Import resolution against the indexed files and workspace packages (by name)
+      const workspacePackages = npmPackageDirs(packages);
+      const relativeFiles = files.map((f) => path.relative(this.rootPath, f).replace(/\\/g, '/'));
+      const resolveImport = createImportResolver(relativeFiles, workspacePackages);
+      // Swift <-> Objective-C references in mixed targets, linked once every file is analyzed
+      const swiftObjcBridge = new SwiftObjcBridge(relativeFiles);
+      // Generated code and handlers -> the .proto / OpenAPI file they implement
+      const schemaLinker = new SchemaLinker(relativeFiles);
+      // Bazel/Buck targets from BUILD files (working tree only): chunks are tagged with theirs
+      const buildGraph = this.ref ? null : await detectBuildGraph(this.rootPath, relativeFiles);
+      const buildTargets = buildGraph ? targetsByFile(buildGraph) : new Map<string, string[]>();
 
       // When incremental, track which files need embedding
       const filesToProcessSet = diff ? new Set(filesToProcess.map((f) => f)) : null;
 
-      for (let i = 0; i < files.length; i++) {
-        const file = files[i];
+      // Reading, chunking and call extraction run in a bounded pool; the tracking below takes
+      // the results in file order, so graphs, stats and chunk order don't depend on concurrency
+      const analyzedFiles = mapOrdered(
+        files,
+        (file) =>
+          this.analyzeSource(file).catch((error: unknown): { error: unknown } => ({ error })),
+        {
+          concurrency: this.config.parsing?.concurrency ?? DEFAULT_PARSE_CONCURRENCY,
+          queueSize: this.config.parsing?.queueSize
+        }
+      );
+
+      for await (const { item: file, index: i, result: analyzed } of analyzedFiles) {
+        this.throwIfCancelled();
         this.progress.currentFile = file;
         this.progress.filesProcessed = i + 1;
         this.progress.percentage = Math.round(((i + 1) / files.length) * 100);
 
         try {
-          // Normalize line endings to \n for consistent cross-platform output
-          const rawContent = await fs.readFile(file, 'utf-8');
-          const content = rawContent.replace(/\r\n/g, '\n');
-          const result = await analyzerRegistry.analyzeFile(file, content);
+          if ('error' in analyzed) throw analyzed.error;
+          const {
+            rawContent,
+            result,
+            fileLanguage,
+            callExtraction,
+            generated,
+            encoding
+          } = analyzed;
+          const relativeFile = path.relative(this.rootPath, file).replace(/\\/g, '/');
+
+          // A file matching an `exclude` compliance rule is left out; the rest is masked below
+          const excludedBy = compliance.active ? compliance.excludingRule(analyzed.content) : null;
+          if (excludedBy) {
+            complianceAudit.push({
+              at: generatedAt,
+              file: relativeFile,
+              rule: excludedBy.rule,
+              action: 'excluded',
+              matches: excludedBy.matches
+            });
+            stats.complianceExcludedFiles = (stats.complianceExcludedFiles ?? 0) + 1;
+            stats.skippedFiles++;
+          } else if (result) {
+            // Patterns and snippets taken from the file see the masked text, like its chunks
+            let content = analyzed.content;
+            if (compliance.active) {
+              const scan = compliance.mask(content);
+              content = scan.text;
+              for (const [rule, matches] of Object.entries(scan.matches)) {
+                complianceAudit.push({
+                  at: generatedAt,
+                  file: relativeFile,
+                  rule,
+                  action: 'masked',
+                  matches
+                });
+                stats.complianceFiltered = (stats.complianceFiltered ?? 0) + matches;
+              }
+            }
 
-          if (result) {
             const isFileChanged = !filesToProcessSet || filesToProcessSet.has(file);
 
-            const mergedChunks = mergeSmallChunks(result.chunks, 15);
+            const merged = mergeSmallChunks(result.chunks, 15);
+            const fitted = this.fitChunksToModel(merged, fileLanguage);
+            if (fitted.length > merged.length) {
+              stats.chunksSplitForModel =
+                (stats.chunksSplitForModel ?? 0) + fitted.length - merged.length;
+            }
+            const mergedChunks = this.capFileChunks(fitted, generated);
+            if (mergedChunks.length > 0 && mergedChunks[0].metadata.droppedChunks) {
+              stats.truncatedFiles = (stats.truncatedFiles ?? 0) + 1;
+            }
+            // Mask credentials before chunks are embedded or written to the keyword index
+            for (const chunk of mergedChunks) {
+              const { text, redactions } = redactSecrets(chunk.content, this.redaction);
+              if (redactions > 0) {
+                chunk.content = text;
+                chunk.metadata = { ...chunk.metadata, redactedSecrets: redactions };
+                stats.redactedSecrets = (stats.redactedSecrets ?? 0) + redactions;
+              }
+              if (!compliance.active) continue;
+              const scan = compliance.mask(chunk.content);
+              const filtered = Object.values(scan.matches).reduce((sum, n) => sum + n, 0);
+              if (filtered > 0) {
+                chunk.content = scan.text;
+                chunk.metadata = { ...chunk.metadata, complianceFiltered: filtered };
+              }
+              if (chunk.summary) chunk.summary = compliance.mask(chunk.summary).text;
+            }
+            const dotnetProject = findOwningProject(dotnetProjects, relativeFile);
+            if (dotnetProject) {
+              for (const chunk of mergedChunks) {
+                chunk.metadata = { ...chunk.metadata, dotnetProject: dotnetProject.name };
+              }
+            }
+            const owningPackage = findOwningPackage(packages, relativeFile);
+            if (owningPackage) {
+              for (const chunk of mergedChunks) {
+                chunk.metadata = { ...chunk.metadata, package: owningPackage.name };
+              }
+            }
+            const fileTargets = buildTargets.get(relativeFile);
+            if (fileTargets) {
+              for (const chunk of mergedChunks) {
+                chunk.metadata = { ...chunk.metadata, buildTargets: fileTargets };
+              }
+            }
+            for (const chunk of mergedChunks) {
+              const enriched = enricher.enrich(relativeFile, chunk.content);
+              if (Object.keys(enriched).length > 0) {
+                chunk.metadata = { ...chunk.metadata, ...enriched };
+              }
+              // Roles for the `role` filter, from every analyzer's framework heuristics
+              const roles = analyzerRegistry.detectRoles(chunk);
+              if (roles.length > 0) chunk.metadata = { ...chunk.metadata, roles };
+            }
+            // SQL strings in application code, attributed to the function they sit in
+            attachEmbeddedSql(mergedChunks, content, fileLanguage, callExtraction?.symbols ?? []);
+            // A Terraform module is the directory its .tf files live in
+            const modulePath = path.posix.dirname(relativeFile);
+            for (const chunk of mergedChunks) {
+              const infra = chunk.metadata.infra;
+              if (infra?.kind === 'terraform') {
+                chunk.metadata = { ...chunk.metadata, infra: { ...infra, modulePath } };
+              }
+            }
+            if (this.ref && this.refCommit) {
+              for (const chunk of mergedChunks) {
+                chunk.metadata = {
+                  ...chunk.metadata,
+                  gitRef: this.ref,
+                  gitCommit: this.refCommit
+                };
+              }
+            }
+            // Size and recency for the file-level search filters and the recency/churn boost;
+            // the last commit date beats mtime, which a fresh checkout resets for every file
+            const fileSize = Buffer.byteLength(rawContent);
+            const commits = recentCommits.get(relativeFile);
+            const modified = this.ref
+              ? undefined
+              : (fileDates.get(relativeFile) ??
+                (await fs.stat(file).then(
+                  (stat) => stat.mtime,
+                  () => undefined
+                )));
+            for (const chunk of mergedChunks) {
+              chunk.metadata = {
+                ...chunk.metadata,
+                fileSize,
+                ...(encoding !== 'utf-8' ? { encoding } : {}),
+                ...(modified ? { lastModified: modified.toISOString() } : {}),
+                ...(commits ? { recentCommits: commits } : {})
+              };
+            }
 
             allChunks.push(...mergedChunks);
             if (isFileChanged) {
@@ -498,23 +1158,22 @@ export class CodebaseIndexer {
               libraryTracker.track(imp.source, file);
               importGraph.trackImport(imp.source, file, imp.line || 1);
 
-              // Track internal file-to-file imports (relative paths)
-              if (imp.source.startsWith('.')) {
-                // Resolve the relative import to an absolute path
-                const fileDir = path.dirname(file);
-                let resolvedPath = path.resolve(fileDir, imp.source);
-
-                // Try common extensions if not already specified
-                const ext = path.extname(resolvedPath);
-                if (!ext) {
-                  for (const tryExt of ['.ts', '.tsx', '.js', '.jsx']) {
-                    const withExt = resolvedPath + tryExt;
-                    // We don't check if file exists for performance - just track what's referenced
-                    resolvedPath = withExt;
-                    break;
-                  }
-                }
-
+              // Track file-to-file imports (relative paths and workspace packages);
+              // other bare specifiers are recorded as external packages
+              const resolved = resolveImport(relativeFile, imp.source);
+              if (resolved?.file) {
+                internalFileGraph.trackImport(
+                  file,
+                  path.join(this.rootPath, resolved.file),
+                  imp.line || 1,
+                  imp.imports
+                );
+              } else if (resolved?.package) {
+                internalFileGraph.trackExternalImport(file, resolved.package);
+              } else if (imp.source.startsWith('.')) {
+                // Unresolved relative import: keep the edge with a guessed extension
+                let resolvedPath = path.resolve(path.dirname(file), imp.source);
+                if (!path.extname(resolvedPath)) resolvedPath += '.ts';
                 internalFileGraph.trackImport(file, resolvedPath, imp.line || 1, imp.imports);
               }
             }
@@ -528,6 +1187,28 @@ export class CodebaseIndexer {
               internalFileGraph.trackExports(file, fileExports);
             }
 
+            // Call sites for find_callers / find_callees and definitions for search_symbols
+            swiftObjcBridge.trackFile(relativeFile, fileLanguage, content);
+            typeHierarchy.trackFile(file, fileLanguage, content);
+            if (callExtraction) {
+              callGraph.trackFile(file, callExtraction);
+              symbolIndex.trackFile(file, fileLanguage, [
+                ...callExtraction.symbols,
+                ...callExtraction.constants
+              ]);
+            }
+
+            // Protobuf messages/services/rpcs and OpenAPI operations/schemas
+            const schemaBlocks = findSchemaBlocks(content, fileLanguage);
+            if (schemaBlocks.length > 0) {
+              schemaLinker.trackSchema(relativeFile, schemaBlocks);
+              symbolIndex.trackFile(file, fileLanguage, schemaSymbols(content, schemaBlocks));
+            }
+            // Tables, views and routines created by SQL scripts
+            if (fileLanguage === 'sql') {
+              symbolIndex.trackFile(file, fileLanguage, sqlSymbols(content));
+            }
+
             // Detect generic patterns from code
             patternDetector.detectFromCode(content, file);
 
@@ -609,6 +1290,9 @@ export class CodebaseIndexer {
             }
           } else {
             stats.skippedFiles++;
+            if (generated) {
+              stats.skippedGeneratedFiles = (stats.skippedGeneratedFiles ?? 0) + 1;
+            }
           }
         } catch (error) {
           stats.skippedFiles++;
@@ -625,6 +1309,26 @@ export class CodebaseIndexer {
         }
       }
 
+      const schemaEdges = await schemaLinker.edges(symbolIndex.toJSON(), (relative) =>
+        this.readSourceFile(path.join(this.rootPath, relative))
+      );
+      for (const edge of [...swiftObjcBridge.edges(symbolIndex.toJSON()), ...schemaEdges]) {
+        internalFileGraph.trackImport(
+          path.join(this.rootPath, edge.from),
+          path.join(this.rootPath, edge.to),
+          edge.line,
+          edge.symbols
+        );
+      }
+
+      if (this.chunkTokens && stats.chunksSplitForModel) {
+        const { counter, maxInputTokens } = this.chunkTokens;
+        console.error(
+          `Split ${stats.chunksSplitForModel} chunks to fit the embedding model's ` +
+            `${maxInputTokens}-token input (${counter.exact ? counter.name : 'estimated'} tokens)`
+        );
+      }
+
       stats.totalChunks = allChunks.length;
       stats.avgChunkSize =
         allChunks.length > 0
@@ -646,6 +1350,10 @@ export class CodebaseIndexer {
 
       // Phase 3: Embedding (only changed/added chunks in incremental mode)
       const chunksWithEmbeddings: CodeChunkWithEmbedding[] = [];
+      // Recorded in index-meta.json; incremental runs keep the model they were checked against
+      let embeddingFingerprint: EmbeddingFingerprint | undefined = diff
+        ? previousMeta?.embedding
+        : undefined;
 
       if (!this.config.skipEmbedding && chunksToEmbed.length > 0) {
         this.updateProgress('embedding', 50);
@@ -657,81 +1365,123 @@ export class CodebaseIndexer {
 
         // Initialize embedding provider
         const embeddingProvider = await getEmbeddingProvider(this.config.embedding);
+        embeddingFingerprint = {
+          ...providerFingerprint(embeddingProvider),
+          ...(postProcessors.signature && { postProcessing: postProcessors.signature })
+        };
 
-        // Generate embeddings for all chunks
-        // Outer batch size controls how many chunks we collect before calling embedBatch.
-        // embedBatch internally sub-batches further based on model context size.
-        const batchSize = Math.min(this.config.embedding?.batchSize || 32, 32);
-
-        for (let i = 0; i < chunksToEmbed.length; i += batchSize) {
-          const batch = chunksToEmbed.slice(i, i + batchSize);
-          const texts = batch.map((chunk) => {
-            const meta: string[] = [];
-            if (chunk.relativePath) {
-              meta.push(`path:${chunk.relativePath}`);
-            }
-            if (chunk.componentType && chunk.componentType !== 'unknown') {
-              meta.push(`type:${chunk.componentType}`);
-            }
-            if (chunk.metadata?.componentName) {
-              meta.push(`component:${chunk.metadata.componentName}`);
-            }
-            if (chunk.layer && chunk.layer !== 'unknown') {
-              meta.push(`layer:${chunk.layer}`);
-            }
-            const prefix = meta.length > 0 ? meta.join(' ') + '\n' : '';
-            return prefix + chunk.content;
-          });
-
-          const embeddings = await embeddingProvider.embedBatch(texts);
+        // Batch size is how many chunks go into one embedBatch call; local providers
+        // sub-batch further based on model context size.
+        const { batchSize = 32, concurrency = 1, maxRetries = 3 } = this.config.embedding ?? {};
 
-          for (let j = 0; j < batch.length; j++) {
-            chunksWithEmbeddings.push({
-              ...batch[j],
-              embedding: embeddings[j]
-            });
+        // Reuse vectors for chunks whose embedding input is byte-identical to a previous run.
+        // Batches are written back as they finish, so a failed run resumes from here.
+        const embeddingCache = await EmbeddingCache.load(
+          contextDir,
+          `${embeddingProvider.name}:${embeddingProvider.modelName}`,
+          embeddingProvider.dimensions
+        );
+        const pending: Array<{ chunk: CodeChunk; text: string; hash: string }> = [];
+        for (const chunk of chunksToEmbed) {
+          const text = buildEmbeddingInput(chunk, postProcessors);
+          const hash = hashEmbeddingInput(text);
+          const cached = embeddingCache.get(hash);
+          if (cached) {
+            chunksWithEmbeddings.push({ ...chunk, embedding: cached });
+          } else {
+            pending.push({ chunk, text, hash });
           }
+        }
+        if (embeddingCache.hits > 0) {
+          console.error(
+            `Embedding cache: reused ${embeddingCache.hits}, embedding ${pending.length}`
+          );
+        }
+        stats.embeddingCache = { hits: embeddingCache.hits, misses: embeddingCache.misses };
+        metrics.embeddingCacheHits.inc({}, embeddingCache.hits);
+        metrics.embeddingCacheMisses.inc({}, embeddingCache.misses);
 
-          // Update progress
-          const embeddingProgress = 50 + Math.round((i / chunksToEmbed.length) * 25);
-          this.updateProgress('embedding', embeddingProgress);
+        this.progress.chunksToEmbed = chunksToEmbed.length;
+        this.progress.chunksEmbedded = embeddingCache.hits;
 
-          if ((i + batchSize) % 100 === 0 || i + batchSize >= chunksToEmbed.length) {
+        const texts = pending.map((p) => p.text);
+        const embeddings: number[][] = new Array(pending.length);
+        let embedded = 0;
+        let batchesSinceCheckpoint = 0;
+        try {
+          await embedInBatches(embeddingProvider, texts, {
+            batchSize,
+            concurrency,
+            maxRetries,
+            beforeBatch: () => this.throwIfCancelled(),
+            onBatch: async (offset, vectors) => {
+              for (let j = 0; j < vectors.length; j++) {
+                embeddings[offset + j] = vectors[j];
+                embeddingCache.set(pending[offset + j].hash, vectors[j]);
+              }
+
+              // Update progress
+              embedded += vectors.length;
+              this.progress.chunksEmbedded = embeddingCache.hits + embedded;
+              const embeddingProgress = 50 + Math.round((embedded / pending.length) * 25);
+              this.updateProgress('embedding', embeddingProgress);
+
+              if (++batchesSinceCheckpoint >= EMBEDDING_CHECKPOINT_BATCHES) {
+                batchesSinceCheckpoint = 0;
+                console.error(`Embedded ${embedded}/${pending.length} chunks`);
+                await embeddingCache.save({ prune: false });
+              }
+            }
+          });
+        } catch (error) {
+          // Keep what was embedded so the next run only sends the remaining chunks
+          if (embedded > 0) {
+            await embeddingCache.save({ prune: false }).catch(() => undefined);
             console.error(
-              `Embedded ${Math.min(i + batchSize, chunksToEmbed.length)}/${
-                chunksToEmbed.length
-              } chunks`
+              `Embedding stopped after ${embedded}/${pending.length} chunks; ` +
+                'the next run resumes from there'
             );
           }
+          throw error;
+        }
+        if (pending.length > 0) console.error(`Embedded ${embedded}/${pending.length} chunks`);
+
+        for (let j = 0; j < pending.length; j++) {
+          chunksWithEmbeddings.push({ ...pending[j].chunk, embedding: embeddings[j] });
         }
+
+        // Full rebuilds see every chunk, so entries they didn't touch are stale
+        await embeddingCache.save({ prune: !diff });
       } else if (this.config.skipEmbedding) {
         console.error('Skipping embedding generation (skipEmbedding=true)');
       } else if (chunksToEmbed.length === 0 && diff) {
         console.error('No chunks to embed (all unchanged)');
       }
 
+      // Last cancellation point: nothing below may be interrupted halfway
+      this.throwIfCancelled();
+
       // Phase 4: Storing
       this.updateProgress('storing', 75);
 
-      // For full rebuilds, use staging directory for atomic swap
-      // For incremental, write directly to active location
-      const isFullRebuild = !diff;
-      let activeContextDir = contextDir;
-
-      if (isFullRebuild) {
-        // Create staging directory for atomic swap
-        const stagingBase = path.join(contextDir, STAGING_DIRNAME);
-        stagingDir = path.join(stagingBase, buildId);
-        await fs.mkdir(stagingDir, { recursive: true });
-        activeContextDir = stagingDir;
-        console.error(`Full rebuild: writing to staging ${stagingDir}`);
+      // Every build writes a new generation to staging and swaps it in once validated, so a
+      // crash leaves the active index untouched. Incremental builds update the vector store
+      // rather than rewrite it, so they start from a copy of the active one.
+      const stagingBase = path.join(contextDir, STAGING_DIRNAME);
+      stagingDir = path.join(stagingBase, buildId);
+      await fs.mkdir(stagingDir, { recursive: true });
+      const activeContextDir = stagingDir;
+      if (diff) {
+        await copyDirIfExists(
+          path.join(contextDir, VECTOR_DB_DIRNAME),
+          path.join(stagingDir, VECTOR_DB_DIRNAME)
+        );
       }
-
-      await fs.mkdir(activeContextDir, { recursive: true });
+      console.error(`${diff ? 'Incremental' : 'Full'} build: writing to staging ${stagingDir}`);
 
       if (!this.config.skipEmbedding) {
         const storagePath = path.join(activeContextDir, VECTOR_DB_DIRNAME);
-        const storageProvider = await getStorageProvider({ path: storagePath });
+        const storageProvider = await getStorageProvider(this.getStorageConfig(storagePath));
 
         if (diff) {
           // Incremental: delete old chunks for changed + deleted files, then add new
@@ -747,18 +1497,31 @@ export class CodebaseIndexer {
           if (allDeletePaths.length > 0) {
             await storageProvider.deleteByFilePaths(allDeletePaths);
           }
+          // Moved files keep their vectors; only the stored paths change
+          if (diff.renamed.length > 0) {
+            await this.moveStoredChunks(storageProvider, diff.renamed, files);
+          }
           if (chunksWithEmbeddings.length > 0) {
-            await storageProvider.store(chunksWithEmbeddings);
+            await storageProvider.store(sealChunks(chunksWithEmbeddings, indexKey));
           }
           console.error(
             `Incremental store: deleted chunks for ${diff.changed.length + diff.deleted.length} files, ` +
               `added ${chunksWithEmbeddings.length} new chunks`
           );
         } else {
-          // Full rebuild: store to staging (no clear - fresh directory)
+          // Full rebuild: store to staging (no clear - fresh directory).
+          // Remote backends keep one collection per project: they rebuild into a new
+          // generation when they can, and start from empty otherwise.
+          if (storageProvider.beginGeneration) {
+            await storageProvider.beginGeneration();
+            pendingGeneration = storageProvider;
+          } else if (isRemoteStorageProvider(storageProvider.name)) {
+            await storageProvider.clear();
+          }
           console.error(`Storing ${chunksToEmbed.length} chunks to staging...`);
-          await storageProvider.store(chunksWithEmbeddings);
+          await storageProvider.store(sealChunks(chunksWithEmbeddings, indexKey));
         }
+        if (!pendingGeneration) await storageProvider.close?.();
       }
 
       // Vector DB build marker (required for version gating)
@@ -779,7 +1542,7 @@ export class CodebaseIndexer {
         indexPath,
         JSON.stringify({
           header: { buildId, formatVersion: INDEX_FORMAT_VERSION },
-          chunks: keywordChunks
+          chunks: sealChunks(keywordChunks, indexKey)
         })
       );
 
@@ -851,6 +1614,7 @@ export class CodebaseIndexer {
         }
       }
 
+      const callGraphData = callGraph.toJSON();
       const relationships = {
         header: { buildId, formatVersion: INDEX_FORMAT_VERSION },
         generatedAt,
@@ -858,22 +1622,37 @@ export class CodebaseIndexer {
           imports: graphData.imports || {},
           ...(graphData.importDetails ? { importDetails: graphData.importDetails } : {}),
           importedBy,
-          exports: graphData.exports || {}
+          exports: graphData.exports || {},
+          ...(graphData.externalImports ? { externalImports: graphData.externalImports } : {}),
+          ...(Object.keys(workspacePackages).length > 0 ? { packages: workspacePackages } : {})
         },
         symbols: {
-          exportedBy
+          exportedBy,
+          definitions: symbolIndex.toJSON()
         },
+        callGraph: callGraphData,
+        // extends/implements and Go method sets, for find_implementations / get_type_hierarchy
+        types: typeHierarchy.toJSON(),
+        // test file links by naming, imports and calls, for get_tests_for
+        tests: buildTestLinks(
+          files.map((file) => path.relative(this.rootPath, file).replace(/\\/g, '/')),
+          graphData.imports || {},
+          callGraphData
+        ),
+        // Bazel/Buck targets and their deps, for get_build_target / get_build_dependents
+        ...(buildGraph ? { build: buildGraph } : {}),
         stats: graphData.stats || internalFileGraph.getStats()
       };
       await fs.writeFile(relationshipsPath, JSON.stringify(relationships, null, 2));
 
       // Write manifest (both full and incremental)
-      // For full rebuild, write to staging; for incremental, write to active
       const activeManifestPath = path.join(activeContextDir, MANIFEST_FILENAME);
       const manifest: FileManifest = {
         version: 1,
         generatedAt: new Date().toISOString(),
-        files: currentHashes ?? (await computeFileHashes(files, this.rootPath))
+        files:
+          currentHashes ??
+          (this.ref ? this.getRefBlobHashes() : await computeFileHashes(files, this.rootPath))
       };
       await writeManifest(activeManifestPath, manifest);
 
@@ -897,9 +1676,22 @@ export class CodebaseIndexer {
             buildId,
             generatedAt,
             toolVersion,
+            ...(embeddingFingerprint ? { embedding: embeddingFingerprint } : {}),
+            ...(head ? { head } : {}),
+            ...(indexKey
+              ? { encryption: { algorithm: ENCRYPTION_ALGORITHM, keyId: indexKey.id } }
+              : {}),
+            ...(this.ref && this.refCommit
+              ? { gitRef: { ref: this.ref, commit: this.refCommit } }
+              : {}),
             artifacts: {
               keywordIndex: { path: KEYWORD_INDEX_FILENAME },
-              vectorDb: { path: VECTOR_DB_DIRNAME, provider: 'lancedb' },
+              vectorDb: {
+                path: VECTOR_DB_DIRNAME,
+                provider:
+                  this.getStorageConfig(VECTOR_DB_DIRNAME).provider ??
+                  DEFAULT_STORAGE_CONFIG.provider
+              },
               intelligence: { path: INTELLIGENCE_FILENAME },
               manifest: { path: MANIFEST_FILENAME },
               indexingStats: { path: INDEXING_STATS_FILENAME },
@@ -911,10 +1703,56 @@ export class CodebaseIndexer {
         )
       );
 
-      // Atomic swap for full rebuilds: move staging into active location
-      if (isFullRebuild && stagingDir) {
-        console.error('Performing atomic swap of staging to active...');
-        await atomicSwapStagingToActive(contextDir, stagingDir, buildId);
+      // Refuse to swap in a generation whose artifacts don't agree with its meta
+      const stagedMeta = await readIndexMeta(this.rootPath, stagingDir);
+      await validateIndexArtifacts(this.rootPath, stagedMeta, stagingDir);
+
+      if (pendingGeneration) {
+        const rebuilt = pendingGeneration;
+        await rebuilt.commitGeneration?.();
+        pendingGeneration = null;
+        await rebuilt.close?.();
+      }
+
+      console.error('Performing atomic swap of staging to active...');
+      await atomicSwapStagingToActive(contextDir, stagingDir, buildId);
+      if (migration) await clearModelMigration(contextDir).catch(() => undefined);
+
+      // What this build changed, for changes_since; the index itself is already in place
+      try {
+        await recordIndexChanges(contextDir, {
+          buildId,
+          generatedAt,
+          files: manifest.files,
+          definitions: symbolIndex.toJSON(),
+          hashes: symbolIndex.contentHashes()
+        });
+      } catch (error) {
+        console.warn('Failed to update the change journal:', error);
+      }
+
+      // Old paths of deleted and moved files, so search can drop any hit still stored for them
+      try {
+        await recordTombstones(contextDir, {
+          buildId,
+          generatedAt,
+          deleted: diff?.deleted ?? [],
+          renamed: diff?.renamed ?? [],
+          present: Object.keys(manifest.files)
+        });
+      } catch (error) {
+        console.warn('Failed to update tombstones:', error);
+      }
+
+      // Built embedding collections follow the index; a failure only affects that collection
+      if (!this.config.skipEmbedding) {
+        await refreshEmbeddingCollections(this.rootPath, contextDir);
+      }
+
+      if (compliance.audit) {
+        await appendComplianceAudit(this.contextDir, complianceAudit).catch((error: unknown) => {
+          console.error('[compliance] Could not write the audit log:', error);
+        });
       }
 
       // Phase 5: Complete
@@ -927,12 +1765,19 @@ export class CodebaseIndexer {
         console.error(
           `Incremental indexing complete in ${stats.duration}ms ` +
             `(${diff.added.length} added, ${diff.changed.length} changed, ` +
-            `${diff.deleted.length} deleted, ${diff.unchanged.length} unchanged)`
+            `${diff.deleted.length} deleted, ${diff.renamed.length} renamed, ` +
+            `${diff.unchanged.length} unchanged)`
         );
       } else {
         console.error(`Indexing complete in ${stats.duration}ms`);
         console.error(`Indexed ${stats.indexedFiles} files, ${stats.totalChunks} chunks`);
       }
+      if (stats.truncatedFiles || stats.skippedGeneratedFiles) {
+        console.error(
+          `Generated or oversized files: ${stats.truncatedFiles ?? 0} truncated, ` +
+            `${stats.skippedGeneratedFiles ?? 0} generated skipped`
+        );
+      }
 
       return stats;
     } catch (error) {
@@ -943,6 +1788,11 @@ export class CodebaseIndexer {
         phase: this.progress.phase,
         timestamp: new Date()
       });
+      // The live remote collection never saw this build; drop what it wrote (best-effort)
+      if (pendingGeneration) {
+        await pendingGeneration.abortGeneration?.().catch(() => undefined);
+        await pendingGeneration.close?.().catch(() => undefined);
+      }
       // Clean up staging directory on failure (best-effort)
       if (stagingDir) {
         console.error('Cleaning up staging directory after failure...');
@@ -952,32 +1802,98 @@ export class CodebaseIndexer {
     }
   }
 
+  /**
+   * Repoint the stored chunks of moved files at their new paths. A provider that can't
+   * update paths drops them instead; those files are found by keyword only until the next
+   * full rebuild.
+   */
+  private async moveStoredChunks(
+    storageProvider: VectorStorageProvider,
+    renamed: FileRename[],
+    files: string[]
+  ): Promise<void> {
+    const byRelative = new Map(
+      files.map((file) => [path.relative(this.rootPath, file).replace(/\\/g, '/'), file])
+    );
+    // Stored paths may use either separator, as for deletes
+    const storedPaths = (rel: string) => [
+      ...new Set([
+        path.join(this.rootPath, rel).replace(/\\/g, '/'),
+        path.resolve(this.rootPath, rel)
+      ])
+    ];
+    if (!storageProvider.renameFilePaths) {
+      console.warn(
+        `${storageProvider.name} can't update stored paths; dropping vectors of ` +
+          `${renamed.length} moved file(s) until the next full rebuild`
+      );
+      await storageProvider.deleteByFilePaths(renamed.flatMap(({ from }) => storedPaths(from)));
+      return;
+    }
+    await storageProvider.renameFilePaths(
+      renamed.flatMap(({ from, to }) =>
+        storedPaths(from).map((stored) => ({
+          from: stored,
+          to: byRelative.get(to) ?? path.resolve(this.rootPath, to),
+          relativePath: to
+        }))
+      )
+    );
+  }
+
+  private getStorageConfig(storagePath: string): Partial<StorageConfig> & { path: string } {
+    const { provider, url, apiKey, collection, quantization } = this.config.storage ?? {};
+    const quantizationMode = resolveQuantizationMode(quantization);
+    return {
+      path: storagePath,
+      // Ref indexes get their own remote collection, derived from their context dir
+      rootPath: this.ref || this.standaloneIndex ? this.contextDir : this.rootPath,
+      ...(isStorageProviderName(provider) ? { provider } : {}),
+      ...(url ? { url } : {}),
+      ...(apiKey ? { apiKey } : {}),
+      ...(collection ? { collection } : {}),
+      ...(quantizationMode !== 'none' ? { quantization: quantizationMode } : {})
+    };
+  }
+
   private async scanFiles(): Promise<string[]> {
     const files: string[] = [];
     const seen = new Set<string>();
 
-    // Read .gitignore if respecting it
-    let ig: ReturnType<typeof ignore.default> | null = null;
-    if (this.config.respectGitignore) {
-      try {
-        const gitignorePath = path.join(this.rootPath, '.gitignore');
-        const gitignoreContent = await fs.readFile(gitignorePath, 'utf-8');
-        ig = ignore.default().add(gitignoreContent);
-      } catch (_error) {
-        // No .gitignore or couldn't read it
-      }
-    }
+    // Nested .gitignore files (when respected) and .mcpignore, loaded per directory on demand
+    const rules = new IgnoreRules(
+      withRootRules(
+        createFsIgnoreLoader(this.rootPath, {
+          respectGitignore: this.config.respectGitignore !== false
+        }),
+        this.configIgnoreRules()
+      ),
+      this.getSkippedDirs()
+    );
+    const excludePatterns = this.config.exclude || [];
+    const isExcluded = (relativePath: string) =>
+      excludePatterns.some((pattern) => matchesGlob(relativePath, pattern));
 
-    // Scan with glob
+    // Scan with glob, pruning ignored directories instead of walking them
     const includePatterns = this.config.include || ['**/*'];
-    const excludePatterns = this.config.exclude || [];
+    // Symlinks are followed only while they stay inside the project
+    const realRoot = await fs.realpath(this.rootPath).catch(() => path.resolve(this.rootPath));
 
     for (const pattern of includePatterns) {
       const matches = await glob(pattern, {
         cwd: this.rootPath,
         absolute: true,
-        ignore: excludePatterns,
-        nodir: true
+        nodir: true,
+        ignore: {
+          ignored: (p) => {
+            const relativePath = p.relativePosix();
+            return isExcluded(relativePath) || rules.isIgnored(relativePath);
+          },
+          childrenIgnored: (p) => {
+            const relativePath = p.relativePosix();
+            return isExcluded(`${relativePath}/`) || rules.isIgnored(relativePath, true);
+          }
+        }
       });
 
       for (const file of matches) {
@@ -987,22 +1903,23 @@ export class CodebaseIndexer {
         }
         seen.add(normalizedFile);
 
-        const relativePath = path.relative(this.rootPath, file);
-
-        // Check gitignore
-        if (ig && ig.ignores(relativePath)) {
-          continue;
-        }
-
         // Check if it's a code file
         if (!isCodeFile(file) || isBinaryFile(file)) {
           continue;
         }
+        const realFile = await fs.realpath(file).catch(() => null);
+        const fromRoot = realFile === null ? '..' : path.relative(realRoot, realFile);
+        if (fromRoot.startsWith('..') || path.isAbsolute(fromRoot)) {
+          continue;
+        }
+        if (this.isSkippedDocumentation(file)) {
+          continue;
+        }
 
         // Check file size
         try {
           const stats = await fs.stat(file);
-          if (stats.size > (this.config.parsing?.maxFileSize || 1048576)) {
+          if (stats.size > this.maxFileSizeFor(file)) {
             console.warn(`Skipping large file: ${file} (${stats.size} bytes)`);
             continue;
           }
@@ -1010,6 +1927,11 @@ export class CodebaseIndexer {
           continue;
         }
 
+        // Content sniffing: binary blobs with a code extension, minified/bundled output
+        if (await this.isGeneratedOrBinary(file)) {
+          continue;
+        }
+
         files.push(file);
       }
     }
@@ -1017,15 +1939,240 @@ export class CodebaseIndexer {
     return files;
   }
 
+  /** Explicit `ignore` config, else the `ignore` section of codebase-context.yaml */
+  private configIgnoreRules(): string[] | undefined {
+    return this.config.ignore ?? loadProjectConfigSettings(this.rootPath)?.ignore;
+  }
+
+  /**
+   * List files of a git ref through the same filters as the working-tree scan
+   * (include/exclude globs, the ref's own ignore files, code file and size checks).
+   */
+  private async scanGitRef(ref: string): Promise<string[]> {
+    this.refCommit = await resolveGitCommit(this.rootPath, ref);
+    const entries = await listGitTreeFiles(this.rootPath, this.refCommit);
+    console.error(`Indexing git ref ${ref} (${this.refCommit.slice(0, 12)})`);
+
+    const respectGitignore = this.config.respectGitignore !== false;
+    const ignoreFiles = new Map<string, string>();
+    for (const entry of entries) {
+      const name = path.posix.basename(entry.path);
+      if (name !== MCPIGNORE_FILENAME && !(respectGitignore && name === GITIGNORE_FILENAME)) {
+        continue;
+      }
+      try {
+        ignoreFiles.set(entry.path, await readGitBlob(this.rootPath, entry.blob));
+      } catch (_error) {
+        // Unreadable ignore blob — index without it
+      }
+    }
+    const rules = new IgnoreRules(
+      withRootRules(
+        createMapIgnoreLoader(ignoreFiles, { respectGitignore }),
+        this.configIgnoreRules()
+      ),
+      this.getSkippedDirs()
+    );
+
+    const includePatterns = this.config.include || ['**/*'];
+    const excludePatterns = this.config.exclude || [];
+    const files: string[] = [];
+    this.refBlobs.clear();
+
+    for (const entry of entries) {
+      if (!includePatterns.some((pattern) => matchesGlob(entry.path, pattern))) continue;
+      if (excludePatterns.some((pattern) => matchesGlob(entry.path, pattern))) continue;
+      if (rules.isIgnored(entry.path)) continue;
+      if (!isCodeFile(entry.path) || isBinaryFile(entry.path)) continue;
+      if (this.isSkippedDocumentation(entry.path)) continue;
+      if (looksMinified(entry.path, '')) continue;
+      if (entry.size > this.maxFileSizeFor(entry.path)) {
+        console.warn(`Skipping large file: ${entry.path} (${entry.size} bytes)`);
+        continue;
+      }
+
+      const file = path.join(this.rootPath, entry.path);
+      this.refBlobs.set(file, entry.blob);
+      files.push(file);
+    }
+
+    return files;
+  }
+
+  /** `parsing.maxFileSizeByExtension` for the file's extension, else `parsing.maxFileSize` */
+  private maxFileSizeFor(file: string): number {
+    const parsing = this.config.parsing;
+    const byExtension = parsing?.maxFileSizeByExtension?.[path.extname(file).toLowerCase()];
+    return byExtension || parsing?.maxFileSize || DEFAULT_MAX_FILE_SIZE;
+  }
+
+  /**
+   * Keep the first `maxChunksPerFile` chunks of a file (the first `generatedSampleChunks` of a
+   * sampled generated file) and record on them how many were left out.
+   */
+  private async loadChunkTokenBudget(): Promise<ChunkTokenBudget | null> {
+    const provider = await getEmbeddingProvider(this.config.embedding);
+    const { modelPath, allowDownload } = { ...DEFAULT_EMBEDDING_CONFIG, ...this.config.embedding };
+    return loadChunkTokenBudget(provider.name, provider.modelName, { modelPath, allowDownload });
+  }
+
+  /**
+   * Split chunks whose embedding input is over the model's input limit, or over the language's
+   * `maxTokens`, counted with the model's tokenizer. Analyzers size chunks by estimate only.
+   */
+  private fitChunksToModel(chunks: CodeChunk[], language: string): CodeChunk[] {
+    const budget = this.chunkTokens;
+    if (!budget) return chunks;
+    const { maxTokens, overlapLines } = resolveLanguageChunking(this.chunking, language);
+    const countTokens = (text: string) => budget.counter.count(text);
+    return chunks.flatMap((chunk) => {
+      // The path/type header of the embedding input counts against the limit too
+      const header = countTokens(buildEmbeddingInput({ ...chunk, content: '' }));
+      const limit = Math.min(budget.maxInputTokens - header, maxTokens ?? Infinity);
+      return splitOversizedChunks([chunk], Infinity, {
+        maxTokens: Math.max(1, limit),
+        overlapLines,
+        countTokens
+      });
+    });
+  }
+
+  private capFileChunks(chunks: CodeChunk[], generated: boolean): CodeChunk[] {
+    const parsing = this.config.parsing;
+    const limit = generated
+      ? (parsing?.generatedSampleChunks ?? DEFAULT_GENERATED_SAMPLE_CHUNKS)
+      : (parsing?.maxChunksPerFile ?? DEFAULT_MAX_CHUNKS_PER_FILE);
+    const kept = chunks.length > limit ? chunks.slice(0, Math.max(1, limit)) : chunks;
+    const droppedChunks = chunks.length - kept.length;
+    if (!generated && droppedChunks === 0) return kept;
+    for (const chunk of kept) {
+      chunk.metadata = {
+        ...chunk.metadata,
+        ...(generated ? { generated: true } : {}),
+        ...(droppedChunks > 0 ? { droppedChunks } : {})
+      };
+    }
+    return kept;
+  }
+
+  /** READMEs and changelogs turned off by `documentation.includeReadmes/includeChangelogs` */
+  private isSkippedDocumentation(file: string): boolean {
+    const name = path.basename(file).toLowerCase();
+    const docs = this.config.documentation;
+    if (/^readme(\.|$)/.test(name)) return docs?.includeReadmes === false;
+    if (/^(changelog|changes|history)\.(md|mdx|markdown|rst|txt)$/.test(name)) {
+      return docs?.includeChangelogs === false;
+    }
+    return false;
+  }
+
+  /** Directory names pruned at any depth; `parseNodeModules` opts node_modules back in */
+  private getSkippedDirs(): ReadonlySet<string> {
+    if (!this.config.parsing?.parseNodeModules) return ALWAYS_SKIPPED_DIRS;
+    const dirs = new Set(ALWAYS_SKIPPED_DIRS);
+    dirs.delete('node_modules');
+    return dirs;
+  }
+
+  private async isGeneratedOrBinary(file: string): Promise<boolean> {
+    let handle: Awaited<ReturnType<typeof fs.open>> | null = null;
+    try {
+      handle = await fs.open(file, 'r');
+      const buffer = Buffer.alloc(CONTENT_SNIFF_BYTES);
+      const { bytesRead } = await handle.read(buffer, 0, CONTENT_SNIFF_BYTES, 0);
+      const sample = buffer.subarray(0, bytesRead);
+      return looksBinary(sample) || looksMinified(file, decodeText(sample).text);
+    } catch (_error) {
+      return false;
+    } finally {
+      await handle?.close();
+    }
+  }
+
+  private getRefBlobHashes(): Record<string, string> {
+    const hashes: Record<string, string> = {};
+    for (const [file, blob] of this.refBlobs) {
+      hashes[path.relative(this.rootPath, file).replace(/\\/g, '/')] = blob;
+    }
+    return hashes;
+  }
+
+  /** Read and analyze one file, and extract its call sites: the concurrent part of analysis */
+  private async analyzeSource(file: string): Promise<AnalyzedSource> {
+    // Normalize line endings to \n for consistent cross-platform output
+    const { text: rawContent, encoding } = await this.readSourceText(file);
+    const content = rawContent.replace(/\r\n/g, '\n');
+    const generated =
+      this.config.parsing?.generatedFiles !== 'index' &&
+      detectGeneratedFile(file, content.slice(0, CONTENT_SNIFF_BYTES)) !== null;
+    const fileLanguage = detectLanguage(file, content);
+    const result =
+      generated && this.config.parsing?.generatedFiles === 'skip'
+        ? null
+        : await analyzerRegistry.analyzeFile(file, content, {
+            chunking: resolveLanguageChunking(this.chunking, fileLanguage)
+          });
+    if (!result) {
+      return {
+        rawContent,
+        content,
+        result,
+        fileLanguage: 'unknown',
+        callExtraction: null,
+        generated,
+        encoding
+      };
+    }
+    // Tree-sitter languages, and the script blocks of Vue/Svelte/Astro components
+    const callExtraction = isSfcLanguage(fileLanguage)
+      ? await extractSfcCalls(content, fileLanguage, file)
+      : await extractTreeSitterCalls(content, fileLanguage);
+    return { rawContent, content, result, fileLanguage, callExtraction, generated, encoding };
+  }
+
+  private async readSourceText(file: string): Promise<DecodedText> {
+    const blob = this.refBlobs.get(file);
+    if (blob) {
+      return decodeText(await readGitBlobBytes(this.rootPath, blob));
+    }
+    return readText(file);
+  }
+
+  private async readSourceFile(file: string): Promise<string> {
+    return (await this.readSourceText(file)).text;
+  }
+
+  private throwIfCancelled(): void {
+    if (this.signal?.aborted) {
+      throw new IndexingCancelledError();
+    }
+  }
+
   private updateProgress(phase: IndexingPhase, percentage: number): void {
     this.progress.phase = phase;
     this.progress.percentage = percentage;
+    if (phase !== this.stage?.phase) {
+      this.endStage();
+      if (phase !== 'complete' && phase !== 'error') {
+        const span = startSpan(`codebase_context.index.${phase}`);
+        this.stage = { phase, span, elapsed: startTimer() };
+      }
+    }
 
     if (this.onProgressCallback) {
       this.onProgressCallback(this.progress);
     }
   }
 
+  private endStage(error?: unknown): void {
+    if (!this.stage) return;
+    const { phase, span, elapsed } = this.stage;
+    this.stage = undefined;
+    metrics.indexStageDuration.observe({ stage: phase }, elapsed());
+    if (error !== undefined) span.recordError(error);
+    span.end();
+  }
+
   async detectMetadata(): Promise<CodebaseMetadata> {
     // Get all registered analyzers (sorted by priority, highest first)
     const analyzers = analyzerRegistry.getAll();
@@ -1194,4 +2341,12 @@ export class CodebaseIndexer {
   getProgress(): IndexingProgress {
     return { ...this.progress };
   }
+
+  /**
+   * Chunks parsed (and redacted) so far while a build runs; empty before parsing starts and
+   * once the build is done. Keyword-searchable only: nothing in them is embedded yet.
+   */
+  getPartialChunks(): CodeChunk[] {
+    return [...this.partialChunks];
+  }
 }
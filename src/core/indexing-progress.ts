@@ -0,0 +1,101 @@
+/**
+ * Turns indexer progress callbacks into client-facing progress updates
+ * (e.g. MCP `notifications/progress`).
+ *
+ * Indexer phases report their own 0-100 percentages; this maps them onto a single
+ * monotonic scale, adds files/chunks counts and an ETA, and throttles the stream.
+ */
+
+import type { IndexingPhase, IndexingProgress } from '../types/index.js';
+
+export interface ProgressUpdate {
+  progress: number;
+  total: number;
+  message: string;
+}
+
+/** Overall progress window per phase: [start, end] on a 0-100 scale */
+const PHASE_WINDOWS: Partial<Record<IndexingPhase, [number, number]>> = {
+  initializing: [0, 0],
+  scanning: [0, 5],
+  analyzing: [5, 50],
+  embedding: [50, 90],
+  storing: [90, 99],
+  complete: [100, 100]
+};
+
+const DEFAULT_MIN_INTERVAL_MS = 250;
+
+export function overallProgress(progress: IndexingProgress): number {
+  const window = PHASE_WINDOWS[progress.phase];
+  if (!window) return 0;
+  const [start, end] = window;
+
+  let fraction: number;
+  if (progress.phase === 'analyzing' && progress.totalFiles > 0) {
+    fraction = progress.filesProcessed / progress.totalFiles;
+  } else if (progress.phase === 'embedding' && progress.chunksToEmbed) {
+    fraction = (progress.chunksEmbedded ?? 0) / progress.chunksToEmbed;
+  } else {
+    fraction = progress.percentage / 100;
+  }
+
+  return Math.round(start + (end - start) * Math.min(Math.max(fraction, 0), 1));
+}
+
+function formatDuration(ms: number): string {
+  const seconds = Math.max(1, Math.round(ms / 1000));
+  if (seconds < 60) return `${seconds}s`;
+  const minutes = Math.floor(seconds / 60);
+  return `${minutes}m${String(seconds % 60).padStart(2, '0')}s`;
+}
+
+export function formatProgressMessage(
+  progress: IndexingProgress,
+  overall: number,
+  now: number = Date.now()
+): string {
+  const parts: string[] = [progress.phase];
+  if (progress.totalFiles > 0) {
+    parts.push(`${progress.filesProcessed}/${progress.totalFiles} files`);
+  }
+  if (progress.chunksToEmbed) {
+    parts.push(`${progress.chunksEmbedded ?? 0}/${progress.chunksToEmbed} chunks embedded`);
+  } else if (progress.chunksCreated > 0) {
+    parts.push(`${progress.chunksCreated} chunks`);
+  }
+
+  const elapsed = now - progress.startedAt.getTime();
+  if (overall > 0 && overall < 100 && elapsed > 0) {
+    parts.push(`ETA ${formatDuration((elapsed * (100 - overall)) / overall)}`);
+  }
+
+  return parts.join(', ');
+}
+
+/**
+ * Build an `onProgress` callback that emits strictly increasing, throttled updates.
+ * Phase changes and completion bypass the throttle.
+ */
+export function createProgressReporter(
+  send: (update: ProgressUpdate) => void,
+  minIntervalMs: number = DEFAULT_MIN_INTERVAL_MS
+): (progress: IndexingProgress) => void {
+  let lastSentAt = 0;
+  let lastPhase: IndexingPhase | null = null;
+  let highest = -1;
+
+  return (progress) => {
+    const now = Date.now();
+    const overall = overallProgress(progress);
+    const phaseChanged = progress.phase !== lastPhase;
+    // MCP requires strictly increasing progress values
+    if (overall <= highest) return;
+    if (!phaseChanged && overall < 100 && now - lastSentAt < minIntervalMs) return;
+
+    lastSentAt = now;
+    lastPhase = progress.phase;
+    highest = overall;
+    send({ progress: overall, total: 100, message: formatProgressMessage(progress, overall, now) });
+  };
+}
@@ -0,0 +1,350 @@
+/**
+ * Language-server bridge: asks a locally installed language server (tsserver through
+ * typescript-language-server, gopls, pyright) for the hover text of a definition, so
+ * `get_definition` and `get_symbol_docs` can show compiler-resolved types and signatures
+ * next to what tree-sitter extracted.
+ *
+ * Off by default. `"lsp": true` in `.codebase-context/config.json` (or CODEBASE_CONTEXT_LSP=on)
+ * turns it on, and `"lsp": { "timeoutMs": 3000 }` sets how long one request may take. Server
+ * commands are only overridden from the user's environment (CODEBASE_CONTEXT_LSP_SERVERS), never
+ * from the repo: a checked-out repo would otherwise choose what runs on the machine. A server
+ * that isn't on PATH, fails to start or doesn't answer in time is skipped and the static
+ * extraction stands on its own. Servers are started on first use, shared by every request for
+ * the project and stopped after 10 idle minutes or when the process exits.
+ */
+
+import { constants as fsConstants, promises as fs } from 'fs';
+import path from 'path';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  PROJECT_CONFIG_FILENAME
+} from '../constants/codebase-context.js';
+import { LspClient } from './lsp-client.js';
+import { redactSecrets, resolveRedactionOptions } from '../utils/secret-redaction.js';
+import { loadComplianceFilter } from './compliance-filters.js';
+import { readTextFile } from '../utils/text-encoding.js';
+
+type ServerGroup = 'typescript' | 'go' | 'python';
+
+const DEFAULT_SERVERS: Record<ServerGroup, string[]> = {
+  typescript: ['typescript-language-server', '--stdio'],
+  go: ['gopls'],
+  python: ['pyright-langserver', '--stdio']
+};
+
+/** Language ids (as detectLanguage returns them) each server handles */
+const GROUP_LANGUAGES: Record<string, ServerGroup> = {
+  typescript: 'typescript',
+  typescriptreact: 'typescript',
+  javascript: 'typescript',
+  javascriptreact: 'typescript',
+  go: 'go',
+  python: 'python'
+};
+
+const DEFAULT_TIMEOUT_MS = 3000;
+const MAX_TIMEOUT_MS = 30_000;
+/** Initializing may take this much longer than one request (the first lookup doesn't wait) */
+const STARTUP_GRACE_MS = 10_000;
+const IDLE_SHUTDOWN_MS = 10 * 60_000;
+/** Lines past a definition's start searched for its name */
+const NAME_SEARCH_LINES = 10;
+const MAX_SIGNATURE_LENGTH = 300;
+const MAX_DOCUMENTATION_LENGTH = 1200;
+
+export interface LspConfig {
+  enabled: boolean;
+  servers: Record<ServerGroup, string[]>;
+  timeoutMs: number;
+}
+
+export interface HoverTarget {
+  /** Repo-relative path */
+  file: string;
+  name: string;
+  language: string;
+  startLine: number;
+  endLine: number;
+}
+
+export interface LspHover {
+  /** Command of the server that answered */
+  server: string;
+  /** Declaration with its types, from the hover's code block */
+  signature?: string;
+  /** Hover prose: the doc comment as the server renders it */
+  documentation?: string;
+  /** Whole hover text */
+  text: string;
+}
+
+export type HoverLookup = (target: HoverTarget) => Promise<LspHover | null>;
+
+interface RunningServer {
+  /** Resolves once initialized; null when the server isn't installed or failed to start */
+  client: Promise<LspClient | null>;
+  /** Set once started, for the synchronous exit hook */
+  started?: LspClient;
+  idleTimer?: NodeJS.Timeout;
+}
+
+const servers = new Map<string, RunningServer>();
+let exitHookInstalled = false;
+/** Roots whose config was already warned about for naming server commands */
+const warnedRoots = new Set<string>();
+
+function parseCommand(value: unknown): string[] | undefined {
+  if (typeof value === 'string' && value.trim()) return value.trim().split(/\s+/);
+  if (Array.isArray(value) && value.length > 0 && value.every((part) => typeof part === 'string')) {
+    return value as string[];
+  }
+  return undefined;
+}
+
+/**
+ * CODEBASE_CONTEXT_LSP_SERVERS: `python=pylsp --check-parent-process;go=/opt/bin/gopls`, or a
+ * JSON object of commands (strings or argument arrays) for paths with spaces
+ */
+export function parseServerOverrides(value: string | undefined): Partial<LspConfig['servers']> {
+  const raw = value?.trim();
+  if (!raw) return {};
+  let entries: Array<[string, unknown]>;
+  if (raw.startsWith('{')) {
+    try {
+      entries = Object.entries(JSON.parse(raw) as Record<string, unknown>);
+    } catch {
+      console.warn('[lsp] CODEBASE_CONTEXT_LSP_SERVERS is not valid JSON; using the defaults');
+      return {};
+    }
+  } else {
+    entries = raw.split(';').map((entry) => {
+      const separator = entry.indexOf('=');
+      return separator < 0
+        ? [entry.trim(), undefined]
+        : [entry.slice(0, separator).trim(), entry.slice(separator + 1)];
+    });
+  }
+  const overrides: Partial<LspConfig['servers']> = {};
+  for (const [group, command] of entries) {
+    const parsed = parseCommand(command);
+    if (group in DEFAULT_SERVERS && parsed) overrides[group as ServerGroup] = parsed;
+  }
+  return overrides;
+}
+
+/**
+ * The `lsp` key of `.codebase-context/config.json`, with CODEBASE_CONTEXT_LSP over it. Server
+ * commands come from CODEBASE_CONTEXT_LSP_SERVERS only; `servers` in the repo's config is ignored.
+ */
+export async function loadLspConfig(
+  rootPath: string,
+  env: NodeJS.ProcessEnv = process.env
+): Promise<LspConfig> {
+  let raw: unknown;
+  try {
+    const configPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME);
+    raw = (JSON.parse(await fs.readFile(configPath, 'utf-8')) as { lsp?: unknown }).lsp;
+  } catch {
+    raw = undefined;
+  }
+  const section = raw && typeof raw === 'object' ? (raw as Record<string, unknown>) : {};
+  const config: LspConfig = {
+    enabled: raw === true || (typeof raw === 'object' && raw !== null && section.enabled !== false),
+    servers: { ...DEFAULT_SERVERS, ...parseServerOverrides(env.CODEBASE_CONTEXT_LSP_SERVERS) },
+    timeoutMs: DEFAULT_TIMEOUT_MS
+  };
+  const root = path.resolve(rootPath);
+  if (section.servers !== undefined && !warnedRoots.has(root)) {
+    warnedRoots.add(root);
+    console.warn(
+      '[lsp] Ignoring "lsp.servers" in the project config; ' +
+        'set CODEBASE_CONTEXT_LSP_SERVERS to change server commands'
+    );
+  }
+  if (typeof section.timeoutMs === 'number' && section.timeoutMs > 0) {
+    config.timeoutMs = Math.min(section.timeoutMs, MAX_TIMEOUT_MS);
+  }
+
+  const flag = env.CODEBASE_CONTEXT_LSP?.trim().toLowerCase();
+  if (flag && ['1', 'true', 'on', 'yes'].includes(flag)) config.enabled = true;
+  if (flag && ['0', 'false', 'off', 'no'].includes(flag)) config.enabled = false;
+  return config;
+}
+
+/** Absolute path of `command` on PATH (or as given, when it is a path), or null */
+export async function findExecutable(
+  command: string,
+  env: NodeJS.ProcessEnv = process.env
+): Promise<string | null> {
+  const isWindows = process.platform === 'win32';
+  const extensions = isWindows
+    ? ['', ...(env.PATHEXT ?? '.EXE;.CMD;.BAT').split(';').filter(Boolean)]
+    : [''];
+  const directories =
+    command.includes('/') || command.includes('\\')
+      ? ['']
+      : (env.PATH ?? env.Path ?? '').split(path.delimiter).filter(Boolean);
+  for (const directory of directories) {
+    for (const extension of extensions) {
+      const candidate = path.resolve(directory, command + extension);
+      try {
+        const stat = await fs.stat(candidate);
+        if (!stat.isFile()) continue;
+        if (!isWindows) await fs.access(candidate, fsConstants.X_OK);
+        return candidate;
+      } catch {
+        // Not here
+      }
+    }
+  }
+  return null;
+}
+
+async function startServer(
+  rootPath: string,
+  command: string[],
+  timeoutMs: number
+): Promise<LspClient | null> {
+  const executable = await findExecutable(command[0]);
+  if (!executable) return null;
+  const startupTimeout = timeoutMs + STARTUP_GRACE_MS;
+  const client = new LspClient(executable, command.slice(1), rootPath, startupTimeout);
+  try {
+    await client.initialize();
+    client.timeoutMs = timeoutMs;
+    return client;
+  } catch (error) {
+    client.kill();
+    const reason = error instanceof Error ? error.message : String(error);
+    console.warn(`[lsp] ${command[0]} did not start: ${reason}`);
+    return null;
+  }
+}
+
+function stopServer(key: string): void {
+  const running = servers.get(key);
+  if (!running) return;
+  servers.delete(key);
+  if (running.idleTimer) clearTimeout(running.idleTimer);
+  void running.client.then((client) => client?.shutdown());
+}
+
+/** The project's running server for `group`, started if needed; null when there is none */
+async function serverFor(
+  rootPath: string,
+  group: ServerGroup,
+  config: LspConfig
+): Promise<LspClient | null> {
+  const key = `${path.resolve(rootPath)}\0${group}`;
+  let running = servers.get(key);
+  if (running?.started && !running.started.alive) {
+    // Crashed since: one restart per lookup that finds it gone
+    stopServer(key);
+    running = undefined;
+  }
+  if (!running) {
+    const entry: RunningServer = {
+      client: startServer(rootPath, config.servers[group], config.timeoutMs)
+    };
+    void entry.client.then((client) => (entry.started = client ?? undefined));
+    servers.set(key, (running = entry));
+    if (!exitHookInstalled) {
+      exitHookInstalled = true;
+      process.once('exit', () => {
+        for (const server of servers.values()) server.started?.kill();
+      });
+    }
+  }
+  if (running.idleTimer) clearTimeout(running.idleTimer);
+  running.idleTimer = setTimeout(() => stopServer(key), IDLE_SHUTDOWN_MS);
+  running.idleTimer.unref();
+  return running.client;
+}
+
+/** Stop every language server this process started */
+export async function stopLanguageServers(): Promise<void> {
+  const running = [...servers.values()];
+  servers.clear();
+  await Promise.all(
+    running.map(async (entry) => {
+      if (entry.idleTimer) clearTimeout(entry.idleTimer);
+      await (await entry.client)?.shutdown();
+    })
+  );
+}
+
+/** Split hover markdown into the first code block (the signature) and the prose around it */
+export function parseHover(text: string): Pick<LspHover, 'signature' | 'documentation'> {
+  const fence = /```[\w+-]*\n([\s\S]*?)\n?```/.exec(text);
+  const signature = fence?.[1].trim().replace(/\s*\n\s*/g, ' ');
+  const rest = fence
+    ? text.slice(0, fence.index) + text.slice(fence.index + fence[0].length)
+    : text;
+  const prose = rest
+    .replace(/^\s*(---|\*\*\*|___)\s*$/gm, '')
+    .replace(/\n{3,}/g, '\n\n')
+    .trim();
+  const cut = (value: string, max: number) =>
+    value.length > max ? `${value.slice(0, max - 1)}…` : value;
+  return {
+    ...(signature ? { signature: cut(signature, MAX_SIGNATURE_LENGTH) } : {}),
+    ...(prose ? { documentation: cut(prose, MAX_DOCUMENTATION_LENGTH) } : {})
+  };
+}
+
+const escapeRegExp = (value: string) => value.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+
+/** Where the definition's name is written, past any doc comment and decorators */
+function namePosition(
+  lines: string[],
+  target: HoverTarget
+): { line: number; character: number } | null {
+  const pattern = new RegExp(`(?<![\\w$])${escapeRegExp(target.name)}(?![\\w$])`);
+  const last = Math.min(target.endLine, target.startLine + NAME_SEARCH_LINES, lines.length);
+  for (let line = target.startLine; line <= last; line++) {
+    const text = lines[line - 1] ?? '';
+    if (/^\s*(\/\/|\/\*|\*|#|@)/.test(text)) continue;
+    const match = pattern.exec(text);
+    if (match) return { line: line - 1, character: match.index };
+  }
+  return null;
+}
+
+/**
+ * Hover lookups against the project's language servers, or undefined when the bridge is off.
+ * Each lookup resolves to null when no server covers the language or none answers.
+ */
+export async function createLspHover(rootPath: string): Promise<HoverLookup | undefined> {
+  const config = await loadLspConfig(rootPath);
+  if (!config.enabled) return undefined;
+  const root = path.resolve(rootPath);
+  const redaction = resolveRedactionOptions();
+  const compliance = await loadComplianceFilter(rootPath);
+
+  return async (target) => {
+    const group = GROUP_LANGUAGES[target.language];
+    if (!group) return null;
+    const absolute = path.resolve(root, target.file);
+    const relative = path.relative(root, absolute);
+    if (!relative || relative.startsWith('..') || path.isAbsolute(relative)) return null;
+    try {
+      // A server still starting answers a later lookup; this one goes without
+      const client = await Promise.race([
+        serverFor(root, group, config),
+        new Promise<null>((resolve) => setTimeout(() => resolve(null), config.timeoutMs).unref())
+      ]);
+      if (!client) return null;
+      const text = await readTextFile(absolute);
+      const position = namePosition(text.replace(/\r\n/g, '\n').split('\n'), target);
+      if (!position) return null;
+      const uri = client.syncDocument(absolute, target.language, text);
+      const hover = await client.hover(uri, position);
+      if (!hover) return null;
+      const redacted = compliance.mask(redactSecrets(hover, redaction).text).text;
+      return { server: path.basename(client.command), ...parseHover(redacted), text: redacted };
+    } catch {
+      // Unanswered or crashed: the static result stands
+      return null;
+    }
+  };
+}
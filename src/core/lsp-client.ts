@@ -0,0 +1,217 @@
+/**
+ * Minimal Language Server Protocol client over stdio: JSON-RPC with `Content-Length` framing,
+ * enough to initialize a server, open documents and ask for hover text. Requests the server
+ * sends back (configuration, capability registration, progress) get empty answers.
+ */
+
+import { spawn, type ChildProcess } from 'child_process';
+import { pathToFileURL } from 'url';
+
+interface JsonRpcMessage {
+  jsonrpc: '2.0';
+  id?: number | string;
+  method?: string;
+  params?: unknown;
+  result?: unknown;
+  error?: { code: number; message: string };
+}
+
+interface Pending {
+  resolve: (value: unknown) => void;
+  reject: (error: Error) => void;
+  timer: NodeJS.Timeout;
+}
+
+export interface LspPosition {
+  /** 0-based */
+  line: number;
+  /** 0-based, in UTF-16 code units (JavaScript string indexes) */
+  character: number;
+}
+
+export class LspClient {
+  private child: ChildProcess;
+  private buffer = Buffer.alloc(0);
+  private nextId = 1;
+  private pending = new Map<number, Pending>();
+  private opened = new Map<string, number>();
+  private exited = false;
+
+  constructor(
+    readonly command: string,
+    args: string[],
+    private rootPath: string,
+    /** Per request; raised while the server starts */
+    public timeoutMs: number
+  ) {
+    this.child = spawn(command, args, {
+      cwd: rootPath,
+      stdio: ['pipe', 'pipe', 'ignore'],
+      windowsHide: true,
+      // npm installs servers on Windows as .cmd shims, which only run through a shell
+      shell: /\.(cmd|bat)$/i.test(command)
+    });
+    this.child.stdout?.on('data', (chunk: Buffer) => this.receive(chunk));
+    this.child.on('error', () => this.fail('failed to start'));
+    this.child.on('exit', () => this.fail('exited'));
+    this.child.stdin?.on('error', () => this.fail('closed its input'));
+  }
+
+  get alive(): boolean {
+    return !this.exited;
+  }
+
+  async initialize(): Promise<void> {
+    const rootUri = pathToFileURL(this.rootPath).href;
+    await this.request('initialize', {
+      processId: process.pid,
+      rootUri,
+      workspaceFolders: [{ uri: rootUri, name: 'root' }],
+      capabilities: {
+        textDocument: {
+          hover: { contentFormat: ['markdown', 'plaintext'] },
+          synchronization: { didSave: false }
+        },
+        workspace: { configuration: true, workspaceFolders: true }
+      }
+    });
+    this.notify('initialized', {});
+  }
+
+  /** Open `absolutePath` with `text`, or send the new text when it changed since */
+  syncDocument(absolutePath: string, languageId: string, text: string): string {
+    const uri = pathToFileURL(absolutePath).href;
+    const version = this.opened.get(uri);
+    if (version === undefined) {
+      this.notify('textDocument/didOpen', {
+        textDocument: { uri, languageId, version: 1, text }
+      });
+      this.opened.set(uri, 1);
+    } else {
+      this.notify('textDocument/didChange', {
+        textDocument: { uri, version: version + 1 },
+        contentChanges: [{ text }]
+      });
+      this.opened.set(uri, version + 1);
+    }
+    return uri;
+  }
+
+  /** Hover contents as plain text or markdown; null when the server has none */
+  async hover(uri: string, position: LspPosition): Promise<string | null> {
+    const result = (await this.request('textDocument/hover', {
+      textDocument: { uri },
+      position
+    })) as { contents?: unknown } | null;
+    return hoverText(result?.contents);
+  }
+
+  async shutdown(): Promise<void> {
+    if (this.exited) return;
+    try {
+      await this.request('shutdown', null);
+      this.notify('exit', null);
+    } catch {
+      // Unresponsive: killed below
+    }
+    this.kill();
+  }
+
+  kill(): void {
+    if (!this.exited) this.child.kill();
+    this.fail('was stopped');
+  }
+
+  private request(method: string, params: unknown): Promise<unknown> {
+    if (this.exited) return Promise.reject(new Error(`${this.command} is not running`));
+    const id = this.nextId++;
+    return new Promise((resolve, reject) => {
+      const timer = setTimeout(() => {
+        this.pending.delete(id);
+        reject(new Error(`${this.command} did not answer ${method} in ${this.timeoutMs}ms`));
+      }, this.timeoutMs);
+      timer.unref();
+      this.pending.set(id, { resolve, reject, timer });
+      this.send({ jsonrpc: '2.0', id, method, params });
+    });
+  }
+
+  private notify(method: string, params: unknown): void {
+    this.send({ jsonrpc: '2.0', method, params });
+  }
+
+  private send(message: JsonRpcMessage): void {
+    if (this.exited) return;
+    const body = Buffer.from(JSON.stringify(message), 'utf-8');
+    this.child.stdin?.write(`Content-Length: ${body.length}\r\n\r\n`);
+    this.child.stdin?.write(body);
+  }
+
+  private receive(chunk: Buffer): void {
+    this.buffer = Buffer.concat([this.buffer, chunk]);
+    for (;;) {
+      const headerEnd = this.buffer.indexOf('\r\n\r\n');
+      if (headerEnd < 0) return;
+      const header = this.buffer.subarray(0, headerEnd).toString('ascii');
+      const length = Number(/Content-Length:\s*(\d+)/i.exec(header)?.[1]);
+      if (!Number.isFinite(length)) {
+        // Not LSP framing; nothing after it can be trusted
+        this.kill();
+        return;
+      }
+      const start = headerEnd + 4;
+      if (this.buffer.length < start + length) return;
+      const body = this.buffer.subarray(start, start + length).toString('utf-8');
+      this.buffer = this.buffer.subarray(start + length);
+      try {
+        this.dispatch(JSON.parse(body) as JsonRpcMessage);
+      } catch {
+        // A malformed message only loses that message
+      }
+    }
+  }
+
+  private dispatch(message: JsonRpcMessage): void {
+    if (message.method !== undefined) {
+      if (message.id === undefined) return;
+      // workspace/configuration expects one entry per requested item
+      const items = (message.params as { items?: unknown[] } | undefined)?.items;
+      const result =
+        message.method === 'workspace/configuration' ? (items ?? []).map(() => null) : null;
+      this.send({ jsonrpc: '2.0', id: message.id, result });
+      return;
+    }
+    const pending = typeof message.id === 'number' ? this.pending.get(message.id) : undefined;
+    if (!pending) return;
+    this.pending.delete(message.id as number);
+    clearTimeout(pending.timer);
+    if (message.error) pending.reject(new Error(`${this.command}: ${message.error.message}`));
+    else pending.resolve(message.result ?? null);
+  }
+
+  private fail(reason: string): void {
+    this.exited = true;
+    for (const [id, pending] of this.pending) {
+      clearTimeout(pending.timer);
+      pending.reject(new Error(`${this.command} ${reason}`));
+      this.pending.delete(id);
+    }
+  }
+}
+
+/** Flatten the three shapes `Hover.contents` comes in */
+export function hoverText(contents: unknown): string | null {
+  const part = (value: unknown): string => {
+    if (typeof value === 'string') return value;
+    if (!value || typeof value !== 'object') return '';
+    const { language, value: text } = value as { language?: unknown; value?: unknown };
+    if (typeof text !== 'string') return '';
+    // MarkedString with a language is a code block; MarkupContent is already markdown
+    return typeof language === 'string' ? `\`\`\`${language}\n${text}\n\`\`\`` : text;
+  };
+  const text = (Array.isArray(contents) ? contents.map(part) : [part(contents)])
+    .filter((entry) => entry.trim())
+    .join('\n\n')
+    .trim();
+  return text || null;
+}
@@ -6,6 +6,7 @@
 import { createHash } from 'crypto';
 import { promises as fs } from 'fs';
 import path from 'path';
+import { readTextFile } from '../utils/text-encoding.js';
 
 export interface FileManifest {
   version: 1;
@@ -13,11 +14,17 @@ export interface FileManifest {
   files: Record<string, string>; // relativePath → SHA-256 hash (first 16 hex chars)
 }
 
+export interface FileRename {
+  from: string;
+  to: string;
+}
+
 export interface ManifestDiff {
   added: string[]; // new files (not in old manifest)
   changed: string[]; // hash differs
   deleted: string[]; // in old manifest but not on disk
   unchanged: string[]; // hash matches
+  renamed: FileRename[]; // same content under a new path; not listed as added or deleted
 }
 
 /**
@@ -59,7 +66,7 @@ export async function writeManifest(manifestPath: string, manifest: FileManifest
 export async function computeFileHashes(
   files: string[],
   rootPath: string,
-  readFile: (p: string) => Promise<string> = (p) => fs.readFile(p, 'utf-8')
+  readFile: (p: string) => Promise<string> = (p) => readTextFile(p)
 ): Promise<Record<string, string>> {
   const hashes: Record<string, string> = {};
   for (const file of files) {
@@ -74,9 +81,45 @@ export async function computeFileHashes(
   return hashes;
 }
 
+/**
+ * Compute hashes when the caller already knows which files changed (e.g. from a file watcher).
+ * Only changed files and files missing from the previous manifest are read; every other file
+ * reuses its previous hash. Paths in changedPaths may be absolute or root-relative.
+ */
+export async function computeFileHashesForChanges(
+  files: string[],
+  rootPath: string,
+  previousManifest: FileManifest,
+  changedPaths: string[],
+  readFile: (p: string) => Promise<string> = (p) => readTextFile(p)
+): Promise<Record<string, string>> {
+  const changed = new Set(
+    changedPaths.map((p) =>
+      path.relative(rootPath, path.resolve(rootPath, p)).replace(/\\/g, '/')
+    )
+  );
+  const toHash: string[] = [];
+  const hashes: Record<string, string> = {};
+
+  for (const file of files) {
+    const relativePath = path.relative(rootPath, file).replace(/\\/g, '/');
+    const previousHash = previousManifest.files[relativePath];
+    if (previousHash !== undefined && !changed.has(relativePath)) {
+      hashes[relativePath] = previousHash;
+    } else {
+      toHash.push(file);
+    }
+  }
+
+  Object.assign(hashes, await computeFileHashes(toHash, rootPath, readFile));
+  return hashes;
+}
+
 /**
  * Diff an old manifest against current file hashes.
  * If oldManifest is null (first run), all files are "added".
+ * A deleted file and an added file with the same hash are reported as a rename instead; when
+ * several deleted files share the hash, one with the same file name is preferred.
  */
 export function diffManifest(
   oldManifest: FileManifest | null,
@@ -106,5 +149,31 @@ export function diffManifest(
     }
   }
 
-  return { added, changed, deleted, unchanged };
+  const deletedByHash = new Map<string, string[]>();
+  for (const filePath of deleted) {
+    const candidates = deletedByHash.get(oldFiles[filePath]) ?? [];
+    candidates.push(filePath);
+    deletedByHash.set(oldFiles[filePath], candidates);
+  }
+  const renamed: FileRename[] = [];
+  for (const filePath of added) {
+    const candidates = deletedByHash.get(currentHashes[filePath]);
+    if (!candidates || candidates.length === 0) continue;
+    const sameName = candidates.findIndex(
+      (candidate) => path.posix.basename(candidate) === path.posix.basename(filePath)
+    );
+    const [from] = candidates.splice(Math.max(sameName, 0), 1);
+    renamed.push({ from, to: filePath });
+  }
+  if (renamed.length === 0) return { added, changed, deleted, unchanged, renamed };
+
+  const movedFrom = new Set(renamed.map((rename) => rename.from));
+  const movedTo = new Set(renamed.map((rename) => rename.to));
+  return {
+    added: added.filter((filePath) => !movedTo.has(filePath)),
+    changed,
+    deleted: deleted.filter((filePath) => !movedFrom.has(filePath)),
+    unchanged,
+    renamed
+  };
 }
@@ -0,0 +1,194 @@
+/**
+ * Configurable chunk metadata enrichment, applied at index time after redaction.
+ *
+ * Three sources, all optional: CODEOWNERS owners (`metadata.owners`), directory-based module
+ * tags (`metadata.modules`) and regex-extracted annotations such as `@deprecated`
+ * (`metadata.annotations`). The settings come from the indexer config or the project's
+ * `.codebase-context/config.json`; enriched fields can then be filtered on with
+ * `filters.metadata` in search_codebase.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import ignore from 'ignore';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  PROJECT_CONFIG_FILENAME
+} from '../constants/codebase-context.js';
+import type { ChunkMetadata, CodebaseConfig } from '../types/index.js';
+
+export type EnrichmentConfig = NonNullable<CodebaseConfig['enrichment']>;
+
+/** Searched in GitHub's order, then GitLab's `.gitlab/`; the first file found is used */
+export const CODEOWNERS_LOCATIONS = [
+  '.github/CODEOWNERS',
+  'CODEOWNERS',
+  'docs/CODEOWNERS',
+  '.gitlab/CODEOWNERS'
+] as const;
+
+export interface CodeownersRule {
+  pattern: string;
+  /** Empty when the rule removes ownership from the matched paths */
+  owners: string[];
+}
+
+type Matcher = ReturnType<typeof ignore.default>;
+
+interface CompiledRule {
+  matcher: Matcher;
+  value: string[];
+}
+
+interface CompiledAnnotation {
+  name: string;
+  regex: RegExp;
+}
+
+export interface MetadataEnricher {
+  /** Metadata fields to merge into every chunk of a file; undefined fields are omitted */
+  enrich(relativeFile: string, content: string): Partial<ChunkMetadata>;
+}
+
+const MAX_ANNOTATION_VALUE_LENGTH = 200;
+
+/**
+ * Parse a CODEOWNERS file: `pattern owner...` per line, `#` comments. GitLab section headers
+ * (`[Section]`) are skipped; their rules still apply.
+ */
+export function parseCodeowners(content: string): CodeownersRule[] {
+  const rules: CodeownersRule[] = [];
+  for (const rawLine of content.split(/\r?\n/)) {
+    const line = rawLine.replace(/(^|\s)#.*$/, '').trim();
+    if (!line || /^\^?\[[^\]]*\]/.test(line)) continue;
+    const [pattern, ...owners] = line.split(/\s+/);
+    rules.push({ pattern: pattern.replace(/\\#/g, '#'), owners });
+  }
+  return rules;
+}
+
+function compileRule(pattern: string): Matcher | null {
+  try {
+    return ignore.default().add(pattern);
+  } catch {
+    return null;
+  }
+}
+
+function compileAnnotations(entries: EnrichmentConfig['annotations'] = []): CompiledAnnotation[] {
+  const compiled: CompiledAnnotation[] = [];
+  for (const entry of entries) {
+    if (!entry?.name || !entry.pattern) continue;
+    try {
+      const flags = (entry.flags ?? '').replace(/[gy]/g, '');
+      compiled.push({ name: entry.name, regex: new RegExp(entry.pattern, flags) });
+    } catch {
+      console.error(`[enrichment] Ignoring invalid annotation pattern: ${entry.pattern}`);
+    }
+  }
+  return compiled;
+}
+
+/** Owners of a repo-relative path: the last matching rule wins, as on GitHub and GitLab. */
+export function ownersForFile(rules: readonly CodeownersRule[], relativeFile: string): string[] {
+  const compiled = rules
+    .map((rule) => ({ matcher: compileRule(rule.pattern), value: rule.owners }))
+    .filter((rule): rule is CompiledRule => rule.matcher !== null);
+  return lastMatch(compiled, relativeFile) ?? [];
+}
+
+function lastMatch(rules: readonly CompiledRule[], relativeFile: string): string[] | null {
+  for (let i = rules.length - 1; i >= 0; i--) {
+    if (rules[i].matcher.ignores(relativeFile)) return rules[i].value;
+  }
+  return null;
+}
+
+async function readCodeowners(rootPath: string): Promise<CodeownersRule[]> {
+  for (const location of CODEOWNERS_LOCATIONS) {
+    try {
+      return parseCodeowners(await fs.readFile(path.join(rootPath, location), 'utf-8'));
+    } catch {
+      // Try the next location
+    }
+  }
+  return [];
+}
+
+/**
+ * Enrichment settings saved with the project, from the `enrichment` key of
+ * `.codebase-context/config.json`. Returns undefined when the file or key is missing.
+ */
+export async function loadProjectEnrichmentConfig(
+  rootPath: string
+): Promise<EnrichmentConfig | undefined> {
+  const configPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME);
+  try {
+    const parsed = JSON.parse(await fs.readFile(configPath, 'utf-8')) as {
+      enrichment?: unknown;
+    };
+    const enrichment = parsed.enrichment;
+    return enrichment && typeof enrichment === 'object'
+      ? (enrichment as EnrichmentConfig)
+      : undefined;
+  } catch {
+    return undefined;
+  }
+}
+
+/**
+ * Build the enricher for one indexing run. CODEOWNERS is read once (on by default when the
+ * file exists); module and annotation rules are compiled up front.
+ */
+export async function createMetadataEnricher(
+  rootPath: string,
+  config: EnrichmentConfig = {},
+  options: { readCodeowners?: boolean } = {}
+): Promise<MetadataEnricher> {
+  const ownerRules: CompiledRule[] = [];
+  if (config.codeowners !== false && options.readCodeowners !== false) {
+    for (const rule of await readCodeowners(rootPath)) {
+      const matcher = compileRule(rule.pattern);
+      if (matcher) ownerRules.push({ matcher, value: rule.owners });
+    }
+  }
+
+  const moduleRules: Array<{ matcher: Matcher; tag: string }> = [];
+  for (const [pattern, tag] of Object.entries(config.modules ?? {})) {
+    const matcher = tag ? compileRule(pattern) : null;
+    if (matcher) moduleRules.push({ matcher, tag });
+  }
+
+  const annotations = compileAnnotations(config.annotations);
+
+  return {
+    enrich(relativeFile, content) {
+      const enriched: Partial<ChunkMetadata> = {};
+
+      const owners = lastMatch(ownerRules, relativeFile);
+      if (owners && owners.length > 0) enriched.owners = owners;
+
+      // Every matching rule contributes its tag
+      const modules = [
+        ...new Set(
+          moduleRules
+            .filter((rule) => rule.matcher.ignores(relativeFile))
+            .map((rule) => rule.tag)
+        )
+      ];
+      if (modules.length > 0) enriched.modules = modules;
+
+      const found: Record<string, string> = {};
+      for (const { name, regex } of annotations) {
+        const match = content.match(regex);
+        if (!match) continue;
+        // The first capture group when it caught something, else the whole match
+        const value = match[1]?.trim() || match[0].trim();
+        found[name] = value.slice(0, MAX_ANNOTATION_VALUE_LENGTH);
+      }
+      if (Object.keys(found).length > 0) enriched.annotations = found;
+
+      return enriched;
+    }
+  };
+}
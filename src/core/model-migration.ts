@@ -0,0 +1,156 @@
+/**
+ * Embedding model migrations: the configured model is no longer the one the active index was
+ * embedded with. Vectors from two models can't be ranked against each other, so a full build
+ * re-embeds every chunk into its staging directory, as every build does, and the old index
+ * stays active meanwhile. Until that build swaps in, searches embed their queries with the
+ * model the active vectors came from; the swap is the switchover.
+ *
+ * The migration is recorded in `.codebase-context/model-migration.json` while it runs, so
+ * other processes searching the same index, and the next server start after a failed or
+ * cancelled build, keep serving the previous vectors instead of failing on the mismatch.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  MODEL_MIGRATION_FILENAME
+} from '../constants/codebase-context.js';
+import type { EmbeddingConfig } from '../embeddings/index.js';
+import type { IndexingProgress } from '../types/index.js';
+import { describeEmbeddingDrift, readIndexMeta, type EmbeddingFingerprint } from './index-meta.js';
+import { overallProgress } from './indexing-progress.js';
+
+export interface EmbeddingModelRef {
+  provider: string;
+  model: string;
+  dimensions?: number;
+  truncateDimensions?: number;
+}
+
+export interface ModelMigration {
+  /** Model of the vectors served until the migration completes */
+  from: EmbeddingFingerprint;
+  /** Configured model the running build embeds with */
+  to: EmbeddingModelRef;
+  startedAt: string;
+}
+
+/** Models whose size is fixed; a `dimensions` setting would only split the provider cache */
+const FIXED_SIZE_PROVIDERS = new Set(['transformers', 'ollama']);
+
+function isModelRef(value: unknown): value is EmbeddingModelRef {
+  if (!value || typeof value !== 'object') return false;
+  const { provider, model } = value as Record<string, unknown>;
+  return typeof provider === 'string' && !!provider && typeof model === 'string' && !!model;
+}
+
+export async function readModelMigration(contextDir: string): Promise<ModelMigration | null> {
+  try {
+    const parsed = JSON.parse(
+      await fs.readFile(path.join(contextDir, MODEL_MIGRATION_FILENAME), 'utf-8')
+    ) as Partial<ModelMigration>;
+    if (!isModelRef(parsed.from) || !isModelRef(parsed.to)) return null;
+    return parsed as ModelMigration;
+  } catch {
+    return null;
+  }
+}
+
+export async function clearModelMigration(contextDir: string): Promise<void> {
+  await fs.rm(path.join(contextDir, MODEL_MIGRATION_FILENAME), { force: true });
+}
+
+/**
+ * Record a migration when `configured` isn't the model the index in `contextDir` was embedded
+ * with, and return it; a record for the same two models is kept as it is. Returns null, and
+ * drops any stale record, when the models agree or there is no readable index to serve.
+ */
+export async function startModelMigration(
+  rootPath: string,
+  configured: EmbeddingModelRef,
+  contextDir = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME)
+): Promise<ModelMigration | null> {
+  const built = await readIndexMeta(rootPath, contextDir).then(
+    (meta) => meta.embedding,
+    () => undefined
+  );
+  if (!built || !describeEmbeddingDrift(built, configured)) {
+    await clearModelMigration(contextDir).catch(() => undefined);
+    return null;
+  }
+
+  const existing = await readModelMigration(contextDir);
+  if (
+    existing &&
+    !describeEmbeddingDrift(built, existing.from) &&
+    !describeEmbeddingDrift({ dimensions: 0, ...existing.to }, configured)
+  ) {
+    return existing;
+  }
+  const migration: ModelMigration = {
+    from: built,
+    to: {
+      provider: configured.provider,
+      model: configured.model,
+      ...(configured.dimensions ? { dimensions: configured.dimensions } : {}),
+      ...(configured.truncateDimensions
+        ? { truncateDimensions: configured.truncateDimensions }
+        : {})
+    },
+    startedAt: new Date().toISOString()
+  };
+  await fs.mkdir(contextDir, { recursive: true });
+  await fs.writeFile(
+    path.join(contextDir, MODEL_MIGRATION_FILENAME),
+    JSON.stringify(migration, null, 2)
+  );
+  return migration;
+}
+
+/** The running migration away from `built`, the vectors of the active index, if any */
+export async function activeModelMigration(
+  contextDir: string,
+  built: EmbeddingFingerprint | undefined
+): Promise<ModelMigration | null> {
+  if (!built) return null;
+  const migration = await readModelMigration(contextDir);
+  return migration && !describeEmbeddingDrift(built, migration.from) ? migration : null;
+}
+
+/**
+ * Settings that embed queries like the active vectors were embedded. API keys come from the
+ * environment; everything set for the new model is left out.
+ */
+export function previousModelConfig(migration: ModelMigration): Partial<EmbeddingConfig> {
+  const { provider, model, dimensions, truncateDimensions } = migration.from;
+  return {
+    provider: provider as EmbeddingConfig['provider'],
+    model,
+    // A truncated index records the cut size, not the size the model was asked for
+    dimensions: truncateDimensions || FIXED_SIZE_PROVIDERS.has(provider) ? undefined : dimensions,
+    truncateDimensions
+  };
+}
+
+export interface ModelMigrationStatus {
+  /** `provider:model` being served */
+  from: string;
+  /** `provider:model` being embedded with */
+  to: string;
+  startedAt: string;
+  /** Overall progress of the re-embedding build (0-100), when it runs in this process */
+  readiness?: number;
+}
+
+export function describeModelMigration(
+  migration: ModelMigration,
+  progress?: IndexingProgress
+): ModelMigrationStatus {
+  return {
+    from: `${migration.from.provider}:${migration.from.model}`,
+    to: `${migration.to.provider}:${migration.to.model}`,
+    startedAt: migration.startedAt,
+    ...(progress && { readiness: overallProgress(progress) })
+  };
+}
@@ -0,0 +1,403 @@
+/**
+ * Module cards: a short structured summary per directory (purpose, key exports, what it
+ * depends on and what uses it, how much of it has tests), served as `codebase://module/`
+ * resources to prime a model on an unfamiliar area.
+ *
+ * A card covers the directory's whole subtree. It is derived from the index and cached
+ * under `.codebase-context/` keyed by a hash of the subtree's file hashes and of the files
+ * importing it, so it is rebuilt only after something it describes changes.
+ */
+
+import { createHash } from 'crypto';
+import { promises as fs } from 'fs';
+import path from 'path';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  MANIFEST_FILENAME,
+  MODULE_CARDS_FILENAME
+} from '../constants/codebase-context.js';
+import { loadDependencyGraph, type DependencyGraphData } from './dependency-graph.js';
+import { isDocumentationPath } from './file-filters.js';
+import { decodeArtifact, encodeArtifact } from './index-encryption.js';
+import { loadIndexedFiles } from './file-resources.js';
+import { firstSentence, leadingDocComment } from './file-summary.js';
+import { readManifest } from './manifest.js';
+import { loadSymbolIndex, topLevelDefinitions } from './symbol-index.js';
+import { isTestSourceFile, loadTestLinks } from './test-mapping.js';
+import type { CodeChunk } from '../types/index.js';
+import { readTextFile } from '../utils/text-encoding.js';
+
+const CACHE_VERSION = 1;
+const MAX_LANGUAGES = 3;
+const MAX_EXPORT_FILES = 5;
+const MAX_EXPORTS_PER_FILE = 5;
+const MAX_MODULES = 8;
+const MAX_PACKAGES = 10;
+const MAX_SUBMODULES = 12;
+
+const README_NAMES = ['README.md', 'README.markdown', 'README.rst', 'README.txt', 'README'];
+/** Files whose leading comment usually describes their directory */
+const ENTRY_FILES = [
+  'index.ts',
+  'index.tsx',
+  'index.js',
+  'index.mjs',
+  '__init__.py',
+  'doc.go',
+  'mod.rs',
+  'lib.rs',
+  'package-info.java'
+];
+
+export interface ModuleSummary {
+  /** Repo-relative posix directory */
+  path: string;
+  /** Indexed files in its subtree */
+  files: number;
+}
+
+export interface ModuleCard extends ModuleSummary {
+  /** First 16 hex chars of the hash the card was built from */
+  hash: string;
+  generatedAt: string;
+  purpose: string;
+  /** The README, package.json or source file the purpose was taken from; absent if derived */
+  purposeFrom?: string;
+  languages: string[];
+  /** What the rest of the project imports from it, most-imported file first */
+  keyExports: Array<{ file: string; names: string[] }>;
+  dependsOn: { modules: string[]; packages: string[] };
+  usedBy: { total: number; modules: string[] };
+  /** Coverage hint from test mapping: source files with at least one linked test */
+  tests: { sourceFiles: number; tested: number; testFiles: number };
+  /** Child directories that have cards of their own */
+  submodules: string[];
+}
+
+export interface ModuleCardOptions {
+  /** Leave out files this returns false for (the path policy) */
+  include?: (file: string) => boolean;
+  /** Save rebuilt cards to the cache (default: true; off on read-only servers) */
+  persist?: boolean;
+}
+
+interface ModuleCardCache {
+  version: number;
+  modules: Record<string, ModuleCard>;
+}
+
+/** `./src/core/` -> `src/core` */
+function normalizeDir(dir: string): string {
+  const trimmed = dir.replace(/\\/g, '/').replace(/^\.?\/+|\/+$/g, '');
+  return trimmed === '.' ? '' : trimmed;
+}
+
+const inDir = (file: string, dir: string) => file.startsWith(`${dir}/`);
+
+/** Directory of `file`, `.` at the root */
+const dirOf = (file: string) => path.posix.dirname(file);
+
+/** Directories above a posix path: `a/b/c.ts` -> `a`, `a/b` */
+function parentDirs(file: string): string[] {
+  const parts = file.split('/').slice(0, -1);
+  return parts.map((_, i) => parts.slice(0, i + 1).join('/'));
+}
+
+/** Most frequent first, then by name */
+function ranked(counts: Map<string, number>, limit: number): string[] {
+  return [...counts.entries()]
+    .sort(([a, x], [b, y]) => y - x || a.localeCompare(b))
+    .slice(0, limit)
+    .map(([name]) => name);
+}
+
+function bump(counts: Map<string, number>, key: string): void {
+  counts.set(key, (counts.get(key) ?? 0) + 1);
+}
+
+async function indexedFileList(
+  rootPath: string,
+  include?: (file: string) => boolean
+): Promise<Map<string, CodeChunk[]> | null> {
+  const files = await loadIndexedFiles(rootPath);
+  if (!files || !include) return files;
+  return new Map([...files.entries()].filter(([file]) => include(file)));
+}
+
+/** Every directory holding indexed files, with its subtree's file count, in path order */
+export async function listModules(
+  rootPath: string,
+  include?: (file: string) => boolean
+): Promise<ModuleSummary[] | null> {
+  const files = await indexedFileList(rootPath, include);
+  if (!files) return null;
+  const counts = new Map<string, number>();
+  for (const file of files.keys()) parentDirs(file).forEach((dir) => bump(counts, dir));
+  return [...counts.entries()]
+    .sort(([a], [b]) => a.localeCompare(b))
+    .map(([dir, count]) => ({ path: dir, files: count }));
+}
+
+/** First prose paragraph of a README, skipping headings, badges and HTML */
+export function readmePurpose(text: string): string | undefined {
+  const paragraph: string[] = [];
+  for (const raw of text.split(/\r?\n/)) {
+    const line = raw.trim();
+    if (!line) {
+      if (paragraph.length > 0) break;
+      continue;
+    }
+    if (/^(#|=+$|-+$|!\[|\[!\[|<|```|\.\. )/.test(line)) {
+      if (paragraph.length > 0) break;
+      continue;
+    }
+    paragraph.push(line);
+  }
+  return paragraph.length > 0 ? firstSentence(paragraph.join(' ')) : undefined;
+}
+
+async function readOptional(file: string): Promise<string | null> {
+  try {
+    return await readTextFile(file);
+  } catch {
+    return null;
+  }
+}
+
+/** From the directory's README, package.json description, or an entry file's doc comment */
+async function documentedPurpose(
+  rootPath: string,
+  dir: string,
+  files: string[],
+  mostImported: string | undefined,
+  include: (file: string) => boolean
+): Promise<{ purpose: string; from: string } | null> {
+  for (const name of README_NAMES) {
+    if (!include(`${dir}/${name}`)) continue;
+    const text = await readOptional(path.join(rootPath, dir, name));
+    const purpose = text ? readmePurpose(text) : undefined;
+    if (purpose) return { purpose, from: `${dir}/${name}` };
+  }
+
+  const manifest = include(`${dir}/package.json`)
+    ? await readOptional(path.join(rootPath, dir, 'package.json'))
+    : null;
+  if (manifest) {
+    try {
+      const { description } = JSON.parse(manifest) as { description?: unknown };
+      if (typeof description === 'string' && description.trim()) {
+        return { purpose: firstSentence(description), from: `${dir}/package.json` };
+      }
+    } catch {
+      // Not JSON: fall through to the source files
+    }
+  }
+
+  const direct = new Set(files.filter((file) => dirOf(file) === dir));
+  const candidates = [
+    ...ENTRY_FILES.map((name) => `${dir}/${name}`).filter((file) => direct.has(file)),
+    ...(mostImported ? [mostImported] : [])
+  ];
+  for (const file of candidates) {
+    const text = await readOptional(path.join(rootPath, file));
+    const purpose = text ? leadingDocComment(text.split(/\r?\n/)) : undefined;
+    if (purpose) return { purpose, from: file };
+  }
+  return null;
+}
+
+function derivedPurpose(chunks: CodeChunk[], symbols: string[], fileCount: number): string {
+  const roles = new Map<string, number>();
+  for (const chunk of chunks) {
+    if (!chunk.componentType || chunk.componentType === 'unknown') continue;
+    const layer = chunk.layer && chunk.layer !== 'unknown' ? ` (${chunk.layer} layer)` : '';
+    bump(roles, `${chunk.componentType}${layer}`);
+  }
+  const [role] = ranked(roles, 1);
+  const defines =
+    symbols.length > 0
+      ? `, defining ${symbols.slice(0, 3).join(', ')}${symbols.length > 3 ? ' and more' : ''}`
+      : '';
+  return `${fileCount} files${role ? `, mostly ${role}` : ''}${defines}.`;
+}
+
+/** Hash of what a card describes: the subtree's files and the files importing into it */
+function cardHash(
+  files: string[],
+  fileHashes: Record<string, string>,
+  importers: string[]
+): string {
+  const hash = createHash('sha256');
+  for (const file of files) hash.update(`${file}:${fileHashes[file] ?? ''}\n`);
+  for (const importer of importers) hash.update(`<${importer}\n`);
+  return hash.digest('hex').slice(0, 16);
+}
+
+/** Files outside `dir` importing files inside it */
+function outsideImporters(
+  dir: string,
+  files: string[],
+  graph: DependencyGraphData | null
+): string[] {
+  const importers = new Set<string>();
+  for (const file of files) {
+    for (const importer of graph?.importedBy[file] ?? []) {
+      if (!inDir(importer, dir)) importers.add(importer);
+    }
+  }
+  return [...importers].sort();
+}
+
+async function readCache(file: string): Promise<ModuleCardCache> {
+  try {
+    const raw = await fs.readFile(file, 'utf-8');
+    const parsed = (await decodeArtifact(raw, 'Module cards')) as Partial<ModuleCardCache>;
+    if (parsed.version === CACHE_VERSION && parsed.modules && typeof parsed.modules === 'object') {
+      return { version: CACHE_VERSION, modules: parsed.modules };
+    }
+  } catch {
+    // Missing or unreadable: start empty
+  }
+  return { version: CACHE_VERSION, modules: {} };
+}
+
+async function writeCache(file: string, cache: ModuleCardCache): Promise<void> {
+  await fs.mkdir(path.dirname(file), { recursive: true });
+  const tmp = `${file}.${process.pid}.tmp`;
+  await fs.writeFile(tmp, await encodeArtifact(cache));
+  await fs.rename(tmp, file);
+}
+
+/**
+ * The card for `dir` (repo-relative), from the cache when nothing it describes has changed.
+ * Null when the directory has no indexed files.
+ */
+export async function getModuleCard(
+  rootPath: string,
+  dir: string,
+  options: ModuleCardOptions = {}
+): Promise<(ModuleCard & { cached: boolean }) | null> {
+  const target = normalizeDir(dir);
+  const indexed = await indexedFileList(rootPath, options.include);
+  if (!target || !indexed) return null;
+  const files = [...indexed.keys()].filter((file) => inDir(file, target));
+  if (files.length === 0) return null;
+
+  const contextDir = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME);
+  const [graph, manifest] = await Promise.all([
+    loadDependencyGraph(rootPath),
+    readManifest(path.join(contextDir, MANIFEST_FILENAME))
+  ]);
+  const importers = outsideImporters(target, files, graph);
+  const hash = cardHash(files, manifest?.files ?? {}, importers);
+
+  const cacheFile = path.join(contextDir, MODULE_CARDS_FILENAME);
+  const cache = await readCache(cacheFile);
+  const cached = cache.modules[target];
+  if (cached?.hash === hash) return { ...cached, cached: true };
+
+  const include = options.include ?? (() => true);
+  const card = await buildModuleCard(rootPath, target, files, indexed, graph, importers, include);
+  if (options.persist !== false) {
+    // Drop cards of directories that are gone
+    const modules = new Set([...indexed.keys()].flatMap(parentDirs));
+    for (const key of Object.keys(cache.modules)) {
+      if (!modules.has(key)) delete cache.modules[key];
+    }
+    cache.modules[target] = { ...card, hash };
+    await writeCache(cacheFile, cache);
+  }
+  return { ...card, hash, cached: false };
+}
+
+async function buildModuleCard(
+  rootPath: string,
+  dir: string,
+  files: string[],
+  indexed: Map<string, CodeChunk[]>,
+  graph: DependencyGraphData | null,
+  importers: string[],
+  include: (file: string) => boolean
+): Promise<Omit<ModuleCard, 'hash'>> {
+  const [definitions, testLinks] = await Promise.all([
+    loadSymbolIndex(rootPath),
+    loadTestLinks(rootPath)
+  ]);
+  const chunks = files.flatMap((file) => indexed.get(file) ?? []);
+
+  const languages = new Map<string, number>();
+  for (const file of files) {
+    const language = indexed.get(file)?.[0]?.language;
+    if (language && language !== 'unknown') bump(languages, language);
+  }
+
+  const topLevel = new Map<string, string[]>();
+  for (const def of topLevelDefinitions((definitions ?? []).filter((d) => inDir(d.file, dir)))) {
+    const list = topLevel.get(def.file) ?? [];
+    list.push(def.name);
+    topLevel.set(def.file, list);
+  }
+  const outsideUse = (file: string) =>
+    (graph?.importedBy[file] ?? []).filter((importer) => !inDir(importer, dir)).length;
+  const testFiles = files.filter(isTestSourceFile);
+  const sourceFiles = files.filter(
+    (file) => !isTestSourceFile(file) && !isDocumentationPath(file)
+  );
+  // Key exports: files the rest of the project imports most, with their exported names
+  const used = sourceFiles
+    .map((file) => ({ file, uses: outsideUse(file) }))
+    .filter(({ uses }) => uses > 0)
+    .sort((a, b) => b.uses - a.uses || a.file.localeCompare(b.file));
+  const keyExports = used
+    .slice(0, MAX_EXPORT_FILES)
+    .map(({ file }) => {
+      const exported = (graph?.exports?.[file] ?? []).map((exp) => exp.name);
+      const names = exported.length > 0 ? exported : (topLevel.get(file) ?? []);
+      return { file, names: [...new Set(names)].slice(0, MAX_EXPORTS_PER_FILE) };
+    });
+
+  const dependsOn = new Map<string, number>();
+  const packages = new Map<string, number>();
+  for (const file of files) {
+    for (const target of graph?.imports[file] ?? []) {
+      if (!inDir(target, dir)) bump(dependsOn, dirOf(target));
+    }
+    for (const pkg of new Set(graph?.externalImports?.[file] ?? [])) bump(packages, pkg);
+  }
+  const usedBy = new Map<string, number>();
+  for (const importer of importers) bump(usedBy, dirOf(importer));
+
+  const tested = sourceFiles.filter((file) => (testLinks?.[file]?.length ?? 0) > 0).length;
+
+  const submodules = [
+    ...new Set(
+      files
+        .map((file) => file.slice(dir.length + 1).split('/'))
+        .filter((parts) => parts.length > 1)
+        .map((parts) => `${dir}/${parts[0]}`)
+    )
+  ].sort();
+
+  const documented = await documentedPurpose(rootPath, dir, files, used[0]?.file, include);
+  const symbols = [...topLevel.entries()]
+    .sort(([a], [b]) => outsideUse(b) - outsideUse(a) || a.localeCompare(b))
+    .flatMap(([, names]) => names);
+
+  return {
+    path: dir,
+    files: files.length,
+    generatedAt: new Date().toISOString(),
+    ...(documented
+      ? { purpose: documented.purpose, purposeFrom: documented.from }
+      : { purpose: derivedPurpose(chunks, symbols, files.length) }),
+    languages: ranked(languages, MAX_LANGUAGES),
+    keyExports,
+    dependsOn: {
+      modules: ranked(dependsOn, MAX_MODULES),
+      packages: ranked(packages, MAX_PACKAGES)
+    },
+    usedBy: { total: importers.length, modules: ranked(usedBy, MAX_MODULES) },
+    tests: { sourceFiles: sourceFiles.length, tested, testFiles: testFiles.length },
+    submodules: submodules.slice(0, MAX_SUBMODULES)
+  };
+}
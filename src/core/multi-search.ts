@@ -0,0 +1,57 @@
+/**
+ * Grouping for `multi_search`: several related queries run at once tend to return the same
+ * chunks. Each chunk is listed once, under the query that scored it highest; the other
+ * queries that found it (or a chunk overlapping it in the same file) are noted on it.
+ */
+
+export interface QueryHit {
+  /** Project-relative path with forward slashes */
+  file: string;
+  startLine: number;
+  endLine: number;
+  score: number;
+  summary?: string;
+}
+
+export interface GroupedHit extends QueryHit {
+  /** 1-based numbers of the other queries that found this chunk or one overlapping it */
+  alsoFor?: number[];
+}
+
+export interface GroupedResults {
+  /** One list per query, in query order, best first */
+  groups: GroupedHit[][];
+  /** Hits dropped because an earlier listed chunk covers them */
+  duplicates: number;
+}
+
+const overlaps = (a: QueryHit, b: QueryHit) =>
+  a.file === b.file && a.startLine <= b.endLine && b.startLine <= a.endLine;
+
+/** Deduplicate the hits of each query (`hitsPerQuery[i]`) across queries */
+export function groupQueryResults(hitsPerQuery: QueryHit[][]): GroupedResults {
+  const candidates = hitsPerQuery
+    .flatMap((hits, query) => hits.map((hit) => ({ hit, query })))
+    .sort((a, b) => b.hit.score - a.hit.score || a.query - b.query);
+
+  const groups: GroupedHit[][] = hitsPerQuery.map(() => []);
+  const kept: Array<{ hit: GroupedHit; query: number }> = [];
+  let duplicates = 0;
+  for (const { hit, query } of candidates) {
+    const covering = kept.find((entry) => overlaps(entry.hit, hit));
+    if (!covering) {
+      const entry = { hit: { ...hit }, query };
+      kept.push(entry);
+      groups[query].push(entry.hit);
+      continue;
+    }
+    duplicates++;
+    if (covering.query !== query) {
+      const alsoFor = covering.hit.alsoFor ?? [];
+      if (!alsoFor.includes(query + 1)) {
+        covering.hit.alsoFor = [...alsoFor, query + 1].sort((a, b) => a - b);
+      }
+    }
+  }
+  return { groups, duplicates };
+}
@@ -0,0 +1,241 @@
+/**
+ * Overlay indexes: a small in-memory index over pending changes, layered on top of the main
+ * index for one search and discarded afterwards. Agents that draft patches in a scratch
+ * worktree (or as a diff) can search "the repo plus my changes" without writing to
+ * `.codebase-context/` or waiting for a rebuild.
+ *
+ * An overlay is built from one of:
+ *
+ * - a directory laid out like the repo (a temp worktree, or a scratch copy of a few files):
+ *   files that differ from the working tree replace it, new ones are added. Files missing
+ *   from the directory are not treated as deleted.
+ * - a unified diff against the working tree: hunks are applied to the current files, and
+ *   deleted or renamed-away files drop out of the results.
+ *
+ * Overlay chunks go through the indexer's chunking, secret redaction and compliance masking,
+ * and files the path policy denies are left out. The searcher embeds them on first use.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import type { ChunkingConfig, CodeChunk } from '../types/index.js';
+import { analyzerRegistry } from './analyzer-registry.js';
+import { loadProjectChunkingConfig, resolveLanguageChunking } from './chunking-config.js';
+import { loadComplianceFilter, type ComplianceFilter } from './compliance-filters.js';
+import { parseUnifiedDiff, type DiffHunk } from './diff-context.js';
+import { loadPathPolicy, type PathPolicy } from './path-policy.js';
+import { mergeSmallChunks } from '../utils/chunking.js';
+import { ALWAYS_SKIPPED_DIRS, looksBinary } from '../utils/ignore-rules.js';
+import { detectLanguage } from '../utils/language-detection.js';
+import { redactSecrets, resolveRedactionOptions } from '../utils/secret-redaction.js';
+import { decodeText } from '../utils/text-encoding.js';
+
+export type OverlaySource = { directory: string } | { diff: string };
+
+export interface OverlaySkippedFile {
+  file: string;
+  reason: string;
+}
+
+export interface OverlayIndex {
+  rootPath: string;
+  /** Repo-relative paths whose indexed chunks the overlay hides: changed, added or deleted */
+  files: Set<string>;
+  /** Paths the overlay removes (deleted, or renamed away); a subset of `files` */
+  deleted: Set<string>;
+  chunks: CodeChunk[];
+  /** Changed files left out of the overlay, and why */
+  skipped: OverlaySkippedFile[];
+}
+
+/** Changed files one overlay takes; an overlay is for a patch, not a second repo */
+export const MAX_OVERLAY_FILES = 200;
+const MAX_OVERLAY_FILE_BYTES = 1024 * 1024;
+/** Files read from a scratch directory while looking for the changed ones */
+const MAX_SCANNED_FILES = 20000;
+
+interface OverlayContext {
+  rootPath: string;
+  chunking: ChunkingConfig | undefined;
+  compliance: ComplianceFilter;
+  pathPolicy: PathPolicy;
+  overlay: OverlayIndex;
+}
+
+function emptyOverlay(rootPath: string): OverlayIndex {
+  return { rootPath, files: new Set(), deleted: new Set(), chunks: [], skipped: [] };
+}
+
+/** Build the overlay for `source` over the repo at `rootPath` */
+export async function buildOverlayIndex(
+  rootPath: string,
+  source: OverlaySource
+): Promise<OverlayIndex> {
+  if (analyzerRegistry.getAll().length === 0) {
+    const { GenericAnalyzer } = await import('../analyzers/generic/index.js');
+    analyzerRegistry.register(new GenericAnalyzer());
+  }
+  const ctx: OverlayContext = {
+    rootPath,
+    chunking: await loadProjectChunkingConfig(rootPath),
+    compliance: await loadComplianceFilter(rootPath),
+    pathPolicy: await loadPathPolicy(rootPath),
+    overlay: emptyOverlay(rootPath)
+  };
+  if ('diff' in source) await overlayDiff(ctx, source.diff);
+  else await overlayDirectory(ctx, path.resolve(rootPath, source.directory));
+  return ctx.overlay;
+}
+
+/** Chunk one file's new content into the overlay, as the indexer would chunk it */
+async function addFile(ctx: OverlayContext, relativePath: string, content: string) {
+  const { overlay } = ctx;
+  const filePath = path.join(ctx.rootPath, relativePath);
+  if (!ctx.pathPolicy.allows(filePath) || ctx.compliance.excludesPath(relativePath)) return;
+  if (overlay.files.size >= MAX_OVERLAY_FILES) {
+    overlay.skipped.push({ file: relativePath, reason: `over ${MAX_OVERLAY_FILES} files` });
+    return;
+  }
+  overlay.files.add(relativePath);
+  const normalized = content.replace(/\r\n/g, '\n');
+  if (ctx.compliance.active && ctx.compliance.excludingRule(normalized)) return;
+
+  const language = detectLanguage(filePath, normalized);
+  const result = await analyzerRegistry.analyzeFile(filePath, normalized, {
+    chunking: resolveLanguageChunking(ctx.chunking, language)
+  });
+  if (!result) return;
+  const redaction = resolveRedactionOptions();
+  for (const [i, chunk] of mergeSmallChunks(result.chunks, 15).entries()) {
+    let text = redactSecrets(chunk.content, redaction).text;
+    if (ctx.compliance.active) text = ctx.compliance.mask(text).text;
+    overlay.chunks.push({
+      ...chunk,
+      id: `overlay:${relativePath}:${i}`,
+      content: text,
+      filePath,
+      metadata: { ...chunk.metadata, overlay: true }
+    });
+  }
+}
+
+function removeFile(ctx: OverlayContext, relativePath: string) {
+  if (!ctx.pathPolicy.allows(path.join(ctx.rootPath, relativePath))) return;
+  ctx.overlay.files.add(relativePath);
+  ctx.overlay.deleted.add(relativePath);
+}
+
+async function readIfText(file: string): Promise<string | null> {
+  const stat = await fs.stat(file).catch(() => null);
+  if (!stat?.isFile() || stat.size > MAX_OVERLAY_FILE_BYTES) return null;
+  const bytes = await fs.readFile(file);
+  return looksBinary(bytes.subarray(0, 8000)) ? null : decodeText(bytes).text;
+}
+
+async function overlayDirectory(ctx: OverlayContext, directory: string) {
+  let scanned = 0;
+  const walk = async (dir: string): Promise<void> => {
+    const entries = await fs.readdir(dir, { withFileTypes: true }).catch(() => []);
+    for (const entry of entries) {
+      if (scanned >= MAX_SCANNED_FILES) return;
+      const full = path.join(dir, entry.name);
+      if (entry.isDirectory()) {
+        if (!ALWAYS_SKIPPED_DIRS.has(entry.name)) await walk(full);
+        continue;
+      }
+      if (!entry.isFile()) continue;
+      scanned++;
+      const relativePath = path.relative(directory, full).replace(/\\/g, '/');
+      const content = await readIfText(full);
+      if (content === null) continue;
+      // A full worktree copy only overlays what it changed
+      const current = await readIfText(path.join(ctx.rootPath, relativePath));
+      if (current !== content) await addFile(ctx, relativePath, content);
+    }
+  };
+  await walk(directory);
+}
+
+/**
+ * Apply `hunks` to `base`, checking every context and removed line against it; null when
+ * the diff was made against other content
+ */
+export function applyHunks(base: string, hunks: readonly DiffHunk[]): string | null {
+  const lines = base.split('\n');
+  const output: string[] = [];
+  let next = 0;
+  for (const hunk of hunks) {
+    // A pure insertion's old start is the line it goes after
+    const start = hunk.oldLines === 0 ? hunk.oldStart : hunk.oldStart - 1;
+    if (start < next || start > lines.length) return null;
+    output.push(...lines.slice(next, start));
+    next = start;
+    for (const line of hunk.lines ?? []) {
+      const text = line.slice(1);
+      if (line.startsWith('+')) {
+        output.push(text);
+        continue;
+      }
+      if (lines[next] !== text) return null;
+      if (line.startsWith(' ')) output.push(text);
+      next++;
+    }
+  }
+  output.push(...lines.slice(next));
+  return output.join('\n');
+}
+
+async function overlayDiff(ctx: OverlayContext, diff: string) {
+  for (const file of parseUnifiedDiff(diff, { hunkLines: true })) {
+    if (file.status === 'deleted') {
+      removeFile(ctx, file.path);
+      continue;
+    }
+    if (file.status === 'added') {
+      const added = file.hunks.flatMap((hunk) => hunk.lines ?? []).map((line) => line.slice(1));
+      await addFile(ctx, file.path, added.length > 0 ? `${added.join('\n')}\n` : '');
+      continue;
+    }
+    const basePath = file.oldPath ?? file.path;
+    const base = await readIfText(path.join(ctx.rootPath, basePath));
+    const patched = base === null ? null : applyHunks(base.replace(/\r\n/g, '\n'), file.hunks);
+    if (patched === null) {
+      ctx.overlay.skipped.push({
+        file: file.path,
+        reason:
+          base === null ? `${basePath} is not in the working tree` : 'does not apply cleanly'
+      });
+      continue;
+    }
+    if (file.oldPath && file.oldPath !== file.path) removeFile(ctx, file.oldPath);
+    await addFile(ctx, file.path, patched);
+  }
+}
+
+/** Whether the overlay replaces or removes the file an indexed chunk comes from */
+export function overlayCovers(overlay: OverlayIndex, chunk: CodeChunk): boolean {
+  const file = path.resolve(overlay.rootPath, chunk.filePath);
+  return overlay.files.has(path.relative(overlay.rootPath, file).replace(/\\/g, '/'));
+}
+
+/** The main index's chunks with the overlay's files swapped for its own */
+export function layerOverlay(chunks: CodeChunk[], overlay: OverlayIndex): CodeChunk[] {
+  return [...chunks.filter((chunk) => !overlayCovers(overlay, chunk)), ...overlay.chunks];
+}
+
+export interface OverlaySummary {
+  files: number;
+  deleted: number;
+  chunks: number;
+  skipped?: OverlaySkippedFile[];
+}
+
+export function summarizeOverlay(overlay: OverlayIndex): OverlaySummary {
+  return {
+    files: overlay.files.size - overlay.deleted.size,
+    deleted: overlay.deleted.size,
+    chunks: overlay.chunks.length,
+    // A few reasons are enough to see why a patch was only partly applied
+    ...(overlay.skipped.length > 0 && { skipped: overlay.skipped.slice(0, 5) })
+  };
+}
@@ -0,0 +1,313 @@
+/**
+ * Path policy for exposing the server to shared agents: an allowlist of repo-relative paths
+ * (`allowPaths`: plain paths select a file or directory, globs like `docs/**` match as usual),
+ * a denylist in .gitignore syntax (`denyPaths`: `.env*`, `secrets/`, `*.pem`) and a
+ * read-only switch. Denied files are never indexed, and tool responses drop entries that point
+ * at them or outside the repo root. Read-only servers serve an existing index and write nothing.
+ *
+ * Read from the `security` key of `.codebase-context/config.json` and from
+ * CODEBASE_CONTEXT_ALLOW_PATHS / CODEBASE_CONTEXT_DENY_PATHS (comma-separated) and
+ * CODEBASE_CONTEXT_READ_ONLY. The environment allowlist replaces the file's; denylists add up
+ * and either source can turn read-only on, so an agent that edits the config file can't widen
+ * what the operator set.
+ */
+
+import path from 'path';
+import { promises as fs } from 'fs';
+import { matchesGlob } from '../utils/git-tree.js';
+import { IgnoreRules } from '../utils/ignore-rules.js';
+import { loadProjectComplianceConfig } from './compliance-filters.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  PROJECT_CONFIG_FILENAME
+} from '../constants/codebase-context.js';
+
+export interface PathPolicyConfig {
+  /** Only these repo-relative paths or globs are indexed and returned */
+  allowPaths?: string[];
+  /** Never index or return these (.gitignore syntax) */
+  denyPaths?: string[];
+  /** Serve an existing index only: no index builds, memory writes or caches */
+  readOnly?: boolean;
+}
+
+export interface PathPolicy {
+  /** Any allow or deny rule is set (outside-root paths are only refused then) */
+  readonly active: boolean;
+  readonly readOnly: boolean;
+  /** Repo-relative path, or absolute path under the root */
+  allows(filePath: string): boolean;
+}
+
+/** Tools that change project state; refused (and not listed) in read-only mode */
+export const WRITING_TOOL_NAMES: readonly string[] = [
+  'refresh_index',
+  'rollback_index',
+  'remember',
+  'index_remote',
+  'index_dependency',
+  'rate_result'
+];
+
+/** Response fields holding one path, possibly with a `:line` or `:start-end` suffix */
+const PATH_FIELDS = new Set([
+  'file',
+  'filePath',
+  'path',
+  'relativePath',
+  'bestExample',
+  'location'
+]);
+
+/** Response fields holding a list of paths */
+const PATH_LIST_FIELDS = new Set([
+  'files',
+  'paths',
+  'importedBy',
+  'tests',
+  'testedIn',
+  'callers',
+  'definedAt',
+  'consumers',
+  'definedIn',
+  'cycle',
+  'cycles',
+  'changedFiles',
+  'addedFiles',
+  'modifiedFiles',
+  'removedFiles',
+  'ci'
+]);
+
+function splitList(value: string | undefined): string[] {
+  return (value ?? '')
+    .split(',')
+    .map((entry) => entry.trim())
+    .filter(Boolean);
+}
+
+function isTruthy(value: string | undefined): boolean {
+  return ['1', 'true', 'yes'].includes((value ?? '').trim().toLowerCase());
+}
+
+/** CODEBASE_CONTEXT_ALLOW_PATHS, CODEBASE_CONTEXT_DENY_PATHS and CODEBASE_CONTEXT_READ_ONLY */
+export function pathPolicyFromEnv(env: NodeJS.ProcessEnv = process.env): PathPolicyConfig {
+  const allowPaths = splitList(env.CODEBASE_CONTEXT_ALLOW_PATHS);
+  const denyPaths = splitList(env.CODEBASE_CONTEXT_DENY_PATHS);
+  return {
+    ...(allowPaths.length > 0 ? { allowPaths } : {}),
+    ...(denyPaths.length > 0 ? { denyPaths } : {}),
+    ...(isTruthy(env.CODEBASE_CONTEXT_READ_ONLY) ? { readOnly: true } : {})
+  };
+}
+
+/** Combine policy sources; later allowlists replace earlier ones, denylists add up */
+export function mergePathPolicyConfigs(
+  ...configs: Array<PathPolicyConfig | undefined>
+): PathPolicyConfig {
+  const merged: PathPolicyConfig = {};
+  for (const config of configs) {
+    if (!config) continue;
+    if (config.allowPaths?.length) merged.allowPaths = [...config.allowPaths];
+    if (config.denyPaths?.length) {
+      merged.denyPaths = [...(merged.denyPaths ?? []), ...config.denyPaths];
+    }
+    if (config.readOnly) merged.readOnly = true;
+  }
+  return merged;
+}
+
+/** The `security` key of `.codebase-context/config.json`, if any */
+export async function loadProjectPathPolicyConfig(
+  rootPath: string
+): Promise<PathPolicyConfig | undefined> {
+  const configPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME);
+  try {
+    const parsed = JSON.parse(await fs.readFile(configPath, 'utf-8')) as { security?: unknown };
+    const security = parsed.security;
+    if (!security || typeof security !== 'object') return undefined;
+    const { allowPaths, denyPaths, readOnly } = security as Record<string, unknown>;
+    const strings = (value: unknown) =>
+      Array.isArray(value) ? value.filter((v): v is string => typeof v === 'string') : undefined;
+    return {
+      allowPaths: strings(allowPaths),
+      denyPaths: strings(denyPaths),
+      readOnly: readOnly === true
+    };
+  } catch {
+    return undefined;
+  }
+}
+
+/**
+ * Repo-relative posix form of `filePath`, or null when it points outside `rootPath`
+ * (absolute paths elsewhere, `..` escapes).
+ */
+export function toRepoRelative(rootPath: string, filePath: string): string | null {
+  const normalized = filePath.replace(/\\/g, '/');
+  if (path.isAbsolute(normalized) || /^[A-Za-z]:\//.test(normalized)) {
+    const relative = path.relative(rootPath, filePath).replace(/\\/g, '/');
+    if (!relative) return '';
+    return relative.startsWith('../') || relative === '..' || path.isAbsolute(relative)
+      ? null
+      : relative;
+  }
+  const relative = path.posix.normalize(normalized).replace(/^\.\/?/, '');
+  return relative === '..' || relative.startsWith('../') ? null : relative;
+}
+
+function matchesAllowPattern(relativePath: string, pattern: string): boolean {
+  const normalized = pattern.replace(/\\/g, '/').replace(/^\.?\//, '');
+  if (/[*?{]/.test(normalized)) return matchesGlob(relativePath, normalized);
+  const dir = normalized.replace(/\/+$/, '');
+  return relativePath === dir || relativePath.startsWith(`${dir}/`);
+}
+
+export function createPathPolicy(config: PathPolicyConfig, rootPath: string): PathPolicy {
+  const allowPaths = config.allowPaths ?? [];
+  const denyPaths = config.denyPaths ?? [];
+  // Root-level ignore rules: a file inside a denied directory stays denied
+  const denyRules = denyPaths.join('\n');
+  const deny =
+    denyPaths.length > 0
+      ? new IgnoreRules((directory) => (directory === '' ? denyRules : null), new Set())
+      : null;
+  const active = allowPaths.length > 0 || deny !== null;
+
+  return {
+    active,
+    readOnly: config.readOnly === true,
+    allows(filePath) {
+      if (!active) return true;
+      const relative = toRepoRelative(rootPath, filePath);
+      if (relative === null) return false;
+      if (!relative) return true;
+      if (deny?.isIgnored(relative)) return false;
+      return allowPaths.length === 0 || allowPaths.some((p) => matchesAllowPattern(relative, p));
+    }
+  };
+}
+
+/** The project's policy: config file, then environment; compliance `excludePaths` deny too */
+export async function loadPathPolicy(
+  rootPath: string,
+  config?: PathPolicyConfig
+): Promise<PathPolicy> {
+  const merged = mergePathPolicyConfigs(
+    config ?? (await loadProjectPathPolicyConfig(rootPath)),
+    { denyPaths: (await loadProjectComplianceConfig(rootPath))?.excludePaths },
+    pathPolicyFromEnv()
+  );
+  return createPathPolicy(merged, rootPath);
+}
+
+/**
+ * A `path` or file `target` argument the policy refuses, checked before the tool runs (so a
+ * denied file is never read, or sent to the client model for summarizing). Globs are left to
+ * the response filter.
+ */
+export function refusedArgumentPath(
+  args: Record<string, unknown>,
+  policy: PathPolicy
+): string | undefined {
+  for (const key of ['path', 'target']) {
+    const value = args[key];
+    if (typeof value !== 'string' || !value.trim() || /[*?{]/.test(value)) continue;
+    // Without a slash a target may be a symbol (`UserService.save`); the response check decides
+    if (key === 'target' && !value.includes('/')) continue;
+    if (!policy.allows(stripLocation(value.trim()))) return value.trim();
+  }
+  return undefined;
+}
+
+function isFileLike(value: string): boolean {
+  return value.includes('/') || /\.[A-Za-z0-9]+$/.test(value);
+}
+
+function stripLocation(value: string): string {
+  return value.replace(/:\d+(-\d+)?$/, '');
+}
+
+/**
+ * A value shaped like a repo file path (`src/a.ts`, `src/a.ts:10-20`) in a field the lists
+ * above don't name; module specifiers, refs and URLs don't qualify.
+ */
+function looksLikePath(value: unknown): value is string {
+  if (typeof value !== 'string' || /\s/.test(value) || value.includes('://')) return false;
+  if (!value.includes('/')) return false;
+  return /:\d+(-\d+)?$/.test(value) || /\.[A-Za-z][A-Za-z0-9]*$/.test(value);
+}
+
+/**
+ * Drop everything in a tool payload that points at a path the policy refuses: list entries
+ * (paths, or objects whose path field is refused), path fields and path-keyed records. Any
+ * other field or list entry whose value looks like a file path or location is checked too, so
+ * tools don't have to register their path fields here. Free text such as summaries is left
+ * alone. `refused` is set when the payload itself is about a refused path (a top-level
+ * `file`, `path` or path-like `target`).
+ */
+export function filterPayloadPaths(
+  payload: unknown,
+  policy: PathPolicy
+): { payload: unknown; withheld: number; refused?: string } {
+  let withheld = 0;
+  const refusedPath = (value: unknown): boolean =>
+    typeof value === 'string' && value !== '' && !policy.allows(stripLocation(value));
+  const refusedObject = (value: Record<string, unknown>): string | undefined => {
+    for (const [key, field] of Object.entries(value)) {
+      if (PATH_FIELDS.has(key) && refusedPath(field)) return field as string;
+      // `target` is a file or a symbol name (`UserService.save`)
+      const fileTarget =
+        key === 'target' &&
+        typeof field === 'string' &&
+        value.kind !== 'symbol' &&
+        isFileLike(field);
+      if (fileTarget && refusedPath(field)) return field;
+    }
+    return undefined;
+  };
+  // List entries also go when any field holds a refused path-like value (`from`, `to`, ...)
+  const refusedEntry = (value: Record<string, unknown>): boolean =>
+    refusedObject(value) !== undefined ||
+    Object.values(value).some((field) => looksLikePath(field) && refusedPath(field));
+
+  const visit = (value: unknown, key: string): unknown => {
+    if (Array.isArray(value)) {
+      const kept: unknown[] = [];
+      for (const item of value) {
+        const isRecord = item !== null && typeof item === 'object' && !Array.isArray(item);
+        const drop =
+          (typeof item === 'string' &&
+            (PATH_LIST_FIELDS.has(key) || looksLikePath(item)) &&
+            refusedPath(item)) ||
+          (isRecord && refusedEntry(item as Record<string, unknown>));
+        if (drop) {
+          withheld++;
+          continue;
+        }
+        kept.push(visit(item, key));
+      }
+      return kept;
+    }
+    if (value !== null && typeof value === 'object') {
+      const kept: Record<string, unknown> = {};
+      for (const [field, child] of Object.entries(value)) {
+        const pathKeyed = field.includes('/') && refusedPath(field);
+        const pathField = PATH_FIELDS.has(field) || looksLikePath(child);
+        if (pathKeyed || (pathField && refusedPath(child))) {
+          withheld++;
+          continue;
+        }
+        kept[field] = visit(child, field);
+      }
+      return kept;
+    }
+    return value;
+  };
+
+  if (payload !== null && typeof payload === 'object' && !Array.isArray(payload)) {
+    const refused = refusedObject(payload as Record<string, unknown>);
+    if (refused !== undefined) return { payload: null, withheld: 0, refused };
+  }
+  return { payload: visit(payload, ''), withheld };
+}
@@ -0,0 +1,74 @@
+/**
+ * Bounded worker pool for indexing stages: items are worked on concurrently but handed to the
+ * consumer strictly in input order, so whatever the consumer builds (graphs, stats, chunk
+ * order) is the same at any concurrency. The number of finished-but-unconsumed results is
+ * capped, which keeps a fast stage from buffering a whole repository behind one slow file.
+ */
+
+export interface OrderedPoolOptions {
+  /** Items worked on at once (at least 1) */
+  concurrency: number;
+  /** Items started ahead of the consumer, running or finished; defaults to 4x concurrency */
+  queueSize?: number;
+}
+
+export interface OrderedResult<T, R> {
+  item: T;
+  index: number;
+  result: R;
+}
+
+/**
+ * Run `work` over `items` with at most `concurrency` in flight, yielding results in input
+ * order. A rejection from `work` is thrown when its turn comes; leaving the loop early (break
+ * or throw) stops new work from starting.
+ */
+export async function* mapOrdered<T, R>(
+  items: readonly T[],
+  work: (item: T, index: number) => Promise<R>,
+  options: OrderedPoolOptions
+): AsyncGenerator<OrderedResult<T, R>> {
+  const concurrency = Math.max(1, Math.floor(options.concurrency) || 1);
+  const queueSize = Math.max(concurrency, Math.floor(options.queueSize ?? concurrency * 4));
+  const tasks = new Map<number, Promise<R>>();
+  let started = 0;
+  let running = 0;
+  let consumed = 0;
+  let stopped = false;
+
+  const fill = (): void => {
+    while (
+      !stopped &&
+      started < items.length &&
+      running < concurrency &&
+      started - consumed < queueSize
+    ) {
+      const index = started++;
+      running++;
+      const task = Promise.resolve()
+        .then(() => work(items[index], index))
+        .finally(() => {
+          running--;
+          fill();
+        });
+      // Rejections are rethrown in order when the item is yielded
+      task.catch(() => undefined);
+      tasks.set(index, task);
+    }
+  };
+
+  try {
+    fill();
+    while (consumed < items.length) {
+      const task = tasks.get(consumed);
+      if (!task) throw new Error(`Pipeline item ${consumed} was never started`);
+      const result = await task;
+      tasks.delete(consumed);
+      yield { item: items[consumed], index: consumed, result };
+      consumed++;
+      fill();
+    }
+  } finally {
+    stopped = true;
+  }
+}
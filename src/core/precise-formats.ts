@@ -0,0 +1,601 @@
+/**
+ * Readers for precise code-intelligence indexes written by compiler-backed indexers: SCIP
+ * (protobuf; scip-typescript, scip-go, scip-java, ...) and LSIF (JSON lines; lsif-tsc and
+ * older tools). Both are reduced to symbols with 1-based definition and reference lines and
+ * hover text. Only the fields the navigation tools use are decoded; document-local symbols
+ * are dropped.
+ */
+
+import path from 'path';
+import { promises as fs } from 'fs';
+import { fileURLToPath } from 'url';
+
+export interface ImportedLocation {
+  /** Repo-relative posix path */
+  file: string;
+  /** 1-based */
+  line: number;
+  /** Last line of the enclosing declaration, when the index records it */
+  endLine?: number;
+}
+
+export interface ImportedSymbol {
+  /** SCIP symbol string or LSIF moniker / result set id */
+  id: string;
+  name: string;
+  qualifiedName?: string;
+  kind?: string;
+  /** Hover text: signature and doc comment */
+  documentation?: string;
+  definitions: ImportedLocation[];
+  references: ImportedLocation[];
+}
+
+export interface ImportedIndex {
+  format: 'scip' | 'lsif';
+  /** Indexer that wrote the file, e.g. `scip-typescript 0.3.14` */
+  tool?: string;
+  /** Files the index has documents for */
+  files: string[];
+  symbols: ImportedSymbol[];
+}
+
+/** Keeps hover text inside the tool response budget */
+const MAX_DOCUMENTATION_CHARS = 1200;
+
+function clipDocumentation(parts: string[]): string | undefined {
+  const text = parts
+    .map((part) => part.trim())
+    .filter(Boolean)
+    .join('\n\n');
+  if (!text) return undefined;
+  return text.length > MAX_DOCUMENTATION_CHARS
+    ? `${text.slice(0, MAX_DOCUMENTATION_CHARS)}…`
+    : text;
+}
+
+function pushUnique(list: ImportedLocation[], location: ImportedLocation): void {
+  if (!list.some((l) => l.file === location.file && l.line === location.line)) list.push(location);
+}
+
+// --- SCIP -------------------------------------------------------------------------------
+
+const utf8 = new TextDecoder();
+
+/** Minimal protobuf wire-format reader: varints, length-delimited fields, skipping the rest */
+class ProtoReader {
+  private pos = 0;
+
+  constructor(private readonly buf: Uint8Array) {}
+
+  done(): boolean {
+    return this.pos >= this.buf.length;
+  }
+
+  varint(): number {
+    let result = 0;
+    let scale = 1;
+    for (;;) {
+      if (this.pos >= this.buf.length) throw new Error('Truncated protobuf varint');
+      const byte = this.buf[this.pos++];
+      result += (byte & 0x7f) * scale;
+      if ((byte & 0x80) === 0) return result;
+      scale *= 128;
+    }
+  }
+
+  bytes(): Uint8Array {
+    const length = this.varint();
+    const start = this.pos;
+    this.pos += length;
+    if (this.pos > this.buf.length) throw new Error('Truncated protobuf field');
+    return this.buf.subarray(start, this.pos);
+  }
+
+  string(): string {
+    return utf8.decode(this.bytes());
+  }
+
+  /** Repeated int32, packed or not */
+  ints(wireType: number, into: number[]): void {
+    if (wireType !== 2) {
+      into.push(this.varint());
+      return;
+    }
+    const packed = new ProtoReader(this.bytes());
+    while (!packed.done()) into.push(packed.varint());
+  }
+
+  skip(wireType: number): void {
+    if (wireType === 0) this.varint();
+    else if (wireType === 1) this.pos += 8;
+    else if (wireType === 2) this.bytes();
+    else if (wireType === 5) this.pos += 4;
+    else throw new Error(`Unsupported protobuf wire type ${wireType}`);
+  }
+}
+
+/** Calls `onField` for each field; fields it returns false for are skipped */
+function readMessage(
+  buf: Uint8Array,
+  onField: (field: number, wireType: number, reader: ProtoReader) => boolean
+): void {
+  const reader = new ProtoReader(buf);
+  while (!reader.done()) {
+    const key = reader.varint();
+    const field = Math.floor(key / 8);
+    const wireType = key % 8;
+    if (!onField(field, wireType, reader)) reader.skip(wireType);
+  }
+}
+
+interface ScipOccurrence {
+  range: number[];
+  symbol: string;
+  roles: number;
+  enclosingRange: number[];
+}
+
+interface ScipSymbolInformation {
+  symbol: string;
+  documentation: string[];
+  displayName?: string;
+}
+
+interface ScipDocument {
+  relativePath: string;
+  occurrences: ScipOccurrence[];
+  symbols: ScipSymbolInformation[];
+}
+
+const SCIP_ROLE_DEFINITION = 0x1;
+
+function readScipOccurrence(buf: Uint8Array): ScipOccurrence {
+  const occurrence: ScipOccurrence = { range: [], symbol: '', roles: 0, enclosingRange: [] };
+  readMessage(buf, (field, wireType, reader) => {
+    if (field === 1) reader.ints(wireType, occurrence.range);
+    else if (field === 2) occurrence.symbol = reader.string();
+    else if (field === 3) occurrence.roles = reader.varint();
+    else if (field === 7) reader.ints(wireType, occurrence.enclosingRange);
+    else return false;
+    return true;
+  });
+  return occurrence;
+}
+
+function readScipSymbolInformation(buf: Uint8Array): ScipSymbolInformation {
+  const info: ScipSymbolInformation = { symbol: '', documentation: [] };
+  readMessage(buf, (field, _wireType, reader) => {
+    if (field === 1) info.symbol = reader.string();
+    else if (field === 3) info.documentation.push(reader.string());
+    else if (field === 6) info.displayName = reader.string();
+    else return false;
+    return true;
+  });
+  return info;
+}
+
+function readScipDocument(buf: Uint8Array): ScipDocument {
+  const doc: ScipDocument = { relativePath: '', occurrences: [], symbols: [] };
+  readMessage(buf, (field, _wireType, reader) => {
+    if (field === 1) doc.relativePath = reader.string();
+    else if (field === 2) doc.occurrences.push(readScipOccurrence(reader.bytes()));
+    else if (field === 3) doc.symbols.push(readScipSymbolInformation(reader.bytes()));
+    else return false;
+    return true;
+  });
+  return doc;
+}
+
+type DescriptorSuffix =
+  | 'namespace'
+  | 'type'
+  | 'term'
+  | 'method'
+  | 'parameter'
+  | 'typeParameter'
+  | 'meta'
+  | 'macro';
+
+interface ScipDescriptor {
+  name: string;
+  suffix: DescriptorSuffix;
+}
+
+const DESCRIPTOR_SUFFIXES: Record<string, DescriptorSuffix> = {
+  '/': 'namespace',
+  '#': 'type',
+  '.': 'term',
+  ':': 'meta',
+  '!': 'macro'
+};
+
+/**
+ * Descriptors of a SCIP symbol (`scip-typescript npm pkg 1.0.0 src/`users.ts`/Users#save().`):
+ * the part after scheme, manager, package name and version, in which doubled spaces escape
+ * spaces.
+ */
+export function parseScipDescriptors(symbol: string): ScipDescriptor[] {
+  let i = 0;
+  for (let fields = 0; fields < 4 && i < symbol.length; i++) {
+    if (symbol[i] !== ' ') continue;
+    if (symbol[i + 1] === ' ') i++;
+    else fields++;
+  }
+  const text = symbol.slice(i);
+  let pos = 0;
+  const readName = (): string => {
+    if (text[pos] !== '`') {
+      const start = pos;
+      while (pos < text.length && /[\w+\-$]/.test(text[pos])) pos++;
+      return text.slice(start, pos);
+    }
+    let name = '';
+    for (pos++; pos < text.length; pos++) {
+      if (text[pos] !== '`') name += text[pos];
+      else if (text[pos + 1] === '`') name += text[pos++];
+      else break;
+    }
+    pos++;
+    return name;
+  };
+
+  const descriptors: ScipDescriptor[] = [];
+  while (pos < text.length) {
+    const open = text[pos];
+    if (open === '(' || open === '[') {
+      pos++;
+      const name = readName();
+      pos++;
+      descriptors.push({ name, suffix: open === '(' ? 'parameter' : 'typeParameter' });
+      continue;
+    }
+    const name = readName();
+    const suffix = text[pos++];
+    if (suffix === '(') {
+      // Method with an optional disambiguator: `save().` or `save(+1).`
+      while (pos < text.length && text[pos] !== ')') pos++;
+      pos += 2;
+      descriptors.push({ name, suffix: 'method' });
+    } else if (suffix !== undefined && DESCRIPTOR_SUFFIXES[suffix]) {
+      descriptors.push({ name, suffix: DESCRIPTOR_SUFFIXES[suffix] });
+    }
+  }
+  return descriptors;
+}
+
+/** Descriptors that name a symbol (not a namespace, parameter or meta entry), and its kind */
+const SCIP_KINDS: Partial<Record<DescriptorSuffix, string>> = {
+  type: 'type',
+  term: 'value',
+  method: 'method',
+  macro: 'macro'
+};
+
+function describeScipSymbol(
+  symbol: string
+): Pick<ImportedSymbol, 'name' | 'qualifiedName' | 'kind'> {
+  const named = parseScipDescriptors(symbol).filter((d) => d.name && SCIP_KINDS[d.suffix]);
+  const last = named[named.length - 1];
+  if (!last) return { name: symbol };
+  return {
+    name: last.name,
+    ...(named.length > 1 ? { qualifiedName: named.map((d) => d.name).join('.') } : {}),
+    kind: SCIP_KINDS[last.suffix]
+  };
+}
+
+/** Repo-relative prefix for paths relative to the index's project root */
+function projectRootPrefix(projectRoot: string | undefined, rootPath: string): string {
+  if (!projectRoot?.startsWith('file:')) return '';
+  try {
+    const relative = path.relative(rootPath, fileURLToPath(projectRoot)).replace(/\\/g, '/');
+    const inside = relative && !relative.startsWith('..') && !path.isAbsolute(relative);
+    return inside ? `${relative}/` : '';
+  } catch {
+    return '';
+  }
+}
+
+export function readScipIndex(
+  buf: Uint8Array,
+  rootPath: string,
+  allows: (file: string) => boolean = () => true
+): ImportedIndex {
+  let tool: string | undefined;
+  let projectRoot: string | undefined;
+  const documents: ScipDocument[] = [];
+  const external: ScipSymbolInformation[] = [];
+
+  readMessage(buf, (field, _wireType, reader) => {
+    if (field === 1) {
+      readMessage(reader.bytes(), (metaField, _metaWire, meta) => {
+        if (metaField === 2) {
+          const parts: string[] = [];
+          readMessage(meta.bytes(), (toolField, _toolWire, toolReader) => {
+            if (toolField !== 1 && toolField !== 2) return false;
+            parts.push(toolReader.string());
+            return true;
+          });
+          tool = parts.join(' ') || undefined;
+        } else if (metaField === 3) projectRoot = meta.string();
+        else return false;
+        return true;
+      });
+    } else if (field === 2) documents.push(readScipDocument(reader.bytes()));
+    else if (field === 3) external.push(readScipSymbolInformation(reader.bytes()));
+    else return false;
+    return true;
+  });
+
+  const prefix = projectRootPrefix(projectRoot, rootPath);
+  const symbols = new Map<string, ImportedSymbol>();
+  const entry = (id: string): ImportedSymbol => {
+    let symbol = symbols.get(id);
+    if (!symbol) {
+      symbol = { id, ...describeScipSymbol(id), definitions: [], references: [] };
+      symbols.set(id, symbol);
+    }
+    return symbol;
+  };
+  const applyInformation = (info: ScipSymbolInformation) => {
+    if (!info.symbol || info.symbol.startsWith('local ')) return;
+    const symbol = entry(info.symbol);
+    if (info.displayName) symbol.name = info.displayName;
+    symbol.documentation ??= clipDocumentation(info.documentation);
+  };
+
+  const files: string[] = [];
+  for (const doc of documents) {
+    const file = `${prefix}${doc.relativePath}`.replace(/\\/g, '/');
+    if (!doc.relativePath || !allows(file)) continue;
+    files.push(file);
+    doc.symbols.forEach(applyInformation);
+    for (const occurrence of doc.occurrences) {
+      if (!occurrence.symbol || occurrence.symbol.startsWith('local ')) continue;
+      if (occurrence.range.length < 3) continue;
+      const line = occurrence.range[0] + 1;
+      const symbol = entry(occurrence.symbol);
+      if (occurrence.roles & SCIP_ROLE_DEFINITION) {
+        const enclosing = occurrence.enclosingRange;
+        const endLine = enclosing.length === 4 ? enclosing[2] + 1 : undefined;
+        pushUnique(symbol.definitions, { file, line, ...(endLine ? { endLine } : {}) });
+      } else {
+        pushUnique(symbol.references, { file, line });
+      }
+    }
+  }
+  external.forEach(applyInformation);
+
+  return {
+    format: 'scip',
+    ...(tool ? { tool } : {}),
+    files,
+    symbols: [...symbols.values()].filter((s) => s.definitions.length + s.references.length > 0)
+  };
+}
+
+// --- LSIF -------------------------------------------------------------------------------
+
+type LsifId = string | number;
+
+interface LsifElement {
+  id: LsifId;
+  type: 'vertex' | 'edge';
+  label: string;
+  [key: string]: unknown;
+}
+
+interface LsifRange {
+  line: number;
+  character: number;
+  endLine: number;
+  endCharacter: number;
+}
+
+interface LsifPosition {
+  line?: number;
+  character?: number;
+}
+
+function parseLsifElements(text: string): LsifElement[] {
+  const trimmed = text.trimStart();
+  if (trimmed.startsWith('[')) return JSON.parse(trimmed) as LsifElement[];
+  return text
+    .split('\n')
+    .filter((line) => line.trim())
+    .map((line) => JSON.parse(line) as LsifElement);
+}
+
+/** Hover `contents`: a string, a MarkupContent / MarkedString, or a list of them */
+function hoverText(contents: unknown): string[] {
+  if (typeof contents === 'string') return [contents];
+  if (Array.isArray(contents)) return contents.flatMap(hoverText);
+  if (contents && typeof contents === 'object') {
+    const { value, language } = contents as { value?: unknown; language?: unknown };
+    if (typeof value !== 'string') return [];
+    return [typeof language === 'string' ? `\`\`\`${language}\n${value}\n\`\`\`` : value];
+  }
+  return [];
+}
+
+export async function readLsifIndex(
+  text: string,
+  rootPath: string,
+  allows: (file: string) => boolean = () => true
+): Promise<ImportedIndex> {
+  const key = (id: unknown) => String(id);
+  const documents = new Map<string, string>();
+  const ranges = new Map<string, LsifRange>();
+  const rangeFile = new Map<string, string>();
+  const next = new Map<string, string>();
+  const definitionResult = new Map<string, string>();
+  const referenceResult = new Map<string, string>();
+  const hoverResult = new Map<string, string>();
+  const monikerOf = new Map<string, string>();
+  const monikers = new Map<string, { identifier: string; kind?: string }>();
+  const hovers = new Map<string, string[]>();
+  const items = new Map<string, Array<{ ranges: string[]; property?: string }>>();
+  let projectRoot: string | undefined;
+  let tool: string | undefined;
+
+  const toFile = (uri: unknown): string | null => {
+    if (typeof uri !== 'string' || !uri.startsWith('file:')) return null;
+    try {
+      const base = projectRoot?.startsWith('file:') ? fileURLToPath(projectRoot) : rootPath;
+      const relative = path.relative(base, fileURLToPath(uri)).replace(/\\/g, '/');
+      const file = `${projectRootPrefix(projectRoot, rootPath)}${relative}`;
+      return relative && !relative.startsWith('..') && !path.isAbsolute(relative) ? file : null;
+    } catch {
+      return null;
+    }
+  };
+
+  for (const element of parseLsifElements(text)) {
+    const id = key(element.id);
+    if (element.type === 'vertex') {
+      if (element.label === 'metaData') {
+        projectRoot = typeof element.projectRoot === 'string' ? element.projectRoot : undefined;
+        const info = element.toolInfo as { name?: unknown; version?: unknown } | undefined;
+        if (typeof info?.name === 'string') {
+          tool = [info.name, info.version].filter((p) => typeof p === 'string').join(' ');
+        }
+      } else if (element.label === 'document') {
+        const file = toFile(element.uri);
+        if (file) documents.set(id, file);
+      } else if (element.label === 'range') {
+        const start = (element.start ?? {}) as LsifPosition;
+        const end = (element.end ?? {}) as LsifPosition;
+        ranges.set(id, {
+          line: start.line ?? 0,
+          character: start.character ?? 0,
+          endLine: end.line ?? start.line ?? 0,
+          endCharacter: end.character ?? 0
+        });
+      } else if (element.label === 'hoverResult') {
+        hovers.set(id, hoverText((element.result as { contents?: unknown })?.contents));
+      } else if (element.label === 'moniker' && typeof element.identifier === 'string') {
+        monikers.set(id, {
+          identifier: element.identifier,
+          ...(typeof element.kind === 'string' ? { kind: element.kind } : {})
+        });
+      }
+      continue;
+    }
+
+    const outV = key(element.outV);
+    const inVs = Array.isArray(element.inVs) ? element.inVs.map(key) : [key(element.inV)];
+    if (element.label === 'contains') {
+      for (const inV of inVs) rangeFile.set(inV, outV);
+    } else if (element.label === 'next') next.set(outV, inVs[0]);
+    else if (element.label === 'textDocument/definition') definitionResult.set(outV, inVs[0]);
+    else if (element.label === 'textDocument/references') referenceResult.set(outV, inVs[0]);
+    else if (element.label === 'textDocument/hover') hoverResult.set(outV, inVs[0]);
+    else if (element.label === 'moniker') monikerOf.set(outV, inVs[0]);
+    else if (element.label === 'item') {
+      const list = items.get(outV) ?? [];
+      list.push({
+        ranges: inVs,
+        ...(typeof element.property === 'string' ? { property: element.property } : {})
+      });
+      items.set(outV, list);
+    }
+  }
+
+  // A range's symbol is the end of its `next` chain (its result set)
+  const resultSetOf = (rangeId: string): string => {
+    let current = rangeId;
+    for (let hops = 0; next.has(current) && hops < 32; hops++) current = next.get(current)!;
+    return current;
+  };
+  // Through the chain, the first vertex carrying the edge
+  const lookup = (rangeId: string, edges: Map<string, string>): string | undefined => {
+    let current: string | undefined = rangeId;
+    for (let hops = 0; current !== undefined && hops < 32; hops++) {
+      if (edges.has(current)) return edges.get(current);
+      current = next.get(current);
+    }
+    return undefined;
+  };
+
+  const files = [...new Set(documents.values())].filter(allows);
+  const covered = new Set(files);
+  const locate = (rangeId: string): ImportedLocation | null => {
+    const range = ranges.get(rangeId);
+    const file = documents.get(rangeFile.get(rangeId) ?? '');
+    if (!range || !file || !covered.has(file)) return null;
+    return { file, line: range.line + 1 };
+  };
+
+  const lineCache = new Map<string, string[] | null>();
+  const rangeText = async (rangeId: string): Promise<string | undefined> => {
+    const range = ranges.get(rangeId);
+    const file = documents.get(rangeFile.get(rangeId) ?? '');
+    if (!range || !file || range.endLine !== range.line) return undefined;
+    if (!lineCache.has(file)) {
+      const content = await fs.readFile(path.join(rootPath, file), 'utf-8').catch(() => null);
+      lineCache.set(file, content === null ? null : content.split(/\r?\n/));
+    }
+    const text = lineCache.get(file)?.[range.line]?.slice(range.character, range.endCharacter);
+    return text && /^[\w$]+$/.test(text) ? text : undefined;
+  };
+
+  const symbols = new Map<string, ImportedSymbol>();
+  for (const rangeId of ranges.keys()) {
+    if (!locate(rangeId)) continue;
+    const resultSet = resultSetOf(rangeId);
+    if (symbols.has(resultSet)) continue;
+    const moniker = monikers.get(lookup(rangeId, monikerOf) ?? '');
+    if (moniker?.kind === 'local') continue;
+
+    const definitionRanges: string[] = [];
+    const referenceRanges: string[] = [];
+    const definitions = lookup(rangeId, definitionResult);
+    for (const item of items.get(definitions ?? '') ?? []) definitionRanges.push(...item.ranges);
+    const references = lookup(rangeId, referenceResult);
+    for (const item of items.get(references ?? '') ?? []) {
+      if (item.property === 'definitions') definitionRanges.push(...item.ranges);
+      else if (item.property !== 'referenceResults') referenceRanges.push(...item.ranges);
+    }
+    if (definitionRanges.length + referenceRanges.length === 0) continue;
+
+    // Monikers look like `src/users:Users.save`; the source text gives the plain name
+    const qualified = moniker?.identifier.split(':').pop();
+    let name: string | undefined;
+    for (const candidate of [...definitionRanges, ...referenceRanges, rangeId]) {
+      name = await rangeText(candidate);
+      if (name) break;
+    }
+    name ??= qualified?.split('.').pop();
+    if (!name) continue;
+
+    const symbol: ImportedSymbol = {
+      id: moniker ? moniker.identifier : `lsif:${resultSet}`,
+      name,
+      ...(qualified && qualified.includes('.') ? { qualifiedName: qualified } : {}),
+      documentation: clipDocumentation(hovers.get(lookup(rangeId, hoverResult) ?? '') ?? []),
+      definitions: [],
+      references: []
+    };
+    for (const id of definitionRanges) {
+      const location = locate(id);
+      if (location) pushUnique(symbol.definitions, location);
+    }
+    for (const id of referenceRanges) {
+      const location = locate(id);
+      const isDefinition = symbol.definitions.some(
+        (d) => d.file === location?.file && d.line === location.line
+      );
+      if (location && !isDefinition) pushUnique(symbol.references, location);
+    }
+    if (!symbol.documentation) delete symbol.documentation;
+    symbols.set(resultSet, symbol);
+  }
+
+  return {
+    format: 'lsif',
+    ...(tool ? { tool } : {}),
+    files,
+    symbols: [...symbols.values()]
+  };
+}
@@ -0,0 +1,194 @@
+/**
+ * Precise definitions, references and hover text from a SCIP or LSIF index, typically built
+ * in CI by scip-typescript, scip-go or lsif-tsc. The source is `index.scip` or `dump.lsif` in
+ * the repo root, or the file named by `preciseIndex` in `.codebase-context/config.json` or
+ * CODEBASE_CONTEXT_PRECISE_INDEX. It is imported into `.codebase-context/precise-index.json`
+ * on first use and again whenever it changes.
+ *
+ * Files the index has no document for, and files edited after it was generated, count as
+ * uncovered: navigation falls back to the tree-sitter symbol table and reference scan there.
+ */
+
+import path from 'path';
+import { promises as fs } from 'fs';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  PRECISE_INDEX_FILENAME,
+  PROJECT_CONFIG_FILENAME
+} from '../constants/codebase-context.js';
+import { loadPathPolicy } from './path-policy.js';
+import {
+  readLsifIndex,
+  readScipIndex,
+  type ImportedIndex,
+  type ImportedSymbol
+} from './precise-formats.js';
+
+export type { ImportedLocation, ImportedSymbol } from './precise-formats.js';
+
+const PRECISE_INDEX_VERSION = 1;
+
+/** Looked for in the repo root when nothing is configured (the indexers' default outputs) */
+const DEFAULT_SOURCES = ['index.scip', 'dump.lsif'];
+
+export interface PreciseIndex extends ImportedIndex {
+  version: number;
+  /** Imported file, repo-relative when inside the repo */
+  source: string;
+  /** Source mtime; files modified after it are treated as uncovered */
+  sourceMtimeMs: number;
+  importedAt: string;
+}
+
+async function configuredSource(rootPath: string): Promise<string | undefined> {
+  const fromEnv = process.env.CODEBASE_CONTEXT_PRECISE_INDEX?.trim();
+  if (fromEnv) return fromEnv;
+  const configPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME);
+  try {
+    const parsed = JSON.parse(await fs.readFile(configPath, 'utf-8')) as { preciseIndex?: unknown };
+    return typeof parsed.preciseIndex === 'string' && parsed.preciseIndex.trim()
+      ? parsed.preciseIndex.trim()
+      : undefined;
+  } catch {
+    return undefined;
+  }
+}
+
+/** Absolute path and mtime of the SCIP/LSIF file to use, or null when there is none */
+export async function resolvePreciseIndexSource(
+  rootPath: string
+): Promise<{ path: string; mtimeMs: number } | null> {
+  const configured = await configuredSource(rootPath);
+  for (const candidate of configured ? [configured] : DEFAULT_SOURCES) {
+    const absolute = path.resolve(rootPath, candidate);
+    try {
+      const stat = await fs.stat(absolute);
+      if (stat.isFile()) return { path: absolute, mtimeMs: stat.mtimeMs };
+    } catch {
+      // Not there; a configured path that is missing leaves precise navigation off
+    }
+  }
+  return null;
+}
+
+async function isScip(filePath: string): Promise<boolean> {
+  const extension = path.extname(filePath).toLowerCase();
+  if (extension === '.scip') return true;
+  if (['.lsif', '.json', '.jsonl'].includes(extension)) return false;
+  // Unknown extension: LSIF is JSON, SCIP is binary
+  const handle = await fs.open(filePath, 'r');
+  try {
+    const { buffer, bytesRead } = await handle.read(Buffer.alloc(64), 0, 64, 0);
+    const head = buffer.subarray(0, bytesRead).toString('utf-8').trimStart();
+    return !(head.startsWith('{') || head.startsWith('['));
+  } finally {
+    await handle.close();
+  }
+}
+
+/** Parse a SCIP or LSIF file, keeping only documents the path policy allows */
+export async function importPreciseIndex(
+  rootPath: string,
+  sourcePath: string
+): Promise<PreciseIndex> {
+  const policy = await loadPathPolicy(rootPath);
+  const allows = (file: string) => policy.allows(file);
+  const stat = await fs.stat(sourcePath);
+  const imported = (await isScip(sourcePath))
+    ? readScipIndex(await fs.readFile(sourcePath), rootPath, allows)
+    : await readLsifIndex(await fs.readFile(sourcePath, 'utf-8'), rootPath, allows);
+  const relative = path.relative(rootPath, sourcePath).replace(/\\/g, '/');
+  return {
+    version: PRECISE_INDEX_VERSION,
+    ...imported,
+    source: relative.startsWith('..') || path.isAbsolute(relative) ? sourcePath : relative,
+    sourceMtimeMs: stat.mtimeMs,
+    importedAt: new Date().toISOString()
+  };
+}
+
+function isPreciseIndex(value: unknown): value is PreciseIndex {
+  const index = value as PreciseIndex | null;
+  return (
+    !!index &&
+    index.version === PRECISE_INDEX_VERSION &&
+    Array.isArray(index.files) &&
+    Array.isArray(index.symbols) &&
+    typeof index.sourceMtimeMs === 'number'
+  );
+}
+
+/**
+ * The imported index for the project, re-imported when the source file changed. Returns null
+ * without a source or when it can't be parsed (navigation then stays heuristic).
+ */
+export async function loadPreciseIndex(
+  rootPath: string,
+  options: { persist?: boolean } = {}
+): Promise<PreciseIndex | null> {
+  const source = await resolvePreciseIndexSource(rootPath);
+  if (!source) return null;
+
+  const cachePath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, PRECISE_INDEX_FILENAME);
+  try {
+    const cached: unknown = JSON.parse(await fs.readFile(cachePath, 'utf-8'));
+    if (isPreciseIndex(cached) && cached.sourceMtimeMs === source.mtimeMs) return cached;
+  } catch {
+    // No import yet
+  }
+
+  let index: PreciseIndex;
+  try {
+    index = await importPreciseIndex(rootPath, source.path);
+  } catch (error) {
+    const message = error instanceof Error ? error.message : String(error);
+    console.error(`[precise-index] Could not import ${source.path}: ${message}`);
+    return null;
+  }
+  if (options.persist !== false) {
+    await fs.mkdir(path.dirname(cachePath), { recursive: true });
+    await fs.writeFile(cachePath, JSON.stringify(index));
+  }
+  return index;
+}
+
+/**
+ * Whether the index can be trusted for a file: it has a document for it and the file hasn't
+ * been modified since the index was generated.
+ */
+export function createCoverageCheck(
+  rootPath: string,
+  index: PreciseIndex
+): (file: string) => Promise<boolean> {
+  const files = new Set(index.files);
+  const cache = new Map<string, boolean>();
+  return async (file) => {
+    if (!files.has(file)) return false;
+    const known = cache.get(file);
+    if (known !== undefined) return known;
+    const stat = await fs.stat(path.join(rootPath, file)).catch(() => null);
+    const covered = !!stat && stat.mtimeMs <= index.sourceMtimeMs;
+    cache.set(file, covered);
+    return covered;
+  };
+}
+
+/**
+ * Symbols named exactly `symbol`, with the same rules as the tree-sitter lookup: qualified
+ * queries match qualified names (or their suffix), and case-insensitive matches are used only
+ * when nothing matches case-sensitively.
+ */
+export function matchPreciseSymbols(index: PreciseIndex, symbol: string): ImportedSymbol[] {
+  const query = symbol.trim();
+  const qualified = query.includes('.') || query.includes('::');
+  const dotted = query.replace(/::/g, '.');
+  const target = (s: ImportedSymbol) => (qualified ? (s.qualifiedName ?? s.name) : s.name);
+
+  const exact = index.symbols.filter((s) => {
+    const name = target(s);
+    return name === query || (qualified && (name === dotted || name.endsWith(`.${dotted}`)));
+  });
+  return exact.length > 0
+    ? exact
+    : index.symbols.filter((s) => target(s).toLowerCase() === query.toLowerCase());
+}
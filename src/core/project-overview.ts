@@ -0,0 +1,490 @@
+/**
+ * Project overview for `describe_project`: how a repository is built and run, read from its
+ * manifests and service definitions rather than from the code index. Build systems come
+ * from marker files (package.json, Cargo.toml, go.mod, pom.xml, Makefile, ...), entry points
+ * from manifest fields (`main`/`bin`, `[[bin]]`, `[project.scripts]`) and conventional
+ * files (`main.go` in `package main`, `src/main.rs`, `__main__.py`, `Program.cs`), and
+ * services from Dockerfiles, compose files and Procfiles.
+ *
+ * Files are looked for a few directories deep, outside dependency and build-output
+ * directories. Every list is capped so the overview stays small.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import { glob } from 'glob';
+import { ALWAYS_SKIPPED_DIRS } from '../utils/ignore-rules.js';
+
+/** Directory levels below the root that are searched */
+const MAX_DEPTH = 4;
+const MAX_ITEMS = 15;
+const MAX_FILES_PER_SYSTEM = 5;
+const MAX_COMMAND_LENGTH = 100;
+
+const SCAN_IGNORE = [
+  ...[...ALWAYS_SKIPPED_DIRS].map((dir) => `**/${dir}/**`),
+  '**/dist/**',
+  '**/build/**',
+  '**/target/**',
+  '**/out/**',
+  'bazel-*/**'
+];
+
+/** Marker file -> build system; `*` matches like a glob */
+const BUILD_MARKERS: Array<[string, string]> = [
+  ['package.json', 'npm'],
+  ['deno.json', 'deno'],
+  ['deno.jsonc', 'deno'],
+  ['Cargo.toml', 'cargo'],
+  ['go.mod', 'go'],
+  ['pom.xml', 'maven'],
+  ['build.gradle', 'gradle'],
+  ['build.gradle.kts', 'gradle'],
+  ['build.sbt', 'sbt'],
+  ['pyproject.toml', 'python'],
+  ['setup.py', 'python'],
+  ['requirements.txt', 'pip'],
+  ['Pipfile', 'pipenv'],
+  ['Gemfile', 'bundler'],
+  ['composer.json', 'composer'],
+  ['mix.exs', 'mix'],
+  ['Package.swift', 'swiftpm'],
+  ['pubspec.yaml', 'pub'],
+  ['*.sln', 'dotnet'],
+  ['*.slnx', 'dotnet'],
+  ['*.csproj', 'dotnet'],
+  ['*.fsproj', 'dotnet'],
+  ['CMakeLists.txt', 'cmake'],
+  ['meson.build', 'meson'],
+  ['Makefile', 'make'],
+  ['makefile', 'make'],
+  ['GNUmakefile', 'make'],
+  ['MODULE.bazel', 'bazel'],
+  ['WORKSPACE', 'bazel'],
+  ['WORKSPACE.bazel', 'bazel'],
+  ['.buckconfig', 'buck'],
+  ['BUCK', 'buck']
+];
+
+/** Lockfile next to a package.json -> the package manager that wrote it */
+const NPM_LOCKFILES: Array<[string, string]> = [
+  ['pnpm-lock.yaml', 'pnpm'],
+  ['yarn.lock', 'yarn'],
+  ['bun.lockb', 'bun'],
+  ['bun.lock', 'bun']
+];
+
+const CI_FILES = [
+  '.github/workflows/*.{yml,yaml}',
+  '.gitlab-ci.yml',
+  '.circleci/config.yml',
+  'azure-pipelines.yml',
+  'Jenkinsfile',
+  '.buildkite/pipeline.{yml,yaml}',
+  'bitbucket-pipelines.yml'
+];
+
+const DOCKERFILES = ['Dockerfile', '*.Dockerfile', 'Dockerfile.*', 'Containerfile'];
+const COMPOSE_FILES = ['docker-compose.{yml,yaml}', 'compose.{yml,yaml}', 'docker-compose.*.yml'];
+
+export interface BuildSystem {
+  name: string;
+  /** Manifests found, nearest the root first */
+  files: string[];
+}
+
+export interface EntryPoint {
+  file: string;
+  /** What it is: `main`, `module`, `exports`, `bin`, or a packaging `script` */
+  kind: 'main' | 'module' | 'exports' | 'bin' | 'script';
+  /** Binary or script name, when the manifest gives one */
+  name?: string;
+  /** What a script runs (`package.cli:main`) */
+  target?: string;
+}
+
+export interface ProjectScript {
+  /** Manifest that declares it */
+  file: string;
+  name: string;
+  command: string;
+}
+
+export interface ServiceDefinition {
+  name: string;
+  file: string;
+  kind: 'dockerfile' | 'compose' | 'procfile';
+  image?: string;
+  ports?: string[];
+  command?: string;
+}
+
+export interface ProjectOverview {
+  buildSystems: BuildSystem[];
+  entryPoints: EntryPoint[];
+  scripts: ProjectScript[];
+  makeTargets: Array<{ file: string; targets: string[] }>;
+  services: ServiceDefinition[];
+  ci: string[];
+}
+
+const posix = (file: string) => file.replace(/\\/g, '/');
+
+async function findFiles(rootPath: string, patterns: string[], deep = true): Promise<string[]> {
+  const files = await glob(
+    patterns.map((pattern) => (deep ? `**/${pattern}` : pattern)),
+    {
+      cwd: rootPath,
+      nodir: true,
+      dot: true,
+      maxDepth: MAX_DEPTH + 1,
+      ignore: SCAN_IGNORE
+    }
+  );
+  // Nearest the root first, then by path
+  return files
+    .map(posix)
+    .sort((a, b) => a.split('/').length - b.split('/').length || a.localeCompare(b));
+}
+
+async function readText(rootPath: string, file: string): Promise<string | null> {
+  try {
+    return await fs.readFile(path.join(rootPath, file), 'utf-8');
+  } catch {
+    return null;
+  }
+}
+
+const shorten = (command: string) =>
+  command.length > MAX_COMMAND_LENGTH ? `${command.slice(0, MAX_COMMAND_LENGTH - 3)}...` : command;
+
+const joinPath = (dir: string, file: string) =>
+  path.posix.normalize(dir === '.' ? file : `${dir}/${file}`).replace(/^\.\//, '');
+
+/** Body of one `[section]` of a TOML file, up to the next table header */
+function tomlSection(content: string, section: string): string | undefined {
+  const lines = content.split(/\r?\n/);
+  const start = lines.findIndex((line) => line.trim() === `[${section}]`);
+  if (start < 0) return undefined;
+  const end = lines.findIndex((line, i) => i > start && /^\s*\[/.test(line));
+  return lines.slice(start + 1, end < 0 ? undefined : end).join('\n');
+}
+
+function tomlKeyValues(section: string | undefined): Array<[string, string]> {
+  if (!section) return [];
+  return [...section.matchAll(/^\s*["']?([\w.-]+)["']?\s*=\s*["']([^"']+)["']/gm)].map(
+    (match) => [match[1], match[2]]
+  );
+}
+
+/** `main`, `module`, `bin`, `exports["."]` and `scripts` of a package.json */
+export function parsePackageJsonEntries(
+  content: string,
+  manifest: string
+): { entryPoints: EntryPoint[]; scripts: ProjectScript[] } {
+  let pkg: Record<string, unknown>;
+  try {
+    pkg = JSON.parse(content) as Record<string, unknown>;
+  } catch {
+    return { entryPoints: [], scripts: [] };
+  }
+  const dir = path.posix.dirname(manifest);
+  const entryPoints: EntryPoint[] = [];
+  const add = (target: unknown, kind: EntryPoint['kind'], name?: string) => {
+    if (typeof target !== 'string' || !target) return;
+    const file = joinPath(dir, target);
+    if (!entryPoints.some((entry) => entry.file === file && entry.kind === kind)) {
+      entryPoints.push({ file, kind, ...(name && { name }) });
+    }
+  };
+
+  add(pkg.main, 'main');
+  add(pkg.module, 'module');
+  const exportsRoot =
+    typeof pkg.exports === 'object' && pkg.exports !== null
+      ? ((pkg.exports as Record<string, unknown>)['.'] ?? pkg.exports)
+      : pkg.exports;
+  if (typeof exportsRoot === 'string') add(exportsRoot, 'exports');
+  else if (exportsRoot && typeof exportsRoot === 'object') {
+    const conditions = exportsRoot as Record<string, unknown>;
+    add(conditions.import ?? conditions.default ?? conditions.require, 'exports');
+  }
+  if (typeof pkg.bin === 'string') {
+    add(pkg.bin, 'bin', typeof pkg.name === 'string' ? pkg.name.replace(/^@[^/]+\//, '') : '');
+  } else if (pkg.bin && typeof pkg.bin === 'object') {
+    for (const [name, target] of Object.entries(pkg.bin as Record<string, unknown>)) {
+      add(target, 'bin', name);
+    }
+  }
+
+  const scripts =
+    pkg.scripts && typeof pkg.scripts === 'object'
+      ? Object.entries(pkg.scripts as Record<string, unknown>)
+          .filter((entry): entry is [string, string] => typeof entry[1] === 'string')
+          .map(([name, command]) => ({ file: manifest, name, command: shorten(command) }))
+      : [];
+  return { entryPoints, scripts };
+}
+
+/** `[[bin]]` targets of a Cargo.toml */
+export function parseCargoBins(content: string, manifest: string): EntryPoint[] {
+  const dir = path.posix.dirname(manifest);
+  const bins: EntryPoint[] = [];
+  for (const block of content.split(/^\s*\[\[bin\]\]\s*$/m).slice(1)) {
+    const body = block.split(/^\s*\[/m)[0];
+    const name = body.match(/^\s*name\s*=\s*["']([^"']+)["']/m)?.[1];
+    const target = body.match(/^\s*path\s*=\s*["']([^"']+)["']/m)?.[1];
+    const file = target ? joinPath(dir, target) : name ? joinPath(dir, `src/bin/${name}.rs`) : '';
+    if (file) bins.push({ file, kind: 'bin', ...(name && { name }) });
+  }
+  return bins;
+}
+
+/** `[project.scripts]` and `[tool.poetry.scripts]` of a pyproject.toml */
+export function parsePyprojectScripts(content: string, manifest: string): EntryPoint[] {
+  return [
+    ...tomlKeyValues(tomlSection(content, 'project.scripts')),
+    ...tomlKeyValues(tomlSection(content, 'tool.poetry.scripts'))
+  ].map(([name, target]) => ({ file: manifest, kind: 'script', name, target }));
+}
+
+/** `["node", "server.js"]` (exec form) or a shell-form command, as one line */
+function dockerCommand(value: string): string {
+  if (value.startsWith('[')) {
+    try {
+      const words = JSON.parse(value) as unknown;
+      if (Array.isArray(words)) return words.map(String).join(' ');
+    } catch {
+      // Not valid JSON: Docker runs it in shell form too
+    }
+  }
+  return value.replace(/\s+/g, ' ');
+}
+
+/** Base image, exposed ports and command of a Dockerfile (its last stage) */
+export function parseDockerfile(content: string): {
+  image?: string;
+  ports?: string[];
+  command?: string;
+} {
+  // Continuation lines belong to the instruction above
+  const lines = content.replace(/\\\r?\n/g, ' ').split(/\r?\n/);
+  let image: string | undefined;
+  let ports: string[] = [];
+  let command: string | undefined;
+  let entrypoint: string | undefined;
+  for (const raw of lines) {
+    const line = raw.trim();
+    const instruction = /^(FROM|EXPOSE|CMD|ENTRYPOINT)\s+(.+)$/i.exec(line);
+    if (!instruction) continue;
+    const value = instruction[2].trim();
+    switch (instruction[1].toUpperCase()) {
+      case 'FROM':
+        // A new stage starts over
+        image = value.replace(/^--platform=\S+\s+/i, '').split(/\s+/)[0];
+        ports = [];
+        command = undefined;
+        entrypoint = undefined;
+        break;
+      case 'EXPOSE':
+        ports.push(...value.split(/\s+/));
+        break;
+      case 'CMD':
+        command = dockerCommand(value);
+        break;
+      case 'ENTRYPOINT':
+        entrypoint = dockerCommand(value);
+        break;
+    }
+  }
+  const run = [entrypoint, command].filter(Boolean).join(' ');
+  return {
+    ...(image && { image }),
+    ...(ports.length > 0 && { ports }),
+    ...(run && { command: shorten(run) })
+  };
+}
+
+const yamlScalar = (value: string) =>
+  value
+    .replace(/\s+#.*$/, '')
+    .trim()
+    .replace(/^(["'])(.*)\1$/, '$2');
+
+/** Services of a compose file: name, image or build context, ports */
+export function parseComposeServices(content: string, file: string): ServiceDefinition[] {
+  const services: ServiceDefinition[] = [];
+  let inServices = false;
+  let serviceIndent = -1;
+  let current: ServiceDefinition | undefined;
+  let listKey: string | undefined;
+  for (const raw of content.split(/\r?\n/)) {
+    if (!raw.trim() || raw.trim().startsWith('#')) continue;
+    const indent = raw.length - raw.trimStart().length;
+    const line = raw.trim();
+    if (indent === 0) {
+      inServices = /^services\s*:\s*$/.test(line);
+      serviceIndent = -1;
+      current = undefined;
+      continue;
+    }
+    if (!inServices) continue;
+    if (serviceIndent < 0) serviceIndent = indent;
+    if (indent === serviceIndent) {
+      const name = /^["']?([\w.-]+)["']?\s*:/.exec(line)?.[1];
+      current = name ? { name, file, kind: 'compose' } : undefined;
+      if (current) services.push(current);
+      listKey = undefined;
+      continue;
+    }
+    if (!current) continue;
+    const entry = /^([\w-]+)\s*:\s*(.*)$/.exec(line);
+    if (entry && !line.startsWith('- ')) {
+      listKey = entry[2] ? undefined : entry[1];
+      if (entry[1] === 'image' && entry[2]) current.image = yamlScalar(entry[2]);
+      else if (entry[1] === 'build' && entry[2] && !current.image) {
+        current.image = `build ${yamlScalar(entry[2])}`;
+      } else if (entry[1] === 'context' && !current.image) {
+        current.image = `build ${yamlScalar(entry[2])}`;
+      } else if (entry[1] === 'command' && entry[2]) {
+        current.command = shorten(yamlScalar(entry[2]));
+      } else if (entry[1] === 'ports' && entry[2].startsWith('[')) {
+        current.ports = entry[2]
+          .replace(/^\[|\]$/g, '')
+          .split(',')
+          .map(yamlScalar)
+          .filter(Boolean);
+      }
+      continue;
+    }
+    if (listKey === 'ports' && line.startsWith('- ')) {
+      current.ports = [...(current.ports ?? []), yamlScalar(line.slice(2))];
+    }
+  }
+  return services;
+}
+
+/** `target other:` at the start of a line, but not `VAR := value` or `a::b` */
+const MAKE_RULE = /^([A-Za-z0-9][\w./-]*(?:[ \t]+[A-Za-z0-9][\w./-]*)*)[ \t]*:(?![=:])/gm;
+
+/** Explicit targets of a Makefile (no pattern rules or special targets) */
+export function parseMakeTargets(content: string): string[] {
+  const targets = new Set<string>();
+  for (const match of content.matchAll(MAKE_RULE)) {
+    for (const target of match[1].split(/\s+/)) targets.add(target);
+  }
+  return [...targets];
+}
+
+/** Conventional entry files: `main.go` in `package main`, `src/main.rs`, `__main__.py`, ... */
+async function conventionalEntryPoints(rootPath: string): Promise<EntryPoint[]> {
+  const entryPoints: EntryPoint[] = [];
+  for (const file of await findFiles(rootPath, ['main.go'])) {
+    const content = await readText(rootPath, file);
+    if (content && /^package main\b/m.test(content) && /^func main\(\)/m.test(content)) {
+      const dir = path.posix.dirname(file);
+      const name = dir === '.' ? undefined : path.posix.basename(dir);
+      entryPoints.push({ file, kind: 'main', ...(name && { name }) });
+    }
+  }
+  for (const file of await findFiles(rootPath, ['src/main.rs'])) {
+    entryPoints.push({ file, kind: 'main' });
+  }
+  for (const file of await findFiles(rootPath, ['src/bin/*.rs'])) {
+    entryPoints.push({ file, kind: 'bin', name: path.posix.basename(file, '.rs') });
+  }
+  for (const file of await findFiles(rootPath, ['__main__.py', 'manage.py', 'Program.cs'])) {
+    entryPoints.push({ file, kind: 'main' });
+  }
+  return entryPoints;
+}
+
+async function buildSystems(rootPath: string): Promise<BuildSystem[]> {
+  const systems = new Map<string, string[]>();
+  const add = (name: string, file: string) => {
+    const files = systems.get(name) ?? [];
+    if (!files.includes(file)) files.push(file);
+    systems.set(name, files);
+  };
+  const markers = await findFiles(rootPath, BUILD_MARKERS.map(([marker]) => marker));
+  const lockfiles = new Set(await findFiles(rootPath, NPM_LOCKFILES.map(([lock]) => lock)));
+  for (const file of markers) {
+    const base = path.posix.basename(file);
+    const marker = BUILD_MARKERS.find(([pattern]) =>
+      pattern.startsWith('*') ? base.endsWith(pattern.slice(1)) : base === pattern
+    );
+    if (!marker) continue;
+    let name = marker[1];
+    if (name === 'npm') {
+      const dir = path.posix.dirname(file);
+      name = NPM_LOCKFILES.find(([lock]) => lockfiles.has(joinPath(dir, lock)))?.[1] ?? 'npm';
+    }
+    add(name, file);
+  }
+  return [...systems]
+    .map(([name, files]) => ({ name, files }))
+    .sort((a, b) => a.files[0].split('/').length - b.files[0].split('/').length)
+    .map((system) => ({
+      name: system.name,
+      files: system.files.slice(0, MAX_FILES_PER_SYSTEM)
+    }));
+}
+
+/** Build systems, entry points, scripts, Make targets, services and CI of a repository */
+export async function describeProject(rootPath: string): Promise<ProjectOverview> {
+  const entryPoints: EntryPoint[] = [];
+  const scripts: ProjectScript[] = [];
+
+  for (const manifest of await findFiles(rootPath, ['package.json'])) {
+    const parsed = parsePackageJsonEntries((await readText(rootPath, manifest)) ?? '', manifest);
+    entryPoints.push(...parsed.entryPoints);
+    scripts.push(...parsed.scripts);
+  }
+  for (const manifest of await findFiles(rootPath, ['Cargo.toml'])) {
+    entryPoints.push(...parseCargoBins((await readText(rootPath, manifest)) ?? '', manifest));
+  }
+  for (const manifest of await findFiles(rootPath, ['pyproject.toml'])) {
+    entryPoints.push(
+      ...parsePyprojectScripts((await readText(rootPath, manifest)) ?? '', manifest)
+    );
+  }
+  for (const entry of await conventionalEntryPoints(rootPath)) {
+    if (!entryPoints.some((known) => known.file === entry.file)) entryPoints.push(entry);
+  }
+
+  const makeTargets: ProjectOverview['makeTargets'] = [];
+  for (const file of await findFiles(rootPath, ['Makefile', 'makefile', 'GNUmakefile'])) {
+    const targets = parseMakeTargets((await readText(rootPath, file)) ?? '');
+    if (targets.length > 0) makeTargets.push({ file, targets: targets.slice(0, MAX_ITEMS) });
+  }
+
+  const services: ServiceDefinition[] = [];
+  for (const file of await findFiles(rootPath, COMPOSE_FILES)) {
+    services.push(...parseComposeServices((await readText(rootPath, file)) ?? '', file));
+  }
+  for (const file of await findFiles(rootPath, DOCKERFILES)) {
+    const dir = path.posix.dirname(file);
+    const name = dir === '.' ? path.basename(path.resolve(rootPath)) : path.posix.basename(dir);
+    services.push({
+      name,
+      file,
+      kind: 'dockerfile',
+      ...parseDockerfile((await readText(rootPath, file)) ?? '')
+    });
+  }
+  for (const file of await findFiles(rootPath, ['Procfile'])) {
+    const content = (await readText(rootPath, file)) ?? '';
+    for (const match of content.matchAll(/^([\w-]+):\s*(.+)$/gm)) {
+      services.push({ name: match[1], file, kind: 'procfile', command: shorten(match[2].trim()) });
+    }
+  }
+
+  return {
+    buildSystems: await buildSystems(rootPath),
+    entryPoints: entryPoints.slice(0, MAX_ITEMS),
+    scripts: scripts.slice(0, MAX_ITEMS),
+    makeTargets: makeTargets.slice(0, 3),
+    services: services.slice(0, MAX_ITEMS),
+    ci: (await findFiles(rootPath, CI_FILES, false)).slice(0, MAX_ITEMS)
+  };
+}
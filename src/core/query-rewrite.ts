@@ -0,0 +1,186 @@
+/**
+ * Query rewriting for code vocabulary. Natural-language queries ("where do we throttle
+ * uploads") often miss code that names the idea differently (`rateLimiter`), so a search can
+ * also retrieve with 2-3 rewritten variants, fused with the original query's results.
+ *
+ * Variants come from a built-in dictionary of code idioms, or from the client's model via
+ * MCP sampling when the caller asks for it (falling back to the dictionary).
+ */
+
+import type { Sampler } from '../types/index.js';
+
+export const QUERY_REWRITE_MODES = ['off', 'synonyms', 'sampling'] as const;
+export type QueryRewriteMode = (typeof QUERY_REWRITE_MODES)[number];
+
+export const MAX_QUERY_REWRITES = 3;
+
+const SAMPLING_MAX_TOKENS = 200;
+const MAX_REWRITE_LENGTH = 200;
+
+/** Everyday phrasings and the words code tends to use for them, most common first */
+const CODE_IDIOMS: Array<{ pattern: RegExp; terms: string[] }> = [
+  {
+    pattern: /\b(throttl\w*|rate[- ]?limit\w*)\b/i,
+    terms: ['rateLimiter', 'rate limit', 'throttle', 'debounce']
+  },
+  {
+    pattern: /\b(retry|retries|retrying|try again)\b/i,
+    terms: ['retry', 'backoff', 'maxAttempts']
+  },
+  { pattern: /\b(cach\w*|memoi[sz]\w*)\b/i, terms: ['cache', 'memoize', 'ttl', 'lru'] },
+  {
+    pattern: /\b(timeouts?|time out|deadlines?)\b/i,
+    terms: ['timeout', 'AbortController', 'deadline']
+  },
+  {
+    pattern: /\b(background (?:jobs?|tasks?)|queue\w*|workers?)\b/i,
+    terms: ['queue', 'worker', 'job', 'enqueue']
+  },
+  { pattern: /\b(schedul\w*|cron|periodic\w*)\b/i, terms: ['cron', 'scheduler', 'setInterval'] },
+  { pattern: /\b(validat\w*|sanitiz\w*)\b/i, terms: ['validator', 'schema', 'validate'] },
+  {
+    pattern: /\b(permissions?|authori[sz]\w*|access control)\b/i,
+    terms: ['guard', 'policy', 'canActivate', 'rbac']
+  },
+  {
+    pattern: /\b(log ?in|sign ?in|authenticat\w*)\b/i,
+    terms: ['auth', 'login', 'session', 'token']
+  },
+  { pattern: /\b(logging|logs?)\b/i, terms: ['logger', 'log', 'console'] },
+  { pattern: /\b(paginat\w*|paging)\b/i, terms: ['pagination', 'cursor', 'offset', 'pageSize'] },
+  {
+    pattern: /\b(feature flags?|feature toggles?)\b/i,
+    terms: ['featureFlag', 'isEnabled', 'flag']
+  },
+  {
+    pattern: /\b(error handling|handle errors?|exceptions?)\b/i,
+    terms: ['catch', 'errorHandler', 'throw']
+  },
+  {
+    pattern: /\b(env(?:ironment)? var(?:iable)?s?|settings)\b/i,
+    terms: ['process.env', 'config', 'dotenv']
+  },
+  {
+    pattern: /\b(notif(?:y|ies|ications?)|subscri\w*|listen\w*)\b/i,
+    terms: ['emit', 'EventEmitter', 'subscribe', 'listener']
+  },
+  { pattern: /\b(serializ\w*|deserializ\w*)\b/i, terms: ['JSON.stringify', 'serialize', 'toJSON'] },
+  {
+    pattern: /\b(hash(?:ing)? passwords?|password hash\w*|encrypt\w*)\b/i,
+    terms: ['bcrypt', 'hash', 'crypto']
+  }
+];
+
+export interface QueryRewrites {
+  variants: string[];
+  source: 'synonyms' | 'sampling';
+  /** Why sampling was not used although it was asked for */
+  samplingError?: string;
+}
+
+function isNewVariant(variant: string, query: string, seen: string[]): boolean {
+  const key = variant.toLowerCase();
+  return key !== query.toLowerCase() && !seen.some((v) => v.toLowerCase() === key);
+}
+
+/**
+ * Variants with each recognized idiom replaced by a code term: the first variant uses every
+ * idiom's first term, the next its second, and so on. Terms already in the query are skipped.
+ */
+export function rewriteWithSynonyms(query: string, max: number = MAX_QUERY_REWRITES): string[] {
+  const lower = query.toLowerCase();
+  const matches = CODE_IDIOMS.flatMap(({ pattern, terms }) => {
+    const match = pattern.exec(query);
+    const fresh = terms.filter((term) => !lower.includes(term.toLowerCase()));
+    return match && fresh.length > 0 ? [{ phrase: match[0], terms: fresh }] : [];
+  });
+  if (matches.length === 0) return [];
+
+  const variants: string[] = [];
+  const rounds = Math.max(...matches.map((m) => m.terms.length));
+  for (let i = 0; i < rounds && variants.length < max; i++) {
+    let variant = query;
+    for (const { phrase, terms } of matches) {
+      variant = variant.replace(phrase, () => terms[i % terms.length]);
+    }
+    if (isNewVariant(variant, query, variants)) variants.push(variant);
+  }
+  return variants;
+}
+
+/** Rewrites from a model reply: the first JSON array of strings in it */
+export function parseSampledRewrites(
+  reply: string,
+  query: string,
+  max: number = MAX_QUERY_REWRITES
+): string[] {
+  const start = reply.indexOf('[');
+  const end = reply.lastIndexOf(']');
+  if (start < 0 || end <= start) return [];
+  let parsed: unknown;
+  try {
+    parsed = JSON.parse(reply.slice(start, end + 1));
+  } catch {
+    return [];
+  }
+  if (!Array.isArray(parsed)) return [];
+
+  const variants: string[] = [];
+  for (const item of parsed) {
+    if (typeof item !== 'string') continue;
+    const variant = item.replace(/\s+/g, ' ').trim().slice(0, MAX_REWRITE_LENGTH);
+    if (variant && isNewVariant(variant, query, variants)) variants.push(variant);
+    if (variants.length >= max) break;
+  }
+  return variants;
+}
+
+function buildSamplingPrompt(query: string, max: number): string {
+  return [
+    `Rewrite this codebase search query into ${max} alternative queries that use the words`,
+    'source code would contain: identifier names (camelCase/snake_case), library and API',
+    'names, and common technical terms. Keep each under 12 words.',
+    '',
+    `Query: ${query}`,
+    '',
+    'Answer with a JSON array of strings only.'
+  ].join('\n');
+}
+
+/**
+ * Query variants for `mode`, or none when it is `off`. With `sampling`, the client's model
+ * writes them; without a sampler, or when it fails, the dictionary is used instead.
+ */
+export async function buildQueryRewrites(
+  query: string,
+  mode: QueryRewriteMode,
+  sample?: Sampler
+): Promise<QueryRewrites | undefined> {
+  if (mode === 'off') return undefined;
+
+  let samplingError: string | undefined;
+  if (mode === 'sampling') {
+    if (sample) {
+      try {
+        const reply = await sample({
+          systemPrompt: 'You rewrite code search queries. Answer with JSON only.',
+          prompt: buildSamplingPrompt(query, MAX_QUERY_REWRITES),
+          maxTokens: SAMPLING_MAX_TOKENS
+        });
+        const variants = parseSampledRewrites(reply, query);
+        if (variants.length > 0) return { variants, source: 'sampling' };
+        samplingError = 'Model reply was not a JSON array of queries';
+      } catch (error) {
+        samplingError = error instanceof Error ? error.message : String(error);
+      }
+    } else {
+      samplingError = 'The client does not support sampling';
+    }
+  }
+
+  return {
+    variants: rewriteWithSynonyms(query),
+    source: 'synonyms',
+    ...(samplingError ? { samplingError } : {})
+  };
+}
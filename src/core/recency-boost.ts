@@ -0,0 +1,105 @@
+/**
+ * Recency and churn boost: ranks recently changed and frequently edited files higher, since
+ * they are usually what a question is about.
+ *
+ * Both signals come from git at index time: `lastModified` (last commit date, mtime for
+ * untracked files) and `recentCommits` (commits in the 90 days before indexing). A result's
+ * score is multiplied by `1 + recencyWeight * 0.5^(ageDays / halfLifeDays)` and by
+ * `1 + churnWeight * log(1 + commits) / log(1 + maxCommits)`, so each weight is the largest
+ * boost it can give.
+ *
+ * Both weights default to 0 (off). Projects set them under `search.recency` in
+ * `.codebase-context/config.json`; a query can override them.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  PROJECT_CONFIG_FILENAME
+} from '../constants/codebase-context.js';
+import type { ChunkMetadata } from '../types/index.js';
+
+export interface RecencyBoostOptions {
+  /** Largest boost for a file changed just now, in [0, 1] */
+  recencyWeight?: number;
+  /** Age at which the recency boost has halved */
+  halfLifeDays?: number;
+  /** Largest boost for the most frequently edited file, in [0, 1] */
+  churnWeight?: number;
+}
+
+export const DEFAULT_RECENCY_BOOST: Required<RecencyBoostOptions> = {
+  recencyWeight: 0,
+  halfLifeDays: 30,
+  churnWeight: 0
+};
+
+const MAX_HALF_LIFE_DAYS = 3650;
+const DAY_MS = 24 * 60 * 60 * 1000;
+
+const isWeight = (value: unknown): value is number =>
+  typeof value === 'number' && value >= 0 && value <= 1;
+
+/** Later sources override earlier ones; out-of-range values are ignored */
+export function resolveRecencyBoost(
+  ...sources: Array<RecencyBoostOptions | undefined>
+): Required<RecencyBoostOptions> {
+  const resolved = { ...DEFAULT_RECENCY_BOOST };
+  for (const source of sources) {
+    if (!source) continue;
+    const { recencyWeight, halfLifeDays, churnWeight } = source;
+    if (isWeight(recencyWeight)) resolved.recencyWeight = recencyWeight;
+    if (isWeight(churnWeight)) resolved.churnWeight = churnWeight;
+    if (typeof halfLifeDays === 'number' && halfLifeDays >= 1) {
+      resolved.halfLifeDays = Math.min(halfLifeDays, MAX_HALF_LIFE_DAYS);
+    }
+  }
+  return resolved;
+}
+
+export function isRecencyBoostEnabled(options: Required<RecencyBoostOptions>): boolean {
+  return options.recencyWeight > 0 || options.churnWeight > 0;
+}
+
+/** The `search.recency` key of `.codebase-context/config.json`, if any */
+export async function loadProjectRecencyConfig(
+  rootPath: string
+): Promise<RecencyBoostOptions | undefined> {
+  const configPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME);
+  try {
+    const parsed = JSON.parse(await fs.readFile(configPath, 'utf-8')) as { search?: unknown };
+    const recency = (parsed.search as { recency?: unknown } | undefined)?.recency;
+    if (!recency || typeof recency !== 'object') return undefined;
+    const { recencyWeight, halfLifeDays, churnWeight } = recency as Record<string, unknown>;
+    return {
+      ...(typeof recencyWeight === 'number' ? { recencyWeight } : {}),
+      ...(typeof halfLifeDays === 'number' ? { halfLifeDays } : {}),
+      ...(typeof churnWeight === 'number' ? { churnWeight } : {})
+    };
+  } catch {
+    return undefined;
+  }
+}
+
+/** Multipliers for one chunk; 1 when a signal is off or unknown for its file */
+export function recencyBoostFactors(
+  metadata: ChunkMetadata | undefined,
+  options: Required<RecencyBoostOptions>,
+  maxRecentCommits: number,
+  now = Date.now()
+): { recency: number; churn: number } {
+  let recency = 1;
+  const modified = Date.parse(metadata?.lastModified ?? '');
+  if (options.recencyWeight > 0 && Number.isFinite(modified)) {
+    const ageDays = Math.max(0, now - modified) / DAY_MS;
+    recency = 1 + options.recencyWeight * Math.pow(0.5, ageDays / options.halfLifeDays);
+  }
+
+  let churn = 1;
+  const commits = metadata?.recentCommits ?? 0;
+  if (options.churnWeight > 0 && commits > 0 && maxRecentCommits > 0) {
+    churn = 1 + (options.churnWeight * Math.log1p(commits)) / Math.log1p(maxRecentCommits);
+  }
+  return { recency, churn };
+}
@@ -0,0 +1,335 @@
+/**
+ * Remote repositories indexed without a local checkout, e.g. a dependency's source while
+ * debugging its behavior. A GitHub or GitLab URL (`https://github.com/org/repo/tree/v2.1`,
+ * `https://gitlab.com/group/repo/-/tree/main`, `git@host:org/repo.git`) is fetched into a
+ * cache directory: one ref with `git fetch --depth 1`, or the host's tarball when git is not
+ * installed or `method: 'tarball'` is asked for. Checkouts live under
+ * CODEBASE_CONTEXT_REMOTES_DIR (default `~/.cache/codebase-context/remotes`) and are reused
+ * until refreshed. GITHUB_TOKEN / GITLAB_TOKEN authorize tarball downloads of private repos;
+ * git uses its own credentials.
+ *
+ * A fetched repo is untrusted: its committed `.codebase-context/` (config, index) and
+ * `codebase-context.yaml` are deleted before it is indexed, and so are symlinks pointing
+ * outside the checkout.
+ */
+
+import { execFile } from 'child_process';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { promisify } from 'util';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  PROJECT_CONFIG_YAML_FILENAME
+} from '../constants/codebase-context.js';
+import { GIT_PLATFORM_ARGS, getRefIndexSlug } from '../utils/git-tree.js';
+import { isPathInside } from '../utils/path-normalization.js';
+
+const execFileAsync = promisify(execFile);
+
+/** A fetch that hangs (credentials prompt, huge repo) is given up on */
+const FETCH_TIMEOUT_MS = 10 * 60_000;
+
+/** Written next to the checkout's index: what was fetched, how and when */
+const REMOTE_SOURCE_FILENAME = 'remote.json';
+
+export type RemoteFetchMethod = 'auto' | 'clone' | 'tarball';
+
+export interface RemoteRepo {
+  host: string;
+  kind: 'github' | 'gitlab' | 'git';
+  /** `org/repo`, or `group/subgroup/repo` on GitLab */
+  repoPath: string;
+  cloneUrl: string;
+  /** Branch, tag or commit from the URL (`/tree/<ref>`) */
+  ref?: string;
+}
+
+export interface FetchedRemote {
+  rootPath: string;
+  method: 'clone' | 'tarball';
+  /** Fetched commit (clones only; tarballs don't say) */
+  commit?: string;
+  ref?: string;
+  fetchedAt: string;
+  /** An earlier checkout of the same ref was reused */
+  reused: boolean;
+}
+
+export interface FetchRemoteOptions {
+  ref?: string;
+  method?: RemoteFetchMethod;
+  /** Fetch again even when a checkout of the ref exists */
+  refresh?: boolean;
+  remotesDir?: string;
+  env?: NodeJS.ProcessEnv;
+}
+
+const SAFE_SEGMENT = /^[A-Za-z0-9_.-]+$/;
+/** A host name is also a directory name: not `.`/`..`, and not read as an option */
+const SAFE_HOST = /^(?!-)(?!\.+$)[\w.-]+$/;
+
+function assertSafeRef(ref: string): string {
+  const trimmed = ref.trim();
+  if (!trimmed || trimmed.startsWith('-') || /[\s\0~^:?*[\\]/.test(trimmed)) {
+    throw new Error(`Invalid git ref: '${ref}'`);
+  }
+  return trimmed;
+}
+
+function hostKind(host: string): RemoteRepo['kind'] {
+  if (host === 'github.com') return 'github';
+  return host.includes('gitlab') ? 'gitlab' : 'git';
+}
+
+/** Parse a repository URL; throws for anything that isn't a plain https or ssh repo URL */
+export function parseRemoteRepoUrl(input: string): RemoteRepo {
+  const trimmed = input.trim();
+  let host: string;
+  let segments: string[];
+  let ssh = false;
+
+  const scp = trimmed.match(/^[\w.-]+@([\w.-]+):(.+)$/);
+  if (scp) {
+    host = scp[1].toLowerCase();
+    segments = scp[2].split('/');
+    ssh = true;
+  } else {
+    let url: URL;
+    try {
+      url = new URL(trimmed);
+    } catch {
+      throw new Error(`Not a repository URL: '${input}'`);
+    }
+    if (url.protocol !== 'https:' && url.protocol !== 'http:') {
+      throw new Error(`Only https and ssh repository URLs are supported: '${input}'`);
+    }
+    host = url.hostname.toLowerCase();
+    segments = decodeURIComponent(url.pathname).split('/');
+  }
+  if (!SAFE_HOST.test(host)) throw new Error(`Not a repository host: '${host}' in '${input}'`);
+  segments = segments.filter(Boolean);
+
+  const kind = hostKind(host);
+  let ref: string | undefined;
+  // github.com/org/repo/tree/<ref>, gitlab.com/group/repo/-/tree/<ref>
+  const marker = kind === 'gitlab' ? segments.indexOf('-') : segments.indexOf('tree');
+  if (marker >= 0) {
+    const refStart = kind === 'gitlab' ? marker + 2 : marker + 1;
+    if (segments.length > refStart) ref = segments.slice(refStart).join('/');
+    segments = segments.slice(0, marker);
+  }
+  if (kind === 'github') segments = segments.slice(0, 2);
+  if (segments.length > 0) {
+    segments[segments.length - 1] = segments[segments.length - 1].replace(/\.git$/, '');
+  }
+  if (segments.length < 2 || !segments.every((s) => SAFE_SEGMENT.test(s) && s !== '..')) {
+    throw new Error(`Can't tell the repository from '${input}' (expected <host>/<owner>/<repo>)`);
+  }
+
+  const repoPath = segments.join('/');
+  return {
+    host,
+    kind,
+    repoPath,
+    cloneUrl: ssh ? trimmed : `https://${host}/${repoPath}.git`,
+    ...(ref ? { ref: assertSafeRef(ref) } : {})
+  };
+}
+
+/** Project name a remote checkout is served under: `org/repo` or `org/repo@ref` */
+export function remoteProjectName(repo: RemoteRepo, ref?: string): string {
+  return ref ? `${repo.repoPath}@${ref}` : repo.repoPath;
+}
+
+export function resolveRemotesDir(env: NodeJS.ProcessEnv = process.env): string {
+  const configured = env.CODEBASE_CONTEXT_REMOTES_DIR?.trim();
+  return configured
+    ? path.resolve(configured)
+    : path.join(os.homedir(), '.cache', 'codebase-context', 'remotes');
+}
+
+/** `<remotesDir>/<host>/<repo path>/<ref slug>`: one checkout per repo and ref */
+export function remoteCheckoutDir(remotesDir: string, repo: RemoteRepo, ref?: string): string {
+  const slug = getRefIndexSlug(ref ?? 'HEAD');
+  const dir = path.join(remotesDir, repo.host, ...repo.repoPath.split('/'), slug);
+  // The checkout is deleted on refresh, so it must never resolve to or above remotesDir
+  if (path.resolve(dir) === path.resolve(remotesDir) || !isPathInside(remotesDir, dir)) {
+    throw new Error(`Checkout directory for ${repo.host}/${repo.repoPath} escapes ${remotesDir}`);
+  }
+  return dir;
+}
+
+async function git(args: string[], cwd?: string): Promise<string> {
+  const { stdout } = await execFileAsync('git', [...GIT_PLATFORM_ARGS, ...args], {
+    cwd,
+    timeout: FETCH_TIMEOUT_MS,
+    // Fail instead of waiting for a username on a private repo
+    env: { ...process.env, GIT_TERMINAL_PROMPT: '0' }
+  });
+  return stdout.trim();
+}
+
+async function isGitAvailable(): Promise<boolean> {
+  try {
+    await git(['--version']);
+    return true;
+  } catch {
+    return false;
+  }
+}
+
+async function shallowClone(repo: RemoteRepo, dir: string, ref?: string): Promise<string> {
+  await git(['init', '--quiet', dir]);
+  // fetch accepts branches, tags and (on GitHub and GitLab) full commit SHAs alike
+  // `--` keeps a URL that starts with `-` from being read as an option
+  await git(['fetch', '--depth', '1', '--quiet', '--', repo.cloneUrl, ref ?? 'HEAD'], dir);
+  await git(['checkout', '--quiet', '--detach', 'FETCH_HEAD'], dir);
+  return git(['rev-parse', 'HEAD'], dir);
+}
+
+function tarballRequest(
+  repo: RemoteRepo,
+  ref: string | undefined,
+  env: NodeJS.ProcessEnv
+): { url: string; headers: Record<string, string> } {
+  if (repo.kind === 'github') {
+    const token = env.GITHUB_TOKEN?.trim();
+    return {
+      url:
+        `https://api.github.com/repos/${repo.repoPath}/tarball` +
+        (ref ? `/${encodeURIComponent(ref)}` : ''),
+      headers: {
+        Accept: 'application/vnd.github+json',
+        ...(token ? { Authorization: `Bearer ${token}` } : {})
+      }
+    };
+  }
+  if (repo.kind === 'gitlab') {
+    const token = env.GITLAB_TOKEN?.trim();
+    const project = encodeURIComponent(repo.repoPath);
+    return {
+      url:
+        `https://${repo.host}/api/v4/projects/${project}/repository/archive.tar.gz` +
+        (ref ? `?sha=${encodeURIComponent(ref)}` : ''),
+      headers: token ? { 'PRIVATE-TOKEN': token } : {}
+    };
+  }
+  throw new Error(`Tarball downloads need a GitHub or GitLab URL, not ${repo.host}`);
+}
+
+async function downloadTarball(
+  repo: RemoteRepo,
+  dir: string,
+  ref: string | undefined,
+  env: NodeJS.ProcessEnv
+): Promise<void> {
+  const { url, headers } = tarballRequest(repo, ref, env);
+  const response = await fetch(url, { headers, signal: AbortSignal.timeout(FETCH_TIMEOUT_MS) });
+  if (!response.ok) {
+    throw new Error(`Tarball download failed: ${response.status} ${response.statusText} (${url})`);
+  }
+  await fs.mkdir(dir, { recursive: true });
+  const archive = path.join(dir, '..', `${path.basename(dir)}.tar.gz`);
+  await fs.writeFile(archive, Buffer.from(await response.arrayBuffer()));
+  try {
+    // Both hosts wrap the tree in one top-level directory
+    await execFileAsync('tar', ['-xzf', archive, '-C', dir, '--strip-components=1'], {
+      timeout: FETCH_TIMEOUT_MS
+    });
+  } finally {
+    await fs.rm(archive, { force: true });
+  }
+}
+
+/**
+ * Drop what the repo would otherwise decide for us: its own `.codebase-context/` and config
+ * file, and symlinks that lead out of the checkout (or nowhere)
+ */
+async function sanitizeCheckout(dir: string): Promise<void> {
+  for (const name of [
+    CODEBASE_CONTEXT_DIRNAME,
+    PROJECT_CONFIG_YAML_FILENAME,
+    PROJECT_CONFIG_YAML_FILENAME.replace(/\.yaml$/, '.yml')
+  ]) {
+    await fs.rm(path.join(dir, name), { recursive: true, force: true });
+  }
+  const root = await fs.realpath(dir);
+  const walk = async (current: string): Promise<void> => {
+    for (const entry of await fs.readdir(current, { withFileTypes: true })) {
+      const full = path.join(current, entry.name);
+      if (entry.isSymbolicLink()) {
+        const target = await fs.realpath(full).catch(() => null);
+        if (!target || !isPathInside(root, target)) await fs.rm(full, { force: true });
+      } else if (entry.isDirectory() && entry.name !== '.git') {
+        await walk(full);
+      }
+    }
+  };
+  await walk(dir);
+}
+
+async function readRemoteSource(dir: string): Promise<FetchedRemote | null> {
+  try {
+    const sourcePath = path.join(dir, CODEBASE_CONTEXT_DIRNAME, REMOTE_SOURCE_FILENAME);
+    const raw = await fs.readFile(sourcePath, 'utf-8');
+    const parsed = JSON.parse(raw) as FetchedRemote;
+    return typeof parsed.fetchedAt === 'string' ? { ...parsed, rootPath: dir, reused: true } : null;
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Fetch `repo` at `ref` (default: its default branch) into the remotes cache, or reuse the
+ * checkout from an earlier call. `auto` clones when git is installed and falls back to the
+ * tarball otherwise.
+ */
+export async function fetchRemoteRepo(
+  repo: RemoteRepo,
+  options: FetchRemoteOptions = {}
+): Promise<FetchedRemote> {
+  const env = options.env ?? process.env;
+  const ref = options.ref?.trim() ? assertSafeRef(options.ref) : repo.ref;
+  const dir = remoteCheckoutDir(options.remotesDir ?? resolveRemotesDir(env), repo, ref);
+
+  if (!options.refresh) {
+    const existing = await readRemoteSource(dir);
+    if (existing) return existing;
+  }
+
+  const requested = options.method ?? 'auto';
+  const method =
+    requested === 'auto'
+      ? (await isGitAvailable()) || repo.kind === 'git'
+        ? 'clone'
+        : 'tarball'
+      : requested;
+
+  await fs.rm(dir, { recursive: true, force: true });
+  await fs.mkdir(path.dirname(dir), { recursive: true });
+  let commit: string | undefined;
+  try {
+    if (method === 'clone') commit = await shallowClone(repo, dir, ref);
+    else await downloadTarball(repo, dir, ref, env);
+    await sanitizeCheckout(dir);
+  } catch (error) {
+    await fs.rm(dir, { recursive: true, force: true });
+    const message = error instanceof Error ? error.message : String(error);
+    throw new Error(`Could not fetch ${repo.cloneUrl}${ref ? ` at ${ref}` : ''}: ${message}`);
+  }
+
+  const fetched: FetchedRemote = {
+    rootPath: dir,
+    method,
+    ...(commit ? { commit } : {}),
+    ...(ref ? { ref } : {}),
+    fetchedAt: new Date().toISOString(),
+    reused: false
+  };
+  const sourcePath = path.join(dir, CODEBASE_CONTEXT_DIRNAME, REMOTE_SOURCE_FILENAME);
+  await fs.mkdir(path.dirname(sourcePath), { recursive: true });
+  const { reused: _reused, rootPath: _rootPath, ...record } = fetched;
+  await fs.writeFile(sourcePath, JSON.stringify({ url: repo.cloneUrl, ...record }, null, 2));
+  return fetched;
+}
@@ -0,0 +1,174 @@
+/**
+ * Repo map: the directory tree annotated with each file's top-level symbols (aider-style),
+ * trimmed to a token budget. Served as the `codebase://repo-map` resource so a client can
+ * prime the model with global structure before drilling into search.
+ *
+ * When the budget cannot fit every file, the most-imported files are kept first, then the
+ * ones defining the most symbols; the rest are counted in the footer.
+ */
+
+import path from 'path';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME
+} from '../constants/codebase-context.js';
+import { estimateTokens } from '../utils/ast-chunker.js';
+import { loadDependencyGraph } from './dependency-graph.js';
+import { readIndexArtifact } from './index-lock.js';
+import { loadSymbolIndex, topLevelDefinitions, type SymbolDefinition } from './symbol-index.js';
+import type { CodeChunk } from '../types/index.js';
+
+export const DEFAULT_REPO_MAP_TOKENS = 1000;
+export const MAX_REPO_MAP_TOKENS = 16000;
+
+const MAX_SYMBOLS_PER_FILE = 8;
+/** Held back for the header and footer lines */
+const FRAME_TOKENS = 40;
+const INDENT = '  ';
+
+export interface RepoMapOptions {
+  /** Estimated-token ceiling for the rendered map (default 1000, max 16000) */
+  tokenBudget?: number;
+  /** Only files under this repo-relative directory */
+  scope?: string;
+  /** Leave out files this returns false for (the path policy) */
+  include?: (file: string) => boolean;
+}
+
+export interface RepoMap {
+  text: string;
+  tokens: number;
+  totalFiles: number;
+  includedFiles: number;
+}
+
+interface MapEntry {
+  file: string;
+  line: string;
+  importers: number;
+  symbols: number;
+}
+
+function symbolLabel(definitions: SymbolDefinition[]): string {
+  const shown = definitions
+    .slice(0, MAX_SYMBOLS_PER_FILE)
+    .map((def) => `${def.kind} ${def.name}`);
+  const more = definitions.length - shown.length;
+  return shown.join(', ') + (more > 0 ? ` (+${more})` : '');
+}
+
+/** Directory prefixes of a posix path: `a/b/c.ts` -> `a/`, `a/b/` */
+function parentDirs(file: string): string[] {
+  const parts = file.split('/').slice(0, -1);
+  return parts.map((_, i) => `${parts.slice(0, i + 1).join('/')}/`);
+}
+
+function dirLine(dir: string): string {
+  const depth = dir.split('/').length - 2;
+  return `${INDENT.repeat(depth)}${path.posix.basename(dir)}/`;
+}
+
+function render(entries: MapEntry[]): string[] {
+  const lines: string[] = [];
+  const opened = new Set<string>();
+  for (const entry of [...entries].sort((a, b) => a.file.localeCompare(b.file))) {
+    for (const dir of parentDirs(entry.file)) {
+      if (opened.has(dir)) continue;
+      opened.add(dir);
+      lines.push(dirLine(dir));
+    }
+    lines.push(entry.line);
+  }
+  return lines;
+}
+
+/** `./src/core/` -> `src/core`; the root (`.`, `/`) -> empty */
+function normalizeScope(scope = ''): string {
+  const trimmed = scope.replace(/\\/g, '/').replace(/^\.?\/+|\/+$/g, '');
+  return trimmed === '.' ? '' : trimmed;
+}
+
+async function indexedFiles(rootPath: string): Promise<string[] | null> {
+  try {
+    const raw = await readIndexArtifact(
+      path.join(rootPath, CODEBASE_CONTEXT_DIRNAME),
+      KEYWORD_INDEX_FILENAME
+    );
+    const chunks = (JSON.parse(raw) as { chunks?: CodeChunk[] }).chunks ?? [];
+    return [...new Set(chunks.map((chunk) => chunk.relativePath.replace(/\\/g, '/')))];
+  } catch {
+    return null;
+  }
+}
+
+/** Render the repo map from the index; null when the project has not been indexed. */
+export async function buildRepoMap(
+  rootPath: string,
+  options: RepoMapOptions = {}
+): Promise<RepoMap | null> {
+  const indexed = await indexedFiles(rootPath);
+  if (!indexed) return null;
+  const files = options.include ? indexed.filter(options.include) : indexed;
+
+  const budget = Math.min(
+    Math.max(options.tokenBudget ?? DEFAULT_REPO_MAP_TOKENS, FRAME_TOKENS * 2),
+    MAX_REPO_MAP_TOKENS
+  );
+  const scope = normalizeScope(options.scope);
+  const inScope = scope
+    ? files.filter((file) => file === scope || file.startsWith(`${scope}/`))
+    : files;
+
+  const definitionsByFile = new Map<string, SymbolDefinition[]>();
+  for (const def of (await loadSymbolIndex(rootPath)) ?? []) {
+    const list = definitionsByFile.get(def.file) ?? [];
+    list.push(def);
+    definitionsByFile.set(def.file, list);
+  }
+  const importedBy = (await loadDependencyGraph(rootPath))?.importedBy ?? {};
+
+  const entries: MapEntry[] = inScope.map((file) => {
+    const symbols = topLevelDefinitions(definitionsByFile.get(file) ?? []);
+    const depth = file.split('/').length - 1;
+    const label = symbols.length > 0 ? `: ${symbolLabel(symbols)}` : '';
+    return {
+      file,
+      line: `${INDENT.repeat(depth)}${path.posix.basename(file)}${label}`,
+      importers: importedBy[file]?.length ?? 0,
+      symbols: symbols.length
+    };
+  });
+  entries.sort(
+    (a, b) => b.importers - a.importers || b.symbols - a.symbols || a.file.localeCompare(b.file)
+  );
+
+  // Greedy fill by rank; a file also pays for the directory lines it opens
+  const included: MapEntry[] = [];
+  const opened = new Set<string>();
+  let used = FRAME_TOKENS;
+  for (const entry of entries) {
+    const newDirs = parentDirs(entry.file).filter((dir) => !opened.has(dir));
+    const dirCost = newDirs.reduce((sum, dir) => sum + estimateTokens(dirLine(dir)) + 1, 0);
+    const cost = estimateTokens(entry.line) + 1 + dirCost;
+    if (used + cost > budget) continue;
+    used += cost;
+    newDirs.forEach((dir) => opened.add(dir));
+    included.push(entry);
+  }
+
+  const omitted = entries.length - included.length;
+  const lines = [
+    `# Repo map${scope ? ` (${scope}/)` : ''}: ${included.length} of ${entries.length} files`,
+    ...render(included)
+  ];
+  if (omitted > 0) {
+    lines.push(`... ${omitted} more files; raise tokens or narrow path to see them`);
+  }
+  const text = lines.join('\n');
+  return {
+    text,
+    tokens: estimateTokens(text),
+    totalFiles: entries.length,
+    includedFiles: included.length
+  };
+}
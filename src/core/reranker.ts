@@ -6,14 +6,71 @@
  * converting high top-3 recall into better top-1 accuracy.
  *
  * Default model: Xenova/ms-marco-MiniLM-L-6-v2 (~22M params, ~80MB, CPU-safe).
+ * Opt-in: RERANKER_PROVIDER=cohere|voyage sends (query, passage) pairs to a hosted rerank API.
  */
 
 import type { SearchResult } from '../types/index.js';
+import {
+  applyLocalModelOptions,
+  explainOfflineLoadFailure,
+  localModelOptionsFromEnv
+} from '../embeddings/local-models.js';
 
-const DEFAULT_RERANKER_MODEL = 'Xenova/ms-marco-MiniLM-L-6-v2';
+export const DEFAULT_RERANKER_MODEL = 'Xenova/ms-marco-MiniLM-L-6-v2';
 
-/** How many top results to rerank (keeps latency bounded) */
-const RERANK_TOP_K = 10;
+/** How many top candidates to rerank before the caller's limit is applied */
+export const RERANK_CANDIDATES = 50;
+
+/**
+ * - auto: rerank only when the top scores are clustered (default)
+ * - always: rerank every query
+ * - off: keep first-stage order
+ */
+export type RerankMode = 'auto' | 'always' | 'off';
+
+export const RERANK_MODES: readonly RerankMode[] = ['auto', 'always', 'off'];
+
+export type RerankerProviderName = 'local' | 'cohere' | 'voyage';
+
+export interface RerankerConfig {
+  provider: RerankerProviderName;
+  model: string;
+  apiKey?: string;
+  endpoint?: string;
+}
+
+const API_PROVIDERS: Record<
+  Exclude<RerankerProviderName, 'local'>,
+  { endpoint: string; model: string; keyEnv: string }
+> = {
+  cohere: {
+    endpoint: 'https://api.cohere.com/v2/rerank',
+    model: 'rerank-v3.5',
+    keyEnv: 'COHERE_API_KEY'
+  },
+  voyage: {
+    endpoint: 'https://api.voyageai.com/v1/rerank',
+    model: 'rerank-2-lite',
+    keyEnv: 'VOYAGE_API_KEY'
+  }
+};
+
+export function resolveRerankerConfig(env: NodeJS.ProcessEnv = process.env): RerankerConfig {
+  const provider = env.RERANKER_PROVIDER?.trim().toLowerCase() || 'local';
+  if (provider === 'cohere' || provider === 'voyage') {
+    const defaults = API_PROVIDERS[provider];
+    return {
+      provider,
+      model: env.RERANKER_MODEL?.trim() || defaults.model,
+      apiKey: env.RERANKER_API_KEY?.trim() || env[defaults.keyEnv]?.trim() || undefined,
+      endpoint: env.RERANKER_API_URL?.trim() || defaults.endpoint
+    };
+  }
+  if (provider !== 'local') {
+    console.warn(`[reranker] Unknown RERANKER_PROVIDER '${provider}', using local cross-encoder`);
+  }
+  return { provider: 'local', model: env.RERANKER_MODEL?.trim() || DEFAULT_RERANKER_MODEL };
+}
 
 /** Trigger reranking when the score gap between #1 and #3 is below this threshold */
 const AMBIGUITY_THRESHOLD = 0.08;
@@ -32,23 +89,32 @@ interface CrossEncoderModel {
 
 let cachedTokenizer: CrossEncoderTokenizer | null = null;
 let cachedModel: CrossEncoderModel | null = null;
+let cachedModelName: string | null = null;
 let initPromise: Promise<void> | null = null;
 
-async function ensureModelLoaded(): Promise<void> {
-  if (cachedModel && cachedTokenizer) return;
-  if (initPromise) return initPromise;
+async function ensureModelLoaded(modelName: string = DEFAULT_RERANKER_MODEL): Promise<void> {
+  if (cachedModel && cachedTokenizer && cachedModelName === modelName) return;
+  if (initPromise && cachedModelName === modelName) return initPromise;
+  cachedModelName = modelName;
 
   initPromise = (async () => {
     const { AutoTokenizer, AutoModelForSequenceClassification } =
       await import('@huggingface/transformers');
+    const localModels = localModelOptionsFromEnv();
+    await applyLocalModelOptions(localModels);
 
-    console.error(`[reranker] Loading cross-encoder: ${DEFAULT_RERANKER_MODEL}`);
+    console.error(`[reranker] Loading cross-encoder: ${modelName}`);
     console.error('[reranker] (First run will download the model - this may take a moment)');
 
-    cachedTokenizer = await AutoTokenizer.from_pretrained(DEFAULT_RERANKER_MODEL);
-    cachedModel = await AutoModelForSequenceClassification.from_pretrained(DEFAULT_RERANKER_MODEL, {
-      dtype: 'q8'
-    });
+    try {
+      cachedTokenizer = await AutoTokenizer.from_pretrained(modelName);
+      cachedModel = await AutoModelForSequenceClassification.from_pretrained(modelName, {
+        dtype: 'q8'
+      });
+    } catch (error) {
+      if (localModels.allowDownload !== false) throw error;
+      throw explainOfflineLoadFailure(modelName, localModels, error);
+    }
 
     console.error('[reranker] Cross-encoder loaded successfully');
   })();
@@ -110,6 +176,55 @@ async function scorePair(query: string, passage: string): Promise<number> {
   return score;
 }
 
+// Sigmoid normalizes raw logits to [0,1] so downstream quality gating works
+const sigmoid = (x: number) => 1 / (1 + Math.exp(-x));
+
+async function scoreLocally(model: string, query: string, passages: string[]): Promise<number[]> {
+  await ensureModelLoaded(model);
+  const scores: number[] = [];
+  for (const passage of passages) {
+    scores.push(sigmoid(await scorePair(query, passage)));
+  }
+  return scores;
+}
+
+interface ApiRerankResponse {
+  results?: Array<{ index: number; relevance_score: number }>;
+  data?: Array<{ index: number; relevance_score: number }>;
+}
+
+/** Hosted rerank APIs (Cohere, Voyage) return [0,1] relevance scores per document index. */
+async function scoreWithApi(
+  config: RerankerConfig,
+  query: string,
+  passages: string[]
+): Promise<number[]> {
+  if (!config.apiKey) {
+    throw new Error(`[reranker] ${config.provider} requires RERANKER_API_KEY`);
+  }
+  const response = await fetch(config.endpoint!, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      Authorization: `Bearer ${config.apiKey}`
+    },
+    body: JSON.stringify({ model: config.model, query, documents: passages })
+  });
+  if (!response.ok) {
+    throw new Error(`[reranker] ${config.provider} API error: ${response.status}`);
+  }
+
+  const body = (await response.json()) as ApiRerankResponse;
+  const entries = body.results ?? body.data ?? [];
+  const scores = new Array<number>(passages.length).fill(0);
+  for (const entry of entries) {
+    if (entry.index >= 0 && entry.index < passages.length) {
+      scores[entry.index] = entry.relevance_score;
+    }
+  }
+  return scores;
+}
+
 /**
  * Detect whether the result set has ambiguous ordering.
  * Returns true when the top scores are clustered, meaning
@@ -126,38 +241,38 @@ export function isAmbiguous(results: SearchResult[]): boolean {
 }
 
 /**
- * Rerank the top-K results using a cross-encoder.
- * Only reranks when scores are ambiguous (clustered).
+ * Rerank the top candidates using a cross-encoder (local or hosted).
+ * In `auto` mode only reranks when scores are ambiguous (clustered).
  * Returns the full result array with the top-K portion re-ordered.
  */
-export async function rerank(query: string, results: SearchResult[]): Promise<SearchResult[]> {
-  if (results.length <= 1) return results;
-  if (!isAmbiguous(results)) return results;
-
-  await ensureModelLoaded();
-
-  const toRerank = results.slice(0, Math.min(RERANK_TOP_K, results.length));
+export async function rerank(
+  query: string,
+  results: SearchResult[],
+  mode: RerankMode = 'auto',
+  config: RerankerConfig = resolveRerankerConfig()
+): Promise<SearchResult[]> {
+  if (mode === 'off' || results.length <= 1) return results;
+  if (mode === 'auto' && !isAmbiguous(results)) return results;
+
+  const toRerank = results.slice(0, Math.min(RERANK_CANDIDATES, results.length));
   const rest = results.slice(toRerank.length);
-
-  // Score each result against the query using the cross-encoder
-  const scored: Array<{ result: SearchResult; crossScore: number }> = [];
-
-  for (const result of toRerank) {
-    const passage = buildPassage(result);
-    const crossScore = await scorePair(query, passage);
-    scored.push({ result, crossScore });
-  }
-
-  // Sort by cross-encoder score (descending)
-  scored.sort((a, b) => b.crossScore - a.crossScore);
-
-  // Rebuild the result array: reranked top-K + unchanged rest
-  // Sigmoid normalizes raw logits to [0,1] so downstream quality gating works
-  const sigmoid = (x: number) => 1 / (1 + Math.exp(-x));
-  const reranked = scored.map(({ result, crossScore }) => ({
-    ...result,
-    score: sigmoid(crossScore)
-  }));
+  const passages = toRerank.map(buildPassage);
+
+  const scores =
+    config.provider === 'local'
+      ? await scoreLocally(config.model, query, passages)
+      : await scoreWithApi(config, query, passages);
+
+  // Rebuild the result array: reranked top-K (by cross-encoder score) + unchanged rest
+  const reranked = toRerank
+    .map((result, i) => ({
+      ...result,
+      score: scores[i],
+      ...(result.scoreBreakdown && {
+        scoreBreakdown: { ...result.scoreBreakdown, rerankScore: scores[i] }
+      })
+    }))
+    .sort((a, b) => b.score - a.score);
 
   return [...reranked, ...rest];
 }
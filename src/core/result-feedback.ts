@@ -0,0 +1,238 @@
+/**
+ * Result feedback: which chunks a search returned, and what the agent thought of them.
+ *
+ * Every `search_codebase` call appends its query and results to
+ * `.codebase-context/access-log.jsonl` under a `queryId`; `rate_result` records a thumbs up or
+ * down for one of those results in `.codebase-context/feedback.json`. Both files stay on disk
+ * for teams to analyse which retrievals helped. Ratings also become a small ranking boost per
+ * chunk: `1 + weight * (up - down) / (up + down + 1)`, so one rating moves a chunk by half the
+ * weight and many agreeing ones by nearly all of it. The weight is `search.feedback.weight` in
+ * `.codebase-context/config.json` (default 0.1, 0 turns the boost off); `search.feedback.log:
+ * false` stops the access log.
+ *
+ * Chunks are keyed by file and symbol path (file and line range for chunks without a symbol),
+ * so a rating still applies after edits elsewhere in the file move the chunk.
+ */
+
+import { randomBytes } from 'crypto';
+import { promises as fs } from 'fs';
+import path from 'path';
+import {
+  ACCESS_LOG_FILENAME,
+  CODEBASE_CONTEXT_DIRNAME,
+  FEEDBACK_FILENAME,
+  PROJECT_CONFIG_FILENAME
+} from '../constants/codebase-context.js';
+
+export const DEFAULT_FEEDBACK_WEIGHT = 0.1;
+const MAX_FEEDBACK_WEIGHT = 0.5;
+/** Searches kept in the access log; it is cut back to this once it grows a fifth past it */
+const MAX_ACCESS_LOG_ENTRIES = 1000;
+/** Ratings kept; the oldest go first */
+const MAX_RATINGS = 5000;
+const MAX_NOTE_LENGTH = 500;
+
+export type Rating = 'up' | 'down';
+
+export interface FeedbackConfig {
+  weight: number;
+  log: boolean;
+}
+
+export interface AccessedResult {
+  /** Repo-relative path */
+  file: string;
+  startLine: number;
+  endLine: number;
+  /** Feedback key, see feedbackKey */
+  key: string;
+}
+
+export interface AccessLogEntry {
+  queryId: string;
+  at: string;
+  query: string;
+  results: AccessedResult[];
+}
+
+export interface RatingEntry {
+  queryId: string;
+  query: string;
+  key: string;
+  file: string;
+  lines: string;
+  rating: Rating;
+  note?: string;
+  at: string;
+}
+
+interface FeedbackFile {
+  version: 1;
+  ratings: RatingEntry[];
+}
+
+export interface FeedbackTotals {
+  up: number;
+  down: number;
+}
+
+/** Stable id of a chunk across rebuilds: its symbol path, else its line range */
+export function feedbackKey(
+  relativePath: string,
+  startLine: number,
+  endLine: number,
+  symbolPath?: string[]
+): string {
+  const file = relativePath.replace(/\\/g, '/');
+  return symbolPath && symbolPath.length > 0
+    ? `${file}#${symbolPath.join('.')}`
+    : `${file}:${startLine}-${endLine}`;
+}
+
+/** The `search.feedback` key of `.codebase-context/config.json` */
+export async function loadFeedbackConfig(rootPath: string): Promise<FeedbackConfig> {
+  const config: FeedbackConfig = { weight: DEFAULT_FEEDBACK_WEIGHT, log: true };
+  try {
+    const configPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME);
+    const parsed = JSON.parse(await fs.readFile(configPath, 'utf-8')) as { search?: unknown };
+    const feedback = (parsed.search as { feedback?: unknown } | undefined)?.feedback;
+    if (!feedback || typeof feedback !== 'object') return config;
+    const { weight, log } = feedback as Record<string, unknown>;
+    if (typeof weight === 'number' && weight >= 0) {
+      config.weight = Math.min(weight, MAX_FEEDBACK_WEIGHT);
+    }
+    if (typeof log === 'boolean') config.log = log;
+  } catch {
+    // No config: defaults
+  }
+  return config;
+}
+
+export function feedbackBoost(totals: FeedbackTotals | undefined, weight: number): number {
+  if (!totals || weight <= 0) return 1;
+  return 1 + (weight * (totals.up - totals.down)) / (totals.up + totals.down + 1);
+}
+
+async function readAccessLog(contextDir: string): Promise<AccessLogEntry[]> {
+  let content: string;
+  try {
+    content = await fs.readFile(path.join(contextDir, ACCESS_LOG_FILENAME), 'utf-8');
+  } catch {
+    return [];
+  }
+  const entries: AccessLogEntry[] = [];
+  for (const line of content.split('\n')) {
+    if (!line.trim()) continue;
+    try {
+      entries.push(JSON.parse(line) as AccessLogEntry);
+    } catch {
+      // A line cut short by a crash only loses that search
+    }
+  }
+  return entries;
+}
+
+async function writeAtomically(file: string, content: string): Promise<void> {
+  const tmp = `${file}.${process.pid}.tmp`;
+  await fs.writeFile(tmp, content);
+  await fs.rename(tmp, file);
+}
+
+/** Append a search to the access log; returns its queryId */
+export async function logSearchAccess(
+  contextDir: string,
+  query: string,
+  results: AccessedResult[]
+): Promise<string> {
+  const entry: AccessLogEntry = {
+    queryId: randomBytes(6).toString('hex'),
+    at: new Date().toISOString(),
+    query,
+    results
+  };
+  const file = path.join(contextDir, ACCESS_LOG_FILENAME);
+  await fs.mkdir(contextDir, { recursive: true });
+  await fs.appendFile(file, `${JSON.stringify(entry)}\n`);
+
+  const stat = await fs.stat(file);
+  // Cheap check first: entries are a few hundred bytes
+  if (stat.size > MAX_ACCESS_LOG_ENTRIES * 200) {
+    const entries = await readAccessLog(contextDir);
+    if (entries.length > MAX_ACCESS_LOG_ENTRIES * 1.2) {
+      const kept = entries.slice(-MAX_ACCESS_LOG_ENTRIES);
+      await writeAtomically(file, kept.map((entry) => JSON.stringify(entry)).join('\n') + '\n');
+    }
+  }
+  return entry.queryId;
+}
+
+export async function findAccessLogEntry(
+  contextDir: string,
+  queryId: string
+): Promise<AccessLogEntry | undefined> {
+  const entries = await readAccessLog(contextDir);
+  for (let i = entries.length - 1; i >= 0; i--) {
+    if (entries[i].queryId === queryId) return entries[i];
+  }
+  return undefined;
+}
+
+async function readFeedbackFile(contextDir: string): Promise<FeedbackFile> {
+  try {
+    const parsed = JSON.parse(
+      await fs.readFile(path.join(contextDir, FEEDBACK_FILENAME), 'utf-8')
+    ) as FeedbackFile;
+    if (parsed.version === 1 && Array.isArray(parsed.ratings)) return parsed;
+  } catch {
+    // Missing or unreadable: start over
+  }
+  return { version: 1, ratings: [] };
+}
+
+/** Up and down votes per feedback key */
+export async function loadFeedbackTotals(contextDir: string): Promise<Map<string, FeedbackTotals>> {
+  const totals = new Map<string, FeedbackTotals>();
+  for (const rating of (await readFeedbackFile(contextDir)).ratings) {
+    const entry = totals.get(rating.key) ?? { up: 0, down: 0 };
+    entry[rating.rating] += 1;
+    totals.set(rating.key, entry);
+  }
+  return totals;
+}
+
+/**
+ * Record a rating of one result of a logged search. Rating the same result of the same
+ * search again replaces the earlier rating. Returns the result's totals afterwards.
+ */
+export async function recordRating(
+  contextDir: string,
+  search: AccessLogEntry,
+  result: AccessedResult,
+  rating: Rating,
+  note?: string
+): Promise<FeedbackTotals> {
+  const feedback = await readFeedbackFile(contextDir);
+  feedback.ratings = feedback.ratings.filter(
+    (entry) => !(entry.queryId === search.queryId && entry.key === result.key)
+  );
+  const trimmed = note?.trim().slice(0, MAX_NOTE_LENGTH);
+  feedback.ratings.push({
+    queryId: search.queryId,
+    query: search.query,
+    key: result.key,
+    file: result.file,
+    lines: `${result.startLine}-${result.endLine}`,
+    rating,
+    ...(trimmed ? { note: trimmed } : {}),
+    at: new Date().toISOString()
+  });
+  feedback.ratings = feedback.ratings.slice(-MAX_RATINGS);
+  await fs.mkdir(contextDir, { recursive: true });
+  await writeAtomically(path.join(contextDir, FEEDBACK_FILENAME), JSON.stringify(feedback));
+
+  const totals: FeedbackTotals = { up: 0, down: 0 };
+  for (const entry of feedback.ratings) {
+    if (entry.key === result.key) totals[entry.rating] += 1;
+  }
+  return totals;
+}
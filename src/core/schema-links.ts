@@ -0,0 +1,263 @@
+/**
+ * Links code back to the API schema it implements, as import-graph edges to the `.proto`,
+ * OpenAPI or GraphQL file, so a hit on an RPC's contract lists its handlers as importers and
+ * the other way round:
+ *
+ * - generated code, by file name: `user.pb.go`, `user_grpc.pb.go`, `user_pb2.py`,
+ *   `user_pb2_grpc.py`, `user_pb.js`, `user.pb.cc`, `user_connect.ts` -> `user.proto`
+ * - rpc handlers: files defining a function or method named after an rpc (`GetUser`,
+ *   `getUser`, `get_user`) that also mention its service or request message
+ * - OpenAPI handlers: files defining a function or method named after an `operationId`
+ * - GraphQL resolvers of `Query`/`Mutation`/`Subscription` fields: functions or methods named
+ *   after the field (`orders`, `resolveOrders`, `resolve_orders`, `ordersResolver`) in files
+ *   that mention resolvers or GraphQL, decorators naming it (`@Query(() => [Order], { name:
+ *   'orders' })`, `@ResolveField('orders')`, `@query.field("orders")`) and resolver-map keys
+ *   (`Query: { orders: ... }`) in files defining a `*Resolver(s)` symbol
+ *
+ * Handlers are matched by name, not resolved, so those edges are candidates.
+ */
+
+import path from 'path';
+import type { SchemaBlock } from '../utils/schema-chunker.js';
+import type { SymbolDefinition } from './symbol-index.js';
+
+export interface SchemaEdge {
+  /** Repo-relative posix paths */
+  from: string;
+  to: string;
+  line: number;
+  symbols: string[];
+}
+
+const GENERATED_CODE = [
+  /^(.+?)(?:_grpc)?\.pb(?:\.gw)?\.go$/,
+  /^(.+?)_pb2(?:_grpc)?\.pyi?$/,
+  /^(.+?)(?:_grpc)?_pb\.(?:js|ts|d\.ts)$/,
+  /^(.+?)(?:\.grpc)?\.pb\.(?:cc|h|swift)$/,
+  /^(.+?)_connect\.(?:ts|js)$/
+];
+const HANDLER_KINDS = new Set(['function', 'method']);
+/** Short operationIds (`get`, `list`) match too much to be useful */
+const MIN_NAME_LENGTH = 4;
+/** Files defining one of these (`resolvers`, `OrdersResolver`, `queryResolver`) hold resolvers */
+const RESOLVER_HOLDER = /resolvers?$/i;
+/** A field-named function is a resolver only in a file that says so */
+const GRAPHQL_MARKER = /resolver|graphql|\bgql\b|@(?:Query|Mutation|Subscription)\b/i;
+/** NestJS/type-graphql `@Query('orders')`, `@Query(() => X, { name: 'orders' })` */
+const RESOLVER_DECORATOR =
+  /@(?:Query|Mutation|Subscription|ResolveField|FieldResolver)\s*\((?:\s*['"](\w+)['"]|[^\n]*?\bname\s*:\s*['"](\w+)['"])/g;
+/** Ariadne `@query.field("orders")` */
+const FIELD_DECORATOR = /@\w+\.field\(\s*['"](\w+)['"]/g;
+const RESOLVER_MAP_KEY = /^\s*(?:async\s+)?['"]?([A-Za-z_$][\w$]*)['"]?\s*[:(]/;
+
+/** `GetUser`, `getUser` and `get_user` compare equal */
+const normalizeName = (name: string) => name.replace(/_/g, '').toLowerCase();
+const escapeRegExp = (value: string) => value.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+
+interface RpcTarget {
+  file: string;
+  name: string;
+  service: string;
+  requestType?: string;
+}
+
+interface FieldTarget {
+  file: string;
+  name: string;
+  parentType: string;
+}
+
+/** Field keys a resolver's name stands for: `resolveOrders`/`ordersResolver` -> `orders` */
+function resolverKeys(name: string): string[] {
+  const key = normalizeName(name);
+  return [
+    ...new Set([key, key.replace(/^resolve(?=.)/, ''), key.replace(/(?<=.)resolver$/, '')])
+  ];
+}
+
+/** 1-based line of `offset` */
+const lineAt = (content: string, offset: number) =>
+  content.slice(0, offset).split('\n').length;
+
+/** `Query: { orders: ..., order(...) {...} }` keys of the resolver maps for `types` */
+function resolverMapKeys(
+  content: string,
+  types: Set<string>
+): Array<{ type: string; name: string; line: number }> {
+  const keys: Array<{ type: string; name: string; line: number }> = [];
+  const names = [...types].map(escapeRegExp).join('|');
+  if (!names) return keys;
+  const mapStart = new RegExp(`['"]?\\b(${names})['"]?\\s*:\\s*\\{`, 'g');
+  for (const match of content.matchAll(mapStart)) {
+    let depth = 0;
+    // Keys start lines, or directly follow the map's `{`
+    let keyStart = false;
+    for (let i = (match.index ?? 0) + match[0].length - 1; i < content.length; i++) {
+      const ch = content[i];
+      if (keyStart && depth === 1) {
+        const eol = content.indexOf('\n', i);
+        const key = RESOLVER_MAP_KEY.exec(content.slice(i, eol < 0 ? undefined : eol));
+        if (key) keys.push({ type: match[1], name: key[1], line: lineAt(content, i) });
+      }
+      keyStart = ch === '\n' || (ch === '{' && depth === 0);
+      if (ch === '{') depth++;
+      else if (ch === '}' && --depth === 0) break;
+    }
+  }
+  return keys;
+}
+
+export class SchemaLinker {
+  private files: string[];
+  private protoByBasename = new Map<string, string[]>();
+  private rpcs = new Map<string, RpcTarget[]>();
+  private operations = new Map<string, Array<{ file: string; name: string }>>();
+  private fields = new Map<string, FieldTarget[]>();
+  private schemaFiles = new Set<string>();
+
+  /** `files` are the indexed repo-relative posix paths */
+  constructor(files: Iterable<string>) {
+    this.files = [...files];
+  }
+
+  trackSchema(relativeFile: string, blocks: SchemaBlock[]): void {
+    this.schemaFiles.add(relativeFile);
+    if (relativeFile.endsWith('.proto')) {
+      const base = path.posix.basename(relativeFile);
+      this.protoByBasename.set(base, [...(this.protoByBasename.get(base) ?? []), relativeFile]);
+    }
+    for (const { schema } of blocks) {
+      if (schema.type === 'rpc' && schema.service) {
+        const key = normalizeName(schema.name);
+        const target = {
+          file: relativeFile,
+          name: schema.name,
+          service: schema.service,
+          requestType: schema.requestType?.split('.').pop()
+        };
+        this.rpcs.set(key, [...(this.rpcs.get(key) ?? []), target]);
+      } else if (schema.type === 'field' && schema.parentType) {
+        const key = normalizeName(schema.name);
+        const target = { file: relativeFile, name: schema.name, parentType: schema.parentType };
+        this.fields.set(key, [...(this.fields.get(key) ?? []), target]);
+      } else if (schema.operationId && schema.operationId.length >= MIN_NAME_LENGTH) {
+        const key = normalizeName(schema.operationId);
+        const target = { file: relativeFile, name: schema.operationId };
+        this.operations.set(key, [...(this.operations.get(key) ?? []), target]);
+      }
+    }
+  }
+
+  /** `.proto` a generated file came from: beside it, else the only one with that name */
+  private protoForGenerated(file: string): string | null {
+    const base = path.posix.basename(file);
+    const stem = GENERATED_CODE.map((pattern) => pattern.exec(base)?.[1]).find(Boolean);
+    if (!stem) return null;
+    const beside = path.posix.join(path.posix.dirname(file), `${stem}.proto`);
+    if (this.schemaFiles.has(beside)) return beside;
+    const candidates = this.protoByBasename.get(`${stem}.proto`) ?? [];
+    return candidates.length === 1 ? candidates[0] : null;
+  }
+
+  /**
+   * Edges from generated code, handlers and resolvers to their schema files, once every file
+   * has been tracked. `readFile` loads a repo-relative file for the service/request-type and
+   * resolver checks.
+   */
+  async edges(
+    definitions: SymbolDefinition[],
+    readFile: (relativeFile: string) => Promise<string>
+  ): Promise<SchemaEdge[]> {
+    if (this.schemaFiles.size === 0) return [];
+    const edges = new Map<string, SchemaEdge>();
+    const add = (from: string, to: string, line: number, symbol?: string) => {
+      if (from === to) return;
+      const key = `${from}\0${to}`;
+      const edge = edges.get(key) ?? { from, to, line, symbols: [] };
+      edge.line = Math.min(edge.line, line);
+      if (symbol && !edge.symbols.includes(symbol)) edge.symbols.push(symbol);
+      edges.set(key, edge);
+    };
+
+    const generated = new Set<string>();
+    for (const file of this.files) {
+      const proto = this.protoForGenerated(file);
+      if (proto) {
+        generated.add(file);
+        add(file, proto, 1);
+      }
+    }
+
+    const rpcCandidates = new Map<string, Array<{ def: SymbolDefinition; rpc: RpcTarget }>>();
+    const fieldCandidates = new Map<string, Array<{ def: SymbolDefinition; field: FieldTarget }>>();
+    const resolverHolders = new Set<string>();
+    for (const def of definitions) {
+      if (this.schemaFiles.has(def.file) || generated.has(def.file)) continue;
+      if (this.fields.size > 0 && RESOLVER_HOLDER.test(def.name)) resolverHolders.add(def.file);
+      if (!HANDLER_KINDS.has(def.kind)) continue;
+      for (const fieldKey of def.name.length >= MIN_NAME_LENGTH ? resolverKeys(def.name) : []) {
+        for (const field of this.fields.get(fieldKey) ?? []) {
+          fieldCandidates.set(def.file, [...(fieldCandidates.get(def.file) ?? []), { def, field }]);
+        }
+      }
+      const key = normalizeName(def.name);
+      for (const operation of this.operations.get(key) ?? []) {
+        add(def.file, operation.file, def.startLine, operation.name);
+      }
+      for (const rpc of this.rpcs.get(key) ?? []) {
+        rpcCandidates.set(def.file, [...(rpcCandidates.get(def.file) ?? []), { def, rpc }]);
+      }
+    }
+
+    // An rpc-named method is a handler only if the file also mentions the service or request
+    for (const [file, candidates] of rpcCandidates) {
+      let content: string;
+      try {
+        content = await readFile(file);
+      } catch {
+        continue;
+      }
+      for (const { def, rpc } of candidates) {
+        const names = [`${escapeRegExp(rpc.service)}\\w*`];
+        if (rpc.requestType) names.push(escapeRegExp(rpc.requestType));
+        if (new RegExp(`\\b(?:${names.join('|')})\\b`).test(content)) {
+          add(file, rpc.file, def.startLine, rpc.name);
+        }
+      }
+    }
+
+    // Resolvers: field-named functions in GraphQL code, decorators and resolver maps
+    const parentTypes = new Set([...this.fields.values()].flat().map((field) => field.parentType));
+    for (const file of new Set([...fieldCandidates.keys(), ...resolverHolders])) {
+      let content: string;
+      try {
+        content = await readFile(file);
+      } catch {
+        continue;
+      }
+      if (!GRAPHQL_MARKER.test(content)) continue;
+      for (const { def, field } of fieldCandidates.get(file) ?? []) {
+        add(file, field.file, def.startLine, field.name);
+      }
+      if (!resolverHolders.has(file)) continue;
+      for (const match of [
+        ...content.matchAll(RESOLVER_DECORATOR),
+        ...content.matchAll(FIELD_DECORATOR)
+      ]) {
+        const name = match[1] ?? match[2];
+        for (const field of this.fields.get(normalizeName(name)) ?? []) {
+          if (field.name === name) add(file, field.file, lineAt(content, match.index ?? 0), name);
+        }
+      }
+      for (const key of resolverMapKeys(content, parentTypes)) {
+        for (const field of this.fields.get(normalizeName(key.name)) ?? []) {
+          if (field.name === key.name && field.parentType === key.type) {
+            add(file, field.file, key.line, key.name);
+          }
+        }
+      }
+    }
+
+    return [...edges.values()];
+  }
+}
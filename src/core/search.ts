@@ -5,15 +5,86 @@
 import Fuse from 'fuse.js';
 import path from 'path';
 import { promises as fs } from 'fs';
-import { CodeChunk, SearchResult, SearchFilters, IntelligenceData } from '../types/index.js';
-import { EmbeddingProvider, getEmbeddingProvider } from '../embeddings/index.js';
-import { VectorStorageProvider, getStorageProvider } from '../storage/index.js';
+import {
+  ChunkMetadata,
+  CodeChunk,
+  IntelligenceData,
+  MetadataFilterValue,
+  ScoreBreakdown,
+  SearchFilters,
+  SearchResult
+} from '../types/index.js';
+import {
+  EmbeddingConfig,
+  EmbeddingProvider,
+  providerFingerprint,
+  getEmbeddingProvider
+} from '../embeddings/index.js';
+import {
+  VectorStorageProvider,
+  getStorageProvider,
+  storageCapabilities
+} from '../storage/index.js';
 import { analyzerRegistry } from './analyzer-registry.js';
-import { IndexCorruptedError } from '../errors/index.js';
+import { EmbeddingCollectionError, IndexCorruptedError, IndexKeyError } from '../errors/index.js';
+import { openIndexedChunks, openSearchResults } from './index-encryption.js';
+import { readConsistently } from './index-lock.js';
 import { isTestingRelatedQuery } from '../preflight/query-scope.js';
 import { assessSearchQuality } from './search-quality.js';
-import { rerank } from './reranker.js';
-import { type IndexMeta, readIndexMeta, validateIndexArtifacts } from './index-meta.js';
+import { rerank, RERANK_CANDIDATES, type RerankMode } from './reranker.js';
+import { BM25Index } from './bm25.js';
+import {
+  diversifyResults,
+  loadProjectDiversityConfig,
+  resolveDiversity,
+  type DiversityOptions
+} from './diversity.js';
+import {
+  isRecencyBoostEnabled,
+  loadProjectRecencyConfig,
+  recencyBoostFactors,
+  resolveRecencyBoost,
+  type RecencyBoostOptions
+} from './recency-boost.js';
+import {
+  feedbackBoost,
+  feedbackKey,
+  loadFeedbackConfig,
+  loadFeedbackTotals,
+  type FeedbackTotals
+} from './result-feedback.js';
+import { matchesFileFilters, resolveFileScope, type FileScope } from './file-filters.js';
+import { matchesChunkFilters, planVectorQuery } from './vector-query-plan.js';
+import {
+  literalTokens,
+  loadPrefilterConfig,
+  TrigramIndex,
+  type PrefilterConfig
+} from './trigram-prefilter.js';
+import {
+  type IndexMeta,
+  describeEmbeddingDrift,
+  readIndexMeta,
+  validateIndexArtifacts
+} from './index-meta.js';
+import {
+  collectionStorageConfig,
+  getCollectionDir,
+  loadEmbeddingCollectionSpecs,
+  readEmbeddingCollection
+} from './embedding-collections.js';
+import { getRefContextDir } from '../utils/git-tree.js';
+import { rrfContribution } from '../utils/rrf.js';
+import { metrics } from './telemetry.js';
+import { readTombstones, type Tombstone } from './tombstones.js';
+import { layerOverlay, overlayCovers, type OverlayIndex } from './overlay-index.js';
+import { buildEmbeddingInput } from './indexer.js';
+import { loadChunkPostProcessors } from './chunk-post-processors.js';
+import {
+  activeModelMigration,
+  previousModelConfig,
+  type ModelMigration
+} from './model-migration.js';
 import {
   CODEBASE_CONTEXT_DIRNAME,
   INTELLIGENCE_FILENAME,
@@ -32,10 +103,70 @@ export interface SearchOptions {
   candidateFloor?: number;
   /** Enable stage-2 cross-encoder reranking when top scores are ambiguous. Default: true. */
   enableReranker?: boolean;
+  /** Per-query reranking: auto (ambiguous only, default), always, or off */
+  rerank?: RerankMode;
+  /** Attach a `scoreBreakdown` to each result and record a `SearchTrace` (see `getLastTrace`) */
+  debug?: boolean;
+  /** Rewritten phrasings of the query (see query-rewrite.ts), retrieved and fused with it */
+  queryRewrites?: string[];
+  /** Per-file cap and MMR trade-off; overrides `search.diversity` from the project config */
+  diversity?: DiversityOptions;
+  /** Boost for recently changed and often edited files; overrides `search.recency` */
+  recency?: RecencyBoostOptions;
+  /** Once aborted, the low-confidence rescue and reranking are skipped (first-stage ranking) */
+  signal?: AbortSignal;
+}
+
+/** How a debug search was run: routing, weights and which stages changed the ranking */
+export interface SearchTrace {
+  intent: string;
+  semanticWeight: number;
+  keywordWeight: number;
+  /** Query variants retrieved for the kept result set */
+  queryVariants: string[];
+  /** The low-confidence rescue (broader variants) replaced the primary results */
+  rescued: boolean;
+  rerank: RerankMode;
+  /** Whether the cross-encoder actually reordered candidates (auto skips clear rankings) */
+  reranked: boolean;
+  candidates: number;
+  /** Files the literal pre-filter scoped the vector channel to (see trigram-prefilter.ts) */
+  prefilter?: { literals: string[]; files: number };
 }
 
 export type SearchIntentProfile = 'explore' | 'edit' | 'refactor' | 'migrate';
 
+export interface SearcherOptions {
+  /** Search the index built for this git ref (see `refresh_index` with `ref`) */
+  ref?: string;
+  /** Read a standalone index from this directory instead (see `IndexerOptions.contextDir`) */
+  contextDir?: string;
+  /** Embedding provider/model to embed queries with; must match the one the index was built with */
+  embedding?: Partial<EmbeddingConfig>;
+  /** Embedding collection for the vector channel (see embedding-collections.ts) */
+  collection?: string;
+  /**
+   * Search these chunks instead of the index on disk, by keyword only: the partial results of
+   * a first build still in progress (see `CodebaseIndexer.getPartialChunks`)
+   */
+  chunks?: readonly CodeChunk[];
+  /**
+   * Pending changes layered over the index for this searcher only: their files replace the
+   * indexed ones in both channels (see overlay-index.ts)
+   */
+  overlay?: OverlayIndex;
+}
+
+/** The embedding collection a search ran against */
+export interface SearchCollectionInfo {
+  name: string;
+  provider: string;
+  model: string;
+  chunks: number;
+  /** Built from an older index; vectors of files changed since may be missing or outdated */
+  stale: boolean;
+}
+
 type QueryIntent = 'EXACT_NAME' | 'CONCEPTUAL' | 'FLOW' | 'CONFIG' | 'WIRING';
 
 interface QueryVariant {
@@ -43,6 +174,13 @@ interface QueryVariant {
   weight: number;
 }
 
+interface RankedMatch {
+  chunk: CodeChunk;
+  ranks: Array<{ rank: number; weight: number; score: number }>;
+}
+
+type RankedMatches = Map<string, RankedMatch>;
+
 interface IntentWeights {
   semantic: number;
   keyword: number;
@@ -61,6 +199,32 @@ const DEFAULT_SEARCH_OPTIONS: SearchOptions = {
   enableReranker: true
 };
 
+const round3 = (value: number): number => Math.round(value * 1000) / 1000;
+
+/** Weight of each caller-supplied query rewrite relative to the original query */
+const QUERY_REWRITE_WEIGHT = 0.6;
+
+/** Best-ranked hit of a chunk in one retrieval channel, across query variants */
+function cosineSimilarity(a: number[], b: number[]): number {
+  if (a.length !== b.length) return 0;
+  let dot = 0;
+  let normA = 0;
+  let normB = 0;
+  for (let i = 0; i < a.length; i++) {
+    dot += a[i] * b[i];
+    normA += a[i] * a[i];
+    normB += b[i] * b[i];
+  }
+  const denom = Math.sqrt(normA) * Math.sqrt(normB);
+  return denom === 0 ? 0 : dot / denom;
+}
+
+function bestHit(match: RankedMatch | undefined): { score: number; rank: number } | undefined {
+  if (!match || match.ranks.length === 0) return undefined;
+  const best = match.ranks.reduce((a, b) => (b.rank < a.rank ? b : a));
+  return { score: round3(best.score), rank: best.rank };
+}
+
 const QUERY_EXPANSION_HINTS: Array<{ pattern: RegExp; terms: string[] }> = [
   {
     pattern: /\b(auth|authentication|login|signin|sign-in|session|token|oauth)\b/i,
@@ -108,14 +272,59 @@ const QUERY_STOP_WORDS = new Set([
   'before'
 ]);
 
+function matchesDotnetProject(chunk: CodeChunk, project: string): boolean {
+  return chunk.metadata?.dotnetProject?.toLowerCase() === project.toLowerCase();
+}
+
+/** Value at a dotted path of chunk metadata (`annotations.deprecated`), or undefined */
+function metadataValue(metadata: ChunkMetadata | undefined, fieldPath: string): unknown {
+  let value: unknown = metadata;
+  for (const key of fieldPath.split('.')) {
+    if (!value || typeof value !== 'object' || !Object.hasOwn(value, key)) return undefined;
+    value = (value as Record<string, unknown>)[key];
+  }
+  return value;
+}
+
+function matchesMetadataField(value: unknown, expected: MetadataFilterValue): boolean {
+  if (typeof expected === 'boolean') {
+    const present =
+      value !== undefined && value !== null && !(Array.isArray(value) && value.length === 0);
+    // Boolean fields (isStandalone, symbolAware) compare by value; others test presence
+    return typeof value === 'boolean' ? value === expected : present === expected;
+  }
+  const wanted = Array.isArray(expected) ? expected : [expected];
+  const actual = Array.isArray(value) ? value : [value];
+  return actual.some((item) => wanted.some((w) => item === w || String(item) === String(w)));
+}
+
+/** Filters on chunk metadata that storage backends can't apply themselves */
+function matchesMetadataFilters(chunk: CodeChunk, filters?: SearchFilters): boolean {
+  if (filters?.dotnetProject && !matchesDotnetProject(chunk, filters.dotnetProject)) return false;
+  if (filters?.package && chunk.metadata?.package !== filters.package) return false;
+  if (filters?.role && !chunk.metadata?.roles?.includes(filters.role)) return false;
+  for (const [field, expected] of Object.entries(filters?.metadata ?? {})) {
+    if (!matchesMetadataField(metadataValue(chunk.metadata, field), expected)) return false;
+  }
+  return true;
+}
+
 export class CodebaseSearcher {
   private rootPath: string;
+  private contextDir: string;
+  private standaloneIndex: boolean;
+  private embeddingConfig: Partial<EmbeddingConfig>;
   private storagePath: string;
+  private collectionName?: string;
+  private collection: SearchCollectionInfo | null = null;
+  private migration: ModelMigration | null = null;
 
   private indexMeta: IndexMeta | null = null;
 
   private fuseIndex: Fuse<CodeChunk> | null = null;
+  private bm25Index: BM25Index | null = null;
   private chunks: CodeChunk[] = [];
+  private chunksById = new Map<string, CodeChunk>();
 
   private embeddingProvider: EmbeddingProvider | null = null;
   private storageProvider: VectorStorageProvider | null = null;
@@ -130,41 +339,151 @@ export class CodebaseSearcher {
   } | null = null;
 
   private importCentrality: Map<string, number> | null = null;
-
-  constructor(rootPath: string) {
+  private lastTrace: SearchTrace | null = null;
+  private projectDiversity: DiversityOptions | undefined;
+  private projectRecency: RecencyBoostOptions | undefined;
+  private maxRecentCommits = 0;
+  private feedbackWeight = 0;
+  private feedbackTotals = new Map<string, FeedbackTotals>();
+  private prefilter: PrefilterConfig | null = null;
+  private trigramIndex: TrigramIndex | null = null;
+  private lastPrefilter: SearchTrace['prefilter'];
+  private tombstones = new Map<string, Tombstone>();
+  private indexedFiles: Set<string> | null = null;
+  private partialChunks: readonly CodeChunk[] | null = null;
+  private overlay: OverlayIndex | null = null;
+  /** Overlay chunk vectors, embedded with the query model on the first semantic search */
+  private overlayVectors: Promise<Map<string, number[]>> | null = null;
+
+  // File-level filters resolve to the same scope for every variant of one search
+  private fileScopes = new WeakMap<SearchFilters, FileScope | null>();
+
+  constructor(rootPath: string, options: SearcherOptions = {}) {
     this.rootPath = rootPath;
-    this.storagePath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, VECTOR_DB_DIRNAME);
+    this.contextDir = options.contextDir
+      ? path.resolve(options.contextDir)
+      : options.ref
+        ? getRefContextDir(rootPath, options.ref)
+        : path.join(rootPath, CODEBASE_CONTEXT_DIRNAME);
+    this.standaloneIndex = Boolean(options.contextDir);
+    this.embeddingConfig = options.embedding ?? {};
+    this.storagePath = path.join(this.contextDir, VECTOR_DB_DIRNAME);
+    this.collectionName = options.collection?.trim() || undefined;
+    this.partialChunks = options.chunks ?? null;
+    this.overlay = options.overlay ?? null;
   }
 
   async initialize(): Promise<void> {
     if (this.initialized) return;
+    this.projectDiversity = await loadProjectDiversityConfig(this.rootPath);
+    this.projectRecency = await loadProjectRecencyConfig(this.rootPath);
+    this.prefilter = await loadPrefilterConfig(this.rootPath);
+    this.feedbackWeight = (await loadFeedbackConfig(this.rootPath)).weight;
+    if (this.feedbackWeight > 0) {
+      // Ratings belong to the project, whichever ref or collection is searched
+      this.feedbackTotals = await loadFeedbackTotals(
+        this.standaloneIndex ? this.contextDir : path.join(this.rootPath, CODEBASE_CONTEXT_DIRNAME)
+      );
+    }
+
+    if (this.partialChunks) {
+      this.indexChunks(this.withOverlay([...this.partialChunks]));
+      this.initialized = true;
+      return;
+    }
 
     try {
       // Fail closed on version mismatch/corruption before serving any results.
-      this.indexMeta = await readIndexMeta(this.rootPath);
-      await validateIndexArtifacts(this.rootPath, this.indexMeta);
+      this.indexMeta = await readIndexMeta(this.rootPath, this.contextDir);
+      await validateIndexArtifacts(this.rootPath, this.indexMeta, this.contextDir);
 
       await this.loadKeywordIndex();
       await this.loadPatternIntelligence();
+      this.tombstones = await readTombstones(this.contextDir);
 
-      this.embeddingProvider = await getEmbeddingProvider();
+      if (this.collectionName) {
+        await this.openCollection(this.collectionName, this.indexMeta);
+        this.initialized = true;
+        return;
+      }
+
+      // While a build re-embeds with a newly configured model, queries are embedded with the
+      // model the active vectors came from until it swaps in
+      this.migration = await activeModelMigration(this.contextDir, this.indexMeta.embedding);
+      this.embeddingProvider = await getEmbeddingProvider(
+        this.migration ? previousModelConfig(this.migration) : this.embeddingConfig
+      );
+      // Query vectors from a different model would rank nonsense against the stored ones
+      const drift = describeEmbeddingDrift(
+        this.indexMeta.embedding,
+        providerFingerprint(this.embeddingProvider)
+      );
+      if (drift) throw new IndexCorruptedError(drift);
       this.storageProvider = await getStorageProvider({
-        path: this.storagePath
+        path: this.storagePath,
+        // Ref indexes get their own remote collection, derived from their context dir
+        rootPath: this.indexMeta.gitRef || this.standaloneIndex ? this.contextDir : this.rootPath
       });
 
       this.initialized = true;
     } catch (error) {
-      if (error instanceof IndexCorruptedError) {
-        throw error; // Propagate to handler for auto-heal
+      if (
+        error instanceof IndexCorruptedError ||
+        error instanceof EmbeddingCollectionError ||
+        error instanceof IndexKeyError
+      ) {
+        throw error; // Propagate to handler for auto-heal (or to report the collection or key)
       }
       console.warn('Partial initialization (keyword search only):', error);
       this.initialized = true;
     }
   }
 
+  /** Embed queries with the collection's model and search its vectors */
+  private async openCollection(name: string, indexMeta: IndexMeta): Promise<void> {
+    const collection = await readEmbeddingCollection(this.contextDir, name);
+    if (!collection) {
+      throw new EmbeddingCollectionError(name, `Embedding collection '${name}' is not built`);
+    }
+    const { provider, model, dimensions, truncateDimensions } = collection.embedding;
+    const declared = (await loadEmbeddingCollectionSpecs(this.rootPath))[name]?.embedding;
+    this.embeddingProvider = await getEmbeddingProvider({
+      ...declared,
+      provider: provider as EmbeddingConfig['provider'],
+      model,
+      // A truncated collection records the cut size, not the size the model was asked for
+      ...(declared?.dimensions && !truncateDimensions ? { dimensions } : {})
+    });
+    const drift = describeEmbeddingDrift(
+      collection.embedding,
+      providerFingerprint(this.embeddingProvider)
+    );
+    if (drift) throw new EmbeddingCollectionError(name, `Collection '${name}': ${drift}`);
+    this.storageProvider = await getStorageProvider(
+      collectionStorageConfig(getCollectionDir(this.contextDir, name))
+    );
+    this.collection = {
+      name,
+      provider,
+      model,
+      chunks: collection.chunks,
+      stale: collection.sourceBuildId !== indexMeta.buildId
+    };
+  }
+
+  /** The embedding collection the vector channel uses, when one was requested */
+  getCollection(): SearchCollectionInfo | null {
+    return this.collection;
+  }
+
+  /** The model migration whose previous vectors the search was served from, if any */
+  getModelMigration(): ModelMigration | null {
+    return this.migration;
+  }
+
   private async loadKeywordIndex(): Promise<void> {
     try {
-      const indexPath = path.join(this.rootPath, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME);
+      const indexPath = path.join(this.contextDir, KEYWORD_INDEX_FILENAME);
       const content = await fs.readFile(indexPath, 'utf-8');
       const parsed = JSON.parse(content) as unknown;
 
@@ -183,25 +502,10 @@ export class CodebaseSearcher {
         throw new IndexCorruptedError('Keyword index corrupted: expected { header, chunks }');
       }
 
-      this.chunks = chunks;
-
-      this.fuseIndex = new Fuse(this.chunks, {
-        keys: [
-          { name: 'content', weight: 0.4 },
-          { name: 'metadata.componentName', weight: 0.25 },
-          { name: 'filePath', weight: 0.15 },
-          { name: 'relativePath', weight: 0.15 },
-          { name: 'componentType', weight: 0.15 },
-          { name: 'layer', weight: 0.1 },
-          { name: 'tags', weight: 0.15 }
-        ],
-        includeScore: true,
-        threshold: 0.4,
-        useExtendedSearch: true,
-        ignoreLocation: true
-      });
+      this.indexChunks(this.withOverlay(await openIndexedChunks(chunks)));
     } catch (error) {
-      if (error instanceof IndexCorruptedError) {
+      // A missing or wrong key is not fixed by a rebuild
+      if (error instanceof IndexCorruptedError || error instanceof IndexKeyError) {
         throw error;
       }
       throw new IndexCorruptedError(
@@ -210,16 +514,55 @@ export class CodebaseSearcher {
     }
   }
 
+  private withOverlay(chunks: CodeChunk[]): CodeChunk[] {
+    return this.overlay ? layerOverlay(chunks, this.overlay) : chunks;
+  }
+
+  /** Build the keyword structures (Fuse, BM25, lookups) over the searchable chunks */
+  private indexChunks(chunks: CodeChunk[]): void {
+    this.chunks = chunks;
+    this.trigramIndex = null;
+    this.indexedFiles = null;
+    this.maxRecentCommits = this.chunks.reduce(
+      (max, chunk) => Math.max(max, chunk.metadata?.recentCommits ?? 0),
+      0
+    );
+
+    this.fuseIndex = new Fuse(this.chunks, {
+      keys: [
+        { name: 'content', weight: 0.4 },
+        { name: 'metadata.componentName', weight: 0.25 },
+        { name: 'filePath', weight: 0.15 },
+        { name: 'relativePath', weight: 0.15 },
+        { name: 'componentType', weight: 0.15 },
+        { name: 'layer', weight: 0.1 },
+        { name: 'tags', weight: 0.15 }
+      ],
+      includeScore: true,
+      threshold: 0.4,
+      useExtendedSearch: true,
+      ignoreLocation: true
+    });
+
+    this.chunksById = new Map(this.chunks.map((chunk) => [chunk.id, chunk]));
+    this.bm25Index = new BM25Index(
+      this.chunks.map((chunk) => ({
+        id: chunk.id,
+        text: [
+          chunk.metadata?.componentName ?? '',
+          chunk.relativePath,
+          chunk.content ?? ''
+        ].join('\n')
+      }))
+    );
+  }
+
   /**
    * Load pattern intelligence for trend detection and warnings
    */
   private async loadPatternIntelligence(): Promise<void> {
     try {
-      const intelligencePath = path.join(
-        this.rootPath,
-        CODEBASE_CONTEXT_DIRNAME,
-        INTELLIGENCE_FILENAME
-      );
+      const intelligencePath = path.join(this.contextDir, INTELLIGENCE_FILENAME);
       const content = await fs.readFile(intelligencePath, 'utf-8');
       const intelligence = JSON.parse(content) as IntelligenceData;
 
@@ -508,18 +851,20 @@ export class CodebaseSearcher {
     query: string,
     limit: number,
     results: {
-      semantic: Map<string, { chunk: CodeChunk; ranks: Array<{ rank: number; weight: number }> }>;
-      keyword: Map<string, { chunk: CodeChunk; ranks: Array<{ rank: number; weight: number }> }>;
+      semantic: RankedMatches;
+      keyword: RankedMatches;
     },
     profile: SearchIntentProfile,
     intent: QueryIntent,
-    totalVariantWeight: number
+    totalVariantWeight: number,
+    debug = false,
+    maxPerFile = 1,
+    recency = resolveRecencyBoost()
   ): SearchResult[] {
     const likelyWiringQuery = this.isLikelyWiringOrFlowQuery(query);
     const actionQuery = this.isActionOrHowQuery(query);
-
-    // RRF: k=60 is the standard parameter (proven robust in Elasticsearch + TOSS paper arXiv:2208.11274)
-    const RRF_K = 60;
+    const boostRecency = isRecencyBoostEnabled(recency);
+    const now = Date.now();
 
     // Collect all unique chunks from both retrieval channels
     const allChunks = new Map<string, CodeChunk>();
@@ -535,7 +880,7 @@ export class CodebaseSearcher {
       }
     }
 
-    // Calculate RRF scores: RRF(d) = SUM(weight_i / (k + rank_i))
+    // Calculate RRF scores: RRF(d) = SUM(weight_i / (k + rank_i)), with 1-based ranks
     for (const [id] of allChunks) {
       let rrfScore = 0;
 
@@ -543,7 +888,7 @@ export class CodebaseSearcher {
       const semanticEntry = results.semantic.get(id);
       if (semanticEntry) {
         for (const { rank, weight } of semanticEntry.ranks) {
-          rrfScore += weight / (RRF_K + rank);
+          rrfScore += rrfContribution(rank, weight);
         }
       }
 
@@ -551,16 +896,16 @@ export class CodebaseSearcher {
       const keywordEntry = results.keyword.get(id);
       if (keywordEntry) {
         for (const { rank, weight } of keywordEntry.ranks) {
-          rrfScore += weight / (RRF_K + rank);
+          rrfScore += rrfContribution(rank, weight);
         }
       }
 
       rrfScores.set(id, rrfScore);
     }
 
-    // Normalize by theoretical maximum (rank-0 in every list), NOT by actual max.
+    // Normalize by theoretical maximum (head of every list), NOT by actual max.
     // Using actual max makes top result always 1.0, breaking quality confidence gating.
-    const theoreticalMaxRrf = totalVariantWeight / (RRF_K + 0);
+    const theoreticalMaxRrf = rrfContribution(0, totalVariantWeight);
     const maxRrfScore = Math.max(theoreticalMaxRrf, 0.01);
 
     // Separate test files from implementation files before scoring
@@ -583,20 +928,26 @@ export class CodebaseSearcher {
       .map(([id, chunk]) => {
         // RRF score normalized to [0,1] range. Boosts below are unclamped
         // to preserve score differentiation — only relative ordering matters.
-        let combinedScore = rrfScores.get(id)! / maxRrfScore;
+        const fused = rrfScores.get(id)! / maxRrfScore;
+        let combinedScore = fused;
+        const adjustments: ScoreBreakdown['adjustments'] = [];
+        const adjust = (reason: string, factor: number): void => {
+          combinedScore *= factor;
+          if (debug) adjustments.push({ reason, factor: round3(factor) });
+        };
 
         // Slight boost when analyzer identified a concrete component type
         if (chunk.componentType && chunk.componentType !== 'unknown') {
-          combinedScore *= 1.1;
+          adjust('component type', 1.1);
         }
 
         // Boost if layer is detected
         if (chunk.layer && chunk.layer !== 'unknown') {
-          combinedScore *= 1.1;
+          adjust('layer', 1.1);
         }
 
         if (actionQuery && this.isDefinitionHeavyResult(chunk)) {
-          combinedScore *= 0.82;
+          adjust('definition-heavy for action query', 0.82);
         }
 
         if (
@@ -605,7 +956,7 @@ export class CodebaseSearcher {
             (chunk.componentType || '').toLowerCase()
           )
         ) {
-          combinedScore *= 1.06;
+          adjust('behavioral component for action query', 1.06);
         }
 
         // Demote template/style files for behavioral queries — they describe
@@ -614,13 +965,13 @@ export class CodebaseSearcher {
           (intent === 'FLOW' || intent === 'WIRING' || actionQuery) &&
           this.isTemplateOrStyleFile(chunk.filePath)
         ) {
-          combinedScore *= 0.75;
+          adjust('template/style for behavioral query', 0.75);
         }
 
         // Light intent-aware boost for likely wiring/configuration queries.
         if (likelyWiringQuery && profile !== 'explore') {
           if (this.isCompositionRootFile(chunk.filePath)) {
-            combinedScore *= 1.12;
+            adjust('composition root for wiring query', 1.12);
           }
         }
 
@@ -631,12 +982,12 @@ export class CodebaseSearcher {
               (chunk.componentType || '').toLowerCase()
             )
           ) {
-            combinedScore *= 1.15;
+            adjust('flow component', 1.15);
           }
         } else if (intent === 'CONFIG') {
           // Boost composition-root files for configuration queries
           if (this.isCompositionRootFile(chunk.filePath)) {
-            combinedScore *= 1.2;
+            adjust('composition root for config query', 1.2);
           }
         } else if (intent === 'WIRING') {
           // Boost DI/module files for wiring queries
@@ -645,16 +996,16 @@ export class CodebaseSearcher {
               (chunk.componentType || '').toLowerCase().includes(type)
             )
           ) {
-            combinedScore *= 1.18;
+            adjust('DI/module file', 1.18);
           }
           if (this.isCompositionRootFile(chunk.filePath)) {
-            combinedScore *= 1.22;
+            adjust('composition root for wiring query', 1.22);
           }
         }
 
         const pathOverlap = this.queryPathTokenOverlap(chunk.filePath, query);
         if (pathOverlap >= 2) {
-          combinedScore *= 1.08;
+          adjust('query terms in path', 1.08);
         }
 
         if (this.importCentrality) {
@@ -664,16 +1015,34 @@ export class CodebaseSearcher {
           if (centrality !== undefined && centrality > 0.1) {
             // Boost files with high centrality (many imports)
             const centralityBoost = 1.0 + centrality * 0.15; // Up to +15% for max centrality
-            combinedScore *= centralityBoost;
+            adjust('import centrality', centralityBoost);
           }
         }
 
         // Detect pattern trend and apply momentum boost
         const { trend, warning } = this.detectChunkTrend(chunk);
         if (trend === 'Rising') {
-          combinedScore *= 1.15; // +15% for modern patterns
+          adjust('rising pattern', 1.15); // +15% for modern patterns
         } else if (trend === 'Declining') {
-          combinedScore *= 0.9; // -10% for legacy patterns
+          adjust('declining pattern', 0.9); // -10% for legacy patterns
+        }
+
+        if (boostRecency) {
+          const factors = recencyBoostFactors(chunk.metadata, recency, this.maxRecentCommits, now);
+          // Skip negligible boosts so old files don't list them in debug output
+          if (factors.recency > 1.001) adjust('recent change', factors.recency);
+          if (factors.churn > 1.001) adjust('edit frequency', factors.churn);
+        }
+
+        if (this.feedbackTotals.size > 0) {
+          const key = feedbackKey(
+            chunk.relativePath,
+            chunk.startLine,
+            chunk.endLine,
+            chunk.metadata?.symbolPath
+          );
+          const factor = feedbackBoost(this.feedbackTotals.get(key), this.feedbackWeight);
+          if (factor !== 1) adjust('result feedback', factor);
         }
 
         const summary = this.generateSummary(chunk);
@@ -693,7 +1062,16 @@ export class CodebaseSearcher {
           layer: chunk.layer,
           metadata: chunk.metadata,
           trend,
-          patternWarning: warning
+          patternWarning: warning,
+          ...(debug && {
+            scoreBreakdown: {
+              semantic: bestHit(results.semantic.get(id)),
+              keyword: bestHit(results.keyword.get(id)),
+              fused: round3(fused),
+              adjustments,
+              preRerankScore: round3(combinedScore)
+            }
+          })
         } as SearchResult;
       })
       .sort((a, b) => b.score - a.score);
@@ -706,19 +1084,21 @@ export class CodebaseSearcher {
         const symbolName = result.metadata?.symbolName;
         if (symbolName && symbolName.toLowerCase() === queryNormalized) {
           result.score *= 1.15; // +15% boost for definition
+          result.scoreBreakdown?.adjustments.push({ reason: 'exact definition', factor: 1.15 });
         }
       }
       // Re-sort after boost
       scoredResults.sort((a, b) => b.score - a.score);
     }
 
-    // File-level deduplication
-    const seenFiles = new Set<string>();
+    // File-level deduplication: at most maxPerFile chunks per file
+    const seenFiles = new Map<string, number>();
     const deduped: SearchResult[] = [];
     for (const result of scoredResults) {
       const normalizedPath = result.filePath.toLowerCase().replace(/\\/g, '/');
-      if (seenFiles.has(normalizedPath)) continue;
-      seenFiles.add(normalizedPath);
+      const seen = seenFiles.get(normalizedPath) ?? 0;
+      if (seen >= maxPerFile) continue;
+      seenFiles.set(normalizedPath, seen + 1);
       deduped.push(result);
       if (deduped.length >= limit) break;
     }
@@ -788,7 +1168,16 @@ export class CodebaseSearcher {
           layer: bestTestChunk.chunk.layer,
           metadata: bestTestChunk.chunk.metadata,
           trend,
-          patternWarning: warning
+          patternWarning: warning,
+          ...(debug && {
+            scoreBreakdown: {
+              semantic: bestHit(results.semantic.get(bestTestChunk.id)),
+              keyword: bestHit(results.keyword.get(bestTestChunk.id)),
+              fused: round3(bestTestChunk.score),
+              adjustments: [{ reason: 'test file', factor: 0.5 }],
+              preRerankScore: round3(bestTestChunk.score * 0.5)
+            }
+          })
         } as SearchResult);
       }
     }
@@ -828,17 +1217,11 @@ export class CodebaseSearcher {
     semanticWeight: number,
     keywordWeight: number
   ): Promise<{
-    semantic: Map<string, { chunk: CodeChunk; ranks: Array<{ rank: number; weight: number }> }>;
-    keyword: Map<string, { chunk: CodeChunk; ranks: Array<{ rank: number; weight: number }> }>;
+    semantic: RankedMatches;
+    keyword: RankedMatches;
   }> {
-    const semanticRanks: Map<
-      string,
-      { chunk: CodeChunk; ranks: Array<{ rank: number; weight: number }> }
-    > = new Map();
-    const keywordRanks: Map<
-      string,
-      { chunk: CodeChunk; ranks: Array<{ rank: number; weight: number }> }
-    > = new Map();
+    const semanticRanks: RankedMatches = new Map();
+    const keywordRanks: RankedMatches = new Map();
 
     // RRF uses ranks instead of scores for fusion robustness
     if (useSemanticSearch && this.embeddingProvider && this.storageProvider) {
@@ -854,16 +1237,20 @@ export class CodebaseSearcher {
             const existing = semanticRanks.get(id);
 
             if (existing) {
-              existing.ranks.push({ rank, weight });
+              existing.ranks.push({ rank, weight, score: result.score });
             } else {
               semanticRanks.set(id, {
                 chunk: result.chunk,
-                ranks: [{ rank, weight }]
+                ranks: [{ rank, weight, score: result.score }]
               });
             }
           });
         }
       } catch (error) {
+        if (error instanceof IndexCorruptedError && this.collection) {
+          // A broken collection doesn't mean the index needs rebuilding
+          throw new EmbeddingCollectionError(this.collection.name, error.message);
+        }
         if (error instanceof IndexCorruptedError) {
           throw error; // Propagate to handler for auto-heal
         }
@@ -884,11 +1271,11 @@ export class CodebaseSearcher {
             const existing = keywordRanks.get(id);
 
             if (existing) {
-              existing.ranks.push({ rank, weight });
+              existing.ranks.push({ rank, weight, score: result.score });
             } else {
               keywordRanks.set(id, {
                 chunk: result.chunk,
-                ranks: [{ rank, weight }]
+                ranks: [{ rank, weight, score: result.score }]
               });
             }
           });
@@ -901,11 +1288,50 @@ export class CodebaseSearcher {
     return { semantic: semanticRanks, keyword: keywordRanks };
   }
 
+  /**
+   * Search one generation of the index: a swap waits for the search to finish, a search that
+   * starts during a swap waits for the new generation, and a searcher kept across builds
+   * reloads once the generation it loaded has been replaced.
+   */
   async search(
     query: string,
     limit: number = 5,
     filters?: SearchFilters,
     options: SearchOptions = DEFAULT_SEARCH_OPTIONS
+  ): Promise<SearchResult[]> {
+    // Chunks in memory have no generation on disk to hold
+    if (this.partialChunks) return this.searchGeneration(query, limit, filters, options);
+    return readConsistently(this.contextDir, async () => {
+      await this.reloadIfSwapped();
+      return this.searchGeneration(query, limit, filters, options);
+    });
+  }
+
+  /** Forget the loaded generation when index-meta.json names another build */
+  private async reloadIfSwapped(): Promise<void> {
+    if (!this.initialized || !this.indexMeta) return;
+    const buildId = await readIndexMeta(this.rootPath, this.contextDir).then(
+      (meta) => meta.buildId,
+      () => null
+    );
+    if (buildId === this.indexMeta.buildId) return;
+    try {
+      await this.storageProvider?.close?.();
+    } catch {
+      // The store belongs to the replaced generation
+    }
+    this.storageProvider = null;
+    this.collection = null;
+    // The next generation may embed queries with another model
+    this.overlayVectors = null;
+    this.initialized = false;
+  }
+
+  private async searchGeneration(
+    query: string,
+    limit: number,
+    filters: SearchFilters | undefined,
+    options: SearchOptions
   ): Promise<SearchResult[]> {
     if (!this.initialized) {
       await this.initialize();
@@ -922,16 +1348,31 @@ export class CodebaseSearcher {
       enableQueryExpansion,
       enableLowConfidenceRescue,
       candidateFloor,
-      enableReranker
+      enableReranker,
+      debug
     } = merged;
+    const rerankMode: RerankMode = enableReranker === false ? 'off' : (merged.rerank ?? 'auto');
+    const searchMode =
+      useSemanticSearch && useKeywordSearch ? 'hybrid' : useSemanticSearch ? 'semantic' : 'keyword';
+    metrics.searchQueries.inc({ mode: searchMode });
+    const diversity = resolveDiversity(this.projectDiversity, merged.diversity);
+    const recency = resolveRecencyBoost(this.projectRecency, merged.recency);
+    // Keep a deeper ranked list when reranking or MMR may reorder it; the limit is applied after
+    const rankLimit =
+      rerankMode === 'off' && diversity.lambda >= 1 ? limit : Math.max(limit, RERANK_CANDIDATES);
 
     const { intent, weights: intentWeights } = this.classifyQueryIntent(query);
+    this.lastPrefilter = undefined;
     // Intent weights are the default; caller-supplied weights override them
     const finalSemanticWeight = merged.semanticWeight ?? intentWeights.semantic;
     const finalKeywordWeight = merged.keywordWeight ?? intentWeights.keyword;
 
     const candidateLimit = Math.max(limit * 2, candidateFloor || 30);
     const primaryVariants = this.buildQueryVariants(query, enableQueryExpansion ? 1 : 0);
+    for (const rewrite of merged.queryRewrites ?? []) {
+      if (primaryVariants.some((variant) => variant.query === rewrite)) continue;
+      primaryVariants.push({ query: rewrite, weight: QUERY_REWRITE_WEIGHT });
+    }
 
     const primaryMatches = await this.collectHybridMatches(
       primaryVariants,
@@ -946,18 +1387,23 @@ export class CodebaseSearcher {
     const primaryTotalWeight =
       primaryVariants.reduce((sum, v) => sum + v.weight, 0) *
       (finalSemanticWeight + finalKeywordWeight);
-    const primaryResults = this.scoreAndSortResults(
+    const primaryCandidates = this.scoreAndSortResults(
       query,
-      limit,
+      rankLimit,
       primaryMatches,
       (profile || 'explore') as SearchIntentProfile,
       intent,
-      primaryTotalWeight
+      primaryTotalWeight,
+      debug,
+      diversity.maxPerFile,
+      recency
     );
+    const primaryResults = primaryCandidates.slice(0, limit);
 
-    let bestResults = primaryResults;
+    let bestCandidates = primaryCandidates;
+    let keptVariants = primaryVariants;
 
-    if (enableLowConfidenceRescue) {
+    if (enableLowConfidenceRescue && !merged.signal?.aborted) {
       const primaryQuality = assessSearchQuality(query, primaryResults);
       if (primaryQuality.status === 'low_confidence') {
         const rescueVariants = this.buildQueryVariants(query, 2).slice(1);
@@ -979,31 +1425,66 @@ export class CodebaseSearcher {
           const rescueTotalWeight =
             rescueVariantWeights.reduce((sum, w) => sum + w, 0) *
             (finalSemanticWeight + finalKeywordWeight);
-          const rescueResults = this.scoreAndSortResults(
+          const rescueCandidates = this.scoreAndSortResults(
             query,
-            limit,
+            rankLimit,
             rescueMatches,
             (profile || 'explore') as SearchIntentProfile,
             intent,
-            rescueTotalWeight
+            rescueTotalWeight,
+            debug,
+            diversity.maxPerFile,
+            recency
           );
+          const rescueResults = rescueCandidates.slice(0, limit);
 
-          bestResults = this.pickBetterResultSet(query, primaryResults, rescueResults);
+          if (this.pickBetterResultSet(query, primaryResults, rescueResults) === rescueResults) {
+            bestCandidates = rescueCandidates;
+            keptVariants = rescueVariants;
+          }
         }
       }
     }
 
-    // Stage-2: cross-encoder reranking when top scores are ambiguous
-    if (enableReranker) {
+    if (debug) {
+      // After the definition boost, which adjusts scores in place
+      for (const result of bestCandidates) {
+        if (result.scoreBreakdown) result.scoreBreakdown.preRerankScore = round3(result.score);
+      }
+    }
+
+    // Stage-2: cross-encoder reranking of the top candidates (auto: only when ambiguous)
+    if (rerankMode !== 'off' && !merged.signal?.aborted) {
       try {
-        bestResults = await rerank(query, bestResults);
+        bestCandidates = await rerank(query, bestCandidates, rerankMode);
       } catch (error) {
         // Reranker is non-critical — log and return unranked results
         console.warn('[reranker] Failed, returning original order:', error);
       }
     }
 
-    return bestResults;
+    this.lastTrace = debug
+      ? {
+          intent,
+          semanticWeight: finalSemanticWeight,
+          keywordWeight: finalKeywordWeight,
+          queryVariants: keptVariants.map((variant) => variant.query),
+          rescued: keptVariants !== primaryVariants,
+          rerank: rerankMode,
+          reranked: bestCandidates.some(
+            (result) => result.scoreBreakdown?.rerankScore !== undefined
+          ),
+          candidates: bestCandidates.length,
+          ...(this.lastPrefilter && { prefilter: this.lastPrefilter })
+        }
+      : null;
+
+    return diversifyResults(bestCandidates, limit, diversity);
+  }
+
+  /** Trace of the last `search()` run with `debug: true` (null otherwise) */
+  getLastTrace(): SearchTrace | null {
+    return this.lastTrace;
   }
 
   private generateSummary(chunk: CodeChunk): string {
@@ -1074,15 +1555,88 @@ export class CodebaseSearcher {
     if (!this.embeddingProvider || !this.storageProvider) {
       return [];
     }
+    const capabilities = storageCapabilities(this.storageProvider);
+
+    // Path, test, size and recency filters reach the store as the matching file list
+    const scope = this.fileScope(filters);
+    if (scope && scope.files.size === 0) return [];
+    // Only the files holding the query's literals, when the pre-filter narrows them down
+    const literalFiles = this.prefilterFiles(query, scope, capabilities.maxFilterPaths);
+    // The store gets the filters it applies; the rest run below over an over-fetched list
+    const plan = planVectorQuery(capabilities, limit, filters, scope, literalFiles);
 
     const queryVector = await this.embeddingProvider.embed(query);
+    const results = await openSearchResults(
+      await this.storageProvider.search(queryVector, plan.limit, plan.filters)
+    );
+
+    const overlay = this.overlay;
+    const stored = overlay
+      ? results.filter((r) => !overlayCovers(overlay, r.chunk))
+      : results;
+    const candidates = overlay
+      ? [...stored, ...(await this.overlayHits(queryVector))].sort((a, b) => b.score - a.score)
+      : stored;
+
+    return candidates
+      .filter((r) => matchesChunkFilters(r.chunk, filters))
+      .filter((r) => matchesMetadataFilters(r.chunk, filters))
+      .filter((r) => !scope || scope.files.has(r.chunk.relativePath))
+      .filter((r) => !literalFiles || literalFiles.has(r.chunk.relativePath))
+      .filter((r) => !this.isTombstoned(r.chunk.relativePath))
+      .slice(0, limit)
+      .map((r) => ({
+        chunk: r.chunk,
+        score: r.score
+      }));
+  }
+
+  /** Overlay chunks scored against the query like the store scores its rows */
+  private async overlayHits(queryVector: number[]): Promise<{ chunk: CodeChunk; score: number }[]> {
+    const overlay = this.overlay;
+    const provider = this.embeddingProvider;
+    if (!overlay || !provider || overlay.chunks.length === 0) return [];
+    this.overlayVectors ??= (async () => {
+      const postProcessors = await loadChunkPostProcessors(this.rootPath);
+      const vectors = await provider.embedBatch(
+        overlay.chunks.map((chunk) => buildEmbeddingInput(chunk, postProcessors))
+      );
+      return new Map(overlay.chunks.map((chunk, i) => [chunk.id, vectors[i]]));
+    })();
+    const vectors = await this.overlayVectors;
+    return overlay.chunks.flatMap((chunk) => {
+      const vector = vectors.get(chunk.id);
+      return vector ? [{ chunk, score: Math.max(0, cosineSimilarity(queryVector, vector)) }] : [];
+    });
+  }
 
-    const results = await this.storageProvider.search(queryVector, limit, filters);
+  /** A path a build deleted or moved away, unless the active index has it again (a rollback) */
+  private isTombstoned(file: string): boolean {
+    if (!this.tombstones.has(file)) return false;
+    this.indexedFiles ??= new Set(this.chunks.map((chunk) => chunk.relativePath));
+    return !this.indexedFiles.has(file);
+  }
 
-    return results.map((r) => ({
-      chunk: r.chunk,
-      score: r.score
-    }));
+  /**
+   * Files containing the query's literal tokens, within the file scope, when the pre-filter
+   * is on for this index and they are few enough to be worth scoping the vector query to
+   */
+  private prefilterFiles(
+    query: string,
+    scope: FileScope | null,
+    maxFilterPaths: number
+  ): Set<string> | null {
+    if (!this.prefilter || this.chunks.length < this.prefilter.minChunks) return null;
+    const literals = literalTokens(query);
+    if (literals.length === 0) return null;
+    this.trigramIndex ??= new TrigramIndex(this.chunks);
+    const found = this.trigramIndex.filesContaining(literals);
+    const files = scope ? new Set([...found].filter((file) => scope.files.has(file))) : found;
+    // No file has them (a typo, a paraphrase) or too many do: the full search does better
+    const limit = Math.min(this.prefilter.maxFiles, maxFilterPaths);
+    if (files.size === 0 || files.size > limit) return null;
+    this.lastPrefilter ??= { literals, files: files.size };
+    return files;
   }
 
   private async keywordSearch(
@@ -1097,33 +1651,10 @@ export class CodebaseSearcher {
     let fuseResults = this.fuseIndex.search(query);
 
     if (filters) {
-      fuseResults = fuseResults.filter((r) => {
-        const chunk = r.item;
-
-        if (filters.componentType && chunk.componentType !== filters.componentType) {
-          return false;
-        }
-        if (filters.layer && chunk.layer !== filters.layer) {
-          return false;
-        }
-        if (filters.framework && chunk.framework !== filters.framework) {
-          return false;
-        }
-        if (filters.language && chunk.language !== filters.language) {
-          return false;
-        }
-        if (filters.tags && filters.tags.length > 0) {
-          const chunkTags = chunk.tags || [];
-          if (!filters.tags.some((tag) => chunkTags.includes(tag))) {
-            return false;
-          }
-        }
-
-        return true;
-      });
+      fuseResults = fuseResults.filter((r) => this.matchesKeywordFilters(r.item, filters));
     }
 
-    return fuseResults.slice(0, limit).map((r) => {
+    const fuzzyResults = fuseResults.slice(0, limit).map((r) => {
       const chunk = r.item;
       let score = 1 - (r.score || 0);
 
@@ -1159,6 +1690,67 @@ export class CodebaseSearcher {
         score
       };
     });
+
+    const bm25Results = this.bm25Search(query, limit, filters);
+    if (bm25Results.length === 0) return fuzzyResults;
+    if (fuzzyResults.length === 0) return bm25Results;
+
+    // Fuse the fuzzy and BM25 lists into a single keyword ranking (RRF, same k as hybrid fusion)
+    const fused = new Map<string, { chunk: CodeChunk; score: number }>();
+    for (const list of [fuzzyResults, bm25Results]) {
+      list.forEach((result, index) => {
+        const existing = fused.get(result.chunk.id);
+        const contribution = rrfContribution(index);
+        if (existing) {
+          existing.score += contribution;
+        } else {
+          fused.set(result.chunk.id, { chunk: result.chunk, score: contribution });
+        }
+      });
+    }
+
+    return Array.from(fused.values())
+      .sort((a, b) => b.score - a.score)
+      .slice(0, limit);
+  }
+
+  private bm25Search(
+    query: string,
+    limit: number,
+    filters?: SearchFilters
+  ): { chunk: CodeChunk; score: number }[] {
+    if (!this.bm25Index || this.bm25Index.size === 0) return [];
+
+    // Over-fetch so post-filtering still leaves enough candidates
+    const hits = this.bm25Index.search(query, filters ? limit * 4 : limit);
+    const results: { chunk: CodeChunk; score: number }[] = [];
+    const topScore = hits[0]?.score || 1;
+
+    for (const hit of hits) {
+      const chunk = this.chunksById.get(hit.id);
+      if (!chunk) continue;
+      if (filters && !this.matchesKeywordFilters(chunk, filters)) continue;
+      results.push({ chunk, score: hit.score / topScore });
+      if (results.length >= limit) break;
+    }
+
+    return results;
+  }
+
+  private fileScope(filters?: SearchFilters): FileScope | null {
+    if (!filters) return null;
+    if (!this.fileScopes.has(filters)) {
+      this.fileScopes.set(filters, resolveFileScope(this.chunks, filters));
+    }
+    return this.fileScopes.get(filters) ?? null;
+  }
+
+  private matchesKeywordFilters(chunk: CodeChunk, filters: SearchFilters): boolean {
+    return (
+      matchesChunkFilters(chunk, filters) &&
+      matchesMetadataFilters(chunk, filters) &&
+      matchesFileFilters(chunk, filters)
+    );
   }
 
   private generateRelevanceReason(chunk: CodeChunk, query: string): string {
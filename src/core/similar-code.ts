@@ -0,0 +1,205 @@
+/**
+ * Near-duplicate code for `find_similar_code`: chunks whose stored embeddings are close to a
+ * snippet or to the chunks of a file region.
+ *
+ * Region chunks are looked up by their embedding input in the embedding cache, so the vectors
+ * compared are the ones already in the store; only a pasted snippet (or a chunk the cache
+ * lost) is embedded. Similarity is the store's cosine score, so the threshold is relative to
+ * the embedding model: structurally copied code scores high, code that merely shares a
+ * topic lower.
+ */
+
+import path from 'path';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../constants/codebase-context.js';
+import {
+  providerFingerprint,
+  getEmbeddingProvider,
+  type EmbeddingProvider
+} from '../embeddings/index.js';
+import { getStorageProvider } from '../storage/index.js';
+import type { CodeChunk } from '../types/index.js';
+import { EmbeddingCache, hashEmbeddingInput } from './embedding-cache.js';
+import { matchesPathFilter } from './file-filters.js';
+import { openIndexedChunks, openSearchResults } from './index-encryption.js';
+import { readIndexArtifact } from './index-lock.js';
+import { describeEmbeddingDrift, readIndexMeta } from './index-meta.js';
+import { buildEmbeddingInput } from './indexer.js';
+import { loadChunkPostProcessors } from './chunk-post-processors.js';
+import { activeModelMigration, previousModelConfig } from './model-migration.js';
+import { isTestSourceFile } from './test-mapping.js';
+
+export const DEFAULT_SIMILARITY_THRESHOLD = 0.85;
+
+/** Chunks shorter than this are left out on both sides: one-liners all look alike */
+const MIN_CHUNK_LINES = 3;
+
+export type SimilarCodeSource =
+  | { snippet: string }
+  | { file: string; startLine?: number; endLine?: number };
+
+export interface SimilarCodeOptions {
+  /** Minimum cosine similarity, 0-1 */
+  threshold: number;
+  limit: number;
+  /** Only matches under this path or glob */
+  scope?: string;
+  excludeTests?: boolean;
+  signal?: AbortSignal;
+}
+
+export interface SimilarCodeMatch {
+  file: string;
+  startLine: number;
+  endLine: number;
+  score: number;
+  symbol?: string;
+  /** Region source only: the lines of the source chunk this one resembles */
+  similarTo?: string;
+}
+
+export type SimilarCodeResult =
+  | {
+      status: 'success';
+      /** Source chunks compared (1 for a snippet) */
+      compared: number;
+      /** Matches above the threshold, before the limit */
+      total: number;
+      matches: SimilarCodeMatch[];
+    }
+  | { status: 'error' | 'not_found'; message: string };
+
+function lineCount(chunk: CodeChunk): number {
+  return chunk.endLine - chunk.startLine + 1;
+}
+
+function overlaps(chunk: CodeChunk, file: string, startLine: number, endLine: number): boolean {
+  return chunk.relativePath === file && chunk.startLine <= endLine && chunk.endLine >= startLine;
+}
+
+export async function findSimilarCode(
+  rootPath: string,
+  source: SimilarCodeSource,
+  options: SimilarCodeOptions
+): Promise<SimilarCodeResult> {
+  const contextDir = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME);
+  let chunks: CodeChunk[];
+  try {
+    const raw = await readIndexArtifact(contextDir, KEYWORD_INDEX_FILENAME);
+    chunks = (JSON.parse(raw) as { chunks?: CodeChunk[] }).chunks ?? [];
+  } catch {
+    return { status: 'error', message: 'Index not available. Run refresh_index to build it.' };
+  }
+  chunks = await openIndexedChunks(chunks);
+
+  const meta = await readIndexMeta(rootPath).catch(() => null);
+  if (!meta?.embedding) {
+    return {
+      status: 'error',
+      message: 'The index has no embeddings (built with skipEmbedding). Run refresh_index.'
+    };
+  }
+
+  // Region sources compare their own chunks; the region itself is never a match
+  let region: { file: string; startLine: number; endLine: number } | undefined;
+  let sourceChunks: CodeChunk[] = [];
+  if ('file' in source) {
+    const file = source.file.replace(/\\/g, '/').replace(/^\.\//, '');
+    const startLine = source.startLine ?? 1;
+    const endLine = source.endLine ?? Number.MAX_SAFE_INTEGER;
+    region = { file, startLine, endLine };
+    sourceChunks = chunks.filter(
+      (chunk) => overlaps(chunk, file, startLine, endLine) && lineCount(chunk) >= MIN_CHUNK_LINES
+    );
+    if (sourceChunks.length === 0) {
+      return {
+        status: 'not_found',
+        message: `No indexed chunks of ${MIN_CHUNK_LINES}+ lines in ${file} for that range.`
+      };
+    }
+  }
+
+  let provider: EmbeddingProvider | undefined;
+  const embed = async (text: string): Promise<number[]> => {
+    if (!provider) {
+      // Mid-migration, the active vectors still come from the previous model
+      const migration = await activeModelMigration(contextDir, meta.embedding);
+      provider = await getEmbeddingProvider(migration ? previousModelConfig(migration) : undefined);
+      const drift = describeEmbeddingDrift(meta.embedding, providerFingerprint(provider));
+      if (drift) throw new Error(drift);
+    }
+    // Documents, not queries: the snippet is compared as code against code
+    const [vector] = await provider.embedBatch([text]);
+    return vector;
+  };
+
+  const queries: Array<{ vector: number[]; chunk?: CodeChunk }> = [];
+  try {
+    if ('snippet' in source) {
+      queries.push({ vector: await embed(source.snippet) });
+    } else {
+      const cache = await EmbeddingCache.load(
+        contextDir,
+        `${meta.embedding.provider}:${meta.embedding.model}`,
+        meta.embedding.dimensions
+      );
+      const postProcessors = await loadChunkPostProcessors(rootPath);
+      for (const chunk of sourceChunks) {
+        const input = buildEmbeddingInput(chunk, postProcessors);
+        const vector = cache.get(hashEmbeddingInput(input)) ?? (await embed(input));
+        queries.push({ vector, chunk });
+      }
+    }
+  } catch (error) {
+    return { status: 'error', message: error instanceof Error ? error.message : String(error) };
+  }
+
+  const storage = await getStorageProvider({
+    path: path.join(contextDir, VECTOR_DB_DIRNAME),
+    rootPath
+  });
+  const best = new Map<string, SimilarCodeMatch>();
+  try {
+    for (const query of queries) {
+      if (options.signal?.aborted) break;
+      // Over-fetch: the region's own chunks and filtered files come back too
+      const hits = await openSearchResults(
+        await storage.search(query.vector, options.limit * 3 + sourceChunks.length)
+      );
+      for (const { chunk, score } of hits) {
+        if (score < options.threshold) continue;
+        if (lineCount(chunk) < MIN_CHUNK_LINES) continue;
+        if (region && overlaps(chunk, region.file, region.startLine, region.endLine)) continue;
+        if (options.scope && !matchesPathFilter(chunk.relativePath, options.scope)) continue;
+        if (options.excludeTests && isTestSourceFile(chunk.relativePath)) continue;
+
+        const key = `${chunk.relativePath}:${chunk.startLine}-${chunk.endLine}`;
+        const previous = best.get(key);
+        if (previous && previous.score >= score) continue;
+        best.set(key, {
+          file: chunk.relativePath,
+          startLine: chunk.startLine,
+          endLine: chunk.endLine,
+          score: Math.round(score * 1000) / 1000,
+          ...(chunk.metadata?.symbolName ? { symbol: chunk.metadata.symbolName } : {}),
+          ...(query.chunk ? { similarTo: `${query.chunk.startLine}-${query.chunk.endLine}` } : {})
+        });
+      }
+    }
+  } finally {
+    await storage.close?.();
+  }
+
+  const matches = [...best.values()].sort(
+    (a, b) => b.score - a.score || a.file.localeCompare(b.file) || a.startLine - b.startLine
+  );
+  return {
+    status: 'success',
+    compared: queries.length,
+    total: matches.length,
+    matches: matches.slice(0, options.limit)
+  };
+}
@@ -0,0 +1,334 @@
+/**
+ * Stack traces for `resolve_stacktrace`: parse Go panics, Java (and other JVM), Python and
+ * JavaScript traces into frames, map each frame onto an indexed file, and read the function
+ * around the failing line.
+ *
+ * Paths in a trace usually come from somewhere else: a CI runner's checkout, a container's
+ * `/app`, a Java frame that names only `OrderService.java`. A path that isn't a project file
+ * is matched by its trailing segments against the indexed files (Java frames get theirs from
+ * the package), and a tie is broken by the frame's function name. Platform and dependency
+ * frames are reported as `library` instead of being matched. Source maps are not applied, so
+ * a frame in compiled output resolves only when that output is indexed.
+ */
+
+import path from 'path';
+import { loadIndexedFiles } from './file-resources.js';
+import { loadSymbolIndex, type SymbolDefinition } from './symbol-index.js';
+import { getEnclosingScope } from './symbol-navigation.js';
+
+export type StackTraceFormat = 'go' | 'java' | 'python' | 'javascript';
+
+export interface StackFrame {
+  format: StackTraceFormat;
+  /** As written in the trace, without arguments or receiver noise */
+  function?: string;
+  /** As written in the trace */
+  file: string;
+  line: number;
+  column?: number;
+}
+
+export type FrameResolution =
+  | { status: 'resolved'; file: string; matchedBy: 'exact' | 'suffix' | 'package' }
+  | { status: 'library' | 'not_indexed' }
+  | { status: 'ambiguous'; candidates: string[] };
+
+export interface ResolvedFrame extends StackFrame {
+  /** 0-based position in the trace, innermost (the failing call) first */
+  index: number;
+  resolution: FrameResolution;
+  /** Enclosing function of the frame's line, for the first `maxFrames` resolved frames */
+  scope?: {
+    name?: string;
+    kind?: string;
+    startLine: number;
+    endLine: number;
+    /** Line-numbered source; the frame's line is marked with `>` */
+    code: string;
+    truncated?: boolean;
+  };
+  /** The frame's line is past the end of the file, so the index or checkout is stale */
+  lineOutOfRange?: boolean;
+  /** Immediately repeated frames (recursion) folded into this one */
+  repeated?: number;
+}
+
+export interface ResolvedStackTrace {
+  format: StackTraceFormat;
+  frames: ResolvedFrame[];
+}
+
+const MAX_CANDIDATES = 3;
+
+const PYTHON_FRAME = /^\s*File "(.+?)", line (\d+)(?:, in (.+))?$/;
+const JAVA_FRAME = /^\s*at\s+([\w$.<>/-]+)\(([\w$.-]+\.\w+):(\d+)\)\s*$/;
+const JS_FRAME = /^\s*at\s+(?:async\s+)?(?:(.+?)\s+\()?(.+?):(\d+)(?::(\d+))?\)?\s*$/;
+const FIREFOX_FRAME = /^\s*([^@\s]*)@(.+?):(\d+):(\d+)\s*$/;
+const GO_LOCATION = /^\s*(.+?\.go):(\d+)(?:\s+\+0x[0-9a-f]+)?\s*$/;
+
+// Runtimes, standard libraries and installed dependencies: never project code
+const LIBRARY_PATHS = [
+  /(^|\/)node_modules\//,
+  /^node:/,
+  /^internal\/.*\.js$/,
+  /(^|\/)(site|dist)-packages\//,
+  /(^|\/)lib\/python\d[\d.]*\//,
+  /^<frozen /,
+  /(^|\/)pkg\/mod\//,
+  // GOROOT: standard packages have no dot in their first path element, modules do
+  /(^|\/)go\/src\/[^/.]+\//,
+  /(^|\/)vendor\//
+];
+const LIBRARY_JAVA_PACKAGES = /^(java|javax|jdk|sun|com\.sun|kotlin|kotlinx|scala)\./;
+
+function stripGoFunction(raw: string): string {
+  return raw
+    .replace(/^created by\s+/, '')
+    .replace(/\s+in goroutine \d+$/, '')
+    .replace(/\([^()]*\)$/, '')
+    .replace(/^.*\//, '');
+}
+
+/** Frames of `trace`, innermost first (Python's most-recent-call-last order is reversed) */
+export function parseStackTrace(trace: string): StackFrame[] {
+  const lines = trace.replace(/\r\n/g, '\n').split('\n');
+  const frames: StackFrame[] = [];
+  let python: StackFrame[] = [];
+
+  for (let i = 0; i < lines.length; i++) {
+    const line = lines[i];
+    let match = PYTHON_FRAME.exec(line);
+    if (match) {
+      python.push({
+        format: 'python',
+        file: match[1],
+        line: Number(match[2]),
+        ...(match[3] ? { function: match[3].trim() } : {})
+      });
+      continue;
+    }
+    if (python.length > 0 && /^\S/.test(line) && !/^Traceback/.test(line)) {
+      // A chained exception ("During handling of ...") starts a new traceback
+      frames.push(...python.reverse());
+      python = [];
+    }
+
+    match = JAVA_FRAME.exec(line);
+    if (match) {
+      frames.push({
+        format: 'java',
+        function: match[1].replace(/^.*\//, ''),
+        file: match[2],
+        line: Number(match[3])
+      });
+      continue;
+    }
+
+    match = GO_LOCATION.exec(line);
+    if (match) {
+      const caller = lines[i - 1]?.trim();
+      frames.push({
+        format: 'go',
+        ...(caller && !GO_LOCATION.test(caller) ? { function: stripGoFunction(caller) } : {}),
+        file: match[1].trim(),
+        line: Number(match[2])
+      });
+      continue;
+    }
+
+    match = JS_FRAME.exec(line) ?? FIREFOX_FRAME.exec(line);
+    if (match && !match[2].startsWith('<anonymous')) {
+      const name = match[1]
+        ?.replace(/^new\s+/, '')
+        .replace(/\s+\[as [^\]]+\]$/, '')
+        .trim();
+      frames.push({
+        format: 'javascript',
+        ...(name ? { function: name } : {}),
+        file: match[2],
+        line: Number(match[3]),
+        ...(match[4] ? { column: Number(match[4]) } : {})
+      });
+    }
+  }
+  frames.push(...python.reverse());
+  return frames;
+}
+
+/** A trace path as a posix path without URL scheme, bundler prefix, query or drive letter */
+function normalizeTracePath(file: string): string {
+  return file
+    .replace(/^(webpack(-internal)?:\/\/\/?|file:\/\/|https?:\/\/[^/]+\/)/, '')
+    .replace(/[?#].*$/, '')
+    .replace(/\\/g, '/')
+    .replace(/^[A-Za-z]:\//, '/')
+    .replace(/^(\.\/)+/, '');
+}
+
+function isLibraryFrame(frame: StackFrame, file: string): boolean {
+  if (frame.format === 'java') return LIBRARY_JAVA_PACKAGES.test(frame.function ?? '');
+  return LIBRARY_PATHS.some((pattern) => pattern.test(file));
+}
+
+/** Java frames name only the file: `com.acme.Ui$1.run` in Ui.java is com/acme/Ui.java */
+function javaSourcePath(frame: StackFrame): string {
+  const parts = (frame.function ?? '').split('.');
+  // The last two parts are the class and the method
+  return [...parts.slice(0, -2), frame.file].join('/');
+}
+
+function trailingSegmentsInCommon(a: string[], b: string[]): number {
+  let count = 0;
+  while (count < a.length && count < b.length) {
+    if (a[a.length - 1 - count] !== b[b.length - 1 - count]) break;
+    count++;
+  }
+  return count;
+}
+
+/** The frame's function defined around its line in `file` */
+function definesFunction(
+  definitions: SymbolDefinition[],
+  file: string,
+  frame: StackFrame
+): boolean {
+  const name = frame.function?.split(/[.:#]/).pop()?.replace(/[()*]/g, '');
+  if (!name) return false;
+  return definitions.some(
+    (d) =>
+      d.file === file && d.name === name && d.startLine <= frame.line && d.endLine >= frame.line
+  );
+}
+
+/**
+ * Which indexed file a frame is in. `byBasename` maps file names to the indexed paths that end
+ * with them; `definitions` only break ties between equally good suffix matches.
+ */
+export function resolveFramePath(
+  rootPath: string,
+  frame: StackFrame,
+  indexed: Set<string>,
+  byBasename: Map<string, string[]>,
+  definitions: SymbolDefinition[] = []
+): FrameResolution {
+  const file = normalizeTracePath(frame.file);
+  if (isLibraryFrame(frame, file)) return { status: 'library' };
+
+  const relative = path.isAbsolute(file)
+    ? path.relative(rootPath, file).replace(/\\/g, '/')
+    : file;
+  if (indexed.has(relative)) return { status: 'resolved', file: relative, matchedBy: 'exact' };
+
+  const wanted = (frame.format === 'java' ? javaSourcePath(frame) : file)
+    .split('/')
+    .filter(Boolean);
+  const candidates = byBasename.get(wanted[wanted.length - 1] ?? '') ?? [];
+  let best: string[] = [];
+  let bestScore = 0;
+  for (const candidate of candidates) {
+    const score = trailingSegmentsInCommon(wanted, candidate.split('/'));
+    if (score > bestScore) {
+      best = [candidate];
+      bestScore = score;
+    } else if (score === bestScore) {
+      best.push(candidate);
+    }
+  }
+  if (best.length === 0) return { status: 'not_indexed' };
+
+  const matchedBy = frame.format === 'java' ? 'package' : 'suffix';
+  if (best.length === 1) return { status: 'resolved', file: best[0], matchedBy };
+  const defining = best.filter((candidate) => definesFunction(definitions, candidate, frame));
+  if (defining.length === 1) return { status: 'resolved', file: defining[0], matchedBy };
+  return { status: 'ambiguous', candidates: best.slice(0, MAX_CANDIDATES) };
+}
+
+/** Lines of `code` (as numbered by getEnclosingScope) around `line`, with that line marked */
+function windowAround(
+  code: string,
+  startLine: number,
+  line: number,
+  maxLines: number
+): { code: string; truncated: boolean } {
+  const lines = code.split('\n');
+  const at = line - startLine;
+  const from =
+    lines.length <= maxLines
+      ? 0
+      : Math.max(0, Math.min(at - Math.floor(maxLines / 2), lines.length - maxLines));
+  const shown = lines.slice(from, from + maxLines);
+  const marked = at - from;
+  if (marked >= 0 && marked < shown.length) {
+    shown[marked] = shown[marked].replace(' | ', ' > ');
+  }
+  return { code: shown.join('\n'), truncated: shown.length < lines.length };
+}
+
+/**
+ * Parse `trace` and resolve its frames against the index. Function bodies are read for the
+ * first `maxFrames` resolved frames, innermost first, each cut to `maxLines` around the
+ * frame's line. Returns null when no index exists or nothing in `trace` looks like a frame.
+ */
+export async function resolveStackTrace(
+  rootPath: string,
+  trace: string,
+  options: { maxFrames: number; maxLines: number }
+): Promise<ResolvedStackTrace | null> {
+  const parsed = parseStackTrace(trace);
+  const files = await loadIndexedFiles(rootPath);
+  if (parsed.length === 0 || !files) return null;
+
+  const indexed = new Set(files.keys());
+  const byBasename = new Map<string, string[]>();
+  for (const file of indexed) {
+    const base = file.slice(file.lastIndexOf('/') + 1);
+    byBasename.set(base, [...(byBasename.get(base) ?? []), file]);
+  }
+  const definitions = (await loadSymbolIndex(rootPath)) ?? [];
+
+  const frames: ResolvedFrame[] = [];
+  let withScope = 0;
+  for (const [index, frame] of parsed.entries()) {
+    const previous = frames[frames.length - 1];
+    if (
+      previous &&
+      previous.file === frame.file &&
+      previous.line === frame.line &&
+      previous.function === frame.function
+    ) {
+      previous.repeated = (previous.repeated ?? 0) + 1;
+      continue;
+    }
+
+    const resolution = resolveFramePath(rootPath, frame, indexed, byBasename, definitions);
+    const resolved: ResolvedFrame = { ...frame, index, resolution };
+    frames.push(resolved);
+    if (resolution.status !== 'resolved' || withScope >= options.maxFrames) continue;
+
+    const scope = await getEnclosingScope(
+      rootPath,
+      definitions,
+      { file: resolution.file, startLine: frame.line },
+      { level: 'symbol', maxLines: Number.MAX_SAFE_INTEGER }
+    );
+    if (!scope) continue;
+    if (frame.line > scope.endLine) {
+      resolved.lineOutOfRange = true;
+      continue;
+    }
+    withScope++;
+    const window = windowAround(scope.code, scope.startLine, frame.line, options.maxLines);
+    resolved.scope = {
+      ...(scope.name ? { name: scope.name, kind: scope.kind } : {}),
+      startLine: scope.startLine,
+      endLine: scope.endLine,
+      code: window.code,
+      ...(window.truncated ? { truncated: true } : {})
+    };
+  }
+
+  const counts = new Map<StackTraceFormat, number>();
+  for (const frame of parsed) counts.set(frame.format, (counts.get(frame.format) ?? 0) + 1);
+  const format = [...counts.entries()].sort((a, b) => b[1] - a[1])[0][0];
+  return { format, frames };
+}
@@ -0,0 +1,189 @@
+/**
+ * Swift / Objective-C mixed targets. Neither side imports the other by path: Swift sees the
+ * Objective-C headers listed in the target's bridging header (`App-Bridging-Header.h`), and
+ * Objective-C sees Swift through the generated `App-Swift.h`. Type names used across that
+ * boundary are matched against the symbol table, and each match becomes an import-graph
+ * edge to the candidate defining files. Matching is by name only, so it can over-link.
+ *
+ * Quoted `#import "Invoice.h"` between Objective-C files are resolved too: next to the
+ * importing file first, else to the only indexed file with that name.
+ */
+
+import path from 'path';
+import type { SymbolDefinition } from './symbol-index.js';
+
+export interface BridgeEdge {
+  /** Repo-relative posix paths */
+  from: string;
+  to: string;
+  line: number;
+  symbols: string[];
+}
+
+const QUOTED_IMPORT = /^[ \t]*#[ \t]*(?:import|include)[ \t]+"([^"]+)"/gm;
+const GENERATED_SWIFT_HEADER = /^[ \t]*#[ \t]*import[ \t]+[<"](?:[^>"]*\/)?[\w.+-]+-Swift\.h[>"]/m;
+const BRIDGING_HEADER = /-Bridging-Header\.h$/i;
+const IDENTIFIER = /[A-Za-z_][A-Za-z0-9_]*/g;
+// Read directly so headers the grammar can't parse (macros, attributes) still bridge
+const OBJC_DECLARATIONS = [
+  /^[ \t]*@(?:interface|implementation|protocol)[ \t]+([A-Za-z_]\w*)/gm,
+  /\bNS_(?:ENUM|OPTIONS|CLOSED_ENUM)\s*\(\s*\w+\s*,\s*([A-Za-z_]\w*)\s*\)/g
+];
+
+/** Objective-C declarations Swift can use through a bridging header */
+const OBJC_BRIDGED_KINDS = new Set(['class', 'protocol', 'extension', 'enum', 'struct', 'type']);
+/** Swift declarations exposed in the generated `-Swift.h` (when marked @objc) */
+const SWIFT_BRIDGED_KINDS = new Set(['class', 'protocol', 'enum']);
+/** Short names (`ID`, `Id`) match too much to be useful */
+const MIN_NAME_LENGTH = 3;
+
+const OBJC_LANGUAGES = new Set(['objective-c', 'objective-cpp']);
+
+export function isBridgingHeader(relativePath: string): boolean {
+  return BRIDGING_HEADER.test(relativePath);
+}
+
+/** Quoted `#import`/`#include` targets; angle-bracket framework imports are skipped */
+export function parseQuotedImports(content: string): Array<{ header: string; line: number }> {
+  const imports: Array<{ header: string; line: number }> = [];
+  for (const match of content.matchAll(QUOTED_IMPORT)) {
+    const line = content.slice(0, match.index).split('\n').length;
+    imports.push({ header: match[1], line });
+  }
+  return imports;
+}
+
+function objcDeclarations(file: string, content: string): Array<{ name: string; file: string }> {
+  return OBJC_DECLARATIONS.flatMap((pattern) =>
+    [...content.matchAll(pattern)].map((match) => ({ name: match[1], file }))
+  );
+}
+
+/** First line of every identifier in the content */
+function identifierLines(content: string): Map<string, number> {
+  const lines = new Map<string, number>();
+  content.split('\n').forEach((text, index) => {
+    for (const [name] of text.matchAll(IDENTIFIER)) {
+      if (name.length >= MIN_NAME_LENGTH && !lines.has(name)) lines.set(name, index + 1);
+    }
+  });
+  return lines;
+}
+
+export class SwiftObjcBridge {
+  private files: Set<string>;
+  private byBasename = new Map<string, string[]>();
+  private headerImports = new Map<string, Array<{ file: string; line: number }>>();
+  private objcDeclared: Array<{ name: string; file: string }> = [];
+  /** Swift files, and Objective-C files importing the generated Swift header */
+  private swiftIdentifiers = new Map<string, Map<string, number>>();
+  private objcIdentifiers = new Map<string, Map<string, number>>();
+
+  /** `files` are the indexed repo-relative posix paths */
+  constructor(files: Iterable<string>) {
+    this.files = new Set(files);
+    for (const file of this.files) {
+      const base = path.posix.basename(file);
+      this.byBasename.set(base, [...(this.byBasename.get(base) ?? []), file]);
+    }
+  }
+
+  /** Resolve `#import "Invoice.h"`: beside the importer, else the unique file with that name */
+  resolveHeader(fromFile: string, header: string): string | null {
+    const beside = path.posix.normalize(path.posix.join(path.posix.dirname(fromFile), header));
+    if (this.files.has(beside)) return beside;
+    if (this.files.has(header)) return header;
+    const candidates = this.byBasename.get(path.posix.basename(header)) ?? [];
+    return candidates.length === 1 ? candidates[0] : null;
+  }
+
+  trackFile(relativeFile: string, language: string, content: string): void {
+    if (language === 'swift') {
+      this.swiftIdentifiers.set(relativeFile, identifierLines(content));
+      return;
+    }
+    if (!OBJC_LANGUAGES.has(language)) return;
+
+    const resolved: Array<{ file: string; line: number }> = [];
+    for (const { header, line } of parseQuotedImports(content)) {
+      const file = this.resolveHeader(relativeFile, header);
+      if (file && file !== relativeFile) resolved.push({ file, line });
+    }
+    if (resolved.length > 0) this.headerImports.set(relativeFile, resolved);
+    this.objcDeclared.push(...objcDeclarations(relativeFile, content));
+    if (GENERATED_SWIFT_HEADER.test(content)) {
+      this.objcIdentifiers.set(relativeFile, identifierLines(content));
+    }
+  }
+
+  /** Header imports plus the cross-language edges, once every file has been tracked */
+  edges(definitions: SymbolDefinition[]): BridgeEdge[] {
+    const edges = new Map<string, BridgeEdge>();
+    const add = (from: string, to: string, line: number, symbol?: string) => {
+      if (from === to) return;
+      const key = `${from}\0${to}`;
+      const edge = edges.get(key) ?? { from, to, line, symbols: [] };
+      edge.line = Math.min(edge.line, line);
+      if (symbol && !edge.symbols.includes(symbol)) edge.symbols.push(symbol);
+      edges.set(key, edge);
+    };
+
+    for (const [from, imports] of this.headerImports) {
+      for (const { file, line } of imports) add(from, file, line);
+    }
+
+    // Everything reachable from a bridging header is visible to Swift
+    const bridged = new Set<string>();
+    const queue = [...this.headerImports.keys()].filter(isBridgingHeader);
+    while (queue.length > 0) {
+      const header = queue.pop()!;
+      if (bridged.has(header)) continue;
+      bridged.add(header);
+      for (const { file } of this.headerImports.get(header) ?? []) queue.push(file);
+    }
+
+    const objcTypes = [
+      ...definitions.filter(
+        (def) => OBJC_LANGUAGES.has(def.language) && OBJC_BRIDGED_KINDS.has(def.kind)
+      ),
+      ...this.objcDeclared
+    ];
+    const bridgedNames = new Set(
+      objcTypes.filter((def) => bridged.has(def.file)).map((def) => def.name)
+    );
+    // Candidates: every Objective-C file defining the name, so the @implementation too
+    const objcTargets = filesByName(objcTypes, bridgedNames);
+    const swiftTargets = filesByName(
+      definitions.filter((def) => def.language === 'swift' && SWIFT_BRIDGED_KINDS.has(def.kind))
+    );
+
+    const link = (
+      identifiers: Map<string, Map<string, number>>,
+      targets: Map<string, Set<string>>
+    ) => {
+      for (const [from, names] of identifiers) {
+        for (const [name, line] of names) {
+          for (const to of targets.get(name) ?? []) add(from, to, line, name);
+        }
+      }
+    };
+    link(this.swiftIdentifiers, objcTargets);
+    link(this.objcIdentifiers, swiftTargets);
+
+    return [...edges.values()];
+  }
+}
+
+function filesByName(
+  definitions: Array<{ name: string; file: string }>,
+  only?: Set<string>
+): Map<string, Set<string>> {
+  const byName = new Map<string, Set<string>>();
+  for (const def of definitions) {
+    if (only && !only.has(def.name)) continue;
+    const files = byName.get(def.name) ?? new Set<string>();
+    files.add(def.file);
+    byName.set(def.name, files);
+  }
+  return byName;
+}
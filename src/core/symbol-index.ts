@@ -0,0 +1,239 @@
+/**
+ * Symbol table built from Tree-sitter definitions during indexing, for name-based lookup
+ * when the caller already knows (roughly) what a symbol is called.
+ *
+ * Stored in the relationships sidecar under `symbols.definitions`.
+ */
+
+import { createHash } from 'crypto';
+import { promises as fs } from 'fs';
+import path from 'path';
+import { CODEBASE_CONTEXT_DIRNAME, RELATIONSHIPS_FILENAME } from '../constants/codebase-context.js';
+import type { TreeSitterSymbol } from '../utils/tree-sitter.js';
+import { matchesGlob } from '../utils/git-tree.js';
+
+export interface SymbolDefinition {
+  name: string;
+  kind: string;
+  /** Repo-relative path with forward slashes */
+  file: string;
+  startLine: number;
+  endLine: number;
+  language: string;
+  /** Package/module-qualified name (Java, Kotlin, C#, Swift, Rust, Ruby, PHP) */
+  qualifiedName?: string;
+}
+
+export type SymbolMatchType = 'exact' | 'prefix' | 'substring' | 'fuzzy';
+
+export interface SymbolMatch {
+  name: string;
+  kind: string;
+  /** "path:startLine-endLine" */
+  file: string;
+  language: string;
+  qualifiedName?: string;
+  match: SymbolMatchType;
+  score: number;
+}
+
+export interface SymbolSearchOptions {
+  kinds?: string[];
+  language?: string;
+  /** Glob over repo-relative paths, e.g. `src/**` or `**\/*.go` */
+  pathGlob?: string;
+  /** Only case-insensitive exact name matches */
+  exact?: boolean;
+  limit: number;
+}
+
+export interface SymbolSearchResult {
+  total: number;
+  results: SymbolMatch[];
+}
+
+export class SymbolIndexBuilder {
+  private definitions: SymbolDefinition[] = [];
+  /** Content hash of each definition, for the change journal; not stored in the sidecar */
+  private hashes: string[] = [];
+
+  constructor(private rootPath: string) {}
+
+  trackFile(filePath: string, language: string, symbols: TreeSitterSymbol[]): void {
+    const file = path.relative(this.rootPath, filePath).replace(/\\/g, '/');
+    for (const symbol of symbols) {
+      this.definitions.push({
+        name: symbol.name,
+        kind: symbol.kind,
+        file,
+        startLine: symbol.startLine,
+        endLine: symbol.endLine,
+        language,
+        ...(symbol.qualifiedName ? { qualifiedName: symbol.qualifiedName } : {})
+      });
+      this.hashes.push(createHash('sha256').update(symbol.content).digest('hex').slice(0, 16));
+    }
+  }
+
+  toJSON(): SymbolDefinition[] {
+    return this.definitions;
+  }
+
+  /** Content hashes, aligned with toJSON() */
+  contentHashes(): string[] {
+    return this.hashes;
+  }
+}
+
+function isSymbolDefinitionList(value: unknown): value is SymbolDefinition[] {
+  return (
+    Array.isArray(value) &&
+    value.every(
+      (entry) =>
+        entry &&
+        typeof entry === 'object' &&
+        typeof (entry as SymbolDefinition).name === 'string' &&
+        typeof (entry as SymbolDefinition).file === 'string'
+    )
+  );
+}
+
+/**
+ * Load the symbol table from the relationships sidecar.
+ * Returns null when the index predates symbol extraction or hasn't been built.
+ */
+export async function loadSymbolIndex(rootPath: string): Promise<SymbolDefinition[] | null> {
+  const relationshipsPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, RELATIONSHIPS_FILENAME);
+  try {
+    const parsed = JSON.parse(await fs.readFile(relationshipsPath, 'utf-8')) as {
+      symbols?: { definitions?: unknown };
+    };
+    const definitions = parsed.symbols?.definitions;
+    return isSymbolDefinitionList(definitions) ? definitions : null;
+  } catch {
+    return null;
+  }
+}
+
+/** Definitions of one file not nested inside another of its definitions */
+export function topLevelDefinitions(definitions: SymbolDefinition[]): SymbolDefinition[] {
+  const sorted = [...definitions].sort((a, b) => a.startLine - b.startLine);
+  return sorted.filter(
+    (def) =>
+      !sorted.some(
+        (outer) =>
+          outer !== def &&
+          outer.startLine <= def.startLine &&
+          outer.endLine >= def.endLine &&
+          (outer.startLine < def.startLine || outer.endLine > def.endLine)
+      )
+  );
+}
+
+/** Characters of `query` appear in order in `name`; tighter spans score higher. */
+function subsequenceScore(query: string, name: string): number {
+  let qi = 0;
+  let first = -1;
+  let last = -1;
+  for (let ni = 0; ni < name.length && qi < query.length; ni++) {
+    if (name[ni] === query[qi]) {
+      if (first < 0) first = ni;
+      last = ni;
+      qi++;
+    }
+  }
+  if (qi < query.length) return 0;
+  return query.length / (last - first + 1);
+}
+
+function editDistance(a: string, b: string): number {
+  let previous = Array.from({ length: b.length + 1 }, (_, i) => i);
+  for (let i = 1; i <= a.length; i++) {
+    const current = [i];
+    for (let j = 1; j <= b.length; j++) {
+      current[j] = Math.min(
+        previous[j] + 1,
+        current[j - 1] + 1,
+        previous[j - 1] + (a[i - 1] === b[j - 1] ? 0 : 1)
+      );
+    }
+    previous = current;
+  }
+  return previous[b.length];
+}
+
+function scoreName(query: string, name: string): { match: SymbolMatchType; score: number } | null {
+  if (name === query) return { match: 'exact', score: 1 };
+
+  const q = query.toLowerCase();
+  const n = name.toLowerCase();
+  if (n === q) return { match: 'exact', score: 0.95 };
+  if (n.startsWith(q)) return { match: 'prefix', score: 0.8 + 0.1 * (q.length / n.length) };
+  if (n.includes(q)) return { match: 'substring', score: 0.6 + 0.1 * (q.length / n.length) };
+
+  // Typos: allow roughly one edit per four characters
+  const maxEdits = Math.max(1, Math.floor(q.length / 4));
+  if (q.length >= 4 && Math.abs(n.length - q.length) <= maxEdits) {
+    const distance = editDistance(q, n);
+    if (distance <= maxEdits) return { match: 'fuzzy', score: 0.55 - 0.05 * distance };
+  }
+
+  // Abbreviations such as `usrSvc` -> `UserService`
+  const subsequence = q.length >= 3 ? subsequenceScore(q, n) : 0;
+  if (subsequence >= 0.3) return { match: 'fuzzy', score: 0.2 + 0.3 * subsequence };
+
+  return null;
+}
+
+/** True when `query` is a trailing `.`/`::` segment path of `qualifiedName` */
+export function isQualifiedSuffix(query: string, qualifiedName: string): boolean {
+  if (query.length >= qualifiedName.length) return false;
+  const tail = qualifiedName.slice(qualifiedName.length - query.length);
+  const before = qualifiedName.slice(0, qualifiedName.length - query.length);
+  return tail === query && (before.endsWith('.') || before.endsWith('::'));
+}
+
+/**
+ * Rank definitions by name match (exact > prefix > substring > fuzzy) after filtering.
+ * Queries containing `.` or `::` are matched against qualified names where available.
+ */
+export function searchSymbols(
+  definitions: SymbolDefinition[],
+  query: string,
+  options: SymbolSearchOptions
+): SymbolSearchResult {
+  const trimmed = query.trim();
+  const kinds = options.kinds && options.kinds.length > 0 ? new Set(options.kinds) : null;
+  const language = options.language?.toLowerCase();
+  const qualified = trimmed.includes('.') || trimmed.includes('::');
+  const matches: SymbolMatch[] = [];
+
+  for (const definition of definitions) {
+    if (kinds && !kinds.has(definition.kind)) continue;
+    if (language && definition.language.toLowerCase() !== language) continue;
+    if (options.pathGlob && !matchesGlob(definition.file, options.pathGlob)) continue;
+
+    const target = qualified ? (definition.qualifiedName ?? definition.name) : definition.name;
+    // `UserService.save` fully names `com.acme.UserService.save`
+    const scored = isQualifiedSuffix(trimmed, target)
+      ? { match: 'exact' as const, score: 0.9 }
+      : scoreName(trimmed, target);
+    if (!scored || (options.exact && scored.match !== 'exact')) continue;
+
+    matches.push({
+      name: definition.name,
+      kind: definition.kind,
+      file: `${definition.file}:${definition.startLine}-${definition.endLine}`,
+      language: definition.language,
+      ...(definition.qualifiedName ? { qualifiedName: definition.qualifiedName } : {}),
+      match: scored.match,
+      score: Math.round(scored.score * 100) / 100
+    });
+  }
+
+  matches.sort(
+    (a, b) => b.score - a.score || a.name.length - b.name.length || a.file.localeCompare(b.file)
+  );
+
+  return { total: matches.length, results: matches.slice(0, options.limit) };
+}
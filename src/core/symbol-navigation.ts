@@ -0,0 +1,509 @@
+/**
+ * Go-to-definition and find-references backed by the index: definitions come from the
+ * symbol table, references from the syntactic reference scan. Both return exact
+ * `file:line` locations with line-numbered code read from the working tree.
+ *
+ * With an imported SCIP/LSIF index (see precise-index.ts), its compiler-resolved locations
+ * and hover text are used for the files it covers, and the heuristics fill in the rest. With
+ * the language-server bridge on (see lsp-bridge.ts), definitions the index has no hover text
+ * for get it from the server.
+ */
+
+import path from 'path';
+import { isQualifiedSuffix, type SymbolDefinition } from './symbol-index.js';
+import { findSymbolReferences } from './symbol-references.js';
+import {
+  createCoverageCheck,
+  matchPreciseSymbols,
+  type ImportedLocation,
+  type PreciseIndex
+} from './precise-index.js';
+import type { HoverLookup, LspHover } from './lsp-bridge.js';
+import { extractDocComment } from '../utils/doc-comments.js';
+import { detectLanguage } from '../utils/language-detection.js';
+import { redactSecrets, resolveRedactionOptions } from '../utils/secret-redaction.js';
+import { loadComplianceFilter } from './compliance-filters.js';
+import { readTextFile } from '../utils/text-encoding.js';
+
+export interface DefinitionLocation {
+  name: string;
+  kind: string;
+  language: string;
+  qualifiedName?: string;
+  /** "path:startLine" */
+  location: string;
+  endLine: number;
+  /** Line-numbered source of the definition */
+  code: string;
+  /** Set when the body was cut at `maxLines` */
+  truncated?: boolean;
+  /** Signature and doc comment from the precise index or a language server */
+  documentation?: string;
+}
+
+/** `precise`: resolved by the SCIP/LSIF index; `syntactic`: tree-sitter heuristics */
+export type NavigationConfidence = 'precise' | 'syntactic' | 'mixed';
+
+export interface ReferenceLocation {
+  /** "path:line" */
+  location: string;
+  /** Line-numbered source around the reference */
+  code: string;
+  /** The reference is the declaration itself */
+  definition?: boolean;
+}
+
+export interface DefinitionResult {
+  total: number;
+  definitions: DefinitionLocation[];
+  confidence: Exclude<NavigationConfidence, 'mixed'>;
+  /** Language servers that supplied hover text */
+  languageServers?: string[];
+}
+
+export type ReferenceResult =
+  | {
+      status: 'success';
+      total: number;
+      isComplete: boolean;
+      definitions: string[];
+      references: ReferenceLocation[];
+      confidence: NavigationConfidence;
+    }
+  | { status: 'error'; message: string };
+
+/** Returns a repo file's lines, or null when it can't be read */
+type LineLoader = (file: string) => Promise<string[] | null>;
+
+function createLineLoader(rootPath: string): LineLoader {
+  const cache = new Map<string, string[] | null>();
+  const redaction = resolveRedactionOptions();
+  const compliance = loadComplianceFilter(rootPath);
+  const root = path.resolve(rootPath);
+
+  return async (file) => {
+    if (cache.has(file)) return cache.get(file) ?? null;
+    let lines: string[] | null = null;
+    const absolute = path.resolve(root, file);
+    const relative = path.relative(root, absolute);
+    if (relative && !relative.startsWith('..') && !path.isAbsolute(relative)) {
+      try {
+        const content = (await readTextFile(absolute)).replace(/\r\n/g, '\n');
+        const redacted = redactSecrets(content, redaction).text;
+        lines = (await compliance).mask(redacted).text.split('\n');
+      } catch {
+        lines = null;
+      }
+    }
+    cache.set(file, lines);
+    return lines;
+  };
+}
+
+function numberLines(lines: string[], startLine: number, endLine: number): string {
+  const width = String(endLine).length;
+  return lines
+    .slice(startLine - 1, endLine)
+    .map((line, i) => `${String(startLine + i).padStart(width)} | ${line}`)
+    .join('\n');
+}
+
+/**
+ * Definitions whose name is exactly `symbol`. Qualified queries (`UserService.save`,
+ * `Cache::get`) match qualified names; case-insensitive matches are used only when
+ * nothing matches case-sensitively.
+ */
+export function matchDefinitions(
+  definitions: SymbolDefinition[],
+  symbol: string
+): SymbolDefinition[] {
+  const query = symbol.trim();
+  const qualified = query.includes('.') || query.includes('::');
+  const target = (d: SymbolDefinition) => (qualified ? (d.qualifiedName ?? d.name) : d.name);
+
+  const exact = definitions.filter((d) => {
+    const name = target(d);
+    return name === query || (qualified && isQualifiedSuffix(query, name));
+  });
+  const matches =
+    exact.length > 0
+      ? exact
+      : definitions.filter((d) => target(d).toLowerCase() === query.toLowerCase());
+
+  return [...matches].sort((a, b) => a.file.localeCompare(b.file) || a.startLine - b.startLine);
+}
+
+/** Precise locations of `symbol` in files the index still covers */
+type PreciseDefinition = ImportedLocation & { name: string; kind?: string; documentation?: string };
+
+async function preciseLocations(
+  rootPath: string,
+  precise: PreciseIndex,
+  symbol: string
+): Promise<{
+  definitions: PreciseDefinition[];
+  references: ImportedLocation[];
+  isCovered: (file: string) => Promise<boolean>;
+}> {
+  const isCovered = createCoverageCheck(rootPath, precise);
+  const definitions: PreciseDefinition[] = [];
+  const references: ImportedLocation[] = [];
+  for (const match of matchPreciseSymbols(precise, symbol)) {
+    for (const def of match.definitions) {
+      if (!(await isCovered(def.file))) continue;
+      definitions.push({
+        ...def,
+        name: match.name,
+        ...(match.kind ? { kind: match.kind } : {}),
+        ...(match.documentation ? { documentation: match.documentation } : {})
+      });
+    }
+    for (const ref of match.references) {
+      if (await isCovered(ref.file)) references.push(ref);
+    }
+  }
+  const byLocation = (a: ImportedLocation, b: ImportedLocation) =>
+    a.file.localeCompare(b.file) || a.line - b.line;
+  definitions.sort(byLocation);
+  references.sort(byLocation);
+  return { definitions, references, isCovered };
+}
+
+/** The tree-sitter definition a precise location points into, for its kind and extent */
+function enclosingDefinition(
+  definitions: SymbolDefinition[],
+  location: ImportedLocation,
+  name: string
+): SymbolDefinition | undefined {
+  return definitions
+    .filter(
+      (d) =>
+        d.file === location.file &&
+        d.startLine <= location.line &&
+        d.endLine >= location.line &&
+        d.name === name
+    )
+    .sort((a, b) => a.endLine - a.startLine - (b.endLine - b.startLine))[0];
+}
+
+export async function getDefinitions(
+  rootPath: string,
+  definitions: SymbolDefinition[],
+  symbol: string,
+  options: { limit: number; maxLines: number; hover?: HoverLookup },
+  precise?: PreciseIndex | null
+): Promise<DefinitionResult> {
+  const loadLines = createLineLoader(rootPath);
+  const results: DefinitionLocation[] = [];
+  const servers = new Set<string>();
+  const hoverFor = async (definition: SymbolDefinition | undefined) => {
+    const hover = definition && options.hover ? await options.hover(definition) : null;
+    if (hover) servers.add(hover.server);
+    return hover?.text;
+  };
+  const withServers = (result: DefinitionResult): DefinitionResult =>
+    servers.size > 0 ? { ...result, languageServers: [...servers].sort() } : result;
+
+  const exact = precise ? (await preciseLocations(rootPath, precise, symbol)).definitions : [];
+  if (exact.length > 0) {
+    for (const def of exact.slice(0, options.limit)) {
+      const lines = await loadLines(def.file);
+      const declared = enclosingDefinition(definitions, def, def.name);
+      const startLine = declared?.startLine ?? def.line;
+      const declaredEnd = declared?.endLine ?? def.endLine ?? def.line;
+      const endLine = Math.min(declaredEnd, lines?.length ?? declaredEnd);
+      const shownEnd = Math.min(endLine, startLine + options.maxLines - 1);
+      const documentation = def.documentation ?? (await hoverFor(declared));
+      results.push({
+        name: def.name,
+        kind: declared?.kind ?? def.kind ?? 'symbol',
+        language: declared?.language ?? detectLanguage(def.file),
+        ...(declared?.qualifiedName ? { qualifiedName: declared.qualifiedName } : {}),
+        location: `${def.file}:${def.line}`,
+        endLine: declaredEnd,
+        code: lines ? numberLines(lines, startLine, shownEnd) : '',
+        ...(shownEnd < endLine ? { truncated: true } : {}),
+        ...(documentation ? { documentation } : {})
+      });
+    }
+    return withServers({ total: exact.length, definitions: results, confidence: 'precise' });
+  }
+
+  const matches = matchDefinitions(definitions, symbol);
+
+  for (const match of matches.slice(0, options.limit)) {
+    const lines = await loadLines(match.file);
+    const endLine = Math.min(match.endLine, lines?.length ?? match.endLine);
+    const shownEnd = Math.min(endLine, match.startLine + options.maxLines - 1);
+    const documentation = await hoverFor(match);
+    results.push({
+      name: match.name,
+      kind: match.kind,
+      language: match.language,
+      ...(match.qualifiedName ? { qualifiedName: match.qualifiedName } : {}),
+      location: `${match.file}:${match.startLine}`,
+      endLine: match.endLine,
+      code: lines ? numberLines(lines, match.startLine, shownEnd) : '',
+      ...(shownEnd < endLine ? { truncated: true } : {}),
+      ...(documentation ? { documentation } : {})
+    });
+  }
+
+  return withServers({ total: matches.length, definitions: results, confidence: 'syntactic' });
+}
+
+export async function findReferences(
+  rootPath: string,
+  definitions: SymbolDefinition[],
+  symbol: string,
+  options: { limit: number; contextLines: number; signal?: AbortSignal },
+  precise?: PreciseIndex | null
+): Promise<ReferenceResult> {
+  // The scan is by identifier, so `UserService.save` looks for `save`
+  const identifier = symbol.trim().split(/\.|::/).pop() ?? symbol;
+  const exact = precise ? await preciseLocations(rootPath, precise, symbol) : null;
+  const hasPrecise = !!exact && exact.definitions.length + exact.references.length > 0;
+  const scan = await findSymbolReferences(rootPath, identifier, options.limit, options.signal);
+  if (scan.status === 'error' && !hasPrecise) return scan;
+
+  const declared = matchDefinitions(definitions, symbol);
+  const isDeclaration = (file: string, line: number) =>
+    declared.some((d) => d.file === file && d.startLine === line);
+  const loadLines = createLineLoader(rootPath);
+  const referenceAt = async (
+    file: string,
+    line: number,
+    definition: boolean,
+    preview = ''
+  ): Promise<ReferenceLocation> => {
+    const lines = await loadLines(file);
+    const code = lines
+      ? numberLines(
+          lines,
+          Math.max(1, line - options.contextLines),
+          Math.min(lines.length, line + options.contextLines)
+        )
+      : preview;
+    return { location: `${file}:${line}`, code, ...(definition ? { definition: true } : {}) };
+  };
+
+  if (exact && hasPrecise) {
+    // Precise locations where the index covers the file, the scan everywhere else
+    const located: Array<{ file: string; line: number; definition: boolean; preview: string }> = [
+      ...exact.definitions.map(({ file, line }) => ({ file, line, definition: true, preview: '' })),
+      ...exact.references.map(({ file, line }) => ({ file, line, definition: false, preview: '' }))
+    ];
+    const usages = scan.status === 'success' ? scan.usages : [];
+    for (const usage of usages) {
+      if (await exact.isCovered(usage.file)) continue;
+      const { file, line, preview } = usage;
+      located.push({ file, line, definition: isDeclaration(file, line), preview });
+    }
+    const fallback = located.length - exact.definitions.length - exact.references.length;
+    const references: ReferenceLocation[] = [];
+    for (const entry of located.slice(0, options.limit)) {
+      references.push(await referenceAt(entry.file, entry.line, entry.definition, entry.preview));
+    }
+    return {
+      status: 'success',
+      total: located.length,
+      isComplete: located.length <= options.limit && (scan.status !== 'success' || scan.isComplete),
+      definitions: exact.definitions.map((d) => `${d.file}:${d.line}`),
+      references,
+      confidence: fallback > 0 ? 'mixed' : 'precise'
+    };
+  }
+  if (scan.status === 'error') return scan;
+
+  const references: ReferenceLocation[] = [];
+  for (const usage of scan.usages) {
+    const definition = isDeclaration(usage.file, usage.line);
+    references.push(await referenceAt(usage.file, usage.line, definition, usage.preview));
+  }
+
+  return {
+    status: 'success',
+    total: scan.usageCount,
+    isComplete: scan.isComplete,
+    definitions: declared.map((d) => `${d.file}:${d.startLine}`),
+    references,
+    confidence: 'syntactic'
+  };
+}
+
+export type ScopeLevel = 'symbol' | 'outermost' | 'file';
+
+export interface EnclosingScope {
+  /** Repo-relative path */
+  file: string;
+  scope: 'symbol' | 'file';
+  name?: string;
+  kind?: string;
+  qualifiedName?: string;
+  language: string;
+  startLine: number;
+  endLine: number;
+  /** Enclosing definitions from the outermost in, ending with this one */
+  symbolPath?: string[];
+  /** Line-numbered source of the scope */
+  code: string;
+  /** Set when the code was cut at `maxLines` */
+  truncated?: boolean;
+}
+
+/**
+ * The declaration surrounding lines `startLine`-`endLine` of a file, from the symbol table's
+ * tree-sitter boundaries: the innermost one by default, the top-level one with `outermost`.
+ * Falls back to the whole file when no definition spans the range (or with `file`).
+ * Returns null when the file can't be read.
+ */
+export async function getEnclosingScope(
+  rootPath: string,
+  definitions: SymbolDefinition[],
+  target: { file: string; startLine: number; endLine?: number },
+  options: { level: ScopeLevel; maxLines: number }
+): Promise<EnclosingScope | null> {
+  const lines = await createLineLoader(rootPath)(target.file);
+  if (!lines) return null;
+
+  const first = Math.max(1, target.startLine);
+  const last = Math.max(first, target.endLine ?? first);
+  const enclosing = definitions
+    .filter((d) => d.file === target.file && d.startLine <= first && d.endLine >= last)
+    .sort((a, b) => b.endLine - b.startLine - (a.endLine - a.startLine));
+  const chosen =
+    options.level === 'file'
+      ? undefined
+      : options.level === 'outermost'
+        ? enclosing[0]
+        : enclosing[enclosing.length - 1];
+
+  const startLine = chosen?.startLine ?? 1;
+  const endLine = Math.min(chosen?.endLine ?? lines.length, lines.length);
+  const shownEnd = Math.min(endLine, startLine + options.maxLines - 1);
+  const code = numberLines(lines, startLine, shownEnd);
+  const truncated = shownEnd < endLine ? { truncated: true } : {};
+
+  if (!chosen) {
+    return {
+      file: target.file,
+      scope: 'file',
+      language: detectLanguage(target.file),
+      startLine,
+      endLine,
+      code,
+      ...truncated
+    };
+  }
+  return {
+    file: target.file,
+    scope: 'symbol',
+    name: chosen.name,
+    kind: chosen.kind,
+    ...(chosen.qualifiedName ? { qualifiedName: chosen.qualifiedName } : {}),
+    language: chosen.language,
+    startLine,
+    endLine,
+    symbolPath: enclosing.slice(0, enclosing.indexOf(chosen) + 1).map((d) => d.name),
+    code,
+    ...truncated
+  };
+}
+
+export interface SymbolDocs {
+  name: string;
+  kind: string;
+  language: string;
+  qualifiedName?: string;
+  /** "path:startLine" */
+  location: string;
+  /** First line of the declaration, or its typed signature from a language server */
+  signature: string;
+  /** Doc comment as written, markers stripped */
+  doc?: string;
+  /**
+   * `comment`: read from the source; `precise`: hover text from the SCIP/LSIF index;
+   * `lsp`: hover text from a language server
+   */
+  docSource?: 'comment' | 'precise' | 'lsp';
+}
+
+export interface SymbolDocsResult {
+  total: number;
+  documented: number;
+  symbols: SymbolDocs[];
+  /** Language servers that supplied signatures or docs */
+  languageServers?: string[];
+}
+
+const MAX_SIGNATURE_LENGTH = 200;
+
+/** The declaration line of a definition, past any leading comment and attributes */
+function declarationLine(lines: string[], startLine: number, endLine: number): string {
+  for (let line = startLine; line <= Math.min(endLine, lines.length); line++) {
+    const text = lines[line - 1].trim();
+    if (!text || /^(\/\/|\/\*|\*|#|@)/.test(text)) continue;
+    return text.length > MAX_SIGNATURE_LENGTH
+      ? `${text.slice(0, MAX_SIGNATURE_LENGTH - 1)}…`
+      : text;
+  }
+  return '';
+}
+
+/**
+ * Doc comments of the definitions named `symbol`, read from the working tree so they match
+ * the current source. Hover text from an imported SCIP/LSIF index, else from a language
+ * server, fills in undocumented ones; a language server's signature replaces the declaration
+ * line.
+ */
+export async function getSymbolDocs(
+  rootPath: string,
+  definitions: SymbolDefinition[],
+  symbol: string,
+  options: { limit: number; hover?: HoverLookup },
+  precise?: PreciseIndex | null
+): Promise<SymbolDocsResult> {
+  const loadLines = createLineLoader(rootPath);
+  const matches = matchDefinitions(definitions, symbol);
+  const hover = precise ? (await preciseLocations(rootPath, precise, symbol)).definitions : [];
+  const servers = new Set<string>();
+
+  const symbols: SymbolDocs[] = [];
+  for (const match of matches.slice(0, options.limit)) {
+    const lines = await loadLines(match.file);
+    const comment = lines
+      ? extractDocComment(lines, match.startLine, match.endLine, match.language)
+      : undefined;
+    const preciseDoc = comment
+      ? undefined
+      : hover.find(
+          (def) =>
+            def.file === match.file &&
+            def.line >= match.startLine &&
+            def.line <= match.endLine &&
+            def.documentation
+        )?.documentation;
+    const lsp: LspHover | null = options.hover ? await options.hover(match) : null;
+    if (lsp) servers.add(lsp.server);
+    const lspDoc = comment || preciseDoc ? undefined : lsp?.documentation;
+    symbols.push({
+      name: match.name,
+      kind: match.kind,
+      language: match.language,
+      ...(match.qualifiedName ? { qualifiedName: match.qualifiedName } : {}),
+      location: `${match.file}:${match.startLine}`,
+      signature:
+        lsp?.signature ?? (lines ? declarationLine(lines, match.startLine, match.endLine) : ''),
+      ...(comment ? { doc: comment, docSource: 'comment' as const } : {}),
+      ...(preciseDoc ? { doc: preciseDoc, docSource: 'precise' as const } : {}),
+      ...(lspDoc ? { doc: lspDoc, docSource: 'lsp' as const } : {})
+    });
+  }
+
+  return {
+    total: matches.length,
+    documented: symbols.filter((entry) => entry.doc).length,
+    symbols,
+    ...(servers.size > 0 ? { languageServers: [...servers].sort() } : {})
+  };
+}
@@ -2,9 +2,14 @@ import { promises as fs } from 'fs';
 import path from 'path';
 import { CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME } from '../constants/codebase-context.js';
 import { IndexCorruptedError } from '../errors/index.js';
-import type { UsageLocation } from '../types/index.js';
+import { openIndexedChunks } from './index-encryption.js';
+import { readIndexArtifact } from './index-lock.js';
+import type { CodeChunk, UsageLocation } from '../types/index.js';
 import { detectLanguage } from '../utils/language-detection.js';
 import { findIdentifierOccurrences } from '../utils/tree-sitter.js';
+import { redactSecrets, resolveRedactionOptions } from '../utils/secret-redaction.js';
+import { loadComplianceFilter } from './compliance-filters.js';
+import { readTextFile } from '../utils/text-encoding.js';
 
 interface IndexedChunk {
   content?: unknown;
@@ -24,6 +29,8 @@ interface SymbolReferencesSuccess {
   usages: SymbolUsage[];
   confidence: 'syntactic';
   isComplete: boolean;
+  /** Stopped by `signal` before every candidate file was scanned */
+  partial?: boolean;
 }
 
 interface SymbolReferencesError {
@@ -104,7 +111,8 @@ async function fileExists(targetPath: string): Promise<boolean> {
 export async function findSymbolReferences(
   rootPath: string,
   symbol: string,
-  limit = 10
+  limit = 10,
+  signal?: AbortSignal
 ): Promise<SymbolReferencesResult> {
   const normalizedSymbol = symbol.trim();
   const normalizedLimit = Number.isFinite(limit) && limit > 0 ? Math.floor(limit) : 10;
@@ -116,11 +124,13 @@ export async function findSymbolReferences(
     };
   }
 
-  const indexPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME);
-
+  const compliance = loadComplianceFilter(rootPath);
   let chunksRaw: unknown;
   try {
-    const content = await fs.readFile(indexPath, 'utf-8');
+    const content = await readIndexArtifact(
+      path.join(rootPath, CODEBASE_CONTEXT_DIRNAME),
+      KEYWORD_INDEX_FILENAME
+    );
     chunksRaw = JSON.parse(content);
   } catch (error) {
     throw new IndexCorruptedError(
@@ -141,7 +151,7 @@ export async function findSymbolReferences(
     typeof chunksRaw === 'object' &&
     'chunks' in chunksRaw &&
     Array.isArray(chunksRaw.chunks)
-      ? (chunksRaw.chunks as unknown[])
+      ? await openIndexedChunks(chunksRaw.chunks as CodeChunk[])
       : null;
 
   if (!chunks) {
@@ -182,23 +192,30 @@ export async function findSymbolReferences(
     }
   }
 
+  let partial = false;
   for (const entry of chunksByFile.values()) {
+    if (signal?.aborted) {
+      partial = true;
+      break;
+    }
     const relPath = entry.relPath;
     const absPath = entry.absPath;
 
     // Preferred: Tree-sitter identifier walk on the real file content.
     if (absPath && (await fileExists(absPath))) {
       try {
-        const raw = await fs.readFile(absPath, 'utf-8');
+        const raw = await readTextFile(absPath);
         const content = raw.replace(/\r\n/g, '\n');
-        const language = detectLanguage(absPath);
+        const language = detectLanguage(absPath, content);
         const occurrences = await findIdentifierOccurrences(content, language, normalizedSymbol);
 
         if (occurrences) {
           usageCount += occurrences.length;
 
           if (usages.length < normalizedLimit && occurrences.length > 0) {
-            const lines = content.split('\n');
+            // Indexed chunks are already redacted; previews read from disk are not
+            const redacted = redactSecrets(content, resolveRedactionOptions()).text;
+            const lines = (await compliance).mask(redacted).text.split('\n');
             for (const occ of occurrences) {
               if (usages.length >= normalizedLimit) break;
               usages.push({
@@ -250,6 +267,7 @@ export async function findSymbolReferences(
     usageCount,
     usages,
     confidence: 'syntactic',
-    isComplete: usageCount < normalizedLimit
+    isComplete: !partial && usageCount < normalizedLimit,
+    ...(partial ? { partial } : {})
   };
 }
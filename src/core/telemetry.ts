@@ -0,0 +1,290 @@
+/**
+ * Optional tracing and in-process metrics, for operating a shared instance.
+ *
+ * Tracing is off unless `CODEBASE_CONTEXT_OTEL=true`. Spans go through `@opentelemetry/api`,
+ * loaded on first use when it is installed, and are exported by whatever OpenTelemetry SDK the
+ * host process registers (e.g. `--import @opentelemetry/auto-instrumentations-node/register`).
+ * Without the package or an SDK, spans are no-ops. Span attributes carry tool names, stages,
+ * providers and counts, never queries, code or paths.
+ *
+ * Metrics are plain in-memory counters and histograms, rendered in the Prometheus text format
+ * and served at `/metrics` in HTTP mode.
+ */
+
+export type Labels = Record<string, string>;
+export type SpanAttributes = Record<string, string | number | boolean>;
+
+const DEFAULT_BUCKETS = [0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60];
+
+function escapeLabelValue(value: string): string {
+  return value.replace(/\\/g, '\\\\').replace(/"/g, '\\"').replace(/\n/g, '\\n');
+}
+
+function formatLabels(labels: Labels, extra?: Labels): string {
+  const pairs = Object.entries({ ...labels, ...extra })
+    .sort(([a], [b]) => a.localeCompare(b))
+    .map(([key, value]) => `${key}="${escapeLabelValue(value)}"`);
+  return pairs.length > 0 ? `{${pairs.join(',')}}` : '';
+}
+
+function seriesKey(labels: Labels): string {
+  return JSON.stringify(Object.entries(labels).sort(([a], [b]) => a.localeCompare(b)));
+}
+
+export class Counter {
+  private series = new Map<string, { labels: Labels; value: number }>();
+
+  constructor(
+    readonly name: string,
+    readonly help: string
+  ) {}
+
+  inc(labels: Labels = {}, value = 1): void {
+    const key = seriesKey(labels);
+    const entry = this.series.get(key) ?? { labels, value: 0 };
+    entry.value += value;
+    this.series.set(key, entry);
+  }
+
+  value(labels: Labels = {}): number {
+    return this.series.get(seriesKey(labels))?.value ?? 0;
+  }
+
+  render(): string[] {
+    const lines = [`# HELP ${this.name} ${this.help}`, `# TYPE ${this.name} counter`];
+    for (const { labels, value } of this.series.values()) {
+      lines.push(`${this.name}${formatLabels(labels)} ${value}`);
+    }
+    return lines;
+  }
+}
+
+export class Histogram {
+  private series = new Map<string, { labels: Labels; counts: number[]; sum: number }>();
+
+  constructor(
+    readonly name: string,
+    readonly help: string,
+    private buckets: number[] = DEFAULT_BUCKETS
+  ) {}
+
+  observe(labels: Labels, value: number): void {
+    const key = seriesKey(labels);
+    const entry = this.series.get(key) ?? {
+      labels,
+      counts: new Array(this.buckets.length + 1).fill(0),
+      sum: 0
+    };
+    // Last slot is +Inf; buckets are made cumulative when rendered
+    const bucket = this.buckets.findIndex((bound) => value <= bound);
+    entry.counts[bucket < 0 ? this.buckets.length : bucket]++;
+    entry.sum += value;
+    this.series.set(key, entry);
+  }
+
+  count(labels: Labels = {}): number {
+    return this.series.get(seriesKey(labels))?.counts.reduce((a, b) => a + b, 0) ?? 0;
+  }
+
+  render(): string[] {
+    const lines = [`# HELP ${this.name} ${this.help}`, `# TYPE ${this.name} histogram`];
+    for (const { labels, counts, sum } of this.series.values()) {
+      let cumulative = 0;
+      this.buckets.forEach((bound, i) => {
+        cumulative += counts[i];
+        const bucketLabels = formatLabels(labels, { le: String(bound) });
+        lines.push(`${this.name}_bucket${bucketLabels} ${cumulative}`);
+      });
+      cumulative += counts[this.buckets.length];
+      lines.push(`${this.name}_bucket${formatLabels(labels, { le: '+Inf' })} ${cumulative}`);
+      lines.push(`${this.name}_sum${formatLabels(labels)} ${sum}`);
+      lines.push(`${this.name}_count${formatLabels(labels)} ${cumulative}`);
+    }
+    return lines;
+  }
+}
+
+export class MetricsRegistry {
+  private metrics: Array<Counter | Histogram> = [];
+
+  counter(name: string, help: string): Counter {
+    const counter = new Counter(name, help);
+    this.metrics.push(counter);
+    return counter;
+  }
+
+  histogram(name: string, help: string, buckets?: number[]): Histogram {
+    const histogram = new Histogram(name, help, buckets);
+    this.metrics.push(histogram);
+    return histogram;
+  }
+
+  /** Prometheus text exposition format (0.0.4) */
+  render(): string {
+    return this.metrics.flatMap((metric) => metric.render()).join('\n') + '\n';
+  }
+}
+
+export const METRICS_CONTENT_TYPE = 'text/plain; version=0.0.4; charset=utf-8';
+
+export const metricsRegistry = new MetricsRegistry();
+
+export const metrics = {
+  toolCalls: metricsRegistry.counter(
+    'codebase_context_tool_calls_total',
+    'Tool invocations by tool and status (ok or error)'
+  ),
+  toolDuration: metricsRegistry.histogram(
+    'codebase_context_tool_duration_seconds',
+    'Tool invocation latency by tool'
+  ),
+  searchQueries: metricsRegistry.counter(
+    'codebase_context_search_queries_total',
+    'Searches run, by retrieval mode'
+  ),
+  embedDuration: metricsRegistry.histogram(
+    'codebase_context_embedding_duration_seconds',
+    'Embedding call latency by provider and operation (query or batch)'
+  ),
+  embeddedTexts: metricsRegistry.counter(
+    'codebase_context_embedded_texts_total',
+    'Texts sent to the embedding provider'
+  ),
+  embeddingCacheHits: metricsRegistry.counter(
+    'codebase_context_embedding_cache_hits_total',
+    'Chunks whose vector was reused from the embedding cache'
+  ),
+  embeddingCacheMisses: metricsRegistry.counter(
+    'codebase_context_embedding_cache_misses_total',
+    'Chunks that had to be embedded'
+  ),
+  indexBuilds: metricsRegistry.counter(
+    'codebase_context_index_builds_total',
+    'Index builds by mode (full or incremental) and status'
+  ),
+  indexStageDuration: metricsRegistry.histogram(
+    'codebase_context_index_stage_duration_seconds',
+    'Time spent in each indexing stage',
+    [0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 1800]
+  )
+};
+
+/** Seconds since the call, for histogram observations */
+export function startTimer(): () => number {
+  const start = performance.now();
+  return () => (performance.now() - start) / 1000;
+}
+
+export interface TraceSpan {
+  setAttribute(key: string, value: string | number | boolean): void;
+  recordError(error: unknown): void;
+  end(): void;
+}
+
+const NOOP_SPAN: TraceSpan = {
+  setAttribute: () => undefined,
+  recordError: () => undefined,
+  end: () => undefined
+};
+
+/** The parts of `@opentelemetry/api` used here */
+interface OtelSpan {
+  setAttribute(key: string, value: string | number | boolean): unknown;
+  recordException(exception: Error | string): void;
+  setStatus(status: { code: number; message?: string }): unknown;
+  end(): void;
+}
+
+interface OtelApi {
+  trace: {
+    getTracer(name: string): {
+      startSpan(
+        name: string,
+        options?: { attributes?: SpanAttributes },
+        context?: unknown
+      ): OtelSpan;
+    };
+    setSpan(context: unknown, span: OtelSpan): unknown;
+  };
+  context: {
+    active(): unknown;
+    with<T>(context: unknown, fn: () => T): T;
+  };
+}
+
+const TRACER_NAME = 'codebase-context';
+/** SpanStatusCode.ERROR */
+const SPAN_STATUS_ERROR = 2;
+
+let otelApi: OtelApi | null = null;
+let otelApiLoad: Promise<OtelApi | null> | null = null;
+
+export function isTracingEnabled(env: NodeJS.ProcessEnv = process.env): boolean {
+  const value = env.CODEBASE_CONTEXT_OTEL?.trim().toLowerCase();
+  return value === 'true' || value === '1';
+}
+
+async function loadOtelApi(): Promise<OtelApi | null> {
+  otelApiLoad ??= (async () => {
+    // Non-literal specifier keeps the package optional for type-checking and bundling
+    const specifier = '@opentelemetry/api';
+    try {
+      const mod = (await import(specifier)) as OtelApi & { default?: OtelApi };
+      otelApi = mod.default ?? mod;
+    } catch {
+      console.error(
+        'CODEBASE_CONTEXT_OTEL is set but @opentelemetry/api is not installed; tracing is off.'
+      );
+    }
+    return otelApi;
+  })();
+  return otelApiLoad;
+}
+
+function wrapSpan(span: OtelSpan): TraceSpan {
+  return {
+    setAttribute: (key, value) => {
+      span.setAttribute(key, value);
+    },
+    recordError: (error) => {
+      const message = error instanceof Error ? error.message : String(error);
+      span.recordException(error instanceof Error ? error : message);
+      span.setStatus({ code: SPAN_STATUS_ERROR, message });
+    },
+    end: () => span.end()
+  };
+}
+
+/**
+ * A span under the active one, ended by the caller. Spans are only recorded once tracing has
+ * loaded, which the first `withSpan` does.
+ */
+export function startSpan(name: string, attributes: SpanAttributes = {}): TraceSpan {
+  if (!otelApi || !isTracingEnabled()) return NOOP_SPAN;
+  const span = otelApi.trace
+    .getTracer(TRACER_NAME)
+    .startSpan(name, { attributes }, otelApi.context.active());
+  return wrapSpan(span);
+}
+
+/** Run `fn` in a span that nested spans attach to; errors are recorded on it and rethrown */
+export async function withSpan<T>(
+  name: string,
+  attributes: SpanAttributes,
+  fn: (span: TraceSpan) => Promise<T>
+): Promise<T> {
+  const api = isTracingEnabled() ? await loadOtelApi() : null;
+  if (!api) return fn(NOOP_SPAN);
+
+  const parent = api.context.active();
+  const span = api.trace.getTracer(TRACER_NAME).startSpan(name, { attributes }, parent);
+  const handle = wrapSpan(span);
+  try {
+    return await api.context.with(api.trace.setSpan(parent, span), () => fn(handle));
+  } catch (error) {
+    handle.recordError(error);
+    throw error;
+  } finally {
+    span.end();
+  }
+}
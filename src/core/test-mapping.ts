@@ -0,0 +1,236 @@
+/**
+ * Test-to-source mapping for `get_tests_for`.
+ *
+ * At index time each test file is linked to the production files it exercises, by three
+ * signals: naming (`user.service.spec.ts`, `test_users.py`, `users_test.go`, `UsersTest.java`),
+ * imports, and calls into functions defined in production code. Symbol lookups add the test
+ * functions that call the symbol directly. All signals are static, so a test reached only
+ * through dynamic dispatch or fixtures is missed.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import { CODEBASE_CONTEXT_DIRNAME, RELATIONSHIPS_FILENAME } from '../constants/codebase-context.js';
+import type { CallGraphData } from './call-graph.js';
+import { isTestFile } from '../utils/language-detection.js';
+
+export type TestLinkSignal = 'naming' | 'import' | 'calls';
+
+export interface TestLink {
+  /** Repo-relative test file */
+  file: string;
+  via: TestLinkSignal[];
+  /** Production symbols of the source file the test calls */
+  symbols?: string[];
+}
+
+/** source file -> tests that exercise it */
+export type TestLinks = Record<string, TestLink[]>;
+
+export interface TestFunctionRef {
+  name: string;
+  line: number;
+}
+
+export interface TestMatch extends TestLink {
+  /** Test functions calling the requested symbol (symbol lookups only) */
+  testFunctions?: TestFunctionRef[];
+}
+
+const SIGNAL_WEIGHT: Record<TestLinkSignal, number> = { calls: 3, import: 2, naming: 1 };
+const MAX_LINKED_SYMBOLS = 10;
+
+const TEST_NAME_PATTERNS: RegExp[] = [
+  /^(.+)\.(?:test|spec|e2e-spec)$/i, // user.service.spec, Button.test
+  /^(.+)_(?:test|spec)$/i, // users_test (Go), users_spec (Ruby)
+  /^test_(.+)$/i // test_users (pytest)
+];
+/** UsersTest, UsersTests, UsersSpec, UsersIT; only for languages that name tests this way */
+const CLASS_TEST_NAME = /^(.*[a-z0-9])(?:Tests?|Spec|IT)$/;
+const CLASS_TEST_EXTENSIONS = new Set([
+  '.java',
+  '.kt',
+  '.kts',
+  '.scala',
+  '.groovy',
+  '.cs',
+  '.swift'
+]);
+
+/** The production stem a test file is named after, or null when it isn't named like a test. */
+export function testSubjectStem(file: string): string | null {
+  const base = path.posix.basename(file.replace(/\\/g, '/'));
+  const extension = path.posix.extname(base).toLowerCase();
+  const stem = base.slice(0, base.length - extension.length);
+  for (const pattern of TEST_NAME_PATTERNS) {
+    const match = stem.match(pattern);
+    if (match?.[1]) return match[1];
+  }
+  return CLASS_TEST_EXTENSIONS.has(extension) ? (stem.match(CLASS_TEST_NAME)?.[1] ?? null) : null;
+}
+
+export function isTestSourceFile(file: string): boolean {
+  return isTestFile(file) || testSubjectStem(file) !== null;
+}
+
+function sourceStem(file: string): string {
+  return path.posix.basename(file).replace(/\.[^.]+$/, '');
+}
+
+/** Directory hops between two files (0 = same directory) */
+function treeDistance(a: string, b: string): number {
+  const left = path.posix.dirname(a).split('/');
+  const right = path.posix.dirname(b).split('/');
+  let common = 0;
+  while (common < left.length && left[common] === right[common]) common++;
+  return left.length - common + (right.length - common);
+}
+
+function linkScore(link: TestLink): number {
+  return link.via.reduce((sum, signal) => sum + SIGNAL_WEIGHT[signal], 0);
+}
+
+function sortLinks<T extends TestLink>(links: T[]): T[] {
+  return links.sort((a, b) => linkScore(b) - linkScore(a) || a.file.localeCompare(b.file));
+}
+
+/**
+ * Link test files to production files by naming, imports and calls. `files` are repo-relative
+ * posix paths of every indexed file.
+ */
+export function buildTestLinks(
+  files: readonly string[],
+  imports: Record<string, string[]>,
+  callGraph: CallGraphData
+): TestLinks {
+  const testFiles = files.filter(isTestSourceFile);
+  const productionFiles = files.filter((file) => !isTestSourceFile(file));
+  const byStem = new Map<string, string[]>();
+  for (const file of productionFiles) {
+    const stem = sourceStem(file).toLowerCase();
+    const sameStem = byStem.get(stem);
+    if (sameStem) sameStem.push(file);
+    else byStem.set(stem, [file]);
+  }
+  const production = new Set(productionFiles);
+
+  const collected = new Map<string, Map<string, TestLink>>();
+  const link = (source: string, test: string, signal: TestLinkSignal, symbol?: string) => {
+    const perSource = collected.get(source) ?? new Map<string, TestLink>();
+    collected.set(source, perSource);
+    const entry: TestLink = perSource.get(test) ?? { file: test, via: [] };
+    perSource.set(test, entry);
+    if (!entry.via.includes(signal)) entry.via.push(signal);
+    if (!symbol) return;
+    const symbols = (entry.symbols ??= []);
+    if (!symbols.includes(symbol)) symbols.push(symbol);
+  };
+
+  const callsByFile = new Map<string, CallGraphData['calls']>();
+  for (const call of callGraph.calls) {
+    const calls = callsByFile.get(call.file);
+    if (calls) calls.push(call);
+    else callsByFile.set(call.file, [call]);
+  }
+
+  for (const test of testFiles) {
+    // Naming: same stem; several candidates -> the ones closest in the tree
+    const stem = testSubjectStem(test)?.toLowerCase();
+    const candidates = stem ? (byStem.get(stem) ?? []) : [];
+    const closest = Math.min(...candidates.map((c) => treeDistance(c, test)));
+    for (const source of candidates) {
+      if (treeDistance(source, test) === closest) link(source, test, 'naming');
+    }
+
+    const imported = (imports[test] ?? []).filter((file) => production.has(file));
+    for (const source of imported) link(source, test, 'import');
+
+    // Calls: name-based, so only trusted when the definition's file is imported or unique
+    const importedSet = new Set(imported);
+    for (const call of callsByFile.get(test) ?? []) {
+      const definitionFiles = [
+        ...new Set((callGraph.definitions[call.callee] ?? []).map((def) => def.file))
+      ].filter((file) => production.has(file));
+      const trusted =
+        definitionFiles.length === 1
+          ? definitionFiles
+          : definitionFiles.filter((file) => importedSet.has(file));
+      for (const source of trusted) link(source, test, 'calls', call.callee);
+    }
+  }
+
+  const links: TestLinks = {};
+  for (const [source, perSource] of [...collected].sort(([a], [b]) => a.localeCompare(b))) {
+    links[source] = sortLinks(
+      [...perSource.values()].map((entry) =>
+        entry.symbols ? { ...entry, symbols: entry.symbols.slice(0, MAX_LINKED_SYMBOLS) } : entry
+      )
+    );
+  }
+  return links;
+}
+
+/**
+ * Load test links from the relationships sidecar.
+ * Returns null when the index predates test mapping or hasn't been built.
+ */
+export async function loadTestLinks(rootPath: string): Promise<TestLinks | null> {
+  const relationshipsPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, RELATIONSHIPS_FILENAME);
+  try {
+    const parsed = JSON.parse(await fs.readFile(relationshipsPath, 'utf-8')) as {
+      tests?: unknown;
+    };
+    return parsed.tests && typeof parsed.tests === 'object' ? (parsed.tests as TestLinks) : null;
+  } catch {
+    return null;
+  }
+}
+
+/** Tests linked to a production file, strongest signal first. */
+export function findTestsForFile(links: TestLinks, file: string): TestMatch[] {
+  return links[file] ?? [];
+}
+
+/**
+ * Tests for a symbol: test functions that call it directly, then the other tests linked to
+ * the files defining it.
+ */
+export function findTestsForSymbol(
+  links: TestLinks,
+  callGraph: CallGraphData | null,
+  symbol: string,
+  definitionFiles: readonly string[]
+): TestMatch[] {
+  type DirectMatch = TestMatch & { testFunctions: TestFunctionRef[] };
+  const direct = new Map<string, DirectMatch>();
+  for (const call of callGraph?.calls ?? []) {
+    if (call.callee !== symbol || !isTestSourceFile(call.file)) continue;
+    const match: DirectMatch = direct.get(call.file) ?? {
+      file: call.file,
+      via: ['calls'],
+      testFunctions: []
+    };
+    direct.set(call.file, match);
+    const name = call.caller ?? '(top level)';
+    if (!match.testFunctions.some((fn) => fn.name === name)) {
+      match.testFunctions.push({ name, line: call.callerStartLine ?? call.line });
+    }
+  }
+
+  // File-level links of the defining files: these tests exercise the file, not the symbol
+  const related = new Map<string, TestMatch>();
+  for (const source of definitionFiles) {
+    for (const linked of links[source] ?? []) {
+      const existing = direct.get(linked.file) ?? related.get(linked.file);
+      if (!existing) {
+        related.set(linked.file, { file: linked.file, via: [...linked.via] });
+        continue;
+      }
+      for (const signal of linked.via) {
+        if (!existing.via.includes(signal)) existing.via.push(signal);
+      }
+    }
+  }
+
+  return [...sortLinks([...direct.values()]), ...sortLinks([...related.values()])];
+}
@@ -0,0 +1,77 @@
+/**
+ * Tombstones for paths that left the index: files deleted, or moved (the same content found
+ * under a new path). Incremental builds update moved files in place and delete removed ones,
+ * but a vector store can still hold rows for an old path (a failed delete, a shared remote
+ * collection, an embedding collection not yet refreshed). Search drops those hits, so results
+ * never point at a file that no longer exists.
+ *
+ * `.codebase-context/tombstones.json` keeps the most recent MAX_TOMBSTONES paths. A path that
+ * comes back is cleared by the build that indexes it again.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import { TOMBSTONES_FILENAME } from '../constants/codebase-context.js';
+import type { FileRename } from './manifest.js';
+
+export const MAX_TOMBSTONES = 2000;
+
+export interface Tombstone {
+  /** Where the content lives now, when the file was moved rather than deleted */
+  movedTo?: string;
+  /** Build that removed the path, and when it finished */
+  buildId: string;
+  removedAt: string;
+}
+
+interface TombstoneFile {
+  version: 1;
+  /** relativePath -> tombstone */
+  paths: Record<string, Tombstone>;
+}
+
+export interface TombstoneUpdate {
+  buildId: string;
+  generatedAt: string;
+  deleted: string[];
+  renamed: FileRename[];
+  /** Paths the build indexed; their tombstones, if any, are cleared */
+  present: string[];
+}
+
+export async function readTombstones(contextDir: string): Promise<Map<string, Tombstone>> {
+  try {
+    const parsed = JSON.parse(
+      await fs.readFile(path.join(contextDir, TOMBSTONES_FILENAME), 'utf-8')
+    ) as Partial<TombstoneFile>;
+    if (parsed.version !== 1 || !parsed.paths || typeof parsed.paths !== 'object') {
+      return new Map();
+    }
+    return new Map(Object.entries(parsed.paths));
+  } catch {
+    return new Map();
+  }
+}
+
+/** Add the paths a build deleted or moved away, and clear the ones it indexed */
+export async function recordTombstones(contextDir: string, update: TombstoneUpdate): Promise<void> {
+  const tombstones = await readTombstones(contextDir);
+  const before = tombstones.size;
+  for (const file of update.present) tombstones.delete(file);
+  const cleared = tombstones.size < before;
+
+  const stamp = { buildId: update.buildId, removedAt: update.generatedAt };
+  for (const file of update.deleted) tombstones.set(file, { ...stamp });
+  for (const { from, to } of update.renamed) tombstones.set(from, { movedTo: to, ...stamp });
+  if (!cleared && update.deleted.length === 0 && update.renamed.length === 0) return;
+
+  // Newest last; the oldest are forgotten first
+  const kept = [...tombstones]
+    .sort((a, b) => a[1].removedAt.localeCompare(b[1].removedAt))
+    .slice(-MAX_TOMBSTONES);
+  const file = path.join(contextDir, TOMBSTONES_FILENAME);
+  const tmp = `${file}.${process.pid}.tmp`;
+  const content: TombstoneFile = { version: 1, paths: Object.fromEntries(kept) };
+  await fs.writeFile(tmp, JSON.stringify(content));
+  await fs.rename(tmp, file);
+}
@@ -0,0 +1,162 @@
+/**
+ * Literal pre-filter for the vector channel. An identifier-heavy query (`parseConfigFile`,
+ * `ECONNREFUSED`, `"connection refused"`) can only be answered by the few files that contain
+ * those literals, yet on a large index the ANN query still ranks every chunk. A trigram index
+ * over the keyword-index chunks finds the files that contain the query's literal tokens, and
+ * the vector query is scoped to them the way path filters are.
+ *
+ * Opt-in: `search.prefilter` in `.codebase-context/config.json` (`true`, or `{ "minChunks":
+ * 10000, "maxFiles": 500 }`) or CODEBASE_CONTEXT_PREFILTER=on. It applies from `minChunks`
+ * indexed chunks up; smaller indexes search everything. A query without identifier-like
+ * tokens, or whose literals appear in no file or in more than `maxFiles`, searches
+ * everything too. Matching ignores case. The index is built on the first filtered query.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  PROJECT_CONFIG_FILENAME
+} from '../constants/codebase-context.js';
+import type { CodeChunk } from '../types/index.js';
+
+export const DEFAULT_PREFILTER_MIN_CHUNKS = 10_000;
+export const DEFAULT_PREFILTER_MAX_FILES = 500;
+/** Shorter literals have no trigram to look up */
+const MIN_LITERAL_LENGTH = 3;
+/** Identifier tokens shorter than this are rarely specific enough to narrow on */
+const MIN_IDENTIFIER_LENGTH = 4;
+const MAX_LITERALS = 8;
+
+/** Single quotes only around one token, so apostrophes (`don't ... isn't`) pair up with nothing */
+const QUOTED = /"([^"\n]+)"|`([^`\n]+)`|'([^'\s]{3,})'/g;
+const WORD = /[A-Za-z_$][\w$]*(?:(?:\.|::|->)[A-Za-z_$][\w$]*)*/g;
+/** camelCase, snake_case, `$scope`, `a.b`, letters with digits, or CONSTANT/ACRONYM case */
+const IDENTIFIER_SHAPE = /[a-z0-9][A-Z]|\w(?:[_$.]|::|->)\w|^[_$]\w|[A-Za-z]\d|^[A-Z][A-Z0-9_]+$/;
+
+export interface PrefilterConfig {
+  /** Smallest index (in chunks) the pre-filter applies to */
+  minChunks: number;
+  /** Beyond this many matching files the full search is used */
+  maxFiles: number;
+}
+
+/** The `search.prefilter` key of `.codebase-context/config.json`; null when it is off */
+export async function loadPrefilterConfig(
+  rootPath: string,
+  env: NodeJS.ProcessEnv = process.env
+): Promise<PrefilterConfig | null> {
+  let raw: unknown;
+  try {
+    const configPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME);
+    const parsed = JSON.parse(await fs.readFile(configPath, 'utf-8')) as { search?: unknown };
+    raw = (parsed.search as { prefilter?: unknown } | undefined)?.prefilter;
+  } catch {
+    raw = undefined;
+  }
+  const section = raw && typeof raw === 'object' ? (raw as Record<string, unknown>) : {};
+  let enabled =
+    raw === true || (typeof raw === 'object' && raw !== null && section.enabled !== false);
+
+  const flag = env.CODEBASE_CONTEXT_PREFILTER?.trim().toLowerCase();
+  if (flag && ['1', 'true', 'on', 'yes'].includes(flag)) enabled = true;
+  if (flag && ['0', 'false', 'off', 'no'].includes(flag)) enabled = false;
+  if (!enabled) return null;
+
+  const count = (value: unknown, fallback: number) =>
+    typeof value === 'number' && value >= 0 ? Math.floor(value) : fallback;
+  return {
+    minChunks: count(section.minChunks, DEFAULT_PREFILTER_MIN_CHUNKS),
+    maxFiles: Math.max(1, count(section.maxFiles, DEFAULT_PREFILTER_MAX_FILES))
+  };
+}
+
+/**
+ * Literals of a query worth narrowing on: quoted strings, and tokens shaped like identifiers
+ * (`parseConfig`, `user_id`, `os.path`, `ECONNREFUSED`). Plain words are left to the vectors.
+ */
+export function literalTokens(query: string): string[] {
+  const literals = new Set<string>();
+  const add = (value: string | undefined) => {
+    const literal = value?.trim().toLowerCase();
+    if (literal && literal.length >= MIN_LITERAL_LENGTH) literals.add(literal);
+  };
+  for (const match of query.matchAll(QUOTED)) add(match[1] ?? match[2] ?? match[3]);
+  for (const [word] of query.replace(QUOTED, ' ').matchAll(WORD)) {
+    if (word.length >= MIN_IDENTIFIER_LENGTH && IDENTIFIER_SHAPE.test(word)) add(word);
+  }
+  return [...literals].slice(0, MAX_LITERALS);
+}
+
+/** Three UTF-16 code units packed into one number (fits in 48 bits) */
+const trigramAt = (text: string, index: number) =>
+  text.charCodeAt(index) * 2 ** 32 +
+  text.charCodeAt(index + 1) * 2 ** 16 +
+  text.charCodeAt(index + 2);
+
+/** Which files contain a literal: trigram postings per file, confirmed against the content */
+export class TrigramIndex {
+  private files: string[] = [];
+  private fileChunks: CodeChunk[][] = [];
+  /** Trigram -> ascending file numbers */
+  private postings = new Map<number, number[]>();
+
+  constructor(chunks: CodeChunk[]) {
+    const byFile = new Map<string, CodeChunk[]>();
+    for (const chunk of chunks) {
+      const list = byFile.get(chunk.relativePath);
+      if (list) list.push(chunk);
+      else byFile.set(chunk.relativePath, [chunk]);
+    }
+    for (const [file, fileChunks] of byFile) {
+      const number = this.files.length;
+      this.files.push(file);
+      this.fileChunks.push(fileChunks);
+      for (const chunk of fileChunks) {
+        const text = (chunk.content ?? '').toLowerCase();
+        for (let i = 0; i + 3 <= text.length; i++) {
+          const trigram = trigramAt(text, i);
+          const posting = this.postings.get(trigram);
+          if (!posting) this.postings.set(trigram, [number]);
+          else if (posting[posting.length - 1] !== number) posting.push(number);
+        }
+      }
+    }
+  }
+
+  get size(): number {
+    return this.files.length;
+  }
+
+  /** Relative paths of the files containing `literal` (lowercase) */
+  private filesWith(literal: string): string[] {
+    const lists: number[][] = [];
+    for (let i = 0; i + 3 <= literal.length; i++) {
+      const posting = this.postings.get(trigramAt(literal, i));
+      if (!posting) return [];
+      lists.push(posting);
+    }
+    lists.sort((a, b) => a.length - b.length);
+    let candidates = lists[0] ?? [];
+    for (const list of lists.slice(1)) {
+      const members = new Set(list);
+      candidates = candidates.filter((file) => members.has(file));
+      if (candidates.length === 0) return [];
+    }
+    // Every trigram present doesn't mean they are adjacent
+    return candidates
+      .filter((file) =>
+        this.fileChunks[file].some((chunk) => chunk.content?.toLowerCase().includes(literal))
+      )
+      .map((file) => this.files[file]);
+  }
+
+  /** Files containing any of the literals */
+  filesContaining(literals: string[]): Set<string> {
+    const files = new Set<string>();
+    for (const literal of literals) {
+      for (const file of this.filesWith(literal)) files.add(file);
+    }
+    return files;
+  }
+}
@@ -0,0 +1,537 @@
+/**
+ * Type hierarchy for TypeScript/JavaScript and Go, built during indexing.
+ *
+ * TypeScript relations are nominal and read from `extends` / `implements` clauses. Go has no
+ * such clauses: a type implements an interface when its method set (methods on the type or
+ * its pointer, plus those promoted from embedded fields) covers the interface's methods,
+ * including embedded interfaces. Matching is by name; method signatures are not compared, and
+ * interfaces or types from outside the repo are not resolved.
+ *
+ * Stored in the relationships sidecar under `types`.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import { CODEBASE_CONTEXT_DIRNAME, RELATIONSHIPS_FILENAME } from '../constants/codebase-context.js';
+
+export type TypeKind = 'class' | 'interface' | 'struct' | 'type';
+
+export interface TypeDeclaration {
+  name: string;
+  kind: TypeKind;
+  /** Repo-relative path with forward slashes */
+  file: string;
+  line: number;
+  language: string;
+  /** Supertypes named in `extends` clauses */
+  extends?: string[];
+  /** Interfaces named in an `implements` clause */
+  implements?: string[];
+  /** Go: interface methods, or methods declared with this type as receiver */
+  methods?: string[];
+  /** Go: embedded interfaces or struct fields */
+  embeds?: string[];
+}
+
+export interface TypeHierarchyData {
+  declarations: TypeDeclaration[];
+}
+
+/** Blank out comments, keeping line numbers. `//` only counts after whitespace (not `http://`) */
+function stripComments(content: string): string {
+  return content
+    .replace(/\/\*[\s\S]*?\*\//g, (comment) => comment.replace(/[^\n]/g, ' '))
+    .replace(/(^|\s)\/\/.*$/gm, '$1');
+}
+
+/** `pkg.Reader` -> `Reader`, `Repository<User>` -> `Repository` */
+function baseName(typeName: string): string {
+  const name = /^[A-Za-z_$][\w$.]*/.exec(typeName.trim())?.[0] ?? '';
+  return name.split('.').pop() ?? '';
+}
+
+/** Names in a heritage list, splitting on top-level commas only */
+function splitTypeList(list: string): string[] {
+  const parts: string[] = [];
+  let depth = 0;
+  let current = '';
+  for (const ch of list) {
+    if ('<([{'.includes(ch)) depth++;
+    else if ('>)]}'.includes(ch)) depth--;
+    if (ch === ',' && depth === 0) {
+      parts.push(current);
+      current = '';
+    } else {
+      current += ch;
+    }
+  }
+  parts.push(current);
+  // Call expressions (`extends mixin(Base)`) don't name a type
+  return parts.filter((part) => !part.includes('(')).map(baseName).filter(Boolean);
+}
+
+function lineOf(content: string, index: number): number {
+  let line = 1;
+  for (let i = 0; i < index; i++) if (content.charCodeAt(i) === 10) line++;
+  return line;
+}
+
+const TS_CLASS =
+  /^[ \t]*(?:export\s+)?(?:default\s+)?(?:declare\s+)?(?:abstract\s+)?class\s+([A-Za-z_$][\w$]*)\s*(?:<[^{]*?>)?\s*(?:extends\s+([^{]+?))?\s*(?:implements\s+([^{]+?))?\s*\{/gm;
+const TS_INTERFACE =
+  /^[ \t]*(?:export\s+)?(?:declare\s+)?interface\s+([A-Za-z_$][\w$]*)\s*(?:<[^{]*?>)?\s*(?:extends\s+([^{]+?))?\s*\{/gm;
+
+export function extractTypeScriptTypes(
+  content: string,
+  file: string,
+  language: string
+): TypeDeclaration[] {
+  const source = stripComments(content);
+  const declarations: TypeDeclaration[] = [];
+  for (const match of source.matchAll(TS_CLASS)) {
+    const extendsList = match[2] ? splitTypeList(match[2]) : [];
+    const implementsList = match[3] ? splitTypeList(match[3]) : [];
+    declarations.push({
+      name: match[1],
+      kind: 'class',
+      file,
+      line: lineOf(source, match.index + match[0].search(/\bclass\b/)),
+      language,
+      ...(extendsList.length > 0 ? { extends: extendsList } : {}),
+      ...(implementsList.length > 0 ? { implements: implementsList } : {})
+    });
+  }
+  for (const match of source.matchAll(TS_INTERFACE)) {
+    const extendsList = match[2] ? splitTypeList(match[2]) : [];
+    declarations.push({
+      name: match[1],
+      kind: 'interface',
+      file,
+      line: lineOf(source, match.index + match[0].search(/\binterface\b/)),
+      language,
+      ...(extendsList.length > 0 ? { extends: extendsList } : {})
+    });
+  }
+  return declarations.sort((a, b) => a.line - b.line);
+}
+
+const GO_TYPE = /^type\s+([A-Za-z_]\w*)(?:\[[^\]]*\])?\s+(=\s*)?(\S.*)$/;
+const GO_GROUP_ENTRY = /^\s+([A-Za-z_]\w*)(?:\[[^\]]*\])?\s+(=\s*)?(\S.*)$/;
+const GO_METHOD =
+  /^func\s*\(\s*(?:[A-Za-z_]\w*\s+)?\*?\s*([A-Za-z_]\w*)(?:\[[^\]]*\])?\s*\)\s*([A-Za-z_]\w*)\s*[[(]/;
+
+/** Lines directly inside the braces opened on `lines[start]`, and the line that closes them */
+function readBraceBody(lines: string[], start: number): { body: string[]; end: number } {
+  const first = lines[start];
+  const open = first.indexOf('{');
+  const close = first.lastIndexOf('}');
+  // One-line bodies: `struct{}`, `interface{ Close() error }`
+  if (close > open) return { body: first.slice(open + 1, close).split(';'), end: start };
+
+  const body: string[] = [];
+  let depth = 1;
+  for (let i = start + 1; i < lines.length; i++) {
+    const depthAtStart = depth;
+    for (const ch of lines[i]) {
+      if (ch === '{') depth++;
+      else if (ch === '}') depth--;
+    }
+    if (depth <= 0) return { body, end: i };
+    if (depthAtStart === 1) body.push(lines[i]);
+  }
+  return { body, end: lines.length - 1 };
+}
+
+export interface GoTypeExtraction {
+  declarations: TypeDeclaration[];
+  /** Methods by receiver type name; receivers may be declared in another file of the package */
+  methods: Array<{ receiver: string; method: string }>;
+}
+
+export function extractGoTypes(content: string, file: string): GoTypeExtraction {
+  const lines = stripComments(content).split('\n');
+  const declarations: TypeDeclaration[] = [];
+  const methods: GoTypeExtraction['methods'] = [];
+  let inTypeGroup = false;
+
+  for (let i = 0; i < lines.length; i++) {
+    const line = lines[i];
+    if (/^type\s*\(\s*$/.test(line)) {
+      inTypeGroup = true;
+      continue;
+    }
+    if (inTypeGroup && /^\)\s*$/.test(line)) {
+      inTypeGroup = false;
+      continue;
+    }
+    const method = GO_METHOD.exec(line);
+    if (method) {
+      methods.push({ receiver: method[1], method: method[2] });
+      continue;
+    }
+
+    const match = (inTypeGroup ? GO_GROUP_ENTRY : GO_TYPE).exec(line);
+    // Aliases (`type A = B`) have B's methods, not their own
+    if (!match || match[2]) continue;
+    const [, name, , rest] = match;
+    const kind: TypeKind = /^interface\s*\{/.test(rest)
+      ? 'interface'
+      : /^struct\s*\{/.test(rest)
+        ? 'struct'
+        : 'type';
+    const declaration: TypeDeclaration = { name, kind, file, line: i + 1, language: 'go' };
+
+    if (kind !== 'type') {
+      const { body, end } = readBraceBody(lines, i);
+      i = end;
+      const members: string[] = [];
+      const embeds: string[] = [];
+      for (const raw of body) {
+        const member = raw.trim();
+        if (!member) continue;
+        const methodName = kind === 'interface' ? /^([A-Za-z_]\w*)\s*\(/.exec(member)?.[1] : null;
+        if (methodName) {
+          members.push(methodName);
+          continue;
+        }
+        // Embedded interface, or embedded struct field with an optional tag
+        const embedded = /^\*?([A-Za-z_][\w.]*)(?:\s+`[^`]*`)?$/.exec(member)?.[1];
+        if (embedded) embeds.push(baseName(embedded));
+      }
+      if (members.length > 0) declaration.methods = members;
+      if (embeds.length > 0) declaration.embeds = embeds;
+    }
+    declarations.push(declaration);
+  }
+  return { declarations, methods };
+}
+
+export class TypeHierarchyBuilder {
+  private declarations: TypeDeclaration[] = [];
+  private goMethods: Array<{ dir: string; receiver: string; method: string }> = [];
+
+  constructor(private rootPath: string) {}
+
+  trackFile(filePath: string, language: string, content: string): void {
+    const file = path.relative(this.rootPath, filePath).replace(/\\/g, '/');
+    if (language === 'typescript' || language === 'javascript') {
+      this.declarations.push(...extractTypeScriptTypes(content, file, language));
+    } else if (language === 'go') {
+      const extraction = extractGoTypes(content, file);
+      this.declarations.push(...extraction.declarations);
+      const dir = path.posix.dirname(file);
+      for (const method of extraction.methods) this.goMethods.push({ dir, ...method });
+    }
+  }
+
+  toJSON(): TypeHierarchyData {
+    // A Go package is a directory; its methods attach to the receiver type declared in it
+    const receivers = new Map<string, TypeDeclaration>();
+    for (const declaration of this.declarations) {
+      if (declaration.language !== 'go' || declaration.kind === 'interface') continue;
+      receivers.set(`${path.posix.dirname(declaration.file)}\0${declaration.name}`, declaration);
+    }
+    for (const { dir, receiver, method } of this.goMethods) {
+      const declaration = receivers.get(`${dir}\0${receiver}`);
+      if (!declaration) continue;
+      declaration.methods = [...new Set([...(declaration.methods ?? []), method])];
+    }
+    return { declarations: this.declarations };
+  }
+}
+
+function isTypeHierarchyData(value: unknown): value is TypeHierarchyData {
+  return (
+    !!value &&
+    typeof value === 'object' &&
+    Array.isArray((value as TypeHierarchyData).declarations)
+  );
+}
+
+/** The type hierarchy from the relationships sidecar; null for indexes built before it */
+export async function loadTypeHierarchy(rootPath: string): Promise<TypeHierarchyData | null> {
+  const relationshipsPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, RELATIONSHIPS_FILENAME);
+  try {
+    const parsed = JSON.parse(await fs.readFile(relationshipsPath, 'utf-8')) as {
+      types?: unknown;
+    };
+    return isTypeHierarchyData(parsed.types) ? parsed.types : null;
+  } catch {
+    return null;
+  }
+}
+
+const isGo = (declaration: TypeDeclaration) => declaration.language === 'go';
+
+/** Lookups shared by the queries below */
+class TypeIndex {
+  private byName = new Map<string, TypeDeclaration[]>();
+  private methodSets = new Map<TypeDeclaration, Set<string>>();
+
+  constructor(readonly declarations: TypeDeclaration[]) {
+    for (const declaration of declarations) {
+      const list = this.byName.get(declaration.name) ?? [];
+      list.push(declaration);
+      this.byName.set(declaration.name, list);
+    }
+  }
+
+  named(name: string): TypeDeclaration[] {
+    return this.byName.get(baseName(name)) ?? [];
+  }
+
+  /** Declarations `name` refers to from `from`: same Go package first, same language family */
+  resolve(name: string, from: TypeDeclaration): TypeDeclaration[] {
+    const candidates = this.named(name).filter((d) => isGo(d) === isGo(from));
+    if (!isGo(from)) return candidates;
+    const dir = path.posix.dirname(from.file);
+    const local = candidates.filter((d) => path.posix.dirname(d.file) === dir);
+    return local.length > 0 ? local : candidates;
+  }
+
+  /** Go method set including embedded types; `unresolved` collects embeds not in the repo */
+  methodSet(
+    declaration: TypeDeclaration,
+    unresolved?: Set<string>,
+    visiting = new Set<TypeDeclaration>()
+  ): Set<string> {
+    const cached = this.methodSets.get(declaration);
+    if (cached && !unresolved) return cached;
+    const methods = new Set(declaration.methods ?? []);
+    // Embedding cycles don't compile in Go, but a name-based lookup can still loop
+    if (visiting.has(declaration)) return methods;
+    visiting.add(declaration);
+    for (const embed of declaration.embeds ?? []) {
+      const target = this.resolve(embed, declaration).find((d) => d !== declaration);
+      if (!target) {
+        unresolved?.add(embed);
+        continue;
+      }
+      for (const method of this.methodSet(target, unresolved, visiting)) methods.add(method);
+    }
+    this.methodSets.set(declaration, methods);
+    return methods;
+  }
+
+  /** Go types (not interfaces) whose method set covers the interface's */
+  goImplementers(iface: TypeDeclaration): TypeDeclaration[] {
+    const required = this.methodSet(iface);
+    if (required.size === 0) return [];
+    return this.declarations.filter((d) => {
+      if (!isGo(d) || d.kind === 'interface') return false;
+      const methods = this.methodSet(d);
+      return [...required].every((method) => methods.has(method));
+    });
+  }
+
+  /** Declarations naming `target` in their extends/implements/embeds */
+  directSubtypes(target: TypeDeclaration): Array<{ declaration: TypeDeclaration; via: string }> {
+    const result: Array<{ declaration: TypeDeclaration; via: string }> = [];
+    for (const d of this.declarations) {
+      if (d === target || isGo(d) !== isGo(target)) continue;
+      const names = (list?: string[]) => list?.includes(target.name) ?? false;
+      if (names(d.implements)) result.push({ declaration: d, via: 'implements' });
+      else if (names(d.extends)) result.push({ declaration: d, via: 'extends' });
+      else if (names(d.embeds) && this.resolve(target.name, d).includes(target)) {
+        result.push({ declaration: d, via: 'embeds' });
+      }
+    }
+    return result;
+  }
+}
+
+export interface TypeReference {
+  name: string;
+  kind: TypeKind;
+  /** "path:line" */
+  file: string;
+  language: string;
+  /** How it relates to the type it is listed under */
+  via?: string;
+}
+
+const toReference = (declaration: TypeDeclaration, via?: string): TypeReference => ({
+  name: declaration.name,
+  kind: declaration.kind,
+  file: `${declaration.file}:${declaration.line}`,
+  language: declaration.language,
+  ...(via ? { via } : {})
+});
+
+export interface ImplementationsResult {
+  /** Interfaces (or classes) with the name asked for */
+  definedAt: TypeReference[];
+  total: number;
+  /** Classes and types implementing one of them, directly or through a subtype */
+  implementations: TypeReference[];
+  /** Interfaces that extend or embed one of them */
+  extendedBy: TypeReference[];
+  /** Embedded interfaces outside the repo; Go matches may be too broad without them */
+  unresolvedEmbeds?: string[];
+}
+
+/** Everything that implements `name`, following sub-interfaces and subclasses */
+export function findImplementations(
+  data: TypeHierarchyData,
+  name: string,
+  limit: number
+): ImplementationsResult {
+  const index = new TypeIndex(data.declarations);
+  const targets = index.named(name).filter((d) => d.kind === 'interface' || d.kind === 'class');
+  const implementations = new Map<TypeDeclaration, TypeReference>();
+  const extendedBy = new Map<TypeDeclaration, TypeReference>();
+  const unresolved = new Set<string>();
+
+  const queue = [...targets];
+  const visited = new Set<TypeDeclaration>(targets);
+  while (queue.length > 0) {
+    const current = queue.shift()!;
+    const found = index.directSubtypes(current).map(({ declaration, via }) => ({
+      declaration,
+      via: `${via} ${current.name}`
+    }));
+    if (isGo(current) && current.kind === 'interface') {
+      index.methodSet(current, unresolved);
+      for (const implementer of index.goImplementers(current)) {
+        found.push({ declaration: implementer, via: `method set of ${current.name}` });
+      }
+    }
+    for (const { declaration, via } of found) {
+      if (visited.has(declaration)) continue;
+      visited.add(declaration);
+      const bucket = declaration.kind === 'interface' ? extendedBy : implementations;
+      bucket.set(declaration, toReference(declaration, via));
+      // Go implementers are found structurally for every interface already
+      if (!isGo(declaration) || declaration.kind === 'interface') queue.push(declaration);
+    }
+  }
+
+  const sorted = (refs: Map<TypeDeclaration, TypeReference>) =>
+    [...refs.entries()]
+      .sort(([a], [b]) => a.file.localeCompare(b.file) || a.line - b.line)
+      .map(([, reference]) => reference);
+  const implemented = sorted(implementations);
+  return {
+    definedAt: targets.map((target) => toReference(target)),
+    total: implemented.length,
+    implementations: implemented.slice(0, limit),
+    extendedBy: sorted(extendedBy),
+    ...(unresolved.size > 0 ? { unresolvedEmbeds: [...unresolved].sort() } : {})
+  };
+}
+
+export type HierarchyDirection = 'up' | 'down' | 'both';
+
+export interface HierarchyNode extends Partial<TypeReference> {
+  name: string;
+  /** Named as a supertype but not declared in the repo */
+  external?: true;
+  children?: HierarchyNode[];
+  /** Children left out at MAX_HIERARCHY_CHILDREN */
+  moreChildren?: number;
+}
+
+export interface TypeHierarchyResult {
+  type: TypeReference;
+  supertypes?: HierarchyNode[];
+  subtypes?: HierarchyNode[];
+}
+
+const MAX_HIERARCHY_CHILDREN = 20;
+const MAX_HIERARCHY_MATCHES = 3;
+
+interface RelatedType {
+  name: string;
+  via: string;
+  /** Unset for names not declared in the repo */
+  declaration?: TypeDeclaration;
+}
+
+function supertypesOf(index: TypeIndex, declaration: TypeDeclaration): RelatedType[] {
+  const result: RelatedType[] = [];
+  const add = (names: string[] | undefined, via: string) => {
+    for (const name of names ?? []) {
+      const targets = index.resolve(name, declaration).filter((d) => d !== declaration);
+      if (targets.length === 0) result.push({ name, via });
+      for (const target of targets) result.push({ name, via, declaration: target });
+    }
+  };
+  add(declaration.extends, 'extends');
+  add(declaration.implements, 'implements');
+  add(declaration.embeds, 'embeds');
+  if (isGo(declaration) && declaration.kind !== 'interface') {
+    const methods = index.methodSet(declaration);
+    for (const iface of index.declarations) {
+      if (!isGo(iface) || iface.kind !== 'interface') continue;
+      const required = index.methodSet(iface);
+      if (required.size > 0 && [...required].every((method) => methods.has(method))) {
+        result.push({ name: iface.name, via: 'implements (method set)', declaration: iface });
+      }
+    }
+  }
+  return result;
+}
+
+function subtypesOf(index: TypeIndex, declaration: TypeDeclaration) {
+  const result = index.directSubtypes(declaration);
+  if (isGo(declaration) && declaration.kind === 'interface') {
+    for (const implementer of index.goImplementers(declaration)) {
+      result.push({ declaration: implementer, via: 'implements (method set)' });
+    }
+  }
+  return result;
+}
+
+function expand(
+  index: TypeIndex,
+  declaration: TypeDeclaration,
+  direction: 'up' | 'down',
+  depth: number,
+  ancestors: Set<TypeDeclaration>
+): HierarchyNode[] {
+  const related: RelatedType[] =
+    direction === 'up'
+      ? supertypesOf(index, declaration)
+      : subtypesOf(index, declaration).map(({ declaration: d, via }) => ({
+          name: d.name,
+          via,
+          declaration: d
+        }));
+  const nodes = related.slice(0, MAX_HIERARCHY_CHILDREN).map(({ name, via, declaration: d }) => {
+    if (!d) return { name, via, external: true } as HierarchyNode;
+    const node: HierarchyNode = toReference(d, via);
+    if (depth > 1 && !ancestors.has(d)) {
+      const children = expand(index, d, direction, depth - 1, new Set([...ancestors, d]));
+      if (children.length > 0) node.children = children;
+    }
+    return node;
+  });
+  if (related.length > MAX_HIERARCHY_CHILDREN) {
+    nodes.push({ name: '…', moreChildren: related.length - MAX_HIERARCHY_CHILDREN });
+  }
+  return nodes;
+}
+
+/** Supertypes and/or subtypes of each type named `name`, `depth` levels deep */
+export function getTypeHierarchy(
+  data: TypeHierarchyData,
+  name: string,
+  options: { direction: HierarchyDirection; depth: number }
+): TypeHierarchyResult[] {
+  const index = new TypeIndex(data.declarations);
+  return index
+    .named(name)
+    .slice(0, MAX_HIERARCHY_MATCHES)
+    .map((declaration) => {
+      const seen = new Set([declaration]);
+      return {
+        type: toReference(declaration),
+        ...(options.direction !== 'down'
+          ? { supertypes: expand(index, declaration, 'up', options.depth, seen) }
+          : {}),
+        ...(options.direction !== 'up'
+          ? { subtypes: expand(index, declaration, 'down', options.depth, seen) }
+          : {})
+      };
+    });
+}
@@ -0,0 +1,188 @@
+/**
+ * Dead-code candidates for `analyze_unused`: exported symbols nothing else in the index refers
+ * to. An export counts as used when another file imports it by name, a barrel (`index.*`)
+ * re-exports the same name, or another file calls it by name.
+ *
+ * Files that something imports without named bindings (`import * as ns`, default-only or
+ * side-effect imports) are left out: their exports can be used in ways the graph does not
+ * record. `export * from` re-exports are not tracked either, so results are candidates to
+ * check, not proof.
+ */
+
+import path from 'path';
+import type { CallGraphData } from './call-graph.js';
+import { normalizeCallSymbol } from './call-graph.js';
+import type { DependencyGraphData } from './dependency-graph.js';
+import type { SymbolDefinition } from './symbol-index.js';
+import { isTestSourceFile } from './test-mapping.js';
+
+export interface UnusedExportSymbol {
+  name: string;
+  kind?: string;
+  line?: number;
+  /** Called elsewhere in its own file: the `export` may go, the code is still live */
+  calledInFile: boolean;
+}
+
+export interface UnusedExportFile {
+  file: string;
+  /** False when no indexed file imports this one: an entry point or a dead file */
+  imported: boolean;
+  symbols: UnusedExportSymbol[];
+}
+
+export interface UnusedExportsOptions {
+  /** Repo-relative path prefix, e.g. `src/features` */
+  scope?: string;
+  /** Also report exports of test files (default: false) */
+  includeTests?: boolean;
+  /** Maximum symbols returned across all files */
+  limit: number;
+}
+
+export interface UnusedExportsReport {
+  /** Files with exports that were checked */
+  analyzedFiles: number;
+  totalSymbols: number;
+  totalFiles: number;
+  files: UnusedExportFile[];
+  truncated: boolean;
+  /** Files imported without named bindings, whose exports weren't judged */
+  opaqueFiles: string[];
+}
+
+const BARREL_BASENAME = /^index\.[cm]?[jt]sx?$/;
+
+function isBarrel(file: string): boolean {
+  return BARREL_BASENAME.test(path.posix.basename(file));
+}
+
+/** file -> names other files import from it, plus the files imported without named bindings */
+function collectNamedImports(graph: DependencyGraphData): {
+  named: Map<string, Set<string>>;
+  opaque: Set<string>;
+} {
+  const named = new Map<string, Set<string>>();
+  const opaque = new Set<string>();
+  for (const [from, targets] of Object.entries(graph.imports)) {
+    for (const to of targets) {
+      if (to === from) continue;
+      const symbols = graph.importDetails?.[from]?.[to]?.importedSymbols ?? [];
+      if (symbols.length === 0) {
+        opaque.add(to);
+        continue;
+      }
+      let names = named.get(to);
+      if (!names) named.set(to, (names = new Set()));
+      for (const symbol of symbols) names.add(symbol);
+    }
+  }
+  return { named, opaque };
+}
+
+/** callee name -> files with a call to it (outside the callee's own body) */
+function collectCallSites(callGraph: CallGraphData | null): Map<string, Set<string>> {
+  const sites = new Map<string, Set<string>>();
+  for (const call of callGraph?.calls ?? []) {
+    const callee = normalizeCallSymbol(call.callee);
+    if (!callee || call.caller === callee) continue;
+    let files = sites.get(callee);
+    if (!files) sites.set(callee, (files = new Set()));
+    files.add(call.file);
+  }
+  return sites;
+}
+
+/** Exports with no importer, re-export or outside call site, grouped per file in path order */
+export function analyzeUnusedExports(
+  graph: DependencyGraphData,
+  definitions: SymbolDefinition[] | null,
+  callGraph: CallGraphData | null,
+  options: UnusedExportsOptions
+): UnusedExportsReport {
+  const { named, opaque } = collectNamedImports(graph);
+  const callSites = collectCallSites(callGraph);
+  const exports = graph.exports ?? {};
+
+  const barrelExports = new Map<string, Set<string>>();
+  for (const [file, fileExports] of Object.entries(exports)) {
+    if (!isBarrel(file)) continue;
+    for (const exp of fileExports) {
+      let files = barrelExports.get(exp.name);
+      if (!files) barrelExports.set(exp.name, (files = new Set()));
+      files.add(file);
+    }
+  }
+
+  const definitionsByFile = new Map<string, SymbolDefinition[]>();
+  for (const definition of definitions ?? []) {
+    const list = definitionsByFile.get(definition.file);
+    if (list) list.push(definition);
+    else definitionsByFile.set(definition.file, [definition]);
+  }
+
+  const candidates: UnusedExportFile[] = [];
+  const opaqueFiles: string[] = [];
+  let analyzedFiles = 0;
+
+  for (const file of Object.keys(exports).sort()) {
+    if (options.scope && !file.startsWith(options.scope)) continue;
+    if (isBarrel(file)) continue;
+    if (!options.includeTests && isTestSourceFile(file)) continue;
+    if (opaque.has(file)) {
+      opaqueFiles.push(file);
+      continue;
+    }
+    analyzedFiles++;
+
+    const importedNames = named.get(file);
+    const symbols: UnusedExportSymbol[] = [];
+    const seen = new Set<string>();
+    for (const exp of exports[file]) {
+      if (exp.type === 'default' || exp.name === 'default' || seen.has(exp.name)) continue;
+      seen.add(exp.name);
+      if (importedNames?.has(exp.name)) continue;
+      if ([...(barrelExports.get(exp.name) ?? [])].some((barrel) => barrel !== file)) continue;
+
+      const callers = callSites.get(exp.name);
+      if (callers && [...callers].some((caller) => caller !== file)) continue;
+
+      const definition = definitionsByFile
+        .get(file)
+        ?.filter((def) => def.name === exp.name)
+        .sort((a, b) => a.startLine - b.startLine)[0];
+      symbols.push({
+        name: exp.name,
+        ...(definition ? { kind: definition.kind, line: definition.startLine } : {}),
+        calledInFile: callers?.has(file) ?? false
+      });
+    }
+
+    if (symbols.length > 0) {
+      candidates.push({
+        file,
+        imported: (graph.importedBy[file] ?? []).some((importer) => importer !== file),
+        symbols
+      });
+    }
+  }
+
+  const totalSymbols = candidates.reduce((sum, entry) => sum + entry.symbols.length, 0);
+  const files: UnusedExportFile[] = [];
+  let remaining = options.limit;
+  for (const entry of candidates) {
+    if (remaining <= 0) break;
+    const symbols = entry.symbols.slice(0, remaining);
+    remaining -= symbols.length;
+    files.push({ ...entry, symbols });
+  }
+
+  return {
+    analyzedFiles,
+    totalSymbols,
+    totalFiles: candidates.length,
+    files,
+    truncated: totalSymbols > options.limit,
+    opaqueFiles
+  };
+}
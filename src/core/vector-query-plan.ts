@@ -0,0 +1,95 @@
+/**
+ * Vector query planning: which filters a storage backend applies itself and which run here.
+ *
+ * Backends differ (see STORAGE_CAPABILITIES): LanceDB and SQLite keep tags as JSON text,
+ * none of them can filter on chunk metadata, and each caps how long a path list one query
+ * may carry. The plan hands the store only what it applies and over-fetches when the rest
+ * has to be filtered out here, so every backend returns the same results.
+ */
+
+import type { StorageCapabilities } from '../storage/types.js';
+import type { CodeChunk, SearchFilters } from '../types/index.js';
+import { type FileScope, pushdownFileScope } from './file-filters.js';
+
+/** Results requested per wanted result when some filters run after the store */
+export const OVER_FETCH_FACTOR = 4;
+
+export interface VectorQueryPlan {
+  /** What the store receives */
+  filters?: SearchFilters;
+  /** Results to request from the store */
+  limit: number;
+  /** Some filters (or the file scope) must still be applied to the store's results */
+  postFilter: boolean;
+}
+
+function hasMetadataFilters(filters?: SearchFilters): boolean {
+  return Boolean(
+    filters?.dotnetProject ||
+      filters?.package ||
+      filters?.role ||
+      (filters?.metadata && Object.keys(filters.metadata).length > 0)
+  );
+}
+
+/**
+ * Plan one vector query. `files` is an explicit file list (the trigram pre-filter's), which
+ * takes precedence over the filters' resolved `scope`.
+ */
+export function planVectorQuery(
+  capabilities: StorageCapabilities,
+  limit: number,
+  filters: SearchFilters | undefined,
+  scope: FileScope | null,
+  files?: Set<string> | null
+): VectorQueryPlan {
+  if (!filters && !scope && !files) return { limit, postFilter: false };
+
+  const pushed: SearchFilters = { ...filters };
+  let postFilter = false;
+  if (!capabilities.filters.fields) {
+    postFilter ||= Boolean(
+      pushed.framework || pushed.componentType || pushed.layer || pushed.language
+    );
+    pushed.framework = pushed.componentType = pushed.layer = pushed.language = undefined;
+  }
+  if (!capabilities.filters.tags) {
+    postFilter ||= Boolean(pushed.tags && pushed.tags.length > 0);
+    pushed.tags = undefined;
+  }
+  if (!capabilities.filters.metadata) {
+    postFilter ||= hasMetadataFilters(pushed);
+    pushed.dotnetProject = pushed.package = pushed.role = pushed.metadata = undefined;
+  }
+
+  let storeFilters: SearchFilters = pushed;
+  if (files) {
+    storeFilters = { ...pushed, excludePaths: undefined, filePaths: undefined };
+    if (files.size <= capabilities.maxFilterPaths) storeFilters.filePaths = [...files];
+    else postFilter = true;
+  } else if (scope) {
+    const pushdown = pushdownFileScope(pushed, scope, capabilities.maxFilterPaths);
+    storeFilters = pushdown.filters;
+    postFilter ||= !pushdown.pushed;
+  }
+
+  return {
+    filters: storeFilters,
+    limit: postFilter ? limit * OVER_FETCH_FACTOR : limit,
+    postFilter
+  };
+}
+
+/** The classification and tag filters, for results of a store that didn't apply them */
+export function matchesChunkFilters(chunk: CodeChunk, filters?: SearchFilters): boolean {
+  if (!filters) return true;
+  if (filters.componentType && chunk.componentType !== filters.componentType) return false;
+  if (filters.layer && chunk.layer !== filters.layer) return false;
+  if (filters.framework && chunk.framework !== filters.framework) return false;
+  if (filters.language && chunk.language !== filters.language) return false;
+  if (filters.tags && filters.tags.length > 0) {
+    const chunkTags = chunk.tags || [];
+    if (!filters.tags.some((tag) => chunkTags.includes(tag))) return false;
+  }
+  return true;
+}
@@ -0,0 +1,110 @@
+/**
+ * Workspace projects: several independent roots served by one server instance.
+ *
+ * Each root keeps its own `.codebase-context/` directory, so indexes, memories and
+ * intelligence never mix. Tools pick a project by name (or path); searches can fan out
+ * to every project with the reserved selector `all`.
+ */
+
+import path from 'path';
+import { normalizeRootPath } from '../utils/path-normalization.js';
+
+export const ALL_PROJECTS_SELECTOR = 'all';
+
+export interface WorkspaceProject {
+  name: string;
+  rootPath: string;
+}
+
+export interface WorkspaceRootEntry {
+  name?: string;
+  rootPath: string;
+}
+
+/**
+ * Parse a root list such as `CODEBASE_ROOTS`. Entries are separated by the platform path
+ * delimiter (`:` on POSIX, `;` on Windows) and may be named: `api=/work/api`.
+ */
+export function parseRootList(
+  value: string | undefined,
+  delimiter: string = path.delimiter
+): WorkspaceRootEntry[] {
+  if (!value) return [];
+
+  const entries: WorkspaceRootEntry[] = [];
+  for (const raw of value.split(delimiter)) {
+    const trimmed = raw.trim();
+    if (!trimmed) continue;
+
+    const eq = trimmed.indexOf('=');
+    if (eq > 0) {
+      const name = trimmed.slice(0, eq).trim();
+      const rootPath = trimmed.slice(eq + 1).trim();
+      if (rootPath) entries.push({ ...(name ? { name } : {}), rootPath });
+    } else {
+      entries.push({ rootPath: trimmed });
+    }
+  }
+  return entries;
+}
+
+/**
+ * Resolve root entries into projects with unique names. Duplicate roots are dropped;
+ * name clashes (including the reserved `all`) get a numeric suffix.
+ */
+export function buildWorkspaceProjects(entries: WorkspaceRootEntry[]): WorkspaceProject[] {
+  const projects: WorkspaceProject[] = [];
+  const seenRoots = new Set<string>();
+  const usedNames = new Set<string>([ALL_PROJECTS_SELECTOR]);
+
+  for (const entry of entries) {
+    const rootPath = normalizeRootPath(entry.rootPath);
+    if (seenRoots.has(rootPath)) continue;
+    seenRoots.add(rootPath);
+
+    const baseName = entry.name || path.basename(rootPath) || 'project';
+    let name = baseName;
+    for (let suffix = 2; usedNames.has(name); suffix++) {
+      name = `${baseName}-${suffix}`;
+    }
+    usedNames.add(name);
+    projects.push({ name, rootPath });
+  }
+
+  return projects;
+}
+
+/**
+ * Projects addressed by a tool's `project` argument. No selector means the primary
+ * (first) project. Returns null when the selector matches nothing.
+ */
+export function selectProjects<T extends WorkspaceProject>(
+  projects: T[],
+  selector: unknown
+): T[] | null {
+  if (selector === undefined || selector === null || selector === '') {
+    return projects.slice(0, 1);
+  }
+  if (typeof selector !== 'string') return null;
+
+  const trimmed = selector.trim();
+  if (trimmed === ALL_PROJECTS_SELECTOR) return projects;
+
+  const byName = projects.find((p) => p.name === trimmed);
+  if (byName) return [byName];
+
+  const resolved = normalizeRootPath(trimmed);
+  const byPath = projects.find((p) => p.rootPath === resolved);
+  return byPath ? [byPath] : null;
+}
+
+/** Interleave per-project results by score, tagging each with its project. */
+export function mergeProjectResults<T extends { score: number }>(
+  groups: Array<{ project: string; results: T[] }>,
+  limit: number
+): Array<T & { project: string }> {
+  return groups
+    .flatMap((group) => group.results.map((result) => ({ ...result, project: group.project })))
+    .sort((a, b) => b.score - a.score)
+    .slice(0, limit);
+}
@@ -0,0 +1,264 @@
+/**
+ * Daemon mode: the engine runs in one long-lived process and editors connect to it through a
+ * thin stdio shim, so an editor restart reconnects to a warm index instead of reloading it.
+ *
+ * The daemon listens on a local socket (a Unix domain socket, or a named pipe on Windows)
+ * derived from the project roots. Each connection gets its own MCP `Server`, speaking the same
+ * newline-delimited JSON-RPC as stdio. The shim relays bytes, starts a detached daemon when
+ * none is listening, and the daemon exits once no client has been connected for a while.
+ *
+ * The default socket lives in a directory only the user can enter ($XDG_RUNTIME_DIR, else a
+ * per-uid 0700 directory under the temp dir), so nobody else can connect while it is being
+ * set up. A lock file next to the socket, holding the daemon's pid, keeps a second daemon
+ * starting at the same time from unlinking the first one's socket.
+ */
+
+import { spawn } from 'child_process';
+import { createHash } from 'crypto';
+import { promises as fs } from 'fs';
+import net from 'net';
+import os from 'os';
+import path from 'path';
+import type { Readable, Writable } from 'stream';
+import type { Server } from '@modelcontextprotocol/sdk/server/index.js';
+import { StdioServerTransport } from '@modelcontextprotocol/sdk/server/stdio.js';
+
+export type DaemonRole = 'shim' | 'server';
+
+export const DEFAULT_DAEMON_IDLE_MINUTES = 30;
+
+const CONNECT_RETRY_MS = 100;
+const DEFAULT_START_TIMEOUT_MS = 30_000;
+
+/** `daemon` runs the stdio shim, `daemon-server` the daemon itself; null for other transports */
+export function resolveDaemonRole(env: NodeJS.ProcessEnv = process.env): DaemonRole | null {
+  const transport = env.CODEBASE_CONTEXT_TRANSPORT?.trim().toLowerCase();
+  if (transport === 'daemon') return 'shim';
+  if (transport === 'daemon-server') return 'server';
+  return null;
+}
+
+/** Minutes without a client before the daemon exits; 0 keeps it running */
+export function resolveDaemonIdleMinutes(env: NodeJS.ProcessEnv = process.env): number {
+  const value = Number.parseFloat(env.CODEBASE_CONTEXT_DAEMON_IDLE_MINUTES ?? '');
+  return Number.isFinite(value) && value >= 0 ? value : DEFAULT_DAEMON_IDLE_MINUTES;
+}
+
+/** Directory of the default sockets: private to the user */
+export function daemonSocketDir(env: NodeJS.ProcessEnv = process.env): string {
+  const runtime = env.XDG_RUNTIME_DIR?.trim();
+  if (runtime) return path.join(runtime, 'codebase-context');
+  return path.join(os.tmpdir(), `codebase-context-${process.getuid?.() ?? 'user'}`);
+}
+
+/** One socket per user and set of project roots, so daemons for other checkouts don't collide */
+export function resolveDaemonSocketPath(
+  rootPaths: string[],
+  env: NodeJS.ProcessEnv = process.env,
+  platform: NodeJS.Platform = process.platform
+): string {
+  const override = env.CODEBASE_CONTEXT_DAEMON_SOCKET?.trim();
+  if (override) return override;
+
+  const key = createHash('sha256')
+    .update(String(process.getuid?.() ?? ''))
+    .update(
+      rootPaths
+        .map((rootPath) => path.resolve(rootPath))
+        .sort()
+        .join('\n')
+    )
+    .digest('hex')
+    .slice(0, 16);
+  return platform === 'win32'
+    ? `\\\\.\\pipe\\codebase-context-${key}`
+    : path.join(daemonSocketDir(env), `${key}.sock`);
+}
+
+/** Create `dir` for the user alone, or check that an existing one is theirs and closed */
+async function ensurePrivateDir(dir: string): Promise<void> {
+  await fs.mkdir(dir, { recursive: true, mode: 0o700 });
+  const stat = await fs.lstat(dir);
+  const uid = process.getuid?.();
+  if (!stat.isDirectory() || (uid !== undefined && stat.uid !== uid)) {
+    throw new Error(`Refusing to put the daemon socket in ${dir}: not a directory of this user`);
+  }
+  if ((stat.mode & 0o077) !== 0) await fs.chmod(dir, 0o700);
+}
+
+function isRunning(pid: number): boolean {
+  try {
+    process.kill(pid, 0);
+    return true;
+  } catch (error) {
+    // EPERM: alive, but someone else's
+    return (error as NodeJS.ErrnoException).code === 'EPERM';
+  }
+}
+
+/**
+ * Take `<socket>.lock` for this process; a lock whose process is gone is taken over. Returns
+ * the release function, or throws when a live daemon holds it.
+ */
+async function acquireSocketLock(socketPath: string): Promise<() => Promise<void>> {
+  const lockPath = `${socketPath}.lock`;
+  for (let attempt = 0; attempt < 2; attempt++) {
+    try {
+      await fs.writeFile(lockPath, String(process.pid), { flag: 'wx', mode: 0o600 });
+      return () => fs.rm(lockPath, { force: true });
+    } catch (error) {
+      if ((error as NodeJS.ErrnoException).code !== 'EEXIST') throw error;
+    }
+    const holder = Number.parseInt(await fs.readFile(lockPath, 'utf-8').catch(() => ''), 10);
+    if (Number.isInteger(holder) && holder > 0 && isRunning(holder)) {
+      throw new Error(
+        `A codebase-context daemon (pid ${holder}) is already starting on ${socketPath}`
+      );
+    }
+    // Left behind by a daemon that crashed
+    await fs.rm(lockPath, { force: true });
+  }
+  throw new Error(`Could not lock ${socketPath}`);
+}
+
+function tryConnect(socketPath: string): Promise<net.Socket | null> {
+  return new Promise((resolve) => {
+    const socket = net.connect(socketPath);
+    socket.once('connect', () => {
+      socket.removeAllListeners('error');
+      resolve(socket);
+    });
+    socket.once('error', () => resolve(null));
+  });
+}
+
+export interface DaemonOptions {
+  socketPath: string;
+  createServer: () => Server;
+  /** Called once no client has been connected for this long; omit to never go idle */
+  idleMs?: number;
+  onIdle?: () => void;
+}
+
+export interface DaemonHandle {
+  socketPath: string;
+  /** Number of connected shims */
+  connectionCount: () => number;
+  close: () => Promise<void>;
+}
+
+export async function startDaemon(options: DaemonOptions): Promise<DaemonHandle> {
+  const existing = await tryConnect(options.socketPath);
+  if (existing) {
+    existing.destroy();
+    throw new Error(`A codebase-context daemon is already listening on ${options.socketPath}`);
+  }
+  let releaseLock = async () => {};
+  if (process.platform !== 'win32') {
+    if (path.dirname(options.socketPath) === daemonSocketDir()) {
+      await ensurePrivateDir(path.dirname(options.socketPath));
+    }
+    releaseLock = await acquireSocketLock(options.socketPath);
+    // Holding the lock, any socket file is one a crashed daemon left behind
+    await fs.rm(options.socketPath, { force: true });
+  }
+
+  const connections = new Set<net.Socket>();
+  let idleTimer: NodeJS.Timeout | undefined;
+  const armIdleTimer = () => {
+    if (!options.idleMs || !options.onIdle) return;
+    clearTimeout(idleTimer);
+    idleTimer = setTimeout(options.onIdle, options.idleMs);
+  };
+
+  const netServer = net.createServer((socket) => {
+    clearTimeout(idleTimer);
+    connections.add(socket);
+    const server = options.createServer();
+    socket.on('error', () => socket.destroy());
+    socket.once('close', () => {
+      connections.delete(socket);
+      void server.close();
+      if (connections.size === 0) armIdleTimer();
+    });
+    server.connect(new StdioServerTransport(socket, socket)).catch(() => socket.destroy());
+  });
+
+  try {
+    await new Promise<void>((resolve, reject) => {
+      netServer.once('error', reject);
+      netServer.listen(options.socketPath, () => {
+        netServer.off('error', reject);
+        resolve();
+      });
+    });
+    if (process.platform !== 'win32') await fs.chmod(options.socketPath, 0o600);
+  } catch (error) {
+    await releaseLock();
+    throw error;
+  }
+  armIdleTimer();
+
+  return {
+    socketPath: options.socketPath,
+    connectionCount: () => connections.size,
+    close: async () => {
+      clearTimeout(idleTimer);
+      connections.forEach((socket) => socket.destroy());
+      await new Promise<void>((resolve) => netServer.close(() => resolve()));
+      await releaseLock();
+    }
+  };
+}
+
+/** Start this server again, detached, as the daemon for the same roots and settings */
+export function spawnDetachedDaemon(argv: string[] = process.argv.slice(1)): void {
+  const child = spawn(process.execPath, [...process.execArgv, ...argv], {
+    detached: true,
+    stdio: 'ignore',
+    windowsHide: true,
+    env: { ...process.env, CODEBASE_CONTEXT_TRANSPORT: 'daemon-server' }
+  });
+  child.unref();
+}
+
+export interface DaemonShimOptions {
+  socketPath: string;
+  /** Start a daemon when none is listening */
+  startDaemon: () => void | Promise<void>;
+  stdin?: Readable;
+  stdout?: Writable;
+  startTimeoutMs?: number;
+}
+
+/**
+ * Relay stdin and stdout to the daemon, starting it first when nothing listens on the socket.
+ * Resolves when the connection closes.
+ */
+export async function runDaemonShim(options: DaemonShimOptions): Promise<void> {
+  let socket = await tryConnect(options.socketPath);
+  if (!socket) {
+    await options.startDaemon();
+    const deadline = Date.now() + (options.startTimeoutMs ?? DEFAULT_START_TIMEOUT_MS);
+    while (!socket && Date.now() < deadline) {
+      await new Promise((resolve) => setTimeout(resolve, CONNECT_RETRY_MS));
+      socket = await tryConnect(options.socketPath);
+    }
+    if (!socket) {
+      throw new Error(`The codebase-context daemon did not start on ${options.socketPath}`);
+    }
+  }
+
+  const connected = socket;
+  const stdin = options.stdin ?? process.stdin;
+  const stdout = options.stdout ?? process.stdout;
+  await new Promise<void>((resolve) => {
+    connected.once('close', () => {
+      stdin.unpipe(connected);
+      resolve();
+    });
+    connected.on('error', () => connected.destroy());
+    stdin.pipe(connected);
+    connected.pipe(stdout, { end: false });
+  });
+}
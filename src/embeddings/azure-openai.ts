@@ -0,0 +1,59 @@
+import { OpenAIEmbeddingProvider } from './openai.js';
+import { shortensOutput } from './hosted.js';
+
+export const DEFAULT_AZURE_OPENAI_API_VERSION = '2024-10-21';
+
+/**
+ * Azure OpenAI Embedding Provider
+ * Same API as OpenAI, routed to a deployment: `modelName` is the deployment name, the
+ * endpoint is the resource URL (`https://<resource>.openai.azure.com`) and the key goes in
+ * an `api-key` header. Dimensions come from the model table when the deployment is named
+ * after its model, else from the first response.
+ */
+export class AzureOpenAIEmbeddingProvider extends OpenAIEmbeddingProvider {
+  readonly name: string = 'azure-openai';
+  protected label = 'Azure OpenAI';
+
+  constructor(
+    deployment: string,
+    apiKey: string | undefined,
+    endpoint: string | undefined,
+    private apiVersion: string = DEFAULT_AZURE_OPENAI_API_VERSION,
+    requestedDimensions?: number
+  ) {
+    super(deployment, apiKey, endpoint ?? '', requestedDimensions);
+  }
+
+  protected checkSettings(): void {
+    if (!this.apiEndpoint) {
+      throw new Error(
+        'Azure OpenAI endpoint is missing. Set AZURE_OPENAI_ENDPOINT to https://<resource>.openai.azure.com.'
+      );
+    }
+    if (!this.apiKey) {
+      throw new Error('Azure OpenAI API key is missing. Set AZURE_OPENAI_API_KEY.');
+    }
+  }
+
+  protected requestUrl(): string {
+    const deployment = encodeURIComponent(this.modelName);
+    return (
+      `${this.apiEndpoint}/openai/deployments/${deployment}/embeddings` +
+      `?api-version=${encodeURIComponent(this.apiVersion)}`
+    );
+  }
+
+  protected authHeaders(): Record<string, string> {
+    return { 'api-key': this.apiKey ?? '' };
+  }
+
+  protected requestBody(input: string[]): Record<string, unknown> {
+    return {
+      input,
+      encoding_format: 'float',
+      ...(shortensOutput(this.modelName, this.requestedDimensions)
+        ? { dimensions: this.requestedDimensions }
+        : {})
+    };
+  }
+}
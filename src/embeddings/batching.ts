@@ -0,0 +1,137 @@
+/**
+ * Batched embedding with bounded concurrency and retry.
+ *
+ * Rate limits (429), server errors and dropped connections are retried with exponential
+ * backoff and full jitter, honouring `Retry-After` when the provider sends it. Other errors
+ * (bad key, malformed request) fail immediately. Each finished batch is reported right away
+ * so callers can checkpoint progress and resume after a failed run.
+ */
+
+import type { EmbeddingProvider } from './types.js';
+
+/** An embedding API call that failed; `status` is absent for network errors */
+export class EmbeddingRequestError extends Error {
+  constructor(
+    message: string,
+    readonly status?: number,
+    readonly retryAfterMs?: number
+  ) {
+    super(message);
+    this.name = 'EmbeddingRequestError';
+  }
+
+  get retryable(): boolean {
+    return this.status === undefined || this.status === 429 || this.status >= 500;
+  }
+}
+
+/** Parse `Retry-After` (seconds or HTTP date) or OpenAI's `retry-after-ms` */
+export function parseRetryAfter(headers: Headers, now = Date.now()): number | undefined {
+  const ms = Number(headers.get('retry-after-ms'));
+  if (Number.isFinite(ms) && ms > 0) return ms;
+  const value = headers.get('retry-after');
+  if (!value) return undefined;
+  const seconds = Number(value);
+  if (Number.isFinite(seconds)) return Math.max(0, seconds * 1000);
+  const date = Date.parse(value);
+  return Number.isNaN(date) ? undefined : Math.max(0, date - now);
+}
+
+export interface BatchEmbeddingOptions {
+  batchSize: number;
+  /** Batches in flight at once */
+  concurrency: number;
+  /** Retries per batch after the first attempt */
+  maxRetries: number;
+  baseDelayMs?: number;
+  maxDelayMs?: number;
+  /** Called before each batch is sent; throw to stop (e.g. cancellation) */
+  beforeBatch?: () => void;
+  /** Called once per successful batch, in completion order */
+  onBatch: (offset: number, embeddings: number[][]) => Promise<void> | void;
+  /** Injected for tests */
+  sleep?: (ms: number) => Promise<void>;
+  random?: () => number;
+}
+
+const DEFAULT_BASE_DELAY_MS = 500;
+const DEFAULT_MAX_DELAY_MS = 30_000;
+
+const defaultSleep = (ms: number) => new Promise<void>((resolve) => setTimeout(resolve, ms));
+
+export function backoffDelay(
+  attempt: number,
+  error: unknown,
+  options: Pick<BatchEmbeddingOptions, 'baseDelayMs' | 'maxDelayMs' | 'random'>
+): number {
+  const base = options.baseDelayMs ?? DEFAULT_BASE_DELAY_MS;
+  const cap = options.maxDelayMs ?? DEFAULT_MAX_DELAY_MS;
+  const jittered = (options.random ?? Math.random)() * Math.min(cap, base * 2 ** attempt);
+  const retryAfter = error instanceof EmbeddingRequestError ? error.retryAfterMs : undefined;
+  return retryAfter !== undefined ? Math.min(cap, Math.max(retryAfter, jittered)) : jittered;
+}
+
+async function embedWithRetry(
+  provider: Pick<EmbeddingProvider, 'embedBatch'>,
+  texts: string[],
+  options: BatchEmbeddingOptions
+): Promise<number[][]> {
+  const sleep = options.sleep ?? defaultSleep;
+  for (let attempt = 0; ; attempt++) {
+    try {
+      const embeddings = await provider.embedBatch(texts);
+      if (embeddings.length !== texts.length) {
+        throw new Error(`Expected ${texts.length} embeddings, got ${embeddings.length}`);
+      }
+      return embeddings;
+    } catch (error) {
+      if (!(error instanceof EmbeddingRequestError) || !error.retryable) throw error;
+      if (attempt >= options.maxRetries) throw error;
+      const delay = backoffDelay(attempt, error, options);
+      console.error(
+        `[embeddings] ${error.message}; retry ${attempt + 1}/${options.maxRetries} ` +
+          `in ${Math.round(delay)}ms`
+      );
+      await sleep(delay);
+    }
+  }
+}
+
+/**
+ * Embed `texts` in batches of `batchSize`, at most `concurrency` at a time.
+ * The first failure stops new batches from starting; in-flight batches still finish and
+ * are reported before the error is rethrown.
+ */
+export async function embedInBatches(
+  provider: Pick<EmbeddingProvider, 'embedBatch'>,
+  texts: string[],
+  options: BatchEmbeddingOptions
+): Promise<void> {
+  const batchSize = Math.max(1, Math.floor(options.batchSize));
+  const offsets: number[] = [];
+  for (let offset = 0; offset < texts.length; offset += batchSize) offsets.push(offset);
+
+  let next = 0;
+  const failures: unknown[] = [];
+
+  const worker = async () => {
+    while (failures.length === 0 && next < offsets.length) {
+      const offset = offsets[next++];
+      try {
+        options.beforeBatch?.();
+        const embeddings = await embedWithRetry(
+          provider,
+          texts.slice(offset, offset + batchSize),
+          options
+        );
+        await options.onBatch(offset, embeddings);
+      } catch (error) {
+        failures.push(error);
+      }
+    }
+  };
+
+  const workers = Math.max(1, Math.min(Math.floor(options.concurrency), offsets.length));
+  await Promise.all(Array.from({ length: workers }, worker));
+  if (failures.length > 0) throw failures[0];
+}
@@ -0,0 +1,100 @@
+import { EmbeddingProvider, DEFAULT_COHERE_MODEL } from './types.js';
+import {
+  checkEmbeddings,
+  postEmbeddingRequest,
+  resolveHostedDimensions,
+  shortensOutput
+} from './hosted.js';
+
+interface CohereEmbeddingResponse {
+  embeddings: { float?: number[][] };
+}
+
+export const DEFAULT_COHERE_ENDPOINT = 'https://api.cohere.com/v2';
+/** The embed endpoint takes at most 96 texts per call */
+export const COHERE_MAX_TEXTS_PER_REQUEST = 96;
+
+/**
+ * Cohere Embedding Provider (`embed-english-v3.0` by default)
+ * v3 models require an `input_type`: `embed()` sends `search_query`, `embedBatch()`
+ * `search_document`. v3 inputs stop at 512 tokens, so long chunks are cut at the end by
+ * the API (`truncate: 'END'`) rather than rejected; only `embed-v4.0` takes a shorter
+ * `output_dimension`. Batches larger than 96 texts are split into several calls.
+ */
+export class CohereEmbeddingProvider implements EmbeddingProvider {
+  readonly name = 'cohere';
+  private resolvedDimensions = 0;
+
+  constructor(
+    readonly modelName: string = DEFAULT_COHERE_MODEL,
+    private apiKey?: string,
+    private apiEndpoint: string = DEFAULT_COHERE_ENDPOINT,
+    private requestedDimensions?: number
+  ) {
+    this.apiEndpoint = apiEndpoint.replace(/\/+$/, '');
+  }
+
+  get dimensions(): number {
+    return this.resolvedDimensions;
+  }
+
+  async initialize(): Promise<void> {
+    if (!this.apiKey) {
+      throw new Error('Cohere API key is missing. Set COHERE_API_KEY.');
+    }
+    this.resolvedDimensions = resolveHostedDimensions(
+      this.name,
+      this.modelName,
+      this.requestedDimensions
+    );
+    if (this.resolvedDimensions === 0) {
+      const [probe] = await this.request(['dimension probe'], 'search_document');
+      this.resolvedDimensions = probe.length;
+    }
+  }
+
+  isReady(): boolean {
+    return !!this.apiKey;
+  }
+
+  async embed(text: string): Promise<number[]> {
+    const [vector] = await this.request([text], 'search_query');
+    return vector;
+  }
+
+  async embedBatch(texts: string[]): Promise<number[][]> {
+    const vectors: number[][] = [];
+    for (let offset = 0; offset < texts.length; offset += COHERE_MAX_TEXTS_PER_REQUEST) {
+      const slice = texts.slice(offset, offset + COHERE_MAX_TEXTS_PER_REQUEST);
+      vectors.push(...(await this.request(slice, 'search_document')));
+    }
+    return vectors;
+  }
+
+  private async request(
+    texts: string[],
+    inputType: 'search_query' | 'search_document'
+  ): Promise<number[][]> {
+    const data = await postEmbeddingRequest<CohereEmbeddingResponse>(
+      'Cohere',
+      `${this.apiEndpoint}/embed`,
+      { Authorization: `Bearer ${this.apiKey}` },
+      {
+        model: this.modelName,
+        texts,
+        input_type: inputType,
+        embedding_types: ['float'],
+        truncate: 'END',
+        ...(shortensOutput(this.modelName, this.requestedDimensions)
+          ? { output_dimension: this.requestedDimensions }
+          : {})
+      }
+    );
+    return checkEmbeddings(
+      'Cohere',
+      data.embeddings?.float,
+      texts.length,
+      this.resolvedDimensions
+    );
+  }
+}
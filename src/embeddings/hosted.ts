@@ -0,0 +1,136 @@
+/**
+ * Model rules for hosted embedding APIs (OpenAI, Azure OpenAI, Voyage, Cohere): native
+ * dimensions, which sizes a model can be shortened to, and how much text fits in one input.
+ * Unknown models (and Azure deployments named after nothing in particular) fall back to the
+ * dimensions of the first vector the API returns.
+ */
+
+import { EmbeddingRequestError, parseRetryAfter } from './batching.js';
+
+export interface HostedModelRule {
+  dimensions: number;
+  /** Output sizes the API accepts for this model; absent = fixed size */
+  shortenTo?: readonly number[] | 'any';
+  /** Input limit in tokens */
+  maxInputTokens: number;
+}
+
+const MATRYOSHKA_VOYAGE = [256, 512, 1024, 2048] as const;
+
+export const HOSTED_MODEL_RULES: Readonly<Record<string, HostedModelRule>> = {
+  'text-embedding-3-small': { dimensions: 1536, shortenTo: 'any', maxInputTokens: 8191 },
+  'text-embedding-3-large': { dimensions: 3072, shortenTo: 'any', maxInputTokens: 8191 },
+  'text-embedding-ada-002': { dimensions: 1536, maxInputTokens: 8191 },
+  'voyage-code-3': { dimensions: 1024, shortenTo: MATRYOSHKA_VOYAGE, maxInputTokens: 32000 },
+  'voyage-3-large': { dimensions: 1024, shortenTo: MATRYOSHKA_VOYAGE, maxInputTokens: 32000 },
+  'voyage-3.5': { dimensions: 1024, shortenTo: MATRYOSHKA_VOYAGE, maxInputTokens: 32000 },
+  'voyage-3.5-lite': { dimensions: 1024, shortenTo: MATRYOSHKA_VOYAGE, maxInputTokens: 32000 },
+  'voyage-code-2': { dimensions: 1536, maxInputTokens: 16000 },
+  'voyage-3': { dimensions: 1024, maxInputTokens: 32000 },
+  'voyage-3-lite': { dimensions: 512, maxInputTokens: 32000 },
+  'embed-english-v3.0': { dimensions: 1024, maxInputTokens: 512 },
+  'embed-multilingual-v3.0': { dimensions: 1024, maxInputTokens: 512 },
+  'embed-english-light-v3.0': { dimensions: 384, maxInputTokens: 512 },
+  'embed-multilingual-light-v3.0': { dimensions: 384, maxInputTokens: 512 },
+  'embed-v4.0': { dimensions: 1536, shortenTo: [256, 512, 1024, 1536], maxInputTokens: 128000 }
+};
+
+/**
+ * Code tokenizes denser than prose; clipping at 3 characters per token keeps an input under
+ * the limit without shipping a tokenizer.
+ */
+const CLIP_CHARS_PER_TOKEN = 3;
+
+/**
+ * Vector size for `model`: the requested size when the model can be shortened to it, else
+ * its native size. Returns 0 for unknown models without a requested size (learn it from the
+ * first response). Throws for a size the model can't produce.
+ */
+export function resolveHostedDimensions(
+  provider: string,
+  model: string,
+  requested: number | undefined
+): number {
+  const rule = HOSTED_MODEL_RULES[model];
+  if (!requested) return rule?.dimensions ?? 0;
+  if (!rule) return requested;
+  const { shortenTo } = rule;
+  const allowed =
+    requested === rule.dimensions ||
+    (shortenTo === 'any' ? requested < rule.dimensions : !!shortenTo?.includes(requested));
+  if (!allowed) {
+    const sizes =
+      shortenTo === 'any'
+        ? `at most ${rule.dimensions}`
+        : shortenTo
+          ? shortenTo.join(', ')
+          : `only ${rule.dimensions}`;
+    throw new Error(
+      `${provider} model '${model}' can't produce ${requested}-dimensional embeddings (${sizes}).`
+    );
+  }
+  return requested;
+}
+
+/** Whether `requested` has to be sent as an output size (not just the model's native size) */
+export function shortensOutput(model: string, requested: number | undefined): boolean {
+  return !!requested && requested !== HOSTED_MODEL_RULES[model]?.dimensions;
+}
+
+/** Cut `text` to the model's input limit, for APIs that reject rather than truncate */
+export function clipToInputLimit(text: string, model: string, fallbackTokens = 8191): string {
+  const maxTokens = HOSTED_MODEL_RULES[model]?.maxInputTokens ?? fallbackTokens;
+  const maxChars = maxTokens * CLIP_CHARS_PER_TOKEN;
+  return text.length > maxChars ? text.slice(0, maxChars) : text;
+}
+
+/** POST a JSON body to an embeddings endpoint, with errors the batch runner can retry on */
+export async function postEmbeddingRequest<T>(
+  label: string,
+  url: string,
+  headers: Record<string, string>,
+  body: unknown
+): Promise<T> {
+  let response: Response;
+  try {
+    response = await fetch(url, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json', ...headers },
+      body: JSON.stringify(body)
+    });
+  } catch (error) {
+    throw new EmbeddingRequestError(
+      `${label} API unreachable: ${error instanceof Error ? error.message : String(error)}`
+    );
+  }
+
+  if (!response.ok) {
+    // Status and Retry-After let the batch runner decide whether and when to retry
+    const error = await response.text();
+    throw new EmbeddingRequestError(
+      `${label} API Error ${response.status}: ${error}`,
+      response.status,
+      parseRetryAfter(response.headers)
+    );
+  }
+  return (await response.json()) as T;
+}
+
+/** Check a response's vector count, and its vector size once that is known (non-zero) */
+export function checkEmbeddings(
+  label: string,
+  embeddings: number[][] | undefined,
+  expectedCount: number,
+  dimensions: number
+): number[][] {
+  if (!Array.isArray(embeddings) || embeddings.length !== expectedCount) {
+    throw new Error(
+      `${label} returned ${embeddings?.length ?? 0} embeddings for ${expectedCount} inputs`
+    );
+  }
+  const size = embeddings[0]?.length ?? 0;
+  if (dimensions > 0 && size !== dimensions) {
+    throw new Error(`${label} returned ${size}-dimensional embeddings, expected ${dimensions}`);
+  }
+  return embeddings;
+}
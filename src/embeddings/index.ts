@@ -1,47 +1,231 @@
 export * from './types.js';
 export * from './transformers.js';
+export * from './batching.js';
 
-import { EmbeddingProvider, EmbeddingConfig, DEFAULT_EMBEDDING_CONFIG } from './types.js';
+import {
+  EmbeddingProvider,
+  EmbeddingConfig,
+  DEFAULT_EMBEDDING_CONFIG,
+  DEFAULT_MODEL,
+  DEFAULT_COHERE_MODEL,
+  DEFAULT_OLLAMA_MODEL,
+  DEFAULT_OPENAI_MODEL,
+  DEFAULT_VOYAGE_MODEL,
+  TRANSFORMERS_DEFAULT_MODEL
+} from './types.js';
 import { TransformersEmbeddingProvider } from './transformers.js';
+import { TruncatedEmbeddingProvider } from './matryoshka.js';
+import { metrics, startTimer, withSpan } from '../core/telemetry.js';
 
-let cachedProvider: EmbeddingProvider | null = null;
-let cachedProviderType: string | null = null;
+/** One provider per provider/model/dimensions, so collections on other models stay loaded */
+const cachedProviders = new Map<string, EmbeddingProvider>();
+
+/** API keys of hosted providers, when the config doesn't carry one */
+const API_KEY_ENV: Partial<Record<EmbeddingConfig['provider'], string>> = {
+  openai: 'OPENAI_API_KEY',
+  'azure-openai': 'AZURE_OPENAI_API_KEY',
+  voyage: 'VOYAGE_API_KEY',
+  cohere: 'COHERE_API_KEY'
+};
+
+/**
+ * Provider and model a config resolves to, without loading anything. Requested dimensions
+ * (and truncation) are included so a change of output size is seen as a different model.
+ */
+export function resolveEmbeddingModel(config: Partial<EmbeddingConfig> = {}): {
+  provider: string;
+  model: string;
+  dimensions?: number;
+  truncateDimensions?: number;
+} {
+  const resolved = resolveUntruncatedModel(config);
+  const { truncateDimensions } = { ...DEFAULT_EMBEDDING_CONFIG, ...config };
+  return truncateDimensions
+    ? { ...resolved, dimensions: truncateDimensions, truncateDimensions }
+    : resolved;
+}
+
+/** What index meta records about the vectors `provider` produces */
+export function providerFingerprint(provider: EmbeddingProvider): {
+  provider: string;
+  model: string;
+  dimensions: number;
+  truncateDimensions?: number;
+} {
+  return {
+    provider: provider.name,
+    model: provider.modelName,
+    dimensions: provider.dimensions,
+    ...(provider.truncateDimensions ? { truncateDimensions: provider.truncateDimensions } : {})
+  };
+}
+
+function resolveUntruncatedModel(config: Partial<EmbeddingConfig>): {
+  provider: string;
+  model: string;
+  dimensions?: number;
+} {
+  const { provider, model, dimensions } = { ...DEFAULT_EMBEDDING_CONFIG, ...config };
+  // The Transformers.js default model name means nothing to hosted providers or Ollama
+  const explicit = model && model !== TRANSFORMERS_DEFAULT_MODEL ? model : undefined;
+  const sized = (name: string) => ({
+    provider,
+    model: name,
+    ...(dimensions ? { dimensions } : {})
+  });
+  if (provider === 'openai') return sized(explicit ?? DEFAULT_OPENAI_MODEL);
+  if (provider === 'azure-openai') {
+    // Azure routes by deployment name, commonly the name of the model deployed
+    return sized(explicit ?? process.env.AZURE_OPENAI_DEPLOYMENT ?? DEFAULT_OPENAI_MODEL);
+  }
+  if (provider === 'voyage') return sized(explicit ?? DEFAULT_VOYAGE_MODEL);
+  if (provider === 'cohere') return sized(explicit ?? DEFAULT_COHERE_MODEL);
+  if (provider === 'ollama') return { provider, model: explicit ?? DEFAULT_OLLAMA_MODEL };
+  return { provider, model: model || DEFAULT_MODEL };
+}
+
+async function createHostedProvider(
+  mergedConfig: EmbeddingConfig,
+  config: Partial<EmbeddingConfig>,
+  model: string
+): Promise<EmbeddingProvider | null> {
+  const keyEnv = API_KEY_ENV[mergedConfig.provider];
+  // OPENAI_API_KEY is part of the default config; other providers read their own variable
+  const apiKey =
+    mergedConfig.provider === 'openai'
+      ? mergedConfig.apiKey
+      : (config.apiKey ?? (keyEnv ? process.env[keyEnv] : undefined));
+  const { dimensions } = mergedConfig;
+
+  switch (mergedConfig.provider) {
+    case 'openai': {
+      const { OpenAIEmbeddingProvider } = await import('./openai.js');
+      return new OpenAIEmbeddingProvider(model, apiKey, mergedConfig.apiEndpoint, dimensions);
+    }
+    case 'azure-openai': {
+      const { AzureOpenAIEmbeddingProvider } = await import('./azure-openai.js');
+      return new AzureOpenAIEmbeddingProvider(
+        model,
+        apiKey,
+        mergedConfig.apiEndpoint || process.env.AZURE_OPENAI_ENDPOINT,
+        mergedConfig.apiVersion || process.env.AZURE_OPENAI_API_VERSION,
+        dimensions
+      );
+    }
+    case 'voyage': {
+      const { VoyageEmbeddingProvider } = await import('./voyage.js');
+      return new VoyageEmbeddingProvider(model, apiKey, mergedConfig.apiEndpoint, dimensions);
+    }
+    case 'cohere': {
+      const { CohereEmbeddingProvider } = await import('./cohere.js');
+      return new CohereEmbeddingProvider(model, apiKey, mergedConfig.apiEndpoint, dimensions);
+    }
+    default:
+      return null;
+  }
+}
+
+/** Counts and times every embedding call, and traces it when tracing is on */
+class InstrumentedEmbeddingProvider implements EmbeddingProvider {
+  constructor(private inner: EmbeddingProvider) {}
+
+  get name(): string {
+    return this.inner.name;
+  }
+
+  get modelName(): string {
+    return this.inner.modelName;
+  }
+
+  get dimensions(): number {
+    return this.inner.dimensions;
+  }
+
+  get truncateDimensions(): number | undefined {
+    return this.inner.truncateDimensions;
+  }
+
+  initialize(): Promise<void> {
+    return this.inner.initialize();
+  }
+
+  isReady(): boolean {
+    return this.inner.isReady();
+  }
+
+  embed(text: string): Promise<number[]> {
+    return this.measure('query', 1, () => this.inner.embed(text));
+  }
+
+  embedBatch(texts: string[]): Promise<number[][]> {
+    return this.measure('batch', texts.length, () => this.inner.embedBatch(texts));
+  }
+
+  private measure<T>(operation: string, texts: number, call: () => Promise<T>): Promise<T> {
+    const labels = { provider: this.inner.name, operation };
+    const attributes = { ...labels, model: this.inner.modelName, texts };
+    return withSpan('codebase_context.embed', attributes, async () => {
+      const elapsed = startTimer();
+      try {
+        return await call();
+      } finally {
+        metrics.embedDuration.observe(labels, elapsed());
+        metrics.embeddedTexts.inc({ provider: this.inner.name }, texts);
+      }
+    });
+  }
+}
+
+async function remember(
+  provider: EmbeddingProvider,
+  providerKey: string,
+  truncateDimensions: number | undefined
+): Promise<EmbeddingProvider> {
+  const sized = truncateDimensions
+    ? new TruncatedEmbeddingProvider(provider, truncateDimensions)
+    : provider;
+  await sized.initialize();
+  const instrumented = new InstrumentedEmbeddingProvider(sized);
+  cachedProviders.set(providerKey, instrumented);
+  return instrumented;
+}
 
 export async function getEmbeddingProvider(
   config: Partial<EmbeddingConfig> = {}
 ): Promise<EmbeddingProvider> {
   const mergedConfig = { ...DEFAULT_EMBEDDING_CONFIG, ...config };
-  const providerKey = `${mergedConfig.provider}:${mergedConfig.model}`;
+  const { model } = resolveEmbeddingModel(mergedConfig);
+  const providerKey =
+    `${mergedConfig.provider}:${mergedConfig.model}` +
+    (mergedConfig.dimensions ? `:${mergedConfig.dimensions}` : '') +
+    (mergedConfig.truncateDimensions ? `~${mergedConfig.truncateDimensions}` : '');
+  const { truncateDimensions } = mergedConfig;
 
-  if (cachedProvider && cachedProviderType === providerKey) {
-    return cachedProvider;
-  }
+  const cached = cachedProviders.get(providerKey);
+  if (cached) return cached;
 
-  if (mergedConfig.provider === 'openai') {
-    const { OpenAIEmbeddingProvider } = await import('./openai.js');
-    const provider = new OpenAIEmbeddingProvider(
-      mergedConfig.model || 'text-embedding-3-small',
-      mergedConfig.apiKey,
-      mergedConfig.apiEndpoint
-    );
-    await provider.initialize();
-    cachedProvider = provider;
-    cachedProviderType = providerKey;
-    return provider;
-  }
+  const hosted = await createHostedProvider(mergedConfig, config, model);
+  if (hosted) return remember(hosted, providerKey, truncateDimensions);
 
   if (mergedConfig.provider === 'custom') {
-    throw new Error("Custom provider not implemented. Use 'openai' or 'transformers'.");
+    throw new Error(
+      "Custom provider not implemented. Use 'openai', 'azure-openai', 'voyage', 'cohere', " +
+        "'ollama' or 'transformers'."
+    );
   }
 
   if (mergedConfig.provider === 'ollama') {
-    console.warn('Ollama provider not yet implemented, falling back to Transformers.js');
+    const { OllamaEmbeddingProvider, DEFAULT_OLLAMA_ENDPOINT } = await import('./ollama.js');
+    const provider = new OllamaEmbeddingProvider(
+      model,
+      mergedConfig.apiEndpoint || process.env.OLLAMA_HOST || DEFAULT_OLLAMA_ENDPOINT
+    );
+    return remember(provider, providerKey, truncateDimensions);
   }
 
-  const provider = new TransformersEmbeddingProvider(mergedConfig.model);
-  await provider.initialize();
-  cachedProvider = provider;
-  cachedProviderType = providerKey;
-
-  return provider;
+  const provider = new TransformersEmbeddingProvider(model, {
+    modelPath: mergedConfig.modelPath,
+    allowDownload: mergedConfig.allowDownload
+  });
+  return remember(provider, providerKey, truncateDimensions);
 }
@@ -0,0 +1,110 @@
+/**
+ * Local model files for the in-process ONNX runtime (Transformers.js), for machines without
+ * network access. `modelPath` is a directory laid out like the Hugging Face cache,
+ * `<org>/<model>/{config.json,tokenizer.json,onnx/model_quantized.onnx}`, which is exactly
+ * what `fetchLocalModel` (the `fetch-model` CLI command) writes; with `allowDownload: false`
+ * nothing is fetched from the Hugging Face Hub. The settings apply to the embedding model and
+ * the local reranker alike.
+ */
+
+import path from 'path';
+import { promises as fs } from 'fs';
+
+export interface LocalModelOptions {
+  /** Root directory of local models (`<org>/<model>/...`) */
+  modelPath?: string;
+  /** Fetch missing models from the Hugging Face Hub (default: true) */
+  allowDownload?: boolean;
+}
+
+export type LocalModelKind = 'embedding' | 'reranker';
+
+/** EMBEDDING_MODEL_PATH and EMBEDDING_ALLOW_DOWNLOAD */
+export function localModelOptionsFromEnv(env: NodeJS.ProcessEnv = process.env): LocalModelOptions {
+  const modelPath = env.EMBEDDING_MODEL_PATH?.trim();
+  const allowDownload = env.EMBEDDING_ALLOW_DOWNLOAD?.trim().toLowerCase();
+  return {
+    ...(modelPath ? { modelPath } : {}),
+    ...(allowDownload === 'false' || allowDownload === '0' ? { allowDownload: false } : {})
+  };
+}
+
+/** Point the ONNX runtime at `modelPath` and turn Hub downloads on or off */
+export async function applyLocalModelOptions(options: LocalModelOptions): Promise<void> {
+  const { env } = await import('@huggingface/transformers');
+  env.allowRemoteModels = options.allowDownload !== false;
+  if (options.modelPath) {
+    env.allowLocalModels = true;
+    env.localModelPath = `${path.resolve(options.modelPath)}${path.sep}`;
+  }
+}
+
+/** A model that failed to load offline, with what to do about it */
+export function explainOfflineLoadFailure(
+  modelName: string,
+  options: LocalModelOptions,
+  error: unknown
+): Error {
+  const where = options.modelPath
+    ? path.join(path.resolve(options.modelPath), modelName)
+    : 'the Transformers.js cache (set EMBEDDING_MODEL_PATH to a model directory)';
+  return new Error(
+    `Model '${modelName}' is not available locally and downloads are off ` +
+      `(EMBEDDING_ALLOW_DOWNLOAD=false). Looked in ${where}. On a machine with network ` +
+      `access run \`codebase-context fetch-model --model ${modelName} --to <dir>\` and copy ` +
+      `<dir> to EMBEDDING_MODEL_PATH. (${error instanceof Error ? error.message : String(error)})`
+  );
+}
+
+async function listFiles(dir: string): Promise<string[]> {
+  const entries = await fs.readdir(dir, { withFileTypes: true }).catch(() => []);
+  const files: string[] = [];
+  for (const entry of entries) {
+    const full = path.join(dir, entry.name);
+    if (entry.isDirectory()) files.push(...(await listFiles(full)));
+    else files.push(full);
+  }
+  return files;
+}
+
+/**
+ * Download the quantized ONNX files of `modelName` into `targetDir/<org>/<model>/`, ready to
+ * be copied to an offline machine and used as its `modelPath`.
+ */
+export async function fetchLocalModel(
+  modelName: string,
+  targetDir: string,
+  kind: LocalModelKind = 'embedding'
+): Promise<{ model: string; directory: string; files: string[]; bytes: number }> {
+  const transformers = await import('@huggingface/transformers');
+  const root = path.resolve(targetDir);
+  await fs.mkdir(root, { recursive: true });
+  transformers.env.allowRemoteModels = true;
+  transformers.env.cacheDir = root;
+
+  if (kind === 'reranker') {
+    await transformers.AutoTokenizer.from_pretrained(modelName);
+    await transformers.AutoModelForSequenceClassification.from_pretrained(modelName, {
+      dtype: 'q8'
+    });
+  } else {
+    // Same cast as the embedding provider: pipeline()'s task union is too complex for TSC
+    const pipeline = transformers.pipeline as (
+      task: 'feature-extraction',
+      model: string,
+      opts: Record<string, unknown>
+    ) => Promise<unknown>;
+    await pipeline('feature-extraction', modelName, { dtype: 'q8' });
+  }
+
+  const directory = path.join(root, modelName);
+  const files = await listFiles(directory);
+  let bytes = 0;
+  for (const file of files) bytes += (await fs.stat(file)).size;
+  return {
+    model: modelName,
+    directory,
+    files: files.map((file) => path.relative(root, file).replace(/\\/g, '/')).sort(),
+    bytes
+  };
+}
@@ -0,0 +1,94 @@
+/**
+ * Matryoshka truncation: keep the first N components of each vector and re-normalise.
+ * Models trained with Matryoshka representation learning put most of the signal up front,
+ * so 256 of 768 or 1024 dimensions cost a few points of recall for a 3-4x smaller index and
+ * faster scans. Other models lose far more; truncating one is allowed but warned about.
+ *
+ * Unlike EMBEDDING_DIMENSIONS (sent to the API, for the hosted models that take it), this
+ * works for any provider, local ones included, because it runs on the returned vectors.
+ */
+
+import type { EmbeddingProvider } from './types.js';
+import { HOSTED_MODEL_RULES } from './hosted.js';
+
+/** Local and Ollama models known to be Matryoshka-trained (hosted ones: see hosted.ts) */
+const MATRYOSHKA_MODELS = new Set([
+  'nomic-embed-text',
+  'nomic-ai/nomic-embed-text-v1.5',
+  'mxbai-embed-large',
+  'mixedbread-ai/mxbai-embed-large-v1',
+  'snowflake-arctic-embed2',
+  'snowflake/snowflake-arctic-embed-m-v1.5',
+  'embeddinggemma',
+  'onnx-community/embeddinggemma-300m-onnx'
+]);
+
+const warned = new Set<string>();
+
+export function isMatryoshkaModel(model: string): boolean {
+  if (HOSTED_MODEL_RULES[model]?.shortenTo) return true;
+  // Ollama tags (`nomic-embed-text:latest`, `:v1.5`) name versions of the same model
+  return MATRYOSHKA_MODELS.has(model.toLowerCase().replace(/:[^/]*$/, ''));
+}
+
+/** The first `size` components of `vector`, scaled back to unit length */
+export function truncateEmbedding(vector: number[], size: number): number[] {
+  if (vector.length < size) {
+    throw new Error(`Can't truncate a ${vector.length}-dimensional embedding to ${size}`);
+  }
+  const head = vector.slice(0, size);
+  const norm = Math.sqrt(head.reduce((sum, value) => sum + value * value, 0));
+  return norm > 0 ? head.map((value) => value / norm) : head;
+}
+
+/** Cuts every vector `inner` returns (documents and queries alike) to `size` dimensions */
+export class TruncatedEmbeddingProvider implements EmbeddingProvider {
+  constructor(
+    private inner: EmbeddingProvider,
+    readonly truncateDimensions: number
+  ) {}
+
+  get name(): string {
+    return this.inner.name;
+  }
+
+  get modelName(): string {
+    return this.inner.modelName;
+  }
+
+  get dimensions(): number {
+    return this.truncateDimensions;
+  }
+
+  async initialize(): Promise<void> {
+    await this.inner.initialize();
+    const native = this.inner.dimensions;
+    if (native > 0 && this.truncateDimensions > native) {
+      throw new Error(
+        `Can't truncate ${this.inner.name} model '${this.inner.modelName}' embeddings to ` +
+          `${this.truncateDimensions} dimensions: it produces ${native}.`
+      );
+    }
+    const key = `${this.inner.name}:${this.inner.modelName}`;
+    if (!isMatryoshkaModel(this.inner.modelName) && !warned.has(key)) {
+      warned.add(key);
+      console.warn(
+        `[embeddings] '${this.inner.modelName}' is not known to be Matryoshka-trained; ` +
+          `truncating it to ${this.truncateDimensions} dimensions may cost much more recall.`
+      );
+    }
+  }
+
+  isReady(): boolean {
+    return this.inner.isReady();
+  }
+
+  async embed(text: string): Promise<number[]> {
+    return truncateEmbedding(await this.inner.embed(text), this.truncateDimensions);
+  }
+
+  async embedBatch(texts: string[]): Promise<number[][]> {
+    const vectors = await this.inner.embedBatch(texts);
+    return vectors.map((vector) => truncateEmbedding(vector, this.truncateDimensions));
+  }
+}
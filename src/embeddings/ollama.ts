@@ -0,0 +1,97 @@
+import { EmbeddingProvider, DEFAULT_OLLAMA_MODEL } from './types.js';
+
+interface OllamaEmbedResponse {
+  embeddings: number[][];
+}
+
+export { DEFAULT_OLLAMA_MODEL };
+export const DEFAULT_OLLAMA_ENDPOINT = 'http://localhost:11434';
+
+/**
+ * Ollama Embedding Provider
+ * Talks to a local Ollama server over native fetch, so embeddings never leave the machine.
+ * Dimensions are discovered from the model on initialize().
+ */
+export class OllamaEmbeddingProvider implements EmbeddingProvider {
+  readonly name = 'ollama';
+  private ready = false;
+  private detectedDimensions = 0;
+
+  constructor(
+    readonly modelName: string = DEFAULT_OLLAMA_MODEL,
+    private apiEndpoint: string = DEFAULT_OLLAMA_ENDPOINT
+  ) {
+    this.apiEndpoint = apiEndpoint.replace(/\/+$/, '');
+  }
+
+  get dimensions(): number {
+    return this.detectedDimensions;
+  }
+
+  async initialize(): Promise<void> {
+    if (this.ready) return;
+
+    // Probe once: verifies the server is reachable, the model is pulled, and learns dimensions
+    const [probe] = await this.request(['dimension probe']);
+    if (!probe || probe.length === 0) {
+      throw new Error(`Ollama model '${this.modelName}' returned an empty embedding.`);
+    }
+    this.detectedDimensions = probe.length;
+    this.ready = true;
+  }
+
+  isReady(): boolean {
+    return this.ready;
+  }
+
+  async embed(text: string): Promise<number[]> {
+    const batch = await this.embedBatch([text]);
+    return batch[0];
+  }
+
+  async embedBatch(texts: string[]): Promise<number[][]> {
+    if (!texts.length) return [];
+    if (!this.ready) await this.initialize();
+
+    try {
+      return await this.request(texts);
+    } catch (error) {
+      console.error('Ollama Embedding Failed:', error);
+      throw error;
+    }
+  }
+
+  private async request(texts: string[]): Promise<number[][]> {
+    let response: Response;
+    try {
+      response = await fetch(`${this.apiEndpoint}/api/embed`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ model: this.modelName, input: texts })
+      });
+    } catch (error) {
+      throw new Error(
+        `Ollama server not reachable at ${this.apiEndpoint}. Start it with 'ollama serve' or set OLLAMA_HOST. ` +
+          `(${error instanceof Error ? error.message : String(error)})`
+      );
+    }
+
+    if (!response.ok) {
+      const error = await response.text();
+      if (response.status === 404) {
+        throw new Error(
+          `Ollama model '${this.modelName}' not found. Run 'ollama pull ${this.modelName}'. (${error})`
+        );
+      }
+      throw new Error(`Ollama API Error ${response.status}: ${error}`);
+    }
+
+    const data = (await response.json()) as OllamaEmbedResponse;
+    if (!Array.isArray(data.embeddings) || data.embeddings.length !== texts.length) {
+      throw new Error(
+        `Ollama returned ${data.embeddings?.length ?? 0} embeddings for ${texts.length} inputs`
+      );
+    }
+    return data.embeddings;
+  }
+}
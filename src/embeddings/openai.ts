@@ -1,29 +1,53 @@
-import { EmbeddingProvider } from './types.js';
+import { EmbeddingProvider, DEFAULT_OPENAI_MODEL } from './types.js';
+import {
+  checkEmbeddings,
+  clipToInputLimit,
+  postEmbeddingRequest,
+  resolveHostedDimensions,
+  shortensOutput
+} from './hosted.js';
 
 interface OpenAIEmbeddingResponse {
-  data: Array<{ embedding: number[] }>;
+  data: Array<{ embedding: number[]; index?: number }>;
 }
 
 /**
  * OpenAI Embedding Provider
  * Uses native fetch to avoid adding the heavy openai npm package dependency.
  * Minimal implementation focusing on high ROI and low bloat.
+ *
+ * The API rejects inputs over the model's token limit instead of truncating, so inputs are
+ * clipped first. `text-embedding-3-*` models can return shorter vectors (`dimensions`).
  */
 export class OpenAIEmbeddingProvider implements EmbeddingProvider {
-  readonly name = 'openai';
-  readonly dimensions = 1536; // Default for text-embedding-3-small
+  readonly name: string = 'openai';
+  protected label = 'OpenAI';
+  private resolvedDimensions = 0;
 
   constructor(
-    readonly modelName: string = 'text-embedding-3-small',
-    private apiKey?: string,
-    private apiEndpoint: string = 'https://api.openai.com/v1'
-  ) {}
+    readonly modelName: string = DEFAULT_OPENAI_MODEL,
+    protected apiKey?: string,
+    protected apiEndpoint: string = 'https://api.openai.com/v1',
+    protected requestedDimensions?: number
+  ) {
+    this.apiEndpoint = apiEndpoint.replace(/\/+$/, '');
+  }
+
+  get dimensions(): number {
+    return this.resolvedDimensions;
+  }
 
   async initialize(): Promise<void> {
-    if (!this.apiKey) {
-      throw new Error(
-        'OpenAI API key is missing. Set OPENAI_API_KEY environment variable or configure it in the MCP settings.'
-      );
+    this.checkSettings();
+    this.resolvedDimensions = resolveHostedDimensions(
+      this.name,
+      this.modelName,
+      this.requestedDimensions
+    );
+    if (this.resolvedDimensions === 0) {
+      // Unknown model: the first vector tells its size
+      const [probe] = await this.embedBatch(['dimension probe']);
+      this.resolvedDimensions = probe.length;
     }
   }
 
@@ -39,32 +63,46 @@ export class OpenAIEmbeddingProvider implements EmbeddingProvider {
   async embedBatch(texts: string[]): Promise<number[][]> {
     if (!texts.length) return [];
 
-    try {
-      const response = await fetch(`${this.apiEndpoint}/embeddings`, {
-        method: 'POST',
-        headers: {
-          'Content-Type': 'application/json',
-          Authorization: `Bearer ${this.apiKey}`
-        },
-        body: JSON.stringify({
-          model: this.modelName,
-          input: texts,
-          encoding_format: 'float'
-        })
-      });
-
-      if (!response.ok) {
-        const error = await response.text();
-        throw new Error(`OpenAI API Error ${response.status}: ${error}`);
-      }
+    const data = await postEmbeddingRequest<OpenAIEmbeddingResponse>(
+      this.label,
+      this.requestUrl(),
+      this.authHeaders(),
+      this.requestBody(texts.map((text) => clipToInputLimit(text, this.modelName)))
+    );
 
-      const data = (await response.json()) as OpenAIEmbeddingResponse;
+    // OpenAI guarantees order matches input
+    return checkEmbeddings(
+      this.label,
+      data.data?.map((item) => item.embedding),
+      texts.length,
+      this.resolvedDimensions
+    );
+  }
 
-      // OpenAI guarantees order matches input
-      return data.data.map((item) => item.embedding);
-    } catch (error) {
-      console.error('OpenAI Embedding Failed:', error);
-      throw error;
+  protected checkSettings(): void {
+    if (!this.apiKey) {
+      throw new Error(
+        'OpenAI API key is missing. Set OPENAI_API_KEY environment variable or configure it in the MCP settings.'
+      );
     }
   }
+
+  protected requestUrl(): string {
+    return `${this.apiEndpoint}/embeddings`;
+  }
+
+  protected authHeaders(): Record<string, string> {
+    return { Authorization: `Bearer ${this.apiKey}` };
+  }
+
+  protected requestBody(input: string[]): Record<string, unknown> {
+    return {
+      model: this.modelName,
+      input,
+      encoding_format: 'float',
+      ...(shortensOutput(this.modelName, this.requestedDimensions)
+        ? { dimensions: this.requestedDimensions }
+        : {})
+    };
+  }
 }
@@ -0,0 +1,178 @@
+/**
+ * Token counting with the tokenizer a model actually uses, so chunk budgets and packed
+ * context are measured in real tokens rather than characters.
+ *
+ * - Transformers.js models: the model's own `tokenizer.json` (WordPiece, BPE or
+ *   SentencePiece/Unigram), loaded like the model itself, offline settings included.
+ * - OpenAI and Azure OpenAI: `cl100k_base` through the optional `js-tiktoken` package.
+ * - Voyage, Cohere, Ollama and anything that fails to load: a character estimate, reported
+ *   as such. CODEBASE_CONTEXT_TOKENIZER names a tokenizer for these (a tiktoken encoding or
+ *   a Hugging Face tokenizer id).
+ */
+
+import { estimateTokens } from '../utils/ast-chunker.js';
+import { HOSTED_MODEL_RULES } from './hosted.js';
+import {
+  applyLocalModelOptions,
+  localModelOptionsFromEnv,
+  type LocalModelOptions
+} from './local-models.js';
+import { transformersContextWindow } from './transformers.js';
+
+export interface TokenCounter {
+  /** Tokenizer id (tiktoken encoding or Hugging Face id), or `estimate` */
+  readonly name: string;
+  /** False when counts are a character estimate */
+  readonly exact: boolean;
+  /** Input limit the tokenizer declares, when it declares a sane one */
+  readonly maxInputTokens?: number;
+  count(text: string): number;
+}
+
+export const TIKTOKEN_ENCODINGS: readonly string[] = [
+  'o200k_base',
+  'cl100k_base',
+  'p50k_base',
+  'r50k_base'
+];
+
+/** The ~4 characters per token used since before tokenizers were wired in */
+export const ESTIMATED_TOKENS: TokenCounter = {
+  name: 'estimate',
+  exact: false,
+  count: estimateTokens
+};
+
+/**
+ * Code tokenizes denser than prose: checking a model's input limit at 3 characters per token
+ * errs on the side of splitting, like clipToInputLimit in hosted.ts.
+ */
+const CONSERVATIVE_ESTIMATE: TokenCounter = {
+  name: 'estimate',
+  exact: false,
+  count: (text) => Math.ceil(text.length / 3)
+};
+
+/** Tokenizers above this `model_max_length` don't really declare one (HF uses 1e30) */
+const MAX_DECLARED_INPUT_TOKENS = 1_000_000;
+
+interface TiktokenModule {
+  getEncoding(encoding: string): { encode(text: string): number[] };
+  encodingForModel(model: string): { encode(text: string): number[] };
+}
+
+const loaded = new Map<string, Promise<TokenCounter>>();
+
+async function loadTiktoken(): Promise<TiktokenModule | null> {
+  // Non-literal specifier keeps `js-tiktoken` optional for type-checking and bundling
+  const specifier = 'js-tiktoken';
+  try {
+    const mod = (await import(specifier)) as TiktokenModule & { default?: TiktokenModule };
+    return mod.default?.getEncoding ? mod.default : mod;
+  } catch {
+    return null;
+  }
+}
+
+async function loadTiktokenCounter(name: string): Promise<TokenCounter | null> {
+  const tiktoken = await loadTiktoken();
+  if (!tiktoken) return null;
+  const encoding = TIKTOKEN_ENCODINGS.includes(name)
+    ? tiktoken.getEncoding(name)
+    : tiktoken.encodingForModel(name);
+  return { name, exact: true, count: (text) => encoding.encode(text).length };
+}
+
+async function loadHuggingFaceCounter(
+  name: string,
+  localModels: LocalModelOptions
+): Promise<TokenCounter> {
+  const { AutoTokenizer } = await import('@huggingface/transformers');
+  await applyLocalModelOptions(localModels);
+  const tokenizer = await AutoTokenizer.from_pretrained(name);
+  const declared = Number(tokenizer.model_max_length);
+  return {
+    name,
+    exact: true,
+    ...(Number.isFinite(declared) && declared > 0 && declared < MAX_DECLARED_INPUT_TOKENS
+      ? { maxInputTokens: declared }
+      : {}),
+    count: (text) => tokenizer.encode(text).length
+  };
+}
+
+async function loadCounter(name: string, localModels: LocalModelOptions): Promise<TokenCounter> {
+  if (!name || name === 'estimate') return ESTIMATED_TOKENS;
+  try {
+    // tiktoken encodings and OpenAI model names have no slash; Hugging Face ids do
+    if (!name.includes('/')) {
+      const counter = await loadTiktokenCounter(name);
+      if (counter) return counter;
+      if (process.env.CODEBASE_CONTEXT_DEBUG) {
+        console.error(`[tokenizer] js-tiktoken is not installed; estimating tokens for ${name}`);
+      }
+      return ESTIMATED_TOKENS;
+    }
+    return await loadHuggingFaceCounter(name, localModels);
+  } catch (error) {
+    if (process.env.CODEBASE_CONTEXT_DEBUG) {
+      console.error(`[tokenizer] Could not load ${name}; estimating tokens:`, error);
+    }
+    return ESTIMATED_TOKENS;
+  }
+}
+
+/**
+ * The tokenizer called `name`: a tiktoken encoding or OpenAI model name (needs `js-tiktoken`),
+ * a Hugging Face tokenizer id, or `estimate`. Falls back to the estimate, never throws; check
+ * `exact` to tell which one you got. Loaded once per name.
+ */
+export function loadTokenizer(
+  name: string,
+  localModels: LocalModelOptions = localModelOptionsFromEnv()
+): Promise<TokenCounter> {
+  const key = name.trim();
+  let counter = loaded.get(key);
+  if (!counter) {
+    counter = loadCounter(key, localModels);
+    loaded.set(key, counter);
+  }
+  return counter;
+}
+
+/** The tokenizer `provider` counts `model` input with; `estimate` when not known */
+export function embeddingTokenizerName(provider: string, model: string): string {
+  const override = process.env.CODEBASE_CONTEXT_TOKENIZER?.trim();
+  if (override) return override;
+  if (provider === 'transformers') return model;
+  // Every OpenAI embedding model (and so every Azure deployment of one) uses cl100k_base
+  if (provider === 'openai' || provider === 'azure-openai') return 'cl100k_base';
+  return 'estimate';
+}
+
+export interface ChunkTokenBudget {
+  /** Counts the text that is sent to the model */
+  counter: TokenCounter;
+  /** Most tokens one embedding input may have */
+  maxInputTokens: number;
+}
+
+/**
+ * How chunk sizes are checked against an embedding model: its tokenizer and input limit.
+ * Null when the limit isn't known (Ollama models, unknown hosted models) and the tokenizer
+ * doesn't declare one.
+ * Without an exact tokenizer the limit is checked against a conservative estimate.
+ */
+export async function loadChunkTokenBudget(
+  provider: string,
+  model: string,
+  localModels: LocalModelOptions = localModelOptionsFromEnv()
+): Promise<ChunkTokenBudget | null> {
+  const tokenizer = await loadTokenizer(embeddingTokenizerName(provider, model), localModels);
+  const maxInputTokens =
+    HOSTED_MODEL_RULES[model]?.maxInputTokens ??
+    (provider === 'transformers' ? transformersContextWindow(model) : undefined) ??
+    tokenizer.maxInputTokens;
+  if (!maxInputTokens) return null;
+  return { counter: tokenizer.exact ? tokenizer : CONSERVATIVE_ESTIMATE, maxInputTokens };
+}
@@ -1,5 +1,10 @@
 import { EmbeddingProvider, DEFAULT_MODEL } from './types.js';
 import type { FeatureExtractionPipelineType } from '@huggingface/transformers';
+import {
+  applyLocalModelOptions,
+  explainOfflineLoadFailure,
+  type LocalModelOptions
+} from './local-models.js';
 
 interface ModelConfig {
   dimensions: number;
@@ -10,9 +15,16 @@ const MODEL_CONFIGS: Record<string, ModelConfig> = {
   'Xenova/bge-small-en-v1.5': { dimensions: 384, maxContext: 512 },
   'Xenova/all-MiniLM-L6-v2': { dimensions: 384, maxContext: 512 },
   'Xenova/bge-base-en-v1.5': { dimensions: 768, maxContext: 512 },
-  'onnx-community/granite-embedding-small-english-r2-ONNX': { dimensions: 384, maxContext: 8192 }
+  'onnx-community/granite-embedding-small-english-r2-ONNX': { dimensions: 384, maxContext: 8192 },
+  // Trained on code and docstrings; ~160MB quantized
+  'jinaai/jina-embeddings-v2-base-code': { dimensions: 768, maxContext: 8192 }
 };
 
+/** Token context window of a known model; undefined for others */
+export function transformersContextWindow(modelName: string): number | undefined {
+  return MODEL_CONFIGS[modelName]?.maxContext;
+}
+
 /**
  * Compute a safe batch size for embedding that won't freeze consumer hardware.
  * Calibrated so 512-ctx models get batch=32, 8192-ctx models get batch=8.
@@ -23,6 +35,10 @@ function computeSafeBatchSize(modelName: string): number {
   return Math.max(4, Math.min(32, Math.floor(16384 / ctx)));
 }
 
+/**
+ * Runs a quantized (q8) ONNX model in-process: no server, and no network once the model files
+ * are cached or present under `modelPath` (see local-models.ts).
+ */
 export class TransformersEmbeddingProvider implements EmbeddingProvider {
   readonly name = 'transformers';
   readonly modelName: string;
@@ -32,7 +48,10 @@ export class TransformersEmbeddingProvider implements EmbeddingProvider {
   private ready = false;
   private initPromise: Promise<void> | null = null;
 
-  constructor(modelName: string = DEFAULT_MODEL) {
+  constructor(
+    modelName: string = DEFAULT_MODEL,
+    private localModels: LocalModelOptions = {}
+  ) {
     this.modelName = modelName;
     this.dimensions = MODEL_CONFIGS[modelName]?.dimensions || 384;
   }
@@ -53,6 +72,7 @@ export class TransformersEmbeddingProvider implements EmbeddingProvider {
       }
 
       const { pipeline } = await import('@huggingface/transformers');
+      await applyLocalModelOptions(this.localModels);
 
       // TS2590: pipeline() resolves AllTasks[T] — a union too complex for TSC to represent.
       // Cast to a simpler signature; the actual return type IS FeatureExtractionPipelineType.
@@ -61,9 +81,14 @@ export class TransformersEmbeddingProvider implements EmbeddingProvider {
         model: string,
         opts: Record<string, unknown>
       ) => Promise<FeatureExtractionPipelineType>;
-      this.pipeline = await (pipeline as PipelineFn)('feature-extraction', this.modelName, {
-        dtype: 'q8'
-      });
+      try {
+        this.pipeline = await (pipeline as PipelineFn)('feature-extraction', this.modelName, {
+          dtype: 'q8'
+        });
+      } catch (error) {
+        if (this.localModels.allowDownload !== false) throw error;
+        throw explainOfflineLoadFailure(this.modelName, this.localModels, error);
+      }
 
       this.ready = true;
       if (process.env.CODEBASE_CONTEXT_DEBUG) {
@@ -128,9 +153,10 @@ export class TransformersEmbeddingProvider implements EmbeddingProvider {
 }
 
 export async function createEmbeddingProvider(
-  modelName: string = DEFAULT_MODEL
+  modelName: string = DEFAULT_MODEL,
+  localModels: LocalModelOptions = {}
 ): Promise<EmbeddingProvider> {
-  const provider = new TransformersEmbeddingProvider(modelName);
+  const provider = new TransformersEmbeddingProvider(modelName, localModels);
   await provider.initialize();
   return provider;
 }
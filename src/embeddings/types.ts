@@ -1,7 +1,11 @@
+import { localModelOptionsFromEnv } from './local-models.js';
+
 export interface EmbeddingProvider {
   readonly name: string;
   readonly modelName: string;
   readonly dimensions: number;
+  /** Set when vectors are cut to their first N components after embedding (Matryoshka) */
+  readonly truncateDimensions?: number;
 
   initialize(): Promise<void>;
   embed(text: string): Promise<number[]>;
@@ -10,23 +14,55 @@ export interface EmbeddingProvider {
 }
 
 export interface EmbeddingConfig {
-  provider: 'transformers' | 'ollama' | 'openai' | 'custom';
+  provider: 'transformers' | 'ollama' | 'openai' | 'azure-openai' | 'voyage' | 'cohere' | 'custom';
+  /** Model name; for `azure-openai` the deployment name */
   model?: string;
+  /** Shorter output vectors where the model supports it (OpenAI v3, Voyage, Cohere v4) */
+  dimensions?: number;
+  /** Keep only the first N components of every vector, for any model (see matryoshka.ts) */
+  truncateDimensions?: number;
   batchSize?: number;
+  /** Embedding batches in flight at once (useful for hosted APIs) */
+  concurrency?: number;
   maxRetries?: number;
   apiKey?: string;
   apiEndpoint?: string;
+  /** Azure OpenAI `api-version` query parameter */
+  apiVersion?: string;
+  /** Local (transformers) models: root of `<org>/<model>/` model directories */
+  modelPath?: string;
+  /** Local (transformers) models: fetch missing ones from the Hugging Face Hub */
+  allowDownload?: boolean;
 }
 
 // Default: bge-small (fast, ~2min indexing, consumer-hardware safe)
 // Opt-in: set EMBEDDING_MODEL=onnx-community/granite-embedding-small-english-r2-ONNX for
 // better conceptual search at the cost of 5-10x slower indexing and higher RAM usage
-export const DEFAULT_MODEL = process.env.EMBEDDING_MODEL || 'Xenova/bge-small-en-v1.5';
+export const TRANSFORMERS_DEFAULT_MODEL = 'Xenova/bge-small-en-v1.5';
+export const DEFAULT_MODEL = process.env.EMBEDDING_MODEL || TRANSFORMERS_DEFAULT_MODEL;
+export const DEFAULT_OPENAI_MODEL = 'text-embedding-3-small';
+export const DEFAULT_OLLAMA_MODEL = 'nomic-embed-text';
+export const DEFAULT_VOYAGE_MODEL = 'voyage-code-3';
+export const DEFAULT_COHERE_MODEL = 'embed-english-v3.0';
+
+function envInteger(name: string, fallback: number, min: number): number {
+  const value = Number.parseInt(process.env[name] ?? '', 10);
+  return Number.isFinite(value) && value >= min ? value : fallback;
+}
+
+/** EMBEDDING_DIMENSIONS, or 0 for each model's native size */
+const REQUESTED_DIMENSIONS = envInteger('EMBEDDING_DIMENSIONS', 0, 1);
+/** EMBEDDING_TRUNCATE_DIMENSIONS, or 0 to keep whole vectors */
+const TRUNCATE_DIMENSIONS = envInteger('EMBEDDING_TRUNCATE_DIMENSIONS', 0, 1);
 
 export const DEFAULT_EMBEDDING_CONFIG: EmbeddingConfig = {
   provider: (process.env.EMBEDDING_PROVIDER as EmbeddingConfig['provider']) || 'transformers',
   model: DEFAULT_MODEL,
-  batchSize: 32,
-  maxRetries: 3,
+  batchSize: envInteger('EMBEDDING_BATCH_SIZE', 32, 1),
+  concurrency: envInteger('EMBEDDING_CONCURRENCY', 1, 1),
+  maxRetries: envInteger('EMBEDDING_MAX_RETRIES', 3, 0),
+  ...(REQUESTED_DIMENSIONS > 0 ? { dimensions: REQUESTED_DIMENSIONS } : {}),
+  ...(TRUNCATE_DIMENSIONS > 0 ? { truncateDimensions: TRUNCATE_DIMENSIONS } : {}),
+  ...localModelOptionsFromEnv(),
   apiKey: process.env.OPENAI_API_KEY
 };
@@ -0,0 +1,91 @@
+import { EmbeddingProvider, DEFAULT_VOYAGE_MODEL } from './types.js';
+import {
+  checkEmbeddings,
+  postEmbeddingRequest,
+  resolveHostedDimensions,
+  shortensOutput
+} from './hosted.js';
+
+interface VoyageEmbeddingResponse {
+  data: Array<{ embedding: number[]; index: number }>;
+}
+
+export const DEFAULT_VOYAGE_ENDPOINT = 'https://api.voyageai.com/v1';
+
+/**
+ * Voyage AI Embedding Provider (`voyage-code-3` by default, trained on code)
+ * Queries and documents are embedded with different `input_type`s: `embed()` is the search
+ * query, `embedBatch()` indexed chunks. Inputs over the model's limit are truncated by the
+ * API (`truncation: true`); `voyage-code-3` and the 3.5/3-large models can return 256, 512
+ * or 2048 dimensions instead of 1024.
+ */
+export class VoyageEmbeddingProvider implements EmbeddingProvider {
+  readonly name = 'voyage';
+  private resolvedDimensions = 0;
+
+  constructor(
+    readonly modelName: string = DEFAULT_VOYAGE_MODEL,
+    private apiKey?: string,
+    private apiEndpoint: string = DEFAULT_VOYAGE_ENDPOINT,
+    private requestedDimensions?: number
+  ) {
+    this.apiEndpoint = apiEndpoint.replace(/\/+$/, '');
+  }
+
+  get dimensions(): number {
+    return this.resolvedDimensions;
+  }
+
+  async initialize(): Promise<void> {
+    if (!this.apiKey) {
+      throw new Error('Voyage API key is missing. Set VOYAGE_API_KEY.');
+    }
+    this.resolvedDimensions = resolveHostedDimensions(
+      this.name,
+      this.modelName,
+      this.requestedDimensions
+    );
+    if (this.resolvedDimensions === 0) {
+      const [probe] = await this.request(['dimension probe'], 'document');
+      this.resolvedDimensions = probe.length;
+    }
+  }
+
+  isReady(): boolean {
+    return !!this.apiKey;
+  }
+
+  async embed(text: string): Promise<number[]> {
+    const [vector] = await this.request([text], 'query');
+    return vector;
+  }
+
+  async embedBatch(texts: string[]): Promise<number[][]> {
+    if (!texts.length) return [];
+    return this.request(texts, 'document');
+  }
+
+  private async request(texts: string[], inputType: 'query' | 'document'): Promise<number[][]> {
+    const data = await postEmbeddingRequest<VoyageEmbeddingResponse>(
+      'Voyage',
+      `${this.apiEndpoint}/embeddings`,
+      { Authorization: `Bearer ${this.apiKey}` },
+      {
+        model: this.modelName,
+        input: texts,
+        input_type: inputType,
+        truncation: true,
+        ...(shortensOutput(this.modelName, this.requestedDimensions)
+          ? { output_dimension: this.requestedDimensions }
+          : {})
+      }
+    );
+    const ordered = [...(data.data ?? [])].sort((a, b) => a.index - b.index);
+    return checkEmbeddings(
+      'Voyage',
+      ordered.map((item) => item.embedding),
+      texts.length,
+      this.resolvedDimensions
+    );
+  }
+}
@@ -8,3 +8,60 @@ export class IndexCorruptedError extends Error {
     this.name = 'IndexCorruptedError';
   }
 }
+
+/**
+ * Thrown when an indexing run is aborted through its AbortSignal.
+ * Cancellation happens before anything is written, so the previous index stays intact.
+ */
+export class IndexingCancelledError extends Error {
+  constructor(message = 'Indexing cancelled') {
+    super(message);
+    this.name = 'IndexingCancelledError';
+  }
+}
+
+/**
+ * Thrown when a search names an embedding collection that isn't built or can't be queried.
+ * Only the collection is affected; the main index is still usable.
+ */
+export class EmbeddingCollectionError extends Error {
+  constructor(
+    readonly collection: string,
+    message: string
+  ) {
+    super(message);
+    this.name = 'EmbeddingCollectionError';
+  }
+}
+
+/**
+ * Thrown when a pagination cursor was not issued by this server (or is malformed).
+ */
+export class InvalidCursorError extends Error {
+  constructor(cursor: string) {
+    super(`Invalid cursor: ${cursor}`);
+    this.name = 'InvalidCursorError';
+  }
+}
+
+/**
+ * Abort reason when a tool call runs past its time budget (CODEBASE_CONTEXT_TOOL_TIMEOUT_MS).
+ * Tools that check their signal return a partial result instead of failing.
+ */
+export class ToolTimeoutError extends Error {
+  constructor(readonly timeoutMs: number) {
+    super(`Tool call exceeded its ${timeoutMs}ms time budget`);
+    this.name = 'ToolTimeoutError';
+  }
+}
+
+/**
+ * Thrown when an encrypted index can't be opened: no key is configured, the keychain lookup
+ * failed, or the key is not the one the index was sealed with. Rebuilding would not help.
+ */
+export class IndexKeyError extends Error {
+  constructor(message: string) {
+    super(message);
+    this.name = 'IndexKeyError';
+  }
+}
@@ -1,12 +1,28 @@
+/**
+ * Retrieval evaluation: fixtures scored as top-1 accuracy and top-3 recall with a pass gate,
+ * and golden query sets — hand-written queries with the files and symbols a good answer must
+ * contain — scored as recall@K and MRR. Two golden runs of the same set (another chunk size,
+ * another embedding model) can be compared query by query.
+ */
+
 import crypto from 'crypto';
-import type { SearchResult } from '../types/index.js';
+import { parseSimpleYaml, type YamlValue } from '../utils/simple-yaml.js';
+import type { SearchOptions } from '../core/search.js';
+import type { ChunkMetadata, SearchResult } from '../types/index.js';
 import type {
   EvalGate,
   EvalQuery,
   EvalResult,
   EvalSummary,
   EvaluateFixtureParams,
-  FormatEvalReportParams
+  EvaluateGoldenSetParams,
+  FormatEvalReportParams,
+  GoldenComparison,
+  GoldenQuery,
+  GoldenQueryComparison,
+  GoldenQueryResult,
+  GoldenRun,
+  GoldenSet
 } from './types.js';
 
 function normalizePath(filePath: string): string {
@@ -115,19 +131,28 @@ function formatPath(filePath: string | null, redactPaths: boolean): string {
   return `path#${hashPath(normalized)}/${base}`;
 }
 
+/** Run each query through the searcher in order and score its results */
+async function searchEach<Q extends { query: string }, R>(
+  queries: Q[],
+  searcher: EvaluateFixtureParams['searcher'],
+  limit: number,
+  searchOptions: SearchOptions | undefined,
+  score: (query: Q, results: SearchResult[]) => R
+): Promise<R[]> {
+  const scored: R[] = [];
+  for (const query of queries) {
+    scored.push(score(query, await searcher.search(query.query, limit, undefined, searchOptions)));
+  }
+  return scored;
+}
+
 export async function evaluateFixture({
   fixture,
   searcher,
   limit = 5,
   searchOptions
 }: EvaluateFixtureParams): Promise<EvalSummary> {
-  const results: EvalResult[] = [];
-
-  for (const query of fixture.queries) {
-    const searchResults = await searcher.search(query.query, limit, undefined, searchOptions);
-    results.push(evaluateQuery(query, searchResults));
-  }
-
+  const results = await searchEach(fixture.queries, searcher, limit, searchOptions, evaluateQuery);
   return summarizeEvaluation(results);
 }
 
@@ -216,3 +241,232 @@ export function formatEvalReport({
   lines.push('\n================================');
   return lines.join('\n');
 }
+
+export const DEFAULT_GOLDEN_K = 10;
+
+function isMapping(value: YamlValue | undefined): value is { [key: string]: YamlValue } {
+  return typeof value === 'object' && value !== null && !Array.isArray(value);
+}
+
+function stringList(value: YamlValue | undefined, field: string, where: string): string[] {
+  if (value === undefined || value === null) return [];
+  const items = Array.isArray(value) ? value : [value];
+  return items.map((item) => {
+    if (typeof item !== 'string' && typeof item !== 'number') {
+      throw new Error(`${where}: "${field}" must be a list of strings`);
+    }
+    return String(item).trim();
+  });
+}
+
+function positiveInteger(value: YamlValue | undefined, field: string): number | undefined {
+  if (value === undefined || value === null) return undefined;
+  if (typeof value !== 'number' || !Number.isInteger(value) || value <= 0) {
+    throw new Error(`Golden set: "${field}" must be a positive integer`);
+  }
+  return value;
+}
+
+/** Parse a golden set from YAML (or JSON, which is valid input too) */
+export function parseGoldenSet(source: string): GoldenSet {
+  const trimmed = source.trim();
+  const raw = (trimmed.startsWith('{') ? JSON.parse(trimmed) : parseSimpleYaml(source)) as
+    | YamlValue
+    | undefined;
+  if (!isMapping(raw) || !Array.isArray(raw.queries) || raw.queries.length === 0) {
+    throw new Error('Golden set: expected a "queries" list with at least one query');
+  }
+
+  const seen = new Set<string>();
+  const queries = raw.queries.map((entry, index): GoldenQuery => {
+    const where = `Golden set query ${index + 1}`;
+    if (!isMapping(entry)) throw new Error(`${where}: expected a mapping`);
+    if (typeof entry.query !== 'string' || !entry.query.trim()) {
+      throw new Error(`${where}: "query" is required`);
+    }
+    const id =
+      typeof entry.id === 'string' || typeof entry.id === 'number'
+        ? String(entry.id)
+        : String(index + 1);
+    if (seen.has(id)) throw new Error(`${where}: duplicate id "${id}"`);
+    seen.add(id);
+
+    const expectFiles = stringList(entry.expectFiles, 'expectFiles', where);
+    const expectSymbols = stringList(entry.expectSymbols, 'expectSymbols', where);
+    if (expectFiles.length === 0 && expectSymbols.length === 0) {
+      throw new Error(`${where}: needs at least one of "expectFiles" or "expectSymbols"`);
+    }
+    return {
+      id,
+      query: entry.query.trim(),
+      expectFiles,
+      expectSymbols,
+      ...(typeof entry.category === 'string' ? { category: entry.category } : {})
+    };
+  });
+
+  return {
+    ...(typeof raw.name === 'string' ? { name: raw.name } : {}),
+    k: positiveInteger(raw.k, 'k') ?? DEFAULT_GOLDEN_K,
+    queries
+  };
+}
+
+/** An expected path matches the result path exactly or as a trailing path segment run */
+function matchesFile(result: SearchResult, expected: string): boolean {
+  const actual = normalizePath(result.filePath).replace(/^\.\//, '');
+  const wanted = normalizePath(expected).replace(/^\.\//, '');
+  return actual === wanted || actual.endsWith(`/${wanted}`);
+}
+
+/** `login` matches `login` and `AuthService.login`; `AuthService.login` only the latter */
+function matchesSymbol(result: SearchResult, expected: string): boolean {
+  const metadata: Partial<ChunkMetadata> = result.metadata ?? {};
+  const { symbolName, symbolPath } = metadata;
+  if (symbolName === expected) return true;
+  if (!symbolPath || symbolPath.length === 0) return false;
+  return symbolPath.join('.') === expected || symbolPath[symbolPath.length - 1] === expected;
+}
+
+export function scoreGoldenQuery(
+  query: GoldenQuery,
+  results: SearchResult[],
+  k: number
+): GoldenQueryResult {
+  const top = results.slice(0, k);
+  const targets = [
+    ...query.expectFiles.map((value) => ({
+      label: value,
+      matches: (result: SearchResult) => matchesFile(result, value)
+    })),
+    ...query.expectSymbols.map((value) => ({
+      label: `symbol:${value}`,
+      matches: (result: SearchResult) => matchesSymbol(result, value)
+    }))
+  ];
+
+  const found: string[] = [];
+  const missing: string[] = [];
+  for (const target of targets) {
+    (top.some(target.matches) ? found : missing).push(target.label);
+  }
+  const firstHit = top.findIndex((result) => targets.some((target) => target.matches(result)));
+
+  return {
+    id: query.id,
+    query: query.query,
+    ...(query.category ? { category: query.category } : {}),
+    recall: targets.length > 0 ? found.length / targets.length : 0,
+    reciprocalRank: firstHit === -1 ? 0 : 1 / (firstHit + 1),
+    firstRelevantRank: firstHit === -1 ? null : firstHit + 1,
+    found,
+    missing,
+    topFiles: top.slice(0, 3).map((result) => result.filePath)
+  };
+}
+
+export function summarizeGoldenRun(
+  label: string,
+  k: number,
+  results: GoldenQueryResult[]
+): GoldenRun {
+  const total = results.length;
+  const mean = (pick: (result: GoldenQueryResult) => number) =>
+    total > 0 ? results.reduce((sum, result) => sum + pick(result), 0) / total : 0;
+  return {
+    label,
+    k,
+    total,
+    recallAtK: mean((result) => result.recall),
+    mrr: mean((result) => result.reciprocalRank),
+    hitRate: mean((result) => (result.firstRelevantRank === null ? 0 : 1)),
+    results
+  };
+}
+
+export async function evaluateGoldenSet({
+  set,
+  searcher,
+  label = 'default',
+  k = set.k,
+  searchOptions
+}: EvaluateGoldenSetParams): Promise<GoldenRun> {
+  const results = await searchEach(set.queries, searcher, k, searchOptions, (query, found) =>
+    scoreGoldenQuery(query, found, k)
+  );
+  return summarizeGoldenRun(label, k, results);
+}
+
+/** Per-query and aggregate differences from run `a` to run `b` (positive = `b` is better) */
+export function compareGoldenRuns(a: GoldenRun, b: GoldenRun): GoldenComparison {
+  const byId = new Map(b.results.map((result) => [result.id, result]));
+  const queries: GoldenQueryComparison[] = [];
+  for (const before of a.results) {
+    const after = byId.get(before.id);
+    if (!after) continue;
+    const recallDelta = after.recall - before.recall;
+    const rrDelta = after.reciprocalRank - before.reciprocalRank;
+    const delta = recallDelta !== 0 ? recallDelta : rrDelta;
+    queries.push({
+      id: before.id,
+      query: before.query,
+      a: { recall: before.recall, reciprocalRank: before.reciprocalRank },
+      b: { recall: after.recall, reciprocalRank: after.reciprocalRank },
+      change: Math.abs(delta) < 1e-9 ? 'same' : delta > 0 ? 'better' : 'worse'
+    });
+  }
+  return {
+    a: a.label,
+    b: b.label,
+    k: a.k,
+    recallDelta: b.recallAtK - a.recallAtK,
+    mrrDelta: b.mrr - a.mrr,
+    better: queries.filter((query) => query.change === 'better').length,
+    worse: queries.filter((query) => query.change === 'worse').length,
+    same: queries.filter((query) => query.change === 'same').length,
+    queries
+  };
+}
+
+const fixed = (value: number) => value.toFixed(3);
+const signed = (value: number) => `${value >= 0 ? '+' : ''}${value.toFixed(3)}`;
+
+export function formatGoldenReport(run: GoldenRun): string {
+  const lines = [
+    `=== Golden Set: ${run.label} ===`,
+    `Queries: ${run.total} | Recall@${run.k}: ${fixed(run.recallAtK)} | MRR: ${fixed(run.mrr)}` +
+      ` | Hit rate: ${(run.hitRate * 100).toFixed(0)}%`
+  ];
+  const misses = run.results.filter((result) => result.missing.length > 0);
+  lines.push('', misses.length === 0 ? 'All expected targets found.' : 'Missing targets:');
+  for (const result of misses) {
+    const rank =
+      result.firstRelevantRank === null ? 'no hit' : `first hit #${result.firstRelevantRank}`;
+    lines.push(`  ${result.id} "${result.query}" (${rank})`);
+    lines.push(`    missing: ${result.missing.join(', ')}`);
+    lines.push(`    top: ${result.topFiles.join(', ') || 'none'}`);
+  }
+  return lines.join('\n');
+}
+
+export function formatGoldenComparison(a: GoldenRun, b: GoldenRun): string {
+  const comparison = compareGoldenRuns(a, b);
+  const row = (metric: string, left: string, right: string, delta: string) =>
+    `${metric.padEnd(12)}${left.padEnd(16)}${right.padEnd(16)}${delta}`;
+  const lines = [
+    `=== Golden Set Comparison: ${a.label} vs ${b.label} (K=${comparison.k}) ===`,
+    row('metric', a.label, b.label, 'delta'),
+    row(`recall@${a.k}`, fixed(a.recallAtK), fixed(b.recallAtK), signed(comparison.recallDelta)),
+    row('mrr', fixed(a.mrr), fixed(b.mrr), signed(comparison.mrrDelta)),
+    '',
+    `Better: ${comparison.better} | Worse: ${comparison.worse} | Same: ${comparison.same}`
+  ];
+  for (const query of comparison.queries.filter((entry) => entry.change !== 'same')) {
+    lines.push(
+      `  ${query.change === 'better' ? '+' : '-'} ${query.id} "${query.query}": recall ` +
+        `${fixed(query.a.recall)} -> ${fixed(query.b.recall)}, rr ` +
+        `${fixed(query.a.reciprocalRank)} -> ${fixed(query.b.reciprocalRank)}`
+    );
+  }
+  return lines.join('\n');
+}
@@ -63,3 +63,71 @@ export interface FormatEvalReportParams {
   summary: EvalSummary;
   redactPaths?: boolean;
 }
+
+export interface GoldenQuery {
+  id: string;
+  query: string;
+  /** Paths (or trailing path segments) that should appear in the top K */
+  expectFiles: string[];
+  /** Symbol names (`login`) or qualified paths (`AuthService.login`) that should appear */
+  expectSymbols: string[];
+  category?: string;
+}
+
+export interface GoldenSet {
+  name?: string;
+  k: number;
+  queries: GoldenQuery[];
+}
+
+export interface GoldenQueryResult {
+  id: string;
+  query: string;
+  category?: string;
+  /** Share of expected files and symbols found in the top K */
+  recall: number;
+  /** 1 / rank of the first result matching any target, 0 when none is in the top K */
+  reciprocalRank: number;
+  firstRelevantRank: number | null;
+  found: string[];
+  missing: string[];
+  topFiles: string[];
+}
+
+export interface GoldenRun {
+  label: string;
+  k: number;
+  total: number;
+  recallAtK: number;
+  mrr: number;
+  hitRate: number;
+  results: GoldenQueryResult[];
+}
+
+export interface GoldenQueryComparison {
+  id: string;
+  query: string;
+  a: { recall: number; reciprocalRank: number };
+  b: { recall: number; reciprocalRank: number };
+  change: 'better' | 'worse' | 'same';
+}
+
+export interface GoldenComparison {
+  a: string;
+  b: string;
+  k: number;
+  recallDelta: number;
+  mrrDelta: number;
+  better: number;
+  worse: number;
+  same: number;
+  queries: GoldenQueryComparison[];
+}
+
+export interface EvaluateGoldenSetParams {
+  set: GoldenSet;
+  searcher: CodebaseSearcher;
+  label?: string;
+  k?: number;
+  searchOptions?: SearchOptions;
+}
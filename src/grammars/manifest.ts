@@ -13,9 +13,17 @@ export const CURATED_LANGUAGE_TO_WASM: Record<string, string> = {
   go: 'tree-sitter-go.wasm',
   rust: 'tree-sitter-rust.wasm',
   java: 'tree-sitter-java.wasm',
+  kotlin: 'tree-sitter-kotlin.wasm',
   c: 'tree-sitter-c.wasm',
   cpp: 'tree-sitter-cpp.wasm',
-  csharp: 'tree-sitter-c_sharp.wasm'
+  csharp: 'tree-sitter-c_sharp.wasm',
+  ruby: 'tree-sitter-ruby.wasm',
+  php: 'tree-sitter-php.wasm',
+  swift: 'tree-sitter-swift.wasm',
+  'objective-c': 'tree-sitter-objc.wasm',
+  scala: 'tree-sitter-scala.wasm',
+  elixir: 'tree-sitter-elixir.wasm',
+  zig: 'tree-sitter-zig.wasm'
 };
 
 /**
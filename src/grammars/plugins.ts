@@ -0,0 +1,181 @@
+/**
+ * Grammar plugins: Tree-sitter languages added without changing the server, for languages
+ * that aren't bundled (COBOL, VHDL, Solidity, ...). Each plugin is a directory with a
+ * `grammar.json` manifest next to the compiled grammar and its queries:
+ *
+ *   solidity/
+ *     grammar.json               { "language": "solidity", "extensions": [".sol"],
+ *                                  "wasm": "tree-sitter-solidity.wasm", "symbols": "tags.scm" }
+ *     tree-sitter-solidity.wasm
+ *     tags.scm
+ *
+ * - `symbols` is a query in the `tags.scm` convention of Tree-sitter grammar repos: a
+ *   `@definition.<kind>` capture on the definition, with a `@name` capture inside it.
+ * - `chunks` (optional) is a query whose `@chunk` captures are the chunk boundaries; without
+ *   it, the symbols are.
+ * - `comments` (optional) gives the comment markers for chunk post-processing, e.g.
+ *   `{ "line": ["--"], "block": [["(*", "*)"]] }`.
+ *
+ * Plugins load once at startup from CODEBASE_CONTEXT_GRAMMAR_PLUGINS_DIR, else the
+ * `grammars` directory next to the user config (`~/.config/codebase-context/grammars`).
+ * Library users can call `registerGrammarPlugin` instead. Bundled languages can't be replaced,
+ * and a grammar only runs inside the Tree-sitter wasm runtime.
+ */
+
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CURATED_LANGUAGE_TO_WASM } from './manifest.js';
+
+export interface GrammarPluginComments {
+  line?: string[];
+  block?: Array<[string, string]>;
+}
+
+export interface GrammarPlugin {
+  /** Language id its files are detected as */
+  language: string;
+  /** File extensions, with the dot */
+  extensions: string[];
+  /** Absolute path of the compiled grammar */
+  wasmPath: string;
+  /** Tree-sitter query finding symbols (`@definition.<kind>` and `@name` captures) */
+  symbolsQuery: string;
+  /** Tree-sitter query whose `@chunk` captures are chunk boundaries */
+  chunksQuery?: string;
+  comments?: GrammarPluginComments;
+}
+
+export const GRAMMAR_PLUGIN_MANIFEST = 'grammar.json';
+
+const plugins = new Map<string, GrammarPlugin>();
+const byExtension = new Map<string, GrammarPlugin>();
+const loadedDirs = new Set<string>();
+
+function normalizeExtension(extension: string): string {
+  const lower = extension.trim().toLowerCase();
+  return lower.startsWith('.') ? lower : `.${lower}`;
+}
+
+/** Add a grammar plugin; bundled languages and extensions another plugin claimed are refused */
+export function registerGrammarPlugin(plugin: GrammarPlugin): GrammarPlugin {
+  const language = plugin.language.trim().toLowerCase();
+  if (!language) throw new Error('Grammar plugin has no language');
+  if (language in CURATED_LANGUAGE_TO_WASM) {
+    throw new Error(`'${language}' has a bundled grammar`);
+  }
+  const extensions = [...new Set(plugin.extensions.map(normalizeExtension))];
+  if (extensions.length === 0) throw new Error(`Grammar plugin '${language}' has no extensions`);
+  for (const extension of extensions) {
+    const owner = byExtension.get(extension);
+    if (owner && owner.language !== language) {
+      throw new Error(`${extension} is already claimed by the '${owner.language}' plugin`);
+    }
+  }
+  const registered = { ...plugin, language, extensions };
+  plugins.set(language, registered);
+  for (const extension of extensions) byExtension.set(extension, registered);
+  return registered;
+}
+
+export function getGrammarPlugin(language: string): GrammarPlugin | undefined {
+  return plugins.get(language);
+}
+
+/** The plugin claiming `extension` (`.sol`), if any */
+export function grammarPluginForExtension(extension: string): GrammarPlugin | undefined {
+  return byExtension.get(extension.toLowerCase());
+}
+
+export function listGrammarPlugins(): GrammarPlugin[] {
+  return [...plugins.values()];
+}
+
+export function grammarPluginsDir(env: NodeJS.ProcessEnv = process.env): string {
+  const explicit = env.CODEBASE_CONTEXT_GRAMMAR_PLUGINS_DIR?.trim();
+  if (explicit) return path.resolve(explicit);
+  const base = env.XDG_CONFIG_HOME?.trim() || path.join(os.homedir(), '.config');
+  return path.join(base, 'codebase-context', 'grammars');
+}
+
+function isStringArray(value: unknown): value is string[] {
+  return Array.isArray(value) && value.every((item) => typeof item === 'string' && item.trim());
+}
+
+function parseComments(value: unknown): GrammarPluginComments | undefined {
+  if (!value || typeof value !== 'object') return undefined;
+  const { line, block } = value as { line?: unknown; block?: unknown };
+  const pairs = Array.isArray(block)
+    ? block.filter((pair): pair is [string, string] => isStringArray(pair) && pair.length === 2)
+    : [];
+  return {
+    ...(isStringArray(line) && { line }),
+    ...(pairs.length > 0 && { block: pairs })
+  };
+}
+
+/** Read one plugin directory; throws with the reason it can't be used */
+export async function readGrammarPlugin(dir: string): Promise<GrammarPlugin> {
+  const manifest = JSON.parse(
+    await fs.readFile(path.join(dir, GRAMMAR_PLUGIN_MANIFEST), 'utf-8')
+  ) as Record<string, unknown>;
+  const { language, extensions, wasm, symbols, chunks, comments } = manifest;
+  if (typeof language !== 'string' || !language.trim()) throw new Error('"language" is missing');
+  if (!isStringArray(extensions) || extensions.length === 0) {
+    throw new Error('"extensions" must list at least one extension');
+  }
+  if (typeof wasm !== 'string' || typeof symbols !== 'string') {
+    throw new Error('"wasm" and "symbols" must name files in the plugin directory');
+  }
+  // Files are looked up inside the plugin directory only
+  const inside = (file: string) => {
+    const resolved = path.resolve(dir, file);
+    if (path.relative(dir, resolved).startsWith('..')) {
+      throw new Error(`${file} is outside the plugin directory`);
+    }
+    return resolved;
+  };
+  const wasmPath = inside(wasm);
+  await fs.access(wasmPath);
+  const commentMarkers = parseComments(comments);
+  return {
+    language,
+    extensions,
+    wasmPath,
+    symbolsQuery: await fs.readFile(inside(symbols), 'utf-8'),
+    ...(typeof chunks === 'string' && {
+      chunksQuery: await fs.readFile(inside(chunks), 'utf-8')
+    }),
+    ...(commentMarkers && { comments: commentMarkers })
+  };
+}
+
+/**
+ * Register every plugin under `dir` (one subdirectory each). A broken plugin is reported and
+ * skipped; a missing directory loads nothing. Loading the same directory again is a no-op.
+ */
+export async function loadGrammarPlugins(dir = grammarPluginsDir()): Promise<GrammarPlugin[]> {
+  const root = path.resolve(dir);
+  if (loadedDirs.has(root)) return [];
+  loadedDirs.add(root);
+  const entries = await fs.readdir(root, { withFileTypes: true }).catch(() => []);
+  const loaded: GrammarPlugin[] = [];
+  for (const entry of entries.sort((a, b) => a.name.localeCompare(b.name))) {
+    if (!entry.isDirectory()) continue;
+    const pluginDir = path.join(root, entry.name);
+    try {
+      loaded.push(registerGrammarPlugin(await readGrammarPlugin(pluginDir)));
+    } catch (error) {
+      console.error(
+        `[grammars] Skipping plugin ${pluginDir}: ` +
+          (error instanceof Error ? error.message : String(error))
+      );
+    }
+  }
+  if (loaded.length > 0 && process.env.CODEBASE_CONTEXT_DEBUG) {
+    console.error(
+      `[DEBUG] Grammar plugins: ${loaded.map((plugin) => plugin.language).join(', ')} from ${root}`
+    );
+  }
+  return loaded;
+}
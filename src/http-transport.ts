@@ -0,0 +1,261 @@
+/**
+ * Streamable HTTP transport: one long-running server shared by several MCP clients.
+ *
+ * Each client session gets its own MCP `Server` (created by the caller), all backed by the same
+ * warm project state. Responses stream over SSE when the SDK needs them to (progress, etc.).
+ * `GET /metrics` serves Prometheus metrics when the caller provides them, behind the same token.
+ *
+ * Requests whose `Host` or `Origin` isn't allowed get a 403, so a web page can't reach the
+ * server through DNS rebinding: on a loopback bind only loopback names pass unless
+ * CODEBASE_CONTEXT_ALLOWED_HOSTS adds more, and browser origins must be loopback or listed
+ * in CODEBASE_CONTEXT_ALLOWED_ORIGINS.
+ */
+
+import { randomUUID, timingSafeEqual } from 'crypto';
+import http from 'http';
+import type { Server } from '@modelcontextprotocol/sdk/server/index.js';
+import { StreamableHTTPServerTransport } from '@modelcontextprotocol/sdk/server/streamableHttp.js';
+import { isInitializeRequest } from '@modelcontextprotocol/sdk/types.js';
+
+export const DEFAULT_HTTP_HOST = '127.0.0.1';
+export const DEFAULT_HTTP_PORT = 3100;
+export const MCP_HTTP_PATH = '/mcp';
+export const METRICS_HTTP_PATH = '/metrics';
+
+const MAX_BODY_BYTES = 4 * 1024 * 1024;
+const SESSION_HEADER = 'mcp-session-id';
+
+export interface HttpTransportOptions {
+  host: string;
+  port: number;
+  /** Required bearer token; mandatory when binding to a non-loopback address */
+  authToken?: string;
+  createServer: () => Server;
+  /** Extra `Host` names accepted (without the port), besides loopback ones and the bind host */
+  allowedHosts?: string[];
+  /** Extra browser origins accepted (`https://app.example.com`), besides loopback ones */
+  allowedOrigins?: string[];
+  /** Prometheus text for GET /metrics; the endpoint is absent without it */
+  metrics?: { render: () => string; contentType: string };
+}
+
+export interface HttpTransportHandle {
+  url: string;
+  /** Number of open client sessions */
+  sessionCount: () => number;
+  close: () => Promise<void>;
+}
+
+export function isLoopbackHost(host: string): boolean {
+  return host === 'localhost' || host === '::1' || /^127(\.\d{1,3}){3}$/.test(host);
+}
+
+/** Read transport settings from the environment. Returns null when stdio should be used. */
+export function resolveHttpTransportConfig(
+  env: NodeJS.ProcessEnv = process.env
+): Omit<HttpTransportOptions, 'createServer'> | null {
+  const transport = env.CODEBASE_CONTEXT_TRANSPORT?.trim().toLowerCase();
+  // daemon and daemon-server are handled by daemon.ts
+  if (!transport || transport === 'stdio' || transport.startsWith('daemon')) return null;
+  if (transport !== 'http') {
+    throw new Error(
+      `Unknown CODEBASE_CONTEXT_TRANSPORT '${transport}'. Use stdio, http or daemon.`
+    );
+  }
+
+  const portValue = env.CODEBASE_CONTEXT_HTTP_PORT?.trim();
+  const port = portValue ? Number.parseInt(portValue, 10) : DEFAULT_HTTP_PORT;
+  if (!Number.isInteger(port) || port < 0 || port > 65535) {
+    throw new Error(`Invalid CODEBASE_CONTEXT_HTTP_PORT '${portValue}'.`);
+  }
+
+  const list = (value: string | undefined) =>
+    (value ?? '')
+      .split(',')
+      .map((entry) => entry.trim())
+      .filter(Boolean);
+  const allowedHosts = list(env.CODEBASE_CONTEXT_ALLOWED_HOSTS);
+  const allowedOrigins = list(env.CODEBASE_CONTEXT_ALLOWED_ORIGINS);
+  return {
+    host: env.CODEBASE_CONTEXT_HTTP_HOST?.trim() || DEFAULT_HTTP_HOST,
+    port,
+    authToken: env.CODEBASE_CONTEXT_AUTH_TOKEN?.trim() || undefined,
+    ...(allowedHosts.length > 0 && { allowedHosts }),
+    ...(allowedOrigins.length > 0 && { allowedOrigins })
+  };
+}
+
+/** Host name of a `Host` header or origin, lower-cased and without IPv6 brackets */
+function hostnameOf(value: string, withScheme: boolean): string | null {
+  try {
+    const { hostname } = new URL(withScheme ? value : `http://${value}`);
+    return hostname.replace(/^\[(.*)\]$/, '$1').toLowerCase();
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Whether the request names this server: its `Host` is loopback, the bind address or an allowed
+ * host (any host off loopback when none are configured, since the token guards those binds),
+ * and a browser `Origin`, if sent, is loopback or allowed
+ */
+function isAllowedRequest(req: http.IncomingMessage, options: HttpTransportOptions): boolean {
+  const allowedHosts = new Set(
+    [options.host, ...(options.allowedHosts ?? [])].map((host) => host.toLowerCase())
+  );
+  const restrictHosts = isLoopbackHost(options.host) || (options.allowedHosts ?? []).length > 0;
+  if (restrictHosts) {
+    const host = hostnameOf(req.headers.host ?? '', false);
+    if (!host || !(isLoopbackHost(host) || allowedHosts.has(host))) return false;
+  }
+  const origin = req.headers.origin;
+  if (origin === undefined) return true;
+  if ((options.allowedOrigins ?? []).some((allowed) => allowed.replace(/\/$/, '') === origin)) {
+    return true;
+  }
+  const originHost = hostnameOf(origin, true);
+  return !!originHost && isLoopbackHost(originHost);
+}
+
+function isAuthorized(header: string | undefined, token: string): boolean {
+  const match = /^Bearer\s+(.+)$/i.exec(header ?? '');
+  if (!match) return false;
+  const provided = Buffer.from(match[1].trim());
+  const expected = Buffer.from(token);
+  return provided.length === expected.length && timingSafeEqual(provided, expected);
+}
+
+function sendJsonRpcError(
+  res: http.ServerResponse,
+  statusCode: number,
+  code: number,
+  message: string,
+  headers: Record<string, string> = {}
+): void {
+  res.writeHead(statusCode, { 'Content-Type': 'application/json', ...headers });
+  res.end(JSON.stringify({ jsonrpc: '2.0', error: { code, message }, id: null }));
+}
+
+async function readJsonBody(req: http.IncomingMessage): Promise<unknown> {
+  const chunks: Buffer[] = [];
+  let size = 0;
+  for await (const chunk of req as AsyncIterable<Buffer | string>) {
+    const buffer = typeof chunk === 'string' ? Buffer.from(chunk) : chunk;
+    size += buffer.length;
+    if (size > MAX_BODY_BYTES) throw new RangeError('Request body too large');
+    chunks.push(buffer);
+  }
+  return JSON.parse(Buffer.concat(chunks).toString('utf-8'));
+}
+
+export async function startHttpTransport(
+  options: HttpTransportOptions
+): Promise<HttpTransportHandle> {
+  if (!options.authToken && !isLoopbackHost(options.host)) {
+    throw new Error(
+      `Refusing to listen on ${options.host} without CODEBASE_CONTEXT_AUTH_TOKEN. ` +
+        'Set a token or bind to 127.0.0.1.'
+    );
+  }
+
+  const sessions = new Map<string, StreamableHTTPServerTransport>();
+
+  const handle = async (req: http.IncomingMessage, res: http.ServerResponse): Promise<void> => {
+    const url = new URL(req.url ?? '/', 'http://localhost');
+    const isMetrics = url.pathname === METRICS_HTTP_PATH && !!options.metrics;
+    if (url.pathname !== MCP_HTTP_PATH && !isMetrics) {
+      sendJsonRpcError(res, 404, -32000, `Not found. The MCP endpoint is ${MCP_HTTP_PATH}`);
+      return;
+    }
+    if (!isAllowedRequest(req, options)) {
+      sendJsonRpcError(res, 403, -32000, 'Forbidden: Host or Origin not allowed');
+      return;
+    }
+    if (options.authToken && !isAuthorized(req.headers.authorization, options.authToken)) {
+      sendJsonRpcError(res, 401, -32001, 'Unauthorized', { 'WWW-Authenticate': 'Bearer' });
+      return;
+    }
+    if (isMetrics && options.metrics) {
+      res.writeHead(200, { 'Content-Type': options.metrics.contentType });
+      res.end(options.metrics.render());
+      return;
+    }
+
+    let body: unknown;
+    if (req.method === 'POST') {
+      try {
+        body = await readJsonBody(req);
+      } catch (error) {
+        if (error instanceof RangeError) {
+          sendJsonRpcError(res, 413, -32600, error.message);
+        } else {
+          sendJsonRpcError(res, 400, -32700, 'Parse error');
+        }
+        return;
+      }
+    }
+
+    const sessionId = req.headers[SESSION_HEADER];
+    if (typeof sessionId === 'string') {
+      const transport = sessions.get(sessionId);
+      if (!transport) {
+        sendJsonRpcError(res, 404, -32001, 'Session not found');
+        return;
+      }
+      await transport.handleRequest(req, res, body);
+      return;
+    }
+
+    if (req.method !== 'POST' || !isInitializeRequest(body)) {
+      sendJsonRpcError(res, 400, -32000, 'Bad Request: missing session id');
+      return;
+    }
+
+    const transport = new StreamableHTTPServerTransport({
+      sessionIdGenerator: () => randomUUID(),
+      onsessioninitialized: (id) => {
+        sessions.set(id, transport);
+      }
+    });
+    transport.onclose = () => {
+      if (transport.sessionId) sessions.delete(transport.sessionId);
+    };
+    await options.createServer().connect(transport);
+    await transport.handleRequest(req, res, body);
+  };
+
+  const httpServer = http.createServer((req, res) => {
+    handle(req, res).catch((error) => {
+      console.error(
+        '[http] Request failed:',
+        error instanceof Error ? error.message : String(error)
+      );
+      if (!res.headersSent) sendJsonRpcError(res, 500, -32603, 'Internal error');
+      else res.end();
+    });
+  });
+
+  await new Promise<void>((resolve, reject) => {
+    httpServer.once('error', reject);
+    httpServer.listen(options.port, options.host, () => {
+      httpServer.off('error', reject);
+      resolve();
+    });
+  });
+
+  const address = httpServer.address();
+  const port = typeof address === 'object' && address ? address.port : options.port;
+  const host = options.host.includes(':') ? `[${options.host}]` : options.host;
+
+  return {
+    url: `http://${host}:${port}${MCP_HTTP_PATH}`,
+    sessionCount: () => sessions.size,
+    close: async () => {
+      await Promise.all([...sessions.values()].map((transport) => transport.close()));
+      sessions.clear();
+      httpServer.closeAllConnections();
+      await new Promise<void>((resolve) => httpServer.close(() => resolve()));
+    }
+  };
+}
@@ -5,17 +5,27 @@
  * Provides codebase indexing and semantic search capabilities
  */
 
+// First: config files set environment variables that modules below read on load
+import './config-bootstrap.js';
 import { promises as fs } from 'fs';
 
 import path from 'path';
 import { Server } from '@modelcontextprotocol/sdk/server/index.js';
 import { StdioServerTransport } from '@modelcontextprotocol/sdk/server/stdio.js';
+import type { RequestHandlerExtra } from '@modelcontextprotocol/sdk/shared/protocol.js';
 import {
   CallToolRequestSchema,
+  CreateMessageResultSchema,
   ListToolsRequestSchema,
   ListResourcesRequestSchema,
+  ListResourceTemplatesRequestSchema,
   ReadResourceRequestSchema,
-  Resource
+  type CallToolRequest,
+  type ListResourcesRequest,
+  type ReadResourceRequest,
+  type Resource,
+  type ServerNotification,
+  type ServerRequest
 } from '@modelcontextprotocol/sdk/types.js';
 import { CodebaseIndexer } from './core/indexer.js';
 import type {
@@ -23,12 +33,14 @@ import type {
   IntelligenceData,
   PatternsData,
   PatternEntry,
-  PatternCandidate
+  PatternCandidate,
+  Sampler
 } from './types/index.js';
 import { analyzerRegistry } from './core/analyzer-registry.js';
+import { loadGrammarPlugins } from './grammars/plugins.js';
 import { AngularAnalyzer } from './analyzers/angular/index.js';
 import { GenericAnalyzer } from './analyzers/generic/index.js';
-import { IndexCorruptedError } from './errors/index.js';
+import { IndexCorruptedError, IndexingCancelledError } from './errors/index.js';
 import {
   CODEBASE_CONTEXT_DIRNAME,
   MEMORY_FILENAME,
@@ -38,16 +50,94 @@ import {
 } from './constants/codebase-context.js';
 import { appendMemoryFile } from './memory/store.js';
 import { handleCliCommand } from './cli.js';
+import { CLI_SUBCOMMANDS } from './constants/cli.js';
+import { resolveHttpTransportConfig, startHttpTransport } from './http-transport.js';
+import {
+  resolveDaemonIdleMinutes,
+  resolveDaemonRole,
+  resolveDaemonSocketPath,
+  runDaemonShim,
+  spawnDetachedDaemon,
+  startDaemon
+} from './daemon.js';
 import { startFileWatcher } from './core/file-watcher.js';
 import { createAutoRefreshController } from './core/auto-refresh.js';
+import { reconcileIndex, resolveGcIntervalMinutes } from './core/index-maintenance.js';
+import { readConsistently } from './core/index-lock.js';
+import { readIndexCheckpoint } from './core/index-checkpoint.js';
+import {
+  activeModelMigration,
+  describeModelMigration,
+  readModelMigration,
+  startModelMigration
+} from './core/model-migration.js';
+import { linkedAbortController } from './core/cancellation.js';
+import { stopLanguageServers } from './core/lsp-bridge.js';
+import { METRICS_CONTENT_TYPE, metricsRegistry } from './core/telemetry.js';
 import { parseGitLogLineToMemory } from './memory/git-memory.js';
 import {
   isComplementaryPatternCategory,
   shouldSkipLegacyTestingFrameworkCategory
 } from './patterns/semantics.js';
-import { CONTEXT_RESOURCE_URI, isContextResourceUri } from './resources/uri.js';
-import { readIndexMeta, validateIndexArtifacts } from './core/index-meta.js';
-import { TOOLS, dispatchTool, type ToolContext } from './tools/index.js';
+import {
+  CONTEXT_RESOURCE_URI,
+  REPO_MAP_RESOURCE_URI,
+  REPO_MAP_URI_TEMPLATE,
+  MODULES_RESOURCE_URI,
+  MODULES_URI_TEMPLATE,
+  MODULE_CARD_URI_TEMPLATE,
+  FILE_URI_TEMPLATE,
+  FILE_CHUNKS_URI_TEMPLATE,
+  FILE_CHUNK_URI_TEMPLATE,
+  isContextResourceUri,
+  parseFileResourceUri,
+  parseModuleCardUri,
+  parseModulesUri,
+  parseRepoMapUri,
+  type RepoMapQuery
+} from './resources/uri.js';
+import { listFileResources, readFileResource } from './resources/files.js';
+import { readModuleCard, readModuleList } from './resources/modules.js';
+import { DEFAULT_REPO_MAP_TOKENS, buildRepoMap } from './core/repo-map.js';
+import {
+  checkIndexCompatibility,
+  describeEmbeddingDrift,
+  readIndexMeta,
+  validateIndexArtifacts
+} from './core/index-meta.js';
+import { resolveEmbeddingModel } from './embeddings/index.js';
+import { describeBranchSwitch, detectBranchSwitch } from './core/branch-switch.js';
+import { readGitHead, type GitHead } from './utils/git-tree.js';
+import { normalizeRootPath } from './utils/path-normalization.js';
+import {
+  createPathPolicy,
+  loadPathPolicy,
+  pathPolicyFromEnv,
+  type PathPolicy
+} from './core/path-policy.js';
+import {
+  TOOLS,
+  dispatchTool,
+  visibleTools,
+  withFormatParam,
+  withProjectParam,
+  type ToolContext,
+  type ToolPaths,
+  type ToolResponse
+} from './tools/index.js';
+import type { IndexingHooks, SearchResultItem } from './tools/types.js';
+import { OUTPUT_FORMATS, formatToolResponse, isOutputFormat } from './tools/output-format.js';
+import { createProgressReporter, overallProgress } from './core/indexing-progress.js';
+import { buildFleetGraph, joinFleetReferences } from './core/fleet-graph.js';
+import type { ReferenceLocation } from './core/symbol-navigation.js';
+import {
+  ALL_PROJECTS_SELECTOR,
+  buildWorkspaceProjects,
+  mergeProjectResults,
+  parseRootList,
+  selectProjects,
+  type WorkspaceProject
+} from './core/workspace.js';
 
 analyzerRegistry.register(new AngularAnalyzer());
 analyzerRegistry.register(new GenericAnalyzer());
@@ -59,7 +149,7 @@ function resolveRootPath(): string {
 
   // Priority: CLI arg > env var > cwd
   let rootPath = arg || envPath || process.cwd();
-  rootPath = path.resolve(rootPath);
+  rootPath = normalizeRootPath(rootPath);
 
   // Warn if using cwd as fallback (guarded to avoid stderr during MCP STDIO handshake)
   if (!arg && !envPath && process.env.CODEBASE_CONTEXT_DEBUG) {
@@ -70,36 +160,107 @@ function resolveRootPath(): string {
   return rootPath;
 }
 
-const ROOT_PATH = resolveRootPath();
+/**
+ * Primary root first (CLI arg > CODEBASE_ROOT > cwd), then extra roots from CODEBASE_ROOTS.
+ * When only CODEBASE_ROOTS is set, its first entry becomes the primary project.
+ */
+function resolveWorkspaceProjects(): WorkspaceProject[] {
+  const extraRoots = parseRootList(process.env.CODEBASE_ROOTS);
+  const hasExplicitRoot = Boolean(process.argv[2] || process.env.CODEBASE_ROOT);
+  const primary =
+    hasExplicitRoot || extraRoots.length === 0 ? [{ rootPath: resolveRootPath() }] : [];
+  return buildWorkspaceProjects([...primary, ...extraRoots]);
+}
 
-// File paths (new structure)
-const PATHS = {
-  baseDir: path.join(ROOT_PATH, CODEBASE_CONTEXT_DIRNAME),
-  memory: path.join(ROOT_PATH, CODEBASE_CONTEXT_DIRNAME, MEMORY_FILENAME),
-  intelligence: path.join(ROOT_PATH, CODEBASE_CONTEXT_DIRNAME, INTELLIGENCE_FILENAME),
-  keywordIndex: path.join(ROOT_PATH, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
-  vectorDb: path.join(ROOT_PATH, CODEBASE_CONTEXT_DIRNAME, VECTOR_DB_DIRNAME)
-};
+// Per-project state: every root has its own artifacts, index lifecycle and auto-refresh queue
+interface ProjectRuntime extends WorkspaceProject {
+  paths: ToolPaths;
+  legacyPaths: { intelligence: string; keywordIndex: string; vectorDb: string };
+  indexState: IndexState;
+  autoRefresh: ReturnType<typeof createAutoRefreshController>;
+  /** HEAD the index reflects: from index-meta.json, then from each successful run */
+  indexedHead?: GitHead;
+  /** Allow/deny paths and read-only mode: environment at first, plus config.json once prepared */
+  pathPolicy: PathPolicy;
+  /** The index build in progress; aborting it stops the build at its next checkpoint */
+  activeBuild?: { controller: AbortController; done: Promise<void> };
+}
 
-const LEGACY_PATHS = {
-  intelligence: path.join(ROOT_PATH, '.codebase-intelligence.json'),
-  keywordIndex: path.join(ROOT_PATH, '.codebase-index.json'),
-  vectorDb: path.join(ROOT_PATH, '.codebase-index')
-};
+function createProjectRuntime(project: WorkspaceProject): ProjectRuntime {
+  const baseDir = path.join(project.rootPath, CODEBASE_CONTEXT_DIRNAME);
+  return {
+    ...project,
+    // File paths (new structure)
+    paths: {
+      baseDir,
+      memory: path.join(baseDir, MEMORY_FILENAME),
+      intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+      keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+      vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+    },
+    legacyPaths: {
+      intelligence: path.join(project.rootPath, '.codebase-intelligence.json'),
+      keywordIndex: path.join(project.rootPath, '.codebase-index.json'),
+      vectorDb: path.join(project.rootPath, '.codebase-index')
+    },
+    indexState: { status: 'idle' },
+    autoRefresh: createAutoRefreshController(),
+    pathPolicy: createPathPolicy(pathPolicyFromEnv(), project.rootPath)
+  };
+}
+
+const READ_ONLY_NO_INDEX =
+  'No usable index and the server is read-only. Build it with `codebase-context index`.';
+
+const PROJECTS: ProjectRuntime[] = resolveWorkspaceProjects().map(createProjectRuntime);
+const PRIMARY_PROJECT = PROJECTS[0];
+const PATHS = PRIMARY_PROJECT.paths;
 
 export const INDEX_CONSUMING_TOOL_NAMES = [
   'search_codebase',
+  'multi_search',
+  'retrieve_with_citations',
+  'pack_context',
+  'search_symbols',
   'get_symbol_references',
+  'get_definition',
+  'get_symbol_docs',
+  'get_enclosing_scope',
+  'find_sql_queries',
+  'find_similar_code',
+  'resolve_stacktrace',
+  'changes_since',
+  'find_references',
+  'find_callers',
+  'find_callees',
+  'find_implementations',
+  'get_type_hierarchy',
+  'get_tests_for',
+  'analyze_unused',
+  'get_dependencies',
+  'get_dependents',
+  'get_build_target',
+  'get_build_dependents',
+  'summarize_file',
+  'get_diff_context',
   'detect_circular_dependencies',
   'get_team_patterns',
   'get_codebase_metadata'
 ] as const;
 
+/** Index-consuming tools that answer from the chunks parsed so far while the first build runs */
+export const PARTIAL_RESULT_TOOL_NAMES = ['search_codebase'] as const;
+
 export const INDEX_CONSUMING_RESOURCE_NAMES = ['Codebase Intelligence'] as const;
 
 type IndexStatus = 'ready' | 'rebuild-required' | 'indexing' | 'unknown';
 type IndexConfidence = 'high' | 'low';
-type IndexAction = 'served' | 'rebuild-started' | 'rebuilt-and-served' | 'rebuild-failed';
+type IndexAction =
+  | 'served'
+  | 'rebuild-started'
+  | 'rebuilt-and-served'
+  | 'refreshed-and-served'
+  | 'rebuild-failed';
 
 export type IndexSignal = {
   status: IndexStatus;
@@ -108,13 +269,33 @@ export type IndexSignal = {
   reason?: string;
 };
 
-async function requireValidIndex(rootPath: string): Promise<IndexSignal> {
-  const meta = await readIndexMeta(rootPath);
-  await validateIndexArtifacts(rootPath, meta);
+async function requireValidIndex(project: ProjectRuntime): Promise<IndexSignal> {
+  // A build swapping in from another process looks corrupt for a moment; read it again first
+  const meta = await readConsistently(project.paths.baseDir, async () => {
+    const active = await readIndexMeta(project.rootPath);
+    await validateIndexArtifacts(project.rootPath, active);
+    return active;
+  });
+  const drift = describeEmbeddingDrift(meta.embedding, resolveEmbeddingModel());
+  // Mid-migration the active vectors keep serving, queried with the model they came from
+  const migration = drift
+    ? await activeModelMigration(project.paths.baseDir, meta.embedding)
+    : null;
+  if (drift && !migration) throw new IndexCorruptedError(drift);
+  project.indexedHead ??= meta.head;
 
   // Optional artifact presence informs confidence.
-  const hasIntelligence = await fileExists(PATHS.intelligence);
+  const hasIntelligence = await fileExists(project.paths.intelligence);
 
+  if (migration) {
+    const { from, to } = describeModelMigration(migration);
+    return {
+      status: 'ready',
+      confidence: hasIntelligence ? 'high' : 'low',
+      action: 'served',
+      reason: `Re-embedding with ${to} in the background; serving ${from} vectors until then`
+    };
+  }
   return {
     status: 'ready',
     confidence: hasIntelligence ? 'high' : 'low',
@@ -123,8 +304,14 @@ async function requireValidIndex(rootPath: string): Promise<IndexSignal> {
   };
 }
 
-async function ensureValidIndexOrAutoHeal(): Promise<IndexSignal> {
-  if (indexState.status === 'indexing') {
+async function ensureValidIndexOrAutoHeal(
+  project: ProjectRuntime = PRIMARY_PROJECT
+): Promise<IndexSignal> {
+  // A model migration rebuilds in the background while the active index keeps serving
+  if (
+    project.indexState.status === 'indexing' &&
+    !(await readModelMigration(project.paths.baseDir))
+  ) {
     return {
       status: 'indexing',
       confidence: 'low',
@@ -134,18 +321,38 @@ async function ensureValidIndexOrAutoHeal(): Promise<IndexSignal> {
   }
 
   try {
-    return await requireValidIndex(ROOT_PATH);
+    const signal = await requireValidIndex(project);
+    // The build in progress reads the checked-out files itself
+    if (project.indexState.status === 'indexing') return signal;
+    const branchRefresh = await refreshAfterBranchSwitch(project);
+    if (!branchRefresh) return signal;
+    return branchRefresh.refreshed
+      ? { ...signal, action: 'refreshed-and-served', reason: branchRefresh.reason }
+      : {
+          ...signal,
+          confidence: 'low',
+          reason: `${branchRefresh.reason}; refresh failed, results may reflect the previous branch`
+        };
   } catch (error) {
     if (error instanceof IndexCorruptedError) {
       const reason = error.message;
       console.error(`[Index] ${reason}`);
+      // A model change doesn't break the active index: keep serving it while re-embedding
+      if (
+        !project.pathPolicy.readOnly &&
+        (await startModelMigration(project.rootPath, resolveEmbeddingModel()))
+      ) {
+        console.error('[Index] Re-embedding with the configured model in the background...');
+        void performIndexing(undefined, undefined, project);
+        return requireValidIndex(project);
+      }
       console.error('[Auto-Heal] Triggering full re-index...');
 
-      await performIndexing();
+      await performIndexing(undefined, undefined, project);
 
-      if (indexState.status === 'ready') {
+      if (project.indexState.status === 'ready') {
         try {
-          let validated = await requireValidIndex(ROOT_PATH);
+          let validated = await requireValidIndex(project);
           validated = { ...validated, action: 'rebuilt-and-served', reason };
           return validated;
         } catch (revalidateError) {
@@ -164,7 +371,7 @@ async function ensureValidIndexOrAutoHeal(): Promise<IndexSignal> {
         status: 'rebuild-required',
         confidence: 'low',
         action: 'rebuild-failed',
-        reason: `Auto-heal failed: ${indexState.error || reason}`
+        reason: `Auto-heal failed: ${project.indexState.error || reason}`
       };
     }
 
@@ -172,6 +379,31 @@ async function ensureValidIndexOrAutoHeal(): Promise<IndexSignal> {
   }
 }
 
+/**
+ * Re-index the files a branch switch changed, so results don't come from the branch that was
+ * checked out when the index was built; unchanged files stay shared. Null when HEAD is still
+ * on the indexed branch (or the branches hold identical files).
+ */
+async function refreshAfterBranchSwitch(
+  project: ProjectRuntime
+): Promise<{ reason: string; refreshed: boolean } | null> {
+  const branchSwitch = await detectBranchSwitch(project.rootPath, project.indexedHead);
+  if (!branchSwitch) return null;
+  if (branchSwitch.changedPaths?.length === 0) {
+    project.indexedHead = branchSwitch.to;
+    return null;
+  }
+
+  const reason = describeBranchSwitch(branchSwitch);
+  if (project.pathPolicy.readOnly) {
+    return { reason: `${reason} (read-only: not re-indexed)`, refreshed: false };
+  }
+  const count = branchSwitch.changedPaths?.length;
+  console.error(`[Index] ${reason}: re-indexing ${count ?? 'all changed'} file(s)`);
+  await performIndexing(true, branchSwitch.changedPaths, project);
+  return { reason, refreshed: project.indexState.status === 'ready' };
+}
+
 /**
  * Check if file/directory exists
  */
@@ -188,16 +420,17 @@ async function fileExists(filePath: string): Promise<boolean> {
  * Migrate legacy file structure to .codebase-context/ folder.
  * Idempotent, fail-safe. Rollback compatibility is not required.
  */
-async function migrateToNewStructure(): Promise<boolean> {
+async function migrateToNewStructure(project: ProjectRuntime): Promise<boolean> {
+  const { paths, legacyPaths } = project;
   let migrated = false;
 
   try {
-    await fs.mkdir(PATHS.baseDir, { recursive: true });
+    await fs.mkdir(paths.baseDir, { recursive: true });
 
     // intelligence.json
-    if (!(await fileExists(PATHS.intelligence))) {
-      if (await fileExists(LEGACY_PATHS.intelligence)) {
-        await fs.copyFile(LEGACY_PATHS.intelligence, PATHS.intelligence);
+    if (!(await fileExists(paths.intelligence))) {
+      if (await fileExists(legacyPaths.intelligence)) {
+        await fs.copyFile(legacyPaths.intelligence, paths.intelligence);
         migrated = true;
         if (process.env.CODEBASE_CONTEXT_DEBUG) {
           console.error('[DEBUG] Migrated intelligence.json');
@@ -206,9 +439,9 @@ async function migrateToNewStructure(): Promise<boolean> {
     }
 
     // index.json (keyword index)
-    if (!(await fileExists(PATHS.keywordIndex))) {
-      if (await fileExists(LEGACY_PATHS.keywordIndex)) {
-        await fs.copyFile(LEGACY_PATHS.keywordIndex, PATHS.keywordIndex);
+    if (!(await fileExists(paths.keywordIndex))) {
+      if (await fileExists(legacyPaths.keywordIndex)) {
+        await fs.copyFile(legacyPaths.keywordIndex, paths.keywordIndex);
         migrated = true;
         if (process.env.CODEBASE_CONTEXT_DEBUG) {
           console.error('[DEBUG] Migrated index.json');
@@ -217,9 +450,9 @@ async function migrateToNewStructure(): Promise<boolean> {
     }
 
     // Vector DB directory
-    if (!(await fileExists(PATHS.vectorDb))) {
-      if (await fileExists(LEGACY_PATHS.vectorDb)) {
-        await fs.rename(LEGACY_PATHS.vectorDb, PATHS.vectorDb);
+    if (!(await fileExists(paths.vectorDb))) {
+      if (await fileExists(legacyPaths.vectorDb)) {
+        await fs.rename(legacyPaths.vectorDb, paths.vectorDb);
         migrated = true;
         if (process.env.CODEBASE_CONTEXT_DEBUG) {
           console.error('[DEBUG] Migrated vector database');
@@ -249,29 +482,12 @@ const PKG_VERSION: string = JSON.parse(
   await fs.readFile(new URL('../package.json', import.meta.url), 'utf-8')
 ).version;
 
-const indexState: IndexState = {
-  status: 'idle'
+const handleListTools = async () => {
+  const readOnly = PROJECTS.every((p) => p.pathPolicy.readOnly);
+  const tools = withProjectParam(visibleTools(TOOLS, readOnly), PROJECTS.map((p) => p.name));
+  return { tools: withFormatParam(tools) };
 };
 
-const autoRefresh = createAutoRefreshController();
-
-const server: Server = new Server(
-  {
-    name: 'codebase-context',
-    version: PKG_VERSION
-  },
-  {
-    capabilities: {
-      tools: {},
-      resources: {}
-    }
-  }
-);
-
-server.setRequestHandler(ListToolsRequestSchema, async () => {
-  return { tools: TOOLS };
-});
-
 // MCP Resources - Proactive context injection
 const RESOURCES: Resource[] = [
   {
@@ -281,12 +497,94 @@ const RESOURCES: Resource[] = [
       'Automatic codebase context: libraries used, team patterns, and conventions. ' +
       'Read this BEFORE generating code to follow team standards.',
     mimeType: 'text/plain'
+  },
+  {
+    uri: REPO_MAP_RESOURCE_URI,
+    name: 'Repo Map',
+    description:
+      "Directory tree with each file's top-level symbols, trimmed to a token budget " +
+      `(default ${DEFAULT_REPO_MAP_TOKENS}). Read it for the overall structure before searching.`,
+    mimeType: 'text/plain'
+  },
+  {
+    uri: MODULES_RESOURCE_URI,
+    name: 'Module cards',
+    description:
+      'Directories of the project with their file counts and module card URIs. A card ' +
+      'sums up a directory: purpose, key exports, dependencies, dependents and test coverage.',
+    mimeType: 'application/json'
   }
 ];
 
-server.setRequestHandler(ListResourcesRequestSchema, async () => {
-  return { resources: RESOURCES };
-});
+const RESOURCE_TEMPLATES = [
+  {
+    uriTemplate: REPO_MAP_URI_TEMPLATE,
+    name: 'Repo Map (custom budget)',
+    description: 'Repo map with a `tokens` budget and an optional `path` directory scope.',
+    mimeType: 'text/plain'
+  },
+  {
+    uriTemplate: MODULES_URI_TEMPLATE,
+    name: 'Module cards (next page)',
+    description: 'Further pages of the directory list, by the `cursor` a page returns.',
+    mimeType: 'application/json'
+  },
+  {
+    uriTemplate: MODULE_CARD_URI_TEMPLATE,
+    name: 'Module card',
+    description:
+      "A directory's card: purpose, key exports, what it depends on and what uses it, and " +
+      'how many of its files have tests. Read it before working in an unfamiliar area.',
+    mimeType: 'application/json'
+  },
+  {
+    uriTemplate: FILE_URI_TEMPLATE,
+    name: 'Indexed file',
+    description: 'Current content of an indexed file, by repo-relative path.',
+    mimeType: 'text/plain'
+  },
+  {
+    uriTemplate: FILE_CHUNKS_URI_TEMPLATE,
+    name: 'Chunks of a file',
+    description: "A file's indexed chunks with line ranges and URIs, paged by `cursor`.",
+    mimeType: 'application/json'
+  },
+  {
+    uriTemplate: FILE_CHUNK_URI_TEMPLATE,
+    name: 'Chunk',
+    description: 'One chunk of a file (0-based, in line order), as listed by `?chunks`.',
+    mimeType: 'text/plain'
+  }
+];
+
+// The fixed resources lead the first page; indexed files follow, paged by cursor
+const handleListResources = async (request: ListResourcesRequest) => {
+  const cursor = request.params?.cursor;
+  const files = await listFileResources(PRIMARY_PROJECT.rootPath, cursor, (file) =>
+    PRIMARY_PROJECT.pathPolicy.allows(file)
+  );
+  return {
+    resources: cursor ? files.resources : [...RESOURCES, ...files.resources],
+    ...(files.nextCursor && { nextCursor: files.nextCursor })
+  };
+};
+
+const handleListResourceTemplates = async () => {
+  return { resourceTemplates: RESOURCE_TEMPLATES };
+};
+
+async function generateRepoMap(query: RepoMapQuery): Promise<string> {
+  const index = await ensureValidIndexOrAutoHeal();
+  if (index.status === 'indexing' || index.action === 'rebuild-failed') {
+    return `# Repo map\n\nIndex not available (${index.status}, ${index.action}). Retry shortly.`;
+  }
+  const map = await buildRepoMap(PRIMARY_PROJECT.rootPath, {
+    tokenBudget: query.tokens,
+    scope: query.path,
+    include: (file) => PRIMARY_PROJECT.pathPolicy.allows(file)
+  });
+  return map?.text ?? '# Repo map\n\nNo index found. Run indexing first.';
+}
 
 async function generateCodebaseContext(): Promise<string> {
   const intelligencePath = PATHS.intelligence;
@@ -456,7 +754,7 @@ async function generateCodebaseContext(): Promise<string> {
   }
 }
 
-server.setRequestHandler(ReadResourceRequestSchema, async (request) => {
+const handleReadResource = async (request: ReadResourceRequest) => {
   const uri = request.params.uri;
 
   if (isContextResourceUri(uri)) {
@@ -473,16 +771,49 @@ server.setRequestHandler(ReadResourceRequestSchema, async (request) => {
     };
   }
 
+  const repoMapQuery = parseRepoMapUri(uri);
+  if (repoMapQuery) {
+    return {
+      contents: [{ uri, mimeType: 'text/plain', text: await generateRepoMap(repoMapQuery) }]
+    };
+  }
+
+  const allows = (file: string) => PRIMARY_PROJECT.pathPolicy.allows(file);
+  const modulesQuery = parseModulesUri(uri);
+  if (modulesQuery) {
+    return {
+      contents: await readModuleList(PRIMARY_PROJECT.rootPath, uri, modulesQuery.cursor, allows)
+    };
+  }
+
+  const moduleDir = parseModuleCardUri(uri);
+  if (moduleDir) {
+    return {
+      contents: await readModuleCard(PRIMARY_PROJECT.rootPath, uri, moduleDir, {
+        include: allows,
+        persist: !PRIMARY_PROJECT.pathPolicy.readOnly
+      })
+    };
+  }
+
+  const fileQuery = parseFileResourceUri(uri);
+  if (fileQuery) {
+    if (!PRIMARY_PROJECT.pathPolicy.allows(fileQuery.path)) {
+      throw new Error(`'${fileQuery.path}' is outside the paths this server exposes.`);
+    }
+    return { contents: await readFileResource(PRIMARY_PROJECT.rootPath, uri, fileQuery) };
+  }
+
   throw new Error(`Unknown resource: ${uri}`);
-});
+};
 
 /**
  * Extract memories from conventional git commits (refactor:, migrate:, fix:, revert:).
  * Scans last 90 days. Deduplicates via content hash. Zero friction alternative to manual memory.
  */
-async function extractGitMemories(): Promise<number> {
+async function extractGitMemories(project: ProjectRuntime): Promise<number> {
   // Quick check: skip if not a git repo
-  if (!(await fileExists(path.join(ROOT_PATH, '.git')))) return 0;
+  if (!(await fileExists(path.join(project.rootPath, '.git')))) return 0;
 
   const { execSync } = await import('child_process');
 
@@ -490,7 +821,7 @@ async function extractGitMemories(): Promise<number> {
   try {
     // Format: ISO-date<TAB>hash subject  (e.g. "2026-01-15T10:00:00+00:00\tabc1234 fix: race condition")
     log = execSync('git log --format="%aI\t%h %s" --since="90 days ago" --no-merges', {
-      cwd: ROOT_PATH,
+      cwd: project.rootPath,
       encoding: 'utf-8',
       timeout: 5000
     }).trim();
@@ -508,24 +839,64 @@ async function extractGitMemories(): Promise<number> {
     const parsedMemory = parseGitLogLineToMemory(line);
     if (!parsedMemory) continue;
 
-    const result = await appendMemoryFile(PATHS.memory, parsedMemory);
+    const result = await appendMemoryFile(project.paths.memory, parsedMemory);
     if (result.status === 'added') added++;
   }
 
   return added;
 }
 
-async function performIndexingOnce(incrementalOnly?: boolean): Promise<void> {
+/** One build, stoppable through `project.activeBuild` as well as the caller's signal */
+async function performIndexingOnce(
+  project: ProjectRuntime,
+  incrementalOnly?: boolean,
+  changedPaths?: string[],
+  hooks?: IndexingHooks
+): Promise<void> {
+  const { controller, release } = linkedAbortController([hooks?.signal]);
+  const done = runIndexBuild(project, incrementalOnly, changedPaths, {
+    ...hooks,
+    signal: controller.signal
+  });
+  project.activeBuild = { controller, done };
+  try {
+    await done;
+  } finally {
+    release();
+    project.activeBuild = undefined;
+  }
+}
+
+/** Stop a running build and wait (bounded) for it to leave its checkpoint */
+async function stopIndexBuild(project: ProjectRuntime, waitMs = 5000): Promise<void> {
+  const build = project.activeBuild;
+  if (!build) return;
+  build.controller.abort();
+  await Promise.race([build.done, new Promise((resolve) => setTimeout(resolve, waitMs).unref())]);
+}
+
+async function runIndexBuild(
+  project: ProjectRuntime,
+  incrementalOnly?: boolean,
+  changedPaths?: string[],
+  hooks?: IndexingHooks
+): Promise<void> {
+  const { indexState } = project;
+  const statusBefore = indexState.status;
   indexState.status = 'indexing';
+  const head = await readGitHead(project.rootPath);
   const mode = incrementalOnly ? 'incremental' : 'full';
-  console.error(`Indexing (${mode}): ${ROOT_PATH}`);
+  console.error(`Indexing (${mode}): ${project.rootPath}`);
 
   try {
     let lastLoggedProgress = { phase: '', percentage: -1 };
     const indexer = new CodebaseIndexer({
-      rootPath: ROOT_PATH,
+      rootPath: project.rootPath,
       incrementalOnly,
+      changedPaths: incrementalOnly ? changedPaths : undefined,
+      signal: hooks?.signal,
       onProgress: (progress) => {
+        hooks?.onProgress?.(progress);
         // Only log when phase or percentage actually changes (prevents duplicate logs)
         const shouldLog =
           progress.phase !== lastLoggedProgress.phase ||
@@ -544,6 +915,7 @@ async function performIndexingOnce(incrementalOnly?: boolean): Promise<void> {
     indexState.status = 'ready';
     indexState.lastIndexed = new Date();
     indexState.stats = stats;
+    project.indexedHead = head ?? undefined;
 
     console.error(
       `Complete: ${stats.indexedFiles} files, ${stats.totalChunks} chunks in ${(
@@ -553,7 +925,7 @@ async function performIndexingOnce(incrementalOnly?: boolean): Promise<void> {
 
     // Auto-extract memories from git history (non-blocking, best-effort)
     try {
-      const gitMemories = await extractGitMemories();
+      const gitMemories = await extractGitMemories(project);
       if (gitMemories > 0) {
         console.error(
           `[git-memory] Extracted ${gitMemories} new memor${gitMemories === 1 ? 'y' : 'ies'} from git history`
@@ -563,29 +935,85 @@ async function performIndexingOnce(incrementalOnly?: boolean): Promise<void> {
       // Git memory extraction is optional — never fail indexing over it
     }
   } catch (error) {
+    if (error instanceof IndexingCancelledError) {
+      // Nothing was written; the previous index (if any) is still valid
+      indexState.status = statusBefore === 'ready' || indexState.lastIndexed ? 'ready' : 'idle';
+      console.error('Indexing cancelled; the next start resumes it');
+      return;
+    }
     indexState.status = 'error';
     indexState.error = error instanceof Error ? error.message : String(error);
     console.error('Indexing failed:', indexState.error);
   }
 }
 
-async function performIndexing(incrementalOnly?: boolean): Promise<void> {
+async function performIndexing(
+  incrementalOnly?: boolean,
+  changedPaths?: string[],
+  project: ProjectRuntime = PRIMARY_PROJECT,
+  hooks?: IndexingHooks
+): Promise<void> {
+  // Read-only servers never write the index; it is built ahead of time
+  if (project.pathPolicy.readOnly) {
+    if (project.indexState.status !== 'ready') {
+      project.indexState.status = 'error';
+      project.indexState.error = READ_ONLY_NO_INDEX;
+    }
+    return;
+  }
   let nextMode = incrementalOnly;
+  let nextChangedPaths = changedPaths;
+  let nextHooks = hooks;
   for (;;) {
-    await performIndexingOnce(nextMode);
+    await performIndexingOnce(project, nextMode, nextChangedPaths, nextHooks);
+    // Queued follow-up refreshes belong to no caller
+    nextHooks = undefined;
 
-    const shouldRunQueuedRefresh = autoRefresh.consumeQueuedRefresh(indexState.status);
+    const shouldRunQueuedRefresh = project.autoRefresh.consumeQueuedRefresh(
+      project.indexState.status
+    );
+    const queuedPaths = project.autoRefresh.takeQueuedPaths();
     if (!shouldRunQueuedRefresh) return;
 
     if (process.env.CODEBASE_CONTEXT_DEBUG) {
       console.error('[file-watcher] Running queued auto-refresh');
     }
     nextMode = true;
+    nextChangedPaths = queuedPaths;
+  }
+}
+
+/** Scheduled garbage collection (CODEBASE_CONTEXT_GC_INTERVAL_MINUTES) for one project */
+async function reconcileProject(project: ProjectRuntime): Promise<void> {
+  if (project.indexState.status !== 'ready' || project.pathPolicy.readOnly) return;
+
+  // Reported as indexing so watcher changes queue up instead of racing the cleanup
+  project.indexState.status = 'indexing';
+  let droppedChangedFiles = false;
+  try {
+    const report = await reconcileIndex(project.rootPath);
+    droppedChangedFiles = report.changedFiles.length > 0;
+    if (process.env.CODEBASE_CONTEXT_DEBUG) {
+      console.error(
+        `[gc] ${project.name}: ${report.removedChunks} chunks, ${report.removedVectors} vectors removed`
+      );
+    }
+  } catch (error) {
+    console.error('[gc] Reconciliation failed:', error instanceof Error ? error.message : error);
+  } finally {
+    project.indexState.status = 'ready';
+  }
+
+  // Changed files lost their manifest entry; an incremental run re-adds them
+  const queued = project.autoRefresh.consumeQueuedRefresh('ready');
+  if (queued || droppedChangedFiles) {
+    const queuedPaths = queued ? project.autoRefresh.takeQueuedPaths() : undefined;
+    await performIndexing(true, queuedPaths, project);
   }
 }
 
-async function shouldReindex(): Promise<boolean> {
-  const indexPath = PATHS.keywordIndex;
+async function shouldReindex(project: ProjectRuntime = PRIMARY_PROJECT): Promise<boolean> {
+  const indexPath = project.paths.keywordIndex;
   try {
     await fs.access(indexPath);
     return false;
@@ -594,79 +1022,399 @@ async function shouldReindex(): Promise<boolean> {
   }
 }
 
-server.setRequestHandler(CallToolRequestSchema, async (request) => {
-  const { name, arguments: args } = request.params;
+/**
+ * Serve another root for the rest of the session (index_remote). A root that is already a
+ * project is reused; new ones get a unique name and are indexed in the background when asked
+ * or when they have no index yet.
+ */
+async function addProject(
+  entry: WorkspaceProject,
+  options: { reindex?: boolean } = {}
+): Promise<ProjectRuntime> {
+  const rootPath = normalizeRootPath(entry.rootPath);
+  let project = PROJECTS.find((p) => p.rootPath === rootPath);
+  if (!project) {
+    const named = buildWorkspaceProjects([...PROJECTS, { name: entry.name, rootPath }]);
+    project = createProjectRuntime(named[named.length - 1]);
+    project.pathPolicy = await loadPathPolicy(rootPath);
+    PROJECTS.push(project);
+  }
 
-  try {
-    // Gate INDEX_CONSUMING tools on a valid, healthy index
-    let indexSignal: IndexSignal | undefined;
-    if ((INDEX_CONSUMING_TOOL_NAMES as readonly string[]).includes(name)) {
-      if (indexState.status === 'indexing') {
-        return {
-          content: [
-            {
-              type: 'text',
-              text: JSON.stringify({
-                status: 'indexing',
-                message: 'Index build in progress — please retry shortly'
+  if (project.indexState.status === 'indexing') return project;
+  if (options.reindex || (await shouldReindex(project))) {
+    void performIndexing(undefined, undefined, project);
+  } else if (project.indexState.status !== 'ready') {
+    project.indexState.status = 'ready';
+    project.indexState.lastIndexed = new Date();
+    project.indexedHead = (await readIndexMeta(rootPath)).head;
+  }
+  return project;
+}
+
+async function callProjectTool(
+  project: ProjectRuntime,
+  name: string,
+  args: Record<string, unknown>,
+  progress?: IndexingHooks,
+  sample?: Sampler,
+  signal?: AbortSignal
+): Promise<ToolResponse> {
+  const { indexState } = project;
+
+  // No index yet: partial-result tools search what the first build has parsed so far
+  const servesPartial =
+    indexState.status === 'indexing' &&
+    !indexState.lastIndexed &&
+    (PARTIAL_RESULT_TOOL_NAMES as readonly string[]).includes(name);
+
+  // Gate INDEX_CONSUMING tools on a valid, healthy index
+  let indexSignal: IndexSignal | undefined;
+  if ((INDEX_CONSUMING_TOOL_NAMES as readonly string[]).includes(name) && !servesPartial) {
+    if (indexState.status === 'indexing') {
+      return {
+        content: [
+          {
+            type: 'text',
+            text: JSON.stringify({
+              status: 'indexing',
+              message: 'Index build in progress — please retry shortly',
+              ...(indexState.indexer && {
+                readiness: overallProgress(indexState.indexer.getProgress())
               })
-            }
-          ]
-        };
+            })
+          }
+        ]
+      };
+    }
+    if (indexState.status === 'error') {
+      return {
+        content: [
+          {
+            type: 'text',
+            text: JSON.stringify({
+              status: 'error',
+              message: `Indexer error: ${indexState.error}`
+            })
+          }
+        ]
+      };
+    }
+    indexSignal = await ensureValidIndexOrAutoHeal(project);
+    if (indexSignal.action === 'rebuild-failed') {
+      return {
+        content: [
+          {
+            type: 'text',
+            text: JSON.stringify({
+              error: 'Index is corrupt and could not be rebuilt automatically.',
+              index: indexSignal
+            })
+          }
+        ],
+        isError: true
+      };
+    }
+  }
+
+  const ctx: ToolContext = {
+    indexState,
+    paths: project.paths,
+    rootPath: project.rootPath,
+    performIndexing: (incrementalOnly, _reason, hooks) =>
+      performIndexing(incrementalOnly, undefined, project, hooks),
+    progress,
+    signal,
+    projectName: project.name,
+    projects: PROJECTS,
+    sample,
+    pathPolicy: project.pathPolicy,
+    addProject
+  };
+
+  const result = await dispatchTool(name, args, ctx);
+
+  // Inject IndexSignal into response so callers can inspect index health
+  if (indexSignal !== undefined && result.content?.[0]) {
+    try {
+      const parsed = JSON.parse(result.content[0].text);
+      result.content[0] = {
+        type: 'text',
+        text: JSON.stringify({ ...parsed, index: indexSignal })
+      };
+    } catch {
+      /* response wasn't JSON, skip injection */
+    }
+  }
+
+  return result;
+}
+
+/**
+ * search_codebase across several projects: results are merged by score and tagged with
+ * their project. Per-project decision cards are dropped to keep the payload small.
+ */
+async function searchAllProjects(
+  projects: ProjectRuntime[],
+  args: Record<string, unknown>,
+  signal?: AbortSignal
+): Promise<ToolResponse> {
+  const limit =
+    typeof args.limit === 'number' && Number.isFinite(args.limit) && args.limit > 0
+      ? Math.floor(args.limit)
+      : 5;
+  const groups: Array<{ project: string; results: SearchResultItem[] }> = [];
+  const summaries: Array<{ project: string; status: string; totalResults: number }> = [];
+
+  for (const project of projects) {
+    if (signal?.aborted) break;
+    const result = await callProjectTool(
+      project,
+      'search_codebase',
+      args,
+      undefined,
+      undefined,
+      signal
+    );
+    let parsed: { status?: string; results?: SearchResultItem[] } = {};
+    try {
+      parsed = JSON.parse(result.content?.[0]?.text ?? '{}') as typeof parsed;
+    } catch {
+      parsed = { status: 'error' };
+    }
+    const results = Array.isArray(parsed.results) ? parsed.results : [];
+    groups.push({ project: project.name, results });
+    summaries.push({
+      project: project.name,
+      status: result.isError ? 'error' : (parsed.status ?? 'error'),
+      totalResults: results.length
+    });
+  }
+
+  const results = mergeProjectResults(groups, limit);
+  return {
+    content: [
+      {
+        type: 'text',
+        text: JSON.stringify(
+          {
+            status: 'success',
+            project: ALL_PROJECTS_SELECTOR,
+            projects: summaries,
+            results,
+            totalResults: results.length
+          },
+          null,
+          2
+        )
       }
-      if (indexState.status === 'error') {
-        return {
-          content: [
-            {
-              type: 'text',
-              text: JSON.stringify({
-                status: 'error',
-                message: `Indexer error: ${indexState.error}`
+    ]
+  };
+}
+
+/**
+ * find_references across several projects: each project's index is searched, then hits are
+ * kept for the projects defining the symbol and those depending on them (see fleet-graph.ts).
+ */
+async function findReferencesAllProjects(
+  projects: ProjectRuntime[],
+  args: Record<string, unknown>,
+  signal?: AbortSignal
+): Promise<ToolResponse> {
+  const limit =
+    typeof args.limit === 'number' && Number.isFinite(args.limit) && args.limit > 0
+      ? Math.min(Math.floor(args.limit), 100)
+      : 20;
+  const perProject: Array<{
+    project: string;
+    definitions: string[];
+    references: ReferenceLocation[];
+  }> = [];
+  const summaries: Array<{ project: string; status: string; totalReferences: number }> = [];
+
+  for (const project of projects) {
+    if (signal?.aborted) break;
+    const result = await callProjectTool(
+      project,
+      'find_references',
+      { ...args, limit },
+      undefined,
+      undefined,
+      signal
+    );
+    let parsed: {
+      status?: string;
+      totalReferences?: number;
+      definitions?: string[];
+      references?: ReferenceLocation[];
+    } = {};
+    try {
+      parsed = JSON.parse(result.content?.[0]?.text ?? '{}') as typeof parsed;
+    } catch {
+      parsed = { status: 'error' };
+    }
+    perProject.push({
+      project: project.name,
+      definitions: Array.isArray(parsed.definitions) ? parsed.definitions : [],
+      references: Array.isArray(parsed.references) ? parsed.references : []
+    });
+    summaries.push({
+      project: project.name,
+      status: result.isError ? 'error' : (parsed.status ?? 'error'),
+      totalReferences: parsed.totalReferences ?? 0
+    });
+  }
+
+  const graph = await buildFleetGraph(projects);
+  const joined = joinFleetReferences(graph, perProject, limit);
+  return {
+    content: [
+      {
+        type: 'text',
+        text: JSON.stringify(
+          {
+            status: 'success',
+            project: ALL_PROJECTS_SELECTOR,
+            symbol: typeof args.symbol === 'string' ? args.symbol.trim() : args.symbol,
+            definedIn: joined.definedIn,
+            projects: summaries,
+            references: joined.references,
+            totalReferences: joined.references.length,
+            ...(joined.unlinked.length > 0 && {
+              unlinked: joined.unlinked,
+              hint:
+                'Projects under unlinked use the same name but declare no dependency on a ' +
+                'project defining it; scope find_references to one of them to see its hits.'
+            })
+          },
+          null,
+          2
+        )
+      }
+    ]
+  };
+}
+
+/** Route sampling through the client that made the call, if it advertised the capability. */
+function createClientSampler(
+  instance: Server | undefined,
+  extra: RequestHandlerExtra<ServerRequest, ServerNotification> | undefined
+): Sampler | undefined {
+  if (!instance?.getClientCapabilities()?.sampling || !extra) return undefined;
+  return async ({ systemPrompt, prompt, maxTokens }) => {
+    const result = await extra.sendRequest(
+      {
+        method: 'sampling/createMessage',
+        params: {
+          messages: [{ role: 'user', content: { type: 'text', text: prompt } }],
+          systemPrompt,
+          maxTokens,
+          includeContext: 'none'
+        }
+      },
+      CreateMessageResultSchema,
+      { signal: extra.signal }
+    );
+    const blocks = Array.isArray(result.content) ? result.content : [result.content];
+    return blocks.flatMap((block) => (block.type === 'text' ? [block.text] : [])).join('\n');
+  };
+}
+
+const handleCallTool = async (
+  request: CallToolRequest,
+  extra?: RequestHandlerExtra<ServerRequest, ServerNotification>,
+  instance?: Server
+): Promise<ToolResponse> => {
+  const { name, arguments: rawArgs } = request.params;
+  const { format, ...args } = rawArgs ?? {};
+  if (format !== undefined && !isOutputFormat(format)) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify({
+            status: 'error',
+            message: `Unknown format '${String(format)}'. Use one of: ${OUTPUT_FORMATS.join(', ')}`
+          })
+        }
+      ],
+      isError: true
+    };
+  }
+
+  const response = await callRequestedTool(name, args, request, extra, instance);
+  return format === undefined ? response : formatToolResponse(name, response, format);
+};
+
+const callRequestedTool = async (
+  name: string,
+  rawArgs: Record<string, unknown>,
+  request: CallToolRequest,
+  extra?: RequestHandlerExtra<ServerRequest, ServerNotification>,
+  instance?: Server
+): Promise<ToolResponse> => {
+  const { project: projectSelector, ...args } = rawArgs;
+
+  // Clients that send a progressToken get notifications/progress and can cancel the request
+  const progressToken = request.params._meta?.progressToken;
+  const progress: IndexingHooks | undefined =
+    progressToken !== undefined
+      ? {
+          signal: extra?.signal,
+          onProgress: createProgressReporter((update) => {
+            void extra
+              ?.sendNotification({
+                method: 'notifications/progress',
+                params: { progressToken, ...update }
               })
-            }
-          ]
-        };
+              .catch(() => {
+                /* client went away; indexing carries on */
+              });
+          })
+        }
+      : undefined;
+
+  try {
+    const selected = selectProjects(PROJECTS, projectSelector);
+    if (!selected) {
+      const available = PROJECTS.map((p) => p.name).join(', ');
+      return {
+        content: [
+          {
+            type: 'text',
+            text: JSON.stringify({
+              status: 'error',
+              message: `Unknown project '${String(projectSelector)}'. Available: ${available}`
+            })
+          }
+        ],
+        isError: true
+      };
+    }
+
+    if (selected.length > 1) {
+      if (name === 'find_references') {
+        return await findReferencesAllProjects(selected, args, extra?.signal);
       }
-      indexSignal = await ensureValidIndexOrAutoHeal();
-      if (indexSignal.action === 'rebuild-failed') {
+      if (name !== 'search_codebase') {
         return {
           content: [
             {
               type: 'text',
               text: JSON.stringify({
-                error: 'Index is corrupt and could not be rebuilt automatically.',
-                index: indexSignal
+                status: 'error',
+                message: `project="${ALL_PROJECTS_SELECTOR}" is only supported by search_codebase and find_references. Pass a single project name.`
               })
             }
           ],
           isError: true
         };
       }
+      return await searchAllProjects(selected, args, extra?.signal);
     }
 
-    const ctx: ToolContext = {
-      indexState,
-      paths: PATHS,
-      rootPath: ROOT_PATH,
-      performIndexing
-    };
-
-    const result = await dispatchTool(name, args ?? {}, ctx);
-
-    // Inject IndexSignal into response so callers can inspect index health
-    if (indexSignal !== undefined && result.content?.[0]) {
-      try {
-        const parsed = JSON.parse(result.content[0].text);
-        result.content[0] = {
-          type: 'text',
-          text: JSON.stringify({ ...parsed, index: indexSignal })
-        };
-      } catch {
-        /* response wasn't JSON, skip injection */
-      }
-    }
-
-    return result;
+    const sample = createClientSampler(instance, extra);
+    // notifications/cancelled aborts this signal whether or not progress was requested
+    return await callProjectTool(selected[0], name, args, progress, sample, extra?.signal);
   } catch (error) {
     return {
       content: [
@@ -678,28 +1426,61 @@ server.setRequestHandler(CallToolRequestSchema, async (request) => {
       isError: true
     };
   }
-});
+};
+
+/**
+ * Build an MCP server with every handler registered. stdio uses a single instance; the HTTP
+ * transport creates one per client session, all sharing the same project state and indexes.
+ */
+function createServer(): Server {
+  const instance = new Server(
+    {
+      name: 'codebase-context',
+      version: PKG_VERSION
+    },
+    {
+      capabilities: {
+        tools: {},
+        resources: {}
+      }
+    }
+  );
+  instance.setRequestHandler(ListToolsRequestSchema, handleListTools);
+  instance.setRequestHandler(ListResourcesRequestSchema, handleListResources);
+  instance.setRequestHandler(ListResourceTemplatesRequestSchema, handleListResourceTemplates);
+  instance.setRequestHandler(ReadResourceRequestSchema, handleReadResource);
+  instance.setRequestHandler(CallToolRequestSchema, (request, extra) =>
+    handleCallTool(request, extra, instance)
+  );
+  return instance;
+}
+
+const server: Server = createServer();
+
+async function prepareProject(project: ProjectRuntime): Promise<void> {
+  const { rootPath } = project;
 
-async function main() {
   // Validate root path exists and is a directory
   try {
-    const stats = await fs.stat(ROOT_PATH);
+    const stats = await fs.stat(rootPath);
     if (!stats.isDirectory()) {
-      console.error(`ERROR: Root path is not a directory: ${ROOT_PATH}`);
+      console.error(`ERROR: Root path is not a directory: ${rootPath}`);
       console.error(`Please specify a valid project directory.`);
       process.exit(1);
     }
   } catch (_error) {
-    console.error(`ERROR: Root path does not exist: ${ROOT_PATH}`);
+    console.error(`ERROR: Root path does not exist: ${rootPath}`);
     console.error(`Please specify a valid project directory.`);
     process.exit(1);
   }
 
-  // Migrate legacy structure before server starts
+  project.pathPolicy = await loadPathPolicy(rootPath);
+
+  // Migrate legacy structure before server starts (it moves files, so not when read-only)
   try {
-    const migrated = await migrateToNewStructure();
+    const migrated = !project.pathPolicy.readOnly && (await migrateToNewStructure(project));
     if (migrated && process.env.CODEBASE_CONTEXT_DEBUG) {
-      console.error('[DEBUG] Migrated to .codebase-context/ structure');
+      console.error(`[DEBUG] Migrated ${project.name} to .codebase-context/ structure`);
     }
   } catch (error) {
     // Non-fatal: continue with current paths
@@ -708,10 +1489,66 @@ async function main() {
     }
   }
 
+  // Check for package.json to confirm it's a project root (guarded to avoid stderr during handshake)
+  if (process.env.CODEBASE_CONTEXT_DEBUG) {
+    try {
+      await fs.access(path.join(rootPath, 'package.json'));
+      console.error(`[DEBUG] Project detected: ${project.name} (${rootPath})`);
+    } catch {
+      console.error(`[DEBUG] WARNING: No package.json found in ${rootPath}.`);
+    }
+  }
+}
+
+function watchProject(project: ProjectRuntime, debounceMs: number): () => void {
+  return startFileWatcher({
+    rootPath: project.rootPath,
+    debounceMs,
+    onChanged: (changedPaths) => {
+      const shouldRunNow = project.autoRefresh.onFileChange(
+        project.indexState.status === 'indexing',
+        changedPaths
+      );
+      if (!shouldRunNow) {
+        if (process.env.CODEBASE_CONTEXT_DEBUG) {
+          console.error('[file-watcher] Index in progress — queueing auto-refresh');
+        }
+        return;
+      }
+      if (process.env.CODEBASE_CONTEXT_DEBUG) {
+        console.error(
+          `[file-watcher] ${changedPaths.length} change(s) detected in ${project.name} — incremental reindex starting`
+        );
+      }
+      void performIndexing(true, changedPaths, project);
+    }
+  });
+}
+
+async function main() {
+  // Validate transport settings before any indexing starts
+  const httpConfig = resolveHttpTransportConfig();
+  const daemonRole = resolveDaemonRole();
+  const daemonSocket = resolveDaemonSocketPath(PROJECTS.map((project) => project.rootPath));
+
+  if (daemonRole === 'shim') {
+    // The daemon owns the index; this process only relays stdio to it
+    await runDaemonShim({ socketPath: daemonSocket, startDaemon: () => spawnDetachedDaemon() });
+    process.exit(0);
+  }
+
+  // Languages added by grammar plugins are detected and parsed from the first build on
+  await loadGrammarPlugins();
+  for (const project of PROJECTS) {
+    await prepareProject(project);
+  }
+
   // Server startup banner (guarded to avoid stderr during MCP STDIO handshake)
   if (process.env.CODEBASE_CONTEXT_DEBUG) {
     console.error('[DEBUG] Codebase Context MCP Server');
-    console.error(`[DEBUG] Root: ${ROOT_PATH}`);
+    for (const project of PROJECTS) {
+      console.error(`[DEBUG] Root: ${project.rootPath} (${project.name})`);
+    }
     console.error(
       `[DEBUG] Analyzers: ${analyzerRegistry
         .getAll()
@@ -720,66 +1557,139 @@ async function main() {
     );
   }
 
-  // Check for package.json to confirm it's a project root (guarded to avoid stderr during handshake)
-  if (process.env.CODEBASE_CONTEXT_DEBUG) {
-    try {
-      await fs.access(path.join(ROOT_PATH, 'package.json'));
-      console.error(`[DEBUG] Project detected: ${path.basename(ROOT_PATH)}`);
-    } catch {
-      console.error(`[DEBUG] WARNING: No package.json found. This may not be a project root.`);
+  const pendingIndex: ProjectRuntime[] = [];
+  const resumeIncremental = new Set<ProjectRuntime>();
+  for (const project of PROJECTS) {
+    if (await shouldReindex(project)) {
+      pendingIndex.push(project);
+      continue;
+    }
+    // A build cancelled last session (shutdown, client gone): run it again, reusing its vectors
+    const checkpoint = project.pathPolicy.readOnly
+      ? null
+      : await readIndexCheckpoint(project.paths.baseDir);
+    if (checkpoint) {
+      console.error(
+        `[Index] Resuming the ${checkpoint.mode} build of ${project.name} stopped while ` +
+          `${checkpoint.phase} (${checkpoint.filesProcessed}/${checkpoint.totalFiles} files)`
+      );
+      project.indexState.status = 'ready';
+      project.indexState.lastIndexed = new Date();
+      project.indexedHead = (await readIndexMeta(project.rootPath)).head;
+      if (checkpoint.mode === 'incremental') resumeIncremental.add(project);
+      pendingIndex.push(project);
+      continue;
+    }
+    // After an upgrade or a model change, rebuild now instead of failing the first query
+    const incompatible = await checkIndexCompatibility(project.rootPath, resolveEmbeddingModel());
+    // A model change keeps serving the old vectors until the new ones are swapped in
+    const migration =
+      incompatible && !project.pathPolicy.readOnly
+        ? await startModelMigration(project.rootPath, resolveEmbeddingModel())
+        : null;
+    if (migration) {
+      const { from, to } = describeModelMigration(migration);
+      console.error(
+        `[Index] Serving ${project.name} from ${from} vectors while re-embedding with ${to} ` +
+          'in the background...'
+      );
+      project.indexState.status = 'ready';
+      project.indexState.lastIndexed = new Date();
+      project.indexedHead = (await readIndexMeta(project.rootPath)).head;
+      pendingIndex.push(project);
+    } else if (incompatible) {
+      console.error(`[Index] ${incompatible}`);
+      console.error(`[Index] Rebuilding ${project.name} in the background...`);
+      pendingIndex.push(project);
+    } else {
+      project.indexState.status = 'ready';
+      project.indexState.lastIndexed = new Date();
+      project.indexedHead = (await readIndexMeta(project.rootPath)).head;
+      // Checked out another branch while the server was down: catch up in the background
+      void refreshAfterBranchSwitch(project);
     }
   }
 
-  const needsIndex = await shouldReindex();
-
-  if (needsIndex) {
+  if (pendingIndex.length > 0) {
     if (process.env.CODEBASE_CONTEXT_DEBUG) console.error('[DEBUG] Starting indexing...');
-    performIndexing();
-  } else {
-    if (process.env.CODEBASE_CONTEXT_DEBUG) console.error('[DEBUG] Index found. Ready.');
-    indexState.status = 'ready';
-    indexState.lastIndexed = new Date();
+    // One project at a time keeps memory and embedding load bounded
+    void (async () => {
+      for (const project of pendingIndex) {
+        await performIndexing(resumeIncremental.has(project) || undefined, undefined, project);
+      }
+    })();
+  } else if (process.env.CODEBASE_CONTEXT_DEBUG) {
+    console.error('[DEBUG] Index found. Ready.');
   }
 
-  const transport = new StdioServerTransport();
-  await server.connect(transport);
+  let stopTransport: (() => Promise<void>) | undefined;
+  if (httpConfig) {
+    const http = await startHttpTransport({
+      ...httpConfig,
+      createServer,
+      metrics: { render: () => metricsRegistry.render(), contentType: METRICS_CONTENT_TYPE }
+    });
+    stopTransport = http.close;
+    console.error(
+      `codebase-context listening on ${http.url}` +
+        (httpConfig.authToken ? ' (bearer auth)' : ' (no auth, loopback only)')
+    );
+  } else if (daemonRole === 'server') {
+    const idleMinutes = resolveDaemonIdleMinutes();
+    const daemon = await startDaemon({
+      socketPath: daemonSocket,
+      createServer,
+      idleMs: idleMinutes * 60_000,
+      // Same cleanup as a signal: stop watchers and the socket, then exit
+      onIdle: () => process.emit('SIGTERM')
+    });
+    stopTransport = daemon.close;
+    console.error(`codebase-context daemon listening on ${daemon.socketPath}`);
+  } else {
+    const transport = new StdioServerTransport();
+    await server.connect(transport);
+    // The client went away: stop builds at a checkpoint and exit instead of running on
+    process.stdin.once('end', () => process.emit('SIGTERM'));
+  }
 
   if (process.env.CODEBASE_CONTEXT_DEBUG) console.error('[DEBUG] Server ready');
 
   // Auto-refresh: watch for file changes and trigger incremental reindex
   const debounceEnv = Number.parseInt(process.env.CODEBASE_CONTEXT_DEBOUNCE_MS ?? '', 10);
   const debounceMs = Number.isFinite(debounceEnv) && debounceEnv >= 0 ? debounceEnv : 2000;
-  const stopWatcher = startFileWatcher({
-    rootPath: ROOT_PATH,
-    debounceMs,
-    onChanged: () => {
-      const shouldRunNow = autoRefresh.onFileChange(indexState.status === 'indexing');
-      if (!shouldRunNow) {
-        if (process.env.CODEBASE_CONTEXT_DEBUG) {
-          console.error('[file-watcher] Index in progress — queueing auto-refresh');
-        }
-        return;
-      }
-      if (process.env.CODEBASE_CONTEXT_DEBUG) {
-        console.error('[file-watcher] Changes detected — incremental reindex starting');
-      }
-      void performIndexing(true);
-    }
-  });
+  const stopWatchers = PROJECTS.filter((project) => !project.pathPolicy.readOnly).map((project) =>
+    watchProject(project, debounceMs)
+  );
+
+  const gcMinutes = resolveGcIntervalMinutes();
+  const gcTimer =
+    gcMinutes > 0
+      ? setInterval(() => {
+          void (async () => {
+            for (const project of PROJECTS) await reconcileProject(project);
+          })();
+        }, gcMinutes * 60_000)
+      : undefined;
+  gcTimer?.unref();
+
+  const stopWatcher = () => {
+    stopWatchers.forEach((stop) => stop());
+    if (gcTimer) clearInterval(gcTimer);
+  };
 
   process.once('exit', stopWatcher);
-  process.once('SIGINT', () => {
+  const shutdown = () => {
     stopWatcher();
-    process.exit(0);
-  });
-  process.once('SIGTERM', () => {
-    stopWatcher();
-    process.exit(0);
-  });
+    void Promise.all([...PROJECTS.map((project) => stopIndexBuild(project)), stopLanguageServers()])
+      .then(() => stopTransport?.())
+      .finally(() => process.exit(0));
+  };
+  process.once('SIGINT', shutdown);
+  process.once('SIGTERM', shutdown);
 }
 
 // Export server components for programmatic use
-export { server, performIndexing, resolveRootPath, shouldReindex, TOOLS };
+export { server, createServer, performIndexing, resolveRootPath, shouldReindex, TOOLS };
 
 // Only auto-start when run directly as CLI (not when imported as module)
 // Check if this module is the entry point
@@ -787,18 +1697,6 @@ const isDirectRun =
   process.argv[1]?.replace(/\\/g, '/').endsWith('index.js') ||
   process.argv[1]?.replace(/\\/g, '/').endsWith('index.ts');
 
-const CLI_SUBCOMMANDS = [
-  'memory',
-  'search',
-  'metadata',
-  'status',
-  'reindex',
-  'style-guide',
-  'patterns',
-  'refs',
-  'cycles'
-];
-
 if (isDirectRun) {
   const subcommand = process.argv[2];
   if (CLI_SUBCOMMANDS.includes(subcommand) || subcommand === '--help') {
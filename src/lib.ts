@@ -39,6 +39,22 @@ import { CodebaseSearcher } from './core/search.js';
 export { AnalyzerRegistry, analyzerRegistry } from './core/analyzer-registry.js';
 import { analyzerRegistry } from './core/analyzer-registry.js';
 
+// Chunk post-processors (transforms on embedding inputs, named in `postProcessing` config)
+export {
+  registerChunkPostProcessor,
+  BUILTIN_CHUNK_POST_PROCESSORS,
+  type ChunkPostProcessor,
+  type ChunkPostProcessorContext
+} from './core/chunk-post-processors.js';
+
+// Grammar plugins (Tree-sitter languages added from a directory or registered in code)
+export {
+  registerGrammarPlugin,
+  loadGrammarPlugins,
+  readGrammarPlugin,
+  type GrammarPlugin
+} from './grammars/plugins.js';
+
 // Embedding providers
 export {
   getEmbeddingProvider,
@@ -0,0 +1,107 @@
+import {
+  CHUNK_PAGE_SIZE,
+  FILE_PAGE_SIZE,
+  describeChunk,
+  loadIndexedFiles,
+  paginate,
+  readIndexedFile
+} from '../core/file-resources.js';
+import { fileResourceUri, type FileResourceQuery } from './uri.js';
+
+export interface FileResource {
+  uri: string;
+  name: string;
+  mimeType: string;
+}
+
+export interface ResourceContents {
+  uri: string;
+  mimeType: string;
+  text: string;
+}
+
+const NO_INDEX = 'No index found. Run indexing first.';
+
+/** One page of the indexed files as `repo://` resources, in path order */
+export async function listFileResources(
+  rootPath: string,
+  cursor?: string,
+  allows?: (file: string) => boolean
+): Promise<{ resources: FileResource[]; nextCursor?: string }> {
+  const files = await loadIndexedFiles(rootPath);
+  if (!files) return { resources: [] };
+  const paths = allows ? [...files.keys()].filter(allows) : [...files.keys()];
+  const page = paginate(paths, cursor, FILE_PAGE_SIZE);
+  return {
+    resources: page.items.map((file) => ({
+      uri: fileResourceUri(file),
+      name: file,
+      mimeType: 'text/plain'
+    })),
+    ...(page.nextCursor ? { nextCursor: page.nextCursor } : {})
+  };
+}
+
+/**
+ * Read a `repo://` URI: the file, one page of its chunk list (`?chunks`), or a single chunk
+ * (`?chunk=N`): the chunk's line range as it is on disk now, or the indexed text for notebook
+ * cells and files that can't be read. Throws for files that aren't indexed.
+ */
+export async function readFileResource(
+  rootPath: string,
+  uri: string,
+  query: FileResourceQuery
+): Promise<ResourceContents[]> {
+  const files = await loadIndexedFiles(rootPath);
+  if (!files) throw new Error(NO_INDEX);
+  const chunks = files.get(query.path);
+  if (!chunks) throw new Error(`Not an indexed file: ${query.path}`);
+
+  if (query.chunk !== undefined) {
+    const chunk = chunks[query.chunk];
+    if (!chunk) {
+      throw new Error(`No chunk ${query.chunk} in ${query.path} (${chunks.length} chunks)`);
+    }
+    // Notebook chunk lines count within their cell, not the .ipynb file
+    const content =
+      chunk.metadata?.cellIndex === undefined
+        ? await readIndexedFile(rootPath, files, query.path)
+        : null;
+    const text =
+      content === null
+        ? chunk.content
+        : content
+            .split('\n')
+            .slice(chunk.startLine - 1, chunk.endLine)
+            .join('\n');
+    return [{ uri, mimeType: 'text/plain', text }];
+  }
+
+  if (query.listChunks) {
+    const page = paginate(
+      chunks.map((chunk, index) => describeChunk(chunk, index)),
+      query.cursor,
+      CHUNK_PAGE_SIZE
+    );
+    const listing = {
+      file: query.path,
+      total: page.total,
+      chunks: page.items.map(({ index, startLine, endLine, ...rest }) => ({
+        uri: fileResourceUri(query.path, `chunk=${index}`),
+        lines: `${startLine}-${endLine}`,
+        ...rest
+      })),
+      ...(page.nextCursor
+        ? {
+            nextCursor: page.nextCursor,
+            next: fileResourceUri(query.path, `chunks&cursor=${page.nextCursor}`)
+          }
+        : {})
+    };
+    return [{ uri, mimeType: 'application/json', text: JSON.stringify(listing, null, 2) }];
+  }
+
+  const content = await readIndexedFile(rootPath, files, query.path);
+  if (content === null) throw new Error(`Could not read ${query.path}`);
+  return [{ uri, mimeType: 'text/plain', text: content }];
+}
@@ -0,0 +1,50 @@
+import { FILE_PAGE_SIZE, paginate } from '../core/file-resources.js';
+import { getModuleCard, listModules, type ModuleCardOptions } from '../core/module-cards.js';
+import type { ResourceContents } from './files.js';
+import { moduleCardUri, MODULES_RESOURCE_URI } from './uri.js';
+
+const NO_INDEX = 'No index found. Run indexing first.';
+
+/** One page of the directories that have module cards, in path order */
+export async function readModuleList(
+  rootPath: string,
+  uri: string,
+  cursor?: string,
+  include?: (file: string) => boolean
+): Promise<ResourceContents[]> {
+  const modules = await listModules(rootPath, include);
+  if (!modules) throw new Error(NO_INDEX);
+  const page = paginate(modules, cursor, FILE_PAGE_SIZE);
+  const listing = {
+    total: page.total,
+    modules: page.items.map((module) => ({ ...module, uri: moduleCardUri(module.path) })),
+    ...(page.nextCursor
+      ? {
+          nextCursor: page.nextCursor,
+          next: `${MODULES_RESOURCE_URI}?cursor=${page.nextCursor}`
+        }
+      : {})
+  };
+  return [{ uri, mimeType: 'application/json', text: JSON.stringify(listing, null, 2) }];
+}
+
+/** The module card of `dir`; throws when it holds no indexed files */
+export async function readModuleCard(
+  rootPath: string,
+  uri: string,
+  dir: string,
+  options: ModuleCardOptions = {}
+): Promise<ResourceContents[]> {
+  const card = await getModuleCard(rootPath, dir, options);
+  if (!card) {
+    if (!(await listModules(rootPath))) throw new Error(NO_INDEX);
+    throw new Error(`No indexed files under ${dir}`);
+  }
+  const { cached: _cached, ...body } = card;
+  const text = JSON.stringify(
+    { ...body, submodules: body.submodules.map((sub) => ({ path: sub, uri: moduleCardUri(sub) })) },
+    null,
+    2
+  );
+  return [{ uri, mimeType: 'application/json', text }];
+}
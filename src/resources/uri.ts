@@ -1,9 +1,29 @@
 const CONTEXT_RESOURCE_URI = 'codebase://context';
+const REPO_MAP_RESOURCE_URI = 'codebase://repo-map';
+/** Advertised as a resource template so clients know which query parameters are understood */
+const REPO_MAP_URI_TEMPLATE = `${REPO_MAP_RESOURCE_URI}{?tokens,path}`;
+
+/** Directories with module cards, paged by `cursor` */
+const MODULES_RESOURCE_URI = 'codebase://modules';
+const MODULES_URI_TEMPLATE = `${MODULES_RESOURCE_URI}{?cursor}`;
+const MODULE_CARD_PREFIX = 'codebase://module/';
+const MODULE_CARD_URI_TEMPLATE = `${MODULE_CARD_PREFIX}{+path}`;
+
+const SCHEME = 'codebase://';
+const FILE_SCHEME = 'repo://';
+/** An indexed file; `?chunks` lists its chunks (paged by `cursor`), `?chunk=N` reads one */
+const FILE_URI_TEMPLATE = `${FILE_SCHEME}{+path}`;
+const FILE_CHUNKS_URI_TEMPLATE = `${FILE_SCHEME}{+path}?chunks{&cursor}`;
+const FILE_CHUNK_URI_TEMPLATE = `${FILE_SCHEME}{+path}?chunk={index}`;
 
 export function normalizeResourceUri(uri: string): string {
   if (!uri) return uri;
-  if (uri === CONTEXT_RESOURCE_URI) return uri;
-  if (uri.endsWith(`/${CONTEXT_RESOURCE_URI}`)) return CONTEXT_RESOURCE_URI;
+  for (const scheme of [SCHEME, FILE_SCHEME]) {
+    if (uri.startsWith(scheme)) return uri;
+    // Some hosts namespace resources as `<server>/codebase://...`
+    const index = uri.indexOf(`/${scheme}`);
+    if (index >= 0) return uri.slice(index + 1);
+  }
   return uri;
 }
 
@@ -11,4 +31,102 @@ export function isContextResourceUri(uri: string): boolean {
   return normalizeResourceUri(uri) === CONTEXT_RESOURCE_URI;
 }
 
-export { CONTEXT_RESOURCE_URI };
+export interface RepoMapQuery {
+  tokens?: number;
+  path?: string;
+}
+
+/** Parameters of a `codebase://repo-map[?tokens=N&path=dir]` URI; null for any other URI. */
+export function parseRepoMapUri(uri: string): RepoMapQuery | null {
+  const normalized = normalizeResourceUri(uri);
+  const [base, search = ''] = normalized.split('?', 2);
+  if (base !== REPO_MAP_RESOURCE_URI) return null;
+
+  const params = new URLSearchParams(search);
+  const query: RepoMapQuery = {};
+  const tokens = Number.parseInt(params.get('tokens') ?? '', 10);
+  if (Number.isFinite(tokens) && tokens > 0) query.tokens = tokens;
+  const scope = params.get('path');
+  if (scope) query.path = scope;
+  return query;
+}
+
+/** Parameters of a `codebase://modules[?cursor=...]` URI; null for any other URI. */
+export function parseModulesUri(uri: string): { cursor?: string } | null {
+  const [base, search = ''] = normalizeResourceUri(uri).split('?', 2);
+  if (base !== MODULES_RESOURCE_URI) return null;
+  const cursor = new URLSearchParams(search).get('cursor');
+  return cursor ? { cursor } : {};
+}
+
+/** `codebase://module/src/core`, path segments percent-encoded */
+export function moduleCardUri(dir: string): string {
+  return `${MODULE_CARD_PREFIX}${dir.split('/').map(encodeURIComponent).join('/')}`;
+}
+
+/** The directory of a `codebase://module/<path>` URI; null for any other URI. */
+export function parseModuleCardUri(uri: string): string | null {
+  const normalized = normalizeResourceUri(uri);
+  if (!normalized.startsWith(MODULE_CARD_PREFIX)) return null;
+  let dir: string;
+  try {
+    dir = decodeURIComponent(normalized.slice(MODULE_CARD_PREFIX.length).split('?', 1)[0]);
+  } catch {
+    return null;
+  }
+  dir = dir.replace(/^\/+|\/+$/g, '');
+  if (!dir || dir.split('/').some((part) => part === '..')) return null;
+  return dir;
+}
+
+export interface FileResourceQuery {
+  /** Repo-relative posix path */
+  path: string;
+  /** `?chunks`: list the file's chunks instead of reading it */
+  listChunks?: boolean;
+  /** `?chunk=N`: the file's Nth chunk (0-based, in line order) */
+  chunk?: number;
+  cursor?: string;
+}
+
+/** `repo://src/core/cart.ts`, path segments percent-encoded */
+export function fileResourceUri(relativePath: string, query = ''): string {
+  const encoded = relativePath.split('/').map(encodeURIComponent).join('/');
+  return `${FILE_SCHEME}${encoded}${query ? `?${query}` : ''}`;
+}
+
+/** Parts of a `repo://<path>[?chunks&cursor=...|?chunk=N]` URI; null for any other URI. */
+export function parseFileResourceUri(uri: string): FileResourceQuery | null {
+  const normalized = normalizeResourceUri(uri);
+  if (!normalized.startsWith(FILE_SCHEME)) return null;
+  const [rawPath, search = ''] = normalized.slice(FILE_SCHEME.length).split('?', 2);
+
+  let relativePath: string;
+  try {
+    relativePath = decodeURIComponent(rawPath).replace(/^\/+|\/+$/g, '');
+  } catch {
+    return null;
+  }
+  if (!relativePath || relativePath.split('/').some((part) => part === '..')) return null;
+
+  const params = new URLSearchParams(search);
+  const query: FileResourceQuery = { path: relativePath };
+  if (params.has('chunks')) query.listChunks = true;
+  const chunk = Number.parseInt(params.get('chunk') ?? '', 10);
+  if (Number.isInteger(chunk) && chunk >= 0) query.chunk = chunk;
+  const cursor = params.get('cursor');
+  if (cursor) query.cursor = cursor;
+  return query;
+}
+
+export {
+  CONTEXT_RESOURCE_URI,
+  REPO_MAP_RESOURCE_URI,
+  REPO_MAP_URI_TEMPLATE,
+  MODULES_RESOURCE_URI,
+  MODULES_URI_TEMPLATE,
+  MODULE_CARD_URI_TEMPLATE,
+  FILE_URI_TEMPLATE,
+  FILE_CHUNKS_URI_TEMPLATE,
+  FILE_CHUNK_URI_TEMPLATE
+};
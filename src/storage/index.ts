@@ -1,10 +1,12 @@
 /**
  * Storage module
- * Provides vector storage using LanceDB
+ * Provides vector storage using LanceDB (embedded, default), SQLite (single file, opt-in),
+ * Qdrant, pgvector or Milvus (remote, opt-in)
  */
 
 export * from './types.js';
 export * from './lancedb.js';
+export * from './quantization.js';
 
 import { VectorStorageProvider, StorageConfig, DEFAULT_STORAGE_CONFIG } from './types.js';
 import { LanceDBStorageProvider } from './lancedb.js';
@@ -17,6 +19,55 @@ export async function getStorageProvider(
 ): Promise<VectorStorageProvider> {
   const mergedConfig = { ...DEFAULT_STORAGE_CONFIG, ...config };
 
+  if (mergedConfig.provider === 'sqlite') {
+    const { SQLiteStorageProvider } = await import('./sqlite.js');
+    const provider = new SQLiteStorageProvider({ quantization: mergedConfig.quantization });
+    await provider.initialize(mergedConfig.path);
+    return provider;
+  }
+
+  if (mergedConfig.quantization && mergedConfig.quantization !== 'none') {
+    console.error(
+      `[storage] Quantization applies to sqlite storage only; ${mergedConfig.provider} ` +
+        'keeps float32 vectors'
+    );
+  }
+
+  if (mergedConfig.provider === 'qdrant') {
+    const { QdrantStorageProvider } = await import('./qdrant.js');
+    const provider = new QdrantStorageProvider({
+      url: mergedConfig.url,
+      apiKey: mergedConfig.apiKey,
+      collection: mergedConfig.collection,
+      rootPath: mergedConfig.rootPath
+    });
+    await provider.initialize(mergedConfig.path);
+    return provider;
+  }
+
+  if (mergedConfig.provider === 'pgvector') {
+    const { PgvectorStorageProvider } = await import('./pgvector.js');
+    const provider = new PgvectorStorageProvider({
+      url: mergedConfig.url,
+      table: mergedConfig.collection,
+      rootPath: mergedConfig.rootPath
+    });
+    await provider.initialize(mergedConfig.path);
+    return provider;
+  }
+
+  if (mergedConfig.provider === 'milvus') {
+    const { MilvusStorageProvider } = await import('./milvus.js');
+    const provider = new MilvusStorageProvider({
+      url: mergedConfig.url,
+      token: mergedConfig.apiKey,
+      collection: mergedConfig.collection,
+      rootPath: mergedConfig.rootPath
+    });
+    await provider.initialize(mergedConfig.path);
+    return provider;
+  }
+
   const provider = new LanceDBStorageProvider();
   await provider.initialize(mergedConfig.path);
 
@@ -5,7 +5,13 @@
 
 import { promises as fs } from 'fs';
 import type { Connection, Table } from '@lancedb/lancedb';
-import { VectorStorageProvider, CodeChunkWithEmbedding, VectorSearchResult } from './types.js';
+import {
+  VectorStorageProvider,
+  CodeChunkWithEmbedding,
+  FilePathRename,
+  STORAGE_CAPABILITIES,
+  VectorSearchResult
+} from './types.js';
 import { CodeChunk, SearchFilters } from '../types/index.js';
 import { IndexCorruptedError } from '../errors/index.js';
 
@@ -28,8 +34,14 @@ interface LanceDBRecord {
   _distance?: number;
 }
 
+/** `'a', 'b'` with single quotes escaped, for IN clauses */
+function sqlStringList(values: string[]): string {
+  return values.map((value) => `'${value.replace(/'/g, "''")}'`).join(', ');
+}
+
 export class LanceDBStorageProvider implements VectorStorageProvider {
   readonly name = 'lancedb';
+  readonly capabilities = STORAGE_CAPABILITIES.lancedb;
 
   private db: Connection | null = null;
   private table: Table | null = null;
@@ -171,6 +183,15 @@ export class LanceDBStorageProvider implements VectorStorageProvider {
         if (filters.language) {
           whereConditions.push(`language = '${filters.language}'`);
         }
+        // Resolved file scope (path, test, size and recency filters), absolute or relative
+        if (filters.filePaths && filters.filePaths.length > 0) {
+          const list = sqlStringList(filters.filePaths);
+          whereConditions.push(`("filePath" IN (${list}) OR "relativePath" IN (${list}))`);
+        }
+        if (filters.excludePaths && filters.excludePaths.length > 0) {
+          const list = sqlStringList(filters.excludePaths);
+          whereConditions.push(`NOT ("filePath" IN (${list}) OR "relativePath" IN (${list}))`);
+        }
 
         if (whereConditions.length > 0) {
           query = query.where(whereConditions.join(' AND '));
@@ -234,9 +255,7 @@ export class LanceDBStorageProvider implements VectorStorageProvider {
 
       // LanceDB supports SQL-style filter for delete
       // Escape single quotes in file paths to prevent SQL injection
-      const escaped = filePaths.map((p) => p.replace(/'/g, "''"));
-      const inClause = escaped.map((p) => `'${p}'`).join(', ');
-      await this.table.delete(`"filePath" IN (${inClause})`);
+      await this.table.delete(`"filePath" IN (${sqlStringList(filePaths)})`);
 
       const countAfter = await this.table.countRows();
       const deleted = countBefore - countAfter;
@@ -248,6 +267,43 @@ export class LanceDBStorageProvider implements VectorStorageProvider {
     }
   }
 
+  async renameFilePaths(renames: FilePathRename[]): Promise<number> {
+    if (!this.initialized || !this.table || renames.length === 0) return 0;
+
+    let renamed = 0;
+    for (const { from, to, relativePath } of renames) {
+      const where = `"filePath" = ${sqlStringList([from])}`;
+      const rows = await this.table.countRows(where);
+      if (rows === 0) continue;
+      await this.table.update({ where, values: { filePath: to, relativePath } });
+      renamed += rows;
+    }
+    console.error(`Moved ${renamed} chunks of ${renames.length} renamed files in LanceDB`);
+    return renamed;
+  }
+
+  async listFilePaths(): Promise<string[]> {
+    if (!this.initialized || !this.table) return [];
+
+    const total = await this.table.countRows();
+    if (total === 0) return [];
+    // Explicit limit: plain queries may otherwise stop at the default page size
+    const rows = await this.table.query().select(['filePath']).limit(total).toArray();
+    return [...new Set((rows as Array<Pick<LanceDBRecord, 'filePath'>>).map((r) => r.filePath))];
+  }
+
+  /** Merge fragments left by deletes and drop superseded versions (older LanceDB builds skip) */
+  async compact(): Promise<void> {
+    if (!this.initialized || !this.table) return;
+
+    const table = this.table as unknown as {
+      optimize?: (options?: { cleanupOlderThan?: Date }) => Promise<unknown>;
+    };
+    if (typeof table.optimize !== 'function') return;
+    await table.optimize({ cleanupOlderThan: new Date() });
+    console.error('Compacted LanceDB storage');
+  }
+
   async clear(): Promise<void> {
     if (!this.initialized) return;
 
@@ -0,0 +1,484 @@
+/**
+ * Milvus Storage Provider
+ * Remote vector store over Milvus' v2 REST API (native fetch, no client SDK). One collection
+ * per project with a fixed schema: paths and classification are scalar fields so
+ * framework/componentType/layer/language/tag/path filters become filter expressions, and
+ * the rest of the chunk rides along in a JSON field.
+ *
+ * As with Qdrant, a full rebuild fills a new generation collection and then moves the
+ * project's alias onto it, so searches never see a half-built collection.
+ */
+
+import { createHash } from 'crypto';
+import path from 'path';
+import {
+  VectorStorageProvider,
+  CodeChunkWithEmbedding,
+  FilePathRename,
+  STORAGE_CAPABILITIES,
+  VectorSearchResult
+} from './types.js';
+import { CodeChunk, SearchFilters } from '../types/index.js';
+import { IndexCorruptedError } from '../errors/index.js';
+
+export const DEFAULT_MILVUS_URL = 'http://localhost:19530';
+
+/** Rows per upsert request — keeps request bodies well under Milvus' default 64MB gRPC limit */
+const UPSERT_BATCH_SIZE = 256;
+/** Milvus caps offset + limit of a query at 16384 rows */
+const QUERY_MAX_ROWS = 16384;
+
+const PATH_MAX_LENGTH = 4096;
+const LABEL_MAX_LENGTH = 256;
+/** VarChar lengths are bytes; 16k characters fit even when every one takes four */
+const CONTENT_MAX_CHARS = 16000;
+const CONTENT_MAX_LENGTH = 65535;
+const TAGS_MAX_CAPACITY = 64;
+
+const COLLECTION_NAME_PATTERN = /^[A-Za-z_][A-Za-z0-9_]{0,254}$/;
+
+const SCALAR_FIELDS = [
+  'filePath',
+  'relativePath',
+  'startLine',
+  'endLine',
+  'language',
+  'framework',
+  'componentType',
+  'layer',
+  'content',
+  'tags',
+  'payload'
+];
+
+interface MilvusPayload {
+  dependencies: string[];
+  imports: CodeChunk['imports'];
+  exports: CodeChunk['exports'];
+  metadata: CodeChunk['metadata'];
+}
+
+interface MilvusRow {
+  id: string;
+  vector: number[];
+  filePath: string;
+  relativePath: string;
+  startLine: number;
+  endLine: number;
+  language: string;
+  framework: string;
+  componentType: string;
+  layer: string;
+  content: string;
+  tags: string[];
+  payload: MilvusPayload;
+}
+
+type MilvusHit = Omit<MilvusRow, 'vector'> & { distance: number };
+
+interface MilvusResponse<T> {
+  code: number;
+  message?: string;
+  data: T;
+}
+
+export interface MilvusStorageOptions {
+  url?: string;
+  /** API key, or `user:password` */
+  token?: string;
+  /** Explicit collection name. Defaults to one derived from the project root. */
+  collection?: string;
+  /** Project root used to derive a stable per-project collection name */
+  rootPath?: string;
+}
+
+/**
+ * Derive a stable, Milvus-safe collection name for a project root:
+ * `codebase_context_<basename>_<8 hex of path hash>`.
+ */
+export function deriveMilvusCollectionName(rootPath: string): string {
+  const resolved = path.resolve(rootPath).replace(/\\/g, '/').toLowerCase();
+  const base = path
+    .basename(resolved)
+    .replace(/[^a-z0-9_]+/g, '_')
+    .replace(/^_+|_+$/g, '')
+    .slice(0, 40);
+  const hash = createHash('sha256').update(resolved).digest('hex').slice(0, 8);
+  return `codebase_context_${base || 'project'}_${hash}`;
+}
+
+/** Milvus string literals take JSON escapes */
+function literalList(values: string[]): string {
+  return `[${values.map((value) => JSON.stringify(value)).join(', ')}]`;
+}
+
+export function buildMilvusFilter(filters?: SearchFilters): string {
+  if (!filters) return '';
+
+  const conditions: string[] = [];
+  for (const field of ['framework', 'componentType', 'layer', 'language'] as const) {
+    const value = filters[field];
+    if (value) conditions.push(`${field} == ${JSON.stringify(value)}`);
+  }
+  if (filters.tags && filters.tags.length > 0) {
+    conditions.push(`ARRAY_CONTAINS_ANY(tags, ${literalList(filters.tags)})`);
+  }
+  if (filters.filePaths && filters.filePaths.length > 0) {
+    const list = literalList(filters.filePaths);
+    conditions.push(`(filePath in ${list} or relativePath in ${list})`);
+  }
+  if (filters.excludePaths && filters.excludePaths.length > 0) {
+    const list = literalList(filters.excludePaths);
+    conditions.push(`filePath not in ${list} and relativePath not in ${list}`);
+  }
+  return conditions.join(' and ');
+}
+
+export class MilvusStorageProvider implements VectorStorageProvider {
+  readonly name = 'milvus';
+  readonly capabilities = STORAGE_CAPABILITIES.milvus;
+
+  private url: string;
+  private token?: string;
+  private collection: string;
+  private collectionExists = false;
+  private initialized = false;
+  /** Collection of the rebuild in progress, receiving writes until it is committed */
+  private generation: string | null = null;
+  private generationExists = false;
+
+  constructor(options: MilvusStorageOptions = {}) {
+    this.url = (options.url || DEFAULT_MILVUS_URL).replace(/\/+$/, '');
+    this.token = options.token;
+    this.collection =
+      options.collection || deriveMilvusCollectionName(options.rootPath || process.cwd());
+    if (!COLLECTION_NAME_PATTERN.test(this.collection)) {
+      throw new Error(
+        `Invalid Milvus collection name "${this.collection}": use letters, digits and _`
+      );
+    }
+  }
+
+  get collectionName(): string {
+    return this.collection;
+  }
+
+  /**
+   * Connect and check whether the project collection exists.
+   * storagePath is unused: data lives on the Milvus server, not on disk.
+   */
+  async initialize(_storagePath: string): Promise<void> {
+    if (this.initialized) return;
+
+    try {
+      const data = await this.call<{ has: boolean }>('/v2/vectordb/collections/has', {
+        collectionName: this.collection
+      });
+      this.collectionExists = data.has || (await this.aliasTarget()) !== null;
+    } catch (error) {
+      throw new IndexCorruptedError(
+        `Milvus initialization failed at ${this.url}: ${error instanceof Error ? error.message : String(error)}`
+      );
+    }
+
+    this.initialized = true;
+    console.error(`Milvus initialized: ${this.url} (collection ${this.collection})`);
+  }
+
+  async store(chunks: CodeChunkWithEmbedding[]): Promise<void> {
+    if (!this.initialized) {
+      throw new Error('Storage not initialized');
+    }
+    if (chunks.length === 0) return;
+
+    await this.ensureCollection(chunks[0].embedding.length);
+    await this.upsertRows(
+      chunks.map((chunk) => this.toRow(chunk)),
+      this.generation ?? this.collection
+    );
+    console.error(
+      `Stored ${chunks.length} chunks in Milvus collection ${this.generation ?? this.collection}`
+    );
+  }
+
+  async search(
+    queryVector: number[],
+    limit: number,
+    filters?: SearchFilters
+  ): Promise<VectorSearchResult[]> {
+    if (!this.initialized) {
+      throw new IndexCorruptedError('Milvus storage not initialized (rebuild required)');
+    }
+    // No semantic index yet (e.g. skipEmbedding) — degrade to keyword-only search
+    if (!this.collectionExists) return [];
+
+    try {
+      const filter = buildMilvusFilter(filters);
+      const hits = await this.call<MilvusHit[]>('/v2/vectordb/entities/search', {
+        collectionName: this.collection,
+        data: [queryVector],
+        annsField: 'vector',
+        limit,
+        outputFields: SCALAR_FIELDS,
+        searchParams: { metricType: 'COSINE' },
+        ...(filter ? { filter } : {})
+      });
+
+      // COSINE "distance" is the similarity: higher is closer
+      return hits.map((hit) => ({
+        chunk: this.fromRow(hit),
+        score: Math.max(0, Math.min(1, hit.distance)),
+        distance: 1 - hit.distance
+      }));
+    } catch (error) {
+      // Transient network errors should not force a rebuild
+      console.error('[Milvus] Search error:', error instanceof Error ? error.message : error);
+      return [];
+    }
+  }
+
+  async deleteByFilePaths(filePaths: string[]): Promise<number> {
+    if (!this.initialized || !this.collectionExists || filePaths.length === 0) {
+      return 0;
+    }
+
+    const filter = `filePath in ${literalList(filePaths)}`;
+    const before = await this.countMatching(filter);
+    await this.call('/v2/vectordb/entities/delete', { collectionName: this.collection, filter });
+    console.error(`Deleted ${before} chunks for ${filePaths.length} files from Milvus`);
+    return before;
+  }
+
+  /** Milvus can't update fields in place: read the moved rows back and upsert them */
+  async renameFilePaths(renames: FilePathRename[]): Promise<number> {
+    if (!this.initialized || !this.collectionExists || renames.length === 0) return 0;
+
+    let renamed = 0;
+    for (const { from, to, relativePath } of renames) {
+      const rows = await this.call<MilvusRow[]>('/v2/vectordb/entities/query', {
+        collectionName: this.collection,
+        filter: `filePath == ${JSON.stringify(from)}`,
+        outputFields: ['id', 'vector', ...SCALAR_FIELDS],
+        limit: QUERY_MAX_ROWS
+      });
+      if (rows.length === 0) continue;
+      await this.upsertRows(rows.map((row) => ({ ...row, filePath: to, relativePath })));
+      renamed += rows.length;
+    }
+    console.error(`Moved ${renamed} chunks of ${renames.length} renamed files in Milvus`);
+    return renamed;
+  }
+
+  // No compact() or listFilePaths(): Milvus compacts segments by itself, and listing every
+  // path would page through the whole collection
+  async clear(): Promise<void> {
+    if (!this.initialized || !this.collectionExists) return;
+
+    const target = await this.aliasTarget();
+    if (target) await this.call('/v2/vectordb/aliases/drop', { aliasName: this.collection });
+    await this.call('/v2/vectordb/collections/drop', { collectionName: target ?? this.collection });
+    this.collectionExists = false;
+    console.error(`Cleared Milvus collection ${this.collection}`);
+  }
+
+  /** Send the writes of a full rebuild to a new generation; searches keep the live one */
+  async beginGeneration(): Promise<void> {
+    if (!this.initialized) throw new Error('Storage not initialized');
+    await this.abortGeneration();
+    this.generation = `${this.collection}_${Date.now().toString(36)}`;
+    this.generationExists = false;
+  }
+
+  /** Point the project alias at the rebuilt generation and drop the one it replaces */
+  async commitGeneration(): Promise<void> {
+    const next = this.generation;
+    if (!next) return;
+    this.generation = null;
+    if (!this.generationExists) {
+      await this.clear();
+      return;
+    }
+
+    const previous = await this.aliasTarget();
+    if (previous) {
+      await this.call('/v2/vectordb/aliases/alter', {
+        aliasName: this.collection,
+        collectionName: next
+      });
+    } else {
+      // A plain collection holds the name the alias needs
+      if (this.collectionExists) {
+        await this.call('/v2/vectordb/collections/drop', { collectionName: this.collection });
+      }
+      await this.call('/v2/vectordb/aliases/create', {
+        aliasName: this.collection,
+        collectionName: next
+      });
+    }
+    this.collectionExists = true;
+    if (previous && previous !== next) {
+      await this.call('/v2/vectordb/collections/drop', { collectionName: previous });
+    }
+    console.error(`Milvus collection ${this.collection} now serves ${next}`);
+  }
+
+  /** Drop a generation that won't be committed; the live collection is untouched */
+  async abortGeneration(): Promise<void> {
+    const abandoned = this.generation;
+    this.generation = null;
+    if (abandoned && this.generationExists) {
+      await this.call('/v2/vectordb/collections/drop', { collectionName: abandoned });
+    }
+    this.generationExists = false;
+  }
+
+  async count(): Promise<number> {
+    if (!this.initialized || !this.collectionExists) return 0;
+    try {
+      return await this.countMatching('');
+    } catch (error) {
+      console.error('Failed to count Milvus entities:', error);
+      return 0;
+    }
+  }
+
+  isInitialized(): boolean {
+    return this.initialized;
+  }
+
+  private async ensureCollection(dimensions: number): Promise<void> {
+    if (this.generation ? this.generationExists : this.collectionExists) return;
+
+    const varChar = (fieldName: string, maxLength: number) => ({
+      fieldName,
+      dataType: 'VarChar',
+      elementTypeParams: { max_length: maxLength }
+    });
+    await this.call('/v2/vectordb/collections/create', {
+      collectionName: this.generation ?? this.collection,
+      schema: {
+        autoId: false,
+        enableDynamicField: false,
+        fields: [
+          { ...varChar('id', 512), isPrimary: true },
+          { fieldName: 'vector', dataType: 'FloatVector', elementTypeParams: { dim: dimensions } },
+          varChar('filePath', PATH_MAX_LENGTH),
+          varChar('relativePath', PATH_MAX_LENGTH),
+          { fieldName: 'startLine', dataType: 'Int64' },
+          { fieldName: 'endLine', dataType: 'Int64' },
+          varChar('language', LABEL_MAX_LENGTH),
+          varChar('framework', LABEL_MAX_LENGTH),
+          varChar('componentType', LABEL_MAX_LENGTH),
+          varChar('layer', LABEL_MAX_LENGTH),
+          varChar('content', CONTENT_MAX_LENGTH),
+          {
+            fieldName: 'tags',
+            dataType: 'Array',
+            elementDataType: 'VarChar',
+            elementTypeParams: { max_capacity: TAGS_MAX_CAPACITY, max_length: LABEL_MAX_LENGTH }
+          },
+          { fieldName: 'payload', dataType: 'JSON' }
+        ]
+      },
+      // Creating the index with the collection also loads it, so it is searchable at once
+      indexParams: [
+        { fieldName: 'vector', indexName: 'vector', metricType: 'COSINE', indexType: 'AUTOINDEX' }
+      ]
+    });
+    if (this.generation) this.generationExists = true;
+    else this.collectionExists = true;
+  }
+
+  /** Collection the project alias points at; null when the name is a plain collection or unused */
+  private async aliasTarget(): Promise<string | null> {
+    try {
+      const alias = await this.call<{ collectionName?: string }>('/v2/vectordb/aliases/describe', {
+        aliasName: this.collection
+      });
+      return alias.collectionName ?? null;
+    } catch {
+      return null;
+    }
+  }
+
+  private async upsertRows(rows: MilvusRow[], collectionName = this.collection): Promise<void> {
+    for (let i = 0; i < rows.length; i += UPSERT_BATCH_SIZE) {
+      await this.call('/v2/vectordb/entities/upsert', {
+        collectionName,
+        data: rows.slice(i, i + UPSERT_BATCH_SIZE)
+      });
+    }
+  }
+
+  private async countMatching(filter: string): Promise<number> {
+    const rows = await this.call<Array<{ 'count(*)': number }>>('/v2/vectordb/entities/query', {
+      collectionName: this.collection,
+      filter,
+      outputFields: ['count(*)']
+    });
+    return Number(rows[0]?.['count(*)'] ?? 0);
+  }
+
+  private toRow(chunk: CodeChunkWithEmbedding): MilvusRow {
+    return {
+      id: chunk.id,
+      vector: chunk.embedding,
+      filePath: chunk.filePath,
+      relativePath: chunk.relativePath,
+      startLine: chunk.startLine,
+      endLine: chunk.endLine,
+      language: chunk.language,
+      framework: chunk.framework || '',
+      componentType: chunk.componentType || '',
+      layer: chunk.layer || '',
+      content: chunk.content.slice(0, CONTENT_MAX_CHARS),
+      tags: chunk.tags.slice(0, TAGS_MAX_CAPACITY),
+      payload: {
+        dependencies: chunk.dependencies,
+        imports: chunk.imports,
+        exports: chunk.exports,
+        metadata: chunk.metadata
+      }
+    };
+  }
+
+  private fromRow(row: Omit<MilvusRow, 'vector'>): CodeChunk {
+    return {
+      id: row.id,
+      content: row.content,
+      filePath: row.filePath,
+      relativePath: row.relativePath,
+      startLine: Number(row.startLine),
+      endLine: Number(row.endLine),
+      language: row.language,
+      framework: row.framework || undefined,
+      componentType: row.componentType || undefined,
+      layer: (row.layer || undefined) as CodeChunk['layer'],
+      dependencies: row.payload?.dependencies ?? [],
+      imports: row.payload?.imports ?? [],
+      exports: row.payload?.exports ?? [],
+      tags: row.tags ?? [],
+      metadata: row.payload?.metadata ?? {}
+    };
+  }
+
+  /** Milvus answers HTTP 200 with a non-zero `code` on failure */
+  private async call<T = unknown>(route: string, body: unknown): Promise<T> {
+    const response = await fetch(`${this.url}${route}`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        ...(this.token ? { Authorization: `Bearer ${this.token}` } : {})
+      },
+      body: JSON.stringify(body)
+    });
+    if (!response.ok) {
+      throw new Error(`Milvus API Error ${response.status}: ${await response.text()}`);
+    }
+    const payload = (await response.json()) as MilvusResponse<T>;
+    if (payload.code !== 0) {
+      throw new Error(`Milvus API Error ${payload.code}: ${payload.message ?? 'unknown error'}`);
+    }
+    return payload.data;
+  }
+}
@@ -0,0 +1,584 @@
+/**
+ * pgvector Storage Provider
+ * Postgres-backed vector store for teams that already run Postgres. One table per project;
+ * path, language, ref and classification live in regular columns so the index can be
+ * queried and administered with standard SQL tooling. Vectors are searched with an HNSW
+ * cosine index. The `pg` driver is an optional peer dependency, loaded only when selected.
+ *
+ * A full rebuild fills a generation table (`<table prefix>_<suffix>`) and renames it over the
+ * project table in one transaction, so searches see the old rows or the new ones, never none.
+ */
+
+import { createHash } from 'crypto';
+import path from 'path';
+import {
+  VectorStorageProvider,
+  CodeChunkWithEmbedding,
+  FilePathRename,
+  STORAGE_CAPABILITIES,
+  VectorSearchResult
+} from './types.js';
+import { CodeChunk, SearchFilters } from '../types/index.js';
+import { IndexCorruptedError } from '../errors/index.js';
+
+export const DEFAULT_PGVECTOR_URL = 'postgresql://localhost:5432/postgres';
+
+/** Applied migrations per chunk table */
+export const PGVECTOR_MIGRATIONS_TABLE = 'codebase_context_migrations';
+
+/** pgvector cannot build HNSW indexes over `vector` columns wider than this */
+export const PGVECTOR_HNSW_MAX_DIMENSIONS = 2000;
+
+/** Rows per INSERT — 14 parameters each stays far below Postgres' 65535 parameter limit */
+const UPSERT_BATCH_SIZE = 200;
+
+const TABLE_NAME_PATTERN = /^[a-z_][a-z0-9_]{0,44}$/;
+
+interface PgQueryResult<R> {
+  rows: R[];
+  rowCount: number | null;
+}
+
+interface PgQueryable {
+  query<R = Record<string, unknown>>(text: string, values?: unknown[]): Promise<PgQueryResult<R>>;
+}
+
+interface PgPoolClient extends PgQueryable {
+  release(): void;
+}
+
+export interface PgPool extends PgQueryable {
+  connect(): Promise<PgPoolClient>;
+  end(): Promise<void>;
+}
+
+interface PgModule {
+  Pool: new (config: { connectionString: string; allowExitOnIdle?: boolean }) => PgPool;
+}
+
+interface PgChunkRow {
+  id: string;
+  file_path: string;
+  relative_path: string;
+  start_line: number;
+  end_line: number;
+  language: string;
+  framework: string;
+  component_type: string;
+  layer: string;
+  content: string;
+  payload: PgChunkPayload | string;
+  distance: number | string;
+}
+
+interface PgChunkPayload {
+  dependencies: string[];
+  imports: CodeChunk['imports'];
+  exports: CodeChunk['exports'];
+  tags: string[];
+  metadata: CodeChunk['metadata'];
+}
+
+export interface PgvectorMigration {
+  version: number;
+  description: string;
+  up: (table: string, dimensions: number) => string[];
+}
+
+/** Append-only: never edit a released migration, add a new version instead */
+export const PGVECTOR_MIGRATIONS: PgvectorMigration[] = [
+  {
+    version: 1,
+    description: 'chunk table with metadata columns',
+    up: (table, dimensions) => [
+      'CREATE EXTENSION IF NOT EXISTS vector',
+      `CREATE TABLE IF NOT EXISTS ${table} (
+        id TEXT PRIMARY KEY,
+        file_path TEXT NOT NULL,
+        relative_path TEXT NOT NULL,
+        start_line INTEGER NOT NULL,
+        end_line INTEGER NOT NULL,
+        language TEXT NOT NULL,
+        framework TEXT NOT NULL DEFAULT '',
+        component_type TEXT NOT NULL DEFAULT '',
+        layer TEXT NOT NULL DEFAULT '',
+        git_ref TEXT,
+        git_commit TEXT,
+        content TEXT NOT NULL,
+        payload JSONB NOT NULL,
+        embedding vector(${dimensions}) NOT NULL,
+        indexed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+      )`,
+      `CREATE INDEX IF NOT EXISTS ${table}_file_path_idx ON ${table} (file_path)`,
+      `CREATE INDEX IF NOT EXISTS ${table}_relative_path_idx ON ${table} (relative_path)`,
+      `CREATE INDEX IF NOT EXISTS ${table}_language_idx ON ${table} (language)`,
+      `CREATE INDEX IF NOT EXISTS ${table}_git_ref_idx ON ${table} (git_ref)`
+    ]
+  },
+  {
+    version: 2,
+    description: 'HNSW cosine index',
+    up: (table, dimensions) =>
+      dimensions > PGVECTOR_HNSW_MAX_DIMENSIONS
+        ? []
+        : [
+            `CREATE INDEX IF NOT EXISTS ${table}_embedding_hnsw ` +
+              `ON ${table} USING hnsw (embedding vector_cosine_ops)`
+          ]
+  }
+];
+
+export interface PgvectorStorageOptions {
+  url?: string;
+  /** Explicit table name. Defaults to one derived from the project root. */
+  table?: string;
+  /** Project root used to derive a stable per-project table name */
+  rootPath?: string;
+  /** Injected for tests */
+  pool?: PgPool;
+}
+
+/**
+ * Derive a stable, identifier-safe table name for a project root:
+ * `codebase_context_<basename>_<8 hex of path hash>`.
+ */
+export function derivePgvectorTableName(rootPath: string): string {
+  const resolved = path.resolve(rootPath).replace(/\\/g, '/').toLowerCase();
+  const base = path
+    .basename(resolved)
+    .replace(/[^a-z0-9_]+/g, '_')
+    .replace(/^_+|_+$/g, '')
+    .slice(0, 16);
+  const hash = createHash('sha256').update(resolved).digest('hex').slice(0, 8);
+  return `codebase_context_${base || 'project'}_${hash}`;
+}
+
+export function buildPgvectorWhere(
+  filters: SearchFilters | undefined,
+  firstParam: number
+): { clause: string; params: unknown[] } {
+  const conditions: string[] = [];
+  const params: unknown[] = [];
+  const param = (value: unknown) => {
+    params.push(value);
+    return `$${firstParam + params.length - 1}`;
+  };
+
+  if (filters?.framework) conditions.push(`framework = ${param(filters.framework)}`);
+  if (filters?.componentType) {
+    conditions.push(`component_type = ${param(filters.componentType)}`);
+  }
+  if (filters?.layer) conditions.push(`layer = ${param(filters.layer)}`);
+  if (filters?.language) conditions.push(`language = ${param(filters.language)}`);
+  if (filters?.tags && filters.tags.length > 0) {
+    conditions.push(`payload->'tags' ?| ${param(filters.tags)}::text[]`);
+  }
+  if (filters?.filePaths && filters.filePaths.length > 0) {
+    const paths = param(filters.filePaths);
+    conditions.push(`(file_path = ANY(${paths}::text[]) OR relative_path = ANY(${paths}::text[]))`);
+  }
+  if (filters?.excludePaths && filters.excludePaths.length > 0) {
+    const paths = param(filters.excludePaths);
+    conditions.push(
+      `NOT (file_path = ANY(${paths}::text[]) OR relative_path = ANY(${paths}::text[]))`
+    );
+  }
+
+  return { clause: conditions.length > 0 ? ` WHERE ${conditions.join(' AND ')}` : '', params };
+}
+
+function toVectorLiteral(vector: number[]): string {
+  return `[${vector.join(',')}]`;
+}
+
+async function loadPg(): Promise<PgModule> {
+  // Non-literal specifier keeps `pg` optional for type-checking and bundling
+  const specifier = 'pg';
+  try {
+    const mod = (await import(specifier)) as PgModule & { default?: PgModule };
+    return mod.default ?? mod;
+  } catch {
+    throw new Error(
+      'pgvector storage requires the `pg` package: npm install pg. ' +
+        'Use STORAGE_PROVIDER=lancedb instead.'
+    );
+  }
+}
+
+export class PgvectorStorageProvider implements VectorStorageProvider {
+  readonly name = 'pgvector';
+  readonly capabilities = STORAGE_CAPABILITIES.pgvector;
+
+  private url: string;
+  private table: string;
+  private pool: PgPool | null;
+  private tableExists = false;
+  private migrated = false;
+  private initialized = false;
+  /** Table of the rebuild in progress, receiving writes until it is committed */
+  private generation: string | null = null;
+  private generationMigrated = false;
+
+  constructor(options: PgvectorStorageOptions = {}) {
+    this.url = options.url || DEFAULT_PGVECTOR_URL;
+    this.table = (
+      options.table || derivePgvectorTableName(options.rootPath || process.cwd())
+    ).toLowerCase();
+    if (!TABLE_NAME_PATTERN.test(this.table)) {
+      throw new Error(
+        `Invalid pgvector table name "${this.table}": use letters, digits and _ (max 45 chars)`
+      );
+    }
+    this.pool = options.pool ?? null;
+  }
+
+  get tableName(): string {
+    return this.table;
+  }
+
+  /**
+   * Connect and check whether the project table exists.
+   * storagePath is unused: data lives in Postgres, not on disk.
+   */
+  async initialize(_storagePath: string): Promise<void> {
+    if (this.initialized) return;
+
+    try {
+      if (!this.pool) {
+        const pg = await loadPg();
+        this.pool = new pg.Pool({ connectionString: this.url, allowExitOnIdle: true });
+      }
+      const { rows } = await this.pool.query<{ oid: string | null }>(
+        'SELECT to_regclass($1)::text AS oid',
+        [this.table]
+      );
+      this.tableExists = rows[0]?.oid != null;
+    } catch (error) {
+      throw new IndexCorruptedError(
+        `pgvector initialization failed: ${error instanceof Error ? error.message : String(error)}`
+      );
+    }
+
+    this.initialized = true;
+    console.error(`pgvector initialized (table ${this.table})`);
+  }
+
+  async store(chunks: CodeChunkWithEmbedding[]): Promise<void> {
+    if (!this.initialized || !this.pool) {
+      throw new Error('Storage not initialized');
+    }
+    if (chunks.length === 0) return;
+
+    const target = this.generation ?? this.table;
+    await this.migrate(chunks[0].embedding.length, target);
+
+    for (let i = 0; i < chunks.length; i += UPSERT_BATCH_SIZE) {
+      const batch = chunks.slice(i, i + UPSERT_BATCH_SIZE);
+      const values: unknown[] = [];
+      const rows = batch.map((chunk) => {
+        const payload: PgChunkPayload = {
+          dependencies: chunk.dependencies,
+          imports: chunk.imports,
+          exports: chunk.exports,
+          tags: chunk.tags,
+          metadata: chunk.metadata
+        };
+        const start = values.length;
+        values.push(
+          chunk.id,
+          chunk.filePath,
+          chunk.relativePath,
+          chunk.startLine,
+          chunk.endLine,
+          chunk.language,
+          chunk.framework || '',
+          chunk.componentType || '',
+          chunk.layer || '',
+          chunk.metadata.gitRef ?? null,
+          chunk.metadata.gitCommit ?? null,
+          chunk.content,
+          JSON.stringify(payload),
+          toVectorLiteral(chunk.embedding)
+        );
+        const placeholders = Array.from({ length: 14 }, (_, j) => `$${start + j + 1}`);
+        placeholders[12] += '::jsonb';
+        placeholders[13] += '::vector';
+        return `(${placeholders.join(', ')})`;
+      });
+
+      await this.pool.query(
+        `INSERT INTO ${target}
+          (id, file_path, relative_path, start_line, end_line, language, framework,
+           component_type, layer, git_ref, git_commit, content, payload, embedding)
+         VALUES ${rows.join(', ')}
+         ON CONFLICT (id) DO UPDATE SET
+           file_path = EXCLUDED.file_path, relative_path = EXCLUDED.relative_path,
+           start_line = EXCLUDED.start_line, end_line = EXCLUDED.end_line,
+           language = EXCLUDED.language, framework = EXCLUDED.framework,
+           component_type = EXCLUDED.component_type, layer = EXCLUDED.layer,
+           git_ref = EXCLUDED.git_ref, git_commit = EXCLUDED.git_commit,
+           content = EXCLUDED.content, payload = EXCLUDED.payload,
+           embedding = EXCLUDED.embedding, indexed_at = now()`,
+        values
+      );
+    }
+
+    console.error(`Stored ${chunks.length} chunks in pgvector table ${target}`);
+  }
+
+  async search(
+    queryVector: number[],
+    limit: number,
+    filters?: SearchFilters
+  ): Promise<VectorSearchResult[]> {
+    if (!this.initialized || !this.pool) {
+      throw new IndexCorruptedError('pgvector storage not initialized (rebuild required)');
+    }
+    // No semantic index yet (e.g. skipEmbedding) — degrade to keyword-only search
+    if (!this.tableExists) return [];
+
+    try {
+      const { clause, params } = buildPgvectorWhere(filters, 3);
+      const { rows } = await this.pool.query<PgChunkRow>(
+        `SELECT id, file_path, relative_path, start_line, end_line, language, framework,
+                component_type, layer, content, payload, embedding <=> $1::vector AS distance
+           FROM ${this.table}${clause}
+          ORDER BY embedding <=> $1::vector
+          LIMIT $2`,
+        [toVectorLiteral(queryVector), limit, ...params]
+      );
+
+      return rows.map((row) => {
+        const distance = Number(row.distance);
+        return {
+          chunk: this.toChunk(row),
+          score: Math.max(0, Math.min(1, 1 - distance)),
+          distance
+        };
+      });
+    } catch (error) {
+      // Transient connection errors should not force a rebuild
+      console.error('[pgvector] Search error:', error instanceof Error ? error.message : error);
+      return [];
+    }
+  }
+
+  async deleteByFilePaths(filePaths: string[]): Promise<number> {
+    if (!this.initialized || !this.pool || !this.tableExists || filePaths.length === 0) {
+      return 0;
+    }
+
+    const result = await this.pool.query(
+      `DELETE FROM ${this.table} WHERE file_path = ANY($1::text[])`,
+      [filePaths]
+    );
+    const deleted = result.rowCount ?? 0;
+    console.error(`Deleted ${deleted} chunks for ${filePaths.length} files from pgvector`);
+    return deleted;
+  }
+
+  async renameFilePaths(renames: FilePathRename[]): Promise<number> {
+    if (!this.initialized || !this.pool || !this.tableExists || renames.length === 0) return 0;
+
+    let renamed = 0;
+    for (const { from, to, relativePath } of renames) {
+      const result = await this.pool.query(
+        `UPDATE ${this.table} SET file_path = $1, relative_path = $2 WHERE file_path = $3`,
+        [to, relativePath, from]
+      );
+      renamed += result.rowCount ?? 0;
+    }
+    console.error(`Moved ${renamed} chunks of ${renames.length} renamed files in pgvector`);
+    return renamed;
+  }
+
+  async listFilePaths(): Promise<string[]> {
+    if (!this.initialized || !this.pool || !this.tableExists) return [];
+    const { rows } = await this.pool.query<{ file_path: string }>(
+      `SELECT DISTINCT file_path FROM ${this.table}`
+    );
+    return rows.map((row) => row.file_path);
+  }
+
+  /** Reclaim dead tuples left by deletes; needs ownership of the table */
+  async compact(): Promise<void> {
+    if (!this.initialized || !this.pool || !this.tableExists) return;
+    await this.pool.query(`VACUUM ${this.table}`);
+  }
+
+  /** Drop the table so the next store re-runs migrations (the embedding width may change) */
+  async clear(): Promise<void> {
+    if (!this.initialized || !this.pool || !this.tableExists) return;
+
+    await this.pool.query(`DROP TABLE IF EXISTS ${this.table}`);
+    await this.pool.query(`DELETE FROM ${PGVECTOR_MIGRATIONS_TABLE} WHERE table_name = $1`, [
+      this.table
+    ]);
+    this.tableExists = false;
+    this.migrated = false;
+    console.error(`Cleared pgvector table ${this.table}`);
+  }
+
+  /** Send the writes of a full rebuild to a new table; searches keep the live one */
+  async beginGeneration(): Promise<void> {
+    if (!this.initialized || !this.pool) throw new Error('Storage not initialized');
+    await this.abortGeneration();
+    // Same length budget as the project table, so index names stay within 63 bytes
+    this.generation = `${this.table.slice(0, 36)}_${Date.now().toString(36)}`;
+    this.generationMigrated = false;
+  }
+
+  /** Replace the project table with the rebuilt one in a single transaction */
+  async commitGeneration(): Promise<void> {
+    const next = this.generation;
+    if (!next || !this.pool) return;
+    this.generation = null;
+    if (!this.generationMigrated) {
+      await this.clear();
+      return;
+    }
+
+    const client = await this.pool.connect();
+    try {
+      await client.query('BEGIN');
+      await client.query('SELECT pg_advisory_xact_lock(hashtext($1))', [this.table]);
+      await client.query(`DROP TABLE IF EXISTS ${this.table}`);
+      await client.query(`ALTER TABLE ${next} RENAME TO ${this.table}`);
+      await client.query(`DELETE FROM ${PGVECTOR_MIGRATIONS_TABLE} WHERE table_name = $1`, [
+        this.table
+      ]);
+      await client.query(
+        `UPDATE ${PGVECTOR_MIGRATIONS_TABLE} SET table_name = $1 WHERE table_name = $2`,
+        [this.table, next]
+      );
+      await client.query('COMMIT');
+    } catch (error) {
+      await client.query('ROLLBACK');
+      throw error;
+    } finally {
+      client.release();
+    }
+    this.tableExists = true;
+    this.migrated = true;
+    console.error(`pgvector table ${this.table} now holds the rows of ${next}`);
+  }
+
+  /** Drop a generation that won't be committed; the live table is untouched */
+  async abortGeneration(): Promise<void> {
+    const abandoned = this.generation;
+    this.generation = null;
+    if (abandoned && this.generationMigrated && this.pool) {
+      await this.pool.query(`DROP TABLE IF EXISTS ${abandoned}`);
+      await this.pool.query(`DELETE FROM ${PGVECTOR_MIGRATIONS_TABLE} WHERE table_name = $1`, [
+        abandoned
+      ]);
+    }
+    this.generationMigrated = false;
+  }
+
+  async count(): Promise<number> {
+    if (!this.initialized || !this.pool || !this.tableExists) return 0;
+    try {
+      const { rows } = await this.pool.query<{ n: string }>(
+        `SELECT COUNT(*) AS n FROM ${this.table}`
+      );
+      return Number(rows[0]?.n ?? 0);
+    } catch (error) {
+      console.error('Failed to count pgvector rows:', error);
+      return 0;
+    }
+  }
+
+  isInitialized(): boolean {
+    return this.initialized;
+  }
+
+  async close(): Promise<void> {
+    await this.pool?.end();
+    this.pool = null;
+    this.initialized = false;
+  }
+
+  /** Apply pending migrations; an advisory lock serialises concurrent indexers */
+  private async migrate(dimensions: number, table = this.table): Promise<void> {
+    const isGeneration = table !== this.table;
+    if ((isGeneration ? this.generationMigrated : this.migrated) || !this.pool) return;
+
+    const client = await this.pool.connect();
+    try {
+      await client.query('BEGIN');
+      await client.query('SELECT pg_advisory_xact_lock(hashtext($1))', [table]);
+      await client.query(
+        `CREATE TABLE IF NOT EXISTS ${PGVECTOR_MIGRATIONS_TABLE} (
+          table_name TEXT NOT NULL,
+          version INTEGER NOT NULL,
+          description TEXT NOT NULL,
+          applied_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+          PRIMARY KEY (table_name, version)
+        )`
+      );
+      const { rows } = await client.query<{ version: number }>(
+        `SELECT version FROM ${PGVECTOR_MIGRATIONS_TABLE} WHERE table_name = $1`,
+        [table]
+      );
+      const applied = new Set(rows.map((row) => Number(row.version)));
+
+      for (const migration of PGVECTOR_MIGRATIONS) {
+        if (applied.has(migration.version)) continue;
+        const statements = migration.up(table, dimensions);
+        // Nothing ran (e.g. no HNSW index above its width limit): stays pending for a later width
+        if (statements.length === 0) continue;
+        for (const statement of statements) {
+          await client.query(statement);
+        }
+        await client.query(
+          `INSERT INTO ${PGVECTOR_MIGRATIONS_TABLE} (table_name, version, description) ` +
+            'VALUES ($1, $2, $3)',
+          [table, migration.version, migration.description]
+        );
+      }
+      await client.query('COMMIT');
+    } catch (error) {
+      await client.query('ROLLBACK');
+      throw error;
+    } finally {
+      client.release();
+    }
+
+    if (dimensions > PGVECTOR_HNSW_MAX_DIMENSIONS) {
+      console.error(
+        `[pgvector] ${dimensions}-dimensional embeddings exceed the HNSW limit ` +
+          `(${PGVECTOR_HNSW_MAX_DIMENSIONS}); searches will scan the table`
+      );
+    }
+    if (isGeneration) {
+      this.generationMigrated = true;
+    } else {
+      this.tableExists = true;
+      this.migrated = true;
+    }
+  }
+
+  private toChunk(row: PgChunkRow): CodeChunk {
+    const payload = (
+      typeof row.payload === 'string' ? JSON.parse(row.payload) : row.payload
+    ) as PgChunkPayload;
+    return {
+      id: row.id,
+      content: row.content,
+      filePath: row.file_path,
+      relativePath: row.relative_path,
+      startLine: row.start_line,
+      endLine: row.end_line,
+      language: row.language,
+      framework: row.framework || undefined,
+      componentType: row.component_type || undefined,
+      layer: (row.layer || undefined) as CodeChunk['layer'],
+      dependencies: payload.dependencies ?? [],
+      imports: payload.imports ?? [],
+      exports: payload.exports ?? [],
+      tags: payload.tags ?? [],
+      metadata: payload.metadata ?? {}
+    };
+  }
+}
@@ -0,0 +1,484 @@
+/**
+ * Qdrant Storage Provider
+ * Remote vector store over Qdrant's REST API (native fetch, no client SDK).
+ * One collection per project; framework/componentType/layer/language/path filters
+ * are pushed down as payload filters.
+ *
+ * Full rebuilds write a new generation collection (`<collection>-<suffix>`) while searches
+ * keep reading the live one; committing points the project's alias at it in one request and
+ * drops the old generation, and a failed build deletes it. The project name is an alias from
+ * the first rebuild on; a collection from before that is replaced when its rebuild commits.
+ */
+
+import { createHash } from 'crypto';
+import path from 'path';
+import {
+  VectorStorageProvider,
+  CodeChunkWithEmbedding,
+  FilePathRename,
+  STORAGE_CAPABILITIES,
+  VectorSearchResult
+} from './types.js';
+import { CodeChunk, SearchFilters } from '../types/index.js';
+import { IndexCorruptedError } from '../errors/index.js';
+
+export const DEFAULT_QDRANT_URL = 'http://localhost:6333';
+
+/** Points per upsert request — keeps request bodies well under Qdrant's default 32MB limit */
+const UPSERT_BATCH_SIZE = 256;
+const SCROLL_PAGE_SIZE = 1000;
+
+const INDEXED_PAYLOAD_FIELDS = [
+  'filePath',
+  'relativePath',
+  'language',
+  'framework',
+  'componentType',
+  'layer'
+];
+
+const UUID_PATTERN = /^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$/i;
+
+interface QdrantPayload {
+  chunkId: string;
+  content: string;
+  filePath: string;
+  relativePath: string;
+  startLine: number;
+  endLine: number;
+  language: string;
+  framework: string;
+  componentType: string;
+  layer: string;
+  dependencies: string[];
+  imports: CodeChunk['imports'];
+  exports: CodeChunk['exports'];
+  tags: string[];
+  metadata: CodeChunk['metadata'];
+}
+
+interface QdrantScoredPoint {
+  id: string | number;
+  score: number;
+  payload?: QdrantPayload;
+}
+
+type QdrantCondition =
+  | { key: string; match: { value: string } }
+  | { key: string; match: { any: string[] } }
+  | { should: QdrantCondition[] };
+
+interface QdrantFilter {
+  must?: QdrantCondition[];
+  must_not?: QdrantCondition[];
+}
+
+export interface QdrantStorageOptions {
+  url?: string;
+  apiKey?: string;
+  /** Explicit collection name. Defaults to one derived from the project root. */
+  collection?: string;
+  /** Project root used to derive a stable per-project collection name */
+  rootPath?: string;
+}
+
+/**
+ * Derive a stable, Qdrant-safe collection name for a project root:
+ * `codebase-context-<basename>-<8 hex of path hash>`.
+ */
+export function deriveQdrantCollectionName(rootPath: string): string {
+  const resolved = path.resolve(rootPath).replace(/\\/g, '/').toLowerCase();
+  const base = path
+    .basename(resolved)
+    .replace(/[^a-z0-9_-]+/g, '-')
+    .replace(/^-+|-+$/g, '')
+    .slice(0, 40);
+  const hash = createHash('sha256').update(resolved).digest('hex').slice(0, 8);
+  return `codebase-context-${base || 'project'}-${hash}`;
+}
+
+/** Qdrant point ids must be UUIDs or unsigned ints; map any other chunk id onto a UUID. */
+function toPointId(chunkId: string): string {
+  if (UUID_PATTERN.test(chunkId)) return chunkId;
+  const hex = createHash('sha256').update(chunkId).digest('hex');
+  return `${hex.slice(0, 8)}-${hex.slice(8, 12)}-${hex.slice(12, 16)}-${hex.slice(16, 20)}-${hex.slice(20, 32)}`;
+}
+
+export function buildQdrantFilter(filters?: SearchFilters): QdrantFilter | undefined {
+  if (!filters) return undefined;
+
+  const must: QdrantCondition[] = [];
+  const mustNot: QdrantCondition[] = [];
+
+  if (filters.framework) must.push({ key: 'framework', match: { value: filters.framework } });
+  if (filters.componentType) {
+    must.push({ key: 'componentType', match: { value: filters.componentType } });
+  }
+  if (filters.layer) must.push({ key: 'layer', match: { value: filters.layer } });
+  if (filters.language) must.push({ key: 'language', match: { value: filters.language } });
+  if (filters.tags && filters.tags.length > 0) {
+    must.push({ key: 'tags', match: { any: filters.tags } });
+  }
+  if (filters.filePaths && filters.filePaths.length > 0) {
+    must.push({
+      should: [
+        { key: 'filePath', match: { any: filters.filePaths } },
+        { key: 'relativePath', match: { any: filters.filePaths } }
+      ]
+    });
+  }
+  if (filters.excludePaths && filters.excludePaths.length > 0) {
+    mustNot.push({ key: 'filePath', match: { any: filters.excludePaths } });
+    mustNot.push({ key: 'relativePath', match: { any: filters.excludePaths } });
+  }
+
+  if (must.length === 0 && mustNot.length === 0) return undefined;
+  return {
+    ...(must.length > 0 ? { must } : {}),
+    ...(mustNot.length > 0 ? { must_not: mustNot } : {})
+  };
+}
+
+interface QdrantScrollResponse {
+  result: {
+    points: Array<{ payload?: { filePath?: string } }>;
+    next_page_offset?: string | number | null;
+  };
+}
+
+export class QdrantStorageProvider implements VectorStorageProvider {
+  readonly name = 'qdrant';
+  readonly capabilities = STORAGE_CAPABILITIES.qdrant;
+
+  private url: string;
+  private apiKey?: string;
+  private collection: string;
+  private collectionExists = false;
+  private initialized = false;
+  /** Collection of the rebuild in progress, receiving writes until it is committed */
+  private generation: string | null = null;
+  private generationExists = false;
+
+  constructor(options: QdrantStorageOptions = {}) {
+    this.url = (options.url || DEFAULT_QDRANT_URL).replace(/\/+$/, '');
+    this.apiKey = options.apiKey;
+    this.collection =
+      options.collection || deriveQdrantCollectionName(options.rootPath || process.cwd());
+  }
+
+  get collectionName(): string {
+    return this.collection;
+  }
+
+  /**
+   * Connect and check whether the project collection exists.
+   * storagePath is unused: data lives on the Qdrant server, not on disk.
+   */
+  async initialize(_storagePath: string): Promise<void> {
+    if (this.initialized) return;
+
+    try {
+      const response = await this.request('GET', `/collections/${this.collection}`);
+      if (!response.ok && response.status !== 404) {
+        throw new Error(`Qdrant API Error ${response.status}: ${await response.text()}`);
+      }
+      this.collectionExists = response.ok || (await this.aliasTarget()) !== null;
+    } catch (error) {
+      throw new IndexCorruptedError(
+        `Qdrant initialization failed at ${this.url}: ${error instanceof Error ? error.message : String(error)}`
+      );
+    }
+
+    this.initialized = true;
+    console.error(`Qdrant initialized: ${this.url} (collection ${this.collection})`);
+  }
+
+  async store(chunks: CodeChunkWithEmbedding[]): Promise<void> {
+    if (!this.initialized) {
+      throw new Error('Storage not initialized');
+    }
+    if (chunks.length === 0) return;
+
+    await this.ensureCollection(chunks[0].embedding.length);
+    const target = this.generation ?? this.collection;
+
+    for (let i = 0; i < chunks.length; i += UPSERT_BATCH_SIZE) {
+      const batch = chunks.slice(i, i + UPSERT_BATCH_SIZE);
+      const points = batch.map((chunk) => ({
+        id: toPointId(chunk.id),
+        vector: chunk.embedding,
+        payload: this.toPayload(chunk)
+      }));
+      await this.requestJson('PUT', `/collections/${target}/points?wait=true`, { points });
+    }
+
+    console.error(`Stored ${chunks.length} chunks in Qdrant collection ${target}`);
+  }
+
+  async search(
+    queryVector: number[],
+    limit: number,
+    filters?: SearchFilters
+  ): Promise<VectorSearchResult[]> {
+    if (!this.initialized) {
+      throw new IndexCorruptedError('Qdrant storage not initialized (rebuild required)');
+    }
+    // No semantic index yet (e.g. skipEmbedding) — degrade to keyword-only search
+    if (!this.collectionExists) return [];
+
+    try {
+      const filter = buildQdrantFilter(filters);
+      const data = await this.requestJson<{ result: QdrantScoredPoint[] }>(
+        'POST',
+        `/collections/${this.collection}/points/search`,
+        {
+          vector: queryVector,
+          limit,
+          with_payload: true,
+          ...(filter ? { filter } : {})
+        }
+      );
+
+      return data.result
+        .filter((point) => point.payload)
+        .map((point) => ({
+          chunk: this.fromPayload(point.payload!),
+          score: Math.max(0, Math.min(1, point.score)),
+          distance: 1 - point.score
+        }));
+    } catch (error) {
+      // Transient network errors should not force a rebuild
+      console.error('[Qdrant] Search error:', error instanceof Error ? error.message : error);
+      return [];
+    }
+  }
+
+  async deleteByFilePaths(filePaths: string[]): Promise<number> {
+    if (!this.initialized || !this.collectionExists || filePaths.length === 0) {
+      return 0;
+    }
+
+    const filter: QdrantFilter = { must: [{ key: 'filePath', match: { any: filePaths } }] };
+    const before = await this.countMatching(filter);
+    await this.requestJson('POST', `/collections/${this.collection}/points/delete?wait=true`, {
+      filter
+    });
+    console.error(`Deleted ${before} chunks for ${filePaths.length} files from Qdrant`);
+    return before;
+  }
+
+  async renameFilePaths(renames: FilePathRename[]): Promise<number> {
+    if (!this.initialized || !this.collectionExists || renames.length === 0) return 0;
+
+    let renamed = 0;
+    for (const { from, to, relativePath } of renames) {
+      const filter: QdrantFilter = { must: [{ key: 'filePath', match: { value: from } }] };
+      const points = await this.countMatching(filter);
+      if (points === 0) continue;
+      await this.requestJson('POST', `/collections/${this.collection}/points/payload?wait=true`, {
+        payload: { filePath: to, relativePath },
+        filter
+      });
+      renamed += points;
+    }
+    console.error(`Moved ${renamed} chunks of ${renames.length} renamed files in Qdrant`);
+    return renamed;
+  }
+
+  // No compact(): Qdrant's optimizer vacuums deleted points by itself
+  async listFilePaths(): Promise<string[]> {
+    if (!this.initialized || !this.collectionExists) return [];
+
+    const paths = new Set<string>();
+    let offset: string | number | null = null;
+    do {
+      const route = `/collections/${this.collection}/points/scroll`;
+      const data = await this.requestJson<QdrantScrollResponse>('POST', route, {
+        limit: SCROLL_PAGE_SIZE,
+        with_payload: ['filePath'],
+        with_vector: false,
+        ...(offset !== null ? { offset } : {})
+      });
+      for (const point of data.result.points) {
+        if (point.payload?.filePath) paths.add(point.payload.filePath);
+      }
+      offset = data.result.next_page_offset ?? null;
+    } while (offset !== null);
+    return [...paths];
+  }
+
+  async clear(): Promise<void> {
+    if (!this.initialized || !this.collectionExists) return;
+
+    const target = await this.aliasTarget();
+    if (target) {
+      await this.requestJson('POST', '/collections/aliases', {
+        actions: [{ delete_alias: { alias_name: this.collection } }]
+      });
+    }
+    await this.deleteCollection(target ?? this.collection);
+    this.collectionExists = false;
+    console.error(`Cleared Qdrant collection ${this.collection}`);
+  }
+
+  /** Send the writes of a full rebuild to a new generation; searches keep the live one */
+  async beginGeneration(): Promise<void> {
+    if (!this.initialized) throw new Error('Storage not initialized');
+    await this.abortGeneration();
+    this.generation = `${this.collection}-${Date.now().toString(36)}`;
+    this.generationExists = false;
+  }
+
+  /** Make the rebuilt generation the live collection and drop the one it replaces */
+  async commitGeneration(): Promise<void> {
+    const next = this.generation;
+    if (!next) return;
+    this.generation = null;
+    // Nothing was stored: the rebuilt index has no vectors
+    if (!this.generationExists) {
+      await this.clear();
+      return;
+    }
+
+    const previous = await this.aliasTarget();
+    if (!previous && this.collectionExists) {
+      // A plain collection holds the name the alias needs
+      await this.deleteCollection(this.collection);
+    }
+    await this.requestJson('POST', '/collections/aliases', {
+      actions: [
+        ...(previous ? [{ delete_alias: { alias_name: this.collection } }] : []),
+        { create_alias: { collection_name: next, alias_name: this.collection } }
+      ]
+    });
+    this.collectionExists = true;
+    if (previous && previous !== next) await this.deleteCollection(previous);
+    console.error(`Qdrant collection ${this.collection} now serves ${next}`);
+  }
+
+  /** Drop a generation that won't be committed; the live collection is untouched */
+  async abortGeneration(): Promise<void> {
+    const abandoned = this.generation;
+    this.generation = null;
+    if (abandoned && this.generationExists) await this.deleteCollection(abandoned);
+    this.generationExists = false;
+  }
+
+  async count(): Promise<number> {
+    if (!this.initialized || !this.collectionExists) return 0;
+    try {
+      return await this.countMatching();
+    } catch (error) {
+      console.error('Failed to count Qdrant points:', error);
+      return 0;
+    }
+  }
+
+  isInitialized(): boolean {
+    return this.initialized;
+  }
+
+  private async ensureCollection(dimensions: number): Promise<void> {
+    if (this.generation ? this.generationExists : this.collectionExists) return;
+
+    const target = this.generation ?? this.collection;
+    await this.requestJson('PUT', `/collections/${target}`, {
+      vectors: { size: dimensions, distance: 'Cosine' }
+    });
+    // Payload indexes keep filtered search fast on large collections
+    for (const field of INDEXED_PAYLOAD_FIELDS) {
+      await this.requestJson('PUT', `/collections/${target}/index?wait=true`, {
+        field_name: field,
+        field_schema: 'keyword'
+      });
+    }
+    if (this.generation) this.generationExists = true;
+    else this.collectionExists = true;
+  }
+
+  /** Collection the project alias points at; null when the name is a plain collection or unused */
+  private async aliasTarget(): Promise<string | null> {
+    const response = await this.request('GET', '/aliases');
+    if (!response.ok) return null;
+    const data = (await response.json()) as {
+      result?: { aliases?: Array<{ alias_name: string; collection_name: string }> };
+    };
+    const alias = data.result?.aliases?.find((entry) => entry.alias_name === this.collection);
+    return alias?.collection_name ?? null;
+  }
+
+  private async deleteCollection(name: string): Promise<void> {
+    const response = await this.request('DELETE', `/collections/${name}`);
+    if (!response.ok && response.status !== 404) {
+      throw new Error(`Qdrant API Error ${response.status}: ${await response.text()}`);
+    }
+  }
+
+  private async countMatching(filter?: QdrantFilter): Promise<number> {
+    const data = await this.requestJson<{ result: { count: number } }>(
+      'POST',
+      `/collections/${this.collection}/points/count`,
+      { exact: true, ...(filter ? { filter } : {}) }
+    );
+    return data.result.count;
+  }
+
+  private toPayload(chunk: CodeChunkWithEmbedding): QdrantPayload {
+    return {
+      chunkId: chunk.id,
+      content: chunk.content,
+      filePath: chunk.filePath,
+      relativePath: chunk.relativePath,
+      startLine: chunk.startLine,
+      endLine: chunk.endLine,
+      language: chunk.language,
+      framework: chunk.framework || '',
+      componentType: chunk.componentType || '',
+      layer: chunk.layer || '',
+      dependencies: chunk.dependencies,
+      imports: chunk.imports,
+      exports: chunk.exports,
+      tags: chunk.tags,
+      metadata: chunk.metadata
+    };
+  }
+
+  private fromPayload(payload: QdrantPayload): CodeChunk {
+    return {
+      id: payload.chunkId,
+      content: payload.content,
+      filePath: payload.filePath,
+      relativePath: payload.relativePath,
+      startLine: payload.startLine,
+      endLine: payload.endLine,
+      language: payload.language,
+      framework: payload.framework || undefined,
+      componentType: payload.componentType || undefined,
+      layer: (payload.layer || undefined) as CodeChunk['layer'],
+      dependencies: payload.dependencies ?? [],
+      imports: payload.imports ?? [],
+      exports: payload.exports ?? [],
+      tags: payload.tags ?? [],
+      metadata: payload.metadata ?? {}
+    };
+  }
+
+  private request(method: string, route: string, body?: unknown): Promise<Response> {
+    return fetch(`${this.url}${route}`, {
+      method,
+      headers: {
+        'Content-Type': 'application/json',
+        ...(this.apiKey ? { 'api-key': this.apiKey } : {})
+      },
+      ...(body !== undefined ? { body: JSON.stringify(body) } : {})
+    });
+  }
+
+  private async requestJson<T = unknown>(method: string, route: string, body?: unknown): Promise<T> {
+    const response = await this.request(method, route, body);
+    if (!response.ok) {
+      throw new Error(`Qdrant API Error ${response.status}: ${await response.text()}`);
+    }
+    return (await response.json()) as T;
+  }
+}
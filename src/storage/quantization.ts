@@ -0,0 +1,150 @@
+/**
+ * In-process vector quantization for stores that scan vectors in memory.
+ *
+ * `int8` keeps one signed byte per dimension, scaled per vector so the largest component maps
+ * to ±127 (4x smaller than float32). `binary` keeps one sign bit per dimension (32x smaller).
+ * Quantized codes are only used to shortlist candidates: the top `limit × oversampling` are
+ * rescored against the exact float32 vectors, which stay on disk.
+ */
+
+export type QuantizationMode = 'none' | 'int8' | 'binary';
+
+export const QUANTIZATION_MODES: readonly QuantizationMode[] = ['none', 'int8', 'binary'];
+
+/** Candidates rescored per requested result; binary codes are coarser, so they shortlist more */
+export const RESCORE_OVERSAMPLING: Record<Exclude<QuantizationMode, 'none'>, number> = {
+  int8: 4,
+  binary: 10
+};
+
+/** Never rescore fewer than this many candidates, so tiny limits keep their recall */
+const MIN_RESCORE_CANDIDATES = 50;
+
+export function isQuantizationMode(value: unknown): value is QuantizationMode {
+  return typeof value === 'string' && (QUANTIZATION_MODES as readonly string[]).includes(value);
+}
+
+/** Quantization for new indexes: the configured mode, else `CODEBASE_CONTEXT_QUANTIZATION` */
+export function resolveQuantizationMode(
+  configured?: string,
+  env: NodeJS.ProcessEnv = process.env
+): QuantizationMode {
+  const fromEnv = env.CODEBASE_CONTEXT_QUANTIZATION?.trim().toLowerCase();
+  if (isQuantizationMode(configured)) return configured;
+  return isQuantizationMode(fromEnv) ? fromEnv : 'none';
+}
+
+export function rescoreCandidateCount(
+  mode: Exclude<QuantizationMode, 'none'>,
+  limit: number
+): number {
+  return Math.max(limit * RESCORE_OVERSAMPLING[mode], MIN_RESCORE_CANDIDATES);
+}
+
+/** Bytes per quantized vector */
+export function quantizedByteLength(
+  mode: Exclude<QuantizationMode, 'none'>,
+  dims: number
+): number {
+  return mode === 'int8' ? dims : Math.ceil(dims / 8);
+}
+
+export function quantizeVector(
+  mode: Exclude<QuantizationMode, 'none'>,
+  vector: ArrayLike<number>
+): Uint8Array {
+  if (mode === 'binary') {
+    const bits = new Uint8Array(Math.ceil(vector.length / 8));
+    for (let i = 0; i < vector.length; i++) {
+      if (vector[i] > 0) bits[i >> 3] |= 1 << (i & 7);
+    }
+    return bits;
+  }
+
+  let maxAbs = 0;
+  for (let i = 0; i < vector.length; i++) maxAbs = Math.max(maxAbs, Math.abs(vector[i]));
+  const codes = new Int8Array(vector.length);
+  if (maxAbs > 0) {
+    for (let i = 0; i < vector.length; i++) {
+      codes[i] = Math.round((vector[i] / maxAbs) * 127);
+    }
+  }
+  return new Uint8Array(codes.buffer);
+}
+
+const POPCOUNT = new Uint8Array(256);
+for (let i = 1; i < 256; i++) POPCOUNT[i] = (i & 1) + POPCOUNT[i >> 1];
+
+/**
+ * Quantized vectors packed into one contiguous buffer, scanned for approximate top-k.
+ * int8 scores are cosine similarity against the float query (the per-vector scale cancels);
+ * binary scores are the fraction of matching sign bits.
+ */
+export class QuantizedMatrix {
+  private codes: Uint8Array;
+  private norms: Float32Array;
+  private size = 0;
+
+  constructor(
+    readonly mode: Exclude<QuantizationMode, 'none'>,
+    readonly dims: number,
+    capacity: number
+  ) {
+    this.codes = new Uint8Array(capacity * quantizedByteLength(mode, dims));
+    this.norms = new Float32Array(mode === 'int8' ? capacity : 0);
+  }
+
+  get length(): number {
+    return this.size;
+  }
+
+  /** Append one vector's codes; returns its row number */
+  add(code: Uint8Array): number {
+    const width = quantizedByteLength(this.mode, this.dims);
+    const row = this.size++;
+    this.codes.set(code.subarray(0, width), row * width);
+    if (this.mode === 'int8') {
+      const signed = new Int8Array(this.codes.buffer, row * width, width);
+      let norm = 0;
+      for (let i = 0; i < width; i++) norm += signed[i] * signed[i];
+      this.norms[row] = Math.sqrt(norm);
+    }
+    return row;
+  }
+
+  /** Approximate top-k over the rows `accept` keeps (all rows when omitted), best first */
+  topK(
+    query: readonly number[],
+    k: number,
+    accept?: (row: number) => boolean
+  ): Array<{ row: number; score: number }> {
+    if (query.length !== this.dims) return [];
+    const width = quantizedByteLength(this.mode, this.dims);
+    const scored: Array<{ row: number; score: number }> = [];
+
+    if (this.mode === 'binary') {
+      const queryBits = quantizeVector('binary', query);
+      for (let row = 0; row < this.size; row++) {
+        if (accept && !accept(row)) continue;
+        let matching = this.dims;
+        const offset = row * width;
+        for (let i = 0; i < width; i++) matching -= POPCOUNT[queryBits[i] ^ this.codes[offset + i]];
+        scored.push({ row, score: matching / this.dims });
+      }
+    } else {
+      const signed = new Int8Array(this.codes.buffer, 0, this.size * width);
+      const queryNorm = Math.sqrt(query.reduce((sum, v) => sum + v * v, 0));
+      for (let row = 0; row < this.size; row++) {
+        if (accept && !accept(row)) continue;
+        let dot = 0;
+        const offset = row * width;
+        for (let i = 0; i < width; i++) dot += query[i] * signed[offset + i];
+        const denom = queryNorm * this.norms[row];
+        scored.push({ row, score: denom === 0 ? 0 : dot / denom });
+      }
+    }
+
+    scored.sort((a, b) => b.score - a.score);
+    return scored.slice(0, k);
+  }
+}
@@ -0,0 +1,631 @@
+/**
+ * SQLite Storage Provider
+ * Single-file embedded store using Node's built-in `node:sqlite` (Node >= 22.5), so it adds
+ * no dependencies. Chunks and metadata live in regular columns, vectors as Float32 blobs;
+ * search is brute-force cosine over the rows that pass the SQL filters, which is fast enough
+ * for small and medium repos.
+ *
+ * With int8 or binary quantization (chosen when the database is created), search scans an
+ * in-memory matrix of quantized codes instead and rescores the shortlist against the float32
+ * vectors read back from disk, so memory grows by 1 byte (or 1 bit) per dimension per chunk.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import {
+  VectorStorageProvider,
+  CodeChunkWithEmbedding,
+  FilePathRename,
+  STORAGE_CAPABILITIES,
+  VectorSearchResult
+} from './types.js';
+import { CodeChunk, SearchFilters } from '../types/index.js';
+import { IndexCorruptedError } from '../errors/index.js';
+import {
+  QuantizedMatrix,
+  isQuantizationMode,
+  quantizeVector,
+  rescoreCandidateCount,
+  type QuantizationMode
+} from './quantization.js';
+
+export const SQLITE_DB_FILENAME = 'chunks.sqlite';
+
+type SqliteValue = string | number | null | Uint8Array;
+
+interface SqliteStatement {
+  run(...params: SqliteValue[]): unknown;
+  all(...params: SqliteValue[]): unknown[];
+  get(...params: SqliteValue[]): unknown;
+}
+
+interface SqliteDatabase {
+  exec(sql: string): void;
+  prepare(sql: string): SqliteStatement;
+  close(): void;
+}
+
+interface NodeSqliteModule {
+  DatabaseSync: new (location: string) => SqliteDatabase;
+}
+
+interface SqliteChunkRow {
+  id: string;
+  file_path: string;
+  relative_path: string;
+  start_line: number;
+  end_line: number;
+  language: string;
+  framework: string;
+  component_type: string;
+  layer: string;
+  content: string;
+  payload: string;
+  vector: Uint8Array;
+  qvector: Uint8Array | null;
+}
+
+type SqliteVectorRow = Pick<SqliteChunkRow, 'id' | 'vector'>;
+
+type SqliteFilterColumn = 'language' | 'framework' | 'component_type' | 'layer';
+
+type SqliteCodeRow = Pick<
+  SqliteChunkRow,
+  'id' | 'file_path' | 'relative_path' | SqliteFilterColumn
+> & {
+  rowid: number;
+  qvector: Uint8Array;
+};
+
+/** Quantized codes of every row plus dictionary-encoded filter columns, in matrix row order */
+interface QuantizedScanIndex {
+  matrix: QuantizedMatrix;
+  ids: string[];
+  columns: Record<SqliteFilterColumn, { values: string[]; codes: Uint16Array }>;
+  /** One code per file: relative paths, the matching absolute paths, and each row's code */
+  files: { relative: string[]; absolute: string[]; codes: Uint32Array };
+}
+
+interface SqliteChunkPayload {
+  dependencies: string[];
+  imports: string[];
+  exports: string[];
+  tags: string[];
+  metadata: CodeChunk['metadata'];
+}
+
+const SCHEMA = `
+CREATE TABLE IF NOT EXISTS code_chunks (
+  id TEXT PRIMARY KEY,
+  file_path TEXT NOT NULL,
+  relative_path TEXT NOT NULL,
+  start_line INTEGER NOT NULL,
+  end_line INTEGER NOT NULL,
+  language TEXT NOT NULL,
+  framework TEXT NOT NULL DEFAULT '',
+  component_type TEXT NOT NULL DEFAULT '',
+  layer TEXT NOT NULL DEFAULT '',
+  content TEXT NOT NULL,
+  payload TEXT NOT NULL,
+  vector BLOB NOT NULL,
+  qvector BLOB
+);
+CREATE INDEX IF NOT EXISTS idx_code_chunks_file_path ON code_chunks (file_path);
+CREATE INDEX IF NOT EXISTS idx_code_chunks_language ON code_chunks (language);
+CREATE TABLE IF NOT EXISTS store_meta (
+  key TEXT PRIMARY KEY,
+  value TEXT NOT NULL
+);
+`;
+
+const FILTER_COLUMNS: readonly SqliteFilterColumn[] = [
+  'language',
+  'framework',
+  'component_type',
+  'layer'
+];
+const SCAN_PAGE_SIZE = 10000;
+const RESCORE_BATCH_SIZE = 500;
+
+/** Every column but the vectors, for the rows a search returns */
+const CHUNK_COLUMNS =
+  'id, file_path, relative_path, start_line, end_line, language, framework, component_type, ' +
+  'layer, content, payload';
+
+/** SQLite messages that mean the file or schema is damaged, rather than busy or unreachable */
+const CORRUPTION_PATTERN =
+  /no such (table|column)|malformed|not a database|SQLITE_(CORRUPT|NOTADB)/i;
+
+/** A damaged index needs a rebuild; anything else (locks, I/O, permissions) is passed on */
+function classifySqliteError(error: unknown, what: string): Error {
+  if (error instanceof IndexCorruptedError) return error;
+  const message = error instanceof Error ? error.message : String(error);
+  if (CORRUPTION_PATTERN.test(message)) {
+    return new IndexCorruptedError(`${what} (rebuild required): ${message}`);
+  }
+  return error instanceof Error ? error : new Error(message);
+}
+
+async function loadNodeSqlite(): Promise<NodeSqliteModule> {
+  // Non-literal specifier keeps bundlers/type-checkers on older Node typings from resolving it
+  const specifier = 'node:sqlite';
+  try {
+    return (await import(specifier)) as NodeSqliteModule;
+  } catch {
+    throw new Error(
+      `SQLite storage requires Node.js >= 22.5 (built-in node:sqlite). Current: ${process.version}. ` +
+        'Use STORAGE_PROVIDER=lancedb instead.'
+    );
+  }
+}
+
+function encodeVector(vector: number[]): Uint8Array {
+  const floats = Float32Array.from(vector);
+  return new Uint8Array(floats.buffer, floats.byteOffset, floats.byteLength);
+}
+
+function decodeVector(blob: Uint8Array): Float32Array {
+  if (blob.byteLength % 4 !== 0) {
+    throw new IndexCorruptedError(
+      `SQLite vector of ${blob.byteLength} bytes is not a float32 array (rebuild required)`
+    );
+  }
+  // Copy to guarantee 4-byte alignment regardless of how the driver allocated the blob
+  const copy = new Uint8Array(blob.byteLength);
+  copy.set(blob);
+  return new Float32Array(copy.buffer, 0, copy.byteLength / 4);
+}
+
+function cosineSimilarity(query: number[], queryNorm: number, vector: Float32Array): number {
+  if (vector.length !== query.length) return 0;
+  let dot = 0;
+  let norm = 0;
+  for (let i = 0; i < vector.length; i++) {
+    dot += query[i] * vector[i];
+    norm += vector[i] * vector[i];
+  }
+  const denom = queryNorm * Math.sqrt(norm);
+  return denom === 0 ? 0 : dot / denom;
+}
+
+export interface SQLiteStorageOptions {
+  /** Quantization for a newly created database; an existing one keeps its own */
+  quantization?: QuantizationMode;
+}
+
+export class SQLiteStorageProvider implements VectorStorageProvider {
+  readonly name = 'sqlite';
+  readonly capabilities = STORAGE_CAPABILITIES.sqlite;
+
+  private db: SqliteDatabase | null = null;
+  private dbPath = '';
+  private initialized = false;
+  private quantization: QuantizationMode;
+  private scanIndex: QuantizedScanIndex | null = null;
+
+  constructor(options: SQLiteStorageOptions = {}) {
+    this.quantization = options.quantization ?? 'none';
+  }
+
+  /** Quantization this database was created with */
+  get quantizationMode(): QuantizationMode {
+    return this.quantization;
+  }
+
+  async initialize(storagePath: string): Promise<void> {
+    if (this.initialized) return;
+
+    const sqlite = await loadNodeSqlite();
+
+    try {
+      await fs.mkdir(storagePath, { recursive: true });
+      this.dbPath = path.join(storagePath, SQLITE_DB_FILENAME);
+      this.db = new sqlite.DatabaseSync(this.dbPath);
+      this.db.exec(SCHEMA);
+      this.migrateSchema(this.db);
+      this.quantization = this.resolveStoredQuantization(this.db);
+      this.initialized = true;
+      console.error(`SQLite storage initialized at: ${this.dbPath}`);
+    } catch (error) {
+      throw classifySqliteError(error, 'SQLite initialization failed');
+    }
+  }
+
+  async store(chunks: CodeChunkWithEmbedding[]): Promise<void> {
+    if (!this.initialized || !this.db) {
+      throw new Error('Storage not initialized');
+    }
+    if (chunks.length === 0) return;
+
+    const insert = this.db.prepare(
+      `INSERT OR REPLACE INTO code_chunks
+        (id, file_path, relative_path, start_line, end_line, language, framework,
+         component_type, layer, content, payload, vector, qvector)
+       VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+    );
+    const quantization = this.quantization;
+    this.scanIndex = null;
+
+    this.db.exec('BEGIN');
+    try {
+      for (const chunk of chunks) {
+        const payload: SqliteChunkPayload = {
+          dependencies: chunk.dependencies,
+          imports: chunk.imports,
+          exports: chunk.exports,
+          tags: chunk.tags,
+          metadata: chunk.metadata
+        };
+        insert.run(
+          chunk.id,
+          chunk.filePath,
+          chunk.relativePath,
+          chunk.startLine,
+          chunk.endLine,
+          chunk.language,
+          chunk.framework || '',
+          chunk.componentType || '',
+          chunk.layer || '',
+          chunk.content,
+          JSON.stringify(payload),
+          encodeVector(chunk.embedding),
+          quantization === 'none' ? null : quantizeVector(quantization, chunk.embedding)
+        );
+      }
+      this.db.exec('COMMIT');
+    } catch (error) {
+      this.db.exec('ROLLBACK');
+      console.error('Failed to store chunks:', error);
+      throw error;
+    }
+
+    console.error(`Stored ${chunks.length} chunks in SQLite`);
+  }
+
+  async search(
+    queryVector: number[],
+    limit: number,
+    filters?: SearchFilters
+  ): Promise<VectorSearchResult[]> {
+    if (!this.initialized || !this.db) {
+      throw new IndexCorruptedError('SQLite storage not initialized (rebuild required)');
+    }
+    if (this.quantization !== 'none') {
+      return this.searchQuantized(this.db, this.quantization, queryVector, limit, filters);
+    }
+
+    const where: string[] = [];
+    const params: SqliteValue[] = [];
+    if (filters?.framework) {
+      where.push('framework = ?');
+      params.push(filters.framework);
+    }
+    if (filters?.componentType) {
+      where.push('component_type = ?');
+      params.push(filters.componentType);
+    }
+    if (filters?.layer) {
+      where.push('layer = ?');
+      params.push(filters.layer);
+    }
+    if (filters?.language) {
+      where.push('language = ?');
+      params.push(filters.language);
+    }
+    const addPathCondition = (paths: string[] | undefined, negate: boolean) => {
+      if (!paths || paths.length === 0) return;
+      const list = paths.map(() => '?').join(', ');
+      where.push(`${negate ? 'NOT ' : ''}(file_path IN (${list}) OR relative_path IN (${list}))`);
+      params.push(...paths, ...paths);
+    };
+    // Resolved file scope (path, test, size and recency filters), absolute or relative
+    addPathCondition(filters?.filePaths, false);
+    addPathCondition(filters?.excludePaths, true);
+
+    // Score on ids and vectors alone; content and payload are read for the top rows only
+    const clause = where.length > 0 ? ` WHERE ${where.join(' AND ')}` : '';
+    let rows: SqliteVectorRow[];
+    try {
+      rows = this.db
+        .prepare(`SELECT id, vector FROM code_chunks${clause}`)
+        .all(...params) as SqliteVectorRow[];
+    } catch (error) {
+      throw classifySqliteError(error, 'SQLite query failed');
+    }
+
+    return this.rankRows(this.db, queryVector, rows, limit);
+  }
+
+  async deleteByFilePaths(filePaths: string[]): Promise<number> {
+    if (!this.initialized || !this.db || filePaths.length === 0) {
+      return 0;
+    }
+
+    const placeholders = filePaths.map(() => '?').join(', ');
+    this.scanIndex = null;
+    const before = await this.count();
+    this.db
+      .prepare(`DELETE FROM code_chunks WHERE file_path IN (${placeholders})`)
+      .run(...filePaths);
+    const deleted = before - (await this.count());
+    console.error(`Deleted ${deleted} chunks for ${filePaths.length} files from SQLite`);
+    return deleted;
+  }
+
+  async renameFilePaths(renames: FilePathRename[]): Promise<number> {
+    if (!this.initialized || !this.db || renames.length === 0) return 0;
+
+    this.scanIndex = null;
+    const count = this.db.prepare('SELECT COUNT(*) AS n FROM code_chunks WHERE file_path = ?');
+    const update = this.db.prepare(
+      'UPDATE code_chunks SET file_path = ?, relative_path = ? WHERE file_path = ?'
+    );
+    let renamed = 0;
+    for (const { from, to, relativePath } of renames) {
+      renamed += (count.get(from) as { n: number } | undefined)?.n ?? 0;
+      update.run(to, relativePath, from);
+    }
+    console.error(`Moved ${renamed} chunks of ${renames.length} renamed files in SQLite`);
+    return renamed;
+  }
+
+  async listFilePaths(): Promise<string[]> {
+    if (!this.initialized || !this.db) return [];
+    const rows = this.db.prepare('SELECT DISTINCT file_path FROM code_chunks').all() as Array<{
+      file_path: string;
+    }>;
+    return rows.map((row) => row.file_path);
+  }
+
+  /** Rewrite the database file so pages freed by deletes go back to the filesystem */
+  async compact(): Promise<void> {
+    if (!this.initialized || !this.db) return;
+    this.db.exec('VACUUM');
+  }
+
+  async clear(): Promise<void> {
+    if (!this.initialized || !this.db) return;
+    this.db.exec('DELETE FROM code_chunks');
+    this.scanIndex = null;
+    console.error('Cleared SQLite storage');
+  }
+
+  async count(): Promise<number> {
+    if (!this.initialized || !this.db) return 0;
+    const row = this.db.prepare('SELECT COUNT(*) AS n FROM code_chunks').get() as { n: number };
+    return Number(row.n);
+  }
+
+  isInitialized(): boolean {
+    return this.initialized;
+  }
+
+  /** Release the file handle (needed before the staging directory is swapped on Windows) */
+  async close(): Promise<void> {
+    this.db?.close();
+    this.db = null;
+    this.scanIndex = null;
+    this.initialized = false;
+  }
+
+  /** Databases created before quantization support lack the qvector column */
+  private migrateSchema(db: SqliteDatabase): void {
+    const columns = db.prepare('PRAGMA table_info(code_chunks)').all() as Array<{ name: string }>;
+    if (!columns.some((column) => column.name === 'qvector')) {
+      db.exec('ALTER TABLE code_chunks ADD COLUMN qvector BLOB');
+    }
+  }
+
+  /**
+   * The mode recorded at creation wins. An empty database without one takes the configured
+   * mode; a populated one predates quantization and stays unquantized.
+   */
+  private resolveStoredQuantization(db: SqliteDatabase): QuantizationMode {
+    const stored = db.prepare("SELECT value FROM store_meta WHERE key = 'quantization'").get() as
+      | { value: string }
+      | undefined;
+    if (stored && isQuantizationMode(stored.value)) return stored.value;
+
+    const { n } = db.prepare('SELECT COUNT(*) AS n FROM code_chunks').get() as { n: number };
+    const mode = Number(n) === 0 ? this.quantization : 'none';
+    db.prepare("INSERT OR REPLACE INTO store_meta (key, value) VALUES ('quantization', ?)").run(
+      mode
+    );
+    return mode;
+  }
+
+  /** Load quantized codes page by page, so the float32 vectors never sit in memory together */
+  private loadScanIndex(
+    db: SqliteDatabase,
+    mode: Exclude<QuantizationMode, 'none'>,
+    dims: number
+  ): QuantizedScanIndex {
+    const { n } = db
+      .prepare('SELECT COUNT(*) AS n FROM code_chunks WHERE qvector IS NOT NULL')
+      .get() as { n: number };
+    const matrix = new QuantizedMatrix(mode, dims, Number(n));
+    const ids: string[] = [];
+    const columns = Object.fromEntries(
+      FILTER_COLUMNS.map((column) => [column, { values: [], codes: new Uint16Array(Number(n)) }])
+    ) as QuantizedScanIndex['columns'];
+    const dictionaries = new Map<SqliteFilterColumn, Map<string, number>>(
+      FILTER_COLUMNS.map((column) => [column, new Map()])
+    );
+    const files: QuantizedScanIndex['files'] = {
+      relative: [],
+      absolute: [],
+      codes: new Uint32Array(Number(n))
+    };
+    const fileCodes = new Map<string, number>();
+
+    const page = db.prepare(
+      `SELECT rowid, id, file_path, relative_path, language, framework, component_type, layer,
+       qvector FROM code_chunks
+       WHERE qvector IS NOT NULL AND rowid > ? ORDER BY rowid LIMIT ${SCAN_PAGE_SIZE}`
+    );
+    let lastRowId = 0;
+    for (;;) {
+      const rows = page.all(lastRowId) as SqliteCodeRow[];
+      for (const row of rows) {
+        if (matrix.length >= Number(n)) break;
+        const matrixRow = matrix.add(row.qvector);
+        ids.push(row.id);
+        for (const column of FILTER_COLUMNS) {
+          const dictionary = dictionaries.get(column)!;
+          const value = row[column] ?? '';
+          let code = dictionary.get(value);
+          if (code === undefined) {
+            code = columns[column].values.push(value) - 1;
+            dictionary.set(value, code);
+          }
+          columns[column].codes[matrixRow] = code;
+        }
+        let fileCode = fileCodes.get(row.file_path);
+        if (fileCode === undefined) {
+          fileCode = files.absolute.push(row.file_path) - 1;
+          files.relative.push(row.relative_path);
+          fileCodes.set(row.file_path, fileCode);
+        }
+        files.codes[matrixRow] = fileCode;
+      }
+      if (rows.length < SCAN_PAGE_SIZE) break;
+      lastRowId = Number(rows[rows.length - 1].rowid);
+    }
+
+    return { matrix, ids, columns, files };
+  }
+
+  private async searchQuantized(
+    db: SqliteDatabase,
+    mode: Exclude<QuantizationMode, 'none'>,
+    queryVector: number[],
+    limit: number,
+    filters?: SearchFilters
+  ): Promise<VectorSearchResult[]> {
+    if (!this.scanIndex || this.scanIndex.matrix.dims !== queryVector.length) {
+      try {
+        this.scanIndex = this.loadScanIndex(db, mode, queryVector.length);
+      } catch (error) {
+        throw classifySqliteError(error, 'SQLite query failed');
+      }
+    }
+    const scanIndex = this.scanIndex;
+
+    // Filters become dictionary codes; a value absent from the index matches nothing
+    const wanted: Array<[Uint16Array, number]> = [];
+    const filterValues: Array<[SqliteFilterColumn, string | undefined]> = [
+      ['framework', filters?.framework],
+      ['component_type', filters?.componentType],
+      ['layer', filters?.layer],
+      ['language', filters?.language]
+    ];
+    for (const [column, value] of filterValues) {
+      if (!value) continue;
+      const code = scanIndex.columns[column].values.indexOf(value);
+      if (code === -1) return [];
+      wanted.push([scanIndex.columns[column].codes, code]);
+    }
+    // The resolved file scope, as sets of file codes (absolute or relative paths)
+    const fileCodesFor = (paths?: string[]): Set<number> | null => {
+      if (!paths || paths.length === 0) return null;
+      const listed = new Set(paths);
+      const codes = new Set<number>();
+      scanIndex.files.absolute.forEach((absolute, code) => {
+        if (listed.has(absolute) || listed.has(scanIndex.files.relative[code])) codes.add(code);
+      });
+      return codes;
+    };
+    const included = fileCodesFor(filters?.filePaths);
+    const excluded = fileCodesFor(filters?.excludePaths);
+    if (included?.size === 0) return [];
+
+    const accept =
+      wanted.length > 0 || included || excluded
+        ? (row: number) =>
+            wanted.every(([codes, code]) => codes[row] === code) &&
+            (!included || included.has(scanIndex.files.codes[row])) &&
+            (!excluded || !excluded.has(scanIndex.files.codes[row]))
+        : undefined;
+
+    const ids = scanIndex.matrix
+      .topK(queryVector, rescoreCandidateCount(mode, limit), accept)
+      .map(({ row }) => scanIndex.ids[row]);
+
+    // Rescore the shortlist with the exact vectors
+    const rows = this.selectByIds<SqliteVectorRow>(db, 'id, vector', ids);
+    return this.rankRows(db, queryVector, rows, limit);
+  }
+
+  /** Exact cosine ranking of candidate vectors; only the top `limit` are read in full */
+  private rankRows(
+    db: SqliteDatabase,
+    queryVector: number[],
+    rows: SqliteVectorRow[],
+    limit: number
+  ): VectorSearchResult[] {
+    const queryNorm = Math.sqrt(queryVector.reduce((sum, v) => sum + v * v, 0));
+    const top = rows
+      .map((row) => ({
+        id: row.id,
+        similarity: cosineSimilarity(queryVector, queryNorm, decodeVector(row.vector))
+      }))
+      .sort((a, b) => b.similarity - a.similarity)
+      .slice(0, limit);
+
+    const chunks = new Map(
+      this.selectByIds<Omit<SqliteChunkRow, 'vector' | 'qvector'>>(
+        db,
+        CHUNK_COLUMNS,
+        top.map(({ id }) => id)
+      ).map((row) => [row.id, this.toChunk(row)])
+    );
+    return top.flatMap(({ id, similarity }) => {
+      const chunk = chunks.get(id);
+      return chunk ? [{ chunk, score: Math.max(0, similarity), distance: 1 - similarity }] : [];
+    });
+  }
+
+  private selectByIds<T>(db: SqliteDatabase, columns: string, ids: string[]): T[] {
+    const rows: T[] = [];
+    try {
+      for (let i = 0; i < ids.length; i += RESCORE_BATCH_SIZE) {
+        const batch = ids.slice(i, i + RESCORE_BATCH_SIZE);
+        const statement = db.prepare(
+          `SELECT ${columns} FROM code_chunks WHERE id IN (${batch.map(() => '?').join(', ')})`
+        );
+        rows.push(...(statement.all(...batch) as T[]));
+      }
+    } catch (error) {
+      throw classifySqliteError(error, 'SQLite query failed');
+    }
+    return rows;
+  }
+
+  private toChunk(row: Omit<SqliteChunkRow, 'vector' | 'qvector'>): CodeChunk {
+    let payload: SqliteChunkPayload;
+    try {
+      payload = JSON.parse(row.payload) as SqliteChunkPayload;
+    } catch {
+      throw new IndexCorruptedError(
+        `SQLite payload of chunk ${row.id} is unreadable (rebuild required)`
+      );
+    }
+    return {
+      id: row.id,
+      content: row.content,
+      filePath: row.file_path,
+      relativePath: row.relative_path,
+      startLine: row.start_line,
+      endLine: row.end_line,
+      language: row.language,
+      framework: row.framework || undefined,
+      componentType: row.component_type || undefined,
+      layer: (row.layer || undefined) as CodeChunk['layer'],
+      dependencies: payload.dependencies ?? [],
+      imports: payload.imports ?? [],
+      exports: payload.exports ?? [],
+      tags: payload.tags ?? [],
+      metadata: payload.metadata ?? {}
+    };
+  }
+}
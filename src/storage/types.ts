@@ -4,10 +4,17 @@
 
 import { CodeChunk, SearchFilters } from '../types/index.js';
 import { CODEBASE_CONTEXT_DIRNAME, VECTOR_DB_DIRNAME } from '../constants/codebase-context.js';
+import type { QuantizationMode } from './quantization.js';
 
 export interface VectorStorageProvider {
   readonly name: string;
 
+  /**
+   * What the backend does itself; search plans its queries from it (see STORAGE_CAPABILITIES).
+   * Providers without it get DEFAULT_STORAGE_CAPABILITIES.
+   */
+  readonly capabilities?: StorageCapabilities;
+
   /**
    * Initialize the storage (create database, tables, etc.)
    */
@@ -33,6 +40,12 @@ export interface VectorStorageProvider {
    */
   deleteByFilePaths(filePaths: string[]): Promise<number>;
 
+  /**
+   * Point the chunks of moved files at their new paths, keeping their vectors (optional;
+   * without it a move is indexed as a delete and an add). Returns the number of updated rows.
+   */
+  renameFilePaths?(renames: FilePathRename[]): Promise<number>;
+
   /**
    * Clear all stored data
    */
@@ -47,6 +60,38 @@ export interface VectorStorageProvider {
    * Check if storage is initialized
    */
   isInitialized(): boolean;
+
+  /**
+   * Distinct file paths that have stored chunks (optional; used by index reconciliation)
+   */
+  listFilePaths?(): Promise<string[]>;
+
+  /**
+   * Full rebuilds (optional; remote backends whose one collection is live while it rebuilds).
+   * Between begin and commit, writes go to a new generation that searches don't see; commit
+   * makes it live in one step and abort drops it, leaving the live data untouched.
+   */
+  beginGeneration?(): Promise<void>;
+  commitGeneration?(): Promise<void>;
+  abortGeneration?(): Promise<void>;
+
+  /**
+   * Reclaim space left behind by deletes (optional; backends that compact on their own skip it)
+   */
+  compact?(): Promise<void>;
+
+  /**
+   * Release open handles (optional; file-backed providers that hold locks implement it)
+   */
+  close?(): Promise<void>;
+}
+
+export interface FilePathRename {
+  /** Stored `filePath` of the old location */
+  from: string;
+  /** `filePath` and `relativePath` of the new one */
+  to: string;
+  relativePath: string;
 }
 
 export interface CodeChunkWithEmbedding extends CodeChunk {
@@ -59,12 +104,130 @@ export interface VectorSearchResult {
   distance: number;
 }
 
+export type StorageProviderName = 'lancedb' | 'sqlite' | 'qdrant' | 'pgvector' | 'milvus';
+
+/**
+ * What a backend can do natively. Search pushes down only the filters a backend applies and
+ * over-fetches to apply the rest in process; keyword ranking always runs in process (BM25
+ * over the keyword index), so no backend needs native hybrid search.
+ */
+export interface StorageCapabilities {
+  /** Filters the store applies itself */
+  filters: {
+    /** language, framework, componentType and layer */
+    fields: boolean;
+    /** Any of `tags` */
+    tags: boolean;
+    /** package, dotnetProject, role and `metadata.*` */
+    metadata: boolean;
+  };
+  /** Longest filePaths/excludePaths list one query may carry; 0 when paths can't be pushed */
+  maxFilterPaths: number;
+  /** Storing a chunk id that is already stored replaces it instead of adding a duplicate */
+  upserts: boolean;
+  /** Projects share one server, each in its own collection or table */
+  namespaces: boolean;
+}
+
+/**
+ * Assumed for a provider that doesn't declare its own: field and path filters only. Search
+ * re-checks field and tag filters on every result, so a store that ignores them returns
+ * fewer results, never wrong ones.
+ */
+export const DEFAULT_STORAGE_CAPABILITIES: StorageCapabilities = {
+  filters: { fields: true, tags: false, metadata: false },
+  maxFilterPaths: 1000,
+  upserts: false,
+  namespaces: false
+};
+
+export const STORAGE_CAPABILITIES: Record<StorageProviderName, StorageCapabilities> = {
+  lancedb: {
+    filters: { fields: true, tags: false, metadata: false },
+    maxFilterPaths: 1000,
+    upserts: false,
+    namespaces: false
+  },
+  sqlite: {
+    filters: { fields: true, tags: false, metadata: false },
+    // Each path is bound twice (file_path, relative_path), well under SQLite's variable limit
+    maxFilterPaths: 1000,
+    upserts: true,
+    namespaces: false
+  },
+  qdrant: {
+    filters: { fields: true, tags: true, metadata: false },
+    maxFilterPaths: 1000,
+    upserts: true,
+    namespaces: true
+  },
+  pgvector: {
+    filters: { fields: true, tags: true, metadata: false },
+    maxFilterPaths: 1000,
+    upserts: true,
+    namespaces: true
+  },
+  milvus: {
+    filters: { fields: true, tags: true, metadata: false },
+    // Filter expressions are parsed per query; long `in` lists slow every search down
+    maxFilterPaths: 500,
+    upserts: true,
+    namespaces: true
+  }
+};
+
+export function storageCapabilities(provider: VectorStorageProvider): StorageCapabilities {
+  return provider.capabilities ?? DEFAULT_STORAGE_CAPABILITIES;
+}
+
 export interface StorageConfig {
-  provider: 'lancedb';
+  provider: StorageProviderName;
   path: string;
+  /** Project root; remote backends derive a per-project collection/table name from it */
+  rootPath?: string;
+  /** Remote backends only */
+  url?: string;
+  apiKey?: string;
+  /** Qdrant or Milvus collection, or pgvector table */
+  collection?: string;
+  /** In-process vector quantization for a new index (sqlite only) */
+  quantization?: QuantizationMode;
 }
 
-export const DEFAULT_STORAGE_CONFIG: StorageConfig = {
-  provider: 'lancedb',
-  path: `${CODEBASE_CONTEXT_DIRNAME}/${VECTOR_DB_DIRNAME}`
-};
+export function isStorageProviderName(value: unknown): value is StorageProviderName {
+  return typeof value === 'string' && Object.hasOwn(STORAGE_CAPABILITIES, value);
+}
+
+/** Backends that keep one server-side collection per project instead of files on disk */
+export function isRemoteStorageProvider(name: string): boolean {
+  return isStorageProviderName(name) && STORAGE_CAPABILITIES[name].namespaces;
+}
+
+const defaultProvider: StorageProviderName = isStorageProviderName(process.env.STORAGE_PROVIDER)
+  ? process.env.STORAGE_PROVIDER
+  : 'lancedb';
+
+// Embedded LanceDB by default; SQLite and remote backends are opt-in via STORAGE_PROVIDER
+export const DEFAULT_STORAGE_CONFIG: StorageConfig =
+  defaultProvider === 'pgvector'
+    ? {
+        provider: defaultProvider,
+        path: `${CODEBASE_CONTEXT_DIRNAME}/${VECTOR_DB_DIRNAME}`,
+        url: process.env.PGVECTOR_URL,
+        collection: process.env.PGVECTOR_TABLE
+      }
+    : defaultProvider === 'milvus'
+    ? {
+        provider: defaultProvider,
+        path: `${CODEBASE_CONTEXT_DIRNAME}/${VECTOR_DB_DIRNAME}`,
+        url: process.env.MILVUS_URL,
+        apiKey: process.env.MILVUS_TOKEN,
+        collection: process.env.MILVUS_COLLECTION
+      }
+    : {
+        provider: defaultProvider,
+        path: `${CODEBASE_CONTEXT_DIRNAME}/${VECTOR_DB_DIRNAME}`,
+        url: process.env.QDRANT_URL,
+        apiKey: process.env.QDRANT_API_KEY,
+        collection: process.env.QDRANT_COLLECTION
+      };
@@ -0,0 +1,95 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { loadCallGraph } from '../core/call-graph.js';
+import { loadDependencyGraph } from '../core/dependency-graph.js';
+import { loadSymbolIndex } from '../core/symbol-index.js';
+import { analyzeUnusedExports } from '../core/unused-exports.js';
+import { jsonResponse } from './json-response.js';
+
+const DEFAULT_LIMIT = 50;
+const MAX_OPAQUE_FILES = 10;
+
+export const definition: Tool = {
+  name: 'analyze_unused',
+  description:
+    'List exported symbols that no other indexed file imports, re-exports or calls: ' +
+    'dead-code candidates for cleaning up a module. Static import and call graphs only ' +
+    '(JS/TS exports), so check dynamic use, public package APIs and `export *` barrels ' +
+    'before deleting.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      scope: {
+        type: 'string',
+        description: "Optional path prefix to limit analysis (e.g., 'src/features/cart')"
+      },
+      includeTests: {
+        type: 'boolean',
+        description: 'Also report exports of test files (default: false)',
+        default: false
+      },
+      limit: {
+        type: 'number',
+        description: `Maximum symbols to return (default: ${DEFAULT_LIMIT})`,
+        default: DEFAULT_LIMIT
+      }
+    }
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { scope, includeTests, limit } = args as {
+    scope?: unknown;
+    includeTests?: unknown;
+    limit?: unknown;
+  };
+  const normalizedScope =
+    typeof scope === 'string' && scope.trim()
+      ? scope.trim().replace(/\\/g, '/').replace(/^\.\//, '')
+      : undefined;
+  const normalizedLimit =
+    typeof limit === 'number' && Number.isFinite(limit) && limit > 0
+      ? Math.floor(limit)
+      : DEFAULT_LIMIT;
+
+  const graph = await loadDependencyGraph(ctx.rootPath);
+  if (!graph) {
+    return jsonResponse({
+      status: 'error',
+      message: 'Import graph not available. Run refresh_index to build it.'
+    });
+  }
+
+  const [definitions, callGraph] = await Promise.all([
+    loadSymbolIndex(ctx.rootPath),
+    loadCallGraph(ctx.rootPath)
+  ]);
+  const report = analyzeUnusedExports(graph, definitions, callGraph, {
+    scope: normalizedScope,
+    includeTests: includeTests === true,
+    limit: normalizedLimit
+  });
+
+  return jsonResponse({
+    status: 'success',
+    ...(normalizedScope ? { scope: normalizedScope } : {}),
+    analyzedFiles: report.analyzedFiles,
+    totalUnused: report.totalSymbols,
+    totalFiles: report.totalFiles,
+    files: report.files,
+    ...(report.truncated ? { truncated: true } : {}),
+    ...(report.opaqueFiles.length > 0
+      ? {
+          notAnalyzed: {
+            reason: 'imported without named bindings (namespace, default or side-effect import)',
+            count: report.opaqueFiles.length,
+            files: report.opaqueFiles.slice(0, MAX_OPAQUE_FILES)
+          }
+        }
+      : {}),
+    confidence: 'syntactic'
+  });
+}
@@ -0,0 +1,158 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import {
+  changesSince,
+  cursorAt,
+  readChangeJournal,
+  type ChangeLists,
+  type SymbolChange
+} from '../core/change-journal.js';
+import { matchesPathFilter } from '../core/file-filters.js';
+import { jsonResponse } from './json-response.js';
+
+const DEFAULT_LIMIT = 50;
+const MAX_LIMIT = 500;
+
+export const definition: Tool = {
+  name: 'changes_since',
+  description:
+    'List the files and symbols that index builds added, modified or removed since a cursor ' +
+    '(or timestamp), so a long-running agent can refresh what it knows without re-reading ' +
+    'the codebase. Every response carries the current cursor; call without one to get it. ' +
+    'Changes are as indexed: edits the index has not picked up yet are not included.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      cursor: {
+        type: 'string',
+        description: 'Cursor from an earlier changes_since response'
+      },
+      since: {
+        type: 'string',
+        description: 'ISO 8601 timestamp to use instead of a cursor'
+      },
+      path: {
+        type: 'string',
+        description: 'Only changes under this path or glob (for example: src/**)'
+      },
+      limit: {
+        type: 'number',
+        description: `Entries per list (default: ${DEFAULT_LIMIT})`,
+        default: DEFAULT_LIMIT
+      }
+    }
+  }
+};
+
+function parseCursor(value: unknown): number | null {
+  const text = typeof value === 'number' ? String(value) : typeof value === 'string' ? value : '';
+  return /^\d+$/.test(text.trim()) ? Number(text.trim()) : null;
+}
+
+function symbolEntry(change: SymbolChange) {
+  return {
+    name: change.name,
+    kind: change.kind,
+    file: change.file,
+    ...(change.startLine !== undefined ? { lines: `${change.startLine}-${change.endLine}` } : {})
+  };
+}
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const journal = await readChangeJournal(ctx.paths.baseDir);
+  if (!journal) {
+    return jsonResponse({
+      status: 'not_found',
+      message: 'No index builds recorded yet. Run refresh_index, then ask again.'
+    });
+  }
+
+  let from: number | null = null;
+  if (args.cursor !== undefined) {
+    from = parseCursor(args.cursor);
+    if (from === null) {
+      return jsonResponse(
+        { status: 'error', message: "Invalid params: 'cursor' must be a cursor from this tool." },
+        true
+      );
+    }
+  } else if (typeof args.since === 'string') {
+    from = cursorAt(journal, args.since);
+    if (from === null) {
+      return jsonResponse(
+        { status: 'error', message: "Invalid params: 'since' must be an ISO 8601 timestamp." },
+        true
+      );
+    }
+  }
+  if (from === null) {
+    const last = journal.entries[journal.entries.length - 1];
+    return jsonResponse({
+      status: 'success',
+      cursor: String(journal.cursor),
+      lastChangeAt: last?.generatedAt ?? journal.baselineAt,
+      hint: 'Pass this cursor to changes_since later to get what changed in between.'
+    });
+  }
+
+  const result = changesSince(journal, from);
+  if (result.status === 'invalid') {
+    return jsonResponse(
+      {
+        status: 'error',
+        message: `Cursor ${from} is newer than this index (current: ${result.cursor}).`
+      },
+      true
+    );
+  }
+  if (result.status === 'expired') {
+    return jsonResponse({
+      status: 'cursor_expired',
+      cursor: String(result.cursor),
+      message:
+        `Changes before cursor ${result.baseline} are no longer recorded (old builds are ` +
+        'dropped; a rollback resets the journal). Re-read what you need, then continue from ' +
+        'this cursor.'
+    });
+  }
+
+  const limit = Math.min(
+    typeof args.limit === 'number' && args.limit >= 1 ? Math.floor(args.limit) : DEFAULT_LIMIT,
+    MAX_LIMIT
+  );
+  const scope = typeof args.path === 'string' && args.path.trim() ? args.path.trim() : undefined;
+  const inScope = (file: string) => !scope || matchesPathFilter(file, scope);
+  let omitted = 0;
+  const take = <T>(list: T[]): T[] => {
+    omitted += Math.max(0, list.length - limit);
+    return list.slice(0, limit);
+  };
+  const files: ChangeLists<string> = {
+    added: take(result.files.added.filter(inScope)),
+    modified: take(result.files.modified.filter(inScope)),
+    removed: take(result.files.removed.filter(inScope))
+  };
+  const symbols: Partial<Record<keyof ChangeLists<SymbolChange>, object[]>> = {};
+  for (const state of ['added', 'modified', 'removed'] as const) {
+    const list = take(result.symbols[state].filter((change) => inScope(change.file)));
+    if (list.length > 0) symbols[state] = list.map(symbolEntry);
+  }
+  const unchanged = Object.values(files).every((list) => list.length === 0);
+
+  return jsonResponse({
+    status: 'success',
+    cursor: String(result.cursor),
+    from: String(from),
+    builds: result.builds,
+    ...(files.added.length > 0 ? { addedFiles: files.added } : {}),
+    ...(files.modified.length > 0 ? { modifiedFiles: files.modified } : {}),
+    ...(files.removed.length > 0 ? { removedFiles: files.removed } : {}),
+    ...(Object.keys(symbols).length > 0 ? { symbols } : {}),
+    ...(omitted > 0 ? { omitted, hint: 'Narrow with path, or raise limit.' } : {}),
+    ...(result.truncated ? { truncated: true } : {}),
+    ...(unchanged ? { message: 'No indexed changes since this cursor.' } : {})
+  });
+}
@@ -0,0 +1,32 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { describeProject } from '../core/project-overview.js';
+import { jsonResponse } from './json-response.js';
+
+export const definition: Tool = {
+  name: 'describe_project',
+  description:
+    'Describe how the project is built and run: build systems, entry points and main ' +
+    'binaries, package.json scripts, Makefile targets, services (Dockerfiles, compose files, ' +
+    'Procfiles) and CI configuration. Read from manifests, so it works before indexing. Use ' +
+    'it first to orient yourself in an unfamiliar repository.',
+  inputSchema: {
+    type: 'object',
+    properties: {}
+  }
+};
+
+export async function handle(
+  _args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const overview = await describeProject(ctx.rootPath);
+  const sections = Object.entries(overview).filter(([, items]) => items.length > 0);
+  if (sections.length === 0) {
+    return jsonResponse({
+      status: 'success',
+      message: 'No build manifests, service definitions or CI configuration found.'
+    });
+  }
+  return jsonResponse({ status: 'success', ...Object.fromEntries(sections) });
+}
@@ -0,0 +1,114 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { findCallees, loadCallGraph } from '../core/call-graph.js';
+
+export const definition: Tool = {
+  name: 'find_callees',
+  description:
+    'List the functions/methods a symbol calls, with call-site lines and where each callee is ' +
+    'defined (file:line ranges). Static, name-based call graph from Tree-sitter; project-defined ' +
+    'callees only unless includeExternal is set.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      symbol: {
+        type: 'string',
+        description: 'Function or method name (for example: saveUser or UserService.save)'
+      },
+      file: {
+        type: 'string',
+        description: 'Optional file path suffix to pick one definition when the name is reused'
+      },
+      includeExternal: {
+        type: 'boolean',
+        description: 'Include calls to symbols not defined in the project (default: false)',
+        default: false
+      },
+      limit: {
+        type: 'number',
+        description: 'Maximum number of callees to return (default: 20)',
+        default: 20
+      }
+    },
+    required: ['symbol']
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { symbol, file, includeExternal, limit } = args as {
+    symbol?: unknown;
+    file?: unknown;
+    includeExternal?: unknown;
+    limit?: unknown;
+  };
+  const normalizedSymbol = typeof symbol === 'string' ? symbol.trim() : '';
+  const normalizedLimit =
+    typeof limit === 'number' && Number.isFinite(limit) && limit > 0 ? Math.floor(limit) : 20;
+
+  if (!normalizedSymbol) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              message: "Invalid params: 'symbol' is required and must be a non-empty string."
+            },
+            null,
+            2
+          )
+        }
+      ],
+      isError: true
+    };
+  }
+
+  const callGraph = await loadCallGraph(ctx.rootPath);
+  if (!callGraph) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              symbol: normalizedSymbol,
+              message: 'Call graph not available. Run refresh_index to build it.'
+            },
+            null,
+            2
+          )
+        }
+      ]
+    };
+  }
+
+  const result = findCallees(callGraph, normalizedSymbol, normalizedLimit, {
+    file: typeof file === 'string' && file.trim() ? file.trim() : undefined,
+    includeExternal: includeExternal === true
+  });
+
+  return {
+    content: [
+      {
+        type: 'text',
+        text: JSON.stringify(
+          {
+            status: 'success',
+            symbol: result.symbol,
+            definedAt: result.definedAt,
+            totalCallees: result.total,
+            callees: result.results,
+            confidence: 'syntactic'
+          },
+          null,
+          2
+        )
+      }
+    ]
+  };
+}
@@ -0,0 +1,97 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { findCallers, loadCallGraph } from '../core/call-graph.js';
+
+export const definition: Tool = {
+  name: 'find_callers',
+  description:
+    'List functions/methods that call a symbol, with caller file:line ranges and call-site lines. ' +
+    'Static, name-based call graph from Tree-sitter: same-name functions are not disambiguated ' +
+    'and dynamic dispatch is not resolved.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      symbol: {
+        type: 'string',
+        description: 'Function or method name (for example: saveUser or UserService.save)'
+      },
+      limit: {
+        type: 'number',
+        description: 'Maximum number of callers to return (default: 20)',
+        default: 20
+      }
+    },
+    required: ['symbol']
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { symbol, limit } = args as { symbol?: unknown; limit?: unknown };
+  const normalizedSymbol = typeof symbol === 'string' ? symbol.trim() : '';
+  const normalizedLimit =
+    typeof limit === 'number' && Number.isFinite(limit) && limit > 0 ? Math.floor(limit) : 20;
+
+  if (!normalizedSymbol) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              message: "Invalid params: 'symbol' is required and must be a non-empty string."
+            },
+            null,
+            2
+          )
+        }
+      ],
+      isError: true
+    };
+  }
+
+  const callGraph = await loadCallGraph(ctx.rootPath);
+  if (!callGraph) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              symbol: normalizedSymbol,
+              message: 'Call graph not available. Run refresh_index to build it.'
+            },
+            null,
+            2
+          )
+        }
+      ]
+    };
+  }
+
+  const result = findCallers(callGraph, normalizedSymbol, normalizedLimit);
+
+  return {
+    content: [
+      {
+        type: 'text',
+        text: JSON.stringify(
+          {
+            status: 'success',
+            symbol: result.symbol,
+            definedAt: result.definedAt,
+            totalCallers: result.total,
+            callers: result.results,
+            confidence: 'syntactic'
+          },
+          null,
+          2
+        )
+      }
+    ]
+  };
+}
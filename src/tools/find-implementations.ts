@@ -0,0 +1,122 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { findImplementations, loadTypeHierarchy } from '../core/type-hierarchy.js';
+
+export const definition: Tool = {
+  name: 'find_implementations',
+  description:
+    'List the classes/structs implementing an interface (TypeScript/JavaScript and Go), with ' +
+    'file:line. TypeScript follows implements/extends clauses, including sub-interfaces and ' +
+    'subclasses. Go matches method sets by method name, so signatures are not compared.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      interface: {
+        type: 'string',
+        description: 'Interface name (for example: Repository or io.Reader)'
+      },
+      limit: {
+        type: 'number',
+        description: 'Maximum number of implementations to return (default: 20)',
+        default: 20
+      }
+    },
+    required: ['interface']
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { interface: interfaceName, limit } = args as { interface?: unknown; limit?: unknown };
+  const normalizedName = typeof interfaceName === 'string' ? interfaceName.trim() : '';
+  const normalizedLimit =
+    typeof limit === 'number' && Number.isFinite(limit) && limit > 0 ? Math.floor(limit) : 20;
+
+  if (!normalizedName) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              message: "Invalid params: 'interface' is required and must be a non-empty string."
+            },
+            null,
+            2
+          )
+        }
+      ],
+      isError: true
+    };
+  }
+
+  const types = await loadTypeHierarchy(ctx.rootPath);
+  if (!types) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              interface: normalizedName,
+              message: 'Type hierarchy not available. Run refresh_index to build it.'
+            },
+            null,
+            2
+          )
+        }
+      ]
+    };
+  }
+
+  const result = findImplementations(types, normalizedName, normalizedLimit);
+  if (result.definedAt.length === 0) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'not_found',
+              interface: normalizedName,
+              message: `No interface or class named ${normalizedName} in TypeScript, JavaScript or Go files.`
+            },
+            null,
+            2
+          )
+        }
+      ]
+    };
+  }
+
+  return {
+    content: [
+      {
+        type: 'text',
+        text: JSON.stringify(
+          {
+            status: 'success',
+            interface: normalizedName,
+            definedAt: result.definedAt,
+            totalImplementations: result.total,
+            implementations: result.implementations,
+            ...(result.extendedBy.length > 0 ? { extendedBy: result.extendedBy } : {}),
+            ...(result.unresolvedEmbeds
+              ? {
+                  unresolvedEmbeds: result.unresolvedEmbeds,
+                  note: 'Embedded interfaces outside the repo are not checked; Go matches may be too broad.'
+                }
+              : {}),
+            confidence: 'syntactic'
+          },
+          null,
+          2
+        )
+      }
+    ]
+  };
+}
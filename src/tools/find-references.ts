@@ -0,0 +1,95 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { loadSymbolIndex } from '../core/symbol-index.js';
+import { findReferences } from '../core/symbol-navigation.js';
+import { loadPreciseIndex } from '../core/precise-index.js';
+import { partialMarker } from '../core/cancellation.js';
+import { jsonResponse } from './json-response.js';
+
+const DEFAULT_LIMIT = 20;
+const DEFAULT_CONTEXT_LINES = 2;
+
+export const definition: Tool = {
+  name: 'find_references',
+  description:
+    'Find references: exact file:line of every syntactic use of a symbol, each with ' +
+    'surrounding line-numbered code; the declaration sites are listed and flagged. ' +
+    'Qualified names are matched by their last segment. With an imported SCIP/LSIF index, ' +
+    'covered files get compiler-resolved references (confidence: precise or mixed).',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      symbol: {
+        type: 'string',
+        description: 'Symbol name (for example: parseConfig or UserService)'
+      },
+      limit: {
+        type: 'number',
+        description: `Maximum references to return (default: ${DEFAULT_LIMIT})`,
+        default: DEFAULT_LIMIT
+      },
+      contextLines: {
+        type: 'number',
+        description: `Context lines around each reference (default: ${DEFAULT_CONTEXT_LINES})`,
+        default: DEFAULT_CONTEXT_LINES
+      }
+    },
+    required: ['symbol']
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { symbol, limit, contextLines } = args as {
+    symbol?: unknown;
+    limit?: unknown;
+    contextLines?: unknown;
+  };
+  const normalizedSymbol = typeof symbol === 'string' ? symbol.trim() : '';
+  const normalizedLimit =
+    typeof limit === 'number' && Number.isFinite(limit) && limit > 0
+      ? Math.min(Math.floor(limit), 100)
+      : DEFAULT_LIMIT;
+  const normalizedContext =
+    typeof contextLines === 'number' && Number.isFinite(contextLines) && contextLines >= 0
+      ? Math.min(Math.floor(contextLines), 10)
+      : DEFAULT_CONTEXT_LINES;
+
+  if (!normalizedSymbol) {
+    return jsonResponse(
+      {
+        status: 'error',
+        message: "Invalid params: 'symbol' is required and must be a non-empty string."
+      },
+      true
+    );
+  }
+
+  // Without a symbol table references still work; they just can't be flagged as declarations
+  const definitions = (await loadSymbolIndex(ctx.rootPath)) ?? [];
+  const precise = await loadPreciseIndex(ctx.rootPath, { persist: !ctx.pathPolicy?.readOnly });
+  const result = await findReferences(
+    ctx.rootPath,
+    definitions,
+    normalizedSymbol,
+    { limit: normalizedLimit, contextLines: normalizedContext, signal: ctx.signal },
+    precise
+  );
+
+  if (result.status === 'error') {
+    return jsonResponse({ status: 'error', symbol: normalizedSymbol, message: result.message });
+  }
+
+  return jsonResponse({
+    status: 'success',
+    symbol: normalizedSymbol,
+    totalReferences: result.total,
+    isComplete: result.isComplete,
+    ...(result.isComplete ? {} : partialMarker(ctx.signal)),
+    definitions: result.definitions,
+    references: result.references,
+    confidence: result.confidence
+  });
+}
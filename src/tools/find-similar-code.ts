@@ -0,0 +1,123 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { toProjectRelativePath } from '../core/file-summary.js';
+import { partialMarker } from '../core/cancellation.js';
+import {
+  DEFAULT_SIMILARITY_THRESHOLD,
+  findSimilarCode,
+  type SimilarCodeSource
+} from '../core/similar-code.js';
+import { jsonResponse } from './json-response.js';
+
+const DEFAULT_LIMIT = 10;
+
+export const definition: Tool = {
+  name: 'find_similar_code',
+  description:
+    'Find near-duplicates of a code snippet or file region across the repo, using the chunk ' +
+    'embeddings already in the index. For DRY refactors and for finding the other copies of a ' +
+    'copy-pasted bug. Scores are embedding similarity, not a diff: check matches before merging.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      path: {
+        type: 'string',
+        description:
+          'File or region to compare, e.g. src/billing/invoice.ts:40-85 (no range: whole file)'
+      },
+      snippet: {
+        type: 'string',
+        description: 'Code to compare instead of a region (embedded once, not stored)'
+      },
+      threshold: {
+        type: 'number',
+        description: `Minimum similarity from 0 to 1 (default: ${DEFAULT_SIMILARITY_THRESHOLD})`,
+        default: DEFAULT_SIMILARITY_THRESHOLD
+      },
+      scope: {
+        type: 'string',
+        description: 'Only report matches under this path or glob (for example: src/**)'
+      },
+      excludeTests: {
+        type: 'boolean',
+        description: 'Leave test files out of the matches (default: false)'
+      },
+      limit: {
+        type: 'number',
+        description: `Maximum matches to return (default: ${DEFAULT_LIMIT})`,
+        default: DEFAULT_LIMIT
+      }
+    }
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { threshold, scope, excludeTests, limit } = args as {
+    threshold?: unknown;
+    scope?: unknown;
+    excludeTests?: unknown;
+    limit?: unknown;
+  };
+  const target = typeof args.path === 'string' ? args.path.trim() : '';
+  const snippet = typeof args.snippet === 'string' ? args.snippet.trim() : '';
+  if (!target === !snippet) {
+    return jsonResponse(
+      { status: 'error', message: "Invalid params: pass either 'path' or 'snippet'." },
+      true
+    );
+  }
+
+  let source: SimilarCodeSource = { snippet };
+  if (target) {
+    const range = /^(.+?):(\d+)(?:-(\d+))?$/.exec(target);
+    const file = toProjectRelativePath(ctx.rootPath, range ? range[1] : target);
+    if (!file) {
+      return jsonResponse(
+        { status: 'error', message: `Invalid params: '${target}' is outside the project root.` },
+        true
+      );
+    }
+    source = range
+      ? { file, startLine: Number(range[2]), endLine: Number(range[3] ?? range[2]) }
+      : { file };
+  }
+
+  const normalizedThreshold =
+    typeof threshold === 'number' && threshold > 0 && threshold <= 1
+      ? threshold
+      : DEFAULT_SIMILARITY_THRESHOLD;
+  const normalizedLimit =
+    typeof limit === 'number' && Number.isFinite(limit) && limit > 0
+      ? Math.min(Math.floor(limit), 50)
+      : DEFAULT_LIMIT;
+
+  const result = await findSimilarCode(ctx.rootPath, source, {
+    threshold: normalizedThreshold,
+    limit: normalizedLimit,
+    ...(typeof scope === 'string' && scope.trim() ? { scope: scope.trim() } : {}),
+    excludeTests: excludeTests === true,
+    signal: ctx.signal
+  });
+  if (result.status !== 'success') return jsonResponse({ ...result });
+
+  return jsonResponse({
+    status: result.matches.length > 0 ? 'success' : 'not_found',
+    ...partialMarker(ctx.signal),
+    ...(target ? { source: target } : {}),
+    threshold: normalizedThreshold,
+    compared: result.compared,
+    totalMatches: result.total,
+    matches: result.matches.map((match) => ({
+      location: `${match.file}:${match.startLine}-${match.endLine}`,
+      score: match.score,
+      ...(match.symbol ? { symbol: match.symbol } : {}),
+      ...(match.similarTo ? { similarTo: match.similarTo } : {})
+    })),
+    ...(result.matches.length === 0
+      ? { hint: 'Nothing above the threshold. Lower it (e.g. 0.75) for looser matches.' }
+      : {})
+  });
+}
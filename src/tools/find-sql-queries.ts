@@ -0,0 +1,135 @@
+import { promises as fs } from 'fs';
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import type { CodeChunk, SqlStatementInfo } from '../types/index.js';
+import { openIndexedChunks } from '../core/index-encryption.js';
+import { withIndexReadLock } from '../core/index-lock.js';
+import { jsonResponse } from './json-response.js';
+
+const DEFAULT_LIMIT = 30;
+
+export const definition: Tool = {
+  name: 'find_sql_queries',
+  description:
+    'List the SQL that touches a table: statements in .sql files (migrations, views, ' +
+    'routines) and query strings embedded in code, with the function each query sits in. ' +
+    'Tables come from FROM/JOIN/INTO/UPDATE clauses and DDL, so dynamically built table ' +
+    'names are not seen.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      table: {
+        type: 'string',
+        description: 'Table name, plain or schema-qualified (for example: orders, billing.orders)'
+      },
+      statement: {
+        type: 'string',
+        description: 'Only this statement kind (for example: select, update, create table)'
+      },
+      limit: {
+        type: 'number',
+        description: `Maximum queries to return (default: ${DEFAULT_LIMIT})`,
+        default: DEFAULT_LIMIT
+      }
+    },
+    required: ['table']
+  }
+};
+
+/** `orders` matches `orders` and `billing.orders`; a qualified name matches only itself */
+function touchesTable(info: SqlStatementInfo, table: string): boolean {
+  return info.tables.some(
+    (name) => name === table || (!table.includes('.') && name.endsWith(`.${table}`))
+  );
+}
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { table, statement, limit } = args as {
+    table?: unknown;
+    statement?: unknown;
+    limit?: unknown;
+  };
+  const normalizedTable =
+    typeof table === 'string' ? table.trim().replace(/["`[\]]/g, '').toLowerCase() : '';
+  const normalizedStatement =
+    typeof statement === 'string' ? statement.trim().toLowerCase() : undefined;
+  const normalizedLimit =
+    typeof limit === 'number' && Number.isFinite(limit) && limit > 0
+      ? Math.min(Math.floor(limit), 200)
+      : DEFAULT_LIMIT;
+
+  if (!normalizedTable) {
+    return jsonResponse(
+      {
+        status: 'error',
+        message: "Invalid params: 'table' is required and must be a non-empty string."
+      },
+      true
+    );
+  }
+
+  let chunks: CodeChunk[];
+  try {
+    const raw = await withIndexReadLock(ctx.paths.baseDir, () =>
+      fs.readFile(ctx.paths.keywordIndex, 'utf-8')
+    );
+    const parsed = JSON.parse(raw) as { chunks?: CodeChunk[] };
+    chunks = parsed.chunks ?? [];
+  } catch {
+    return jsonResponse({
+      status: 'error',
+      table: normalizedTable,
+      message: 'Index not available. Run refresh_index to build it.'
+    });
+  }
+  chunks = await openIndexedChunks(chunks);
+
+  const matches: Array<SqlStatementInfo & { file: string; embedded: boolean }> = [];
+  const seen = new Set<string>();
+  for (const chunk of chunks) {
+    const embedded = chunk.language !== 'sql';
+    for (const info of chunk.metadata?.sql ?? []) {
+      if (!touchesTable(info, normalizedTable)) continue;
+      if (normalizedStatement && info.statement !== normalizedStatement) continue;
+      // Split oversized chunks repeat their statement
+      const key = `${chunk.relativePath}:${info.line}:${info.statement}`;
+      if (seen.has(key)) continue;
+      seen.add(key);
+      matches.push({ ...info, file: chunk.relativePath, embedded });
+    }
+  }
+  matches.sort((a, b) => a.file.localeCompare(b.file) || a.line - b.line);
+
+  if (matches.length === 0) {
+    return jsonResponse({
+      status: 'not_found',
+      table: normalizedTable,
+      message:
+        'No indexed SQL touches this table. Try search_codebase for queries built at runtime.'
+    });
+  }
+
+  const byStatement: Record<string, number> = {};
+  for (const match of matches) {
+    byStatement[match.statement] = (byStatement[match.statement] ?? 0) + 1;
+  }
+
+  return jsonResponse({
+    status: 'success',
+    table: normalizedTable,
+    totalQueries: matches.length,
+    byStatement,
+    queries: matches.slice(0, normalizedLimit).map((match) => ({
+      location: `${match.file}:${match.line}`,
+      statement: match.statement,
+      tables: match.tables,
+      ...(match.function ? { function: match.function } : {}),
+      ...(match.repeated ? { repeated: match.repeated } : {}),
+      source: match.embedded ? 'embedded' : 'sql-file'
+    })),
+    ...(matches.length > normalizedLimit ? { truncated: true } : {})
+  });
+}
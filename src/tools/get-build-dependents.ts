@@ -0,0 +1,113 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { getBuildDependents, loadBuildGraph, resolveBuildTarget } from '../core/build-graph.js';
+import { jsonResponse } from './json-response.js';
+
+const DEFAULT_LIMIT = 30;
+const MAX_DEPTH = 10;
+
+export const definition: Tool = {
+  name: 'get_build_dependents',
+  description:
+    'List the Bazel/Buck targets that depend on a target or file (reverse deps from BUILD ' +
+    'files), nearest first. Set depth > 1 for the transitive set a change rebuilds, or ' +
+    'testsOnly to list the test targets to run.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      target: {
+        type: 'string',
+        description:
+          'Label (//services/api:server, //services/api, //libs/...), repo-relative file ' +
+          'path, or target name'
+      },
+      depth: {
+        type: 'number',
+        description: `Reverse-dependency levels to follow (default: 1, max: ${MAX_DEPTH})`,
+        default: 1
+      },
+      testsOnly: {
+        type: 'boolean',
+        description: 'Only return test targets (default: false)',
+        default: false
+      },
+      limit: {
+        type: 'number',
+        description: `Maximum number of targets to return (default: ${DEFAULT_LIMIT})`,
+        default: DEFAULT_LIMIT
+      }
+    },
+    required: ['target']
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { target, depth, testsOnly, limit } = args as {
+    target?: unknown;
+    depth?: unknown;
+    testsOnly?: unknown;
+    limit?: unknown;
+  };
+  const normalizedTarget = typeof target === 'string' ? target.trim() : '';
+  const normalizedDepth =
+    typeof depth === 'number' && Number.isFinite(depth) && depth > 0
+      ? Math.min(Math.floor(depth), MAX_DEPTH)
+      : 1;
+  const normalizedLimit =
+    typeof limit === 'number' && Number.isFinite(limit) && limit > 0
+      ? Math.floor(limit)
+      : DEFAULT_LIMIT;
+
+  if (!normalizedTarget) {
+    return jsonResponse(
+      {
+        status: 'error',
+        message: "Invalid params: 'target' is required and must be a non-empty string."
+      },
+      true
+    );
+  }
+
+  const graph = await loadBuildGraph(ctx.rootPath);
+  if (!graph) {
+    return jsonResponse({
+      status: 'error',
+      target: normalizedTarget,
+      message:
+        'No build graph in the index. It is built for Bazel and Buck projects; run ' +
+        'refresh_index if this is one.'
+    });
+  }
+
+  const resolved = resolveBuildTarget(graph, normalizedTarget);
+  if (resolved.labels.length === 0) {
+    return jsonResponse({
+      status: 'not_found',
+      target: normalizedTarget,
+      ...(resolved.candidates
+        ? {
+            candidates: resolved.candidates.slice(0, 10),
+            message: 'Several targets have this name; pass one of the labels.'
+          }
+        : { message: 'No target or file with this name in the build graph.' })
+    });
+  }
+
+  const dependents = getBuildDependents(graph, resolved.labels, {
+    depth: normalizedDepth,
+    testsOnly: testsOnly === true
+  });
+  return jsonResponse({
+    status: 'success',
+    target: normalizedTarget,
+    resolved: resolved.labels.slice(0, 10),
+    depth: normalizedDepth,
+    total: dependents.length,
+    tests: dependents.filter((dependent) => dependent.test).length,
+    dependents: dependents.slice(0, normalizedLimit),
+    ...(dependents.length > normalizedLimit ? { truncated: true } : {})
+  });
+}
@@ -0,0 +1,105 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import {
+  getBuildDependents,
+  isTestTarget,
+  loadBuildGraph,
+  resolveBuildTarget
+} from '../core/build-graph.js';
+import { jsonResponse } from './json-response.js';
+
+const MAX_LISTED = 30;
+
+export const definition: Tool = {
+  name: 'get_build_target',
+  description:
+    'Describe Bazel/Buck targets from BUILD files: rule kind, source files, deps and how many ' +
+    'targets depend on them. Pass a file to find the targets that own it.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      target: {
+        type: 'string',
+        description:
+          'Label (//services/api:server, //services/api:all), repo-relative file path, or ' +
+          'target name'
+      }
+    },
+    required: ['target']
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const target = typeof args.target === 'string' ? args.target.trim() : '';
+  if (!target) {
+    return jsonResponse(
+      {
+        status: 'error',
+        message: "Invalid params: 'target' is required and must be a non-empty string."
+      },
+      true
+    );
+  }
+
+  const graph = await loadBuildGraph(ctx.rootPath);
+  if (!graph) {
+    return jsonResponse({
+      status: 'error',
+      target,
+      message:
+        'No build graph in the index. It is built for Bazel and Buck projects; run ' +
+        'refresh_index if this is one.'
+    });
+  }
+
+  const resolved = resolveBuildTarget(graph, target);
+  const labels = resolved.labels.filter((label) => graph.targets[label]);
+  if (labels.length === 0) {
+    return jsonResponse({
+      status: 'not_found',
+      target,
+      ...(resolved.candidates
+        ? {
+            candidates: resolved.candidates.slice(0, 10),
+            message: 'Several targets have this name; pass one of the labels.'
+          }
+        : resolved.labels.length > 0
+          ? { message: 'Only referenced as a dependency; not declared in an indexed BUILD file.' }
+          : { message: 'No target or file with this name in the build graph.' })
+    });
+  }
+
+  // Several targets (a file's owners, `//pkg:all`) are listed without their files and deps
+  const detailed = labels.length === 1;
+  const targets = labels.slice(0, 10).map((label) => {
+    const info = graph.targets[label];
+    const deps = info.deps ?? [];
+    const internal = deps.filter((dep) => dep.startsWith('//'));
+    const external = deps.filter((dep) => !dep.startsWith('//'));
+    return {
+      label,
+      kind: info.kind,
+      test: isTestTarget(info.kind),
+      fileCount: info.files?.length ?? 0,
+      depCount: deps.length,
+      directDependents: getBuildDependents(graph, [label]).length,
+      ...(detailed
+        ? {
+            files: (info.files ?? []).slice(0, MAX_LISTED),
+            deps: internal.slice(0, MAX_LISTED),
+            ...(external.length > 0 ? { externalDeps: external.slice(0, MAX_LISTED) } : {})
+          }
+        : {})
+    };
+  });
+  return jsonResponse({
+    status: 'success',
+    target,
+    system: graph.system,
+    total: labels.length,
+    targets
+  });
+}
@@ -0,0 +1,108 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { loadSymbolIndex } from '../core/symbol-index.js';
+import { getDefinitions } from '../core/symbol-navigation.js';
+import { loadPreciseIndex } from '../core/precise-index.js';
+import { createLspHover } from '../core/lsp-bridge.js';
+import { jsonResponse } from './json-response.js';
+
+const DEFAULT_LIMIT = 5;
+const DEFAULT_MAX_LINES = 40;
+
+export const definition: Tool = {
+  name: 'get_definition',
+  description:
+    'Go to definition: exact file:line of where a symbol is declared, with its line-numbered ' +
+    'source. Accepts plain or qualified names (UserService.save, Cache::get). Uses an ' +
+    'imported SCIP/LSIF index when present (confidence: precise, with hover documentation). ' +
+    'With the LSP bridge on, a local language server adds types and docs as hover text.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      symbol: {
+        type: 'string',
+        description: 'Exact symbol name (for example: parseConfig or UserService.save)'
+      },
+      limit: {
+        type: 'number',
+        description: `Maximum definitions to return (default: ${DEFAULT_LIMIT})`,
+        default: DEFAULT_LIMIT
+      },
+      maxLines: {
+        type: 'number',
+        description: `Maximum source lines per definition (default: ${DEFAULT_MAX_LINES})`,
+        default: DEFAULT_MAX_LINES
+      }
+    },
+    required: ['symbol']
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { symbol, limit, maxLines } = args as {
+    symbol?: unknown;
+    limit?: unknown;
+    maxLines?: unknown;
+  };
+  const normalizedSymbol = typeof symbol === 'string' ? symbol.trim() : '';
+  const normalizedLimit =
+    typeof limit === 'number' && Number.isFinite(limit) && limit > 0
+      ? Math.min(Math.floor(limit), 20)
+      : DEFAULT_LIMIT;
+  const normalizedMaxLines =
+    typeof maxLines === 'number' && Number.isFinite(maxLines) && maxLines > 0
+      ? Math.min(Math.floor(maxLines), 200)
+      : DEFAULT_MAX_LINES;
+
+  if (!normalizedSymbol) {
+    return jsonResponse(
+      {
+        status: 'error',
+        message: "Invalid params: 'symbol' is required and must be a non-empty string."
+      },
+      true
+    );
+  }
+
+  const definitions = await loadSymbolIndex(ctx.rootPath);
+  const precise = await loadPreciseIndex(ctx.rootPath, { persist: !ctx.pathPolicy?.readOnly });
+  if (!definitions && !precise) {
+    return jsonResponse({
+      status: 'error',
+      symbol: normalizedSymbol,
+      message: 'Symbol index not available. Run refresh_index to build it.'
+    });
+  }
+
+  const result = await getDefinitions(
+    ctx.rootPath,
+    definitions ?? [],
+    normalizedSymbol,
+    {
+      limit: normalizedLimit,
+      maxLines: normalizedMaxLines,
+      hover: await createLspHover(ctx.rootPath)
+    },
+    precise
+  );
+
+  if (result.total === 0) {
+    return jsonResponse({
+      status: 'not_found',
+      symbol: normalizedSymbol,
+      message: 'No definition with this exact name. Try search_symbols for fuzzy matches.'
+    });
+  }
+
+  return jsonResponse({
+    status: 'success',
+    symbol: normalizedSymbol,
+    totalDefinitions: result.total,
+    definitions: result.definitions,
+    confidence: result.confidence,
+    ...(result.languageServers ? { languageServers: result.languageServers } : {})
+  });
+}
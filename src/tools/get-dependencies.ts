@@ -0,0 +1,135 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import {
+  getDependencies,
+  loadDependencyGraph,
+  resolveDependencyTarget
+} from '../core/dependency-graph.js';
+
+export const definition: Tool = {
+  name: 'get_dependencies',
+  description:
+    'List what a file or workspace package imports: project files (with import line and ' +
+    'symbols for direct imports) and external packages. Set depth > 1 for transitive imports. ' +
+    'Built from static imports at index time.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      target: {
+        type: 'string',
+        description:
+          'Repo-relative file path (src/auth/session.ts) or workspace package name/directory'
+      },
+      depth: {
+        type: 'number',
+        description: 'Import hops to follow (default: 1, max: 5)',
+        default: 1
+      },
+      limit: {
+        type: 'number',
+        description: 'Maximum number of files to return (default: 30)',
+        default: 30
+      }
+    },
+    required: ['target']
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { target, depth, limit } = args as { target?: unknown; depth?: unknown; limit?: unknown };
+  const normalizedTarget = typeof target === 'string' ? target.trim() : '';
+  const normalizedDepth =
+    typeof depth === 'number' && Number.isFinite(depth) && depth > 0
+      ? Math.min(Math.floor(depth), 5)
+      : 1;
+  const normalizedLimit =
+    typeof limit === 'number' && Number.isFinite(limit) && limit > 0 ? Math.floor(limit) : 30;
+
+  if (!normalizedTarget) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              message: "Invalid params: 'target' is required and must be a non-empty string."
+            },
+            null,
+            2
+          )
+        }
+      ],
+      isError: true
+    };
+  }
+
+  const graph = await loadDependencyGraph(ctx.rootPath);
+  if (!graph) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              target: normalizedTarget,
+              message: 'Import graph not available. Run refresh_index to build it.'
+            },
+            null,
+            2
+          )
+        }
+      ]
+    };
+  }
+
+  const resolved = resolveDependencyTarget(graph, ctx.rootPath, normalizedTarget);
+  if (!resolved || (resolved.kind === 'package' && !resolved.directory)) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'not_found',
+              target: normalizedTarget,
+              message: resolved
+                ? 'External package: only its dependents are tracked. Use get_dependents.'
+                : 'No indexed file or workspace package matches this target.'
+            },
+            null,
+            2
+          )
+        }
+      ]
+    };
+  }
+
+  const result = getDependencies(graph, resolved, normalizedDepth, normalizedLimit);
+
+  return {
+    content: [
+      {
+        type: 'text',
+        text: JSON.stringify(
+          {
+            status: 'success',
+            target: result.target,
+            kind: result.kind,
+            depth: normalizedDepth,
+            totalDependencies: result.total,
+            dependencies: result.results,
+            ...(result.externalPackages ? { externalPackages: result.externalPackages } : {}),
+            ...(result.byPackage ? { byPackage: result.byPackage } : {})
+          },
+          null,
+          2
+        )
+      }
+    ]
+  };
+}
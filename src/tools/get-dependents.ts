@@ -0,0 +1,133 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import {
+  getDependents,
+  loadDependencyGraph,
+  resolveDependencyTarget
+} from '../core/dependency-graph.js';
+
+export const definition: Tool = {
+  name: 'get_dependents',
+  description:
+    'List files that import a file or package (workspace or external), with import line and ' +
+    'symbols for direct importers. Set depth > 1 to see the transitive blast radius of a change. ' +
+    'Built from static imports at index time.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      target: {
+        type: 'string',
+        description:
+          'Repo-relative file path (src/auth/session.ts), workspace package name/directory, ' +
+          'or external package name'
+      },
+      depth: {
+        type: 'number',
+        description: 'Import hops to follow (default: 1, max: 5)',
+        default: 1
+      },
+      limit: {
+        type: 'number',
+        description: 'Maximum number of files to return (default: 30)',
+        default: 30
+      }
+    },
+    required: ['target']
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { target, depth, limit } = args as { target?: unknown; depth?: unknown; limit?: unknown };
+  const normalizedTarget = typeof target === 'string' ? target.trim() : '';
+  const normalizedDepth =
+    typeof depth === 'number' && Number.isFinite(depth) && depth > 0
+      ? Math.min(Math.floor(depth), 5)
+      : 1;
+  const normalizedLimit =
+    typeof limit === 'number' && Number.isFinite(limit) && limit > 0 ? Math.floor(limit) : 30;
+
+  if (!normalizedTarget) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              message: "Invalid params: 'target' is required and must be a non-empty string."
+            },
+            null,
+            2
+          )
+        }
+      ],
+      isError: true
+    };
+  }
+
+  const graph = await loadDependencyGraph(ctx.rootPath);
+  if (!graph) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              target: normalizedTarget,
+              message: 'Import graph not available. Run refresh_index to build it.'
+            },
+            null,
+            2
+          )
+        }
+      ]
+    };
+  }
+
+  const resolved = resolveDependencyTarget(graph, ctx.rootPath, normalizedTarget);
+  if (!resolved) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'not_found',
+              target: normalizedTarget,
+              message: 'No indexed file or package matches this target, or nothing imports it.'
+            },
+            null,
+            2
+          )
+        }
+      ]
+    };
+  }
+
+  const result = getDependents(graph, resolved, normalizedDepth, normalizedLimit);
+
+  return {
+    content: [
+      {
+        type: 'text',
+        text: JSON.stringify(
+          {
+            status: 'success',
+            target: result.target,
+            kind: result.kind,
+            depth: normalizedDepth,
+            totalDependents: result.total,
+            dependents: result.results,
+            ...(result.byPackage ? { byPackage: result.byPackage } : {})
+          },
+          null,
+          2
+        )
+      }
+    ]
+  };
+}
@@ -0,0 +1,189 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import path from 'path';
+import type { ToolContext, ToolResponse } from './types.js';
+import { CodebaseSearcher } from '../core/search.js';
+import { findCallers, loadCallGraph } from '../core/call-graph.js';
+import {
+  buildDiffFileContexts,
+  hunkLineRange,
+  parseUnifiedDiff,
+  type DiffFileContext
+} from '../core/diff-context.js';
+import { getGitDiff } from '../utils/git-tree.js';
+import { jsonResponse } from './json-response.js';
+
+const MAX_FILES = 20;
+const MAX_HUNKS_PER_FILE = 5;
+const MAX_CALLER_SYMBOLS = 5;
+const MAX_CALLERS_PER_SYMBOL = 3;
+
+export const definition: Tool = {
+  name: 'get_diff_context',
+  description:
+    'Review context for a change: the functions/classes each diff hunk touches, their callers, ' +
+    'and semantically related code in unchanged files. Pass base (and optionally head) refs, ' +
+    'or a unified diff. Without head, base is compared to the working tree.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      base: {
+        type: 'string',
+        description: 'Base git ref (for example: main, HEAD~1, a commit SHA)'
+      },
+      head: {
+        type: 'string',
+        description: 'Head git ref. Omit to compare base against the working tree.'
+      },
+      diff: {
+        type: 'string',
+        description: 'Unified diff text to analyze instead of computing one from refs'
+      },
+      limit: {
+        type: 'number',
+        description: 'Maximum number of related code results (default: 5)',
+        default: 5
+      }
+    }
+  }
+};
+
+function errorResponse(message: string, isError = false): ToolResponse {
+  return jsonResponse({ status: 'error', message }, isError);
+}
+
+function optionalString(value: unknown): string | undefined {
+  return typeof value === 'string' && value.trim() ? value.trim() : undefined;
+}
+
+function buildRelatedQuery(files: DiffFileContext[]): string {
+  const names = new Set<string>();
+  for (const file of files) {
+    for (const symbol of file.changedSymbols) names.add(symbol.name);
+  }
+  if (names.size === 0) {
+    for (const file of files) names.add(path.basename(file.path, path.extname(file.path)));
+  }
+  return Array.from(names).slice(0, 10).join(' ');
+}
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const base = optionalString(args.base);
+  const head = optionalString(args.head);
+  const diff = typeof args.diff === 'string' && args.diff.trim() ? args.diff : undefined;
+  const limit =
+    typeof args.limit === 'number' && Number.isFinite(args.limit) && args.limit > 0
+      ? Math.floor(args.limit)
+      : 5;
+
+  if (!base && !diff) {
+    return errorResponse(
+      "Invalid params: provide 'base' (git ref) or 'diff' (unified diff).",
+      true
+    );
+  }
+
+  let diffText: string;
+  if (diff) {
+    diffText = diff;
+  } else {
+    try {
+      diffText = await getGitDiff(ctx.rootPath, base as string, head);
+    } catch (error) {
+      return errorResponse(
+        `Could not compute git diff: ${error instanceof Error ? error.message : String(error)}`
+      );
+    }
+  }
+
+  const parsed = parseUnifiedDiff(diffText);
+  if (parsed.length === 0) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            { status: 'success', base, head, totalFiles: 0, files: [], message: 'No changes.' },
+            null,
+            2
+          )
+        }
+      ]
+    };
+  }
+
+  const files = await buildDiffFileContexts(ctx.rootPath, parsed.slice(0, MAX_FILES), { head });
+  const changedPaths = new Set(parsed.map((f) => f.path));
+
+  // Callers come from the working-tree call graph; they may lag behind an unindexed head
+  const callers: Array<{
+    symbol: string;
+    totalCallers: number;
+    callers: ReturnType<typeof findCallers>['results'];
+  }> = [];
+  const callGraph = await loadCallGraph(ctx.rootPath);
+  if (callGraph) {
+    const symbols = files.flatMap((f) => f.changedSymbols.map((s) => s.name));
+    for (const name of Array.from(new Set(symbols)).slice(0, MAX_CALLER_SYMBOLS)) {
+      const result = findCallers(callGraph, name, MAX_CALLERS_PER_SYMBOL);
+      if (result.total > 0) {
+        callers.push({ symbol: name, totalCallers: result.total, callers: result.results });
+      }
+    }
+  }
+
+  let related: Array<{ file: string; summary: string; score: number }> = [];
+  if (ctx.indexState.status !== 'indexing' && ctx.indexState.status !== 'error') {
+    try {
+      const searcher = new CodebaseSearcher(ctx.rootPath);
+      const results = await searcher.search(buildRelatedQuery(files), limit * 3);
+      related = results
+        .map((r) => ({ ...r, rel: path.relative(ctx.rootPath, r.filePath).replace(/\\/g, '/') }))
+        .filter((r) => !changedPaths.has(r.rel))
+        .slice(0, limit)
+        .map((r) => ({
+          file: `${r.rel}:${r.startLine}-${r.endLine}`,
+          summary: r.summary,
+          score: Math.round(r.score * 100) / 100
+        }));
+    } catch {
+      // Related code is best-effort; a missing or stale index shouldn't hide the diff summary
+      related = [];
+    }
+  }
+
+  return {
+    content: [
+      {
+        type: 'text',
+        text: JSON.stringify(
+          {
+            status: 'success',
+            base,
+            head,
+            totalFiles: parsed.length,
+            ...(parsed.length > MAX_FILES ? { truncated: true } : {}),
+            files: files.map((f) => ({
+              file: f.path,
+              ...(f.oldPath ? { oldPath: f.oldPath } : {}),
+              status: f.status,
+              additions: f.additions,
+              deletions: f.deletions,
+              hunks: f.hunks.slice(0, MAX_HUNKS_PER_FILE).map((h) => {
+                const { start, end } = hunkLineRange(h);
+                return start === end ? `${start}` : `${start}-${end}`;
+              }),
+              changedSymbols: f.changedSymbols
+            })),
+            callers,
+            related
+          },
+          null,
+          2
+        )
+      }
+    ]
+  };
+}
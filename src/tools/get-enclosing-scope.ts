@@ -0,0 +1,106 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { loadSymbolIndex } from '../core/symbol-index.js';
+import { getEnclosingScope, type ScopeLevel } from '../core/symbol-navigation.js';
+import { toProjectRelativePath } from '../core/file-summary.js';
+import { jsonResponse } from './json-response.js';
+
+const DEFAULT_MAX_LINES = 120;
+const SCOPE_LEVELS: ScopeLevel[] = ['symbol', 'outermost', 'file'];
+
+export const definition: Tool = {
+  name: 'get_enclosing_scope',
+  description:
+    'Expand a search hit to the whole function, method or class around it, with line-numbered ' +
+    'source. Pass the `file` of a search_codebase result ("path:12-30") or a path and line. ' +
+    'Uses the tree-sitter boundaries from the index; falls back to the whole file.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      path: {
+        type: 'string',
+        description: 'File, optionally with a line range: src/auth/session.ts:42-60'
+      },
+      line: {
+        type: 'number',
+        description: 'First line of the range to expand (when path has no range)'
+      },
+      endLine: {
+        type: 'number',
+        description: 'Last line of the range (default: line)'
+      },
+      scope: {
+        type: 'string',
+        enum: SCOPE_LEVELS,
+        description:
+          'symbol (default): innermost enclosing declaration; outermost: its top-level ' +
+          'declaration, e.g. the class around a method; file: the whole file'
+      },
+      maxLines: {
+        type: 'number',
+        description: `Maximum source lines to return (default: ${DEFAULT_MAX_LINES})`,
+        default: DEFAULT_MAX_LINES
+      }
+    },
+    required: ['path']
+  }
+};
+
+function positiveInteger(value: unknown): number | undefined {
+  return typeof value === 'number' && Number.isFinite(value) && value >= 1
+    ? Math.floor(value)
+    : undefined;
+}
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const target = typeof args.path === 'string' ? args.path.trim() : '';
+  if (!target) {
+    return jsonResponse(
+      {
+        status: 'error',
+        message: "Invalid params: 'path' is required and must be a non-empty string."
+      },
+      true
+    );
+  }
+
+  const range = /^(.+?):(\d+)(?:-(\d+))?$/.exec(target);
+  const file = toProjectRelativePath(ctx.rootPath, range ? range[1] : target);
+  if (!file) {
+    return jsonResponse(
+      { status: 'error', message: `Invalid params: '${target}' is outside the project root.` },
+      true
+    );
+  }
+
+  const startLine = range ? Number(range[2]) : (positiveInteger(args.line) ?? 1);
+  const endLine = range?.[3] ? Number(range[3]) : (positiveInteger(args.endLine) ?? startLine);
+  const level = SCOPE_LEVELS.includes(args.scope as ScopeLevel)
+    ? (args.scope as ScopeLevel)
+    : range || args.line !== undefined
+      ? 'symbol'
+      : 'file';
+  const maxLines = Math.min(positiveInteger(args.maxLines) ?? DEFAULT_MAX_LINES, 400);
+
+  const definitions = await loadSymbolIndex(ctx.rootPath);
+  const scope = await getEnclosingScope(
+    ctx.rootPath,
+    definitions ?? [],
+    { file, startLine, endLine },
+    { level, maxLines }
+  );
+  if (!scope) {
+    return jsonResponse({ status: 'error', file, message: `File not found: ${file}` });
+  }
+
+  return jsonResponse({
+    status: 'success',
+    ...scope,
+    ...(!definitions && level !== 'file'
+      ? { hint: 'Symbol index not available, so the whole file is returned. Run refresh_index.' }
+      : {})
+  });
+}
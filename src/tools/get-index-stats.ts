@@ -0,0 +1,82 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { collectIndexStats, type IndexStatsReport } from '../core/index-maintenance.js';
+
+export const definition: Tool = {
+  name: 'get_index_stats',
+  description:
+    'Diagnose the index: file and chunk counts, languages, size on disk, embedding model, ' +
+    'last index time and an estimate of files changed since. Use it when results look wrong ' +
+    'or incomplete.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      ref: {
+        type: 'string',
+        description: 'Optional git ref whose index to inspect (built with refresh_index({ ref }))'
+      }
+    }
+  }
+};
+
+/** Likely causes of poor results, most serious first */
+function buildHints(report: IndexStatsReport, ctx: ToolContext): string[] {
+  const hints: string[] = [];
+  if (!report.indexed) {
+    hints.push(
+      report.problem
+        ? `The index can't be read (${report.problem}); run refresh_index to rebuild it.`
+        : 'There is no index yet; run refresh_index.'
+    );
+    return hints;
+  }
+  if (ctx.indexState.status === 'indexing') {
+    hints.push('An index build is running; these numbers are from the previous build.');
+  }
+  const stale = report.staleFiles;
+  if (stale && stale.missing + stale.changed > 0) {
+    const count = stale.missing + stale.changed;
+    hints.push(
+      `${stale.sampled ? 'About ' : ''}${count} indexed file(s) changed or were deleted since ` +
+        'indexing; run refresh_index({ incrementalOnly: true }).'
+    );
+  }
+  if (!report.embedding) {
+    hints.push(
+      'No embedding model is recorded (keyword-only or older index), so semantic matches ' +
+        'may be missing.'
+    );
+  }
+  if (report.chunks === 0) {
+    hints.push('The index has no chunks; check ignore rules and allow/deny paths.');
+  }
+  return hints;
+}
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const ref = typeof args.ref === 'string' && args.ref.trim() ? args.ref.trim() : undefined;
+  const report = await collectIndexStats(ctx.rootPath, { ref });
+  const hints = buildHints(report, ctx);
+
+  return {
+    content: [
+      {
+        type: 'text',
+        text: JSON.stringify(
+          {
+            status: 'success',
+            indexState: ctx.indexState.status,
+            lastIndexed: report.generatedAt ?? ctx.indexState.lastIndexed?.toISOString(),
+            ...report,
+            ...(hints.length > 0 ? { hints } : {})
+          },
+          null,
+          2
+        )
+      }
+    ]
+  };
+}
@@ -1,5 +1,8 @@
 import type { Tool } from '@modelcontextprotocol/sdk/types.js';
 import type { ToolContext, ToolResponse } from './types.js';
+import { readIndexCheckpoint } from '../core/index-checkpoint.js';
+import { overallProgress } from '../core/indexing-progress.js';
+import { describeModelMigration, readModelMigration } from '../core/model-migration.js';
 
 export const definition: Tool = {
   name: 'get_indexing_status',
@@ -17,6 +20,9 @@ export async function handle(
   ctx: ToolContext
 ): Promise<ToolResponse> {
   const progress = ctx.indexState.indexer?.getProgress();
+  const checkpoint =
+    ctx.indexState.status === 'indexing' ? null : await readIndexCheckpoint(ctx.paths.baseDir);
+  const migration = await readModelMigration(ctx.paths.baseDir);
 
   return {
     content: [
@@ -40,12 +46,33 @@ export async function handle(
               ? {
                   phase: progress.phase,
                   percentage: progress.percentage,
+                  readiness: overallProgress(progress),
                   filesProcessed: progress.filesProcessed,
                   totalFiles: progress.totalFiles
                 }
               : undefined,
             error: ctx.indexState.error,
-            hint: 'Use refresh_index to manually trigger re-indexing when needed.'
+            ...(checkpoint && {
+              interruptedBuild: {
+                mode: checkpoint.mode,
+                phase: checkpoint.phase,
+                filesProcessed: checkpoint.filesProcessed,
+                totalFiles: checkpoint.totalFiles,
+                stoppedAt: checkpoint.stoppedAt
+              }
+            }),
+            ...(migration && {
+              migration: describeModelMigration(
+                migration,
+                ctx.indexState.status === 'indexing' ? progress : undefined
+              )
+            }),
+            hint: checkpoint
+              ? 'A build was cancelled before it finished. refresh_index resumes it, reusing ' +
+                'the vectors embedded so far.'
+              : migration
+                ? 'Searches use the previous model until re-embedding completes, then switch over.'
+                : 'Use refresh_index to manually trigger re-indexing when needed.'
           },
           null,
           2
@@ -0,0 +1,90 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { loadSymbolIndex } from '../core/symbol-index.js';
+import { getSymbolDocs } from '../core/symbol-navigation.js';
+import { loadPreciseIndex } from '../core/precise-index.js';
+import { createLspHover } from '../core/lsp-bridge.js';
+import { jsonResponse } from './json-response.js';
+
+const DEFAULT_LIMIT = 5;
+
+export const definition: Tool = {
+  name: 'get_symbol_docs',
+  description:
+    "The author's documentation for a symbol: its doc comment (JSDoc, KDoc, Javadoc, GoDoc, " +
+    '`///` comments or Python docstring) and declaration line, read from the current source. ' +
+    'Accepts plain or qualified names. Falls back to hover text from an imported SCIP/LSIF ' +
+    'index, or from a language server when the LSP bridge is on.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      symbol: {
+        type: 'string',
+        description: 'Exact symbol name (for example: parseConfig or UserService.save)'
+      },
+      limit: {
+        type: 'number',
+        description: `Maximum definitions to return (default: ${DEFAULT_LIMIT})`,
+        default: DEFAULT_LIMIT
+      }
+    },
+    required: ['symbol']
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { symbol, limit } = args as { symbol?: unknown; limit?: unknown };
+  const normalizedSymbol = typeof symbol === 'string' ? symbol.trim() : '';
+  const normalizedLimit =
+    typeof limit === 'number' && Number.isFinite(limit) && limit > 0
+      ? Math.min(Math.floor(limit), 20)
+      : DEFAULT_LIMIT;
+
+  if (!normalizedSymbol) {
+    return jsonResponse(
+      {
+        status: 'error',
+        message: "Invalid params: 'symbol' is required and must be a non-empty string."
+      },
+      true
+    );
+  }
+
+  const definitions = await loadSymbolIndex(ctx.rootPath);
+  if (!definitions) {
+    return jsonResponse({
+      status: 'error',
+      symbol: normalizedSymbol,
+      message: 'Symbol index not available. Run refresh_index to build it.'
+    });
+  }
+  const precise = await loadPreciseIndex(ctx.rootPath, { persist: !ctx.pathPolicy?.readOnly });
+
+  const result = await getSymbolDocs(
+    ctx.rootPath,
+    definitions,
+    normalizedSymbol,
+    { limit: normalizedLimit, hover: await createLspHover(ctx.rootPath) },
+    precise
+  );
+
+  if (result.total === 0) {
+    return jsonResponse({
+      status: 'not_found',
+      symbol: normalizedSymbol,
+      message: 'No definition with this exact name. Try search_symbols for fuzzy matches.'
+    });
+  }
+
+  return jsonResponse({
+    status: 'success',
+    symbol: normalizedSymbol,
+    totalDefinitions: result.total,
+    documented: result.documented,
+    symbols: result.symbols,
+    ...(result.languageServers ? { languageServers: result.languageServers } : {})
+  });
+}
@@ -1,6 +1,7 @@
 import type { Tool } from '@modelcontextprotocol/sdk/types.js';
 import type { ToolContext, ToolResponse } from './types.js';
 import { findSymbolReferences } from '../core/symbol-references.js';
+import { partialMarker } from '../core/cancellation.js';
 
 export const definition: Tool = {
   name: 'get_symbol_references',
@@ -51,7 +52,12 @@ export async function handle(
     };
   }
 
-  const result = await findSymbolReferences(ctx.rootPath, normalizedSymbol, normalizedLimit);
+  const result = await findSymbolReferences(
+    ctx.rootPath,
+    normalizedSymbol,
+    normalizedLimit,
+    ctx.signal
+  );
 
   if (result.status === 'error') {
     return {
@@ -83,7 +89,8 @@ export async function handle(
             usageCount: result.usageCount,
             usages: result.usages,
             confidence: result.confidence,
-            isComplete: result.isComplete
+            isComplete: result.isComplete,
+            ...(result.partial ? partialMarker(ctx.signal) : {})
           },
           null,
           2
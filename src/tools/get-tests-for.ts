@@ -0,0 +1,135 @@
+import { promises as fs } from 'fs';
+import path from 'path';
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { loadCallGraph, normalizeCallSymbol } from '../core/call-graph.js';
+import { loadSymbolIndex } from '../core/symbol-index.js';
+import { matchDefinitions } from '../core/symbol-navigation.js';
+import {
+  findTestsForFile,
+  findTestsForSymbol,
+  isTestSourceFile,
+  loadTestLinks
+} from '../core/test-mapping.js';
+import { jsonResponse } from './json-response.js';
+
+const DEFAULT_LIMIT = 10;
+
+export const definition: Tool = {
+  name: 'get_tests_for',
+  description:
+    'Find the tests that exercise a file or symbol, to run or update them alongside a change. ' +
+    'Tests are linked by naming convention, imports and calls; symbol lookups also list the ' +
+    'test functions that call the symbol directly.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      target: {
+        type: 'string',
+        description: 'Repo-relative file path or symbol name (for example: UserService.save)'
+      },
+      limit: {
+        type: 'number',
+        description: `Maximum test files to return (default: ${DEFAULT_LIMIT})`,
+        default: DEFAULT_LIMIT
+      }
+    },
+    required: ['target']
+  }
+};
+
+async function isProjectFile(rootPath: string, relative: string): Promise<boolean> {
+  try {
+    return (await fs.stat(path.join(rootPath, relative))).isFile();
+  } catch {
+    return false;
+  }
+}
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { target, limit } = args as { target?: unknown; limit?: unknown };
+  const normalizedTarget = typeof target === 'string' ? target.trim() : '';
+  const normalizedLimit =
+    typeof limit === 'number' && Number.isFinite(limit) && limit > 0
+      ? Math.min(Math.floor(limit), 50)
+      : DEFAULT_LIMIT;
+
+  if (!normalizedTarget) {
+    return jsonResponse(
+      {
+        status: 'error',
+        message: "Invalid params: 'target' is required and must be a non-empty string."
+      },
+      true
+    );
+  }
+
+  const links = await loadTestLinks(ctx.rootPath);
+  if (!links) {
+    return jsonResponse({
+      status: 'error',
+      target: normalizedTarget,
+      message: 'Test links not available. Run refresh_index to rebuild the index.'
+    });
+  }
+
+  const relativeFile = (
+    path.isAbsolute(normalizedTarget)
+      ? path.relative(ctx.rootPath, normalizedTarget)
+      : normalizedTarget
+  )
+    .replace(/\\/g, '/')
+    .replace(/^\.\//, '');
+  if (links[relativeFile] || (await isProjectFile(ctx.rootPath, relativeFile))) {
+    if (isTestSourceFile(relativeFile)) {
+      return jsonResponse({
+        status: 'success',
+        target: relativeFile,
+        kind: 'file',
+        isTest: true,
+        tests: [],
+        message: 'The target is itself a test file.'
+      });
+    }
+    const tests = findTestsForFile(links, relativeFile);
+    return jsonResponse({
+      status: tests.length > 0 ? 'success' : 'not_found',
+      target: relativeFile,
+      kind: 'file',
+      total: tests.length,
+      tests: tests.slice(0, normalizedLimit),
+      ...(tests.length === 0 ? { message: 'No tests found for this file.' } : {})
+    });
+  }
+
+  const symbolIndex = (await loadSymbolIndex(ctx.rootPath)) ?? [];
+  const definitions = matchDefinitions(symbolIndex, normalizedTarget);
+  const symbolName = definitions[0]?.name ?? normalizeCallSymbol(normalizedTarget);
+  const definitionFiles = [...new Set(definitions.map((d) => d.file))];
+  const tests = findTestsForSymbol(
+    links,
+    await loadCallGraph(ctx.rootPath),
+    symbolName,
+    definitionFiles
+  );
+
+  return jsonResponse({
+    status: tests.length > 0 ? 'success' : 'not_found',
+    target: normalizedTarget,
+    kind: 'symbol',
+    definedIn: definitionFiles.slice(0, 5),
+    total: tests.length,
+    tests: tests.slice(0, normalizedLimit),
+    ...(tests.length === 0
+      ? {
+          message:
+            definitions.length === 0
+              ? 'Symbol not found in the index and no test calls it.'
+              : 'No tests found for this symbol or the files that define it.'
+        }
+      : {})
+  });
+}
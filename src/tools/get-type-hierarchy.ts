@@ -0,0 +1,139 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import {
+  getTypeHierarchy,
+  loadTypeHierarchy,
+  type HierarchyDirection
+} from '../core/type-hierarchy.js';
+
+const DEFAULT_DEPTH = 2;
+const MAX_DEPTH = 5;
+
+export const definition: Tool = {
+  name: 'get_type_hierarchy',
+  description:
+    'Supertypes and subtypes of a class, interface or struct (TypeScript/JavaScript and Go) as ' +
+    'a tree with file:line. Go interface satisfaction is inferred from method names; names ' +
+    'declared outside the repo are marked external.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      type: {
+        type: 'string',
+        description: 'Type name (for example: UserService or FileStore)'
+      },
+      direction: {
+        type: 'string',
+        enum: ['up', 'down', 'both'],
+        description: 'up = supertypes, down = subtypes and implementations (default: both)',
+        default: 'both'
+      },
+      depth: {
+        type: 'number',
+        description: `Levels to expand in each direction (default: ${DEFAULT_DEPTH}, max: ${MAX_DEPTH})`,
+        default: DEFAULT_DEPTH
+      }
+    },
+    required: ['type']
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { type, direction, depth } = args as {
+    type?: unknown;
+    direction?: unknown;
+    depth?: unknown;
+  };
+  const normalizedType = typeof type === 'string' ? type.trim() : '';
+  const normalizedDirection: HierarchyDirection =
+    direction === 'up' || direction === 'down' ? direction : 'both';
+  const normalizedDepth =
+    typeof depth === 'number' && Number.isFinite(depth) && depth >= 1
+      ? Math.min(Math.floor(depth), MAX_DEPTH)
+      : DEFAULT_DEPTH;
+
+  if (!normalizedType) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              message: "Invalid params: 'type' is required and must be a non-empty string."
+            },
+            null,
+            2
+          )
+        }
+      ],
+      isError: true
+    };
+  }
+
+  const types = await loadTypeHierarchy(ctx.rootPath);
+  if (!types) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              type: normalizedType,
+              message: 'Type hierarchy not available. Run refresh_index to build it.'
+            },
+            null,
+            2
+          )
+        }
+      ]
+    };
+  }
+
+  const hierarchies = getTypeHierarchy(types, normalizedType, {
+    direction: normalizedDirection,
+    depth: normalizedDepth
+  });
+  if (hierarchies.length === 0) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'not_found',
+              type: normalizedType,
+              message: `No type named ${normalizedType} in TypeScript, JavaScript or Go files.`
+            },
+            null,
+            2
+          )
+        }
+      ]
+    };
+  }
+
+  return {
+    content: [
+      {
+        type: 'text',
+        text: JSON.stringify(
+          {
+            status: 'success',
+            type: normalizedType,
+            direction: normalizedDirection,
+            depth: normalizedDepth,
+            hierarchies,
+            confidence: 'syntactic'
+          },
+          null,
+          2
+        )
+      }
+    ]
+  };
+}
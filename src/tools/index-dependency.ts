@@ -0,0 +1,83 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { indexDependency, parseDependencySpec } from '../core/dependency-sources.js';
+
+export const definition: Tool = {
+  name: 'index_dependency',
+  description:
+    'Add a third-party dependency to the index from its local source: the Go module cache or ' +
+    'vendor/, node_modules, or vendored/registry crates. Indexed separately and left out of ' +
+    'searches unless search_codebase is called with includeDependencies. Waits for indexing.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      name: {
+        type: 'string',
+        description:
+          'Module, package or crate, optionally @version: "github.com/gin-gonic/gin@v1.9", ' +
+          '"express", "serde@1.0". Without a version, the one go.mod/Cargo.lock/node_modules has.'
+      },
+      refresh: {
+        type: 'boolean',
+        description: 'Re-index even if this version is already indexed. Default: false'
+      }
+    },
+    required: ['name']
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { name, refresh } = args as { name?: unknown; refresh?: unknown };
+
+  try {
+    if (typeof name !== 'string' || !name.trim()) {
+      throw new Error('name is required, e.g. "github.com/gin-gonic/gin@v1.9".');
+    }
+    const dependency = await indexDependency(ctx.rootPath, parseDependencySpec(name), {
+      refresh: refresh === true
+    });
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: dependency.reused ? 'ready' : 'indexed',
+              dependency: dependency.id,
+              ecosystem: dependency.ecosystem,
+              sourceDir: dependency.sourceDir,
+              indexedFiles: dependency.indexedFiles,
+              totalChunks: dependency.totalChunks,
+              indexedAt: dependency.indexedAt,
+              message:
+                'Search it with search_codebase({ includeDependencies: ' +
+                `["${dependency.name}"] }).`
+            },
+            null,
+            2
+          )
+        }
+      ]
+    };
+  } catch (error) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              message: error instanceof Error ? error.message : String(error)
+            },
+            null,
+            2
+          )
+        }
+      ],
+      isError: true
+    };
+  }
+}
@@ -0,0 +1,146 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import { promises as fs } from 'fs';
+import path from 'path';
+import type { ToolContext, ToolResponse } from './types.js';
+import { CodebaseIndexer } from '../core/indexer.js';
+import {
+  fetchRemoteRepo,
+  parseRemoteRepoUrl,
+  remoteProjectName,
+  type FetchedRemote,
+  type RemoteFetchMethod,
+  type RemoteRepo
+} from '../core/remote-repo.js';
+import { CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME } from '../constants/codebase-context.js';
+import { jsonResponse } from './json-response.js';
+
+export const definition: Tool = {
+  name: 'index_remote',
+  description:
+    'Index a GitHub or GitLab repository by URL without a local checkout, e.g. a dependency ' +
+    'whose behavior you are debugging. Fetches one ref shallowly (git, or the tarball when git ' +
+    'is unavailable) into a local cache and adds it as a project for the other tools.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      url: {
+        type: 'string',
+        description:
+          'Repository URL, e.g. https://github.com/org/repo, .../tree/v2.1.0, ' +
+          'https://gitlab.com/group/repo/-/tree/main or git@github.com:org/repo.git'
+      },
+      ref: {
+        type: 'string',
+        description: 'Branch, tag or commit SHA (default: from the URL, else the default branch)'
+      },
+      method: {
+        type: 'string',
+        enum: ['auto', 'clone', 'tarball'],
+        description: 'auto (default): shallow git fetch when git is installed, else the tarball'
+      },
+      refresh: {
+        type: 'boolean',
+        description: 'Fetch and re-index even if this ref was fetched before. Default: false'
+      }
+    },
+    required: ['url']
+  }
+};
+
+async function hasIndex(rootPath: string): Promise<boolean> {
+  try {
+    await fs.access(path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME));
+    return true;
+  } catch {
+    return false;
+  }
+}
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { url, ref, method, refresh } = args as {
+    url?: unknown;
+    ref?: unknown;
+    method?: unknown;
+    refresh?: unknown;
+  };
+  if (typeof url !== 'string' || !url.trim()) {
+    return jsonResponse({ status: 'error', message: 'url is required.' }, true);
+  }
+
+  let repo: RemoteRepo;
+  let fetched: FetchedRemote;
+  try {
+    repo = parseRemoteRepoUrl(url);
+    fetched = await fetchRemoteRepo(repo, {
+      ref: typeof ref === 'string' ? ref : undefined,
+      method: ['auto', 'clone', 'tarball'].includes(method as string)
+        ? (method as RemoteFetchMethod)
+        : 'auto',
+      refresh: refresh === true
+    });
+  } catch (error) {
+    return jsonResponse(
+      { status: 'error', message: error instanceof Error ? error.message : String(error) },
+      true
+    );
+  }
+
+  const name = remoteProjectName(repo, fetched.ref);
+  const source = {
+    url: repo.cloneUrl,
+    ...(fetched.ref ? { ref: fetched.ref } : {}),
+    ...(fetched.commit ? { commit: fetched.commit } : {}),
+    method: fetched.method,
+    fetchedAt: fetched.fetchedAt,
+    reused: fetched.reused
+  };
+
+  // Inside the MCP server: serve it as another project, indexed in the background
+  if (ctx.addProject) {
+    const project = await ctx.addProject(
+      { name, rootPath: fetched.rootPath },
+      { reindex: !fetched.reused }
+    );
+    const ready = project.indexState.status === 'ready';
+    return jsonResponse({
+      status: ready ? 'ready' : 'indexing',
+      project: project.name,
+      rootPath: project.rootPath,
+      ...source,
+      message: ready
+        ? `Query it with search_codebase({ project: "${project.name}" }).`
+        : `Indexing ${project.name}. Check get_indexing_status({ project: "${project.name}" }), ` +
+          `then search_codebase({ project: "${project.name}" }).`
+    });
+  }
+
+  // CLI: index in the foreground
+  if (!fetched.reused || !(await hasIndex(fetched.rootPath))) {
+    try {
+      await new CodebaseIndexer({ rootPath: fetched.rootPath }).index();
+    } catch (error) {
+      return jsonResponse(
+        {
+          status: 'error',
+          project: name,
+          rootPath: fetched.rootPath,
+          ...source,
+          message:
+            'Fetched but indexing failed: ' +
+            (error instanceof Error ? error.message : String(error))
+        },
+        true
+      );
+    }
+  }
+  return jsonResponse({
+    status: 'ready',
+    project: name,
+    rootPath: fetched.rootPath,
+    ...source,
+    message: `Indexed. Search it with CODEBASE_ROOT=${fetched.rootPath} codebase-context search.`
+  });
+}
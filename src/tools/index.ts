@@ -1,4 +1,4 @@
-export type { ToolContext, ToolResponse, ToolPaths } from './types.js';
+export type { ToolContext, ToolResponse, ToolPaths, ToolProject } from './types.js';
 
 import type { Tool } from '@modelcontextprotocol/sdk/types.js';
 
@@ -12,15 +12,255 @@ import { definition as d7, handle as h7 } from './get-symbol-references.js';
 import { definition as d8, handle as h8 } from './detect-circular-dependencies.js';
 import { definition as d9, handle as h9 } from './remember.js';
 import { definition as d10, handle as h10 } from './get-memory.js';
+import { definition as d11, handle as h11 } from './find-callers.js';
+import { definition as d12, handle as h12 } from './find-callees.js';
+import { definition as d13, handle as h13 } from './get-diff-context.js';
+import { definition as d14, handle as h14 } from './list-projects.js';
+import { definition as d15, handle as h15 } from './search-symbols.js';
+import { definition as d16, handle as h16 } from './get-dependencies.js';
+import { definition as d17, handle as h17 } from './get-dependents.js';
+import { definition as d18, handle as h18 } from './pack-context.js';
+import { definition as d19, handle as h19 } from './get-definition.js';
+import { definition as d20, handle as h20 } from './find-references.js';
+import { definition as d21, handle as h21 } from './search-history.js';
+import { definition as d22, handle as h22 } from './summarize-file.js';
+import { definition as d23, handle as h23 } from './list-packages.js';
+import { definition as d24, handle as h24 } from './get-tests-for.js';
+import { definition as d25, handle as h25 } from './analyze-unused.js';
+import { definition as d26, handle as h26 } from './index-remote.js';
+import { definition as d27, handle as h27 } from './index-dependency.js';
+import { definition as d28, handle as h28 } from './get-enclosing-scope.js';
+import { definition as d29, handle as h29 } from './get-index-stats.js';
+import { definition as d30, handle as h30 } from './find-implementations.js';
+import { definition as d31, handle as h31 } from './get-type-hierarchy.js';
+import { definition as d32, handle as h32 } from './rollback-index.js';
+import { definition as d33, handle as h33 } from './get-symbol-docs.js';
+import { definition as d34, handle as h34 } from './find-sql-queries.js';
+import { definition as d35, handle as h35 } from './find-similar-code.js';
+import { definition as d36, handle as h36 } from './resolve-stacktrace.js';
+import { definition as d37, handle as h37 } from './changes-since.js';
+import { definition as d38, handle as h38 } from './rate-result.js';
+import { definition as d39, handle as h39 } from './multi-search.js';
+import { definition as d40, handle as h40 } from './describe-project.js';
+import { definition as d41, handle as h41 } from './get-build-target.js';
+import { definition as d42, handle as h42 } from './get-build-dependents.js';
+import { definition as d43, handle as h43 } from './retrieve-with-citations.js';
 
 import type { ToolContext, ToolResponse } from './types.js';
+import {
+  WRITING_TOOL_NAMES,
+  filterPayloadPaths,
+  loadPathPolicy,
+  refusedArgumentPath,
+  type PathPolicy
+} from '../core/path-policy.js';
+import { OUTPUT_FORMATS } from './output-format.js';
+import { jsonResponse } from './json-response.js';
+import { metrics, startTimer, withSpan } from '../core/telemetry.js';
+import { resolveToolTimeoutMs, withTimeBudget, type StopReason } from '../core/cancellation.js';
 
-export const TOOLS: Tool[] = [d1, d2, d3, d4, d5, d6, d7, d8, d9, d10];
+export const TOOLS: Tool[] = [
+  d1,
+  d2,
+  d3,
+  d4,
+  d5,
+  d6,
+  d7,
+  d8,
+  d9,
+  d10,
+  d11,
+  d12,
+  d13,
+  d14,
+  d15,
+  d16,
+  d17,
+  d18,
+  d19,
+  d20,
+  d21,
+  d22,
+  d23,
+  d24,
+  d25,
+  d26,
+  d27,
+  d28,
+  d29,
+  d30,
+  d31,
+  d32,
+  d33,
+  d34,
+  d35,
+  d36,
+  d37,
+  d38,
+  d39,
+  d40,
+  d41,
+  d42,
+  d43
+];
 
+/**
+ * Add a `project` selector to every tool schema. Only advertised when more than one
+ * project root is configured, so single-root clients see unchanged schemas.
+ */
+export function withProjectParam(tools: Tool[], projectNames: string[]): Tool[] {
+  if (projectNames.length < 2) return tools;
+
+  const defaultHint = ` (default: ${projectNames[0]})`;
+  return tools.map((tool) => {
+    if (tool.name === 'list_projects' || tool.name === 'index_remote') return tool;
+    const scope = tool.name === 'search_codebase' ? ', or "all" to search every project' : '';
+    return {
+      ...tool,
+      inputSchema: {
+        ...tool.inputSchema,
+        properties: {
+          ...tool.inputSchema.properties,
+          project: {
+            type: 'string',
+            description: `Project to target: ${projectNames.join(', ')}${defaultHint}${scope}`
+          }
+        }
+      }
+    };
+  });
+}
+
+/** Add the `format` selector every tool accepts (see output-format.ts) */
+export function withFormatParam(tools: Tool[]): Tool[] {
+  return tools.map((tool) => ({
+    ...tool,
+    inputSchema: {
+      ...tool.inputSchema,
+      properties: {
+        ...tool.inputSchema.properties,
+        format: {
+          type: 'string',
+          enum: [...OUTPUT_FORMATS],
+          description:
+            'Response format. json: stable schema (results with path, start_line, end_line, ' +
+            'symbol, score, language, content); markdown or plain for reading. Default: raw JSON'
+        }
+      }
+    }
+  }));
+}
+
+/** Tools a client may call; writing tools are left out when the server is read-only */
+export function visibleTools(tools: Tool[], readOnly: boolean): Tool[] {
+  return readOnly ? tools.filter((tool) => !WRITING_TOOL_NAMES.includes(tool.name)) : tools;
+}
+
+function errorResponse(errorCode: string, message: string): ToolResponse {
+  return jsonResponse({ status: 'error', errorCode, message }, true);
+}
+
+function stoppedResponse(name: string, reason: StopReason, timeoutMs: number): ToolResponse {
+  return reason === 'timeout'
+    ? errorResponse(
+        'timeout',
+        `${name} did not finish within ${timeoutMs}ms. Narrow the request or raise ` +
+          'CODEBASE_CONTEXT_TOOL_TIMEOUT_MS.'
+      )
+    : errorResponse('cancelled', `${name} was cancelled by the client.`);
+}
+
+function refusedPathResponse(filePath: string): ToolResponse {
+  return errorResponse(
+    'path_not_allowed',
+    `'${filePath}' is outside the paths this server exposes.`
+  );
+}
+
+/** Drop the parts of a JSON response that point at paths the policy refuses */
+function applyPathPolicy(response: ToolResponse, policy: PathPolicy): ToolResponse {
+  const [first, ...rest] = response.content ?? [];
+  if (!first) return response;
+  let parsed: unknown;
+  try {
+    parsed = JSON.parse(first.text);
+  } catch {
+    return response;
+  }
+
+  const filtered = filterPayloadPaths(parsed, policy);
+  if (filtered.refused !== undefined) return refusedPathResponse(filtered.refused);
+  const payload =
+    filtered.withheld > 0 && filtered.payload && typeof filtered.payload === 'object'
+      ? { ...filtered.payload, withheldByPathPolicy: filtered.withheld }
+      : filtered.payload;
+  return {
+    ...response,
+    content: [{ type: 'text', text: JSON.stringify(payload, null, 2) }, ...rest]
+  };
+}
+
+const TOOL_NAMES = new Set(TOOLS.map((tool) => tool.name));
+
+/**
+ * Run a tool under the project's path policy: writing tools are refused in read-only mode,
+ * and entries pointing at denied paths are dropped from the response. Every call is counted
+ * and timed, and traced when tracing is on. Calls run under a time budget and stop when the
+ * client cancels them.
+ */
 export async function dispatchTool(
   name: string,
   args: Record<string, unknown>,
   ctx: ToolContext
+): Promise<ToolResponse> {
+  // Unknown names from a client would otherwise become unbounded metric labels
+  const tool = TOOL_NAMES.has(name) ? name : 'unknown';
+  return withSpan(`tools/call ${tool}`, { 'mcp.tool.name': tool }, async (span) => {
+    const elapsed = startTimer();
+    let status = 'error';
+    const timeoutMs = resolveToolTimeoutMs(name);
+    try {
+      const response = await withTimeBudget(
+        (signal) => dispatchUnderPolicy(name, args, { ...ctx, signal }),
+        {
+          timeoutMs,
+          signal: ctx.signal,
+          onStopped: (reason) => {
+            status = reason;
+            return stoppedResponse(name, reason, timeoutMs);
+          }
+        }
+      );
+      if (response.isError) span.setAttribute('error', true);
+      else status = 'ok';
+      return response;
+    } finally {
+      metrics.toolCalls.inc({ tool, status });
+      metrics.toolDuration.observe({ tool }, elapsed());
+    }
+  });
+}
+
+async function dispatchUnderPolicy(
+  name: string,
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const pathPolicy = ctx.pathPolicy ?? (await loadPathPolicy(ctx.rootPath));
+  if (pathPolicy.readOnly && WRITING_TOOL_NAMES.includes(name)) {
+    return errorResponse('read_only', `${name} is disabled: this server is read-only.`);
+  }
+  const refused = pathPolicy.active ? refusedArgumentPath(args, pathPolicy) : undefined;
+  if (refused !== undefined) return refusedPathResponse(refused);
+  const response = await routeTool(name, args, { ...ctx, pathPolicy });
+  return pathPolicy.active ? applyPathPolicy(response, pathPolicy) : response;
+}
+
+async function routeTool(
+  name: string,
+  args: Record<string, unknown>,
+  ctx: ToolContext
 ): Promise<ToolResponse> {
   switch (name) {
     case 'search_codebase':
@@ -43,6 +283,72 @@ export async function dispatchTool(
       return h9(args, ctx);
     case 'get_memory':
       return h10(args, ctx);
+    case 'find_callers':
+      return h11(args, ctx);
+    case 'find_callees':
+      return h12(args, ctx);
+    case 'get_diff_context':
+      return h13(args, ctx);
+    case 'list_projects':
+      return h14(args, ctx);
+    case 'search_symbols':
+      return h15(args, ctx);
+    case 'get_dependencies':
+      return h16(args, ctx);
+    case 'get_dependents':
+      return h17(args, ctx);
+    case 'pack_context':
+      return h18(args, ctx);
+    case 'get_definition':
+      return h19(args, ctx);
+    case 'find_references':
+      return h20(args, ctx);
+    case 'search_history':
+      return h21(args, ctx);
+    case 'summarize_file':
+      return h22(args, ctx);
+    case 'list_packages':
+      return h23(args, ctx);
+    case 'get_tests_for':
+      return h24(args, ctx);
+    case 'analyze_unused':
+      return h25(args, ctx);
+    case 'index_remote':
+      return h26(args, ctx);
+    case 'index_dependency':
+      return h27(args, ctx);
+    case 'get_enclosing_scope':
+      return h28(args, ctx);
+    case 'get_index_stats':
+      return h29(args, ctx);
+    case 'find_implementations':
+      return h30(args, ctx);
+    case 'get_type_hierarchy':
+      return h31(args, ctx);
+    case 'rollback_index':
+      return h32(args, ctx);
+    case 'get_symbol_docs':
+      return h33(args, ctx);
+    case 'find_sql_queries':
+      return h34(args, ctx);
+    case 'find_similar_code':
+      return h35(args, ctx);
+    case 'resolve_stacktrace':
+      return h36(args, ctx);
+    case 'changes_since':
+      return h37(args, ctx);
+    case 'rate_result':
+      return h38(args, ctx);
+    case 'multi_search':
+      return h39(args, ctx);
+    case 'describe_project':
+      return h40(args, ctx);
+    case 'get_build_target':
+      return h41(args, ctx);
+    case 'get_build_dependents':
+      return h42(args, ctx);
+    case 'retrieve_with_citations':
+      return h43(args, ctx);
     default:
       return {
         content: [{ type: 'text', text: JSON.stringify({ error: `Unknown tool: ${name}` }) }],
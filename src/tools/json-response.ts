@@ -0,0 +1,9 @@
+import type { ToolResponse } from './types.js';
+
+/** A tool payload as pretty-printed JSON text, flagged as an error when `isError` is set */
+export function jsonResponse(payload: Record<string, unknown>, isError = false): ToolResponse {
+  return {
+    content: [{ type: 'text', text: JSON.stringify(payload, null, 2) }],
+    ...(isError ? { isError: true } : {})
+  };
+}
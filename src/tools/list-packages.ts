@@ -0,0 +1,106 @@
+import { promises as fs } from 'fs';
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import type { CodeChunk } from '../types/index.js';
+import { openIndexedChunks } from '../core/index-encryption.js';
+import { withIndexReadLock } from '../core/index-lock.js';
+import { detectWorkspacePackages, type WorkspaceEcosystem } from '../utils/workspace-detection.js';
+import { jsonResponse } from './json-response.js';
+
+const DEFAULT_LIMIT = 50;
+const ECOSYSTEMS: WorkspaceEcosystem[] = ['npm', 'go', 'cargo', 'bazel'];
+
+export const definition: Tool = {
+  name: 'list_packages',
+  description:
+    'List the packages of a monorepo (npm/pnpm workspaces, go.work modules, Cargo crates, ' +
+    'Bazel packages) with their directories and indexed file counts. Pass a name as ' +
+    'filters.package to search_codebase to scope a search to one package.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      ecosystem: {
+        type: 'string',
+        enum: ECOSYSTEMS,
+        description: 'Only packages of this kind'
+      },
+      limit: {
+        type: 'number',
+        description: `Maximum packages to return (default: ${DEFAULT_LIMIT})`,
+        default: DEFAULT_LIMIT
+      }
+    }
+  }
+};
+
+/** package name -> files and chunks in the keyword index, or null without an index */
+async function countIndexedByPackage(
+  keywordIndexPath: string
+): Promise<Map<string, { files: Set<string>; chunks: number }> | null> {
+  let chunks: CodeChunk[];
+  try {
+    const raw = await withIndexReadLock(path.dirname(keywordIndexPath), () =>
+      fs.readFile(keywordIndexPath, 'utf-8')
+    );
+    const parsed = JSON.parse(raw) as { chunks?: CodeChunk[] };
+    chunks = parsed.chunks ?? [];
+  } catch {
+    return null;
+  }
+  const counts = new Map<string, { files: Set<string>; chunks: number }>();
+  for (const chunk of await openIndexedChunks(chunks)) {
+    const name = chunk.metadata?.package;
+    if (!name) continue;
+    const entry = counts.get(name) ?? { files: new Set<string>(), chunks: 0 };
+    entry.files.add(chunk.relativePath);
+    entry.chunks++;
+    counts.set(name, entry);
+  }
+  return counts;
+}
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { ecosystem, limit } = args as { ecosystem?: unknown; limit?: unknown };
+  if (ecosystem !== undefined && !ECOSYSTEMS.includes(ecosystem as WorkspaceEcosystem)) {
+    return jsonResponse(
+      {
+        status: 'error',
+        message: `Invalid params: 'ecosystem' must be one of ${ECOSYSTEMS.join(', ')}.`
+      },
+      true
+    );
+  }
+  const normalizedLimit =
+    typeof limit === 'number' && Number.isFinite(limit) && limit > 0
+      ? Math.min(Math.floor(limit), 200)
+      : DEFAULT_LIMIT;
+
+  const detected = await detectWorkspacePackages(ctx.rootPath);
+  const packages = ecosystem ? detected.filter((pkg) => pkg.ecosystem === ecosystem) : detected;
+  if (packages.length === 0) {
+    return jsonResponse({
+      status: 'success',
+      totalPackages: 0,
+      packages: [],
+      message: 'No workspace packages detected; the project is indexed as a single package.'
+    });
+  }
+
+  const counts = await countIndexedByPackage(ctx.paths.keywordIndex);
+  return jsonResponse({
+    status: 'success',
+    totalPackages: packages.length,
+    packages: packages.slice(0, normalizedLimit).map((pkg) => {
+      const indexed = counts?.get(pkg.name);
+      return {
+        ...pkg,
+        ...(counts ? { files: indexed?.files.size ?? 0, chunks: indexed?.chunks ?? 0 } : {})
+      };
+    }),
+    ...(packages.length > normalizedLimit ? { truncated: true } : {}),
+    ...(counts ? {} : { hint: 'Run refresh_index to see indexed file counts per package.' })
+  });
+}
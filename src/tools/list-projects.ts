@@ -0,0 +1,59 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import path from 'path';
+import type { ToolContext, ToolProject, ToolResponse } from './types.js';
+import { buildFleetGraph } from '../core/fleet-graph.js';
+
+export const definition: Tool = {
+  name: 'list_projects',
+  description:
+    'List the project roots served by this instance with their index status. ' +
+    'Pass a project name as `project` to scope any tool, or project="all" to search every root ' +
+    'or find references across them. dependsOn lists the other projects whose packages each ' +
+    'project declares as dependencies.',
+  inputSchema: {
+    type: 'object',
+    properties: {}
+  }
+};
+
+export async function handle(
+  _args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const projects: ToolProject[] = ctx.projects ?? [
+    { name: path.basename(ctx.rootPath), rootPath: ctx.rootPath, indexState: ctx.indexState }
+  ];
+  // Shared libraries link projects: the packages one publishes that another's manifests list
+  const fleet = projects.length > 1 ? await buildFleetGraph(projects) : null;
+
+  return {
+    content: [
+      {
+        type: 'text',
+        text: JSON.stringify(
+          {
+            status: 'success',
+            totalProjects: projects.length,
+            projects: projects.map((project, i) => ({
+              name: project.name,
+              rootPath: project.rootPath,
+              ...(i === 0 && { primary: true }),
+              indexStatus: project.indexState.status,
+              ...(project.indexState.lastIndexed && {
+                lastIndexed: project.indexState.lastIndexed.toISOString()
+              }),
+              ...(project.indexState.stats && {
+                indexedFiles: project.indexState.stats.indexedFiles
+              }),
+              ...(fleet?.projects[project.name]?.dependsOn.length && {
+                dependsOn: fleet.projects[project.name].dependsOn
+              })
+            }))
+          },
+          null,
+          2
+        )
+      }
+    ]
+  };
+}
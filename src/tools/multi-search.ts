@@ -0,0 +1,133 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import path from 'path';
+import type { ToolContext, ToolResponse } from './types.js';
+import { CodebaseSearcher, type SearchOptions } from '../core/search.js';
+import { groupQueryResults, type QueryHit } from '../core/multi-search.js';
+import { partialMarker } from '../core/cancellation.js';
+import { IndexCorruptedError } from '../errors/index.js';
+import { jsonResponse } from './json-response.js';
+
+const MAX_QUERIES = 8;
+const DEFAULT_LIMIT = 3;
+const MAX_LIMIT = 10;
+
+export const definition: Tool = {
+  name: 'multi_search',
+  description:
+    'Run several related searches in one call, concurrently against the same index. A chunk ' +
+    'found by more than one query is listed once, under the query that ranked it highest, ' +
+    'with `alsoFor` naming the other queries. Use it instead of back-to-back search_codebase ' +
+    'calls when exploring one area from several angles.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      queries: {
+        type: 'array',
+        items: { type: 'string' },
+        minItems: 1,
+        maxItems: MAX_QUERIES,
+        description: `Natural language search queries (at most ${MAX_QUERIES})`
+      },
+      limit: {
+        type: 'number',
+        description:
+          `Results per query before deduplication (default: ${DEFAULT_LIMIT}, ` +
+          `max: ${MAX_LIMIT})`,
+        default: DEFAULT_LIMIT
+      },
+      mode: {
+        type: 'string',
+        enum: ['hybrid', 'keyword', 'semantic'],
+        description: 'Retrieval mode for every query, as in search_codebase (default: hybrid)',
+        default: 'hybrid'
+      }
+    },
+    required: ['queries']
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { queries, limit, mode } = args as { queries?: unknown; limit?: unknown; mode?: unknown };
+  const queryList = Array.isArray(queries)
+    ? queries
+        .filter((query): query is string => typeof query === 'string')
+        .map((query) => query.trim())
+        .filter(Boolean)
+    : [];
+  if (queryList.length === 0 || queryList.length > MAX_QUERIES) {
+    return jsonResponse(
+      {
+        status: 'error',
+        message: `Invalid params: 'queries' must hold 1 to ${MAX_QUERIES} non-empty strings.`
+      },
+      true
+    );
+  }
+  const perQuery =
+    typeof limit === 'number' && Number.isFinite(limit) && limit > 0
+      ? Math.min(Math.floor(limit), MAX_LIMIT)
+      : DEFAULT_LIMIT;
+
+  if (ctx.indexState.status === 'indexing') {
+    return jsonResponse({
+      status: 'indexing',
+      message: 'Index is still being built. Retry in a moment.',
+      progress: ctx.indexState.indexer?.getProgress()
+    });
+  }
+
+  const retrievalMode = mode === 'keyword' || mode === 'semantic' ? mode : 'hybrid';
+  const options: SearchOptions = {
+    useSemanticSearch: retrievalMode !== 'keyword',
+    useKeywordSearch: retrievalMode !== 'semantic',
+    ...(ctx.signal ? { signal: ctx.signal } : {})
+  };
+
+  let hitsPerQuery: QueryHit[][];
+  try {
+    // One searcher loads the index once; the queries then share it
+    const searcher = new CodebaseSearcher(ctx.rootPath);
+    await searcher.initialize();
+    const root = path.resolve(ctx.rootPath);
+    hitsPerQuery = await Promise.all(
+      queryList.map(async (query) =>
+        (await searcher.search(query, perQuery, undefined, options)).map((result) => ({
+          file: path.relative(root, path.resolve(root, result.filePath)).replace(/\\/g, '/'),
+          startLine: result.startLine,
+          endLine: result.endLine,
+          score: result.score,
+          summary: result.summary
+        }))
+      )
+    );
+  } catch (error) {
+    return jsonResponse({
+      status: 'error',
+      message:
+        error instanceof IndexCorruptedError
+          ? `Index unavailable: ${error.message}`
+          : `Search failed: ${error instanceof Error ? error.message : String(error)}`,
+      hint: 'Run refresh_index, then retry.'
+    });
+  }
+
+  const { groups, duplicates } = groupQueryResults(hitsPerQuery);
+  return jsonResponse({
+    status: 'success',
+    ...partialMarker(ctx.signal),
+    searches: queryList.map((query, i) => ({
+      query,
+      results: groups[i].map((hit) => ({
+        file: `${hit.file}:${hit.startLine}-${hit.endLine}`,
+        ...(hit.summary && { summary: hit.summary }),
+        score: Math.round(hit.score * 100) / 100,
+        ...(hit.alsoFor && { alsoFor: hit.alsoFor })
+      }))
+    })),
+    totalResults: groups.reduce((sum, group) => sum + group.length, 0),
+    ...(duplicates > 0 && { duplicatesRemoved: duplicates })
+  });
+}
@@ -0,0 +1,265 @@
+import path from 'path';
+import type { ToolResponse } from './types.js';
+import { detectLanguage } from '../utils/language-detection.js';
+import { fenceLanguage } from '../utils/syntax-highlight.js';
+
+export const OUTPUT_FORMATS = ['json', 'markdown', 'plain'] as const;
+export type OutputFormat = (typeof OUTPUT_FORMATS)[number];
+
+export const STRUCTURED_OUTPUT_VERSION = 1;
+
+/** One located result, the same shape for every tool. Unknown fields are null, never absent. */
+export interface ResultRecord {
+  /** Response field the entry came from, e.g. `results` or `references.usages` */
+  group: string;
+  path: string;
+  start_line: number | null;
+  end_line: number | null;
+  symbol: string | null;
+  score: number | null;
+  language: string | null;
+  content: string | null;
+}
+
+export interface StructuredOutput {
+  schemaVersion: typeof STRUCTURED_OUTPUT_VERSION;
+  tool: string;
+  status: string;
+  results: ResultRecord[];
+  /** The rest of the tool's payload, minus the lists that became `results` */
+  data: Record<string, unknown>;
+}
+
+const nullable = (type: string) => ({ type: [type, 'null'] });
+
+/** JSON Schema for `format: "json"` responses */
+export const STRUCTURED_OUTPUT_SCHEMA = {
+  type: 'object',
+  properties: {
+    schemaVersion: { type: 'integer', const: STRUCTURED_OUTPUT_VERSION },
+    tool: { type: 'string' },
+    status: { type: 'string' },
+    results: {
+      type: 'array',
+      items: {
+        type: 'object',
+        properties: {
+          group: { type: 'string' },
+          path: { type: 'string' },
+          start_line: nullable('integer'),
+          end_line: nullable('integer'),
+          symbol: nullable('string'),
+          score: nullable('number'),
+          language: nullable('string'),
+          content: nullable('string')
+        },
+        required: [
+          'group',
+          'path',
+          'start_line',
+          'end_line',
+          'symbol',
+          'score',
+          'language',
+          'content'
+        ]
+      }
+    },
+    data: { type: 'object' }
+  },
+  required: ['schemaVersion', 'tool', 'status', 'results', 'data']
+} as const;
+
+const PATH_KEYS = ['file', 'filePath', 'path', 'relativePath'];
+const SYMBOL_KEYS = ['symbol', 'symbolName', 'qualifiedName', 'name'];
+const CONTENT_KEYS = ['snippet', 'content', 'code', 'preview', 'text', 'summary'];
+
+export function isOutputFormat(value: unknown): value is OutputFormat {
+  return typeof value === 'string' && (OUTPUT_FORMATS as readonly string[]).includes(value);
+}
+
+function isPlainObject(value: unknown): value is Record<string, unknown> {
+  return typeof value === 'object' && value !== null && !Array.isArray(value);
+}
+
+function firstString(entry: Record<string, unknown>, keys: string[]): string | undefined {
+  for (const key of keys) {
+    const value = entry[key];
+    if (typeof value === 'string' && value !== '') return value;
+  }
+  return undefined;
+}
+
+function lineNumber(value: unknown): number | null {
+  return typeof value === 'number' && Number.isInteger(value) && value > 0 ? value : null;
+}
+
+/** A list entry pointing at a file (`file: "src/a.ts:10-20"`, `path` + `line`, ...) */
+function toResultRecord(entry: Record<string, unknown>, group: string): ResultRecord | undefined {
+  const location = firstString(entry, PATH_KEYS);
+  if (!location) return undefined;
+
+  // "path:start-end" or "path:line"
+  const suffix = /^(.+?):(\d+)(?:-(\d+))?$/.exec(location);
+  const filePath = suffix ? suffix[1] : location;
+  const start =
+    lineNumber(entry.startLine) ??
+    lineNumber(entry.start_line) ??
+    lineNumber(entry.line) ??
+    (suffix ? Number(suffix[2]) : null);
+  const end =
+    lineNumber(entry.endLine) ??
+    lineNumber(entry.end_line) ??
+    (suffix?.[3] ? Number(suffix[3]) : start);
+
+  const language =
+    firstString(entry, ['language']) ??
+    (path.extname(filePath) ? detectLanguage(filePath) : undefined);
+
+  return {
+    group,
+    path: filePath,
+    start_line: start,
+    end_line: end,
+    symbol: firstString(entry, SYMBOL_KEYS) ?? null,
+    score: typeof entry.score === 'number' && Number.isFinite(entry.score) ? entry.score : null,
+    language: language && language !== 'plaintext' ? language : null,
+    content: firstString(entry, CONTENT_KEYS) ?? null
+  };
+}
+
+/** Pull located list entries out of a payload; returns what is left of it */
+function extractRecords(value: unknown, group: string, records: ResultRecord[]): unknown {
+  if (Array.isArray(value)) {
+    const kept: unknown[] = [];
+    for (const item of value) {
+      const record = isPlainObject(item) ? toResultRecord(item, group) : undefined;
+      if (record) records.push(record);
+      else kept.push(extractRecords(item, group, records));
+    }
+    return kept;
+  }
+  if (!isPlainObject(value)) return value;
+
+  const rest: Record<string, unknown> = {};
+  for (const [key, field] of Object.entries(value)) {
+    const remaining = extractRecords(field, group ? `${group}.${key}` : key, records);
+    // Lists that turned entirely into records are dropped rather than left empty
+    if (Array.isArray(field) && field.length > 0 && Array.isArray(remaining) && !remaining.length) {
+      continue;
+    }
+    rest[key] = remaining;
+  }
+  return rest;
+}
+
+/** Normalize a tool response into the stable `format: "json"` shape */
+export function toStructuredOutput(tool: string, response: ToolResponse): StructuredOutput {
+  const text = (response.content ?? []).map((block) => block.text).join('\n');
+  let payload: unknown;
+  try {
+    payload = JSON.parse(text);
+  } catch {
+    payload = { message: text };
+  }
+
+  const records: ResultRecord[] = [];
+  const rest = extractRecords(payload, '', records);
+  const { status, ...data } = isPlainObject(rest) ? rest : { value: rest };
+  return {
+    schemaVersion: STRUCTURED_OUTPUT_VERSION,
+    tool,
+    status: typeof status === 'string' ? status : response.isError ? 'error' : 'success',
+    results: records,
+    data
+  };
+}
+
+function location(record: ResultRecord): string {
+  if (record.start_line === null) return record.path;
+  if (record.end_line === null || record.end_line === record.start_line) {
+    return `${record.path}:${record.start_line}`;
+  }
+  return `${record.path}:${record.start_line}-${record.end_line}`;
+}
+
+function heading(record: ResultRecord): string[] {
+  return [
+    ...(record.symbol ? [record.symbol] : []),
+    ...(record.score !== null ? [`score ${record.score.toFixed(2)}`] : [])
+  ];
+}
+
+function scalarLines(
+  data: Record<string, unknown>,
+  render: (key: string, value: string) => string
+): { scalars: string[]; nested: Record<string, unknown> } {
+  const scalars: string[] = [];
+  const nested: Record<string, unknown> = {};
+  for (const [key, value] of Object.entries(data)) {
+    if (value === undefined) continue;
+    if (value === null || typeof value !== 'object') scalars.push(render(key, String(value)));
+    else nested[key] = value;
+  }
+  return { scalars, nested };
+}
+
+function renderMarkdown(output: StructuredOutput): string {
+  const lines = [`**${output.tool}**: ${output.status}`];
+  const { scalars, nested } = scalarLines(output.data, (key, value) => `- ${key}: ${value}`);
+  if (scalars.length) lines.push('', ...scalars);
+
+  output.results.forEach((record, i) => {
+    lines.push('', [`### ${i + 1}. \`${location(record)}\``, ...heading(record)].join(' · '));
+    if (record.content !== null) {
+      // Longer fences than any backtick run inside, so the content can't close the block
+      const runs = record.content.match(/`{3,}/g) ?? [];
+      const fence = '`'.repeat(Math.max(3, ...runs.map((run) => run.length + 1)));
+      const info = record.language ? fenceLanguage(record.language) : '';
+      lines.push(`${fence}${info}`, record.content, fence);
+    }
+  });
+
+  if (Object.keys(nested).length) {
+    lines.push('', '```json', JSON.stringify(nested, null, 2), '```');
+  }
+  return lines.join('\n');
+}
+
+function renderPlain(output: StructuredOutput): string {
+  const lines = [`${output.tool}: ${output.status}`];
+  const { scalars, nested } = scalarLines(output.data, (key, value) => `${key}: ${value}`);
+  lines.push(...scalars);
+  for (const [key, value] of Object.entries(nested)) lines.push(`${key}: ${JSON.stringify(value)}`);
+
+  for (const record of output.results) {
+    lines.push('', [location(record), ...heading(record)].join('  '));
+    if (record.content !== null) {
+      lines.push(...record.content.split('\n').map((line) => `    ${line}`));
+    }
+  }
+  return lines.join('\n');
+}
+
+/**
+ * Re-render a tool response in the requested format. `json` also sets `structuredContent`
+ * for clients that read it; the text block always carries the same output.
+ */
+export function formatToolResponse(
+  tool: string,
+  response: ToolResponse,
+  format: OutputFormat
+): ToolResponse {
+  const output = toStructuredOutput(tool, response);
+  const text =
+    format === 'json'
+      ? JSON.stringify(output, null, 2)
+      : format === 'markdown'
+        ? renderMarkdown(output)
+        : renderPlain(output);
+  return {
+    content: [{ type: 'text', text }],
+    ...(format === 'json' ? { structuredContent: output } : {}),
+    ...(response.isError ? { isError: true } : {})
+  };
+}
@@ -0,0 +1,158 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import path from 'path';
+import type { ToolContext, ToolResponse } from './types.js';
+import { CodebaseSearcher } from '../core/search.js';
+import { packContext, type PackCandidate } from '../core/context-packer.js';
+import { IndexCorruptedError } from '../errors/index.js';
+import { loadTokenizer } from '../embeddings/tokenizers.js';
+import { redactSecrets, resolveRedactionOptions } from '../utils/secret-redaction.js';
+import { loadComplianceFilter } from '../core/compliance-filters.js';
+import { readTextFile } from '../utils/text-encoding.js';
+import { jsonResponse } from './json-response.js';
+
+const DEFAULT_TOKEN_BUDGET = 8000;
+const MIN_TOKEN_BUDGET = 200;
+const MAX_TOKEN_BUDGET = 100_000;
+const DEFAULT_CANDIDATES = 30;
+/** Used when js-tiktoken is installed; without it tokens are estimated */
+const DEFAULT_TOKENIZER = 'o200k_base';
+
+export const definition: Tool = {
+  name: 'pack_context',
+  description:
+    'Retrieve code for a query and pack it into one payload that fits a token budget: ' +
+    'overlapping hits are merged, sections are ordered by relevance and carry file headers ' +
+    'and line numbers. Tokens are counted with `tokenizer` when it can be loaded, else ' +
+    'estimated at ~4 characters each.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      query: {
+        type: 'string',
+        description: 'Natural language search query'
+      },
+      tokenBudget: {
+        type: 'number',
+        description: `Maximum tokens for the packed code (default: ${DEFAULT_TOKEN_BUDGET})`,
+        default: DEFAULT_TOKEN_BUDGET
+      },
+      maxCandidates: {
+        type: 'number',
+        description: `Search hits to consider before packing (default: ${DEFAULT_CANDIDATES})`,
+        default: DEFAULT_CANDIDATES
+      },
+      tokenizer: {
+        type: 'string',
+        description:
+          'Tokenizer to count with: a tiktoken encoding (o200k_base, cl100k_base), a Hugging ' +
+          `Face tokenizer id, or "estimate" (default: ${DEFAULT_TOKENIZER})`
+      }
+    },
+    required: ['query']
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { query, tokenBudget, maxCandidates, tokenizer } = args as {
+    query?: unknown;
+    tokenBudget?: unknown;
+    maxCandidates?: unknown;
+    tokenizer?: unknown;
+  };
+  const queryStr = typeof query === 'string' ? query.trim() : '';
+  const budget =
+    typeof tokenBudget === 'number' && Number.isFinite(tokenBudget)
+      ? Math.min(Math.max(Math.floor(tokenBudget), MIN_TOKEN_BUDGET), MAX_TOKEN_BUDGET)
+      : DEFAULT_TOKEN_BUDGET;
+  const candidateLimit =
+    typeof maxCandidates === 'number' && Number.isFinite(maxCandidates) && maxCandidates > 0
+      ? Math.min(Math.floor(maxCandidates), 100)
+      : DEFAULT_CANDIDATES;
+
+  if (!queryStr) {
+    return jsonResponse(
+      {
+        status: 'error',
+        message: "Invalid params: 'query' is required and must be a non-empty string."
+      },
+      true
+    );
+  }
+
+  if (ctx.indexState.status === 'indexing') {
+    return jsonResponse({
+      status: 'indexing',
+      message: 'Index is still being built. Retry in a moment.',
+      progress: ctx.indexState.indexer?.getProgress()
+    });
+  }
+
+  let candidates: PackCandidate[];
+  try {
+    const results = await new CodebaseSearcher(ctx.rootPath).search(queryStr, candidateLimit);
+    candidates = results.map((result) => ({
+      file: path.relative(ctx.rootPath, result.filePath).replace(/\\/g, '/'),
+      startLine: result.startLine,
+      endLine: result.endLine,
+      score: result.score
+    }));
+  } catch (error) {
+    return jsonResponse({
+      status: 'error',
+      message:
+        error instanceof IndexCorruptedError
+          ? `Index unavailable: ${error.message}`
+          : `Search failed: ${error instanceof Error ? error.message : String(error)}`,
+      hint: 'Run refresh_index, then retry.'
+    });
+  }
+
+  const counter = await loadTokenizer(
+    (typeof tokenizer === 'string' && tokenizer.trim()) ||
+      process.env.CODEBASE_CONTEXT_PACK_TOKENIZER?.trim() ||
+      DEFAULT_TOKENIZER
+  );
+
+  // Sections are read from the working tree so line numbers match what's on disk
+  const redaction = resolveRedactionOptions();
+  const compliance = await loadComplianceFilter(ctx.rootPath);
+  const packed = await packContext(
+    candidates,
+    budget,
+    async (file) => {
+      try {
+        const content = await readTextFile(path.join(ctx.rootPath, file));
+        const { text: redacted } = redactSecrets(content.replace(/\r\n/g, '\n'), redaction);
+        const { text } = compliance.mask(redacted);
+        return text.replace(/\n$/, '').split('\n');
+      } catch {
+        return null;
+      }
+    },
+    (text) => counter.count(text)
+  );
+
+  const summary = {
+    status: packed.sections.length > 0 ? 'success' : 'no_results',
+    query: queryStr,
+    tokenBudget: budget,
+    tokensUsed: packed.tokens,
+    tokenizer: counter.exact ? counter.name : 'estimate (~4 characters per token)',
+    sections: packed.sections.map((s) => ({
+      file: `${s.file}:${s.startLine}-${s.endLine}`,
+      score: Math.round(s.score * 100) / 100,
+      ...(s.truncated ? { truncated: true } : {})
+    })),
+    omittedCandidates: packed.omitted
+  };
+
+  return {
+    content: [
+      { type: 'text', text: JSON.stringify(summary, null, 2) },
+      ...(packed.text ? [{ type: 'text' as const, text: packed.text }] : [])
+    ]
+  };
+}
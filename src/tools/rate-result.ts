@@ -0,0 +1,124 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import path from 'path';
+import type { ToolContext, ToolResponse } from './types.js';
+import {
+  feedbackBoost,
+  findAccessLogEntry,
+  loadFeedbackConfig,
+  recordRating,
+  type AccessedResult
+} from '../core/result-feedback.js';
+import { jsonResponse } from './json-response.js';
+
+export const definition: Tool = {
+  name: 'rate_result',
+  description:
+    'Thumbs up or down for one search_codebase result: did this chunk help with the query? ' +
+    'Ratings are kept for the team to review and nudge the ranking of rated chunks in later ' +
+    'searches. Pass the queryId of the search and the result as its `file` value or 1-based rank.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      queryId: {
+        type: 'string',
+        description: 'queryId from the search_codebase response'
+      },
+      result: {
+        type: ['string', 'number'],
+        description: 'The result: its `file` value (src/auth.ts:10-42) or its rank (1 = first)'
+      },
+      rating: {
+        type: 'string',
+        enum: ['up', 'down'],
+        description: 'up: it helped; down: it was not relevant'
+      },
+      note: {
+        type: 'string',
+        description: 'Optional: why (kept with the rating)'
+      }
+    },
+    required: ['queryId', 'result', 'rating']
+  }
+};
+
+/** The logged result `reference` names: a rank, `path:start-end`, or a path returned once */
+function findResult(
+  results: AccessedResult[],
+  reference: string | number,
+  rootPath: string
+): AccessedResult | undefined {
+  const rank = typeof reference === 'number' ? reference : Number(reference);
+  if (Number.isInteger(rank) && rank >= 1) return results[rank - 1];
+
+  const text = String(reference).trim();
+  const match = /^(.*?)(?::(\d+)(?:-(\d+))?)?$/.exec(text);
+  const root = path.resolve(rootPath);
+  const file = path
+    .relative(root, path.resolve(root, match?.[1] ?? text))
+    .replace(/\\/g, '/');
+  const startLine = match?.[2] ? Number(match[2]) : undefined;
+  const endLine = match?.[3] ? Number(match[3]) : undefined;
+  const candidates = results.filter(
+    (result) =>
+      result.file === file &&
+      (startLine === undefined || result.startLine === startLine) &&
+      (endLine === undefined || result.endLine === endLine)
+  );
+  return candidates.length === 1 ? candidates[0] : undefined;
+}
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const queryId = typeof args.queryId === 'string' ? args.queryId.trim() : '';
+  const reference =
+    typeof args.result === 'number' || typeof args.result === 'string' ? args.result : '';
+  const rating = args.rating === 'up' || args.rating === 'down' ? args.rating : undefined;
+  if (!queryId || reference === '' || !rating) {
+    return jsonResponse(
+      {
+        status: 'error',
+        message:
+          "Invalid params: 'queryId', 'result' (file or rank) and 'rating' ('up' or 'down') " +
+          'are required.'
+      },
+      true
+    );
+  }
+
+  const search = await findAccessLogEntry(ctx.paths.baseDir, queryId);
+  if (!search) {
+    return jsonResponse({
+      status: 'not_found',
+      queryId,
+      message:
+        'No logged search with this queryId (old searches are dropped, and nothing is logged ' +
+        'when search.feedback.log is false). Rate results of a recent search.'
+    });
+  }
+  const result = findResult(search.results, reference, ctx.rootPath);
+  if (!result) {
+    return jsonResponse({
+      status: 'not_found',
+      queryId,
+      message: 'That search returned no such result. Pass its file value or rank.',
+      results: search.results.map((entry) => `${entry.file}:${entry.startLine}-${entry.endLine}`)
+    });
+  }
+
+  const note = typeof args.note === 'string' ? args.note : undefined;
+  const totals = await recordRating(ctx.paths.baseDir, search, result, rating, note);
+  const { weight } = await loadFeedbackConfig(ctx.rootPath);
+
+  return jsonResponse({
+    status: 'success',
+    queryId,
+    file: `${result.file}:${result.startLine}-${result.endLine}`,
+    rating,
+    totals,
+    ...(weight > 0
+      ? { rankingBoost: Math.round(feedbackBoost(totals, weight) * 1000) / 1000 }
+      : { message: 'Recorded. Ranking boosts are off (search.feedback.weight is 0).' })
+  });
+}
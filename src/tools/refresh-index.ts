@@ -1,11 +1,24 @@
 import type { Tool } from '@modelcontextprotocol/sdk/types.js';
 import type { ToolContext, ToolResponse } from './types.js';
+import { CodebaseIndexer } from '../core/indexer.js';
+import {
+  buildEmbeddingCollection,
+  loadEmbeddingCollectionSpecs,
+  type EmbeddingCollectionMeta
+} from '../core/embedding-collections.js';
+import { resolveGitCommit } from '../utils/git-tree.js';
+import { IndexingCancelledError } from '../errors/index.js';
+import type { IndexingStats } from '../types/index.js';
+import { jsonResponse } from './json-response.js';
 
 export const definition: Tool = {
   name: 'refresh_index',
   description:
     'Re-index the codebase. Supports full re-index or incremental mode. ' +
-    'Use incrementalOnly=true to only process files changed since last index.',
+    'Use incrementalOnly=true to only process files changed since last index. ' +
+    'Pass ref to index a branch, tag or commit from git without checking it out. ' +
+    'Pass collection to embed the current index with another model, without re-indexing. ' +
+    'With a progressToken, waits for completion, streams progress and can be cancelled.',
   inputSchema: {
     type: 'object',
     properties: {
@@ -17,20 +30,57 @@ export const definition: Tool = {
         type: 'boolean',
         description:
           'If true, only re-index files changed since last full index (faster). Default: false (full re-index)'
+      },
+      ref: {
+        type: 'string',
+        description:
+          'Optional git branch, tag or commit SHA. Builds a separate ref-scoped index next to the ' +
+          'working-tree index; query it with search_codebase({ ref }).'
+      },
+      collection: {
+        type: 'string',
+        description:
+          'Optional embedding collection declared under embeddingCollections in ' +
+          '.codebase-context/config.json. Embeds the indexed chunks it covers with its model; ' +
+          'query it with search_codebase({ collection }).'
       }
     }
   }
 };
 
+/** In-flight ref builds keyed by ref, so repeated requests don't race on the same directory */
+const refBuilds = new Map<string, Promise<void>>();
+/** In-flight collection builds keyed by project root and collection name */
+const collectionBuilds = new Map<string, Promise<EmbeddingCollectionMeta>>();
+
 export async function handle(
   args: Record<string, unknown>,
   ctx: ToolContext
 ): Promise<ToolResponse> {
-  const { reason, incrementalOnly } = args as { reason?: string; incrementalOnly?: boolean };
+  const { reason, incrementalOnly, ref, collection } = args as {
+    reason?: string;
+    incrementalOnly?: boolean;
+    ref?: unknown;
+    collection?: unknown;
+  };
 
   const mode = incrementalOnly ? 'incremental' : 'full';
+
+  if (typeof ref === 'string' && ref.trim()) {
+    return startRefIndexing(ref.trim(), incrementalOnly === true, reason, ctx);
+  }
+
+  if (typeof collection === 'string' && collection.trim()) {
+    return startCollectionBuild(collection.trim(), reason, ctx);
+  }
+
   console.error(`Refresh requested (${mode}): ${reason || 'Manual trigger'}`);
 
+  if (ctx.progress) {
+    await ctx.performIndexing(incrementalOnly, reason, ctx.progress);
+    return foregroundResult(mode, ctx);
+  }
+
   ctx.performIndexing(incrementalOnly);
 
   return {
@@ -53,3 +103,295 @@ export async function handle(
     ]
   };
 }
+
+function cancelledResult(mode: string, ref?: string): ToolResponse {
+  return {
+    content: [
+      {
+        type: 'text',
+        text: JSON.stringify(
+          {
+            status: 'cancelled',
+            mode,
+            ...(ref ? { ref } : {}),
+            message:
+              'Indexing cancelled. The previous index was left unchanged; vectors embedded ' +
+              'so far are kept, so the next refresh resumes from there.'
+          },
+          null,
+          2
+        )
+      }
+    ]
+  };
+}
+
+function foregroundResult(mode: string, ctx: ToolContext): ToolResponse {
+  if (ctx.progress?.signal?.aborted) {
+    return cancelledResult(mode);
+  }
+  const { indexState } = ctx;
+  if (indexState.status !== 'ready') {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            { status: 'error', mode, message: indexState.error ?? 'Indexing did not complete.' },
+            null,
+            2
+          )
+        }
+      ]
+    };
+  }
+  return {
+    content: [
+      {
+        type: 'text',
+        text: JSON.stringify(
+          {
+            status: 'complete',
+            mode,
+            indexedFiles: indexState.stats?.indexedFiles,
+            totalChunks: indexState.stats?.totalChunks,
+            durationMs: indexState.stats?.duration
+          },
+          null,
+          2
+        )
+      }
+    ]
+  };
+}
+
+async function startRefIndexing(
+  ref: string,
+  incrementalOnly: boolean,
+  reason: string | undefined,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const mode = incrementalOnly ? 'incremental' : 'full';
+
+  let commit: string;
+  try {
+    commit = await resolveGitCommit(ctx.rootPath, ref);
+  } catch (error) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              ref,
+              message: error instanceof Error ? error.message : String(error)
+            },
+            null,
+            2
+          )
+        }
+      ]
+    };
+  }
+
+  if (refBuilds.has(ref)) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'indexing',
+              ref,
+              message: `Ref '${ref}' is already being indexed. Retry the search shortly.`
+            },
+            null,
+            2
+          )
+        }
+      ]
+    };
+  }
+
+  console.error(`Refresh requested for ref ${ref} (${mode}): ${reason || 'Manual trigger'}`);
+
+  const indexer = new CodebaseIndexer({
+    rootPath: ctx.rootPath,
+    ref,
+    incrementalOnly,
+    signal: ctx.progress?.signal,
+    onProgress: ctx.progress?.onProgress
+  });
+  const outcome: Promise<{ stats?: IndexingStats; error?: unknown }> = indexer.index().then(
+    (stats) => ({ stats }),
+    (error: unknown) => ({ error })
+  );
+  const build = outcome
+    .then(({ stats, error }) => {
+      if (stats) {
+        console.error(
+          `Ref ${ref} indexed: ${stats.indexedFiles} files, ${stats.totalChunks} chunks in ${(
+            stats.duration / 1000
+          ).toFixed(2)}s`
+        );
+      } else if (error instanceof IndexingCancelledError) {
+        console.error(`Ref ${ref} indexing cancelled`);
+      } else {
+        console.error(
+          `Ref ${ref} indexing failed:`,
+          error instanceof Error ? error.message : String(error)
+        );
+      }
+    })
+    .finally(() => {
+      refBuilds.delete(ref);
+    });
+  refBuilds.set(ref, build);
+
+  if (ctx.progress) {
+    const { stats: refStats, error: refError } = await outcome;
+    await build;
+    if (refError instanceof IndexingCancelledError) {
+      return cancelledResult(mode, ref);
+    }
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            refStats
+              ? {
+                  status: 'complete',
+                  mode,
+                  ref,
+                  commit,
+                  indexedFiles: refStats.indexedFiles,
+                  totalChunks: refStats.totalChunks,
+                  durationMs: refStats.duration
+                }
+              : {
+                  status: 'error',
+                  ref,
+                  message: refError instanceof Error ? refError.message : String(refError)
+                },
+            null,
+            2
+          )
+        }
+      ]
+    };
+  }
+
+  return {
+    content: [
+      {
+        type: 'text',
+        text: JSON.stringify(
+          {
+            status: 'started',
+            mode,
+            ref,
+            commit,
+            message: `Indexing ${ref} (${commit.slice(0, 12)}) from git. Search it with search_codebase({ ref: "${ref}" }) once done.`,
+            reason
+          },
+          null,
+          2
+        )
+      }
+    ]
+  };
+}
+
+async function startCollectionBuild(
+  name: string,
+  reason: string | undefined,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const spec = (await loadEmbeddingCollectionSpecs(ctx.rootPath))[name];
+  if (!spec) {
+    return jsonResponse(
+      {
+        status: 'error',
+        errorCode: 'collection_not_declared',
+        collection: name,
+        message: `No embedding collection '${name}' is declared.`,
+        hint:
+          'Add it under embeddingCollections in .codebase-context/config.json, e.g. ' +
+          `{ "${name}": { "provider": "voyage", "model": "voyage-code-3", "include": ["src"] } }`
+      },
+      true
+    );
+  }
+  if (ctx.indexState.status === 'indexing') {
+    return jsonResponse({
+      status: 'indexing',
+      collection: name,
+      message: 'The index is being rebuilt; collections are refreshed once it finishes.'
+    });
+  }
+
+  const key = `${ctx.rootPath}\0${name}`;
+  if (collectionBuilds.has(key)) {
+    return jsonResponse({
+      status: 'indexing',
+      collection: name,
+      message: `Collection '${name}' is already being built.`
+    });
+  }
+
+  console.error(`Collection build requested for ${name}: ${reason || 'Manual trigger'}`);
+  const build = buildEmbeddingCollection({
+    rootPath: ctx.rootPath,
+    name,
+    spec,
+    signal: ctx.progress?.signal
+  }).finally(() => collectionBuilds.delete(key));
+  collectionBuilds.set(key, build);
+
+  if (!ctx.progress) {
+    build.then(
+      (meta) => console.error(`Collection ${name} built: ${meta.chunks} chunks`),
+      (error: unknown) =>
+        console.error(
+          `Collection ${name} build failed:`,
+          error instanceof Error ? error.message : String(error)
+        )
+    );
+    return jsonResponse({
+      status: 'started',
+      collection: name,
+      message: `Embedding the indexed chunks for '${name}'. Search it with search_codebase({ collection: "${name}" }) once done.`,
+      reason
+    });
+  }
+
+  try {
+    const meta = await build;
+    return jsonResponse({
+      status: 'complete',
+      collection: name,
+      provider: meta.embedding.provider,
+      model: meta.embedding.model,
+      files: meta.files,
+      chunks: meta.chunks
+    });
+  } catch (error) {
+    if (error instanceof IndexingCancelledError) {
+      return jsonResponse({
+        status: 'cancelled',
+        collection: name,
+        message: 'Build cancelled. The collection was left unchanged.'
+      });
+    }
+    return jsonResponse(
+      {
+        status: 'error',
+        collection: name,
+        message: error instanceof Error ? error.message : String(error)
+      },
+      true
+    );
+  }
+}
@@ -0,0 +1,131 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { resolveStackTrace } from '../core/stack-trace.js';
+import { jsonResponse } from './json-response.js';
+
+const DEFAULT_MAX_FRAMES = 5;
+const DEFAULT_MAX_LINES = 30;
+/** Unresolved frames listed after the resolved ones */
+const MAX_OTHER_FRAMES = 10;
+
+export const definition: Tool = {
+  name: 'resolve_stacktrace',
+  description:
+    'Map a raw stack trace (Go panic, Java/JVM, Python, JavaScript/Node) onto the indexed ' +
+    'files and return the function around each failing line, innermost frame first. Paths ' +
+    'from CI machines or containers are matched by their trailing segments; runtime and ' +
+    'dependency frames are skipped. Source maps are not applied.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      trace: {
+        type: 'string',
+        description: 'The stack trace as printed, including the error line'
+      },
+      maxFrames: {
+        type: 'number',
+        description: `Project frames to return source for (default: ${DEFAULT_MAX_FRAMES})`,
+        default: DEFAULT_MAX_FRAMES
+      },
+      maxLines: {
+        type: 'number',
+        description: `Source lines per frame, around its line (default: ${DEFAULT_MAX_LINES})`,
+        default: DEFAULT_MAX_LINES
+      }
+    },
+    required: ['trace']
+  }
+};
+
+function positiveInteger(value: unknown): number | undefined {
+  return typeof value === 'number' && Number.isFinite(value) && value >= 1
+    ? Math.floor(value)
+    : undefined;
+}
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const trace = typeof args.trace === 'string' ? args.trace : '';
+  if (!trace.trim()) {
+    return jsonResponse(
+      {
+        status: 'error',
+        message: "Invalid params: 'trace' is required and must be a non-empty string."
+      },
+      true
+    );
+  }
+  const maxFrames = Math.min(positiveInteger(args.maxFrames) ?? DEFAULT_MAX_FRAMES, 20);
+  const maxLines = Math.min(positiveInteger(args.maxLines) ?? DEFAULT_MAX_LINES, 200);
+
+  const resolved = await resolveStackTrace(ctx.rootPath, trace, { maxFrames, maxLines });
+  if (!resolved) {
+    return jsonResponse({
+      status: 'error',
+      message:
+        'No frames recognised, or the index is not available. Pass the trace as printed; ' +
+        'run refresh_index if the project has not been indexed.'
+    });
+  }
+
+  const project = resolved.frames.filter((frame) => frame.resolution.status === 'resolved');
+  const others = resolved.frames.filter((frame) => frame.resolution.status !== 'resolved');
+  const unresolved = others.filter((frame) => frame.resolution.status !== 'library');
+  const counts: Record<string, number> = {};
+  for (const frame of others) {
+    counts[frame.resolution.status] = (counts[frame.resolution.status] ?? 0) + 1;
+  }
+
+  return jsonResponse({
+    status: project.length > 0 ? 'success' : 'not_found',
+    format: resolved.format,
+    totalFrames: resolved.frames.length,
+    frames: project.map((frame) => {
+      const file = frame.resolution.status === 'resolved' ? frame.resolution.file : frame.file;
+      return {
+        index: frame.index,
+        ...(frame.function ? { function: frame.function } : {}),
+        location: `${file}:${frame.line}`,
+        ...(file !== frame.file ? { tracePath: frame.file } : {}),
+        ...(frame.repeated ? { repeated: frame.repeated } : {}),
+        ...(frame.lineOutOfRange
+          ? { lineOutOfRange: true }
+          : frame.scope
+            ? {
+                scope: frame.scope.name
+                  ? `${frame.scope.kind} ${frame.scope.name}`
+                  : 'file (no enclosing symbol)',
+                lines: `${frame.scope.startLine}-${frame.scope.endLine}`,
+                code: frame.scope.code,
+                ...(frame.scope.truncated ? { truncated: true } : {})
+              }
+            : {})
+      };
+    }),
+    ...(others.length > 0 ? { otherFrames: counts } : {}),
+    ...(unresolved.length > 0
+      ? {
+          unresolved: unresolved.slice(0, MAX_OTHER_FRAMES).map((frame) => ({
+            index: frame.index,
+            ...(frame.function ? { function: frame.function } : {}),
+            tracePath: `${frame.file}:${frame.line}`,
+            reason: frame.resolution.status,
+            ...(frame.resolution.status === 'ambiguous'
+              ? { candidates: frame.resolution.candidates }
+              : {})
+          }))
+        }
+      : {}),
+    ...(project.length === 0
+      ? {
+          hint:
+            'No frame maps to an indexed file. Check that the trace comes from this project ' +
+            'and that the index is current (refresh_index).'
+        }
+      : project.some((frame) => frame.lineOutOfRange)
+        ? { hint: 'Some frame lines are past the end of the file: the deployed code differs.' }
+        : {})
+  });
+}
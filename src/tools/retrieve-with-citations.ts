@@ -0,0 +1,158 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import path from 'path';
+import type { ToolContext, ToolResponse } from './types.js';
+import { CodebaseSearcher } from '../core/search.js';
+import { citeRanges, type CitedRange } from '../core/citations.js';
+import { readIndexMeta } from '../core/index-meta.js';
+import { partialMarker } from '../core/cancellation.js';
+import { highlightFields } from '../utils/syntax-highlight.js';
+import { IndexCorruptedError } from '../errors/index.js';
+import { jsonResponse } from './json-response.js';
+
+const DEFAULT_LIMIT = 5;
+const MAX_LIMIT = 10;
+/** Lines quoted per chunk; the citation covers exactly the quoted lines */
+const MAX_CITED_LINES = 15;
+
+export const definition: Tool = {
+  name: 'retrieve_with_citations',
+  description:
+    'Search the codebase and return each matching chunk with a stable citation id and a ' +
+    'source map (path, lines, commit, link). Cite chunks as [id] in an answer so the claims ' +
+    'can be checked against the exact lines.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      query: {
+        type: 'string',
+        description: 'Natural language search query'
+      },
+      limit: {
+        type: 'number',
+        description: `Chunks to return (default: ${DEFAULT_LIMIT}, max: ${MAX_LIMIT})`,
+        default: DEFAULT_LIMIT
+      },
+      mode: {
+        type: 'string',
+        enum: ['hybrid', 'keyword', 'semantic'],
+        description: 'Retrieval mode, as in search_codebase (default: hybrid)',
+        default: 'hybrid'
+      },
+      highlight: {
+        type: 'boolean',
+        description:
+          'Add token classes per chunk for syntax highlighting, as in search_codebase ' +
+          '(default: false)',
+        default: false
+      }
+    },
+    required: ['query']
+  }
+};
+
+/** The first lines of a result's snippet, without the "... [n more lines]" marker */
+function quotedLines(snippet: string): string[] {
+  const lines = snippet.replace(/\n\n\.\.\. \[\d+ more lines\]$/, '').split('\n');
+  if (lines.length > 1 && lines[lines.length - 1] === '') lines.pop();
+  return lines.slice(0, MAX_CITED_LINES);
+}
+
+async function indexedCommit(rootPath: string): Promise<string | null> {
+  try {
+    const meta = await readIndexMeta(rootPath);
+    return meta.gitRef?.commit ?? meta.head?.commit ?? null;
+  } catch {
+    return null;
+  }
+}
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { query, limit, mode, highlight } = args as {
+    query?: unknown;
+    limit?: unknown;
+    mode?: unknown;
+    highlight?: unknown;
+  };
+  const normalizedQuery = typeof query === 'string' ? query.trim() : '';
+  if (!normalizedQuery) {
+    return jsonResponse(
+      {
+        status: 'error',
+        message: "Invalid params: 'query' is required and must be a non-empty string."
+      },
+      true
+    );
+  }
+  const normalizedLimit =
+    typeof limit === 'number' && Number.isFinite(limit) && limit > 0
+      ? Math.min(Math.floor(limit), MAX_LIMIT)
+      : DEFAULT_LIMIT;
+
+  if (ctx.indexState.status === 'indexing') {
+    return jsonResponse({
+      status: 'indexing',
+      message: 'Index is still being built. Retry in a moment.',
+      progress: ctx.indexState.indexer?.getProgress()
+    });
+  }
+
+  const retrievalMode = mode === 'keyword' || mode === 'semantic' ? mode : 'hybrid';
+  const root = path.resolve(ctx.rootPath);
+  const ranges: Array<CitedRange & { summary: string; score: number; language: string }> = [];
+  try {
+    const results = await new CodebaseSearcher(ctx.rootPath).search(
+      normalizedQuery,
+      normalizedLimit,
+      undefined,
+      {
+        useSemanticSearch: retrievalMode !== 'keyword',
+        useKeywordSearch: retrievalMode !== 'semantic',
+        ...(ctx.signal ? { signal: ctx.signal } : {})
+      }
+    );
+    for (const result of results) {
+      const lines = quotedLines(result.snippet ?? '');
+      ranges.push({
+        file: path.relative(root, path.resolve(root, result.filePath)).replace(/\\/g, '/'),
+        startLine: result.startLine,
+        endLine: Math.min(result.endLine, result.startLine + lines.length - 1),
+        content: lines.join('\n'),
+        summary: result.summary,
+        score: result.score,
+        language: result.language
+      });
+    }
+  } catch (error) {
+    return jsonResponse({
+      status: 'error',
+      message:
+        error instanceof IndexCorruptedError
+          ? `Index unavailable: ${error.message}`
+          : `Search failed: ${error instanceof Error ? error.message : String(error)}`,
+      hint: 'Run refresh_index, then retry.'
+    });
+  }
+
+  const commit = await indexedCommit(ctx.rootPath);
+  const sources = await citeRanges(ctx.rootPath, ranges, commit);
+  const chunks = ranges.map((range, i) => ({
+    id: sources[i].id,
+    file: `${range.file}:${range.startLine}-${range.endLine}`,
+    ...(range.summary && { summary: range.summary }),
+    score: Math.round(range.score * 100) / 100,
+    ...highlightFields(range.content, range.language, highlight === true),
+    content: range.content
+  }));
+  return jsonResponse({
+    status: chunks.length > 0 ? 'success' : 'not_found',
+    ...partialMarker(ctx.signal),
+    query: normalizedQuery,
+    commit,
+    citationFormat: 'Cite a chunk as [id]; sourceMap[id] locates the quoted lines.',
+    chunks,
+    sourceMap: Object.fromEntries(sources.map(({ id, ...source }) => [id, source]))
+  });
+}
@@ -0,0 +1,76 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { readPreviousIndexGeneration, rollbackToPreviousIndex } from '../core/indexer.js';
+import { DEFAULT_STORAGE_CONFIG, isRemoteStorageProvider } from '../storage/index.js';
+import { jsonResponse } from './json-response.js';
+
+export const definition: Tool = {
+  name: 'rollback_index',
+  description:
+    'Restore the index generation that the last successful re-index replaced. The replaced ' +
+    'generation is kept, so calling this again undoes the rollback. Use dryRun to see what ' +
+    'would be restored.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      dryRun: {
+        type: 'boolean',
+        description: 'Report the generation that would be restored without swapping it in',
+        default: false
+      }
+    }
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const dryRun = args.dryRun === true;
+
+  if (ctx.indexState.status === 'indexing') {
+    return jsonResponse(
+      {
+        status: 'error',
+        message: 'Indexing is in progress. Roll back once it has finished or been cancelled.'
+      },
+      true
+    );
+  }
+  // Only the local artifacts are versioned; a remote collection holds the newest vectors only
+  if (isRemoteStorageProvider(DEFAULT_STORAGE_CONFIG.provider)) {
+    return jsonResponse(
+      {
+        status: 'error',
+        message:
+          `Rollback is not available with the ${DEFAULT_STORAGE_CONFIG.provider} storage ` +
+          'provider: its collection is updated in place. Run refresh_index instead.'
+      },
+      true
+    );
+  }
+
+  const previous = dryRun
+    ? await readPreviousIndexGeneration(ctx.paths.baseDir)
+    : await rollbackToPreviousIndex(ctx.paths.baseDir, { signal: ctx.signal });
+  if (!previous) {
+    return jsonResponse({
+      status: 'unavailable',
+      message:
+        'No previous index generation to roll back to. One is kept after each successful ' +
+        're-index.'
+    });
+  }
+
+  if (dryRun) {
+    return jsonResponse({ status: 'success', dryRun, wouldRestore: previous });
+  }
+  ctx.indexState.lastIndexed = new Date(previous.generatedAt);
+  return jsonResponse({
+    status: 'success',
+    restored: previous,
+    message:
+      'Rolled back. Call rollback_index again to undo; files changed since this build are ' +
+      'picked up by the next refresh_index.'
+  });
+}
@@ -1,10 +1,34 @@
 import type { Tool } from '@modelcontextprotocol/sdk/types.js';
 import { promises as fs } from 'fs';
 import path from 'path';
-import type { ToolContext, ToolResponse, DecisionCard } from './types.js';
+import type {
+  ToolContext,
+  ToolResponse,
+  DecisionCard,
+  SearchDebug,
+  SearchResultDebug
+} from './types.js';
 import { CodebaseSearcher } from '../core/search.js';
-import type { SearchIntentProfile } from '../core/search.js';
+import type { CodebaseIndexer } from '../core/indexer.js';
+import type {
+  SearchCollectionInfo,
+  SearchIntentProfile,
+  SearchOptions,
+  SearchTrace
+} from '../core/search.js';
+import { RERANK_MODES, type RerankMode } from '../core/reranker.js';
+import { searchDependencies } from '../core/dependency-sources.js';
+import {
+  QUERY_REWRITE_MODES,
+  buildQueryRewrites,
+  type QueryRewriteMode
+} from '../core/query-rewrite.js';
+import type { DiversityOptions } from '../core/diversity.js';
+import type { RecencyBoostOptions } from '../core/recency-boost.js';
+import { docSummary } from '../utils/doc-comments.js';
+import { highlightFields } from '../utils/syntax-highlight.js';
 import type {
+  CodeChunk,
   SearchResult,
   IntelligenceData,
   PatternsData,
@@ -19,10 +43,27 @@ import {
   shouldSkipLegacyTestingFrameworkCategory
 } from '../patterns/semantics.js';
 import { assessSearchQuality } from '../core/search-quality.js';
-import { IndexCorruptedError } from '../errors/index.js';
+import { partialMarker } from '../core/cancellation.js';
+import { overallProgress } from '../core/indexing-progress.js';
+import {
+  describeModelMigration,
+  readModelMigration,
+  startModelMigration,
+  type ModelMigration
+} from '../core/model-migration.js';
+import { resolveEmbeddingModel } from '../embeddings/index.js';
+import { EmbeddingCollectionError, IndexCorruptedError } from '../errors/index.js';
 import { readMemoriesFile, withConfidence } from '../memory/store.js';
 import { InternalFileGraph } from '../utils/usage-tracker.js';
 import { RELATIONSHIPS_FILENAME } from '../constants/codebase-context.js';
+import { feedbackKey, loadFeedbackConfig, logSearchAccess } from '../core/result-feedback.js';
+import { blameRange, type BlameSummary } from '../core/blame-context.js';
+import {
+  buildOverlayIndex,
+  summarizeOverlay,
+  type OverlayIndex,
+  type OverlaySource
+} from '../core/overlay-index.js';
 
 interface RelationshipsData {
   graph?: {
@@ -61,6 +102,109 @@ export const definition: Tool = {
           'Include code snippets in results (default: false). If you need code, prefer read_file instead.',
         default: false
       },
+      highlight: {
+        type: 'boolean',
+        description:
+          'With includeSnippets, add token classes per snippet for syntax highlighting: ' +
+          '[line, column, length, kind] with 0-based line and column (default: false)',
+        default: false
+      },
+      mode: {
+        type: 'string',
+        enum: ['hybrid', 'keyword', 'semantic'],
+        description:
+          'Retrieval mode (default: hybrid = BM25 keyword + vector similarity fused with RRF). ' +
+          'Use "keyword" for exact identifiers or error strings, "semantic" for embeddings only.',
+        default: 'hybrid'
+      },
+      rerank: {
+        type: 'string',
+        enum: ['auto', 'always', 'off'],
+        description:
+          'Cross-encoder reranking of the top 50 candidates (default: auto = only when top ' +
+          'scores are close). "always" trades latency for precision.',
+        default: 'auto'
+      },
+      rewrite: {
+        type: 'string',
+        enum: ['off', 'synonyms', 'sampling'],
+        description:
+          'Also search 2-3 rewrites of the query in code vocabulary ("throttle" -> ' +
+          '"rateLimiter") and merge the results: synonyms uses a built-in dictionary, ' +
+          "sampling asks the client's model (default: off)",
+        default: 'off'
+      },
+      diversity: {
+        type: 'object',
+        description:
+          'Spread results across files: maxPerFile caps chunks per file (default: 1), lambda ' +
+          'below 1 picks by maximal marginal relevance, trading relevance for novelty (0-1, ' +
+          'default: 1 = off). Overrides search.diversity in .codebase-context/config.json.',
+        properties: {
+          lambda: { type: 'number', minimum: 0, maximum: 1 },
+          maxPerFile: { type: 'number', minimum: 1, maximum: 10 }
+        }
+      },
+      recency: {
+        type: 'object',
+        description:
+          'Rank recently changed and often edited files higher: recencyWeight and churnWeight ' +
+          'are the largest boosts (0-1, default: 0 = off), halfLifeDays is the age at which ' +
+          'the recency boost halves (default: 30). Overrides search.recency in ' +
+          '.codebase-context/config.json.',
+        properties: {
+          recencyWeight: { type: 'number', minimum: 0, maximum: 1 },
+          halfLifeDays: { type: 'number', minimum: 1 },
+          churnWeight: { type: 'number', minimum: 0, maximum: 1 }
+        }
+      },
+      debug: {
+        type: 'boolean',
+        description:
+          'Explain the ranking: per-result vector, keyword, fusion and rerank scores, score ' +
+          'adjustments and chunk boundaries, plus the intent, weights and filters used ' +
+          '(default: false)',
+        default: false
+      },
+      ownership: {
+        type: 'boolean',
+        description:
+          'Add who owns and last changed each result: its CODEOWNERS owners and a git blame ' +
+          'summary of its lines (last author, date, commit subject). Default: false',
+        default: false
+      },
+      ref: {
+        type: 'string',
+        description:
+          'Optional git branch, tag or commit to search instead of the working tree. ' +
+          'The ref must be indexed first with refresh_index({ ref }).'
+      },
+      collection: {
+        type: 'string',
+        description:
+          'Optional embedding collection (embeddingCollections in .codebase-context/config.json) ' +
+          'whose model and vectors the semantic half of the search uses; keyword matching still ' +
+          'covers the whole index. Build it first with refresh_index({ collection }).'
+      },
+      includeDependencies: {
+        type: ['boolean', 'array'],
+        items: { type: 'string' },
+        description:
+          'Also search third-party dependencies indexed with index_dependency: true for all, ' +
+          'or names such as ["gin"]. Left out by default; their results carry `dependency`.'
+      },
+      overlay: {
+        type: 'object',
+        description:
+          'Pending changes to search as if applied, for this call only: { "diff": "<unified ' +
+          'diff against the working tree>" } or { "directory": "<scratch dir or worktree laid ' +
+          'out like the repo>" }. Changed files replace their indexed versions; results from ' +
+          'them carry `overlay: true`. Nothing is written to the index.',
+        properties: {
+          diff: { type: 'string' },
+          directory: { type: 'string' }
+        }
+      },
       filters: {
         type: 'object',
         description: 'Optional filters',
@@ -82,10 +226,68 @@ export const definition: Tool = {
             description:
               'Filter by architectural layer (presentation, business, data, state, core, shared)'
           },
+          dotnetProject: {
+            type: 'string',
+            description: 'Only chunks from this .NET project (.csproj name, as listed in the .sln)'
+          },
+          package: {
+            type: 'string',
+            description: 'Only chunks from this monorepo package (name as listed by list_packages)'
+          },
+          role: {
+            type: 'string',
+            description:
+              'Only chunks with this role: http_handler, ui_component, migration, cron_job, test'
+          },
+          path: {
+            type: 'string',
+            description:
+              'Glob over repo-relative paths, e.g. "internal/billing/**"; a plain path selects ' +
+              'that directory'
+          },
+          excludeTests: {
+            type: 'boolean',
+            description: 'Leave out test files'
+          },
+          docsOnly: {
+            type: 'boolean',
+            description: 'Only documentation (markdown design docs, ADRs, READMEs)'
+          },
+          codeOnly: {
+            type: 'boolean',
+            description: 'Leave out documentation files'
+          },
+          modifiedAfter: {
+            type: 'string',
+            description: 'Only files last changed on or after this date (ISO 8601, e.g. 2024-01-01)'
+          },
+          modifiedBefore: {
+            type: 'string',
+            description: 'Only files last changed before this date (ISO 8601)'
+          },
+          minFileSize: {
+            type: 'number',
+            description: 'Minimum source file size in bytes'
+          },
+          maxFileSize: {
+            type: 'number',
+            description: 'Maximum source file size in bytes'
+          },
           tags: {
             type: 'array',
             items: { type: 'string' },
             description: 'Filter by tags'
+          },
+          metadata: {
+            type: 'object',
+            description:
+              'Match any chunk metadata field by dotted path, e.g. { "owners": "@acme/billing", ' +
+              '"annotations.deprecated": true }. Arrays match when they contain the value; ' +
+              'true/false test whether the field is set.',
+            additionalProperties: {
+              type: ['string', 'number', 'boolean', 'array'],
+              items: { type: 'string' }
+            }
           }
         }
       }
@@ -98,13 +300,53 @@ export async function handle(
   args: Record<string, unknown>,
   ctx: ToolContext
 ): Promise<ToolResponse> {
-  const { query, limit, filters, intent, includeSnippets } = args as {
+  const {
+    query,
+    limit,
+    filters,
+    intent,
+    includeSnippets,
+    highlight,
+    mode,
+    ref,
+    collection,
+    rerank,
+    rewrite,
+    diversity,
+    recency,
+    debug,
+    includeDependencies,
+    ownership,
+    overlay: overlayArg
+  } = args as {
     query?: unknown;
     limit?: number;
     filters?: Record<string, unknown>;
     intent?: string;
     includeSnippets?: boolean;
+    highlight?: unknown;
+    mode?: string;
+    ref?: unknown;
+    collection?: unknown;
+    rerank?: unknown;
+    rewrite?: unknown;
+    diversity?: unknown;
+    recency?: unknown;
+    debug?: unknown;
+    includeDependencies?: unknown;
+    ownership?: unknown;
+    overlay?: unknown;
   };
+  const debugRanking = debug === true;
+  const dependencySelector: true | string[] | undefined =
+    includeDependencies === true
+      ? true
+      : Array.isArray(includeDependencies) && includeDependencies.length > 0
+        ? includeDependencies.filter((name): name is string => typeof name === 'string')
+        : undefined;
+  const gitRef = typeof ref === 'string' && ref.trim() ? ref.trim() : undefined;
+  const collectionName =
+    typeof collection === 'string' && collection.trim() ? collection.trim() : undefined;
   const queryStr = typeof query === 'string' ? query.trim() : '';
 
   if (!queryStr) {
@@ -128,7 +370,149 @@ export async function handle(
     };
   }
 
-  if (ctx.indexState.status === 'indexing') {
+  const metadataFilter = filters?.metadata;
+  if (
+    metadataFilter !== undefined &&
+    (!metadataFilter || typeof metadataFilter !== 'object' || Array.isArray(metadataFilter))
+  ) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              errorCode: 'invalid_params',
+              message: "Invalid params: 'filters.metadata' must be an object of field -> value.",
+              hint: 'For example: { "annotations.deprecated": true }'
+            },
+            null,
+            2
+          )
+        }
+      ],
+      isError: true
+    };
+  }
+
+  if (filters?.docsOnly === true && filters?.codeOnly === true) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              errorCode: 'invalid_params',
+              message:
+                "Invalid params: 'filters.docsOnly' and 'filters.codeOnly' exclude each other.",
+              hint: 'Set at most one of them.'
+            },
+            null,
+            2
+          )
+        }
+      ],
+      isError: true
+    };
+  }
+
+  const invalidDate = (['modifiedAfter', 'modifiedBefore'] as const).find((key) => {
+    const value = filters?.[key];
+    return value !== undefined && (typeof value !== 'string' || Number.isNaN(Date.parse(value)));
+  });
+  if (invalidDate) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              errorCode: 'invalid_params',
+              message: `Invalid params: 'filters.${invalidDate}' must be an ISO 8601 date.`,
+              hint: 'For example: "2024-01-01"'
+            },
+            null,
+            2
+          )
+        }
+      ],
+      isError: true
+    };
+  }
+
+  const overlaySource = parseOverlaySource(overlayArg);
+  if (overlaySource === 'invalid' || (overlaySource && gitRef)) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              errorCode: 'invalid_params',
+              message: gitRef
+                ? "Invalid params: 'overlay' is applied to the working tree, not to a 'ref'."
+                : "Invalid params: 'overlay' takes one of { diff } or { directory }.",
+              hint: 'For example: { "diff": "<output of git diff>" }'
+            },
+            null,
+            2
+          )
+        }
+      ],
+      isError: true
+    };
+  }
+  let overlay: OverlayIndex | undefined;
+  if (overlaySource) {
+    if ('directory' in overlaySource) {
+      const stat = await fs
+        .stat(path.resolve(ctx.rootPath, overlaySource.directory))
+        .catch(() => null);
+      if (!stat?.isDirectory()) {
+        return {
+          content: [
+            {
+              type: 'text',
+              text: JSON.stringify(
+                {
+                  status: 'error',
+                  errorCode: 'invalid_params',
+                  message: `Invalid params: overlay directory not found: ${overlaySource.directory}`
+                },
+                null,
+                2
+              )
+            }
+          ],
+          isError: true
+        };
+      }
+    }
+    overlay = await buildOverlayIndex(ctx.rootPath, overlaySource);
+  }
+
+  // A model migration rebuilds in the background while the active index keeps serving
+  const migrating =
+    ctx.indexState.status === 'indexing' && !!(await readModelMigration(ctx.paths.baseDir));
+  if (ctx.indexState.status === 'indexing' && !migrating) {
+    const indexer = ctx.indexState.indexer;
+    // First build only: a rebuild keeps the previous index, ref and collection indexes are apart
+    const partialChunks =
+      indexer && !ctx.indexState.lastIndexed && !gitRef && !collectionName
+        ? indexer.getPartialChunks()
+        : [];
+    if (indexer && partialChunks.length > 0) {
+      return searchPartialIndex(ctx, indexer, partialChunks, queryStr, {
+        limit: limit || 5,
+        filters,
+        includeSnippets: includeSnippets === true,
+        highlight: highlight === true,
+        overlay
+      });
+    }
     return {
       content: [
         {
@@ -137,7 +521,8 @@ export async function handle(
             {
               status: 'indexing',
               message: 'Index is still being built. Retry in a moment.',
-              progress: ctx.indexState.indexer?.getProgress()
+              ...(indexer && { readiness: overallProgress(indexer.getProgress()) }),
+              progress: indexer?.getProgress()
             },
             null,
             2
@@ -165,29 +550,117 @@ export async function handle(
     };
   }
 
-  const searcher = new CodebaseSearcher(ctx.rootPath);
+  const searcher = new CodebaseSearcher(ctx.rootPath, {
+    ref: gitRef,
+    collection: collectionName,
+    overlay
+  });
   let results: SearchResult[];
+  let trace: SearchTrace | null = null;
+  let collectionInfo: SearchCollectionInfo | null = null;
+  let migration: ModelMigration | null = null;
   const searchProfile = (
     intent && ['explore', 'edit', 'refactor', 'migrate'].includes(intent) ? intent : 'explore'
   ) as SearchIntentProfile;
+  const retrievalMode = mode === 'keyword' || mode === 'semantic' ? mode : 'hybrid';
+  const rewriteMode: QueryRewriteMode =
+    typeof rewrite === 'string' && (QUERY_REWRITE_MODES as readonly string[]).includes(rewrite)
+      ? (rewrite as QueryRewriteMode)
+      : 'off';
+  const queryRewrites = await buildQueryRewrites(queryStr, rewriteMode, ctx.sample);
+  const searchOptions: SearchOptions = {
+    profile: searchProfile,
+    useSemanticSearch: retrievalMode !== 'keyword',
+    useKeywordSearch: retrievalMode !== 'semantic',
+    ...(typeof rerank === 'string' && (RERANK_MODES as readonly string[]).includes(rerank)
+      ? { rerank: rerank as RerankMode }
+      : {}),
+    ...(queryRewrites?.variants.length ? { queryRewrites: queryRewrites.variants } : {}),
+    ...(diversity && typeof diversity === 'object'
+      ? { diversity: diversity as DiversityOptions }
+      : {}),
+    ...(recency && typeof recency === 'object' ? { recency: recency as RecencyBoostOptions } : {}),
+    ...(debugRanking ? { debug: true } : {}),
+    ...(ctx.signal ? { signal: ctx.signal } : {})
+  };
 
   try {
-    results = await searcher.search(queryStr, limit || 5, filters, {
-      profile: searchProfile
-    });
+    results = await searcher.search(queryStr, limit || 5, filters, searchOptions);
+    trace = searcher.getLastTrace();
+    collectionInfo = searcher.getCollection();
+    migration = searcher.getModelMigration();
   } catch (error) {
-    if (error instanceof IndexCorruptedError) {
+    if (error instanceof EmbeddingCollectionError) {
+      return {
+        content: [
+          {
+            type: 'text',
+            text: JSON.stringify(
+              {
+                status: 'error',
+                errorCode: 'collection_unavailable',
+                collection: error.collection,
+                message: error.message,
+                hint:
+                  'Declare it under embeddingCollections in .codebase-context/config.json and ' +
+                  `build it with refresh_index({ collection: "${error.collection}" }).`
+              },
+              null,
+              2
+            )
+          }
+        ],
+        isError: true
+      };
+    }
+    // Ref indexes are built on request only; auto-heal would rebuild the working tree instead
+    if (error instanceof IndexCorruptedError && gitRef) {
+      return {
+        content: [
+          {
+            type: 'text',
+            text: JSON.stringify(
+              {
+                status: 'error',
+                ref: gitRef,
+                message: `No usable index for ref '${gitRef}': ${error.message}`,
+                hint: `Call refresh_index with ref="${gitRef}" first.`
+              },
+              null,
+              2
+            )
+          }
+        ]
+      };
+    }
+    // A model change: the active index keeps serving while the new vectors are built
+    const modelChange =
+      error instanceof IndexCorruptedError &&
+      !collectionName &&
+      !ctx.pathPolicy?.readOnly &&
+      (await startModelMigration(ctx.rootPath, resolveEmbeddingModel()));
+    if (modelChange) {
+      console.error('[Index] Embedding model changed. Re-embedding in the background...');
+      void ctx.performIndexing();
+      const previousModel = new CodebaseSearcher(ctx.rootPath, { overlay });
+      results = await previousModel.search(queryStr, limit || 5, filters, searchOptions);
+      trace = previousModel.getLastTrace();
+      migration = previousModel.getModelMigration();
+    } else if (error instanceof IndexCorruptedError) {
       console.error('[Auto-Heal] Index corrupted. Triggering full re-index...');
 
       await ctx.performIndexing();
 
       if (ctx.indexState.status === 'ready') {
         console.error('[Auto-Heal] Success. Retrying search...');
-        const freshSearcher = new CodebaseSearcher(ctx.rootPath);
+        const freshSearcher = new CodebaseSearcher(ctx.rootPath, {
+          collection: collectionName,
+          overlay
+        });
         try {
-          results = await freshSearcher.search(queryStr, limit || 5, filters, {
-            profile: searchProfile
-          });
+          results = await freshSearcher.search(queryStr, limit || 5, filters, searchOptions);
+          trace = freshSearcher.getLastTrace();
+          collectionInfo = freshSearcher.getCollection();
         } catch (retryError) {
           return {
             content: [
@@ -230,6 +703,23 @@ export async function handle(
     }
   }
 
+  // Dependency indexes are separate; their results compete with the project's on score
+  let dependencySearch: { searched: string[]; skipped: string[] } | undefined;
+  if (dependencySelector) {
+    const found = await searchDependencies(
+      ctx.rootPath,
+      dependencySelector,
+      queryStr,
+      limit || 5,
+      filters,
+      searchOptions
+    );
+    results = [...results, ...found.results]
+      .sort((a, b) => b.score - a.score)
+      .slice(0, limit || 5);
+    dependencySearch = { searched: found.searched, skipped: found.skipped };
+  }
+
   // Load memories for keyword matching, enriched with confidence
   const allMemories = await readMemoriesFile(ctx.paths.memory);
   const allMemoriesWithConf = withConfidence(allMemories);
@@ -866,6 +1356,47 @@ export async function handle(
     return `// ${scopeHeader}\n${cleanedSnippet}`;
   }
 
+  const round = (value: number): number => Math.round(value * 1000) / 1000;
+
+  function buildResultDebug(r: SearchResult): SearchResultDebug | undefined {
+    const breakdown = r.scoreBreakdown;
+    if (!breakdown) return undefined;
+    const symbol = r.metadata?.symbolPath?.join('.') ?? r.metadata?.symbolName;
+    return {
+      ...(breakdown.semantic && { vector: breakdown.semantic }),
+      ...(breakdown.keyword && { keyword: breakdown.keyword }),
+      fused: breakdown.fused,
+      ...(breakdown.adjustments.length > 0 && {
+        adjustments: breakdown.adjustments.map((a) => `${a.reason} x${a.factor}`)
+      }),
+      preRerank: breakdown.preRerankScore,
+      ...(breakdown.rerankScore !== undefined && { rerank: round(breakdown.rerankScore) }),
+      chunk: {
+        lines: `${r.startLine}-${r.endLine}`,
+        ...(r.metadata?.chunkStrategy && { strategy: r.metadata.chunkStrategy }),
+        ...(r.metadata?.overlapLines ? { overlapLines: r.metadata.overlapLines } : {}),
+        ...(symbol && { symbol })
+      }
+    };
+  }
+
+  const searchDebug: SearchDebug | undefined = trace
+    ? {
+        intent: trace.intent,
+        mode: retrievalMode,
+        weights: { semantic: round(trace.semanticWeight), keyword: round(trace.keywordWeight) },
+        queryVariants: trace.queryVariants,
+        rescued: trace.rescued,
+        rerank: { mode: trace.rerank, applied: trace.reranked },
+        candidates: trace.candidates,
+        ...(trace.prefilter && { prefilter: trace.prefilter }),
+        ...(filters && Object.keys(filters).length > 0 && { filters })
+      }
+    : undefined;
+
+  const queryId = await logResultAccess(ctx, queryStr, results);
+  const owned = ownership === true ? await resultOwnership(ctx, results) : undefined;
+
   return {
     content: [
       {
@@ -873,6 +1404,18 @@ export async function handle(
         text: JSON.stringify(
           {
             status: 'success',
+            ...partialMarker(ctx.signal),
+            ...(queryId && { queryId }),
+            ...(gitRef && { ref: gitRef }),
+            ...(collectionInfo && { collection: collectionInfo }),
+            ...(migration && {
+              migration: describeModelMigration(
+                migration,
+                ctx.indexState.status === 'indexing'
+                  ? ctx.indexState.indexer?.getProgress()
+                  : undefined
+              )
+            }),
             searchQuality: {
               status: searchQuality.status,
               confidence: searchQuality.confidence,
@@ -882,15 +1425,18 @@ export async function handle(
                 })
             },
             ...(preflightPayload && { preflight: preflightPayload }),
-            results: results.map((r) => {
+            ...(searchDebug && { debug: searchDebug }),
+            results: results.map((r, i) => {
               const relationshipsAndHints = buildRelationshipHints(r);
               const enrichedSnippet = includeSnippets
                 ? enrichSnippetWithScope(r.snippet, r.metadata, r.filePath, r.startLine)
                 : undefined;
+              const resultDebug = buildResultDebug(r);
 
               return {
                 file: `${r.filePath}:${r.startLine}-${r.endLine}`,
                 summary: r.summary,
+                ...(r.metadata?.docComment && { doc: docSummary(r.metadata.docComment) }),
                 score: Math.round(r.score * 100) / 100,
                 ...(r.componentType &&
                   r.layer &&
@@ -901,10 +1447,32 @@ export async function handle(
                   relationships: relationshipsAndHints.relationships
                 }),
                 ...(relationshipsAndHints.hints && { hints: relationshipsAndHints.hints }),
-                ...(enrichedSnippet && { snippet: enrichedSnippet })
+                ...(enrichedSnippet && {
+                  snippet: enrichedSnippet,
+                  ...highlightFields(enrichedSnippet, r.language, highlight === true)
+                }),
+                ...(owned?.[i] && { ownership: owned[i] }),
+                ...(resultDebug && { debug: resultDebug }),
+                ...(r.dependency && { dependency: r.dependency }),
+                ...(r.metadata?.overlay && { overlay: true })
               };
             }),
             totalResults: results.length,
+            ...(overlay && { overlay: summarizeOverlay(overlay) }),
+            ...(queryRewrites && {
+              queryRewrites: {
+                source: queryRewrites.source,
+                variants: queryRewrites.variants,
+                ...(queryRewrites.samplingError && {
+                  samplingError: queryRewrites.samplingError
+                })
+              }
+            }),
+            ...(dependencySearch && { dependencies: dependencySearch.searched }),
+            ...(dependencySearch &&
+              dependencySearch.skipped.length > 0 && {
+                dependencyWarnings: dependencySearch.skipped
+              }),
             ...(relatedMemories.length > 0 && {
               relatedMemories: relatedMemories
                 .slice(0, 3)
@@ -918,3 +1486,132 @@ export async function handle(
     ]
   };
 }
+
+/** CODEOWNERS owners and the blame summary of each result's lines, where there are any */
+async function resultOwnership(
+  ctx: ToolContext,
+  results: SearchResult[]
+): Promise<Array<{ owners?: string[]; lastChange?: BlameSummary } | undefined>> {
+  const root = path.resolve(ctx.rootPath);
+  return Promise.all(
+    results.map(async (r) => {
+      // Overlay lines aren't committed yet, so blame has nothing to say about them
+      if (r.dependency || r.metadata?.overlay) return undefined;
+      const file = path.relative(root, path.resolve(root, r.filePath)).replace(/\\/g, '/');
+      // Chunks of a ref index are blamed at the commit they were indexed from
+      const lastChange = await blameRange(
+        ctx.rootPath,
+        file,
+        r.startLine,
+        r.endLine,
+        r.metadata?.gitCommit
+      );
+      const owners = r.metadata?.owners;
+      if (!lastChange && !owners?.length) return undefined;
+      return {
+        ...(owners?.length && { owners }),
+        ...(lastChange && { lastChange })
+      };
+    })
+  );
+}
+
+/**
+ * Keyword search over the chunks a first build has parsed so far. Nothing is embedded yet and
+ * files still unparsed are missing, so results are flagged partial with how far the build is.
+ */
+async function searchPartialIndex(
+  ctx: ToolContext,
+  indexer: CodebaseIndexer,
+  chunks: CodeChunk[],
+  query: string,
+  options: {
+    limit: number;
+    filters?: Record<string, unknown>;
+    includeSnippets: boolean;
+    highlight: boolean;
+    overlay?: OverlayIndex;
+  }
+): Promise<ToolResponse> {
+  const progress = indexer.getProgress();
+  const searcher = new CodebaseSearcher(ctx.rootPath, { chunks, overlay: options.overlay });
+  const results = await searcher.search(query, options.limit, options.filters, {
+    useSemanticSearch: false,
+    useKeywordSearch: true,
+    rerank: 'off',
+    ...(ctx.signal ? { signal: ctx.signal } : {})
+  });
+  return {
+    content: [
+      {
+        type: 'text',
+        text: JSON.stringify(
+          {
+            status: 'success',
+            partial: true,
+            index: {
+              status: 'indexing',
+              readiness: overallProgress(progress),
+              filesIndexed: progress.filesProcessed,
+              totalFiles: progress.totalFiles
+            },
+            message:
+              'The first index build is still running: keyword matches from the files ' +
+              'indexed so far. Retry once it finishes for complete, ranked results.',
+            results: results.map((r) => ({
+              file: `${r.filePath}:${r.startLine}-${r.endLine}`,
+              summary: r.summary,
+              score: Math.round(r.score * 100) / 100,
+              ...(options.includeSnippets &&
+                r.snippet && {
+                  snippet: r.snippet,
+                  ...highlightFields(r.snippet, r.language, options.highlight)
+                }),
+              ...(r.metadata?.overlay && { overlay: true })
+            })),
+            totalResults: results.length,
+            ...(options.overlay && { overlay: summarizeOverlay(options.overlay) })
+          },
+          null,
+          2
+        )
+      }
+    ]
+  };
+}
+
+/** The `overlay` argument: undefined when absent, 'invalid' unless exactly one source is given */
+function parseOverlaySource(value: unknown): OverlaySource | 'invalid' | undefined {
+  if (value === undefined || value === null) return undefined;
+  if (typeof value !== 'object' || Array.isArray(value)) return 'invalid';
+  const { diff, directory } = value as { diff?: unknown; directory?: unknown };
+  if (typeof diff === 'string' && diff.trim() && directory === undefined) return { diff };
+  if (typeof directory === 'string' && directory.trim() && diff === undefined) {
+    return { directory: directory.trim() };
+  }
+  return 'invalid';
+}
+
+/** Log the returned chunks for rate_result; undefined when logging is off or failed */
+async function logResultAccess(
+  ctx: ToolContext,
+  query: string,
+  results: SearchResult[]
+): Promise<string | undefined> {
+  if (ctx.pathPolicy?.readOnly || results.length === 0) return undefined;
+  if (!(await loadFeedbackConfig(ctx.rootPath)).log) return undefined;
+  const root = path.resolve(ctx.rootPath);
+  const accessed = results
+    .filter((r) => !r.dependency && !r.metadata?.overlay)
+    .map((r) => {
+      const file = path.relative(root, path.resolve(root, r.filePath)).replace(/\\/g, '/');
+      const key = feedbackKey(file, r.startLine, r.endLine, r.metadata?.symbolPath);
+      return { file, startLine: r.startLine, endLine: r.endLine, key };
+    });
+  try {
+    return await logSearchAccess(ctx.paths.baseDir, query, accessed);
+  } catch {
+    // Feedback is optional; the search result stands
+    return undefined;
+  }
+}
@@ -0,0 +1,112 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { searchHistory } from '../core/commit-history.js';
+import { jsonResponse } from './json-response.js';
+
+const DEFAULT_LIMIT = 5;
+const DEFAULT_MAX_HUNKS = 2;
+
+export const definition: Tool = {
+  name: 'search_history',
+  description:
+    'Search git commit history by meaning: "when did we change the retry logic and why". ' +
+    'Returns commit SHA, author, date, message and the most relevant diff hunks. ' +
+    'Indexes recent commits on first use, then only new ones.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      query: {
+        type: 'string',
+        description: 'What changed, in natural language or identifiers'
+      },
+      limit: {
+        type: 'number',
+        description: `Maximum commits to return (default: ${DEFAULT_LIMIT})`,
+        default: DEFAULT_LIMIT
+      },
+      author: {
+        type: 'string',
+        description: 'Only commits whose author name or email contains this'
+      },
+      since: {
+        type: 'string',
+        description: 'Only commits authored on or after this ISO date (for example: 2025-01-01)'
+      },
+      until: {
+        type: 'string',
+        description: 'Only commits authored on or before this ISO date'
+      },
+      path: {
+        type: 'string',
+        description: 'Only commits touching this repo-relative file or directory'
+      },
+      maxHunks: {
+        type: 'number',
+        description: `Diff hunks per commit (default: ${DEFAULT_MAX_HUNKS})`,
+        default: DEFAULT_MAX_HUNKS
+      }
+    },
+    required: ['query']
+  }
+};
+
+function optionalString(value: unknown): string | undefined {
+  return typeof value === 'string' && value.trim() ? value.trim() : undefined;
+}
+
+function boundedInteger(value: unknown, fallback: number, max: number): number {
+  return typeof value === 'number' && Number.isFinite(value) && value > 0
+    ? Math.min(Math.floor(value), max)
+    : fallback;
+}
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const query = optionalString(args.query);
+  if (!query) {
+    return jsonResponse(
+      {
+        status: 'error',
+        message: "Invalid params: 'query' is required and must be a non-empty string."
+      },
+      true
+    );
+  }
+
+  const since = optionalString(args.since);
+  const until = optionalString(args.until);
+  for (const [name, value] of [
+    ['since', since],
+    ['until', until]
+  ]) {
+    if (value && Number.isNaN(Date.parse(value))) {
+      return jsonResponse(
+        { status: 'error', message: `Invalid params: '${name}' must be an ISO date.` },
+        true
+      );
+    }
+  }
+
+  try {
+    const response = await searchHistory(ctx.rootPath, query, {
+      limit: boundedInteger(args.limit, DEFAULT_LIMIT, 20),
+      maxHunks: boundedInteger(args.maxHunks, DEFAULT_MAX_HUNKS, 5),
+      author: optionalString(args.author),
+      since,
+      until,
+      path: optionalString(args.path),
+      persist: !ctx.pathPolicy?.readOnly
+    });
+    return jsonResponse({
+      status: 'success',
+      query,
+      ...response,
+      ...(response.results.length === 0 ? { message: 'No matching commits.' } : {})
+    });
+  } catch (error) {
+    const reason = error instanceof Error ? error.message : String(error);
+    return jsonResponse({ status: 'error', message: `Could not search git history: ${reason}` });
+  }
+}
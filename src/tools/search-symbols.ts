@@ -0,0 +1,162 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { loadSymbolIndex, searchSymbols } from '../core/symbol-index.js';
+
+const SYMBOL_KINDS = [
+  'function',
+  'method',
+  'class',
+  'struct',
+  'interface',
+  'enum',
+  'trait',
+  'protocol',
+  'extension',
+  'impl',
+  'module',
+  'type',
+  'constant',
+  'component',
+  'property',
+  'message',
+  'service',
+  'rpc',
+  'endpoint',
+  'table',
+  'view',
+  'procedure',
+  'trigger'
+] as const;
+
+export const definition: Tool = {
+  name: 'search_symbols',
+  description:
+    'Find definitions by symbol name (exact, prefix, substring, or fuzzy/typo-tolerant), ' +
+    'filtered by kind, language, and path glob. Use when you know the name; use ' +
+    'search_codebase for concepts.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      query: {
+        type: 'string',
+        description:
+          'Symbol name or fragment (for example: UserService, parseCfg). Qualified names ' +
+          'such as com.acme.UserService.save, Cache::get or Billing::Invoice#total match ' +
+          'Java, Kotlin, Scala, C#, Rust, Ruby, PHP and Elixir symbols.'
+      },
+      kind: {
+        type: 'array',
+        items: { type: 'string', enum: [...SYMBOL_KINDS] },
+        description: 'Only these symbol kinds'
+      },
+      language: {
+        type: 'string',
+        description: 'Only this language (for example: typescript, go, python)'
+      },
+      path: {
+        type: 'string',
+        description: 'Glob over repo-relative paths (for example: src/api/**)'
+      },
+      exact: {
+        type: 'boolean',
+        description: 'Only exact (case-insensitive) name matches',
+        default: false
+      },
+      limit: {
+        type: 'number',
+        description: 'Maximum number of results (default: 20)',
+        default: 20
+      }
+    },
+    required: ['query']
+  }
+};
+
+function invalidParams(message: string): ToolResponse {
+  return {
+    content: [
+      {
+        type: 'text',
+        text: JSON.stringify({ status: 'error', message: `Invalid params: ${message}` }, null, 2)
+      }
+    ],
+    isError: true
+  };
+}
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const { query, kind, language, path, exact, limit } = args as {
+    query?: unknown;
+    kind?: unknown;
+    language?: unknown;
+    path?: unknown;
+    exact?: unknown;
+    limit?: unknown;
+  };
+  const normalizedQuery = typeof query === 'string' ? query.trim() : '';
+  const normalizedLimit =
+    typeof limit === 'number' && Number.isFinite(limit) && limit > 0 ? Math.floor(limit) : 20;
+
+  if (!normalizedQuery) {
+    return invalidParams("'query' is required and must be a non-empty string.");
+  }
+
+  const kinds: unknown[] = typeof kind === 'string' ? [kind] : Array.isArray(kind) ? kind : [];
+  const unknownKind = kinds.find(
+    (k) => typeof k !== 'string' || !(SYMBOL_KINDS as readonly string[]).includes(k)
+  );
+  if (unknownKind !== undefined) {
+    return invalidParams(
+      `unknown kind '${String(unknownKind)}'. Use: ${SYMBOL_KINDS.join(', ')}.`
+    );
+  }
+
+  const definitions = await loadSymbolIndex(ctx.rootPath);
+  if (!definitions) {
+    return {
+      content: [
+        {
+          type: 'text',
+          text: JSON.stringify(
+            {
+              status: 'error',
+              query: normalizedQuery,
+              message: 'Symbol index not available. Run refresh_index to build it.'
+            },
+            null,
+            2
+          )
+        }
+      ]
+    };
+  }
+
+  const result = searchSymbols(definitions, normalizedQuery, {
+    kinds: kinds as string[],
+    language: typeof language === 'string' && language.trim() ? language.trim() : undefined,
+    pathGlob: typeof path === 'string' && path.trim() ? path.trim() : undefined,
+    exact: exact === true,
+    limit: normalizedLimit
+  });
+
+  return {
+    content: [
+      {
+        type: 'text',
+        text: JSON.stringify(
+          {
+            status: 'success',
+            query: normalizedQuery,
+            totalMatches: result.total,
+            results: result.results
+          },
+          null,
+          2
+        )
+      }
+    ]
+  };
+}
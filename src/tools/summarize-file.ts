@@ -0,0 +1,72 @@
+import type { Tool } from '@modelcontextprotocol/sdk/types.js';
+import type { ToolContext, ToolResponse } from './types.js';
+import { summarizeFile, toProjectRelativePath } from '../core/file-summary.js';
+import { jsonResponse } from './json-response.js';
+
+export const definition: Tool = {
+  name: 'summarize_file',
+  description:
+    'Structured summary of one file: purpose, responsibilities, exported and top-level ' +
+    'symbols, imports and importers. Uses the client model via MCP sampling when available; ' +
+    'sampled summaries are cached until the file changes.',
+  inputSchema: {
+    type: 'object',
+    properties: {
+      path: {
+        type: 'string',
+        description: 'Repo-relative file path (for example: src/auth/session.ts)'
+      },
+      useSampling: {
+        type: 'boolean',
+        description: "Ask the client's model to write purpose and responsibilities (default: true)",
+        default: true
+      },
+      refresh: {
+        type: 'boolean',
+        description: 'Regenerate the sampled summary even if a cached one is current',
+        default: false
+      }
+    },
+    required: ['path']
+  }
+};
+
+export async function handle(
+  args: Record<string, unknown>,
+  ctx: ToolContext
+): Promise<ToolResponse> {
+  const target = typeof args.path === 'string' ? args.path.trim() : '';
+  if (!target) {
+    return jsonResponse(
+      {
+        status: 'error',
+        message: "Invalid params: 'path' is required and must be a non-empty string."
+      },
+      true
+    );
+  }
+
+  const file = toProjectRelativePath(ctx.rootPath, target);
+  if (!file) {
+    return jsonResponse(
+      { status: 'error', message: `Invalid params: '${target}' is outside the project root.` },
+      true
+    );
+  }
+
+  try {
+    const summary = await summarizeFile(ctx.rootPath, file, {
+      sample: args.useSampling === false ? undefined : ctx.sample,
+      refresh: args.refresh === true,
+      persist: !ctx.pathPolicy?.readOnly
+    });
+    return jsonResponse({ status: 'success', ...summary });
+  } catch (error) {
+    const code = (error as NodeJS.ErrnoException).code;
+    if (code === 'ENOENT' || code === 'EISDIR') {
+      return jsonResponse({ status: 'error', file, message: `File not found: ${file}` });
+    }
+    const reason = error instanceof Error ? error.message : String(error);
+    return jsonResponse({ status: 'error', file, message: `Could not summarize file: ${reason}` });
+  }
+}
@@ -1,5 +1,7 @@
 import type { CodebaseIndexer } from '../core/indexer.js';
-import type { IndexingStats } from '../types/index.js';
+import type { PathPolicy } from '../core/path-policy.js';
+import type { IndexingProgress, IndexingStats, Sampler } from '../types/index.js';
+import type { SyntaxToken } from '../utils/syntax-highlight.js';
 
 export interface DecisionCard {
   ready: boolean;
@@ -34,11 +36,49 @@ export interface IndexState {
   indexer?: CodebaseIndexer;
 }
 
+export interface ToolProject {
+  name: string;
+  rootPath: string;
+  indexState: IndexState;
+}
+
+/** Per-call hooks for a client that asked for progress notifications */
+export interface IndexingHooks {
+  signal?: AbortSignal;
+  onProgress?: (progress: IndexingProgress) => void;
+}
+
 export interface ToolContext {
   indexState: IndexState;
   paths: ToolPaths;
   rootPath: string;
-  performIndexing: (incrementalOnly?: boolean, reason?: string) => void;
+  performIndexing: (
+    incrementalOnly?: boolean,
+    reason?: string,
+    hooks?: IndexingHooks
+  ) => void | Promise<void>;
+  /** Set when the client sent a progressToken; indexing tools then run in the foreground */
+  progress?: IndexingHooks;
+  /**
+   * Fires when the client cancels the call or its time budget runs out (see
+   * cancellation.ts). Long tools stop there and return what they have, marked `partial`.
+   */
+  signal?: AbortSignal;
+  /** Current project name and all workspace projects (absent outside the MCP server) */
+  projectName?: string;
+  projects?: ToolProject[];
+  /** MCP sampling through the calling client; absent when the client doesn't support it */
+  sample?: Sampler;
+  /** Allow/deny paths and read-only mode; loaded from the project when absent */
+  pathPolicy?: PathPolicy;
+  /**
+   * Serve another root (a fetched remote repo) as a workspace project, indexing it in the
+   * background when asked or when it has no index. Absent outside the MCP server.
+   */
+  addProject?: (
+    project: { name: string; rootPath: string },
+    options?: { reindex?: boolean }
+  ) => Promise<ToolProject>;
 }
 
 export interface ToolResponse {
@@ -71,12 +111,48 @@ export interface SearchResultItem {
     tests?: string[];
   };
   snippet?: string;
+  /** Snippet language id */
+  language?: string;
+  /** Token classes for the snippet, `[line, column, length, kind]`, with `highlight: true` */
+  tokens?: SyntaxToken[];
+  debug?: SearchResultDebug;
+  /** Set on results from an indexed dependency (`name@version`) */
+  dependency?: string;
+}
+
+/** Per-result score breakdown returned with `debug: true` */
+export interface SearchResultDebug {
+  vector?: { rank: number; score: number };
+  keyword?: { rank: number; score: number };
+  /** Normalized reciprocal-rank-fusion score, before adjustments */
+  fused: number;
+  /** e.g. "layer x1.1" */
+  adjustments?: string[];
+  preRerank: number;
+  rerank?: number;
+  chunk: { lines: string; strategy?: string; overlapLines?: number; symbol?: string };
+}
+
+/** Query-level ranking trace returned with `debug: true` */
+export interface SearchDebug {
+  intent: string;
+  mode: string;
+  weights: { semantic: number; keyword: number };
+  queryVariants: string[];
+  rescued: boolean;
+  rerank: { mode: string; applied: boolean };
+  candidates: number;
+  /** Literal pre-filter: the query's literals and how many files the vector query kept */
+  prefilter?: { literals: string[]; files: number };
+  filters?: Record<string, unknown>;
 }
 
 export interface SearchResponse {
   status: string;
+  ref?: string;
   searchQuality: SearchQuality;
   preflight?: DecisionCard;
+  debug?: SearchDebug;
   results: SearchResultItem[];
   totalResults: number;
   relatedMemories?: string[];
@@ -21,7 +21,7 @@ export interface FrameworkAnalyzer {
   canAnalyze(filePath: string, content?: string): boolean;
 
   /** Parse a file and extract structured information */
-  analyze(filePath: string, content: string): Promise<AnalysisResult>;
+  analyze(filePath: string, content: string, options?: AnalyzeOptions): Promise<AnalysisResult>;
 
   /** Detect framework-specific patterns and metadata from the entire codebase */
   detectCodebaseMetadata(rootPath: string): Promise<CodebaseMetadata>;
@@ -37,12 +37,42 @@ export interface FrameworkAnalyzer {
 
   /** Get a regex to extract a code snippet for a detected pattern (optional) */
   getSnippetPattern?(category: string, name: string): RegExp | null;
+
+  /**
+   * Roles of a chunk (`http_handler`, `ui_component`, `migration`, ...) for the `role` search
+   * filter. Called for every chunk, including those another analyzer produced (optional)
+   */
+  detectRoles?(chunk: CodeChunk): string[];
 }
 
 // ============================================================================
 // ANALYSIS RESULTS
 // ============================================================================
 
+/** How code is cut into chunks; unset fields keep the analyzer's defaults */
+export interface LanguageChunkingOptions {
+  /** Estimated-token ceiling per chunk; larger symbols are split */
+  maxTokens?: number;
+  /** Line ceiling per chunk (default 150) */
+  maxLines?: number;
+  /** Declarations shorter than this are merged with short neighbours (default 10) */
+  minLines?: number;
+  /** Lines repeated at the start of each part of a split symbol (default 0) */
+  overlapLines?: number;
+  /** Merge short adjacent declarations into one chunk (default true) */
+  mergeSmall?: boolean;
+}
+
+/** Defaults for every language, overridden per language id (`go`, `python`, ...) */
+export interface ChunkingConfig extends LanguageChunkingOptions {
+  languages?: Record<string, LanguageChunkingOptions>;
+}
+
+export interface AnalyzeOptions {
+  /** Chunking for this file's language, already resolved from the project config */
+  chunking?: LanguageChunkingOptions;
+}
+
 export interface AnalysisResult {
   filePath: string;
   language: string;
@@ -149,7 +179,72 @@ export interface ChunkMetadata {
   symbolKind?: string;
   symbolPath?: string[];
   parentSymbol?: string;
+  /** The symbol's doc comment (JSDoc, KDoc, GoDoc, `///`, docstring), markers stripped */
+  docComment?: string;
+  /** Package/module-qualified symbol name (Java, Kotlin, C#, Rust) */
+  qualifiedName?: string;
+  /** Enclosing package or namespace (Java, Kotlin, C#) */
+  namespace?: string;
+  /** Owning .NET project (`.csproj`), when the file belongs to one */
+  dotnetProject?: string;
+  /** Owning monorepo package: npm name, Go module, crate or Bazel label */
+  package?: string;
+  /** Bazel/Buck targets listing the file in srcs/hdrs (`//services/api:server`) */
+  buildTargets?: string[];
+  /** CODEOWNERS owners of the file (last matching rule) */
+  owners?: string[];
+  /** Module tags from the configured directory rules */
+  modules?: string[];
+  /** Roles the analyzers' heuristics found (`http_handler`, `migration`, `test`, ...) */
+  roles?: string[];
+  /** Configured annotation name -> extracted text (e.g. `deprecated` -> "use v2") */
+  annotations?: Record<string, string>;
+  /** Terraform block or Kubernetes manifest described by this chunk */
+  infra?: InfraMetadata;
+  /** Protobuf message/service/rpc or OpenAPI operation/schema described by this chunk */
+  schema?: SchemaMetadata;
+  /** The statement of a `.sql` chunk, or the SQL query strings embedded in a code chunk */
+  sql?: SqlStatementInfo[];
+  /** Tables those statements touch, also unqualified (`billing.orders` and `orders`) */
+  sqlTables?: string[];
+  /** Vue/Svelte/Astro block the chunk comes from, and the block's `lang` */
+  sfcBlock?: 'script' | 'template' | 'style';
+  blockLang?: string;
+  /** Props declared by the single-file component (on its script chunks) */
+  props?: string[];
+  /** Jupyter cell the chunk comes from (0-based), its type and saved execution count */
+  cellIndex?: number;
+  cellType?: 'code' | 'markdown' | 'raw';
+  executionCount?: number;
+  cellId?: string;
+  /** Markdown section heading, its level (1-6) and the breadcrumb of enclosing headings */
+  heading?: string;
+  headingLevel?: number;
+  headingPath?: string[];
   chunkStrategy?: string;
+  /** Lines repeated from the previous piece when an oversized symbol was split */
+  overlapLines?: number;
+  /** Number of secrets masked in `content` */
+  redactedSecrets?: number;
+  /** Number of compliance-rule matches (personal data, custom rules) masked in `content` */
+  complianceFiltered?: number;
+  /** Git ref and resolved commit, for chunks in a per-ref index */
+  gitRef?: string;
+  gitCommit?: string;
+  /** Size of the source file in bytes */
+  fileSize?: number;
+  /** Encoding the file was saved in, when not plain UTF-8 (content is transcoded) */
+  encoding?: 'utf-8-bom' | 'utf-16le' | 'utf-16be' | 'windows-1252';
+  /** Last commit date of the file (modification time when untracked), ISO 8601 */
+  lastModified?: string;
+  /** Commits that touched the file in the 90 days before indexing */
+  recentCommits?: number;
+  /** Machine-generated source (lockfile, generator output), see `parsing.generatedFiles` */
+  generated?: boolean;
+  /** Chunks of the file left out by `parsing.maxChunksPerFile` or generated-file sampling */
+  droppedChunks?: number;
+  /** Chunk of a query-time overlay (scratch directory or diff), not of the index on disk */
+  overlay?: boolean;
 
   // Framework-specific
   isStandalone?: boolean;
@@ -185,6 +280,79 @@ export interface ChunkMetadata {
   [key: string]: unknown;
 }
 
+export interface InfraMetadata {
+  kind: 'terraform' | 'kubernetes';
+  /** HCL block type: `resource`, `data`, `module`, `variable`, ... (Terraform only) */
+  blockType?: string;
+  /** Terraform resource/data type (`aws_s3_bucket`) or Kubernetes `kind` (`Deployment`) */
+  resourceType?: string;
+  resourceName?: string;
+  /** Terraform address (`aws_s3_bucket.uploads`, `module.vpc`) or `Kind/name` */
+  address: string;
+  /** Repo-relative directory of the Terraform module the file belongs to (set by the indexer) */
+  modulePath?: string;
+  /** `source` of a `module` block */
+  source?: string;
+  /** Kubernetes `metadata.namespace` */
+  namespace?: string;
+  apiVersion?: string;
+}
+
+export interface SqlStatementInfo {
+  /** `select`, `insert`, `update`, `delete`, `merge`, ... or verb and object (`create table`) */
+  statement: string;
+  /** Tables read or written, lower-cased and schema-qualified as written */
+  tables: string[];
+  /** 1-based line the statement or query string starts on */
+  line: number;
+  /** What a DDL statement creates, alters or drops (`billing.orders`) */
+  object?: string;
+  /** Function or method an embedded query sits in */
+  function?: string;
+  /** Consecutive statements of the same shape collapsed into this one (seed data) */
+  repeated?: number;
+}
+
+export interface SchemaMetadata {
+  kind: 'protobuf' | 'openapi' | 'graphql';
+  /**
+   * Protobuf `message`/`enum`/`service`/`rpc`; OpenAPI `operation` or `schema`; GraphQL
+   * `type`/`interface`/`input`/`enum`/`union`/`scalar`, and `field` for root operation fields
+   */
+  type:
+    | 'message'
+    | 'enum'
+    | 'service'
+    | 'rpc'
+    | 'operation'
+    | 'schema'
+    | 'type'
+    | 'interface'
+    | 'input'
+    | 'union'
+    | 'scalar'
+    | 'field';
+  name: string;
+  /**
+   * `user.v1.UserService.GetUser`, `GET /users/{id}`, `#/components/schemas/User`,
+   * `Query.orders`
+   */
+  address: string;
+  /** Protobuf package */
+  package?: string;
+  /** Service of an rpc */
+  service?: string;
+  requestType?: string;
+  responseType?: string;
+  /** OpenAPI operation */
+  httpMethod?: string;
+  path?: string;
+  operationId?: string;
+  /** GraphQL field: the type declaring it and the operation it belongs to */
+  parentType?: string;
+  operationType?: 'query' | 'mutation' | 'subscription';
+}
+
 // ============================================================================
 // CODEBASE METADATA
 // ============================================================================
@@ -320,12 +488,39 @@ export interface SearchQuery {
   includeRelated?: boolean;
 }
 
+export type MetadataFilterValue = string | number | boolean | string[];
+
 export interface SearchFilters {
   framework?: string;
   language?: string;
   componentType?: string;
   layer?: ArchitecturalLayer;
+  /** Only chunks from this .NET project (case-insensitive project name) */
+  dotnetProject?: string;
+  /** Only chunks from this workspace package (exact name, as listed by list_packages) */
+  package?: string;
+  /** Only chunks with this role (`http_handler`, `ui_component`, `migration`, ...) */
+  role?: string;
+  /**
+   * Any chunk metadata field, by dotted path (`owners`, `annotations.deprecated`, `infra.kind`).
+   * Arrays match when they contain the value; `true`/`false` test presence.
+   */
+  metadata?: Record<string, MetadataFilterValue>;
+  /** Glob over repo-relative paths (`internal/billing/**`); a plain path means that directory */
+  path?: string;
+  /** Drop test files (`*.test.*`, `*_test.go`, `tests/`, ...) */
+  excludeTests?: boolean;
+  /** Only documentation files (`.md`, `.mdx`, `.rst`, ...), or only everything else */
+  docsOnly?: boolean;
+  codeOnly?: boolean;
+  /** Only files last modified on or after / before this date (ISO 8601) */
+  modifiedAfter?: string;
+  modifiedBefore?: string;
+  /** Source file size bounds in bytes */
+  minFileSize?: number;
+  maxFileSize?: number;
   tags?: string[];
+  /** Absolute or repo-relative paths; the file-level filters above resolve into these */
   filePaths?: string[];
   excludePaths?: string[];
   hasTests?: boolean;
@@ -361,12 +556,34 @@ export interface SearchResult {
 
   relationships?: RelationshipData;
 
+  // How the score was reached; only with `SearchOptions.debug`
+  scoreBreakdown?: ScoreBreakdown;
+
+  /** Indexed third-party dependency (`name@version`) the result comes from */
+  dependency?: string;
+
   // Optional detailed context (for agent to request if needed)
   fullContent?: string; // Only included if explicitly requested
   relatedChunks?: CodeChunk[];
   highlights?: TextHighlight[];
 }
 
+/** Per-result ranking trace for debugging why a chunk ranked where it did */
+export interface ScoreBreakdown {
+  /** Best vector-similarity hit across query variants: raw score and 0-based rank */
+  semantic?: { score: number; rank: number };
+  /** Best keyword (fuzzy + BM25) hit across query variants */
+  keyword?: { score: number; rank: number };
+  /** Reciprocal-rank-fusion score, normalized by the best possible fusion score */
+  fused: number;
+  /** Multipliers applied to the fused score, in order */
+  adjustments: Array<{ reason: string; factor: number }>;
+  /** Score before stage-2 reranking */
+  preRerankScore: number;
+  /** Cross-encoder score, when reranking ran and covered this result */
+  rerankScore?: number;
+}
+
 export interface RelationshipData {
   /** Files that import this result */
   importedBy?: string[];
@@ -396,6 +613,9 @@ export interface IndexingProgress {
   filesProcessed: number;
   totalFiles: number;
   chunksCreated: number;
+  /** Embedding phase only: chunks embedded so far out of `chunksToEmbed` */
+  chunksEmbedded?: number;
+  chunksToEmbed?: number;
   errors: IndexingError[];
   startedAt: Date;
   estimatedCompletion?: Date;
@@ -438,7 +658,25 @@ export interface IndexingStats {
     changed: number;
     deleted: number;
     unchanged: number;
+    /** Moved files whose stored chunks were repointed instead of re-embedded */
+    renamed: number;
   };
+  /** Chunks whose vector came from the embedding cache vs. were sent to the provider */
+  embeddingCache?: {
+    hits: number;
+    misses: number;
+  };
+  /** Secrets masked in chunk content (see CODEBASE_CONTEXT_REDACT_SECRETS) */
+  redactedSecrets?: number;
+  /** Compliance-rule matches masked in indexed files, and files the rules left out */
+  complianceFiltered?: number;
+  complianceExcludedFiles?: number;
+  /** Files cut to their first chunks by `maxChunksPerFile` or generated-file sampling */
+  truncatedFiles?: number;
+  /** Generated files left out entirely (`generatedFiles: 'skip'`); also in skippedFiles */
+  skippedGeneratedFiles?: number;
+  /** Extra chunks from splitting ones over the embedding model's input limit */
+  chunksSplitForModel?: number;
 }
 
 // ============================================================================
@@ -464,15 +702,31 @@ export interface CodebaseConfig {
   // File filtering
   include?: string[];
   exclude?: string[];
+  /** Ignore rules (.gitignore syntax) applied at the root; default: codebase-context.yaml */
+  ignore?: string[];
   respectGitignore?: boolean;
 
   // Parsing options
   parsing: {
     maxFileSize?: number; // bytes
+    /** Size caps for particular extensions, e.g. `{ ".yaml": 262144 }`; overrides maxFileSize */
+    maxFileSizeByExtension?: Record<string, number>;
+    /** Chunks kept per file; the rest of a longer file is dropped (default 400) */
+    maxChunksPerFile?: number;
+    /**
+     * Lockfiles, generator output names and `Code generated ... DO NOT EDIT` headers:
+     * 'skip' leaves them out, 'sample' keeps their first chunks, 'index' treats them as
+     * ordinary files (default 'sample')
+     */
+    generatedFiles?: 'skip' | 'sample' | 'index';
+    /** Chunks kept from a generated file in 'sample' mode (default 2) */
+    generatedSampleChunks?: number;
     chunkSize?: number; // lines
     chunkOverlap?: number; // lines
     parseTests?: boolean;
     parseNodeModules?: boolean;
+    concurrency?: number; // files read and parsed at once
+    queueSize?: number; // parsed files buffered ahead of tracking (default 4x concurrency)
   };
 
   // Style guides
@@ -492,19 +746,73 @@ export interface CodebaseConfig {
 
   // Embedding
   embedding?: {
-    provider?: 'transformers' | 'openai' | 'ollama' | 'custom';
-    model?: string;
+    provider?:
+      | 'transformers'
+      | 'openai'
+      | 'azure-openai'
+      | 'voyage'
+      | 'cohere'
+      | 'ollama'
+      | 'custom';
+    model?: string; // for azure-openai, the deployment name
+    dimensions?: number; // shorter vectors where the model supports it
     batchSize?: number;
+    concurrency?: number; // batches in flight at once
+    maxRetries?: number; // per batch, for rate limits and transient errors
   };
 
   // Optimization flags
   skipEmbedding?: boolean;
 
+  // Secret redaction before embedding and retrieval (on by default)
+  redaction?: {
+    enabled?: boolean;
+    allowlist?: string[]; // regexes; matching values are kept
+  };
+
+  // Personal data and custom compliance rules, on top of secret redaction
+  // (also read from .codebase-context/config.json)
+  compliance?: {
+    excludePaths?: string[]; // .gitignore syntax; never indexed or returned
+    detectors?: string[]; // built-in: email, us-ssn, uk-nino, credit-card, iban
+    rules?: Array<{ name: string; pattern: string; flags?: string; action?: 'mask' | 'exclude' }>;
+    allowlist?: string[]; // regexes; matching values are kept
+    audit?: boolean; // log what was filtered to compliance-audit.jsonl (default: true)
+  };
+
+  // Chunk sizing, per language (also read from .codebase-context/config.json)
+  chunking?: ChunkingConfig;
+
+  // Transforms on each chunk's embedding input (also read from .codebase-context/config.json)
+  postProcessing?: {
+    processors?: string[]; // in order, for every language: built-in or rule names
+    languages?: Record<string, string[]>; // language id -> processors, replacing `processors`
+    rules?: Array<{ name: string; pattern: string; flags?: string; replace?: string }>;
+  };
+
+  // Chunk metadata enrichment (also read from .codebase-context/config.json)
+  enrichment?: {
+    codeowners?: boolean; // tag chunks with CODEOWNERS owners when the file exists (default)
+    modules?: Record<string, string>; // gitignore-style path pattern -> module tag
+    annotations?: Array<{ name: string; pattern: string; flags?: string }>; // regex per name
+  };
+
+  // Path allow/deny lists and read-only serving (also read from .codebase-context/config.json)
+  security?: {
+    allowPaths?: string[]; // repo-relative paths or globs; everything else is left out
+    denyPaths?: string[]; // .gitignore syntax, e.g. ".env*", "secrets/"
+    readOnly?: boolean; // the server serves an existing index and writes nothing
+  };
+
   // Storage
   storage?: {
-    provider?: 'lancedb' | 'milvus' | 'chromadb' | 'custom';
+    provider?: 'lancedb' | 'sqlite' | 'qdrant' | 'pgvector' | 'milvus' | 'chromadb' | 'custom';
     path?: string;
+    url?: string;
+    apiKey?: string;
+    collection?: string;
     connection?: Record<string, unknown>;
+    quantization?: 'none' | 'int8' | 'binary'; // in-process vectors (sqlite), set at creation
   };
 
   // Custom metadata
@@ -602,6 +910,16 @@ export interface UsageLocation {
   line: number;
 }
 
+/** A single-turn completion request for the MCP client's model (sampling) */
+export interface SamplingRequest {
+  systemPrompt: string;
+  prompt: string;
+  maxTokens: number;
+}
+
+/** Returns the model's text reply; throws when the client declines or fails */
+export type Sampler = (request: SamplingRequest) => Promise<string>;
+
 // ============================================================================
 // INTELLIGENCE / PATTERN DATA
 // ============================================================================
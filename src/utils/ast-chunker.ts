@@ -10,6 +10,7 @@
 import { v4 as uuidv4 } from 'uuid';
 import type { TreeSitterSymbol } from './tree-sitter.js';
 import type { CodeChunk, ChunkMetadata } from '../types/index.js';
+import { extractDocComment } from './doc-comments.js';
 
 // ---------------------------------------------------------------------------
 // Types
@@ -29,6 +30,17 @@ export interface ASTChunkOptions {
   framework?: string;
   componentType?: string;
   includeScopePrefix?: boolean;
+  /** Token ceiling per chunk (estimated); oversized symbols are split even under maxChunkLines */
+  maxChunkTokens?: number;
+  /** Lines repeated at the start of each continuation when an oversized chunk is split */
+  overlapLines?: number;
+}
+
+export interface SplitOptions {
+  maxTokens?: number;
+  overlapLines?: number;
+  /** Counts tokens against `maxTokens` (default: estimateTokens) */
+  countTokens?: (text: string) => number;
 }
 
 export const DEFAULT_AST_CHUNK_OPTIONS = {
@@ -36,6 +48,16 @@ export const DEFAULT_AST_CHUNK_OPTIONS = {
   maxChunkLines: 150
 } as const;
 
+/** Rough chars-per-token ratio for code with BPE tokenizers */
+const CHARS_PER_TOKEN = 4;
+
+/**
+ * Cheap token estimate used for chunk sizing (no tokenizer dependency).
+ */
+export function estimateTokens(text: string): number {
+  return Math.ceil(text.length / CHARS_PER_TOKEN);
+}
+
 // File ceiling constants: beyond these, AST chunking is skipped in favor of line chunks
 export const MAX_AST_CHUNK_FILE_SIZE = 500_000; // 500KB
 export const MAX_AST_CHUNK_FILE_LINES = 10_000; // 10K lines
@@ -78,6 +100,10 @@ function extractSignatureHint(sym: TreeSitterSymbol): string {
   if (kind === 'enum') return 'enum';
   if (kind === 'struct') return 'struct';
   if (kind === 'trait') return 'trait';
+  if (kind === 'protocol') return 'protocol';
+  if (kind === 'extension') return 'extension';
+  if (kind === 'impl') return 'impl';
+  if (kind === 'module') return 'module';
 
   // For functions/methods, try to extract parameter list and return type
   if (kind === 'function' || kind === 'method') {
@@ -222,6 +248,7 @@ function processNode(
   if (node.children.length === 0) {
     // Leaf symbol → single chunk
     const chunk = makeSymbolChunk(sym, lines, options, symbolPath, parentName);
+    attachDocComment(chunk, sym, lines, options);
     if (shouldPrefix) {
       const prefix = generateScopePrefix(node, ancestors);
       chunk.content = prefix + '\n' + chunk.content;
@@ -256,6 +283,7 @@ function processNode(
         parentName,
         true // use provided content
       );
+      attachDocComment(headerChunk, sym, lines, options);
       if (shouldPrefix) {
         const prefix = generateScopePrefix(node, ancestors);
         headerChunk.content = `${prefix}\n${headerChunk.content}`;
@@ -369,34 +397,54 @@ export function mergeSmallSymbolChunks(chunks: CodeChunk[], minLines: number): C
 // 4. splitOversizedChunks
 // ---------------------------------------------------------------------------
 
+/** Lines are counted over the source range (not the scope prefix); tokens over the content */
+function isOversized(chunk: CodeChunk, maxLines: number, options: SplitOptions): boolean {
+  if (chunk.endLine - chunk.startLine + 1 > maxLines) return true;
+  const { maxTokens, countTokens = estimateTokens } = options;
+  // A single line can't be split further, whatever its size
+  if (maxTokens === undefined || !chunk.content.includes('\n')) return false;
+  return countTokens(chunk.content) > maxTokens;
+}
+
 /**
- * Split any chunk exceeding `maxLines` at safe structural boundaries.
+ * Split any chunk exceeding `maxLines` (or `maxTokens`, when set) at safe structural
+ * boundaries. Chunks within bounds are never touched; only split continuations carry
+ * `overlapLines` of leading context from the previous piece.
  */
-export function splitOversizedChunks(chunks: CodeChunk[], maxLines: number): CodeChunk[] {
+export function splitOversizedChunks(
+  chunks: CodeChunk[],
+  maxLines: number,
+  options: SplitOptions = {}
+): CodeChunk[] {
   const result: CodeChunk[] = [];
   for (const chunk of chunks) {
-    const lineCount = chunk.endLine - chunk.startLine + 1;
-    if (lineCount <= maxLines) {
+    if (!isOversized(chunk, maxLines, options)) {
       result.push(chunk);
     } else {
-      result.push(...splitChunk(chunk, maxLines));
+      result.push(...splitChunk(chunk, maxLines, options));
     }
   }
   return result;
 }
 
-function splitChunk(chunk: CodeChunk, maxLines: number): CodeChunk[] {
+function splitChunk(chunk: CodeChunk, maxLines: number, options: SplitOptions): CodeChunk[] {
   const chunkLines = chunk.content.split('\n');
   const lineCount = chunkLines.length;
 
-  if (lineCount <= maxLines) return [chunk];
+  if (!isOversized(chunk, maxLines, options)) return [chunk];
 
   // Find safe split point near midpoint
   const mid = Math.floor(lineCount / 2);
   const splitIdx = findSafeSplitPoint(chunkLines, mid);
 
+  // Overlap must stay smaller than the first piece so every split makes progress
+  const overlap = Math.max(
+    0,
+    Math.min(options.overlapLines ?? 0, splitIdx - 1, Math.floor(lineCount / 4))
+  );
+
   const firstContent = chunkLines.slice(0, splitIdx).join('\n');
-  const secondContent = chunkLines.slice(splitIdx).join('\n');
+  const secondContent = chunkLines.slice(splitIdx - overlap).join('\n');
 
   const baseName = chunk.metadata?.symbolName || 'chunk';
   // Strip existing suffix like ":1" before adding new ones
@@ -417,17 +465,18 @@ function splitChunk(chunk: CodeChunk, maxLines: number): CodeChunk[] {
     ...chunk,
     id: uuidv4(),
     content: secondContent,
-    startLine: chunk.startLine + splitIdx,
+    startLine: chunk.startLine + splitIdx - overlap,
     metadata: {
       ...chunk.metadata,
-      symbolName: `${cleanName}:2`
+      symbolName: `${cleanName}:2`,
+      ...(overlap > 0 ? { overlapLines: overlap } : {})
     }
   };
 
   // Recursively split if still oversized
   const result: CodeChunk[] = [];
-  result.push(...splitOversizedChunks([firstChunk], maxLines));
-  result.push(...splitOversizedChunks([secondChunk], maxLines));
+  result.push(...splitOversizedChunks([firstChunk], maxLines, options));
+  result.push(...splitOversizedChunks([secondChunk], maxLines, options));
 
   // Renumber after recursive splits
   if (result.length > 2) {
@@ -499,7 +548,10 @@ export function createASTAlignedChunks(
 ): CodeChunk[] {
   const raw = generateASTChunks(content, symbols, options);
   const merged = mergeSmallSymbolChunks(raw, options.minChunkLines);
-  const final = splitOversizedChunks(merged, options.maxChunkLines);
+  const final = splitOversizedChunks(merged, options.maxChunkLines, {
+    maxTokens: options.maxChunkTokens,
+    overlapLines: options.overlapLines
+  });
   return final;
 }
 
@@ -539,6 +591,17 @@ function makeFillerChunk(
   };
 }
 
+/** The symbol's doc comment, on its leaf or header chunk */
+function attachDocComment(
+  chunk: CodeChunk,
+  sym: TreeSitterSymbol,
+  lines: string[],
+  options: ASTChunkOptions
+): void {
+  const doc = extractDocComment(lines, sym.startLine, sym.endLine, options.language);
+  if (doc) chunk.metadata.docComment = doc;
+}
+
 function makeSymbolChunk(
   sym: TreeSitterSymbol,
   lines: string[],
@@ -571,6 +634,8 @@ function makeSymbolChunk(
       symbolKind: sym.kind,
       symbolPath,
       parentSymbol: parentName ?? undefined,
+      qualifiedName: sym.qualifiedName,
+      namespace: sym.namespace,
       chunkStrategy: 'ast-aligned',
       componentName: sym.name
     } as ChunkMetadata
@@ -0,0 +1,586 @@
+/**
+ * Bazel and Buck build files: the targets they declare, with their sources and deps.
+ *
+ * BUILD / BUILD.bazel (Bazel) and BUCK / BUCK.v2 / TARGETS (Buck, Buck2) are Starlark. They are
+ * read with a small evaluator rather than executed: every top-level call with a literal `name`
+ * is a target (rules and macros alike), `glob()` is matched against the project's files,
+ * `select()` contributes every branch, and top-level list/string variables and `+` are
+ * followed. Anything computed (string formatting, comprehensions, macro internals) is skipped,
+ * so the graph can miss targets that a real `bazel query` would list.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import { glob } from 'glob';
+import { matchesGlob } from './git-tree.js';
+
+export type BuildSystem = 'bazel' | 'buck';
+
+export interface BuildTargetInfo {
+  /** Rule or macro name (`go_library`, `cc_test`, `python_binary`) */
+  kind: string;
+  /** Repo-relative posix source files from srcs/hdrs (globs resolved) */
+  files?: string[];
+  /** Labels this target depends on (`//pkg:name`, `@repo//pkg:name`); file labels included */
+  deps?: string[];
+}
+
+export interface BuildGraphData {
+  system: BuildSystem;
+  /** Canonical label (`//services/api:server`) -> target */
+  targets: Record<string, BuildTargetInfo>;
+  /** Buck cell name -> repo-relative directory, for `cell//pkg:name` labels */
+  cells?: Record<string, string>;
+}
+
+const BAZEL_MARKERS = ['MODULE.bazel', 'WORKSPACE', 'WORKSPACE.bazel', 'REPO.bazel'];
+const BUCK_MARKERS = ['.buckconfig', '.buckroot'];
+/** Preferred first: a directory with BUILD.bazel and BUILD uses BUILD.bazel */
+const BAZEL_BUILD_FILES = ['BUILD.bazel', 'BUILD'];
+const BUCK_BUILD_FILES = ['BUCK.v2', 'BUCK', 'TARGETS'];
+const SCAN_IGNORE = ['**/node_modules/**', '**/.git/**', 'bazel-*/**', 'buck-out/**'];
+
+const FILE_ATTRS = ['srcs', 'hdrs', 'textual_hdrs', 'src', 'headers', 'exported_headers'];
+const DEP_ATTRS = [
+  'deps',
+  'runtime_deps',
+  'implementation_deps',
+  'exports',
+  'exported_deps',
+  'embed',
+  'data',
+  'tests',
+  'actual'
+];
+/** Calls that take a `name` but don't declare a buildable target */
+const NOT_TARGETS = new Set(['package_group', 'module', 'bazel_dep', 'workspace', 'load']);
+
+// ---------------------------------------------------------------------------
+// Tokens
+// ---------------------------------------------------------------------------
+
+interface Token {
+  type: 'str' | 'ident' | 'num' | 'op';
+  value: string;
+  /** Column of the token's first character; 0 starts a top-level statement */
+  col: number;
+}
+
+const TWO_CHAR_OPS = new Set(['==', '!=', '<=', '>=', '**', '+=', '-=', '//']);
+
+function tokenize(source: string): Token[] {
+  const tokens: Token[] = [];
+  let lineStart = 0;
+  let i = 0;
+  while (i < source.length) {
+    const ch = source[i];
+    if (ch === '\n') {
+      lineStart = ++i;
+      continue;
+    }
+    if (ch === ' ' || ch === '\t' || ch === '\r' || ch === '\\') {
+      i++;
+      continue;
+    }
+    if (ch === '#') {
+      while (i < source.length && source[i] !== '\n') i++;
+      continue;
+    }
+    const col = i - lineStart;
+    const prefixed = /[rRbB]/.test(ch) && (source[i + 1] === '"' || source[i + 1] === "'");
+    if (ch === '"' || ch === "'" || prefixed) {
+      const start = prefixed ? i + 1 : i;
+      const quote = source[start];
+      const triple = source.startsWith(quote.repeat(3), start);
+      const delimiter = triple ? quote.repeat(3) : quote;
+      let j = start + delimiter.length;
+      let value = '';
+      while (j < source.length && !source.startsWith(delimiter, j)) {
+        if (source[j] === '\\' && j + 1 < source.length) {
+          value += source[j + 1];
+          j += 2;
+          continue;
+        }
+        if (source[j] === '\n') {
+          if (!triple) break;
+          lineStart = j + 1;
+        }
+        value += source[j++];
+      }
+      tokens.push({ type: 'str', value, col });
+      i = j + delimiter.length;
+      continue;
+    }
+    const ident = /^[A-Za-z_]\w*/.exec(source.slice(i, i + 200));
+    if (ident) {
+      tokens.push({ type: 'ident', value: ident[0], col });
+      i += ident[0].length;
+      continue;
+    }
+    const num = /^\d[\w.]*/.exec(source.slice(i, i + 50));
+    if (num) {
+      tokens.push({ type: 'num', value: num[0], col });
+      i += num[0].length;
+      continue;
+    }
+    const two = source.slice(i, i + 2);
+    const op = TWO_CHAR_OPS.has(two) ? two : ch;
+    tokens.push({ type: 'op', value: op, col });
+    i += op.length;
+  }
+  return tokens;
+}
+
+// ---------------------------------------------------------------------------
+// Evaluation
+// ---------------------------------------------------------------------------
+
+/** What an attribute value evaluates to: strings and unresolved globs */
+export type AttrItem =
+  | { type: 'str'; value: string }
+  | { type: 'glob'; include: string[]; exclude: string[] };
+/** A string (so `+` concatenates) or a list (so `+` appends) */
+type Value = string | AttrItem[];
+
+function toItems(value: Value | null): AttrItem[] {
+  if (value === null) return [];
+  return typeof value === 'string' ? [{ type: 'str', value }] : value;
+}
+
+interface CallArgs {
+  positional: AttrItem[][];
+  keyword: Map<string, AttrItem[]>;
+}
+
+export interface DeclaredTarget {
+  name: string;
+  kind: string;
+  attrs: Map<string, AttrItem[]>;
+}
+
+const CLOSERS: Record<string, string> = { '(': ')', '[': ']', '{': '}' };
+
+class BuildFileParser {
+  private pos = 0;
+  private readonly vars = new Map<string, Value>();
+
+  constructor(private readonly tokens: Token[]) {}
+
+  parse(): DeclaredTarget[] {
+    const targets: DeclaredTarget[] = [];
+    while (this.pos < this.tokens.length) {
+      const token = this.tokens[this.pos];
+      if (token.type === 'ident' && token.value === 'def' && token.col === 0) {
+        this.skipBlock();
+        continue;
+      }
+      if (token.type === 'ident' && this.peek(1)?.value === '=' && token.col === 0) {
+        this.pos += 2;
+        this.vars.set(token.value, this.parseExpr() ?? []);
+        continue;
+      }
+      const callee = this.calleeAt(this.pos);
+      if (callee) {
+        this.pos = callee.argsStart;
+        const args = this.parseArgs();
+        const name = args.keyword.get('name')?.[0];
+        if (name?.type === 'str' && !NOT_TARGETS.has(callee.kind)) {
+          targets.push({ name: name.value, kind: callee.kind, attrs: args.keyword });
+        }
+        continue;
+      }
+      this.skipToken();
+    }
+    return targets;
+  }
+
+  private peek(offset = 0): Token | undefined {
+    return this.tokens[this.pos + offset];
+  }
+
+  /** `name(` or `native.name(` at `index`: the kind and where the arguments start */
+  private calleeAt(index: number): { kind: string; argsStart: number } | null {
+    let i = index;
+    if (this.tokens[i]?.type !== 'ident') return null;
+    let kind = this.tokens[i].value;
+    while (this.tokens[i + 1]?.value === '.' && this.tokens[i + 2]?.type === 'ident') {
+      i += 2;
+      kind = this.tokens[i].value;
+    }
+    return this.tokens[i + 1]?.value === '(' ? { kind, argsStart: i + 2 } : null;
+  }
+
+  /** Skip a token, and everything up to its closer when it opens a bracket */
+  private skipToken(): void {
+    const opener = this.tokens[this.pos++];
+    const closer = opener && opener.type === 'op' ? CLOSERS[opener.value] : undefined;
+    if (!closer) return;
+    while (this.pos < this.tokens.length && this.peek()?.value !== closer) this.skipToken();
+    this.pos++;
+  }
+
+  /** A `def` body: everything up to the next statement that starts at column 0 */
+  private skipBlock(): void {
+    this.pos++;
+    while (this.pos < this.tokens.length && this.peek()?.col !== 0) this.skipToken();
+  }
+
+  private expect(value: string): boolean {
+    if (this.peek()?.value !== value) return false;
+    this.pos++;
+    return true;
+  }
+
+  /** Arguments up to and including the closing `)` */
+  private parseArgs(): CallArgs {
+    const args: CallArgs = { positional: [], keyword: new Map() };
+    while (this.pos < this.tokens.length && !this.expect(')')) {
+      if (this.expect(',')) continue;
+      if (this.peek()?.value === '*' || this.peek()?.value === '**') this.pos++;
+      const token = this.peek();
+      const keyword = token?.type === 'ident' && this.peek(1)?.value === '=' ? token.value : null;
+      if (keyword) this.pos += 2;
+      const start = this.pos;
+      const value = toItems(this.parseExpr());
+      if (this.pos === start) this.skipToken();
+      if (keyword) args.keyword.set(keyword, value);
+      else args.positional.push(value);
+    }
+    return args;
+  }
+
+  /** `a + b`, `a if cond else b`; null when the value isn't strings or globs */
+  private parseExpr(): Value | null {
+    let value = this.parseTerm();
+    for (;;) {
+      const op = this.peek();
+      if (this.expect('+')) {
+        const right = this.parseTerm();
+        if (typeof value === 'string' && typeof right === 'string') value = value + right;
+        else value = value || right ? [...toItems(value), ...toItems(right)] : null;
+      } else if (op?.type === 'ident' && op.value === 'if') {
+        this.pos++;
+        this.parseExpr();
+        const otherwise = this.expect('else') ? this.parseExpr() : null;
+        value = value || otherwise ? [...toItems(value), ...toItems(otherwise)] : null;
+      } else if (op?.type === 'op' && ['%', '*', '-', '==', '!='].includes(op.value)) {
+        // `"%s_lib" % name` and friends compute a value we can't follow
+        this.pos++;
+        this.parseTerm();
+        value = null;
+      } else {
+        return value;
+      }
+    }
+  }
+
+  private parseTerm(): Value | null {
+    const token = this.peek();
+    if (!token) return null;
+    let value: Value | null = null;
+    if (token.type === 'str') {
+      this.pos++;
+      value = token.value;
+      // Adjacent literals concatenate
+      while (this.peek()?.type === 'str') value += this.tokens[this.pos++].value;
+    } else if (token.value === '[' || token.value === '(') {
+      this.pos++;
+      value = this.parseSequence(CLOSERS[token.value]);
+    } else if (token.value === '{') {
+      this.pos++;
+      value = this.parseDict();
+    } else if (token.type === 'ident') {
+      const callee = this.calleeAt(this.pos);
+      if (callee) {
+        this.pos = callee.argsStart;
+        value = this.evaluateCall(callee.kind, this.parseArgs());
+      } else {
+        this.pos++;
+        value = this.vars.get(token.value) ?? null;
+      }
+    } else if (token.value === '-' || token.value === 'not') {
+      this.pos++;
+      this.parseTerm();
+    } else if (token.type === 'num') {
+      this.pos++;
+    } else {
+      return null;
+    }
+    // Indexing, slicing and method calls (`"x".format(...)`) compute something else
+    while (this.peek()?.value === '[' || this.peek()?.value === '.') {
+      if (this.peek()?.value === '[') {
+        this.skipToken();
+      } else {
+        this.pos += 2;
+        if (this.peek()?.value === '(') this.skipToken();
+      }
+      value = null;
+    }
+    return value;
+  }
+
+  /** List or tuple items up to `closer`; a comprehension evaluates to nothing */
+  private parseSequence(closer: string): AttrItem[] {
+    const items: AttrItem[] = [];
+    while (this.pos < this.tokens.length && !this.expect(closer)) {
+      if (this.expect(',')) continue;
+      if (this.peek()?.value === 'for') {
+        while (this.pos < this.tokens.length && this.peek()?.value !== closer) this.skipToken();
+        this.pos++;
+        return [];
+      }
+      const start = this.pos;
+      items.push(...toItems(this.parseExpr()));
+      if (this.pos === start) this.skipToken();
+    }
+    return items;
+  }
+
+  /** A dict's values, merged: `select({...})` depends on every branch */
+  private parseDict(): AttrItem[] {
+    const items: AttrItem[] = [];
+    while (this.pos < this.tokens.length && !this.expect('}')) {
+      if (this.expect(',')) continue;
+      const start = this.pos;
+      this.parseExpr();
+      if (this.expect(':')) items.push(...toItems(this.parseExpr()));
+      if (this.pos === start) this.skipToken();
+    }
+    return items;
+  }
+
+  private evaluateCall(kind: string, args: CallArgs): AttrItem[] | null {
+    if (kind === 'glob') {
+      const strings = (items: AttrItem[] | undefined) =>
+        (items ?? []).flatMap((item) => (item.type === 'str' ? [item.value] : []));
+      return [
+        {
+          type: 'glob',
+          include: strings(args.positional[0] ?? args.keyword.get('include')),
+          exclude: strings(args.positional[1] ?? args.keyword.get('exclude'))
+        }
+      ];
+    }
+    if (kind === 'select') return args.positional[0] ?? null;
+    return null;
+  }
+}
+
+/** Targets declared in one build file, before labels and globs are resolved */
+export function parseBuildFile(content: string): DeclaredTarget[] {
+  return new BuildFileParser(tokenize(content)).parse();
+}
+
+// ---------------------------------------------------------------------------
+// Labels
+// ---------------------------------------------------------------------------
+
+/**
+ * Canonical form of `raw` as written in package `pkg`: `//pkg:name` for this repo, the label
+ * as written for external repos (`@maven//:guava`) and unknown Buck cells. Relative labels
+ * (`:lib`, `lib`) resolve against `pkg`; `//a/b` is `//a/b:b`.
+ */
+export function normalizeLabel(
+  raw: string,
+  pkg: string,
+  cells: Record<string, string> = {}
+): string {
+  let label = raw.trim().replace(/^@@?(?=\/\/)/, '');
+  const cell = /^(\w[\w.-]*)\/\/([^:]*)(:.*)?$/.exec(label);
+  if (cell) {
+    const dir = cells[cell[1]];
+    if (dir === undefined) return label;
+    const joined = path.posix.join(dir, cell[2]).replace(/\/+$/, '');
+    label = `//${joined === '.' ? '' : joined}${cell[3] ?? ''}`;
+  }
+  if (label.startsWith('@')) return label;
+  if (!label.startsWith('//')) return `//${pkg}:${label.replace(/^:/, '')}`;
+  const colon = label.indexOf(':');
+  if (colon >= 0) return label;
+  const dir = label.slice(2).replace(/\/+$/, '');
+  return `//${dir}:${path.posix.basename(dir)}`;
+}
+
+/** `//pkg:name` -> `pkg` (empty for the root package); null for external labels */
+export function labelPackage(label: string): string | null {
+  if (!label.startsWith('//')) return null;
+  const colon = label.indexOf(':');
+  return label.slice(2, colon >= 0 ? colon : undefined);
+}
+
+/** `//pkg:name` -> `name` */
+export function labelName(label: string): string {
+  return label.slice(label.indexOf(':') + 1);
+}
+
+// ---------------------------------------------------------------------------
+// Detection
+// ---------------------------------------------------------------------------
+
+async function exists(file: string): Promise<boolean> {
+  return fs.access(file).then(
+    () => true,
+    () => false
+  );
+}
+
+/** The `[cells]` (or Buck2 `[repositories]`) and `[buildfile] name` of a `.buckconfig` */
+export function parseBuckConfig(content: string): {
+  cells: Record<string, string>;
+  buildFile?: string;
+} {
+  const cells: Record<string, string> = {};
+  let buildFile: string | undefined;
+  let section = '';
+  for (const raw of content.split('\n')) {
+    const line = raw.replace(/[#;].*$/, '').trim();
+    const header = /^\[([^\]]+)\]$/.exec(line);
+    if (header) {
+      section = header[1].trim();
+      continue;
+    }
+    const entry = /^([\w.-]+)\s*=\s*(.+)$/.exec(line);
+    if (!entry) continue;
+    const [, key, value] = entry;
+    if (section === 'cells' || section === 'repositories') {
+      cells[key] = path.posix.normalize(value.trim().replace(/\\/g, '/')).replace(/\/$/, '');
+    } else if (section === 'buildfile' && key === 'name') {
+      buildFile = value.trim();
+    }
+  }
+  return { cells, ...(buildFile ? { buildFile } : {}) };
+}
+
+/** Deepest package directory containing `file`, from the set of package directories */
+function owningPackage(file: string, packages: Set<string>): string | null {
+  let dir = path.posix.dirname(file);
+  for (;;) {
+    const key = dir === '.' ? '' : dir;
+    if (packages.has(key)) return key;
+    if (key === '') return null;
+    dir = path.posix.dirname(dir);
+  }
+}
+
+function resolveTargets(
+  declared: Array<{ pkg: string; target: DeclaredTarget }>,
+  knownFiles: readonly string[],
+  cells: Record<string, string>
+): Record<string, BuildTargetInfo> {
+  const packages = new Set(declared.map((entry) => entry.pkg));
+  const filesByPackage = new Map<string, string[]>();
+  for (const file of knownFiles) {
+    const pkg = owningPackage(file, packages);
+    if (pkg === null) continue;
+    const list = filesByPackage.get(pkg) ?? [];
+    list.push(file);
+    filesByPackage.set(pkg, list);
+  }
+  const known = new Set(knownFiles);
+  const labels = new Set(declared.map(({ pkg, target }) => `//${pkg}:${target.name}`));
+
+  const targets: Record<string, BuildTargetInfo> = {};
+  for (const { pkg, target } of declared) {
+    const files = new Set<string>();
+    const deps = new Set<string>();
+    const packageFiles = filesByPackage.get(pkg) ?? [];
+    const inPackage = (file: string) => (pkg ? file.slice(pkg.length + 1) : file);
+
+    for (const attr of FILE_ATTRS) {
+      for (const item of target.attrs.get(attr) ?? []) {
+        if (item.type === 'glob') {
+          for (const file of packageFiles) {
+            const relative = inPackage(file);
+            if (
+              item.include.some((pattern) => matchesGlob(relative, pattern)) &&
+              !item.exclude.some((pattern) => matchesGlob(relative, pattern))
+            ) {
+              files.add(file);
+            }
+          }
+          continue;
+        }
+        const label = normalizeLabel(item.value, pkg, cells);
+        const filePath = labelPackage(label) !== null ? labelFile(label) : null;
+        if (labels.has(label)) deps.add(label);
+        else if (filePath && known.has(filePath)) files.add(filePath);
+      }
+    }
+    for (const attr of DEP_ATTRS) {
+      for (const item of target.attrs.get(attr) ?? []) {
+        if (item.type === 'str') deps.add(normalizeLabel(item.value, pkg, cells));
+      }
+    }
+    const label = `//${pkg}:${target.name}`;
+    deps.delete(label);
+    targets[label] = {
+      kind: target.kind,
+      ...(files.size > 0 ? { files: [...files].sort() } : {}),
+      ...(deps.size > 0 ? { deps: [...deps].sort() } : {})
+    };
+  }
+  return targets;
+}
+
+/** `//pkg:sub/file.go` -> `pkg/sub/file.go` */
+export function labelFile(label: string): string | null {
+  const pkg = labelPackage(label);
+  return pkg === null ? null : path.posix.join(pkg, labelName(label));
+}
+
+/**
+ * The Bazel or Buck build graph of the project at `rootPath`, or null when it uses neither.
+ * `knownFiles` are the repo-relative posix paths globs and srcs are matched against.
+ */
+export async function detectBuildGraph(
+  rootPath: string,
+  knownFiles: readonly string[]
+): Promise<BuildGraphData | null> {
+  let system: BuildSystem;
+  let buildFileNames: string[];
+  let cells: Record<string, string> = {};
+  const markerIn = async (markers: string[]) =>
+    (await Promise.all(markers.map((m) => exists(path.join(rootPath, m))))).some(Boolean);
+
+  if (await markerIn(BAZEL_MARKERS)) {
+    system = 'bazel';
+    buildFileNames = BAZEL_BUILD_FILES;
+  } else if (await markerIn(BUCK_MARKERS)) {
+    system = 'buck';
+    const config = parseBuckConfig(
+      await fs.readFile(path.join(rootPath, '.buckconfig'), 'utf-8').catch(() => '')
+    );
+    cells = config.cells;
+    buildFileNames = config.buildFile ? [config.buildFile] : BUCK_BUILD_FILES;
+  } else {
+    return null;
+  }
+
+  const found = await glob(
+    buildFileNames.map((name) => `**/${name}`),
+    { cwd: rootPath, nodir: true, posix: true, ignore: SCAN_IGNORE }
+  );
+  // One build file per directory, the preferred name first
+  const byDirectory = new Map<string, string>();
+  for (const file of found.sort(
+    (a, b) =>
+      buildFileNames.indexOf(path.posix.basename(a)) -
+      buildFileNames.indexOf(path.posix.basename(b))
+  )) {
+    const dir = path.posix.dirname(file);
+    if (!byDirectory.has(dir)) byDirectory.set(dir, file);
+  }
+
+  const declared: Array<{ pkg: string; target: DeclaredTarget }> = [];
+  for (const [dir, file] of byDirectory) {
+    const content = await fs.readFile(path.join(rootPath, file), 'utf-8').catch(() => '');
+    const pkg = dir === '.' ? '' : dir;
+    for (const target of parseBuildFile(content)) declared.push({ pkg, target });
+  }
+  if (declared.length === 0) return null;
+  return {
+    system,
+    targets: resolveTargets(declared, knownFiles, cells),
+    ...(Object.keys(cells).length > 0 ? { cells } : {})
+  };
+}
@@ -258,7 +258,8 @@ function generateTags(component: CodeComponent, metadata?: Record<string, any>):
 }
 
 /**
- * Merge adjacent chunks if they're small
+ * Merge adjacent chunks if they're small. Symbol chunks and markdown sections keep their
+ * boundaries.
  */
 export function mergeSmallChunks(chunks: CodeChunk[], minSize: number = 20): CodeChunk[] {
   if (chunks.length <= 1) return chunks;
@@ -270,8 +271,10 @@ export function mergeSmallChunks(chunks: CodeChunk[], minSize: number = 20): Cod
     const next = chunks[i];
     const currentLines = current.content.split('\n').length;
     const nextLines = next.content.split('\n').length;
-    const currentIsSymbolAware = current.metadata?.symbolAware === true;
-    const nextIsSymbolAware = next.metadata?.symbolAware === true;
+    const currentIsSymbolAware =
+      current.metadata?.symbolAware === true || current.metadata?.headingPath !== undefined;
+    const nextIsSymbolAware =
+      next.metadata?.symbolAware === true || next.metadata?.headingPath !== undefined;
 
     if (
       !currentIsSymbolAware &&
@@ -0,0 +1,168 @@
+/**
+ * Doc comments attached to symbols: JSDoc/KDoc/Javadoc blocks, GoDoc and `///` line comments
+ * directly above a declaration, `#` comments in Ruby and shell, Python docstrings, and Elixir
+ * `@doc`/`@moduledoc` attributes.
+ *
+ * Extracted per symbol at chunk time (`metadata.docComment`) and read live by
+ * `get_symbol_docs`. Text is kept as written, minus comment markers, so `@param`/`@returns`
+ * tags and paragraphs survive.
+ */
+
+/** Longer docs are cut; the source is a `get_definition` away */
+export const MAX_DOC_COMMENT_LENGTH = 1200;
+
+const HASH_COMMENT_LANGUAGES = new Set(['python', 'ruby', 'shellscript', 'powershell', 'elixir']);
+const LINE_DOC_LANGUAGES = new Set(['go', 'c', 'cpp']);
+
+// Decorators, annotations and attributes between a doc comment and its declaration
+const ATTRIBUTE_LINE = /^\s*(@[\w.]+|#\[.*\]\s*$|\[[\w.]+(\(.*\))?\]\s*$)/;
+
+function cleanBlock(lines: string[]): string | undefined {
+  const cleaned = lines.map((line) =>
+    line
+      .trim()
+      .replace(/^(\/\*\*?|\*\/|\*(?!\/)|\/\/[/!]?|#+|[rubRUB]?"""|[rubRUB]?''')\s?/, '')
+      .replace(/\s*(\*\/|"""|''')$/, '')
+      .trimEnd()
+  );
+  while (cleaned.length > 0 && !cleaned[0].trim()) cleaned.shift();
+  while (cleaned.length > 0 && !cleaned[cleaned.length - 1].trim()) cleaned.pop();
+  const text = cleaned.join('\n').replace(/\n{3,}/g, '\n\n');
+  if (!text) return undefined;
+  return text.length > MAX_DOC_COMMENT_LENGTH
+    ? `${text.slice(0, MAX_DOC_COMMENT_LENGTH - 1)}…`
+    : text;
+}
+
+/** The comment block ending right above 0-based `index` (decorators skipped) */
+function commentAbove(lines: string[], index: number, language: string): string[] {
+  let i = index - 1;
+  while (i >= 0 && ATTRIBUTE_LINE.test(lines[i])) i--;
+  const last = lines[i]?.trim() ?? '';
+
+  if (last.endsWith('*/')) {
+    const block: string[] = [];
+    for (; i >= 0; i--) {
+      block.unshift(lines[i]);
+      const trimmed = lines[i].trim();
+      if (trimmed.startsWith('/*')) {
+        // Plain /* */ blocks are doc comments only where the language has no doc syntax
+        return trimmed.startsWith('/**') || LINE_DOC_LANGUAGES.has(language) ? block : [];
+      }
+    }
+    return [];
+  }
+
+  const marker = HASH_COMMENT_LANGUAGES.has(language)
+    ? /^#(?!!)/
+    : LINE_DOC_LANGUAGES.has(language)
+      ? /^\/\//
+      : /^\/\/\//;
+  const block: string[] = [];
+  for (; i >= 0 && marker.test(lines[i].trim()); i--) block.unshift(lines[i]);
+  return block;
+}
+
+/** The docstring opening the body of the definition starting at 0-based `index` */
+function pythonDocstring(lines: string[], index: number, end: number): string[] {
+  let i = index;
+  // Skip the (possibly multi-line) signature up to its closing colon
+  while (i < end && !/:\s*(#.*)?$/.test(lines[i])) i++;
+  i++;
+  while (i < end && !lines[i].trim()) i++;
+  const first = lines[i]?.trim() ?? '';
+  const quote = first.match(/^[rubRUB]?("""|''')/)?.[1];
+  if (!quote) return [];
+
+  const block = [lines[i]];
+  const rest = first.slice(first.indexOf(quote) + 3);
+  if (rest.includes(quote)) return block;
+  for (let j = i + 1; j < end; j++) {
+    block.push(lines[j]);
+    if (lines[j].includes(quote)) return block;
+  }
+  return [];
+}
+
+/** The string of the `@doc`/`@moduledoc` attribute on 0-based `index`, without delimiters */
+function elixirDocAttribute(lines: string[], index: number): string[] {
+  const match = lines[index]?.match(/^\s*@(?:module)?doc\s+(?:~[sS])?("""|")(.*)$/);
+  if (!match) return [];
+  if (match[1] === '"') return [match[2].replace(/"\s*$/, '')];
+
+  const block = [match[2]];
+  for (let i = index + 1; i < lines.length; i++) {
+    if (lines[i].trim().startsWith('"""')) return block;
+    block.push(lines[i]);
+  }
+  return [];
+}
+
+/** `@moduledoc` opening a `defmodule`, or the `@doc` above a `def` (past `@spec`/`@impl`) */
+function elixirDoc(lines: string[], index: number, language: string): string[] {
+  if (/^\s*defmodule\b/.test(lines[index])) {
+    let i = index + 1;
+    while (i < lines.length && !lines[i].trim()) i++;
+    return elixirDocAttribute(lines, i);
+  }
+
+  let i = index - 1;
+  while (i >= 0 && /^\s*@(?!doc\b)\w+/.test(lines[i])) i--;
+  const last = lines[i]?.trim() ?? '';
+  if (/^@doc\b/.test(last)) return elixirDocAttribute(lines, i);
+  if (last.endsWith('"""')) {
+    for (let j = i - 1; j >= 0; j--) {
+      if (/^\s*@doc\b/.test(lines[j])) return elixirDocAttribute(lines, j);
+      if (lines[j].trim().endsWith('"""')) break;
+    }
+    return [];
+  }
+  return commentAbove(lines, index, language);
+}
+
+/**
+ * Doc comment of the symbol declared on 1-based `startLine`, or undefined when it has none.
+ * A comment that the symbol's own range starts with counts too.
+ */
+export function extractDocComment(
+  lines: string[],
+  startLine: number,
+  endLine: number,
+  language: string
+): string | undefined {
+  const index = startLine - 1;
+  if (index < 0 || index >= lines.length) return undefined;
+
+  if (language === 'python') {
+    const docstring = pythonDocstring(lines, index, Math.min(endLine, lines.length));
+    if (docstring.length > 0) return cleanBlock(docstring);
+    return cleanBlock(commentAbove(lines, index, language));
+  }
+
+  if (language === 'elixir') return cleanBlock(elixirDoc(lines, index, language));
+
+  // Some grammars start the symbol at its leading comment
+  const first = lines[index].trim();
+  if (first.startsWith('/**') || first.startsWith('///')) {
+    let declaration = index;
+    while (declaration < endLine - 1 && /^(\/\*|\*|\/\/)/.test(lines[declaration].trim())) {
+      declaration++;
+    }
+    while (declaration < endLine - 1 && ATTRIBUTE_LINE.test(lines[declaration])) declaration++;
+    return cleanBlock(commentAbove(lines, declaration, language));
+  }
+
+  return cleanBlock(commentAbove(lines, index, language));
+}
+
+/** First sentence of a doc comment, for result listings */
+export function docSummary(doc: string, maxLength = 160): string {
+  const prose = doc
+    .split('\n')
+    .filter((line) => !line.trim().startsWith('@'))
+    .join(' ')
+    .replace(/\s+/g, ' ')
+    .trim();
+  const sentence = prose.match(/^(.+?[.!?])(\s|$)/)?.[1] ?? prose;
+  return sentence.length > maxLength ? `${sentence.slice(0, maxLength - 1)}…` : sentence;
+}
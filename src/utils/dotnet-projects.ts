@@ -0,0 +1,126 @@
+/**
+ * .NET solution awareness: maps source files to the `.csproj` that owns them, so chunks can
+ * carry a project name and searches can be scoped to one project of a solution.
+ *
+ * A project owns every file under its directory unless a nested project claims it, which
+ * matches SDK-style default globbing.
+ */
+
+import { promises as fs } from 'fs';
+import path from 'path';
+import { glob } from 'glob';
+
+export interface DotnetProject {
+  /** Project name: the solution entry name, else the `.csproj` file name */
+  name: string;
+  /** Repo-relative posix path of the `.csproj` */
+  projectFile: string;
+  /** Repo-relative posix directory the project owns ('' for the root) */
+  directory: string;
+  rootNamespace?: string;
+  assemblyName?: string;
+  /** Repo-relative `.sln`/`.slnx` files that reference this project */
+  solutions: string[];
+}
+
+export interface SolutionProjectEntry {
+  name: string;
+  /** Project path as written in the solution, relative to the solution file */
+  projectPath: string;
+}
+
+// Project("{FAE04EC0-...}") = "Acme.Api", "src\Acme.Api\Acme.Api.csproj", "{GUID}"
+const SLN_PROJECT_LINE = /^Project\("\{[^}]+\}"\)\s*=\s*"([^"]+)",\s*"([^"]+)"/gm;
+const SLNX_PROJECT_ELEMENT = /<Project\s+[^>]*Path="([^"]+)"/g;
+
+/** Project entries of a `.sln` (or XML `.slnx`) file; solution folders are skipped. */
+export function parseSolutionProjects(content: string): SolutionProjectEntry[] {
+  const entries: SolutionProjectEntry[] = [];
+  for (const match of content.matchAll(SLN_PROJECT_LINE)) {
+    if (!match[2].toLowerCase().endsWith('.csproj')) continue;
+    entries.push({ name: match[1], projectPath: match[2].replace(/\\/g, '/') });
+  }
+  for (const match of content.matchAll(SLNX_PROJECT_ELEMENT)) {
+    const projectPath = match[1].replace(/\\/g, '/');
+    if (!projectPath.toLowerCase().endsWith('.csproj')) continue;
+    entries.push({ name: path.posix.basename(projectPath, '.csproj'), projectPath });
+  }
+  return entries;
+}
+
+function readProperty(content: string, property: string): string | undefined {
+  const match = new RegExp(`<${property}>\\s*([^<]+?)\\s*</${property}>`).exec(content);
+  return match?.[1];
+}
+
+export function parseCsproj(content: string): { rootNamespace?: string; assemblyName?: string } {
+  return {
+    rootNamespace: readProperty(content, 'RootNamespace'),
+    assemblyName: readProperty(content, 'AssemblyName')
+  };
+}
+
+const DOTNET_SCAN_IGNORE = ['**/node_modules/**', '**/bin/**', '**/obj/**', '**/.git/**'];
+
+/**
+ * Find `.csproj` files under `rootPath` and attach the solutions that reference them.
+ * Returns an empty list for non-.NET repositories.
+ */
+export async function detectDotnetProjects(rootPath: string): Promise<DotnetProject[]> {
+  const options = { cwd: rootPath, ignore: DOTNET_SCAN_IGNORE, nodir: true, posix: true };
+  const [projectFiles, solutionFiles] = await Promise.all([
+    glob('**/*.csproj', options),
+    glob('**/*.{sln,slnx}', options)
+  ]);
+  if (projectFiles.length === 0) return [];
+
+  const projects = new Map<string, DotnetProject>();
+  for (const projectFile of projectFiles.sort()) {
+    let properties: ReturnType<typeof parseCsproj> = {};
+    try {
+      properties = parseCsproj(await fs.readFile(path.join(rootPath, projectFile), 'utf-8'));
+    } catch {
+      // Unreadable project file: still usable for ownership
+    }
+    const directory = path.posix.dirname(projectFile);
+    projects.set(projectFile, {
+      name: path.posix.basename(projectFile, '.csproj'),
+      projectFile,
+      directory: directory === '.' ? '' : directory,
+      ...properties,
+      solutions: []
+    });
+  }
+
+  for (const solutionFile of solutionFiles.sort()) {
+    let content: string;
+    try {
+      content = await fs.readFile(path.join(rootPath, solutionFile), 'utf-8');
+    } catch {
+      continue;
+    }
+    const solutionDir = path.posix.dirname(solutionFile);
+    for (const entry of parseSolutionProjects(content)) {
+      const projectFile = path.posix.normalize(path.posix.join(solutionDir, entry.projectPath));
+      const project = projects.get(projectFile);
+      if (!project) continue;
+      project.name = entry.name;
+      if (!project.solutions.includes(solutionFile)) project.solutions.push(solutionFile);
+    }
+  }
+
+  return [...projects.values()];
+}
+
+/** The innermost project whose directory contains `relativePath` (posix, repo-relative). */
+export function findOwningProject(
+  projects: readonly DotnetProject[],
+  relativePath: string
+): DotnetProject | null {
+  let best: DotnetProject | null = null;
+  for (const project of projects) {
+    const owns = project.directory === '' || relativePath.startsWith(`${project.directory}/`);
+    if (owns && (!best || project.directory.length > best.directory.length)) best = project;
+  }
+  return best;
+}
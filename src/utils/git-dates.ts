@@ -1,13 +1,25 @@
 /**
  * Git Date Utility
- * Extracts file commit dates from git history for pattern momentum analysis
+ * Extracts file commit dates and recent commit counts from git history for pattern momentum
+ * analysis and recency/churn ranking
  */
 
 import { exec } from 'child_process';
 import { promisify } from 'util';
 
 const execAsync = promisify(exec);
-const commitDateCache = new Map<string, Map<string, Date>>();
+
+/** Commits within this many days of indexing count as recent edits (churn) */
+export const CHURN_WINDOW_DAYS = 90;
+
+interface FileHistory {
+  /** Last commit date per file */
+  dates: Map<string, Date>;
+  /** Commits touching the file in the last CHURN_WINDOW_DAYS */
+  recentCommits: Map<string, number>;
+}
+
+const commitDateCache = new Map<string, FileHistory>();
 
 function normalizeRootPath(rootPath: string): string {
   return rootPath.replace(/\\/g, '/').toLowerCase();
@@ -30,16 +42,30 @@ export function clearFileCommitDatesCache(rootPath?: string): void {
  * @returns Map of relative file paths to their last commit date
  */
 export async function getFileCommitDates(rootPath: string): Promise<Map<string, Date>> {
+  return new Map((await loadFileHistory(rootPath)).dates);
+}
+
+/**
+ * Number of commits that touched each file in the last CHURN_WINDOW_DAYS, from the same
+ * git log as `getFileCommitDates`. Files without recent commits are absent.
+ */
+export async function getRecentCommitCounts(rootPath: string): Promise<Map<string, number>> {
+  return new Map((await loadFileHistory(rootPath)).recentCommits);
+}
+
+async function loadFileHistory(rootPath: string): Promise<FileHistory> {
   const cacheKey = normalizeRootPath(rootPath);
   const cached = commitDateCache.get(cacheKey);
   if (cached) {
     if (process.env.CODEBASE_CONTEXT_DEBUG) {
       console.error(`[git-dates] Cache hit for ${cacheKey}`);
     }
-    return new Map(cached);
+    return cached;
   }
 
   const fileDates = new Map<string, Date>();
+  const recentCommits = new Map<string, number>();
+  const churnSince = Date.now() - CHURN_WINDOW_DAYS * 24 * 60 * 60 * 1000;
 
   try {
     // Single git command to get all file dates
@@ -66,6 +92,9 @@ export async function getFileCommitDates(rootPath: string): Promise<Map<string,
         if (!fileDates.has(normalizedPath)) {
           fileDates.set(normalizedPath, currentDate);
         }
+        if (currentDate.getTime() >= churnSince) {
+          recentCommits.set(normalizedPath, (recentCommits.get(normalizedPath) ?? 0) + 1);
+        }
       }
     }
 
@@ -80,8 +109,9 @@ export async function getFileCommitDates(rootPath: string): Promise<Map<string,
     }
   }
 
-  commitDateCache.set(cacheKey, new Map(fileDates));
-  return fileDates;
+  const history = { dates: fileDates, recentCommits };
+  commitDateCache.set(cacheKey, history);
+  return history;
 }
 
 /**
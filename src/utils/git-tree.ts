@@ -0,0 +1,346 @@
+/**
+ * Git Tree Utility
+ * Lists files and reads blob contents for a ref straight from the git object store,
+ * so a branch, tag or commit can be indexed without checking it out.
+ */
+
+import { execFile } from 'child_process';
+import { createHash } from 'crypto';
+import path from 'path';
+import { promisify } from 'util';
+import { CODEBASE_CONTEXT_DIRNAME, REF_INDEXES_DIRNAME } from '../constants/codebase-context.js';
+import { decodeText } from './text-encoding.js';
+
+const execFileAsync = promisify(execFile);
+
+const GIT_MAX_BUFFER = 50 * 1024 * 1024;
+
+/**
+ * Leading git options for this platform: Git for Windows refuses working-tree paths over 260
+ * characters unless `core.longpaths` is set.
+ */
+export const GIT_PLATFORM_ARGS: string[] =
+  process.platform === 'win32' ? ['-c', 'core.longpaths=true'] : [];
+
+/** Regular files only (no symlinks `120000` or submodules `160000`) */
+const REGULAR_FILE_MODES = new Set(['100644', '100755']);
+
+export interface GitTreeEntry {
+  /** Repo-relative path with forward slashes */
+  path: string;
+  blob: string;
+  size: number;
+}
+
+function assertSafeRef(ref: string): string {
+  const trimmed = ref.trim();
+  // execFile avoids shell injection; a leading dash would still be parsed as a git option
+  if (!trimmed || trimmed.startsWith('-') || /[\s\0]/.test(trimmed)) {
+    throw new Error(`Invalid git ref: '${ref}'`);
+  }
+  return trimmed;
+}
+
+/**
+ * Resolve a branch, tag or (abbreviated) SHA to a full commit SHA.
+ * Throws a descriptive error when the ref doesn't exist or rootPath isn't a git repo.
+ */
+export async function resolveGitCommit(rootPath: string, ref: string): Promise<string> {
+  const safeRef = assertSafeRef(ref);
+  try {
+    const { stdout } = await execFileAsync(
+      'git',
+      ['rev-parse', '--verify', '--quiet', `${safeRef}^{commit}`],
+      { cwd: rootPath }
+    );
+    const commit = stdout.trim();
+    if (commit) return commit;
+  } catch {
+    // Fall through to the error below
+  }
+  throw new Error(`Git ref '${safeRef}' not found in ${rootPath}`);
+}
+
+/** List regular files in the tree of `commit`. */
+export async function listGitTreeFiles(rootPath: string, commit: string): Promise<GitTreeEntry[]> {
+  const { stdout } = await execFileAsync('git', ['ls-tree', '-r', '-z', '--long', commit], {
+    cwd: rootPath,
+    maxBuffer: GIT_MAX_BUFFER
+  });
+
+  const entries: GitTreeEntry[] = [];
+  // Record format: "<mode> <type> <object> <size>\t<path>"
+  for (const record of stdout.split('\0')) {
+    const tab = record.indexOf('\t');
+    if (tab <= 0) continue;
+
+    const [mode, type, blob, size] = record.slice(0, tab).trim().split(/\s+/);
+    if (type !== 'blob' || !REGULAR_FILE_MODES.has(mode)) continue;
+
+    entries.push({ path: record.slice(tab + 1), blob, size: Number(size) || 0 });
+  }
+
+  return entries;
+}
+
+export async function readGitBlobBytes(rootPath: string, blob: string): Promise<Buffer> {
+  const { stdout } = await execFileAsync('git', ['cat-file', 'blob', blob], {
+    cwd: rootPath,
+    maxBuffer: GIT_MAX_BUFFER,
+    encoding: 'buffer'
+  });
+  return stdout;
+}
+
+/** Blob content as text, decoded like files in the working tree */
+export async function readGitBlob(rootPath: string, blob: string): Promise<string> {
+  return decodeText(await readGitBlobBytes(rootPath, blob)).text;
+}
+
+/**
+ * Unified diff between two refs, or between `base` and the working tree when `head` is
+ * omitted. Zero context lines keep hunk ranges tight around the actual change.
+ */
+export async function getGitDiff(rootPath: string, base: string, head?: string): Promise<string> {
+  const refs = head ? [assertSafeRef(base), assertSafeRef(head)] : [assertSafeRef(base)];
+  const { stdout } = await execFileAsync(
+    'git',
+    [
+      ...GIT_PLATFORM_ARGS,
+      'diff',
+      '--no-color',
+      '--no-ext-diff',
+      '--unified=0',
+      '-M',
+      ...refs,
+      '--'
+    ],
+    { cwd: rootPath, maxBuffer: GIT_MAX_BUFFER }
+  );
+  return stdout;
+}
+
+export interface GitHead {
+  commit: string;
+  /** Short branch name; null on a detached HEAD */
+  branch: string | null;
+}
+
+/** Current HEAD commit and branch; null outside a git repository or before the first commit */
+export async function readGitHead(rootPath: string): Promise<GitHead | null> {
+  try {
+    const { stdout } = await execFileAsync('git', ['rev-parse', 'HEAD', '--abbrev-ref', 'HEAD'], {
+      cwd: rootPath
+    });
+    const [commit, branch] = stdout.trim().split('\n');
+    if (!commit) return null;
+    return { commit, branch: branch && branch !== 'HEAD' ? branch : null };
+  } catch {
+    return null;
+  }
+}
+
+/** URL of the `origin` remote (or the first remote); null when there is none */
+export async function readGitRemoteUrl(rootPath: string): Promise<string | null> {
+  try {
+    const { stdout } = await execFileAsync('git', ['remote', '-v'], { cwd: rootPath });
+    const remotes = stdout
+      .split('\n')
+      .map((line) => line.split(/\s+/))
+      .filter((parts) => parts.length >= 2 && parts[1]);
+    const origin = remotes.find((parts) => parts[0] === 'origin') ?? remotes[0];
+    return origin ? origin[1] : null;
+  } catch {
+    return null;
+  }
+}
+
+/** rootPath relative to the repository root, with a trailing slash; empty at the root */
+export async function readGitPathPrefix(rootPath: string): Promise<string> {
+  try {
+    const { stdout } = await execFileAsync('git', ['rev-parse', '--show-prefix'], {
+      cwd: rootPath
+    });
+    return stdout.trim();
+  } catch {
+    return '';
+  }
+}
+
+/**
+ * Paths under rootPath (relative to it) that differ between two commits; renames count as a
+ * delete plus an add.
+ */
+export async function listChangedFiles(
+  rootPath: string,
+  base: string,
+  head: string
+): Promise<string[]> {
+  const { stdout } = await execFileAsync(
+    'git',
+    [
+      'diff',
+      '--name-only',
+      '--relative',
+      '-z',
+      '--no-renames',
+      assertSafeRef(base),
+      assertSafeRef(head),
+      '--'
+    ],
+    { cwd: rootPath, maxBuffer: GIT_MAX_BUFFER }
+  );
+  return stdout.split('\0').filter(Boolean);
+}
+
+export interface GitCommitRecord {
+  sha: string;
+  author: string;
+  email: string;
+  /** Author date, ISO 8601 */
+  date: string;
+  message: string;
+  /** Unified diff of the commit against its first parent */
+  patch: string;
+}
+
+const RECORD_SEPARATOR = '\x1e';
+const FIELD_SEPARATOR = '\x1f';
+const COMMIT_SHA_PATTERN = /^[0-9a-f]{7,40}$/i;
+/** Commits per `git log` call; keeps argv and output sizes bounded */
+const COMMIT_LOG_BATCH_SIZE = 100;
+
+/** SHAs of the newest `limit` non-merge commits reachable from HEAD, newest first. */
+export async function listRecentCommits(rootPath: string, limit: number): Promise<string[]> {
+  const { stdout } = await execFileAsync(
+    'git',
+    ['rev-list', '--no-merges', `--max-count=${Math.max(1, Math.floor(limit))}`, 'HEAD'],
+    { cwd: rootPath, maxBuffer: GIT_MAX_BUFFER }
+  );
+  return stdout.split('\n').filter((line) => COMMIT_SHA_PATTERN.test(line));
+}
+
+/** Author, message and patch for each commit, in the order given. */
+export async function readCommitRecords(
+  rootPath: string,
+  commits: string[]
+): Promise<GitCommitRecord[]> {
+  const shas = commits.filter((sha) => COMMIT_SHA_PATTERN.test(sha));
+  const records: GitCommitRecord[] = [];
+
+  for (let i = 0; i < shas.length; i += COMMIT_LOG_BATCH_SIZE) {
+    const { stdout } = await execFileAsync(
+      'git',
+      [
+        'log',
+        '--no-walk=unsorted',
+        '--no-color',
+        '--no-ext-diff',
+        '-M',
+        '-p',
+        `--format=${RECORD_SEPARATOR}%H${FIELD_SEPARATOR}%an${FIELD_SEPARATOR}%ae` +
+          `${FIELD_SEPARATOR}%aI${FIELD_SEPARATOR}%B${FIELD_SEPARATOR}`,
+        ...shas.slice(i, i + COMMIT_LOG_BATCH_SIZE),
+        '--'
+      ],
+      { cwd: rootPath, maxBuffer: GIT_MAX_BUFFER }
+    );
+
+    for (const record of stdout.split(RECORD_SEPARATOR)) {
+      const fields = record.split(FIELD_SEPARATOR);
+      if (fields.length < 6 || !COMMIT_SHA_PATTERN.test(fields[0])) continue;
+      const [sha, author, email, date, message] = fields;
+      records.push({
+        sha,
+        author,
+        email,
+        date,
+        message: message.trim(),
+        patch: fields.slice(5).join(FIELD_SEPARATOR).replace(/^\n+/, '')
+      });
+    }
+  }
+
+  return records;
+}
+
+/** File content at `ref`, or null when the path doesn't exist there. */
+export async function readGitFileAtRef(
+  rootPath: string,
+  ref: string,
+  relativePath: string
+): Promise<string | null> {
+  try {
+    const spec = `${assertSafeRef(ref)}:${relativePath}`;
+    const { stdout } = await execFileAsync('git', ['show', spec], {
+      cwd: rootPath,
+      maxBuffer: GIT_MAX_BUFFER,
+      encoding: 'buffer'
+    });
+    return decodeText(stdout).text;
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Filesystem-safe, collision-resistant directory name for a ref:
+ * `<readable-ref>-<8 hex of ref hash>`.
+ */
+export function getRefIndexSlug(ref: string): string {
+  const trimmed = ref.trim();
+  const readable = trimmed
+    .replace(/^refs\/(heads|tags|remotes)\//, '')
+    .replace(/[^A-Za-z0-9._-]+/g, '-')
+    .replace(/^[-.]+|-+$/g, '')
+    .slice(0, 60);
+  const hash = createHash('sha256').update(trimmed).digest('hex').slice(0, 8);
+  return `${readable || 'ref'}-${hash}`;
+}
+
+/** Context directory holding the artifacts of a ref-scoped index. */
+export function getRefContextDir(rootPath: string, ref: string): string {
+  return path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, REF_INDEXES_DIRNAME, getRefIndexSlug(ref));
+}
+
+function expandBraces(pattern: string): string[] {
+  const match = pattern.match(/\{([^{}]*)\}/);
+  if (!match || match.index === undefined) return [pattern];
+  const head = pattern.slice(0, match.index);
+  const tail = pattern.slice(match.index + match[0].length);
+  return match[1].split(',').flatMap((option) => expandBraces(head + option + tail));
+}
+
+function globToRegExp(pattern: string): RegExp {
+  let source = '';
+  for (let i = 0; i < pattern.length; i++) {
+    const ch = pattern[i];
+    if (ch === '*') {
+      if (pattern[i + 1] === '*') {
+        // `**/` matches zero or more directories; a trailing `**` matches everything below
+        if (pattern[i + 2] === '/') {
+          source += '(?:.*/)?';
+          i += 2;
+        } else {
+          source += '.*';
+          i += 1;
+        }
+      } else {
+        source += '[^/]*';
+      }
+    } else if (ch === '?') {
+      source += '[^/]';
+    } else {
+      source += ch.replace(/[.+^${}()|[\]\\]/g, '\\$&');
+    }
+  }
+  return new RegExp(`^${source}$`);
+}
+
+/**
+ * Minimal glob matcher for git tree paths (`*`, `**`, `?`, `{a,b}`), matching the
+ * include/exclude semantics the working-tree scan gets from `glob`.
+ */
+export function matchesGlob(relativePath: string, pattern: string): boolean {
+  return expandBraces(pattern).some((expanded) => globToRegExp(expanded).test(relativePath));
+}
@@ -0,0 +1,257 @@
+/**
+ * Ignore rules for the indexer: nested `.gitignore` files with git semantics, a dedicated
+ * `.mcpignore` (same syntax, always honored), and directories that are never worth indexing.
+ *
+ * Rules are loaded lazily per directory through a loader, so the same matcher serves the
+ * working tree (sync fs reads) and git refs (pre-read blobs).
+ */
+
+import { readFileSync } from 'fs';
+import path from 'path';
+import { detectUtf16 } from './text-encoding.js';
+import ignore from 'ignore';
+
+export const GITIGNORE_FILENAME = '.gitignore';
+export const MCPIGNORE_FILENAME = '.mcpignore';
+
+/** Pruned at any depth: dependencies, VCS metadata, tool caches, our own artifacts */
+export const ALWAYS_SKIPPED_DIRS: ReadonlySet<string> = new Set([
+  'node_modules',
+  'bower_components',
+  'vendor',
+  '.git',
+  '.hg',
+  '.svn',
+  '.codebase-context',
+  '__pycache__',
+  '.ipynb_checkpoints',
+  '.venv',
+  'venv',
+  '.tox',
+  '.next',
+  '.nuxt',
+  '.turbo',
+  '.gradle'
+]);
+
+/** Repo-relative posix directory ('' for the root) -> ignore file contents, or null */
+export type IgnoreFileLoader = (directory: string) => string | null;
+
+type Matcher = ReturnType<typeof ignore.default>;
+
+export class IgnoreRules {
+  private matchers = new Map<string, Matcher | null>();
+  private directoryDecisions = new Map<string, boolean>();
+
+  constructor(
+    private loader: IgnoreFileLoader,
+    private skippedDirs: ReadonlySet<string> = ALWAYS_SKIPPED_DIRS
+  ) {}
+
+  /**
+   * Whether a repo-relative posix path is ignored. Like git, a file inside an ignored
+   * directory can't be re-included by a negation pattern.
+   */
+  isIgnored(relativePath: string, isDirectory = false): boolean {
+    const normalized = relativePath.replace(/\\/g, '/').replace(/^\.\/|\/+$/g, '');
+    if (!normalized) return false;
+    if (isDirectory) return this.isDirectoryIgnored(normalized);
+
+    const parent = path.posix.dirname(normalized);
+    if (parent !== '.' && this.isDirectoryIgnored(parent)) return true;
+    return this.matches(normalized, false);
+  }
+
+  private isDirectoryIgnored(directory: string): boolean {
+    const cached = this.directoryDecisions.get(directory);
+    if (cached !== undefined) return cached;
+
+    const parent = path.posix.dirname(directory);
+    const ignored =
+      this.skippedDirs.has(path.posix.basename(directory)) ||
+      (parent !== '.' && this.isDirectoryIgnored(parent)) ||
+      this.matches(directory, true);
+    this.directoryDecisions.set(directory, ignored);
+    return ignored;
+  }
+
+  /** Apply the ignore files of every ancestor directory, deepest last (deeper rules win). */
+  private matches(relativePath: string, isDirectory: boolean): boolean {
+    const segments = relativePath.split('/');
+    let ignored = false;
+    for (let depth = 0; depth < segments.length; depth++) {
+      const directory = segments.slice(0, depth).join('/');
+      const matcher = this.getMatcher(directory);
+      if (!matcher) continue;
+      const subPath = segments.slice(depth).join('/') + (isDirectory ? '/' : '');
+      const result = matcher.test(subPath);
+      if (result.ignored) ignored = true;
+      else if (result.unignored) ignored = false;
+    }
+    return ignored;
+  }
+
+  private getMatcher(directory: string): Matcher | null {
+    if (this.matchers.has(directory)) return this.matchers.get(directory) ?? null;
+    const content = this.loader(directory);
+    const matcher = content ? ignore.default().add(content) : null;
+    this.matchers.set(directory, matcher);
+    return matcher;
+  }
+}
+
+function readIfExists(filePath: string): string | null {
+  try {
+    return readFileSync(filePath, 'utf-8');
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Loader for the working tree: `.gitignore` (and `.git/info/exclude` at the root) when
+ * `respectGitignore` is set, `.mcpignore` always. `.mcpignore` rules are applied after
+ * `.gitignore` in the same directory, so they can also re-include files.
+ */
+export function createFsIgnoreLoader(
+  rootPath: string,
+  options: { respectGitignore: boolean }
+): IgnoreFileLoader {
+  return (directory) => {
+    const absoluteDir = path.join(rootPath, directory);
+    const parts: string[] = [];
+    if (options.respectGitignore) {
+      if (!directory) {
+        const exclude = readIfExists(path.join(rootPath, '.git', 'info', 'exclude'));
+        if (exclude) parts.push(exclude);
+      }
+      const gitignore = readIfExists(path.join(absoluteDir, GITIGNORE_FILENAME));
+      if (gitignore) parts.push(gitignore);
+    }
+    const mcpignore = readIfExists(path.join(absoluteDir, MCPIGNORE_FILENAME));
+    if (mcpignore) parts.push(mcpignore);
+    return parts.length > 0 ? parts.join('\n') : null;
+  };
+}
+
+/**
+ * `loader` plus extra rules at the root (the `ignore` section of codebase-context.yaml),
+ * applied after the root's own ignore files.
+ */
+export function withRootRules(
+  loader: IgnoreFileLoader,
+  rules: readonly string[] | undefined
+): IgnoreFileLoader {
+  if (!rules?.length) return loader;
+  return (directory) => {
+    const content = loader(directory);
+    return directory ? content : [content ?? '', ...rules].join('\n');
+  };
+}
+
+/** Loader over pre-read ignore file contents keyed by repo-relative posix path. */
+export function createMapIgnoreLoader(
+  files: ReadonlyMap<string, string>,
+  options: { respectGitignore: boolean }
+): IgnoreFileLoader {
+  return (directory) => {
+    const prefix = directory ? `${directory}/` : '';
+    const parts: string[] = [];
+    const gitignore = options.respectGitignore ? files.get(prefix + GITIGNORE_FILENAME) : undefined;
+    if (gitignore) parts.push(gitignore);
+    const mcpignore = files.get(prefix + MCPIGNORE_FILENAME);
+    if (mcpignore) parts.push(mcpignore);
+    return parts.length > 0 ? parts.join('\n') : null;
+  };
+}
+
+/** Bytes sniffed from the start of a file for binary/minified detection */
+export const CONTENT_SNIFF_BYTES = 8192;
+
+/**
+ * Same heuristic as git: a NUL byte in the first few KB means binary. UTF-16 text is full of
+ * them and is decoded instead.
+ */
+export function looksBinary(sample: Uint8Array): boolean {
+  return sample.includes(0) && !detectUtf16(sample);
+}
+
+const MINIFIED_NAME = /[.-]min\.(js|mjs|cjs|css)$|[.-]bundle\.(js|mjs)$|\.chunk\.(js|css)$/i;
+
+/**
+ * Minified or bundled output: recognizable names, or very long average lines in the first
+ * few KB (hand-written code rarely averages more than ~100 characters per line).
+ */
+export function looksMinified(filePath: string, sample: string): boolean {
+  if (MINIFIED_NAME.test(filePath)) return true;
+  // Notebook outputs (base64 images, serialized frames) are long lines by nature
+  if (filePath.toLowerCase().endsWith('.ipynb')) return false;
+  if (sample.length < 1024) return false;
+  const lines = sample.split('\n').length;
+  return sample.length / lines > 300;
+}
+
+/** Dependency lockfiles: machine-written, huge, and never the answer to a code question */
+const LOCKFILE_NAMES: ReadonlySet<string> = new Set([
+  'package-lock.json',
+  'npm-shrinkwrap.json',
+  'yarn.lock',
+  'pnpm-lock.yaml',
+  'bun.lock',
+  'composer.lock',
+  'gemfile.lock',
+  'cargo.lock',
+  'poetry.lock',
+  'pipfile.lock',
+  'uv.lock',
+  'go.sum',
+  'packages.lock.json',
+  'podfile.lock',
+  'pubspec.lock',
+  'mix.lock',
+  'flake.lock'
+]);
+
+/** Conventional output names of code generators (protobuf, gRPC, Dart build_runner, WinForms) */
+const GENERATED_NAMES: readonly RegExp[] = [
+  /\.pb\.(go|cc|h|swift)$/,
+  /_pb2(_grpc)?\.pyi?$/,
+  /_pb\.(js|d\.ts)$/,
+  /\.(g|freezed)\.dart$/,
+  /\.(designer|g)\.cs$/,
+  /\.generated\.\w+$/
+];
+
+/**
+ * Generator banners, matched in the first lines only: Go's `Code generated ... DO NOT EDIT.`,
+ * `@generated` (Meta tooling), .NET `<auto-generated>`, protoc and similar headers.
+ */
+const GENERATED_HEADER = new RegExp(
+  '^[\\s/*#;!<-]{0,8}(' +
+    [
+      'code generated .*do not edit',
+      '@generated\\b',
+      '<auto-?generated',
+      'generated by the protocol buffer compiler',
+      '(this file (is|was) )?(automatically|auto-?) ?generated( by| from|\\b.*do not (edit|modify))'
+    ].join('|') +
+    ')',
+  'im'
+);
+
+const GENERATED_HEADER_LINES = 10;
+
+/**
+ * Why a file looks machine-generated (`lockfile`, `name`, `header`), or null. The header check
+ * reads the first few lines of `sample`, so the file's first KB is enough.
+ */
+export function detectGeneratedFile(
+  filePath: string,
+  sample: string
+): 'lockfile' | 'name' | 'header' | null {
+  const name = path.basename(filePath).toLowerCase();
+  if (LOCKFILE_NAMES.has(name)) return 'lockfile';
+  if (GENERATED_NAMES.some((pattern) => pattern.test(name))) return 'name';
+  const head = sample.split('\n', GENERATED_HEADER_LINES).join('\n');
+  return GENERATED_HEADER.test(head) ? 'header' : null;
+}
@@ -0,0 +1,304 @@
+/**
+ * Resource-level chunking for infrastructure code: one chunk per top-level Terraform/HCL
+ * block and one per Kubernetes manifest document, carrying the resource type and name as
+ * metadata so "where is the uploads bucket defined" lands on the exact block.
+ */
+
+import { v4 as uuidv4 } from 'uuid';
+import type { CodeChunk, InfraMetadata } from '../types/index.js';
+import { DEFAULT_AST_CHUNK_OPTIONS, splitOversizedChunks } from './ast-chunker.js';
+
+export interface InfraChunkOptions {
+  filePath: string;
+  relativePath: string;
+  language: string;
+}
+
+export interface InfraBlock {
+  /** 1-based inclusive line range */
+  startLine: number;
+  endLine: number;
+  infra: InfraMetadata;
+}
+
+// ---------------------------------------------------------------------------
+// Terraform / HCL
+// ---------------------------------------------------------------------------
+
+// `resource "aws_s3_bucket" "uploads" {`, `locals {`, `module "vpc" {`
+const HCL_BLOCK_HEADER = /^\s*([A-Za-z_][\w-]*)((?:\s+(?:"[^"]*"|[A-Za-z_][\w-]*))*)\s*\{/;
+const HCL_LABEL = /"([^"]*)"|([A-Za-z_][\w-]*)/g;
+const HCL_HEREDOC = /<<-?\s*([A-Za-z_]\w*)/;
+
+function describeHclBlock(type: string, labels: string[], body: string): InfraMetadata {
+  const [first, second] = labels;
+  switch (type) {
+    case 'resource':
+      return {
+        kind: 'terraform',
+        blockType: type,
+        resourceType: first,
+        resourceName: second,
+        address: `${first}.${second}`
+      };
+    case 'data':
+      return {
+        kind: 'terraform',
+        blockType: type,
+        resourceType: first,
+        resourceName: second,
+        address: `data.${first}.${second}`
+      };
+    case 'module': {
+      const source = /^\s*source\s*=\s*"([^"]+)"/m.exec(body)?.[1];
+      return {
+        kind: 'terraform',
+        blockType: type,
+        resourceName: first,
+        address: `module.${first}`,
+        ...(source ? { source } : {})
+      };
+    }
+    case 'variable':
+      return { kind: 'terraform', blockType: type, resourceName: first, address: `var.${first}` };
+    case 'provider':
+      return {
+        kind: 'terraform',
+        blockType: type,
+        resourceType: first,
+        address: `provider.${first}`
+      };
+    default:
+      return {
+        kind: 'terraform',
+        blockType: type,
+        ...(first ? { resourceName: labels[labels.length - 1] } : {}),
+        address: [type, ...labels].join('.')
+      };
+  }
+}
+
+/**
+ * Find top-level HCL blocks by tracking brace depth outside strings, comments and heredocs.
+ */
+export function findHclBlocks(content: string): InfraBlock[] {
+  const lines = content.split('\n');
+  const blocks: InfraBlock[] = [];
+  let depth = 0;
+  let inBlockComment = false;
+  let heredocTag: string | null = null;
+  let open: { startLine: number; type: string; labels: string[] } | null = null;
+
+  for (let i = 0; i < lines.length; i++) {
+    const line = lines[i];
+    if (heredocTag) {
+      if (line.trim() === heredocTag) heredocTag = null;
+      continue;
+    }
+
+    if (depth === 0 && !inBlockComment && !open) {
+      const header = HCL_BLOCK_HEADER.exec(line);
+      if (header) {
+        const labels = [...header[2].matchAll(HCL_LABEL)].map((m) => m[1] ?? m[2]);
+        open = { startLine: i + 1, type: header[1], labels };
+      }
+    }
+
+    let inString = false;
+    for (let c = 0; c < line.length; c++) {
+      const ch = line[c];
+      if (inBlockComment) {
+        if (ch === '*' && line[c + 1] === '/') {
+          inBlockComment = false;
+          c++;
+        }
+        continue;
+      }
+      if (inString) {
+        if (ch === '\\') c++;
+        else if (ch === '"') inString = false;
+        continue;
+      }
+      if (ch === '#' || (ch === '/' && line[c + 1] === '/')) break;
+      if (ch === '/' && line[c + 1] === '*') {
+        inBlockComment = true;
+        c++;
+      } else if (ch === '"') {
+        inString = true;
+      } else if (ch === '<' && line[c + 1] === '<') {
+        const heredoc = HCL_HEREDOC.exec(line.slice(c));
+        if (heredoc) {
+          heredocTag = heredoc[1];
+          break;
+        }
+      } else if (ch === '{') {
+        depth++;
+      } else if (ch === '}') {
+        depth = Math.max(0, depth - 1);
+        if (depth === 0 && open) {
+          const body = lines.slice(open.startLine - 1, i + 1).join('\n');
+          blocks.push({
+            startLine: open.startLine,
+            endLine: i + 1,
+            infra: describeHclBlock(open.type, open.labels, body)
+          });
+          open = null;
+        }
+      }
+    }
+  }
+
+  return blocks;
+}
+
+// ---------------------------------------------------------------------------
+// Kubernetes manifests
+// ---------------------------------------------------------------------------
+
+const YAML_DOCUMENT_SEPARATOR = /^(---|\.\.\.)(\s|$)/;
+
+function topLevelScalar(lines: string[], key: string): string | undefined {
+  const pattern = new RegExp(`^${key}:\\s*["']?([^"'\\s#]+)`);
+  for (const line of lines) {
+    const match = pattern.exec(line);
+    if (match) return match[1];
+  }
+  return undefined;
+}
+
+/** `name`/`namespace` directly under the top-level `metadata:` mapping */
+function metadataFields(lines: string[]): { name?: string; namespace?: string } {
+  const start = lines.findIndex((line) => /^metadata:\s*(#.*)?$/.test(line));
+  if (start < 0) return {};
+
+  const fields: { name?: string; namespace?: string } = {};
+  let childIndent: number | null = null;
+  for (const line of lines.slice(start + 1)) {
+    if (!line.trim() || line.trim().startsWith('#')) continue;
+    const indent = line.length - line.trimStart().length;
+    if (indent === 0) break;
+    childIndent ??= indent;
+    if (indent !== childIndent) continue;
+    const match = /^\s*(name|namespace):\s*["']?([^"'\s#]+)/.exec(line);
+    if (match) fields[match[1] as 'name' | 'namespace'] = match[2];
+  }
+  return fields;
+}
+
+/** Manifest documents with both `apiVersion` and `kind`; other YAML documents are ignored. */
+export function findKubernetesDocuments(content: string): InfraBlock[] {
+  const lines = content.split('\n');
+  const documents: InfraBlock[] = [];
+
+  let docStart = 0;
+  for (let i = 0; i <= lines.length; i++) {
+    if (i < lines.length && !YAML_DOCUMENT_SEPARATOR.test(lines[i])) continue;
+
+    const docLines = lines.slice(docStart, i);
+    const apiVersion = topLevelScalar(docLines, 'apiVersion');
+    const kind = topLevelScalar(docLines, 'kind');
+    if (apiVersion && kind) {
+      let first = 0;
+      let last = docLines.length - 1;
+      while (first < last && !docLines[first].trim()) first++;
+      while (last > first && !docLines[last].trim()) last--;
+
+      const { name, namespace } = metadataFields(docLines);
+      documents.push({
+        startLine: docStart + first + 1,
+        endLine: docStart + last + 1,
+        infra: {
+          kind: 'kubernetes',
+          resourceType: kind,
+          ...(name ? { resourceName: name } : {}),
+          address: name ? `${kind}/${name}` : kind,
+          ...(namespace ? { namespace } : {}),
+          apiVersion
+        }
+      });
+    }
+    docStart = i + 1;
+  }
+
+  return documents;
+}
+
+// ---------------------------------------------------------------------------
+// Chunks
+// ---------------------------------------------------------------------------
+
+function makeChunk(
+  lines: string[],
+  startLine: number,
+  endLine: number,
+  options: InfraChunkOptions,
+  block?: InfraBlock
+): CodeChunk {
+  const body = lines.slice(startLine - 1, endLine).join('\n');
+  const infra = block?.infra;
+  const hint = infra ? (infra.kind === 'terraform' ? infra.blockType : 'kubernetes') : undefined;
+
+  return {
+    id: uuidv4(),
+    content: infra ? `# ${infra.address} :: ${hint}\n${body}` : body,
+    filePath: options.filePath,
+    relativePath: options.relativePath,
+    startLine,
+    endLine,
+    language: options.language,
+    framework: infra?.kind,
+    componentType: 'infrastructure',
+    dependencies: [],
+    imports: [],
+    exports: [],
+    tags: infra ? [infra.kind, (infra.blockType ?? infra.resourceType ?? '').toLowerCase()] : [],
+    metadata: infra
+      ? {
+          symbolAware: true,
+          symbolName: infra.address,
+          symbolKind: hint,
+          componentName: infra.address,
+          chunkStrategy: 'infra-block',
+          infra
+        }
+      : { chunkStrategy: 'infra-block' }
+  };
+}
+
+/**
+ * Resource-level chunks for `.tf`/`.tfvars`/`.hcl` files and Kubernetes YAML.
+ * Returns null when the file has no recognizable blocks (e.g. plain YAML config),
+ * so callers fall back to regular chunking.
+ */
+export function createInfraChunks(
+  content: string,
+  options: InfraChunkOptions
+): CodeChunk[] | null {
+  const blocks =
+    options.language === 'hcl'
+      ? findHclBlocks(content)
+      : options.language === 'yaml'
+        ? findKubernetesDocuments(content)
+        : [];
+  if (blocks.length === 0) return null;
+
+  const lines = content.split('\n');
+  const chunks: CodeChunk[] = [];
+  let cursor = 1;
+  const pushGap = (endLine: number) => {
+    const gap = lines.slice(cursor - 1, endLine);
+    if (gap.some((line) => line.trim() && !YAML_DOCUMENT_SEPARATOR.test(line))) {
+      chunks.push(makeChunk(lines, cursor, endLine, options));
+    }
+  };
+
+  for (const block of blocks) {
+    if (block.startLine > cursor) pushGap(block.startLine - 1);
+    chunks.push(makeChunk(lines, block.startLine, block.endLine, options, block));
+    cursor = block.endLine + 1;
+  }
+  if (cursor <= lines.length) pushGap(lines.length);
+
+  return splitOversizedChunks(chunks, DEFAULT_AST_CHUNK_OPTIONS.maxChunkLines);
+}
+
@@ -4,6 +4,8 @@
  */
 
 import path from 'path';
+import { supportsCuratedTreeSitter } from '../grammars/manifest.js';
+import { grammarPluginForExtension, listGrammarPlugins } from '../grammars/plugins.js';
 
 // Map of file extensions to languages
 const extensionToLanguage: Record<string, string> = {
@@ -17,6 +19,11 @@ const extensionToLanguage: Record<string, string> = {
   '.mts': 'typescript',
   '.cts': 'typescript',
 
+  // Single-file components
+  '.vue': 'vue',
+  '.svelte': 'svelte',
+  '.astro': 'astro',
+
   // Web
   '.html': 'html',
   '.htm': 'html',
@@ -33,13 +40,22 @@ const extensionToLanguage: Record<string, string> = {
   '.toml': 'toml',
   '.xml': 'xml',
 
+  // Infrastructure
+  '.tf': 'hcl',
+  '.tfvars': 'hcl',
+  '.hcl': 'hcl',
+
   // Markdown
   '.md': 'markdown',
   '.mdx': 'mdx',
 
+  // Notebooks
+  '.ipynb': 'jupyter',
+
   // Other
   '.graphql': 'graphql',
   '.gql': 'graphql',
+  '.proto': 'proto',
   '.sql': 'sql',
   '.sh': 'shellscript',
   '.bash': 'shellscript',
@@ -56,7 +72,12 @@ const extensionToLanguage: Record<string, string> = {
   '.rs': 'rust',
   '.cs': 'csharp',
   '.swift': 'swift',
+  '.m': 'objective-c',
+  '.mm': 'objective-cpp',
   '.scala': 'scala',
+  '.ex': 'elixir',
+  '.exs': 'elixir',
+  '.zig': 'zig',
   '.c': 'c',
   '.cpp': 'cpp',
   '.cc': 'cpp',
@@ -116,6 +137,9 @@ const codeExtensions = new Set([
   '.tsx',
   '.mts',
   '.cts',
+  '.vue',
+  '.svelte',
+  '.astro',
   '.html',
   '.htm',
   '.css',
@@ -128,10 +152,15 @@ const codeExtensions = new Set([
   '.yml',
   '.md',
   '.mdx',
+  '.ipynb',
   '.graphql',
   '.gql',
+  '.proto',
   '.toml',
   '.xml',
+  '.tf',
+  '.tfvars',
+  '.hcl',
   '.py',
   '.pyi',
   '.rb',
@@ -143,7 +172,12 @@ const codeExtensions = new Set([
   '.rs',
   '.cs',
   '.swift',
+  '.m',
+  '.mm',
   '.scala',
+  '.ex',
+  '.exs',
+  '.zig',
   '.c',
   '.cpp',
   '.cc',
@@ -152,12 +186,25 @@ const codeExtensions = new Set([
   '.hpp'
 ]);
 
+// `.h` is shared by C, C++ and Objective-C; these directives only appear in Objective-C
+const OBJC_HEADER_PATTERN = /^\s*(?:#import\b|@(?:interface|protocol|class)\b)/m;
+
 /**
- * Detect language from file path
+ * Detect language from file path. With the content, `.h` headers using Objective-C
+ * directives (including Swift bridging headers) are reported as `objective-c`. A grammar
+ * plugin's extensions map to its language unless a bundled grammar already parses them.
  */
-export function detectLanguage(filePath: string): string {
+export function detectLanguage(filePath: string, content?: string): string {
   const ext = path.extname(filePath).toLowerCase();
-  return extensionToLanguage[ext] || 'plaintext';
+  const plugin = grammarPluginForExtension(ext);
+  if (plugin && !supportsCuratedTreeSitter(extensionToLanguage[ext] ?? '')) {
+    return plugin.language;
+  }
+  const language = extensionToLanguage[ext] || 'plaintext';
+  if (ext === '.h' && content !== undefined && OBJC_HEADER_PATTERN.test(content)) {
+    return 'objective-c';
+  }
+  return language;
 }
 
 /**
@@ -165,7 +212,7 @@ export function detectLanguage(filePath: string): string {
  */
 export function isCodeFile(filePath: string): boolean {
   const ext = path.extname(filePath).toLowerCase();
-  return codeExtensions.has(ext);
+  return codeExtensions.has(ext) || grammarPluginForExtension(ext) !== undefined;
 }
 
 /**
@@ -213,5 +260,6 @@ export function isDocumentationFile(filePath: string): boolean {
  * Get all supported extensions
  */
 export function getSupportedExtensions(): string[] {
-  return Array.from(codeExtensions);
+  const pluginExtensions = listGrammarPlugins().flatMap((plugin) => plugin.extensions);
+  return Array.from(new Set([...codeExtensions, ...pluginExtensions]));
 }
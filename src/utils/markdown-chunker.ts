@@ -0,0 +1,179 @@
+/**
+ * Markdown chunking by heading section. Each ATX (`## Title`) or setext (`Title` over `---`)
+ * heading starts a section running to the next heading; headings inside fenced code and YAML
+ * front matter don't count. A section carries its heading breadcrumb (`Design > Storage >
+ * Index format`) in `headingPath`, and nested sections repeat it as a comment on their first
+ * line so the chunk keeps its context when retrieved alone.
+ */
+
+import { v4 as uuidv4 } from 'uuid';
+import type { CodeChunk } from '../types/index.js';
+import { DEFAULT_AST_CHUNK_OPTIONS, splitOversizedChunks } from './ast-chunker.js';
+
+export interface MarkdownChunkOptions {
+  filePath: string;
+  relativePath: string;
+  language: string;
+  maxChunkTokens?: number;
+  overlapLines?: number;
+}
+
+export interface MarkdownSection {
+  /** 1-based inclusive line range, heading line included */
+  startLine: number;
+  endLine: number;
+  /** 1-6; 0 for text before the first heading */
+  level: number;
+  heading?: string;
+  /** Headings from the top level down to this section's own */
+  headingPath: string[];
+}
+
+const ATX_HEADING = /^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$/;
+const SETEXT_UNDERLINE = /^ {0,3}(=+|-+)[ \t]*$/;
+const FENCE = /^ {0,3}(`{3,}|~{3,})/;
+
+/** Inline markup stripped from heading text: links, emphasis, code spans, trailing anchors */
+function headingText(raw: string): string {
+  return raw
+    .replace(/\s*\{#[\w-]+\}\s*$/, '')
+    .replace(/!?\[([^\]]*)\]\([^)]*\)/g, '$1')
+    .replace(/[*_`]+/g, '')
+    .trim();
+}
+
+interface Heading {
+  /** 0-based line index where the section starts (the text line for setext headings) */
+  index: number;
+  level: number;
+  text: string;
+}
+
+function findHeadings(lines: string[]): Heading[] {
+  const headings: Heading[] = [];
+  let fence: string | null = null;
+  let start = 0;
+
+  // YAML front matter is metadata, not a section break
+  if (lines[0]?.trim() === '---') {
+    const end = lines.findIndex((line, i) => i > 0 && /^(---|\.\.\.)\s*$/.test(line));
+    if (end > 0) start = end + 1;
+  }
+
+  for (let i = start; i < lines.length; i++) {
+    const line = lines[i];
+    const fenceMatch = FENCE.exec(line);
+    if (fence) {
+      if (fenceMatch && fenceMatch[1][0] === fence[0] && fenceMatch[1].length >= fence.length) {
+        fence = null;
+      }
+      continue;
+    }
+    if (fenceMatch) {
+      fence = fenceMatch[1];
+      continue;
+    }
+
+    const atx = ATX_HEADING.exec(line);
+    if (atx) {
+      headings.push({ index: i, level: atx[1].length, text: headingText(atx[2] ?? '') });
+      continue;
+    }
+
+    // Setext: a paragraph line directly over `===` or `---` (a `---` after a blank is a rule)
+    const next = lines[i + 1];
+    const underline = next !== undefined ? SETEXT_UNDERLINE.exec(next) : null;
+    const paragraph =
+      line.trim() !== '' && !/^ {0,3}([>*+-]|\d+[.)])\s/.test(line) && !/^\s{4}/.test(line);
+    if (underline && paragraph && (i === start || lines[i - 1].trim() === '')) {
+      headings.push({ index: i, level: underline[1][0] === '=' ? 1 : 2, text: headingText(line) });
+      i++;
+    }
+  }
+  return headings;
+}
+
+/**
+ * Heading sections of a markdown document, in order. A heading directly followed by a deeper
+ * one (no text of its own) is folded into that subsection instead of becoming a chunk of one
+ * line.
+ */
+export function findMarkdownSections(content: string): MarkdownSection[] {
+  const lines = content.split('\n');
+  const headings = findHeadings(lines);
+  const sections: MarkdownSection[] = [];
+  const stack: Heading[] = [];
+
+  const firstHeading = headings[0]?.index ?? lines.length;
+  if (lines.slice(0, firstHeading).some((line) => line.trim())) {
+    sections.push({ startLine: 1, endLine: firstHeading, level: 0, headingPath: [] });
+  }
+
+  let pendingStart: number | null = null;
+  headings.forEach((heading, i) => {
+    while (stack.length > 0 && stack[stack.length - 1].level >= heading.level) stack.pop();
+    stack.push(heading);
+
+    const endIndex = (headings[i + 1]?.index ?? lines.length) - 1;
+    const bodyStart = heading.index + (ATX_HEADING.test(lines[heading.index]) ? 1 : 2);
+    const hasBody = lines.slice(bodyStart, endIndex + 1).some((line) => line.trim());
+    const deeperNext = headings[i + 1] && headings[i + 1].level > heading.level;
+    if (!hasBody && deeperNext) {
+      pendingStart ??= heading.index + 1;
+      return;
+    }
+
+    sections.push({
+      startLine: pendingStart ?? heading.index + 1,
+      endLine: endIndex + 1,
+      level: heading.level,
+      heading: heading.text,
+      headingPath: stack.map((h) => h.text)
+    });
+    pendingStart = null;
+  });
+  return sections;
+}
+
+function makeChunk(
+  lines: string[],
+  section: MarkdownSection,
+  options: MarkdownChunkOptions
+): CodeChunk {
+  const body = lines.slice(section.startLine - 1, section.endLine).join('\n');
+  const breadcrumb = section.headingPath.join(' > ');
+
+  return {
+    id: uuidv4(),
+    content: section.headingPath.length > 1 ? `<!-- ${breadcrumb} -->\n${body}` : body,
+    filePath: options.filePath,
+    relativePath: options.relativePath,
+    startLine: section.startLine,
+    endLine: section.endLine,
+    language: options.language,
+    componentType: 'documentation',
+    dependencies: [],
+    imports: [],
+    exports: [],
+    tags: ['documentation'],
+    metadata: {
+      ...(section.heading !== undefined
+        ? { heading: section.heading, headingLevel: section.level }
+        : {}),
+      headingPath: section.headingPath,
+      chunkStrategy: 'markdown-section'
+    }
+  };
+}
+
+/** One chunk per heading section (oversized sections are split); empty documents yield none */
+export function createMarkdownChunks(content: string, options: MarkdownChunkOptions): CodeChunk[] {
+  const lines = content.split('\n');
+  const chunks = findMarkdownSections(content).map((section) =>
+    makeChunk(lines, section, options)
+  );
+  return splitOversizedChunks(chunks, DEFAULT_AST_CHUNK_OPTIONS.maxChunkLines, {
+    maxTokens: options.maxChunkTokens,
+    overlapLines: options.overlapLines
+  });
+}
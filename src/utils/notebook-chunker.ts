@@ -0,0 +1,166 @@
+/**
+ * Jupyter notebooks, indexed cell by cell instead of as one JSON document. Code cells are
+ * chunked with the kernel language's grammar (AST-aligned when it parses them), markdown
+ * cells become documentation chunks, and outputs are left out. Chunk line numbers are
+ * 1-based within the cell; `cellIndex` says which cell.
+ */
+
+import type { CodeChunk } from '../types/index.js';
+import { createASTAlignedChunks, DEFAULT_AST_CHUNK_OPTIONS } from './ast-chunker.js';
+import { extractTreeSitterSymbols } from './tree-sitter.js';
+
+export type NotebookCellType = 'code' | 'markdown' | 'raw';
+
+export interface NotebookCell {
+  /** 0-based position in the notebook */
+  index: number;
+  cellType: NotebookCellType;
+  /** Code cells: the kernel's language unless a cell magic (`%%bash`) says otherwise */
+  language: string;
+  source: string;
+  /** `execution_count` when the notebook was saved; null for cells never run */
+  executionCount: number | null;
+  id?: string;
+}
+
+export interface Notebook {
+  /** Kernel language (`python` when the notebook doesn't say) */
+  language: string;
+  cells: NotebookCell[];
+}
+
+export interface NotebookChunkOptions {
+  filePath: string;
+  relativePath: string;
+  maxChunkTokens?: number;
+  overlapLines?: number;
+}
+
+interface RawNotebook {
+  cells?: Array<{
+    cell_type?: string;
+    source?: string | string[];
+    execution_count?: number | null;
+    id?: string;
+  }>;
+  metadata?: {
+    kernelspec?: { language?: string };
+    language_info?: { name?: string };
+  };
+}
+
+const KERNEL_LANGUAGES: Record<string, string> = {
+  python3: 'python',
+  ipython: 'python',
+  ipython3: 'python',
+  r: 'r',
+  julia: 'julia',
+  scala: 'scala',
+  typescript: 'typescript',
+  javascript: 'javascript',
+  bash: 'shellscript',
+  sh: 'shellscript'
+};
+
+/** `%%bash`-style cell magics that switch the cell to another language */
+const CELL_MAGIC_LANGUAGES: Record<string, string> = {
+  bash: 'shellscript',
+  sh: 'shellscript',
+  script: 'shellscript',
+  sql: 'sql',
+  javascript: 'javascript',
+  js: 'javascript',
+  html: 'html'
+};
+
+function kernelLanguage(raw: RawNotebook): string {
+  const name = (
+    raw.metadata?.kernelspec?.language ??
+    raw.metadata?.language_info?.name ??
+    'python'
+  ).toLowerCase();
+  return KERNEL_LANGUAGES[name] ?? name;
+}
+
+function cellLanguage(source: string, kernel: string): string {
+  const magic = /^%%(\w+)/.exec(source)?.[1];
+  return (magic && CELL_MAGIC_LANGUAGES[magic.toLowerCase()]) || kernel;
+}
+
+/** Cells of an `.ipynb` document; null when the content is not a notebook */
+export function parseNotebook(content: string): Notebook | null {
+  let raw: RawNotebook;
+  try {
+    raw = JSON.parse(content) as RawNotebook;
+  } catch {
+    return null;
+  }
+  if (!raw || !Array.isArray(raw.cells)) return null;
+
+  const language = kernelLanguage(raw);
+  const cells: NotebookCell[] = raw.cells.map((cell, index) => {
+    const source = Array.isArray(cell.source) ? cell.source.join('') : (cell.source ?? '');
+    const cellType: NotebookCellType =
+      cell.cell_type === 'code' || cell.cell_type === 'markdown' ? cell.cell_type : 'raw';
+    return {
+      index,
+      cellType,
+      language:
+        cellType === 'code'
+          ? cellLanguage(source, language)
+          : cellType === 'markdown'
+            ? 'markdown'
+            : 'plaintext',
+      source: source.replace(/\r\n/g, '\n').replace(/\n$/, ''),
+      executionCount: typeof cell.execution_count === 'number' ? cell.execution_count : null,
+      ...(cell.id ? { id: cell.id } : {})
+    };
+  });
+  return { language, cells };
+}
+
+/**
+ * One or more chunks per non-empty code or markdown cell, in notebook order. Null when the
+ * content is not a notebook, so the caller can fall back to plain chunking.
+ */
+export async function createNotebookChunks(
+  content: string,
+  options: NotebookChunkOptions
+): Promise<{ chunks: CodeChunk[]; notebook: Notebook } | null> {
+  const notebook = parseNotebook(content);
+  if (!notebook) return null;
+
+  const chunks: CodeChunk[] = [];
+  for (const cell of notebook.cells) {
+    if (cell.cellType === 'raw' || !cell.source.trim()) continue;
+    const symbols =
+      cell.cellType === 'code'
+        ? ((await extractTreeSitterSymbols(cell.source, cell.language))?.symbols ?? [])
+        : [];
+    const cellChunks = createASTAlignedChunks(cell.source, symbols, {
+      ...DEFAULT_AST_CHUNK_OPTIONS,
+      maxChunkTokens: options.maxChunkTokens,
+      overlapLines: options.overlapLines,
+      filePath: options.filePath,
+      language: cell.language,
+      componentType: cell.cellType === 'code' ? 'notebook-cell' : 'documentation'
+    });
+
+    for (const chunk of cellChunks) {
+      chunks.push({
+        ...chunk,
+        relativePath: options.relativePath,
+        tags: [...new Set([...chunk.tags, 'notebook', cell.cellType])],
+        metadata: {
+          ...chunk.metadata,
+          cellIndex: cell.index,
+          cellType: cell.cellType,
+          ...(cell.executionCount !== null ? { executionCount: cell.executionCount } : {}),
+          ...(cell.id ? { cellId: cell.id } : {}),
+          chunkStrategy: symbols.length > 0 ? 'notebook-ast-aligned' : 'notebook-cell'
+        }
+      });
+    }
+  }
+  return { chunks, notebook };
+}
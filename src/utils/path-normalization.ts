@@ -0,0 +1,40 @@
+/**
+ * Project roots arrive from CLI arguments, env vars and MCP client configs in whatever form
+ * the user typed them. On Windows that includes quoted paths, forward slashes, lowercase drive
+ * letters, UNC shares and `\\?\` long-path prefixes. Roots are normalized to one absolute form
+ * so relative paths, artifact locations and project lookups agree however the root was given.
+ */
+
+import path from 'path';
+
+const QUOTED = /^(["'])(.*)\1$/;
+/** `\\?\UNC\server\share` (or with forward slashes) */
+const LONG_UNC_PREFIX = /^[\\/]{2}\?[\\/]UNC[\\/]/i;
+/** `\\?\C:\...`; Node adds the prefix itself where it's needed */
+const LONG_PATH_PREFIX = /^[\\/]{2}\?[\\/](?=[A-Za-z]:)/;
+
+/** Absolute, platform-native root path */
+export function normalizeRootPath(
+  input: string,
+  platform: NodeJS.Platform = process.platform
+): string {
+  let value = input.trim();
+  const quoted = QUOTED.exec(value);
+  if (quoted) value = quoted[2].trim();
+
+  if (platform !== 'win32') return path.posix.resolve(value);
+
+  value = value.replace(LONG_UNC_PREFIX, '\\\\').replace(LONG_PATH_PREFIX, '');
+  // Resolves forward slashes and drops a trailing separator (but not the one of `C:\`)
+  const resolved = path.win32.resolve(value);
+  return /^[a-z]:/.test(resolved) ? resolved[0].toUpperCase() + resolved.slice(1) : resolved;
+}
+
+/** Whether `target` is `root` or lies below it (both resolved; no symlinks are followed) */
+export function isPathInside(root: string, target: string): boolean {
+  const relative = path.relative(path.resolve(root), path.resolve(target));
+  return (
+    relative === '' ||
+    (relative !== '..' && !relative.startsWith(`..${path.sep}`) && !path.isAbsolute(relative))
+  );
+}
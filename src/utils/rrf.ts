@@ -0,0 +1,17 @@
+/**
+ * Reciprocal Rank Fusion
+ * Shared scoring for every ranked-list merge (hybrid search, keyword lists, commit history) so
+ * all of them use the same constant and rank convention
+ */
+
+/** RRF constant: k=60 is the standard parameter (Elasticsearch, TOSS paper arXiv:2208.11274) */
+export const RRF_K = 60;
+
+/**
+ * Contribution of one list position to a document's fused score.
+ * `index` is the 0-based position in the list; RRF ranks are 1-based, so the head of a list
+ * scores `weight / (k + 1)`.
+ */
+export function rrfContribution(index: number, weight = 1): number {
+  return weight / (RRF_K + index + 1);
+}
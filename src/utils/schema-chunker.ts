@@ -0,0 +1,688 @@
+/**
+ * Contract-level chunking for API schemas: Protobuf `.proto` files get one chunk per message
+ * and enum and one per rpc, OpenAPI/Swagger specs (YAML or JSON) one per operation and per
+ * component schema, and GraphQL SDL one per type and one per `Query`/`Mutation`/`Subscription`
+ * field. Each chunk names what it defines (`user.v1.UserService.GetUser`, `GET /users/{id}`,
+ * `Query.orders`), so a question about an RPC lands on its contract; the indexer then links
+ * handlers, resolvers and generated code back to the schema file.
+ */
+
+import { v4 as uuidv4 } from 'uuid';
+import type { CodeChunk, SchemaMetadata } from '../types/index.js';
+import { DEFAULT_AST_CHUNK_OPTIONS, splitOversizedChunks } from './ast-chunker.js';
+import type { TreeSitterSymbol } from './tree-sitter.js';
+
+export interface SchemaChunkOptions {
+  filePath: string;
+  relativePath: string;
+  language: string;
+}
+
+export interface SchemaBlock {
+  /** 1-based inclusive line range, leading comments included */
+  startLine: number;
+  endLine: number;
+  schema: SchemaMetadata;
+}
+
+// ---------------------------------------------------------------------------
+// Protobuf
+// ---------------------------------------------------------------------------
+
+const PROTO_DECLARATION = /(message|enum|service)\s+([A-Za-z_]\w*)\s*\{/y;
+const PROTO_RPC =
+  /rpc\s+([A-Za-z_]\w*)\s*\(\s*(?:stream\s+)?([\w.]+)\s*\)\s*returns\s*\(\s*(?:stream\s+)?([\w.]+)\s*\)/y;
+const PROTO_PACKAGE = /^\s*package\s+([\w.]+)\s*;/m;
+const PROTO_COMMENT_LINE = /^\s*(\/\/|\/\*|\*)/;
+
+/** Comments and string contents blanked out (newlines kept), so offsets still line up */
+function maskProto(content: string): string {
+  let out = '';
+  let state: 'code' | 'line' | 'block' | 'string' = 'code';
+  let quote = '';
+  for (let i = 0; i < content.length; i++) {
+    const ch = content[i];
+    const next = content[i + 1];
+    if (ch === '\n') {
+      if (state === 'line') state = 'code';
+      out += ch;
+    } else if (state === 'line') {
+      out += ' ';
+    } else if (state === 'block') {
+      if (ch === '*' && next === '/') {
+        state = 'code';
+        out += '  ';
+        i++;
+      } else out += ' ';
+    } else if (state === 'string') {
+      if (ch === '\\') {
+        out += '  ';
+        i++;
+      } else if (ch === quote) {
+        state = 'code';
+        out += ch;
+      } else out += ' ';
+    } else if (ch === '/' && next === '/') {
+      state = 'line';
+      out += ' ';
+    } else if (ch === '/' && next === '*') {
+      state = 'block';
+      out += '  ';
+      i++;
+    } else if (ch === '"' || ch === "'") {
+      state = 'string';
+      quote = ch;
+      out += ch;
+    } else out += ch;
+  }
+  return out;
+}
+
+function lineIndex(content: string): (offset: number) => number {
+  const starts = [0];
+  for (let i = 0; i < content.length; i++) if (content[i] === '\n') starts.push(i + 1);
+  return (offset) => {
+    let lo = 0;
+    let hi = starts.length - 1;
+    while (lo < hi) {
+      const mid = (lo + hi + 1) >> 1;
+      if (starts[mid] <= offset) lo = mid;
+      else hi = mid - 1;
+    }
+    return lo + 1;
+  };
+}
+
+/** Extend a block over the comment lines directly above it, not past `floor` */
+function withLeadingComments(
+  lines: string[],
+  startLine: number,
+  floor: number,
+  commentLine = PROTO_COMMENT_LINE
+): number {
+  let start = startLine;
+  while (start - 1 > floor && commentLine.test(lines[start - 2])) start--;
+  return start;
+}
+
+/**
+ * Top-level messages, enums and services, plus each service's rpcs (whose ranges fall inside
+ * their service block). Nested messages stay inside their parent.
+ */
+export function findProtoBlocks(content: string): SchemaBlock[] {
+  const masked = maskProto(content);
+  const lines = content.split('\n');
+  const lineOf = lineIndex(content);
+  const pkg = PROTO_PACKAGE.exec(masked)?.[1];
+  const qualify = (name: string) => (pkg ? `${pkg}.${name}` : name);
+
+  const blocks: SchemaBlock[] = [];
+  let depth = 0;
+  let open: { type: 'message' | 'enum' | 'service'; name: string; start: number } | null = null;
+  let rpc: { name: string; request: string; response: string; start: number } | null = null;
+  // Leading comments never reach back into the previous block
+  let topFloor = 0;
+  let rpcFloor = 0;
+
+  const closeRpc = (end: number) => {
+    if (!rpc || !open) return;
+    const startLine = lineOf(rpc.start);
+    blocks.push({
+      startLine: withLeadingComments(lines, startLine, rpcFloor),
+      endLine: lineOf(end),
+      schema: {
+        kind: 'protobuf',
+        type: 'rpc',
+        name: rpc.name,
+        address: qualify(`${open.name}.${rpc.name}`),
+        ...(pkg ? { package: pkg } : {}),
+        service: open.name,
+        requestType: rpc.request,
+        responseType: rpc.response
+      }
+    });
+    rpcFloor = lineOf(end);
+    rpc = null;
+  };
+
+  for (let i = 0; i < masked.length; i++) {
+    const ch = masked[i];
+    const atWord = i === 0 || !/\w/.test(masked[i - 1]);
+    if (atWord && depth === 0 && !open) {
+      PROTO_DECLARATION.lastIndex = i;
+      const match = PROTO_DECLARATION.exec(masked);
+      if (match) {
+        open = { type: match[1] as 'message' | 'enum' | 'service', name: match[2], start: i };
+        rpcFloor = lineOf(i);
+        i += match[0].length - 2; // the `{` is counted below
+        continue;
+      }
+    }
+    if (atWord && depth === 1 && open?.type === 'service' && !rpc) {
+      PROTO_RPC.lastIndex = i;
+      const match = PROTO_RPC.exec(masked);
+      if (match) {
+        rpc = { name: match[1], request: match[2], response: match[3], start: i };
+        i += match[0].length - 1;
+        continue;
+      }
+    }
+
+    if (ch === '{') {
+      depth++;
+    } else if (ch === '}') {
+      depth = Math.max(0, depth - 1);
+      if (depth === 1 && rpc) closeRpc(i);
+      if (depth === 0 && open) {
+        blocks.push({
+          startLine: withLeadingComments(lines, lineOf(open.start), topFloor),
+          endLine: lineOf(i),
+          schema: {
+            kind: 'protobuf',
+            type: open.type,
+            name: open.name,
+            address: qualify(open.name),
+            ...(pkg ? { package: pkg } : {})
+          }
+        });
+        open = null;
+        topFloor = lineOf(i);
+      }
+    } else if (ch === ';' && depth === 1 && rpc) {
+      closeRpc(i);
+    }
+  }
+
+  return blocks.sort((a, b) => a.startLine - b.startLine || b.endLine - a.endLine);
+}
+
+// ---------------------------------------------------------------------------
+// OpenAPI / Swagger
+// ---------------------------------------------------------------------------
+
+const HTTP_METHODS = new Set(['get', 'put', 'post', 'delete', 'options', 'head', 'patch', 'trace']);
+// `paths:` / `  /users/{id}:` (YAML) and `  "paths": {` (JSON)
+const KEY_LINE = /^(\s*)(?:"((?:[^"\\]|\\.)*)"\s*:|'([^']*)'\s*:|([^\s#'"{}[\],-][^:#]*?)\s*:(?=\s|$))/;
+const OPERATION_ID = /^\s*"?operationId"?\s*:\s*["']?([^"',\s]+)/;
+
+interface KeyLine {
+  /** 0-based */
+  line: number;
+  indent: number;
+  key: string;
+}
+
+/** True for YAML/JSON documents with a top-level `openapi` or `swagger` version key */
+export function isOpenApiDocument(content: string, language: string): boolean {
+  if (language === 'yaml') return /^(openapi|swagger)\s*:/m.test(content);
+  if (language === 'json') return /^\s*"(openapi|swagger)"\s*:/m.test(content);
+  return false;
+}
+
+function keyLines(lines: string[]): KeyLine[] {
+  const keys: KeyLine[] = [];
+  lines.forEach((text, line) => {
+    const match = KEY_LINE.exec(text);
+    if (match) {
+      keys.push({ line, indent: match[1].length, key: match[2] ?? match[3] ?? match[4] });
+    }
+  });
+  return keys;
+}
+
+/** Direct children of `keys[parent]`, each with the last line (0-based) of its subtree */
+function children(keys: KeyLine[], parent: number, lastLine: number) {
+  const result: Array<KeyLine & { end: number }> = [];
+  const parentIndent = keys[parent].indent;
+  let childIndent = -1;
+  for (let k = parent + 1; k < keys.length && keys[k].indent > parentIndent; k++) {
+    if (childIndent < 0) childIndent = keys[k].indent;
+    if (keys[k].indent !== childIndent) continue;
+    if (result.length > 0) result[result.length - 1].end = keys[k].line - 1;
+    result.push({ ...keys[k], end: lastLine });
+  }
+  return result;
+}
+
+/** Last line (0-based) of the subtree of `keys[index]` */
+function subtreeEnd(keys: KeyLine[], index: number, lines: string[]): number {
+  const next = keys.findIndex((key, k) => k > index && key.indent <= keys[index].indent);
+  return next < 0 ? lines.length - 1 : keys[next].line - 1;
+}
+
+const indentOf = (line: string) => line.length - line.trimStart().length;
+
+/** Drop trailing blank lines, and in JSON the closing brackets of enclosing objects */
+function trimEnd(lines: string[], start: number, end: number): number {
+  const indent = indentOf(lines[start]);
+  while (
+    end > start &&
+    (!lines[end].trim() || (/^\s*[}\]],?\s*$/.test(lines[end]) && indentOf(lines[end]) < indent))
+  ) {
+    end--;
+  }
+  return end;
+}
+
+/** One block per path operation and per component schema (`definitions` in Swagger 2) */
+export function findOpenApiBlocks(content: string): SchemaBlock[] {
+  const lines = content.split('\n');
+  const keys = keyLines(lines);
+  const version = keys.find((key) => key.key === 'openapi' || key.key === 'swagger');
+  if (!version) return [];
+  const top = (name: string) =>
+    keys.findIndex((key) => key.key === name && key.indent === version.indent);
+
+  const blocks: SchemaBlock[] = [];
+  const paths = top('paths');
+  if (paths >= 0) {
+    const pathsEnd = subtreeEnd(keys, paths, lines);
+    for (const route of children(keys, paths, pathsEnd)) {
+      const routeIndex = keys.findIndex((key) => key.line === route.line);
+      for (const method of children(keys, routeIndex, route.end)) {
+        if (!HTTP_METHODS.has(method.key.toLowerCase())) continue;
+        const end = trimEnd(lines, method.line, method.end);
+        const body = lines.slice(method.line, end + 1);
+        const operationId = body.map((line) => OPERATION_ID.exec(line)?.[1]).find(Boolean);
+        const httpMethod = method.key.toUpperCase();
+        const address = `${httpMethod} ${route.key}`;
+        blocks.push({
+          startLine: method.line + 1,
+          endLine: end + 1,
+          schema: {
+            kind: 'openapi',
+            type: 'operation',
+            name: operationId ?? address,
+            address,
+            httpMethod,
+            path: route.key,
+            ...(operationId ? { operationId } : {})
+          }
+        });
+      }
+    }
+  }
+
+  const schemaSections: Array<[number, string]> = [];
+  const components = top('components');
+  if (components >= 0) {
+    const componentsEnd = subtreeEnd(keys, components, lines);
+    const schemas = children(keys, components, componentsEnd).find((c) => c.key === 'schemas');
+    if (schemas) {
+      schemaSections.push([keys.findIndex((k) => k.line === schemas.line), '#/components/schemas']);
+    }
+  }
+  const definitions = top('definitions');
+  if (definitions >= 0) schemaSections.push([definitions, '#/definitions']);
+
+  for (const [section, prefix] of schemaSections) {
+    const sectionEnd = subtreeEnd(keys, section, lines);
+    for (const schema of children(keys, section, sectionEnd)) {
+      blocks.push({
+        startLine: schema.line + 1,
+        endLine: trimEnd(lines, schema.line, schema.end) + 1,
+        schema: {
+          kind: 'openapi',
+          type: 'schema',
+          name: schema.key,
+          address: `${prefix}/${schema.key}`
+        }
+      });
+    }
+  }
+
+  return blocks.sort((a, b) => a.startLine - b.startLine);
+}
+
+// ---------------------------------------------------------------------------
+// GraphQL SDL
+// ---------------------------------------------------------------------------
+
+const GRAPHQL_DEFINITION = /(extend\s+)?(type|interface|input|enum|union|scalar)\s+([_A-Za-z]\w*)/y;
+const GRAPHQL_FIELD = /([_A-Za-z]\w*)\s*[(:]/y;
+/** A line starting the next definition (or its description), which ends a body-less one */
+const GRAPHQL_NEXT_DEFINITION =
+  /\n[ \t]*(?:"|(?:extend\s+)?(?:type|interface|input|enum|union|scalar|schema|directive)\b)/g;
+const GRAPHQL_COMMENT_LINE = /^\s*#/;
+const GRAPHQL_OPERATIONS = ['query', 'mutation', 'subscription'] as const;
+
+type GraphqlOperation = (typeof GRAPHQL_OPERATIONS)[number];
+type GraphqlBlockType = 'type' | 'interface' | 'input' | 'enum' | 'union' | 'scalar';
+
+/** Comments and string contents (descriptions included) blanked out, quotes and newlines kept */
+function maskGraphql(content: string): string {
+  let out = '';
+  let state: 'code' | 'comment' | 'string' | 'block' = 'code';
+  for (let i = 0; i < content.length; i++) {
+    const ch = content[i];
+    if (ch === '\n') {
+      if (state === 'comment') state = 'code';
+      // A newline ends an unterminated single-line string
+      if (state === 'string') state = 'code';
+      out += ch;
+    } else if (state === 'comment') {
+      out += ' ';
+    } else if (state === 'block') {
+      if (content.startsWith('\\"""', i)) {
+        out += '    ';
+        i += 3;
+      } else if (content.startsWith('"""', i)) {
+        state = 'code';
+        out += '"""';
+        i += 2;
+      } else out += ' ';
+    } else if (state === 'string') {
+      if (ch === '\\') {
+        out += '  ';
+        i++;
+      } else if (ch === '"') {
+        state = 'code';
+        out += ch;
+      } else out += ' ';
+    } else if (ch === '#') {
+      state = 'comment';
+      out += ' ';
+    } else if (content.startsWith('"""', i)) {
+      state = 'block';
+      out += '"""';
+      i += 2;
+    } else if (ch === '"') {
+      state = 'string';
+      out += ch;
+    } else out += ch;
+  }
+  return out;
+}
+
+/** Types named by `schema { query: ... }`, else `Query`, `Mutation` and `Subscription` */
+function rootOperationTypes(masked: string): Map<string, GraphqlOperation> {
+  const roots = new Map<string, GraphqlOperation>();
+  const schema = /(?:^|\n)\s*schema\b[^{]*\{([^}]*)\}/.exec(masked);
+  if (schema) {
+    const entries = schema[1].matchAll(/\b(query|mutation|subscription)\s*:\s*(\w+)/g);
+    for (const [, operation, type] of entries) {
+      roots.set(type, operation as GraphqlOperation);
+    }
+  }
+  if (roots.size === 0) {
+    for (const operation of GRAPHQL_OPERATIONS) {
+      roots.set(operation[0].toUpperCase() + operation.slice(1), operation);
+    }
+  }
+  return roots;
+}
+
+/** Offset of the description string right before `offset`, else `offset` */
+function descriptionStart(masked: string, offset: number): number {
+  let end = offset - 1;
+  while (end >= 0 && /\s/.test(masked[end])) end--;
+  if (masked[end] !== '"') return offset;
+  const start = masked.startsWith('"""', end - 2)
+    ? masked.lastIndexOf('"""', end - 3)
+    : masked.lastIndexOf('"', end - 1);
+  return start >= 0 ? start : offset;
+}
+
+/** Last non-whitespace offset before `offset` */
+function lastCode(masked: string, offset: number): number {
+  let end = offset - 1;
+  while (end > 0 && /\s/.test(masked[end])) end--;
+  return end;
+}
+
+/**
+ * Types, interfaces, inputs, enums, unions and scalars (`extend type` included, under the
+ * extended name), plus one block per field of the root operation types, inside its type.
+ * Descriptions and `#` comments directly above go with what they describe.
+ */
+export function findGraphqlBlocks(content: string): SchemaBlock[] {
+  const masked = maskGraphql(content);
+  const lines = content.split('\n');
+  const lineOf = lineIndex(content);
+  const roots = rootOperationTypes(masked);
+
+  const blocks: SchemaBlock[] = [];
+  let depth = 0;
+  let parens = 0;
+  let open: { type: GraphqlBlockType; name: string; start: number } | null = null;
+  let field: { name: string; start: number; colon: number } | null = null;
+  let topFloor = 0;
+  let fieldFloor = 0;
+
+  const pushType = (type: GraphqlBlockType, name: string, start: number, end: number) => {
+    blocks.push({
+      startLine: withLeadingComments(lines, lineOf(start), topFloor, GRAPHQL_COMMENT_LINE),
+      endLine: lineOf(end),
+      schema: { kind: 'graphql', type, name, address: name }
+    });
+    topFloor = lineOf(end);
+  };
+  const closeField = (before: number) => {
+    if (!field || !open) return;
+    const endLine = lineOf(lastCode(masked, before));
+    // `[Order!]!` -> `Order`
+    const responseType =
+      field.colon >= 0
+        ? /^[\s[]*([_A-Za-z]\w*)/.exec(masked.slice(field.colon + 1))?.[1]
+        : undefined;
+    blocks.push({
+      startLine: withLeadingComments(lines, lineOf(field.start), fieldFloor, GRAPHQL_COMMENT_LINE),
+      endLine,
+      schema: {
+        kind: 'graphql',
+        type: 'field',
+        name: field.name,
+        address: `${open.name}.${field.name}`,
+        parentType: open.name,
+        operationType: roots.get(open.name),
+        ...(responseType ? { responseType } : {})
+      }
+    });
+    fieldFloor = endLine;
+    field = null;
+  };
+
+  for (let i = 0; i < masked.length; i++) {
+    const ch = masked[i];
+    const atWord = i === 0 || !/\w/.test(masked[i - 1]);
+    if (atWord && depth === 0 && !open) {
+      GRAPHQL_DEFINITION.lastIndex = i;
+      const match = GRAPHQL_DEFINITION.exec(masked);
+      if (match) {
+        const type = match[2] as GraphqlBlockType;
+        const start = descriptionStart(masked, i);
+        GRAPHQL_NEXT_DEFINITION.lastIndex = i + match[0].length;
+        const next = GRAPHQL_NEXT_DEFINITION.exec(masked)?.index ?? masked.length;
+        const brace = masked.indexOf('{', i + match[0].length);
+        if (type !== 'union' && type !== 'scalar' && brace >= 0 && brace < next) {
+          open = { type, name: match[3], start };
+          fieldFloor = lineOf(brace);
+          i = brace - 1; // the `{` is counted below
+        } else {
+          // `union X = A | B`, `scalar Date`, `extend type X @key(fields: "id")`
+          pushType(type, match[3], start, lastCode(masked, next));
+          i = next - 1;
+        }
+        continue;
+      }
+    }
+    if (atWord && depth === 1 && parens === 0 && open && roots.has(open.name)) {
+      GRAPHQL_FIELD.lastIndex = i;
+      const match = masked[i - 1] === '@' ? null : GRAPHQL_FIELD.exec(masked);
+      if (match) {
+        const start = descriptionStart(masked, i);
+        closeField(start);
+        field = { name: match[1], start, colon: -1 };
+        i += match[1].length - 1;
+        continue;
+      }
+    }
+
+    if (ch === '(') parens++;
+    else if (ch === ')') parens = Math.max(0, parens - 1);
+    else if (ch === ':' && depth === 1 && parens === 0 && field && field.colon < 0) field.colon = i;
+    else if (ch === '{') depth++;
+    else if (ch === '}') {
+      depth = Math.max(0, depth - 1);
+      if (depth === 0 && open) {
+        closeField(i);
+        pushType(open.type, open.name, open.start, i);
+        open = null;
+      }
+    }
+  }
+
+  return blocks.sort((a, b) => a.startLine - b.startLine || b.endLine - a.endLine);
+}
+
+/** Schema blocks of a file: `.proto`, GraphQL SDL, or a YAML/JSON OpenAPI document */
+export function findSchemaBlocks(content: string, language: string): SchemaBlock[] {
+  if (language === 'proto') return findProtoBlocks(content);
+  if (language === 'graphql') return findGraphqlBlocks(content);
+  return isOpenApiDocument(content, language) ? findOpenApiBlocks(content) : [];
+}
+
+// ---------------------------------------------------------------------------
+// Symbols and chunks
+// ---------------------------------------------------------------------------
+
+const SYMBOL_KINDS: Record<SchemaMetadata['type'], string> = {
+  message: 'message',
+  enum: 'enum',
+  service: 'service',
+  rpc: 'rpc',
+  operation: 'endpoint',
+  schema: 'type',
+  type: 'type',
+  interface: 'interface',
+  input: 'type',
+  union: 'type',
+  scalar: 'type',
+  field: 'field'
+};
+
+/** Blocks chunked through their children: services through rpcs, root types through fields */
+const CHILD_TYPES: Partial<Record<SchemaMetadata['type'], SchemaMetadata['type']>> = {
+  service: 'rpc',
+  type: 'field'
+};
+
+/** Definitions for the symbol index: messages, services, rpcs, operations, schemas, types */
+export function schemaSymbols(content: string, blocks: SchemaBlock[]): TreeSitterSymbol[] {
+  const lines = content.split('\n');
+  return blocks.map((block) => ({
+    name: block.schema.name,
+    kind: SYMBOL_KINDS[block.schema.type],
+    startLine: block.startLine,
+    endLine: block.endLine,
+    startIndex: 0,
+    endIndex: 0,
+    content: lines.slice(block.startLine - 1, block.endLine).join('\n'),
+    nodeType: block.schema.type,
+    qualifiedName: block.schema.address,
+    ...(block.schema.package ? { namespace: block.schema.package } : {})
+  }));
+}
+
+/**
+ * Services that declare rpcs are chunked through them: the first rpc chunk also covers the
+ * service header and the last one its closing brace. GraphQL root types go the same way
+ * through their fields.
+ */
+function chunkBlocks(blocks: SchemaBlock[]): SchemaBlock[] {
+  const result: SchemaBlock[] = [];
+  for (const block of blocks) {
+    if (block.schema.type === 'rpc' || block.schema.type === 'field') continue;
+    const childType = CHILD_TYPES[block.schema.type];
+    const rpcs = blocks.filter(
+      (rpc) =>
+        rpc.schema.type === childType &&
+        rpc.schema.kind === block.schema.kind &&
+        rpc.startLine >= block.startLine &&
+        rpc.endLine <= block.endLine
+    );
+    if (!childType || rpcs.length === 0) {
+      result.push(block);
+      continue;
+    }
+    rpcs.forEach((rpc, index) => {
+      result.push({
+        ...rpc,
+        startLine: index === 0 ? block.startLine : rpc.startLine,
+        endLine: index === rpcs.length - 1 ? block.endLine : rpc.endLine
+      });
+    });
+  }
+  return result.sort((a, b) => a.startLine - b.startLine);
+}
+
+function makeChunk(
+  lines: string[],
+  startLine: number,
+  endLine: number,
+  options: SchemaChunkOptions,
+  block?: SchemaBlock
+): CodeChunk {
+  const body = lines.slice(startLine - 1, endLine).join('\n');
+  const schema = block?.schema;
+  const comment = options.language === 'proto' ? '//' : '#';
+
+  return {
+    id: uuidv4(),
+    content: schema ? `${comment} ${schema.address} :: ${schema.type}\n${body}` : body,
+    filePath: options.filePath,
+    relativePath: options.relativePath,
+    startLine,
+    endLine,
+    language: options.language,
+    framework: schema?.kind,
+    componentType: 'schema',
+    dependencies: [],
+    imports: [],
+    exports: [],
+    tags: schema ? [schema.kind, schema.type] : [],
+    metadata: schema
+      ? {
+          symbolAware: true,
+          symbolName: schema.name,
+          symbolKind: SYMBOL_KINDS[schema.type],
+          qualifiedName: schema.address,
+          ...(schema.package ? { namespace: schema.package } : {}),
+          chunkStrategy: 'schema-block',
+          schema
+        }
+      : { chunkStrategy: 'schema-block' }
+  };
+}
+
+/**
+ * Contract-level chunks for `.proto` files, GraphQL SDL and OpenAPI documents. Returns null
+ * when the file defines nothing recognizable (including YAML/JSON that is not OpenAPI), so
+ * callers fall back to regular chunking.
+ */
+export function createSchemaChunks(
+  content: string,
+  options: SchemaChunkOptions
+): CodeChunk[] | null {
+  const blocks = chunkBlocks(findSchemaBlocks(content, options.language));
+  if (blocks.length === 0) return null;
+
+  const lines = content.split('\n');
+  const chunks: CodeChunk[] = [];
+  let cursor = 1;
+  const pushGap = (endLine: number) => {
+    const gap = lines.slice(cursor - 1, endLine);
+    if (gap.some((line) => /[^\s{}[\],]/.test(line))) {
+      chunks.push(makeChunk(lines, cursor, endLine, options));
+    }
+  };
+
+  for (const block of blocks) {
+    if (block.startLine > cursor) pushGap(block.startLine - 1);
+    chunks.push(makeChunk(lines, block.startLine, block.endLine, options, block));
+    cursor = block.endLine + 1;
+  }
+  if (cursor <= lines.length) pushGap(lines.length);
+
+  return splitOversizedChunks(chunks, DEFAULT_AST_CHUNK_OPTIONS.maxChunkLines);
+}
@@ -0,0 +1,435 @@
+/**
+ * Single-file components (Vue, Svelte, Astro): the file is split into its script, template
+ * (markup) and style blocks so each is chunked on its own. Script blocks, including Astro
+ * frontmatter, are parsed with the TypeScript or JavaScript grammar and their symbols, call
+ * sites and line numbers are mapped back to the file. The component itself and the props it
+ * declares are recorded as symbols too.
+ */
+
+import path from 'path';
+import type { CodeChunk } from '../types/index.js';
+import { createASTAlignedChunks, DEFAULT_AST_CHUNK_OPTIONS } from './ast-chunker.js';
+import {
+  extractTreeSitterCalls,
+  extractTreeSitterSymbols,
+  type TreeSitterCallExtraction,
+  type TreeSitterSymbol
+} from './tree-sitter.js';
+
+export const SFC_LANGUAGES: ReadonlySet<string> = new Set(['vue', 'svelte', 'astro']);
+
+export function isSfcLanguage(language: string): boolean {
+  return SFC_LANGUAGES.has(language);
+}
+
+export type SfcBlockKind = 'script' | 'template' | 'style';
+
+export interface SfcBlock {
+  kind: SfcBlockKind;
+  /** Script grammar (`typescript`, `javascript`, ...) or the style/template `lang` attribute */
+  lang?: string;
+  /** 1-based inclusive line range of the block body, without its tags */
+  startLine: number;
+  endLine: number;
+  /** Character offset of the body in the file */
+  offset: number;
+  content: string;
+}
+
+export interface SfcProp {
+  name: string;
+  line: number;
+}
+
+export interface SfcFile {
+  componentName: string;
+  blocks: SfcBlock[];
+  props: SfcProp[];
+}
+
+export interface SfcChunkOptions {
+  filePath: string;
+  relativePath: string;
+  language: string;
+  maxChunkTokens?: number;
+  overlapLines?: number;
+}
+
+const BLOCK_OPEN = /^<(script|template|style)\b([^>]*)>/i;
+const LANG_ATTRIBUTE = /\blang\s*=\s*["']([\w-]+)["']/i;
+/** Vue: `defineOptions({ name: 'X' })` or `name: 'X'` in the exported options object */
+const VUE_COMPONENT_NAME =
+  /\b(?:defineOptions\s*\(|export\s+default\s+(?:defineComponent\s*\()?)\s*\{[^}]*?\bname\s*:\s*["']([\w-]+)["']/s;
+
+function scriptGrammar(lang?: string): string {
+  switch (lang?.toLowerCase()) {
+    case 'ts':
+    case 'typescript':
+      return 'typescript';
+    case 'tsx':
+      return 'typescriptreact';
+    case 'jsx':
+      return 'javascriptreact';
+    default:
+      return 'javascript';
+  }
+}
+
+/** `user-card.vue` -> `UserCard` */
+function componentNameFromPath(filePath: string): string {
+  const base = path.basename(filePath, path.extname(filePath));
+  return base
+    .split(/[-_.\s]+/)
+    .filter(Boolean)
+    .map((part) => part[0].toUpperCase() + part.slice(1))
+    .join('');
+}
+
+/** Text between the bracket at `open` and its match (strings and comments are not special) */
+function bracketBody(text: string, open: number): { body: string; start: number } | null {
+  const pairs: Record<string, string> = { '{': '}', '[': ']', '(': ')', '<': '>' };
+  const opener = text[open];
+  const closer = pairs[opener];
+  if (!closer) return null;
+  let depth = 0;
+  for (let i = open; i < text.length; i++) {
+    if (text[i] === opener) depth++;
+    else if (text[i] === closer && --depth === 0) {
+      return { body: text.slice(open + 1, i), start: open + 1 };
+    }
+  }
+  return null;
+}
+
+const MEMBER_NAME = /^(?:readonly\s+)?["']?([A-Za-z_$][\w$-]*)["']?\s*(?:\?\s*)?(?::|=|\(|$)/;
+
+/** Names of the top-level members of an object literal, type literal or array of strings */
+function topLevelMembers(body: string, start: number): Array<{ name: string; offset: number }> {
+  const members: Array<{ name: string; offset: number }> = [];
+  let depth = 0;
+  let pieceStart = 0;
+  const flush = (end: number) => {
+    const raw = body.slice(pieceStart, end);
+    const leading = raw.length - raw.trimStart().length;
+    const piece = raw.trim();
+    // Comments, and `...rest` which collects undeclared props
+    const skip = piece.startsWith('//') || piece.startsWith('/*') || piece.startsWith('...');
+    const match = skip ? null : MEMBER_NAME.exec(piece);
+    if (match) members.push({ name: match[1], offset: start + pieceStart + leading });
+  };
+  for (let i = 0; i < body.length; i++) {
+    const char = body[i];
+    if ('{[(<'.includes(char)) depth++;
+    else if ('}])>'.includes(char) && !(char === '>' && body[i - 1] === '=')) depth--;
+    else if (depth === 0 && (char === ',' || char === ';' || char === '\n')) {
+      flush(i);
+      pieceStart = i + 1;
+    }
+  }
+  flush(body.length);
+  return members;
+}
+
+/** Members of `interface Props {...}` / `type Props = {...}` declared in the script */
+function propsTypeMembers(script: string, typeName: string) {
+  const declaration = new RegExp(
+    `\\b(?:interface\\s+${typeName}\\b[^{]*|type\\s+${typeName}\\s*=\\s*)\\{`
+  ).exec(script);
+  if (!declaration) return [];
+  const body = bracketBody(script, declaration.index + declaration[0].length - 1);
+  return body ? topLevelMembers(body.body, body.start) : [];
+}
+
+/** Names destructured by `let {...}`/`const {...}` when the value matches `source` */
+function destructuredFrom(script: string, declaration: RegExp, source: RegExp) {
+  for (const match of script.matchAll(declaration)) {
+    const open = match.index + match[0].length - 1;
+    const body = bracketBody(script, open);
+    if (body && source.test(script.slice(body.start + body.body.length + 1))) {
+      return topLevelMembers(body.body, body.start);
+    }
+  }
+  return [];
+}
+
+function findProps(language: string, script: string): Array<{ name: string; offset: number }> {
+  const members: Array<{ name: string; offset: number }> = [];
+  const fromBracket = (open: number) => {
+    const body = bracketBody(script, open);
+    if (body) members.push(...topLevelMembers(body.body, body.start));
+  };
+
+  if (language === 'vue') {
+    // defineProps<{ ... }>() / defineProps<Props>() / defineProps({ ... }) / defineProps([...])
+    for (const match of script.matchAll(/\bdefineProps\s*(<\s*)?/g)) {
+      const after = match.index + match[0].length;
+      if (match[1]) {
+        if (script[after] === '{') fromBracket(after);
+        else {
+          const typeName = /^[A-Za-z_$][\w$]*/.exec(script.slice(after))?.[0];
+          if (typeName) members.push(...propsTypeMembers(script, typeName));
+        }
+      } else if (script[after] === '(') {
+        const argument = /^\(\s*/.exec(script.slice(after))![0];
+        if ('{['.includes(script[after + argument.length] ?? '')) {
+          fromBracket(after + argument.length);
+        }
+      }
+    }
+    // Options API: `props: ['a']` / `props: { a: String }`
+    const options = /\bprops\s*:\s*([[{])/.exec(script);
+    if (options) fromBracket(options.index + options[0].length - 1);
+  } else if (language === 'svelte') {
+    for (const match of script.matchAll(/^\s*export\s+let\s+([A-Za-z_$][\w$]*)/gm)) {
+      members.push({ name: match[1], offset: match.index + match[0].length - match[1].length });
+    }
+    // Svelte 5 runes: `let { a, b = 1 } = $props()`
+    members.push(...destructuredFrom(script, /\blet\s*\{/g, /^\s*(?::[^=]+)?=\s*\$props\s*\(/));
+  } else if (language === 'astro') {
+    members.push(...propsTypeMembers(script, 'Props'));
+    if (members.length === 0) {
+      members.push(...destructuredFrom(script, /\bconst\s*\{/g, /^\s*=\s*Astro\.props\b/));
+    }
+  }
+
+  const seen = new Set<string>();
+  return members.filter((member) => !seen.has(member.name) && seen.add(member.name));
+}
+
+function lineAt(content: string, offset: number): number {
+  let line = 1;
+  for (let i = 0; i < offset && i < content.length; i++) {
+    if (content[i] === '\n') line++;
+  }
+  return line;
+}
+
+/** Split an SFC into blocks. Markup outside the blocks is the template for Svelte/Astro. */
+export function parseSfc(content: string, language: string, filePath: string): SfcFile {
+  const lines = content.split('\n');
+  const lineOffsets: number[] = [];
+  let offset = 0;
+  for (const line of lines) {
+    lineOffsets.push(offset);
+    offset += line.length + 1;
+  }
+
+  const blocks: SfcBlock[] = [];
+  const addBlock = (kind: SfcBlockKind, first: number, last: number, lang?: string) => {
+    // first/last are 0-based line indices of the body
+    if (last < first) return;
+    blocks.push({
+      kind,
+      lang,
+      startLine: first + 1,
+      endLine: last + 1,
+      offset: lineOffsets[first],
+      content: lines.slice(first, last + 1).join('\n')
+    });
+  };
+
+  let i = 0;
+  if (language === 'astro' && lines[0]?.trim() === '---') {
+    const close = lines.findIndex((line, index) => index > 0 && line.trim() === '---');
+    if (close > 0) {
+      addBlock('script', 1, close - 1, 'typescript');
+      i = close + 1;
+    }
+  }
+
+  const markup: Array<[number, number]> = [];
+  let markupStart = i;
+  for (; i < lines.length; i++) {
+    const open = BLOCK_OPEN.exec(lines[i]);
+    if (!open) continue;
+    const tag = open[1].toLowerCase();
+    // Only Vue has a top-level <template> block; elsewhere it is part of the markup
+    if (tag === 'template' && language !== 'vue') continue;
+
+    const closeTag = new RegExp(`</${tag}\\s*>`, 'gi');
+    let depth = 0;
+    let close = -1;
+    for (let j = i; j < lines.length; j++) {
+      const text = j === i ? lines[j].slice(open[0].length) : lines[j];
+      if (tag === 'template' && j > i) depth += (text.match(/<template\b/gi) ?? []).length;
+      const closes = (text.match(closeTag) ?? []).length;
+      if (closes > depth) {
+        close = j;
+        break;
+      }
+      depth -= closes;
+    }
+    if (close < 0) break;
+
+    if (i > markupStart) markup.push([markupStart, i - 1]);
+    const lang = LANG_ATTRIBUTE.exec(open[2])?.[1];
+    const kind = tag as SfcBlockKind;
+    const blockLang = kind === 'script' ? scriptGrammar(lang) : lang;
+    if (close === i) {
+      // `<style>h1 { color: red }</style>` on one line
+      blocks.push({
+        kind,
+        lang: blockLang,
+        startLine: i + 1,
+        endLine: i + 1,
+        offset: lineOffsets[i] + open[0].length,
+        content: lines[i].slice(open[0].length, lines[i].search(closeTag))
+      });
+    } else {
+      addBlock(kind, i + 1, close - 1, blockLang);
+    }
+    i = close;
+    markupStart = close + 1;
+  }
+  if (markupStart < lines.length) markup.push([markupStart, lines.length - 1]);
+
+  if (language !== 'vue') {
+    for (let [first, last] of markup) {
+      while (first <= last && !lines[first].trim()) first++;
+      while (last >= first && !lines[last].trim()) last--;
+      addBlock('template', first, last);
+    }
+  }
+  blocks.sort((a, b) => a.startLine - b.startLine);
+
+  const props: SfcProp[] = [];
+  let componentName = componentNameFromPath(filePath);
+  for (const block of blocks) {
+    if (block.kind !== 'script') continue;
+    for (const prop of findProps(language, block.content)) {
+      const line = block.startLine + lineAt(block.content, prop.offset) - 1;
+      props.push({ name: prop.name, line });
+    }
+    const declaredName =
+      language === 'vue' ? VUE_COMPONENT_NAME.exec(block.content)?.[1] : undefined;
+    if (declaredName) componentName = componentNameFromPath(declaredName);
+  }
+
+  return { componentName, blocks, props };
+}
+
+function shiftSymbol(symbol: TreeSitterSymbol, block: SfcBlock, byteOffset: number) {
+  return {
+    ...symbol,
+    startLine: symbol.startLine + block.startLine - 1,
+    endLine: symbol.endLine + block.startLine - 1,
+    startIndex: symbol.startIndex + byteOffset,
+    endIndex: symbol.endIndex + byteOffset
+  };
+}
+
+function componentSymbols(file: SfcFile, content: string): TreeSitterSymbol[] {
+  const lines = content.split('\n');
+  const component: TreeSitterSymbol = {
+    name: file.componentName,
+    kind: 'component',
+    startLine: 1,
+    endLine: lines.length,
+    startIndex: 0,
+    endIndex: Buffer.byteLength(content),
+    content,
+    nodeType: 'component'
+  };
+  const props = file.props.map((prop): TreeSitterSymbol => {
+    const text = lines[prop.line - 1] ?? '';
+    const before = lines.slice(0, prop.line - 1);
+    const startIndex = before.length > 0 ? Buffer.byteLength(before.join('\n')) + 1 : 0;
+    return {
+      name: prop.name,
+      kind: 'property',
+      startLine: prop.line,
+      endLine: prop.line,
+      startIndex,
+      endIndex: startIndex + Buffer.byteLength(text),
+      content: text.trim(),
+      nodeType: 'prop',
+      qualifiedName: `${file.componentName}.${prop.name}`
+    };
+  });
+  return [component, ...props];
+}
+
+/**
+ * Definitions and call sites of an SFC for the symbol index and call graph: the component,
+ * its props and everything its script blocks define, with file line numbers.
+ */
+export async function extractSfcCalls(
+  content: string,
+  language: string,
+  filePath: string
+): Promise<TreeSitterCallExtraction> {
+  const file = parseSfc(content, language, filePath);
+  const extraction: TreeSitterCallExtraction = {
+    symbols: componentSymbols(file, content),
+    calls: [],
+    constants: []
+  };
+
+  for (const block of file.blocks) {
+    if (block.kind !== 'script' || !block.lang) continue;
+    const result = await extractTreeSitterCalls(block.content, block.lang);
+    if (!result) continue;
+    const byteOffset = Buffer.byteLength(content.slice(0, block.offset));
+    extraction.symbols.push(...result.symbols.map((s) => shiftSymbol(s, block, byteOffset)));
+    extraction.constants.push(...result.constants.map((s) => shiftSymbol(s, block, byteOffset)));
+    extraction.calls.push(
+      ...result.calls.map((call) => ({
+        callee: call.callee,
+        caller: call.caller ? shiftSymbol(call.caller, block, byteOffset) : null,
+        line: call.line + block.startLine - 1
+      }))
+    );
+  }
+  return extraction;
+}
+
+/**
+ * Chunks per block: script blocks are AST-aligned when their grammar parses them, template
+ * and style blocks are line-chunked. Every chunk carries the component name and its block.
+ */
+export async function createSfcChunks(
+  content: string,
+  options: SfcChunkOptions
+): Promise<{ chunks: CodeChunk[]; file: SfcFile }> {
+  const file = parseSfc(content, options.language, options.filePath);
+  const chunks: CodeChunk[] = [];
+
+  for (const block of file.blocks) {
+    if (!block.content.trim()) continue;
+    const symbols =
+      block.kind === 'script' && block.lang
+        ? ((await extractTreeSitterSymbols(block.content, block.lang))?.symbols ?? [])
+        : [];
+    const blockChunks = createASTAlignedChunks(block.content, symbols, {
+      ...DEFAULT_AST_CHUNK_OPTIONS,
+      maxChunkTokens: options.maxChunkTokens,
+      overlapLines: options.overlapLines,
+      filePath: options.filePath,
+      language: options.language,
+      framework: options.language,
+      componentType: 'component'
+    });
+
+    for (const chunk of blockChunks) {
+      chunks.push({
+        ...chunk,
+        relativePath: options.relativePath,
+        startLine: chunk.startLine + block.startLine - 1,
+        endLine: chunk.endLine + block.startLine - 1,
+        tags: [...new Set([...chunk.tags, options.language, block.kind])],
+        metadata: {
+          ...chunk.metadata,
+          componentName: file.componentName,
+          sfcBlock: block.kind,
+          ...(block.lang ? { blockLang: block.lang } : {}),
+          ...(block.kind === 'script' && file.props.length > 0
+            ? { props: file.props.map((prop) => prop.name) }
+            : {}),
+          chunkStrategy: symbols.length > 0 ? 'sfc-ast-aligned' : 'sfc-block'
+        }
+      });
+    }
+  }
+
+  return { chunks, file };
+}
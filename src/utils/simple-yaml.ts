@@ -0,0 +1,237 @@
+/**
+ * Simple YAML
+ * Parses the subset of YAML used by hand-written config files (golden query sets,
+ * codebase-context.yaml): nested
+ * mappings and sequences by indentation, `- key: value` items, flow lists `[a, b]`, quoted and
+ * plain scalars, numbers, booleans, null and `#` comments. Anchors, tags, flow mappings and
+ * block scalars (`|`, `>`) are rejected with a line number instead of being misread.
+ * Mappings have no prototype, and keys that would reach one are rejected: project files are
+ * untrusted input.
+ */
+
+export type YamlValue = string | number | boolean | null | YamlValue[] | YamlMapping;
+export interface YamlMapping {
+  [key: string]: YamlValue;
+}
+
+interface Line {
+  indent: number;
+  text: string;
+  number: number;
+}
+
+export class YamlSyntaxError extends Error {
+  constructor(message: string, line?: number) {
+    super(line ? `YAML line ${line}: ${message}` : `YAML: ${message}`);
+    this.name = 'YamlSyntaxError';
+  }
+}
+
+/** A quote only opens a string at the start of a value, not inside `it's` */
+function opensQuote(text: string, index: number): boolean {
+  const ch = text[index];
+  if (ch !== '"' && ch !== "'") return false;
+  const before = text.slice(0, index).trimEnd();
+  return before === '' || /[:\-[,]$/.test(before);
+}
+
+/** Drop a trailing `# comment` that isn't inside quotes */
+function stripComment(text: string): string {
+  let quote: string | null = null;
+  for (let i = 0; i < text.length; i++) {
+    const ch = text[i];
+    if (quote) {
+      if (ch === '\\' && quote === '"') i++;
+      else if (ch === quote) quote = null;
+    } else if (opensQuote(text, i)) {
+      quote = ch;
+    } else if (ch === '#' && (i === 0 || /\s/.test(text[i - 1]))) {
+      return text.slice(0, i);
+    }
+  }
+  return text;
+}
+
+function toLines(source: string): Line[] {
+  const lines: Line[] = [];
+  source.split(/\r?\n/).forEach((raw, index) => {
+    const number = index + 1;
+    if (/^\t/.test(raw)) throw new YamlSyntaxError('tabs are not allowed for indentation', number);
+    const text = stripComment(raw).trimEnd();
+    if (!text.trim() || text.trim() === '---') return;
+    lines.push({ indent: text.length - text.trimStart().length, text: text.trim(), number });
+  });
+  return lines;
+}
+
+/** Split on `separator` outside quotes and brackets */
+function splitOutside(text: string, separator: string): string[] {
+  const parts: string[] = [];
+  let quote: string | null = null;
+  let depth = 0;
+  let start = 0;
+  for (let i = 0; i < text.length; i++) {
+    const ch = text[i];
+    if (quote) {
+      if (ch === '\\' && quote === '"') i++;
+      else if (ch === quote) quote = null;
+    } else if (opensQuote(text, i)) quote = ch;
+    else if (ch === '[') depth++;
+    else if (ch === ']') depth--;
+    else if (ch === separator && depth === 0) {
+      parts.push(text.slice(start, i));
+      start = i + 1;
+    }
+  }
+  parts.push(text.slice(start));
+  return parts;
+}
+
+/** Index of the `:` that ends a mapping key, or -1 */
+function findKeySeparator(text: string): number {
+  let quote: string | null = null;
+  for (let i = 0; i < text.length; i++) {
+    const ch = text[i];
+    if (quote) {
+      if (ch === '\\' && quote === '"') i++;
+      else if (ch === quote) quote = null;
+    } else if ((ch === '"' || ch === "'") && i === 0) quote = ch;
+    else if (ch === ':' && (i === text.length - 1 || text[i + 1] === ' ')) return i;
+  }
+  return -1;
+}
+
+function parseScalar(raw: string, line: number): YamlValue {
+  const text = raw.trim();
+  if (text.startsWith('"')) {
+    if (!text.endsWith('"') || text.length < 2) {
+      throw new YamlSyntaxError(`unterminated string ${text}`, line);
+    }
+    try {
+      return JSON.parse(text) as string;
+    } catch {
+      throw new YamlSyntaxError(`invalid double-quoted string ${text}`, line);
+    }
+  }
+  if (text.startsWith("'")) {
+    if (!text.endsWith("'") || text.length < 2) {
+      throw new YamlSyntaxError(`unterminated string ${text}`, line);
+    }
+    return text.slice(1, -1).replace(/''/g, "'");
+  }
+  if (text.startsWith('[')) {
+    if (!text.endsWith(']')) throw new YamlSyntaxError(`unterminated list ${text}`, line);
+    const inner = text.slice(1, -1).trim();
+    return inner ? splitOutside(inner, ',').map((item) => parseScalar(item, line)) : [];
+  }
+  if (/^[{&*!|>]/.test(text)) {
+    throw new YamlSyntaxError(`unsupported YAML syntax "${text}"`, line);
+  }
+  if (text === '' || text === '~' || text === 'null') return null;
+  if (text === 'true') return true;
+  if (text === 'false') return false;
+  if (/^[-+]?(\d+(\.\d+)?|\.\d+)([eE][-+]?\d+)?$/.test(text)) return Number(text);
+  return text;
+}
+
+const FORBIDDEN_KEYS = new Set(['__proto__', 'constructor', 'prototype']);
+
+function parseKey(raw: string, line: number): string {
+  const key = parseScalar(raw, line);
+  if (typeof key !== 'string' && typeof key !== 'number') {
+    throw new YamlSyntaxError(`invalid mapping key "${raw}"`, line);
+  }
+  if (FORBIDDEN_KEYS.has(String(key))) {
+    throw new YamlSyntaxError(`mapping key "${key}" is not allowed`, line);
+  }
+  return String(key);
+}
+
+class Parser {
+  private position = 0;
+
+  constructor(private lines: Line[]) {}
+
+  parseDocument(): YamlValue {
+    if (this.lines.length === 0) return null;
+    const value = this.parseBlock(this.lines[0].indent);
+    const extra = this.lines[this.position];
+    if (extra) throw new YamlSyntaxError('unexpected indentation', extra.number);
+    return value;
+  }
+
+  private peek(): Line | undefined {
+    return this.lines[this.position];
+  }
+
+  private parseBlock(indent: number): YamlValue {
+    const first = this.peek();
+    if (!first) return null;
+    if (first.text === '-' || first.text.startsWith('- ')) return this.parseSequence(indent);
+    if (findKeySeparator(first.text) === -1) {
+      this.position++;
+      return parseScalar(first.text, first.number);
+    }
+    return this.parseMapping(indent);
+  }
+
+  private parseSequence(indent: number): YamlValue[] {
+    const items: YamlValue[] = [];
+    for (let line = this.peek(); line && line.indent === indent; line = this.peek()) {
+      if (line.text !== '-' && !line.text.startsWith('- ')) break;
+      const rest = line.text.slice(1).trimStart();
+      if (!rest) {
+        this.position++;
+        const next = this.peek();
+        items.push(next && next.indent > indent ? this.parseBlock(next.indent) : null);
+        continue;
+      }
+      // `- key: value` opens a mapping whose keys line up with `key`
+      const childIndent = indent + (line.text.length - rest.length);
+      if (findKeySeparator(rest) !== -1 && !rest.startsWith('[')) {
+        this.lines[this.position] = { indent: childIndent, text: rest, number: line.number };
+        items.push(this.parseMapping(childIndent));
+      } else {
+        this.position++;
+        items.push(parseScalar(rest, line.number));
+      }
+    }
+    return items;
+  }
+
+  private parseMapping(indent: number): YamlMapping {
+    const mapping = Object.create(null) as YamlMapping;
+    for (let line = this.peek(); line && line.indent === indent; line = this.peek()) {
+      if (line.text.startsWith('- ') || line.text === '-') break;
+      const separator = findKeySeparator(line.text);
+      if (separator === -1) {
+        throw new YamlSyntaxError(`expected "key: value", got "${line.text}"`, line.number);
+      }
+      const key = parseKey(line.text.slice(0, separator), line.number);
+      if (Object.prototype.hasOwnProperty.call(mapping, key)) {
+        throw new YamlSyntaxError(`duplicate key "${key}"`, line.number);
+      }
+      const rest = line.text.slice(separator + 1).trim();
+      this.position++;
+      if (rest) {
+        mapping[key] = parseScalar(rest, line.number);
+        continue;
+      }
+      const next = this.peek();
+      // A sequence may sit at the same indent as its key
+      const nestedSequence =
+        next && next.indent === indent && (next.text === '-' || next.text.startsWith('- '));
+      mapping[key] =
+        next && (next.indent > indent || nestedSequence) ? this.parseBlock(next.indent) : null;
+    }
+    const stray = this.peek();
+    if (stray && stray.indent > indent) {
+      throw new YamlSyntaxError('unexpected indentation', stray.number);
+    }
+    return mapping;
+  }
+}
+
+export function parseSimpleYaml(source: string): YamlValue {
+  return new Parser(toLines(source)).parseDocument();
+}
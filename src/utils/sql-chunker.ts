@@ -0,0 +1,680 @@
+/**
+ * SQL indexing: `.sql` files are chunked per statement, and SQL query strings embedded in
+ * application code are found and attributed to the function they sit in. Both record the
+ * statement kind and the tables it touches (`metadata.sql`, `metadata.sqlTables`), so
+ * `find_sql_queries` and `filters.metadata` can answer "which queries touch orders".
+ *
+ * Parsing is lexical, not a SQL grammar. Statements end at `;` (or a `DELIMITER`, `GO` or `/`
+ * separator) outside comments, strings, dollar-quoted bodies and the BEGIN/END blocks of
+ * routines. Tables are the identifiers after FROM, JOIN, INTO, UPDATE, USING, REFERENCES and
+ * the table of DDL statements. Queries assembled by concatenation are only seen when a single
+ * literal holds a whole statement.
+ */
+
+import { v4 as uuidv4 } from 'uuid';
+import type { CodeChunk, SqlStatementInfo } from '../types/index.js';
+import { DEFAULT_AST_CHUNK_OPTIONS, splitOversizedChunks } from './ast-chunker.js';
+import type { TreeSitterSymbol } from './tree-sitter.js';
+
+export interface SqlChunkOptions {
+  filePath: string;
+  relativePath: string;
+  language: string;
+}
+
+export interface SqlStatement {
+  /** Offsets into the source: `start` at the statement's leading comments, `end` before `;` */
+  start: number;
+  end: number;
+  /** 1-based lines: the first line of leading comments, the first code line, the last line */
+  startLine: number;
+  codeLine: number;
+  endLine: number;
+  info: SqlStatementInfo;
+}
+
+// ---------------------------------------------------------------------------
+// Statement splitting
+// ---------------------------------------------------------------------------
+
+const ROUTINE_TYPES = new Set(['function', 'procedure', 'trigger', 'package']);
+// Words between CREATE and the routine type: CREATE OR REPLACE, CREATE OR ALTER, DEFINER=...
+const ROUTINE_MODIFIERS = new Set(['or', 'replace', 'alter', 'definer', 'editionable']);
+const BLOCK_END_IGNORED = new Set(['if', 'loop', 'while', 'repeat', 'for']);
+const DOLLAR_QUOTE = /\$([A-Za-z_]\w*)?\$/y;
+const WORD = /[A-Za-z_]\w*/y;
+const NEXT_WORD = /\s*([A-Za-z_]\w*)?/y;
+const NEXT_CHAR = /\s*(\S?)/y;
+
+function skipQuoted(content: string, i: number, quote: string): number {
+  for (let j = i + 1; j < content.length; j++) {
+    if (content[j] !== quote) continue;
+    // Doubled quotes escape themselves
+    if (content[j + 1] === quote) {
+      j++;
+      continue;
+    }
+    return j + 1;
+  }
+  return content.length;
+}
+
+/**
+ * Offsets past a comment, string, quoted identifier or dollar-quoted body starting at `i`,
+ * or -1 when `i` starts plain code.
+ */
+function skipNonCode(content: string, i: number): number {
+  const ch = content[i];
+  if (ch === '-' && content[i + 1] === '-') {
+    const end = content.indexOf('\n', i);
+    return end === -1 ? content.length : end;
+  }
+  if (ch === '/' && content[i + 1] === '*') {
+    const end = content.indexOf('*/', i + 2);
+    return end === -1 ? content.length : end + 2;
+  }
+  if (ch === "'" || ch === '"' || ch === '`') return skipQuoted(content, i, ch);
+  if (ch === '$' && !/[\w$]/.test(content[i - 1] ?? '')) {
+    DOLLAR_QUOTE.lastIndex = i;
+    const open = DOLLAR_QUOTE.exec(content);
+    if (open) {
+      const close = content.indexOf(open[0], i + open[0].length);
+      return close === -1 ? content.length : close + open[0].length;
+    }
+  }
+  return -1;
+}
+
+/** Comments blanked out and string contents masked (newlines kept), so offsets line up */
+function maskSql(content: string): string {
+  let out = '';
+  let i = 0;
+  while (i < content.length) {
+    const end = skipNonCode(content, i);
+    if (end === -1) {
+      out += content[i++];
+      continue;
+    }
+    const text = content.slice(i, end);
+    const blank = text.replace(/[^\n]/g, ' ');
+    if (text.startsWith("'") && text.length > 1) out += `'${blank.slice(1, -1)}'`;
+    // Quoted identifiers and dollar-quoted routine bodies stay readable
+    else if (/^["`$]/.test(text)) out += text;
+    else out += blank;
+    i = end;
+  }
+  return out;
+}
+
+function lineAt(lineStarts: number[], offset: number): number {
+  let low = 0;
+  let high = lineStarts.length - 1;
+  while (low < high) {
+    const mid = (low + high + 1) >> 1;
+    if (lineStarts[mid] <= offset) low = mid;
+    else high = mid - 1;
+  }
+  return low + 1;
+}
+
+/** Statement spans of a SQL script, as [start, end) offsets without the terminator */
+export function splitSqlStatements(content: string): Array<{ start: number; end: number }> {
+  const spans: Array<{ start: number; end: number }> = [];
+  let delimiter = ';';
+  let start = 0;
+  let words: string[] = [];
+  let routine: string | undefined;
+  const blocks: string[] = [];
+
+  const finish = (end: number, next: number) => {
+    if (content.slice(start, end).trim()) spans.push({ start, end });
+    start = next;
+    words = [];
+    routine = undefined;
+    blocks.length = 0;
+  };
+
+  let i = 0;
+  while (i < content.length) {
+    if (i === 0 || content[i - 1] === '\n') {
+      const lineEnd = content.indexOf('\n', i);
+      const next = lineEnd === -1 ? content.length : lineEnd + 1;
+      const line = content.slice(i, lineEnd === -1 ? content.length : lineEnd);
+      const custom = line.match(/^\s*delimiter\s+(\S+)\s*$/i);
+      // MySQL DELIMITER, T-SQL GO and the Oracle `/` line end the statement before them
+      if (custom || /^\s*go\s*$/i.test(line) || /^\s*\/\s*$/.test(line)) {
+        finish(i, next);
+        if (custom) delimiter = custom[1];
+        i = next;
+        continue;
+      }
+    }
+
+    // Before quotes, since `DELIMITER $$` is common
+    if (content.startsWith(delimiter, i) && (delimiter !== ';' || blocks.length === 0)) {
+      finish(i, i + delimiter.length);
+      i += delimiter.length;
+      continue;
+    }
+
+    const skipped = skipNonCode(content, i);
+    if (skipped !== -1) {
+      i = skipped;
+      continue;
+    }
+
+    WORD.lastIndex = i;
+    const match = /[\w$]/.test(content[i - 1] ?? '') ? null : WORD.exec(content);
+    if (!match) {
+      i++;
+      continue;
+    }
+    const word = match[0].toLowerCase();
+    i += match[0].length;
+    if (words.length < 8 && !routine) {
+      if (words[0] === 'create' && ROUTINE_TYPES.has(word)) {
+        if (words.slice(1).every((w) => ROUTINE_MODIFIERS.has(w))) routine = word;
+      }
+      words.push(word);
+    }
+    if (!routine) continue;
+
+    if (word === 'begin' || word === 'case') {
+      // PL/SQL and T-SQL `AS ... BEGIN ... END` is one block
+      if (word === 'begin' && blocks[blocks.length - 1] === 'as') blocks[blocks.length - 1] = word;
+      else blocks.push(word);
+    } else if ((word === 'as' || word === 'is') && blocks.length === 0 && routine !== 'trigger') {
+      // `AS $$ ... $$` and `AS 'body'` quote the body; a bare AS/IS opens a block
+      NEXT_CHAR.lastIndex = i;
+      const next = NEXT_CHAR.exec(content)?.[1];
+      if (next !== '$' && next !== "'") blocks.push('as');
+    } else if (word === 'end') {
+      NEXT_WORD.lastIndex = i;
+      const following = NEXT_WORD.exec(content)?.[1]?.toLowerCase();
+      if (!following || !BLOCK_END_IGNORED.has(following)) blocks.pop();
+    }
+  }
+  finish(content.length, content.length);
+  return spans;
+}
+
+// ---------------------------------------------------------------------------
+// Statement analysis
+// ---------------------------------------------------------------------------
+
+const IDENT_PART = '(?:"[^"]+"|`[^`]+`|\\[[^\\]]+\\]|[A-Za-z_][\\w$]*)';
+const TOKEN = new RegExp(
+  `${IDENT_PART}(?:\\s*\\.\\s*${IDENT_PART})*|'[^']*'|\\$\\w*|\\d[\\w.]*|\\S`,
+  'g'
+);
+
+const STATEMENT_VERBS = new Set([
+  'select',
+  'insert',
+  'update',
+  'delete',
+  'merge',
+  'replace',
+  'upsert',
+  'create',
+  'alter',
+  'drop',
+  'truncate',
+  'grant',
+  'revoke',
+  'comment',
+  'with',
+  'values',
+  'copy',
+  'do',
+  'call',
+  'exec',
+  'execute',
+  'declare',
+  'explain',
+  'refresh'
+]);
+const DML_VERBS = new Set(['select', 'insert', 'update', 'delete', 'merge']);
+const DDL_VERBS = new Set(['create', 'alter', 'drop']);
+const OBJECT_TYPES = new Set([
+  'table',
+  'view',
+  'index',
+  'function',
+  'procedure',
+  'trigger',
+  'type',
+  'sequence',
+  'schema',
+  'extension',
+  'package',
+  'policy',
+  'domain',
+  'database'
+]);
+// Keywords that follow FROM/INTO/UPDATE without being a table name
+const NOT_TABLES = new Set([
+  'select',
+  'where',
+  'set',
+  'values',
+  'on',
+  'as',
+  'join',
+  'left',
+  'right',
+  'inner',
+  'outer',
+  'full',
+  'cross',
+  'natural',
+  'group',
+  'order',
+  'limit',
+  'offset',
+  'union',
+  'having',
+  'window',
+  'returning',
+  'lateral',
+  'only',
+  'of',
+  'nowait',
+  'skip',
+  'default',
+  'with',
+  'not',
+  'exists',
+  'if',
+  'and',
+  'or',
+  'table',
+  'fetch',
+  'for',
+  'cascade',
+  'restrict',
+  'no',
+  'null',
+  'using',
+  'into',
+  'stdin',
+  'stdout'
+]);
+const TABLE_KEYWORDS = new Set(['from', 'join', 'into', 'update', 'using', 'references']);
+const INTO_VERBS = new Set(['insert', 'merge', 'replace', 'ignore', 'upsert']);
+// FROM inside these calls is not a table: EXTRACT(YEAR FROM created_at)
+const FROM_FUNCTIONS = new Set(['extract', 'substring', 'trim', 'overlay', 'position']);
+
+const isIdent = (token: string | undefined): token is string =>
+  token !== undefined && /^["`[A-Za-z_]/.test(token);
+
+/** `"Billing".[Orders]` -> `billing.orders` */
+function normalizeIdent(token: string): string {
+  return token
+    .split(/\s*\.\s*/)
+    .map((part) => part.replace(/^["`[]|["`\]]$/g, ''))
+    .join('.')
+    .toLowerCase();
+}
+
+/** CTE names, which FROM can name without them being tables */
+function cteNames(tokens: string[], lower: string[]): Set<string> {
+  const names = new Set<string>();
+  tokens.forEach((token, index) => {
+    if (lower[index] !== 'as' || tokens[index + 1] !== '(') return;
+    let name = index - 1;
+    if (tokens[name] === ')') {
+      while (name > 0 && tokens[name] !== '(') name--;
+      name--;
+    }
+    const before = lower[name - 1];
+    if (isIdent(tokens[name]) && (before === 'with' || before === 'recursive' || before === ',')) {
+      names.add(normalizeIdent(tokens[name]));
+    }
+  });
+  return names;
+}
+
+/** Statement kind, defined object and tables of one SQL statement */
+export function analyzeSqlStatement(sql: string): Omit<SqlStatementInfo, 'line'> | null {
+  const tokens = maskSql(sql).match(TOKEN) ?? [];
+  const lower = tokens.map((token) => token.toLowerCase());
+  const verb = lower[0];
+  if (!verb || !STATEMENT_VERBS.has(verb)) return null;
+
+  const tables: string[] = [];
+  const addTable = (token: string) => {
+    const name = normalizeIdent(token);
+    if (!tables.includes(name)) tables.push(name);
+  };
+
+  let statement = verb;
+  let object: string | undefined;
+  if (verb === 'with') {
+    let depth = 0;
+    statement = 'select';
+    for (let i = 1; i < tokens.length; i++) {
+      if (tokens[i] === '(') depth++;
+      else if (tokens[i] === ')') depth--;
+      else if (depth === 0 && DML_VERBS.has(lower[i])) {
+        statement = lower[i];
+        break;
+      }
+    }
+  } else if (DDL_VERBS.has(verb)) {
+    const typeIndex = lower.findIndex((token, index) => index > 0 && OBJECT_TYPES.has(token));
+    if (typeIndex > 0 && typeIndex <= 8) {
+      const type = lower[typeIndex];
+      statement = `${verb} ${type}`;
+      let nameIndex = typeIndex + 1;
+      while (['if', 'not', 'exists', 'concurrently', 'only', 'body'].includes(lower[nameIndex])) {
+        nameIndex++;
+      }
+      if (isIdent(tokens[nameIndex]) && lower[nameIndex] !== 'on') {
+        object = normalizeIdent(tokens[nameIndex]);
+        if (type === 'table') addTable(tokens[nameIndex]);
+      }
+      if (type === 'index' || type === 'trigger' || type === 'policy') {
+        const on = lower.indexOf('on', typeIndex);
+        if (on !== -1 && isIdent(tokens[on + 1])) addTable(tokens[on + 1]);
+      }
+    }
+  } else if (verb === 'truncate' || verb === 'copy') {
+    const target = lower[1] === 'table' ? 2 : 1;
+    if (isIdent(tokens[target])) addTable(tokens[target]);
+  }
+
+  const ctes = cteNames(tokens, lower);
+  const calls: string[] = [];
+  for (let i = 0; i < tokens.length; i++) {
+    const token = lower[i];
+    if (token === '(') calls.push(isIdent(tokens[i - 1]) ? lower[i - 1] : '');
+    else if (token === ')') calls.pop();
+    if (!TABLE_KEYWORDS.has(token)) continue;
+    const inCall = FROM_FUNCTIONS.has(calls[calls.length - 1]);
+    if (token === 'from' && (inCall || lower[i - 1] === 'distinct')) continue;
+    // SELECT ... INTO names variables (PL/SQL, PL/pgSQL), not tables
+    if (token === 'into' && !INTO_VERBS.has(lower[i - 1]) && lower[i - 2] !== 'or') continue;
+
+    let j = i + 1;
+    while (lower[j] === 'only' || lower[j] === 'lateral') j++;
+    // A call in FROM (`generate_series(...)`) is not a table; INTO/REFERENCES take columns
+    const takesColumns = token === 'into' || token === 'references';
+    for (;;) {
+      const candidate = tokens[j];
+      if (!isIdent(candidate) || NOT_TABLES.has(lower[j])) break;
+      if (tokens[j + 1] === '(' && !takesColumns) break;
+      if (!ctes.has(normalizeIdent(candidate))) addTable(candidate);
+      if (token !== 'from') break;
+      // FROM a x, b AS y
+      j++;
+      if (lower[j] === 'as') j++;
+      if (isIdent(tokens[j]) && !NOT_TABLES.has(lower[j])) j++;
+      if (tokens[j] !== ',') break;
+      j++;
+    }
+  }
+
+  return { statement, tables, ...(object ? { object } : {}) };
+}
+
+/** Statements of a SQL script with their lines and analysis; unrecognized ones are skipped */
+export function findSqlStatements(content: string): SqlStatement[] {
+  const lineStarts = [0];
+  for (let i = 0; i < content.length; i++) if (content[i] === '\n') lineStarts.push(i + 1);
+  const masked = maskSql(content);
+
+  const statements: SqlStatement[] = [];
+  let previousEnd = 0;
+  for (const span of splitSqlStatements(content)) {
+    const codeStart = masked.slice(span.start, span.end).search(/\S/);
+    if (codeStart === -1) continue;
+    const codeOffset = span.start + codeStart;
+    const analyzed = analyzeSqlStatement(content.slice(codeOffset, span.end));
+    const codeLine = lineAt(lineStarts, codeOffset);
+    const endLine = lineAt(lineStarts, Math.max(span.start, span.end - 1));
+    // Leading comments belong to the statement; a trailing comment of the previous one doesn't
+    const firstText = span.start + content.slice(span.start, span.end).search(/\S/);
+    const startLine = Math.min(codeLine, Math.max(lineAt(lineStarts, firstText), previousEnd + 1));
+    previousEnd = endLine;
+    if (!analyzed) continue;
+    statements.push({
+      ...span,
+      startLine,
+      codeLine,
+      endLine,
+      info: { ...analyzed, line: codeLine }
+    });
+  }
+  return statements;
+}
+
+// ---------------------------------------------------------------------------
+// Chunks and symbols
+// ---------------------------------------------------------------------------
+
+const SYMBOL_KINDS: Record<string, string> = {
+  table: 'table',
+  view: 'view',
+  function: 'function',
+  procedure: 'procedure',
+  trigger: 'trigger',
+  type: 'type'
+};
+
+/** Tables plus their unqualified names, so `{ "sqlTables": "orders" }` matches billing.orders */
+export function sqlTableTags(infos: SqlStatementInfo[]): string[] {
+  const tags = new Set<string>();
+  for (const info of infos) {
+    for (const table of info.tables) {
+      tags.add(table);
+      tags.add(table.slice(table.lastIndexOf('.') + 1));
+    }
+  }
+  return [...tags];
+}
+
+/** Tables, views, routines and types created by a SQL script, for the symbol index */
+export function sqlSymbols(content: string): TreeSitterSymbol[] {
+  const symbols: TreeSitterSymbol[] = [];
+  for (const statement of findSqlStatements(content)) {
+    const { info } = statement;
+    const kind = SYMBOL_KINDS[info.statement.split(' ')[1] ?? ''];
+    if (!info.statement.startsWith('create ') || !kind || !info.object) continue;
+    const namespace = info.object.includes('.')
+      ? info.object.slice(0, info.object.lastIndexOf('.'))
+      : undefined;
+    symbols.push({
+      name: info.object.slice(info.object.lastIndexOf('.') + 1),
+      kind,
+      startLine: statement.codeLine,
+      endLine: statement.endLine,
+      startIndex: statement.start,
+      endIndex: statement.end,
+      content: content.slice(statement.start, statement.end).trim(),
+      nodeType: info.statement,
+      qualifiedName: info.object,
+      ...(namespace ? { namespace } : {})
+    });
+  }
+  return symbols;
+}
+
+const sameShape = (a: SqlStatementInfo, b: SqlStatementInfo): boolean =>
+  a.statement === b.statement && !a.object && !b.object && a.tables.join() === b.tables.join();
+
+function makeChunk(
+  lines: string[],
+  startLine: number,
+  endLine: number,
+  infos: SqlStatementInfo[],
+  options: SqlChunkOptions
+): CodeChunk {
+  const [first] = infos;
+  const body = lines.slice(startLine - 1, endLine).join('\n');
+  const tables = first.tables.length > 0 ? `:: ${first.tables.join(', ')}` : '';
+  const header = [first.statement, first.object, tables].filter(Boolean).join(' ');
+  const kind = first.object ? SYMBOL_KINDS[first.statement.split(' ')[1] ?? ''] : undefined;
+
+  return {
+    id: uuidv4(),
+    content: `-- ${header}\n${body}`,
+    filePath: options.filePath,
+    relativePath: options.relativePath,
+    startLine,
+    endLine,
+    language: options.language,
+    componentType: 'sql',
+    dependencies: [],
+    imports: [],
+    exports: [],
+    tags: ['sql', first.statement],
+    metadata: {
+      // Statement-aligned, so the indexer keeps them apart
+      symbolAware: true,
+      ...(first.object ? { symbolName: first.object, qualifiedName: first.object } : {}),
+      ...(kind ? { symbolKind: kind } : {}),
+      chunkStrategy: 'sql-statement',
+      sql: infos,
+      sqlTables: sqlTableTags(infos)
+    }
+  };
+}
+
+/**
+ * One chunk per statement of a `.sql` file. Runs of the same statement on the same tables
+ * (seed data, bulk inserts) share chunks, recorded once with a `repeated` count. Returns null
+ * when no statement is recognized, so callers fall back to line chunking.
+ */
+export function createSqlChunks(content: string, options: SqlChunkOptions): CodeChunk[] | null {
+  const statements = findSqlStatements(content);
+  if (statements.length === 0) return null;
+
+  const lines = content.split('\n');
+  const maxLines = DEFAULT_AST_CHUNK_OPTIONS.maxChunkLines;
+  const chunks: CodeChunk[] = [];
+  let run: SqlStatement[] = [];
+  const flush = () => {
+    if (run.length === 0) return;
+    const info: SqlStatementInfo =
+      run.length > 1 ? { ...run[0].info, repeated: run.length } : run[0].info;
+    chunks.push(makeChunk(lines, run[0].startLine, run[run.length - 1].endLine, [info], options));
+    run = [];
+  };
+
+  for (const statement of statements) {
+    const head = run[0];
+    if (
+      head &&
+      (!sameShape(head.info, statement.info) || statement.endLine - head.startLine + 1 > maxLines)
+    ) {
+      flush();
+    }
+    run.push(statement);
+  }
+  flush();
+  return splitOversizedChunks(chunks, maxLines);
+}
+
+// ---------------------------------------------------------------------------
+// Embedded queries
+// ---------------------------------------------------------------------------
+
+// Heredocs (Ruby, PHP, shell), triple-quoted and raw strings, C# verbatim, then plain literals
+const STRING_LITERAL =
+  /<<<?[~-]?(['"]?)([A-Z][A-Z_]*)\1[^\n]*\n([\s\S]*?)\n\s*\2\b|"""([\s\S]*?)"""|'''([\s\S]*?)'''|`((?:\\[\s\S]|[^\\`])*)`|@"((?:""|[^"])*)"|"((?:\\.|[^"\\\n])*)"|'((?:\\.|[^'\\\n])*)'/g;
+
+// The start of a query, strict enough to skip prose such as "Select a file from the list"
+const SQL_QUERY_START =
+  /^\s*(?:select\s+(?:distinct\s+|top\s+\d+\s+)?[\w."`[\]*]+(?=\s*[,(]|\s+as\s|\s+from\s)[\s\S]*\bfrom\b|insert\s+(?:ignore\s+|or\s+\w+\s+)?into\s+[\w."`[\]]+|update\s+[\w."`[\]]+\s+set\s|delete\s+from\s+[\w."`[\]]+\s*(?:where\b|using\b|returning\b|;|$)|merge\s+into\s|replace\s+into\s|with\s+(?:recursive\s+)?\w+\s*(?:\([^)]*\))?\s+as\s*\(|(?:create|alter|drop)\s+(?:(?:or\s+replace|temporary|temp|unique|if\s+(?:not\s+)?exists)\s+)*(?:table|view|index)\s|truncate\s+(?:table\s+)?[\w."`[\]]+)/i;
+// Lower-case or Title-case text must also look like SQL: "Select text from the menu" doesn't
+const SQL_STRUCTURE =
+  /\b(?:where|join|group\s+by|order\s+by|limit|union|values|set|returning)\b|[*=?;]|\$\d|:\w/i;
+const SQL_HINT = /\b(?:select|insert|update|delete|merge|replace|with|create|alter|drop|truncate)\b/i;
+
+const isLikelySql = (text: string): boolean =>
+  SQL_QUERY_START.test(text) && (/^\s*[A-Z]+\b/.test(text) || SQL_STRUCTURE.test(text));
+
+/** Languages whose strings are not application queries (or that are SQL already) */
+const EMBEDDED_SQL_SKIPPED_LANGUAGES = new Set(['sql', 'markdown', 'mdx', 'jupyter', 'json']);
+
+/** SQL queries in the string literals of a source file, with their 1-based lines */
+export function findEmbeddedSql(content: string, language: string): SqlStatementInfo[] {
+  if (EMBEDDED_SQL_SKIPPED_LANGUAGES.has(language) || !SQL_HINT.test(content)) return [];
+
+  const lineStarts = [0];
+  for (let i = 0; i < content.length; i++) if (content[i] === '\n') lineStarts.push(i + 1);
+
+  const queries: SqlStatementInfo[] = [];
+  for (const match of content.matchAll(STRING_LITERAL)) {
+    const groupIndex = match.slice(3).findIndex((group) => group !== undefined);
+    if (groupIndex === -1) continue;
+    const body = match[groupIndex + 3];
+    if (body.length < 12 || !isLikelySql(body)) continue;
+
+    const bodyOffset = (match.index ?? 0) + match[0].indexOf(body);
+    for (const span of splitSqlStatements(body)) {
+      const text = body.slice(span.start, span.end);
+      const analyzed = analyzeSqlStatement(text.trim());
+      if (!analyzed) continue;
+      const leading = text.length - text.trimStart().length;
+      queries.push({ ...analyzed, line: lineAt(lineStarts, bodyOffset + span.start + leading) });
+    }
+  }
+  return queries;
+}
+
+const CALLABLE_KINDS = new Set(['function', 'method', 'constructor']);
+
+function innermost(
+  symbols: TreeSitterSymbol[],
+  startLine: number,
+  endLine: number,
+  accept: (symbol: TreeSitterSymbol) => boolean
+): TreeSitterSymbol | undefined {
+  let best: TreeSitterSymbol | undefined;
+  for (const symbol of symbols) {
+    if (!accept(symbol) || symbol.startLine > startLine || symbol.endLine < endLine) continue;
+    if (!best || symbol.endLine - symbol.startLine < best.endLine - best.startLine) best = symbol;
+  }
+  return best;
+}
+
+/** Innermost function or method around a line: `OrderRepository.listOpen` */
+function enclosingFunction(symbols: TreeSitterSymbol[], line: number): string | undefined {
+  const callable = innermost(symbols, line, line, (s) => CALLABLE_KINDS.has(s.kind));
+  if (!callable || callable.qualifiedName) return callable?.qualifiedName;
+  // Grammars without qualified names: prefix the enclosing type
+  const owner = innermost(
+    symbols,
+    callable.startLine,
+    callable.endLine,
+    (s) => s !== callable && !CALLABLE_KINDS.has(s.kind)
+  );
+  return owner ? `${owner.name}.${callable.name}` : callable.name;
+}
+
+/**
+ * Tag the chunks of a code file with the SQL queries embedded in them, each attributed to
+ * its enclosing function (from `symbols`, else the chunk's own symbol).
+ */
+export function attachEmbeddedSql(
+  chunks: CodeChunk[],
+  content: string,
+  language: string,
+  symbols: TreeSitterSymbol[]
+): void {
+  for (const query of findEmbeddedSql(content, language)) {
+    let chunk: CodeChunk | undefined;
+    for (const candidate of chunks) {
+      if (candidate.startLine > query.line || candidate.endLine < query.line) continue;
+      const span = candidate.endLine - candidate.startLine;
+      if (!chunk || span < chunk.endLine - chunk.startLine) chunk = candidate;
+    }
+    if (!chunk) continue;
+    const owner = enclosingFunction(symbols, query.line) ?? chunk.metadata.symbolName;
+    const tagged = owner ? { ...query, function: owner } : query;
+    const sql = [...(chunk.metadata.sql ?? []), tagged];
+    chunk.metadata = { ...chunk.metadata, sql, sqlTables: sqlTableTags(sql) };
+  }
+}
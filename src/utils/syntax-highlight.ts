@@ -0,0 +1,378 @@
+/**
+ * Highlighting metadata for returned snippets, so clients can color code without a parser.
+ *
+ * A small lexer per language family classifies comments, strings, numbers and keywords
+ * exactly; `function` (an identifier followed by `(`) and `type` (a PascalCase identifier) are
+ * heuristics. Snippets are often cut mid-construct, so a lexer that never fails fits them
+ * better than a grammar. Language ids are the ones from language-detection.ts.
+ */
+
+import { getGrammarPlugin } from '../grammars/plugins.js';
+
+export const SYNTAX_TOKEN_KINDS = [
+  'comment',
+  'string',
+  'number',
+  'keyword',
+  'type',
+  'function'
+] as const;
+export type SyntaxTokenKind = (typeof SYNTAX_TOKEN_KINDS)[number];
+
+/** `[line, column, length, kind]`: line and column are 0-based within the snippet (UTF-16) */
+export type SyntaxToken = [number, number, number, SyntaxTokenKind];
+
+interface LexicalRules {
+  lineComments: readonly string[];
+  blockComments: ReadonlyArray<readonly [string, string]>;
+  /** Longest first, so `"""` is tried before `"` */
+  quotes: readonly string[];
+  /** Quotes whose strings may span lines */
+  multilineQuotes: readonly string[];
+  keywords: ReadonlySet<string>;
+  caseInsensitive?: boolean;
+  /** Classify identifiers as `function` or `type`; off for data and markup */
+  identifiers: boolean;
+}
+
+const words = (list: string): ReadonlySet<string> => new Set(list.split(/\s+/).filter(Boolean));
+
+const JS_KEYWORDS = words(`
+  abstract as async await break case catch class const continue debugger declare default delete
+  do else enum export extends false finally for from function if implements import in instanceof
+  interface keyof let namespace new null of private protected public readonly return satisfies
+  static super switch this throw true try type typeof undefined var void while with yield`);
+
+const PYTHON_KEYWORDS = words(`
+  False None True and as assert async await break case class continue def del elif else except
+  finally for from global if import in is lambda match nonlocal not or pass raise return self try
+  while with yield`);
+
+const GO_KEYWORDS = words(`
+  break case chan const continue default defer else fallthrough false for func go goto if import
+  interface iota map nil package range return select struct switch true type var`);
+
+const RUST_KEYWORDS = words(`
+  as async await break const continue crate dyn else enum extern false fn for if impl in let loop
+  match mod move mut pub ref return self Self static struct super trait true type unsafe use where
+  while`);
+
+/** Java, C#, C/C++, Kotlin, Scala, Swift, PHP and friends share most of their keywords */
+const C_FAMILY_KEYWORDS = words(`
+  abstract as async await bool boolean break byte case catch char class const continue data def
+  default defer delete do double else enum extends extension false final finally float fn for
+  func fun goto guard if implements import in include instanceof int interface internal is let
+  long namespace new nil null nullptr object operator override package private protected public
+  record return sealed self short static string struct super switch template this throw throws
+  trait true try typedef typename union unsigned using val var virtual void volatile when where
+  while`);
+
+const RUBY_KEYWORDS = words(`
+  alias and begin break case class def defined do else elsif end ensure false for if in module next
+  nil not or redo rescue retry return self super then true undef unless until when while yield`);
+
+const ELIXIR_KEYWORDS = words(`
+  alias case cond def defmacro defmodule defp defstruct do else end false fn if import in nil not
+  quote receive require rescue true unless use when with`);
+
+const SHELL_KEYWORDS = words(`
+  case do done elif else esac export fi for function if in local return select then until while`);
+
+const SQL_KEYWORDS = words(`
+  all alter and as asc begin by case commit create default delete desc distinct drop else end
+  exists foreign from group having in index inner insert into is join key left like limit not null
+  offset on or order outer primary references returning right select set table then union unique
+  update values view when where with`);
+
+const GRAPHQL_KEYWORDS = words(`
+  directive enum extend fragment implements input interface mutation on query scalar schema
+  subscription type union`);
+
+const LITERAL_KEYWORDS = words('true false null');
+
+const C_COMMENTS = { lineComments: ['//'], blockComments: [['/*', '*/']] } as const;
+const HASH_COMMENTS = { lineComments: ['#'], blockComments: [] } as const;
+
+const JS_RULES: LexicalRules = {
+  ...C_COMMENTS,
+  quotes: ['`', '"', "'"],
+  multilineQuotes: ['`'],
+  keywords: JS_KEYWORDS,
+  identifiers: true
+};
+
+const C_FAMILY_RULES: LexicalRules = {
+  ...C_COMMENTS,
+  quotes: ['"""', '"', "'"],
+  multilineQuotes: ['"""'],
+  keywords: C_FAMILY_KEYWORDS,
+  identifiers: true
+};
+
+const PYTHON_RULES: LexicalRules = {
+  ...HASH_COMMENTS,
+  quotes: ['"""', "'''", '"', "'"],
+  multilineQuotes: ['"""', "'''"],
+  keywords: PYTHON_KEYWORDS,
+  identifiers: true
+};
+
+const SHELL_RULES: LexicalRules = {
+  ...HASH_COMMENTS,
+  quotes: ['"', "'"],
+  multilineQuotes: ['"', "'"],
+  keywords: SHELL_KEYWORDS,
+  identifiers: false
+};
+
+const DATA_RULES: LexicalRules = {
+  ...HASH_COMMENTS,
+  quotes: ['"""', "'''", '"', "'"],
+  multilineQuotes: ['"""', "'''"],
+  keywords: LITERAL_KEYWORDS,
+  identifiers: false
+};
+
+const JSON_RULES: LexicalRules = {
+  ...C_COMMENTS,
+  quotes: ['"'],
+  multilineQuotes: [],
+  keywords: LITERAL_KEYWORDS,
+  identifiers: false
+};
+
+const CSS_RULES: LexicalRules = {
+  ...C_COMMENTS,
+  quotes: ['"', "'"],
+  multilineQuotes: [],
+  keywords: new Set(),
+  identifiers: false
+};
+
+const MARKUP_RULES: LexicalRules = {
+  lineComments: [],
+  blockComments: [['<!--', '-->']],
+  quotes: [],
+  multilineQuotes: [],
+  keywords: new Set(),
+  identifiers: false
+};
+
+const RULES: Record<string, LexicalRules> = {
+  javascript: JS_RULES,
+  javascriptreact: JS_RULES,
+  typescript: JS_RULES,
+  typescriptreact: JS_RULES,
+  vue: JS_RULES,
+  svelte: JS_RULES,
+  astro: JS_RULES,
+  java: C_FAMILY_RULES,
+  kotlin: C_FAMILY_RULES,
+  scala: C_FAMILY_RULES,
+  swift: C_FAMILY_RULES,
+  csharp: C_FAMILY_RULES,
+  c: C_FAMILY_RULES,
+  cpp: C_FAMILY_RULES,
+  'objective-c': C_FAMILY_RULES,
+  'objective-cpp': C_FAMILY_RULES,
+  php: { ...C_FAMILY_RULES, lineComments: ['//', '#'] },
+  zig: C_FAMILY_RULES,
+  proto: C_FAMILY_RULES,
+  go: {
+    ...C_COMMENTS,
+    quotes: ['`', '"', "'"],
+    multilineQuotes: ['`'],
+    keywords: GO_KEYWORDS,
+    identifiers: true
+  },
+  // No `'` quote: it also opens lifetimes (`'a`)
+  rust: {
+    ...C_COMMENTS,
+    quotes: ['"'],
+    multilineQuotes: ['"'],
+    keywords: RUST_KEYWORDS,
+    identifiers: true
+  },
+  python: PYTHON_RULES,
+  jupyter: PYTHON_RULES,
+  ruby: { ...PYTHON_RULES, quotes: ['"', "'"], multilineQuotes: [], keywords: RUBY_KEYWORDS },
+  elixir: {
+    ...PYTHON_RULES,
+    quotes: ['"""', '"', "'"],
+    multilineQuotes: ['"""'],
+    keywords: ELIXIR_KEYWORDS
+  },
+  shellscript: SHELL_RULES,
+  powershell: { ...SHELL_RULES, caseInsensitive: true },
+  sql: {
+    lineComments: ['--'],
+    blockComments: [['/*', '*/']],
+    quotes: ["'", '"'],
+    multilineQuotes: ["'"],
+    keywords: SQL_KEYWORDS,
+    caseInsensitive: true,
+    identifiers: true
+  },
+  graphql: { ...DATA_RULES, quotes: ['"""', '"'], keywords: GRAPHQL_KEYWORDS, identifiers: true },
+  yaml: DATA_RULES,
+  toml: DATA_RULES,
+  hcl: { ...DATA_RULES, lineComments: ['#', '//'], blockComments: [['/*', '*/']] },
+  json: JSON_RULES,
+  jsonc: JSON_RULES,
+  css: CSS_RULES,
+  scss: CSS_RULES,
+  sass: CSS_RULES,
+  less: CSS_RULES,
+  html: MARKUP_RULES,
+  xml: MARKUP_RULES,
+  markdown: MARKUP_RULES,
+  mdx: MARKUP_RULES
+};
+
+const NUMBER = /0[xXbBoO][\da-fA-F_]+|\d[\d_]*(?:\.\d[\d_]*)?(?:[eE][+-]?\d+)?/y;
+const IDENTIFIER = /[A-Za-z_$][\w$]*/y;
+const CALL_PAREN = /[ \t]*\(/y;
+const PASCAL_CASE = /^[A-Z][A-Za-z0-9]*[a-z][A-Za-z0-9]*$/;
+
+/** Whether tokens can be computed for the language */
+export function supportsHighlighting(language: string): boolean {
+  return language in RULES;
+}
+
+export interface CommentSyntax {
+  lineComments: readonly string[];
+  blockComments: ReadonlyArray<readonly [string, string]>;
+}
+
+/**
+ * How the language writes comments; null for languages neither the lexer nor a grammar
+ * plugin's `comments` knows
+ */
+export function commentSyntax(language: string): CommentSyntax | null {
+  const rules = RULES[language];
+  if (rules) return { lineComments: rules.lineComments, blockComments: rules.blockComments };
+  const comments = getGrammarPlugin(language)?.comments;
+  if (!comments) return null;
+  return { lineComments: comments.line ?? [], blockComments: comments.block ?? [] };
+}
+
+function stringEnd(content: string, start: number, quote: string, multiline: boolean): number {
+  let i = start + quote.length;
+  while (i < content.length) {
+    if (content[i] === '\\') {
+      i += 2;
+      continue;
+    }
+    if (content.startsWith(quote, i)) return i + quote.length;
+    if (content[i] === '\n' && !multiline) return i;
+    i++;
+  }
+  return content.length;
+}
+
+/**
+ * Classify a snippet's tokens. Plain identifiers, operators and whitespace get no token; a
+ * comment or string spanning lines becomes one token per line. Unknown languages get none.
+ */
+export function classifyTokens(content: string, language: string): SyntaxToken[] {
+  const rules = RULES[language];
+  if (!rules || !content) return [];
+
+  const tokens: SyntaxToken[] = [];
+  let line = 0;
+  let lineStart = 0;
+  const emit = (start: number, end: number, kind: SyntaxTokenKind) => {
+    let from = start;
+    while (from < end) {
+      const newline = content.indexOf('\n', from);
+      const to = newline === -1 || newline >= end ? end : newline;
+      if (to > from) tokens.push([line, from - lineStart, to - from, kind]);
+      if (to === end) break;
+      line++;
+      lineStart = to + 1;
+      from = to + 1;
+    }
+  };
+
+  let i = 0;
+  while (i < content.length) {
+    const ch = content[i];
+    if (ch === '\n') {
+      line++;
+      lineStart = ++i;
+      continue;
+    }
+
+    let end = -1;
+    let kind: SyntaxTokenKind | null = null;
+    if (rules.lineComments.some((marker) => content.startsWith(marker, i))) {
+      const newline = content.indexOf('\n', i);
+      end = newline === -1 ? content.length : newline;
+      kind = 'comment';
+    } else {
+      const block = rules.blockComments.find(([open]) => content.startsWith(open, i));
+      const quote = block ? undefined : rules.quotes.find((q) => content.startsWith(q, i));
+      if (block) {
+        const close = content.indexOf(block[1], i + block[0].length);
+        end = close === -1 ? content.length : close + block[1].length;
+        kind = 'comment';
+      } else if (quote) {
+        end = stringEnd(content, i, quote, rules.multilineQuotes.includes(quote));
+        kind = 'string';
+      } else if (ch >= '0' && ch <= '9') {
+        NUMBER.lastIndex = i;
+        end = NUMBER.exec(content) ? NUMBER.lastIndex : i + 1;
+        kind = 'number';
+      } else {
+        IDENTIFIER.lastIndex = i;
+        const word = IDENTIFIER.exec(content)?.[0];
+        if (word) {
+          end = i + word.length;
+          CALL_PAREN.lastIndex = end;
+          if (rules.keywords.has(rules.caseInsensitive ? word.toLowerCase() : word)) {
+            kind = 'keyword';
+          } else if (rules.identifiers && CALL_PAREN.test(content)) {
+            kind = 'function';
+          } else if (rules.identifiers && PASCAL_CASE.test(word)) {
+            kind = 'type';
+          }
+        }
+      }
+    }
+
+    if (end <= i) {
+      i++;
+      continue;
+    }
+    if (kind) emit(i, end, kind);
+    i = end;
+  }
+  return tokens;
+}
+
+/** Language ids that Markdown renderers spell differently */
+const FENCE_TAGS: Record<string, string> = {
+  typescriptreact: 'tsx',
+  javascriptreact: 'jsx',
+  shellscript: 'bash',
+  'objective-c': 'objectivec',
+  'objective-cpp': 'objectivec',
+  proto: 'protobuf',
+  jupyter: 'python',
+  plaintext: 'text'
+};
+
+/** Info string for a fenced code block in `language` */
+export function fenceLanguage(language: string): string {
+  return FENCE_TAGS[language] ?? language;
+}
+
+/** `language` and, when asked for, `tokens` to spread into an entry carrying a snippet */
+export function highlightFields(
+  content: string,
+  language: string,
+  withTokens: boolean
+): { language: string; tokens?: SyntaxToken[] } {
+  const tokens = withTokens ? classifyTokens(content, language) : [];
+  return { language, ...(tokens.length > 0 && { tokens }) };
+}
@@ -0,0 +1,103 @@
+/**
+ * Source files are read as bytes and decoded here, so chunks, hashes and snippets are UTF-8
+ * text whatever the file was saved as:
+ *
+ * - a UTF-8 byte order mark is dropped
+ * - UTF-16 is recognized by its byte order mark, or without one by the zero bytes ASCII text
+ *   leaves in every other position, and transcoded (such files used to look binary)
+ * - bytes that aren't valid UTF-8 are decoded as Windows-1252, the legacy default of editors
+ *   on Windows (other single-byte code pages decode the ASCII range correctly and the rest
+ *   approximately)
+ */
+
+import { promises as fs } from 'fs';
+
+export type TextEncodingName = 'utf-8' | 'utf-8-bom' | 'utf-16le' | 'utf-16be' | 'windows-1252';
+
+export interface DecodedText {
+  text: string;
+  encoding: TextEncodingName;
+}
+
+/** Bytes looked at to recognize BOM-less UTF-16 */
+const UTF16_SNIFF_BYTES = 4096;
+/** Share of zero bytes on one side of each 16-bit unit (and almost none on the other) */
+const UTF16_ZERO_RATIO = 0.3;
+const UTF16_OTHER_ZERO_RATIO = 0.02;
+
+/** Windows-1252 0x80-0x9F; the five unassigned bytes map to the same code point (WHATWG) */
+const CP1252_C1 = [
+  0x20ac, 0x81, 0x201a, 0x192, 0x201e, 0x2026, 0x2020, 0x2021, 0x2c6, 0x2030, 0x160, 0x2039,
+  0x152, 0x8d, 0x17d, 0x8f, 0x90, 0x2018, 0x2019, 0x201c, 0x201d, 0x2022, 0x2013, 0x2014, 0x2dc,
+  0x2122, 0x161, 0x203a, 0x153, 0x9d, 0x17e, 0x178
+];
+
+const UTF8 = new TextDecoder('utf-8');
+const UTF8_STRICT = new TextDecoder('utf-8', { fatal: true });
+
+/** `utf-16le`/`utf-16be` and the offset of the text (past a BOM), or null for other text */
+export function detectUtf16(
+  bytes: Uint8Array
+): { encoding: 'utf-16le' | 'utf-16be'; offset: number } | null {
+  if (bytes[0] === 0xff && bytes[1] === 0xfe) return { encoding: 'utf-16le', offset: 2 };
+  if (bytes[0] === 0xfe && bytes[1] === 0xff) return { encoding: 'utf-16be', offset: 2 };
+
+  const units = Math.floor(Math.min(bytes.length, UTF16_SNIFF_BYTES) / 2);
+  if (units < 2) return null;
+  let evenZeros = 0;
+  let oddZeros = 0;
+  for (let i = 0; i < units; i++) {
+    if (bytes[2 * i] === 0) evenZeros++;
+    if (bytes[2 * i + 1] === 0) oddZeros++;
+  }
+  const even = evenZeros / units;
+  const odd = oddZeros / units;
+  if (odd >= UTF16_ZERO_RATIO && even <= UTF16_OTHER_ZERO_RATIO) {
+    return { encoding: 'utf-16le', offset: 0 };
+  }
+  if (even >= UTF16_ZERO_RATIO && odd <= UTF16_OTHER_ZERO_RATIO) {
+    return { encoding: 'utf-16be', offset: 0 };
+  }
+  return null;
+}
+
+function decodeWindows1252(bytes: Uint8Array): string {
+  let text = '';
+  // Chunked to keep String.fromCharCode's argument list bounded
+  for (let start = 0; start < bytes.length; start += 8192) {
+    const codes = Array.from(bytes.subarray(start, start + 8192), (byte) =>
+      byte >= 0x80 && byte <= 0x9f ? CP1252_C1[byte - 0x80] : byte
+    );
+    text += String.fromCharCode(...codes);
+  }
+  return text;
+}
+
+/** Decode file bytes to text, recognizing the encoding as described above */
+export function decodeText(bytes: Uint8Array): DecodedText {
+  if (bytes[0] === 0xef && bytes[1] === 0xbb && bytes[2] === 0xbf) {
+    return { text: UTF8.decode(bytes.subarray(3)), encoding: 'utf-8-bom' };
+  }
+  const utf16 = detectUtf16(bytes);
+  if (utf16) {
+    // A trailing odd byte can't be half of anything
+    const end = utf16.offset + Math.floor((bytes.length - utf16.offset) / 2) * 2;
+    const units = Buffer.from(bytes.subarray(utf16.offset, end));
+    if (utf16.encoding === 'utf-16be') units.swap16();
+    return { text: units.toString('utf16le'), encoding: utf16.encoding };
+  }
+  try {
+    return { text: UTF8_STRICT.decode(bytes), encoding: 'utf-8' };
+  } catch {
+    return { text: decodeWindows1252(bytes), encoding: 'windows-1252' };
+  }
+}
+
+export async function readText(file: string): Promise<DecodedText> {
+  return decodeText(await fs.readFile(file));
+}
+
+/** A source file's content as text, whatever it was saved as */
+export async function readTextFile(file: string): Promise<string> {
+  return (await readText(file)).text;
+}
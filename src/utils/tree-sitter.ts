@@ -1,10 +1,12 @@
 import { createRequire } from 'module';
-import { Language, Parser, type Node } from 'web-tree-sitter';
+import path from 'path';
+import { Language, Parser, Query, type Node } from 'web-tree-sitter';
 import {
   CURATED_LANGUAGE_TO_WASM,
   supportsCuratedTreeSitter,
   resolveGrammarPath
 } from '../grammars/manifest.js';
+import { getGrammarPlugin, type GrammarPlugin } from '../grammars/plugins.js';
 
 export interface TreeSitterSymbol {
   name: string;
@@ -15,11 +17,21 @@ export interface TreeSitterSymbol {
   endIndex: number;
   content: string;
   nodeType: string;
+  /**
+   * Qualified name: `com.acme.UserService.save` (Java/Kotlin/C#/Scala), `Invoice.total`
+   * (Swift, Zig), `Cache::get` (Rust), `Billing::Invoice#total` (Ruby),
+   * `App\Models\User::save` (PHP), `Billing.Invoice.total` (Elixir)
+   */
+  qualifiedName?: string;
+  /** Enclosing package or namespace (Java/Kotlin/C#/PHP/Scala) */
+  namespace?: string;
 }
 
 export interface TreeSitterSymbolExtraction {
   grammarFile: string;
   symbols: TreeSitterSymbol[];
+  /** Chunk boundaries from a grammar plugin's `chunks` query, when it has one */
+  chunkBoundaries?: TreeSitterSymbol[];
 }
 
 const require = createRequire(import.meta.url);
@@ -27,27 +39,54 @@ const require = createRequire(import.meta.url);
 const CORE_WASM_PATH = require.resolve('web-tree-sitter/tree-sitter.wasm');
 
 const SYMBOL_CANDIDATE_NODE_TYPES = [
+  'FnProto',
+  'VarDecl',
+  'annotation_type_declaration',
+  'call',
+  'category_implementation',
+  'category_interface',
+  'class',
   'class_declaration',
   'class_definition',
+  'class_implementation',
+  'class_interface',
   'class_specifier',
   'constructor_declaration',
   'enum_declaration',
+  'enum_definition',
   'enum_item',
   'function_declaration',
   'function_definition',
   'function_item',
+  'function_signature_item',
   'generator_function_declaration',
+  'impl_item',
+  'init_declaration',
   'interface_declaration',
   'lexical_declaration',
   'method',
   'method_declaration',
   'method_definition',
+  'mod_item',
+  'module',
+  'object_declaration',
+  'object_definition',
+  'protocol_declaration',
+  'protocol_function_declaration',
+  'record_declaration',
+  'singleton_method',
+  'struct_declaration',
   'struct_item',
   'struct_specifier',
+  'trait_declaration',
+  'trait_definition',
   'trait_item',
   'type_alias_declaration',
   'type_declaration',
+  'type_definition',
+  'type_item',
   'type_spec',
+  'typealias_declaration',
   'variable_declarator'
 ] as const;
 
@@ -67,6 +106,8 @@ const TREE_SITTER_PARSE_TIMEOUT_MICROS = 30_000_000n;
 let initPromise: Promise<void> | null = null;
 const languageCache = new Map<string, Promise<Language>>();
 const parserCache = new Map<string, Promise<Parser>>();
+/** Compiled queries of grammar plugins, by language */
+const pluginQueryCache = new Map<string, Promise<{ symbols: Query; chunks: Query | null }>>();
 
 function maybeResetParser(parser: Parser): void {
   const maybeReset = (parser as Parser & { reset?: () => void }).reset;
@@ -120,7 +161,12 @@ function isTreeSitterDebugEnabled(): boolean {
 }
 
 export function supportsTreeSitter(language: string): boolean {
-  return supportsCuratedTreeSitter(language);
+  return supportsCuratedTreeSitter(language) || getGrammarPlugin(language) !== undefined;
+}
+
+function grammarFileFor(language: string): string {
+  const plugin = getGrammarPlugin(language);
+  return plugin ? path.basename(plugin.wasmPath) : (CURATED_LANGUAGE_TO_WASM[language] ?? language);
 }
 
 async function ensureParserInitialized(): Promise<void> {
@@ -139,14 +185,15 @@ async function ensureParserInitialized(): Promise<void> {
 }
 
 async function loadLanguage(language: string): Promise<Language> {
+  const plugin = getGrammarPlugin(language);
   const wasmFile = CURATED_LANGUAGE_TO_WASM[language];
-  if (!wasmFile) {
+  if (!wasmFile && !plugin) {
     throw new Error(`Tree-sitter grammar is not configured for '${language}'.`);
   }
 
   let cachedLanguage = languageCache.get(language);
   if (!cachedLanguage) {
-    const { wasmPath } = resolveGrammarPath(language, import.meta.url);
+    const wasmPath = plugin?.wasmPath ?? resolveGrammarPath(language, import.meta.url).wasmPath;
     cachedLanguage = Language.load(wasmPath).catch((err) => {
       // Evict failed entry so later calls can retry after fixes
       languageCache.delete(language);
@@ -180,7 +227,126 @@ async function getParserForLanguage(language: string): Promise<Parser> {
   return cachedParser;
 }
 
-function getNodeKind(nodeType: string): string {
+/** Containers whose directly nested functions are methods (Rust impl/trait, Kotlin classes) */
+const MEMBER_CONTAINER_NODE_TYPES = new Set([
+  'impl_item',
+  'trait_item',
+  'class_declaration',
+  'object_declaration',
+  'companion_object',
+  'protocol_declaration'
+]);
+
+function isMemberFunction(node: Node): boolean {
+  // Zig functions declared inside a struct, enum or union
+  if (node.type === 'FnProto') return Boolean(closestAncestor(node, ZIG_CONTAINER_NODE_TYPES));
+  // Scala `def` in a class, object or trait body
+  if (node.parent?.type === 'template_body') {
+    return node.type === 'function_definition' || node.type === 'function_declaration';
+  }
+  if (
+    node.type !== 'function_item' &&
+    node.type !== 'function_signature_item' &&
+    node.type !== 'function_declaration'
+  ) {
+    return false;
+  }
+  // function -> body (declaration_list / class_body) -> container
+  const container = node.parent?.parent;
+  return Boolean(container && MEMBER_CONTAINER_NODE_TYPES.has(container.type));
+}
+
+/** Ruby scopes: methods defined inside them are instance methods */
+const RUBY_SCOPE_NODE_TYPES = new Set(['class', 'module', 'singleton_class']);
+
+const ZIG_CONTAINER_NODE_TYPES = new Set(['ContainerDecl']);
+
+/** Elixir definitions are macro calls: `defmodule Billing.Invoice do`, `def total(x) do` */
+const ELIXIR_DEFINITION_KINDS: Record<string, string> = {
+  defmodule: 'module',
+  defprotocol: 'protocol',
+  defimpl: 'impl',
+  def: 'function',
+  defp: 'function',
+  defmacro: 'function',
+  defmacrop: 'function',
+  defguard: 'function',
+  defguardp: 'function',
+  defdelegate: 'function'
+};
+
+const ELIXIR_SCOPE_KINDS = new Set(['module', 'protocol', 'impl']);
+
+function elixirDefinitionKind(node: Node): string | undefined {
+  if (node.type !== 'call') return undefined;
+  const target = node.childForFieldName('target');
+  return target?.type === 'identifier' ? ELIXIR_DEFINITION_KINDS[target.text] : undefined;
+}
+
+/** `Billing.Invoice` for modules, `total` for `def total(a) when a > 0` */
+function elixirDefinitionName(node: Node): string | null {
+  const args = node.namedChildren.find((child) => child?.type === 'arguments');
+  let head = args?.namedChild(0) ?? null;
+  if (head?.type === 'binary_operator') head = head.childForFieldName('left');
+  if (head?.type === 'call') head = head.childForFieldName('target');
+  return head?.type === 'alias' || head?.type === 'identifier' ? head.text : null;
+}
+
+/** `const Point = struct { ... };` declares a type; other Zig `const`s are values */
+function zigContainerKind(node: Node): string | undefined {
+  if (node.type !== 'VarDecl') return undefined;
+  const match = node.text
+    .slice(0, 300)
+    .match(/^(?:pub\s+)?(?:const|var)\s+[A-Za-z_]\w*\s*(?::[^=]+)?=\s*(?:extern\s+|packed\s+)?(\w+)/);
+  const keyword = match?.[1];
+  if (keyword === 'struct' || keyword === 'enum') return keyword;
+  return keyword === 'union' || keyword === 'opaque' ? 'type' : undefined;
+}
+
+function closestAncestor(node: Node, types: ReadonlySet<string>): Node | null {
+  for (let cursor = node.parent; cursor; cursor = cursor.parent) {
+    if (types.has(cursor.type)) return cursor;
+  }
+  return null;
+}
+
+function getNodeKind(node: Node): string {
+  const nodeType = node.type;
+  const declaredKind = elixirDefinitionKind(node) ?? zigContainerKind(node);
+  if (declaredKind) return declaredKind;
+  if (nodeType === 'impl_item') return 'impl';
+  if (nodeType === 'mod_item' || nodeType === 'module') return 'module';
+  // A Ruby `def` outside any class or module is a plain (Object-private) function
+  if (nodeType === 'method' && !closestAncestor(node, RUBY_SCOPE_NODE_TYPES)) return 'function';
+  if (
+    nodeType === 'object_declaration' ||
+    nodeType === 'object_definition' ||
+    nodeType === 'record_declaration'
+  ) {
+    return 'class';
+  }
+  if (nodeType === 'annotation_type_declaration') return 'interface';
+  // Swift protocols and Objective-C @protocol
+  if (nodeType === 'protocol_declaration') return 'protocol';
+  // Objective-C categories add methods to an existing class, like Swift extensions
+  if (nodeType === 'category_interface' || nodeType === 'category_implementation') {
+    return 'extension';
+  }
+  if (nodeType === 'init_declaration' || nodeType === 'protocol_function_declaration') {
+    return 'method';
+  }
+  if (nodeType === 'typealias_declaration') return 'type';
+  if (nodeType === 'class_declaration') {
+    // Swift: one node for class, struct, enum, extension and actor
+    const swiftKind = node.childForFieldName('declaration_kind')?.text;
+    if (swiftKind === 'struct' || swiftKind === 'enum' || swiftKind === 'extension') {
+      return swiftKind;
+    }
+    if (swiftKind) return 'class';
+    // Kotlin uses class_declaration for interfaces and enum classes as well
+    if (node.children.some((child) => child?.type === 'interface')) return 'interface';
+    if (node.namedChildren.some((child) => child?.type === 'enum_class_body')) return 'enum';
+  }
   if (nodeType.includes('class')) return 'class';
   if (nodeType.includes('interface')) return 'interface';
   if (nodeType.includes('enum')) return 'enum';
@@ -188,7 +354,15 @@ function getNodeKind(nodeType: string): string {
   if (nodeType.includes('trait')) return 'trait';
   if (nodeType.includes('constructor')) return 'method';
   if (nodeType.includes('method')) return 'method';
-  if (nodeType.includes('type_alias') || nodeType === 'type_spec') return 'type';
+  if (
+    nodeType.includes('type_alias') ||
+    nodeType === 'type_spec' ||
+    nodeType === 'type_item' ||
+    nodeType === 'type_definition'
+  ) {
+    return 'type';
+  }
+  if (isMemberFunction(node)) return 'method';
   return 'function';
 }
 
@@ -217,8 +391,56 @@ function maybeGetNameNode(node: Node): Node | null {
   return null;
 }
 
+/** `impl<T> fmt::Display for Stack<T>` is named after the implementing type: `Stack` */
+function extractImplName(node: Node): string | null {
+  const typeNode = node.childForFieldName('type');
+  if (!typeNode) return null;
+  const name = typeNode.text
+    .replace(/<[\s\S]*>/g, '')
+    .split('::')
+    .pop()
+    ?.trim();
+  return name || null;
+}
+
+/** Objective-C selector: `count` or `addItem:withCount:` */
+function extractObjcSelector(node: Node): string | null {
+  const keywords: string[] = [];
+  for (const child of node.namedChildren) {
+    if (!child) continue;
+    if (child.type === 'identifier' && keywords.length === 0) return child.text;
+    if (child.type === 'keyword_declarator') {
+      const keyword = child.childForFieldName('keyword') ?? child.namedChild(0);
+      if (keyword) keywords.push(`${keyword.text}:`);
+    }
+  }
+  return keywords.length > 0 ? keywords.join('') : null;
+}
+
 function extractNodeName(node: Node): string {
-  const nameNode = maybeGetNameNode(node);
+  if (node.type === 'impl_item') {
+    return extractImplName(node) ?? 'anonymous';
+  }
+  if (node.type === 'init_declaration') {
+    return 'init';
+  }
+  if (elixirDefinitionKind(node)) {
+    // Modules are named after their last segment; the rest is scope
+    return elixirDefinitionName(node)?.split('.').pop() ?? 'anonymous';
+  }
+  if (node.type === 'FnProto' || node.type === 'VarDecl') {
+    const nameNode =
+      node.childForFieldName('function') ??
+      node.childForFieldName('variable_type_function') ??
+      node.namedChildren.find((child) => child?.type === 'IDENTIFIER');
+    return nameNode?.text || 'anonymous';
+  }
+
+  let nameNode = maybeGetNameNode(node);
+  // Ruby `class Billing::Invoice` is named after its last segment; the rest is scope
+  if (nameNode?.type === 'scope_resolution') {
+    nameNode = nameNode.childForFieldName('name') ?? nameNode;
+  }
   if (nameNode?.text) {
     const normalized = normalizeSymbolName(nameNode.text);
     if (normalized) {
@@ -226,6 +448,11 @@ function extractNodeName(node: Node): string {
     }
   }
 
+  if (node.type === 'method_declaration' || node.type === 'method_definition') {
+    const selector = extractObjcSelector(node);
+    if (selector) return selector;
+  }
+
   const compact = node.text.slice(0, 120).replace(/\s+/g, ' ').trim();
   const match = compact.match(
     /(?:class|interface|enum|struct|trait|function|def|fn)\s+([A-Za-z_][\w$]*)/
@@ -266,6 +493,29 @@ function shouldSkipNode(language: string, node: Node): boolean {
     return true;
   }
 
+  // Scala `type Id = Long`; C typedefs share the node type
+  if (node.type === 'type_definition') {
+    return language !== 'scala';
+  }
+
+  if (node.type === 'call') {
+    return language !== 'elixir' || !elixirDefinitionKind(node);
+  }
+
+  if (node.type === 'VarDecl') {
+    return !zigContainerKind(node);
+  }
+
+  // `class`/`module` are Ruby declarations; elsewhere they are expressions or the root
+  if (node.type === 'class' || node.type === 'module') {
+    return language !== 'ruby';
+  }
+
+  // `mod foo;` only points at another file
+  if (node.type === 'mod_item') {
+    return !node.childForFieldName('body');
+  }
+
   return false;
 }
 
@@ -274,6 +524,10 @@ function getSymbolRangeNode(node: Node): Node {
   if (parent?.type === 'export_statement') {
     return parent;
   }
+  // Zig `fn` prototypes sit next to their body inside a Decl
+  if (node.type === 'FnProto' && parent?.type === 'Decl') {
+    return parent;
+  }
   return node;
 }
 
@@ -282,7 +536,7 @@ function buildSymbol(node: Node, content: string): TreeSitterSymbol {
 
   return {
     name: extractNodeName(node),
-    kind: getNodeKind(node.type),
+    kind: getNodeKind(node),
     startLine: rangeNode.startPosition.row + 1,
     endLine: rangeNode.endPosition.row + 1,
     startIndex: rangeNode.startIndex,
@@ -292,6 +546,259 @@ function buildSymbol(node: Node, content: string): TreeSitterSymbol {
   };
 }
 
+const QUALIFIED_NAME_SEPARATORS: Record<string, string> = {
+  java: '.',
+  kotlin: '.',
+  csharp: '.',
+  scala: '.',
+  swift: '.',
+  zig: '.',
+  elixir: '.',
+  rust: '::',
+  ruby: '::',
+  php: '\\'
+};
+
+const PHP_TYPE_NODE_TYPES = new Set([
+  'class_declaration',
+  'enum_declaration',
+  'interface_declaration',
+  'trait_declaration'
+]);
+
+/**
+ * Separator before a member's own name where it differs from the scope separator:
+ * Ruby `Invoice#total` (instance) and `Invoice.create` (singleton), PHP `User::save`
+ */
+const MEMBER_SEPARATORS: Record<string, (node: Node) => string | undefined> = {
+  ruby: (node) => {
+    if (node.type === 'singleton_method') return '.';
+    if (node.type !== 'method') return undefined;
+    return closestAncestor(node, RUBY_SCOPE_NODE_TYPES)?.type === 'singleton_class' ? '.' : '#';
+  },
+  php: (node) => (closestAncestor(node, PHP_TYPE_NODE_TYPES) ? '::' : undefined)
+};
+
+const NAMESPACE_NODE_TYPES = new Set(['namespace_declaration', 'namespace_definition']);
+
+/** Declarations that contribute a segment to nested symbols' qualified names */
+const SCOPE_NODE_TYPES = new Set([
+  'annotation_type_declaration',
+  'class',
+  'class_declaration',
+  'class_definition',
+  'enum_declaration',
+  'enum_definition',
+  'impl_item',
+  'interface_declaration',
+  'mod_item',
+  'module',
+  'namespace_declaration',
+  'namespace_definition',
+  'object_declaration',
+  'object_definition',
+  'protocol_declaration',
+  'record_declaration',
+  'struct_declaration',
+  'trait_declaration',
+  'trait_definition',
+  'trait_item'
+]);
+
+function isScopeNode(node: Node): boolean {
+  if (SCOPE_NODE_TYPES.has(node.type)) return true;
+  const elixirKind = elixirDefinitionKind(node);
+  return elixirKind ? ELIXIR_SCOPE_KINDS.has(elixirKind) : Boolean(zigContainerKind(node));
+}
+
+/**
+ * Scope segment of a declaration: Ruby `class Billing::Invoice` contributes
+ * `Billing::Invoice`, Elixir `defmodule Billing.Invoice` contributes `Billing.Invoice`
+ */
+function scopeSegment(node: Node): string {
+  if (elixirDefinitionKind(node)) return elixirDefinitionName(node) ?? 'anonymous';
+  const nameNode = node.childForFieldName('name');
+  return nameNode?.type === 'scope_resolution' ? nameNode.text : extractNodeName(node);
+}
+
+/**
+ * Java `package a.b;`, Kotlin `package a.b`, C# file-scoped `namespace A.B;`, PHP statement
+ * `namespace A\B;`, Scala `package a.b`
+ */
+function findPackageName(rootNode: Node, language: string): string | null {
+  for (const child of rootNode.namedChildren) {
+    if (!child) continue;
+    if (language === 'java' && child.type === 'package_declaration') {
+      const nameNode = child.namedChildren.find(
+        (n) => n?.type === 'scoped_identifier' || n?.type === 'identifier'
+      );
+      return nameNode?.text ?? null;
+    }
+    if (language === 'scala' && child.type === 'package_clause') {
+      return child.childForFieldName('name')?.text ?? null;
+    }
+    if (language === 'kotlin' && child.type === 'package_header') {
+      const nameNode = child.namedChildren.find((n) => n?.type === 'identifier');
+      return nameNode ? nameNode.text.replace(/\s+/g, '') : null;
+    }
+    if (language === 'csharp' && child.type === 'file_scoped_namespace_declaration') {
+      return child.childForFieldName('name')?.text ?? null;
+    }
+    if (
+      language === 'php' &&
+      child.type === 'namespace_definition' &&
+      !child.childForFieldName('body')
+    ) {
+      return child.childForFieldName('name')?.text ?? null;
+    }
+  }
+  return null;
+}
+
+/**
+ * Attach a qualified name built from the file's package and enclosing namespaces, classes,
+ * objects, impls, traits and inline modules. Rust paths are relative to the file's module.
+ */
+function qualifySymbols(
+  entries: Array<{ node: Node; symbol: TreeSitterSymbol }>,
+  rootNode: Node,
+  language: string
+): void {
+  const separator = QUALIFIED_NAME_SEPARATORS[language];
+  if (!separator) return;
+  const packageName = findPackageName(rootNode, language);
+
+  for (const { node, symbol } of entries) {
+    const parts: string[] = [];
+    const namespaces: string[] = [];
+    for (let cursor = node.parent; cursor; cursor = cursor.parent) {
+      if (!isScopeNode(cursor)) continue;
+      const scopeName = scopeSegment(cursor);
+      if (scopeName === 'anonymous') continue;
+      parts.unshift(scopeName);
+      if (NAMESPACE_NODE_TYPES.has(cursor.type)) namespaces.unshift(scopeName);
+    }
+    if (packageName) {
+      parts.unshift(packageName);
+      namespaces.unshift(packageName);
+    }
+    const nameField = node.childForFieldName('name');
+    const ownName =
+      nameField?.type === 'scope_resolution' || elixirDefinitionKind(node)
+        ? scopeSegment(node)
+        : symbol.name;
+    const memberSeparator = parts.length > 0 ? MEMBER_SEPARATORS[language]?.(node) : undefined;
+    symbol.qualifiedName =
+      parts.length === 0
+        ? ownName
+        : `${parts.join(separator)}${memberSeparator ?? separator}${ownName}`;
+    if (namespaces.length > 0) symbol.namespace = namespaces.join(separator);
+  }
+}
+
+function collectSymbols(rootNode: Node, language: string, content: string): TreeSitterSymbol[] {
+  const nodes = rootNode.descendantsOfType([...SYMBOL_CANDIDATE_NODE_TYPES]);
+  const entries: Array<{ node: Node; symbol: TreeSitterSymbol }> = [];
+  const seen = new Set<string>();
+  const symbols: TreeSitterSymbol[] = [];
+
+  for (const node of nodes) {
+    if (!node || !node.isNamed || shouldSkipNode(language, node)) {
+      continue;
+    }
+
+    const symbol = buildSymbol(node, content);
+    if (symbol.name === 'anonymous') {
+      continue;
+    }
+
+    const key = `${symbol.kind}:${symbol.name}:${symbol.startLine}:${symbol.endLine}`;
+    if (seen.has(key)) {
+      continue;
+    }
+
+    seen.add(key);
+    symbols.push(symbol);
+    entries.push({ node, symbol });
+  }
+
+  qualifySymbols(entries, rootNode, language);
+
+  symbols.sort((a, b) => {
+    if (a.startLine !== b.startLine) {
+      return a.startLine - b.startLine;
+    }
+    return a.endLine - b.endLine;
+  });
+
+  return symbols;
+}
+
+/** A grammar plugin's queries, compiled once; a query that doesn't compile is reported once */
+function pluginQueries(plugin: GrammarPlugin): Promise<{ symbols: Query; chunks: Query | null }> {
+  let cached = pluginQueryCache.get(plugin.language);
+  if (!cached) {
+    cached = loadLanguage(plugin.language).then((language) => ({
+      symbols: new Query(language, plugin.symbolsQuery),
+      chunks: plugin.chunksQuery ? new Query(language, plugin.chunksQuery) : null
+    }));
+    cached.catch((error: unknown) => {
+      console.error(
+        `[grammars] The '${plugin.language}' plugin's queries failed to load: ` +
+          (error instanceof Error ? error.message : String(error))
+      );
+    });
+    pluginQueryCache.set(plugin.language, cached);
+  }
+  return cached;
+}
+
+/**
+ * Symbols from the `@<tag>.<kind>` captures of a plugin query (`@definition.function`,
+ * `@chunk`), named by the `@name` capture of the same match
+ */
+function collectQuerySymbols(
+  query: Query,
+  rootNode: Node,
+  content: string,
+  tag: 'definition' | 'chunk'
+): TreeSitterSymbol[] {
+  const symbols: TreeSitterSymbol[] = [];
+  const seen = new Set<string>();
+  for (const match of query.matches(rootNode)) {
+    const target = match.captures.find(
+      (capture) => capture.name === tag || capture.name.startsWith(`${tag}.`)
+    );
+    if (!target) continue;
+    const node = target.node;
+    const nameNode = match.captures.find((capture) => capture.name === 'name')?.node;
+    const name = nameNode ? normalizeSymbolName(nameNode.text) : extractNodeName(node);
+    if (name === 'anonymous' && tag === 'definition') continue;
+    const symbol: TreeSitterSymbol = {
+      name: name === 'anonymous' ? node.type : name,
+      kind: target.name.slice(tag.length + 1) || (tag === 'chunk' ? 'block' : 'symbol'),
+      startLine: node.startPosition.row + 1,
+      endLine: node.endPosition.row + 1,
+      startIndex: node.startIndex,
+      endIndex: node.endIndex,
+      content: extractNodeContent(node, content),
+      nodeType: node.type
+    };
+    const key = `${symbol.kind}:${symbol.name}:${symbol.startLine}:${symbol.endLine}`;
+    if (seen.has(key)) continue;
+    seen.add(key);
+    symbols.push(symbol);
+  }
+  return symbols.sort((a, b) => a.startLine - b.startLine || a.endLine - b.endLine);
+}
+
+function hasTreeError(rootNode: Node): boolean {
+  const hasErrorValue = rootNode.hasError as unknown;
+  return typeof hasErrorValue === 'function'
+    ? Boolean((hasErrorValue as () => unknown)())
+    : Boolean(hasErrorValue);
+}
+
 export async function extractTreeSitterSymbols(
   content: string,
   language: string
@@ -322,49 +829,25 @@ export async function extractTreeSitterSymbols(
     }
 
     try {
-      const hasErrorValue = tree.rootNode.hasError as unknown;
-      const rootHasError =
-        typeof hasErrorValue === 'function'
-          ? Boolean((hasErrorValue as () => unknown)())
-          : Boolean(hasErrorValue);
-
-      if (rootHasError) {
+      if (hasTreeError(tree.rootNode)) {
         return null;
       }
 
-      const nodes = tree.rootNode.descendantsOfType([...SYMBOL_CANDIDATE_NODE_TYPES]);
-      const seen = new Set<string>();
-      const symbols: TreeSitterSymbol[] = [];
-
-      for (const node of nodes) {
-        if (!node || !node.isNamed || shouldSkipNode(language, node)) {
-          continue;
-        }
-
-        const symbol = buildSymbol(node, content);
-        if (symbol.name === 'anonymous') {
-          continue;
-        }
-
-        const key = `${symbol.kind}:${symbol.name}:${symbol.startLine}:${symbol.endLine}`;
-        if (seen.has(key)) {
-          continue;
-        }
-
-        seen.add(key);
-        symbols.push(symbol);
+      const plugin = getGrammarPlugin(language);
+      if (plugin) {
+        const queries = await pluginQueries(plugin);
+        return {
+          grammarFile: grammarFileFor(language),
+          symbols: collectQuerySymbols(queries.symbols, tree.rootNode, content, 'definition'),
+          ...(queries.chunks && {
+            chunkBoundaries: collectQuerySymbols(queries.chunks, tree.rootNode, content, 'chunk')
+          })
+        };
       }
 
-      symbols.sort((a, b) => {
-        if (a.startLine !== b.startLine) {
-          return a.startLine - b.startLine;
-        }
-        return a.endLine - b.endLine;
-      });
-
       return {
-        grammarFile: CURATED_LANGUAGE_TO_WASM[language] ?? language,
-        symbols
+        grammarFile: grammarFileFor(language),
+        symbols: collectSymbols(tree.rootNode, language, content)
       };
     } finally {
       tree.delete();
@@ -391,6 +874,8 @@ export interface IdentifierOccurrence {
 
 const IDENTIFIER_NODE_TYPES = [
   'identifier',
+  'constant',
+  'name',
   'type_identifier',
   'property_identifier',
   'field_identifier',
@@ -464,13 +949,7 @@ export async function findIdentifierOccurrences(
     }
 
     try {
-      const hasErrorValue = tree.rootNode.hasError as unknown;
-      const rootHasError =
-        typeof hasErrorValue === 'function'
-          ? Boolean((hasErrorValue as () => unknown)())
-          : Boolean(hasErrorValue);
-
-      if (rootHasError) {
+      if (hasTreeError(tree.rootNode)) {
         return null;
       }
 
@@ -516,3 +995,337 @@ export async function findIdentifierOccurrences(
     return null;
   }
 }
+
+export interface TreeSitterCall {
+  /** Bare callee name, e.g. `save` for `this.repo.save(user)` */
+  callee: string;
+  /** Innermost enclosing function/method, or null for top-level calls */
+  caller: TreeSitterSymbol | null;
+  line: number;
+}
+
+export interface TreeSitterCallExtraction {
+  symbols: TreeSitterSymbol[];
+  calls: TreeSitterCall[];
+  /** Top-level constants (kind `constant`), collected from the same parse for symbol search */
+  constants: TreeSitterSymbol[];
+}
+
+const CALL_NODE_TYPES = [
+  'call',
+  'call_expression',
+  'function_call_expression',
+  'invocation_expression',
+  'member_call_expression',
+  'message_expression',
+  'method_invocation',
+  'new_expression',
+  'nullsafe_member_call_expression',
+  'object_creation_expression',
+  'scoped_call_expression'
+] as const;
+
+// `function` (JS/TS/Python/Go/Rust/C/C++/C#/PHP/Scala), `name` (Java, PHP methods), `method`
+// (Ruby, Objective-C messages), `constructor`/`type` (new Foo()), `target` (Elixir). Kotlin and
+// Swift call_expression and PHP `new Foo()` have no fields: the callee is their first named child.
+const CALLEE_FIELD_CANDIDATES = [
+  'function',
+  'name',
+  'method',
+  'constructor',
+  'type',
+  'target'
+] as const;
+
+/** Elixir macros that declare or import rather than call */
+const ELIXIR_NON_CALL_TARGETS = new Set([
+  ...Object.keys(ELIXIR_DEFINITION_KINDS),
+  'alias',
+  'import',
+  'require',
+  'use',
+  'defstruct'
+]);
+
+/** `total(a)` in `def total(a) do`, which names the definition */
+function isElixirDefinitionHead(node: Node): boolean {
+  let args = node.parent;
+  if (args?.type === 'binary_operator') args = args.parent;
+  return args?.type === 'arguments' && Boolean(args.parent && elixirDefinitionKind(args.parent));
+}
+
+const CALLER_KINDS = new Set(['function', 'method']);
+
+function extractCalleeName(callNode: Node): string | null {
+  let calleeNode: Node | null = null;
+  for (const fieldName of CALLEE_FIELD_CANDIDATES) {
+    calleeNode = callNode.childForFieldName(fieldName);
+    if (calleeNode) break;
+  }
+  if (!calleeNode && callNode.type === 'call_expression') {
+    calleeNode = callNode.namedChild(0);
+  }
+  if (!calleeNode && callNode.type === 'object_creation_expression') {
+    const first = callNode.namedChild(0);
+    if (first?.type === 'name' || first?.type === 'qualified_name') calleeNode = first;
+  }
+  if (!calleeNode) return null;
+
+  // Reduce `a.b.c`, `a::b`, `a->b`, `Foo<T>` to the last plain identifier
+  let text = calleeNode.text.slice(0, 200);
+  let previous = '';
+  while (previous !== text) {
+    previous = text;
+    text = text.replace(/<[^<>]*>/g, '');
+  }
+  const match = text.match(/([A-Za-z_$][\w$]*)[!?]?\s*$/);
+  return match?.[1] ?? null;
+}
+
+function findEnclosingCaller(symbols: TreeSitterSymbol[], index: number): TreeSitterSymbol | null {
+  let best: TreeSitterSymbol | null = null;
+  for (const symbol of symbols) {
+    if (!CALLER_KINDS.has(symbol.kind)) continue;
+    if (index < symbol.startIndex || index >= symbol.endIndex) continue;
+    if (!best || symbol.endIndex - symbol.startIndex < best.endIndex - best.startIndex) {
+      best = symbol;
+    }
+  }
+  return best;
+}
+
+const CONSTANT_NODE_TYPES = [
+  'lexical_declaration',
+  'expression_statement',
+  'assignment',
+  'const_declaration',
+  'const_spec',
+  'const_item',
+  'static_item',
+  'field_declaration',
+  'property_declaration',
+  'preproc_def'
+] as const;
+
+const JS_LIKE_LANGUAGES = new Set([
+  'javascript',
+  'javascriptreact',
+  'typescript',
+  'typescriptreact'
+]);
+
+function isModuleLevel(node: Node): boolean {
+  let parent = node.parent;
+  if (parent?.type === 'export_statement') parent = parent.parent;
+  return !parent || ['program', 'module', 'source_file', 'translation_unit'].includes(parent.type);
+}
+
+function constantSymbol(declaration: Node, nameNode: Node, content: string): TreeSitterSymbol {
+  const rangeNode = getSymbolRangeNode(declaration);
+  return {
+    name: normalizeSymbolName(nameNode.text),
+    kind: 'constant',
+    startLine: rangeNode.startPosition.row + 1,
+    endLine: rangeNode.endPosition.row + 1,
+    startIndex: rangeNode.startIndex,
+    endIndex: rangeNode.endIndex,
+    content: extractNodeContent(rangeNode, content),
+    nodeType: declaration.type
+  };
+}
+
+/** Bodies in which a Ruby constant assignment is not a constant definition */
+const RUBY_LOCAL_SCOPE_NODE_TYPES = new Set([
+  'method',
+  'singleton_method',
+  'block',
+  'do_block',
+  'lambda'
+]);
+
+function hasModifier(node: Node, ...keywords: string[]): boolean {
+  const modifierText = node.namedChildren
+    .map((child) => (child?.type.includes('modifier') ? child.text : ''))
+    .join(' ');
+  return keywords.every((keyword) => new RegExp(`\\b${keyword}\\b`).test(modifierText));
+}
+
+/**
+ * Module-level constants: JS/TS `const` (non-function values), Python UPPER_CASE
+ * assignments, Go/Rust `const`/`static`, Java `static final` and C# `const` fields,
+ * Kotlin `const val`, Ruby `RATE = 0.2` in a class, module or the top level, PHP `const`,
+ * and C/C++ `#define`.
+ */
+function collectConstants(rootNode: Node, language: string, content: string): TreeSitterSymbol[] {
+  const constants: TreeSitterSymbol[] = [];
+  const entries: Array<{ node: Node; symbol: TreeSitterSymbol }> = [];
+  const push = (declaration: Node, nameNode: Node) => {
+    const symbol = constantSymbol(declaration, nameNode, content);
+    constants.push(symbol);
+    entries.push({ node: declaration, symbol });
+  };
+
+  for (const node of rootNode.descendantsOfType([...CONSTANT_NODE_TYPES])) {
+    if (!node || !node.isNamed) continue;
+
+    switch (node.type) {
+      case 'lexical_declaration': {
+        if (!JS_LIKE_LANGUAGES.has(language) || !isModuleLevel(node)) break;
+        if (node.child(0)?.text !== 'const') break;
+        for (const declarator of node.namedChildren) {
+          if (!declarator || declarator.type !== 'variable_declarator') continue;
+          if (isFunctionVariableDeclarator(declarator)) continue;
+          const nameNode = declarator.childForFieldName('name');
+          if (nameNode?.type === 'identifier') {
+            push(node, nameNode);
+          }
+        }
+        break;
+      }
+      case 'expression_statement': {
+        if (language !== 'python' || node.parent?.type !== 'module') break;
+        const assignment = node.namedChild(0);
+        const left =
+          assignment?.type === 'assignment' ? assignment.childForFieldName('left') : null;
+        if (left?.type === 'identifier' && /^[A-Z][A-Z0-9_]*$/.test(left.text)) {
+          push(node, left);
+        }
+        break;
+      }
+      case 'assignment': {
+        if (language !== 'ruby' || closestAncestor(node, RUBY_LOCAL_SCOPE_NODE_TYPES)) break;
+        const left = node.childForFieldName('left');
+        if (left?.type === 'constant') push(node, left);
+        break;
+      }
+      case 'const_declaration': {
+        if (language !== 'php') break;
+        for (const element of node.namedChildren) {
+          if (element?.type !== 'const_element') continue;
+          const nameNode = element.namedChildren.find((child) => child?.type === 'name');
+          if (nameNode) push(node, nameNode);
+        }
+        break;
+      }
+      case 'const_spec': {
+        for (const nameNode of node.childrenForFieldName('name')) {
+          if (nameNode) push(node, nameNode);
+        }
+        break;
+      }
+      case 'const_item':
+      case 'static_item':
+      case 'preproc_def': {
+        const nameNode = node.childForFieldName('name');
+        if (nameNode) push(node, nameNode);
+        break;
+      }
+      case 'field_declaration': {
+        const isConstant =
+          language === 'java'
+            ? hasModifier(node, 'static', 'final')
+            : language === 'csharp' && hasModifier(node, 'const');
+        if (!isConstant) break;
+        for (const declarator of node.descendantsOfType('variable_declarator')) {
+          const nameNode =
+            declarator?.childForFieldName('name') ??
+            declarator?.namedChildren.find((child) => child?.type === 'identifier');
+          if (declarator && nameNode) push(node, nameNode);
+        }
+        break;
+      }
+      case 'property_declaration': {
+        if (language !== 'kotlin' || !hasModifier(node, 'const')) break;
+        const nameNode = node.namedChildren
+          .find((child) => child?.type === 'variable_declaration')
+          ?.namedChildren.find((child) => child?.type === 'simple_identifier');
+        if (nameNode) push(node, nameNode);
+        break;
+      }
+    }
+  }
+
+  qualifySymbols(entries, rootNode, language);
+
+  return constants.sort((a, b) => a.startLine - b.startLine);
+}
+
+/**
+ * Extract definitions and call sites in one parse, attributing each call to its innermost
+ * enclosing function or method. Callees are bare names; resolution to a definition is left to
+ * the caller, so dynamic dispatch and same-name functions are not disambiguated.
+ * Returns null when Tree-sitter isn't available/supported.
+ */
+export async function extractTreeSitterCalls(
+  content: string,
+  language: string
+): Promise<TreeSitterCallExtraction | null> {
+  if (!supportsTreeSitter(language) || !content.trim()) {
+    return null;
+  }
+
+  if (Buffer.byteLength(content, 'utf8') > MAX_TREE_SITTER_PARSE_BYTES) {
+    return null;
+  }
+
+  try {
+    const parser = await getParserForLanguage(language);
+    setParseTimeout(parser);
+
+    let tree: ReturnType<Parser['parse']>;
+    try {
+      tree = parser.parse(content);
+    } catch (error) {
+      evictParser(language, parser);
+      throw error;
+    }
+
+    if (!tree) {
+      evictParser(language, parser);
+      return null;
+    }
+
+    try {
+      if (hasTreeError(tree.rootNode)) {
+        return null;
+      }
+
+      const symbols = collectSymbols(tree.rootNode, language, content);
+      const calls: TreeSitterCall[] = [];
+
+      for (const node of tree.rootNode.descendantsOfType([...CALL_NODE_TYPES])) {
+        if (!node || !node.isNamed) continue;
+        const callee = extractCalleeName(node);
+        if (!callee) continue;
+        if (
+          language === 'elixir' &&
+          (ELIXIR_NON_CALL_TARGETS.has(callee) || isElixirDefinitionHead(node))
+        ) {
+          continue;
+        }
+
+        calls.push({
+          callee,
+          caller: findEnclosingCaller(symbols, node.startIndex),
+          line: node.startPosition.row + 1
+        });
+      }
+
+      calls.sort((a, b) => a.line - b.line);
+
+      return { symbols, calls, constants: collectConstants(tree.rootNode, language, content) };
+    } finally {
+      tree.delete();
+    }
+  } catch (error) {
+    evictParser(language);
+
+    if (isTreeSitterDebugEnabled()) {
+      console.error(
+        `[DEBUG] Tree-sitter call extraction failed for '${language}':`,
+        error instanceof Error ? error.message : String(error)
+      );
+    }
+    return null;
+  }
+}
@@ -636,6 +636,8 @@ export class InternalFileGraph {
   private importedSymbols: Map<string, Set<string>> = new Map();
   // Map: fromFile -> toFile -> edge details (line/symbols)
   private importDetails: Map<string, Map<string, ImportEdgeDetail>> = new Map();
+  // Map: normalized file path -> external packages it imports
+  private externalImports: Map<string, Set<string>> = new Map();
   // Root path for relative path conversion
   private rootPath: string;
 
@@ -727,6 +729,17 @@ export class InternalFileGraph {
     }
   }
 
+  /**
+   * Track that importingFile imports an external (non-workspace) package
+   */
+  trackExternalImport(importingFile: string, packageName: string): void {
+    const fromFile = this.normalizePath(importingFile);
+    if (!this.externalImports.has(fromFile)) {
+      this.externalImports.set(fromFile, new Set());
+    }
+    this.externalImports.get(fromFile)!.add(packageName);
+  }
+
   /**
    * Track exports from a file
    */
@@ -865,6 +878,7 @@ export class InternalFileGraph {
     imports: Record<string, string[]>;
     exports: Record<string, FileExport[]>;
     importDetails?: Record<string, Record<string, ImportEdgeDetail>>;
+    externalImports?: Record<string, string[]>;
     stats: { files: number; edges: number; avgDependencies: number };
   } {
     const imports: Record<string, string[]> = {};
@@ -890,10 +904,16 @@ export class InternalFileGraph {
       }
     }
 
+    const externalImports: Record<string, string[]> = {};
+    for (const [file, packages] of this.externalImports.entries()) {
+      externalImports[file] = Array.from(packages).sort();
+    }
+
     return {
       imports,
       exports,
       ...(Object.keys(importDetails).length > 0 ? { importDetails } : {}),
+      ...(Object.keys(externalImports).length > 0 ? { externalImports } : {}),
       stats: this.getStats()
     };
   }
@@ -906,6 +926,7 @@ export class InternalFileGraph {
       imports?: Record<string, string[]>;
       exports?: Record<string, FileExport[]>;
       importDetails?: Record<string, Record<string, ImportEdgeDetail>>;
+      externalImports?: Record<string, string[]>;
     },
     rootPath: string
   ): InternalFileGraph {
@@ -923,6 +944,12 @@ export class InternalFileGraph {
       }
     }
 
+    if (data.externalImports) {
+      for (const [file, packages] of Object.entries(data.externalImports)) {
+        graph.externalImports.set(file, new Set(packages));
+      }
+    }
+
     if (data.importDetails) {
       for (const [fromFile, edges] of Object.entries(data.importDetails)) {
         const edgeMap = new Map<string, ImportEdgeDetail>();
@@ -2,7 +2,8 @@
  * Workspace Detection Utilities
  *
  * Scans monorepo workspace structures and detects ecosystem configuration.
- * Supports Nx, Turborepo, Lerna, pnpm, and npm workspaces.
+ * Supports Nx, Turborepo, Lerna, pnpm, and npm workspaces, plus Go workspaces (`go.work`),
+ * Cargo workspaces and Bazel packages for package-scoped indexing.
  */
 
 import { promises as fs } from 'fs';
@@ -104,6 +105,305 @@ export async function detectWorkspaceType(rootPath: string): Promise<WorkspaceTy
   return 'single';
 }
 
+export type WorkspaceEcosystem = 'npm' | 'go' | 'cargo' | 'bazel';
+
+export interface WorkspacePackage {
+  /** npm package name, Go module path, crate name or Bazel label (`//services/api`) */
+  name: string;
+  /** Repo-relative posix directory the package owns (never the root) */
+  directory: string;
+  ecosystem: WorkspaceEcosystem;
+}
+
+const SCAN_IGNORE = [
+  '**/node_modules/**',
+  '**/dist/**',
+  '**/.git/**',
+  '**/target/**',
+  'bazel-*/**'
+];
+const ECOSYSTEM_ORDER: WorkspaceEcosystem[] = ['npm', 'go', 'cargo', 'bazel'];
+
+async function readText(file: string): Promise<string | null> {
+  try {
+    return await fs.readFile(file, 'utf-8');
+  } catch {
+    return null;
+  }
+}
+
+/** Workspace globs from package.json `workspaces` (array or `{ packages }`). */
+export function parsePackageJsonWorkspaces(content: string): string[] {
+  try {
+    const workspaces = (JSON.parse(content) as { workspaces?: unknown }).workspaces;
+    const list = Array.isArray(workspaces)
+      ? workspaces
+      : (workspaces as { packages?: unknown } | undefined)?.packages;
+    return Array.isArray(list) ? list.filter((g): g is string => typeof g === 'string') : [];
+  } catch {
+    return [];
+  }
+}
+
+/** `packages:` globs from pnpm-workspace.yaml (block list form). */
+export function parsePnpmWorkspace(content: string): string[] {
+  const globs: string[] = [];
+  let inPackages = false;
+  for (const line of content.split(/\r?\n/)) {
+    if (/^packages\s*:/.test(line)) {
+      inPackages = true;
+      continue;
+    }
+    if (!inPackages) continue;
+    const item = line.match(/^\s+-\s*['"]?([^'"#]+?)['"]?\s*(#.*)?$/);
+    if (item) globs.push(item[1]);
+    else if (/^\S/.test(line)) inPackages = false;
+  }
+  return globs;
+}
+
+/** Directories from the `use` directives of a go.work file. */
+export function parseGoWork(content: string): string[] {
+  const dirs: string[] = [];
+  const stripped = content.replace(/\/\/.*$/gm, '');
+  for (const match of stripped.matchAll(/^\s*use\s*(?:\(([^)]*)\)|(\S+))/gm)) {
+    const entries = match[1] !== undefined ? match[1].split(/\s+/) : [match[2]];
+    dirs.push(...entries.filter(Boolean).map((entry) => entry.replace(/^"|"$/g, '')));
+  }
+  return dirs;
+}
+
+export function parseGoModulePath(content: string): string | undefined {
+  return content.match(/^\s*module\s+"?([^\s"]+)"?/m)?.[1];
+}
+
+/** Body of one `[section]` of a TOML file, up to the next table header. */
+function tomlSection(content: string, section: string): string | undefined {
+  const lines = content.split(/\r?\n/);
+  const start = lines.findIndex((line) => line.trim() === `[${section}]`);
+  if (start < 0) return undefined;
+  const end = lines.findIndex((line, i) => i > start && /^\s*\[/.test(line));
+  return lines.slice(start + 1, end < 0 ? undefined : end).join('\n');
+}
+
+function tomlStringArray(section: string, key: string): string[] {
+  const match = section.match(new RegExp(`^\\s*${key}\\s*=\\s*\\[([\\s\\S]*?)\\]`, 'm'));
+  if (!match) return [];
+  return [...match[1].replace(/#.*$/gm, '').matchAll(/["']([^"']+)["']/g)].map((m) => m[1]);
+}
+
+/** `members` and `exclude` globs of a Cargo `[workspace]`, or null when there is none. */
+export function parseCargoWorkspace(
+  content: string
+): { members: string[]; exclude: string[] } | null {
+  const section = tomlSection(content, 'workspace');
+  if (section === undefined) return null;
+  return {
+    members: tomlStringArray(section, 'members'),
+    exclude: tomlStringArray(section, 'exclude')
+  };
+}
+
+export function parseCargoPackageName(content: string): string | undefined {
+  return tomlSection(content, 'package')?.match(/^\s*name\s*=\s*["']([^"']+)["']/m)?.[1];
+}
+
+/** Module paths a go.mod requires, from single-line and block `require` directives. */
+export function parseGoRequires(content: string): string[] {
+  const modules: string[] = [];
+  const stripped = content.replace(/\/\/.*$/gm, '');
+  for (const match of stripped.matchAll(/^\s*require\s*\(([\s\S]*?)\)/gm)) {
+    for (const line of match[1].split('\n')) {
+      const module = line.trim().split(/\s+/)[0];
+      if (module) modules.push(module.replace(/"/g, ''));
+    }
+  }
+  for (const match of stripped.matchAll(/^\s*require\s+"?([^\s("]+)"?\s/gm)) {
+    modules.push(match[1]);
+  }
+  return [...new Set(modules)];
+}
+
+/** Crate names a Cargo.toml depends on, including dev, build and workspace dependencies. */
+export function parseCargoDependencies(content: string): string[] {
+  const names: string[] = [];
+  for (const section of [
+    'dependencies',
+    'dev-dependencies',
+    'build-dependencies',
+    'workspace.dependencies'
+  ]) {
+    const body = tomlSection(content, section) ?? '';
+    for (const match of body.matchAll(/^\s*([A-Za-z0-9_-]+)\s*=/gm)) names.push(match[1]);
+  }
+  // `[dependencies.serde]` tables
+  const tables = /^\s*\[(?:workspace\.)?(?:dev-|build-)?dependencies\.([A-Za-z0-9_-]+)\]/gm;
+  for (const match of content.matchAll(tables)) names.push(match[1]);
+  return [...new Set(names)];
+}
+
+function toDirectory(rootPath: string, file: string): string {
+  return path.relative(rootPath, path.dirname(file)).replace(/\\/g, '/');
+}
+
+async function globManifests(
+  rootPath: string,
+  dirGlobs: string[],
+  manifest: string,
+  exclude: string[] = []
+): Promise<string[]> {
+  const include = dirGlobs.filter((g) => !g.startsWith('!'));
+  if (include.length === 0) return [];
+  const negated = dirGlobs.filter((g) => g.startsWith('!')).map((g) => g.slice(1));
+  const toManifestGlob = (g: string) =>
+    `${g.replace(/^\.\//, '').replace(/\/+$/, '')}/${manifest}`;
+  return glob(include.map(toManifestGlob), {
+    cwd: rootPath,
+    absolute: true,
+    nodir: true,
+    ignore: [...SCAN_IGNORE, ...[...negated, ...exclude].map(toManifestGlob)]
+  });
+}
+
+async function detectNpmPackages(rootPath: string): Promise<WorkspacePackage[]> {
+  const rootManifest = await readText(path.join(rootPath, 'package.json'));
+  const pnpmManifest = await readText(path.join(rootPath, 'pnpm-workspace.yaml'));
+  const globs = [
+    ...(rootManifest ? parsePackageJsonWorkspaces(rootManifest) : []),
+    ...(pnpmManifest ? parsePnpmWorkspace(pnpmManifest) : [])
+  ];
+  const declared = await globManifests(rootPath, globs, 'package.json');
+  const conventional = (await scanWorkspacePackageJsons(rootPath)).map((pkg) => pkg.filePath);
+
+  const packages: WorkspacePackage[] = [];
+  for (const file of new Set([...conventional, ...declared])) {
+    const directory = toDirectory(rootPath, file);
+    if (!directory) continue;
+    try {
+      const name = (JSON.parse((await readText(file)) ?? '') as { name?: unknown }).name;
+      if (typeof name === 'string' && name) packages.push({ name, directory, ecosystem: 'npm' });
+    } catch {
+      // skip
+    }
+  }
+  return packages;
+}
+
+async function detectGoModules(rootPath: string): Promise<WorkspacePackage[]> {
+  const goWork = await readText(path.join(rootPath, 'go.work'));
+  if (!goWork) return [];
+  const packages: WorkspacePackage[] = [];
+  for (const dir of parseGoWork(goWork)) {
+    const directory = path.posix.normalize(dir.replace(/\\/g, '/')).replace(/\/$/, '');
+    if (!directory || directory === '.' || directory.startsWith('..')) continue;
+    const goMod = await readText(path.join(rootPath, directory, 'go.mod'));
+    const name = (goMod && parseGoModulePath(goMod)) || directory;
+    packages.push({ name, directory, ecosystem: 'go' });
+  }
+  return packages;
+}
+
+async function detectCargoCrates(rootPath: string): Promise<WorkspacePackage[]> {
+  const cargoToml = await readText(path.join(rootPath, 'Cargo.toml'));
+  const workspace = cargoToml ? parseCargoWorkspace(cargoToml) : null;
+  if (!workspace) return [];
+  const { members, exclude } = workspace;
+  const manifests = await globManifests(rootPath, members, 'Cargo.toml', exclude);
+  const packages: WorkspacePackage[] = [];
+  for (const file of manifests) {
+    const directory = toDirectory(rootPath, file);
+    if (!directory) continue;
+    const name = parseCargoPackageName((await readText(file)) ?? '');
+    packages.push({ name: name ?? path.posix.basename(directory), directory, ecosystem: 'cargo' });
+  }
+  return packages;
+}
+
+async function detectBazelPackages(rootPath: string): Promise<WorkspacePackage[]> {
+  const markers = ['MODULE.bazel', 'WORKSPACE', 'WORKSPACE.bazel'];
+  const contents = await Promise.all(markers.map((m) => readText(path.join(rootPath, m))));
+  const isBazel = contents.some((content) => content !== null);
+  if (!isBazel) return [];
+  const buildFiles = await glob(['**/BUILD', '**/BUILD.bazel'], {
+    cwd: rootPath,
+    absolute: true,
+    nodir: true,
+    ignore: SCAN_IGNORE
+  });
+  return [...new Set(buildFiles.map((file) => toDirectory(rootPath, file)))]
+    .filter(Boolean)
+    .map((directory): WorkspacePackage => ({
+      name: `//${directory}`,
+      directory,
+      ecosystem: 'bazel'
+    }));
+}
+
+/**
+ * Workspace packages of a monorepo across ecosystems, sorted by directory. Only declared
+ * members count: a lone root go.mod or Cargo.toml is a single package, not a workspace.
+ */
+export async function detectWorkspacePackages(rootPath: string): Promise<WorkspacePackage[]> {
+  const found = (
+    await Promise.all([
+      detectNpmPackages(rootPath),
+      detectGoModules(rootPath),
+      detectCargoCrates(rootPath),
+      detectBazelPackages(rootPath)
+    ])
+  ).flat();
+
+  const seen = new Set<string>();
+  return found
+    .filter((pkg) => {
+      const key = `${pkg.ecosystem}:${pkg.directory}`;
+      if (seen.has(key)) return false;
+      seen.add(key);
+      return true;
+    })
+    .sort(
+      (a, b) =>
+        a.directory.localeCompare(b.directory) ||
+        ECOSYSTEM_ORDER.indexOf(a.ecosystem) - ECOSYSTEM_ORDER.indexOf(b.ecosystem)
+    );
+}
+
+/** Package owning `relativePath`: the deepest directory match, language package managers first. */
+export function findOwningPackage(
+  packages: readonly WorkspacePackage[],
+  relativePath: string
+): WorkspacePackage | null {
+  let best: WorkspacePackage | null = null;
+  for (const pkg of packages) {
+    if (!relativePath.startsWith(`${pkg.directory}/`)) continue;
+    const deeper = !best || pkg.directory.length > best.directory.length;
+    const preferred =
+      best?.directory === pkg.directory &&
+      ECOSYSTEM_ORDER.indexOf(pkg.ecosystem) < ECOSYSTEM_ORDER.indexOf(best.ecosystem);
+    if (deeper || preferred) best = pkg;
+  }
+  return best;
+}
+
+/** npm package name -> directory, for resolving imports of workspace packages by name. */
+export function npmPackageDirs(packages: readonly WorkspacePackage[]): Record<string, string> {
+  const dirs: Record<string, string> = {};
+  for (const pkg of packages) {
+    if (pkg.ecosystem === 'npm') dirs[pkg.name] = pkg.directory;
+  }
+  return dirs;
+}
+
+/**
+ * Map workspace package names to their repo-relative posix directories.
+ * The root package.json is not a workspace package and is left out.
+ */
+export async function detectWorkspacePackageDirs(
+  rootPath: string
+): Promise<Record<string, string>> {
+  return npmPackageDirs(await detectNpmPackages(rootPath));
+}
+
 /**
  * Aggregate dependencies from all workspace packages.
  */
@@ -136,29 +136,27 @@ describe('AST Chunker Integration', () => {
   });
 
   // Test 4: Unsupported language — regex/line fallback
-  it('produces chunks via fallback for unsupported languages (.rb)', async () => {
-    const rubyContent = [
-      'class Calculator',
-      '  def add(a, b)',
-      '    a + b',
-      '  end',
+  it('produces chunks via fallback for unsupported languages (.sh)', async () => {
+    const shellContent = [
+      'add() {',
+      '  echo $(($1 + $2))',
+      '}',
       '',
-      '  def subtract(a, b)',
-      '    a - b',
-      '  end',
-      'end',
+      'subtract() {',
+      '  echo $(($1 - $2))',
+      '}',
       '',
-      'def standalone_function(x)',
-      '  x * 2',
-      'end'
+      'standalone_function() {',
+      '  echo $(($1 * 2))',
+      '}'
     ].join('\n');
 
-    const result = await analyzer.analyze('/virtual/calculator.rb', rubyContent);
+    const result = await analyzer.analyze('/virtual/calculator.sh', shellContent);
 
     // Chunks produced
     expect(result.chunks.length).toBeGreaterThan(0);
 
-    // Should NOT be ast-aligned (Ruby has no grammar)
+    // Should NOT be ast-aligned (shell has no grammar)
     expect(result.metadata.chunkStrategy).toBe('line-or-component');
     expect(result.metadata.symbolAware).toBeUndefined();
 
@@ -6,6 +6,7 @@ import {
   mergeSmallSymbolChunks,
   splitOversizedChunks,
   createASTAlignedChunks,
+  estimateTokens,
   type ASTChunkOptions
 } from '../src/utils/ast-chunker.js';
 
@@ -334,6 +335,17 @@ describe('splitOversizedChunks', () => {
     expect(symbolChunks).toHaveLength(1);
   });
 
+  it('150-line function (maxLines=150) → unchanged despite its scope prefix line', () => {
+    const content = makeContent(150);
+    const symbols = [sym('edgeFunc', 'function', 1, 150)];
+
+    const chunks = generateASTChunks(content, symbols, { ...defaultOptions, maxChunkLines: 150 });
+    const symbolChunks = chunks.filter((c) => c.metadata?.symbolAware === true);
+    expect(symbolChunks).toHaveLength(1);
+    expect(symbolChunks[0].content.split('\n').length).toBeGreaterThan(150);
+    expect(splitOversizedChunks(symbolChunks, 150)).toHaveLength(1);
+  });
+
   it('400-line function → split into 3+ pieces', () => {
     const content = makeContent(400);
     const symbols = [sym('hugeFunc', 'function', 1, 400)];
@@ -364,6 +376,61 @@ describe('splitOversizedChunks', () => {
   });
 });
 
+describe('splitOversizedChunks with token limit and overlap', () => {
+  it('splits a symbol under maxLines when it exceeds maxTokens', () => {
+    const content = makeContent(100);
+    const symbols = [sym('denseFunc', 'function', 1, 100)];
+
+    const chunks = generateASTChunks(content, symbols, defaultOptions);
+    const split = splitOversizedChunks(chunks, 150, { maxTokens: 200 });
+
+    const symbolChunks = split.filter((c) => c.metadata?.symbolAware === true);
+    expect(symbolChunks.length).toBeGreaterThanOrEqual(2);
+    for (const c of symbolChunks) {
+      expect(estimateTokens(c.content)).toBeLessThanOrEqual(200);
+    }
+  });
+
+  it('repeats overlap lines only at the start of continuation pieces', () => {
+    const content = makeContent(200);
+    const symbols = [sym('bigFunc', 'function', 1, 200)];
+
+    const chunks = generateASTChunks(content, symbols, defaultOptions);
+    const split = splitOversizedChunks(chunks, 150, { overlapLines: 5 });
+
+    expect(split).toHaveLength(2);
+    const [first, second] = split;
+    expect(first.metadata.overlapLines).toBeUndefined();
+    expect(second.metadata.overlapLines).toBe(5);
+    expect(second.startLine).toBe(first.endLine - 4);
+    expect(second.content.split('\n').slice(0, 5)).toEqual(first.content.split('\n').slice(-5));
+  });
+
+  it('never overlaps chunks that fit the limits', () => {
+    const content = makeContent(100);
+    const symbols = [sym('normalFunc', 'function', 1, 100)];
+
+    const chunks = generateASTChunks(content, symbols, defaultOptions);
+    const split = splitOversizedChunks(chunks, 150, { maxTokens: 10_000, overlapLines: 10 });
+
+    expect(split).toEqual(chunks);
+  });
+
+  it('leaves a single oversized line intact', () => {
+    const line = 'x'.repeat(4000);
+    const symbols = [{ ...sym('oneLiner', 'function', 1, 1), content: line }];
+
+    const chunks = generateASTChunks(line, symbols, {
+      ...defaultOptions,
+      includeScopePrefix: false
+    });
+    const split = splitOversizedChunks(chunks, 150, { maxTokens: 100 });
+
+    expect(split).toHaveLength(1);
+    expect(split[0].content).toBe(line);
+  });
+});
+
 // ---------------------------------------------------------------------------
 // createASTAlignedChunks (integration)
 // ---------------------------------------------------------------------------
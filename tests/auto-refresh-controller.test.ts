@@ -27,5 +27,21 @@ describe('AutoRefreshController', () => {
     expect(controller.consumeQueuedRefresh('ready')).toBe(true);
     expect(controller.consumeQueuedRefresh('ready')).toBe(false);
   });
-});
 
+  it('collects changed paths reported while indexing', () => {
+    const controller = createAutoRefreshController();
+    controller.onFileChange(true, ['/repo/a.ts']);
+    controller.onFileChange(true, ['/repo/b.ts', '/repo/a.ts']);
+    expect(controller.consumeQueuedRefresh('ready')).toBe(true);
+    expect(controller.takeQueuedPaths()?.sort()).toEqual(['/repo/a.ts', '/repo/b.ts']);
+    expect(controller.takeQueuedPaths()).toEqual([]);
+  });
+
+  it('falls back to a full incremental scan when a change has no paths', () => {
+    const controller = createAutoRefreshController();
+    controller.onFileChange(true, ['/repo/a.ts']);
+    controller.onFileChange(true);
+    expect(controller.consumeQueuedRefresh('ready')).toBe(true);
+    expect(controller.takeQueuedPaths()).toBeUndefined();
+  });
+});
@@ -0,0 +1,146 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { execFileSync } from 'child_process';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { describeAge, parseBlamePorcelain } from '../src/core/blame-context.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const ADA = 'a'.repeat(40);
+const GRACE = 'b'.repeat(40);
+const PORCELAIN = [
+  `${ADA} 1 1 2`,
+  'author Ada Lovelace',
+  'author-mail <ada@example.com>',
+  'author-time 1790848800',
+  'summary Add refunds',
+  'filename src/refunds.ts',
+  '\texport function refund() {',
+  `${ADA} 2 2`,
+  '\t  return 1;',
+  `${GRACE} 3 3 1`,
+  'author Grace Hopper',
+  'author-mail <grace@example.com>',
+  'author-time 1791100000',
+  'summary Cap refunds at the order total',
+  'filename src/refunds.ts',
+  '\t}',
+  `${'0'.repeat(40)} 4 4 1`,
+  'author Not Committed Yet',
+  'author-time 1791200000',
+  'summary Version of src/refunds.ts from src/refunds.ts',
+  '\t// edited'
+].join('\n');
+
+function git(cwd: string, ...args: string[]): string {
+  return execFileSync(
+    'git',
+    ['-c', 'user.name=Ada Lovelace', '-c', 'user.email=ada@example.com', ...args],
+    { cwd, encoding: 'utf8' }
+  ).trim();
+}
+
+describe('blame context', () => {
+  it('summarizes the newest commit and the authors of a range, without emails', () => {
+    const summary = parseBlamePorcelain(PORCELAIN, new Date('2026-10-11T12:00:00Z'));
+
+    expect(summary).toEqual({
+      lastAuthor: 'Grace Hopper',
+      lastChanged: '2026-10-04',
+      ago: '7 days ago',
+      commit: 'bbbbbbbb',
+      subject: 'Cap refunds at the order total',
+      authors: ['Ada Lovelace', 'Grace Hopper'],
+      uncommittedLines: 1
+    });
+    expect(JSON.stringify(summary)).not.toContain('@example.com');
+    expect(parseBlamePorcelain(`${'0'.repeat(40)} 1 1 1\n\tnew file`)).toBeNull();
+  });
+
+  it('describes ages coarsely', () => {
+    const now = new Date('2026-10-14T12:00:00Z');
+    expect(describeAge(new Date('2026-10-14T08:00:00Z'), now)).toBe('today');
+    expect(describeAge(new Date('2026-10-13T08:00:00Z'), now)).toBe('1 day ago');
+    expect(describeAge(new Date('2026-09-20T08:00:00Z'), now)).toBe('3 weeks ago');
+    expect(describeAge(new Date('2026-03-01T08:00:00Z'), now)).toBe('7 months ago');
+    expect(describeAge(new Date('2021-10-01T08:00:00Z'), now)).toBe('5 years ago');
+  });
+
+  describe('search_codebase ownership', () => {
+    let tempRoot: string;
+    let ctx: ToolContext;
+
+    beforeEach(async () => {
+      tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'blame-context-'));
+      await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+      await fs.mkdir(path.join(tempRoot, '.github'), { recursive: true });
+      await fs.writeFile(path.join(tempRoot, '.github', 'CODEOWNERS'), 'src/ @acme/payments\n');
+      await fs.writeFile(
+        path.join(tempRoot, 'src', 'refunds.ts'),
+        'export function refundPayment(amount: number): number {\n' +
+          '  // refund payment up to the captured amount\n' +
+          '  return Math.max(0, amount);\n' +
+          '}\n'
+      );
+      git(tempRoot, 'init', '-q');
+      git(tempRoot, 'add', '-A');
+      git(tempRoot, 'commit', '-q', '-m', 'Add payment refunds');
+      await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+      const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+      ctx = {
+        indexState: { status: 'ready' },
+        paths: {
+          baseDir,
+          memory: path.join(baseDir, MEMORY_FILENAME),
+          intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+          keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+          vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+        },
+        rootPath: tempRoot,
+        performIndexing: () => {}
+      };
+    });
+
+    afterEach(async () => {
+      await rmWithRetries(tempRoot);
+    });
+
+    it('adds owners and the last change to results when asked', async () => {
+      const search = async (extra: Record<string, unknown>) =>
+        JSON.parse(
+          (
+            await dispatchTool(
+              'search_codebase',
+              { query: 'refund payment', mode: 'keyword', ...extra },
+              ctx
+            )
+          ).content![0].text
+        );
+
+      const plain = await search({});
+      expect(plain.results[0].ownership).toBeUndefined();
+
+      const owned = await search({ ownership: true });
+      expect(owned.results[0].ownership).toMatchObject({
+        owners: ['@acme/payments'],
+        lastChange: {
+          lastAuthor: 'Ada Lovelace',
+          ago: 'today',
+          subject: 'Add payment refunds',
+          authors: ['Ada Lovelace']
+        }
+      });
+    });
+  });
+});
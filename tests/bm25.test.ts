@@ -0,0 +1,55 @@
+import { describe, it, expect } from 'vitest';
+import { BM25Index, tokenizeForBM25 } from '../src/core/bm25.js';
+
+describe('BM25', () => {
+  describe('tokenizeForBM25', () => {
+    it('keeps identifiers whole and splits camelCase parts', () => {
+      const tokens = tokenizeForBM25('func NewCalculator() *Calculator');
+      expect(tokens).toContain('newcalculator');
+      expect(tokens).toContain('new');
+      expect(tokens).toContain('calculator');
+      expect(tokens).toContain('func');
+    });
+
+    it('splits snake_case and acronyms', () => {
+      expect(tokenizeForBM25('parse_HTTPResponse')).toEqual(
+        expect.arrayContaining(['parse_httpresponse', 'parse', 'http', 'response'])
+      );
+    });
+
+    it('drops single-character tokens', () => {
+      expect(tokenizeForBM25('a b cd')).toEqual(['cd']);
+    });
+  });
+
+  describe('BM25Index', () => {
+    const docs = [
+      { id: 'calc', text: 'func NewCalculator() *Calculator { return &Calculator{} }' },
+      { id: 'err', text: 'return errors.New("connection refused by upstream")' },
+      { id: 'misc', text: 'func helper() { log.Println("calculator ready") }' }
+    ];
+
+    it('ranks exact identifier matches first', () => {
+      const index = new BM25Index(docs);
+      const hits = index.search('NewCalculator', 5);
+      expect(hits[0].id).toBe('calc');
+    });
+
+    it('matches error strings', () => {
+      const index = new BM25Index(docs);
+      const hits = index.search('connection refused', 5);
+      expect(hits.map((h) => h.id)).toEqual(['err']);
+    });
+
+    it('returns nothing when no terms overlap', () => {
+      const index = new BM25Index(docs);
+      expect(index.search('kubernetes', 5)).toEqual([]);
+      expect(new BM25Index([]).search('anything', 5)).toEqual([]);
+    });
+
+    it('respects the limit', () => {
+      const index = new BM25Index(docs);
+      expect(index.search('calculator', 1)).toHaveLength(1);
+    });
+  });
+});
@@ -0,0 +1,101 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { execFileSync } from 'child_process';
+import { promises as fs } from 'fs';
+import path from 'path';
+import os from 'os';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { readIndexMeta } from '../src/core/index-meta.js';
+import { detectBranchSwitch, isBranchSwitch } from '../src/core/branch-switch.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME
+} from '../src/constants/codebase-context.js';
+import type { CodeChunk } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+function git(cwd: string, ...args: string[]): string {
+  return execFileSync(
+    'git',
+    ['-c', 'user.name=test', '-c', 'user.email=test@example.com', ...args],
+    { cwd, encoding: 'utf8' }
+  ).trim();
+}
+
+describe('isBranchSwitch', () => {
+  it('ignores new commits on the same branch but not moves between detached commits', () => {
+    expect(isBranchSwitch({ commit: 'a', branch: 'main' }, { commit: 'b', branch: 'main' })).toBe(
+      false
+    );
+    expect(isBranchSwitch({ commit: 'a', branch: 'main' }, { commit: 'a', branch: 'dev' })).toBe(
+      true
+    );
+    expect(isBranchSwitch({ commit: 'a', branch: null }, { commit: 'b', branch: null })).toBe(true);
+  });
+});
+
+describe('branch switches', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'branch-switch-test-'));
+    git(tempDir, 'init', '-q');
+    git(tempDir, 'checkout', '-q', '-b', 'main');
+    await fs.writeFile(path.join(tempDir, '.gitignore'), `${CODEBASE_CONTEXT_DIRNAME}/\n`);
+    await fs.writeFile(path.join(tempDir, 'service.ts'), 'export function mainOnly() {}\n');
+    await fs.writeFile(path.join(tempDir, 'shared.ts'), 'export const shared = 1;\n');
+    git(tempDir, 'add', '-A');
+    git(tempDir, 'commit', '-q', '-m', 'initial');
+
+    git(tempDir, 'checkout', '-q', '-b', 'feature');
+    await fs.writeFile(path.join(tempDir, 'service.ts'), 'export function featureOnly() {}\n');
+    await fs.writeFile(path.join(tempDir, 'extra.ts'), 'export const added = true;\n');
+    git(tempDir, 'add', '-A');
+    git(tempDir, 'commit', '-q', '-m', 'feature work');
+    git(tempDir, 'checkout', '-q', 'main');
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('re-indexes only the files that differ after checking out another branch', async () => {
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+    const indexed = (await readIndexMeta(tempDir)).head;
+    expect(indexed?.branch).toBe('main');
+
+    git(tempDir, 'checkout', '-q', 'feature');
+    const branchSwitch = await detectBranchSwitch(tempDir, indexed);
+    expect(branchSwitch?.to.branch).toBe('feature');
+    expect(branchSwitch?.changedPaths?.sort()).toEqual([
+      path.join(tempDir, 'extra.ts'),
+      path.join(tempDir, 'service.ts')
+    ]);
+
+    const stats = await new CodebaseIndexer({
+      rootPath: tempDir,
+      incrementalOnly: true,
+      changedPaths: branchSwitch?.changedPaths,
+      config: { skipEmbedding: true }
+    }).index();
+    expect(stats.incremental).toMatchObject({ added: 1, changed: 1, deleted: 0 });
+
+    const raw = await fs.readFile(
+      path.join(tempDir, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+      'utf-8'
+    );
+    const content = (JSON.parse(raw) as { chunks: CodeChunk[] }).chunks
+      .map((c) => c.content)
+      .join('\n');
+    expect(content).toContain('featureOnly');
+    expect(content).not.toContain('mainOnly');
+    expect((await readIndexMeta(tempDir)).head?.branch).toBe('feature');
+    expect(await detectBranchSwitch(tempDir, (await readIndexMeta(tempDir)).head)).toBeNull();
+  });
+
+  it('reports no changed files for a new branch at the same commit', async () => {
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+    git(tempDir, 'checkout', '-q', '-b', 'copy-of-main');
+    const branchSwitch = await detectBranchSwitch(tempDir, (await readIndexMeta(tempDir)).head);
+    expect(branchSwitch?.changedPaths).toEqual([]);
+  });
+});
@@ -0,0 +1,282 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { CodebaseSearcher } from '../src/core/search.js';
+import {
+  getBuildDependents,
+  loadBuildGraph,
+  resolveBuildTarget,
+  type BuildGraphData
+} from '../src/core/build-graph.js';
+import {
+  detectBuildGraph,
+  normalizeLabel,
+  parseBuckConfig,
+  parseBuildFile
+} from '../src/utils/build-files.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+async function writeFiles(root: string, files: Record<string, string>): Promise<void> {
+  for (const [file, content] of Object.entries(files)) {
+    await fs.mkdir(path.dirname(path.join(root, file)), { recursive: true });
+    await fs.writeFile(path.join(root, file), content);
+  }
+}
+
+describe('BUILD file parsing', () => {
+  it('reads targets through variables, concatenation, select and glob', () => {
+    const targets = parseBuildFile(
+      [
+        'load("@rules_go//go:def.bzl", "go_library")',
+        'COMMON = ["//libs/log", ":util"]',
+        '',
+        'def local_macro(name):',
+        '    native.cc_library(name = name + "_impl")',
+        '',
+        'go_library(',
+        '    name = "server",',
+        '    srcs = glob(["*.go"], exclude = ["*_test.go"]) + ["gen.go"],',
+        '    deps = COMMON + select({',
+        '        "//conditions:default": ["@com_github_x//:x"],',
+        '        ":linux": ["//libs/linux"],',
+        '    }),',
+        ')',
+        "py_binary(name = 'tool', srcs = ['''tool.py'''], deps = [d for d in COMMON])",
+        'filegroup(name = "docs", srcs = ["README." + "md"])',
+        'cc_library(name = "%s_gen" % "x")',
+        ''
+      ].join('\n')
+    );
+
+    expect(targets.map((target) => `${target.kind} ${target.name}`)).toEqual([
+      'go_library server',
+      'py_binary tool',
+      'filegroup docs'
+    ]);
+    const [server, tool, docs] = targets;
+    expect(server.attrs.get('srcs')).toEqual([
+      { type: 'glob', include: ['*.go'], exclude: ['*_test.go'] },
+      { type: 'str', value: 'gen.go' }
+    ]);
+    expect(server.attrs.get('deps')?.map((item) => item.type === 'str' && item.value)).toEqual([
+      '//libs/log',
+      ':util',
+      '@com_github_x//:x',
+      '//libs/linux'
+    ]);
+    expect(tool.attrs.get('deps')).toEqual([]);
+    expect(docs.attrs.get('srcs')).toEqual([{ type: 'str', value: 'README.md' }]);
+  });
+
+  it('normalizes Bazel and Buck labels', () => {
+    const cells = { root: '.', shared: 'third_party/shared' };
+    expect(normalizeLabel(':lib', 'services/api')).toBe('//services/api:lib');
+    expect(normalizeLabel('lib', 'services/api')).toBe('//services/api:lib');
+    expect(normalizeLabel('//libs/log', '')).toBe('//libs/log:log');
+    expect(normalizeLabel('@//libs/log:log', '')).toBe('//libs/log:log');
+    expect(normalizeLabel('@maven//:guava', 'x')).toBe('@maven//:guava');
+    expect(normalizeLabel('root//libs/log:log', '', cells)).toBe('//libs/log:log');
+    expect(normalizeLabel('shared//json', '', cells)).toBe('//third_party/shared/json:json');
+    expect(normalizeLabel('toolchains//:python', '', cells)).toBe('toolchains//:python');
+
+    const buckconfig = '[cells]\n  root = .\n  prelude = prelude/\n[buildfile]\n  name = TARGETS\n';
+    expect(parseBuckConfig(buckconfig)).toEqual({
+      cells: { root: '.', prelude: 'prelude' },
+      buildFile: 'TARGETS'
+    });
+  });
+});
+
+describe('build graph', () => {
+  const graph: BuildGraphData = {
+    system: 'bazel',
+    targets: {
+      '//libs/log:log': { kind: 'go_library', files: ['libs/log/log.go'] },
+      '//libs/log:log_test': { kind: 'go_test', deps: ['//libs/log:log'] },
+      '//services/api:server': {
+        kind: 'go_library',
+        files: ['services/api/server.go'],
+        deps: ['//libs/log:log', '@com_github_x//:x']
+      },
+      '//services/api:server_test': { kind: 'go_test', deps: ['//services/api:server'] },
+      '//services/api:bin': { kind: 'go_binary', deps: ['//services/api:server'] },
+      '//services/web:log': { kind: 'sh_binary', deps: ['//services/api:bin'] }
+    }
+  };
+
+  it('resolves labels, patterns, files and unique names', () => {
+    expect(resolveBuildTarget(graph, '//libs/log').labels).toEqual(['//libs/log:log']);
+    expect(resolveBuildTarget(graph, '//services/...').labels).toHaveLength(4);
+    expect(resolveBuildTarget(graph, '//services/api:all').labels).toHaveLength(3);
+    expect(resolveBuildTarget(graph, 'services/api/server.go').labels).toEqual([
+      '//services/api:server'
+    ]);
+    expect(resolveBuildTarget(graph, 'bin').labels).toEqual(['//services/api:bin']);
+    expect(resolveBuildTarget(graph, '@com_github_x//:x').labels).toEqual(['@com_github_x//:x']);
+    expect(resolveBuildTarget(graph, 'log')).toEqual({
+      labels: [],
+      candidates: ['//libs/log:log', '//services/web:log']
+    });
+    expect(resolveBuildTarget(graph, '//nowhere:thing').labels).toEqual([]);
+  });
+
+  it('walks reverse dependencies nearest first', () => {
+    const direct = getBuildDependents(graph, ['//libs/log:log']);
+    expect(direct.map((dependent) => dependent.label)).toEqual([
+      '//libs/log:log_test',
+      '//services/api:server'
+    ]);
+
+    const all = getBuildDependents(graph, ['//libs/log:log'], { depth: 10 });
+    expect(all.map((dependent) => [dependent.label, dependent.depth, dependent.via])).toEqual([
+      ['//libs/log:log_test', 1, '//libs/log:log'],
+      ['//services/api:server', 1, '//libs/log:log'],
+      ['//services/api:bin', 2, '//services/api:server'],
+      ['//services/api:server_test', 2, '//services/api:server'],
+      ['//services/web:log', 3, '//services/api:bin']
+    ]);
+
+    const tests = getBuildDependents(graph, ['//libs/log:log'], { depth: 3, testsOnly: true });
+    expect(tests.map((dependent) => dependent.label)).toEqual([
+      '//libs/log:log_test',
+      '//services/api:server_test'
+    ]);
+  });
+});
+
+describe('build graph in the index', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'build-graph-'));
+    await writeFiles(tempRoot, {
+      'MODULE.bazel': 'module(name = "shop")\n',
+      'libs/money/BUILD.bazel': [
+        'go_library(name = "money", srcs = glob(["*.go"], exclude = ["*_test.go"]))',
+        'go_test(name = "money_test", srcs = ["money_test.go"], embed = [":money"])',
+        ''
+      ].join('\n'),
+      'libs/money/money.go': 'package money\n\nfunc Round(cents int) int {\n\treturn cents\n}\n',
+      'libs/money/money_test.go': 'package money\n\nfunc TestRound(t *testing.T) {}\n',
+      'libs/money/fx/BUILD': 'go_library(name = "fx", srcs = glob(["**/*.go"]))\n',
+      'libs/money/fx/rates.go': 'package fx\n\nfunc Rate(cents int) int {\n\treturn cents\n}\n',
+      'services/checkout/BUILD.bazel': [
+        'go_library(',
+        '    name = "checkout",',
+        '    srcs = ["checkout.go"],',
+        '    deps = ["//libs/money", "@com_github_google_uuid//:uuid"],',
+        ')',
+        'go_binary(name = "server", embed = [":checkout"])',
+        ''
+      ].join('\n'),
+      'services/checkout/checkout.go':
+        'package checkout\n\nfunc Total(cents int) int {\n\treturn money.Round(cents)\n}\n'
+    });
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('stores targets with globs resolved per package', async () => {
+    const graph = await loadBuildGraph(tempRoot);
+    expect(graph?.system).toBe('bazel');
+    // fx is its own package, so the money glob stops at its BUILD file
+    expect(graph?.targets['//libs/money:money'].files).toEqual(['libs/money/money.go']);
+    expect(graph?.targets['//libs/money/fx:fx'].files).toEqual(['libs/money/fx/rates.go']);
+    expect(graph?.targets['//services/checkout:checkout'].deps).toEqual([
+      '//libs/money:money',
+      '@com_github_google_uuid//:uuid'
+    ]);
+  });
+
+  it('tags chunks with their targets for metadata filters', async () => {
+    const results = await new CodebaseSearcher(tempRoot).search(
+      'cents',
+      10,
+      { metadata: { buildTargets: '//libs/money:money' } },
+      { useSemanticSearch: false }
+    );
+    expect(results.length).toBeGreaterThan(0);
+    expect(results.every((result) => result.filePath.endsWith('libs/money/money.go'))).toBe(true);
+  });
+
+  it('lists the targets a change to a file rebuilds', async () => {
+    const result = await dispatchTool(
+      'get_build_dependents',
+      { target: 'libs/money/money.go', depth: 5 },
+      ctx
+    );
+    const payload = JSON.parse(result.content![0].text);
+
+    expect(payload.status).toBe('success');
+    expect(payload.resolved).toEqual(['//libs/money:money']);
+    expect(payload.tests).toBe(1);
+    expect(
+      payload.dependents.map((dependent: { label: string; depth: number }) => [
+        dependent.label,
+        dependent.depth
+      ])
+    ).toEqual([
+      ['//libs/money:money_test', 1],
+      ['//services/checkout:checkout', 1],
+      ['//services/checkout:server', 2]
+    ]);
+  });
+
+  it('describes a target', async () => {
+    const result = await dispatchTool('get_build_target', { target: '//services/checkout' }, ctx);
+    const payload = JSON.parse(result.content![0].text);
+
+    expect(payload.targets).toEqual([
+      expect.objectContaining({
+        label: '//services/checkout:checkout',
+        kind: 'go_library',
+        files: ['services/checkout/checkout.go'],
+        deps: ['//libs/money:money'],
+        externalDeps: ['@com_github_google_uuid//:uuid'],
+        directDependents: 1
+      })
+    ]);
+  });
+
+  it('reports projects without a build graph', async () => {
+    // No MODULE.bazel / WORKSPACE / .buckconfig at the root, no graph
+    const libs = path.join(tempRoot, 'libs');
+    expect(await detectBuildGraph(libs, ['money/money.go'])).toBeNull();
+
+    const result = await dispatchTool(
+      'get_build_dependents',
+      { target: '//libs/money' },
+      { ...ctx, rootPath: libs }
+    );
+    expect(JSON.parse(result.content![0].text).status).toBe('error');
+  });
+});
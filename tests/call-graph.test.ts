@@ -0,0 +1,132 @@
+import { describe, expect, it } from 'vitest';
+import { CallGraphBuilder, findCallees, findCallers } from '../src/core/call-graph';
+import { extractTreeSitterCalls } from '../src/utils/tree-sitter';
+
+const ROOT = '/repo';
+
+async function buildGraph(files: Record<string, { language: string; source: string }>) {
+  const builder = new CallGraphBuilder(ROOT);
+  for (const [relPath, { language, source }] of Object.entries(files)) {
+    const extraction = await extractTreeSitterCalls(source, language);
+    expect(extraction).not.toBeNull();
+    builder.trackFile(`${ROOT}/${relPath}`, extraction!);
+  }
+  return builder.toJSON();
+}
+
+describe('Tree-sitter call extraction', () => {
+  it('attributes TypeScript calls to the innermost enclosing function or method', async () => {
+    const source = [
+      'export class UserService {',
+      '  save(user: User) {',
+      '    validate(user);',
+      '    this.repo.persist(user);',
+      '  }',
+      '}',
+      '',
+      'export function validate(user: User) {',
+      '  return check(user.id);',
+      '}',
+      '',
+      'main();'
+    ].join('\n');
+
+    const extraction = await extractTreeSitterCalls(source, 'typescript');
+
+    expect(extraction).not.toBeNull();
+    const calls = extraction!.calls.map((c) => [c.caller?.name ?? null, c.callee, c.line]);
+    expect(calls).toEqual([
+      ['save', 'validate', 3],
+      ['save', 'persist', 4],
+      ['validate', 'check', 9],
+      [null, 'main', 12]
+    ]);
+  });
+
+  it('extracts Python calls including attribute calls', async () => {
+    const source = [
+      'def handler(event):',
+      '    payload = parse(event)',
+      '    return client.send(payload)'
+    ].join('\n');
+
+    const extraction = await extractTreeSitterCalls(source, 'python');
+
+    expect(extraction!.calls.map((c) => c.callee)).toEqual(['parse', 'send']);
+    expect(extraction!.calls.every((c) => c.caller?.name === 'handler')).toBe(true);
+  });
+
+  it('returns null for languages without a grammar', async () => {
+    expect(await extractTreeSitterCalls('foo()', 'plaintext')).toBeNull();
+  });
+});
+
+describe('find callers / callees', () => {
+  const files = {
+    'src/service.ts': {
+      language: 'typescript',
+      source: [
+        'export function saveUser(user: User) {',
+        '  validate(user);',
+        '  console.log(user);',
+        '  validate(user);',
+        '}'
+      ].join('\n')
+    },
+    'src/validate.ts': {
+      language: 'typescript',
+      source: ['export function validate(user: User) {', '  return user.id.length > 0;', '}'].join(
+        '\n'
+      )
+    },
+    'src/api.ts': {
+      language: 'typescript',
+      source: [
+        'export function createHandler() {',
+        '  return (req: Request) => saveUser(req.body);',
+        '}'
+      ].join('\n')
+    }
+  };
+
+  it('groups call sites per caller with caller line ranges', async () => {
+    const graph = await buildGraph(files);
+
+    const result = findCallers(graph, 'validate', 10);
+
+    expect(result.definedAt).toEqual(['src/validate.ts:1-3']);
+    expect(result.total).toBe(1);
+    expect(result.results).toEqual([
+      { symbol: 'saveUser', file: 'src/service.ts:1-5', callLines: [2, 4] }
+    ]);
+  });
+
+  it('accepts qualified symbol names', async () => {
+    const graph = await buildGraph(files);
+
+    expect(findCallers(graph, 'UserService.saveUser', 10).results[0]?.symbol).toBe(
+      'createHandler'
+    );
+  });
+
+  it('lists project callees with definitions and hides external calls by default', async () => {
+    const graph = await buildGraph(files);
+
+    const result = findCallees(graph, 'saveUser', 10);
+    expect(result.results).toEqual([
+      { symbol: 'validate', callLines: [2, 4], definedAt: ['src/validate.ts:1-3'] }
+    ]);
+
+    const withExternal = findCallees(graph, 'saveUser', 10, { includeExternal: true });
+    expect(withExternal.results.map((r) => r.symbol)).toEqual(['validate', 'log']);
+    expect(withExternal.results[1].definedAt).toBeUndefined();
+  });
+
+  it('respects the limit while reporting the full total', async () => {
+    const graph = await buildGraph(files);
+
+    const result = findCallees(graph, 'saveUser', 1, { includeExternal: true });
+    expect(result.total).toBe(2);
+    expect(result.results).toHaveLength(1);
+  });
+});
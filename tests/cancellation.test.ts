@@ -0,0 +1,142 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import {
+  partialMarker,
+  resolveToolTimeoutMs,
+  withTimeBudget,
+  DEFAULT_TOOL_TIMEOUT_MS
+} from '../src/core/cancellation.js';
+import { readIndexCheckpoint } from '../src/core/index-checkpoint.js';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import { IndexingCancelledError } from '../src/errors/index.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+describe('resolveToolTimeoutMs', () => {
+  it('reads one budget or per-tool budgets, leaving indexing tools unbounded', () => {
+    expect(resolveToolTimeoutMs('search_codebase', {})).toBe(DEFAULT_TOOL_TIMEOUT_MS);
+    expect(resolveToolTimeoutMs('refresh_index', {})).toBe(0);
+
+    const env = { CODEBASE_CONTEXT_TOOL_TIMEOUT_MS: 'search_codebase=5000, *=20000' };
+    expect(resolveToolTimeoutMs('search_codebase', env)).toBe(5000);
+    expect(resolveToolTimeoutMs('find_references', env)).toBe(20000);
+    expect(resolveToolTimeoutMs('refresh_index', env)).toBe(0);
+    expect(resolveToolTimeoutMs('get_memory', { CODEBASE_CONTEXT_TOOL_TIMEOUT_MS: '0' })).toBe(0);
+  });
+});
+
+describe('withTimeBudget', () => {
+  const waitForAbort = (signal: AbortSignal) =>
+    new Promise<void>((resolve) => signal.addEventListener('abort', () => resolve()));
+
+  it('lets a task that honours its signal return a partial result', async () => {
+    const result = await withTimeBudget(
+      async (signal) => {
+        await waitForAbort(signal);
+        return { found: 3, ...partialMarker(signal) };
+      },
+      { timeoutMs: 20, onStopped: () => ({ found: 0 }) }
+    );
+    expect(result).toEqual({ found: 3, partial: true, stoppedBy: 'timeout' });
+  });
+
+  it('answers for a task that ignores its signal after the grace period', async () => {
+    const result = await withTimeBudget(() => new Promise<string>(() => undefined), {
+      timeoutMs: 10,
+      graceMs: 10,
+      onStopped: (reason) => `stopped: ${reason}`
+    });
+    expect(result).toBe('stopped: timeout');
+  });
+
+  it('stops when the client cancels', async () => {
+    const client = new AbortController();
+    const running = withTimeBudget(() => new Promise<string>(() => undefined), {
+      timeoutMs: 0,
+      signal: client.signal,
+      graceMs: 10,
+      onStopped: (reason) => `stopped: ${reason}`
+    });
+    client.abort();
+    await expect(running).resolves.toBe('stopped: cancelled');
+  });
+});
+
+describe('cancelled work', () => {
+  let tempDir: string;
+  let contextDir: string;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'cancellation-test-'));
+    contextDir = path.join(tempDir, CODEBASE_CONTEXT_DIRNAME);
+    await fs.mkdir(path.join(tempDir, 'src'), { recursive: true });
+    for (const name of ['a', 'b', 'c']) {
+      await fs.writeFile(
+        path.join(tempDir, 'src', `${name}.ts`),
+        `export function ${name}() {\n  return 'shared';\n}\nexport const use${name} = ${name}();\n`
+      );
+    }
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('leaves a checkpoint when a build is cancelled and clears it on success', async () => {
+    const controller = new AbortController();
+    const cancelled = new CodebaseIndexer({
+      rootPath: tempDir,
+      config: { skipEmbedding: true },
+      signal: controller.signal,
+      onProgress: (progress) => {
+        if (progress.phase === 'analyzing') controller.abort();
+      }
+    });
+    await expect(cancelled.index()).rejects.toBeInstanceOf(IndexingCancelledError);
+    expect(await readIndexCheckpoint(contextDir)).toMatchObject({
+      mode: 'full',
+      phase: 'analyzing',
+      totalFiles: 3
+    });
+
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+    expect(await readIndexCheckpoint(contextDir)).toBeNull();
+  });
+
+  it('returns what a reference scan found before the call was cancelled', async () => {
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+    const controller = new AbortController();
+    controller.abort();
+    const ctx: ToolContext = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir: contextDir,
+        memory: path.join(contextDir, MEMORY_FILENAME),
+        intelligence: path.join(contextDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(contextDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(contextDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempDir,
+      performIndexing: () => {},
+      signal: controller.signal
+    };
+
+    const response = await dispatchTool('get_symbol_references', { symbol: 'a' }, ctx);
+    expect(JSON.parse(response.content![0].text)).toMatchObject({
+      status: 'success',
+      isComplete: false,
+      partial: true,
+      stoppedBy: 'cancelled'
+    });
+  });
+});
@@ -0,0 +1,163 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer, rollbackToPreviousIndex } from '../src/core/indexer.js';
+import { changesSince, type ChangeJournal } from '../src/core/change-journal.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const ORDERS = `export function orderTotal(items: number[]): number {
+  return items.reduce((sum, item) => sum + item, 0);
+}
+
+export function orderLabel(id: string): string {
+  return 'Order ' + id;
+}
+`;
+
+function entry(cursor: number, files: Partial<ChangeJournal['entries'][number]['files']>) {
+  return {
+    cursor,
+    buildId: `build-${cursor}`,
+    generatedAt: new Date(2026, 0, cursor).toISOString(),
+    files: { added: [], modified: [], removed: [], ...files },
+    symbols: { added: [], modified: [], removed: [] }
+  };
+}
+
+describe('changesSince', () => {
+  it('nets out changes across builds', () => {
+    const journal: ChangeJournal = {
+      version: 1,
+      cursor: 4,
+      baseline: 1,
+      baselineAt: new Date(2026, 0, 1).toISOString(),
+      entries: [
+        entry(2, { added: ['src/tmp.ts', 'src/new.ts'], removed: ['src/old.ts'] }),
+        entry(3, { removed: ['src/tmp.ts'], modified: ['src/new.ts'], added: ['src/old.ts'] }),
+        entry(4, { modified: ['src/app.ts'] })
+      ]
+    };
+
+    const all = changesSince(journal, 1);
+    expect(all).toMatchObject({
+      status: 'success',
+      builds: 3,
+      files: { added: ['src/new.ts'], modified: ['src/app.ts', 'src/old.ts'], removed: [] }
+    });
+    expect(changesSince(journal, 3)).toMatchObject({ files: { modified: ['src/app.ts'] } });
+    expect(changesSince(journal, 0)).toEqual({ status: 'expired', cursor: 4, baseline: 1 });
+    expect(changesSince(journal, 9)).toEqual({ status: 'invalid', cursor: 4 });
+  });
+});
+
+describe('changes_since', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  const write = async (relativePath: string, content: string) => {
+    await fs.mkdir(path.dirname(path.join(tempRoot, relativePath)), { recursive: true });
+    await fs.writeFile(path.join(tempRoot, relativePath), content);
+  };
+  const reindex = () =>
+    new CodebaseIndexer({
+      rootPath: tempRoot,
+      config: { skipEmbedding: true },
+      incrementalOnly: true
+    }).index();
+  const changes = async (args: Record<string, unknown> = {}) =>
+    JSON.parse((await dispatchTool('changes_since', args, ctx)).content![0].text);
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'changes-since-test-'));
+    await write('src/orders.ts', ORDERS);
+    await write('src/legacy.ts', 'export function legacyTotal(): number {\n  return 0;\n}\n');
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const contextDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir: contextDir,
+        memory: path.join(contextDir, MEMORY_FILENAME),
+        intelligence: path.join(contextDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(contextDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(contextDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('lists files and symbols changed since a cursor', async () => {
+    const { cursor } = await changes();
+    expect(cursor).toBe('1');
+
+    await write(
+      'src/orders.ts',
+      ORDERS.replace("'Order ' + id", '`Order #${id}`') +
+        '\nexport function orderCount(items: number[]): number {\n  return items.length;\n}\n'
+    );
+    await fs.rm(path.join(tempRoot, 'src', 'legacy.ts'));
+    await write('src/cart.ts', 'export function cartSize(): number {\n  return 1;\n}\n');
+    await reindex();
+
+    const payload = await changes({ cursor });
+    expect(payload).toMatchObject({
+      status: 'success',
+      cursor: '2',
+      from: '1',
+      builds: 1,
+      addedFiles: ['src/cart.ts'],
+      modifiedFiles: ['src/orders.ts'],
+      removedFiles: ['src/legacy.ts']
+    });
+    const names = (list: Array<{ name: string }> = []) => list.map((symbol) => symbol.name);
+    expect(names(payload.symbols.added)).toEqual(
+      expect.arrayContaining(['orderCount', 'cartSize'])
+    );
+    expect(names(payload.symbols.modified)).toContain('orderLabel');
+    expect(names(payload.symbols.modified)).not.toContain('orderTotal');
+    expect(names(payload.symbols.removed)).toContain('legacyTotal');
+    expect(payload.symbols.added).toContainEqual({
+      name: 'orderCount',
+      kind: 'function',
+      file: 'src/orders.ts',
+      lines: expect.stringMatching(/^\d+-\d+$/)
+    });
+
+    expect(await changes({ cursor: '2' })).toMatchObject({
+      builds: 0,
+      message: 'No indexed changes since this cursor.'
+    });
+    expect(await changes({ cursor, path: 'src/cart.ts' })).toMatchObject({
+      addedFiles: ['src/cart.ts']
+    });
+  });
+
+  it('expires cursors after a rollback', async () => {
+    await write('src/cart.ts', 'export function cartSize(): number {\n  return 1;\n}\n');
+    await reindex();
+    await rollbackToPreviousIndex(ctx.paths.baseDir);
+
+    expect(await changes({ cursor: '1' })).toMatchObject({
+      status: 'cursor_expired',
+      cursor: '3'
+    });
+    const response = await dispatchTool('changes_since', { cursor: 'abc' }, ctx);
+    expect(response.isError).toBe(true);
+  });
+});
@@ -0,0 +1,208 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import {
+  createChunkPostProcessorChain,
+  describePostProcessingDrift,
+  registerChunkPostProcessor
+} from '../src/core/chunk-post-processors.js';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { readIndexMeta } from '../src/core/index-meta.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const embedded = vi.hoisted(() => ({ texts: [] as string[] }));
+
+vi.mock('../src/embeddings/index.js', async (importOriginal) => {
+  const original = await importOriginal<typeof import('../src/embeddings/index.js')>();
+  const provider = {
+    name: 'fake',
+    modelName: 'fake-model',
+    dimensions: 4,
+    initialize: async () => {},
+    isReady: () => true,
+    embed: async (text: string) => [text.length, 1, 0, 0],
+    embedBatch: async (texts: string[]) => {
+      embedded.texts.push(...texts);
+      return texts.map((text) => [text.length, 1, 0, 0]);
+    }
+  };
+  return { ...original, getEmbeddingProvider: async () => provider };
+});
+
+const TS = { language: 'typescript' };
+
+describe('chunk post-processors', () => {
+  beforeEach(() => {
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+  });
+
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  it('strips license headers but keeps other leading comments', () => {
+    const chain = createChunkPostProcessorChain({ processors: ['strip-license-header'] });
+    const licensed = [
+      '/*',
+      ' * Copyright (c) 2024 Acme Corp.',
+      ' * Licensed under the MIT license.',
+      ' */',
+      '',
+      'export const total = 1;'
+    ].join('\n');
+    expect(chain.apply(licensed, TS)).toBe('export const total = 1;');
+
+    const documented = '/** Sum of invoice lines */\nexport const total = 1;';
+    expect(chain.apply(documented, TS)).toBe(documented);
+
+    const script = '#!/usr/bin/env python\n# SPDX-License-Identifier: Apache-2.0\nprint(1)';
+    expect(chain.apply(script, { language: 'python' })).toBe('#!/usr/bin/env python\nprint(1)');
+  });
+
+  it('folds comment banners and drops minified lines', () => {
+    const banner = [
+      '// ============================================',
+      '// Billing helpers',
+      '// --------------------------------------------',
+      ...Array.from({ length: 12 }, (_, i) => `// note ${i}`),
+      'export const rate = 0.2;'
+    ].join('\n');
+    const folded = createChunkPostProcessorChain({ processors: ['fold-comment-banners'] }).apply(
+      banner,
+      TS
+    );
+    expect(folded.split('\n')).toEqual([
+      '// Billing helpers',
+      ...Array.from({ length: 7 }, (_, i) => `// note ${i}`),
+      'export const rate = 0.2;'
+    ]);
+
+    const minified = `var a=1;${'b=a+1;'.repeat(100)}`;
+    const stripped = createChunkPostProcessorChain({ processors: ['strip-minified'] }).apply(
+      `export const x = 1;\n${minified}\nexport const y = 2;`,
+      TS
+    );
+    expect(stripped).toBe('export const x = 1;\nexport const y = 2;');
+  });
+
+  it('normalizes whitespace', () => {
+    const chain = createChunkPostProcessorChain({ processors: ['normalize-whitespace'] });
+    expect(chain.apply('    if (ok) {  \n\t\t  run();\n\n\n\n    }\n\n', TS)).toBe(
+      'if (ok) {\n  run();\n\n}'
+    );
+  });
+
+  it('applies rules in order with per-language overrides', () => {
+    const chain = createChunkPostProcessorChain({
+      processors: ['drop-todos', 'normalize-whitespace'],
+      languages: { Python: [], Go: ['redact-ids'] },
+      rules: [
+        { name: 'drop-todos', pattern: '^\\s*// TODO.*$', flags: 'm' },
+        { name: 'redact-ids', pattern: 'acct_[0-9]+', replace: 'acct_ID' }
+      ]
+    });
+    expect(chain.apply('  // TODO: cache\n  charge();', TS)).toBe('charge();');
+    expect(chain.apply('  # TODO: cache\n', { language: 'python' })).toBe('  # TODO: cache\n');
+    expect(chain.apply('id := "acct_42" // TODO', { language: 'go' })).toBe(
+      'id := "acct_ID" // TODO'
+    );
+  });
+
+  it('runs registered processors and skips ones that throw', () => {
+    registerChunkPostProcessor('upper-test', (content) => content.toUpperCase());
+    registerChunkPostProcessor('broken-test', () => {
+      throw new Error('boom');
+    });
+    expect(() => registerChunkPostProcessor('strip-minified', (content) => content)).toThrow(
+      /built-in/
+    );
+
+    const chain = createChunkPostProcessorChain({
+      processors: ['broken-test', 'upper-test', 'missing']
+    });
+    expect(chain.apply('charge()', TS)).toBe('CHARGE()');
+    expect(chain.apply('refund()', TS)).toBe('REFUND()');
+    const warnings = vi.mocked(console.error).mock.calls.map(([message]) => String(message));
+    expect(warnings.filter((message) => message.includes("'broken-test' failed"))).toHaveLength(1);
+    expect(warnings.some((message) => message.includes('unknown processor: missing'))).toBe(true);
+  });
+
+  it('signs only chains that do something', () => {
+    expect(createChunkPostProcessorChain().signature).toBeUndefined();
+    expect(createChunkPostProcessorChain({ processors: ['missing'] }).signature).toBeUndefined();
+
+    const a = createChunkPostProcessorChain({ processors: ['strip-minified'] }).signature;
+    expect(a).toMatch(/^[0-9a-f]{16}$/);
+    expect(createChunkPostProcessorChain({ processors: ['strip-minified'] }).signature).toBe(a);
+    expect(
+      createChunkPostProcessorChain({ processors: ['strip-minified', 'normalize-whitespace'] })
+        .signature
+    ).not.toBe(a);
+
+    const built = { provider: 'fake', model: 'fake-model', dimensions: 4 };
+    expect(describePostProcessingDrift(built, undefined)).toBeNull();
+    expect(describePostProcessingDrift(built, a)).toMatch(/rebuild required/);
+    expect(describePostProcessingDrift({ ...built, postProcessing: a }, a)).toBeNull();
+  });
+});
+
+describe('post-processed embedding inputs', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    embedded.texts = [];
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'post-processing-test-'));
+    await fs.mkdir(path.join(tempDir, 'src'), { recursive: true });
+    await fs.writeFile(
+      path.join(tempDir, 'src', 'refunds.ts'),
+      [
+        'export function refund(total: number) {',
+        "  return issueRefund('acct_42', total * 0.5);",
+        '}',
+        ''
+      ].join('\n')
+    );
+  });
+
+  afterEach(async () => {
+    vi.restoreAllMocks();
+    await rmWithRetries(tempDir);
+  });
+
+  it('embeds processed text, keeps stored content and records the chain', async () => {
+    const config = {
+      postProcessing: {
+        processors: ['redact-ids'],
+        rules: [{ name: 'redact-ids', pattern: 'acct_[0-9]+', replace: 'acct_ID' }]
+      }
+    };
+    await new CodebaseIndexer({ rootPath: tempDir, config }).index();
+    expect(embedded.texts.some((text) => text.includes("issueRefund('acct_ID'"))).toBe(true);
+    expect(embedded.texts.some((text) => text.includes('acct_42'))).toBe(false);
+
+    const keywordIndex = await fs.readFile(
+      path.join(tempDir, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+      'utf-8'
+    );
+    expect(keywordIndex).toContain('acct_42');
+
+    const meta = await readIndexMeta(tempDir);
+    const signature = createChunkPostProcessorChain(config.postProcessing).signature;
+    expect(meta.embedding?.postProcessing).toBe(signature);
+
+    // Same chain: every vector comes from the cache
+    embedded.texts = [];
+    await new CodebaseIndexer({ rootPath: tempDir, config }).index();
+    expect(embedded.texts).toEqual([]);
+
+    // No chain: the inputs change, so they are embedded again
+    await new CodebaseIndexer({ rootPath: tempDir }).index();
+    expect(embedded.texts.some((text) => text.includes("issueRefund('acct_42'"))).toBe(true);
+  });
+});
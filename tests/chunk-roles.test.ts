@@ -0,0 +1,138 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { AnalyzerRegistry } from '../src/core/analyzer-registry.js';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { CodebaseSearcher } from '../src/core/search.js';
+import { AngularAnalyzer } from '../src/analyzers/angular/index.js';
+import { GenericAnalyzer } from '../src/analyzers/generic/index.js';
+import { detectGenericRoles } from '../src/analyzers/generic/roles.js';
+import type { CodeChunk } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+function chunk(relativePath: string, content: string, extra: Partial<CodeChunk> = {}): CodeChunk {
+  return {
+    id: relativePath,
+    content,
+    filePath: `/repo/${relativePath}`,
+    relativePath,
+    startLine: 1,
+    endLine: content.split('\n').length,
+    language: 'typescript',
+    dependencies: [],
+    imports: [],
+    exports: [],
+    tags: [],
+    metadata: {},
+    ...extra
+  };
+}
+
+const roles = (relativePath: string, content: string) =>
+  detectGenericRoles(chunk(relativePath, content));
+
+describe('chunk roles', () => {
+  it('recognizes HTTP handlers but not HTTP clients', () => {
+    const cases: Array<[string, string, string[]]> = [
+      ['src/api.ts', "app.post('/orders', async (req, res) => res.json({}));", ['http_handler']],
+      ['internal/orders.go', 'func Create(c *gin.Context) {}', ['http_handler']],
+      ['app/api/orders/route.ts', 'export async function GET() {}', ['http_handler']],
+      ['src/orders.controller.ts', "@Controller('orders')\nclass Orders {}", ['http_handler']],
+      ['views.py', "@app.route('/orders')\ndef orders():\n    pass", ['http_handler']],
+      ['src/client.ts', "await api.get('/orders', { params });", []]
+    ];
+    for (const [file, content, expected] of cases) {
+      expect(roles(file, content), file).toEqual(expected);
+    }
+  });
+
+  it('recognizes components, migrations, cron jobs and tests', () => {
+    const cases: Array<[string, string, string[]]> = [
+      ['src/Badge.tsx', 'export function Badge() {\n  return <span />;\n}', ['ui_component']],
+      ['src/format.tsx', 'export function formatDate(d: Date) { return d; }', []],
+      ['db/migrations/20240101_orders.sql', 'CREATE TABLE orders (id int);', ['migration']],
+      ['src/schema.ts', 'export class AddOrders implements MigrationInterface {}', ['migration']],
+      ['src/jobs.ts', "cron.schedule('0 3 * * *', purge);", ['cron_job']],
+      ['src/orders.test.ts', "it('creates', () => {});", ['test']],
+      ['src/lib.rs', '#[cfg(test)]\nmod tests {}', ['test']],
+      ['src/util.ts', 'export const add = (a: number, b: number) => a + b;', []]
+    ];
+    for (const [file, content, expected] of cases) {
+      expect(roles(file, content), file).toEqual(expected);
+    }
+  });
+
+  it('merges the roles of every registered analyzer', () => {
+    const registry = new AnalyzerRegistry();
+    registry.register(new AngularAnalyzer());
+    registry.register(new GenericAnalyzer());
+
+    const component = chunk('src/app/card.component.ts', "@Component({ selector: 'app-card' })", {
+      framework: 'angular',
+      componentType: 'component'
+    });
+    expect(registry.detectRoles(component)).toEqual(['ui_component']);
+    expect(
+      registry.detectRoles(chunk('src/app/card.component.spec.ts', "it('renders', () => {});"))
+    ).toEqual(['test']);
+  });
+});
+
+describe('role filter', () => {
+  let tempRoot: string;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'chunk-roles-'));
+    const files: Record<string, string> = {
+      'src/orders/routes.ts': [
+        "import express from 'express';",
+        'const app = express();',
+        "app.post('/orders', (req, res) => {",
+        '  res.status(201).json({ order: createOrder(req.body) });',
+        '});',
+        'export function createOrder(body: unknown) {',
+        '  return body;',
+        '}',
+        ''
+      ].join('\n'),
+      'src/orders/OrderList.tsx': [
+        'export function OrderList({ orders }: { orders: string[] }) {',
+        '  return <ul>{orders.map((order) => <li key={order}>{order}</li>)}</ul>;',
+        '}',
+        ''
+      ].join('\n'),
+      'src/orders/orders.test.ts': [
+        "import { createOrder } from './routes.js';",
+        "it('creates an order', () => createOrder({}));",
+        ''
+      ].join('\n')
+    };
+    for (const [file, content] of Object.entries(files)) {
+      await fs.mkdir(path.dirname(path.join(tempRoot, file)), { recursive: true });
+      await fs.writeFile(path.join(tempRoot, file), content);
+    }
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('keeps only chunks with the requested role', async () => {
+    const search = (role: string) =>
+      new CodebaseSearcher(tempRoot).search('order', 10, { role }, { useSemanticSearch: false });
+
+    const handlers = await search('http_handler');
+    expect(handlers.length).toBeGreaterThan(0);
+    expect(handlers.every((result) => result.filePath.endsWith('routes.ts'))).toBe(true);
+
+    const components = await search('ui_component');
+    expect(components.length).toBeGreaterThan(0);
+    expect(components.every((result) => result.filePath.endsWith('OrderList.tsx'))).toBe(true);
+
+    const tests = await search('test');
+    expect(tests.every((result) => result.filePath.endsWith('orders.test.ts'))).toBe(true);
+    expect(await search('cron_job')).toEqual([]);
+  });
+});
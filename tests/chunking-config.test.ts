@@ -0,0 +1,85 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { GenericAnalyzer } from '../src/analyzers/generic/index.js';
+import {
+  loadProjectChunkingConfig,
+  resolveLanguageChunking,
+  sanitizeChunkingConfig
+} from '../src/core/chunking-config.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  PROJECT_CONFIG_FILENAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const longFunction = [
+  'export function reconcile(entries: number[]): number {',
+  '  let total = 0;',
+  ...Array.from({ length: 60 }, (_, i) => `  total += entries[${i}] ?? ${i};`),
+  '  return total;',
+  '}'
+].join('\n');
+
+describe('chunking config', () => {
+  it('keeps valid fields and lets languages override the defaults', () => {
+    const config = sanitizeChunkingConfig({
+      maxLines: 120,
+      overlapLines: 0,
+      maxTokens: -5,
+      mergeSmall: 'no',
+      languages: { Go: { maxLines: 60, mergeSmall: false }, python: { minLines: 2.5 } }
+    });
+    expect(config).toEqual({
+      maxLines: 120,
+      overlapLines: 0,
+      languages: { go: { maxLines: 60, mergeSmall: false }, python: {} }
+    });
+    expect(resolveLanguageChunking(config, 'go')).toEqual({
+      maxLines: 60,
+      overlapLines: 0,
+      mergeSmall: false
+    });
+    expect(resolveLanguageChunking(config, 'rust')).toEqual({ maxLines: 120, overlapLines: 0 });
+    expect(resolveLanguageChunking(undefined, 'go')).toEqual({});
+  });
+
+  describe('project file', () => {
+    let tempRoot: string;
+
+    beforeEach(async () => {
+      tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'chunking-config-'));
+    });
+
+    afterEach(async () => {
+      await rmWithRetries(tempRoot);
+    });
+
+    it('reads the chunking key of config.json', async () => {
+      expect(await loadProjectChunkingConfig(tempRoot)).toBeUndefined();
+      await fs.mkdir(path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME), { recursive: true });
+      await fs.writeFile(
+        path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME),
+        JSON.stringify({ chunking: { languages: { typescript: { maxLines: 20 } } } })
+      );
+      expect(await loadProjectChunkingConfig(tempRoot)).toEqual({
+        languages: { typescript: { maxLines: 20 } }
+      });
+    });
+  });
+
+  it('sizes AST chunks from the options the indexer passes', async () => {
+    const analyzer = new GenericAnalyzer();
+    const byDefault = await analyzer.analyze('/virtual/reconcile.ts', longFunction);
+    const capped = await analyzer.analyze('/virtual/reconcile.ts', longFunction, {
+      chunking: { maxLines: 20, overlapLines: 2 }
+    });
+
+    expect(byDefault.metadata.chunkStrategy).toBe('ast-aligned');
+    expect(capped.chunks.length).toBeGreaterThan(byDefault.chunks.length);
+    for (const chunk of capped.chunks) {
+      expect(chunk.endLine - chunk.startLine + 1).toBeLessThanOrEqual(20);
+    }
+  });
+});
@@ -81,6 +81,21 @@ describe('CLI', () => {
     });
   });
 
+  it('search forwards --mode and rejects unknown modes', async () => {
+    toolMocks.dispatchTool.mockResolvedValue({
+      content: [{ type: 'text', text: JSON.stringify({ ok: true }) }]
+    });
+
+    await handleCliCommand(['search', '--query', 'NewCalculator', '--mode', 'keyword', '--json']);
+    const [, toolArgs] = toolMocks.dispatchTool.mock.calls[0] ?? [];
+    expect(toolArgs).toEqual({ query: 'NewCalculator', includeSnippets: true, mode: 'keyword' });
+
+    await expect(
+      handleCliCommand(['search', '--query', 'foo', '--mode', 'fuzzy'])
+    ).rejects.toThrow(/process\.exit:1/);
+    expect(toolMocks.dispatchTool).toHaveBeenCalledTimes(1);
+  });
+
   it('patterns errors on invalid category', async () => {
     await expect(handleCliCommand(['patterns', '--category', 'nope'])).rejects.toThrow(/process\.exit:1/);
     expect(toolMocks.dispatchTool).not.toHaveBeenCalled();
@@ -195,5 +210,70 @@ describe('CLI', () => {
       await fs.rm(tempDir, { recursive: true, force: true });
     }
   });
-});
 
+  it('index, stats and purge manage the index without the MCP server', async () => {
+    const tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'cli-index-'));
+    process.env.CODEBASE_ROOT = tempDir;
+    const lastJson = <T>() => JSON.parse(String(logSpy.mock.calls.at(-1)?.[0] ?? '')) as T;
+
+    try {
+      await fs.mkdir(path.join(tempDir, 'src'), { recursive: true });
+      await fs.writeFile(
+        path.join(tempDir, 'src', 'cart.ts'),
+        'export function addItem(items: string[], item: string) {\n  return [...items, item];\n}\n'
+      );
+      await fs.writeFile(
+        path.join(tempDir, 'src', 'util.py'),
+        'def slugify(value):\n    return value\n'
+      );
+
+      await handleCliCommand(['index', '--keyword-only', '--json']);
+      expect(lastJson<Record<string, unknown>>()).toMatchObject({
+        status: 'ready',
+        mode: 'full',
+        keywordOnly: true,
+        indexedFiles: 2
+      });
+      await handleMemoryCli([
+        'add',
+        '--type',
+        'decision',
+        '--category',
+        'tooling',
+        '--memory',
+        'Keep carts immutable',
+        '--reason',
+        'Shared across tabs',
+        '--json'
+      ]);
+
+      await handleCliCommand(['stats', '--json']);
+      const stats = lastJson<{
+        indexed: boolean;
+        files: number;
+        languages: Array<{ language: string }>;
+        disk: { totalBytes: number };
+      }>();
+      expect(stats).toMatchObject({ indexed: true, files: 2 });
+      expect(stats.languages.map((l) => l.language).sort()).toEqual(['python', 'typescript']);
+      expect(stats.disk.totalBytes).toBeGreaterThan(0);
+
+      await handleCliCommand(['purge', '--dry-run', '--json']);
+      expect(lastJson<{ removed: string[] }>().removed).toContain('index.json');
+      await fs.access(path.join(tempDir, '.codebase-context', 'index.json'));
+
+      await handleCliCommand(['purge', '--json']);
+      expect(lastJson<{ kept: string[] }>().kept).toEqual(['memory.json']);
+      expect(await fs.readdir(path.join(tempDir, '.codebase-context'))).toEqual(['memory.json']);
+
+      await handleCliCommand(['stats', '--json']);
+      const empty = lastJson<{ indexed: boolean; problem?: string }>();
+      expect(empty.indexed).toBe(false);
+      expect(empty.problem).toBeUndefined();
+    } finally {
+      if (originalEnvRoot === undefined) delete process.env.CODEBASE_ROOT;
+      else process.env.CODEBASE_ROOT = originalEnvRoot;
+      await fs.rm(tempDir, { recursive: true, force: true });
+    }
+  });
+});
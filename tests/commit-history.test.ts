@@ -0,0 +1,163 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { execFileSync } from 'child_process';
+import { promises as fs } from 'fs';
+import path from 'path';
+import os from 'os';
+import { parseCommitPatch, searchHistory } from '../src/core/commit-history.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const embedded = vi.hoisted(() => ({ texts: [] as string[] }));
+
+vi.mock('../src/embeddings/index.js', async (importOriginal) => {
+  const original = await importOriginal<typeof import('../src/embeddings/index.js')>();
+  const vector = (text: string) => [/backoff|retry/i.test(text) ? 1 : 0, 1];
+  const provider = {
+    name: 'fake',
+    modelName: 'fake-model',
+    dimensions: 2,
+    initialize: async () => {},
+    isReady: () => true,
+    embed: async (text: string) => vector(text),
+    embedBatch: async (texts: string[]) => {
+      embedded.texts.push(...texts);
+      return texts.map(vector);
+    }
+  };
+  return { ...original, getEmbeddingProvider: async () => provider };
+});
+
+function git(cwd: string, ...args: string[]): string {
+  return execFileSync(
+    'git',
+    ['-c', 'user.name=test', '-c', 'user.email=test@example.com', ...args],
+    { cwd, encoding: 'utf8' }
+  ).trim();
+}
+
+async function commit(
+  cwd: string,
+  files: Record<string, string>,
+  message: string,
+  author: string
+): Promise<string> {
+  for (const [file, content] of Object.entries(files)) {
+    await fs.mkdir(path.dirname(path.join(cwd, file)), { recursive: true });
+    await fs.writeFile(path.join(cwd, file), content);
+  }
+  git(cwd, 'add', '-A');
+  git(cwd, 'commit', '-q', '-m', message, `--author=${author}`);
+  return git(cwd, 'rev-parse', 'HEAD');
+}
+
+describe('parseCommitPatch', () => {
+  it('splits hunks per file and skips lockfiles', () => {
+    const patch = [
+      'diff --git a/src/retry.ts b/src/retry.ts',
+      '--- a/src/retry.ts',
+      '+++ b/src/retry.ts',
+      '@@ -1,2 +1,2 @@ export function retry() {',
+      '-  const delay = 100;',
+      '+  const delay = 100 * 2 ** attempt;',
+      ' }',
+      'diff --git a/package-lock.json b/package-lock.json',
+      '@@ -1 +1 @@',
+      '-"a"',
+      '+"b"'
+    ].join('\n');
+
+    expect(parseCommitPatch(patch)).toEqual({
+      files: ['src/retry.ts', 'package-lock.json'],
+      hunks: [
+        {
+          file: 'src/retry.ts',
+          header: '@@ -1,2 +1,2 @@ export function retry() {',
+          diff: '-  const delay = 100;\n+  const delay = 100 * 2 ** attempt;\n }'
+        }
+      ]
+    });
+  });
+});
+
+describe('searchHistory', () => {
+  let tempDir: string;
+  let backoffSha: string;
+
+  beforeEach(async () => {
+    embedded.texts = [];
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'commit-history-test-'));
+    git(tempDir, 'init', '-q');
+    await commit(
+      tempDir,
+      { 'src/retry.ts': 'export function retry(fn) {\n  return fn();\n}\n' },
+      'Add retry helper',
+      'Ana <ana@example.com>'
+    );
+    backoffSha = await commit(
+      tempDir,
+      {
+        'src/retry.ts':
+          'export function retry(fn, attempt = 0) {\n' +
+          '  const delay = 100 * 2 ** attempt;\n' +
+          '  return sleep(delay).then(fn);\n' +
+          '}\n'
+      },
+      'Use exponential backoff between attempts\n\nReconnect storms were hammering the API.',
+      'Bo <bo@example.com>'
+    );
+    await commit(
+      tempDir,
+      { 'docs/setup.md': '# Setup\n\nRun the installer.\n' },
+      'Document setup',
+      'Ana <ana@example.com>'
+    );
+  });
+
+  afterEach(async () => {
+    vi.restoreAllMocks();
+    await rmWithRetries(tempDir);
+  });
+
+  it('returns matching commits with metadata and the relevant hunk', async () => {
+    const response = await searchHistory(tempDir, 'exponential backoff delay', {
+      skipEmbedding: true
+    });
+
+    expect(response).toMatchObject({ mode: 'keyword', indexedCommits: 3 });
+    const [top] = response.results;
+    expect(top).toMatchObject({
+      sha: backoffSha,
+      author: 'Bo',
+      filesChanged: 1,
+      files: ['src/retry.ts']
+    });
+    expect(top.message).toContain('Reconnect storms');
+    expect(Number.isNaN(Date.parse(top.date))).toBe(false);
+    expect(top.hunks[0].file).toBe('src/retry.ts');
+    expect(top.hunks[0].diff).toContain('+  const delay = 100 * 2 ** attempt;');
+  });
+
+  it('filters by author and path', async () => {
+    const byAna = await searchHistory(tempDir, 'retry', { skipEmbedding: true, author: 'ana@' });
+    expect(byAna.results.map((r) => r.author)).toEqual(['Ana']);
+
+    const docs = await searchHistory(tempDir, 'setup', { skipEmbedding: true, path: 'src' });
+    expect(docs.results).toEqual([]);
+  });
+
+  it('embeds only commits added since the last search', async () => {
+    const first = await searchHistory(tempDir, 'when did the retry backoff change');
+    expect(first.mode).toBe('hybrid');
+    expect(first.results[0].sha).toBe(backoffSha);
+    const embeddedFirst = embedded.texts.length;
+    expect(embeddedFirst).toBeGreaterThan(0);
+
+    embedded.texts = [];
+    await commit(tempDir, { 'src/other.ts': 'export const x = 1;\n' }, 'Add x', 'Cy <cy@x.io>');
+    const second = await searchHistory(tempDir, 'add x');
+
+    expect(second.indexedCommits).toBe(4);
+    expect(embedded.texts.length).toBeGreaterThan(0);
+    expect(embedded.texts.every((text) => text.startsWith('Add x'))).toBe(true);
+  });
+});
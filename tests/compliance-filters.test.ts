@@ -0,0 +1,131 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { CodebaseSearcher } from '../src/core/search.js';
+import {
+  createComplianceFilter,
+  isValidIban,
+  passesLuhn,
+  readComplianceAudit
+} from '../src/core/compliance-filters.js';
+import { loadPathPolicy } from '../src/core/path-policy.js';
+import { readIndexedFile } from '../src/core/file-resources.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  PROJECT_CONFIG_FILENAME
+} from '../src/constants/codebase-context.js';
+import type { CodeChunk } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+describe('compliance filters', () => {
+  it('checks card numbers and IBANs before masking them', () => {
+    expect(passesLuhn('4111 1111 1111 1111')).toBe(true);
+    expect(passesLuhn('4111 1111 1111 1112')).toBe(false);
+    expect(isValidIban('GB82 WEST 1234 5698 7654 32')).toBe(true);
+    expect(isValidIban('GB82 WEST 1234 5698 7654 33')).toBe(false);
+  });
+
+  it('masks detector and rule matches without moving lines', () => {
+    const filter = createComplianceFilter({
+      detectors: ['email', 'us-ssn', 'credit-card'],
+      rules: [{ name: 'customer-id', pattern: 'CUST-\\d{6}' }],
+      allowlist: ['@example\\.com$']
+    });
+    const text = [
+      'const owner = "jane.doe@acme.io";',
+      'const demo = "someone@example.com";',
+      'ssn("123-45-6789"); card("4111-1111-1111-1111"); order("4111-1111-1111-1112");',
+      'lookup("CUST-004211")'
+    ].join('\n');
+
+    const { text: masked, matches } = filter.mask(text);
+    expect(masked.split('\n')).toEqual([
+      'const owner = "[FILTERED:email]";',
+      'const demo = "someone@example.com";',
+      'ssn("[FILTERED:us-ssn]"); card("[FILTERED:credit-card]"); order("4111-1111-1111-1112");',
+      'lookup("[FILTERED:customer-id]")'
+    ]);
+    expect(matches).toEqual({ email: 1, 'us-ssn': 1, 'credit-card': 1, 'customer-id': 1 });
+    expect(createComplianceFilter().active).toBe(false);
+  });
+});
+
+describe('compliance filters in the index', () => {
+  let tempRoot: string;
+
+  beforeEach(async () => {
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'compliance-'));
+    const files: Record<string, string> = {
+      'src/support.ts': 'export function escalate() {\n  return notify("ops-lead@acme.io");\n}\n',
+      'src/seed.ts': 'export const seedRows = [{ customer: "CUST-004211", plan: "pro" }];\n',
+      'fixtures/customers.ts': 'export const customers = [{ name: "Jane Doe" }];\n'
+    };
+    for (const [file, content] of Object.entries(files)) {
+      await fs.mkdir(path.dirname(path.join(tempRoot, file)), { recursive: true });
+      await fs.writeFile(path.join(tempRoot, file), content);
+    }
+    await fs.mkdir(path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME), { recursive: true });
+    await fs.writeFile(
+      path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME),
+      JSON.stringify({
+        compliance: {
+          excludePaths: ['fixtures/'],
+          detectors: ['email'],
+          rules: [{ name: 'customer-id', pattern: 'CUST-\\d{6}', action: 'exclude' }]
+        }
+      })
+    );
+  });
+
+  afterEach(async () => {
+    vi.restoreAllMocks();
+    await rmWithRetries(tempRoot);
+  });
+
+  it('masks, excludes and logs what it filtered', async () => {
+    const stats = await new CodebaseIndexer({
+      rootPath: tempRoot,
+      config: { skipEmbedding: true }
+    }).index();
+    expect(stats.complianceFiltered).toBe(1);
+    expect(stats.complianceExcludedFiles).toBe(2);
+
+    const searcher = new CodebaseSearcher(tempRoot);
+    const results = await searcher.search('escalate notify', 5, undefined, {
+      useSemanticSearch: false
+    });
+    expect(results[0].snippet).toContain('notify("[FILTERED:email]")');
+    const indexedFiles = new Set(
+      (await searcher.search('export', 10, undefined, { useSemanticSearch: false })).map(
+        (result) => path.relative(tempRoot, result.filePath).replace(/\\/g, '/')
+      )
+    );
+    expect(indexedFiles).toEqual(new Set(['src/support.ts']));
+
+    const audit = await readComplianceAudit(path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME));
+    const findings = audit.map(({ file, rule, action, matches }) => [file, rule, action, matches]);
+    expect(findings.sort()).toEqual([
+      ['fixtures/customers.ts', 'excludePaths', 'excluded', 0],
+      ['src/seed.ts', 'customer-id', 'excluded', 1],
+      ['src/support.ts', 'email', 'masked', 1]
+    ]);
+    expect(JSON.stringify(audit)).not.toContain('acme.io');
+  });
+
+  it('filters content and paths on the way out too', async () => {
+    const chunk = { filePath: path.join(tempRoot, 'src/support.ts') } as CodeChunk;
+    const content = await readIndexedFile(
+      tempRoot,
+      new Map([['src/support.ts', [chunk]]]),
+      'src/support.ts'
+    );
+    expect(content).toContain('notify("[FILTERED:email]")');
+
+    const policy = await loadPathPolicy(tempRoot);
+    expect(policy.allows('fixtures/customers.ts')).toBe(false);
+    expect(policy.allows('src/support.ts')).toBe(true);
+  });
+});
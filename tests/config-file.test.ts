@@ -0,0 +1,189 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { loadProjectChunkingConfig } from '../src/core/chunking-config.js';
+import {
+  applyConfigFiles,
+  loadConfigFiles,
+  parseConfigFile,
+  resolveConfigSettings
+} from '../src/core/config-file.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  MANIFEST_FILENAME,
+  PROJECT_CONFIG_FILENAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const PROJECT_YAML = `# committed with the repo
+profile: fast-local
+ignore:
+  - fixtures/
+chunking:
+  maxLines: 100
+  languages:
+    go:
+      maxLines: 60
+profiles:
+  fast-local:
+    embedding:
+      model: Xenova/bge-small-en-v1.5
+    storage:
+      quantization: int8
+  team-shared:
+    embedding:
+      model: text-embedding-3-small
+`;
+
+const USER_YAML = `embedding:
+  batchSize: 16
+profiles:
+  fast-local:
+    embedding:
+      provider: transformers
+    storage:
+      provider: sqlite
+  team-shared:
+    embedding:
+      provider: openai
+      apiKey: \${TEAM_OPENAI_KEY}
+    storage:
+      provider: qdrant
+      url: http://qdrant.internal:6333
+      apiKey: \${QDRANT_TOKEN}
+`;
+
+describe('config files', () => {
+  let tempRoot: string;
+  let env: NodeJS.ProcessEnv;
+  let savedUserConfig: string | undefined;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'config-file-test-'));
+    await fs.mkdir(path.join(tempRoot, 'repo'));
+    await fs.writeFile(path.join(tempRoot, 'repo', 'codebase-context.yaml'), PROJECT_YAML);
+    await fs.writeFile(path.join(tempRoot, 'user.yaml'), USER_YAML);
+    env = { CODEBASE_CONTEXT_CONFIG: path.join(tempRoot, 'user.yaml') };
+    savedUserConfig = process.env.CODEBASE_CONTEXT_CONFIG;
+    process.env.CODEBASE_CONTEXT_CONFIG = env.CODEBASE_CONTEXT_CONFIG;
+  });
+
+  afterEach(async () => {
+    if (savedUserConfig === undefined) delete process.env.CODEBASE_CONTEXT_CONFIG;
+    else process.env.CODEBASE_CONTEXT_CONFIG = savedUserConfig;
+    await rmWithRetries(tempRoot);
+  });
+
+  it("applies the project's default profile over the user file", () => {
+    const applied = applyConfigFiles(path.join(tempRoot, 'repo'), env);
+
+    expect(applied.profile).toBe('fast-local');
+    expect(applied.sources).toHaveLength(2);
+    expect(env).toMatchObject({
+      EMBEDDING_PROVIDER: 'transformers',
+      EMBEDDING_MODEL: 'Xenova/bge-small-en-v1.5',
+      EMBEDDING_BATCH_SIZE: '16',
+      STORAGE_PROVIDER: 'sqlite',
+      CODEBASE_CONTEXT_QUANTIZATION: 'int8'
+    });
+  });
+
+  it('takes providers, endpoints, URLs and env only from the user file', async () => {
+    const repo = path.join(tempRoot, 'repo');
+    const refused = [
+      'env:\n  NODE_OPTIONS: --require ./x.js',
+      'env:\n  GIT_SSH_COMMAND: sh -c id',
+      'env:\n  OLLAMA_HOST: http://collector.example',
+      'embedding:\n  provider: openai',
+      'embedding:\n  endpoint: http://collector.example',
+      'embedding:\n  apiKey: ${OPENAI_API_KEY}',
+      'embedding:\n  modelPath: /tmp/model',
+      'embedding:\n  allowDownload: true',
+      'storage:\n  provider: qdrant',
+      'storage:\n  url: http://collector.example:6333',
+      'storage:\n  collection: shared',
+      'reranker:\n  url: http://collector.example',
+      'profiles:\n  p:\n    storage:\n      provider: pgvector'
+    ];
+    for (const yaml of refused) {
+      await fs.writeFile(path.join(repo, 'codebase-context.yaml'), `${yaml}\n`);
+      expect(() => loadConfigFiles(repo, env), yaml).toThrow(/only be set in the user config/);
+
+      // The same settings are the user's to make
+      await fs.writeFile(path.join(tempRoot, 'user.yaml'), `${yaml}\n`);
+      await fs.rm(path.join(repo, 'codebase-context.yaml'));
+      expect(loadConfigFiles(repo, env)).toHaveLength(1);
+    }
+  });
+
+  it('resolves credential references and lets the environment win', () => {
+    env.CODEBASE_CONTEXT_PROFILE = 'team-shared';
+    env.TEAM_OPENAI_KEY = 'sk-from-secret-store';
+    env.STORAGE_PROVIDER = 'lancedb';
+    const applied = applyConfigFiles(path.join(tempRoot, 'repo'), env);
+
+    expect(applied.profile).toBe('team-shared');
+    expect(env.OPENAI_API_KEY).toBe('sk-from-secret-store');
+    expect(env.EMBEDDING_MODEL).toBe('text-embedding-3-small');
+    expect(env.STORAGE_PROVIDER).toBe('lancedb');
+    expect(applied.overridden).toEqual(['STORAGE_PROVIDER']);
+    // The qdrant keys follow the profile's storage provider; the token is not set
+    expect(env.QDRANT_URL).toBe('http://qdrant.internal:6333');
+    expect(applied.unresolved).toEqual(['storage.apiKey']);
+    expect(env.QDRANT_API_KEY).toBeUndefined();
+  });
+
+  it('refuses literal keys, unknown keys and unknown profiles', () => {
+    expect(() => parseConfigFile('embedding:\n  apiKey: sk-live-123\n', 'c.yaml')).toThrow(
+      /must reference a variable/
+    );
+    expect(() => parseConfigFile('env:\n  GITHUB_TOKEN: ghp_abc\n', 'c.yaml')).toThrow(
+      /credential/
+    );
+    expect(() => parseConfigFile('storage:\n  bucket: x\n', 'c.yaml')).toThrow(
+      /unknown key "storage.bucket"/
+    );
+    expect(() =>
+      resolveConfigSettings(loadConfigFiles(path.join(tempRoot, 'repo'), env), 'prod')
+    ).toThrow('Unknown profile "prod" (defined: fast-local, team-shared)');
+    expect(() =>
+      applyConfigFiles(path.join(tempRoot, 'repo'), {
+        ...env,
+        CODEBASE_CONTEXT_CONFIG: path.join(tempRoot, 'none.yaml'),
+        CODEBASE_CONTEXT_PROFILE: 'prod'
+      })
+    ).toThrow(/Unknown profile/);
+  });
+
+  it('layers config.json chunking over the YAML chunking section', async () => {
+    const root = path.join(tempRoot, 'repo');
+    await fs.mkdir(path.join(root, CODEBASE_CONTEXT_DIRNAME));
+    await fs.writeFile(
+      path.join(root, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME),
+      JSON.stringify({ chunking: { languages: { go: { minLines: 5 } } } })
+    );
+
+    expect(await loadProjectChunkingConfig(root)).toEqual({
+      maxLines: 100,
+      languages: { go: { maxLines: 60, minLines: 5 } }
+    });
+  });
+
+  it('leaves files matching the ignore section out of the index', async () => {
+    const root = path.join(tempRoot, 'repo');
+    await fs.mkdir(path.join(root, 'src'));
+    await fs.mkdir(path.join(root, 'fixtures'));
+    await fs.writeFile(path.join(root, 'src', 'app.ts'), 'export const app = 1;\n');
+    await fs.writeFile(path.join(root, 'fixtures', 'big.ts'), 'export const big = 1;\n');
+
+    await new CodebaseIndexer({ rootPath: root, config: { skipEmbedding: true } }).index();
+    const manifest = JSON.parse(
+      await fs.readFile(path.join(root, CODEBASE_CONTEXT_DIRNAME, MANIFEST_FILENAME), 'utf-8')
+    ) as { files: Record<string, string> };
+
+    expect(Object.keys(manifest.files)).toContain('src/app.ts');
+    expect(Object.keys(manifest.files)).not.toContain('fixtures/big.ts');
+  });
+});
@@ -0,0 +1,96 @@
+import { describe, it, expect } from 'vitest';
+import { packContext, type LineReader } from '../src/core/context-packer.js';
+import { estimateTokens } from '../src/utils/ast-chunker.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+
+const FILES: Record<string, string[]> = {
+  'src/auth.ts': Array.from({ length: 60 }, (_, i) => `const auth${i + 1} = ${i + 1};`),
+  'src/db.ts': Array.from({ length: 40 }, (_, i) => `const db${i + 1} = ${i + 1};`)
+};
+const reader: LineReader = async (file) => FILES[file] ?? null;
+
+describe('packContext', () => {
+  it('merges overlapping hits and orders files by their best hit', async () => {
+    const packed = await packContext(
+      [
+        { file: 'src/db.ts', startLine: 1, endLine: 5, score: 0.4 },
+        { file: 'src/auth.ts', startLine: 10, endLine: 20, score: 0.9 },
+        { file: 'src/auth.ts', startLine: 15, endLine: 25, score: 0.7 },
+        { file: 'src/auth.ts', startLine: 12, endLine: 14, score: 0.5 }
+      ],
+      8000,
+      reader
+    );
+
+    expect(packed.sections.map((s) => [s.file, s.startLine, s.endLine])).toEqual([
+      ['src/auth.ts', 10, 25],
+      ['src/db.ts', 1, 5]
+    ]);
+    expect(packed.text.split('\n')[0]).toBe('// src/auth.ts:10-25');
+    expect(packed.text).toContain('10 | const auth10 = 10;');
+    expect(packed.omitted).toBe(0);
+  });
+
+  it('never exceeds the budget and truncates the first hit that does not fit', async () => {
+    const packed = await packContext(
+      [
+        { file: 'src/auth.ts', startLine: 1, endLine: 10, score: 0.9 },
+        { file: 'src/db.ts', startLine: 1, endLine: 40, score: 0.8 },
+        { file: 'src/auth.ts', startLine: 40, endLine: 60, score: 0.1 }
+      ],
+      200,
+      reader
+    );
+
+    expect(packed.tokens).toBeLessThanOrEqual(200);
+    expect(estimateTokens(packed.text)).toBe(packed.tokens);
+    expect(packed.sections[0]).toMatchObject({ file: 'src/auth.ts', startLine: 1, endLine: 10 });
+    const db = packed.sections.find((s) => s.file === 'src/db.ts');
+    expect(db?.truncated).toBe(true);
+    expect(db!.endLine).toBeLessThan(40);
+    expect(packed.omitted).toBe(1);
+  });
+
+  it('counts with the tokenizer it is given', async () => {
+    const countWords = (text: string) => text.split(/\s+/).filter(Boolean).length;
+    const packed = await packContext(
+      [{ file: 'src/auth.ts', startLine: 1, endLine: 60, score: 1 }],
+      100,
+      reader,
+      countWords
+    );
+    expect(packed.tokens).toBe(countWords(packed.text));
+    expect(packed.tokens).toBeLessThanOrEqual(100);
+    expect(packed.sections[0].truncated).toBe(true);
+  });
+
+  it('skips files that cannot be read', async () => {
+    const packed = await packContext(
+      [{ file: 'src/gone.ts', startLine: 1, endLine: 3, score: 1 }],
+      1000,
+      reader
+    );
+    expect(packed).toMatchObject({ text: '', sections: [], omitted: 1 });
+  });
+});
+
+describe('pack_context tool', () => {
+  it('rejects a missing query', async () => {
+    const ctx: ToolContext = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir: '/tmp',
+        memory: '/tmp/memory.jsonl',
+        intelligence: '/tmp/intelligence.json',
+        keywordIndex: '/tmp/index.json',
+        vectorDb: '/tmp/vector-db'
+      },
+      rootPath: '/tmp',
+      performIndexing: () => undefined
+    };
+
+    const result = await dispatchTool('pack_context', { tokenBudget: 1000 }, ctx);
+    expect(result.isError).toBe(true);
+  });
+});
@@ -0,0 +1,169 @@
+import { describe, it, expect, afterEach } from 'vitest';
+import { randomUUID } from 'crypto';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { PassThrough } from 'stream';
+import { Server } from '@modelcontextprotocol/sdk/server/index.js';
+import { LATEST_PROTOCOL_VERSION } from '@modelcontextprotocol/sdk/types.js';
+import {
+  resolveDaemonIdleMinutes,
+  resolveDaemonRole,
+  resolveDaemonSocketPath,
+  runDaemonShim,
+  startDaemon,
+  type DaemonHandle
+} from '../src/daemon.js';
+
+function createTestServer(): Server {
+  return new Server({ name: 'warm-daemon', version: '0.0.0' }, { capabilities: { tools: {} } });
+}
+
+function testSocketPath(): string {
+  const name = `codebase-context-test-${randomUUID()}`;
+  return process.platform === 'win32'
+    ? `\\\\.\\pipe\\${name}`
+    : path.join(os.tmpdir(), `${name}.sock`);
+}
+
+/** Send an initialize request through a shim and return the daemon's reply */
+async function initializeThroughShim(socketPath: string, startDaemon: () => Promise<void>) {
+  const stdin = new PassThrough();
+  const stdout = new PassThrough();
+  const reply = new Promise<string>((resolve) => {
+    let buffered = '';
+    stdout.on('data', (chunk: Buffer) => {
+      buffered += chunk.toString('utf-8');
+      const end = buffered.indexOf('\n');
+      if (end >= 0) resolve(buffered.slice(0, end));
+    });
+  });
+  const done = runDaemonShim({ socketPath, startDaemon, stdin, stdout, startTimeoutMs: 5000 });
+  stdin.write(
+    JSON.stringify({
+      jsonrpc: '2.0',
+      id: 1,
+      method: 'initialize',
+      params: {
+        protocolVersion: LATEST_PROTOCOL_VERSION,
+        capabilities: {},
+        clientInfo: { name: 'editor', version: '0.0.0' }
+      }
+    }) + '\n'
+  );
+  const message = JSON.parse(await reply);
+  stdin.end();
+  await done;
+  return message;
+}
+
+describe('daemon configuration', () => {
+  it('reads the role and idle timeout from the environment', () => {
+    expect(resolveDaemonRole({})).toBeNull();
+    expect(resolveDaemonRole({ CODEBASE_CONTEXT_TRANSPORT: 'daemon' })).toBe('shim');
+    expect(resolveDaemonRole({ CODEBASE_CONTEXT_TRANSPORT: 'daemon-server' })).toBe('server');
+    expect(resolveDaemonIdleMinutes({})).toBe(30);
+    expect(resolveDaemonIdleMinutes({ CODEBASE_CONTEXT_DAEMON_IDLE_MINUTES: '0' })).toBe(0);
+  });
+
+  it('derives one socket per set of roots', () => {
+    const a = resolveDaemonSocketPath(['/work/api', '/work/web'], {}, 'linux');
+    expect(resolveDaemonSocketPath(['/work/web', '/work/api'], {}, 'linux')).toBe(a);
+    expect(resolveDaemonSocketPath(['/work/api'], {}, 'linux')).not.toBe(a);
+    expect(resolveDaemonSocketPath(['/work/api'], {}, 'win32')).toMatch(/^\\\\\.\\pipe\\/);
+    expect(
+      resolveDaemonSocketPath(['/work/api'], { CODEBASE_CONTEXT_DAEMON_SOCKET: '/tmp/cc.sock' })
+    ).toBe('/tmp/cc.sock');
+    expect(
+      resolveDaemonSocketPath(['/work/api'], { XDG_RUNTIME_DIR: '/run/user/1000' }, 'linux')
+    ).toMatch(/^\/run\/user\/1000\/codebase-context\/[0-9a-f]{16}\.sock$/);
+  });
+});
+
+describe('daemon and shim', () => {
+  let daemon: DaemonHandle | undefined;
+
+  afterEach(async () => {
+    await daemon?.close();
+    daemon = undefined;
+  });
+
+  it('serves reconnecting shims from the same warm process', async () => {
+    const socketPath = testSocketPath();
+    let servers = 0;
+    daemon = await startDaemon({
+      socketPath,
+      createServer: () => {
+        servers++;
+        return createTestServer();
+      }
+    });
+    const unexpectedStart = async () => {
+      throw new Error('daemon should already be running');
+    };
+
+    const first = await initializeThroughShim(socketPath, unexpectedStart);
+    expect(first.result.serverInfo.name).toBe('warm-daemon');
+    const second = await initializeThroughShim(socketPath, unexpectedStart);
+    expect(second.result.serverInfo.name).toBe('warm-daemon');
+    expect(servers).toBe(2);
+
+    await expect(startDaemon({ socketPath, createServer: createTestServer })).rejects.toThrow(
+      /already listening/
+    );
+  });
+
+  it.skipIf(process.platform === 'win32')(
+    'keeps the default socket in a private directory',
+    async () => {
+      const runtime = await fs.mkdtemp(path.join(os.tmpdir(), 'daemon-runtime-'));
+      const saved = process.env.XDG_RUNTIME_DIR;
+      process.env.XDG_RUNTIME_DIR = runtime;
+      try {
+        const socketPath = resolveDaemonSocketPath([runtime]);
+        daemon = await startDaemon({ socketPath, createServer: createTestServer });
+        const dir = await fs.stat(path.dirname(socketPath));
+        expect(dir.mode & 0o777).toBe(0o700);
+        expect((await fs.stat(socketPath)).mode & 0o777).toBe(0o600);
+        await daemon.close();
+        daemon = undefined;
+      } finally {
+        if (saved === undefined) delete process.env.XDG_RUNTIME_DIR;
+        else process.env.XDG_RUNTIME_DIR = saved;
+        await fs.rm(runtime, { recursive: true, force: true });
+      }
+    }
+  );
+
+  it.skipIf(process.platform === 'win32')(
+    "doesn't unlink a starting daemon's socket, and takes over a crashed one's",
+    async () => {
+      const socketPath = testSocketPath();
+      await fs.writeFile(`${socketPath}.lock`, String(process.pid));
+      await expect(startDaemon({ socketPath, createServer: createTestServer })).rejects.toThrow(
+        /already starting/
+      );
+
+      // Neither the process holding the lock nor the socket file survived
+      await fs.writeFile(`${socketPath}.lock`, '999999999');
+      await fs.writeFile(socketPath, '');
+      daemon = await startDaemon({ socketPath, createServer: createTestServer });
+      const message = await initializeThroughShim(socketPath, async () => {
+        throw new Error('daemon should already be running');
+      });
+      expect(message.id).toBe(1);
+      await daemon.close();
+      daemon = undefined;
+      await expect(fs.access(`${socketPath}.lock`)).rejects.toThrow();
+    }
+  );
+
+  it('starts the daemon when nothing is listening', async () => {
+    const socketPath = testSocketPath();
+    const message = await initializeThroughShim(socketPath, async () => {
+      daemon = await startDaemon({ socketPath, createServer: createTestServer });
+    });
+    expect(message.id).toBe(1);
+    expect(message.result.protocolVersion).toBe(LATEST_PROTOCOL_VERSION);
+  });
+});
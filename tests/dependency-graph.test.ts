@@ -0,0 +1,183 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import {
+  createImportResolver,
+  getDependencies,
+  getDependents,
+  packageNameOf,
+  resolveDependencyTarget,
+  type DependencyGraphData
+} from '../src/core/dependency-graph.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+describe('createImportResolver', () => {
+  const resolve = createImportResolver(
+    ['src/a.ts', 'src/lib/index.ts', 'src/view.tsx', 'packages/ui/src/index.ts'],
+    { '@acme/ui': 'packages/ui' }
+  );
+
+  it('resolves emitted .js paths, missing extensions and index files', () => {
+    expect(resolve('src/b.ts', './a.js')).toEqual({ file: 'src/a.ts' });
+    expect(resolve('src/b.ts', './view')).toEqual({ file: 'src/view.tsx' });
+    expect(resolve('src/b.ts', './lib')).toEqual({ file: 'src/lib/index.ts' });
+    expect(resolve('src/b.ts', './missing')).toBeNull();
+  });
+
+  it('resolves workspace packages to files and keeps other packages by name', () => {
+    expect(resolve('src/a.ts', '@acme/ui')).toEqual({
+      file: 'packages/ui/src/index.ts',
+      package: '@acme/ui'
+    });
+    expect(resolve('src/a.ts', 'lodash/fp')).toEqual({ package: 'lodash' });
+    expect(resolve('src/a.ts', 'node:fs')).toBeNull();
+    expect(resolve('src/a.ts', 'path')).toBeNull();
+    expect(packageNameOf('@scope/pkg/deep/path')).toBe('@scope/pkg');
+  });
+});
+
+describe('dependency traversal', () => {
+  const data: DependencyGraphData = {
+    imports: {
+      'apps/web/main.ts': ['apps/web/routes.ts', 'packages/ui/src/index.ts'],
+      'apps/web/routes.ts': ['packages/ui/src/button.ts'],
+      'packages/ui/src/index.ts': ['packages/ui/src/button.ts']
+    },
+    importedBy: {
+      'apps/web/routes.ts': ['apps/web/main.ts'],
+      'packages/ui/src/index.ts': ['apps/web/main.ts'],
+      'packages/ui/src/button.ts': ['apps/web/routes.ts', 'packages/ui/src/index.ts']
+    },
+    importDetails: {
+      'apps/web/routes.ts': {
+        'packages/ui/src/button.ts': { line: 3, importedSymbols: ['Button'] }
+      }
+    },
+    externalImports: { 'apps/web/main.ts': ['react'], 'packages/ui/src/button.ts': ['react'] },
+    packages: { '@acme/ui': 'packages/ui', '@acme/web': 'apps/web' }
+  };
+
+  it('lists direct and transitive dependents of a file', () => {
+    const target = resolveDependencyTarget(data, '/repo', 'packages/ui/src/button.ts')!;
+    const result = getDependents(data, target, 2, 10);
+
+    expect(result.results).toEqual([
+      {
+        file: 'apps/web/routes.ts',
+        depth: 1,
+        line: 3,
+        symbols: ['Button'],
+        package: '@acme/web'
+      },
+      { file: 'packages/ui/src/index.ts', depth: 1, package: '@acme/ui' },
+      { file: 'apps/web/main.ts', depth: 2, package: '@acme/web' }
+    ]);
+    expect(result.byPackage).toEqual({ '@acme/web': 2, '@acme/ui': 1 });
+  });
+
+  it('treats a workspace package as all of its files', () => {
+    const target = resolveDependencyTarget(data, '/repo', '@acme/ui')!;
+
+    expect(getDependents(data, target, 1, 10).results.map((r) => r.file)).toEqual([
+      'apps/web/main.ts',
+      'apps/web/routes.ts'
+    ]);
+    expect(getDependencies(data, target, 1, 10)).toMatchObject({
+      total: 0,
+      externalPackages: ['react']
+    });
+  });
+
+  it('finds importers of an external package', () => {
+    const target = resolveDependencyTarget(data, '/repo', 'react')!;
+
+    expect(target).toEqual({ kind: 'package', name: 'react', files: [] });
+    expect(getDependents(data, target, 1, 10).total).toBe(2);
+  });
+});
+
+describe('get_dependencies / get_dependents tools', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'dependency-graph-'));
+    await fs.mkdir(path.join(tempRoot, 'packages', 'shared', 'src'), { recursive: true });
+    await fs.mkdir(path.join(tempRoot, 'apps', 'web', 'src'), { recursive: true });
+    await fs.writeFile(path.join(tempRoot, 'package.json'), JSON.stringify({ name: 'root' }));
+    await fs.writeFile(
+      path.join(tempRoot, 'packages', 'shared', 'package.json'),
+      JSON.stringify({ name: '@acme/shared' })
+    );
+    await fs.writeFile(
+      path.join(tempRoot, 'packages', 'shared', 'src', 'index.ts'),
+      `export function formatDate(d: Date) {\n  return d.toISOString();\n}\n`
+    );
+    await fs.writeFile(
+      path.join(tempRoot, 'apps', 'web', 'src', 'util.ts'),
+      `import { formatDate } from '@acme/shared';\nexport const today = formatDate(new Date());\n`
+    );
+    await fs.writeFile(
+      path.join(tempRoot, 'apps', 'web', 'src', 'main.ts'),
+      `import { today } from './util.js';\nimport { merge } from 'lodash';\n` +
+        `export const app = merge({}, { today });\n`
+    );
+
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('reports the transitive blast radius of a workspace package', async () => {
+    const result = await dispatchTool('get_dependents', { target: '@acme/shared', depth: 2 }, ctx);
+    const payload = JSON.parse(result.content![0].text);
+
+    expect(payload.status).toBe('success');
+    expect(payload.dependents).toEqual([
+      { file: 'apps/web/src/util.ts', depth: 1, line: 1, symbols: ['formatDate'] },
+      { file: 'apps/web/src/main.ts', depth: 2 }
+    ]);
+  });
+
+  it('lists file and external package dependencies', async () => {
+    const result = await dispatchTool('get_dependencies', { target: 'apps/web/src/main.ts' }, ctx);
+    const payload = JSON.parse(result.content![0].text);
+
+    expect(payload.dependencies.map((d: { file: string }) => d.file)).toEqual([
+      'apps/web/src/util.ts'
+    ]);
+    expect(payload.externalPackages).toEqual(['lodash']);
+  });
+
+  it('rejects a missing target', async () => {
+    const result = await dispatchTool('get_dependents', {}, ctx);
+    expect(result.isError).toBe(true);
+  });
+});
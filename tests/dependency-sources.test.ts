@@ -0,0 +1,239 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import {
+  cargoLockVersions,
+  goModRequirement,
+  indexDependency,
+  listIndexedDependencies,
+  parseDependencySpec,
+  resolveDependencySource
+} from '../src/core/dependency-sources.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+vi.mock('../src/embeddings/index.js', async (importOriginal) => {
+  const original = await importOriginal<typeof import('../src/embeddings/index.js')>();
+  const provider = {
+    name: 'fake',
+    modelName: 'fake-model',
+    dimensions: 2,
+    initialize: async () => {},
+    isReady: () => true,
+    embed: async () => [1, 0],
+    embedBatch: async (texts: string[]) => texts.map(() => [1, 0])
+  };
+  return { ...original, getEmbeddingProvider: async () => provider };
+});
+
+describe('dependency specs and lockfiles', () => {
+  it('splits names from versions, scoped npm packages included', () => {
+    expect(parseDependencySpec('github.com/gin-gonic/gin@v1.9')).toEqual({
+      name: 'github.com/gin-gonic/gin',
+      version: 'v1.9'
+    });
+    expect(parseDependencySpec('@types/node@20')).toEqual({ name: '@types/node', version: '20' });
+    expect(parseDependencySpec('serde')).toEqual({ name: 'serde' });
+    expect(() => parseDependencySpec('../etc/passwd')).toThrow();
+  });
+
+  it('reads required and pinned versions', () => {
+    const goMod = [
+      'module example.com/app',
+      '',
+      'require github.com/google/uuid v1.6.0',
+      'require (',
+      '\tgithub.com/gin-gonic/gin v1.9.1',
+      '\tgolang.org/x/text v0.14.0 // indirect',
+      ')'
+    ].join('\n');
+    expect(goModRequirement(goMod, 'github.com/gin-gonic/gin')).toBe('v1.9.1');
+    expect(goModRequirement(goMod, 'github.com/google/uuid')).toBe('v1.6.0');
+    expect(goModRequirement(goMod, 'github.com/gin-gonic')).toBeUndefined();
+
+    const lock = [
+      '[[package]]',
+      'name = "serde"',
+      'version = "1.0.197"',
+      '',
+      '[[package]]',
+      'name = "syn"',
+      'version = "2.0.52"',
+      '',
+      '[[package]]',
+      'name = "syn"',
+      'version = "1.0.109"'
+    ].join('\n');
+    expect(cargoLockVersions(lock, 'syn')).toEqual(['1.0.109', '2.0.52']);
+  });
+});
+
+describe('dependency sources', () => {
+  let tempRoot: string;
+  let modCache: string;
+
+  const write = async (file: string, content: string) => {
+    await fs.mkdir(path.dirname(file), { recursive: true });
+    await fs.writeFile(file, content);
+  };
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'dependency-sources-'));
+    modCache = path.join(tempRoot, 'gomodcache');
+    for (const version of ['v1.9.0', 'v1.9.1', 'v1.10.0']) {
+      await write(
+        path.join(modCache, 'github.com', 'gin-gonic', `gin@${version}`, 'gin.go'),
+        'package gin\n'
+      );
+    }
+    await write(
+      path.join(modCache, 'github.com', '!azure', 'azure-sdk@v0.3.0', 'sdk.go'),
+      'package sdk\n'
+    );
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('picks the newest cached Go version matching a partial one', async () => {
+    const env = { GOMODCACHE: modCache };
+    const gin = await resolveDependencySource(
+      tempRoot,
+      { name: 'github.com/gin-gonic/gin', version: 'v1.9' },
+      env
+    );
+    expect(gin).toMatchObject({ ecosystem: 'go', id: 'github.com/gin-gonic/gin@v1.9.1' });
+    expect(gin.sourceDir).toBe(path.join(modCache, 'github.com', 'gin-gonic', 'gin@v1.9.1'));
+
+    const azure = await resolveDependencySource(
+      tempRoot,
+      { name: 'github.com/Azure/azure-sdk' },
+      env
+    );
+    expect(azure.id).toBe('github.com/Azure/azure-sdk@v0.3.0');
+  });
+
+  it('uses the version go.mod requires and prefers vendor/', async () => {
+    await write(
+      path.join(tempRoot, 'go.mod'),
+      'module example.com/app\n\nrequire github.com/gin-gonic/gin v1.9.0\n'
+    );
+    const env = { GOMODCACHE: modCache };
+    const cached = await resolveDependencySource(
+      tempRoot,
+      { name: 'github.com/gin-gonic/gin' },
+      env
+    );
+    expect(cached.version).toBe('v1.9.0');
+
+    await write(path.join(tempRoot, 'vendor', 'github.com', 'gin-gonic', 'gin', 'gin.go'), '');
+    const vendored = await resolveDependencySource(
+      tempRoot,
+      { name: 'github.com/gin-gonic/gin' },
+      env
+    );
+    const vendorDir = path.join(tempRoot, 'vendor', 'github.com', 'gin-gonic', 'gin');
+    expect(vendored.sourceDir).toBe(vendorDir);
+  });
+
+  it('refuses a node_modules package at another version', async () => {
+    await write(
+      path.join(tempRoot, 'node_modules', 'left-pad', 'package.json'),
+      JSON.stringify({ name: 'left-pad', version: '1.3.0' })
+    );
+    await expect(
+      resolveDependencySource(tempRoot, { name: 'left-pad', version: '2' })
+    ).rejects.toThrow(/left-pad@1.3.0, not 2/);
+    await expect(resolveDependencySource(tempRoot, { name: 'missing-pkg' })).rejects.toThrow(
+      /No source for missing-pkg/
+    );
+  });
+});
+
+describe('searching indexed dependencies', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'dependency-search-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'format.ts'),
+      "import leftPad from 'left-pad';\nexport const formatId = (id: string) => leftPad(id, 8);\n"
+    );
+    const pkgDir = path.join(tempRoot, 'node_modules', 'left-pad');
+    await fs.mkdir(pkgDir, { recursive: true });
+    await fs.writeFile(
+      path.join(pkgDir, 'package.json'),
+      JSON.stringify({ name: 'left-pad', version: '1.3.0' })
+    );
+    await fs.writeFile(
+      path.join(pkgDir, 'index.js'),
+      'export default function leftPad(str, len) {\n  return String(str).padStart(len);\n}\n'
+    );
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('indexes a dependency once and reuses it', async () => {
+    const first = await indexDependency(tempRoot, { name: 'left-pad' });
+    expect(first).toMatchObject({ id: 'left-pad@1.3.0', ecosystem: 'npm', reused: false });
+    expect(first.indexedFiles).toBeGreaterThan(0);
+
+    const again = await indexDependency(tempRoot, { name: 'left-pad' });
+    expect(again.reused).toBe(true);
+    expect((await listIndexedDependencies(tempRoot)).map((d) => d.id)).toEqual(['left-pad@1.3.0']);
+  });
+
+  it('leaves dependencies out of searches unless asked', async () => {
+    await dispatchTool('index_dependency', { name: 'left-pad' }, ctx);
+    const search = async (extra: Record<string, unknown>) => {
+      const result = await dispatchTool(
+        'search_codebase',
+        { query: 'padStart', mode: 'keyword', limit: 10, ...extra },
+        ctx
+      );
+      return JSON.parse(result.content![0].text);
+    };
+
+    const projectOnly = await search({});
+    expect(
+      projectOnly.results.some((r: { dependency?: string }) => r.dependency !== undefined)
+    ).toBe(false);
+    expect(projectOnly.dependencies).toBeUndefined();
+
+    const withDeps = await search({ includeDependencies: ['left-pad'] });
+    expect(withDeps.dependencies).toEqual(['left-pad@1.3.0']);
+    const external = withDeps.results.find((r: { dependency?: string }) => r.dependency);
+    expect(external).toMatchObject({ dependency: 'left-pad@1.3.0' });
+    expect(external.file).toContain('index.js');
+  });
+});
@@ -0,0 +1,145 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { execFileSync } from 'child_process';
+import { promises as fs } from 'fs';
+import path from 'path';
+import os from 'os';
+import { buildDiffFileContexts, parseUnifiedDiff } from '../src/core/diff-context.js';
+import { getGitDiff } from '../src/utils/git-tree.js';
+import { rmWithRetries } from './test-helpers.js';
+
+function git(cwd: string, ...args: string[]): string {
+  return execFileSync(
+    'git',
+    ['-c', 'user.name=test', '-c', 'user.email=test@example.com', ...args],
+    { cwd, encoding: 'utf8' }
+  ).trim();
+}
+
+describe('parseUnifiedDiff', () => {
+  it('parses git diffs with added, deleted, renamed and modified files', () => {
+    const diff = [
+      'diff --git a/src/service.ts b/src/service.ts',
+      'index 1111111..2222222 100644',
+      '--- a/src/service.ts',
+      '+++ b/src/service.ts',
+      '@@ -3,2 +3,3 @@ export function save() {',
+      '-  old();',
+      '--- removed comment line',
+      '+  next();',
+      '+  audit();',
+      '+  done();',
+      '@@ -20,0 +22 @@',
+      '+  tail();',
+      'diff --git a/src/new.ts b/src/new.ts',
+      'new file mode 100644',
+      '--- /dev/null',
+      '+++ b/src/new.ts',
+      '@@ -0,0 +1,2 @@',
+      '+export const a = 1;',
+      '+export const b = 2;',
+      'diff --git a/src/gone.ts b/src/gone.ts',
+      'deleted file mode 100644',
+      '--- a/src/gone.ts',
+      '+++ /dev/null',
+      '@@ -1 +0,0 @@',
+      '-export const gone = true;',
+      'diff --git a/src/old-name.ts b/src/new-name.ts',
+      'similarity index 100%',
+      'rename from src/old-name.ts',
+      'rename to src/new-name.ts'
+    ].join('\n');
+
+    const files = parseUnifiedDiff(diff);
+
+    expect(files.map((f) => [f.path, f.status])).toEqual([
+      ['src/service.ts', 'modified'],
+      ['src/new.ts', 'added'],
+      ['src/gone.ts', 'deleted'],
+      ['src/new-name.ts', 'renamed']
+    ]);
+    expect(files[0].hunks).toEqual([
+      { oldStart: 3, oldLines: 2, newStart: 3, newLines: 3 },
+      { oldStart: 20, oldLines: 0, newStart: 22, newLines: 1 }
+    ]);
+    expect(files[0].additions).toBe(4);
+    expect(files[0].deletions).toBe(2);
+    expect(files[3].oldPath).toBe('src/old-name.ts');
+  });
+
+  it('parses plain diff -u output without git headers', () => {
+    const diff = [
+      '--- a.ts\t2024-01-01',
+      '+++ a.ts\t2024-01-02',
+      '@@ -1 +1 @@',
+      '-x',
+      '+y',
+      '--- b.ts',
+      '+++ b.ts',
+      '@@ -5,2 +5,0 @@',
+      '-p',
+      '-q'
+    ].join('\n');
+
+    const files = parseUnifiedDiff(diff);
+
+    expect(files.map((f) => f.path)).toEqual(['a.ts', 'b.ts']);
+    expect(files[1].deletions).toBe(2);
+  });
+});
+
+describe('buildDiffFileContexts', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'diff-context-test-'));
+    git(tempDir, 'init', '-q');
+    await fs.writeFile(
+      path.join(tempDir, 'service.ts'),
+      [
+        'export class UserService {',
+        '  save(user: string) {',
+        '    return user;',
+        '  }',
+        '',
+        '  load(id: string) {',
+        '    return id;',
+        '  }',
+        '}',
+        ''
+      ].join('\n')
+    );
+    git(tempDir, 'add', '-A');
+    git(tempDir, 'commit', '-q', '-m', 'initial');
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('maps hunks to the innermost changed method', async () => {
+    const servicePath = path.join(tempDir, 'service.ts');
+    const original = await fs.readFile(servicePath, 'utf-8');
+    await fs.writeFile(servicePath, original.replace('return id;', 'return id.trim();'));
+
+    const files = parseUnifiedDiff(await getGitDiff(tempDir, 'HEAD'));
+    const contexts = await buildDiffFileContexts(tempDir, files);
+
+    expect(contexts).toHaveLength(1);
+    expect(contexts[0].changedSymbols.map((s) => [s.name, s.lines])).toEqual([['load', '6-8']]);
+    expect(contexts[0].changedSymbols[0].signature).toBe('load(id: string) {');
+  });
+
+  it('reads file content at head when comparing two refs', async () => {
+    git(tempDir, 'checkout', '-q', '-b', 'feature');
+    const servicePath = path.join(tempDir, 'service.ts');
+    const original = await fs.readFile(servicePath, 'utf-8');
+    await fs.writeFile(servicePath, original.replace('return user;', 'return user.trim();'));
+    git(tempDir, 'commit', '-q', '-am', 'change save');
+    git(tempDir, 'checkout', '-q', '-');
+
+    const files = parseUnifiedDiff(await getGitDiff(tempDir, 'HEAD', 'feature'));
+    const contexts = await buildDiffFileContexts(tempDir, files, { head: 'feature' });
+
+    expect(contexts[0].changedSymbols.map((s) => s.name)).toEqual(['save']);
+  });
+});
@@ -0,0 +1,75 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import {
+  diversifyResults,
+  loadProjectDiversityConfig,
+  resolveDiversity,
+  type DiversityCandidate
+} from '../src/core/diversity.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  PROJECT_CONFIG_FILENAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const candidates: DiversityCandidate[] = [
+  { filePath: 'src/auth/session.ts', score: 0.95, snippet: 'refreshSession token expiry' },
+  { filePath: 'src/auth/session.ts', score: 0.93, snippet: 'refreshSession token renew' },
+  { filePath: 'src/auth/session-store.ts', score: 0.9, snippet: 'refreshSession token store' },
+  { filePath: 'src/billing/invoice.ts', score: 0.7, snippet: 'invoice total currency' },
+  { filePath: 'docs/auth.md', score: 0.6, snippet: 'how sessions are refreshed' }
+];
+
+describe('diversifyResults', () => {
+  it('keeps relevance order with one chunk per file by default', () => {
+    const picked = diversifyResults(candidates, 3, resolveDiversity());
+    expect(picked.map((c) => c.filePath)).toEqual([
+      'src/auth/session.ts',
+      'src/auth/session-store.ts',
+      'src/billing/invoice.ts'
+    ]);
+  });
+
+  it('allows several chunks of a file under a higher cap', () => {
+    const picked = diversifyResults(candidates, 3, resolveDiversity({ maxPerFile: 2 }));
+    expect(picked.map((c) => c.score)).toEqual([0.95, 0.93, 0.9]);
+  });
+
+  it('prefers other modules over near-duplicates with MMR', () => {
+    const picked = diversifyResults(candidates, 3, resolveDiversity({ lambda: 0.5 }));
+    expect(picked.map((c) => c.filePath)).toEqual([
+      'src/auth/session.ts',
+      'docs/auth.md',
+      'src/billing/invoice.ts'
+    ]);
+  });
+});
+
+describe('resolveDiversity', () => {
+  let tempRoot: string;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'diversity-'));
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('layers project config under per-query options and ignores bad values', async () => {
+    await fs.mkdir(path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME), { recursive: true });
+    await fs.writeFile(
+      path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME),
+      JSON.stringify({ search: { diversity: { lambda: 0.7, maxPerFile: 3 } } })
+    );
+    const project = await loadProjectDiversityConfig(tempRoot);
+    expect(project).toEqual({ lambda: 0.7, maxPerFile: 3 });
+    expect(resolveDiversity(project, { maxPerFile: 50, lambda: 2 })).toEqual({
+      lambda: 0.7,
+      maxPerFile: 10
+    });
+    expect(await loadProjectDiversityConfig(path.join(tempRoot, 'missing'))).toBeUndefined();
+  });
+});
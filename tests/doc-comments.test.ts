@@ -0,0 +1,170 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { docSummary, extractDocComment } from '../src/utils/doc-comments.js';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import type { CodeChunk } from '../src/types/index.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+describe('extractDocComment', () => {
+  it('reads JSDoc/KDoc blocks above the declaration, past decorators', () => {
+    const lines = [
+      '/**',
+      ' * Loads the config.',
+      ' *',
+      ' * @param path - file to read',
+      ' */',
+      '@Injectable()',
+      'export function loadConfig(path: string) {}'
+    ];
+    expect(extractDocComment(lines, 7, 7, 'typescript')).toBe(
+      'Loads the config.\n\n@param path - file to read'
+    );
+  });
+
+  it('reads GoDoc and /// line comments, but not plain // elsewhere', () => {
+    const go = ['// Open opens the store.', '// It is safe for concurrent use.', 'func Open() {}'];
+    expect(extractDocComment(go, 3, 3, 'go')).toBe(
+      'Open opens the store.\nIt is safe for concurrent use.'
+    );
+    const rust = ['/// Parses a header.', '#[inline]', 'pub fn parse() {}'];
+    expect(extractDocComment(rust, 3, 3, 'rust')).toBe('Parses a header.');
+    const ts = ['// TODO: remove', 'export function legacy() {}'];
+    expect(extractDocComment(ts, 2, 2, 'typescript')).toBeUndefined();
+  });
+
+  it('reads Python docstrings and Ruby comments', () => {
+    const python = [
+      '@cached',
+      'def total(',
+      '    items: list,',
+      ') -> int:',
+      '    """Sum the items.',
+      '',
+      '    Ignores None.',
+      '    """',
+      '    return sum(items)'
+    ];
+    expect(extractDocComment(python, 2, 9, 'python')).toBe('Sum the items.\n\nIgnores None.');
+    expect(extractDocComment(['def f():', "    '''One line.'''"], 1, 2, 'python')).toBe(
+      'One line.'
+    );
+    const ruby = ['# Charges the card.', 'def charge', 'end'];
+    expect(extractDocComment(ruby, 2, 3, 'ruby')).toBe('Charges the card.');
+  });
+
+  it('reads Elixir @moduledoc and @doc attributes, past @spec', () => {
+    const elixir = [
+      'defmodule Billing do',
+      '  @moduledoc """',
+      '  Invoices and payments.',
+      '  """',
+      '',
+      '  @doc "Sums the invoice."',
+      '  @spec total(list) :: number',
+      '  def total(items), do: Enum.sum(items)',
+      'end'
+    ];
+    expect(extractDocComment(elixir, 1, 9, 'elixir')).toBe('Invoices and payments.');
+    expect(extractDocComment(elixir, 8, 8, 'elixir')).toBe('Sums the invoice.');
+    const hidden = ['  @doc false', '  def hidden, do: nil'];
+    expect(extractDocComment(hidden, 2, 2, 'elixir')).toBeUndefined();
+  });
+
+  it('summarizes to the first sentence without tags', () => {
+    expect(docSummary('@deprecated\nLoads the config. Falls back to defaults.')).toBe(
+      'Loads the config.'
+    );
+  });
+});
+
+describe('symbol docs', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'doc-comments-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'rates.ts'),
+      [
+        '/**',
+        ' * Converts an amount between currencies at the daily rate.',
+        ' * @throws RangeError for unknown currencies',
+        ' */',
+        'export function convert(amount: number, from: string, to: string): number {',
+        '  return amount;',
+        '}',
+        '',
+        'export function undocumented(): void {',
+        '  return;',
+        '}',
+        ''
+      ].join('\n')
+    );
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const contextDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir: contextDir,
+        memory: path.join(contextDir, MEMORY_FILENAME),
+        intelligence: path.join(contextDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(contextDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(contextDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('attaches doc comments to symbol chunks', async () => {
+    const raw = await fs.readFile(ctx.paths.keywordIndex, 'utf-8');
+    const chunks = (JSON.parse(raw) as { chunks: CodeChunk[] }).chunks;
+    // Small neighbouring symbols may share a chunk ("convert+undocumented")
+    const convert = chunks.find((chunk) => chunk.metadata?.symbolName?.startsWith('convert'));
+    expect(convert?.metadata.docComment).toBe(
+      'Converts an amount between currencies at the daily rate.\n' +
+        '@throws RangeError for unknown currencies'
+    );
+  });
+
+  it('get_symbol_docs returns the doc and declaration line', async () => {
+    const response = await dispatchTool('get_symbol_docs', { symbol: 'convert' }, ctx);
+    const payload = JSON.parse(response.content![0].text);
+    expect(payload).toMatchObject({ status: 'success', totalDefinitions: 1, documented: 1 });
+    expect(payload.symbols[0]).toMatchObject({
+      name: 'convert',
+      location: 'src/rates.ts:5',
+      signature: 'export function convert(amount: number, from: string, to: string): number {',
+      docSource: 'comment'
+    });
+    expect(payload.symbols[0].doc).toContain('daily rate');
+
+    const bare = JSON.parse(
+      (await dispatchTool('get_symbol_docs', { symbol: 'undocumented' }, ctx)).content![0].text
+    );
+    expect(bare.documented).toBe(0);
+    expect(bare.symbols[0].doc).toBeUndefined();
+
+    const missing = JSON.parse(
+      (await dispatchTool('get_symbol_docs', { symbol: 'nope' }, ctx)).content![0].text
+    );
+    expect(missing.status).toBe('not_found');
+  });
+});
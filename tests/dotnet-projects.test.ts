@@ -0,0 +1,154 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import {
+  detectDotnetProjects,
+  findOwningProject,
+  parseCsproj,
+  parseSolutionProjects
+} from '../src/utils/dotnet-projects.js';
+import { extractTreeSitterSymbols } from '../src/utils/tree-sitter.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME
+} from '../src/constants/codebase-context.js';
+import type { CodeChunk } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const SOLUTION = [
+  'Microsoft Visual Studio Solution File, Format Version 12.00',
+  'Project("{2150E333-8FDC-42A3-9474-1A3956D46DE8}") = "src", "src", "{11111111-0000-0000-0000-000000000000}"',
+  'EndProject',
+  'Project("{FAE04EC0-301F-11D3-BF4B-00C04F79EFBC}") = "Acme.Api", "src\\Api\\Api.csproj", "{22222222-0000-0000-0000-000000000000}"',
+  'EndProject',
+  'Project("{9A19103F-16F7-4668-BE54-9A1E7A4F7556}") = "Acme.Core", "src\\Core\\Core.csproj", "{33333333-0000-0000-0000-000000000000}"',
+  'EndProject'
+].join('\r\n');
+
+describe('.NET project parsing', () => {
+  it('reads project entries from .sln and .slnx files, skipping solution folders', () => {
+    expect(parseSolutionProjects(SOLUTION)).toEqual([
+      { name: 'Acme.Api', projectPath: 'src/Api/Api.csproj' },
+      { name: 'Acme.Core', projectPath: 'src/Core/Core.csproj' }
+    ]);
+    expect(
+      parseSolutionProjects('<Solution><Project Path="src/Web/Web.csproj" /></Solution>')
+    ).toEqual([{ name: 'Web', projectPath: 'src/Web/Web.csproj' }]);
+  });
+
+  it('reads RootNamespace and AssemblyName from a .csproj', () => {
+    const csproj = [
+      '<Project Sdk="Microsoft.NET.Sdk">',
+      '  <PropertyGroup>',
+      '    <RootNamespace>Acme.Api</RootNamespace>',
+      '    <AssemblyName>Acme.Api.Host</AssemblyName>',
+      '  </PropertyGroup>',
+      '</Project>'
+    ].join('\n');
+
+    expect(parseCsproj(csproj)).toEqual({
+      rootNamespace: 'Acme.Api',
+      assemblyName: 'Acme.Api.Host'
+    });
+  });
+
+  it('qualifies C# symbols with block and file-scoped namespaces', async () => {
+    const blockScoped = await extractTreeSitterSymbols(
+      [
+        'namespace Acme.Billing',
+        '{',
+        '    public class InvoiceService',
+        '    {',
+        '        public void Send() { }',
+        '    }',
+        '}'
+      ].join('\n'),
+      'csharp'
+    );
+    expect(blockScoped!.symbols.map((s) => [s.qualifiedName, s.namespace])).toEqual([
+      ['Acme.Billing.InvoiceService', 'Acme.Billing'],
+      ['Acme.Billing.InvoiceService.Send', 'Acme.Billing']
+    ]);
+
+    const fileScoped = await extractTreeSitterSymbols(
+      ['namespace Acme.Core;', '', 'public struct Money', '{', '}'].join('\n'),
+      'csharp'
+    );
+    expect(fileScoped!.symbols.map((s) => [s.kind, s.qualifiedName])).toEqual([
+      ['struct', 'Acme.Core.Money']
+    ]);
+  });
+});
+
+describe('.NET solution indexing', () => {
+  let tempDir: string;
+
+  async function write(relativePath: string, content: string): Promise<void> {
+    const fullPath = path.join(tempDir, relativePath);
+    await fs.mkdir(path.dirname(fullPath), { recursive: true });
+    await fs.writeFile(fullPath, content);
+  }
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'dotnet-projects-test-'));
+    await write('Acme.sln', SOLUTION);
+    await write('src/Api/Api.csproj', '<Project Sdk="Microsoft.NET.Sdk.Web"></Project>');
+    await write('src/Core/Core.csproj', '<Project Sdk="Microsoft.NET.Sdk"></Project>');
+    await write(
+      'src/Api/Controllers/OrdersController.cs',
+      [
+        'namespace Acme.Api.Controllers;',
+        '',
+        'public class OrdersController',
+        '{',
+        '    public string Get() => "orders";',
+        '}'
+      ].join('\n')
+    );
+    await write(
+      'src/Core/Orders.cs',
+      ['namespace Acme.Core;', '', 'public class Orders', '{', '}'].join('\n')
+    );
+    await write('tools/build.cs', 'public class Build { }\n');
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('maps files to their owning project using solution names', async () => {
+    const projects = await detectDotnetProjects(tempDir);
+
+    expect(projects.map((p) => [p.name, p.directory, p.solutions])).toEqual([
+      ['Acme.Api', 'src/Api', ['Acme.sln']],
+      ['Acme.Core', 'src/Core', ['Acme.sln']]
+    ]);
+    expect(findOwningProject(projects, 'src/Api/Controllers/OrdersController.cs')?.name).toBe(
+      'Acme.Api'
+    );
+    expect(findOwningProject(projects, 'tools/build.cs')).toBeNull();
+  });
+
+  it('tags chunks with project and namespace metadata', async () => {
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+
+    const raw = await fs.readFile(
+      path.join(tempDir, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+      'utf-8'
+    );
+    const chunks = (JSON.parse(raw) as { chunks: CodeChunk[] }).chunks;
+    const byFile = (suffix: string) =>
+      chunks.filter((chunk) => chunk.relativePath.replace(/\\/g, '/').endsWith(suffix));
+
+    const controller = byFile('OrdersController.cs');
+    expect(controller.length).toBeGreaterThan(0);
+    expect(controller.every((chunk) => chunk.metadata.dotnetProject === 'Acme.Api')).toBe(true);
+    expect(controller.some((chunk) => chunk.metadata.namespace === 'Acme.Api.Controllers')).toBe(
+      true
+    );
+    expect(byFile('Orders.cs').every((c) => c.metadata.dotnetProject === 'Acme.Core')).toBe(true);
+    expect(byFile('build.cs').every((c) => c.metadata.dotnetProject === undefined)).toBe(true);
+  });
+});
@@ -0,0 +1,179 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import {
+  EmbeddingRequestError,
+  embedInBatches,
+  parseRetryAfter
+} from '../src/embeddings/batching.js';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const fake = vi.hoisted(() => ({ texts: [] as string[], failOnCall: 0, calls: 0 }));
+
+vi.mock('../src/embeddings/index.js', async (importOriginal) => {
+  const original = await importOriginal<typeof import('../src/embeddings/index.js')>();
+  const provider = {
+    name: 'fake',
+    modelName: 'fake-model',
+    dimensions: 2,
+    initialize: async () => {},
+    isReady: () => true,
+    embed: async (text: string) => [text.length, 1],
+    embedBatch: async (texts: string[]) => {
+      fake.calls++;
+      if (fake.calls === fake.failOnCall) {
+        throw new original.EmbeddingRequestError('invalid key', 401);
+      }
+      fake.texts.push(...texts);
+      return texts.map((text) => [text.length, 1]);
+    }
+  };
+  return { ...original, getEmbeddingProvider: async () => provider };
+});
+
+const noSleep = { sleep: async () => {}, random: () => 1 };
+
+describe('embedInBatches', () => {
+  beforeEach(() => {
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+  });
+
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  it('retries rate limits with exponential backoff and honours Retry-After', async () => {
+    const delays: number[] = [];
+    let attempts = 0;
+    const provider = {
+      embedBatch: async (texts: string[]) => {
+        attempts++;
+        if (attempts === 1) throw new EmbeddingRequestError('rate limited', 429);
+        if (attempts === 2) throw new EmbeddingRequestError('rate limited', 429, 4000);
+        if (attempts === 3) throw new EmbeddingRequestError('bad gateway', 502);
+        return texts.map(() => [1]);
+      }
+    };
+
+    const batches: number[] = [];
+    await embedInBatches(provider, ['a', 'b'], {
+      batchSize: 2,
+      concurrency: 1,
+      maxRetries: 3,
+      baseDelayMs: 100,
+      ...noSleep,
+      sleep: async (ms) => {
+        delays.push(ms);
+      },
+      onBatch: (offset) => {
+        batches.push(offset);
+      }
+    });
+
+    expect(delays).toEqual([100, 4000, 400]);
+    expect(batches).toEqual([0]);
+  });
+
+  it('fails fast on non-retryable errors and after the retry budget', async () => {
+    let calls = 0;
+    const unauthorized = {
+      embedBatch: async () => {
+        calls++;
+        throw new EmbeddingRequestError('invalid key', 401);
+      }
+    };
+    const options = { batchSize: 1, concurrency: 1, maxRetries: 2, onBatch: () => {}, ...noSleep };
+
+    await expect(embedInBatches(unauthorized, ['a'], options)).rejects.toThrow('invalid key');
+    expect(calls).toBe(1);
+
+    calls = 0;
+    const throttled = {
+      embedBatch: async () => {
+        calls++;
+        throw new EmbeddingRequestError('rate limited', 429);
+      }
+    };
+    await expect(embedInBatches(throttled, ['a'], options)).rejects.toThrow('rate limited');
+    expect(calls).toBe(3);
+  });
+
+  it('keeps at most `concurrency` batches in flight and reports each one', async () => {
+    let inFlight = 0;
+    let peak = 0;
+    const provider = {
+      embedBatch: async (texts: string[]) => {
+        inFlight++;
+        peak = Math.max(peak, inFlight);
+        await new Promise((resolve) => setTimeout(resolve, 5));
+        inFlight--;
+        return texts.map((t) => [Number(t)]);
+      }
+    };
+
+    const received = new Map<number, number[][]>();
+    await embedInBatches(provider, ['0', '1', '2', '3', '4', '5', '6'], {
+      batchSize: 2,
+      concurrency: 2,
+      maxRetries: 0,
+      onBatch: (offset, embeddings) => {
+        received.set(offset, embeddings);
+      }
+    });
+
+    expect(peak).toBe(2);
+    expect([...received.keys()].sort((a, b) => a - b)).toEqual([0, 2, 4, 6]);
+    expect(received.get(6)).toEqual([[6]]);
+  });
+
+  it('parses Retry-After seconds, dates and retry-after-ms', () => {
+    const now = Date.parse('2026-01-01T00:00:00Z');
+    expect(parseRetryAfter(new Headers({ 'retry-after': '3' }), now)).toBe(3000);
+    expect(parseRetryAfter(new Headers({ 'retry-after-ms': '250' }), now)).toBe(250);
+    expect(
+      parseRetryAfter(new Headers({ 'retry-after': 'Thu, 01 Jan 2026 00:00:10 GMT' }), now)
+    ).toBe(10_000);
+    expect(parseRetryAfter(new Headers(), now)).toBeUndefined();
+  });
+});
+
+describe('indexer embedding resumption', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    Object.assign(fake, { texts: [], failOnCall: 0, calls: 0 });
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'embedding-resume-test-'));
+    await fs.mkdir(path.join(tempDir, 'src'), { recursive: true });
+    for (const name of ['alpha', 'beta', 'gamma']) {
+      await fs.writeFile(
+        path.join(tempDir, 'src', `${name}.ts`),
+        `export function ${name}() {\n  return '${name}';\n}\n`
+      );
+    }
+  });
+
+  afterEach(async () => {
+    vi.restoreAllMocks();
+    await rmWithRetries(tempDir);
+  });
+
+  it('continues from the last embedded batch after a failed run', async () => {
+    const config = { embedding: { batchSize: 1 } };
+    fake.failOnCall = 2;
+    await expect(new CodebaseIndexer({ rootPath: tempDir, config }).index()).rejects.toThrow(
+      'invalid key'
+    );
+    const embeddedBeforeFailure = [...fake.texts];
+    expect(embeddedBeforeFailure).toHaveLength(1);
+
+    fake.texts = [];
+    const stats = await new CodebaseIndexer({ rootPath: tempDir, config }).index();
+
+    expect(stats.embeddingCache?.hits).toBe(1);
+    expect(fake.texts).not.toContain(embeddedBeforeFailure[0]);
+    expect(fake.texts.length).toBe(stats.totalChunks - 1);
+  });
+});
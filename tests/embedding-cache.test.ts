@@ -0,0 +1,107 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { EmbeddingCache, hashEmbeddingInput } from '../src/core/embedding-cache.js';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const embedded = vi.hoisted(() => ({ texts: [] as string[] }));
+
+vi.mock('../src/embeddings/index.js', async (importOriginal) => {
+  const original = await importOriginal<typeof import('../src/embeddings/index.js')>();
+  const provider = {
+    name: 'fake',
+    modelName: 'fake-model',
+    dimensions: 4,
+    initialize: async () => {},
+    isReady: () => true,
+    embed: async (text: string) => [text.length, 1, 0, 0],
+    embedBatch: async (texts: string[]) => {
+      embedded.texts.push(...texts);
+      return texts.map((text) => [text.length, 1, 0, 0]);
+    }
+  };
+  return { ...original, getEmbeddingProvider: async () => provider };
+});
+
+describe('EmbeddingCache', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'embedding-cache-test-'));
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('round-trips vectors and ignores caches from another model', async () => {
+    const cache = await EmbeddingCache.load(tempDir, 'fake:a', 3);
+    cache.set(hashEmbeddingInput('hello'), [0.5, -1, 2]);
+    await cache.save({ prune: false });
+
+    const reloaded = await EmbeddingCache.load(tempDir, 'fake:a', 3);
+    expect(reloaded.get(hashEmbeddingInput('hello'))).toEqual([0.5, -1, 2]);
+    expect(reloaded.get(hashEmbeddingInput('other'))).toBeNull();
+    expect([reloaded.hits, reloaded.misses]).toEqual([1, 1]);
+
+    expect((await EmbeddingCache.load(tempDir, 'fake:b', 3)).size).toBe(0);
+  });
+
+  it('prunes entries that were not used in a full run', async () => {
+    const cache = await EmbeddingCache.load(tempDir, 'fake:a', 1);
+    cache.set('keep', [1]);
+    cache.set('drop', [2]);
+    await cache.save({ prune: false });
+
+    const next = await EmbeddingCache.load(tempDir, 'fake:a', 1);
+    next.get('keep');
+    await next.save({ prune: true });
+
+    const pruned = await EmbeddingCache.load(tempDir, 'fake:a', 1);
+    expect(pruned.size).toBe(1);
+    expect(pruned.get('keep')).toEqual([1]);
+  });
+});
+
+describe('indexer embedding reuse', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    embedded.texts = [];
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'embedding-reuse-test-'));
+    await fs.mkdir(path.join(tempDir, 'src'), { recursive: true });
+    for (const name of ['alpha', 'beta']) {
+      await fs.writeFile(
+        path.join(tempDir, 'src', `${name}.ts`),
+        `export function ${name}() {\n  return '${name}';\n}\n`
+      );
+    }
+  });
+
+  afterEach(async () => {
+    vi.restoreAllMocks();
+    await rmWithRetries(tempDir);
+  });
+
+  it('only re-embeds chunks whose content changed on a full re-index', async () => {
+    await new CodebaseIndexer({ rootPath: tempDir }).index();
+    const firstRun = embedded.texts.length;
+    expect(firstRun).toBeGreaterThan(0);
+
+    embedded.texts = [];
+    const unchanged = await new CodebaseIndexer({ rootPath: tempDir }).index();
+    expect(embedded.texts).toEqual([]);
+    expect(unchanged.embeddingCache).toEqual({ hits: firstRun, misses: 0 });
+
+    await fs.writeFile(
+      path.join(tempDir, 'src', 'beta.ts'),
+      "export function beta() {\n  return 'changed';\n}\n"
+    );
+    await new CodebaseIndexer({ rootPath: tempDir }).index();
+    expect(embedded.texts.length).toBeGreaterThan(0);
+    expect(embedded.texts.every((text) => text.includes('beta'))).toBe(true);
+  });
+});
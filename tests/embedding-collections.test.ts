@@ -0,0 +1,178 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import {
+  buildEmbeddingCollection,
+  loadEmbeddingCollectionSpecs,
+  readEmbeddingCollection
+} from '../src/core/embedding-collections.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INDEX_META_FILENAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  PROJECT_CONFIG_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const embedded = vi.hoisted(() => ({ byModel: {} as Record<string, string[]> }));
+
+// One fake provider per configured model, so the index and a collection use different ones
+vi.mock('../src/embeddings/index.js', async (importOriginal) => {
+  const original = await importOriginal<typeof import('../src/embeddings/index.js')>();
+  return {
+    ...original,
+    getEmbeddingProvider: async (config: { provider?: string; model?: string } = {}) => {
+      const modelName = config.model ?? original.DEFAULT_MODEL;
+      return {
+        name: config.provider ?? 'transformers',
+        modelName,
+        dimensions: 3,
+        initialize: async () => {},
+        isReady: () => true,
+        embed: async (text: string) => [text.length, 1, 0],
+        embedBatch: async (texts: string[]) => {
+          (embedded.byModel[modelName] ??= []).push(...texts);
+          return texts.map((text) => [text.length, 1, 0]);
+        }
+      };
+    }
+  };
+});
+
+describe('embedding collections', () => {
+  let tempRoot: string;
+  let contextDir: string;
+  let ctx: ToolContext;
+
+  beforeEach(async () => {
+    embedded.byModel = {};
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+    vi.spyOn(console, 'warn').mockImplementation(() => {});
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'embedding-collections-test-'));
+    contextDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    for (const [dir, name] of [
+      ['src', 'billing'],
+      ['src', 'invoices'],
+      ['scripts', 'deploy']
+    ]) {
+      await fs.mkdir(path.join(tempRoot, dir), { recursive: true });
+      await fs.writeFile(
+        path.join(tempRoot, dir, `${name}.ts`),
+        `export function ${name}() {\n  return '${name}';\n}\n`
+      );
+    }
+    await fs.mkdir(contextDir, { recursive: true });
+    await fs.writeFile(
+      path.join(contextDir, PROJECT_CONFIG_FILENAME),
+      JSON.stringify({
+        embeddingCollections: {
+          trial: { provider: 'voyage', model: 'trial-model', include: ['src'] },
+          'bad name': { provider: 'voyage' },
+          unknown: { provider: 'nope' }
+        }
+      })
+    );
+    await new CodebaseIndexer({ rootPath: tempRoot }).index();
+
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir: contextDir,
+        memory: path.join(contextDir, MEMORY_FILENAME),
+        intelligence: path.join(contextDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(contextDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(contextDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    vi.restoreAllMocks();
+    await rmWithRetries(tempRoot);
+  });
+
+  it('reads valid declarations only', async () => {
+    expect(await loadEmbeddingCollectionSpecs(tempRoot)).toEqual({
+      trial: { embedding: { provider: 'voyage', model: 'trial-model' }, include: ['src'] }
+    });
+  });
+
+  it('embeds the covered part of the index with the collection model', async () => {
+    const meta = await buildEmbeddingCollection({ rootPath: tempRoot, name: 'trial' });
+    expect(meta).toMatchObject({
+      name: 'trial',
+      embedding: { provider: 'voyage', model: 'trial-model', dimensions: 3 },
+      files: 2
+    });
+    expect(meta.chunks).toBeGreaterThan(0);
+    expect(embedded.byModel['trial-model'].every((text) => text.includes('src/'))).toBe(true);
+    expect(await readEmbeddingCollection(contextDir, 'trial')).toEqual(meta);
+
+    embedded.byModel = {};
+    await buildEmbeddingCollection({ rootPath: tempRoot, name: 'trial' });
+    expect(embedded.byModel['trial-model']).toBeUndefined();
+  });
+
+  it('follows index builds once built', async () => {
+    await buildEmbeddingCollection({ rootPath: tempRoot, name: 'trial' });
+    embedded.byModel = {};
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'invoices.ts'),
+      "export function invoices() {\n  return 'changed';\n}\n"
+    );
+
+    await new CodebaseIndexer({ rootPath: tempRoot }).index();
+    const trialTexts = embedded.byModel['trial-model'];
+    expect(trialTexts.length).toBeGreaterThan(0);
+    expect(trialTexts.every((text) => text.includes('invoices'))).toBe(true);
+    const meta = await readEmbeddingCollection(contextDir, 'trial');
+    const indexMeta = JSON.parse(
+      await fs.readFile(path.join(contextDir, INDEX_META_FILENAME), 'utf-8')
+    );
+    expect(meta?.sourceBuildId).toBe(indexMeta.buildId);
+  });
+
+  it('selects the collection per query', async () => {
+    const search = async (collection: string) => {
+      const result = await dispatchTool(
+        'search_codebase',
+        { query: 'billing', mode: 'semantic', rerank: 'off', limit: 10, collection },
+        ctx
+      );
+      return { isError: result.isError, payload: JSON.parse(result.content![0].text) };
+    };
+
+    const missing = await search('trial');
+    expect(missing.isError).toBe(true);
+    expect(missing.payload).toMatchObject({
+      errorCode: 'collection_unavailable',
+      collection: 'trial'
+    });
+
+    await dispatchTool('refresh_index', { collection: 'trial' }, { ...ctx, progress: {} });
+    const found = await search('trial');
+    expect(found.payload.status).toBe('success');
+    expect(found.payload.collection).toMatchObject({
+      name: 'trial',
+      provider: 'voyage',
+      model: 'trial-model',
+      stale: false
+    });
+    const files: string[] = found.payload.results.map((r: { file: string }) => r.file);
+    expect(files.length).toBeGreaterThan(0);
+    expect(files.some((file) => file.includes('billing'))).toBe(true);
+    expect(files.some((file) => file.includes('deploy'))).toBe(false);
+
+    const undeclared = await dispatchTool('refresh_index', { collection: 'other' }, ctx);
+    expect(JSON.parse(undeclared.content![0].text).errorCode).toBe('collection_not_declared');
+  });
+});
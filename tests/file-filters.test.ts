@@ -0,0 +1,136 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { CodebaseSearcher } from '../src/core/search.js';
+import {
+  matchesFileFilters,
+  pushdownFileScope,
+  resolveFileScope
+} from '../src/core/file-filters.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME
+} from '../src/constants/codebase-context.js';
+import type { CodeChunk } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+function chunk(relativePath: string, metadata: CodeChunk['metadata'] = {}): CodeChunk {
+  return {
+    id: relativePath,
+    content: '',
+    filePath: `/repo/${relativePath}`,
+    relativePath,
+    startLine: 1,
+    endLine: 1,
+    language: 'typescript',
+    dependencies: [],
+    imports: [],
+    exports: [],
+    tags: [],
+    metadata
+  };
+}
+
+describe('matchesFileFilters', () => {
+  it('applies path, test, size and date filters per file', () => {
+    const file = chunk('src/core/cart.ts', { fileSize: 500, lastModified: '2024-06-01T00:00:00Z' });
+
+    expect(matchesFileFilters(file, { path: 'src/core' })).toBe(true);
+    expect(matchesFileFilters(file, { path: './src/core/' })).toBe(true);
+    expect(matchesFileFilters(file, { path: 'src/co' })).toBe(false);
+    expect(matchesFileFilters(file, { path: 'src/**/*.ts' })).toBe(true);
+    expect(matchesFileFilters(chunk('src/cart.test.ts'), { excludeTests: true })).toBe(false);
+    expect(matchesFileFilters(chunk('docs/adr/0001.md'), { docsOnly: true })).toBe(true);
+    expect(matchesFileFilters(file, { docsOnly: true })).toBe(false);
+    expect(matchesFileFilters(chunk('README.MD'), { codeOnly: true })).toBe(false);
+    expect(matchesFileFilters(file, { minFileSize: 100, maxFileSize: 500 })).toBe(true);
+    expect(matchesFileFilters(file, { maxFileSize: 499 })).toBe(false);
+    expect(matchesFileFilters(file, { modifiedAfter: '2024-01-01' })).toBe(true);
+    expect(matchesFileFilters(file, { modifiedBefore: '2024-06-01' })).toBe(false);
+    // No recorded date: date filters never match
+    expect(matchesFileFilters(chunk('src/old.ts'), { modifiedAfter: '2000-01-01' })).toBe(false);
+  });
+});
+
+describe('file scope pushdown', () => {
+  const chunks = [
+    chunk('src/a.ts'),
+    chunk('src/a.ts'),
+    chunk('src/b.ts'),
+    chunk('src/b.test.ts'),
+    chunk('lib/c.ts')
+  ];
+
+  it('sends whichever path list is shorter', () => {
+    const narrow = resolveFileScope(chunks, { path: 'lib' });
+    expect(narrow).toEqual({ files: new Set(['lib/c.ts']), excluded: expect.any(Array) });
+    expect(pushdownFileScope({ path: 'lib' }, narrow!).filters.filePaths).toEqual(['lib/c.ts']);
+
+    const broad = resolveFileScope(chunks, { excludeTests: true })!;
+    const pushed = pushdownFileScope({ excludeTests: true }, broad);
+    expect(pushed).toMatchObject({ pushed: true, filters: { excludePaths: ['src/b.test.ts'] } });
+    expect(pushed.filters.filePaths).toBeUndefined();
+  });
+
+  it('returns null without file-level filters', () => {
+    expect(resolveFileScope(chunks, { language: 'typescript' })).toBeNull();
+    expect(resolveFileScope(chunks)).toBeNull();
+  });
+});
+
+describe('file filters in search', () => {
+  let tempRoot: string;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'file-filters-test-'));
+    await fs.mkdir(path.join(tempRoot, 'src', 'billing'), { recursive: true });
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'billing', 'invoice.ts'),
+      'export function renderInvoice(total: number) {\n  return `invoice ${total}`;\n}\n'
+    );
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'billing', 'invoice.test.ts'),
+      "import { renderInvoice } from './invoice';\nexport const invoiceCase = renderInvoice(1);\n"
+    );
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'legacy-invoice.ts'),
+      'export function legacyInvoice() {\n  return "invoice";\n}\n'
+    );
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('records file size and modification time on chunks', async () => {
+    const raw = await fs.readFile(
+      path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+      'utf-8'
+    );
+    const chunks = (JSON.parse(raw) as { chunks: CodeChunk[] }).chunks;
+    expect(chunks.length).toBeGreaterThan(0);
+    for (const indexed of chunks) {
+      expect(indexed.metadata.fileSize).toBeGreaterThan(0);
+      expect(Number.isNaN(Date.parse(indexed.metadata.lastModified ?? ''))).toBe(false);
+    }
+  });
+
+  it('scopes keyword results by path and excludes tests', async () => {
+    const searcher = new CodebaseSearcher(tempRoot);
+    const options = { useSemanticSearch: false, useKeywordSearch: true, enableReranker: false };
+
+    const scoped = await searcher.search('invoice', 10, { path: 'src/billing' }, options);
+    expect(scoped.length).toBeGreaterThan(0);
+    expect(scoped.every((r) => r.filePath.replace(/\\/g, '/').includes('/billing/'))).toBe(true);
+
+    const noTests = await searcher.search('invoice', 10, { excludeTests: true }, options);
+    expect(noTests.length).toBeGreaterThan(0);
+    expect(noTests.some((r) => r.filePath.endsWith('.test.ts'))).toBe(false);
+
+    const none = await searcher.search('invoice', 10, { modifiedBefore: '2000-01-01' }, options);
+    expect(none).toEqual([]);
+  });
+});
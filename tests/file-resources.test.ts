@@ -0,0 +1,92 @@
+import { describe, it, expect, beforeAll, afterAll } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { paginate } from '../src/core/file-resources.js';
+import { InvalidCursorError } from '../src/errors/index.js';
+import { listFileResources, readFileResource } from '../src/resources/files.js';
+import { parseFileResourceUri } from '../src/resources/uri.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const CART = [
+  'export class Cart {',
+  '  private items: string[] = [];',
+  '',
+  '  add(item: string) {',
+  '    this.items.push(item);',
+  '    return this.items.length;',
+  '  }',
+  '}',
+  '',
+  'export function emptyCart() {',
+  '  return new Cart();',
+  '}',
+  ''
+].join('\n');
+
+async function read(rootPath: string, uri: string) {
+  const query = parseFileResourceUri(uri);
+  if (!query) throw new Error(`not a file URI: ${uri}`);
+  return readFileResource(rootPath, uri, query);
+}
+
+describe('paginate', () => {
+  it('pages with opaque cursors and rejects foreign ones', () => {
+    const items = Array.from({ length: 5 }, (_, i) => i);
+    const first = paginate(items, undefined, 2);
+    expect(first.items).toEqual([0, 1]);
+    const second = paginate(items, first.nextCursor, 2);
+    const last = paginate(items, second.nextCursor, 2);
+    expect([second.items, last.items]).toEqual([[2, 3], [4]]);
+    expect(last.nextCursor).toBeUndefined();
+    expect(() => paginate(items, 'not-a-cursor', 2)).toThrow(InvalidCursorError);
+  });
+});
+
+describe('repo:// resources', () => {
+  let tempDir: string;
+
+  beforeAll(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'file-resources-'));
+    await fs.mkdir(path.join(tempDir, 'src'), { recursive: true });
+    await fs.writeFile(path.join(tempDir, 'src', 'cart.ts'), CART);
+    await fs.writeFile(path.join(tempDir, 'notes.bin'), 'not indexed');
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+  });
+
+  afterAll(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('lists indexed files and reads their content', async () => {
+    const { resources, nextCursor } = await listFileResources(tempDir);
+    expect(resources).toEqual([
+      { uri: 'repo://src/cart.ts', name: 'src/cart.ts', mimeType: 'text/plain' }
+    ]);
+    expect(nextCursor).toBeUndefined();
+
+    const [file] = await read(tempDir, 'repo://src/cart.ts');
+    expect(file.text).toBe(CART);
+  });
+
+  it('lists chunks with URIs and reads one by its line range', async () => {
+    const [listing] = await read(tempDir, 'repo://src/cart.ts?chunks');
+    const parsed = JSON.parse(listing.text) as {
+      total: number;
+      chunks: Array<{ uri: string; lines: string; symbol?: string }>;
+    };
+    expect(listing.mimeType).toBe('application/json');
+    expect(parsed.chunks.length).toBe(parsed.total);
+
+    const emptyCart = parsed.chunks.find((c) => c.symbol === 'emptyCart');
+    expect(emptyCart?.lines).toBe('10-12');
+    const [chunk] = await read(tempDir, emptyCart?.uri ?? '');
+    expect(chunk.text).toBe('export function emptyCart() {\n  return new Cart();\n}');
+  });
+
+  it('refuses files outside the index', async () => {
+    await expect(read(tempDir, 'repo://notes.bin')).rejects.toThrow('Not an indexed file');
+    await expect(read(tempDir, 'repo://src/cart.ts?chunk=99')).rejects.toThrow('No chunk 99');
+  });
+});
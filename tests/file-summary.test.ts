@@ -0,0 +1,161 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { leadingDocComment, summarizeFile } from '../src/core/file-summary.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import type { Sampler } from '../src/types/index.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  FILE_SUMMARIES_FILENAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const CONFIG_SOURCE = [
+  '/**',
+  ' * Loads server settings from the environment. Falls back to defaults.',
+  ' */',
+  "import { z } from 'zod';",
+  '',
+  'export interface ServerConfig {',
+  '  port: number;',
+  '}',
+  '',
+  '/** Read and validate the current settings. */',
+  'export function loadConfig(): ServerConfig {',
+  '  return { port: Number(process.env.PORT ?? 8080) };',
+  '}',
+  ''
+].join('\n');
+
+describe('leadingDocComment', () => {
+  it('reads the first sentence of block, line and docstring comments', () => {
+    expect(leadingDocComment(['/**', ' * Parses flags. More.', ' */'])).toBe('Parses flags.');
+    expect(leadingDocComment(['#!/usr/bin/env node', '// Entry point', 'main();'])).toBe(
+      'Entry point'
+    );
+    expect(leadingDocComment(['"""Sync jobs for billing."""', 'import os'])).toBe(
+      'Sync jobs for billing.'
+    );
+    expect(leadingDocComment(['#include <stdio.h>'])).toBeUndefined();
+  });
+});
+
+describe('summarize_file', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'file-summary-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.writeFile(path.join(tempRoot, 'src', 'config.ts'), CONFIG_SOURCE);
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'server.ts'),
+      [
+        "import { loadConfig } from './config.js';",
+        '',
+        'export function start() {',
+        '  return loadConfig().port;',
+        '}',
+        ''
+      ].join('\n')
+    );
+
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('summarizes structure from the index without sampling', async () => {
+    const result = await dispatchTool('summarize_file', { path: './src/config.ts' }, ctx);
+    const payload = JSON.parse(result.content![0].text);
+
+    expect(payload).toMatchObject({
+      status: 'success',
+      file: 'src/config.ts',
+      language: 'typescript',
+      source: 'static',
+      purpose: 'Loads server settings from the environment.',
+      dependencies: { files: [], packages: ['zod'] },
+      usedBy: { total: 1, files: ['src/server.ts'] }
+    });
+    expect(payload.exports).toEqual(expect.arrayContaining(['ServerConfig', 'loadConfig']));
+    expect(payload.symbols).toContainEqual({ name: 'loadConfig', kind: 'function', line: 11 });
+    expect(payload.responsibilities).toContain(
+      'loadConfig (function): Read and validate the current settings.'
+    );
+  });
+
+  it('caches sampled summaries until the file changes', async () => {
+    const sample = vi.fn<Sampler>(async () =>
+      JSON.stringify({ purpose: 'Server settings.', responsibilities: ['Read PORT'] })
+    );
+
+    const first = await summarizeFile(tempRoot, 'src/config.ts', { sample });
+    expect(first).toMatchObject({
+      source: 'sampling',
+      cached: false,
+      purpose: 'Server settings.',
+      responsibilities: ['Read PORT']
+    });
+    expect(sample.mock.calls[0][0].prompt).toContain('export function loadConfig');
+
+    const second = await summarizeFile(tempRoot, 'src/config.ts', { sample });
+    expect(second).toMatchObject({ source: 'sampling', cached: true, purpose: 'Server settings.' });
+    expect(sample).toHaveBeenCalledTimes(1);
+
+    const cacheFile = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME, FILE_SUMMARIES_FILENAME);
+    expect(JSON.parse(await fs.readFile(cacheFile, 'utf-8')).files['src/config.ts'].hash).toBe(
+      first.hash
+    );
+
+    await fs.writeFile(path.join(tempRoot, 'src', 'config.ts'), `${CONFIG_SOURCE}// edited\n`);
+    const third = await summarizeFile(tempRoot, 'src/config.ts', { sample });
+    expect(third.cached).toBe(false);
+    expect(third.hash).not.toBe(first.hash);
+    expect(sample).toHaveBeenCalledTimes(2);
+  });
+
+  it('falls back to the static summary when the model reply is unusable', async () => {
+    const summary = await summarizeFile(tempRoot, 'src/config.ts', {
+      sample: async () => 'Sure! This file loads config.'
+    });
+
+    expect(summary.source).toBe('static');
+    expect(summary.purpose).toBe('Loads server settings from the environment.');
+    expect(summary.samplingError).toMatch(/not the requested JSON/);
+  });
+
+  it('rejects paths outside the project and reports missing files', async () => {
+    const outside = await dispatchTool('summarize_file', { path: '../etc/passwd' }, ctx);
+    expect(outside.isError).toBe(true);
+
+    const missing = await dispatchTool('summarize_file', { path: 'src/nope.ts' }, ctx);
+    expect(JSON.parse(missing.content![0].text)).toMatchObject({
+      status: 'error',
+      message: 'File not found: src/nope.ts'
+    });
+  });
+});
@@ -159,4 +159,35 @@ describe('FileWatcher', () => {
       stop();
     }
   }, 5000);
+
+  it('reports the changed paths collected during the debounce window', async () => {
+    const debounceMs = 400;
+    const reported: string[][] = [];
+
+    let resolveReady!: () => void;
+    const ready = new Promise<void>((resolve) => {
+      resolveReady = resolve;
+    });
+
+    const stop = startFileWatcher({
+      rootPath: tempDir,
+      debounceMs,
+      onReady: () => resolveReady(),
+      onChanged: (changedPaths) => {
+        reported.push(changedPaths);
+      }
+    });
+
+    try {
+      await ready;
+      await fs.writeFile(path.join(tempDir, 'one.ts'), 'export const one = 1;');
+      await fs.writeFile(path.join(tempDir, 'two.ts'), 'export const two = 2;');
+      await new Promise((resolve) => setTimeout(resolve, debounceMs + 1000));
+      expect(reported).toHaveLength(1);
+      expect(reported[0].map((p) => path.basename(p)).sort()).toEqual(['one.ts', 'two.ts']);
+      expect(reported[0].every((p) => path.isAbsolute(p))).toBe(true);
+    } finally {
+      stop();
+    }
+  }, 8000);
 });
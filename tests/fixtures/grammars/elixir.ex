@@ -0,0 +1,5 @@
+defmodule Calculator do
+  def add(value, n) do
+    value + n
+  end
+end
@@ -0,0 +1,10 @@
+package com.example
+
+class Calculator(private var value: Int) {
+    fun add(n: Int): Int {
+        value += n
+        return value
+    }
+}
+
+fun newCalculator(initial: Int): Calculator = Calculator(initial)
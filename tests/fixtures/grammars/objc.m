@@ -0,0 +1,10 @@
+#import "Calculator.h"
+
+@implementation Calculator
+
+- (NSInteger)add:(NSInteger)n {
+    self.value += n;
+    return self.value;
+}
+
+@end
@@ -0,0 +1,12 @@
+<?php
+
+class Calculator
+{
+    private int $value = 0;
+
+    public function add(int $n): int
+    {
+        $this->value += $n;
+        return $this->value;
+    }
+}
@@ -0,0 +1,9 @@
+class Calculator
+  def initialize(value)
+    @value = value
+  end
+
+  def add(n)
+    @value += n
+  end
+end
@@ -0,0 +1,8 @@
+package calc
+
+class Calculator(private var value: Int) {
+  def add(n: Int): Int = {
+    value += n
+    value
+  }
+}
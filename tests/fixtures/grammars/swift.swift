@@ -0,0 +1,12 @@
+class Calculator {
+    private var value: Int
+
+    init(value: Int) {
+        self.value = value
+    }
+
+    func add(_ n: Int) -> Int {
+        value += n
+        return value
+    }
+}
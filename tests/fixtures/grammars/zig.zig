@@ -0,0 +1,8 @@
+const Calculator = struct {
+    value: i32,
+
+    pub fn add(self: *Calculator, n: i32) i32 {
+        self.value += n;
+        return self.value;
+    }
+};
@@ -0,0 +1,212 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import {
+  joinFleetReferences,
+  linkProjects,
+  projectDependents,
+  readProjectPackages
+} from '../src/core/fleet-graph.js';
+import { parseCargoDependencies, parseGoRequires } from '../src/utils/workspace-detection.js';
+import { rmWithRetries } from './test-helpers.js';
+
+type CallToolHandler = (request: {
+  params: { name: string; arguments?: Record<string, unknown> };
+}) => Promise<{ content?: Array<{ text: string }>; isError?: boolean }>;
+
+async function writeFiles(root: string, files: Record<string, string>): Promise<void> {
+  for (const [file, content] of Object.entries(files)) {
+    await fs.mkdir(path.dirname(path.join(root, file)), { recursive: true });
+    await fs.writeFile(path.join(root, file), content);
+  }
+}
+
+describe('fleet graph', () => {
+  it('reads Go requires and Cargo dependencies', () => {
+    const goMod = [
+      'module github.com/acme/orders',
+      '',
+      'require github.com/acme/billing v1.4.0',
+      'require (',
+      '\tgithub.com/acme/auth v0.3.1 // indirect',
+      '\tgolang.org/x/sync v0.7.0',
+      ')'
+    ].join('\n');
+    expect(parseGoRequires(goMod)).toEqual([
+      'github.com/acme/auth',
+      'golang.org/x/sync',
+      'github.com/acme/billing'
+    ]);
+
+    const cargo = [
+      '[package]',
+      'name = "orders"',
+      '',
+      '[dependencies]',
+      'serde = "1"',
+      'billing_client = { path = "../billing" }',
+      '',
+      '[dev-dependencies.tokio]',
+      'version = "1"'
+    ].join('\n');
+    expect(parseCargoDependencies(cargo)).toEqual(['serde', 'billing_client', 'tokio']);
+  });
+
+  it('reads what a project publishes and depends on from its manifests', async () => {
+    const root = await fs.mkdtemp(path.join(os.tmpdir(), 'fleet-packages-'));
+    try {
+      await writeFiles(root, {
+        'go.mod': 'module github.com/acme/orders\n\nrequire github.com/acme/billing v1.4.0\n',
+        'web/package.json': JSON.stringify({
+          name: '@acme/orders-web',
+          dependencies: { '@acme/billing-client': '^1.0.0', '@acme/orders-ui': '*' }
+        }),
+        'ui/package.json': JSON.stringify({ name: '@acme/orders-ui' }),
+        'package.json': JSON.stringify({ private: true, workspaces: ['web', 'ui'] })
+      });
+
+      expect(await readProjectPackages(root)).toEqual({
+        publishes: ['@acme/orders-ui', '@acme/orders-web', 'github.com/acme/orders'],
+        dependsOn: ['@acme/billing-client', 'github.com/acme/billing']
+      });
+    } finally {
+      await rmWithRetries(root);
+    }
+  });
+
+  it('links projects through the packages they publish and depend on', () => {
+    const graph = linkProjects({
+      billing: { publishes: ['@acme/billing-client'], dependsOn: [] },
+      orders: { publishes: ['@acme/orders'], dependsOn: ['@acme/billing-client', 'zod'] },
+      gateway: { publishes: [], dependsOn: ['@acme/orders'] },
+      docs: { publishes: [], dependsOn: ['react'] }
+    });
+
+    expect(graph.projects.orders.dependsOn).toEqual([
+      { project: 'billing', packages: ['@acme/billing-client'] }
+    ]);
+    expect(graph.projects.docs.dependsOn).toEqual([]);
+    expect(projectDependents(graph, 'billing')).toEqual(['gateway', 'orders']);
+    expect(projectDependents(graph, 'docs')).toEqual([]);
+  });
+
+  it('keeps references from projects linked to a definition', () => {
+    const graph = linkProjects({
+      billing: { publishes: ['github.com/acme/billing'], dependsOn: [] },
+      orders: { publishes: [], dependsOn: ['github.com/acme/billing/invoice'] },
+      docs: { publishes: [], dependsOn: [] }
+    });
+    const perProject = [
+      { project: 'orders', definitions: [], references: [{ location: 'main.go:9' }] },
+      { project: 'docs', definitions: [], references: [{ location: 'demo.go:3' }] },
+      {
+        project: 'billing',
+        definitions: ['invoice/invoice.go:4'],
+        references: [{ location: 'invoice/invoice.go:4' }]
+      }
+    ];
+
+    expect(joinFleetReferences(graph, perProject, 10)).toEqual({
+      definedIn: ['billing'],
+      references: [
+        { location: 'invoice/invoice.go:4', project: 'billing' },
+        { location: 'main.go:9', project: 'orders' }
+      ],
+      unlinked: [{ project: 'docs', references: 1 }]
+    });
+
+    // Nobody defines it: a third-party name, so every project's hits count
+    const external = perProject.map((entry) => ({ ...entry, definitions: [] }));
+    expect(joinFleetReferences(graph, external, 2).references).toHaveLength(2);
+    expect(joinFleetReferences(graph, external, 10).unlinked).toEqual([]);
+  });
+});
+
+describe('find_references across projects', () => {
+  let rootA: string;
+  let rootB: string;
+  let rootC: string;
+  let originalArgv: string[];
+  let originalEnv: { root?: string; roots?: string };
+
+  beforeEach(async () => {
+    vi.resetModules();
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+    rootA = await fs.mkdtemp(path.join(os.tmpdir(), 'fleet-billing-'));
+    rootB = await fs.mkdtemp(path.join(os.tmpdir(), 'fleet-orders-'));
+    rootC = await fs.mkdtemp(path.join(os.tmpdir(), 'fleet-docs-'));
+    await writeFiles(rootA, {
+      'package.json': JSON.stringify({ name: '@acme/billing-client', version: '1.0.0' }),
+      'src/invoice.ts': 'export interface Invoice {\n  total: number;\n}\n'
+    });
+    await writeFiles(rootB, {
+      'package.json': JSON.stringify({
+        name: 'orders-service',
+        dependencies: { '@acme/billing-client': '^1.0.0' }
+      }),
+      'src/checkout.ts': [
+        "import type { Invoice } from '@acme/billing-client';",
+        '',
+        'export function charge(invoice: Invoice) {',
+        '  return invoice.total;',
+        '}',
+        ''
+      ].join('\n')
+    });
+    await writeFiles(rootC, {
+      'package.json': JSON.stringify({ name: 'docs-site', dependencies: { stripe: '^14.0.0' } }),
+      'src/example.ts': "import type { Invoice } from 'stripe';\n\nexport type Sample = Invoice;\n"
+    });
+    for (const rootPath of [rootA, rootB, rootC]) {
+      await new CodebaseIndexer({ rootPath, config: { skipEmbedding: true } }).index();
+    }
+
+    originalArgv = [...process.argv];
+    originalEnv = { root: process.env.CODEBASE_ROOT, roots: process.env.CODEBASE_ROOTS };
+    process.env.CODEBASE_ROOT = rootA;
+    process.argv[2] = rootA;
+    process.env.CODEBASE_ROOTS = [`orders=${rootB}`, `docs=${rootC}`].join(path.delimiter);
+  });
+
+  afterEach(async () => {
+    vi.restoreAllMocks();
+    process.argv = originalArgv;
+    for (const [key, value] of [
+      ['CODEBASE_ROOT', originalEnv.root],
+      ['CODEBASE_ROOTS', originalEnv.roots]
+    ] as const) {
+      if (value === undefined) delete process.env[key];
+      else process.env[key] = value;
+    }
+    for (const root of [rootA, rootB, rootC]) await rmWithRetries(root);
+  });
+
+  async function getCallToolHandler(): Promise<CallToolHandler> {
+    const { server } = await import('../src/index.js');
+    const handlers = (server as unknown as { _requestHandlers: Map<string, CallToolHandler> })
+      ._requestHandlers;
+    return handlers.get('tools/call')!;
+  }
+
+  it('follows a library symbol into the services depending on it', async () => {
+    const handler = await getCallToolHandler();
+    const call = async (name: string, args: Record<string, unknown>) =>
+      JSON.parse((await handler({ params: { name, arguments: args } })).content![0].text);
+
+    const listed = await call('list_projects', {});
+    expect(listed.projects[1]).toMatchObject({
+      name: 'orders',
+      dependsOn: [{ project: path.basename(rootA), packages: ['@acme/billing-client'] }]
+    });
+
+    const payload = await call('find_references', { project: 'all', symbol: 'Invoice' });
+    expect(payload.status).toBe('success');
+    expect(payload.definedIn).toEqual([path.basename(rootA)]);
+    const projects = new Set(payload.references.map((r: { project: string }) => r.project));
+    expect(projects).toEqual(new Set([path.basename(rootA), 'orders']));
+    expect(payload.references[0].project).toBe(path.basename(rootA));
+    expect(payload.unlinked).toEqual([{ project: 'docs', references: expect.any(Number) }]);
+  });
+});
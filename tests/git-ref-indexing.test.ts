@@ -0,0 +1,124 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { execFileSync } from 'child_process';
+import { promises as fs } from 'fs';
+import path from 'path';
+import os from 'os';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { readIndexMeta } from '../src/core/index-meta.js';
+import { getRefContextDir, getRefIndexSlug, matchesGlob } from '../src/utils/git-tree.js';
+import { KEYWORD_INDEX_FILENAME } from '../src/constants/codebase-context.js';
+import type { CodeChunk } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+function git(cwd: string, ...args: string[]): string {
+  return execFileSync(
+    'git',
+    ['-c', 'user.name=test', '-c', 'user.email=test@example.com', ...args],
+    { cwd, encoding: 'utf8' }
+  ).trim();
+}
+
+async function readKeywordChunks(contextDir: string): Promise<CodeChunk[]> {
+  const raw = await fs.readFile(path.join(contextDir, KEYWORD_INDEX_FILENAME), 'utf-8');
+  return (JSON.parse(raw) as { chunks: CodeChunk[] }).chunks;
+}
+
+describe('matchesGlob', () => {
+  it('supports brace expansion and globstar like the working-tree scan', () => {
+    expect(matchesGlob('src/app/user.service.ts', '**/*.{ts,tsx}')).toBe(true);
+    expect(matchesGlob('index.ts', '**/*.{ts,tsx}')).toBe(true);
+    expect(matchesGlob('src/styles.css', '**/*.{ts,tsx}')).toBe(false);
+    expect(matchesGlob('node_modules/lib/index.js', 'node_modules/**')).toBe(true);
+    expect(matchesGlob('src/node_modules.ts', 'node_modules/**')).toBe(false);
+  });
+
+  it('derives distinct directory names for refs that sanitize alike', () => {
+    expect(getRefIndexSlug('feature/a')).not.toBe(getRefIndexSlug('feature-a'));
+    expect(getRefIndexSlug('refs/heads/main')).toMatch(/^main-[0-9a-f]{8}$/);
+  });
+});
+
+describe('Git ref indexing', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'git-ref-index-test-'));
+    git(tempDir, 'init', '-q');
+    git(tempDir, 'checkout', '-q', '-b', 'main');
+    await fs.writeFile(path.join(tempDir, 'service.ts'), 'export function mainOnly() {}\n');
+    git(tempDir, 'add', '-A');
+    git(tempDir, 'commit', '-q', '-m', 'initial');
+
+    git(tempDir, 'checkout', '-q', '-b', 'feature');
+    await fs.writeFile(path.join(tempDir, 'service.ts'), 'export function featureOnly() {}\n');
+    await fs.writeFile(path.join(tempDir, 'extra.ts'), 'export const added = true;\n');
+    git(tempDir, 'add', '-A');
+    git(tempDir, 'commit', '-q', '-m', 'feature work');
+    git(tempDir, 'checkout', '-q', 'main');
+
+    // Uncommitted working-tree edit must not leak into the ref index
+    await fs.writeFile(path.join(tempDir, 'service.ts'), 'export function dirtyEdit() {}\n');
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('indexes blobs from the ref without touching the checkout', async () => {
+    const stats = await new CodebaseIndexer({
+      rootPath: tempDir,
+      ref: 'feature',
+      config: { skipEmbedding: true }
+    }).index();
+
+    expect(stats.indexedFiles).toBe(2);
+
+    const contextDir = getRefContextDir(tempDir, 'feature');
+    const chunks = await readKeywordChunks(contextDir);
+    const content = chunks.map((c) => c.content).join('\n');
+    expect(content).toContain('featureOnly');
+    expect(content).toContain('added');
+    expect(content).not.toContain('dirtyEdit');
+
+    const commit = git(tempDir, 'rev-parse', 'feature');
+    expect(chunks.every((c) => c.metadata.gitRef === 'feature')).toBe(true);
+    expect(chunks.every((c) => c.metadata.gitCommit === commit)).toBe(true);
+
+    const meta = await readIndexMeta(tempDir, contextDir);
+    expect(meta.gitRef).toEqual({ ref: 'feature', commit });
+
+    expect(await fs.readFile(path.join(tempDir, 'service.ts'), 'utf-8')).toContain('dirtyEdit');
+  });
+
+  it('keeps ref indexes alongside the working-tree index', async () => {
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+    await new CodebaseIndexer({
+      rootPath: tempDir,
+      ref: 'main',
+      config: { skipEmbedding: true }
+    }).index();
+    await new CodebaseIndexer({
+      rootPath: tempDir,
+      ref: 'feature',
+      config: { skipEmbedding: true }
+    }).index();
+
+    const working = await readKeywordChunks(path.join(tempDir, '.codebase-context'));
+    const main = await readKeywordChunks(getRefContextDir(tempDir, 'main'));
+    const feature = await readKeywordChunks(getRefContextDir(tempDir, 'feature'));
+
+    expect(working.map((c) => c.content).join('\n')).toContain('dirtyEdit');
+    expect(main.map((c) => c.content).join('\n')).toContain('mainOnly');
+    expect(feature.map((c) => c.content).join('\n')).toContain('featureOnly');
+  });
+
+  it('rejects unknown refs', async () => {
+    await expect(
+      new CodebaseIndexer({
+        rootPath: tempDir,
+        ref: 'does-not-exist',
+        config: { skipEmbedding: true }
+      }).index()
+    ).rejects.toThrow(/not found/);
+  });
+});
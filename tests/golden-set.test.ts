@@ -0,0 +1,191 @@
+import { describe, expect, it, vi } from 'vitest';
+import { readFileSync } from 'fs';
+import path from 'path';
+import { fileURLToPath } from 'url';
+import { CodebaseSearcher } from '../src/core/search.js';
+import type { SearchResult } from '../src/types/index.js';
+import { parseSimpleYaml } from '../src/utils/simple-yaml.js';
+import {
+  compareGoldenRuns,
+  evaluateGoldenSet,
+  formatGoldenComparison,
+  parseGoldenSet,
+  scoreGoldenQuery
+} from '../src/eval/harness.js';
+
+const FIXTURES = path.join(path.dirname(fileURLToPath(import.meta.url)), 'fixtures');
+const ROOT = '/repo';
+
+function result(relativePath: string, symbolName?: string, symbolPath?: string[]): SearchResult {
+  return {
+    filePath: `${ROOT}/${relativePath}`,
+    score: 0.5,
+    metadata: { symbolName, symbolPath }
+  } as SearchResult;
+}
+
+function searcherReturning(byQuery: Record<string, SearchResult[]>): CodebaseSearcher {
+  const searcher = new CodebaseSearcher(ROOT);
+  vi.spyOn(searcher, 'search').mockImplementation(async (query: string) => byQuery[query] ?? []);
+  return searcher;
+}
+
+describe('parseSimpleYaml', () => {
+  it('reads nested mappings, sequences, flow lists, quotes and comments', () => {
+    const parsed = parseSimpleYaml(
+      [
+        'name: demo # trailing comment',
+        'k: 3',
+        'queries:',
+        '  - id: a',
+        '    query: "where: is # this"',
+        "    notes: it's fine",
+        "    expectFiles: [src/a.ts, 'src/b, c.ts']",
+        '    expectSymbols:',
+        '    - login',
+        '  - query: second',
+        '    enabled: true'
+      ].join('\n')
+    );
+    expect(parsed).toEqual({
+      name: 'demo',
+      k: 3,
+      queries: [
+        {
+          id: 'a',
+          query: 'where: is # this',
+          notes: "it's fine",
+          expectFiles: ['src/a.ts', 'src/b, c.ts'],
+          expectSymbols: ['login']
+        },
+        { query: 'second', enabled: true }
+      ]
+    });
+  });
+
+  it('rejects syntax outside the supported subset with a line number', () => {
+    expect(() => parseSimpleYaml('a: 1\n  b: 2')).toThrow('YAML line 2: unexpected indentation');
+    expect(() => parseSimpleYaml('a: |\n  text')).toThrow('unsupported YAML syntax');
+    expect(() => parseSimpleYaml('a: 1\na: 2')).toThrow('duplicate key "a"');
+    expect(() => parseSimpleYaml('\ta: 1')).toThrow('tabs are not allowed');
+  });
+
+  it('builds mappings without a prototype and refuses keys that would reach one', () => {
+    const parsed = parseSimpleYaml('search:\n  mode: hybrid') as Record<string, unknown>;
+    expect(Object.getPrototypeOf(parsed)).toBeNull();
+    expect(Object.getPrototypeOf(parsed.search)).toBeNull();
+    for (const key of ['__proto__', 'constructor', 'prototype', '"__proto__"']) {
+      expect(() => parseSimpleYaml(`a:\n  ${key}:\n    polluted: true`)).toThrow('not allowed');
+    }
+    expect(({} as Record<string, unknown>).polluted).toBeUndefined();
+  });
+});
+
+describe('parseGoldenSet', () => {
+  it('parses the shipped fixture and defaults ids and K', () => {
+    const source = readFileSync(path.join(FIXTURES, 'golden-controlled.yaml'), 'utf-8');
+    const set = parseGoldenSet(source);
+    expect(set.name).toBe('eval-controlled');
+    expect(set.k).toBe(5);
+    expect(set.queries.map((query) => query.id)).toEqual([
+      'next-track',
+      'volume',
+      'attach-token',
+      'saved-albums'
+    ]);
+
+    const json = parseGoldenSet(
+      JSON.stringify({ queries: [{ query: 'q', expectSymbols: 'login' }] })
+    );
+    expect(json.k).toBe(10);
+    expect(json.queries[0]).toMatchObject({ id: '1', expectFiles: [], expectSymbols: ['login'] });
+  });
+
+  it('names the query that is missing its expectations', () => {
+    expect(() => parseGoldenSet('queries:\n  - query: lonely')).toThrow(
+      'Golden set query 1: needs at least one of "expectFiles" or "expectSymbols"'
+    );
+    expect(() => parseGoldenSet('k: 0\nqueries:\n  - query: q\n    expectFiles: [a.ts]')).toThrow(
+      '"k" must be a positive integer'
+    );
+  });
+});
+
+describe('scoreGoldenQuery', () => {
+  const query = {
+    id: 'auth',
+    query: 'token refresh',
+    expectFiles: ['src/auth/auth.service.ts', 'src/http/auth.interceptor.ts'],
+    expectSymbols: ['AuthService.login']
+  };
+
+  it('computes recall over all targets and the rank of the first hit', () => {
+    const scored = scoreGoldenQuery(
+      query,
+      [
+        result('src/player/player-api.ts'),
+        result('src/auth/auth.service.ts', 'login', ['AuthService', 'login']),
+        result('src/http/auth.interceptor.ts')
+      ],
+      2
+    );
+    expect(scored.recall).toBeCloseTo(2 / 3);
+    expect(scored.firstRelevantRank).toBe(2);
+    expect(scored.reciprocalRank).toBe(0.5);
+    expect(scored.missing).toEqual(['src/http/auth.interceptor.ts']);
+  });
+
+  it('does not match a file by a partial path segment', () => {
+    const scored = scoreGoldenQuery(query, [result('src/auth/old-auth.service.ts')], 5);
+    expect(scored.recall).toBe(0);
+    expect(scored.firstRelevantRank).toBeNull();
+  });
+});
+
+describe('evaluateGoldenSet', () => {
+  const set = parseGoldenSet(
+    [
+      'k: 3',
+      'queries:',
+      '  - id: volume',
+      '    query: adjust volume',
+      '    expectSymbols: [setVolume]',
+      '  - id: albums',
+      '    query: saved albums',
+      '    expectFiles: [src/state/album.store.ts]'
+    ].join('\n')
+  );
+
+  it('reports recall@K and MRR and compares two runs per query', async () => {
+    const a = await evaluateGoldenSet({
+      set,
+      label: 'small-chunks',
+      searcher: searcherReturning({
+        'adjust volume': [result('src/player/player-api.ts', 'setVolume')],
+        'saved albums': [result('src/player/player-api.ts'), result('src/state/album.store.ts')]
+      })
+    });
+    expect(a.recallAtK).toBe(1);
+    expect(a.mrr).toBe(0.75);
+
+    const b = await evaluateGoldenSet({
+      set,
+      label: 'large-chunks',
+      searcher: searcherReturning({
+        'adjust volume': [result('src/player/player-api.ts', 'nextTrack')],
+        'saved albums': [result('src/state/album.store.ts')]
+      })
+    });
+    expect(b.recallAtK).toBe(0.5);
+    expect(b.hitRate).toBe(0.5);
+
+    const comparison = compareGoldenRuns(a, b);
+    expect(comparison.recallDelta).toBe(-0.5);
+    expect(comparison.mrrDelta).toBe(-0.25);
+    expect(comparison.queries.map((query) => [query.id, query.change])).toEqual([
+      ['volume', 'worse'],
+      ['albums', 'better']
+    ]);
+    expect(formatGoldenComparison(a, b)).toContain('Better: 1 | Worse: 1 | Same: 0');
+  });
+});
@@ -0,0 +1,141 @@
+import { describe, it, expect, beforeAll, afterAll, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { resolveGrammarDir } from '../src/grammars/manifest.js';
+import {
+  getGrammarPlugin,
+  loadGrammarPlugins,
+  registerGrammarPlugin
+} from '../src/grammars/plugins.js';
+import { GenericAnalyzer } from '../src/analyzers/generic/index.js';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { detectLanguage, isCodeFile } from '../src/utils/language-detection.js';
+import { commentSyntax } from '../src/utils/syntax-highlight.js';
+import { extractTreeSitterSymbols, supportsTreeSitter } from '../src/utils/tree-sitter.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const GRAMMAR_DIR = resolveGrammarDir(new URL('../src/grammars/manifest.ts', import.meta.url).href);
+
+// Starlark is Python syntax, so the bundled Python grammar stands in for a community one
+const STARLARK = [
+  '# Build rules',
+  'def cc_rule(name, srcs):',
+  '    return native.cc_library(name = name, srcs = srcs)',
+  '',
+  'def _impl(ctx):',
+  '    pass',
+  ''
+].join('\n');
+
+describe('grammar plugins', () => {
+  let pluginsDir: string;
+  let tempRoot: string;
+
+  beforeAll(async () => {
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+    vi.spyOn(console, 'warn').mockImplementation(() => {});
+    pluginsDir = await fs.mkdtemp(path.join(os.tmpdir(), 'grammar-plugins-'));
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'grammar-plugins-root-'));
+
+    const starlark = path.join(pluginsDir, 'starlark');
+    await fs.mkdir(starlark);
+    await fs.copyFile(
+      path.join(GRAMMAR_DIR, 'tree-sitter-python.wasm'),
+      path.join(starlark, 'tree-sitter-starlark.wasm')
+    );
+    await fs.writeFile(
+      path.join(starlark, 'grammar.json'),
+      JSON.stringify({
+        language: 'starlark',
+        extensions: ['.star', 'bzl'],
+        wasm: 'tree-sitter-starlark.wasm',
+        symbols: 'tags.scm',
+        chunks: 'chunks.scm',
+        comments: { line: ['#'] }
+      })
+    );
+    await fs.writeFile(
+      path.join(starlark, 'tags.scm'),
+      '(function_definition name: (identifier) @name) @definition.function\n'
+    );
+    await fs.writeFile(path.join(starlark, 'chunks.scm'), '(function_definition) @chunk\n');
+
+    // No wasm: reported and skipped, the others still load
+    const broken = path.join(pluginsDir, 'broken');
+    await fs.mkdir(broken);
+    await fs.writeFile(
+      path.join(broken, 'grammar.json'),
+      JSON.stringify({ language: 'cobol', extensions: ['.cbl'], wasm: 'x.wasm', symbols: 'q' })
+    );
+  });
+
+  afterAll(async () => {
+    vi.restoreAllMocks();
+    await rmWithRetries(pluginsDir);
+    await rmWithRetries(tempRoot);
+  });
+
+  it('loads plugins from a directory and skips broken ones', async () => {
+    const loaded = await loadGrammarPlugins(pluginsDir);
+    expect(loaded.map((plugin) => plugin.language)).toEqual(['starlark']);
+    expect(getGrammarPlugin('starlark')?.extensions).toEqual(['.star', '.bzl']);
+    expect(getGrammarPlugin('cobol')).toBeUndefined();
+    const errors = vi.mocked(console.error).mock.calls.map(([message]) => String(message));
+    expect(errors.some((message) => message.includes('broken'))).toBe(true);
+
+    expect(await loadGrammarPlugins(pluginsDir)).toEqual([]);
+    const starlark = getGrammarPlugin('starlark')!;
+    expect(() => registerGrammarPlugin({ ...starlark, language: 'python' })).toThrow(/bundled/);
+    expect(() => registerGrammarPlugin({ ...starlark, language: 'skylark' })).toThrow(/claimed/);
+  });
+
+  it('detects and parses the plugin language with its queries', async () => {
+    expect(detectLanguage('rules/defs.bzl')).toBe('starlark');
+    expect(detectLanguage('src/app.py')).toBe('python');
+    expect(isCodeFile('BUILD.star')).toBe(true);
+    expect(supportsTreeSitter('starlark')).toBe(true);
+    expect(commentSyntax('starlark')).toEqual({ lineComments: ['#'], blockComments: [] });
+
+    const extracted = await extractTreeSitterSymbols(STARLARK, 'starlark');
+    expect(extracted?.grammarFile).toBe('tree-sitter-starlark.wasm');
+    const symbols = extracted?.symbols.map(({ name, kind, startLine }) => ({
+      name,
+      kind,
+      startLine
+    }));
+    expect(symbols).toEqual([
+      { name: 'cc_rule', kind: 'function', startLine: 2 },
+      { name: '_impl', kind: 'function', startLine: 5 }
+    ]);
+    expect(extracted?.chunkBoundaries?.map((symbol) => symbol.kind)).toEqual(['block', 'block']);
+
+    const analyzed = await new GenericAnalyzer().analyze(
+      path.join(tempRoot, 'defs.bzl'),
+      STARLARK
+    );
+    expect(analyzed.metadata).toMatchObject({
+      chunkStrategy: 'ast-aligned',
+      treeSitterGrammar: 'tree-sitter-starlark.wasm'
+    });
+  });
+
+  it('indexes plugin files with the default include patterns', async () => {
+    await fs.writeFile(path.join(tempRoot, 'defs.bzl'), STARLARK);
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+    const keywordIndex = JSON.parse(
+      await fs.readFile(
+        path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+        'utf-8'
+      )
+    ) as { chunks: Array<{ filePath: string; language: string; content: string }> };
+    const chunks = keywordIndex.chunks.filter((chunk) => chunk.filePath.endsWith('defs.bzl'));
+    expect(chunks.length).toBeGreaterThan(0);
+    expect(chunks.every((chunk) => chunk.language === 'starlark')).toBe(true);
+    expect(chunks.some((chunk) => chunk.content.includes('def cc_rule'))).toBe(true);
+  });
+});
@@ -0,0 +1,159 @@
+import { describe, it, expect, afterEach, vi } from 'vitest';
+import { VoyageEmbeddingProvider } from '../src/embeddings/voyage.js';
+import { CohereEmbeddingProvider } from '../src/embeddings/cohere.js';
+import { AzureOpenAIEmbeddingProvider } from '../src/embeddings/azure-openai.js';
+import { OpenAIEmbeddingProvider } from '../src/embeddings/openai.js';
+import { clipToInputLimit, resolveHostedDimensions } from '../src/embeddings/hosted.js';
+import { resolveEmbeddingModel } from '../src/embeddings/index.js';
+
+function jsonResponse(body: unknown, status = 200): Response {
+  return new Response(JSON.stringify(body), {
+    status,
+    headers: { 'Content-Type': 'application/json' }
+  });
+}
+
+function requestBody(call: unknown[] | undefined): Record<string, unknown> {
+  const init = call?.[1] as RequestInit | undefined;
+  return JSON.parse(String(init?.body)) as Record<string, unknown>;
+}
+
+describe('hosted embedding providers', () => {
+  afterEach(() => {
+    vi.unstubAllGlobals();
+  });
+
+  it('sends Voyage queries and documents with their input types and output size', async () => {
+    const fetchMock = vi.fn(async (_url: string, init?: RequestInit) => {
+      const body = JSON.parse(String(init?.body)) as { input: string[] };
+      const rest = Array.from({ length: 511 }, () => 0.1);
+      // Out of order on purpose: results are matched back by index
+      const data = body.input.map((_, index) => ({ index, embedding: [index, ...rest] }));
+      return jsonResponse({ data: data.reverse() });
+    });
+    vi.stubGlobal('fetch', fetchMock);
+
+    const provider = new VoyageEmbeddingProvider('voyage-code-3', 'vk', undefined, 512);
+    await provider.initialize();
+    expect(provider.dimensions).toBe(512);
+    expect(fetchMock).not.toHaveBeenCalled();
+
+    const vectors = await provider.embedBatch(['a', 'b']);
+    expect(vectors.map((vector) => vector[0])).toEqual([0, 1]);
+    await provider.embed('where is auth');
+
+    const [url] = fetchMock.mock.calls[0] ?? [];
+    expect(url).toBe('https://api.voyageai.com/v1/embeddings');
+    expect(requestBody(fetchMock.mock.calls[0])).toMatchObject({
+      model: 'voyage-code-3',
+      input_type: 'document',
+      truncation: true,
+      output_dimension: 512
+    });
+    expect(requestBody(fetchMock.mock.calls[1])).toMatchObject({ input_type: 'query' });
+  });
+
+  it('splits Cohere batches at 96 texts and asks the API to truncate long inputs', async () => {
+    const fetchMock = vi.fn(async (_url: string, init?: RequestInit) => {
+      const body = JSON.parse(String(init?.body)) as { texts: string[] };
+      const vector = Array.from({ length: 1024 }, () => 0.2);
+      return jsonResponse({ embeddings: { float: body.texts.map(() => vector) } });
+    });
+    vi.stubGlobal('fetch', fetchMock);
+
+    const provider = new CohereEmbeddingProvider(undefined, 'ck');
+    await provider.initialize();
+    expect(provider.dimensions).toBe(1024);
+
+    const texts = Array.from({ length: 100 }, (_, i) => `chunk ${i}`);
+    expect(await provider.embedBatch(texts)).toHaveLength(100);
+    expect(fetchMock).toHaveBeenCalledTimes(2);
+    expect((requestBody(fetchMock.mock.calls[0]).texts as string[]).length).toBe(96);
+    expect(requestBody(fetchMock.mock.calls[0])).toMatchObject({
+      model: 'embed-english-v3.0',
+      input_type: 'search_document',
+      embedding_types: ['float'],
+      truncate: 'END'
+    });
+    expect(requestBody(fetchMock.mock.calls[0])).not.toHaveProperty('output_dimension');
+  });
+
+  it('routes Azure OpenAI requests to the deployment and learns unknown dimensions', async () => {
+    const fetchMock = vi.fn(async () => jsonResponse({ data: [{ embedding: [0.1, 0.2, 0.3] }] }));
+    vi.stubGlobal('fetch', fetchMock);
+
+    const provider = new AzureOpenAIEmbeddingProvider(
+      'code-embeddings',
+      'ak',
+      'https://acme.openai.azure.com/',
+      '2024-10-21'
+    );
+    await provider.initialize();
+    expect(provider.name).toBe('azure-openai');
+    expect(provider.dimensions).toBe(3);
+
+    const [url, init] = (fetchMock.mock.calls[0] ?? []) as unknown as [string, RequestInit];
+    expect(url).toBe(
+      'https://acme.openai.azure.com/openai/deployments/code-embeddings/embeddings' +
+        '?api-version=2024-10-21'
+    );
+    expect(init.headers).toMatchObject({ 'api-key': 'ak' });
+    expect(requestBody(fetchMock.mock.calls[0])).not.toHaveProperty('model');
+  });
+
+  it('requires an Azure endpoint and key', async () => {
+    await expect(
+      new AzureOpenAIEmbeddingProvider('dep', 'ak', undefined).initialize()
+    ).rejects.toThrow(/AZURE_OPENAI_ENDPOINT/);
+    await expect(
+      new AzureOpenAIEmbeddingProvider('dep', undefined, 'https://x.openai.azure.com').initialize()
+    ).rejects.toThrow(/AZURE_OPENAI_API_KEY/);
+  });
+
+  it('clips OpenAI inputs to the model limit and rejects mismatched vector sizes', async () => {
+    const fetchMock = vi.fn(async () => jsonResponse({ data: [{ embedding: [1, 2] }] }));
+    vi.stubGlobal('fetch', fetchMock);
+
+    const provider = new OpenAIEmbeddingProvider('text-embedding-3-small', 'ok');
+    await provider.initialize();
+    expect(provider.dimensions).toBe(1536);
+    await expect(provider.embedBatch(['x'.repeat(100_000)])).rejects.toThrow(
+      /2-dimensional embeddings, expected 1536/
+    );
+    const [input] = requestBody(fetchMock.mock.calls[0]).input as string[];
+    expect(input.length).toBeLessThan(100_000);
+    expect(requestBody(fetchMock.mock.calls[0])).not.toHaveProperty('dimensions');
+  });
+});
+
+describe('hosted model rules', () => {
+  it('accepts only output sizes a model can produce', () => {
+    expect(resolveHostedDimensions('openai', 'text-embedding-3-large', 256)).toBe(256);
+    expect(resolveHostedDimensions('voyage', 'voyage-code-3', undefined)).toBe(1024);
+    expect(resolveHostedDimensions('voyage', 'unknown-model', undefined)).toBe(0);
+    expect(() => resolveHostedDimensions('voyage', 'voyage-code-3', 300)).toThrow(
+      /256, 512, 1024, 2048/
+    );
+    expect(() => resolveHostedDimensions('cohere', 'embed-english-v3.0', 512)).toThrow(
+      /only 1024/
+    );
+  });
+
+  it('clips by the model input limit', () => {
+    expect(clipToInputLimit('short', 'text-embedding-3-small')).toBe('short');
+    expect(clipToInputLimit('x'.repeat(5000), 'embed-english-v3.0')).toHaveLength(1536);
+  });
+
+  it('resolves provider defaults and includes requested dimensions', () => {
+    expect(resolveEmbeddingModel({ provider: 'voyage', model: undefined })).toEqual({
+      provider: 'voyage',
+      model: 'voyage-code-3'
+    });
+    expect(resolveEmbeddingModel({ provider: 'cohere', model: undefined })).toMatchObject({
+      model: 'embed-english-v3.0'
+    });
+    expect(
+      resolveEmbeddingModel({ provider: 'azure-openai', model: 'prod-embed', dimensions: 512 })
+    ).toEqual({ provider: 'azure-openai', model: 'prod-embed', dimensions: 512 });
+  });
+});
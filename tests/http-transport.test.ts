@@ -0,0 +1,149 @@
+import { describe, it, expect, afterEach } from 'vitest';
+import http from 'http';
+import { Client } from '@modelcontextprotocol/sdk/client/index.js';
+import { StreamableHTTPClientTransport } from '@modelcontextprotocol/sdk/client/streamableHttp.js';
+import { Server } from '@modelcontextprotocol/sdk/server/index.js';
+import { ListToolsRequestSchema } from '@modelcontextprotocol/sdk/types.js';
+import {
+  isLoopbackHost,
+  resolveHttpTransportConfig,
+  startHttpTransport,
+  type HttpTransportHandle
+} from '../src/http-transport.js';
+
+function createTestServer(): Server {
+  const server = new Server({ name: 'test', version: '0.0.0' }, { capabilities: { tools: {} } });
+  server.setRequestHandler(ListToolsRequestSchema, async () => ({
+    tools: [{ name: 'ping', inputSchema: { type: 'object' } }]
+  }));
+  return server;
+}
+
+/** Status of a POST to `url` with the given headers; `http.request` lets Host be set */
+function postStatus(url: string, headers: Record<string, string>): Promise<number> {
+  return new Promise((resolve, reject) => {
+    const req = http.request(
+      url,
+      { method: 'POST', headers: { 'Content-Type': 'application/json', ...headers } },
+      (res) => {
+        res.resume();
+        resolve(res.statusCode ?? 0);
+      }
+    );
+    req.on('error', reject);
+    req.end('{}');
+  });
+}
+
+async function connectClient(url: string, token?: string): Promise<Client> {
+  const client = new Client({ name: 'test-client', version: '0.0.0' });
+  await client.connect(
+    new StreamableHTTPClientTransport(new URL(url), {
+      requestInit: token ? { headers: { Authorization: `Bearer ${token}` } } : undefined
+    })
+  );
+  return client;
+}
+
+describe('HTTP transport configuration', () => {
+  it('defaults to stdio and reads host, port and token for http', () => {
+    expect(resolveHttpTransportConfig({})).toBeNull();
+    expect(
+      resolveHttpTransportConfig({
+        CODEBASE_CONTEXT_TRANSPORT: 'http',
+        CODEBASE_CONTEXT_HTTP_HOST: '0.0.0.0',
+        CODEBASE_CONTEXT_HTTP_PORT: '8080',
+        CODEBASE_CONTEXT_AUTH_TOKEN: 'secret'
+      })
+    ).toEqual({ host: '0.0.0.0', port: 8080, authToken: 'secret' });
+    expect(() => resolveHttpTransportConfig({ CODEBASE_CONTEXT_TRANSPORT: 'sse' })).toThrow();
+    expect(
+      resolveHttpTransportConfig({
+        CODEBASE_CONTEXT_TRANSPORT: 'http',
+        CODEBASE_CONTEXT_ALLOWED_HOSTS: 'mcp.internal, dev.box',
+        CODEBASE_CONTEXT_ALLOWED_ORIGINS: 'https://app.example.com'
+      })
+    ).toMatchObject({
+      allowedHosts: ['mcp.internal', 'dev.box'],
+      allowedOrigins: ['https://app.example.com']
+    });
+  });
+
+  it('refuses non-loopback binds without a token', async () => {
+    expect(isLoopbackHost('127.0.0.1')).toBe(true);
+    expect(isLoopbackHost('0.0.0.0')).toBe(false);
+    await expect(
+      startHttpTransport({ host: '0.0.0.0', port: 0, createServer: createTestServer })
+    ).rejects.toThrow(/CODEBASE_CONTEXT_AUTH_TOKEN/);
+  });
+});
+
+describe('HTTP transport sessions', () => {
+  let handle: HttpTransportHandle | undefined;
+  const clients: Client[] = [];
+
+  afterEach(async () => {
+    await Promise.all(clients.splice(0).map((client) => client.close()));
+    await handle?.close();
+    handle = undefined;
+  });
+
+  it('serves several authenticated clients at once', async () => {
+    handle = await startHttpTransport({
+      host: '127.0.0.1',
+      port: 0,
+      authToken: 'team-token',
+      createServer: createTestServer
+    });
+
+    clients.push(await connectClient(handle.url, 'team-token'));
+    clients.push(await connectClient(handle.url, 'team-token'));
+
+    const [first, second] = await Promise.all(clients.map((client) => client.listTools()));
+    expect(first.tools.map((t) => t.name)).toEqual(['ping']);
+    expect(second.tools.map((t) => t.name)).toEqual(['ping']);
+    expect(handle.sessionCount()).toBe(2);
+  });
+
+  it('rejects requests without the bearer token', async () => {
+    handle = await startHttpTransport({
+      host: '127.0.0.1',
+      port: 0,
+      authToken: 'team-token',
+      createServer: createTestServer
+    });
+
+    const response = await fetch(handle.url, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json', Authorization: 'Bearer wrong' },
+      body: '{}'
+    });
+
+    expect(response.status).toBe(401);
+    expect(response.headers.get('www-authenticate')).toBe('Bearer');
+    await expect(connectClient(handle.url)).rejects.toThrow();
+  });
+
+  it('rejects rebound hosts and foreign origins on a tokenless loopback bind', async () => {
+    handle = await startHttpTransport({
+      host: '127.0.0.1',
+      port: 0,
+      allowedOrigins: ['https://app.example.com'],
+      createServer: createTestServer
+    });
+    const port = new URL(handle.url).port;
+
+    expect(await postStatus(handle.url, { Host: `attacker.example:${port}` })).toBe(403);
+    const foreign = { Host: `localhost:${port}`, Origin: 'https://attacker.example' };
+    expect(await postStatus(handle.url, foreign)).toBe(403);
+    // Allowed requests go through to the MCP handling (no session: 400)
+    expect(await postStatus(handle.url, { Host: `localhost:${port}` })).toBe(400);
+    expect(
+      await postStatus(handle.url, { Host: `[::1]:${port}`, Origin: 'http://localhost:5173' })
+    ).toBe(400);
+    expect(await postStatus(handle.url, { Origin: 'https://app.example.com' })).toBe(400);
+
+    clients.push(await connectClient(handle.url));
+    expect((await clients[0].listTools()).tools).toHaveLength(1);
+  });
+});
@@ -0,0 +1,120 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  MANIFEST_FILENAME
+} from '../src/constants/codebase-context.js';
+import {
+  IgnoreRules,
+  createMapIgnoreLoader,
+  detectGeneratedFile,
+  looksBinary,
+  looksMinified
+} from '../src/utils/ignore-rules.js';
+import { rmWithRetries } from './test-helpers.js';
+
+function rulesFor(files: Record<string, string>, respectGitignore = true): IgnoreRules {
+  const loader = createMapIgnoreLoader(new Map(Object.entries(files)), { respectGitignore });
+  return new IgnoreRules(loader);
+}
+
+describe('IgnoreRules', () => {
+  it('applies nested ignore files relative to their directory, deeper rules winning', () => {
+    const rules = rulesFor({
+      '.gitignore': '*.log\ngenerated/\n',
+      'packages/app/.gitignore': '!keep.log\nlocal.ts\n'
+    });
+
+    expect(rules.isIgnored('debug.log')).toBe(true);
+    expect(rules.isIgnored('packages/app/keep.log')).toBe(false);
+    expect(rules.isIgnored('packages/app/local.ts')).toBe(true);
+    expect(rules.isIgnored('local.ts')).toBe(false);
+    expect(rules.isIgnored('src/generated/api.ts')).toBe(true);
+  });
+
+  it('does not re-include files inside an ignored directory', () => {
+    const rules = rulesFor({ '.gitignore': 'build/\n!build/keep.ts\n' });
+
+    expect(rules.isIgnored('build/keep.ts')).toBe(true);
+  });
+
+  it('always honors .mcpignore and prunes dependency directories at any depth', () => {
+    const rules = rulesFor({ '.gitignore': 'secret.ts\n', '.mcpignore': 'fixtures/\n' }, false);
+
+    expect(rules.isIgnored('secret.ts')).toBe(false);
+    expect(rules.isIgnored('test/fixtures/big.ts')).toBe(true);
+    expect(rules.isIgnored('packages/web/node_modules/lib/index.js')).toBe(true);
+    expect(rules.isIgnored('go/vendor/mod/file.go')).toBe(true);
+  });
+
+  it('detects binary and minified content', () => {
+    expect(looksBinary(Buffer.from([0x50, 0x4b, 0x00, 0x01]))).toBe(true);
+    expect(looksBinary(Buffer.from('export const a = 1;\n'))).toBe(false);
+    expect(looksMinified('dist/app.min.js', '')).toBe(true);
+    expect(looksMinified('src/app.js', 'var a=1;'.repeat(500))).toBe(true);
+    expect(looksMinified('src/app.js', 'const a = 1;\n'.repeat(200))).toBe(false);
+  });
+});
+
+describe('detectGeneratedFile', () => {
+  it('recognizes lockfiles, generator output names and generator banners', () => {
+    expect(detectGeneratedFile('web/package-lock.json', '{')).toBe('lockfile');
+    expect(detectGeneratedFile('go.sum', '')).toBe('lockfile');
+    expect(detectGeneratedFile('api/user.pb.go', 'package api')).toBe('name');
+    expect(detectGeneratedFile('proto/user_pb2.py', '')).toBe('name');
+    expect(detectGeneratedFile('src/schema.generated.ts', '')).toBe('name');
+    expect(
+      detectGeneratedFile('api/client.go', '// Code generated by oapi-codegen. DO NOT EDIT.\n')
+    ).toBe('header');
+    expect(detectGeneratedFile('src/types.ts', '/**\n * @generated\n */\nexport {}')).toBe(
+      'header'
+    );
+    expect(detectGeneratedFile('Model.cs', '//---\n// <auto-generated>\n//---\n')).toBe('header');
+  });
+
+  it('ignores generator wording in code and banners past the first lines', () => {
+    expect(detectGeneratedFile('src/id.ts', 'export function generatedId() {}\n')).toBeNull();
+    expect(detectGeneratedFile('src/tag.ts', "const tag = '@generated';\n")).toBeNull();
+    const late = `${'const x = 1;\n'.repeat(20)}// Code generated by tool. DO NOT EDIT.\n`;
+    expect(detectGeneratedFile('src/late.ts', late)).toBeNull();
+  });
+});
+
+describe('indexer file selection', () => {
+  let tempDir: string;
+
+  async function write(relativePath: string, content: string | Buffer): Promise<void> {
+    const fullPath = path.join(tempDir, relativePath);
+    await fs.mkdir(path.dirname(fullPath), { recursive: true });
+    await fs.writeFile(fullPath, content);
+  }
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'ignore-rules-test-'));
+    await write('src/app.ts', 'export const app = 1;\n');
+    await write('packages/web/node_modules/lib/index.js', 'module.exports = 1;\n');
+    await write('packages/web/src/generated/client.ts', 'export const client = 1;\n');
+    await write('packages/web/.gitignore', 'src/generated/\n');
+    await write('scratch/notes.ts', 'export const notes = 1;\n');
+    await write('.mcpignore', 'scratch/\n');
+    await write('public/vendor.js', 'var a=1;'.repeat(500));
+    await write('src/blob.js', Buffer.from([0x7f, 0x45, 0x4c, 0x46, 0x00, 0x00]));
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('skips ignored, dependency, minified and binary files', async () => {
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+
+    const manifest = JSON.parse(
+      await fs.readFile(path.join(tempDir, CODEBASE_CONTEXT_DIRNAME, MANIFEST_FILENAME), 'utf-8')
+    ) as { files: Record<string, string> };
+
+    expect(Object.keys(manifest.files)).toEqual(['src/app.ts']);
+  });
+});
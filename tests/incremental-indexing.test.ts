@@ -10,6 +10,7 @@ import {
   KEYWORD_INDEX_FILENAME,
   INDEXING_STATS_FILENAME
 } from '../src/constants/codebase-context.js';
+import { readTombstones } from '../src/core/tombstones.js';
 
 describe('Incremental Indexing', () => {
   let tempDir: string;
@@ -246,6 +247,41 @@ describe('Incremental Indexing', () => {
     expect(stats.incremental!.deleted).toBeGreaterThanOrEqual(1);
   });
 
+  it('should index a moved file once, under its new path, and tombstone the old one', async () => {
+    await fs.writeFile(path.join(tempDir, 'index.ts'), 'export const x = 1;');
+    await fs.writeFile(path.join(tempDir, 'helpers.ts'), 'export const helper = () => 2;');
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+
+    await fs.mkdir(path.join(tempDir, 'lib'));
+    await fs.rename(path.join(tempDir, 'helpers.ts'), path.join(tempDir, 'lib', 'helpers.ts'));
+    const incremental = () =>
+      new CodebaseIndexer({
+        rootPath: tempDir,
+        config: { skipEmbedding: true },
+        incrementalOnly: true
+      }).index();
+    const stats = await incremental();
+
+    expect(stats.incremental).toMatchObject({ added: 0, deleted: 0, changed: 0, renamed: 1 });
+    const contextDir = path.join(tempDir, CODEBASE_CONTEXT_DIRNAME);
+    const index = JSON.parse(
+      await fs.readFile(path.join(contextDir, KEYWORD_INDEX_FILENAME), 'utf-8')
+    ) as { chunks: Array<{ relativePath: string }> };
+    const files = index.chunks.map((chunk) => chunk.relativePath);
+    expect(files).toContain('lib/helpers.ts');
+    expect(files).not.toContain('helpers.ts');
+    expect((await readTombstones(contextDir)).get('helpers.ts')).toMatchObject({
+      movedTo: 'lib/helpers.ts'
+    });
+
+    // Moving it back brings the old path back to life
+    await fs.rename(path.join(tempDir, 'lib', 'helpers.ts'), path.join(tempDir, 'helpers.ts'));
+    await incremental();
+    const tombstones = await readTombstones(contextDir);
+    expect(tombstones.has('helpers.ts')).toBe(false);
+    expect(tombstones.get('lib/helpers.ts')).toMatchObject({ movedTo: 'helpers.ts' });
+  });
+
   it('should fall back to full-like behavior when no manifest exists', async () => {
     await fs.writeFile(path.join(tempDir, 'index.ts'), 'export const x = 1;');
 
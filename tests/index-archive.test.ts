@@ -0,0 +1,144 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { gunzipSync, gzipSync } from 'zlib';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { exportIndex, importIndex } from '../src/core/index-archive.js';
+import { readIndexMeta, validateIndexArtifacts } from '../src/core/index-meta.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME
+} from '../src/constants/codebase-context.js';
+import type { CodeChunk } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const embedded = vi.hoisted(() => ({ texts: [] as string[] }));
+
+vi.mock('../src/embeddings/index.js', async (importOriginal) => {
+  const original = await importOriginal<typeof import('../src/embeddings/index.js')>();
+  const provider = {
+    name: 'fake',
+    modelName: 'fake-model',
+    dimensions: 4,
+    initialize: async () => {},
+    isReady: () => true,
+    embed: async (text: string) => [text.length, 1, 0, 0],
+    embedBatch: async (texts: string[]) => {
+      embedded.texts.push(...texts);
+      return texts.map((text) => [text.length, 1, 0, 0]);
+    }
+  };
+  return { ...original, getEmbeddingProvider: async () => provider };
+});
+
+async function writeProject(root: string): Promise<void> {
+  await fs.mkdir(path.join(root, 'src'), { recursive: true });
+  await fs.writeFile(
+    path.join(root, 'src', 'auth.ts'),
+    "import { db } from './db.js';\n\nexport function login(user: string) {\n  return db(user);\n}\n"
+  );
+  await fs.writeFile(
+    path.join(root, 'src', 'db.ts'),
+    'export function db(key: string) {\n  return key.length;\n}\n'
+  );
+}
+
+describe('index export/import', () => {
+  let workDir: string;
+  let ciRoot: string;
+  let devRoot: string;
+
+  beforeEach(async () => {
+    embedded.texts = [];
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+    workDir = await fs.mkdtemp(path.join(os.tmpdir(), 'index-archive-test-'));
+    ciRoot = path.join(workDir, 'ci', 'repo');
+    devRoot = path.join(workDir, 'dev', 'checkout');
+    await writeProject(ciRoot);
+    await writeProject(devRoot);
+  });
+
+  afterEach(async () => {
+    vi.restoreAllMocks();
+    await rmWithRetries(workDir);
+  });
+
+  it('restores a complete index under another root without re-embedding', async () => {
+    await new CodebaseIndexer({ rootPath: ciRoot }).index();
+    const archiveFile = path.join(workDir, 'index.json.gz');
+    const exported = await exportIndex(ciRoot, archiveFile);
+    expect(exported.chunks).toBeGreaterThan(0);
+    expect(exported.embeddingModel).toBe('fake:fake-model');
+
+    embedded.texts = [];
+    const imported = await importIndex(devRoot, archiveFile);
+    expect(imported).toMatchObject({
+      buildId: exported.buildId,
+      chunks: exported.chunks,
+      embeddedChunks: exported.chunks,
+      missingEmbeddings: 0
+    });
+    expect(imported.warnings.join('\n')).toContain('fake:fake-model');
+    expect(embedded.texts).toEqual([]);
+
+    const meta = await readIndexMeta(devRoot);
+    await expect(validateIndexArtifacts(devRoot, meta)).resolves.toBeUndefined();
+    const raw = await fs.readFile(
+      path.join(devRoot, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+      'utf-8'
+    );
+    expect(raw).not.toContain(ciRoot);
+    const chunks = (JSON.parse(raw) as { chunks: CodeChunk[] }).chunks;
+    expect(chunks.every((c) => c.filePath.startsWith(devRoot))).toBe(true);
+
+    // The imported embedding cache serves later rebuilds of the same code
+    await new CodebaseIndexer({ rootPath: devRoot }).index();
+    expect(embedded.texts).toEqual([]);
+  });
+
+  it('refuses archives whose build id or paths reach outside the project', async () => {
+    await new CodebaseIndexer({ rootPath: ciRoot }).index();
+    const archiveFile = path.join(workDir, 'index.json.gz');
+    await exportIndex(ciRoot, archiveFile);
+    const archive = JSON.parse(gunzipSync(await fs.readFile(archiveFile)).toString('utf-8')) as {
+      buildId: string;
+      files: Record<string, string>;
+    };
+    const tampered = async (change: (copy: typeof archive) => void): Promise<string> => {
+      const copy = structuredClone(archive);
+      change(copy);
+      const file = path.join(workDir, 'tampered.json.gz');
+      await fs.writeFile(file, gzipSync(JSON.stringify(copy)));
+      return file;
+    };
+    const victim = path.join(workDir, 'victim');
+    await fs.mkdir(victim);
+
+    const escapingId = await tampered((copy) => {
+      copy.buildId = '../../../victim';
+    });
+    await expect(importIndex(devRoot, escapingId)).rejects.toThrow('invalid build id');
+    await expect(fs.stat(victim)).resolves.toBeDefined();
+
+    const escapingPath = await tampered((copy) => {
+      copy.files[KEYWORD_INDEX_FILENAME] = copy.files[KEYWORD_INDEX_FILENAME].replace(
+        '<codebase-root>/src/auth.ts',
+        '<codebase-root>/../../victim/auth.ts'
+      );
+    });
+    await expect(importIndex(devRoot, escapingPath)).rejects.toThrow('points outside the project');
+    await expect(
+      fs.stat(path.join(devRoot, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME))
+    ).rejects.toThrow();
+  });
+
+  it('rejects files that are not index archives', async () => {
+    const bogus = path.join(workDir, 'bogus.json.gz');
+    await fs.writeFile(bogus, 'not gzip');
+
+    await expect(importIndex(devRoot, bogus)).rejects.toThrow(
+      'Not a codebase-context index archive'
+    );
+  });
+});
@@ -0,0 +1,199 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { randomBytes } from 'crypto';
+import { execFileSync } from 'child_process';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { CodebaseSearcher } from '../src/core/search.js';
+import { loadIndexedFiles } from '../src/core/file-resources.js';
+import { readIndexMeta } from '../src/core/index-meta.js';
+import { getModuleCard } from '../src/core/module-cards.js';
+import { searchHistory, updateHistoryIndex } from '../src/core/commit-history.js';
+import {
+  ENCRYPTION_KEY_ENV,
+  isSealedChunk,
+  openChunk,
+  openIndexedChunks,
+  parseIndexKey,
+  resolveIndexKey,
+  sealChunk
+} from '../src/core/index-encryption.js';
+import { IndexKeyError } from '../src/errors/index.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  HISTORY_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MODULE_CARDS_FILENAME
+} from '../src/constants/codebase-context.js';
+import type { CodeChunk } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const KEY = 'ab'.repeat(32);
+
+function chunk(content: string): CodeChunk {
+  return {
+    id: 'c1',
+    content,
+    filePath: '/repo/src/a.ts',
+    relativePath: 'src/a.ts',
+    startLine: 1,
+    endLine: 1,
+    language: 'typescript',
+    dependencies: [],
+    imports: [],
+    exports: [],
+    tags: ['api'],
+    metadata: { symbolName: 'chargeCard', docComment: 'Charges the card.' }
+  };
+}
+
+describe('chunk sealing', () => {
+  it('accepts 32-byte keys as hex or base64', () => {
+    const hex = parseIndexKey(KEY);
+    expect(hex.id).toMatch(/^[0-9a-f]{16}$/);
+    expect(parseIndexKey(Buffer.from(KEY, 'hex').toString('base64')).id).toBe(hex.id);
+    expect(() => parseIndexKey('too-short')).toThrow(IndexKeyError);
+    expect(() => parseIndexKey(randomBytes(16).toString('hex'))).toThrow(/32 bytes/);
+  });
+
+  it('hides content and metadata and restores them with the same key only', () => {
+    const key = parseIndexKey(KEY);
+    const plain = chunk('export function chargeCard() {}');
+    const sealed = sealChunk(plain, key);
+
+    expect(isSealedChunk(sealed)).toBe(true);
+    expect(JSON.stringify(sealed)).not.toContain('chargeCard');
+    expect(sealed).toMatchObject({ relativePath: 'src/a.ts', tags: ['api'], metadata: {} });
+    expect(sealChunk(sealed, key)).toBe(sealed);
+    expect(openChunk(sealed, key)).toEqual(plain);
+
+    const other = parseIndexKey(randomBytes(32).toString('hex'));
+    expect(() => openChunk(sealed, other)).toThrow(/different key/);
+  });
+
+  it('reads the key from the environment', async () => {
+    expect(await resolveIndexKey({})).toBeNull();
+    expect((await resolveIndexKey({ [ENCRYPTION_KEY_ENV]: KEY }))?.id).toBe(parseIndexKey(KEY).id);
+    await expect(resolveIndexKey({ [ENCRYPTION_KEY_ENV]: 'nope' })).rejects.toThrow(IndexKeyError);
+  });
+});
+
+describe('encrypted index', () => {
+  let tempRoot: string;
+  let savedKey: string | undefined;
+
+  beforeEach(async () => {
+    savedKey = process.env[ENCRYPTION_KEY_ENV];
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'index-encryption-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'billing.ts'),
+      [
+        '/** Charges a stored card. */',
+        'export function chargeCard(amountCents: number) {',
+        '  return { status: "captured", amountCents };',
+        '}',
+        ''
+      ].join('\n')
+    );
+  });
+
+  afterEach(async () => {
+    if (savedKey === undefined) delete process.env[ENCRYPTION_KEY_ENV];
+    else process.env[ENCRYPTION_KEY_ENV] = savedKey;
+    await rmWithRetries(tempRoot);
+  });
+
+  const index = (incrementalOnly = false) =>
+    new CodebaseIndexer({
+      rootPath: tempRoot,
+      config: { skipEmbedding: true },
+      incrementalOnly
+    }).index();
+
+  it('stores sealed chunks and serves them decrypted', async () => {
+    process.env[ENCRYPTION_KEY_ENV] = KEY;
+    await index();
+
+    const raw = await fs.readFile(
+      path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+      'utf-8'
+    );
+    expect(raw).not.toContain('captured');
+    expect((await readIndexMeta(tempRoot)).encryption).toEqual({
+      algorithm: 'aes-256-gcm',
+      keyId: parseIndexKey(KEY).id
+    });
+
+    const results = await new CodebaseSearcher(tempRoot).search('chargeCard', 3, undefined, {
+      useSemanticSearch: false
+    });
+    expect(results[0]?.snippet).toContain('captured');
+
+    const files = await loadIndexedFiles(tempRoot);
+    expect(files?.get('src/billing.ts')?.[0].content).toContain('captured');
+  });
+
+  it('refuses to read or rebuild an encrypted index without its key', async () => {
+    process.env[ENCRYPTION_KEY_ENV] = KEY;
+    await index();
+    delete process.env[ENCRYPTION_KEY_ENV];
+
+    await expect(new CodebaseSearcher(tempRoot).search('chargeCard', 3)).rejects.toThrow(
+      IndexKeyError
+    );
+    await expect(index()).rejects.toThrow(/index is encrypted/);
+    const [stored] = JSON.parse(
+      await fs.readFile(
+        path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+        'utf-8'
+      )
+    ).chunks as CodeChunk[];
+    await expect(openIndexedChunks([stored])).rejects.toThrow(ENCRYPTION_KEY_ENV);
+  });
+
+  it('seals the commit history and module cards too', async () => {
+    const git = (...args: string[]) =>
+      execFileSync('git', ['-c', 'user.name=t', '-c', 'user.email=t@example.com', ...args], {
+        cwd: tempRoot
+      });
+    git('init', '-q');
+    git('add', '-A');
+    git('commit', '-q', '-m', 'Add card charging');
+    await updateHistoryIndex(tempRoot, { skipEmbedding: true });
+    process.env[ENCRYPTION_KEY_ENV] = KEY;
+    await index();
+
+    // The plaintext history from before the key was set is rewritten sealed
+    await updateHistoryIndex(tempRoot, { skipEmbedding: true });
+    const contextDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    const history = await fs.readFile(path.join(contextDir, HISTORY_FILENAME), 'utf-8');
+    expect(history.startsWith('ccsealed:')).toBe(true);
+    expect(history).not.toContain('captured');
+    const found = await searchHistory(tempRoot, 'card charging', { skipEmbedding: true });
+    expect(found.results[0]?.message).toContain('Add card charging');
+
+    expect((await getModuleCard(tempRoot, 'src'))?.cached).toBe(false);
+    const cards = await fs.readFile(path.join(contextDir, MODULE_CARDS_FILENAME), 'utf-8');
+    expect(cards.startsWith('ccsealed:')).toBe(true);
+    expect(cards).not.toContain('chargeCard');
+    expect((await getModuleCard(tempRoot, 'src'))?.cached).toBe(true);
+  });
+
+  it('rebuilds fully when encryption is turned on', async () => {
+    await index();
+    process.env[ENCRYPTION_KEY_ENV] = KEY;
+    await index(true);
+
+    const { chunks } = JSON.parse(
+      await fs.readFile(
+        path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+        'utf-8'
+      )
+    ) as { chunks: CodeChunk[] };
+    expect(chunks.length).toBeGreaterThan(0);
+    expect(chunks.every(isSealedChunk)).toBe(true);
+    expect((await readIndexMeta(tempRoot)).encryption?.keyId).toBe(parseIndexKey(KEY).id);
+  });
+});
@@ -0,0 +1,203 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { CodebaseSearcher } from '../src/core/search.js';
+import { readIndexMeta } from '../src/core/index-meta.js';
+import {
+  acquireBuildLock,
+  readConsistently,
+  withIndexReadLock,
+  withIndexWriteLock
+} from '../src/core/index-lock.js';
+import { IndexCorruptedError, IndexingCancelledError } from '../src/errors/index.js';
+import {
+  BUILD_LOCK_FILENAME,
+  CODEBASE_CONTEXT_DIRNAME,
+  SWAP_LOCK_FILENAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const tick = (ms = 5) => new Promise((resolve) => setTimeout(resolve, ms));
+
+describe('index read/write lock', () => {
+  let dir: string;
+
+  beforeEach(async () => {
+    dir = await fs.mkdtemp(path.join(os.tmpdir(), 'index-lock-'));
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(dir);
+  });
+
+  it('lets a swap wait for readers and readers wait for a swap', async () => {
+    const events: string[] = [];
+    let finishRead!: () => void;
+    const reading = withIndexReadLock(dir, async () => {
+      events.push('read 1 start');
+      await new Promise<void>((resolve) => (finishRead = resolve));
+      events.push('read 1 end');
+    });
+    await tick();
+
+    const swapping = withIndexWriteLock(dir, async () => {
+      events.push('swap start');
+      expect(await fs.readFile(path.join(dir, SWAP_LOCK_FILENAME), 'utf-8')).toContain(
+        String(process.pid)
+      );
+      await tick();
+      events.push('swap end');
+    });
+    await tick();
+    // Queued behind the waiting swap, not alongside the first read
+    const lateRead = withIndexReadLock(dir, async () => {
+      events.push('read 2');
+    });
+    await tick();
+    expect(events).toEqual(['read 1 start']);
+
+    finishRead();
+    await Promise.all([reading, swapping, lateRead]);
+    expect(events).toEqual(['read 1 start', 'read 1 end', 'swap start', 'swap end', 'read 2']);
+    await expect(fs.access(path.join(dir, SWAP_LOCK_FILENAME))).rejects.toThrow();
+  });
+
+  it('runs nested reads straight away and refuses a swap from inside a read', async () => {
+    let gate!: () => void;
+    const outer = withIndexReadLock(dir, async () => {
+      await new Promise<void>((resolve) => (gate = resolve));
+      // A swap is queued by now; a nested read must not wait behind it
+      return withIndexReadLock(dir, async () => 'nested');
+    });
+    await tick();
+    const swap = withIndexWriteLock(dir, async () => 'swapped');
+    await tick();
+    gate();
+    expect(await outer).toBe('nested');
+    expect(await swap).toBe('swapped');
+
+    await expect(
+      withIndexReadLock(dir, () => withIndexWriteLock(dir, async () => undefined))
+    ).rejects.toThrow(/while reading/);
+  });
+
+  it('retries a read that saw a half-swapped index once', async () => {
+    let calls = 0;
+    const result = await readConsistently(dir, async () => {
+      calls++;
+      if (calls === 1) throw new IndexCorruptedError('index-meta.json missing');
+      return 'ok';
+    });
+    expect(result).toBe('ok');
+    expect(calls).toBe(2);
+
+    await expect(
+      readConsistently(dir, async () => {
+        throw new IndexCorruptedError('still broken');
+      })
+    ).rejects.toThrow('still broken');
+  });
+});
+
+describe('build lock', () => {
+  let dir: string;
+
+  beforeEach(async () => {
+    dir = await fs.mkdtemp(path.join(os.tmpdir(), 'build-lock-'));
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(dir);
+  });
+
+  it('runs one build at a time', async () => {
+    const release = await acquireBuildLock(dir);
+    let second = false;
+    const waiting = acquireBuildLock(dir).then((releaseSecond) => {
+      second = true;
+      return releaseSecond;
+    });
+    await tick(300);
+    expect(second).toBe(false);
+
+    await release();
+    await (await waiting)();
+    expect(second).toBe(true);
+    await expect(fs.access(path.join(dir, BUILD_LOCK_FILENAME))).rejects.toThrow();
+  });
+
+  it('takes over a lock left by a process that is gone', async () => {
+    await fs.writeFile(
+      path.join(dir, BUILD_LOCK_FILENAME),
+      JSON.stringify({ pid: 2 ** 22 + 1, startedAt: new Date().toISOString() })
+    );
+    const release = await acquireBuildLock(dir, { timeoutMs: 1000 });
+    const owner = JSON.parse(await fs.readFile(path.join(dir, BUILD_LOCK_FILENAME), 'utf-8'));
+    expect(owner.pid).toBe(process.pid);
+    await release();
+  });
+
+  it('gives up when cancelled or out of time', async () => {
+    const release = await acquireBuildLock(dir);
+    const controller = new AbortController();
+    const cancelled = acquireBuildLock(dir, { signal: controller.signal });
+    controller.abort();
+    await expect(cancelled).rejects.toThrow(IndexingCancelledError);
+    await expect(acquireBuildLock(dir, { timeoutMs: 100 })).rejects.toThrow(/Another build/);
+    await release();
+  });
+});
+
+describe('searching during a reindex', () => {
+  let tempRoot: string;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'index-lock-search-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'invoice.ts'),
+      'export function issueInvoice(total: number) {\n  return { total };\n}\n'
+    );
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  const index = () =>
+    new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+  const keyword = { useSemanticSearch: false };
+
+  it('serves whole generations while builds swap, and reloads after one', async () => {
+    await index();
+    const searcher = new CodebaseSearcher(tempRoot);
+    const first = await searcher.search('issueInvoice', 3, undefined, keyword);
+    expect(first.length).toBeGreaterThan(0);
+    const firstBuild = (await readIndexMeta(tempRoot)).buildId;
+
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'refund.ts'),
+      'export function issueRefund(total: number) {\n  return { total: -total };\n}\n'
+    );
+    const builds = [index(), index()];
+    const queries = Array.from({ length: 20 }, (_, i) =>
+      tick(i * 5).then(() =>
+        new CodebaseSearcher(tempRoot).search('issueInvoice', 3, undefined, keyword)
+      )
+    );
+    await Promise.all(builds);
+    for (const results of await Promise.all(queries)) {
+      expect(results.some((result) => result.filePath.endsWith('invoice.ts'))).toBe(true);
+    }
+
+    // The long-lived searcher picks up the generation that replaced the one it loaded
+    expect((await readIndexMeta(tempRoot)).buildId).not.toBe(firstBuild);
+    const refunds = await searcher.search('issueRefund', 3, undefined, keyword);
+    expect(refunds.some((result) => result.filePath.endsWith('refund.ts'))).toBe(true);
+    await expect(
+      fs.access(path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME, BUILD_LOCK_FILENAME))
+    ).rejects.toThrow();
+  });
+});
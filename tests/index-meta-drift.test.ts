@@ -0,0 +1,110 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import {
+  checkIndexCompatibility,
+  describeEmbeddingDrift,
+  migrateIndexMeta,
+  readIndexMeta
+} from '../src/core/index-meta.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INDEX_FORMAT_VERSION,
+  INDEX_META_FILENAME,
+  INDEX_META_VERSION,
+  KEYWORD_INDEX_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { IndexCorruptedError } from '../src/errors/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const TRANSFORMERS = { provider: 'transformers', model: 'Xenova/bge-small-en-v1.5' };
+
+function meta(overrides: Record<string, unknown> = {}) {
+  return {
+    metaVersion: INDEX_META_VERSION,
+    formatVersion: INDEX_FORMAT_VERSION,
+    buildId: 'build-1',
+    generatedAt: new Date().toISOString(),
+    toolVersion: 'test',
+    artifacts: {
+      keywordIndex: { path: KEYWORD_INDEX_FILENAME },
+      vectorDb: { path: VECTOR_DB_DIRNAME, provider: 'lancedb' }
+    },
+    ...overrides
+  };
+}
+
+describe('describeEmbeddingDrift', () => {
+  it('accepts the same model and indexes without a fingerprint', () => {
+    expect(describeEmbeddingDrift(undefined, TRANSFORMERS)).toBeNull();
+    expect(
+      describeEmbeddingDrift({ ...TRANSFORMERS, dimensions: 384 }, { ...TRANSFORMERS })
+    ).toBeNull();
+  });
+
+  it('names both models when they differ', () => {
+    const drift = describeEmbeddingDrift(
+      { ...TRANSFORMERS, dimensions: 384 },
+      { provider: 'openai', model: 'text-embedding-3-small' }
+    );
+    expect(drift).toBe(
+      'Embedding model changed (rebuild required): index built with ' +
+        'transformers:Xenova/bge-small-en-v1.5 (384d), configured openai:text-embedding-3-small'
+    );
+    const resized = describeEmbeddingDrift(
+      { ...TRANSFORMERS, dimensions: 384 },
+      { ...TRANSFORMERS, dimensions: 768 }
+    );
+    expect(resized).toContain('(768d)');
+  });
+});
+
+describe('index meta migration', () => {
+  let tempDir: string;
+  let ctxDir: string;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'index-meta-drift-'));
+    ctxDir = path.join(tempDir, CODEBASE_CONTEXT_DIRNAME);
+    await fs.mkdir(ctxDir, { recursive: true });
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  const writeMeta = (value: unknown) =>
+    fs.writeFile(path.join(ctxDir, INDEX_META_FILENAME), JSON.stringify(value));
+
+  it('upgrades a v1 meta file once and leaves current ones alone', async () => {
+    expect(migrateIndexMeta(meta())).toBeNull();
+
+    await writeMeta(meta({ metaVersion: 1 }));
+    const upgraded = await readIndexMeta(tempDir);
+    expect(upgraded.metaVersion).toBe(INDEX_META_VERSION);
+    expect(upgraded.embedding).toBeUndefined();
+
+    const persisted = JSON.parse(
+      await fs.readFile(path.join(ctxDir, INDEX_META_FILENAME), 'utf-8')
+    ) as { metaVersion: number };
+    expect(persisted.metaVersion).toBe(INDEX_META_VERSION);
+  });
+
+  it('refuses meta written by a newer version', async () => {
+    await writeMeta(meta({ metaVersion: INDEX_META_VERSION + 1 }));
+    await expect(readIndexMeta(tempDir)).rejects.toThrow(IndexCorruptedError);
+    await expect(readIndexMeta(tempDir)).rejects.toThrow('written by a newer version');
+  });
+
+  it('reports incompatible indexes without throwing', async () => {
+    expect(await checkIndexCompatibility(tempDir, TRANSFORMERS)).toContain('Index meta missing');
+
+    await writeMeta(meta({ embedding: { ...TRANSFORMERS, dimensions: 384 } }));
+    expect(await checkIndexCompatibility(tempDir, TRANSFORMERS)).toBeNull();
+    expect(
+      await checkIndexCompatibility(tempDir, { provider: 'ollama', model: 'nomic-embed-text' })
+    ).toContain('Embedding model changed');
+  });
+});
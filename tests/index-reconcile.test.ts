@@ -0,0 +1,114 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { reconcileIndex, resolveGcIntervalMinutes } from '../src/core/index-maintenance.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME,
+  MANIFEST_FILENAME
+} from '../src/constants/codebase-context.js';
+import type { CodeChunk } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const store = vi.hoisted(() => ({
+  filePaths: [] as string[],
+  deleted: [] as string[][],
+  compactions: 0
+}));
+
+vi.mock('../src/storage/index.js', async (importOriginal) => {
+  const original = await importOriginal<typeof import('../src/storage/index.js')>();
+  return {
+    ...original,
+    DEFAULT_STORAGE_CONFIG: { ...original.DEFAULT_STORAGE_CONFIG, provider: 'lancedb' },
+    getStorageProvider: async () => ({
+      name: 'fake',
+      initialize: async () => {},
+      store: async () => {},
+      search: async () => [],
+      deleteByFilePaths: async (filePaths: string[]) => {
+        store.deleted.push(filePaths);
+        return filePaths.length;
+      },
+      clear: async () => {},
+      count: async () => store.filePaths.length,
+      isInitialized: () => true,
+      listFilePaths: async () => store.filePaths,
+      compact: async () => {
+        store.compactions++;
+      }
+    })
+  };
+});
+
+describe('reconcileIndex', () => {
+  let tempDir: string;
+  const contextFile = (name: string) => path.join(tempDir, CODEBASE_CONTEXT_DIRNAME, name);
+  const indexedPaths = async () => {
+    const raw = await fs.readFile(contextFile(KEYWORD_INDEX_FILENAME), 'utf-8');
+    const chunks = (JSON.parse(raw) as { chunks: CodeChunk[] }).chunks;
+    return [...new Set(chunks.map((chunk) => chunk.relativePath.replace(/\\/g, '/')))].sort();
+  };
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'index-reconcile-'));
+    await fs.mkdir(path.join(tempDir, 'src'), { recursive: true });
+    for (const name of ['cart', 'pricing', 'tax']) {
+      await fs.writeFile(
+        path.join(tempDir, 'src', `${name}.ts`),
+        `export function ${name}Total(amount: number) {\n  return amount;\n}\n`
+      );
+    }
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+
+    store.filePaths = ['cart', 'pricing', 'tax', 'renamed'].map((name) =>
+      path.join(tempDir, 'src', `${name}.ts`).replace(/\\/g, '/')
+    );
+    store.deleted = [];
+    store.compactions = 0;
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('drops deleted, edited and unknown files from both stores and compacts', async () => {
+    await fs.rm(path.join(tempDir, 'src', 'pricing.ts'));
+    await fs.writeFile(path.join(tempDir, 'src', 'tax.ts'), 'export const rate = 0.2;\n');
+
+    const preview = await reconcileIndex(tempDir, { dryRun: true });
+    expect(preview).toMatchObject({
+      missingFiles: ['src/pricing.ts'],
+      changedFiles: ['src/tax.ts'],
+      orphanedVectorFiles: ['src/pricing.ts', 'src/renamed.ts', 'src/tax.ts'],
+      removedVectors: 0,
+      compacted: false
+    });
+    expect(preview.removedChunks).toBeGreaterThan(0);
+    expect(store.deleted).toEqual([]);
+    expect(await indexedPaths()).toEqual(['src/cart.ts', 'src/pricing.ts', 'src/tax.ts']);
+
+    const report = await reconcileIndex(tempDir);
+    expect(report.removedVectors).toBe(3);
+    expect(report.compacted).toBe(true);
+    expect(store.compactions).toBe(1);
+    expect(await indexedPaths()).toEqual(['src/cart.ts']);
+
+    // Edited files leave the manifest so the next incremental run indexes them again
+    const manifest = JSON.parse(await fs.readFile(contextFile(MANIFEST_FILENAME), 'utf-8')) as {
+      files: Record<string, string>;
+    };
+    expect(Object.keys(manifest.files)).toEqual(['src/cart.ts']);
+
+    const again = await reconcileIndex(tempDir);
+    expect(again).toMatchObject({ missingFiles: [], changedFiles: [], removedChunks: 0 });
+  });
+
+  it('reads the schedule from CODEBASE_CONTEXT_GC_INTERVAL_MINUTES', () => {
+    expect(resolveGcIntervalMinutes({ CODEBASE_CONTEXT_GC_INTERVAL_MINUTES: '30' })).toBe(30);
+    expect(resolveGcIntervalMinutes({ CODEBASE_CONTEXT_GC_INTERVAL_MINUTES: '-5' })).toBe(0);
+    expect(resolveGcIntervalMinutes({})).toBe(0);
+  });
+});
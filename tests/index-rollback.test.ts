@@ -0,0 +1,88 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { readIndexMeta, validateIndexArtifacts } from '../src/core/index-meta.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+describe('index generations and rollback_index', () => {
+  let tempRoot: string;
+  let contextDir: string;
+  let ctx: ToolContext;
+
+  const build = async (incrementalOnly = false) => {
+    await new CodebaseIndexer({
+      rootPath: tempRoot,
+      incrementalOnly,
+      config: { skipEmbedding: true }
+    }).index();
+    return (await readIndexMeta(tempRoot)).buildId;
+  };
+
+  const rollback = async (args: Record<string, unknown> = {}) =>
+    JSON.parse((await dispatchTool('rollback_index', args, ctx)).content![0].text);
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'index-rollback-'));
+    contextDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.writeFile(path.join(tempRoot, 'src', 'a.ts'), 'export const a = 1;\n');
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir: contextDir,
+        memory: path.join(contextDir, MEMORY_FILENAME),
+        intelligence: path.join(contextDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(contextDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(contextDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('keeps the replaced generation and swaps it back, reversibly', async () => {
+    expect(await rollback()).toMatchObject({ status: 'unavailable' });
+
+    const first = await build();
+    await fs.writeFile(path.join(tempRoot, 'src', 'b.ts'), 'export const b = 2;\n');
+    const second = await build(true);
+    expect(second).not.toBe(first);
+    await expect(fs.readdir(path.join(contextDir, '.staging'))).rejects.toThrow();
+
+    expect(await rollback({ dryRun: true })).toMatchObject({ wouldRestore: { buildId: first } });
+    expect((await readIndexMeta(tempRoot)).buildId).toBe(second);
+
+    expect(await rollback()).toMatchObject({ status: 'success', restored: { buildId: first } });
+    const restored = await readIndexMeta(tempRoot);
+    expect(restored.buildId).toBe(first);
+    await validateIndexArtifacts(tempRoot, restored);
+    const keywordIndex = await fs.readFile(path.join(contextDir, KEYWORD_INDEX_FILENAME), 'utf-8');
+    expect(keywordIndex).not.toContain('b.ts');
+
+    expect(await rollback()).toMatchObject({ restored: { buildId: second } });
+    expect((await readIndexMeta(tempRoot)).buildId).toBe(second);
+  });
+
+  it('refuses while indexing is in progress', async () => {
+    await build();
+    await build();
+    ctx.indexState.status = 'indexing';
+    const result = await dispatchTool('rollback_index', {}, ctx);
+    expect(result.isError).toBe(true);
+  });
+});
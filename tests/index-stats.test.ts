@@ -0,0 +1,105 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { estimateStaleFiles } from '../src/core/index-maintenance.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+describe('get_index_stats', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  const stats = async () => {
+    const result = await dispatchTool('get_index_stats', {}, ctx);
+    return JSON.parse(result.content![0].text);
+  };
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'index-stats-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'cart.ts'),
+      'export const total = (items: number[]) => items.reduce((a, b) => a + b, 0);\n'
+    );
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'tax.ts'),
+      'export const withTax = (amount: number) => amount * 1.2;\n'
+    );
+    await fs.writeFile(path.join(tempRoot, 'src', 'notes.py'), 'def hello():\n    return 1\n');
+
+    const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('tells the caller to index when there is no index', async () => {
+    const payload = await stats();
+    expect(payload).toMatchObject({ status: 'success', indexed: false, files: 0 });
+    expect(payload.hints).toEqual(['There is no index yet; run refresh_index.']);
+  });
+
+  it('reports counts, languages, disk usage and freshness', async () => {
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const payload = await stats();
+    expect(payload).toMatchObject({ indexed: true, files: 3, indexState: 'ready' });
+    expect(payload.chunks).toBeGreaterThanOrEqual(3);
+    expect(payload.languages.map((l: { language: string }) => l.language).sort()).toEqual([
+      'python',
+      'typescript'
+    ]);
+    expect(payload.disk.totalBytes).toBeGreaterThan(0);
+    expect(payload.lastIndexed).toBe(payload.generatedAt);
+    expect(payload.storageProvider).toBe('lancedb');
+    expect(payload.storageCapabilities).toMatchObject({
+      filters: { fields: true, tags: false, metadata: false },
+      namespaces: false
+    });
+    expect(payload.staleFiles).toEqual({
+      missing: 0,
+      changed: 0,
+      checked: 3,
+      indexed: 3,
+      sampled: false
+    });
+  });
+
+  it('estimates files edited or deleted since indexing', async () => {
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+    const edited = path.join(tempRoot, 'src', 'cart.ts');
+    await fs.writeFile(edited, 'export const total = 0;\n');
+    const later = new Date(Date.now() + 60_000);
+    await fs.utimes(edited, later, later);
+    await fs.rm(path.join(tempRoot, 'src', 'notes.py'));
+
+    expect(await estimateStaleFiles(tempRoot)).toMatchObject({ missing: 1, changed: 1 });
+    const payload = await stats();
+    expect(payload.hints).toContainEqual(
+      expect.stringContaining('2 indexed file(s) changed or were deleted')
+    );
+  });
+});
@@ -367,6 +367,56 @@ describe('index versioning migration (MIGR-01)', () => {
     expect(String(payload.index.reason || '')).toContain('Vector DB');
     expect(indexerMocks.index).toHaveBeenCalledTimes(1);
   });
+
+  it('rebuilds an index embedded with a different model instead of querying it', async () => {
+    if (!tempRoot) throw new Error('tempRoot not initialized');
+
+    const ctxDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    await fs.mkdir(path.join(ctxDir, VECTOR_DB_DIRNAME), { recursive: true });
+
+    const buildId = 'other-model-build';
+    await fs.writeFile(
+      path.join(ctxDir, VECTOR_DB_DIRNAME, 'index-build.json'),
+      JSON.stringify({ buildId, formatVersion: INDEX_FORMAT_VERSION }),
+      'utf-8'
+    );
+    await fs.writeFile(
+      path.join(ctxDir, KEYWORD_INDEX_FILENAME),
+      JSON.stringify({ header: { buildId, formatVersion: INDEX_FORMAT_VERSION }, chunks: [] }),
+      'utf-8'
+    );
+    await fs.writeFile(
+      path.join(ctxDir, INDEX_META_FILENAME),
+      JSON.stringify({
+        metaVersion: INDEX_META_VERSION,
+        formatVersion: INDEX_FORMAT_VERSION,
+        buildId,
+        generatedAt: new Date().toISOString(),
+        toolVersion: 'test',
+        embedding: { provider: 'custom', model: 'retired-model', dimensions: 7 },
+        artifacts: {
+          keywordIndex: { path: KEYWORD_INDEX_FILENAME },
+          vectorDb: { path: VECTOR_DB_DIRNAME, provider: 'lancedb' }
+        }
+      }),
+      'utf-8'
+    );
+
+    const { server } = await import('../src/index.js');
+    const handler = (server as any)._requestHandlers.get('tools/call');
+
+    const response = await handler({
+      jsonrpc: '2.0',
+      id: 1,
+      method: 'tools/call',
+      params: { name: 'search_codebase', arguments: { query: 'test' } }
+    });
+
+    const payload = JSON.parse(response.content[0].text);
+    expect(payload.index.action).toBe('rebuilt-and-served');
+    expect(String(payload.index.reason || '')).toContain('Embedding model changed');
+    expect(indexerMocks.index).toHaveBeenCalledTimes(1);
+  });
 });
 
 describe('index-consuming allowlist enforcement', () => {
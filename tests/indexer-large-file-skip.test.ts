@@ -9,6 +9,30 @@ import {
   CODEBASE_CONTEXT_DIRNAME,
   KEYWORD_INDEX_FILENAME
 } from '../src/constants/codebase-context.js';
+import type { ChunkMetadata } from '../src/types/index.js';
+
+async function readIndexedChunks(
+  rootPath: string
+): Promise<Array<{ filePath: string; metadata: ChunkMetadata }>> {
+  const indexPath = path.join(rootPath, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME);
+  const indexRaw = JSON.parse(await fs.readFile(indexPath, 'utf-8')) as {
+    chunks: Array<{ filePath: string; metadata: ChunkMetadata }>;
+  };
+  return indexRaw.chunks;
+}
+
+function chunksOf(chunks: Array<{ filePath: string; metadata: ChunkMetadata }>, name: string) {
+  return chunks.filter((chunk) => chunk.filePath.split(/[\\/]/).pop() === name);
+}
+
+/** `count` functions of 20 lines each, so chunking yields several chunks */
+function manyFunctions(count: number, header = ''): string {
+  const body = Array.from({ length: 18 }, (_, i) => `  const v${i} = ${i};`).join('\n');
+  return (
+    header +
+    Array.from({ length: count }, (_, i) => `export function f${i}() {\n${body}\n}\n`).join('\n')
+  );
+}
 
 describe('Indexer large file skip regression', () => {
   let tempDir: string;
@@ -57,4 +81,46 @@ describe('Indexer large file skip regression', () => {
     expect(indexedFiles.has('big.ts')).toBe(false);
     expect(indexedFiles.has('big.generated.ts')).toBe(false);
   });
+
+  it('applies per-extension size caps and keeps only the first chunks of long files', async () => {
+    await fs.writeFile(path.join(tempDir, 'vendor.css'), '.a { color: red; }\n'.repeat(40));
+    await fs.writeFile(path.join(tempDir, 'theme.css'), '.b { color: blue; }\n');
+    await fs.writeFile(path.join(tempDir, 'long.ts'), manyFunctions(12));
+
+    const stats = await new CodebaseIndexer({
+      rootPath: tempDir,
+      config: {
+        skipEmbedding: true,
+        parsing: { maxFileSizeByExtension: { '.css': 256 }, maxChunksPerFile: 3 }
+      }
+    }).index();
+
+    const chunks = await readIndexedChunks(tempDir);
+    expect(chunksOf(chunks, 'vendor.css')).toHaveLength(0);
+    expect(chunksOf(chunks, 'theme.css').length).toBeGreaterThan(0);
+    const long = chunksOf(chunks, 'long.ts');
+    expect(long).toHaveLength(3);
+    expect(long[0].metadata.droppedChunks).toBeGreaterThan(0);
+    expect(stats.truncatedFiles).toBe(1);
+  });
+
+  it('samples generated files by default and skips them on request', async () => {
+    const banner = '// Code generated by protoc-gen-ts. DO NOT EDIT.\n';
+    await fs.writeFile(path.join(tempDir, 'api.ts'), manyFunctions(12, banner));
+    await fs.writeFile(path.join(tempDir, 'handwritten.ts'), 'export const keep = 1;\n');
+
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+    const sampled = chunksOf(await readIndexedChunks(tempDir), 'api.ts');
+    expect(sampled).toHaveLength(2);
+    expect(sampled.every((chunk) => chunk.metadata.generated === true)).toBe(true);
+
+    const stats = await new CodebaseIndexer({
+      rootPath: tempDir,
+      config: { skipEmbedding: true, parsing: { generatedFiles: 'skip' } }
+    }).index();
+    const chunks = await readIndexedChunks(tempDir);
+    expect(chunksOf(chunks, 'api.ts')).toHaveLength(0);
+    expect(chunksOf(chunks, 'handwritten.ts')).toHaveLength(1);
+    expect(stats.skippedGeneratedFiles).toBe(1);
+  });
 });
@@ -0,0 +1,213 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import {
+  createProgressReporter,
+  formatProgressMessage,
+  overallProgress,
+  type ProgressUpdate
+} from '../src/core/indexing-progress.js';
+import { IndexingCancelledError } from '../src/errors/index.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME
+} from '../src/constants/codebase-context.js';
+import type { IndexingPhase, IndexingProgress } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+function progressAt(phase: IndexingPhase, extra: Partial<IndexingProgress> = {}): IndexingProgress {
+  return {
+    phase,
+    percentage: 0,
+    filesProcessed: 0,
+    totalFiles: 10,
+    chunksCreated: 0,
+    errors: [],
+    startedAt: new Date(Date.now() - 10_000),
+    ...extra
+  };
+}
+
+describe('indexing progress reporter', () => {
+  it('maps phases onto one monotonic scale', () => {
+    expect(overallProgress(progressAt('scanning'))).toBe(0);
+    expect(overallProgress(progressAt('analyzing', { filesProcessed: 5 }))).toBe(28);
+    expect(
+      overallProgress(progressAt('embedding', { chunksEmbedded: 20, chunksToEmbed: 40 }))
+    ).toBe(70);
+    expect(overallProgress(progressAt('complete', { percentage: 100 }))).toBe(100);
+  });
+
+  it('describes files, embedded chunks and an ETA', () => {
+    const progress = progressAt('embedding', {
+      filesProcessed: 10,
+      chunksEmbedded: 20,
+      chunksToEmbed: 40
+    });
+    const message = formatProgressMessage(progress, 50, progress.startedAt.getTime() + 10_000);
+
+    expect(message).toBe('embedding, 10/10 files, 20/40 chunks embedded, ETA 10s');
+  });
+
+  it('sends strictly increasing updates and throttles within a phase', () => {
+    const sent: ProgressUpdate[] = [];
+    const report = createProgressReporter((update) => sent.push(update), 60_000);
+
+    report(progressAt('analyzing', { filesProcessed: 1 }));
+    report(progressAt('analyzing', { filesProcessed: 2 }));
+    report(progressAt('embedding', { chunksEmbedded: 1, chunksToEmbed: 10 }));
+    report(progressAt('embedding', { chunksEmbedded: 0, chunksToEmbed: 10 }));
+    report(progressAt('complete', { percentage: 100 }));
+
+    expect(sent.map((u) => u.progress)).toEqual([10, 54, 100]);
+    expect(sent.every((u) => u.total === 100)).toBe(true);
+  });
+});
+
+describe('indexer cancellation', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'indexing-cancel-test-'));
+    await fs.mkdir(path.join(tempDir, 'src'), { recursive: true });
+    for (const name of ['a', 'b', 'c']) {
+      await fs.writeFile(
+        path.join(tempDir, 'src', `${name}.ts`),
+        `export function ${name}() {\n  return '${name}';\n}\n`
+      );
+    }
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('rejects with IndexingCancelledError and writes no index when aborted mid-run', async () => {
+    const controller = new AbortController();
+    const indexer = new CodebaseIndexer({
+      rootPath: tempDir,
+      config: { skipEmbedding: true },
+      signal: controller.signal,
+      onProgress: (progress) => {
+        if (progress.phase === 'analyzing') controller.abort();
+      }
+    });
+
+    await expect(indexer.index()).rejects.toBeInstanceOf(IndexingCancelledError);
+    await expect(
+      fs.access(path.join(tempDir, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME))
+    ).rejects.toThrow();
+  });
+});
+
+interface Notification {
+  method: string;
+  params: Record<string, unknown>;
+}
+
+type CallToolHandler = (
+  request: {
+    params: {
+      name: string;
+      arguments?: Record<string, unknown>;
+      _meta?: { progressToken?: string | number };
+    };
+  },
+  extra: {
+    signal: AbortSignal;
+    sendNotification: (notification: Notification) => Promise<void>;
+  }
+) => Promise<{ content?: Array<{ text: string }>; isError?: boolean }>;
+
+describe('refresh_index with a progressToken', () => {
+  let tempRoot: string;
+  let originalArgv: string[];
+  let originalEnvRoot: string | undefined;
+
+  beforeEach(async () => {
+    vi.resetModules();
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+    vi.doMock('../src/core/indexer.js', async () => {
+      const { IndexingCancelledError: CancelledError } = await import('../src/errors/index.js');
+      class CodebaseIndexer {
+        constructor(
+          private options: {
+            signal?: AbortSignal;
+            onProgress?: (progress: IndexingProgress) => void;
+          }
+        ) {}
+
+        getProgress() {
+          return { phase: 'complete', percentage: 100 };
+        }
+
+        async index() {
+          for (let file = 1; file <= 2; file++) {
+            if (this.options.signal?.aborted) throw new CancelledError();
+            this.options.onProgress?.(
+              progressAt('analyzing', { filesProcessed: file, totalFiles: 2 })
+            );
+          }
+          this.options.onProgress?.(progressAt('complete', { percentage: 100, totalFiles: 2 }));
+          return { indexedFiles: 2, totalChunks: 4, duration: 5, errors: [] };
+        }
+      }
+      return { CodebaseIndexer };
+    });
+
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'indexing-progress-server-'));
+    originalArgv = [...process.argv];
+    originalEnvRoot = process.env.CODEBASE_ROOT;
+    process.env.CODEBASE_ROOT = tempRoot;
+    process.argv[2] = tempRoot;
+  });
+
+  afterEach(async () => {
+    vi.doUnmock('../src/core/indexer.js');
+    vi.restoreAllMocks();
+    process.argv = originalArgv;
+    if (originalEnvRoot === undefined) delete process.env.CODEBASE_ROOT;
+    else process.env.CODEBASE_ROOT = originalEnvRoot;
+    await rmWithRetries(tempRoot);
+  });
+
+  async function getCallToolHandler(): Promise<CallToolHandler> {
+    const { server } = await import('../src/index.js');
+    const handlers = (server as unknown as { _requestHandlers: Map<string, CallToolHandler> })
+      ._requestHandlers;
+    return handlers.get('tools/call')!;
+  }
+
+  it('waits for completion and streams progress notifications', async () => {
+    const handler = await getCallToolHandler();
+    const sendNotification = vi.fn(async (_notification: Notification) => {});
+
+    const result = await handler(
+      { params: { name: 'refresh_index', arguments: {}, _meta: { progressToken: 'tok' } } },
+      { signal: new AbortController().signal, sendNotification }
+    );
+    const payload = JSON.parse(result.content![0].text);
+
+    expect(payload).toMatchObject({ status: 'complete', indexedFiles: 2, totalChunks: 4 });
+    const notifications = sendNotification.mock.calls.map(([n]) => n);
+    expect(notifications.length).toBeGreaterThan(0);
+    expect(notifications.every((n) => n.method === 'notifications/progress')).toBe(true);
+    expect(notifications.every((n) => n.params.progressToken === 'tok')).toBe(true);
+    expect(notifications[notifications.length - 1].params.progress).toBe(100);
+  });
+
+  it('reports cancellation when the request is aborted', async () => {
+    const handler = await getCallToolHandler();
+    const controller = new AbortController();
+    controller.abort();
+
+    const result = await handler(
+      { params: { name: 'refresh_index', arguments: {}, _meta: { progressToken: 1 } } },
+      { signal: controller.signal, sendNotification: vi.fn(async () => {}) }
+    );
+
+    expect(JSON.parse(result.content![0].text).status).toBe('cancelled');
+  });
+});
@@ -0,0 +1,167 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import {
+  createInfraChunks,
+  findHclBlocks,
+  findKubernetesDocuments
+} from '../src/utils/infra-chunker.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME
+} from '../src/constants/codebase-context.js';
+import type { CodeChunk } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const TERRAFORM = `terraform {
+  required_version = ">= 1.5"
+}
+
+# Uploads land here
+resource "aws_s3_bucket" "uploads" {
+  bucket = "acme-uploads"
+  tags = {
+    Name = "uploads { not a brace }"
+  }
+}
+
+resource "aws_iam_policy" "uploads_write" {
+  policy = <<-EOT
+    { "Statement": [ {
+    EOT
+}
+
+data "aws_caller_identity" "current" {}
+
+module "vpc" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+
+variable "region" {
+  default = "eu-west-1"
+}
+`;
+
+const MANIFESTS = `# app manifests
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: prod
+  labels:
+    name: not-this-one
+spec:
+  replicas: 2
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+spec:
+  ports:
+    - port: 80
+`;
+
+function options(relativePath: string, language: string) {
+  return { filePath: `/repo/${relativePath}`, relativePath, language };
+}
+
+describe('findHclBlocks', () => {
+  it('finds top-level blocks across heredocs, strings and nested braces', () => {
+    const blocks = findHclBlocks(TERRAFORM);
+
+    expect(blocks.map((b) => b.infra.address)).toEqual([
+      'terraform',
+      'aws_s3_bucket.uploads',
+      'aws_iam_policy.uploads_write',
+      'data.aws_caller_identity.current',
+      'module.vpc',
+      'var.region'
+    ]);
+    expect(blocks[1]).toMatchObject({
+      startLine: 6,
+      endLine: 11,
+      infra: { blockType: 'resource', resourceType: 'aws_s3_bucket', resourceName: 'uploads' }
+    });
+    expect(blocks[2].endLine).toBe(17);
+    expect(blocks[4].infra.source).toBe('terraform-aws-modules/vpc/aws');
+  });
+});
+
+describe('findKubernetesDocuments', () => {
+  it('reads kind, metadata.name and namespace per document, leading comments included', () => {
+    const docs = findKubernetesDocuments(MANIFESTS);
+
+    expect(docs.map((d) => d.infra.address)).toEqual(['Deployment/api', 'Service/api']);
+    expect(docs[0]).toMatchObject({
+      startLine: 1,
+      endLine: 10,
+      infra: { kind: 'kubernetes', namespace: 'prod', apiVersion: 'apps/v1' }
+    });
+    expect(docs[1].infra.namespace).toBeUndefined();
+  });
+
+  it('ignores YAML that is not a manifest', () => {
+    expect(createInfraChunks('name: ci\non: push\n', options('ci.yml', 'yaml'))).toBeNull();
+  });
+});
+
+describe('createInfraChunks', () => {
+  it('emits one symbol-aware chunk per block with an address header', () => {
+    const chunks = createInfraChunks(TERRAFORM, options('main.tf', 'hcl'))!;
+    const bucket = chunks.find((c) => c.metadata.symbolName === 'aws_s3_bucket.uploads')!;
+
+    expect(bucket.content.split('\n')[0]).toBe('# aws_s3_bucket.uploads :: resource');
+    expect(bucket.metadata).toMatchObject({ symbolAware: true, chunkStrategy: 'infra-block' });
+    expect(bucket.tags).toEqual(['terraform', 'resource']);
+    expect(bucket.componentType).toBe('infrastructure');
+  });
+
+  it('keeps comments between blocks as filler chunks', () => {
+    const chunks = createInfraChunks(TERRAFORM, options('main.tf', 'hcl'))!;
+
+    expect(chunks.map((c) => c.metadata.infra?.address ?? null)).toEqual([
+      'terraform',
+      null,
+      'aws_s3_bucket.uploads',
+      'aws_iam_policy.uploads_write',
+      'data.aws_caller_identity.current',
+      'module.vpc',
+      'var.region'
+    ]);
+    expect(chunks[1]).toMatchObject({ startLine: 4, endLine: 5 });
+  });
+});
+
+describe('indexer with infrastructure files', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'infra-index-test-'));
+    await fs.mkdir(path.join(tempDir, 'infra', 'storage'), { recursive: true });
+    await fs.writeFile(path.join(tempDir, 'infra', 'storage', 'main.tf'), TERRAFORM);
+    await fs.writeFile(path.join(tempDir, 'deploy.yaml'), MANIFESTS);
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('indexes resource chunks with the Terraform module path', async () => {
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+
+    const raw = await fs.readFile(
+      path.join(tempDir, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+      'utf-8'
+    );
+    const chunks = (JSON.parse(raw) as { chunks: CodeChunk[] }).chunks;
+    const bucket = chunks.find((c) => c.metadata.infra?.address === 'aws_s3_bucket.uploads');
+    const deployment = chunks.find((c) => c.metadata.infra?.address === 'Deployment/api');
+
+    expect(bucket?.metadata.infra?.modulePath).toBe('infra/storage');
+    expect(bucket?.startLine).toBe(6);
+    expect(deployment?.metadata.infra?.modulePath).toBeUndefined();
+  });
+});
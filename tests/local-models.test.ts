@@ -0,0 +1,60 @@
+import { describe, it, expect, beforeEach, vi } from 'vitest';
+import path from 'path';
+import { TransformersEmbeddingProvider } from '../src/embeddings/transformers.js';
+import { localModelOptionsFromEnv } from '../src/embeddings/local-models.js';
+
+const transformersEnv = vi.hoisted(() => ({
+  allowRemoteModels: true,
+  allowLocalModels: false,
+  localModelPath: '/models/',
+  cacheDir: ''
+}));
+const pipelineMock = vi.hoisted(() => vi.fn());
+
+vi.mock('@huggingface/transformers', () => ({ env: transformersEnv, pipeline: pipelineMock }));
+
+describe('local ONNX models', () => {
+  beforeEach(() => {
+    transformersEnv.allowRemoteModels = true;
+    transformersEnv.allowLocalModels = false;
+    transformersEnv.localModelPath = '/models/';
+    pipelineMock.mockReset();
+  });
+
+  it('reads the model directory and download switch from the environment', () => {
+    const env = { EMBEDDING_MODEL_PATH: ' /opt/models ', EMBEDDING_ALLOW_DOWNLOAD: 'false' };
+    expect(localModelOptionsFromEnv(env)).toEqual({
+      modelPath: '/opt/models',
+      allowDownload: false
+    });
+    expect(localModelOptionsFromEnv({ EMBEDDING_ALLOW_DOWNLOAD: 'true' })).toEqual({});
+  });
+
+  it('loads the model from the local directory without Hub downloads', async () => {
+    pipelineMock.mockResolvedValue(vi.fn());
+    const provider = new TransformersEmbeddingProvider('Xenova/bge-small-en-v1.5', {
+      modelPath: '/opt/models',
+      allowDownload: false
+    });
+    await provider.initialize();
+
+    expect(transformersEnv.allowRemoteModels).toBe(false);
+    expect(transformersEnv.allowLocalModels).toBe(true);
+    expect(transformersEnv.localModelPath).toBe(`${path.resolve('/opt/models')}${path.sep}`);
+    expect(pipelineMock).toHaveBeenCalledWith('feature-extraction', 'Xenova/bge-small-en-v1.5', {
+      dtype: 'q8'
+    });
+  });
+
+  it('explains how to provide a model that is missing offline', async () => {
+    pipelineMock.mockRejectedValue(new Error('Could not locate file'));
+    const provider = new TransformersEmbeddingProvider('Xenova/bge-small-en-v1.5', {
+      modelPath: '/opt/models',
+      allowDownload: false
+    });
+
+    await expect(provider.initialize()).rejects.toThrow(
+      /fetch-model --model Xenova\/bge-small-en-v1\.5 --to <dir>/
+    );
+  });
+});
@@ -0,0 +1,190 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import {
+  loadLspConfig,
+  parseHover,
+  parseServerOverrides,
+  stopLanguageServers
+} from '../src/core/lsp-bridge.js';
+import { loadConfigFiles } from '../src/core/config-file.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+// Answers initialize and hover like a real server; the hover names the position it was asked
+const FAKE_SERVER = String.raw`
+const fence = '\x60\x60\x60';
+let buffer = Buffer.alloc(0);
+const send = (message) => {
+  const body = Buffer.from(JSON.stringify({ jsonrpc: '2.0', ...message }));
+  process.stdout.write('Content-Length: ' + body.length + '\r\n\r\n');
+  process.stdout.write(body);
+};
+process.stdin.on('data', (chunk) => {
+  buffer = Buffer.concat([buffer, chunk]);
+  for (;;) {
+    const end = buffer.indexOf('\r\n\r\n');
+    if (end < 0) return;
+    const length = Number(/Content-Length: (\d+)/.exec(buffer.subarray(0, end).toString())[1]);
+    if (buffer.length < end + 4 + length) return;
+    const message = JSON.parse(buffer.subarray(end + 4, end + 4 + length).toString());
+    buffer = buffer.subarray(end + 4 + length);
+    if (message.method === 'initialize') {
+      send({ id: message.id, result: { capabilities: { hoverProvider: true } } });
+    } else if (message.method === 'textDocument/hover') {
+      const { line, character } = message.params.position;
+      send({
+        id: message.id,
+        result: {
+          contents: {
+            kind: 'markdown',
+            value:
+              fence + 'typescript\nfunction orderTotal(items: number[]): number\n' + fence +
+              '\n---\nSums the order (asked at ' + line + ':' + character + ').'
+          }
+        }
+      });
+    } else if (message.method === 'shutdown') {
+      send({ id: message.id, result: null });
+    } else if (message.method === 'exit') {
+      process.exit(0);
+    }
+  }
+});
+`;
+
+describe('LSP bridge', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  const configure = async (lsp: unknown) => {
+    await fs.writeFile(
+      path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME, 'config.json'),
+      JSON.stringify({ lsp })
+    );
+  };
+  const call = async (tool: string, args: Record<string, unknown>) =>
+    JSON.parse((await dispatchTool(tool, args, ctx)).content![0].text);
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'lsp-bridge-test-'));
+    await fs.mkdir(path.join(tempRoot, 'src'));
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'orders.ts'),
+      'export function orderTotal(items: number[]): number {\n' +
+        '  return items.reduce((sum, item) => sum + item, 0);\n' +
+        '}\n'
+    );
+    await fs.writeFile(path.join(tempRoot, 'fake-lsp.cjs'), FAKE_SERVER);
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const contextDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir: contextDir,
+        memory: path.join(contextDir, MEMORY_FILENAME),
+        intelligence: path.join(contextDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(contextDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(contextDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    delete process.env.CODEBASE_CONTEXT_LSP_SERVERS;
+    vi.restoreAllMocks();
+    await stopLanguageServers();
+    await rmWithRetries(tempRoot);
+  });
+
+  it('splits hover markdown into signature and prose', () => {
+    expect(parseHover('```go\nfunc Total(items []int) int\n```\n\nTotal sums items.')).toEqual({
+      signature: 'func Total(items []int) int',
+      documentation: 'Total sums items.'
+    });
+    expect(parseHover('(variable) count: number')).toEqual({
+      documentation: '(variable) count: number'
+    });
+  });
+
+  it('is off unless configured, and the environment wins', async () => {
+    expect((await loadLspConfig(tempRoot, {})).enabled).toBe(false);
+    await configure({ timeoutMs: 500 });
+    const config = await loadLspConfig(tempRoot, {
+      CODEBASE_CONTEXT_LSP_SERVERS: 'python=pylsp --check-parent-process;ruby=solargraph'
+    });
+    expect(config).toMatchObject({ enabled: true, timeoutMs: 500 });
+    expect(config.servers.python).toEqual(['pylsp', '--check-parent-process']);
+    expect(config.servers.go).toEqual(['gopls']);
+    expect((await loadLspConfig(tempRoot, { CODEBASE_CONTEXT_LSP: 'off' })).enabled).toBe(false);
+  });
+
+  it('takes server commands from the environment, never from the repo', async () => {
+    const warn = vi.spyOn(console, 'warn').mockImplementation(() => {});
+    await configure({ servers: { typescript: ['/bin/sh', '-c', 'touch pwned'] } });
+    const config = await loadLspConfig(tempRoot, {});
+    expect(config.servers.typescript).toEqual(['typescript-language-server', '--stdio']);
+    expect(warn.mock.calls.some(([message]) => String(message).includes('lsp.servers'))).toBe(true);
+
+    expect(parseServerOverrides('{"go": ["/opt/go tools/gopls", "serve"]}')).toEqual({
+      go: ['/opt/go tools/gopls', 'serve']
+    });
+
+    // A repo's codebase-context.yaml can't set the variable either
+    await fs.writeFile(
+      path.join(tempRoot, 'codebase-context.yaml'),
+      'env:\n  CODEBASE_CONTEXT_LSP_SERVERS: "typescript=/bin/sh -c id"\n'
+    );
+    expect(() =>
+      loadConfigFiles(tempRoot, { CODEBASE_CONTEXT_CONFIG: path.join(tempRoot, 'none.yaml') })
+    ).toThrow(/only be set in the user config/);
+  });
+
+  it('adds hover text and typed signatures from the language server', async () => {
+    process.env.CODEBASE_CONTEXT_LSP_SERVERS = JSON.stringify({
+      typescript: [process.execPath, path.join(tempRoot, 'fake-lsp.cjs')]
+    });
+    await configure({ timeoutMs: 10_000 });
+
+    const definition = await call('get_definition', { symbol: 'orderTotal' });
+    expect(definition.confidence).toBe('syntactic');
+    expect(definition.languageServers).toHaveLength(1);
+    // The hover was asked for where the name is written: line 0, after `export function `
+    expect(definition.definitions[0].documentation).toContain('Sums the order (asked at 0:16)');
+
+    const docs = await call('get_symbol_docs', { symbol: 'orderTotal' });
+    expect(docs.symbols[0]).toMatchObject({
+      signature: 'function orderTotal(items: number[]): number',
+      doc: 'Sums the order (asked at 0:16).',
+      docSource: 'lsp'
+    });
+  });
+
+  it('falls back to static extraction when the server is not installed', async () => {
+    process.env.CODEBASE_CONTEXT_LSP_SERVERS = 'typescript=no-such-language-server --stdio';
+    await configure(true);
+
+    const definition = await call('get_definition', { symbol: 'orderTotal' });
+    expect(definition.status).toBe('success');
+    expect(definition.definitions[0].documentation).toBeUndefined();
+    expect(definition.languageServers).toBeUndefined();
+
+    const docs = await call('get_symbol_docs', { symbol: 'orderTotal' });
+    expect(docs.symbols[0].signature).toBe(
+      'export function orderTotal(items: number[]): number {'
+    );
+  });
+});
@@ -7,6 +7,7 @@ import {
   readManifest,
   writeManifest,
   computeFileHashes,
+  computeFileHashesForChanges,
   diffManifest,
   type FileManifest
 } from '../src/core/manifest.js';
@@ -121,6 +122,60 @@ describe('Manifest System', () => {
     });
   });
 
+  describe('computeFileHashesForChanges', () => {
+    const previous: FileManifest = {
+      version: 1,
+      generatedAt: '2026-01-01T00:00:00.000Z',
+      files: { 'a.ts': 'aaaaaaaaaaaaaaaa', 'b.ts': 'bbbbbbbbbbbbbbbb' }
+    };
+
+    it('should only read changed and new files', async () => {
+      const reads: string[] = [];
+      const mockRead = async (p: string) => {
+        reads.push(path.basename(p));
+        return `content of ${path.basename(p)}`;
+      };
+      const files = ['a.ts', 'b.ts', 'c.ts'].map((f) => path.join(testDir, f));
+
+      const hashes = await computeFileHashesForChanges(
+        files,
+        testDir,
+        previous,
+        [path.join(testDir, 'b.ts')],
+        mockRead
+      );
+
+      expect(reads.sort()).toEqual(['b.ts', 'c.ts']);
+      expect(hashes['a.ts']).toBe('aaaaaaaaaaaaaaaa');
+      expect(hashes['b.ts']).toBe(hashFileContent('content of b.ts'));
+      expect(hashes['c.ts']).toBe(hashFileContent('content of c.ts'));
+    });
+
+    it('should accept root-relative changed paths', async () => {
+      const mockRead = async () => 'updated';
+      const hashes = await computeFileHashesForChanges(
+        [path.join(testDir, 'a.ts')],
+        testDir,
+        previous,
+        ['a.ts'],
+        mockRead
+      );
+      expect(hashes['a.ts']).toBe(hashFileContent('updated'));
+    });
+
+    it('should drop files that are no longer on disk so diff reports them deleted', async () => {
+      const hashes = await computeFileHashesForChanges(
+        [path.join(testDir, 'a.ts')],
+        testDir,
+        previous,
+        [path.join(testDir, 'b.ts')]
+      );
+      const diff = diffManifest(previous, hashes);
+      expect(diff.deleted).toEqual(['b.ts']);
+      expect(diff.unchanged).toEqual(['a.ts']);
+    });
+  });
+
   describe('diffManifest', () => {
     it('should treat all files as added when old manifest is null', () => {
       const currentHashes = {
@@ -158,6 +213,33 @@ describe('Manifest System', () => {
       expect(diff.unchanged).toEqual(['src/unchanged.ts']);
     });
 
+    it('should report a deleted and an added file with the same hash as a rename', () => {
+      const oldManifest: FileManifest = {
+        version: 1,
+        generatedAt: '2026-01-01T00:00:00.000Z',
+        files: {
+          'src/util.ts': 'aaaa000000000000',
+          'src/a/main.ts': 'bbbb000000000000',
+          'src/b/index.ts': 'bbbb000000000000',
+          'src/gone.ts': 'cccc000000000000'
+        }
+      };
+
+      const diff = diffManifest(oldManifest, {
+        'lib/util.ts': 'aaaa000000000000',
+        'lib/index.ts': 'bbbb000000000000',
+        'src/b/main.ts': 'bbbb000000000000',
+        'src/new.ts': 'dddd000000000000'
+      });
+      expect(diff.renamed).toEqual([
+        { from: 'src/util.ts', to: 'lib/util.ts' },
+        { from: 'src/b/index.ts', to: 'lib/index.ts' },
+        { from: 'src/a/main.ts', to: 'src/b/main.ts' }
+      ]);
+      expect(diff.added).toEqual(['src/new.ts']);
+      expect(diff.deleted).toEqual(['src/gone.ts']);
+    });
+
     it('should handle empty manifests', () => {
       const oldManifest: FileManifest = {
         version: 1,
@@ -0,0 +1,124 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { CodebaseSearcher } from '../src/core/search.js';
+import { createMarkdownChunks, findMarkdownSections } from '../src/utils/markdown-chunker.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME
+} from '../src/constants/codebase-context.js';
+import type { CodeChunk } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const ADR = `---
+status: accepted
+---
+Context first.
+
+# Storage design
+
+We keep the index on disk.
+
+## Decisions
+
+### Index format
+
+Chunks are stored as JSON.
+
+\`\`\`bash
+# not a heading
+\`\`\`
+
+Consequences
+------------
+
+Readers must tolerate [older](./0001.md) files.
+`;
+
+describe('findMarkdownSections', () => {
+  it('splits on headings with their breadcrumb, ignoring fences and front matter', () => {
+    const sections = findMarkdownSections(ADR);
+
+    expect(sections.map((s) => [s.startLine, s.endLine, s.level, s.headingPath])).toEqual([
+      [1, 5, 0, []],
+      [6, 9, 1, ['Storage design']],
+      // "Decisions" has no text of its own and is folded into its subsection
+      [10, 19, 3, ['Storage design', 'Decisions', 'Index format']],
+      [20, 24, 2, ['Storage design', 'Consequences']]
+    ]);
+  });
+});
+
+describe('createMarkdownChunks', () => {
+  it('emits documentation chunks carrying the heading path', () => {
+    const chunks = createMarkdownChunks(ADR, {
+      filePath: '/repo/docs/adr/0002-storage.md',
+      relativePath: 'docs/adr/0002-storage.md',
+      language: 'markdown'
+    });
+    const format = chunks.find((c) => c.metadata.heading === 'Index format');
+
+    expect(chunks).toHaveLength(4);
+    expect(format).toMatchObject({ componentType: 'documentation', startLine: 10, endLine: 19 });
+    expect(format?.metadata).toMatchObject({
+      headingLevel: 3,
+      headingPath: ['Storage design', 'Decisions', 'Index format'],
+      chunkStrategy: 'markdown-section'
+    });
+    expect(format?.content.split('\n')[0]).toBe(
+      '<!-- Storage design > Decisions > Index format -->'
+    );
+    // Top-level sections start with their own heading; no breadcrumb line
+    expect(chunks[1].content.startsWith('# Storage design')).toBe(true);
+  });
+});
+
+describe('indexer and search with docs', () => {
+  let tempRoot: string;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'markdown-index-test-'));
+    await fs.mkdir(path.join(tempRoot, 'docs', 'adr'), { recursive: true });
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.writeFile(path.join(tempRoot, 'docs', 'adr', '0002-storage.md'), ADR);
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'storage.ts'),
+      'export function writeIndexFormat(chunks: string[]) {\n  return JSON.stringify(chunks);\n}\n'
+    );
+    await fs.writeFile(path.join(tempRoot, 'CHANGELOG.md'), '# Changelog\n\n- index format\n');
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('indexes markdown sections and skips changelogs by default', async () => {
+    const raw = await fs.readFile(
+      path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+      'utf-8'
+    );
+    const chunks = (JSON.parse(raw) as { chunks: CodeChunk[] }).chunks;
+    const files = new Set(chunks.map((c) => c.relativePath.replace(/\\/g, '/')));
+
+    expect([...files].some((file) => file.endsWith('CHANGELOG.md'))).toBe(false);
+    expect(chunks.find((c) => c.metadata.heading === 'Consequences')?.metadata.headingPath).toEqual(
+      ['Storage design', 'Consequences']
+    );
+  });
+
+  it('restricts keyword results to docs or to code', async () => {
+    const searcher = new CodebaseSearcher(tempRoot);
+    const options = { useSemanticSearch: false, useKeywordSearch: true, enableReranker: false };
+
+    const docs = await searcher.search('chunks JSON', 10, { docsOnly: true }, options);
+    expect(docs.length).toBeGreaterThan(0);
+    expect(docs.every((r) => r.filePath.endsWith('.md'))).toBe(true);
+
+    const code = await searcher.search('chunks JSON', 10, { codeOnly: true }, options);
+    expect(code.length).toBeGreaterThan(0);
+    expect(code.every((r) => r.filePath.endsWith('storage.ts'))).toBe(true);
+  });
+});
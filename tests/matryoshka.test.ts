@@ -0,0 +1,87 @@
+import { describe, it, expect, vi, afterEach } from 'vitest';
+import {
+  TruncatedEmbeddingProvider,
+  isMatryoshkaModel,
+  truncateEmbedding
+} from '../src/embeddings/matryoshka.js';
+import { providerFingerprint, resolveEmbeddingModel } from '../src/embeddings/index.js';
+import type { EmbeddingProvider } from '../src/embeddings/types.js';
+import { describeEmbeddingDrift } from '../src/core/index-meta.js';
+
+function fakeProvider(modelName: string, dimensions = 8): EmbeddingProvider {
+  const vector = (text: string) =>
+    Array.from({ length: dimensions }, (_, i) => ((text.length + i) % 5) + 1);
+  return {
+    name: 'ollama',
+    modelName,
+    dimensions,
+    initialize: async () => {},
+    isReady: () => true,
+    embed: async (text) => vector(text),
+    embedBatch: async (texts) => texts.map(vector)
+  };
+}
+
+const norm = (vector: number[]) => Math.sqrt(vector.reduce((sum, v) => sum + v * v, 0));
+
+describe('Matryoshka truncation', () => {
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  it('keeps the leading components at unit length', () => {
+    const truncated = truncateEmbedding([3, 4, 12, 84], 2);
+    expect(truncated).toEqual([0.6, 0.8]);
+    expect(() => truncateEmbedding([1, 2], 3)).toThrow(/2-dimensional/);
+  });
+
+  it('cuts documents and queries alike and reports the cut size', async () => {
+    const provider = new TruncatedEmbeddingProvider(fakeProvider('nomic-embed-text:latest'), 4);
+    await provider.initialize();
+
+    const [document] = await provider.embedBatch(['export function total() {}']);
+    const query = await provider.embed('export function total() {}');
+    expect(document).toHaveLength(4);
+    expect(query).toEqual(document);
+    expect(norm(query)).toBeCloseTo(1, 6);
+    expect(providerFingerprint(provider)).toEqual({
+      provider: 'ollama',
+      model: 'nomic-embed-text:latest',
+      dimensions: 4,
+      truncateDimensions: 4
+    });
+  });
+
+  it('refuses sizes past the native one and warns for models not trained for it', async () => {
+    await expect(
+      new TruncatedEmbeddingProvider(fakeProvider('mxbai-embed-large'), 16).initialize()
+    ).rejects.toThrow(/it produces 8/);
+
+    const warn = vi.spyOn(console, 'warn').mockImplementation(() => {});
+    await new TruncatedEmbeddingProvider(fakeProvider('all-minilm'), 4).initialize();
+    expect(warn).toHaveBeenCalledWith(expect.stringContaining('not known to be Matryoshka'));
+    expect(isMatryoshkaModel('voyage-code-3')).toBe(true);
+    expect(isMatryoshkaModel('Xenova/bge-small-en-v1.5')).toBe(false);
+  });
+
+  it('treats a change of truncation as a different model', () => {
+    const configured = resolveEmbeddingModel({ provider: 'ollama', truncateDimensions: 256 });
+    expect(configured).toEqual({
+      provider: 'ollama',
+      model: 'nomic-embed-text',
+      dimensions: 256,
+      truncateDimensions: 256
+    });
+
+    const built = { provider: 'ollama', model: 'nomic-embed-text', dimensions: 768 };
+    const truncated = { ...built, dimensions: 256, truncateDimensions: 256 };
+    expect(describeEmbeddingDrift(built, configured)).toContain(
+      ', configured ollama:nomic-embed-text (256d), truncated to 256'
+    );
+    expect(describeEmbeddingDrift(truncated, configured)).toBeNull();
+    // Dimensions of Ollama models aren't known up front; the recorded truncation still is
+    expect(
+      describeEmbeddingDrift(truncated, resolveEmbeddingModel({ provider: 'ollama' }))
+    ).toContain('truncated to 256');
+  });
+});
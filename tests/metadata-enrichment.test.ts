@@ -0,0 +1,177 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { CodebaseSearcher } from '../src/core/search.js';
+import { ownersForFile, parseCodeowners } from '../src/core/metadata-enrichment.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  PROJECT_CONFIG_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import type { CodeChunk } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+vi.mock('../src/embeddings/index.js', async (importOriginal) => {
+  const original = await importOriginal<typeof import('../src/embeddings/index.js')>();
+  const provider = {
+    name: 'fake',
+    modelName: 'fake-model',
+    dimensions: 2,
+    initialize: async () => {},
+    isReady: () => true,
+    embed: async () => [1, 0],
+    embedBatch: async (texts: string[]) => texts.map(() => [1, 0])
+  };
+  return { ...original, getEmbeddingProvider: async () => provider };
+});
+
+describe('CODEOWNERS parsing', () => {
+  it('applies the last matching rule and honors ownerless overrides', () => {
+    const rules = parseCodeowners(
+      [
+        '# Default owners',
+        '*       @acme/core',
+        '[Billing]',
+        '/services/billing/  @acme/payments @alice  # payments team',
+        '/services/billing/generated/'
+      ].join('\n')
+    );
+
+    expect(rules).toHaveLength(3);
+    expect(ownersForFile(rules, 'src/app.ts')).toEqual(['@acme/core']);
+    expect(ownersForFile(rules, 'services/billing/invoice.ts')).toEqual([
+      '@acme/payments',
+      '@alice'
+    ]);
+    expect(ownersForFile(rules, 'services/billing/generated/client.ts')).toEqual([]);
+  });
+});
+
+describe('metadata enrichment', () => {
+  let tempDir: string;
+
+  const write = async (file: string, content: string) => {
+    await fs.mkdir(path.dirname(path.join(tempDir, file)), { recursive: true });
+    await fs.writeFile(path.join(tempDir, file), content);
+  };
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'metadata-enrichment-'));
+    await write('.github/CODEOWNERS', '* @acme/core\n/services/billing/ @acme/payments\n');
+    await write(
+      path.join(CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME),
+      JSON.stringify({
+        enrichment: {
+          modules: { 'services/billing/': 'billing', '**/legacy/': 'legacy' },
+          annotations: [{ name: 'deprecated', pattern: '@deprecated\\b[ \\t]*([^*\\n]*)' }]
+        }
+      })
+    );
+    await write(
+      'services/billing/legacy/client.ts',
+      [
+        '/** @deprecated use PaymentGateway */',
+        'export function chargePaymentClient(amount: number) {',
+        '  return amount;',
+        '}',
+        ''
+      ].join('\n')
+    );
+    await write(
+      'services/billing/gateway.ts',
+      'export function chargePaymentGateway(amount: number) {\n  return amount;\n}\n'
+    );
+    await write('web/checkout.ts', 'export function payment() {\n  return "payment";\n}\n');
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('tags chunks from the project config and filters searches on any field', async () => {
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+
+    const raw = await fs.readFile(
+      path.join(tempDir, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+      'utf-8'
+    );
+    const chunks = (JSON.parse(raw) as { chunks: CodeChunk[] }).chunks;
+    const metadataOf = (suffix: string) =>
+      chunks.find((chunk) => chunk.relativePath.replace(/\\/g, '/').endsWith(suffix))?.metadata;
+
+    expect(metadataOf('legacy/client.ts')).toMatchObject({
+      owners: ['@acme/payments'],
+      modules: ['billing', 'legacy'],
+      annotations: { deprecated: 'use PaymentGateway' }
+    });
+    expect(metadataOf('gateway.ts')?.annotations).toBeUndefined();
+    expect(metadataOf('checkout.ts')).toMatchObject({ owners: ['@acme/core'] });
+    expect(metadataOf('checkout.ts')?.modules).toBeUndefined();
+
+    const searcher = new CodebaseSearcher(tempDir);
+    const keywordOnly = {
+      useSemanticSearch: false,
+      useKeywordSearch: true,
+      enableReranker: false
+    };
+    const deprecated = await searcher.search(
+      'amount',
+      10,
+      { metadata: { 'annotations.deprecated': true } },
+      keywordOnly
+    );
+    expect(deprecated.map((r) => r.filePath.replace(/\\/g, '/'))).toEqual([
+      expect.stringContaining('legacy/client.ts')
+    ]);
+
+    const billing = await searcher.search(
+      'amount',
+      10,
+      { metadata: { modules: 'billing', 'annotations.deprecated': false } },
+      keywordOnly
+    );
+    expect(billing.length).toBeGreaterThan(0);
+    expect(billing.every((r) => r.filePath.replace(/\\/g, '/').endsWith('gateway.ts'))).toBe(true);
+  });
+
+  it('prefers explicit indexer config and rejects malformed metadata filters', async () => {
+    await new CodebaseIndexer({
+      rootPath: tempDir,
+      config: { skipEmbedding: true, enrichment: { codeowners: false } }
+    }).index();
+
+    const raw = await fs.readFile(
+      path.join(tempDir, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+      'utf-8'
+    );
+    const chunks = (JSON.parse(raw) as { chunks: CodeChunk[] }).chunks;
+    expect(chunks.some((chunk) => chunk.metadata.owners || chunk.metadata.modules)).toBe(false);
+
+    const baseDir = path.join(tempDir, CODEBASE_CONTEXT_DIRNAME);
+    const ctx: ToolContext = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempDir,
+      performIndexing: () => {}
+    };
+    const invalid = await dispatchTool(
+      'search_codebase',
+      { query: 'payment', filters: { metadata: 'owners' } },
+      ctx
+    );
+    expect(invalid.isError).toBe(true);
+  });
+});
@@ -0,0 +1,214 @@
+import { describe, it, expect, afterEach, vi } from 'vitest';
+import {
+  MilvusStorageProvider,
+  buildMilvusFilter,
+  deriveMilvusCollectionName
+} from '../src/storage/milvus.js';
+import type { CodeChunkWithEmbedding } from '../src/storage/types.js';
+
+interface RecordedCall {
+  url: string;
+  body: Record<string, unknown>;
+}
+
+function mockMilvus(handler: (call: RecordedCall) => unknown) {
+  const calls: RecordedCall[] = [];
+  vi.stubGlobal(
+    'fetch',
+    vi.fn(async (url: string, init?: RequestInit) => {
+      const call: RecordedCall = { url, body: JSON.parse(String(init?.body)) };
+      calls.push(call);
+      return new Response(JSON.stringify({ code: 0, data: handler(call) ?? {} }), {
+        status: 200
+      });
+    })
+  );
+  return calls;
+}
+
+function makeChunk(id: string, filePath: string): CodeChunkWithEmbedding {
+  return {
+    id,
+    content: 'export const x = 1;',
+    filePath,
+    relativePath: filePath.replace('/repo/', ''),
+    startLine: 1,
+    endLine: 1,
+    language: 'typescript',
+    dependencies: [],
+    imports: [],
+    exports: ['x'],
+    tags: ['util'],
+    metadata: { componentName: 'x' },
+    embedding: [0.1, 0.2, 0.3]
+  };
+}
+
+describe('MilvusStorageProvider', () => {
+  afterEach(() => {
+    vi.unstubAllGlobals();
+  });
+
+  it('derives a stable per-project collection name', () => {
+    const a = deriveMilvusCollectionName('/work/My Repo');
+    expect(a).toMatch(/^codebase_context_my_repo_[0-9a-f]{8}$/);
+    expect(deriveMilvusCollectionName('/work/My Repo')).toBe(a);
+    expect(deriveMilvusCollectionName('/other/My Repo')).not.toBe(a);
+    expect(() => new MilvusStorageProvider({ collection: 'no-dashes' })).toThrow(/Invalid/);
+  });
+
+  it('builds filter expressions for fields, tags and paths', () => {
+    expect(buildMilvusFilter(undefined)).toBe('');
+    expect(buildMilvusFilter({})).toBe('');
+    expect(
+      buildMilvusFilter({
+        language: 'go',
+        tags: ['api'],
+        filePaths: ['src/a "quoted".go'],
+        excludePaths: ['vendor/x.go']
+      })
+    ).toBe(
+      'language == "go" and ARRAY_CONTAINS_ANY(tags, ["api"]) and ' +
+        '(filePath in ["src/a \\"quoted\\".go"] or ' +
+        'relativePath in ["src/a \\"quoted\\".go"]) and ' +
+        'filePath not in ["vendor/x.go"] and relativePath not in ["vendor/x.go"]'
+    );
+  });
+
+  it('creates the collection on first store and upserts rows', async () => {
+    const calls = mockMilvus((call) =>
+      call.url.endsWith('/collections/has') ? { has: false } : undefined
+    );
+
+    const provider = new MilvusStorageProvider({ url: 'http://m:19530/', collection: 'proj' });
+    await provider.initialize('/unused');
+    expect(await provider.search([0.1, 0.2, 0.3], 5)).toEqual([]);
+
+    await provider.store([makeChunk('chunk-1', '/repo/a.ts')]);
+
+    const create = calls.find((c) => c.url === 'http://m:19530/v2/vectordb/collections/create');
+    const fields = (create?.body.schema as { fields: Array<Record<string, unknown>> }).fields;
+    expect(fields.find((f) => f.fieldName === 'vector')).toEqual({
+      fieldName: 'vector',
+      dataType: 'FloatVector',
+      elementTypeParams: { dim: 3 }
+    });
+    expect(fields.find((f) => f.isPrimary)?.fieldName).toBe('id');
+
+    const upsert = calls.find((c) => c.url.endsWith('/entities/upsert'));
+    expect(upsert?.body).toMatchObject({
+      collectionName: 'proj',
+      data: [
+        {
+          id: 'chunk-1',
+          filePath: '/repo/a.ts',
+          framework: '',
+          tags: ['util'],
+          payload: { exports: ['x'], metadata: { componentName: 'x' } }
+        }
+      ]
+    });
+  });
+
+  it('returns chunks from search results and sends the token', async () => {
+    const { embedding: _embedding, ...chunk } = makeChunk('chunk-1', '/repo/a.ts');
+    const calls = mockMilvus((call) => {
+      if (call.url.endsWith('/collections/has')) return { has: true };
+      if (call.url.endsWith('/entities/search')) {
+        return [
+          {
+            ...chunk,
+            framework: '',
+            componentType: '',
+            layer: '',
+            payload: { dependencies: [], imports: [], exports: ['x'], metadata: {} },
+            distance: 0.87
+          }
+        ];
+      }
+      return undefined;
+    });
+
+    const provider = new MilvusStorageProvider({ collection: 'proj', token: 'root:Milvus' });
+    await provider.initialize('/unused');
+    const results = await provider.search([0.1, 0.2, 0.3], 3, { language: 'typescript' });
+
+    expect(results).toHaveLength(1);
+    expect(results[0].score).toBeCloseTo(0.87);
+    expect(results[0].chunk).toMatchObject({ id: 'chunk-1', relativePath: 'a.ts', tags: ['util'] });
+    expect(results[0].chunk.framework).toBeUndefined();
+
+    const search = calls.find((c) => c.url.endsWith('/entities/search'));
+    expect(search?.body).toMatchObject({
+      limit: 3,
+      annsField: 'vector',
+      filter: 'language == "typescript"'
+    });
+    const headers = vi.mocked(fetch).mock.calls[0]?.[1]?.headers as Record<string, string>;
+    expect(headers.Authorization).toBe('Bearer root:Milvus');
+  });
+
+  it('reports error codes sent with HTTP 200', async () => {
+    vi.stubGlobal(
+      'fetch',
+      vi.fn(async () => {
+        const body = { code: 1800, message: "user hasn't authenticated" };
+        return new Response(JSON.stringify(body));
+      })
+    );
+    const provider = new MilvusStorageProvider({ collection: 'proj' });
+    await expect(provider.initialize('/unused')).rejects.toThrow(/user hasn't authenticated/);
+  });
+
+  it('deletes by file path and moves renamed files by rewriting their rows', async () => {
+    const stored = { ...makeChunk('chunk-1', '/repo/old.ts'), vector: [0.1, 0.2, 0.3] };
+    const calls = mockMilvus((call) => {
+      if (call.url.endsWith('/collections/has')) return { has: true };
+      if (call.url.endsWith('/entities/query')) {
+        return (call.body.outputFields as string[]).includes('count(*)')
+          ? [{ 'count(*)': 4 }]
+          : [stored];
+      }
+      return undefined;
+    });
+
+    const provider = new MilvusStorageProvider({ collection: 'proj' });
+    await provider.initialize('/unused');
+    expect(await provider.deleteByFilePaths(['/repo/a.ts'])).toBe(4);
+    const del = calls.find((c) => c.url.endsWith('/entities/delete'));
+    expect(del?.body).toEqual({ collectionName: 'proj', filter: 'filePath in ["/repo/a.ts"]' });
+
+    const moved = await provider.renameFilePaths([
+      { from: '/repo/old.ts', to: '/repo/new.ts', relativePath: 'new.ts' }
+    ]);
+    expect(moved).toBe(1);
+    const upsert = calls.find((c) => c.url.endsWith('/entities/upsert'));
+    expect(upsert?.body.data).toEqual([
+      { ...stored, filePath: '/repo/new.ts', relativePath: 'new.ts' }
+    ]);
+  });
+
+  it('rebuilds into a new generation and moves the alias only on commit', async () => {
+    const calls = mockMilvus((call) => {
+      if (call.url.endsWith('/collections/has')) return { has: false };
+      if (call.url.endsWith('/aliases/describe')) return { collectionName: 'proj_old' };
+      return undefined;
+    });
+
+    const provider = new MilvusStorageProvider({ collection: 'proj' });
+    await provider.initialize('/unused');
+    await provider.beginGeneration();
+    await provider.store([makeChunk('chunk-1', '/repo/a.ts')]);
+
+    const upsert = calls.find((c) => c.url.endsWith('/entities/upsert'));
+    const generation = upsert?.body.collectionName;
+    expect(generation).toMatch(/^proj_[0-9a-z]+$/);
+    expect(calls.some((c) => c.url.endsWith('/collections/drop'))).toBe(false);
+
+    await provider.commitGeneration();
+    const alter = calls.find((c) => c.url.endsWith('/aliases/alter'));
+    expect(alter?.body).toEqual({ aliasName: 'proj', collectionName: generation });
+    const dropped = calls.filter((c) => c.url.endsWith('/collections/drop'));
+    expect(dropped.map((c) => c.body)).toEqual([{ collectionName: 'proj_old' }]);
+  });
+});
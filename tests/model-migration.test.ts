@@ -0,0 +1,176 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { CodebaseSearcher } from '../src/core/search.js';
+import { readIndexMeta } from '../src/core/index-meta.js';
+import {
+  activeModelMigration,
+  clearModelMigration,
+  previousModelConfig,
+  readModelMigration,
+  startModelMigration
+} from '../src/core/model-migration.js';
+import { resolveEmbeddingModel } from '../src/embeddings/index.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const fake = vi.hoisted(() => ({
+  /** Model of every query embedded, in order */
+  queries: [] as string[],
+  /** Runs inside the first embedding batch of a build, while the build is in progress */
+  duringBuild: null as (() => Promise<void>) | null
+}));
+
+vi.mock('../src/embeddings/index.js', async (importOriginal) => {
+  const original = await importOriginal<typeof import('../src/embeddings/index.js')>();
+  // Each model's vectors point another way, so mixing them would be visible
+  const providerFor = (modelName: string) => ({
+    name: 'ollama',
+    modelName,
+    dimensions: 4,
+    initialize: async () => {},
+    isReady: () => true,
+    embed: async () => {
+      fake.queries.push(modelName);
+      return modelName === 'model-a' ? [1, 0, 0, 0] : [0, 1, 0, 0];
+    },
+    embedBatch: async (texts: string[]) => {
+      const duringBuild = fake.duringBuild;
+      fake.duringBuild = null;
+      await duringBuild?.();
+      return texts.map(() => (modelName === 'model-a' ? [1, 0, 0, 0] : [0, 1, 0, 0]));
+    }
+  });
+  return {
+    ...original,
+    getEmbeddingProvider: async (config?: { model?: string }) =>
+      providerFor(config?.model ?? 'model-a')
+  };
+});
+
+const MODEL_A = { provider: 'ollama' as const, model: 'model-a' };
+const MODEL_B = { provider: 'ollama' as const, model: 'model-b' };
+
+describe('embedding model migration', () => {
+  let tempRoot: string;
+  let contextDir: string;
+
+  beforeEach(async () => {
+    fake.queries = [];
+    fake.duringBuild = null;
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+    vi.spyOn(console, 'warn').mockImplementation(() => {});
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'model-migration-'));
+    contextDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'refunds.ts'),
+      'export function refundPayment(total: number) {\n  return total * 0.5;\n}\n'
+    );
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { embedding: MODEL_A } }).index();
+  });
+
+  afterEach(async () => {
+    vi.restoreAllMocks();
+    await rmWithRetries(tempRoot);
+  });
+
+  it('records a model change once and drops it when the models agree again', async () => {
+    expect(await startModelMigration(tempRoot, resolveEmbeddingModel(MODEL_A))).toBeNull();
+
+    const migration = await startModelMigration(tempRoot, resolveEmbeddingModel(MODEL_B));
+    expect(migration).toMatchObject({
+      from: { provider: 'ollama', model: 'model-a', dimensions: 4 },
+      to: { provider: 'ollama', model: 'model-b' }
+    });
+    const again = await startModelMigration(tempRoot, resolveEmbeddingModel(MODEL_B));
+    expect(again?.startedAt).toBe(migration?.startedAt);
+
+    const { embedding } = await readIndexMeta(tempRoot);
+    expect(await activeModelMigration(contextDir, embedding)).toEqual(migration);
+    expect(
+      await activeModelMigration(contextDir, { provider: 'ollama', model: 'other', dimensions: 4 })
+    ).toBeNull();
+    expect(previousModelConfig(migration!)).toEqual({
+      provider: 'ollama',
+      model: 'model-a',
+      dimensions: undefined,
+      truncateDimensions: undefined
+    });
+
+    // Switched back before the build ran: nothing to migrate
+    expect(await startModelMigration(tempRoot, resolveEmbeddingModel(MODEL_A))).toBeNull();
+    expect(await readModelMigration(contextDir)).toBeNull();
+  });
+
+  it('queries the previous model until the re-embedded index swaps in', async () => {
+    const search = (model: typeof MODEL_A) =>
+      new CodebaseSearcher(tempRoot, { embedding: model }).search('refund payment', 3);
+
+    await expect(search(MODEL_B)).rejects.toThrow(/Embedding model changed/);
+
+    let midBuild: { file?: string; migrating: boolean } | null = null;
+    fake.duringBuild = async () => {
+      const searcher = new CodebaseSearcher(tempRoot, { embedding: MODEL_B });
+      const [result] = await searcher.search('refund payment', 3);
+      midBuild = { file: result?.filePath, migrating: searcher.getModelMigration() !== null };
+    };
+    fake.queries = [];
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { embedding: MODEL_B } }).index();
+
+    expect(midBuild).toEqual({ file: expect.stringContaining('refunds.ts'), migrating: true });
+    expect(new Set(fake.queries)).toEqual(new Set(['model-a']));
+    expect(await readModelMigration(contextDir)).toBeNull();
+    expect((await readIndexMeta(tempRoot)).embedding?.model).toBe('model-b');
+
+    fake.queries = [];
+    const searcher = new CodebaseSearcher(tempRoot, { embedding: MODEL_B });
+    await searcher.search('refund payment', 3);
+    expect(searcher.getModelMigration()).toBeNull();
+    expect(new Set(fake.queries)).toEqual(new Set(['model-b']));
+  });
+
+  it('keeps search_codebase serving while the migration build runs', async () => {
+    await startModelMigration(tempRoot, resolveEmbeddingModel(MODEL_B));
+    const ctx: ToolContext = {
+      indexState: { status: 'indexing', lastIndexed: new Date() },
+      paths: {
+        baseDir: contextDir,
+        memory: path.join(contextDir, MEMORY_FILENAME),
+        intelligence: path.join(contextDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(contextDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(contextDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+
+    const served = JSON.parse(
+      (await dispatchTool('search_codebase', { query: 'refund payment' }, ctx)).content![0].text
+    );
+    expect(served.status).toBe('success');
+    expect(served.migration).toMatchObject({ from: 'ollama:model-a', to: 'ollama:model-b' });
+    expect(served.results[0].file).toContain('refunds.ts');
+
+    const status = JSON.parse(
+      (await dispatchTool('get_indexing_status', {}, ctx)).content![0].text
+    );
+    expect(status.migration).toMatchObject({ from: 'ollama:model-a', to: 'ollama:model-b' });
+
+    await clearModelMigration(contextDir);
+    const waiting = JSON.parse(
+      (await dispatchTool('search_codebase', { query: 'refund payment' }, ctx)).content![0].text
+    );
+    expect(waiting.status).toBe('indexing');
+  });
+});
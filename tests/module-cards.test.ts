@@ -0,0 +1,180 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { getModuleCard, listModules, readmePurpose } from '../src/core/module-cards.js';
+import { readModuleCard, readModuleList } from '../src/resources/modules.js';
+import { moduleCardUri, parseModuleCardUri, parseModulesUri } from '../src/resources/uri.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const FILES: Record<string, string> = {
+  'src/payments/README.md': [
+    '# Payments',
+    '',
+    '[![build](https://ci.example/badge.svg)](https://ci.example)',
+    '',
+    'Refunds and captures for card payments. Talks to the acquirer through the gateway client.',
+    ''
+  ].join('\n'),
+  'src/payments/refund.ts': [
+    "import { z } from 'zod';",
+    "import { toCents } from '../utils/money.js';",
+    '',
+    'export const RefundRequest = z.object({ amount: z.number() });',
+    '',
+    'export function refundPayment(amount: number) {',
+    '  return toCents(amount);',
+    '}',
+    ''
+  ].join('\n'),
+  'src/payments/refund.test.ts': [
+    "import { refundPayment } from './refund.js';",
+    '',
+    'refundPayment(1);',
+    ''
+  ].join('\n'),
+  'src/payments/capture.ts': [
+    'export function capturePayment(id: string) {',
+    '  return id;',
+    '}',
+    ''
+  ].join('\n'),
+  'src/utils/money.ts': [
+    '/**',
+    ' * Money helpers in integer cents.',
+    ' */',
+    '',
+    'export function toCents(amount: number) {',
+    '  return Math.round(amount * 100);',
+    '}',
+    ''
+  ].join('\n'),
+  'src/api/routes.ts': [
+    "import { refundPayment } from '../payments/refund.js';",
+    '',
+    'export function refundRoute() {',
+    '  return refundPayment(5);',
+    '}',
+    ''
+  ].join('\n')
+};
+
+describe('module cards', () => {
+  let tempRoot: string;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'module-cards-'));
+    for (const [file, content] of Object.entries(FILES)) {
+      await fs.mkdir(path.dirname(path.join(tempRoot, file)), { recursive: true });
+      await fs.writeFile(path.join(tempRoot, file), content);
+    }
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('takes the purpose from the first README paragraph', () => {
+    expect(readmePurpose('# Title\n\n![logo](x.png)\n\nDoes one thing. Then more.\n')).toBe(
+      'Does one thing.'
+    );
+    expect(readmePurpose('# Only a heading\n')).toBeUndefined();
+  });
+
+  it('summarizes a directory from the index', async () => {
+    const card = await getModuleCard(tempRoot, './src/payments/');
+
+    expect(card).toMatchObject({
+      path: 'src/payments',
+      cached: false,
+      purpose: 'Refunds and captures for card payments.',
+      purposeFrom: 'src/payments/README.md',
+      languages: ['typescript'],
+      dependsOn: { modules: ['src/utils'], packages: ['zod'] },
+      usedBy: { total: 1, modules: ['src/api'] },
+      tests: { sourceFiles: 2, tested: 1, testFiles: 1 },
+      submodules: []
+    });
+    expect(card?.keyExports).toHaveLength(1);
+    expect(card?.keyExports[0].file).toBe('src/payments/refund.ts');
+    expect(card?.keyExports[0].names).toContain('refundPayment');
+
+    const utils = await getModuleCard(tempRoot, 'src/utils');
+    expect(utils).toMatchObject({
+      purpose: 'Money helpers in integer cents.',
+      purposeFrom: 'src/utils/money.ts',
+      usedBy: { total: 1, modules: ['src/payments'] }
+    });
+
+    expect(await getModuleCard(tempRoot, 'src/missing')).toBeNull();
+  });
+
+  it('serves cached cards until the directory changes', async () => {
+    const first = await getModuleCard(tempRoot, 'src/payments');
+    const second = await getModuleCard(tempRoot, 'src/payments');
+    expect(second?.cached).toBe(true);
+    expect(second?.hash).toBe(first?.hash);
+
+    await fs.writeFile(
+      path.join(tempRoot, 'src/payments/capture.ts'),
+      'export function capturePayment(id: string, amount: number) {\n  return [id, amount];\n}\n'
+    );
+    await new CodebaseIndexer({
+      rootPath: tempRoot,
+      config: { skipEmbedding: true },
+      incrementalOnly: true
+    }).index();
+
+    const third = await getModuleCard(tempRoot, 'src/payments');
+    expect(third?.cached).toBe(false);
+    expect(third?.hash).not.toBe(first?.hash);
+    // An unrelated directory keeps its card
+    await getModuleCard(tempRoot, 'src/api');
+    expect((await getModuleCard(tempRoot, 'src/api'))?.cached).toBe(true);
+  });
+
+  it('skips the cache on read-only servers', async () => {
+    await getModuleCard(tempRoot, 'src/payments', { persist: false });
+    expect((await getModuleCard(tempRoot, 'src/payments'))?.cached).toBe(false);
+  });
+
+  it('lists modules and links submodules as resources', async () => {
+    expect(await listModules(tempRoot)).toEqual([
+      { path: 'src', files: 6 },
+      { path: 'src/api', files: 1 },
+      { path: 'src/payments', files: 4 },
+      { path: 'src/utils', files: 1 }
+    ]);
+    const hidden = await listModules(tempRoot, (file) => !file.startsWith('src/payments/'));
+    expect(hidden?.map((module) => module.path)).toEqual(['src', 'src/api', 'src/utils']);
+
+    const [listing] = await readModuleList(tempRoot, 'codebase://modules');
+    expect(JSON.parse(listing.text).modules[0]).toEqual({
+      path: 'src',
+      files: 6,
+      uri: 'codebase://module/src'
+    });
+
+    const uri = moduleCardUri('src');
+    const [contents] = await readModuleCard(tempRoot, uri, 'src');
+    const card = JSON.parse(contents.text);
+    expect(card.cached).toBeUndefined();
+    expect(card.submodules).toEqual([
+      { path: 'src/api', uri: 'codebase://module/src/api' },
+      { path: 'src/payments', uri: 'codebase://module/src/payments' },
+      { path: 'src/utils', uri: 'codebase://module/src/utils' }
+    ]);
+    await expect(readModuleCard(tempRoot, uri, 'lib')).rejects.toThrow(/No indexed files/);
+  });
+
+  it('parses module URIs and rejects parent-directory segments', () => {
+    expect(parseModuleCardUri('codebase-context/codebase://module/src/api')).toBe('src/api');
+    expect(parseModuleCardUri(moduleCardUri('docs/design notes'))).toBe('docs/design notes');
+    expect(parseModuleCardUri('codebase://module/src/../..')).toBeNull();
+    expect(parseModuleCardUri('codebase://module/')).toBeNull();
+    expect(parseModulesUri('codebase://modules?cursor=abc')).toEqual({ cursor: 'abc' });
+    expect(parseModulesUri('codebase://module/src')).toBeNull();
+  });
+});
@@ -0,0 +1,119 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { groupQueryResults } from '../src/core/multi-search.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const hit = (file: string, startLine: number, endLine: number, score: number) => ({
+  file,
+  startLine,
+  endLine,
+  score
+});
+
+describe('multi_search', () => {
+  it('lists each chunk once, under the query that scored it highest', () => {
+    const { groups, duplicates } = groupQueryResults([
+      [hit('src/auth.ts', 1, 20, 0.6), hit('src/session.ts', 1, 30, 0.5)],
+      [hit('src/auth.ts', 10, 40, 0.9), hit('src/token.ts', 1, 10, 0.4)],
+      [hit('src/session.ts', 1, 30, 0.3), hit('src/auth.ts', 1, 20, 0.2)]
+    ]);
+
+    expect(groups).toEqual([
+      [{ ...hit('src/session.ts', 1, 30, 0.5), alsoFor: [3] }],
+      [{ ...hit('src/auth.ts', 10, 40, 0.9), alsoFor: [1, 3] }, hit('src/token.ts', 1, 10, 0.4)],
+      []
+    ]);
+    expect(duplicates).toBe(3);
+  });
+
+  describe('tool', () => {
+    let tempRoot: string;
+    let ctx: ToolContext;
+
+    beforeEach(async () => {
+      tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'multi-search-'));
+      await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+      await fs.writeFile(
+        path.join(tempRoot, 'src', 'invoice.ts'),
+        'export function invoiceTotal(lines: number[]): number {\n' +
+          '  // invoice total across all lines\n' +
+          '  return lines.reduce((sum, line) => sum + line, 0);\n' +
+          '}\n'
+      );
+      await fs.writeFile(
+        path.join(tempRoot, 'src', 'shipping.ts'),
+        'export function shippingCost(weightKg: number): number {\n' +
+          '  // shipping cost by parcel weight\n' +
+          '  return 4.5 + weightKg * 1.2;\n' +
+          '}\n'
+      );
+      await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+      const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+      ctx = {
+        indexState: { status: 'ready' },
+        paths: {
+          baseDir,
+          memory: path.join(baseDir, MEMORY_FILENAME),
+          intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+          keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+          vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+        },
+        rootPath: tempRoot,
+        performIndexing: () => {}
+      };
+    });
+
+    afterEach(async () => {
+      await rmWithRetries(tempRoot);
+    });
+
+    it('runs every query and groups the deduplicated results', async () => {
+      const response = await dispatchTool(
+        'multi_search',
+        {
+          queries: ['invoice total', 'shipping cost weight', 'invoiceTotal lines'],
+          mode: 'keyword'
+        },
+        ctx
+      );
+      const payload = JSON.parse(response.content![0].text);
+
+      expect(payload.status).toBe('success');
+      expect(payload.searches.map((s: { query: string }) => s.query)).toEqual([
+        'invoice total',
+        'shipping cost weight',
+        'invoiceTotal lines'
+      ]);
+      const files = payload.searches.flatMap((s: { results: Array<{ file: string }> }) =>
+        s.results.map((r) => r.file.replace(/:\d+-\d+$/, ''))
+      );
+      expect(new Set(files).size).toBe(files.length);
+      expect(files).toEqual(expect.arrayContaining(['src/invoice.ts', 'src/shipping.ts']));
+      expect(payload.duplicatesRemoved).toBeGreaterThan(0);
+    });
+
+    it('rejects a missing or oversized query list', async () => {
+      const empty = await dispatchTool('multi_search', { queries: [] }, ctx);
+      expect(empty.isError).toBe(true);
+      const many = await dispatchTool(
+        'multi_search',
+        { queries: Array.from({ length: 9 }, (_, i) => `query ${i}`) },
+        ctx
+      );
+      expect(many.isError).toBe(true);
+    });
+  });
+});
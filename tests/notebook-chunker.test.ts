@@ -0,0 +1,148 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { createNotebookChunks, parseNotebook } from '../src/utils/notebook-chunker.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME
+} from '../src/constants/codebase-context.js';
+import type { CodeChunk } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const NOTEBOOK = JSON.stringify(
+  {
+    cells: [
+      {
+        cell_type: 'markdown',
+        id: 'intro',
+        metadata: {},
+        source: ['# Churn model\n', '\n', 'Loads the events and fits a baseline.']
+      },
+      {
+        cell_type: 'code',
+        execution_count: 3,
+        id: 'load',
+        metadata: {},
+        outputs: [
+          { output_type: 'display_data', data: { 'image/png': 'iVBORw0KGgo'.repeat(200) } }
+        ],
+        source: [
+          'import pandas as pd\n',
+          '\n',
+          'def load_events(path):\n',
+          '    frame = pd.read_csv(path)\n',
+          '    return frame.dropna()\n'
+        ]
+      },
+      { cell_type: 'code', execution_count: null, metadata: {}, outputs: [], source: [] },
+      {
+        cell_type: 'code',
+        execution_count: 1,
+        metadata: {},
+        outputs: [],
+        source: '%%bash\nls data/'
+      },
+      { cell_type: 'raw', metadata: {}, source: 'raw text' }
+    ],
+    metadata: {
+      kernelspec: { display_name: 'Python 3', language: 'python', name: 'python3' }
+    },
+    nbformat: 4,
+    nbformat_minor: 5
+  },
+  null,
+  1
+);
+
+describe('parseNotebook', () => {
+  it('reads cells with their kernel or cell-magic language and execution count', () => {
+    const notebook = parseNotebook(NOTEBOOK);
+
+    expect(notebook?.language).toBe('python');
+    expect(
+      notebook?.cells.map((c) => [c.index, c.cellType, c.language, c.executionCount])
+    ).toEqual([
+      [0, 'markdown', 'markdown', null],
+      [1, 'code', 'python', 3],
+      [2, 'code', 'python', null],
+      [3, 'code', 'shellscript', 1],
+      [4, 'raw', 'plaintext', null]
+    ]);
+    expect(notebook?.cells[1].source).toBe(
+      'import pandas as pd\n\ndef load_events(path):\n    frame = pd.read_csv(path)\n' +
+        '    return frame.dropna()'
+    );
+  });
+
+  it('returns null for JSON that is not a notebook', () => {
+    expect(parseNotebook('{"name": "pkg"}')).toBeNull();
+    expect(parseNotebook('not json')).toBeNull();
+  });
+});
+
+describe('createNotebookChunks', () => {
+  it('chunks code and markdown cells with cell metadata and no outputs', async () => {
+    const result = await createNotebookChunks(NOTEBOOK, {
+      filePath: '/repo/analysis/churn.ipynb',
+      relativePath: 'analysis/churn.ipynb'
+    });
+    const chunks = result?.chunks ?? [];
+
+    expect(new Set(chunks.map((c) => c.metadata.cellIndex))).toEqual(new Set([0, 1, 3]));
+    expect(chunks.some((c) => c.content.includes('iVBORw0KGgo'))).toBe(false);
+
+    const markdown = chunks.find((c) => c.metadata.cellIndex === 0);
+    expect(markdown).toMatchObject({
+      language: 'markdown',
+      componentType: 'documentation',
+      relativePath: 'analysis/churn.ipynb',
+      startLine: 1
+    });
+    expect(markdown?.metadata).toMatchObject({ cellType: 'markdown', cellId: 'intro' });
+
+    const code = chunks.find((c) => c.content.includes('def load_events'));
+    expect(code).toMatchObject({ language: 'python', componentType: 'notebook-cell' });
+    expect(code?.metadata).toMatchObject({ cellIndex: 1, cellType: 'code', executionCount: 3 });
+    expect(code?.endLine).toBeLessThanOrEqual(5);
+
+    const shell = chunks.find((c) => c.metadata.cellIndex === 3);
+    expect(shell?.language).toBe('shellscript');
+  });
+});
+
+describe('indexer with notebooks', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'notebook-index-test-'));
+    await fs.mkdir(path.join(tempDir, 'analysis', '.ipynb_checkpoints'), { recursive: true });
+    await fs.writeFile(path.join(tempDir, 'analysis', 'churn.ipynb'), NOTEBOOK);
+    await fs.writeFile(
+      path.join(tempDir, 'analysis', '.ipynb_checkpoints', 'churn-checkpoint.ipynb'),
+      NOTEBOOK
+    );
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('indexes notebook cells and skips checkpoint copies', async () => {
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+
+    const raw = await fs.readFile(
+      path.join(tempDir, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+      'utf-8'
+    );
+    const chunks = (JSON.parse(raw) as { chunks: CodeChunk[] }).chunks;
+    const files = new Set(chunks.map((c) => c.relativePath.replace(/\\/g, '/')));
+
+    expect([...files].some((file) => file.includes('.ipynb_checkpoints'))).toBe(false);
+    expect(chunks.find((c) => c.content.includes('def load_events'))?.metadata).toMatchObject({
+      cellIndex: 1,
+      executionCount: 3
+    });
+  });
+});
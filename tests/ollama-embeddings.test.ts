@@ -0,0 +1,73 @@
+import { describe, it, expect, afterEach, vi } from 'vitest';
+import { OllamaEmbeddingProvider } from '../src/embeddings/ollama.js';
+
+function jsonResponse(body: unknown, status = 200): Response {
+  return new Response(JSON.stringify(body), {
+    status,
+    headers: { 'Content-Type': 'application/json' }
+  });
+}
+
+describe('OllamaEmbeddingProvider', () => {
+  afterEach(() => {
+    vi.unstubAllGlobals();
+  });
+
+  it('detects dimensions on initialize and embeds batches via /api/embed', async () => {
+    const fetchMock = vi.fn(async (_url: string, init?: RequestInit) => {
+      const body = JSON.parse(String(init?.body)) as { input: string[] };
+      return jsonResponse({ embeddings: body.input.map(() => [0.1, 0.2, 0.3]) });
+    });
+    vi.stubGlobal('fetch', fetchMock);
+
+    const provider = new OllamaEmbeddingProvider('nomic-embed-text', 'http://ollama.local:11434/');
+    expect(provider.isReady()).toBe(false);
+    await provider.initialize();
+
+    expect(provider.isReady()).toBe(true);
+    expect(provider.dimensions).toBe(3);
+
+    const vectors = await provider.embedBatch(['a', 'b']);
+    expect(vectors).toEqual([
+      [0.1, 0.2, 0.3],
+      [0.1, 0.2, 0.3]
+    ]);
+
+    const [url, init] = fetchMock.mock.calls[1] ?? [];
+    expect(url).toBe('http://ollama.local:11434/api/embed');
+    expect(JSON.parse(String(init?.body))).toEqual({ model: 'nomic-embed-text', input: ['a', 'b'] });
+  });
+
+  it('explains how to pull a missing model', async () => {
+    vi.stubGlobal(
+      'fetch',
+      vi.fn(async () => new Response('model not found', { status: 404 }))
+    );
+
+    const provider = new OllamaEmbeddingProvider('mxbai-embed-large');
+    await expect(provider.initialize()).rejects.toThrow(/ollama pull mxbai-embed-large/);
+  });
+
+  it('reports an unreachable server', async () => {
+    vi.stubGlobal(
+      'fetch',
+      vi.fn(async () => {
+        throw new TypeError('fetch failed');
+      })
+    );
+
+    const provider = new OllamaEmbeddingProvider();
+    await expect(provider.initialize()).rejects.toThrow(/not reachable at http:\/\/localhost:11434/);
+  });
+
+  it('rejects responses with a mismatched embedding count', async () => {
+    vi.stubGlobal(
+      'fetch',
+      vi.fn(async () => jsonResponse({ embeddings: [[1, 2]] }))
+    );
+
+    const provider = new OllamaEmbeddingProvider();
+    await provider.initialize();
+    await expect(provider.embedBatch(['a', 'b'])).rejects.toThrow(/1 embeddings for 2 inputs/);
+  });
+});
@@ -0,0 +1,119 @@
+import { describe, it, expect } from 'vitest';
+import {
+  formatToolResponse,
+  isOutputFormat,
+  toStructuredOutput
+} from '../src/tools/output-format.js';
+import { withFormatParam, TOOLS } from '../src/tools/index.js';
+import type { ToolResponse } from '../src/tools/types.js';
+
+const json = (payload: unknown, isError = false): ToolResponse => ({
+  content: [{ type: 'text', text: JSON.stringify(payload) }],
+  ...(isError ? { isError: true } : {})
+});
+
+const searchResponse = json({
+  status: 'success',
+  searchQuality: { status: 'ok', confidence: 0.82 },
+  results: [
+    {
+      file: 'src/auth/session.ts:12-40',
+      summary: 'Session refresh',
+      score: 0.91,
+      snippet: 'export function refreshSession() {}'
+    },
+    { file: 'README.md', summary: 'Auth overview', score: 0.4 }
+  ],
+  totalResults: 2
+});
+
+describe('structured tool output', () => {
+  it('normalizes search results into the stable record shape', () => {
+    const output = toStructuredOutput('search_codebase', searchResponse);
+    expect(output).toMatchObject({ schemaVersion: 1, tool: 'search_codebase', status: 'success' });
+    expect(output.results[0]).toEqual({
+      group: 'results',
+      path: 'src/auth/session.ts',
+      start_line: 12,
+      end_line: 40,
+      symbol: null,
+      score: 0.91,
+      language: 'typescript',
+      content: 'export function refreshSession() {}'
+    });
+    expect(output.results[1]).toMatchObject({
+      path: 'README.md',
+      start_line: null,
+      content: 'Auth overview'
+    });
+    expect(output.data).toEqual({
+      searchQuality: { status: 'ok', confidence: 0.82 },
+      totalResults: 2
+    });
+  });
+
+  it('reads path + line entries nested under other fields', () => {
+    const output = toStructuredOutput(
+      'get_symbol_references',
+      json({
+        symbol: 'refreshSession',
+        usages: [{ file: 'src/app.ts', line: 7, preview: 'refreshSession();' }],
+        notes: ['caller list is approximate']
+      })
+    );
+    expect(output.status).toBe('success');
+    expect(output.results).toEqual([
+      {
+        group: 'usages',
+        path: 'src/app.ts',
+        start_line: 7,
+        end_line: 7,
+        symbol: null,
+        score: null,
+        language: 'typescript',
+        content: 'refreshSession();'
+      }
+    ]);
+    expect(output.data).toEqual({
+      symbol: 'refreshSession',
+      notes: ['caller list is approximate']
+    });
+  });
+
+  it('keeps non-JSON errors as a message', () => {
+    const response: ToolResponse = {
+      content: [{ type: 'text', text: 'Unexpected error: boom' }],
+      isError: true
+    };
+    const formatted = formatToolResponse('get_memory', response, 'json');
+    expect(formatted.isError).toBe(true);
+    expect(formatted.structuredContent).toMatchObject({
+      status: 'error',
+      results: [],
+      data: { message: 'Unexpected error: boom' }
+    });
+  });
+
+  it('renders markdown and plain text with the result locations', () => {
+    const markdown = formatToolResponse('search_codebase', searchResponse, 'markdown');
+    const md = markdown.content![0].text;
+    expect(md).toContain('### 1. `src/auth/session.ts:12-40` · score 0.91');
+    expect(md).toContain('```typescript\nexport function refreshSession() {}\n```');
+    expect(md).toContain('- totalResults: 2');
+    expect(markdown.structuredContent).toBeUndefined();
+
+    const plain = formatToolResponse('search_codebase', searchResponse, 'plain').content![0].text;
+    expect(plain.split('\n')[0]).toBe('search_codebase: success');
+    expect(plain).toContain('src/auth/session.ts:12-40  score 0.91\n    export function');
+  });
+
+  it('advertises the format parameter on every tool', () => {
+    expect(isOutputFormat('markdown')).toBe(true);
+    expect(isOutputFormat('yaml')).toBe(false);
+    const tools = withFormatParam(TOOLS);
+    for (const tool of tools) {
+      expect(tool.inputSchema.properties).toHaveProperty('format');
+    }
+    expect(TOOLS[0].inputSchema.properties).not.toHaveProperty('format');
+  });
+});
@@ -0,0 +1,192 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { CodebaseSearcher } from '../src/core/search.js';
+import { parseUnifiedDiff } from '../src/core/diff-context.js';
+import { applyHunks, buildOverlayIndex } from '../src/core/overlay-index.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+vi.mock('../src/embeddings/index.js', async (importOriginal) => {
+  const original = await importOriginal<typeof import('../src/embeddings/index.js')>();
+  // One axis per topic, so a query only lands near chunks about the same thing
+  const vectorOf = (text: string) => [
+    ...['refund', 'invoice', 'ship'].map((word) => (text.toLowerCase().includes(word) ? 1 : 0)),
+    0.1
+  ];
+  const provider = {
+    name: 'ollama',
+    modelName: 'fake-model',
+    dimensions: 4,
+    initialize: async () => {},
+    isReady: () => true,
+    embed: async (text: string) => vectorOf(text),
+    embedBatch: async (texts: string[]) => texts.map(vectorOf)
+  };
+  return { ...original, getEmbeddingProvider: async () => provider };
+});
+
+const PATCH = [
+  'diff --git a/src/refunds.ts b/src/refunds.ts',
+  '--- a/src/refunds.ts',
+  '+++ b/src/refunds.ts',
+  '@@ -1,3 +1,3 @@',
+  '-export function refundPayment(total: number) {',
+  '+export function refundInvoiceLater(total: number) {',
+  '   return total * 0.5;',
+  ' }',
+  'diff --git a/src/shipping.ts b/src/shipping.ts',
+  'deleted file mode 100644',
+  '--- a/src/shipping.ts',
+  '+++ /dev/null',
+  '@@ -1,3 +0,0 @@',
+  '-export function shipOrder(id: string) {',
+  '-  return id;',
+  '-}',
+  ''
+].join('\n');
+
+describe('overlay indexes', () => {
+  let tempRoot: string;
+  let scratchDir: string;
+  let ctx: ToolContext;
+
+  const search = async (args: Record<string, unknown>) =>
+    JSON.parse((await dispatchTool('search_codebase', args, ctx)).content![0].text);
+
+  beforeEach(async () => {
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+    vi.spyOn(console, 'warn').mockImplementation(() => {});
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'overlay-index-'));
+    scratchDir = await fs.mkdtemp(path.join(os.tmpdir(), 'overlay-scratch-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'refunds.ts'),
+      'export function refundPayment(total: number) {\n  return total * 0.5;\n}\n'
+    );
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'shipping.ts'),
+      'export function shipOrder(id: string) {\n  return id;\n}\n'
+    );
+    await new CodebaseIndexer({ rootPath: tempRoot }).index();
+
+    const contextDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready', lastIndexed: new Date() },
+      paths: {
+        baseDir: contextDir,
+        memory: path.join(contextDir, MEMORY_FILENAME),
+        intelligence: path.join(contextDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(contextDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(contextDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    vi.restoreAllMocks();
+    await rmWithRetries(tempRoot);
+    await rmWithRetries(scratchDir);
+  });
+
+  it('applies hunks only to the content they were made against', () => {
+    const [refunds, shipping] = parseUnifiedDiff(PATCH, { hunkLines: true });
+    expect(refunds.hunks[0].lines).toEqual([
+      '-export function refundPayment(total: number) {',
+      '+export function refundInvoiceLater(total: number) {',
+      '   return total * 0.5;',
+      ' }'
+    ]);
+    expect(shipping.status).toBe('deleted');
+    expect(parseUnifiedDiff(PATCH)[0].hunks[0].lines).toBeUndefined();
+
+    const base = 'export function refundPayment(total: number) {\n  return total * 0.5;\n}\n';
+    expect(applyHunks(base, refunds.hunks)).toBe(
+      'export function refundInvoiceLater(total: number) {\n  return total * 0.5;\n}\n'
+    );
+    expect(applyHunks(base.replace('0.5', '0.25'), refunds.hunks)).toBeNull();
+  });
+
+  it('searches the index as if a diff were applied, without writing to it', async () => {
+    const overlay = await buildOverlayIndex(tempRoot, { diff: PATCH });
+    expect([...overlay.files].sort()).toEqual(['src/refunds.ts', 'src/shipping.ts']);
+    expect([...overlay.deleted]).toEqual(['src/shipping.ts']);
+    expect(overlay.chunks.every((chunk) => chunk.metadata.overlay === true)).toBe(true);
+
+    const semantic = await new CodebaseSearcher(tempRoot, { overlay }).search(
+      'invoice refunds',
+      5,
+      undefined,
+      { useKeywordSearch: false }
+    );
+    expect(semantic[0]?.snippet).toContain('refundInvoiceLater');
+    expect(semantic[0]?.metadata.overlay).toBe(true);
+    expect(semantic.some((r) => r.snippet.includes('refundPayment'))).toBe(false);
+    expect(semantic.some((r) => r.filePath.includes('shipping.ts'))).toBe(false);
+
+    const patched = await search({ query: 'refundInvoiceLater', overlay: { diff: PATCH } });
+    expect(patched.status).toBe('success');
+    expect(patched.overlay).toEqual({ files: 1, deleted: 1, chunks: expect.any(Number) });
+    expect(patched.results[0]).toMatchObject({
+      file: expect.stringContaining('refunds.ts'),
+      overlay: true
+    });
+    const shipped = await search({ query: 'shipOrder', overlay: { diff: PATCH } });
+    expect(shipped.results.some((r: { file: string }) => r.file.includes('shipping.ts'))).toBe(
+      false
+    );
+
+    // The overlay is gone after the call
+    const plain = await search({ query: 'shipOrder' });
+    expect(plain.overlay).toBeUndefined();
+    expect(plain.results[0].file).toContain('shipping.ts');
+    expect(plain.results[0].overlay).toBeUndefined();
+  });
+
+  it('overlays what a scratch directory changes and reports unapplied hunks', async () => {
+    await fs.mkdir(path.join(scratchDir, 'src'), { recursive: true });
+    await fs.copyFile(
+      path.join(tempRoot, 'src', 'refunds.ts'),
+      path.join(scratchDir, 'src', 'refunds.ts')
+    );
+    await fs.writeFile(
+      path.join(scratchDir, 'src', 'invoices.ts'),
+      'export function issueInvoice(total: number) {\n  return { total };\n}\n'
+    );
+
+    const fromDirectory = await search({
+      query: 'issueInvoice',
+      overlay: { directory: scratchDir }
+    });
+    expect(fromDirectory.overlay).toMatchObject({ files: 1, deleted: 0 });
+    expect(fromDirectory.results[0]).toMatchObject({
+      file: expect.stringContaining('invoices.ts'),
+      overlay: true
+    });
+
+    const stale = await search({
+      query: 'refundPayment',
+      overlay: { diff: PATCH.replace('   return total * 0.5;', '   return total * 0.9;') }
+    });
+    expect(stale.overlay.skipped).toEqual([
+      { file: 'src/refunds.ts', reason: 'does not apply cleanly' }
+    ]);
+    expect(stale.results[0].file).toContain('refunds.ts');
+    expect(stale.results[0].overlay).toBeUndefined();
+
+    const invalid = await search({ query: 'refund', overlay: { diff: PATCH }, ref: 'main' });
+    expect(invalid.errorCode).toBe('invalid_params');
+  });
+});
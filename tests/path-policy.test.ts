@@ -0,0 +1,271 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import {
+  createPathPolicy,
+  filterPayloadPaths,
+  mergePathPolicyConfigs,
+  pathPolicyFromEnv,
+  refusedArgumentPath
+} from '../src/core/path-policy.js';
+import { dispatchTool, visibleTools, TOOLS } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  PROJECT_CONFIG_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const ROOT = '/work/repo';
+
+describe('createPathPolicy', () => {
+  const policy = createPathPolicy(
+    { allowPaths: ['src', 'docs/**'], denyPaths: ['.env*', 'secrets/'] },
+    ROOT
+  );
+
+  it('allows only the listed paths and never the denied ones', () => {
+    expect(policy.active).toBe(true);
+    expect(policy.allows('src/auth/login.ts')).toBe(true);
+    expect(policy.allows('docs/guide.md')).toBe(true);
+    expect(policy.allows(`${ROOT}/src/index.ts`)).toBe(true);
+    expect(policy.allows('scripts/deploy.ts')).toBe(false);
+    expect(policy.allows('src/.env.local')).toBe(false);
+    expect(policy.allows('src/secrets/token.ts')).toBe(false);
+  });
+
+  it('refuses paths outside the repo root', () => {
+    expect(policy.allows('../other/src/a.ts')).toBe(false);
+    expect(policy.allows('src/../../etc/passwd')).toBe(false);
+    expect(policy.allows('/etc/passwd')).toBe(false);
+  });
+
+  it('allows everything without rules', () => {
+    const open = createPathPolicy({}, ROOT);
+    expect(open.active).toBe(false);
+    expect(open.allows('/etc/passwd')).toBe(true);
+  });
+});
+
+describe('policy sources', () => {
+  it('reads comma lists and the read-only switch from the environment', () => {
+    expect(
+      pathPolicyFromEnv({
+        CODEBASE_CONTEXT_ALLOW_PATHS: 'src, docs',
+        CODEBASE_CONTEXT_DENY_PATHS: '.env*',
+        CODEBASE_CONTEXT_READ_ONLY: 'true'
+      })
+    ).toEqual({ allowPaths: ['src', 'docs'], denyPaths: ['.env*'], readOnly: true });
+    expect(pathPolicyFromEnv({ CODEBASE_CONTEXT_READ_ONLY: '0' })).toEqual({});
+  });
+
+  it('lets the environment narrow the config file but not widen it', () => {
+    const merged = mergePathPolicyConfigs(
+      { allowPaths: ['src', 'scripts'], denyPaths: ['secrets/'], readOnly: true },
+      { allowPaths: ['src'], denyPaths: ['.env*'] }
+    );
+    expect(merged).toEqual({
+      allowPaths: ['src'],
+      denyPaths: ['secrets/', '.env*'],
+      readOnly: true
+    });
+  });
+});
+
+describe('filterPayloadPaths', () => {
+  const policy = createPathPolicy({ denyPaths: ['secrets/'] }, ROOT);
+
+  it('drops entries, fields and records pointing at denied paths', () => {
+    const { payload, withheld } = filterPayloadPaths(
+      {
+        results: [
+          { file: 'src/a.ts:10-20', summary: 'a' },
+          { file: 'secrets/keys.ts:1-5', summary: 'keys' }
+        ],
+        importedBy: ['src/b.ts', 'secrets/keys.ts'],
+        hotspots: { 'src/a.ts': 3, 'secrets/keys.ts': 9 },
+        references: [{ kind: 'symbol', target: 'secrets.ts' }]
+      },
+      policy
+    );
+
+    expect(payload).toEqual({
+      results: [{ file: 'src/a.ts:10-20', summary: 'a' }],
+      importedBy: ['src/b.ts'],
+      hotspots: { 'src/a.ts': 3 },
+      references: [{ kind: 'symbol', target: 'secrets.ts' }]
+    });
+    expect(withheld).toBe(3);
+  });
+
+  it('refuses a payload about a denied file', () => {
+    const filtered = filterPayloadPaths({ file: 'secrets/keys.ts', summary: 'x' }, policy);
+    expect(filtered).toEqual({ payload: null, withheld: 0, refused: 'secrets/keys.ts' });
+  });
+
+  it('checks path and file targets before a tool runs, leaving symbols alone', () => {
+    expect(refusedArgumentPath({ path: 'secrets/keys.ts' }, policy)).toBe('secrets/keys.ts');
+    expect(refusedArgumentPath({ target: 'secrets/keys.ts' }, policy)).toBe('secrets/keys.ts');
+    expect(refusedArgumentPath({ target: 'SecretStore.load' }, policy)).toBeUndefined();
+    expect(refusedArgumentPath({ path: 'secrets/**' }, policy)).toBeUndefined();
+  });
+});
+
+describe('path policy on indexing and tools', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'path-policy-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.mkdir(path.join(tempRoot, 'scripts'), { recursive: true });
+    await fs.mkdir(path.join(tempRoot, 'src', 'secrets'), { recursive: true });
+    await fs.mkdir(path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME), { recursive: true });
+    await fs.writeFile(path.join(tempRoot, 'package.json'), JSON.stringify({ name: 'app' }));
+    await fs.writeFile(
+      path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME),
+      JSON.stringify({ security: { allowPaths: ['src'], denyPaths: ['secrets/'] } })
+    );
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'app.ts'),
+      `import { token } from './secrets/token.js';\nexport const app = () => token;\n`
+    );
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'secrets', 'token.ts'),
+      `export const token = 'x';\n`
+    );
+    await fs.writeFile(path.join(tempRoot, 'scripts', 'deploy.ts'), `export const deploy = 1;\n`);
+
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('indexes only allowed files', async () => {
+    const raw = await fs.readFile(ctx.paths.keywordIndex, 'utf-8');
+    const { chunks } = JSON.parse(raw) as { chunks: Array<{ relativePath: string }> };
+    const files = new Set(chunks.map((chunk) => chunk.relativePath.replace(/\\/g, '/')));
+    expect([...files]).toEqual(['src/app.ts']);
+  });
+
+  it('refuses denied and outside-root paths before the tool runs', async () => {
+    for (const target of ['src/secrets/token.ts', 'scripts/deploy.ts', '../elsewhere/a.ts']) {
+      const result = await dispatchTool('get_dependents', { target }, ctx);
+      expect(result.isError).toBe(true);
+      expect(JSON.parse(result.content![0].text).errorCode).toBe('path_not_allowed');
+    }
+  });
+
+  it('refuses and hides writing tools in read-only mode', async () => {
+    const readOnly = { ...ctx, pathPolicy: createPathPolicy({ readOnly: true }, tempRoot) };
+    const result = await dispatchTool('remember', { type: 'decision', memory: 'x' }, readOnly);
+
+    expect(JSON.parse(result.content![0].text).errorCode).toBe('read_only');
+    await expect(fs.access(ctx.paths.memory)).rejects.toThrow();
+    const names = visibleTools(TOOLS, true).map((tool) => tool.name);
+    expect(names).not.toContain('remember');
+    expect(names).not.toContain('refresh_index');
+    expect(names).toContain('search_codebase');
+  });
+});
+
+describe('path policy on navigation tools', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'path-policy-nav-'));
+    await fs.mkdir(path.join(tempRoot, 'src', 'secrets'), { recursive: true });
+    await fs.writeFile(path.join(tempRoot, 'package.json'), JSON.stringify({ name: 'app' }));
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'secrets', 'token.ts'),
+      [
+        'export function loadToken() {',
+        '  return readToken();',
+        '}',
+        '',
+        'export function readToken() {',
+        "  return 'x';",
+        '}',
+        ''
+      ].join('\n')
+    );
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'token.ts'),
+      ['export function loadToken() {', "  return 'public';", '}', ''].join('\n')
+    );
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'app.ts'),
+      [
+        "import { loadToken } from './token.js';",
+        '',
+        'export function start() {',
+        '  return loadToken();',
+        '}',
+        ''
+      ].join('\n')
+    );
+
+    // Indexed without a policy, so the call graph and symbol index know the denied file
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {},
+      pathPolicy: createPathPolicy({ denyPaths: ['secrets/'] }, tempRoot)
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('redacts denied locations from call-graph and definition results', async () => {
+    const calls: Array<[string, Record<string, unknown>]> = [
+      ['find_callers', { symbol: 'loadToken' }],
+      ['find_callers', { symbol: 'readToken' }],
+      ['find_callees', { symbol: 'start' }],
+      ['get_definition', { symbol: 'loadToken' }]
+    ];
+    for (const [name, args] of calls) {
+      const text = (await dispatchTool(name, args, ctx)).content![0].text;
+      expect(text, name).not.toContain('secrets/');
+      expect(JSON.parse(text).withheldByPathPolicy, name).toBeGreaterThan(0);
+    }
+
+    const definition = await dispatchTool('get_definition', { symbol: 'loadToken' }, ctx);
+    const { definitions } = JSON.parse(definition.content![0].text);
+    expect(definitions.map((d: { location: string }) => d.location)).toEqual(['src/token.ts:1']);
+  });
+});
@@ -0,0 +1,223 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import {
+  PGVECTOR_MIGRATIONS,
+  PgvectorStorageProvider,
+  buildPgvectorWhere,
+  derivePgvectorTableName,
+  type PgPool
+} from '../src/storage/pgvector.js';
+import type { CodeChunkWithEmbedding } from '../src/storage/types.js';
+
+interface RecordedQuery {
+  text: string;
+  values?: unknown[];
+}
+
+/** In-memory stand-in for a pg Pool: records SQL and answers from `handler` */
+function fakePool(handler: (query: RecordedQuery) => Record<string, unknown>[] = () => []) {
+  const queries: RecordedQuery[] = [];
+  const query = async <R>(text: string, values?: unknown[]) => {
+    const recorded = { text: text.replace(/\s+/g, ' ').trim(), values };
+    queries.push(recorded);
+    const rows = handler(recorded) as R[];
+    return { rows, rowCount: rows.length };
+  };
+  const pool: PgPool = {
+    query,
+    connect: async () => ({ query, release: () => {} }),
+    end: async () => {}
+  };
+  return { pool, queries };
+}
+
+function makeChunk(id: string, filePath: string): CodeChunkWithEmbedding {
+  return {
+    id,
+    content: 'export const x = 1;',
+    filePath,
+    relativePath: filePath.replace('/repo/', ''),
+    startLine: 1,
+    endLine: 1,
+    language: 'typescript',
+    dependencies: [],
+    imports: [],
+    exports: ['x'],
+    tags: ['util'],
+    metadata: { componentName: 'x', gitRef: 'main', gitCommit: 'abc123' },
+    embedding: [0.1, 0.2, 0.3]
+  };
+}
+
+describe('PgvectorStorageProvider', () => {
+  beforeEach(() => {
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+  });
+
+  afterEach(() => {
+    vi.restoreAllMocks();
+  });
+
+  it('derives a stable, identifier-safe per-project table name', () => {
+    const a = derivePgvectorTableName('/work/My-Repo');
+    expect(a).toMatch(/^codebase_context_my_repo_[0-9a-f]{8}$/);
+    expect(derivePgvectorTableName('/work/My-Repo')).toBe(a);
+    expect(derivePgvectorTableName('/other/My-Repo')).not.toBe(a);
+    expect(() => new PgvectorStorageProvider({ table: 'chunks; DROP TABLE x' })).toThrow(
+      'Invalid pgvector table name'
+    );
+  });
+
+  it('builds parameterised filters for language, tags and paths', () => {
+    expect(buildPgvectorWhere(undefined, 3)).toEqual({ clause: '', params: [] });
+    expect(
+      buildPgvectorWhere(
+        { language: 'go', tags: ['api'], filePaths: ['src/a.go'], excludePaths: ['vendor/x.go'] },
+        3
+      )
+    ).toEqual({
+      clause:
+        " WHERE language = $3 AND payload->'tags' ?| $4::text[]" +
+        ' AND (file_path = ANY($5::text[]) OR relative_path = ANY($5::text[]))' +
+        ' AND NOT (file_path = ANY($6::text[]) OR relative_path = ANY($6::text[]))',
+      params: ['go', ['api'], ['src/a.go'], ['vendor/x.go']]
+    });
+  });
+
+  it('runs pending migrations once, then upserts rows with metadata columns', async () => {
+    const { pool, queries } = fakePool((q) =>
+      q.text.startsWith('SELECT to_regclass') ? [{ oid: null }] : []
+    );
+    const provider = new PgvectorStorageProvider({ table: 'proj', pool });
+    await provider.initialize('/unused');
+    expect(await provider.search([0.1, 0.2, 0.3], 5)).toEqual([]);
+
+    await provider.store([makeChunk('a', '/repo/a.ts')]);
+    await provider.store([makeChunk('b', '/repo/b.ts')]);
+
+    const texts = queries.map((q) => q.text);
+    expect(texts).toContain('CREATE EXTENSION IF NOT EXISTS vector');
+    expect(texts.find((t) => t.startsWith('CREATE TABLE IF NOT EXISTS proj'))).toContain(
+      'embedding vector(3) NOT NULL'
+    );
+    expect(texts).toContain(
+      'CREATE INDEX IF NOT EXISTS proj_embedding_hnsw ' +
+        'ON proj USING hnsw (embedding vector_cosine_ops)'
+    );
+    const recorded = queries.filter((q) =>
+      q.text.startsWith('INSERT INTO codebase_context_migrations')
+    );
+    expect(recorded.map((q) => q.values?.[1])).toEqual(PGVECTOR_MIGRATIONS.map((m) => m.version));
+
+    const upserts = queries.filter((q) => q.text.startsWith('INSERT INTO proj'));
+    expect(upserts).toHaveLength(2);
+    expect(upserts[0].values).toEqual([
+      'a',
+      '/repo/a.ts',
+      'a.ts',
+      1,
+      1,
+      'typescript',
+      '',
+      '',
+      '',
+      'main',
+      'abc123',
+      'export const x = 1;',
+      expect.any(String),
+      '[0.1,0.2,0.3]'
+    ]);
+  });
+
+  it('leaves the HNSW migration pending when the embeddings are too wide for it', async () => {
+    const { pool, queries } = fakePool((q) =>
+      q.text.startsWith('SELECT to_regclass') ? [{ oid: null }] : []
+    );
+    const provider = new PgvectorStorageProvider({ table: 'proj', pool });
+    await provider.initialize('/unused');
+    const wide = { ...makeChunk('a', '/repo/a.ts'), embedding: new Array(3072).fill(0.1) };
+    await provider.store([wide]);
+
+    expect(queries.some((q) => q.text.includes('USING hnsw'))).toBe(false);
+    const recorded = queries.filter((q) =>
+      q.text.startsWith('INSERT INTO codebase_context_migrations')
+    );
+    expect(recorded.map((q) => q.values?.[1])).toEqual([1]);
+  });
+
+  it('skips migrations that were already applied', async () => {
+    const { pool, queries } = fakePool((q) => {
+      if (q.text.startsWith('SELECT to_regclass')) return [{ oid: 'proj' }];
+      if (q.text.startsWith('SELECT version')) return [{ version: 1 }, { version: 2 }];
+      return [];
+    });
+    const provider = new PgvectorStorageProvider({ table: 'proj', pool });
+    await provider.initialize('/unused');
+    await provider.store([makeChunk('a', '/repo/a.ts')]);
+
+    expect(queries.some((q) => q.text.startsWith('CREATE EXTENSION'))).toBe(false);
+    expect(queries.some((q) => q.text.startsWith('INSERT INTO proj'))).toBe(true);
+  });
+
+  it('rebuilds into a generation table and renames it over the project table', async () => {
+    const { pool, queries } = fakePool((q) =>
+      q.text.startsWith('SELECT to_regclass') ? [{ oid: 'proj' }] : []
+    );
+    const provider = new PgvectorStorageProvider({ table: 'proj', pool });
+    await provider.initialize('/unused');
+    await provider.beginGeneration();
+    await provider.store([makeChunk('a', '/repo/a.ts')]);
+
+    const upsert = queries.find((q) => q.text.startsWith('INSERT INTO proj_'));
+    const generation = upsert?.text.match(/^INSERT INTO (proj_[0-9a-z]+)/)?.[1];
+    expect(generation).toBeDefined();
+    expect(queries.some((q) => q.text.startsWith('DROP TABLE'))).toBe(false);
+
+    await provider.commitGeneration();
+    const texts = queries.map((q) => q.text);
+    const swap = texts.slice(texts.lastIndexOf('BEGIN'));
+    expect(swap).toEqual([
+      'BEGIN',
+      'SELECT pg_advisory_xact_lock(hashtext($1))',
+      'DROP TABLE IF EXISTS proj',
+      `ALTER TABLE ${generation} RENAME TO proj`,
+      'DELETE FROM codebase_context_migrations WHERE table_name = $1',
+      'UPDATE codebase_context_migrations SET table_name = $1 WHERE table_name = $2',
+      'COMMIT'
+    ]);
+  });
+
+  it('maps nearest-neighbour rows back to chunks', async () => {
+    const chunk = makeChunk('a', '/repo/a.ts');
+    const { pool, queries } = fakePool((q) => {
+      if (q.text.startsWith('SELECT to_regclass')) return [{ oid: 'proj' }];
+      if (!q.text.includes('<=>')) return [];
+      return [
+        {
+          id: 'a',
+          file_path: chunk.filePath,
+          relative_path: chunk.relativePath,
+          start_line: 1,
+          end_line: 1,
+          language: 'typescript',
+          framework: '',
+          component_type: '',
+          layer: '',
+          content: chunk.content,
+          payload: { dependencies: [], imports: [], exports: ['x'], tags: ['util'], metadata: {} },
+          distance: '0.25'
+        }
+      ];
+    });
+    const provider = new PgvectorStorageProvider({ table: 'proj', pool });
+    await provider.initialize('/unused');
+
+    const [result] = await provider.search([0.1, 0.2, 0.3], 5, { language: 'typescript' });
+
+    expect(result.score).toBe(0.75);
+    expect(result.chunk).toMatchObject({ id: 'a', relativePath: 'a.ts', tags: ['util'] });
+    expect(result.chunk.framework).toBeUndefined();
+    const search = queries.find((q) => q.text.includes('<=>'));
+    expect(search?.text).toContain('WHERE language = $3');
+    expect(search?.values).toEqual(['[0.1,0.2,0.3]', 5, 'typescript']);
+  });
+});
@@ -0,0 +1,148 @@
+import { describe, it, expect, beforeAll, afterAll } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { mapOrdered } from '../src/core/pipeline.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const delay = (ms: number) => new Promise((resolve) => setTimeout(resolve, ms));
+
+async function collect<T>(source: AsyncIterable<T>): Promise<T[]> {
+  const out: T[] = [];
+  for await (const value of source) out.push(value);
+  return out;
+}
+
+describe('mapOrdered', () => {
+  it('yields in input order while bounding work in flight', async () => {
+    let running = 0;
+    let peak = 0;
+    const results = await collect(
+      mapOrdered(
+        [30, 5, 20, 1, 10, 2],
+        async (ms, index) => {
+          running++;
+          peak = Math.max(peak, running);
+          await delay(ms);
+          running--;
+          return index * 10;
+        },
+        { concurrency: 3 }
+      )
+    );
+    expect(results.map((r) => r.result)).toEqual([0, 10, 20, 30, 40, 50]);
+    expect(results.map((r) => r.item)).toEqual([30, 5, 20, 1, 10, 2]);
+    expect(peak).toBe(3);
+  });
+
+  it('does not start more than queueSize items ahead of the consumer', async () => {
+    const started: number[] = [];
+    const source = mapOrdered(
+      Array.from({ length: 10 }, (_, i) => i),
+      async (item) => {
+        started.push(item);
+        return item;
+      },
+      { concurrency: 2, queueSize: 3 }
+    );
+    const iterator = source[Symbol.asyncIterator]();
+    await iterator.next();
+    await delay(5);
+    // Item 0 is with the consumer; 1 and 2 fill the rest of the queue
+    expect(Math.max(...started)).toBe(2);
+    await iterator.return(undefined);
+  });
+
+  it('throws a failed item in order and stops starting new work on break', async () => {
+    const seen: number[] = [];
+    const failing = mapOrdered(
+      [0, 1, 2, 3],
+      async (item) => {
+        if (item === 2) throw new Error('boom');
+        return item;
+      },
+      { concurrency: 4 }
+    );
+    await expect(
+      (async () => {
+        for await (const { result } of failing) seen.push(result);
+      })()
+    ).rejects.toThrow('boom');
+    expect(seen).toEqual([0, 1]);
+
+    const started: number[] = [];
+    for await (const { index } of mapOrdered(
+      Array.from({ length: 20 }, (_, i) => i),
+      async (item) => {
+        started.push(item);
+        await delay(1);
+        return item;
+      },
+      { concurrency: 2, queueSize: 2 }
+    )) {
+      if (index === 1) break;
+    }
+    await delay(10);
+    expect(started.length).toBeLessThanOrEqual(4);
+  });
+});
+
+describe('concurrent indexing', () => {
+  let tempDir: string;
+
+  beforeAll(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'pipeline-'));
+    await fs.mkdir(path.join(tempDir, 'src'), { recursive: true });
+    for (let i = 0; i < 12; i++) {
+      const next = (i + 1) % 12;
+      await fs.writeFile(
+        path.join(tempDir, 'src', `mod${i}.ts`),
+        [
+          `import { run${next} } from './mod${next}';`,
+          '',
+          `export function run${i}(depth: number): number {`,
+          `  return depth > 0 ? run${next}(depth - 1) : ${i};`,
+          '}',
+          ''
+        ].join('\n')
+      );
+    }
+  });
+
+  afterAll(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  async function indexWith(concurrency: number) {
+    const stats = await new CodebaseIndexer({
+      rootPath: tempDir,
+      config: { skipEmbedding: true, parsing: { concurrency } }
+    }).index();
+    const raw = await fs.readFile(
+      path.join(tempDir, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+      'utf-8'
+    );
+    const chunks = (JSON.parse(raw) as { chunks: Array<Record<string, unknown>> }).chunks;
+    return {
+      files: stats.indexedFiles,
+      chunks: chunks.map(({ filePath, startLine, endLine, content }) => ({
+        filePath,
+        startLine,
+        endLine,
+        content
+      }))
+    };
+  }
+
+  it('produces the same index serially and in parallel', async () => {
+    const serial = await indexWith(1);
+    const parallel = await indexWith(8);
+    expect(serial.files).toBe(12);
+    expect(parallel).toEqual(serial);
+  });
+});
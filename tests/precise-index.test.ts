@@ -0,0 +1,263 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { parseScipDescriptors, readLsifIndex } from '../src/core/precise-formats.js';
+import { loadPreciseIndex } from '../src/core/precise-index.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  PRECISE_INDEX_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+// Just enough protobuf encoding to write a SCIP index
+function varint(value: number): number[] {
+  const bytes: number[] = [];
+  while (value > 127) {
+    bytes.push((value % 128) | 0x80);
+    value = Math.floor(value / 128);
+  }
+  bytes.push(value);
+  return bytes;
+}
+
+function field(number: number, value: string | number | number[]): number[] {
+  if (typeof value === 'number') return [...varint(number * 8), ...varint(value)];
+  const bytes = typeof value === 'string' ? [...Buffer.from(value, 'utf-8')] : value;
+  return [...varint(number * 8 + 2), ...varint(bytes.length), ...bytes];
+}
+
+const packed = (values: number[]) => values.flatMap(varint);
+
+const LOAD_CONFIG = 'scip-typescript npm app 1.0.0 src/`config.ts`/loadConfig().';
+
+function occurrence(range: number[], symbol: string, roles = 0, enclosing?: number[]): number[] {
+  return [
+    ...field(1, packed(range)),
+    ...field(2, symbol),
+    ...(roles ? field(3, roles) : []),
+    ...(enclosing ? field(7, packed(enclosing)) : [])
+  ];
+}
+
+function scipIndex(): Buffer {
+  const metadata = [...field(2, [...field(1, 'scip-typescript'), ...field(2, '0.3.14')])];
+  const config = [
+    ...field(1, 'src/config.ts'),
+    ...field(2, occurrence([4, 16, 26], LOAD_CONFIG, 1, [4, 0, 6, 1])),
+    ...field(2, occurrence([0, 7, 19], 'local 3', 1)),
+    ...field(3, [
+      ...field(1, LOAD_CONFIG),
+      ...field(3, '```ts\nfunction loadConfig(): ServerConfig\n```'),
+      ...field(3, 'Reads the server settings.')
+    ])
+  ];
+  const server = [
+    ...field(1, 'src/server.ts'),
+    ...field(2, occurrence([0, 9, 19], LOAD_CONFIG, 2)),
+    ...field(2, occurrence([3, 17, 27], LOAD_CONFIG))
+  ];
+  return Buffer.from([...field(1, metadata), ...field(2, config), ...field(2, server)]);
+}
+
+describe('SCIP symbols', () => {
+  it('reads descriptors after the package fields', () => {
+    expect(
+      parseScipDescriptors('scip-typescript npm app 1.0.0 src/`users.ts`/Users#save().')
+    ).toEqual([
+      { name: 'src', suffix: 'namespace' },
+      { name: 'users.ts', suffix: 'namespace' },
+      { name: 'Users', suffix: 'type' },
+      { name: 'save', suffix: 'method' }
+    ]);
+    const goSymbol = 'scip-go gomod example.com/app v1 `example.com/app`/Run(+1).(ctx)';
+    expect(parseScipDescriptors(goSymbol)).toEqual([
+      { name: 'example.com/app', suffix: 'namespace' },
+      { name: 'Run', suffix: 'method' },
+      { name: 'ctx', suffix: 'parameter' }
+    ]);
+  });
+});
+
+describe('LSIF import', () => {
+  it('follows result sets to definitions, references, hover and monikers', async () => {
+    const elements = [
+      { id: 1, type: 'vertex', label: 'metaData', projectRoot: 'file:///work/app' },
+      { id: 2, type: 'vertex', label: 'document', uri: 'file:///work/app/src/config.ts' },
+      { id: 3, type: 'vertex', label: 'resultSet' },
+      {
+        id: 4,
+        type: 'vertex',
+        label: 'range',
+        start: { line: 4, character: 16 },
+        end: { line: 4, character: 26 }
+      },
+      { id: 5, type: 'edge', label: 'next', outV: 4, inV: 3 },
+      { id: 6, type: 'vertex', label: 'definitionResult' },
+      { id: 7, type: 'edge', label: 'textDocument/definition', outV: 3, inV: 6 },
+      { id: 8, type: 'edge', label: 'item', outV: 6, inVs: [4], document: 2 },
+      {
+        id: 9,
+        type: 'vertex',
+        label: 'hoverResult',
+        result: { contents: [{ language: 'typescript', value: 'function loadConfig()' }] }
+      },
+      { id: 10, type: 'edge', label: 'textDocument/hover', outV: 3, inV: 9 },
+      { id: 11, type: 'vertex', label: 'document', uri: 'file:///work/app/src/server.ts' },
+      {
+        id: 12,
+        type: 'vertex',
+        label: 'range',
+        start: { line: 3, character: 17 },
+        end: { line: 3, character: 27 }
+      },
+      { id: 13, type: 'edge', label: 'next', outV: 12, inV: 3 },
+      { id: 14, type: 'vertex', label: 'referenceResult' },
+      { id: 15, type: 'edge', label: 'textDocument/references', outV: 3, inV: 14 },
+      { id: 16, type: 'edge', label: 'item', outV: 14, inVs: [4], property: 'definitions' },
+      { id: 17, type: 'edge', label: 'item', outV: 14, inVs: [12], property: 'references' },
+      { id: 18, type: 'edge', label: 'contains', outV: 2, inVs: [4] },
+      { id: 19, type: 'edge', label: 'contains', outV: 11, inVs: [12] },
+      { id: 20, type: 'vertex', label: 'moniker', identifier: 'src/config:loadConfig' },
+      { id: 21, type: 'edge', label: 'moniker', outV: 3, inV: 20 }
+    ];
+    const text = elements.map((element) => JSON.stringify(element)).join('\n');
+
+    const index = await readLsifIndex(text, '/work/app');
+
+    expect(index.files).toEqual(['src/config.ts', 'src/server.ts']);
+    expect(index.symbols).toEqual([
+      {
+        id: 'src/config:loadConfig',
+        name: 'loadConfig',
+        documentation: '```typescript\nfunction loadConfig()\n```',
+        definitions: [{ file: 'src/config.ts', line: 5 }],
+        references: [{ file: 'src/server.ts', line: 4 }]
+      }
+    ]);
+  });
+});
+
+describe('precise navigation tools', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'precise-index-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'config.ts'),
+      [
+        'export interface ServerConfig {',
+        '  port: number;',
+        '}',
+        '',
+        'export function loadConfig(): ServerConfig {',
+        '  return { port: 8080 };',
+        '}',
+        ''
+      ].join('\n')
+    );
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'server.ts'),
+      [
+        "import { loadConfig } from './config.js';",
+        '',
+        'export function start() {',
+        '  const config = loadConfig();',
+        '  return config.port;',
+        '}',
+        ''
+      ].join('\n')
+    );
+    // Not in the SCIP index: found by the syntactic scan
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'legacy.ts'),
+      "import { loadConfig } from './config.js';\nexport const port = loadConfig().port;\n"
+    );
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+    await fs.writeFile(path.join(tempRoot, 'index.scip'), scipIndex());
+
+    const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('imports index.scip once and keeps only repo symbols', async () => {
+    const index = await loadPreciseIndex(tempRoot);
+
+    expect(index).toMatchObject({
+      format: 'scip',
+      tool: 'scip-typescript 0.3.14',
+      source: 'index.scip',
+      files: ['src/config.ts', 'src/server.ts']
+    });
+    expect(index?.symbols.map((s) => s.name)).toEqual(['loadConfig']);
+    await expect(
+      fs.access(path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME, PRECISE_INDEX_FILENAME))
+    ).resolves.toBeUndefined();
+  });
+
+  it('answers get_definition from the precise index with hover documentation', async () => {
+    const result = await dispatchTool('get_definition', { symbol: 'loadConfig' }, ctx);
+    const payload = JSON.parse(result.content![0].text);
+
+    expect(payload.confidence).toBe('precise');
+    expect(payload.definitions[0]).toMatchObject({
+      name: 'loadConfig',
+      kind: 'function',
+      location: 'src/config.ts:5',
+      endLine: 7,
+      documentation: '```ts\nfunction loadConfig(): ServerConfig\n```\n\nReads the server settings.'
+    });
+    expect(payload.definitions[0].code.split('\n')).toHaveLength(3);
+  });
+
+  it('merges precise references with the scan for files the index does not cover', async () => {
+    const result = await dispatchTool('find_references', { symbol: 'loadConfig' }, ctx);
+    const payload = JSON.parse(result.content![0].text);
+
+    expect(payload.confidence).toBe('mixed');
+    expect(payload.definitions).toEqual(['src/config.ts:5']);
+    expect(payload.references.map((r: { location: string }) => r.location)).toEqual([
+      'src/config.ts:5',
+      'src/server.ts:1',
+      'src/server.ts:4',
+      'src/legacy.ts:1',
+      'src/legacy.ts:2'
+    ]);
+  });
+
+  it('falls back to heuristics for files edited after the index was generated', async () => {
+    const later = new Date(Date.now() + 60_000);
+    await fs.utimes(path.join(tempRoot, 'src', 'config.ts'), later, later);
+
+    const result = await dispatchTool('get_definition', { symbol: 'loadConfig' }, ctx);
+    const payload = JSON.parse(result.content![0].text);
+
+    expect(payload.confidence).toBe('syntactic');
+    expect(payload.definitions[0].location).toBe('src/config.ts:5');
+    expect(payload.definitions[0].documentation).toBeUndefined();
+  });
+});
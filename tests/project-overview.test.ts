@@ -0,0 +1,195 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import {
+  parseCargoBins,
+  parseComposeServices,
+  parseDockerfile,
+  parseMakeTargets,
+  parsePackageJsonEntries,
+  parsePyprojectScripts
+} from '../src/core/project-overview.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import { rmWithRetries } from './test-helpers.js';
+
+describe('project overview', () => {
+  it('reads entry points and scripts from package.json', () => {
+    const parsed = parsePackageJsonEntries(
+      JSON.stringify({
+        name: '@acme/cli',
+        main: 'dist/index.js',
+        bin: 'bin/cli.js',
+        exports: { '.': { import: './dist/index.mjs', require: './dist/index.cjs' } },
+        scripts: { build: 'tsc -p .', start: 'node dist/index.js' }
+      }),
+      'packages/cli/package.json'
+    );
+
+    expect(parsed.entryPoints).toEqual([
+      { file: 'packages/cli/dist/index.js', kind: 'main' },
+      { file: 'packages/cli/dist/index.mjs', kind: 'exports' },
+      { file: 'packages/cli/bin/cli.js', kind: 'bin', name: 'cli' }
+    ]);
+    expect(parsed.scripts).toEqual([
+      { file: 'packages/cli/package.json', name: 'build', command: 'tsc -p .' },
+      { file: 'packages/cli/package.json', name: 'start', command: 'node dist/index.js' }
+    ]);
+    expect(parsePackageJsonEntries('{ not json', 'package.json')).toEqual({
+      entryPoints: [],
+      scripts: []
+    });
+  });
+
+  it('reads Cargo binaries and Python console scripts', () => {
+    const cargo =
+      '[package]\nname = "acme"\n\n[[bin]]\nname = "server"\npath = "src/server.rs"\n\n' +
+      '[[bin]]\nname = "worker"\n\n[dependencies]\nserde = "1"\n';
+    expect(parseCargoBins(cargo, 'Cargo.toml')).toEqual([
+      { file: 'src/server.rs', kind: 'bin', name: 'server' },
+      { file: 'src/bin/worker.rs', kind: 'bin', name: 'worker' }
+    ]);
+
+    const pyproject =
+      '[project]\nname = "acme"\n\n[project.scripts]\nacme = "acme.cli:main"\n\n' +
+      '[tool.poetry.scripts]\nseed = "acme.db:seed"\n';
+    expect(parsePyprojectScripts(pyproject, 'pyproject.toml')).toEqual([
+      { file: 'pyproject.toml', kind: 'script', name: 'acme', target: 'acme.cli:main' },
+      { file: 'pyproject.toml', kind: 'script', name: 'seed', target: 'acme.db:seed' }
+    ]);
+  });
+
+  it('describes the last stage of a Dockerfile and the services of a compose file', () => {
+    const dockerfile =
+      'FROM node:20 AS build\nEXPOSE 9999\nRUN npm ci\n' +
+      'FROM --platform=linux/amd64 node:20-slim\nEXPOSE 3000 9229\nENTRYPOINT ["node"]\n' +
+      'CMD ["dist/server.js", \\\n  "--port=3000"]\n';
+    expect(parseDockerfile(dockerfile)).toEqual({
+      image: 'node:20-slim',
+      ports: ['3000', '9229'],
+      command: 'node dist/server.js --port=3000'
+    });
+
+    const compose =
+      'version: "3.9"\nservices:\n  api:\n    build:\n      context: ./api\n    ports:\n' +
+      '      - "8080:80"\n    environment:\n      - FOO=bar\n  db:\n' +
+      '    image: postgres:16 # pinned\n    ports: ["5432:5432"]\n  worker:\n' +
+      '    image: "acme/worker"\n    command: celery worker\nvolumes:\n  data:\n';
+    expect(parseComposeServices(compose, 'docker-compose.yml')).toEqual([
+      {
+        name: 'api',
+        file: 'docker-compose.yml',
+        kind: 'compose',
+        image: 'build ./api',
+        ports: ['8080:80']
+      },
+      {
+        name: 'db',
+        file: 'docker-compose.yml',
+        kind: 'compose',
+        image: 'postgres:16',
+        ports: ['5432:5432']
+      },
+      {
+        name: 'worker',
+        file: 'docker-compose.yml',
+        kind: 'compose',
+        image: 'acme/worker',
+        command: 'celery worker'
+      }
+    ]);
+  });
+
+  it('lists explicit Makefile targets only', () => {
+    const makefile =
+      'VAR := 1\nOTHER = 2\n.PHONY: build test\nbuild test: deps\n\tgo build ./...\n' +
+      '%.o: %.c\n\tcc -c $<\nlint:\n\tgolangci-lint run\n';
+    expect(parseMakeTargets(makefile)).toEqual(['build', 'test', 'lint']);
+  });
+
+  describe('describe_project tool', () => {
+    let tempRoot: string;
+    let ctx: ToolContext;
+
+    beforeEach(async () => {
+      tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'project-overview-'));
+      const write = async (file: string, content: string) => {
+        await fs.mkdir(path.dirname(path.join(tempRoot, file)), { recursive: true });
+        await fs.writeFile(path.join(tempRoot, file), content);
+      };
+      await write(
+        'package.json',
+        JSON.stringify({ name: 'shop', main: 'src/index.js', scripts: { test: 'vitest run' } })
+      );
+      await write('pnpm-lock.yaml', 'lockfileVersion: 9.0\n');
+      await write('src/index.js', 'export {};\n');
+      await write('cmd/worker/main.go', 'package main\n\nfunc main() {}\n');
+      await write('go.mod', 'module example.com/shop\n');
+      await write('Makefile', 'build:\n\tgo build ./...\n');
+      await write('deploy/Dockerfile', 'FROM golang:1.22\nEXPOSE 8080\nCMD ["/worker"]\n');
+      await write('.github/workflows/ci.yml', 'on: push\n');
+      await write('node_modules/dep/package.json', JSON.stringify({ main: 'index.js' }));
+
+      ctx = {
+        indexState: { status: 'idle' },
+        paths: {
+          baseDir: path.join(tempRoot, '.codebase-context'),
+          memory: path.join(tempRoot, '.codebase-context', 'memory.json'),
+          intelligence: path.join(tempRoot, '.codebase-context', 'intelligence.json'),
+          keywordIndex: path.join(tempRoot, '.codebase-context', 'index.json'),
+          vectorDb: path.join(tempRoot, '.codebase-context', 'index')
+        },
+        rootPath: tempRoot,
+        performIndexing: () => {}
+      };
+    });
+
+    afterEach(async () => {
+      await rmWithRetries(tempRoot);
+    });
+
+    it('summarizes how the project is built and run, without an index', async () => {
+      const response = await dispatchTool('describe_project', {}, ctx);
+      const payload = JSON.parse(response.content![0].text);
+
+      expect(payload.status).toBe('success');
+      expect(payload.buildSystems).toEqual([
+        { name: 'go', files: ['go.mod'] },
+        { name: 'make', files: ['Makefile'] },
+        { name: 'pnpm', files: ['package.json'] }
+      ]);
+      expect(payload.entryPoints).toEqual([
+        { file: 'src/index.js', kind: 'main' },
+        { file: 'cmd/worker/main.go', kind: 'main', name: 'worker' }
+      ]);
+      expect(payload.scripts).toEqual([
+        { file: 'package.json', name: 'test', command: 'vitest run' }
+      ]);
+      expect(payload.makeTargets).toEqual([{ file: 'Makefile', targets: ['build'] }]);
+      expect(payload.services).toEqual([
+        {
+          name: 'deploy',
+          file: 'deploy/Dockerfile',
+          kind: 'dockerfile',
+          image: 'golang:1.22',
+          ports: ['8080'],
+          command: '/worker'
+        }
+      ]);
+      expect(payload.ci).toEqual(['.github/workflows/ci.yml']);
+    });
+
+    it('says so when nothing is found', async () => {
+      const empty = await fs.mkdtemp(path.join(os.tmpdir(), 'project-overview-empty-'));
+      try {
+        const response = await dispatchTool('describe_project', {}, { ...ctx, rootPath: empty });
+        const payload = JSON.parse(response.content![0].text);
+        expect(payload.status).toBe('success');
+        expect(payload.message).toContain('No build manifests');
+      } finally {
+        await rmWithRetries(empty);
+      }
+    });
+  });
+});
@@ -0,0 +1,218 @@
+import { describe, it, expect, afterEach, vi } from 'vitest';
+import {
+  QdrantStorageProvider,
+  buildQdrantFilter,
+  deriveQdrantCollectionName
+} from '../src/storage/qdrant.js';
+import type { CodeChunkWithEmbedding } from '../src/storage/types.js';
+
+interface RecordedCall {
+  method: string;
+  url: string;
+  body?: unknown;
+}
+
+function mockQdrant(handler: (call: RecordedCall) => { status?: number; body?: unknown }) {
+  const calls: RecordedCall[] = [];
+  vi.stubGlobal(
+    'fetch',
+    vi.fn(async (url: string, init?: RequestInit) => {
+      const call: RecordedCall = {
+        method: init?.method ?? 'GET',
+        url,
+        body: init?.body ? JSON.parse(String(init.body)) : undefined
+      };
+      calls.push(call);
+      const { status = 200, body = { result: true } } = handler(call);
+      return new Response(JSON.stringify(body), { status });
+    })
+  );
+  return calls;
+}
+
+function makeChunk(id: string, filePath: string): CodeChunkWithEmbedding {
+  return {
+    id,
+    content: 'export const x = 1;',
+    filePath,
+    relativePath: filePath.replace('/repo/', ''),
+    startLine: 1,
+    endLine: 1,
+    language: 'typescript',
+    dependencies: [],
+    imports: [],
+    exports: ['x'],
+    tags: ['util'],
+    metadata: { componentName: 'x' },
+    embedding: [0.1, 0.2, 0.3]
+  };
+}
+
+describe('QdrantStorageProvider', () => {
+  afterEach(() => {
+    vi.unstubAllGlobals();
+  });
+
+  it('derives a stable per-project collection name', () => {
+    const a = deriveQdrantCollectionName('/work/My Repo');
+    expect(a).toMatch(/^codebase-context-my-repo-[0-9a-f]{8}$/);
+    expect(deriveQdrantCollectionName('/work/My Repo')).toBe(a);
+    expect(deriveQdrantCollectionName('/other/My Repo')).not.toBe(a);
+  });
+
+  it('builds payload filters for language and paths', () => {
+    expect(buildQdrantFilter(undefined)).toBeUndefined();
+    expect(buildQdrantFilter({})).toBeUndefined();
+    expect(
+      buildQdrantFilter({ language: 'go', filePaths: ['src/a.go'], excludePaths: ['vendor/x.go'] })
+    ).toEqual({
+      must: [
+        { key: 'language', match: { value: 'go' } },
+        {
+          should: [
+            { key: 'filePath', match: { any: ['src/a.go'] } },
+            { key: 'relativePath', match: { any: ['src/a.go'] } }
+          ]
+        }
+      ],
+      must_not: [
+        { key: 'filePath', match: { any: ['vendor/x.go'] } },
+        { key: 'relativePath', match: { any: ['vendor/x.go'] } }
+      ]
+    });
+  });
+
+  it('creates the collection on first store and upserts points with payload', async () => {
+    const calls = mockQdrant((call) =>
+      call.method === 'GET' ? { status: 404, body: { status: 'not found' } } : {}
+    );
+
+    const provider = new QdrantStorageProvider({ url: 'http://q:6333/', collection: 'proj' });
+    await provider.initialize('/unused');
+    expect(await provider.search([0.1, 0.2, 0.3], 5)).toEqual([]);
+
+    await provider.store([makeChunk('11111111-2222-3333-4444-555555555555', '/repo/a.ts')]);
+
+    const create = calls.find((c) => c.method === 'PUT' && c.url === 'http://q:6333/collections/proj');
+    expect(create?.body).toEqual({ vectors: { size: 3, distance: 'Cosine' } });
+
+    const upsert = calls.find((c) => c.url.endsWith('/collections/proj/points?wait=true'));
+    const points = (upsert?.body as { points: Array<{ id: string; payload: { chunkId: string } }> })
+      .points;
+    expect(points[0].id).toBe('11111111-2222-3333-4444-555555555555');
+    expect(points[0].payload.chunkId).toBe('11111111-2222-3333-4444-555555555555');
+  });
+
+  it('maps non-UUID chunk ids onto deterministic UUID point ids', async () => {
+    const calls = mockQdrant(() => ({}));
+    const provider = new QdrantStorageProvider({ collection: 'proj' });
+    await provider.initialize('/unused');
+    await provider.store([makeChunk('chunk-1', '/repo/a.ts')]);
+
+    const upsert = calls.find((c) => c.url.includes('/points?wait=true'));
+    const [point] = (upsert?.body as { points: Array<{ id: string }> }).points;
+    expect(point.id).toMatch(/^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$/);
+  });
+
+  it('returns chunks from search results and sends the api key', async () => {
+    const chunk = makeChunk('11111111-2222-3333-4444-555555555555', '/repo/a.ts');
+    const calls = mockQdrant((call) => {
+      if (call.url.endsWith('/points/search')) {
+        return {
+          body: {
+            result: [
+              {
+                id: chunk.id,
+                score: 0.87,
+                payload: { ...chunk, chunkId: chunk.id, framework: '', componentType: '', layer: '' }
+              }
+            ]
+          }
+        };
+      }
+      return {};
+    });
+
+    const provider = new QdrantStorageProvider({ collection: 'proj', apiKey: 'secret' });
+    await provider.initialize('/unused');
+    const results = await provider.search([0.1, 0.2, 0.3], 3, { language: 'typescript' });
+
+    expect(results).toHaveLength(1);
+    expect(results[0].score).toBeCloseTo(0.87);
+    expect(results[0].chunk.relativePath).toBe('a.ts');
+    expect(results[0].chunk.framework).toBeUndefined();
+
+    const search = calls.find((c) => c.url.endsWith('/points/search'));
+    expect(search?.body).toMatchObject({
+      limit: 3,
+      filter: { must: [{ key: 'language', match: { value: 'typescript' } }] }
+    });
+    const fetchMock = vi.mocked(fetch);
+    const headers = fetchMock.mock.calls[0]?.[1]?.headers as Record<string, string>;
+    expect(headers['api-key']).toBe('secret');
+  });
+
+  it('deletes points by file path and reports the count removed', async () => {
+    const calls = mockQdrant((call) =>
+      call.url.endsWith('/points/count') ? { body: { result: { count: 4 } } } : {}
+    );
+
+    const provider = new QdrantStorageProvider({ collection: 'proj' });
+    await provider.initialize('/unused');
+    const deleted = await provider.deleteByFilePaths(['/repo/a.ts']);
+
+    expect(deleted).toBe(4);
+    const del = calls.find((c) => c.url.includes('/points/delete'));
+    expect(del?.body).toEqual({
+      filter: { must: [{ key: 'filePath', match: { any: ['/repo/a.ts'] } }] }
+    });
+  });
+
+  it('rebuilds into a new generation and swaps the alias only on commit', async () => {
+    const calls = mockQdrant((call) =>
+      call.url.endsWith('/aliases')
+        ? { body: { result: { aliases: [{ alias_name: 'proj', collection_name: 'proj-old' }] } } }
+        : {}
+    );
+
+    const provider = new QdrantStorageProvider({ url: 'http://q:6333', collection: 'proj' });
+    await provider.initialize('/unused');
+    await provider.beginGeneration();
+    await provider.store([makeChunk('chunk-1', '/repo/a.ts')]);
+
+    const upsert = calls.find((c) => c.url.includes('/points?wait=true'));
+    const generation = upsert?.url.match(/\/collections\/(proj-[^/]+)\/points/)?.[1];
+    expect(generation).toBeDefined();
+    expect(generation).not.toBe('proj-old');
+    // Searches keep reading the live collection until the commit
+    expect(calls.some((c) => c.method !== 'GET' && c.url.endsWith('/collections/proj'))).toBe(
+      false
+    );
+
+    await provider.commitGeneration();
+    const swap = calls.find((c) => c.method === 'POST' && c.url.endsWith('/collections/aliases'));
+    expect(swap?.body).toEqual({
+      actions: [
+        { delete_alias: { alias_name: 'proj' } },
+        { create_alias: { collection_name: generation, alias_name: 'proj' } }
+      ]
+    });
+    const dropped = calls.filter((c) => c.method === 'DELETE').map((c) => c.url);
+    expect(dropped).toEqual(['http://q:6333/collections/proj-old']);
+  });
+
+  it('drops an aborted generation without touching the live collection', async () => {
+    const calls = mockQdrant(() => ({}));
+    const provider = new QdrantStorageProvider({ url: 'http://q:6333', collection: 'proj' });
+    await provider.initialize('/unused');
+    await provider.beginGeneration();
+    await provider.store([makeChunk('chunk-1', '/repo/a.ts')]);
+    await provider.abortGeneration();
+
+    const upsert = calls.find((c) => c.url.includes('/points?wait=true'));
+    const generation = upsert?.url.match(/\/collections\/(proj-[^/]+)\/points/)?.[1];
+    const dropped = calls.filter((c) => c.method === 'DELETE').map((c) => c.url);
+    expect(dropped).toEqual([`http://q:6333/collections/${generation}`]);
+    expect(calls.some((c) => c.url.endsWith('/collections/aliases'))).toBe(false);
+  });
+});
@@ -0,0 +1,121 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import {
+  buildQueryRewrites,
+  parseSampledRewrites,
+  rewriteWithSynonyms
+} from '../src/core/query-rewrite.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import type { Sampler } from '../src/types/index.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+describe('rewriteWithSynonyms', () => {
+  it('replaces everyday phrasings with code terms, one variant per term', () => {
+    expect(rewriteWithSynonyms('where do we throttle uploads')).toEqual([
+      'where do we rateLimiter uploads',
+      'where do we rate limit uploads',
+      'where do we debounce uploads'
+    ]);
+  });
+
+  it('combines idioms and skips terms the query already has', () => {
+    const variants = rewriteWithSynonyms('retry with backoff when the cache misses', 2);
+    expect(variants).toEqual([
+      'maxAttempts with backoff when the memoize misses',
+      'maxAttempts with backoff when the ttl misses'
+    ]);
+  });
+
+  it('returns nothing for queries without a known idiom', () => {
+    expect(rewriteWithSynonyms('UserService.save')).toEqual([]);
+  });
+});
+
+describe('sampled rewrites', () => {
+  it('reads the JSON array out of a model reply', () => {
+    const reply =
+      'Sure:\n["rateLimiter middleware", "throttle upload", "RATELIMITER middleware", 3]';
+    expect(parseSampledRewrites(reply, 'throttle upload')).toEqual(['rateLimiter middleware']);
+    expect(parseSampledRewrites('no json here', 'q')).toEqual([]);
+  });
+
+  it('asks the client model and falls back to the dictionary', async () => {
+    const sample: Sampler = async () => '["tokenBucket limiter", "rateLimit middleware"]';
+    expect(await buildQueryRewrites('throttle uploads', 'sampling', sample)).toEqual({
+      variants: ['tokenBucket limiter', 'rateLimit middleware'],
+      source: 'sampling'
+    });
+
+    const failing: Sampler = async () => {
+      throw new Error('declined');
+    };
+    const fallback = await buildQueryRewrites('throttle uploads', 'sampling', failing);
+    expect(fallback).toMatchObject({ source: 'synonyms', samplingError: 'declined' });
+    expect(fallback?.variants[0]).toBe('rateLimiter uploads');
+
+    expect(await buildQueryRewrites('throttle uploads', 'off', sample)).toBeUndefined();
+  });
+});
+
+describe('search_codebase rewrite', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'query-rewrite-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'limits.ts'),
+      'export const rateLimiter = createRateLimiter({ perMinute: 60 });\n'
+    );
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'banner.ts'),
+      "export const banner = 'Welcome back';\n"
+    );
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('retrieves code named in the rewritten vocabulary', async () => {
+    const result = await dispatchTool(
+      'search_codebase',
+      { query: 'throttle', mode: 'keyword', rewrite: 'synonyms' },
+      ctx
+    );
+    const payload = JSON.parse(result.content![0].text);
+
+    expect(payload.queryRewrites).toEqual({
+      source: 'synonyms',
+      variants: ['rateLimiter', 'rate limit', 'debounce']
+    });
+    expect(payload.results[0].file).toContain('limits.ts');
+  });
+});
@@ -0,0 +1,132 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { execFileSync } from 'child_process';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import {
+  loadProjectRecencyConfig,
+  recencyBoostFactors,
+  resolveRecencyBoost
+} from '../src/core/recency-boost.js';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { CodebaseSearcher } from '../src/core/search.js';
+import { getRecentCommitCounts } from '../src/utils/git-dates.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  PROJECT_CONFIG_FILENAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const DAY_MS = 24 * 60 * 60 * 1000;
+const NOW = Date.parse('2026-06-01T00:00:00Z');
+
+function git(cwd: string, date: string, ...args: string[]): void {
+  execFileSync('git', ['-c', 'user.name=test', '-c', 'user.email=test@example.com', ...args], {
+    cwd,
+    env: { ...process.env, GIT_AUTHOR_DATE: date, GIT_COMMITTER_DATE: date },
+    stdio: 'ignore'
+  });
+}
+
+describe('recencyBoostFactors', () => {
+  const options = resolveRecencyBoost({ recencyWeight: 0.2, halfLifeDays: 10, churnWeight: 0.1 });
+
+  it('halves the recency boost every half-life and scales churn to the busiest file', () => {
+    const fresh = recencyBoostFactors(
+      { lastModified: new Date(NOW).toISOString(), recentCommits: 9 },
+      options,
+      9,
+      NOW
+    );
+    expect(fresh.recency).toBeCloseTo(1.2);
+    expect(fresh.churn).toBeCloseTo(1.1);
+
+    const older = recencyBoostFactors(
+      { lastModified: new Date(NOW - 10 * DAY_MS).toISOString(), recentCommits: 1 },
+      options,
+      9,
+      NOW
+    );
+    expect(older.recency).toBeCloseTo(1.1);
+    expect(older.churn).toBeCloseTo(1 + (0.1 * Math.log(2)) / Math.log(10));
+  });
+
+  it('is neutral when off or when the file has no history', () => {
+    const metadata = { lastModified: new Date(NOW).toISOString(), recentCommits: 3 };
+    expect(recencyBoostFactors(metadata, resolveRecencyBoost(), 3, NOW)).toEqual({
+      recency: 1,
+      churn: 1
+    });
+    expect(recencyBoostFactors({}, options, 3, NOW)).toEqual({ recency: 1, churn: 1 });
+  });
+});
+
+describe('recency boost in search', () => {
+  let tempRoot: string;
+
+  beforeEach(async () => {
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'recency-boost-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    const write = (name: string, body: string) =>
+      fs.writeFile(path.join(tempRoot, 'src', name), body);
+
+    git(tempRoot, '2020-01-01T00:00:00Z', 'init', '-q');
+    await write('legacy.ts', "export function parseInvoice() {\n  return 'legacy';\n}\n");
+    git(tempRoot, '2020-01-01T00:00:00Z', 'add', '.');
+    git(tempRoot, '2020-01-01T00:00:00Z', 'commit', '-q', '-m', 'legacy');
+    const recent = new Date(Date.now() - 2 * DAY_MS).toISOString();
+    for (const version of ['a', 'b', 'c']) {
+      await write('current.ts', `export function parseInvoice() {\n  return '${version}';\n}\n`);
+      git(tempRoot, recent, 'add', '.');
+      git(tempRoot, recent, 'commit', '-q', '-m', version);
+    }
+  });
+
+  afterEach(async () => {
+    vi.restoreAllMocks();
+    await rmWithRetries(tempRoot);
+  });
+
+  it('counts recent commits per file', async () => {
+    const counts = await getRecentCommitCounts(tempRoot);
+    expect(counts.get('src/current.ts')).toBe(3);
+    expect(counts.has('src/legacy.ts')).toBe(false);
+  });
+
+  it('boosts from the project config, and a query can turn it off', async () => {
+    await fs.mkdir(path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME), { recursive: true });
+    await fs.writeFile(
+      path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME, PROJECT_CONFIG_FILENAME),
+      JSON.stringify({ search: { recency: { recencyWeight: 0.3, churnWeight: 0.2, bad: 1 } } })
+    );
+    expect(await loadProjectRecencyConfig(tempRoot)).toEqual({
+      recencyWeight: 0.3,
+      churnWeight: 0.2
+    });
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const searcher = new CodebaseSearcher(tempRoot);
+    const search = (recency?: { recencyWeight: number; churnWeight: number }) =>
+      searcher.search('parseInvoice', 5, undefined, {
+        useSemanticSearch: false,
+        useKeywordSearch: true,
+        debug: true,
+        ...(recency ? { recency } : {})
+      });
+    const reasons = (results: Awaited<ReturnType<typeof search>>, file: string) =>
+      results
+        .find((result) => result.filePath.endsWith(file))
+        ?.scoreBreakdown?.adjustments.map((adjustment) => adjustment.reason) ?? [];
+
+    const boosted = await search();
+    expect(reasons(boosted, 'current.ts')).toEqual(
+      expect.arrayContaining(['recent change', 'edit frequency'])
+    );
+    expect(reasons(boosted, 'legacy.ts')).not.toContain('edit frequency');
+    expect(reasons(boosted, 'legacy.ts')).not.toContain('recent change');
+
+    const off = await search({ recencyWeight: 0, churnWeight: 0 });
+    expect(reasons(off, 'current.ts')).not.toContain('recent change');
+  });
+});
@@ -0,0 +1,189 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { execFileSync } from 'child_process';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import {
+  fetchRemoteRepo,
+  parseRemoteRepoUrl,
+  remoteCheckoutDir,
+  remoteProjectName,
+  type RemoteRepo
+} from '../src/core/remote-repo.js';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+function git(cwd: string, ...args: string[]): string {
+  return execFileSync(
+    'git',
+    ['-c', 'user.name=test', '-c', 'user.email=test@example.com', ...args],
+    { cwd, encoding: 'utf8' }
+  ).trim();
+}
+
+describe('parseRemoteRepoUrl', () => {
+  it('reads GitHub URLs with and without a tree ref', () => {
+    expect(parseRemoteRepoUrl('https://github.com/acme/widgets')).toEqual({
+      host: 'github.com',
+      kind: 'github',
+      repoPath: 'acme/widgets',
+      cloneUrl: 'https://github.com/acme/widgets.git'
+    });
+    expect(parseRemoteRepoUrl('https://github.com/acme/widgets.git')).toMatchObject({
+      repoPath: 'acme/widgets'
+    });
+    expect(parseRemoteRepoUrl('https://github.com/acme/widgets/tree/release/2.x')).toMatchObject({
+      repoPath: 'acme/widgets',
+      ref: 'release/2.x'
+    });
+  });
+
+  it('keeps GitLab subgroups and reads /-/tree refs', () => {
+    expect(parseRemoteRepoUrl('https://gitlab.com/acme/platform/api/-/tree/v1.4.0')).toEqual({
+      host: 'gitlab.com',
+      kind: 'gitlab',
+      repoPath: 'acme/platform/api',
+      cloneUrl: 'https://gitlab.com/acme/platform/api.git',
+      ref: 'v1.4.0'
+    });
+  });
+
+  it('accepts ssh URLs as given', () => {
+    expect(parseRemoteRepoUrl('git@github.com:acme/widgets.git')).toEqual({
+      host: 'github.com',
+      kind: 'github',
+      repoPath: 'acme/widgets',
+      cloneUrl: 'git@github.com:acme/widgets.git'
+    });
+  });
+
+  it('rejects local paths, other schemes and unsafe refs', () => {
+    expect(() => parseRemoteRepoUrl('/home/me/repo')).toThrow();
+    expect(() => parseRemoteRepoUrl('file:///home/me/repo')).toThrow();
+    expect(() => parseRemoteRepoUrl('https://github.com/acme')).toThrow();
+    const optionRef = 'https://github.com/acme/widgets/tree/--upload-pack=x';
+    expect(() => parseRemoteRepoUrl(optionRef)).toThrow();
+    // Hosts become directories under the remotes cache
+    for (const url of ['git@..:org/repo', 'git@.:org/repo', 'git@-oProxy:org/repo']) {
+      expect(() => parseRemoteRepoUrl(url), url).toThrow('Not a repository host');
+    }
+  });
+
+  it('refuses checkout directories outside the remotes cache', () => {
+    const repo = { host: '..', kind: 'git' as const, repoPath: '../..', cloneUrl: 'x' };
+    expect(() => remoteCheckoutDir('/cache/remotes', repo)).toThrow(/escapes/);
+  });
+
+  it('names projects and checkouts by repo and ref', () => {
+    const repo = parseRemoteRepoUrl('https://github.com/acme/widgets');
+    expect(remoteProjectName(repo)).toBe('acme/widgets');
+    expect(remoteProjectName(repo, 'v2')).toBe('acme/widgets@v2');
+    const dir = remoteCheckoutDir('/cache', repo, 'feature/a');
+    expect(path.dirname(dir)).toBe(path.join('/cache', 'github.com', 'acme', 'widgets'));
+    expect(dir).not.toBe(remoteCheckoutDir('/cache', repo, 'feature-a'));
+  });
+});
+
+describe('fetchRemoteRepo', () => {
+  let tempRoot: string;
+  let repo: RemoteRepo;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'remote-repo-'));
+    const origin = path.join(tempRoot, 'origin');
+    await fs.mkdir(origin);
+    git(origin, 'init', '-q');
+    git(origin, 'checkout', '-q', '-b', 'main');
+    await fs.writeFile(path.join(origin, 'lib.ts'), 'export const version = 1;\n');
+    git(origin, 'add', '-A');
+    git(origin, 'commit', '-q', '-m', 'v1');
+    git(origin, 'tag', 'v1');
+    await fs.writeFile(path.join(origin, 'lib.ts'), 'export const version = 2;\n');
+    git(origin, 'commit', '-q', '-am', 'v2');
+
+    repo = { host: 'example.test', kind: 'git', repoPath: 'acme/lib', cloneUrl: origin };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('fetches one ref shallowly and reuses the checkout until refreshed', async () => {
+    const remotesDir = path.join(tempRoot, 'remotes');
+
+    const first = await fetchRemoteRepo(repo, { ref: 'v1', method: 'clone', remotesDir });
+    expect(first).toMatchObject({ method: 'clone', ref: 'v1', reused: false });
+    expect(first.rootPath.startsWith(remotesDir)).toBe(true);
+    expect(await fs.readFile(path.join(first.rootPath, 'lib.ts'), 'utf-8')).toContain('1');
+    expect(git(first.rootPath, 'rev-list', '--count', 'HEAD')).toBe('1');
+
+    const again = await fetchRemoteRepo(repo, { ref: 'v1', method: 'clone', remotesDir });
+    expect(again).toMatchObject({ reused: true, commit: first.commit });
+
+    const refreshed = await fetchRemoteRepo(repo, {
+      ref: 'v1',
+      method: 'clone',
+      remotesDir,
+      refresh: true
+    });
+    expect(refreshed.reused).toBe(false);
+
+    const head = await fetchRemoteRepo(repo, { method: 'clone', remotesDir });
+    expect(head.rootPath).not.toBe(first.rootPath);
+    expect(await fs.readFile(path.join(head.rootPath, 'lib.ts'), 'utf-8')).toContain('2');
+  });
+
+  it("drops the checkout's own context directory, config and escaping symlinks", async () => {
+    const origin = repo.cloneUrl;
+    await fs.writeFile(path.join(tempRoot, 'secret.ts'), 'export const token = "outside";\n');
+    await fs.mkdir(path.join(origin, CODEBASE_CONTEXT_DIRNAME));
+    await fs.writeFile(
+      path.join(origin, CODEBASE_CONTEXT_DIRNAME, 'config.json'),
+      JSON.stringify({ lsp: { servers: { typescript: ['/bin/sh', '-c', 'id'] } } })
+    );
+    await fs.writeFile(path.join(origin, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME), '{}');
+    await fs.writeFile(path.join(origin, 'codebase-context.yaml'), 'env:\n  FOO: bar\n');
+    await fs.symlink(path.join(tempRoot, 'secret.ts'), path.join(origin, 'leak.ts'));
+    await fs.symlink('lib.ts', path.join(origin, 'alias.ts'));
+    git(origin, 'add', '-A');
+    git(origin, 'commit', '-q', '-m', 'untrusted');
+
+    const remotesDir = path.join(tempRoot, 'remotes');
+    const fetched = await fetchRemoteRepo(repo, { method: 'clone', remotesDir });
+    const entries = await fs.readdir(fetched.rootPath);
+    expect(entries).not.toContain('codebase-context.yaml');
+    expect(entries).not.toContain('leak.ts');
+    expect(entries).toContain('alias.ts');
+    expect(await fs.readdir(path.join(fetched.rootPath, CODEBASE_CONTEXT_DIRNAME))).toEqual([
+      'remote.json'
+    ]);
+  });
+
+  it('does not index files that symlinks lead to outside the root', async () => {
+    const root = path.join(tempRoot, 'project');
+    await fs.mkdir(root);
+    await fs.writeFile(path.join(tempRoot, 'secret.ts'), 'export const token = "outside";\n');
+    await fs.writeFile(path.join(root, 'lib.ts'), 'export const version = 1;\n');
+    await fs.symlink(path.join(tempRoot, 'secret.ts'), path.join(root, 'leak.ts'));
+    await new CodebaseIndexer({ rootPath: root, config: { skipEmbedding: true } }).index();
+
+    const keywordIndex = await fs.readFile(
+      path.join(root, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+      'utf-8'
+    );
+    expect(keywordIndex).toContain('version');
+    expect(keywordIndex).not.toContain('outside');
+  });
+
+  it('cleans up and reports the ref when the fetch fails', async () => {
+    const remotesDir = path.join(tempRoot, 'remotes');
+    await expect(
+      fetchRemoteRepo(repo, { ref: 'no-such-tag', method: 'clone', remotesDir })
+    ).rejects.toThrow(/at no-such-tag/);
+    await expect(fs.access(remoteCheckoutDir(remotesDir, repo, 'no-such-tag'))).rejects.toThrow();
+  });
+});
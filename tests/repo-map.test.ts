@@ -0,0 +1,84 @@
+import { describe, it, expect, beforeAll, afterAll } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { buildRepoMap } from '../src/core/repo-map.js';
+import { rmWithRetries } from './test-helpers.js';
+
+describe('buildRepoMap', () => {
+  let tempDir: string;
+
+  const write = async (file: string, lines: string[]) => {
+    await fs.mkdir(path.dirname(path.join(tempDir, file)), { recursive: true });
+    await fs.writeFile(path.join(tempDir, file), lines.join('\n'));
+  };
+
+  beforeAll(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'repo-map-'));
+    await write('src/core/cart.ts', [
+      'export class Cart {',
+      '  add(item: string) {',
+      '    return item;',
+      '  }',
+      '}',
+      '',
+      'export function emptyCart() {',
+      '  return new Cart();',
+      '}',
+      ''
+    ]);
+    await write('src/checkout.ts', [
+      "import { emptyCart } from './core/cart';",
+      '',
+      'export function checkout() {',
+      '  return emptyCart();',
+      '}',
+      ''
+    ]);
+    for (let i = 0; i < 40; i++) {
+      await write(`src/widgets/widget${i}.ts`, [
+        `export function renderWidgetNumber${i}() {`,
+        `  return 'widget ${i}';`,
+        '}',
+        ''
+      ]);
+    }
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+  });
+
+  afterAll(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('renders the tree with top-level symbols only', async () => {
+    const map = await buildRepoMap(tempDir, { tokenBudget: 4000 });
+    expect(map?.includedFiles).toBe(42);
+    const lines = map!.text.split('\n');
+    expect(lines).toContain('src/');
+    expect(lines).toContain('  core/');
+    expect(lines).toContain('    cart.ts: class Cart, function emptyCart');
+    expect(map!.text).not.toContain('method add');
+  });
+
+  it('keeps the most-imported files within a tight budget and honors the path scope', async () => {
+    const tight = await buildRepoMap(tempDir, { tokenBudget: 120 });
+    expect(tight!.tokens).toBeLessThanOrEqual(120);
+    expect(tight!.includedFiles).toBeLessThan(tight!.totalFiles);
+    expect(tight!.text).toContain('cart.ts');
+    expect(tight!.text).toMatch(/more files; raise tokens or narrow path/);
+
+    const scoped = await buildRepoMap(tempDir, { scope: './src/core/' });
+    expect(scoped).toMatchObject({ totalFiles: 1, includedFiles: 1 });
+    expect(scoped!.text.split('\n')[0]).toBe('# Repo map (src/core/): 1 of 1 files');
+  });
+
+  it('returns null before the project is indexed', async () => {
+    const empty = await fs.mkdtemp(path.join(os.tmpdir(), 'repo-map-empty-'));
+    try {
+      expect(await buildRepoMap(empty)).toBeNull();
+    } finally {
+      await rmWithRetries(empty);
+    }
+  });
+});
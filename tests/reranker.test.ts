@@ -1,5 +1,11 @@
-import { describe, it, expect } from 'vitest';
-import { isAmbiguous } from '../src/core/reranker.js';
+import { describe, it, expect, afterEach, vi } from 'vitest';
+import {
+  isAmbiguous,
+  rerank,
+  resolveRerankerConfig,
+  RERANK_CANDIDATES,
+  type RerankerConfig
+} from '../src/core/reranker.js';
 import type { SearchResult } from '../src/types/index.js';
 
 function makeResult(score: number, filePath: string): SearchResult {
@@ -61,6 +67,64 @@ describe('Reranker ambiguity detection', () => {
   });
 });
 
+describe('Reranker providers and modes', () => {
+  const apiConfig: RerankerConfig = {
+    provider: 'cohere',
+    model: 'rerank-test',
+    apiKey: 'key',
+    endpoint: 'https://rerank.example/v2/rerank'
+  };
+
+  afterEach(() => {
+    vi.unstubAllGlobals();
+  });
+
+  it('defaults to the local cross-encoder and resolves hosted providers from env', () => {
+    expect(resolveRerankerConfig({}).provider).toBe('local');
+    expect(
+      resolveRerankerConfig({ RERANKER_PROVIDER: 'voyage', VOYAGE_API_KEY: 'v-key' })
+    ).toMatchObject({ provider: 'voyage', apiKey: 'v-key', model: 'rerank-2-lite' });
+  });
+
+  it('leaves results untouched when off, or in auto mode with a clear winner', async () => {
+    const fetchMock = vi.fn();
+    vi.stubGlobal('fetch', fetchMock);
+    const clear = [makeResult(0.95, '/a.ts'), makeResult(0.6, '/b.ts'), makeResult(0.5, '/c.ts')];
+
+    expect(await rerank('q', clear, 'off', apiConfig)).toBe(clear);
+    expect(await rerank('q', clear, 'auto', apiConfig)).toBe(clear);
+    expect(fetchMock).not.toHaveBeenCalled();
+  });
+
+  it('reorders the top candidates by API relevance in always mode', async () => {
+    const results = Array.from({ length: RERANK_CANDIDATES + 5 }, (_, i) =>
+      makeResult(0.9 - i * 0.01, `/f${i}.ts`)
+    );
+    const fetchMock = vi.fn(async (_url: string, init: { body: string }) => {
+      const { documents } = JSON.parse(init.body) as { documents: string[] };
+      // Prefer the last candidate sent
+      return new Response(
+        JSON.stringify({
+          results: documents.map((_, index) => ({
+            index,
+            relevance_score: index / documents.length
+          }))
+        })
+      );
+    });
+    vi.stubGlobal('fetch', fetchMock);
+
+    const reranked = await rerank('q', results, 'always', apiConfig);
+
+    const sent = JSON.parse(fetchMock.mock.calls[0][1].body) as { documents: string[] };
+    expect(sent.documents).toHaveLength(RERANK_CANDIDATES);
+    expect(reranked[0].filePath).toBe(`/f${RERANK_CANDIDATES - 1}.ts`);
+    expect(reranked.slice(RERANK_CANDIDATES).map((r) => r.filePath)).toEqual(
+      results.slice(RERANK_CANDIDATES).map((r) => r.filePath)
+    );
+  });
+});
+
 describe('File-level dedupe in search results', () => {
   it('removes duplicate files keeping best score via CodebaseSearcher', async () => {
     // This is tested via the integration in search-ranking.test.ts
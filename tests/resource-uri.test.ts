@@ -1,8 +1,11 @@
 import { describe, it, expect } from 'vitest';
 import {
   CONTEXT_RESOURCE_URI,
+  fileResourceUri,
   isContextResourceUri,
-  normalizeResourceUri
+  normalizeResourceUri,
+  parseFileResourceUri,
+  parseRepoMapUri
 } from '../src/resources/uri.js';
 
 describe('resource URI normalization', () => {
@@ -22,3 +25,33 @@ describe('resource URI normalization', () => {
     expect(isContextResourceUri('other/codebase://other')).toBe(false);
   });
 });
+
+describe('repo map resource URI', () => {
+  it('parses the token budget and path scope', () => {
+    expect(parseRepoMapUri('codebase://repo-map')).toEqual({});
+    const namespaced = 'codebase-context/codebase://repo-map?tokens=2000&path=src/core';
+    expect(parseRepoMapUri(namespaced)).toEqual({ tokens: 2000, path: 'src/core' });
+    expect(parseRepoMapUri('codebase://repo-map?tokens=lots')).toEqual({});
+    expect(parseRepoMapUri(CONTEXT_RESOURCE_URI)).toBeNull();
+  });
+});
+
+describe('file resource URI', () => {
+  it('round-trips paths and parses chunk queries', () => {
+    const uri = fileResourceUri('docs/design notes.md');
+    expect(uri).toBe('repo://docs/design%20notes.md');
+    expect(parseFileResourceUri(uri)).toEqual({ path: 'docs/design notes.md' });
+    expect(parseFileResourceUri('codebase-context/repo://src/a.ts?chunks&cursor=abc')).toEqual({
+      path: 'src/a.ts',
+      listChunks: true,
+      cursor: 'abc'
+    });
+    expect(parseFileResourceUri('repo://src/a.ts?chunk=2')).toEqual({ path: 'src/a.ts', chunk: 2 });
+  });
+
+  it('rejects other schemes and parent-directory segments', () => {
+    expect(parseFileResourceUri(CONTEXT_RESOURCE_URI)).toBeNull();
+    expect(parseFileResourceUri('repo://../secrets.txt')).toBeNull();
+    expect(parseFileResourceUri('repo://src/%2E%2E/%2E%2E/etc/passwd')).toBeNull();
+  });
+});
@@ -0,0 +1,118 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { feedbackBoost, feedbackKey } from '../src/core/result-feedback.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  ACCESS_LOG_FILENAME,
+  CODEBASE_CONTEXT_DIRNAME,
+  FEEDBACK_FILENAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+describe('result feedback', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  const call = async (tool: string, args: Record<string, unknown>) =>
+    JSON.parse((await dispatchTool(tool, args, ctx)).content![0].text);
+  const search = (extra: Record<string, unknown> = {}) =>
+    call('search_codebase', { query: 'invoice total', mode: 'keyword', ...extra });
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'result-feedback-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'invoice.ts'),
+      'export function invoiceTotal(lines: number[]): number {\n' +
+        '  // invoice total across all lines\n' +
+        '  return lines.reduce((sum, line) => sum + line, 0);\n' +
+        '}\n'
+    );
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('keys chunks by symbol and turns votes into a bounded boost', () => {
+    expect(feedbackKey('src\\a.ts', 3, 9, ['Cart', 'total'])).toBe('src/a.ts#Cart.total');
+    expect(feedbackKey('src/a.ts', 3, 9)).toBe('src/a.ts:3-9');
+    expect(feedbackBoost({ up: 1, down: 0 }, 0.1)).toBeCloseTo(1.05);
+    expect(feedbackBoost({ up: 0, down: 99 }, 0.1)).toBeCloseTo(0.901);
+    expect(feedbackBoost({ up: 4, down: 0 }, 0)).toBe(1);
+  });
+
+  it('logs searches and records ratings that feed back into ranking', async () => {
+    const first = await search();
+    expect(first.queryId).toMatch(/^[0-9a-f]{12}$/);
+    expect(first.results[0].file).toContain('invoice.ts');
+    const log = await fs.readFile(path.join(ctx.paths.baseDir, ACCESS_LOG_FILENAME), 'utf-8');
+    expect(JSON.parse(log.trim().split('\n').pop()!)).toMatchObject({
+      queryId: first.queryId,
+      query: 'invoice total',
+      results: expect.arrayContaining([expect.objectContaining({ file: 'src/invoice.ts' })])
+    });
+
+    const rated = await call('rate_result', { queryId: first.queryId, result: 1, rating: 'down' });
+    expect(rated).toMatchObject({ status: 'success', totals: { up: 0, down: 1 } });
+    // Rating the same result of the same search again replaces the rating
+    const rerated = await call('rate_result', {
+      queryId: first.queryId,
+      result: first.results[0].file,
+      rating: 'up',
+      note: 'exactly the function'
+    });
+    expect(rerated).toMatchObject({ totals: { up: 1, down: 0 }, rankingBoost: 1.05 });
+    const stored = JSON.parse(
+      await fs.readFile(path.join(ctx.paths.baseDir, FEEDBACK_FILENAME), 'utf-8')
+    );
+    expect(stored.ratings).toEqual([
+      expect.objectContaining({
+        query: 'invoice total',
+        rating: 'up',
+        note: 'exactly the function'
+      })
+    ]);
+
+    const second = await search({ debug: true });
+    expect(second.results[0].debug.adjustments).toContain('result feedback x1.05');
+  });
+
+  it('reports unknown searches and results', async () => {
+    const { queryId } = await search();
+
+    const stale = await call('rate_result', { queryId: 'feedbeef0000', result: 1, rating: 'up' });
+    expect(stale).toMatchObject({ status: 'not_found' });
+    const missing = await call('rate_result', { queryId, result: 'src/other.ts', rating: 'up' });
+    expect(missing).toMatchObject({
+      status: 'not_found',
+      results: expect.arrayContaining([expect.stringContaining('invoice.ts')])
+    });
+
+    const invalid = await dispatchTool('rate_result', { queryId, result: 1, rating: 'meh' }, ctx);
+    expect(invalid.isError).toBe(true);
+  });
+});
@@ -0,0 +1,339 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { loadDependencyGraph } from '../src/core/dependency-graph.js';
+import { loadSymbolIndex } from '../src/core/symbol-index.js';
+import {
+  createSchemaChunks,
+  findGraphqlBlocks,
+  findOpenApiBlocks,
+  findProtoBlocks
+} from '../src/utils/schema-chunker.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const PROTO = `syntax = "proto3";
+
+package user.v1;
+
+// A registered user.
+message User {
+  string id = 1;
+  string name = 2; // "not { a brace"
+  message Address { string city = 1; }
+}
+
+enum Role {
+  ROLE_UNSPECIFIED = 0;
+}
+
+// Manages users.
+service UserService {
+  // Fetch one user by id.
+  rpc GetUser(GetUserRequest) returns (User);
+  rpc ListUsers(ListUsersRequest) returns (stream User) {
+    option (google.api.http) = { get: "/v1/users" };
+  }
+}
+
+message GetUserRequest { string id = 1; }
+`;
+
+const OPENAPI_YAML = `openapi: 3.0.3
+info:
+  title: Users
+paths:
+  /users/{id}:
+    parameters:
+      - name: id
+        in: path
+    get:
+      operationId: getUserById
+      responses:
+        '200':
+          description: OK
+    delete:
+      responses:
+        '204':
+          description: Deleted
+components:
+  schemas:
+    User:
+      type: object
+`;
+
+const OPENAPI_JSON = JSON.stringify(
+  {
+    openapi: '3.1.0',
+    paths: { '/pets': { post: { operationId: 'createPet' } } },
+    components: { schemas: { Pet: { type: 'object' } } }
+  },
+  null,
+  2
+);
+
+const SDL = `# Orders API
+
+"""
+An order placed by a customer.
+"""
+type Order implements Node @key(fields: "id") {
+  id: ID!
+  total: Float # "not { a brace"
+}
+
+union SearchResult = Order
+  | Customer
+
+type Query {
+  "Orders of the signed-in customer, newest first."
+  orders(first: Int = 10, status: OrderStatus @deprecated(reason: "use filter")): [Order!]!
+  # Look one up
+  order(id: ID!): Order
+}
+
+extend type Mutation {
+  placeOrder(input: PlaceOrderInput!): Order!
+}
+`;
+
+describe('findProtoBlocks', () => {
+  it('finds messages, enums, services and rpcs with their leading comments', () => {
+    const blocks = findProtoBlocks(PROTO);
+
+    expect(blocks.map((b) => [b.schema.type, b.schema.address, b.startLine, b.endLine])).toEqual([
+      ['message', 'user.v1.User', 5, 10],
+      ['enum', 'user.v1.Role', 12, 14],
+      ['service', 'user.v1.UserService', 16, 23],
+      ['rpc', 'user.v1.UserService.GetUser', 18, 19],
+      ['rpc', 'user.v1.UserService.ListUsers', 20, 22],
+      ['message', 'user.v1.GetUserRequest', 25, 25]
+    ]);
+    expect(blocks[3].schema).toMatchObject({
+      service: 'UserService',
+      requestType: 'GetUserRequest',
+      responseType: 'User',
+      package: 'user.v1'
+    });
+  });
+});
+
+describe('findOpenApiBlocks', () => {
+  it('finds operations and component schemas in YAML', () => {
+    const blocks = findOpenApiBlocks(OPENAPI_YAML);
+
+    expect(blocks.map((b) => [b.schema.type, b.schema.name, b.startLine, b.endLine])).toEqual([
+      ['operation', 'getUserById', 9, 13],
+      ['operation', 'DELETE /users/{id}', 14, 17],
+      ['schema', 'User', 20, 21]
+    ]);
+    expect(blocks[0].schema).toMatchObject({
+      address: 'GET /users/{id}',
+      httpMethod: 'GET',
+      path: '/users/{id}'
+    });
+  });
+
+  it('finds operations in JSON without the closing brackets of enclosing objects', () => {
+    const blocks = findOpenApiBlocks(OPENAPI_JSON);
+    const lines = OPENAPI_JSON.split('\n');
+
+    expect(blocks.map((b) => b.schema.address)).toEqual([
+      'POST /pets',
+      '#/components/schemas/Pet'
+    ]);
+    expect(lines[blocks[0].endLine - 1].trim()).toBe('}');
+    expect(lines[blocks[0].endLine]).toMatch(/^ {4}}/);
+  });
+});
+
+describe('findGraphqlBlocks', () => {
+  it('finds types with their descriptions and the fields of root operation types', () => {
+    const blocks = findGraphqlBlocks(SDL);
+
+    expect(blocks.map((b) => [b.schema.type, b.schema.address, b.startLine, b.endLine])).toEqual([
+      ['type', 'Order', 3, 9],
+      ['union', 'SearchResult', 11, 12],
+      ['type', 'Query', 14, 19],
+      ['field', 'Query.orders', 15, 16],
+      ['field', 'Query.order', 17, 18],
+      ['type', 'Mutation', 21, 23],
+      ['field', 'Mutation.placeOrder', 22, 22]
+    ]);
+    expect(blocks[3].schema).toMatchObject({
+      kind: 'graphql',
+      parentType: 'Query',
+      operationType: 'query',
+      responseType: 'Order'
+    });
+  });
+
+  it('takes root operation types from the schema definition', () => {
+    const blocks = findGraphqlBlocks(
+      'schema {\n  query: RootQuery\n}\n\ntype RootQuery {\n  viewer: User\n}\n'
+    );
+
+    expect(blocks.find((b) => b.schema.type === 'field')?.schema).toMatchObject({
+      address: 'RootQuery.viewer',
+      operationType: 'query'
+    });
+  });
+});
+
+describe('createSchemaChunks', () => {
+  it('emits one chunk per rpc, the service header going with the first one', () => {
+    const chunks =
+      createSchemaChunks(PROTO, {
+        filePath: '/repo/user.proto',
+        relativePath: 'user.proto',
+        language: 'proto'
+      }) ?? [];
+    const getUser = chunks.find((c) => c.metadata.schema?.name === 'GetUser');
+    const listUsers = chunks.find((c) => c.metadata.schema?.name === 'ListUsers');
+
+    expect(getUser?.startLine).toBe(16);
+    expect(getUser?.content.split('\n')[0]).toBe('// user.v1.UserService.GetUser :: rpc');
+    expect(getUser?.content).toContain('service UserService {');
+    expect(getUser?.metadata.symbolKind).toBe('rpc');
+    expect(listUsers?.endLine).toBe(23);
+    expect(chunks.some((c) => c.metadata.schema?.type === 'service')).toBe(false);
+  });
+
+  it('emits one chunk per root field of a GraphQL schema', () => {
+    const chunks =
+      createSchemaChunks(SDL, {
+        filePath: '/repo/schema.graphql',
+        relativePath: 'schema.graphql',
+        language: 'graphql'
+      }) ?? [];
+    const orders = chunks.find((c) => c.metadata.schema?.name === 'orders');
+
+    expect(orders?.startLine).toBe(14);
+    expect(orders?.content.split('\n')[0]).toBe('# Query.orders :: field');
+    expect(orders?.content).toContain('type Query {');
+    expect(orders?.framework).toBe('graphql');
+    expect(chunks.find((c) => c.metadata.schema?.name === 'order')?.endLine).toBe(19);
+  });
+
+  it('returns null for YAML that is not an OpenAPI document', () => {
+    expect(
+      createSchemaChunks('name: app\nversion: 1\n', {
+        filePath: '/repo/config.yaml',
+        relativePath: 'config.yaml',
+        language: 'yaml'
+      })
+    ).toBeNull();
+  });
+});
+
+describe('indexer with API schemas', () => {
+  let tempDir: string;
+
+  const write = async (file: string, content: string) => {
+    await fs.mkdir(path.dirname(path.join(tempDir, file)), { recursive: true });
+    await fs.writeFile(path.join(tempDir, file), content);
+  };
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'schema-index-test-'));
+    await write('proto/user/v1/user.proto', PROTO);
+    await write('api/openapi.yaml', OPENAPI_YAML);
+    await write(
+      'gen/user/v1/user.pb.go',
+      'package userv1\n\ntype User struct {\n\tId string\n}\n'
+    );
+    await write(
+      'server/users.go',
+      `package server
+
+type Server struct {
+	userv1.UnimplementedUserServiceServer
+}
+
+func (s *Server) GetUser(ctx context.Context, req *userv1.GetUserRequest) (*userv1.User, error) {
+	return &userv1.User{Id: req.Id}, nil
+}
+`
+    );
+    await write(
+      'cache/users.go',
+      'package cache\n\nfunc GetUser(id string) string {\n\treturn id\n}\n'
+    );
+    await write(
+      'src/handlers/users.ts',
+      'export async function getUserById(id: string) {\n  return { id };\n}\n'
+    );
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('links generated code and handlers to their schema and indexes schema symbols', async () => {
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+
+    const graph = await loadDependencyGraph(tempDir);
+    expect(graph?.imports['gen/user/v1/user.pb.go']).toContain('proto/user/v1/user.proto');
+    expect(graph?.imports['server/users.go']).toContain('proto/user/v1/user.proto');
+    expect(graph?.imports['src/handlers/users.ts']).toContain('api/openapi.yaml');
+    expect(graph?.imports['cache/users.go'] ?? []).not.toContain('proto/user/v1/user.proto');
+
+    const definitions = (await loadSymbolIndex(tempDir)) ?? [];
+    expect(definitions).toContainEqual(
+      expect.objectContaining({
+        name: 'GetUser',
+        kind: 'rpc',
+        file: 'proto/user/v1/user.proto',
+        qualifiedName: 'user.v1.UserService.GetUser'
+      })
+    );
+    expect(definitions).toContainEqual(
+      expect.objectContaining({ name: 'getUserById', kind: 'endpoint', file: 'api/openapi.yaml' })
+    );
+  });
+
+  it('links GraphQL resolvers to the fields they resolve', async () => {
+    await write('graphql/orders.graphql', SDL);
+    await write(
+      'src/graphql/resolvers.ts',
+      `export const resolvers = {
+  Query: {
+    orders: (_parent: unknown, args: { first: number }) => listOrders(args.first)
+  }
+};
+`
+    );
+    await write(
+      'src/orders/orders.resolver.ts',
+      `@Resolver(() => Order)
+export class OrdersResolver {
+  @Mutation(() => Order)
+  async placeOrder(@Args('input') input: PlaceOrderInput) {
+    return this.orders.place(input);
+  }
+}
+`
+    );
+    await write(
+      'src/cart/orders.ts',
+      'export function orders(cartId: string) {\n  return [cartId];\n}\n'
+    );
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+
+    const graph = await loadDependencyGraph(tempDir);
+    expect(graph?.imports['src/graphql/resolvers.ts']).toContain('graphql/orders.graphql');
+    expect(graph?.imports['src/orders/orders.resolver.ts']).toContain('graphql/orders.graphql');
+    expect(graph?.imports['src/cart/orders.ts'] ?? []).not.toContain('graphql/orders.graphql');
+
+    const definitions = (await loadSymbolIndex(tempDir)) ?? [];
+    expect(definitions).toContainEqual(
+      expect.objectContaining({
+        name: 'orders',
+        kind: 'field',
+        file: 'graphql/orders.graphql',
+        qualifiedName: 'Query.orders'
+      })
+    );
+  });
+});
@@ -0,0 +1,147 @@
+import { describe, it, expect, afterEach, vi } from 'vitest';
+import type { CodeChunk, SearchResult } from '../src/types/index.js';
+import { CodebaseSearcher } from '../src/core/search.js';
+import { rerank, type RerankerConfig } from '../src/core/reranker.js';
+
+function createChunk(id: string, filePath: string, symbolName?: string): CodeChunk {
+  return {
+    id,
+    content: `export class ${symbolName ?? 'Thing'} {}`,
+    filePath,
+    relativePath: filePath,
+    startLine: 3,
+    endLine: 18,
+    language: 'typescript',
+    framework: 'generic',
+    componentType: 'service',
+    layer: 'core',
+    dependencies: [],
+    imports: [],
+    exports: [],
+    tags: [],
+    metadata: symbolName ? { symbolName, chunkStrategy: 'ast-aligned' } : {}
+  };
+}
+
+function setupSearcher(
+  semantic: { chunk: CodeChunk; score: number }[],
+  keyword: { chunk: CodeChunk; score: number }[]
+): CodebaseSearcher {
+  const searcher = new CodebaseSearcher('C:/repo') as any;
+  searcher.initialized = true;
+  searcher.embeddingProvider = {};
+  searcher.storageProvider = {};
+  searcher.fuseIndex = {};
+  searcher.patternIntelligence = null;
+  searcher.semanticSearch = vi.fn(async () => semantic);
+  searcher.keywordSearch = vi.fn(async () => keyword);
+  return searcher as CodebaseSearcher;
+}
+
+const options = {
+  enableQueryExpansion: false,
+  enableLowConfidenceRescue: false,
+  enableReranker: false
+};
+
+describe('search debug mode', () => {
+  afterEach(() => {
+    vi.unstubAllGlobals();
+  });
+
+  it('leaves results and trace alone without debug', async () => {
+    const auth = createChunk('auth', 'src/auth/auth-service.ts', 'AuthService');
+    const searcher = setupSearcher([{ chunk: auth, score: 0.8 }], []);
+
+    const results = await searcher.search('AuthService', 3, undefined, options);
+
+    expect(results[0].scoreBreakdown).toBeUndefined();
+    expect(searcher.getLastTrace()).toBeNull();
+  });
+
+  it('breaks each score down into channel hits, fusion and adjustments', async () => {
+    const auth = createChunk('auth', 'src/auth/auth-service.ts', 'AuthService');
+    const login = createChunk('login', 'src/auth/login-form.ts');
+    const searcher = setupSearcher(
+      [
+        { chunk: auth, score: 0.81 },
+        { chunk: login, score: 0.77 }
+      ],
+      [
+        { chunk: login, score: 0.05 },
+        { chunk: auth, score: 0.04 }
+      ]
+    );
+
+    const results = await searcher.search('AuthService', 3, undefined, {
+      ...options,
+      debug: true
+    });
+
+    const top = results.find((r) => r.filePath.endsWith('auth-service.ts'));
+    expect(top?.scoreBreakdown).toMatchObject({
+      semantic: { rank: 0, score: 0.81 },
+      keyword: { rank: 1, score: 0.04 }
+    });
+    // 0.4 / (60 + 1) + 0.6 / (60 + 2), normalized by the best possible 1 / 61
+    expect(top?.scoreBreakdown?.fused).toBeCloseTo(0.99, 2);
+    const reasons = top?.scoreBreakdown?.adjustments.map((a) => a.reason);
+    expect(reasons).toContain('component type');
+    expect(reasons).toContain('exact definition');
+    expect(top?.scoreBreakdown?.preRerankScore).toBeCloseTo(top?.score ?? 0, 3);
+
+    expect(searcher.getLastTrace()).toMatchObject({
+      intent: 'EXACT_NAME',
+      semanticWeight: 0.4,
+      keywordWeight: 0.6,
+      queryVariants: ['AuthService'],
+      rescued: false,
+      rerank: 'off',
+      reranked: false
+    });
+  });
+
+  it('records the cross-encoder score next to the pre-rerank score', async () => {
+    const breakdown = { fused: 0.5, adjustments: [], preRerankScore: 0.5 };
+    const results = ['/a.ts', '/b.ts'].map(
+      (filePath, i) =>
+        ({
+          summary: filePath,
+          snippet: '',
+          filePath,
+          startLine: 1,
+          endLine: 5,
+          score: 0.5 - i * 0.01,
+          language: 'typescript',
+          metadata: {},
+          scoreBreakdown: { ...breakdown, preRerankScore: 0.5 - i * 0.01 }
+        }) as SearchResult
+    );
+    vi.stubGlobal(
+      'fetch',
+      vi.fn(
+        async () =>
+          new Response(
+            JSON.stringify({
+              results: [
+                { index: 0, relevance_score: 0.1 },
+                { index: 1, relevance_score: 0.9 }
+              ]
+            })
+          )
+      )
+    );
+    const config: RerankerConfig = {
+      provider: 'cohere',
+      model: 'rerank-test',
+      apiKey: 'key',
+      endpoint: 'https://rerank.example/v2/rerank'
+    };
+
+    const reranked = await rerank('q', results, 'always', config);
+
+    expect(reranked[0].filePath).toBe('/b.ts');
+    expect(reranked[0].scoreBreakdown).toMatchObject({ preRerankScore: 0.49, rerankScore: 0.9 });
+    expect(results[1].scoreBreakdown?.rerankScore).toBeUndefined();
+  });
+});
@@ -0,0 +1,237 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { loadDependencyGraph } from '../src/core/dependency-graph.js';
+import { loadSymbolIndex } from '../src/core/symbol-index.js';
+import { createSfcChunks, extractSfcCalls, parseSfc } from '../src/utils/sfc-chunker.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME
+} from '../src/constants/codebase-context.js';
+import type { CodeChunk } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const VUE = `<template>
+  <div class="card">
+    <template v-if="title">
+      <h2>{{ title }}</h2>
+    </template>
+    <button @click="increment">{{ count }}</button>
+  </div>
+</template>
+
+<script setup lang="ts">
+import { ref } from 'vue';
+import { formatTitle } from './format';
+
+interface Props {
+  /** Card heading */
+  title: string;
+  initial?: number;
+  onSelect: (id: string) => void;
+}
+
+const props = withDefaults(defineProps<Props>(), { initial: 0 });
+const count = ref(props.initial);
+
+function increment() {
+  count.value = count.value + 1;
+  formatTitle(props.title);
+}
+</script>
+
+<style scoped lang="scss">
+.card { padding: 1rem; }
+</style>
+`;
+
+const SVELTE = `<script lang="ts">
+  export let name: string;
+  export let greeting = 'Hello';
+</script>
+
+<h1>{greeting} {name}</h1>
+
+<style>h1 { color: teal; }</style>
+`;
+
+const ASTRO = `---
+import Layout from '../layouts/Layout.astro';
+interface Props {
+  title: string;
+  draft?: boolean;
+}
+const { title } = Astro.props;
+---
+<Layout title={title}>
+  <h1>{title}</h1>
+</Layout>
+`;
+
+describe('parseSfc', () => {
+  it('splits a Vue component into template, script and style blocks', () => {
+    const file = parseSfc(VUE, 'vue', 'src/components/user-card.vue');
+
+    expect(file.componentName).toBe('UserCard');
+    expect(file.blocks.map((b) => [b.kind, b.lang, b.startLine, b.endLine])).toEqual([
+      ['template', undefined, 2, 7],
+      ['script', 'typescript', 11, 27],
+      ['style', 'scss', 31, 31]
+    ]);
+    expect(file.props).toEqual([
+      { name: 'title', line: 16 },
+      { name: 'initial', line: 17 },
+      { name: 'onSelect', line: 18 }
+    ]);
+  });
+
+  it('reads runtime and Options API props, and the declared component name', () => {
+    const options = parseSfc(
+      `<script>
+export default defineComponent({
+  name: 'fancy-button',
+  props: { label: String, size: { type: Number, default: 1 } }
+});
+</script>`,
+      'vue',
+      'Button.vue'
+    );
+    const runtime = parseSfc(
+      `<script setup>\ndefineProps(['label', 'disabled']);\n</script>`,
+      'vue',
+      'Button.vue'
+    );
+
+    expect(options.componentName).toBe('FancyButton');
+    expect(options.props.map((p) => p.name)).toEqual(['label', 'size']);
+    expect(runtime.props.map((p) => p.name)).toEqual(['label', 'disabled']);
+  });
+
+  it('treats Svelte markup outside script and style as the template', () => {
+    const file = parseSfc(SVELTE, 'svelte', 'src/Greeting.svelte');
+    const runes = parseSfc(
+      `<script>\n  let { name, greeting = 'Hi', ...rest } = $props();\n</script>\n<p>{name}</p>`,
+      'svelte',
+      'Greeting.svelte'
+    );
+
+    expect(file.blocks.map((b) => [b.kind, b.startLine, b.endLine])).toEqual([
+      ['script', 2, 3],
+      ['template', 6, 6],
+      ['style', 8, 8]
+    ]);
+    expect(file.blocks[2].content).toBe('h1 { color: teal; }');
+    expect(file.props.map((p) => p.name)).toEqual(['name', 'greeting']);
+    expect(runes.props.map((p) => p.name)).toEqual(['name', 'greeting']);
+  });
+
+  it('parses Astro frontmatter as a TypeScript script block', () => {
+    const file = parseSfc(ASTRO, 'astro', 'src/pages/blog-post.astro');
+
+    expect(file.componentName).toBe('BlogPost');
+    expect(file.blocks.map((b) => [b.kind, b.lang, b.startLine, b.endLine])).toEqual([
+      ['script', 'typescript', 2, 7],
+      ['template', undefined, 9, 11]
+    ]);
+    expect(file.props).toEqual([
+      { name: 'title', line: 4 },
+      { name: 'draft', line: 5 }
+    ]);
+  });
+});
+
+describe('createSfcChunks', () => {
+  it('chunks each block with file line numbers and the block it came from', async () => {
+    const { chunks } = await createSfcChunks(VUE, {
+      filePath: '/repo/src/components/user-card.vue',
+      relativePath: 'src/components/user-card.vue',
+      language: 'vue'
+    });
+
+    const blocks = chunks.map((c) => c.metadata.sfcBlock);
+    expect(blocks[0]).toBe('template');
+    expect(blocks[blocks.length - 1]).toBe('style');
+    for (const chunk of chunks) {
+      expect(chunk.relativePath).toBe('src/components/user-card.vue');
+      expect(chunk.metadata.componentName).toBe('UserCard');
+      expect(chunk.language).toBe('vue');
+    }
+
+    const script = chunks.find((c) => c.content.includes('function increment()'));
+    expect(script?.metadata.sfcBlock).toBe('script');
+    expect(script?.metadata.blockLang).toBe('typescript');
+    expect(script?.metadata.props).toEqual(['title', 'initial', 'onSelect']);
+    expect(script?.startLine).toBeGreaterThanOrEqual(11);
+    expect(script?.endLine).toBeLessThanOrEqual(27);
+    expect(script?.content).toContain(VUE.split('\n')[script!.startLine - 1]);
+    expect(script?.content).toContain(VUE.split('\n')[script!.endLine - 1]);
+
+    const style = chunks.find((c) => c.metadata.sfcBlock === 'style');
+    expect(style?.metadata.blockLang).toBe('scss');
+    expect(style?.startLine).toBe(31);
+  });
+});
+
+describe('extractSfcCalls', () => {
+  it('records the component, its props and script definitions at file lines', async () => {
+    const extraction = await extractSfcCalls(VUE, 'vue', 'src/components/user-card.vue');
+    const byName = new Map(extraction.symbols.map((s) => [s.name, s]));
+
+    expect(byName.get('UserCard')?.kind).toBe('component');
+    expect(byName.get('title')).toMatchObject({
+      kind: 'property',
+      qualifiedName: 'UserCard.title',
+      startLine: 16
+    });
+    expect(byName.get('increment')).toMatchObject({ kind: 'function', startLine: 24 });
+    expect(extraction.calls).toContainEqual(
+      expect.objectContaining({ callee: 'formatTitle', line: 26 })
+    );
+  });
+});
+
+describe('indexer with single-file components', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'sfc-index-test-'));
+    await fs.mkdir(path.join(tempDir, 'src', 'components'), { recursive: true });
+    await fs.writeFile(path.join(tempDir, 'src', 'components', 'user-card.vue'), VUE);
+    await fs.writeFile(
+      path.join(tempDir, 'src', 'components', 'format.ts'),
+      'export function formatTitle(title: string): string {\n  return title.trim();\n}\n'
+    );
+    await fs.writeFile(path.join(tempDir, 'src', 'Greeting.svelte'), SVELTE);
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('indexes block chunks, script imports and component symbols', async () => {
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+
+    const raw = await fs.readFile(
+      path.join(tempDir, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+      'utf-8'
+    );
+    const chunks = (JSON.parse(raw) as { chunks: CodeChunk[] }).chunks;
+    const svelte = chunks.filter((c) =>
+      c.relativePath.replace(/\\/g, '/').endsWith('src/Greeting.svelte')
+    );
+    expect(svelte.map((c) => c.metadata.sfcBlock)).toEqual(['script', 'template', 'style']);
+
+    const graph = await loadDependencyGraph(tempDir);
+    expect(graph?.imports['src/components/user-card.vue']).toContain('src/components/format.ts');
+
+    const definitions = (await loadSymbolIndex(tempDir)) ?? [];
+    expect(definitions).toContainEqual(
+      expect.objectContaining({ name: 'Greeting', kind: 'component', language: 'svelte' })
+    );
+    expect(definitions).toContainEqual(
+      expect.objectContaining({ qualifiedName: 'UserCard.onSelect', kind: 'property' })
+    );
+  });
+});
@@ -0,0 +1,152 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const embedded = vi.hoisted(() => ({ texts: [] as string[] }));
+
+// Bag-of-words vectors: copies of the same code land close together, unrelated code doesn't
+vi.mock('../src/embeddings/index.js', async (importOriginal) => {
+  const original = await importOriginal<typeof import('../src/embeddings/index.js')>();
+  const dimensions = 64;
+  const vectorize = (text: string) => {
+    const vector = new Array<number>(dimensions).fill(0);
+    for (const word of text.toLowerCase().match(/[a-z]+/g) ?? []) {
+      let bucket = 0;
+      for (const char of word) bucket = (bucket * 31 + char.charCodeAt(0)) % dimensions;
+      vector[bucket] += 1;
+    }
+    const norm = Math.hypot(...vector) || 1;
+    return vector.map((value) => value / norm);
+  };
+  return {
+    ...original,
+    getEmbeddingProvider: async () => ({
+      name: 'transformers',
+      modelName: original.DEFAULT_MODEL,
+      dimensions,
+      initialize: async () => {},
+      isReady: () => true,
+      embed: async (text: string) => vectorize(text),
+      embedBatch: async (texts: string[]) => {
+        embedded.texts.push(...texts);
+        return texts.map(vectorize);
+      }
+    })
+  };
+});
+
+const total = (name: string) =>
+  [
+    `export function ${name}(items: LineItem[]): number {`,
+    '  let total = 0;',
+    '  for (const item of items) {',
+    '    if (item.taxable) {',
+    '      total += item.price * item.quantity * 1.2;',
+    '    } else {',
+    '      total += item.price * item.quantity;',
+    '    }',
+    '  }',
+    '  return Math.round(total * 100) / 100;',
+    '}',
+    ''
+  ].join('\n');
+
+describe('find_similar_code', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  beforeEach(async () => {
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'similar-code-test-'));
+    const files: Record<string, string> = {
+      'src/billing/invoice.ts': total('invoiceTotal'),
+      'src/orders/order.ts': total('orderTotal'),
+      'src/auth/session.ts': [
+        'export function openSession(user: User): string {',
+        '  const token = crypto.randomUUID();',
+        '  sessions.set(token, { user, createdAt: Date.now() });',
+        "  audit.log('session opened', user.id);",
+        '  return token;',
+        '}',
+        ''
+      ].join('\n')
+    };
+    for (const [file, content] of Object.entries(files)) {
+      await fs.mkdir(path.dirname(path.join(tempRoot, file)), { recursive: true });
+      await fs.writeFile(path.join(tempRoot, file), content);
+    }
+    await new CodebaseIndexer({ rootPath: tempRoot }).index();
+    embedded.texts = [];
+
+    const contextDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir: contextDir,
+        memory: path.join(contextDir, MEMORY_FILENAME),
+        intelligence: path.join(contextDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(contextDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(contextDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    vi.restoreAllMocks();
+    await rmWithRetries(tempRoot);
+  });
+
+  it('finds copies of a file region from the stored vectors', async () => {
+    const response = await dispatchTool(
+      'find_similar_code',
+      { path: 'src/billing/invoice.ts:1-11' },
+      ctx
+    );
+    const payload = JSON.parse(response.content![0].text);
+
+    expect(payload).toMatchObject({ status: 'success', totalMatches: 1 });
+    expect(payload.matches[0].location).toMatch(/^src\/orders\/order\.ts:1-/);
+    expect(payload.matches[0].score).toBeGreaterThan(0.9);
+    // Region vectors come from the embedding cache, not the provider
+    expect(embedded.texts).toEqual([]);
+  });
+
+  it('embeds a pasted snippet and respects scope', async () => {
+    const payload = JSON.parse(
+      (await dispatchTool('find_similar_code', { snippet: total('sum'), scope: 'src/orders' }, ctx))
+        .content![0].text
+    );
+    expect(payload.matches).toHaveLength(1);
+    expect(payload.matches[0].location).toMatch(/^src\/orders\/order\.ts:/);
+    expect(embedded.texts).toHaveLength(1);
+  });
+
+  it('reports nothing above the threshold, and rejects ambiguous input', async () => {
+    const unrelated = JSON.parse(
+      (await dispatchTool('find_similar_code', { path: 'src/auth/session.ts' }, ctx)).content![0]
+        .text
+    );
+    expect(unrelated.status).toBe('not_found');
+
+    const both = await dispatchTool(
+      'find_similar_code',
+      { path: 'src/auth/session.ts', snippet: 'x' },
+      ctx
+    );
+    expect(both.isError).toBe(true);
+  });
+});
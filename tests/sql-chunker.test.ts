@@ -0,0 +1,266 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { loadSymbolIndex } from '../src/core/symbol-index.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  analyzeSqlStatement,
+  createSqlChunks,
+  findEmbeddedSql,
+  findSqlStatements
+} from '../src/utils/sql-chunker.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const SCHEMA = `-- Orders placed by customers
+CREATE TABLE billing.orders (
+  id serial PRIMARY KEY,
+  customer_id int REFERENCES customers(id) ON DELETE CASCADE,
+  note text DEFAULT 'a; b'
+);
+
+CREATE OR REPLACE FUNCTION order_total(o int) RETURNS numeric AS $$
+BEGIN
+  RETURN (SELECT sum(amount) FROM line_items WHERE order_id = o);
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER count_orders AFTER INSERT ON billing.orders
+BEGIN
+  UPDATE stats SET n = n + 1;
+END;
+
+INSERT INTO audit_log (msg) VALUES ('one');
+INSERT INTO audit_log (msg) VALUES ('two');
+
+CREATE VIEW open_orders AS SELECT * FROM billing.orders o, payments p WHERE o.id = p.order_id;
+`;
+
+const REPOSITORY = `import { db } from './db';
+
+export class OrderRepository {
+  async listOpen(customerId: string) {
+    return db.query(\`
+      SELECT o.id, o.total
+      FROM billing.orders o
+      JOIN customers c ON c.id = o.customer_id
+      WHERE c.id = \${customerId}\`);
+  }
+
+  markPaid(id: string) {
+    return db.run('UPDATE orders SET paid = true WHERE id = ?', [id]);
+  }
+}
+
+export const hint = 'Select a file from the list';
+`;
+
+describe('findSqlStatements', () => {
+  it('splits on semicolons outside strings, dollar quotes and trigger bodies', () => {
+    const statements = findSqlStatements(SCHEMA);
+
+    expect(statements.map((s) => [s.info.statement, s.startLine, s.endLine])).toEqual([
+      ['create table', 1, 6],
+      ['create function', 8, 12],
+      ['create trigger', 14, 17],
+      ['insert', 19, 19],
+      ['insert', 20, 20],
+      ['create view', 22, 22]
+    ]);
+    expect(statements[0].info).toMatchObject({
+      object: 'billing.orders',
+      tables: ['billing.orders', 'customers'],
+      line: 2
+    });
+    expect(statements[1].info.tables).toEqual(['line_items']);
+    expect(statements[2].info.tables).toEqual(['billing.orders', 'stats']);
+  });
+
+  it('honours DELIMITER, GO and the Oracle slash separator', () => {
+    const script = [
+      'DELIMITER $$',
+      'CREATE PROCEDURE close_day()',
+      'BEGIN',
+      '  DELETE FROM carts;',
+      '  IF 1 THEN UPDATE stats SET n = 0; END IF;',
+      'END$$',
+      'DELIMITER ;',
+      'CREATE PROCEDURE dbo.report AS',
+      'BEGIN',
+      '  SELECT * FROM [dbo].[Orders];',
+      'END',
+      'GO',
+      'CREATE PROCEDURE tally IS',
+      '  v NUMBER;',
+      'BEGIN',
+      '  SELECT count(*) INTO v FROM emp;',
+      'END;',
+      '/'
+    ].join('\n');
+
+    expect(findSqlStatements(script).map((s) => [s.info.object, s.info.tables])).toEqual([
+      ['close_day', ['carts', 'stats']],
+      ['dbo.report', ['dbo.orders']],
+      ['tally', ['emp']]
+    ]);
+  });
+});
+
+describe('analyzeSqlStatement', () => {
+  it('finds joined tables but not CTE names, calls or FROM inside EXTRACT', () => {
+    const analyzed = analyzeSqlStatement(
+      'WITH recent AS (SELECT * FROM orders WHERE placed > now()) ' +
+        'SELECT EXTRACT(YEAR FROM r.placed) FROM recent r ' +
+        'JOIN customers c ON c.id = r.customer_id, generate_series(1, 3) g'
+    );
+    expect(analyzed).toEqual({ statement: 'select', tables: ['orders', 'customers'] });
+    const deletion = analyzeSqlStatement(
+      'DELETE FROM orders USING archived a WHERE a.id = orders.id'
+    );
+    expect(deletion).toEqual({ statement: 'delete', tables: ['orders', 'archived'] });
+  });
+});
+
+describe('createSqlChunks', () => {
+  it('emits one chunk per statement and collapses repeated inserts', () => {
+    const chunks =
+      createSqlChunks(SCHEMA, {
+        filePath: '/repo/db/schema.sql',
+        relativePath: 'db/schema.sql',
+        language: 'sql'
+      }) ?? [];
+
+    expect(chunks.map((c) => c.content.split('\n')[0])).toEqual([
+      '-- create table billing.orders :: billing.orders, customers',
+      '-- create function order_total :: line_items',
+      '-- create trigger count_orders :: billing.orders, stats',
+      '-- insert :: audit_log',
+      '-- create view open_orders :: billing.orders, payments'
+    ]);
+    expect(chunks[3].metadata.sql).toEqual([
+      { statement: 'insert', tables: ['audit_log'], line: 19, repeated: 2 }
+    ]);
+    expect(chunks[0].metadata.sqlTables).toEqual(['billing.orders', 'orders', 'customers']);
+  });
+});
+
+describe('findEmbeddedSql', () => {
+  it('finds queries in template and quoted literals, skipping prose', () => {
+    expect(findEmbeddedSql(REPOSITORY, 'typescript')).toEqual([
+      { statement: 'select', tables: ['billing.orders', 'customers'], line: 6 },
+      { statement: 'update', tables: ['orders'], line: 13 }
+    ]);
+  });
+
+  it('reads Python triple-quoted strings and Ruby heredocs', () => {
+    const python = [
+      'def top_customers(conn):',
+      '    return conn.execute("""',
+      '        select c.name, count(*) as n',
+      '        from customers c join orders o on o.customer_id = c.id',
+      '        group by c.name',
+      '    """)'
+    ].join('\n');
+    expect(findEmbeddedSql(python, 'python')).toEqual([
+      { statement: 'select', tables: ['customers', 'orders'], line: 3 }
+    ]);
+
+    const ruby = ['sql = <<~SQL', '  DELETE FROM sessions WHERE stale', 'SQL'].join('\n');
+    expect(findEmbeddedSql(ruby, 'ruby')).toEqual([
+      { statement: 'delete', tables: ['sessions'], line: 2 }
+    ]);
+  });
+});
+
+describe('indexer with SQL', () => {
+  let tempDir: string;
+  let ctx: ToolContext;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'sql-index-test-'));
+    await fs.mkdir(path.join(tempDir, 'db'), { recursive: true });
+    await fs.mkdir(path.join(tempDir, 'src'), { recursive: true });
+    await fs.writeFile(path.join(tempDir, 'db', 'schema.sql'), SCHEMA);
+    await fs.writeFile(path.join(tempDir, 'src', 'order-repository.ts'), REPOSITORY);
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+
+    const contextDir = path.join(tempDir, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir: contextDir,
+        memory: path.join(contextDir, MEMORY_FILENAME),
+        intelligence: path.join(contextDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(contextDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(contextDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempDir,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('lists statements and embedded queries touching a table', async () => {
+    const response = await dispatchTool('find_sql_queries', { table: 'orders' }, ctx);
+    const payload = JSON.parse(response.content![0].text);
+
+    expect(payload.status).toBe('success');
+    expect(payload.queries).toEqual(
+      expect.arrayContaining([
+        expect.objectContaining({
+          location: 'db/schema.sql:2',
+          statement: 'create table',
+          source: 'sql-file'
+        }),
+        expect.objectContaining({
+          location: 'src/order-repository.ts:6',
+          statement: 'select',
+          function: 'OrderRepository.listOpen',
+          source: 'embedded'
+        }),
+        expect.objectContaining({
+          location: 'src/order-repository.ts:13',
+          statement: 'update',
+          function: 'OrderRepository.markPaid'
+        })
+      ])
+    );
+
+    const updates = JSON.parse(
+      (await dispatchTool('find_sql_queries', { table: 'orders', statement: 'update' }, ctx))
+        .content![0].text
+    );
+    expect(updates.totalQueries).toBe(1);
+
+    const missing = JSON.parse(
+      (await dispatchTool('find_sql_queries', { table: 'invoices' }, ctx)).content![0].text
+    );
+    expect(missing.status).toBe('not_found');
+  });
+
+  it('indexes tables, views and routines created by scripts as symbols', async () => {
+    const definitions = (await loadSymbolIndex(tempDir)) ?? [];
+    const sql = definitions.filter((d) => d.language === 'sql');
+
+    expect(sql.map((d) => [d.kind, d.qualifiedName ?? d.name])).toEqual(
+      expect.arrayContaining([
+        ['table', 'billing.orders'],
+        ['function', 'order_total'],
+        ['trigger', 'count_orders'],
+        ['view', 'open_orders']
+      ])
+    );
+  });
+});
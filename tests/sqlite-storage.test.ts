@@ -0,0 +1,210 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import path from 'path';
+import os from 'os';
+import { SQLiteStorageProvider, SQLITE_DB_FILENAME } from '../src/storage/sqlite.js';
+import { QuantizedMatrix, quantizeVector } from '../src/storage/quantization.js';
+import type { CodeChunkWithEmbedding } from '../src/storage/types.js';
+import { IndexCorruptedError } from '../src/errors/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const sqliteSpecifier = 'node:sqlite';
+const hasNodeSqlite = await import(sqliteSpecifier).then(
+  () => true,
+  () => false
+);
+
+function makeChunk(
+  id: string,
+  filePath: string,
+  embedding: number[],
+  language = 'typescript'
+): CodeChunkWithEmbedding {
+  return {
+    id,
+    content: `// ${id}`,
+    filePath,
+    relativePath: path.basename(filePath),
+    startLine: 1,
+    endLine: 3,
+    language,
+    dependencies: [],
+    imports: ['./dep'],
+    exports: [id],
+    tags: ['tag'],
+    metadata: { componentName: id },
+    embedding
+  };
+}
+
+describe.skipIf(!hasNodeSqlite)('SQLiteStorageProvider', () => {
+  let tempDir: string;
+  let provider: SQLiteStorageProvider;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'sqlite-storage-test-'));
+    provider = new SQLiteStorageProvider();
+    await provider.initialize(tempDir);
+  });
+
+  afterEach(async () => {
+    await provider.close();
+    await rmWithRetries(tempDir);
+  });
+
+  it('persists chunks in a single file that survives reopening', async () => {
+    await provider.store([makeChunk('a', '/repo/a.ts', [1, 0, 0])]);
+    await provider.close();
+
+    await fs.access(path.join(tempDir, SQLITE_DB_FILENAME));
+    const reopened = new SQLiteStorageProvider();
+    await reopened.initialize(tempDir);
+    expect(await reopened.count()).toBe(1);
+
+    const [hit] = await reopened.search([1, 0, 0], 1);
+    expect(hit.chunk).toMatchObject({ id: 'a', imports: ['./dep'], metadata: { componentName: 'a' } });
+    await reopened.close();
+    provider = reopened;
+  });
+
+  it('ranks by cosine similarity and applies language filters', async () => {
+    await provider.store([
+      makeChunk('near', '/repo/near.ts', [1, 0.1, 0]),
+      makeChunk('far', '/repo/far.ts', [0, 1, 0]),
+      makeChunk('py', '/repo/near.py', [1, 0, 0], 'python')
+    ]);
+
+    const results = await provider.search([1, 0, 0], 2);
+    expect(results.map((r) => r.chunk.id)).toEqual(['py', 'near']);
+    expect(results[0].score).toBeCloseTo(1);
+
+    const tsOnly = await provider.search([1, 0, 0], 5, { language: 'typescript' });
+    expect(tsOnly.map((r) => r.chunk.id)).toEqual(['near', 'far']);
+  });
+
+  it('restricts search to filePaths and skips excludePaths', async () => {
+    await provider.store([
+      makeChunk('a', '/repo/a.ts', [1, 0]),
+      makeChunk('b', '/repo/b.ts', [0.9, 0.1]),
+      makeChunk('c', '/repo/c.ts', [0, 1])
+    ]);
+
+    const only = await provider.search([1, 0], 5, { filePaths: ['b.ts', '/repo/c.ts'] });
+    expect(only.map((r) => r.chunk.id)).toEqual(['b', 'c']);
+    const rest = await provider.search([1, 0], 5, { excludePaths: ['a.ts'] });
+    expect(rest.map((r) => r.chunk.id)).toEqual(['b', 'c']);
+  });
+
+  it('reads content for the top hits only and flags undecodable rows as corruption', async () => {
+    await provider.store([
+      makeChunk('hit', '/repo/hit.ts', [1, 0]),
+      makeChunk('miss', '/repo/miss.ts', [0, 1])
+    ]);
+    const { DatabaseSync } = (await import(sqliteSpecifier)) as {
+      DatabaseSync: new (location: string) => {
+        exec(sql: string): void;
+        close(): void;
+      };
+    };
+    const db = new DatabaseSync(path.join(tempDir, SQLITE_DB_FILENAME));
+    // A broken payload outside the top hits is never parsed
+    db.exec("UPDATE code_chunks SET payload = '{' WHERE id = 'miss'");
+    expect((await provider.search([1, 0], 1)).map((r) => r.chunk.id)).toEqual(['hit']);
+
+    db.exec("UPDATE code_chunks SET vector = x'010203' WHERE id = 'miss'");
+    db.close();
+    await expect(provider.search([1, 0], 1)).rejects.toBeInstanceOf(IndexCorruptedError);
+  });
+
+  it('deletes by file path and clears', async () => {
+    await provider.store([
+      makeChunk('a', '/repo/a.ts', [1, 0]),
+      makeChunk('a2', '/repo/a.ts', [0, 1]),
+      makeChunk('b', '/repo/b.ts', [1, 1])
+    ]);
+
+    expect(await provider.deleteByFilePaths(['/repo/a.ts'])).toBe(2);
+    expect(await provider.count()).toBe(1);
+
+    await provider.clear();
+    expect(await provider.count()).toBe(0);
+  });
+
+  it('moves the chunks of a renamed file without touching their vectors', async () => {
+    await provider.store([
+      makeChunk('a', '/repo/a.ts', [1, 0]),
+      makeChunk('a2', '/repo/a.ts', [0, 1]),
+      makeChunk('b', '/repo/b.ts', [1, 1])
+    ]);
+
+    const renamed = await provider.renameFilePaths([
+      { from: '/repo/a.ts', to: '/repo/lib/a.ts', relativePath: 'lib/a.ts' }
+    ]);
+    expect(renamed).toBe(2);
+    expect((await provider.listFilePaths()).sort()).toEqual(['/repo/b.ts', '/repo/lib/a.ts']);
+    const [hit] = await provider.search([1, 0], 1, { filePaths: ['lib/a.ts'] });
+    expect(hit.chunk).toMatchObject({ id: 'a', filePath: '/repo/lib/a.ts' });
+    expect(hit.score).toBeCloseTo(1);
+  });
+});
+
+describe('vector quantization', () => {
+  it('packs int8 codes and sign bits and ranks by approximate similarity', () => {
+    expect([...new Int8Array(quantizeVector('int8', [0.5, -1, 0]).buffer)]).toEqual([64, -127, 0]);
+    expect([...quantizeVector('binary', [1, -1, 0, 2, 0, 0, 0, 0, -3, 4])]).toEqual([0b1001, 0b10]);
+
+    for (const mode of ['int8', 'binary'] as const) {
+      const matrix = new QuantizedMatrix(mode, 3, 3);
+      matrix.add(quantizeVector(mode, [0, 1, 0]));
+      matrix.add(quantizeVector(mode, [1, 0.2, -0.5]));
+      matrix.add(quantizeVector(mode, [-1, -1, 1]));
+
+      const ranked = matrix.topK([1, 0.1, -0.4], 2);
+      expect(ranked.map((r) => r.row)).toEqual([1, 0]);
+      expect(matrix.topK([1, 0.1, -0.4], 3, (row) => row !== 1)[0].row).toBe(0);
+    }
+  });
+});
+
+describe.skipIf(!hasNodeSqlite)('SQLiteStorageProvider with quantization', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'sqlite-quantized-test-'));
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('keeps the mode chosen at creation and rescores with exact vectors', async () => {
+    const provider = new SQLiteStorageProvider({ quantization: 'binary' });
+    await provider.initialize(tempDir);
+    // Same sign pattern, so binary codes tie; rescoring must put the closer vector first
+    await provider.store([
+      makeChunk('close', '/repo/close.ts', [1, 0.1, 0.1]),
+      makeChunk('closer', '/repo/closer.ts', [1, 0.01, 0.01]),
+      makeChunk('opposite', '/repo/opposite.ts', [-1, -1, -1]),
+      makeChunk('py', '/repo/same.py', [1, 0, 0.01], 'python')
+    ]);
+
+    const results = await provider.search([1, 0.001, 0.001], 2, { language: 'typescript' });
+    expect(results.map((r) => r.chunk.id)).toEqual(['closer', 'close']);
+    expect(results[0].score).toBeCloseTo(1);
+    expect(await provider.search([1, 0, 0], 5, { language: 'rust' })).toEqual([]);
+    await provider.close();
+
+    // The stored mode wins over the one passed when reopening
+    const reopened = new SQLiteStorageProvider({ quantization: 'int8' });
+    await reopened.initialize(tempDir);
+    expect(reopened.quantizationMode).toBe('binary');
+
+    await reopened.deleteByFilePaths(['/repo/closer.ts']);
+    const afterDelete = await reopened.search([1, 0, 0], 1);
+    expect(afterDelete.map((r) => r.chunk.id)).toEqual(['py']);
+    expect(await reopened.search([1, 0, 0], 5, { filePaths: ['missing.ts'] })).toEqual([]);
+    const scoped = await reopened.search([1, 0, 0], 5, { excludePaths: ['same.py'] });
+    expect(scoped.map((r) => r.chunk.id)).toEqual(['close', 'opposite']);
+    await reopened.close();
+  });
+});
@@ -0,0 +1,215 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { parseStackTrace, resolveFramePath } from '../src/core/stack-trace.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const GO_PANIC = `panic: runtime error: invalid memory address or nil pointer dereference
+
+goroutine 1 [running]:
+github.com/acme/shop/internal/orders.(*Service).Place(0x0, {0x5c1f20, 0xc000012345})
+\t/home/runner/work/shop/shop/internal/orders/service.go:42 +0x1c
+main.main()
+\t/home/runner/work/shop/shop/cmd/server/main.go:17 +0x85
+runtime.main()
+\t/usr/local/go/src/runtime/proc.go:267 +0x2bb`;
+
+const JAVA_TRACE = `Exception in thread "main" java.lang.IllegalStateException: empty cart
+\tat com.acme.shop.orders.OrderService.place(OrderService.java:42)
+\tat java.base/java.lang.Thread.run(Thread.java:833)
+\tat com.acme.shop.Main.main(Unknown Source)`;
+
+const PYTHON_TRACE = `Traceback (most recent call last):
+  File "/app/shop/api/views.py", line 12, in checkout
+    order = place_order(cart)
+  File "/app/shop/orders/service.py", line 42, in place_order
+    raise ValueError("empty cart")
+ValueError: empty cart`;
+
+const SERVICE = `export class OrderService {
+  place(cart: Cart): Order {
+    if (cart.items.length === 0) {
+      throw new Error('empty cart');
+    }
+    return { id: cart.id, items: cart.items };
+  }
+}
+`;
+
+function index(files: string[]) {
+  const indexed = new Set(files);
+  const byBasename = new Map<string, string[]>();
+  for (const file of files) {
+    const base = path.posix.basename(file);
+    byBasename.set(base, [...(byBasename.get(base) ?? []), file]);
+  }
+  return { indexed, byBasename };
+}
+
+describe('parseStackTrace', () => {
+  it('reads Go, Java and Python frames innermost first', () => {
+    expect(parseStackTrace(GO_PANIC).map((f) => [f.function, f.line])).toEqual([
+      ['orders.(*Service).Place', 42],
+      ['main.main', 17],
+      ['runtime.main', 267]
+    ]);
+    expect(parseStackTrace(JAVA_TRACE)).toEqual([
+      {
+        format: 'java',
+        function: 'com.acme.shop.orders.OrderService.place',
+        file: 'OrderService.java',
+        line: 42
+      },
+      { format: 'java', function: 'java.lang.Thread.run', file: 'Thread.java', line: 833 }
+    ]);
+    expect(parseStackTrace(PYTHON_TRACE).map((f) => [f.function, f.file])).toEqual([
+      ['place_order', '/app/shop/orders/service.py'],
+      ['checkout', '/app/shop/api/views.py']
+    ]);
+  });
+
+  it('reads V8 and Firefox JavaScript frames', () => {
+    const frames = parseStackTrace(
+      [
+        "TypeError: Cannot read properties of undefined (reading 'total')",
+        '    at OrderService.place (/srv/ci/shop/src/orders/service.ts:4:13)',
+        '    at async Promise.all (index 0)',
+        '    at Layer.handle [as handle_request] (/app/node_modules/express/lib/layer.js:95:5)',
+        '    at /app/src/index.ts:1:1',
+        'placeOrder@http://localhost:3000/static/js/orders.js:10:5'
+      ].join('\n')
+    );
+    expect(frames.map((f) => [f.function, f.file, f.line, f.column])).toEqual([
+      ['OrderService.place', '/srv/ci/shop/src/orders/service.ts', 4, 13],
+      ['Layer.handle', '/app/node_modules/express/lib/layer.js', 95, 5],
+      [undefined, '/app/src/index.ts', 1, 1],
+      ['placeOrder', 'http://localhost:3000/static/js/orders.js', 10, 5]
+    ]);
+  });
+});
+
+describe('resolveFramePath', () => {
+  it('matches build-machine paths by suffix and Java frames by package', () => {
+    const { indexed, byBasename } = index([
+      'internal/orders/service.go',
+      'cmd/server/main.go',
+      'src/main/java/com/acme/shop/orders/OrderService.java',
+      'src/main/java/com/acme/shop/legacy/OrderService.java'
+    ]);
+    const resolve = (trace: string) =>
+      parseStackTrace(trace).map((frame) =>
+        resolveFramePath('/work/shop', frame, indexed, byBasename)
+      );
+
+    expect(resolve(GO_PANIC)).toEqual([
+      { status: 'resolved', file: 'internal/orders/service.go', matchedBy: 'suffix' },
+      { status: 'resolved', file: 'cmd/server/main.go', matchedBy: 'suffix' },
+      { status: 'library' }
+    ]);
+    expect(resolve(JAVA_TRACE)).toEqual([
+      {
+        status: 'resolved',
+        file: 'src/main/java/com/acme/shop/orders/OrderService.java',
+        matchedBy: 'package'
+      },
+      { status: 'library' }
+    ]);
+  });
+
+  it('breaks suffix ties with the frame function, else reports the candidates', () => {
+    const { indexed, byBasename } = index(['api/handlers.py', 'admin/handlers.py']);
+    const [frame] = parseStackTrace('  File "/srv/handlers.py", line 5, in delete_user');
+    const definitions = [
+      {
+        name: 'delete_user',
+        kind: 'function',
+        file: 'admin/handlers.py',
+        startLine: 3,
+        endLine: 9,
+        language: 'python'
+      }
+    ];
+
+    expect(resolveFramePath('/repo', frame, indexed, byBasename, definitions)).toEqual({
+      status: 'resolved',
+      file: 'admin/handlers.py',
+      matchedBy: 'suffix'
+    });
+    expect(resolveFramePath('/repo', frame, indexed, byBasename)).toEqual({
+      status: 'ambiguous',
+      candidates: ['api/handlers.py', 'admin/handlers.py']
+    });
+  });
+});
+
+describe('resolve_stacktrace', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'stack-trace-test-'));
+    await fs.mkdir(path.join(tempRoot, 'src', 'orders'), { recursive: true });
+    await fs.writeFile(path.join(tempRoot, 'src', 'orders', 'service.ts'), SERVICE);
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const contextDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir: contextDir,
+        memory: path.join(contextDir, MEMORY_FILENAME),
+        intelligence: path.join(contextDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(contextDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(contextDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('returns the function around each project frame with the line marked', async () => {
+    const trace = [
+      'Error: empty cart',
+      '    at OrderService.place (/home/ci/build/shop/src/orders/service.ts:4:13)',
+      '    at Layer.handle (/app/node_modules/express/lib/router/layer.js:95:5)',
+      '    at checkout (/home/ci/build/shop/src/api/checkout.ts:9:3)'
+    ].join('\n');
+    const response = await dispatchTool('resolve_stacktrace', { trace }, ctx);
+    const payload = JSON.parse(response.content![0].text);
+
+    expect(payload).toMatchObject({ status: 'success', format: 'javascript', totalFrames: 3 });
+    expect(payload.frames).toHaveLength(1);
+    expect(payload.frames[0]).toMatchObject({
+      index: 0,
+      function: 'OrderService.place',
+      location: 'src/orders/service.ts:4',
+      tracePath: '/home/ci/build/shop/src/orders/service.ts'
+    });
+    expect(payload.frames[0].scope).toContain('place');
+    expect(payload.frames[0].code).toMatch(/^\s*4 > \s*throw new Error/m);
+    expect(payload.otherFrames).toEqual({ library: 1, not_indexed: 1 });
+    expect(payload.unresolved).toEqual([
+      expect.objectContaining({ function: 'checkout', reason: 'not_indexed' })
+    ]);
+  });
+
+  it('rejects an empty trace', async () => {
+    const response = await dispatchTool('resolve_stacktrace', { trace: '  ' }, ctx);
+    expect(response.isError).toBe(true);
+  });
+});
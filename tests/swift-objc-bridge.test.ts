@@ -0,0 +1,121 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { loadDependencyGraph } from '../src/core/dependency-graph.js';
+import { SwiftObjcBridge, parseQuotedImports } from '../src/core/swift-objc-bridge.js';
+import type { SymbolDefinition } from '../src/core/symbol-index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const files = [
+  'App/App-Bridging-Header.h',
+  'Legacy/Invoice.h',
+  'Legacy/Invoice.m',
+  'Legacy/Ledger.m',
+  'App/InvoiceView.swift',
+  'App/Receipt.swift'
+];
+
+function definition(name: string, kind: string, file: string, language: string): SymbolDefinition {
+  return { name, kind, file, language, startLine: 1, endLine: 5 };
+}
+
+describe('SwiftObjcBridge', () => {
+  it('parses quoted imports and skips framework imports', () => {
+    expect(
+      parseQuotedImports('#import <UIKit/UIKit.h>\n#import "Invoice.h"\n  #include "util/log.h"')
+    ).toEqual([
+      { header: 'Invoice.h', line: 2 },
+      { header: 'util/log.h', line: 3 }
+    ]);
+  });
+
+  it('links Swift to bridged headers and Objective-C to Swift through -Swift.h', () => {
+    const bridge = new SwiftObjcBridge(files);
+    bridge.trackFile('App/App-Bridging-Header.h', 'objective-c', '#import "Invoice.h"\n');
+    bridge.trackFile(
+      'Legacy/Invoice.h',
+      'objective-c',
+      '@interface Invoice : NSObject\n@end\ntypedef NS_ENUM(NSInteger, InvoiceState) { Open };\n'
+    );
+    bridge.trackFile(
+      'Legacy/Invoice.m',
+      'objective-c',
+      '#import "Invoice.h"\n@implementation Invoice\n@end'
+    );
+    bridge.trackFile(
+      'Legacy/Ledger.m',
+      'objective-c',
+      '#import "App-Swift.h"\n\nvoid record(void) {\n  [[Receipt alloc] init];\n}\n'
+    );
+    bridge.trackFile(
+      'App/InvoiceView.swift',
+      'swift',
+      'import UIKit\n\nfinal class InvoiceView {\n' +
+        '  let invoice: Invoice\n  var state: InvoiceState\n}\n'
+    );
+    bridge.trackFile('App/Receipt.swift', 'swift', '@objc class Receipt: NSObject {}\n');
+
+    const edges = bridge.edges([
+      definition('InvoiceView', 'class', 'App/InvoiceView.swift', 'swift'),
+      definition('Receipt', 'class', 'App/Receipt.swift', 'swift')
+    ]);
+    const byPair = Object.fromEntries(edges.map((e) => [`${e.from} -> ${e.to}`, e]));
+
+    expect(Object.keys(byPair).sort()).toEqual([
+      'App/App-Bridging-Header.h -> Legacy/Invoice.h',
+      'App/InvoiceView.swift -> Legacy/Invoice.h',
+      'App/InvoiceView.swift -> Legacy/Invoice.m',
+      'Legacy/Invoice.m -> Legacy/Invoice.h',
+      'Legacy/Ledger.m -> App/Receipt.swift'
+    ]);
+    expect(byPair['App/InvoiceView.swift -> Legacy/Invoice.h']).toMatchObject({
+      line: 4,
+      symbols: ['Invoice', 'InvoiceState']
+    });
+    expect(byPair['Legacy/Ledger.m -> App/Receipt.swift'].symbols).toEqual(['Receipt']);
+  });
+
+  it('does not link Swift to Objective-C types no bridging header exposes', () => {
+    const bridge = new SwiftObjcBridge(files);
+    bridge.trackFile('Legacy/Invoice.h', 'objective-c', '@interface Invoice : NSObject\n@end\n');
+    bridge.trackFile('App/InvoiceView.swift', 'swift', 'let invoice: Invoice? = nil\n');
+
+    expect(bridge.edges([])).toEqual([]);
+  });
+});
+
+describe('Swift / Objective-C edges in the dependency graph', () => {
+  let tempRoot: string;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'swift-objc-bridge-test-'));
+    await fs.mkdir(path.join(tempRoot, 'App'), { recursive: true });
+    await fs.mkdir(path.join(tempRoot, 'Legacy'), { recursive: true });
+    await fs.writeFile(
+      path.join(tempRoot, 'App', 'App-Bridging-Header.h'),
+      '#import "Invoice.h"\n'
+    );
+    await fs.writeFile(
+      path.join(tempRoot, 'Legacy', 'Invoice.h'),
+      '#import <Foundation/Foundation.h>\n\n@interface Invoice : NSObject\n- (double)total;\n@end\n'
+    );
+    await fs.writeFile(
+      path.join(tempRoot, 'App', 'InvoiceView.swift'),
+      'import UIKit\n\nfinal class InvoiceView {\n    let invoice = Invoice()\n}\n'
+    );
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('records bridged references as imports', async () => {
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const graph = await loadDependencyGraph(tempRoot);
+    expect(graph?.imports['App/InvoiceView.swift']).toContain('Legacy/Invoice.h');
+    expect(graph?.imports['App/App-Bridging-Header.h']).toEqual(['Legacy/Invoice.h']);
+  });
+});
@@ -0,0 +1,156 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import {
+  loadSymbolIndex,
+  searchSymbols,
+  type SymbolDefinition
+} from '../src/core/symbol-index.js';
+import { extractTreeSitterCalls } from '../src/utils/tree-sitter.js';
+import { rmWithRetries } from './test-helpers.js';
+
+function def(name: string, kind: string, file = 'src/a.ts', language = 'typescript') {
+  return { name, kind, file, startLine: 1, endLine: 5, language } satisfies SymbolDefinition;
+}
+
+describe('Tree-sitter constant extraction', () => {
+  it('collects module-level JS/TS constants but not const functions or locals', async () => {
+    const source = [
+      'export const MAX_RETRIES = 3;',
+      'const handler = () => run();',
+      'let mutable = 1;',
+      'function run() {',
+      '  const local = 2;',
+      '  return local;',
+      '}'
+    ].join('\n');
+
+    const extraction = await extractTreeSitterCalls(source, 'typescript');
+
+    expect(extraction!.constants.map((c) => [c.name, c.kind])).toEqual([
+      ['MAX_RETRIES', 'constant']
+    ]);
+  });
+
+  it('collects Python UPPER_CASE module assignments and Go consts', async () => {
+    const python = await extractTreeSitterCalls(
+      ['TIMEOUT = 30', 'value = 1', 'def f():', '    LOCAL = 2'].join('\n'),
+      'python'
+    );
+    expect(python!.constants.map((c) => c.name)).toEqual(['TIMEOUT']);
+
+    const go = await extractTreeSitterCalls(
+      ['package main', '', 'const (', '  A = 1', '  B = 2', ')'].join('\n'),
+      'go'
+    );
+    expect(go!.constants.map((c) => c.name)).toEqual(['A', 'B']);
+  });
+});
+
+describe('searchSymbols', () => {
+  const definitions = [
+    def('UserService', 'class'),
+    def('UserServiceImpl', 'class', 'src/impl/user.ts'),
+    def('createUserService', 'function'),
+    def('UserRepository', 'interface'),
+    def('USER_LIMIT', 'constant', 'pkg/limits.go', 'go')
+  ];
+
+  it('ranks exact before prefix before substring matches', () => {
+    const result = searchSymbols(definitions, 'UserService', { limit: 10 });
+
+    expect(result.results.map((r) => [r.name, r.match])).toEqual([
+      ['UserService', 'exact'],
+      ['UserServiceImpl', 'prefix'],
+      ['createUserService', 'substring']
+    ]);
+  });
+
+  it('tolerates typos and abbreviations', () => {
+    expect(searchSymbols(definitions, 'UserSevice', { limit: 1 }).results[0].name).toBe(
+      'UserService'
+    );
+    expect(
+      searchSymbols(definitions, 'usrRepo', { limit: 5 }).results.map((r) => r.name)
+    ).toContain('UserRepository');
+  });
+
+  it('filters by kind, language, path glob and exactness', () => {
+    expect(
+      searchSymbols(definitions, 'User', { kinds: ['interface'], limit: 10 }).results.map(
+        (r) => r.name
+      )
+    ).toEqual(['UserRepository']);
+    expect(
+      searchSymbols(definitions, 'user', { language: 'go', limit: 10 }).results.map((r) => r.name)
+    ).toEqual(['USER_LIMIT']);
+    expect(
+      searchSymbols(definitions, 'UserService', { pathGlob: 'src/impl/**', limit: 10 }).results
+    ).toHaveLength(1);
+    expect(searchSymbols(definitions, 'userservice', { exact: true, limit: 10 }).total).toBe(1);
+  });
+
+  it('matches qualified queries against package-qualified names', () => {
+    const qualified = [
+      {
+        ...def('save', 'method', 'src/Users.java', 'java'),
+        qualifiedName: 'com.acme.Users.save'
+      },
+      {
+        ...def('save', 'method', 'src/Orders.java', 'java'),
+        qualifiedName: 'com.acme.Orders.save'
+      },
+      { ...def('get', 'method', 'src/cache.rs', 'rust'), qualifiedName: 'Cache::get' }
+    ];
+
+    const result = searchSymbols(qualified, 'Users.save', { limit: 10 });
+    expect(result.results[0]).toMatchObject({
+      qualifiedName: 'com.acme.Users.save',
+      match: 'exact'
+    });
+    expect(searchSymbols(qualified, 'Cache::get', { exact: true, limit: 10 }).total).toBe(1);
+  });
+});
+
+describe('symbol index persistence', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'symbol-index-test-'));
+    await fs.mkdir(path.join(tempDir, 'src'), { recursive: true });
+    await fs.writeFile(
+      path.join(tempDir, 'src', 'config.ts'),
+      [
+        'export const DEFAULT_PORT = 8080;',
+        'export interface ServerConfig {',
+        '  port: number;',
+        '}',
+        'export function loadConfig(): ServerConfig {',
+        '  return { port: DEFAULT_PORT };',
+        '}'
+      ].join('\n')
+    );
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('writes definitions with kinds and languages during indexing', async () => {
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+
+    const definitions = await loadSymbolIndex(tempDir);
+
+    expect(definitions).not.toBeNull();
+    const byName = new Map(definitions!.map((d) => [d.name, d]));
+    expect(byName.get('DEFAULT_PORT')?.kind).toBe('constant');
+    expect(byName.get('ServerConfig')?.kind).toBe('interface');
+    expect(byName.get('loadConfig')).toMatchObject({
+      kind: 'function',
+      file: 'src/config.ts',
+      language: 'typescript'
+    });
+  });
+});
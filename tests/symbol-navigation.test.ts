@@ -0,0 +1,253 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import type { SymbolDefinition } from '../src/core/symbol-index.js';
+import { getEnclosingScope, matchDefinitions } from '../src/core/symbol-navigation.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+function def(name: string, file: string, startLine: number, qualifiedName?: string) {
+  return {
+    name,
+    kind: 'method',
+    file,
+    startLine,
+    endLine: startLine + 3,
+    language: 'java',
+    ...(qualifiedName ? { qualifiedName } : {})
+  } satisfies SymbolDefinition;
+}
+
+describe('matchDefinitions', () => {
+  const definitions = [
+    def('save', 'src/Users.java', 10, 'com.acme.Users.save'),
+    def('save', 'src/Orders.java', 4, 'com.acme.Orders.save'),
+    def('Save', 'src/Legacy.java', 1),
+    def('saveAll', 'src/Users.java', 20, 'com.acme.Users.saveAll')
+  ];
+
+  it('returns exact name matches ordered by location', () => {
+    expect(matchDefinitions(definitions, 'save').map((d) => d.file)).toEqual([
+      'src/Orders.java',
+      'src/Users.java'
+    ]);
+  });
+
+  it('narrows qualified queries and falls back to case-insensitive names', () => {
+    expect(matchDefinitions(definitions, 'Users.save').map((d) => d.qualifiedName)).toEqual([
+      'com.acme.Users.save'
+    ]);
+    expect(matchDefinitions(definitions, 'SAVEALL').map((d) => d.name)).toEqual(['saveAll']);
+    expect(matchDefinitions(definitions, 'sav')).toEqual([]);
+  });
+});
+
+describe('get_definition / find_references tools', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'symbol-navigation-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'config.ts'),
+      [
+        'export interface ServerConfig {',
+        '  port: number;',
+        '}',
+        '',
+        'export function loadConfig(): ServerConfig {',
+        '  return { port: 8080 };',
+        '}',
+        ''
+      ].join('\n')
+    );
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'server.ts'),
+      [
+        "import { loadConfig } from './config.js';",
+        '',
+        'export function start() {',
+        '  const config = loadConfig();',
+        '  return config.port;',
+        '}',
+        ''
+      ].join('\n')
+    );
+
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('returns the declaration location with its source', async () => {
+    const result = await dispatchTool('get_definition', { symbol: 'loadConfig' }, ctx);
+    const payload = JSON.parse(result.content![0].text);
+
+    expect(payload.status).toBe('success');
+    expect(payload.definitions).toHaveLength(1);
+    expect(payload.definitions[0]).toMatchObject({
+      name: 'loadConfig',
+      kind: 'function',
+      location: 'src/config.ts:5',
+      endLine: 7
+    });
+    expect(payload.definitions[0].code).toBe(
+      [
+        '5 | export function loadConfig(): ServerConfig {',
+        '6 |   return { port: 8080 };',
+        '7 | }'
+      ].join('\n')
+    );
+  });
+
+  it('reports unknown symbols as not found', async () => {
+    const result = await dispatchTool('get_definition', { symbol: 'loadConfg' }, ctx);
+    expect(JSON.parse(result.content![0].text).status).toBe('not_found');
+  });
+
+  it('lists references with surrounding code and flags the declaration', async () => {
+    const result = await dispatchTool(
+      'find_references',
+      { symbol: 'loadConfig', contextLines: 1 },
+      ctx
+    );
+    const payload = JSON.parse(result.content![0].text);
+
+    expect(payload.definitions).toEqual(['src/config.ts:5']);
+    const byLocation = new Map(
+      payload.references.map((r: { location: string }) => [r.location, r])
+    );
+    expect(byLocation.get('src/config.ts:5')).toMatchObject({ definition: true });
+    expect(byLocation.get('src/server.ts:4')).toEqual({
+      location: 'src/server.ts:4',
+      code: [
+        '3 | export function start() {',
+        '4 |   const config = loadConfig();',
+        '5 |   return config.port;'
+      ].join('\n')
+    });
+    expect(byLocation.has('src/server.ts:1')).toBe(true);
+  });
+
+  it('rejects a missing symbol', async () => {
+    const result = await dispatchTool('find_references', {}, ctx);
+    expect(result.isError).toBe(true);
+  });
+
+  it('expands a search hit to its enclosing function', async () => {
+    const hit = `${path.join(tempRoot, 'src', 'config.ts')}:6-6`;
+    const result = await dispatchTool('get_enclosing_scope', { path: hit }, ctx);
+    const payload = JSON.parse(result.content![0].text);
+
+    expect(payload).toMatchObject({
+      status: 'success',
+      file: 'src/config.ts',
+      scope: 'symbol',
+      name: 'loadConfig',
+      kind: 'function',
+      startLine: 5,
+      endLine: 7,
+      symbolPath: ['loadConfig']
+    });
+    expect(payload.code.split('\n')[0]).toBe('5 | export function loadConfig(): ServerConfig {');
+  });
+
+  it('falls back to the whole file outside any declaration', async () => {
+    const result = await dispatchTool(
+      'get_enclosing_scope',
+      { path: 'src/config.ts', line: 4 },
+      ctx
+    );
+    const payload = JSON.parse(result.content![0].text);
+    expect(payload).toMatchObject({ scope: 'file', startLine: 1, language: 'typescript' });
+    expect(payload.name).toBeUndefined();
+
+    const missing = await dispatchTool('get_enclosing_scope', { path: 'src/nope.ts:3' }, ctx);
+    expect(JSON.parse(missing.content![0].text).status).toBe('error');
+  });
+});
+
+function scoped(name: string, kind: string, startLine: number, endLine: number) {
+  return {
+    name,
+    kind,
+    file: 'src/cart.ts',
+    startLine,
+    endLine,
+    language: 'typescript'
+  } satisfies SymbolDefinition;
+}
+
+describe('getEnclosingScope', () => {
+  let tempRoot: string;
+  const definitions = [
+    scoped('Cart', 'class', 1, 9),
+    scoped('total', 'method', 3, 8),
+    scoped('sum', 'function', 4, 6)
+  ];
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'enclosing-scope-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    const lines = Array.from({ length: 10 }, (_, i) => `line ${i + 1}`);
+    await fs.writeFile(path.join(tempRoot, 'src', 'cart.ts'), lines.join('\n'));
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('picks the innermost or outermost declaration spanning the range', async () => {
+    const target = { file: 'src/cart.ts', startLine: 5, endLine: 7 };
+    const inner = await getEnclosingScope(tempRoot, definitions, target, {
+      level: 'symbol',
+      maxLines: 50
+    });
+    expect(inner).toMatchObject({ name: 'total', startLine: 3, endLine: 8 });
+    expect(inner?.symbolPath).toEqual(['Cart', 'total']);
+
+    const outer = await getEnclosingScope(tempRoot, definitions, target, {
+      level: 'outermost',
+      maxLines: 3
+    });
+    expect(outer).toMatchObject({ name: 'Cart', startLine: 1, endLine: 9, truncated: true });
+    expect(outer?.code.split('\n')).toEqual(['1 | line 1', '2 | line 2', '3 | line 3']);
+  });
+
+  it('returns null for unreadable files', async () => {
+    const scope = await getEnclosingScope(
+      tempRoot,
+      definitions,
+      { file: '../outside.ts', startLine: 1 },
+      { level: 'symbol', maxLines: 10 }
+    );
+    expect(scope).toBeNull();
+  });
+});
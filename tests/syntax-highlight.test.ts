@@ -0,0 +1,150 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { classifyTokens, type SyntaxToken } from '../src/utils/syntax-highlight.js';
+import { dispatchTool } from '../src/tools/index.js';
+import { formatToolResponse } from '../src/tools/output-format.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+/** Tokens as `line:column kind text`, easier to read in a failure */
+function describeTokens(content: string, tokens: SyntaxToken[]): string[] {
+  const lines = content.split('\n');
+  return tokens.map(
+    ([line, column, length, kind]) =>
+      `${line}:${column} ${kind} ${lines[line].slice(column, column + length)}`
+  );
+}
+
+const REFUNDS = [
+  "import { Invoice } from './invoice';",
+  '',
+  'export function refundPayment(invoice: Invoice) {',
+  '  /* partial refunds',
+  '     come later */',
+  '  return applyRefund(invoice.total * 0.5);',
+  '}',
+  ''
+].join('\n');
+
+describe('syntax highlighting', () => {
+  it('classifies comments, strings, numbers, keywords, calls and types', () => {
+    expect(describeTokens(REFUNDS, classifyTokens(REFUNDS, 'typescript'))).toEqual([
+      '0:0 keyword import',
+      '0:9 type Invoice',
+      '0:19 keyword from',
+      "0:24 string './invoice'",
+      '2:0 keyword export',
+      '2:7 keyword function',
+      '2:16 function refundPayment',
+      '2:39 type Invoice',
+      '3:2 comment /* partial refunds',
+      '4:0 comment      come later */',
+      '5:2 keyword return',
+      '5:9 function applyRefund',
+      '5:37 number 0.5'
+    ]);
+  });
+
+  it('follows each language family', () => {
+    const python = 'def charge(total):\n    return total  # "not a string"\n';
+    expect(describeTokens(python, classifyTokens(python, 'python'))).toEqual([
+      '0:0 keyword def',
+      '0:4 function charge',
+      '1:4 keyword return',
+      '1:18 comment # "not a string"'
+    ]);
+
+    const sql = "SELECT id FROM refunds WHERE status = 'open' -- oldest first";
+    expect(describeTokens(sql, classifyTokens(sql, 'sql'))).toEqual([
+      '0:0 keyword SELECT',
+      '0:10 keyword FROM',
+      '0:23 keyword WHERE',
+      "0:38 string 'open'",
+      '0:45 comment -- oldest first'
+    ]);
+
+    // A lifetime is not a string
+    const rust = "fn total<'a>(items: &'a [Item]) -> u64 { 0 }";
+    expect(describeTokens(rust, classifyTokens(rust, 'rust'))).toEqual([
+      '0:0 keyword fn',
+      '0:25 type Item',
+      '0:41 number 0'
+    ]);
+    expect(classifyTokens('anything', 'plaintext')).toEqual([]);
+  });
+});
+
+describe('highlighting in results', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'syntax-highlight-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'RefundButton.tsx'),
+      [
+        'export function RefundButton() {',
+        '  // issue the refund',
+        '  return <button onClick={() => refundPayment(1)}>Refund</button>;',
+        '}',
+        ''
+      ].join('\n')
+    );
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  const args = { query: 'RefundButton refund', mode: 'keyword', includeSnippets: true };
+
+  it('tags snippets with their language and tokens on request', async () => {
+    const plain = JSON.parse((await dispatchTool('search_codebase', args, ctx)).content![0].text);
+    expect(plain.results[0]).toMatchObject({ language: 'typescriptreact' });
+    expect(plain.results[0].tokens).toBeUndefined();
+
+    const highlighted = JSON.parse(
+      (await dispatchTool('search_codebase', { ...args, highlight: true }, ctx)).content![0].text
+    );
+    const [result] = highlighted.results;
+    const described = describeTokens(result.snippet, result.tokens);
+    expect(described).toContain('0:0 comment ' + result.snippet.split('\n')[0]);
+    expect(described.some((token) => token.endsWith('function refundPayment'))).toBe(true);
+    expect(described.some((token) => token.endsWith('comment // issue the refund'))).toBe(true);
+  });
+
+  it('fences markdown snippets with the language tag renderers know', async () => {
+    const response = await dispatchTool('retrieve_with_citations', args, ctx);
+    const chunk = JSON.parse(response.content![0].text).chunks[0];
+    expect(chunk.language).toBe('typescriptreact');
+
+    const markdown = formatToolResponse('retrieve_with_citations', response, 'markdown');
+    expect(markdown.content![0].text).toContain('```tsx\nexport function RefundButton() {');
+  });
+});
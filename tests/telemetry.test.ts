@@ -0,0 +1,105 @@
+import { describe, it, expect, afterEach } from 'vitest';
+import { Server } from '@modelcontextprotocol/sdk/server/index.js';
+import {
+  METRICS_CONTENT_TYPE,
+  MetricsRegistry,
+  isTracingEnabled,
+  metrics,
+  metricsRegistry,
+  withSpan
+} from '../src/core/telemetry.js';
+import { startHttpTransport, type HttpTransportHandle } from '../src/http-transport.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+
+describe('metrics registry', () => {
+  it('renders counters and cumulative histograms in the Prometheus text format', () => {
+    const registry = new MetricsRegistry();
+    const calls = registry.counter('demo_calls_total', 'Calls');
+    const latency = registry.histogram('demo_seconds', 'Latency', [0.1, 1]);
+    calls.inc({ tool: 'search', status: 'ok' });
+    calls.inc({ status: 'ok', tool: 'search' }, 2);
+    calls.inc({ tool: 'say "hi"' });
+    latency.observe({ tool: 'search' }, 0.05);
+    latency.observe({ tool: 'search' }, 0.5);
+    latency.observe({ tool: 'search' }, 3);
+
+    expect(registry.render().split('\n')).toEqual([
+      '# HELP demo_calls_total Calls',
+      '# TYPE demo_calls_total counter',
+      'demo_calls_total{status="ok",tool="search"} 3',
+      'demo_calls_total{tool="say \\"hi\\""} 1',
+      '# HELP demo_seconds Latency',
+      '# TYPE demo_seconds histogram',
+      'demo_seconds_bucket{le="0.1",tool="search"} 1',
+      'demo_seconds_bucket{le="1",tool="search"} 2',
+      'demo_seconds_bucket{le="+Inf",tool="search"} 3',
+      'demo_seconds_sum{tool="search"} 3.55',
+      'demo_seconds_count{tool="search"} 3',
+      ''
+    ]);
+  });
+});
+
+describe('instrumentation', () => {
+  it('counts and times tool calls, folding unknown names into one label', async () => {
+    const ctx: ToolContext = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir: '/tmp',
+        memory: '/tmp/memory.jsonl',
+        intelligence: '/tmp/intelligence.json',
+        keywordIndex: '/tmp/index.json',
+        vectorDb: '/tmp/vector-db'
+      },
+      rootPath: '/tmp',
+      performIndexing: () => undefined
+    };
+    const before = metrics.toolCalls.value({ tool: 'unknown', status: 'error' });
+    const timed = metrics.toolDuration.count({ tool: 'unknown' });
+
+    await dispatchTool('no_such_tool_a', {}, ctx);
+    await dispatchTool('no_such_tool_b', {}, ctx);
+
+    expect(metrics.toolCalls.value({ tool: 'unknown', status: 'error' })).toBe(before + 2);
+    expect(metrics.toolDuration.count({ tool: 'unknown' })).toBe(timed + 2);
+    expect(metricsRegistry.render()).toContain('codebase_context_tool_calls_total{');
+  });
+
+  it('runs spans as plain calls when tracing is off', async () => {
+    expect(isTracingEnabled({})).toBe(false);
+    expect(isTracingEnabled({ CODEBASE_CONTEXT_OTEL: 'true' })).toBe(true);
+    await expect(withSpan('demo', {}, async () => 42)).resolves.toBe(42);
+    await expect(
+      withSpan('demo', {}, async () => {
+        throw new Error('boom');
+      })
+    ).rejects.toThrow('boom');
+  });
+});
+
+describe('metrics endpoint', () => {
+  let handle: HttpTransportHandle | undefined;
+
+  afterEach(async () => {
+    await handle?.close();
+    handle = undefined;
+  });
+
+  it('serves /metrics in HTTP mode behind the bearer token', async () => {
+    handle = await startHttpTransport({
+      host: '127.0.0.1',
+      port: 0,
+      authToken: 'ops-token',
+      createServer: () => new Server({ name: 'test', version: '0.0.0' }, { capabilities: {} }),
+      metrics: { render: () => metricsRegistry.render(), contentType: METRICS_CONTENT_TYPE }
+    });
+    const metricsUrl = handle.url.replace(/\/mcp$/, '/metrics');
+
+    expect((await fetch(metricsUrl)).status).toBe(401);
+    const response = await fetch(metricsUrl, { headers: { Authorization: 'Bearer ops-token' } });
+    expect(response.status).toBe(200);
+    expect(response.headers.get('content-type')).toContain('text/plain');
+    expect(await response.text()).toContain('# TYPE codebase_context_tool_calls_total counter');
+  });
+});
@@ -0,0 +1,151 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { buildTestLinks, testSubjectStem } from '../src/core/test-mapping.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+describe('testSubjectStem', () => {
+  it('recognizes common test naming conventions', () => {
+    expect(testSubjectStem('src/user.service.spec.ts')).toBe('user.service');
+    expect(testSubjectStem('pkg/users_test.go')).toBe('users');
+    expect(testSubjectStem('tests/test_users.py')).toBe('users');
+    expect(testSubjectStem('src/test/java/UsersServiceTest.java')).toBe('UsersService');
+    expect(testSubjectStem('src/OpenApiSpec.ts')).toBeNull();
+    expect(testSubjectStem('src/users.ts')).toBeNull();
+  });
+});
+
+describe('buildTestLinks', () => {
+  it('combines naming, import and call signals and skips ambiguous calls', () => {
+    const links = buildTestLinks(
+      ['src/cart.ts', 'src/pricing.ts', 'src/legacy/cart.ts', 'src/cart.test.ts'],
+      { 'src/cart.test.ts': ['src/cart.ts'] },
+      {
+        definitions: {
+          addItem: [{ file: 'src/cart.ts', kind: 'function', startLine: 1, endLine: 3 }],
+          total: [
+            { file: 'src/pricing.ts', kind: 'function', startLine: 1, endLine: 3 },
+            { file: 'src/legacy/cart.ts', kind: 'function', startLine: 1, endLine: 3 }
+          ]
+        },
+        calls: [
+          { caller: null, callee: 'addItem', file: 'src/cart.test.ts', line: 4 },
+          { caller: null, callee: 'total', file: 'src/cart.test.ts', line: 5 }
+        ]
+      }
+    );
+
+    expect(links).toEqual({
+      'src/cart.ts': [
+        { file: 'src/cart.test.ts', via: ['naming', 'import', 'calls'], symbols: ['addItem'] }
+      ]
+    });
+  });
+});
+
+describe('get_tests_for', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  const write = async (file: string, lines: string[]) => {
+    await fs.mkdir(path.dirname(path.join(tempRoot, file)), { recursive: true });
+    await fs.writeFile(path.join(tempRoot, file), lines.join('\n'));
+  };
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'test-mapping-'));
+    await write('src/cart.ts', [
+      'export function addItem(items: string[], item: string) {',
+      '  return [...items, item];',
+      '}',
+      '',
+      'export function clearCart() {',
+      '  return [];',
+      '}',
+      ''
+    ]);
+    await write('src/cart.test.ts', [
+      "import { addItem } from './cart';",
+      '',
+      'function testAddItem() {',
+      "  return addItem([], 'apple');",
+      '}',
+      '',
+      'testAddItem();',
+      ''
+    ]);
+    await write('src/checkout.ts', [
+      "import { clearCart } from './cart';",
+      '',
+      'export function checkout() {',
+      '  return clearCart();',
+      '}',
+      ''
+    ]);
+
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('returns the tests linked to a source file', async () => {
+    const result = await dispatchTool('get_tests_for', { target: './src/cart.ts' }, ctx);
+    const payload = JSON.parse(result.content![0].text);
+
+    expect(payload).toMatchObject({ status: 'success', kind: 'file', target: 'src/cart.ts' });
+    expect(payload.tests).toHaveLength(1);
+    expect(payload.tests[0].file).toBe('src/cart.test.ts');
+    expect(payload.tests[0].via).toEqual(expect.arrayContaining(['naming', 'import', 'calls']));
+
+    const untested = await dispatchTool('get_tests_for', { target: 'src/checkout.ts' }, ctx);
+    expect(JSON.parse(untested.content![0].text).status).toBe('not_found');
+  });
+
+  it('lists the test functions calling a symbol before file-level matches', async () => {
+    const direct = await dispatchTool('get_tests_for', { target: 'addItem' }, ctx);
+    const payload = JSON.parse(direct.content![0].text);
+
+    expect(payload).toMatchObject({
+      status: 'success',
+      kind: 'symbol',
+      definedIn: ['src/cart.ts']
+    });
+    expect(payload.tests[0]).toMatchObject({
+      file: 'src/cart.test.ts',
+      testFunctions: [{ name: 'testAddItem', line: 3 }]
+    });
+
+    // clearCart has no direct test call, so only the file-level link remains
+    const related = await dispatchTool('get_tests_for', { target: 'clearCart' }, ctx);
+    const relatedTests = JSON.parse(related.content![0].text).tests;
+    expect(relatedTests).toHaveLength(1);
+    expect(relatedTests[0].testFunctions).toBeUndefined();
+  });
+});
@@ -0,0 +1,111 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { decodeText } from '../src/utils/text-encoding.js';
+import { looksBinary } from '../src/utils/ignore-rules.js';
+import { normalizeRootPath } from '../src/utils/path-normalization.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  KEYWORD_INDEX_FILENAME
+} from '../src/constants/codebase-context.js';
+import type { CodeChunk } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const SOURCE = 'export const greeting = "héllo – wörld";\n';
+
+const utf16le = (text: string, bom = true) =>
+  Buffer.concat([Buffer.from(bom ? [0xff, 0xfe] : []), Buffer.from(text, 'utf16le')]);
+
+describe('text encoding', () => {
+  it('decodes UTF-16 with or without a BOM, UTF-8 BOMs and Windows-1252', () => {
+    expect(decodeText(utf16le(SOURCE))).toEqual({ text: SOURCE, encoding: 'utf-16le' });
+    expect(decodeText(utf16le(SOURCE, false))).toEqual({ text: SOURCE, encoding: 'utf-16le' });
+
+    const be = Buffer.from(SOURCE, 'utf16le').swap16();
+    expect(decodeText(Buffer.concat([Buffer.from([0xfe, 0xff]), be]))).toEqual({
+      text: SOURCE,
+      encoding: 'utf-16be'
+    });
+    expect(decodeText(be)).toEqual({ text: SOURCE, encoding: 'utf-16be' });
+
+    const bom = Buffer.concat([Buffer.from([0xef, 0xbb, 0xbf]), Buffer.from(SOURCE)]);
+    expect(decodeText(bom)).toEqual({ text: SOURCE, encoding: 'utf-8-bom' });
+    expect(decodeText(Buffer.from(SOURCE))).toEqual({ text: SOURCE, encoding: 'utf-8' });
+
+    // "héllo – wörld" saved by a Windows editor: é, en dash and ö as single bytes
+    const cp1252 = Buffer.from([
+      ...Buffer.from('// h'),
+      0xe9,
+      ...Buffer.from('llo '),
+      0x96,
+      ...Buffer.from(' w'),
+      0xf6,
+      ...Buffer.from('rld'),
+      0x80
+    ]);
+    expect(decodeText(cp1252)).toEqual({ text: '// héllo – wörld€', encoding: 'windows-1252' });
+  });
+
+  it('treats UTF-16 text as text, and other NUL bytes as binary', () => {
+    expect(looksBinary(utf16le(SOURCE, false))).toBe(false);
+    const png = Buffer.from([0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0, 0, 0, 13]);
+    expect(looksBinary(png)).toBe(true);
+  });
+
+  it('normalizes Windows roots: quotes, slashes, drive case, UNC and long-path prefixes', () => {
+    expect(normalizeRootPath('"c:/Work/app/"', 'win32')).toBe('C:\\Work\\app');
+    expect(normalizeRootPath('\\\\?\\D:\\very\\long\\path', 'win32')).toBe('D:\\very\\long\\path');
+    expect(normalizeRootPath('\\\\?\\UNC\\fs01\\repos\\app\\', 'win32')).toBe(
+      '\\\\fs01\\repos\\app'
+    );
+    expect(normalizeRootPath('\\\\fs01\\repos\\app', 'win32')).toBe('\\\\fs01\\repos\\app');
+    expect(normalizeRootPath('C:\\', 'win32')).toBe('C:\\');
+    expect(normalizeRootPath("'/home/me/app/'", 'linux')).toBe('/home/me/app');
+  });
+});
+
+describe('indexing files in other encodings', () => {
+  let tempRoot: string;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'text-encoding-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('indexes UTF-16 and Windows-1252 sources as UTF-8 text', async () => {
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'wide.ts'),
+      utf16le('export function wideGreeting(): string {\r\n  return "grüße";\r\n}\r\n')
+    );
+    await fs.writeFile(
+      path.join(tempRoot, 'src', 'legacy.ts'),
+      Buffer.concat([
+        Buffer.from('export function legacyGreeting(): string {\n  return "caf'),
+        Buffer.from([0xe9]),
+        Buffer.from('";\n}\n')
+      ])
+    );
+
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const index = JSON.parse(
+      await fs.readFile(
+        path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+        'utf-8'
+      )
+    ) as { chunks: CodeChunk[] };
+    const chunkOf = (file: string) => index.chunks.find((c) => c.relativePath === file);
+
+    expect(chunkOf('src/wide.ts')?.content).toContain('return "grüße";');
+    expect(chunkOf('src/wide.ts')?.content).not.toContain('\0');
+    expect(chunkOf('src/wide.ts')?.metadata.encoding).toBe('utf-16le');
+    expect(chunkOf('src/legacy.ts')?.content).toContain('return "café";');
+    expect(chunkOf('src/legacy.ts')?.metadata.encoding).toBe('windows-1252');
+  });
+});
@@ -0,0 +1,143 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import {
+  embeddingTokenizerName,
+  loadChunkTokenBudget,
+  loadTokenizer
+} from '../src/embeddings/tokenizers.js';
+import { splitOversizedChunks } from '../src/utils/ast-chunker.js';
+import type { CodeChunk } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const fake = vi.hoisted(() => ({ texts: [] as string[] }));
+
+// Cohere's v3 models take 512 tokens and have no tokenizer here, so limits use the estimate
+vi.mock('../src/embeddings/index.js', async (importOriginal) => {
+  const original = await importOriginal<typeof import('../src/embeddings/index.js')>();
+  const provider = {
+    name: 'cohere',
+    modelName: 'embed-english-v3.0',
+    dimensions: 2,
+    initialize: async () => {},
+    isReady: () => true,
+    embed: async (text: string) => [text.length, 1],
+    embedBatch: async (texts: string[]) => {
+      fake.texts.push(...texts);
+      return texts.map((text) => [text.length, 1]);
+    }
+  };
+  return { ...original, getEmbeddingProvider: async () => provider };
+});
+
+describe('tokenizers', () => {
+  let savedOverride: string | undefined;
+
+  beforeEach(() => {
+    savedOverride = process.env.CODEBASE_CONTEXT_TOKENIZER;
+    delete process.env.CODEBASE_CONTEXT_TOKENIZER;
+  });
+
+  afterEach(() => {
+    if (savedOverride === undefined) delete process.env.CODEBASE_CONTEXT_TOKENIZER;
+    else process.env.CODEBASE_CONTEXT_TOKENIZER = savedOverride;
+  });
+
+  it('maps each provider to the tokenizer it counts with', () => {
+    expect(embeddingTokenizerName('transformers', 'Xenova/bge-small-en-v1.5')).toBe(
+      'Xenova/bge-small-en-v1.5'
+    );
+    expect(embeddingTokenizerName('openai', 'text-embedding-3-large')).toBe('cl100k_base');
+    expect(embeddingTokenizerName('azure-openai', 'my-deployment')).toBe('cl100k_base');
+    expect(embeddingTokenizerName('voyage', 'voyage-code-3')).toBe('estimate');
+
+    process.env.CODEBASE_CONTEXT_TOKENIZER = 'nomic-ai/nomic-embed-text-v1.5';
+    expect(embeddingTokenizerName('ollama', 'nomic-embed-text')).toBe(
+      'nomic-ai/nomic-embed-text-v1.5'
+    );
+  });
+
+  it('falls back to the estimate, and says so', async () => {
+    const estimate = await loadTokenizer('estimate');
+    expect(estimate).toMatchObject({ name: 'estimate', exact: false });
+    expect(estimate.count('x'.repeat(40))).toBe(10);
+    expect((await loadTokenizer('no-such-encoding')).exact).toBe(false);
+  });
+
+  it('knows the input limit of hosted and local models', async () => {
+    expect((await loadChunkTokenBudget('openai', 'text-embedding-3-small'))?.maxInputTokens).toBe(
+      8191
+    );
+    const cohere = await loadChunkTokenBudget('cohere', 'embed-english-v3.0');
+    expect(cohere?.maxInputTokens).toBe(512);
+    // No tokenizer: the limit is checked at 3 characters per token, not 4
+    expect(cohere?.counter.count('x'.repeat(30))).toBe(10);
+    expect(await loadChunkTokenBudget('ollama', 'nomic-embed-text')).toBeNull();
+  });
+
+  it('splits chunks with the counter it is given', () => {
+    const content = Array.from({ length: 8 }, (_, i) => `line ${i}`).join('\n');
+    const chunk: CodeChunk = {
+      id: 'c1',
+      content,
+      filePath: '/repo/a.ts',
+      relativePath: 'a.ts',
+      startLine: 1,
+      endLine: 8,
+      language: 'typescript',
+      dependencies: [],
+      imports: [],
+      exports: [],
+      tags: [],
+      metadata: {}
+    };
+    const countLines = (text: string) => text.split('\n').length;
+    const pieces = splitOversizedChunks([chunk], 100, { maxTokens: 4, countTokens: countLines });
+    expect(pieces.length).toBe(2);
+    expect(pieces.every((piece) => countLines(piece.content) <= 4)).toBe(true);
+  });
+});
+
+describe('chunk sizing against the embedding model', () => {
+  let tempRoot: string;
+
+  beforeEach(async () => {
+    fake.texts = [];
+    vi.spyOn(console, 'error').mockImplementation(() => {});
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'tokenizers-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    const body = Array.from(
+      { length: 100 },
+      (_, i) => `  const line${i} = lineItemAmount(order.items[${i}], order.currency, rates);`
+    );
+    const source = ['export function orderTotal(order, rates) {', ...body, '  return 0;', '}'];
+    await fs.writeFile(path.join(tempRoot, 'src', 'totals.ts'), source.join('\n') + '\n');
+  });
+
+  afterEach(async () => {
+    vi.restoreAllMocks();
+    await rmWithRetries(tempRoot);
+  });
+
+  it('splits chunks whose embedding input is over the input limit', async () => {
+    const stats = await new CodebaseIndexer({ rootPath: tempRoot }).index();
+
+    expect(stats.chunksSplitForModel).toBeGreaterThan(0);
+    expect(fake.texts.length).toBeGreaterThan(1);
+    for (const text of fake.texts) {
+      expect(Math.ceil(text.length / 3)).toBeLessThanOrEqual(512);
+    }
+  });
+
+  it('leaves chunks alone when nothing is embedded', async () => {
+    const stats = await new CodebaseIndexer({
+      rootPath: tempRoot,
+      config: { skipEmbedding: true }
+    }).index();
+
+    expect(stats.chunksSplitForModel).toBeUndefined();
+    expect(fake.texts).toEqual([]);
+  });
+});
@@ -3,8 +3,8 @@ import { TOOLS, dispatchTool } from '../../src/tools/index.js';
 import type { ToolContext } from '../../src/tools/types.js';
 
 describe('Tool Dispatch', () => {
-  it('exports all 10 tools', () => {
-    expect(TOOLS.length).toBe(10);
+  it('exports all 43 tools', () => {
+    expect(TOOLS.length).toBe(43);
     expect(TOOLS.map((t) => t.name)).toEqual([
       'search_codebase',
       'get_codebase_metadata',
@@ -15,7 +15,40 @@ describe('Tool Dispatch', () => {
       'get_symbol_references',
       'detect_circular_dependencies',
       'remember',
-      'get_memory'
+      'get_memory',
+      'find_callers',
+      'find_callees',
+      'get_diff_context',
+      'list_projects',
+      'search_symbols',
+      'get_dependencies',
+      'get_dependents',
+      'pack_context',
+      'get_definition',
+      'find_references',
+      'search_history',
+      'summarize_file',
+      'list_packages',
+      'get_tests_for',
+      'analyze_unused',
+      'index_remote',
+      'index_dependency',
+      'get_enclosing_scope',
+      'get_index_stats',
+      'find_implementations',
+      'get_type_hierarchy',
+      'rollback_index',
+      'get_symbol_docs',
+      'find_sql_queries',
+      'find_similar_code',
+      'resolve_stacktrace',
+      'changes_since',
+      'rate_result',
+      'multi_search',
+      'describe_project',
+      'get_build_target',
+      'get_build_dependents',
+      'retrieve_with_citations'
     ]);
   });
 
@@ -30,9 +30,17 @@ const LANGUAGE_FIXTURE_FILE: Record<string, string> = {
   go: 'go.go',
   rust: 'rust.rs',
   java: 'java.java',
+  kotlin: 'kotlin.kt',
   c: 'c.c',
   cpp: 'cpp.cpp',
-  csharp: 'csharp.cs'
+  csharp: 'csharp.cs',
+  ruby: 'ruby.rb',
+  php: 'php.php',
+  swift: 'swift.swift',
+  'objective-c': 'objc.m',
+  scala: 'scala.scala',
+  elixir: 'elixir.ex',
+  zig: 'zig.zig'
 };
 
 const fixturesDir = path.join(__dirname, 'fixtures', 'grammars');
@@ -1,6 +1,11 @@
 import { describe, expect, it } from 'vitest';
 import { GenericAnalyzer } from '../src/analyzers/generic/index';
-import { extractTreeSitterSymbols, supportsTreeSitter } from '../src/utils/tree-sitter';
+import { detectLanguage } from '../src/utils/language-detection';
+import {
+  extractTreeSitterCalls,
+  extractTreeSitterSymbols,
+  supportsTreeSitter
+} from '../src/utils/tree-sitter';
 
 describe('Tree-sitter symbol extraction', () => {
   it('extracts TypeScript symbols including function variables', async () => {
@@ -77,7 +82,410 @@ describe('Tree-sitter symbol extraction', () => {
     expect(componentNames).toEqual(expect.arrayContaining(['Greeter', 'hello', 'top_level']));
   });
 
+  it('extracts Rust impl and trait blocks with methods qualified by their type', async () => {
+    const source = [
+      'pub struct Stack<T> {',
+      '    items: Vec<T>,',
+      '}',
+      '',
+      'pub trait Container {',
+      '    fn size(&self) -> usize;',
+      '}',
+      '',
+      'impl<T> Stack<T> {',
+      '    pub fn push(&mut self, item: T) {',
+      '        self.items.push(item);',
+      '    }',
+      '}',
+      '',
+      'impl<T> Container for Stack<T> {',
+      '    fn size(&self) -> usize {',
+      '        self.items.len()',
+      '    }',
+      '}',
+      '',
+      'mod helpers {',
+      '    pub fn clamp(v: i32) -> i32 {',
+      '        v.max(0)',
+      '    }',
+      '}'
+    ].join('\n');
+
+    const extracted = await extractTreeSitterSymbols(source, 'rust');
+
+    const summary = extracted!.symbols.map((s) => [s.kind, s.qualifiedName]);
+    expect(summary).toEqual(
+      expect.arrayContaining([
+        ['struct', 'Stack'],
+        ['trait', 'Container'],
+        ['method', 'Container::size'],
+        ['impl', 'Stack'],
+        ['method', 'Stack::push'],
+        ['method', 'Stack::size'],
+        ['module', 'helpers'],
+        ['function', 'helpers::clamp']
+      ])
+    );
+  });
+
+  it('qualifies Java symbols with the package and enclosing types', async () => {
+    const source = [
+      'package com.acme.users;',
+      '',
+      'public class UserService {',
+      '    public record Page(int number) {}',
+      '',
+      '    public User find(String id) {',
+      '        return null;',
+      '    }',
+      '}'
+    ].join('\n');
+
+    const extracted = await extractTreeSitterSymbols(source, 'java');
+
+    expect(extracted!.symbols.map((s) => [s.kind, s.qualifiedName])).toEqual([
+      ['class', 'com.acme.users.UserService'],
+      ['class', 'com.acme.users.UserService.Page'],
+      ['method', 'com.acme.users.UserService.find']
+    ]);
+  });
+
+  it('extracts Kotlin classes, interfaces, objects and member functions', async () => {
+    const source = [
+      'package com.acme.billing',
+      '',
+      'interface Gateway {',
+      '    fun charge(amount: Long): Boolean',
+      '}',
+      '',
+      'class StripeGateway(private val key: String) : Gateway {',
+      '    override fun charge(amount: Long): Boolean {',
+      '        return amount > 0',
+      '    }',
+      '}',
+      '',
+      'object Invoices {',
+      '    const val PREFIX = "INV"',
+      '    fun nextNumber(): String = PREFIX + "1"',
+      '}',
+      '',
+      'fun formatAmount(amount: Long): String = amount.toString()'
+    ].join('\n');
+
+    const extracted = await extractTreeSitterSymbols(source, 'kotlin');
+
+    expect(extracted!.symbols.map((s) => [s.kind, s.qualifiedName])).toEqual(
+      expect.arrayContaining([
+        ['interface', 'com.acme.billing.Gateway'],
+        ['class', 'com.acme.billing.StripeGateway'],
+        ['method', 'com.acme.billing.StripeGateway.charge'],
+        ['class', 'com.acme.billing.Invoices'],
+        ['method', 'com.acme.billing.Invoices.nextNumber'],
+        ['function', 'com.acme.billing.formatAmount']
+      ])
+    );
+
+    const calls = await extractTreeSitterCalls(source, 'kotlin');
+    expect(calls!.constants.map((c) => c.qualifiedName)).toEqual([
+      'com.acme.billing.Invoices.PREFIX'
+    ]);
+    expect(calls!.calls.map((c) => [c.caller?.name, c.callee])).toContainEqual([
+      'formatAmount',
+      'toString'
+    ]);
+  });
+
+  it('extracts Ruby classes, modules and methods with Ruby-style qualified names', async () => {
+    const source = [
+      'module Billing',
+      '  TAX_RATE = 0.2',
+      '',
+      '  class Invoice < Base',
+      '    def self.create(attrs)',
+      '      new(attrs)',
+      '    end',
+      '',
+      '    def total',
+      '      subtotal * (1 + TAX_RATE)',
+      '    end',
+      '  end',
+      'end',
+      '',
+      'def format_amount(value)',
+      '  value.round(2)',
+      'end'
+    ].join('\n');
+
+    const extracted = await extractTreeSitterSymbols(source, 'ruby');
+
+    expect(extracted!.symbols.map((s) => [s.kind, s.qualifiedName])).toEqual([
+      ['module', 'Billing'],
+      ['class', 'Billing::Invoice'],
+      ['method', 'Billing::Invoice.create'],
+      ['method', 'Billing::Invoice#total'],
+      ['function', 'format_amount']
+    ]);
+
+    const calls = await extractTreeSitterCalls(source, 'ruby');
+    expect(calls!.constants.map((c) => c.qualifiedName)).toEqual(['Billing::TAX_RATE']);
+    expect(calls!.calls.map((c) => [c.caller?.name, c.callee])).toContainEqual([
+      'format_amount',
+      'round'
+    ]);
+  });
+
+  it('qualifies PHP symbols with the namespace and class', async () => {
+    const source = [
+      '<?php',
+      '',
+      'namespace App\\Models;',
+      '',
+      'interface HasTotal',
+      '{',
+      '    public function total(): int;',
+      '}',
+      '',
+      'class Invoice implements HasTotal',
+      '{',
+      '    const PREFIX = "INV";',
+      '',
+      '    public function total(): int',
+      '    {',
+      '        return $this->sum(1, 2);',
+      '    }',
+      '}',
+      '',
+      'function make_invoice(): Invoice',
+      '{',
+      '    return new Invoice();',
+      '}'
+    ].join('\n');
+
+    const extracted = await extractTreeSitterSymbols(source, 'php');
+
+    expect(extracted!.symbols.map((s) => [s.kind, s.qualifiedName])).toEqual([
+      ['interface', 'App\\Models\\HasTotal'],
+      ['method', 'App\\Models\\HasTotal::total'],
+      ['class', 'App\\Models\\Invoice'],
+      ['method', 'App\\Models\\Invoice::total'],
+      ['function', 'App\\Models\\make_invoice']
+    ]);
+    expect(extracted!.symbols[0].namespace).toBe('App\\Models');
+
+    const calls = await extractTreeSitterCalls(source, 'php');
+    expect(calls!.constants.map((c) => c.qualifiedName)).toEqual([
+      'App\\Models\\Invoice::PREFIX'
+    ]);
+    expect(calls!.calls.map((c) => [c.caller?.name, c.callee])).toEqual([
+      ['total', 'sum'],
+      ['make_invoice', 'Invoice']
+    ]);
+  });
+
+  it('extracts Swift protocols, structs, classes and extensions', async () => {
+    const source = [
+      'protocol Priced {',
+      '    func price() -> Double',
+      '}',
+      '',
+      'struct LineItem {',
+      '    let amount: Double',
+      '}',
+      '',
+      'class Invoice: Priced {',
+      '    init(items: [LineItem]) {}',
+      '',
+      '    func price() -> Double {',
+      '        return total()',
+      '    }',
+      '}',
+      '',
+      'extension Invoice {',
+      '    func total() -> Double { 0 }',
+      '}'
+    ].join('\n');
+
+    const extracted = await extractTreeSitterSymbols(source, 'swift');
+
+    expect(extracted!.symbols.map((s) => [s.kind, s.qualifiedName])).toEqual(
+      expect.arrayContaining([
+        ['protocol', 'Priced'],
+        ['method', 'Priced.price'],
+        ['struct', 'LineItem'],
+        ['class', 'Invoice'],
+        ['method', 'Invoice.init'],
+        ['method', 'Invoice.price'],
+        ['extension', 'Invoice'],
+        ['method', 'Invoice.total']
+      ])
+    );
+
+    const calls = await extractTreeSitterCalls(source, 'swift');
+    expect(calls!.calls.map((c) => [c.caller?.name, c.callee])).toContainEqual(['price', 'total']);
+  });
+
+  it('extracts Objective-C classes, categories, protocols and selectors', async () => {
+    const source = [
+      '#import <Foundation/Foundation.h>',
+      '',
+      '@protocol Priced',
+      '- (double)price;',
+      '@end',
+      '',
+      '@interface Invoice : NSObject <Priced>',
+      '- (void)addItem:(NSString *)name quantity:(NSInteger)quantity;',
+      '@end',
+      '',
+      '@interface Invoice (Formatting)',
+      '- (NSString *)formatted;',
+      '@end'
+    ].join('\n');
+
+    expect(detectLanguage('/virtual/Invoice.h', source)).toBe('objective-c');
+    expect(detectLanguage('/virtual/invoice.h', 'int total(void);')).toBe('c');
+
+    const extracted = await extractTreeSitterSymbols(source, 'objective-c');
+
+    expect(extracted!.symbols.map((s) => [s.kind, s.name])).toEqual(
+      expect.arrayContaining([
+        ['protocol', 'Priced'],
+        ['class', 'Invoice'],
+        ['extension', 'Invoice'],
+        ['method', 'addItem:quantity:'],
+        ['method', 'formatted']
+      ])
+    );
+  });
+
+  it('qualifies Scala symbols with the package and enclosing type', async () => {
+    const source = [
+      'package com.acme.billing',
+      '',
+      'trait Priced {',
+      '  def price(): Double',
+      '}',
+      '',
+      'class Invoice(items: Seq[Double]) extends Priced {',
+      '  def price(): Double = total()',
+      '  def total(): Double = items.sum',
+      '}',
+      '',
+      'object Invoice {',
+      '  def empty(): Invoice = new Invoice(Seq.empty)',
+      '}'
+    ].join('\n');
+
+    const extracted = await extractTreeSitterSymbols(source, 'scala');
+
+    expect(extracted!.symbols.map((s) => [s.kind, s.qualifiedName])).toEqual(
+      expect.arrayContaining([
+        ['trait', 'com.acme.billing.Priced'],
+        ['method', 'com.acme.billing.Priced.price'],
+        ['class', 'com.acme.billing.Invoice'],
+        ['method', 'com.acme.billing.Invoice.total'],
+        ['class', 'com.acme.billing.Invoice'],
+        ['method', 'com.acme.billing.Invoice.empty']
+      ])
+    );
+  });
+
+  it('extracts Elixir modules and functions from definition calls', async () => {
+    const source = [
+      'defmodule Billing.Invoice do',
+      '  alias Billing.LineItem',
+      '',
+      '  def total(items) do',
+      '    Enum.sum(amounts(items))',
+      '  end',
+      '',
+      '  defp amounts(items), do: Enum.map(items, &LineItem.amount/1)',
+      'end'
+    ].join('\n');
+
+    expect(detectLanguage('/virtual/invoice.ex', source)).toBe('elixir');
+
+    const extracted = await extractTreeSitterSymbols(source, 'elixir');
+
+    expect(extracted!.symbols.map((s) => [s.kind, s.qualifiedName])).toEqual([
+      ['module', 'Billing.Invoice'],
+      ['function', 'Billing.Invoice.total'],
+      ['function', 'Billing.Invoice.amounts']
+    ]);
+
+    const calls = await extractTreeSitterCalls(source, 'elixir');
+    const edges = calls!.calls.map((c) => [c.caller?.name, c.callee]);
+    expect(edges).toContainEqual(['total', 'amounts']);
+    expect(edges.some(([, callee]) => callee === 'def' || callee === 'alias')).toBe(false);
+  });
+
+  it('extracts Zig container types and their methods', async () => {
+    const source = [
+      'const std = @import("std");',
+      '',
+      'pub const Point = struct {',
+      '    x: i32,',
+      '    y: i32,',
+      '',
+      '    pub fn init(x: i32, y: i32) Point {',
+      '        return .{ .x = x, .y = y };',
+      '    }',
+      '};',
+      '',
+      'const Color = enum { red, green };',
+      '',
+      'pub fn origin() Point {',
+      '    return Point.init(0, 0);',
+      '}'
+    ].join('\n');
+
+    const extracted = await extractTreeSitterSymbols(source, 'zig');
+
+    expect(extracted!.symbols.map((s) => [s.kind, s.qualifiedName])).toEqual(
+      expect.arrayContaining([
+        ['struct', 'Point'],
+        ['method', 'Point.init'],
+        ['enum', 'Color'],
+        ['function', 'origin']
+      ])
+    );
+    expect(extracted!.symbols.some((s) => s.name === 'std')).toBe(false);
+  });
+
+  it('chunks Rust impl blocks per method with the impl as parent', async () => {
+    const analyzer = new GenericAnalyzer();
+    const body = Array.from({ length: 12 }, (_, i) => `        let v${i} = ${i};`);
+    const source = [
+      'pub struct Cache {}',
+      '',
+      'impl Cache {',
+      '    pub fn get(&self) -> i32 {',
+      ...body,
+      '        0',
+      '    }',
+      '',
+      '    pub fn put(&mut self) {',
+      ...body,
+      '    }',
+      '}'
+    ].join('\n');
+
+    const result = await analyzer.analyze('/virtual/cache.rs', source);
+
+    const getChunk = result.chunks.find((chunk) => chunk.metadata.componentName === 'get');
+    expect(getChunk).toBeDefined();
+    expect(getChunk!.content).not.toContain('fn put');
+    expect(getChunk!.metadata.parentSymbol).toBe('Cache');
+    expect(getChunk!.metadata.qualifiedName).toBe('Cache::get');
+  });
+
   it('reports unsupported language grammars', () => {
     expect(supportsTreeSitter('markdown')).toBe(false);
+    expect(supportsTreeSitter('ruby')).toBe(true);
+    expect(supportsTreeSitter('php')).toBe(true);
+    expect(supportsTreeSitter('swift')).toBe(true);
+    expect(supportsTreeSitter('objective-c')).toBe(true);
+    expect(supportsTreeSitter('scala')).toBe(true);
+    expect(supportsTreeSitter('elixir')).toBe(true);
+    expect(supportsTreeSitter('zig')).toBe(true);
   });
 });
@@ -0,0 +1,109 @@
+import { describe, it, expect, vi } from 'vitest';
+import { CodebaseSearcher } from '../src/core/search.js';
+import { literalTokens, TrigramIndex } from '../src/core/trigram-prefilter.js';
+import type { CodeChunk } from '../src/types/index.js';
+
+function chunk(relativePath: string, content: string): CodeChunk {
+  return {
+    id: `${relativePath}:${content.length}`,
+    content,
+    filePath: `/repo/${relativePath}`,
+    relativePath,
+    startLine: 1,
+    endLine: 10,
+    language: 'typescript',
+    framework: 'generic',
+    componentType: 'service',
+    layer: 'core',
+    dependencies: [],
+    imports: [],
+    exports: [],
+    tags: [],
+    metadata: {}
+  };
+}
+
+const CHUNKS = [
+  chunk('src/config/loader.ts', 'export function parseConfigFile(path: string) {}'),
+  chunk('src/config/loader.ts', 'const retries = 3;'),
+  chunk('src/cli.ts', 'parseConfigFile(argv.config);'),
+  // Every trigram of `parseconfigfile`, never next to each other
+  chunk('src/parse.ts', 'parse(); config(); file(); configfile; parseconf'),
+  chunk('src/net/client.ts', "if (error.code === 'ECONNREFUSED') retry();")
+];
+
+function setupSearcher(prefilter: { minChunks: number; maxFiles: number } | null) {
+  const searcher = new CodebaseSearcher('/repo') as any;
+  const search = vi.fn(async () => [{ chunk: CHUNKS[2], score: 0.8, distance: 0.2 }]);
+  searcher.initialized = true;
+  searcher.chunks = CHUNKS;
+  searcher.embeddingProvider = { embed: vi.fn(async () => [0.1, 0.2]) };
+  searcher.storageProvider = { search };
+  searcher.fuseIndex = null;
+  searcher.patternIntelligence = null;
+  searcher.prefilter = prefilter;
+  return { searcher: searcher as CodebaseSearcher, search };
+}
+
+const SEMANTIC_ONLY = {
+  useSemanticSearch: true,
+  useKeywordSearch: false,
+  enableQueryExpansion: false,
+  enableLowConfidenceRescue: false,
+  enableReranker: false,
+  debug: true
+};
+
+describe('literal pre-filter', () => {
+  it('takes identifier-shaped tokens and quoted strings, not plain words', () => {
+    expect(literalTokens('where is parseConfigFile called')).toEqual(['parseconfigfile']);
+    expect(literalTokens('handle ECONNREFUSED and "connection refused" in v2Client')).toEqual([
+      'connection refused',
+      'econnrefused',
+      'v2client'
+    ]);
+    expect(literalTokens('user_id from os.path')).toEqual(['user_id', 'os.path']);
+    expect(literalTokens("how does the Calculator work, isn't it cached")).toEqual([]);
+  });
+
+  it('finds the files containing a literal, ignoring scattered trigrams', () => {
+    const index = new TrigramIndex(CHUNKS);
+
+    expect(index.size).toBe(4);
+    expect(index.filesContaining(['parseconfigfile'])).toEqual(
+      new Set(['src/config/loader.ts', 'src/cli.ts'])
+    );
+    expect(index.filesContaining(['econnrefused', 'retries'])).toEqual(
+      new Set(['src/net/client.ts', 'src/config/loader.ts'])
+    );
+    expect(index.filesContaining(['nowhere_to_be_found'])).toEqual(new Set());
+  });
+
+  it('scopes the vector query to the files holding the literals', async () => {
+    const { searcher, search } = setupSearcher({ minChunks: 0, maxFiles: 500 });
+
+    await searcher.search('where is parseConfigFile called', 5, undefined, SEMANTIC_ONLY);
+
+    const filters = (search.mock.calls[0] as unknown[])[2] as { filePaths?: string[] };
+    expect(filters.filePaths?.sort()).toEqual(['src/cli.ts', 'src/config/loader.ts']);
+    expect(searcher.getLastTrace()?.prefilter).toEqual({
+      literals: ['parseconfigfile'],
+      files: 2
+    });
+  });
+
+  it('searches everything when off, too small, or nothing matches', async () => {
+    const off = setupSearcher(null);
+    await off.searcher.search('where is parseConfigFile called', 5, undefined, SEMANTIC_ONLY);
+    expect((off.search.mock.calls[0] as unknown[])[2]).toBeUndefined();
+
+    const small = setupSearcher({ minChunks: 10_000, maxFiles: 500 });
+    await small.searcher.search('where is parseConfigFile called', 5, undefined, SEMANTIC_ONLY);
+    expect((small.search.mock.calls[0] as unknown[])[2]).toBeUndefined();
+
+    const typo = setupSearcher({ minChunks: 0, maxFiles: 500 });
+    await typo.searcher.search('where is parseConfgFile called', 5, undefined, SEMANTIC_ONLY);
+    expect((typo.search.mock.calls[0] as unknown[])[2]).toBeUndefined();
+    expect(typo.searcher.getLastTrace()?.prefilter).toBeUndefined();
+  });
+});
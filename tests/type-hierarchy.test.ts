@@ -0,0 +1,185 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import {
+  TypeHierarchyBuilder,
+  extractGoTypes,
+  extractTypeScriptTypes,
+  findImplementations,
+  getTypeHierarchy
+} from '../src/core/type-hierarchy.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+const TS_STORE = `
+// class Commented implements Store {}
+export interface Store<T> extends Disposable {
+  get(id: string): T;
+}
+export interface CachedStore<T> extends Store<T> {}
+export abstract class BaseStore<T extends Map<string, number>> implements Store<T> {}
+export class SqlStore
+  extends BaseStore<Row>
+  implements Auditable {}
+class MemoryStore implements CachedStore<User>, Iterable<User> {}
+`;
+
+const GO_STORAGE = `package storage
+
+type Reader interface {
+	Read(p []byte) (int, error)
+}
+
+type ReadCloser interface {
+	Reader
+	Close() error
+}
+
+type (
+	File struct {
+		name string
+	}
+	Alias = File
+)
+
+type Buffer struct {
+	*File
+	data []byte \`json:"data"\`
+}
+
+func (f *File) Read(p []byte) (int, error) { return 0, nil }
+`;
+
+const GO_CLOSE = `package storage
+
+func (f File) Close() error { return nil }
+func (b *Buffer) Reset() {}
+`;
+
+function buildTypes() {
+  const root = path.join(os.tmpdir(), 'types-root');
+  const builder = new TypeHierarchyBuilder(root);
+  builder.trackFile(path.join(root, 'src/store.ts'), 'typescript', TS_STORE);
+  builder.trackFile(path.join(root, 'storage/reader.go'), 'go', GO_STORAGE);
+  builder.trackFile(path.join(root, 'storage/close.go'), 'go', GO_CLOSE);
+  return builder.toJSON();
+}
+
+describe('type extraction', () => {
+  it('reads class and interface heritage from TypeScript', () => {
+    const declarations = extractTypeScriptTypes(TS_STORE, 'src/store.ts', 'typescript');
+    expect(declarations.map((d) => [d.name, d.kind, d.line])).toEqual([
+      ['Store', 'interface', 3],
+      ['CachedStore', 'interface', 6],
+      ['BaseStore', 'class', 7],
+      ['SqlStore', 'class', 8],
+      ['MemoryStore', 'class', 11]
+    ]);
+    expect(declarations[0].extends).toEqual(['Disposable']);
+    expect(declarations[3]).toMatchObject({ extends: ['BaseStore'], implements: ['Auditable'] });
+    expect(declarations[4].implements).toEqual(['CachedStore', 'Iterable']);
+  });
+
+  it('reads Go interfaces, structs, embeds and methods, skipping aliases', () => {
+    const { declarations, methods } = extractGoTypes(GO_STORAGE, 'storage/reader.go');
+    expect(declarations.map((d) => [d.name, d.kind])).toEqual([
+      ['Reader', 'interface'],
+      ['ReadCloser', 'interface'],
+      ['File', 'struct'],
+      ['Buffer', 'struct']
+    ]);
+    expect(declarations[1]).toMatchObject({ methods: ['Close'], embeds: ['Reader'] });
+    expect(declarations[3].embeds).toEqual(['File']);
+    expect(methods).toEqual([{ receiver: 'File', method: 'Read' }]);
+  });
+});
+
+describe('type hierarchy queries', () => {
+  it('finds TypeScript implementations through sub-interfaces and subclasses', () => {
+    const result = findImplementations(buildTypes(), 'Store', 20);
+    expect(result.definedAt[0].file).toBe('src/store.ts:3');
+    expect(result.implementations.map((r) => [r.name, r.via])).toEqual([
+      ['BaseStore', 'implements Store'],
+      ['SqlStore', 'extends BaseStore'],
+      ['MemoryStore', 'implements CachedStore']
+    ]);
+    expect(result.extendedBy.map((r) => r.name)).toEqual(['CachedStore']);
+  });
+
+  it('matches Go method sets across files, pointer receivers and embedded structs', () => {
+    const readers = findImplementations(buildTypes(), 'ReadCloser', 20);
+    expect(readers.implementations.map((r) => r.name).sort()).toEqual(['Buffer', 'File']);
+
+    const hierarchy = getTypeHierarchy(buildTypes(), 'File', { direction: 'up', depth: 1 });
+    expect(hierarchy[0].supertypes?.map((node) => node.name)).toEqual(['Reader', 'ReadCloser']);
+  });
+
+  it('walks subtypes and marks supertypes declared outside the repo', () => {
+    const [store] = getTypeHierarchy(buildTypes(), 'Store', { direction: 'both', depth: 2 });
+    expect(store.supertypes).toEqual([{ name: 'Disposable', via: 'extends', external: true }]);
+    const base = store.subtypes?.find((node) => node.name === 'BaseStore');
+    expect(base?.children?.map((node) => node.name)).toEqual(['SqlStore']);
+  });
+});
+
+describe('find_implementations and get_type_hierarchy tools', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'type-hierarchy-'));
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.writeFile(path.join(tempRoot, 'src', 'store.ts'), TS_STORE);
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('answers from the indexed relationships', async () => {
+    const implementations = await dispatchTool('find_implementations', { interface: 'Store' }, ctx);
+    const payload = JSON.parse(implementations.content![0].text);
+    expect(payload.status).toBe('success');
+    expect(payload.totalImplementations).toBe(3);
+
+    const hierarchy = await dispatchTool(
+      'get_type_hierarchy',
+      { type: 'SqlStore', direction: 'up' },
+      ctx
+    );
+    const tree = JSON.parse(hierarchy.content![0].text);
+    expect(tree.hierarchies[0].supertypes.map((node: { name: string }) => node.name)).toEqual([
+      'BaseStore',
+      'Auditable'
+    ]);
+    expect(tree.hierarchies[0].subtypes).toBeUndefined();
+
+    const missing = await dispatchTool('find_implementations', { interface: 'Nope' }, ctx);
+    expect(JSON.parse(missing.content![0].text).status).toBe('not_found');
+  });
+});
@@ -0,0 +1,170 @@
+import { describe, it, expect, beforeAll, afterAll } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import type { CallGraphData } from '../src/core/call-graph.js';
+import type { DependencyGraphData } from '../src/core/dependency-graph.js';
+import { analyzeUnusedExports } from '../src/core/unused-exports.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+describe('analyzeUnusedExports', () => {
+  const graph: DependencyGraphData = {
+    imports: {
+      'src/app.ts': ['src/util.ts', 'src/plugins.ts'],
+      'src/util.test.ts': ['src/util.ts']
+    },
+    importedBy: {
+      'src/util.ts': ['src/app.ts', 'src/util.test.ts'],
+      'src/plugins.ts': ['src/app.ts']
+    },
+    importDetails: {
+      'src/app.ts': {
+        'src/util.ts': { line: 1, importedSymbols: ['used'] },
+        'src/plugins.ts': { line: 2 }
+      },
+      'src/util.test.ts': { 'src/util.ts': { line: 1, importedSymbols: ['testedOnly'] } }
+    },
+    exports: {
+      'src/util.ts': [
+        { name: 'used', type: 'function' },
+        { name: 'testedOnly', type: 'function' },
+        { name: 'calledByName', type: 'function' },
+        { name: 'localOnly', type: 'function' },
+        { name: 'dead', type: 'variable' },
+        { name: 'default', type: 'default' }
+      ],
+      'src/plugins.ts': [{ name: 'plugin', type: 'function' }],
+      'src/orphan.ts': [{ name: 'orphan', type: 'class' }],
+      'src/util.test.ts': [{ name: 'fixture', type: 'variable' }]
+    }
+  };
+  const callGraph: CallGraphData = {
+    definitions: {},
+    calls: [
+      { caller: 'main', callee: 'registry.calledByName', file: 'src/app.ts', line: 4 },
+      { caller: 'dead', callee: 'localOnly', file: 'src/util.ts', line: 9 }
+    ]
+  };
+  const definitions = [
+    {
+      name: 'dead',
+      kind: 'variable',
+      file: 'src/util.ts',
+      startLine: 12,
+      endLine: 12,
+      language: 'typescript'
+    }
+  ];
+
+  it('reports exports nothing imports or calls, skipping barrels, tests and opaque imports', () => {
+    const report = analyzeUnusedExports(graph, definitions, callGraph, { limit: 10 });
+
+    expect(report.files).toEqual([
+      {
+        file: 'src/orphan.ts',
+        imported: false,
+        symbols: [{ name: 'orphan', calledInFile: false }]
+      },
+      {
+        file: 'src/util.ts',
+        imported: true,
+        symbols: [
+          { name: 'localOnly', calledInFile: true },
+          { name: 'dead', kind: 'variable', line: 12, calledInFile: false }
+        ]
+      }
+    ]);
+    expect(report.opaqueFiles).toEqual(['src/plugins.ts']);
+    expect(report.analyzedFiles).toBe(2);
+  });
+
+  it('scopes by path prefix and caps the symbol count', () => {
+    const report = analyzeUnusedExports(graph, null, callGraph, {
+      scope: 'src/util',
+      includeTests: true,
+      limit: 2
+    });
+    expect(report.totalSymbols).toBe(3);
+    expect(report.truncated).toBe(true);
+    expect(report.files.flatMap((entry) => entry.symbols.map((s) => s.name))).toEqual([
+      'fixture',
+      'localOnly'
+    ]);
+  });
+});
+
+describe('analyze_unused tool', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+
+  beforeAll(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'unused-exports-'));
+    const src = path.join(tempRoot, 'src');
+    await fs.mkdir(path.join(src, 'lib'), { recursive: true });
+    await fs.writeFile(
+      path.join(src, 'cart.ts'),
+      'export function addItem() {}\n' +
+        'export function unusedHelper() {\n  return 1;\n}\n' +
+        'export const TAX = 0.2;\n'
+    );
+    await fs.writeFile(
+      path.join(src, 'checkout.ts'),
+      "import { addItem } from './cart.js';\nexport function checkout() {\n  addItem();\n}\n"
+    );
+    await fs.writeFile(path.join(src, 'lib', 'format.ts'), 'export function formatPrice() {}\n');
+    await fs.writeFile(
+      path.join(src, 'lib', 'index.ts'),
+      "export { formatPrice } from './format.js';\n"
+    );
+    await fs.writeFile(path.join(src, 'plugins.ts'), 'export const plugins = [];\n');
+    await fs.writeFile(
+      path.join(src, 'main.ts'),
+      "import { checkout } from './checkout.js';\nimport * as registry from './plugins.js';\n" +
+        'checkout();\nconsole.log(registry);\n'
+    );
+
+    await new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } }).index();
+
+    const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterAll(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  it('lists unreferenced exports of an indexed project', async () => {
+    const result = await dispatchTool('analyze_unused', {}, ctx);
+    const payload = JSON.parse(result.content![0].text);
+
+    expect(payload.status).toBe('success');
+    expect(payload.files).toHaveLength(1);
+    expect(payload.files[0].file).toBe('src/cart.ts');
+    expect(payload.files[0].symbols.map((s: { name: string }) => s.name)).toEqual([
+      'unusedHelper',
+      'TAX'
+    ]);
+    expect(payload.notAnalyzed).toMatchObject({ count: 1, files: ['src/plugins.ts'] });
+  });
+});
@@ -0,0 +1,122 @@
+import { describe, it, expect, vi } from 'vitest';
+import { CodebaseSearcher } from '../src/core/search.js';
+import { OVER_FETCH_FACTOR, planVectorQuery } from '../src/core/vector-query-plan.js';
+import {
+  DEFAULT_STORAGE_CAPABILITIES,
+  STORAGE_CAPABILITIES,
+  isRemoteStorageProvider,
+  isStorageProviderName
+} from '../src/storage/types.js';
+import type { CodeChunk } from '../src/types/index.js';
+
+function chunk(relativePath: string, tags: string[]): CodeChunk {
+  return {
+    id: relativePath,
+    content: `// ${relativePath}`,
+    filePath: `/repo/${relativePath}`,
+    relativePath,
+    startLine: 1,
+    endLine: 1,
+    language: 'typescript',
+    dependencies: [],
+    imports: [],
+    exports: [],
+    tags,
+    metadata: {}
+  };
+}
+
+describe('vector query planning', () => {
+  it('names every backend and which ones are remote', () => {
+    expect(Object.keys(STORAGE_CAPABILITIES).sort()).toEqual([
+      'lancedb',
+      'milvus',
+      'pgvector',
+      'qdrant',
+      'sqlite'
+    ]);
+    expect(isStorageProviderName('milvus')).toBe(true);
+    expect(isStorageProviderName('toString')).toBe(false);
+    expect(['lancedb', 'sqlite', 'qdrant', 'pgvector', 'milvus'].filter(isRemoteStorageProvider))
+      .toEqual(['qdrant', 'pgvector', 'milvus']);
+  });
+
+  it('pushes down what the backend applies and over-fetches for the rest', () => {
+    const filters = { language: 'go', tags: ['api'], package: '@acme/core' };
+
+    expect(planVectorQuery(STORAGE_CAPABILITIES.qdrant, 10, filters, null)).toEqual({
+      filters: { language: 'go', tags: ['api'] },
+      limit: 10 * OVER_FETCH_FACTOR,
+      postFilter: true
+    });
+    expect(planVectorQuery(STORAGE_CAPABILITIES.qdrant, 10, { tags: ['api'] }, null)).toEqual({
+      filters: { tags: ['api'] },
+      limit: 10,
+      postFilter: false
+    });
+    expect(planVectorQuery(STORAGE_CAPABILITIES.milvus, 10, { role: 'test' }, null)).toEqual({
+      filters: {},
+      limit: 10 * OVER_FETCH_FACTOR,
+      postFilter: true
+    });
+    // LanceDB stores tags as JSON text, so they are applied here
+    expect(planVectorQuery(STORAGE_CAPABILITIES.lancedb, 10, { tags: ['api'] }, null)).toEqual({
+      filters: {},
+      limit: 10 * OVER_FETCH_FACTOR,
+      postFilter: true
+    });
+    expect(planVectorQuery(STORAGE_CAPABILITIES.lancedb, 10, undefined, null)).toEqual({
+      limit: 10,
+      postFilter: false
+    });
+  });
+
+  it('caps path lists at what the backend takes', () => {
+    const files = new Set(Array.from({ length: 600 }, (_, i) => `src/f${i}.ts`));
+    const scope = { files, excluded: Array.from({ length: 900 }, (_, i) => `lib/g${i}.ts`) };
+
+    const qdrant = planVectorQuery(STORAGE_CAPABILITIES.qdrant, 5, {}, scope);
+    expect(qdrant.filters?.filePaths).toHaveLength(600);
+    expect(qdrant.postFilter).toBe(false);
+
+    const milvus = planVectorQuery(STORAGE_CAPABILITIES.milvus, 5, {}, scope);
+    expect(milvus.filters?.filePaths).toBeUndefined();
+    expect(milvus.filters?.excludePaths).toBeUndefined();
+    expect(milvus).toMatchObject({ limit: 5 * OVER_FETCH_FACTOR, postFilter: true });
+
+    const literal = planVectorQuery(STORAGE_CAPABILITIES.milvus, 5, {}, scope, new Set(['a.ts']));
+    expect(literal).toEqual({ filters: { filePaths: ['a.ts'] }, limit: 5, postFilter: false });
+  });
+
+  it('applies the filters a backend ignores to its results', async () => {
+    const searcher = new CodebaseSearcher('/repo') as any;
+    const search = vi.fn(async () => [
+      { chunk: chunk('src/untagged.ts', []), score: 0.9, distance: 0.1 },
+      { chunk: chunk('src/api.ts', ['api']), score: 0.8, distance: 0.2 }
+    ]);
+    searcher.initialized = true;
+    searcher.chunks = [];
+    searcher.embeddingProvider = { embed: vi.fn(async () => [0.1, 0.2]) };
+    searcher.storageProvider = { search, capabilities: DEFAULT_STORAGE_CAPABILITIES };
+    searcher.fuseIndex = null;
+    searcher.patternIntelligence = null;
+
+    const results = await (searcher as CodebaseSearcher).search(
+      'handler',
+      5,
+      { tags: ['api'] },
+      {
+        useSemanticSearch: true,
+        useKeywordSearch: false,
+        enableQueryExpansion: false,
+        enableLowConfidenceRescue: false,
+        enableReranker: false
+      }
+    );
+
+    expect(results.map((r) => r.filePath)).toEqual(['/repo/src/api.ts']);
+    const [, limit, filters] = search.mock.calls[0] as unknown[];
+    expect(filters).toEqual({});
+    expect(limit).toBeGreaterThan(5);
+  });
+});
@@ -0,0 +1,125 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import type { CodeChunk, IndexingProgress } from '../src/types/index.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+function chunk(root: string, file: string, content: string): CodeChunk {
+  return {
+    id: file,
+    content,
+    filePath: path.join(root, file),
+    relativePath: file,
+    startLine: 1,
+    endLine: content.split('\n').length,
+    language: 'typescript',
+    dependencies: [],
+    imports: [],
+    exports: [],
+    tags: [],
+    metadata: {}
+  };
+}
+
+describe('warm start', () => {
+  let tempRoot: string;
+  let ctx: ToolContext;
+  let chunks: CodeChunk[];
+
+  // Halfway through parsing 4 files; analyzing runs from 5% to 50% overall
+  const progress: IndexingProgress = {
+    phase: 'analyzing',
+    percentage: 50,
+    filesProcessed: 2,
+    totalFiles: 4,
+    chunksCreated: 2,
+    errors: [],
+    startedAt: new Date()
+  };
+
+  beforeEach(async () => {
+    tempRoot = await fs.mkdtemp(path.join(os.tmpdir(), 'warm-start-'));
+    chunks = [
+      chunk(tempRoot, 'src/refunds.ts', 'export function refundPayment(amount: number) {}'),
+      chunk(tempRoot, 'src/cart.ts', 'export function addToCart(item: string) {}')
+    ];
+    const indexer = {
+      getProgress: () => ({ ...progress }),
+      getPartialChunks: () => [...chunks]
+    } as unknown as CodebaseIndexer;
+
+    const baseDir = path.join(tempRoot, CODEBASE_CONTEXT_DIRNAME);
+    ctx = {
+      indexState: { status: 'indexing', indexer },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempRoot,
+      performIndexing: () => {}
+    };
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempRoot);
+  });
+
+  const search = async (query: string) => {
+    const result = await dispatchTool('search_codebase', { query }, ctx);
+    return JSON.parse(result.content![0].text);
+  };
+
+  it('serves flagged keyword results from the files parsed so far', async () => {
+    const payload = await search('refundPayment');
+
+    expect(payload.status).toBe('success');
+    expect(payload.partial).toBe(true);
+    expect(payload.index).toEqual({
+      status: 'indexing',
+      readiness: 28,
+      filesIndexed: 2,
+      totalFiles: 4
+    });
+    expect(payload.results[0].file).toMatch(/refunds\.ts:1-1$/);
+  });
+
+  it('reports readiness until there is something to search', async () => {
+    chunks = [];
+    const payload = await search('refundPayment');
+
+    expect(payload.status).toBe('indexing');
+    expect(payload.readiness).toBe(28);
+    expect(payload.partial).toBeUndefined();
+  });
+
+  it('does not serve partial results while rebuilding an existing index', async () => {
+    ctx.indexState.lastIndexed = new Date();
+    const payload = await search('refundPayment');
+
+    expect(payload.status).toBe('indexing');
+    expect(payload.results).toBeUndefined();
+  });
+
+  it('lets go of the partial chunks once the build is done', async () => {
+    await fs.mkdir(path.join(tempRoot, 'src'), { recursive: true });
+    await fs.writeFile(path.join(tempRoot, 'src', 'refunds.ts'), chunks[0].content + '\n');
+    const indexer = new CodebaseIndexer({ rootPath: tempRoot, config: { skipEmbedding: true } });
+    await indexer.index();
+
+    expect(indexer.getPartialChunks()).toEqual([]);
+  });
+});
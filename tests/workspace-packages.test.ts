@@ -0,0 +1,180 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import { CodebaseIndexer } from '../src/core/indexer.js';
+import { CodebaseSearcher } from '../src/core/search.js';
+import { dispatchTool } from '../src/tools/index.js';
+import type { ToolContext } from '../src/tools/types.js';
+import {
+  detectWorkspacePackages,
+  findOwningPackage,
+  parseCargoWorkspace,
+  parseGoWork,
+  parsePnpmWorkspace
+} from '../src/utils/workspace-detection.js';
+import {
+  CODEBASE_CONTEXT_DIRNAME,
+  INTELLIGENCE_FILENAME,
+  KEYWORD_INDEX_FILENAME,
+  MEMORY_FILENAME,
+  VECTOR_DB_DIRNAME
+} from '../src/constants/codebase-context.js';
+import type { CodeChunk } from '../src/types/index.js';
+import { rmWithRetries } from './test-helpers.js';
+
+vi.mock('../src/embeddings/index.js', async (importOriginal) => {
+  const original = await importOriginal<typeof import('../src/embeddings/index.js')>();
+  const provider = {
+    name: 'fake',
+    modelName: 'fake-model',
+    dimensions: 2,
+    initialize: async () => {},
+    isReady: () => true,
+    embed: async () => [1, 0],
+    embedBatch: async (texts: string[]) => texts.map(() => [1, 0])
+  };
+  return { ...original, getEmbeddingProvider: async () => provider };
+});
+
+describe('workspace manifest parsing', () => {
+  it('reads go.work use directives in both forms', () => {
+    const goWork = ['go 1.22', '', 'use ./tools // codegen', 'use (', '  ./services/api', ')'];
+    expect(parseGoWork(goWork.join('\n'))).toEqual(['./tools', './services/api']);
+  });
+
+  it('reads Cargo workspace members and excludes', () => {
+    const cargo = [
+      '[workspace]',
+      'members = [',
+      '  "crates/*", # libraries',
+      '  "cli"',
+      ']',
+      'exclude = ["crates/scratch"]',
+      '',
+      '[workspace.dependencies]',
+      'serde = "1"'
+    ].join('\n');
+    expect(parseCargoWorkspace(cargo)).toEqual({
+      members: ['crates/*', 'cli'],
+      exclude: ['crates/scratch']
+    });
+    expect(parseCargoWorkspace('[package]\nname = "solo"\n')).toBeNull();
+  });
+
+  it('reads pnpm workspace package globs', () => {
+    const yaml = "packages:\n  - 'apps/*'\n  - \"!apps/legacy\"\ncatalog:\n  react: ^18\n";
+    expect(parsePnpmWorkspace(yaml)).toEqual(['apps/*', '!apps/legacy']);
+  });
+});
+
+describe('monorepo packages', () => {
+  let tempDir: string;
+
+  const write = async (file: string, content: string) => {
+    await fs.mkdir(path.dirname(path.join(tempDir, file)), { recursive: true });
+    await fs.writeFile(path.join(tempDir, file), content);
+  };
+
+  beforeEach(async () => {
+    tempDir = await fs.mkdtemp(path.join(os.tmpdir(), 'workspace-packages-'));
+    await write(
+      'package.json',
+      JSON.stringify({ name: 'root', private: true, workspaces: ['services/*'] })
+    );
+    await write('services/billing/package.json', JSON.stringify({ name: '@acme/billing' }));
+    await write(
+      'services/billing/src/invoice.ts',
+      'export function retryInvoice(id: string) {\n  return `invoice ${id}`;\n}\n'
+    );
+    await write('services/web/package.json', JSON.stringify({ name: '@acme/web' }));
+    await write(
+      'services/web/src/page.ts',
+      'export function renderInvoicePage() {\n  return "invoice page";\n}\n'
+    );
+    await write('go.work', 'go 1.22\n\nuse ./gateway\n');
+    await write('gateway/go.mod', 'module github.com/acme/gateway\n\ngo 1.22\n');
+    await write('gateway/main.go', 'package main\n\nfunc main() {}\n');
+    await write('Cargo.toml', '[workspace]\nmembers = ["crates/*"]\n');
+    await write('crates/ledger/Cargo.toml', '[package]\nname = "acme-ledger"\nversion = "0.1.0"\n');
+    await write('crates/ledger/src/lib.rs', 'pub fn post_invoice() {}\n');
+    await write('scripts/build.ts', 'export const invoiceBuild = true;\n');
+  });
+
+  afterEach(async () => {
+    await rmWithRetries(tempDir);
+  });
+
+  it('detects packages across ecosystems and maps files to the innermost one', async () => {
+    const packages = await detectWorkspacePackages(tempDir);
+
+    expect(packages.map((p) => [p.ecosystem, p.name, p.directory])).toEqual([
+      ['cargo', 'acme-ledger', 'crates/ledger'],
+      ['go', 'github.com/acme/gateway', 'gateway'],
+      ['npm', '@acme/billing', 'services/billing'],
+      ['npm', '@acme/web', 'services/web']
+    ]);
+    expect(findOwningPackage(packages, 'services/web/src/page.ts')?.name).toBe('@acme/web');
+    expect(findOwningPackage(packages, 'services/webhooks/x.ts')).toBeNull();
+  });
+
+  it('names Bazel packages by label when the root is a Bazel workspace', async () => {
+    await write('MODULE.bazel', 'module(name = "acme")\n');
+    await write('tools/lint/BUILD.bazel', '');
+    await write('BUILD', '');
+
+    const bazel = (await detectWorkspacePackages(tempDir)).filter((p) => p.ecosystem === 'bazel');
+    expect(bazel).toEqual([{ name: '//tools/lint', directory: 'tools/lint', ecosystem: 'bazel' }]);
+  });
+
+  it('tags chunks with their package and scopes searches and listings to it', async () => {
+    await new CodebaseIndexer({ rootPath: tempDir, config: { skipEmbedding: true } }).index();
+
+    const raw = await fs.readFile(
+      path.join(tempDir, CODEBASE_CONTEXT_DIRNAME, KEYWORD_INDEX_FILENAME),
+      'utf-8'
+    );
+    const chunks = (JSON.parse(raw) as { chunks: CodeChunk[] }).chunks;
+    const packageOf = (suffix: string) =>
+      chunks.find((chunk) => chunk.relativePath.replace(/\\/g, '/').endsWith(suffix))?.metadata
+        .package;
+    expect(packageOf('invoice.ts')).toBe('@acme/billing');
+    expect(packageOf('main.go')).toBe('github.com/acme/gateway');
+    expect(packageOf('build.ts')).toBeUndefined();
+
+    const searcher = new CodebaseSearcher(tempDir);
+    const results = await searcher.search(
+      'invoice',
+      10,
+      { package: '@acme/billing' },
+      { useSemanticSearch: false, useKeywordSearch: true, enableReranker: false }
+    );
+    expect(results.length).toBeGreaterThan(0);
+    expect(results.every((r) => r.metadata.package === '@acme/billing')).toBe(true);
+
+    const baseDir = path.join(tempDir, CODEBASE_CONTEXT_DIRNAME);
+    const ctx: ToolContext = {
+      indexState: { status: 'ready' },
+      paths: {
+        baseDir,
+        memory: path.join(baseDir, MEMORY_FILENAME),
+        intelligence: path.join(baseDir, INTELLIGENCE_FILENAME),
+        keywordIndex: path.join(baseDir, KEYWORD_INDEX_FILENAME),
+        vectorDb: path.join(baseDir, VECTOR_DB_DIRNAME)
+      },
+      rootPath: tempDir,
+      performIndexing: () => {}
+    };
+    const listed = await dispatchTool('list_packages', { ecosystem: 'npm' }, ctx);
+    const payload = JSON.parse(listed.content![0].text);
+    expect(payload.totalPackages).toBe(2);
+    expect(payload.packages[0]).toMatchObject({
+      name: '@acme/billing',
+      directory: 'services/billing',
+      files: 1
+    });
+
+    const invalid = await dispatchTool('list_packages', { ecosystem: 'maven' }, ctx);
+    expect(invalid.isError).toBe(true);
+  });
+});
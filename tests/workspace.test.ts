@@ -0,0 +1,150 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import { promises as fs } from 'fs';
+import os from 'os';
+import path from 'path';
+import {
+  buildWorkspaceProjects,
+  mergeProjectResults,
+  parseRootList,
+  selectProjects
+} from '../src/core/workspace.js';
+import { CODEBASE_CONTEXT_DIRNAME, MEMORY_FILENAME } from '../src/constants/codebase-context.js';
+import { rmWithRetries } from './test-helpers.js';
+
+type CallToolHandler = (request: {
+  params: { name: string; arguments?: Record<string, unknown> };
+}) => Promise<{ content?: Array<{ text: string }>; isError?: boolean }>;
+
+describe('workspace project resolution', () => {
+  it('parses delimited root lists with optional names', () => {
+    expect(parseRootList('api=/work/api:/work/web::', ':')).toEqual([
+      { name: 'api', rootPath: '/work/api' },
+      { rootPath: '/work/web' }
+    ]);
+    expect(parseRootList(undefined)).toEqual([]);
+  });
+
+  it('dedupes roots and keeps project names unique', () => {
+    const projects = buildWorkspaceProjects([
+      { rootPath: '/a/app' },
+      { rootPath: '/b/app' },
+      { rootPath: '/a/app/' },
+      { rootPath: '/c/all' }
+    ]);
+
+    expect(projects).toEqual([
+      { name: 'app', rootPath: path.resolve('/a/app') },
+      { name: 'app-2', rootPath: path.resolve('/b/app') },
+      { name: 'all-2', rootPath: path.resolve('/c/all') }
+    ]);
+  });
+
+  it('selects the primary project by default, by name, by path, or all', () => {
+    const projects = buildWorkspaceProjects([{ rootPath: '/w/api' }, { rootPath: '/w/web' }]);
+
+    expect(selectProjects(projects, undefined)?.map((p) => p.name)).toEqual(['api']);
+    expect(selectProjects(projects, 'web')?.map((p) => p.name)).toEqual(['web']);
+    expect(selectProjects(projects, '/w/web')?.map((p) => p.name)).toEqual(['web']);
+    expect(selectProjects(projects, 'all')?.map((p) => p.name)).toEqual(['api', 'web']);
+    expect(selectProjects(projects, 'mobile')).toBeNull();
+  });
+
+  it('merges per-project results by score', () => {
+    const merged = mergeProjectResults(
+      [
+        { project: 'api', results: [{ score: 0.4 }, { score: 0.9 }] },
+        { project: 'web', results: [{ score: 0.7 }] }
+      ],
+      2
+    );
+
+    expect(merged).toEqual([
+      { score: 0.9, project: 'api' },
+      { score: 0.7, project: 'web' }
+    ]);
+  });
+});
+
+describe('multi-root server routing', () => {
+  let rootA: string;
+  let rootB: string;
+  let originalArgv: string[];
+  let originalEnv: { root?: string; roots?: string };
+
+  beforeEach(async () => {
+    vi.resetModules();
+    rootA = await fs.mkdtemp(path.join(os.tmpdir(), 'workspace-a-'));
+    rootB = await fs.mkdtemp(path.join(os.tmpdir(), 'workspace-b-'));
+    originalArgv = [...process.argv];
+    originalEnv = { root: process.env.CODEBASE_ROOT, roots: process.env.CODEBASE_ROOTS };
+    process.env.CODEBASE_ROOT = rootA;
+    process.argv[2] = rootA;
+    process.env.CODEBASE_ROOTS = `second=${rootB}`;
+  });
+
+  afterEach(async () => {
+    process.argv = originalArgv;
+    for (const [key, value] of [
+      ['CODEBASE_ROOT', originalEnv.root],
+      ['CODEBASE_ROOTS', originalEnv.roots]
+    ] as const) {
+      if (value === undefined) delete process.env[key];
+      else process.env[key] = value;
+    }
+    await rmWithRetries(rootA);
+    await rmWithRetries(rootB);
+  });
+
+  async function getCallToolHandler(): Promise<CallToolHandler> {
+    const { server } = await import('../src/index.js');
+    const handlers = (server as unknown as { _requestHandlers: Map<string, CallToolHandler> })
+      ._requestHandlers;
+    return handlers.get('tools/call')!;
+  }
+
+  it('lists every configured project', async () => {
+    const handler = await getCallToolHandler();
+
+    const result = await handler({ params: { name: 'list_projects', arguments: {} } });
+    const payload = JSON.parse(result.content![0].text);
+
+    expect(payload.projects.map((p: { name: string }) => p.name)).toEqual([
+      path.basename(rootA),
+      'second'
+    ]);
+    expect(payload.projects[0].primary).toBe(true);
+  });
+
+  it('routes tool calls to the selected project only', async () => {
+    const handler = await getCallToolHandler();
+
+    await handler({
+      params: {
+        name: 'remember',
+        arguments: {
+          project: 'second',
+          type: 'decision',
+          category: 'architecture',
+          memory: 'Second project uses events',
+          reason: 'test'
+        }
+      }
+    });
+
+    const memoryB = path.join(rootB, CODEBASE_CONTEXT_DIRNAME, MEMORY_FILENAME);
+    const memoryA = path.join(rootA, CODEBASE_CONTEXT_DIRNAME, MEMORY_FILENAME);
+    expect(await fs.readFile(memoryB, 'utf-8')).toContain('Second project uses events');
+    await expect(fs.access(memoryA)).rejects.toThrow();
+  });
+
+  it('rejects unknown projects', async () => {
+    const handler = await getCallToolHandler();
+
+    const result = await handler({
+      params: { name: 'get_memory', arguments: { project: 'missing' } }
+    });
+
+    expect(result.isError).toBe(true);
+    expect(result.content![0].text).toContain('second');
+  });
+});